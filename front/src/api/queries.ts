@@ -1,5 +1,6 @@
+import { useEffect, useState } from "react";
 import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
-import { apiClient } from "./client";
+import { apiBaseUrl, apiClient, getStoredToken } from "./client";
 import type { components } from "./schema";
 
 // Type aliases for convenience
@@ -23,6 +24,7 @@ export type CreateGrantDefinitionRequest =
 export type GrantRequest = components["schemas"]["GrantRequest"];
 export type CreateGrantRequestPayload =
   components["schemas"]["CreateGrantRequestPayload"];
+export type StatementApproval = components["schemas"]["StatementApproval"];
 export type Connection = components["schemas"]["Connection"];
 export type Query = components["schemas"]["Query"];
 export type QueryWithRows = components["schemas"]["QueryWithRows"];
@@ -34,6 +36,10 @@ export type CreateAPIKeyResponse =
 export type ConnectionInfo = components["schemas"]["ConnectionInfo"];
 export type ConnectionTestResult =
   components["schemas"]["ConnectionTestResult"];
+export type TestDatabaseConnectionRequest =
+  components["schemas"]["TestDatabaseConnectionRequest"];
+export type PrivilegeCheckResult =
+  components["schemas"]["PrivilegeCheckResult"];
 export type DeviceConsentInfo = components["schemas"]["DeviceConsentInfo"];
 
 // ============================================================================
@@ -321,6 +327,41 @@ export function useTestServerConnection(uid: string) {
   });
 }
 
+export function useTestDatabaseConnection() {
+  return useMutation({
+    mutationFn: async (
+      data: TestDatabaseConnectionRequest
+    ): Promise<ConnectionTestResult> => {
+      const response = await apiClient.POST("/servers/test", { body: data });
+      if (response.error) {
+        throw new Error(
+          response.error.message || "Failed to test the connection"
+        );
+      }
+      return response.data as ConnectionTestResult;
+    },
+  });
+}
+
+// Privilege check: logs in with the stored credentials and reports what the
+// account can do upstream. Like the connectivity check, a failed login is a
+// successful request with `ok: false`.
+export function useCheckServerPrivileges(uid: string) {
+  return useMutation({
+    mutationFn: async (): Promise<PrivilegeCheckResult> => {
+      const response = await apiClient.POST("/servers/{uid}/check-privileges", {
+        params: { path: { uid } },
+      });
+      if (response.error) {
+        throw new Error(
+          response.error.message || "Failed to check the privileges"
+        );
+      }
+      return response.data as PrivilegeCheckResult;
+    },
+  });
+}
+
 // SSH servers (bastions). These are excluded from the regular database list;
 // used by the "via SSH server" selector and admin SSH management.
 export function useSSHServers(enabled = true) {
@@ -652,6 +693,44 @@ export function useGrantRequests(filters?: {
   });
 }
 
+export function useRequestGrantExtension(options?: {
+  onSuccess?: (req: GrantRequest) => void;
+  onError?: (error: Error) => void;
+}) {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: async ({
+      uid,
+      durationSeconds,
+      justification,
+    }: {
+      uid: string;
+      durationSeconds: number;
+      justification?: string;
+    }): Promise<GrantRequest> => {
+      const response = await apiClient.POST(
+        "/grants/{uid}/extension-requests",
+        {
+          params: { path: { uid } },
+          body: { duration_seconds: durationSeconds, justification },
+        }
+      );
+      if (response.error || !response.data) {
+        throw new Error(
+          response.error?.message || "Failed to request grant extension"
+        );
+      }
+      return response.data;
+    },
+    onSuccess: (req) => {
+      queryClient.invalidateQueries({ queryKey: ["grant-requests"] });
+      options?.onSuccess?.(req);
+    },
+    onError: options?.onError,
+  });
+}
+
 export function useCreateGrantRequest(options?: {
   onSuccess?: (req: GrantRequest) => void;
   onError?: (error: Error) => void;
@@ -763,6 +842,91 @@ export function useCancelGrantRequest(options?: {
   });
 }
 
+// ============================================================================
+// Statement Approvals
+// ============================================================================
+
+export function useStatementApprovals(filters?: {
+  status?: "pending" | "approved" | "denied" | "expired";
+  user_id?: string;
+  database_id?: string;
+}) {
+  return useQuery({
+    queryKey: ["statement-approvals", filters],
+    queryFn: async (): Promise<StatementApproval[]> => {
+      const response = await apiClient.GET("/statement-approvals", {
+        params: { query: filters as Record<string, unknown> },
+      });
+      if (response.error) {
+        throw new Error(
+          response.error.message || "Failed to load statement approvals"
+        );
+      }
+      return response.data?.statement_approvals || [];
+    },
+    // A held statement waits minutes at most: keep the list live.
+    refetchInterval: 5000,
+  });
+}
+
+export function useApproveStatementApproval(options?: {
+  onSuccess?: () => void;
+  onError?: (error: Error) => void;
+}) {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: async (uid: string): Promise<void> => {
+      const response = await apiClient.POST(
+        "/statement-approvals/{uid}/approve",
+        { params: { path: { uid } } }
+      );
+      if (response.error) {
+        throw new Error(
+          response.error.message || "Failed to approve statement"
+        );
+      }
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ["statement-approvals"] });
+      options?.onSuccess?.();
+    },
+    onError: options?.onError,
+  });
+}
+
+export function useDenyStatementApproval(options?: {
+  onSuccess?: () => void;
+  onError?: (error: Error) => void;
+}) {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: async (args: {
+      uid: string;
+      reason?: string;
+    }): Promise<void> => {
+      const response = await apiClient.POST(
+        "/statement-approvals/{uid}/deny",
+        {
+          params: { path: { uid: args.uid } },
+          body: { reason: args.reason ?? "" },
+        }
+      );
+      if (response.error) {
+        throw new Error(
+          response.error.message || "Failed to deny statement"
+        );
+      }
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ["statement-approvals"] });
+      options?.onSuccess?.();
+    },
+    onError: options?.onError,
+  });
+}
+
 // ============================================================================
 // Connections
 // ============================================================================
@@ -836,6 +1000,93 @@ export function useQueries(
   });
 }
 
+// Maximum number of streamed queries kept by useQueryStream
+const QUERY_STREAM_LIMIT = 500;
+
+// useQueryStream follows GET /queries/stream while enabled, newest query
+// first. EventSource cannot send the bearer token, so the stream is read
+// with fetch.
+export function useQueryStream(
+  filters?: { connection_id?: string; user_id?: string; database_id?: string },
+  options?: { enabled?: boolean }
+) {
+  const enabled = options?.enabled ?? true;
+  const [queries, setQueries] = useState<Query[]>([]);
+  const [dropped, setDropped] = useState(0);
+  const [error, setError] = useState<Error | null>(null);
+  const { connection_id, user_id, database_id } = filters ?? {};
+
+  useEffect(() => {
+    if (!enabled) {
+      return;
+    }
+
+    setQueries([]);
+    setDropped(0);
+    setError(null);
+
+    const params = new URLSearchParams();
+    if (connection_id) params.set("connection_id", connection_id);
+    if (user_id) params.set("user_id", user_id);
+    if (database_id) params.set("database_id", database_id);
+
+    const controller = new AbortController();
+    const token = getStoredToken();
+
+    const follow = async () => {
+      const response = await fetch(`${apiBaseUrl}/queries/stream?${params}`, {
+        headers: token ? { Authorization: `Bearer ${token}` } : {},
+        signal: controller.signal,
+      });
+      if (!response.ok || !response.body) {
+        throw new Error(`Failed to stream queries (${response.status})`);
+      }
+
+      const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) {
+          return;
+        }
+
+        buffer += value;
+        let end: number;
+        while ((end = buffer.indexOf("\n\n")) >= 0) {
+          const block = buffer.slice(0, end);
+          buffer = buffer.slice(end + 2);
+
+          let event = "message";
+          let data = "";
+          for (const line of block.split("\n")) {
+            if (line.startsWith("event:")) event = line.slice(6).trim();
+            else if (line.startsWith("data:")) data += line.slice(5);
+          }
+
+          if (event === "query") {
+            const query = JSON.parse(data) as Query;
+            setQueries((prev) => [query, ...prev].slice(0, QUERY_STREAM_LIMIT));
+          } else if (event === "dropped") {
+            const { count } = JSON.parse(data) as { count: number };
+            setDropped((prev) => prev + count);
+          }
+        }
+      }
+    };
+
+    follow().catch((err: unknown) => {
+      if (!controller.signal.aborted) {
+        setError(err instanceof Error ? err : new Error(String(err)));
+      }
+    });
+
+    return () => controller.abort();
+  }, [enabled, connection_id, user_id, database_id]);
+
+  return { queries, dropped, error };
+}
+
 export function useQueryDetails(uid: string) {
   return useQuery({
     queryKey: ["queries", uid],
@@ -862,7 +1113,7 @@ export type QueryRowsResult = {
 
 export function useQueryRows(
   uid: string,
-  options?: { cursor?: string; limit?: number }
+  options?: { cursor?: string; limit?: number; enabled?: boolean }
 ) {
   return useQuery({
     queryKey: ["queries", uid, "rows", options?.cursor, options?.limit],
@@ -878,7 +1129,7 @@ export function useQueryRows(
       }
       return response.data as QueryRowsResult;
     },
-    enabled: !!uid,
+    enabled: !!uid && options?.enabled !== false,
   });
 }
 
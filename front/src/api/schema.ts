@@ -203,6 +203,52 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
+    "/auth/oidc": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Initiate OpenID Connect login
+         * @description Redirects to the identity provider's authorization page, discovered
+         *     from the configured issuer. After approval, the provider redirects
+         *     back to the callback endpoint.
+         */
+        get: operations["initiateOIDCAuth"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/auth/oidc/callback": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * OpenID Connect callback
+         * @description Handles the redirect from the identity provider. Checks the ID token,
+         *     creates or links the user, syncs their roles when a role mapping is
+         *     configured, creates a session and redirects to the app. A user with
+         *     no mapped role and no default role is sent back with
+         *     error=OAUTH_NO_ROLE.
+         */
+        get: operations["oidcAuthCallback"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
     "/auth/device": {
         parameters: {
             query?: never;
@@ -453,6 +499,86 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
+    "/users/{uid}/rate-limit": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
+            };
+            cookie?: never;
+        };
+        /**
+         * Get a user's API rate limit
+         * @description Returns the API rate limit applied to the user, where it comes from, and how much
+         *     of the current window they used. Users can read their own; admins can read anyone's.
+         *
+         *     The limit applied is, in order: the user's own override, the most generous override
+         *     of their roles, then the `requests_per_minute` default of the configuration.
+         */
+        get: operations["getUserRateLimit"];
+        /**
+         * Override a user's API rate limit (admin only)
+         * @description Sets the API rate limit of the user, taking precedence over their roles' overrides and the default.
+         */
+        put: operations["setUserRateLimit"];
+        post?: never;
+        /**
+         * Remove a user's API rate limit override (admin only)
+         * @description The user falls back to their roles' overrides, then the default.
+         */
+        delete: operations["deleteUserRateLimit"];
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/rate-limits": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Get the API rate limits (admin only)
+         * @description Returns the rate limit defaults of the configuration and the per-role overrides.
+         */
+        get: operations["getRateLimits"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/rate-limits/roles/{role}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Role */
+                role: "admin" | "viewer" | "auditor" | "connector";
+            };
+            cookie?: never;
+        };
+        get?: never;
+        /**
+         * Override a role's API rate limit (admin only)
+         * @description Sets the API rate limit of the users having the role. A user with several
+         *     overridden roles gets the most generous one. Other instances apply it within 30 seconds.
+         */
+        put: operations["setRoleRateLimit"];
+        post?: never;
+        /** Remove a role's API rate limit override (admin only) */
+        delete: operations["deleteRoleRateLimit"];
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
     "/servers": {
         parameters: {
             query?: never;
@@ -573,6 +699,172 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
+    "/grant-templates": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /** List grant templates (admin) */
+        get: operations["listGrantTemplates"];
+        put?: never;
+        /**
+         * Create grant template (admin)
+         * @description Saves a grant shape (controls, tables, duration, quotas, labels) to apply to many
+         *     users and databases at once with `POST /grant-templates/{uid}/grants`. Unlike grant
+         *     definitions, templates are never offered to users.
+         */
+        post: operations["createGrantTemplate"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/grant-templates/{uid}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                uid: string;
+            };
+            cookie?: never;
+        };
+        /** Get grant template by UID (admin) */
+        get: operations["getGrantTemplate"];
+        put?: never;
+        post?: never;
+        /**
+         * Delete grant template (admin)
+         * @description Hard-deletes the template; the grants created from it stay.
+         */
+        delete: operations["deleteGrantTemplate"];
+        options?: never;
+        head?: never;
+        /**
+         * Update grant template (admin)
+         * @description Replaces the whole template. The grants already created from it are unchanged.
+         */
+        patch: operations["updateGrantTemplate"];
+        trace?: never;
+    };
+    "/grant-templates/{uid}/grants": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                uid: string;
+            };
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Apply grant template (admin)
+         * @description Creates one grant per user and database pair from the template, at most 500 per
+         *     call. Each grant is completed and checked against its database's grant defaults,
+         *     as with `POST /grants`. Either every grant is created or none is; the call is
+         *     recorded as a single `grant.bulk_created` audit event.
+         */
+        post: operations["applyGrantTemplate"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/grants/{uid}/extension-requests": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
+            };
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Request a grant extension
+         * @description The grant's user asks for more time on a grant that is neither
+         *     revoked nor expired. The request goes through the grant request
+         *     flow: admins are notified and approve or deny it. On approval the
+         *     grant's expiry is pushed back by `duration_seconds`, counted from its
+         *     current expiry (or from the approval if it expired meanwhile), and
+         *     its live sessions keep running.
+         *
+         *     `duration_seconds` is at most the grant's original duration, and the
+         *     database's default maximum grant duration still bounds it.
+         */
+        post: operations["requestGrantExtension"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/grants/{uid}/proxy-credentials": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
+            };
+            cookie?: never;
+        };
+        /**
+         * List the proxy credentials of a grant (admin)
+         * @description The latest first. Passwords are never returned again.
+         */
+        get: operations["listProxyCredentials"];
+        put?: never;
+        /**
+         * Issue a one-time proxy credential (admin)
+         * @description Issues a username and password that open a single PostgreSQL
+         *     connection under the grant, as the grant's user, for someone without
+         *     a DBBat user: a contractor, a vendor's support engineer. `principal`
+         *     names who the credential is handed to; the audit log records it with
+         *     the issuing administrator when the credential is created and used.
+         *
+         *     The credential is valid for `valid_minutes`, at most a day and never
+         *     past the grant's expiry. The password is only returned once. Only
+         *     PostgreSQL databases are supported.
+         */
+        post: operations["createProxyCredential"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/grants/{uid}/proxy-credentials/{credential_uid}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
+                credential_uid: string;
+            };
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        post?: never;
+        /**
+         * Revoke a proxy credential (admin)
+         * @description Revokes a credential that was not used yet. The connection of a used
+         *     one is terminated with `DELETE /connections/{uid}` instead.
+         */
+        delete: operations["revokeProxyCredential"];
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
     "/user-groups": {
         parameters: {
             query?: never;
@@ -798,8 +1090,10 @@ export interface paths {
          * Approve grant request (admin)
          * @description Atomically transitions pending → approved and creates a real
          *     AccessGrant from the linked definition + the request's
-         *     user/database. Returns 409 if not pending or if the linked
-         *     definition has been deactivated.
+         *     user/database. An extension request instead pushes back the expiry
+         *     of the grant it extends. Returns 409 if not pending, if the linked
+         *     definition has been deactivated, or if the grant to extend has been
+         *     revoked.
          */
         post: operations["approveGrantRequest"];
         delete?: never;
@@ -846,7 +1140,7 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/keys": {
+    "/statement-approvals": {
         parameters: {
             query?: never;
             header?: never;
@@ -854,117 +1148,87 @@ export interface paths {
             cookie?: never;
         };
         /**
-         * List API keys
-         * @description Returns a list of API keys.
-         *
-         *     - Default for every caller, including admins: only the caller's own
-         *       keys.
-         *     - Admins can override the default with `all_users=true` (every user's
-         *       keys) or `user_id=<uuid>` (one specific user's keys). If both are
-         *       given, `user_id` wins.
-         *     - Non-admins can never see another user's keys: `all_users` and
-         *       `user_id` are ignored for them.
+         * List statement approvals
+         * @description Write statements the PostgreSQL proxy holds under the
+         *     `write_requires_approval` control, newest first. Admins see all
+         *     (filterable). Non-admins see only their own statements.
          */
-        get: operations["listAPIKeys"];
+        get: operations["listStatementApprovals"];
         put?: never;
-        /**
-         * Create API key
-         * @description Creates a new API key for the authenticated user.
-         *
-         *     **Important**: The full API key is only returned once in this response.
-         *     Store it securely as it cannot be retrieved later.
-         *
-         *     Requires Web Session or Basic Auth (API keys cannot create other API keys).
-         */
-        post: operations["createAPIKey"];
+        post?: never;
         delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/keys/{id}": {
+    "/statement-approvals/{uid}": {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description API key ID */
-                id: string;
+                uid: string;
             };
             cookie?: never;
         };
-        /**
-         * Get API key by ID
-         * @description Retrieves a specific API key.
-         *
-         *     Non-admins can only see their own keys.
-         */
-        get: operations["getAPIKey"];
+        /** Get statement approval */
+        get: operations["getStatementApproval"];
         put?: never;
         post?: never;
-        /**
-         * Revoke API key
-         * @description Revokes an API key.
-         *
-         *     - Non-admins can only revoke their own keys
-         *     - Requires Web Session or Basic Auth (API keys cannot revoke API keys)
-         */
-        delete: operations["revokeAPIKey"];
+        delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/connections": {
+    "/statement-approvals/{uid}/approve": {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                uid: string;
+            };
             cookie?: never;
         };
+        get?: never;
+        put?: never;
         /**
-         * List connections
-         * @description Returns a list of proxy connections.
-         *
-         *     Connectors can only see their own connections.
+         * Approve a held statement (admin)
+         * @description Transitions pending → approved: the proxy runs the statement within
+         *     a second. The approver must be another user than the statement's.
+         *     Returns 409 once the statement is no longer pending or past its
+         *     expiry.
          */
-        get: operations["listConnections"];
-        put?: never;
-        post?: never;
+        post: operations["approveStatementApproval"];
         delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/connections/{uid}": {
+    "/statement-approvals/{uid}/deny": {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Connection UID */
-                uid: components["parameters"]["ConnectionUID"];
+                uid: string;
             };
             cookie?: never;
         };
+        get?: never;
+        put?: never;
         /**
-         * Get connection details
-         * @description Retrieves a specific connection.
-         *
-         *     Connectors can only fetch their own connections. Connections
-         *     belonging to another user are reported as `404 Not Found` (not
-         *     `403 Forbidden`) so their existence isn't leaked.
+         * Deny a held statement (admin)
+         * @description The proxy refuses the statement, showing the reason to its user.
          */
-        get: operations["getConnection"];
-        put?: never;
-        post?: never;
+        post: operations["denyStatementApproval"];
         delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/queries": {
+    "/keys": {
         parameters: {
             query?: never;
             header?: never;
@@ -972,39 +1236,81 @@ export interface paths {
             cookie?: never;
         };
         /**
-         * List queries
-         * @description Returns a list of executed queries.
+         * List API keys
+         * @description Returns a list of API keys.
          *
-         *     Requires admin or viewer role.
+         *     - Default for every caller, including admins: only the caller's own
+         *       keys.
+         *     - Admins can override the default with `all_users=true` (every user's
+         *       keys) or `user_id=<uuid>` (one specific user's keys). If both are
+         *       given, `user_id` wins.
+         *     - Non-admins can never see another user's keys: `all_users` and
+         *       `user_id` are ignored for them.
          */
-        get: operations["listQueries"];
+        get: operations["listAPIKeys"];
         put?: never;
-        post?: never;
+        /**
+         * Create API key
+         * @description Creates a new API key for the authenticated user.
+         *
+         *     **Important**: The full API key is only returned once in this response.
+         *     Store it securely as it cannot be retrieved later.
+         *
+         *     Requires Web Session or Basic Auth (API keys cannot create other API keys).
+         */
+        post: operations["createAPIKey"];
         delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/queries/{uid}": {
+    "/keys/{id}": {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Query UID */
-                uid: components["parameters"]["QueryUID"];
+                /** @description API key ID */
+                id: string;
             };
             cookie?: never;
         };
         /**
-         * Get query details
-         * @description Retrieves a specific query without its result rows.
+         * Get API key by ID
+         * @description Retrieves a specific API key.
          *
-         *     Use `GET /queries/{uid}/rows` to retrieve the result rows.
+         *     Non-admins can only see their own keys.
+         */
+        get: operations["getAPIKey"];
+        put?: never;
+        post?: never;
+        /**
+         * Revoke API key
+         * @description Revokes an API key.
          *
-         *     Requires admin or viewer role.
+         *     - Non-admins can only revoke their own keys
+         *     - Requires Web Session or Basic Auth (API keys cannot revoke API keys)
          */
-        get: operations["getQuery"];
+        delete: operations["revokeAPIKey"];
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/connections": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List connections
+         * @description Returns a list of proxy connections.
+         *
+         *     Connectors can only see their own connections.
+         */
+        get: operations["listConnections"];
         put?: never;
         post?: never;
         delete?: never;
@@ -1013,29 +1319,61 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/queries/{uid}/rows": {
+    "/connections/{uid}": {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Query UID */
-                uid: components["parameters"]["QueryUID"];
+                /** @description Connection UID */
+                uid: components["parameters"]["ConnectionUID"];
             };
             cookie?: never;
         };
         /**
-         * List query result rows (paginated)
-         * @description Retrieves paginated result rows for a specific query.
+         * Get connection details
+         * @description Retrieves a specific connection.
          *
-         *     Uses cursor-based pagination with two limits per request:
-         *     - Maximum 1000 rows per response
-         *     - Maximum 1MB of row data per response
+         *     Connectors can only fetch their own connections. Connections
+         *     belonging to another user are reported as `404 Not Found` (not
+         *     `403 Forbidden`) so their existence isn't leaked.
+         */
+        get: operations["getConnection"];
+        put?: never;
+        post?: never;
+        /**
+         * Terminate a live connection
+         * @description Forcefully ends a live proxy session. Its client and upstream
+         *     connections are closed within a fraction of a second; PostgreSQL
+         *     clients receive a `FATAL` `57P01` (admin_shutdown) error first. The
+         *     connection then records `terminated` as its `disconnect_reason`, and a
+         *     `connection.terminated` audit event is emitted.
          *
-         *     The response stops at whichever limit is reached first.
+         *     Sessions are signaled in-process: a connection served by another DBBat
+         *     instance sharing the same storage is reported as `409 Conflict`, as is
+         *     one already closed.
+         *
+         *     Requires the admin role.
+         */
+        delete: operations["terminateConnection"];
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/queries": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List queries
+         * @description Returns a list of executed queries.
          *
          *     Requires admin or viewer role.
          */
-        get: operations["getQueryRows"];
+        get: operations["listQueries"];
         put?: never;
         post?: never;
         delete?: never;
@@ -1044,7 +1382,7 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/audit": {
+    "/queries/stream": {
         parameters: {
             query?: never;
             header?: never;
@@ -1052,12 +1390,12 @@ export interface paths {
             cookie?: never;
         };
         /**
-         * List audit events
-         * @description Returns a list of audit log events.
+         * Stream queries
+         * @description Pushes the queries logged from now on as server-sent events.
          *
-         *     Requires admin or viewer role.
+         *     Requires the `queries:read` permission (admin, viewer or auditor role).
          */
-        get: operations["listAudit"];
+        get: operations["streamQueries"];
         put?: never;
         post?: never;
         delete?: never;
@@ -1066,7 +1404,7 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/servers/{uid}/connection": {
+    "/queries/stats": {
         parameters: {
             query?: never;
             header?: never;
@@ -1074,13 +1412,21 @@ export interface paths {
             cookie?: never;
         };
         /**
-         * Get connection URL template for a database
-         * @description Returns a connection URL with `{DBBAT_KEY}` placeholder for the specified database.
-         *     - Admin callers: always 200.
-         *     - Non-admin callers: 200 if they have at least one active grant; 404 otherwise (to avoid leaking database existence).
-         *     - If the protocol proxy is disabled (resolved port = 0): returns 409.
+         * Query statistics
+         * @description Aggregates the queries matching the filters of the list per statement fingerprint and
+         *     database, like `pg_stat_statements` does upstream. The fingerprint, computed when the
+         *     query is logged, is shared by the statements that only differ by their literal values,
+         *     bind parameters, comments, whitespace or the case of their unquoted identifiers; lists
+         *     of values (`IN (1, 2, 3)`) count as one. Queries logged before fingerprinting are
+         *     left out.
+         *
+         *     `query` is the statement in its canonical form, literals replaced with `?`, so it is
+         *     returned without the `sql:raw` permission. `calls` counts the statements folded into
+         *     a repeated query, while durations and rows only cover the first of them.
+         *
+         *     Requires the `queries:read` permission (admin, viewer or auditor role).
          */
-        get: operations["getDatabaseConnection"];
+        get: operations["getQueryStats"];
         put?: never;
         post?: never;
         delete?: never;
@@ -1089,56 +1435,59 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/servers/{uid}/test": {
+    "/queries/{uid}": {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
+            };
             cookie?: never;
         };
-        get?: never;
-        put?: never;
         /**
-         * Test that a server is actually reachable and usable (admin only)
-         * @description Validates a server row by dialing it for real, so provisioning is not write-and-hope.
+         * Get query details
+         * @description Retrieves a specific query without its result rows.
          *
-         *     - `protocol: ssh` rows: dials the bastion (through its own `via_uid` chain when set),
-         *       completes the SSH handshake with the stored key or password, and — on the first
-         *       success — pins the presented host key into `ssh_known_host_key`, so TOFU pinning
-         *       happens under admin supervision rather than implicitly on a user's first query.
-         *     - Database rows: dials the target (through the bastion when `via_uid` is set) and
-         *       performs a real protocol-level login with the stored credentials — PostgreSQL,
-         *       MySQL / MariaDB, MongoDB and Oracle are all probed. A protocol dbbat has no probe
-         *       for would be reported reachability-only, with code `auth_not_verified`.
-         *       An Oracle row with neither `oracle_service_name` nor `database_name` cannot be
-         *       probed at all and reports `stage: config`, `code: missing_config`.
+         *     Use `GET /queries/{uid}/rows` to retrieve the result rows.
          *
-         *     A failed check is still HTTP 200 with `ok: false` — the staged result *is* the answer.
-         *     `stage` and `code` tell the admin which field is wrong. No credential or key material is
-         *     ever returned.
+         *     Requires admin or viewer role.
          */
-        post: operations["testServerConnection"];
+        get: operations["getQuery"];
+        put?: never;
+        post?: never;
         delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/ssh-servers": {
+    "/queries/{uid}/plan": {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
+            };
             cookie?: never;
         };
         /**
-         * List SSH bastion servers (admin only)
-         * @description Returns SSH bastion rows (protocol `ssh`). These are excluded from the
-         *     regular database listing and every grantable/connectable target
-         *     context; they appear only here, for management and the "via SSH server"
-         *     selector. Secrets (private key, passphrase) are never returned.
+         * Get query plan
+         * @description Retrieves the plan captured for a slow query: on PostgreSQL databases with a
+         *     `slow_query_threshold_ms`, the statements running longer are explained in the
+         *     background with `EXPLAIN (FORMAT JSON)` (planned, not run, in a read-only
+         *     transaction) using the database's stored credentials.
+         *
+         *     A statement is explained once per 5 minutes per database: when the query was not
+         *     explained itself, the latest plan of a statement of the same fingerprint on its
+         *     database is returned, its `query_uid` then being the other query's. A capture
+         *     that failed has an `error` and no `plan`.
+         *
+         *     Plans show literal values: requires the `queries:read` and `sql:raw` permissions
+         *     (admin or viewer role), and is refused with `redact=true`.
          */
-        get: operations["listSSHServers"];
+        get: operations["getQueryPlan"];
         put?: never;
         post?: never;
         delete?: never;
@@ -1147,18 +1496,30 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/parameters": {
+    "/queries/{uid}/result-url": {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
+            };
             cookie?: never;
         };
         /**
-         * List all active parameters
-         * @description Returns all active global parameters. Admin-only. Optional `group_key` query filter.
+         * Get spilled result download URL
+         * @description Returns a signed URL downloading the result a query spilled past the query storage
+         *     limits (`spilled_rows` is set), as gzip-compressed JSON lines: one object per row,
+         *     keyed by column. Results are spilled when `DBB_RESULT_SPILL_TARGET` is set, by the
+         *     PostgreSQL and MySQL proxies.
+         *
+         *     With the `s3` target, the URL is presigned for the bucket; with the `file` target,
+         *     it points to `GET /query-results/{uid}`. Either is valid for
+         *     `DBB_RESULT_SPILL_URL_EXPIRY` and needs no other authentication.
+         *
+         *     Requires the `rows:read` permission (admin or viewer role).
          */
-        get: operations["listParameters"];
+        get: operations["getQueryResultURL"];
         put?: never;
         post?: never;
         delete?: never;
@@ -1167,37 +1528,54 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/parameters/{group}/{key}": {
+    "/query-results/{uid}": {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
+            };
             cookie?: never;
         };
-        /** Get a single parameter */
-        get: operations["getParameter"];
-        /** Create or update a parameter */
-        put: operations["setParameter"];
+        /**
+         * Download spilled result
+         * @description Downloads a result spilled to the `file` target, as gzip-compressed JSON lines.
+         *     Unauthenticated: use the URL returned by `GET /queries/{uid}/result-url`, whose
+         *     signature authorizes the download until it expires.
+         */
+        get: operations["downloadQueryResult"];
+        put?: never;
         post?: never;
-        /** Delete a parameter */
-        delete: operations["deleteParameter"];
+        delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/instance": {
+    "/queries/{uid}/rows": {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
+            };
             cookie?: never;
         };
         /**
-         * Get instance information
-         * @description Returns listen addresses, public endpoint settings, and resolved connection info. Any authenticated user can call this endpoint; non-admins receive only `listen` and `resolved`.
+         * List query result rows (paginated)
+         * @description Retrieves paginated result rows for a specific query.
+         *
+         *     Uses cursor-based pagination with two limits per request:
+         *     - Maximum 1000 rows per response
+         *     - Maximum 1MB of row data per response
+         *
+         *     The response stops at whichever limit is reached first.
+         *
+         *     Requires admin or viewer role.
          */
-        get: operations["getInstance"];
+        get: operations["getQueryRows"];
         put?: never;
         post?: never;
         delete?: never;
@@ -1206,377 +1584,637 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/instance/public": {
+    "/query-alerts": {
         parameters: {
             query?: never;
             header?: never;
             path?: never;
             cookie?: never;
         };
-        get?: never;
+        /** List query alerts (admin) */
+        get: operations["listQueryAlerts"];
+        put?: never;
         /**
-         * Update public endpoint settings
-         * @description Atomically upserts all public.* parameters. Admin-only.
+         * Create query alert (admin)
+         * @description Saves a query log filter. Every instance evaluates the enabled alerts
+         *     every `DBB_QUERY_ALERTS_INTERVAL` (default 30s) against the queries logged
+         *     since their last evaluation, about 10 seconds behind, and records the matches:
+         *     list them with `GET /queries?alert_id=`. With a `webhook_url`, each match is
+         *     also POSTed there as JSON: `{"alert": {"uid", "name"}, "query": Query,
+         *     "matched_at"}`, with the query unredacted.
+         *
+         *     An alert only matches the queries logged after its creation.
          */
-        put: operations["updateInstancePublic"];
-        post?: never;
+        post: operations["createQueryAlert"];
         delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-}
-export type webhooks = Record<string, never>;
-export interface components {
-    schemas: {
-        /** @description API and build version information */
-        VersionInfo: {
-            /**
-             * @description API version (e.g., "v1")
-             * @example v1
-             */
-            api_version: string;
-            /**
-             * @description Build version
-             * @example 1.2.3
-             */
-            build_version: string;
-            /**
-             * @description Git commit hash
-             * @example abc1234
-             */
-            build_commit: string;
-            /**
-             * Format: date-time
-             * @description Build timestamp
-             * @example 2026-01-09T12:00:00Z
-             */
-            build_time: string;
-            /**
-             * @description Run mode of the server. Empty string for production, "test" for test mode, "demo" for demo mode.
-             * @example
-             */
-            run_mode: string;
-        };
-        LoginRequest: {
-            /** @description Username */
-            username: string;
-            /**
-             * Format: password
-             * @description Password
-             */
-            password: string;
-        };
-        LoginResponse: {
-            /** @description Web session token (use as Bearer token) */
-            token: string;
-            /**
-             * Format: date-time
-             * @description Token expiration time
-             */
-            expires_at: string;
-            user: components["schemas"]["AuthUser"];
-        };
-        /** @description User info returned in auth responses */
-        AuthUser: {
-            /**
-             * Format: uuid
-             * @description User UID
-             */
-            uid: string;
-            /** @description Username */
-            username: string;
-            /** @description User roles */
-            roles: ("admin" | "viewer" | "connector")[];
-            /** @description Whether user must change their password */
-            password_change_required: boolean;
-        };
-        MeResponse: {
-            /**
-             * Format: uuid
-             * @description User UID
-             */
-            uid: string;
-            /** @description Username */
-            username: string;
-            /** @description User roles */
-            roles: ("admin" | "viewer" | "connector")[];
-            /** @description Whether user must change their password */
-            password_change_required: boolean;
-            session: components["schemas"]["SessionInfo"];
-        };
-        /** @description Current session information */
-        SessionInfo: {
-            /**
-             * Format: date-time
-             * @description Session expiration time
-             */
-            expires_at?: string;
-            /**
-             * Format: date-time
-             * @description Session creation time
-             */
-            created_at?: string;
-        };
-        /** @description Request for authenticated password change (re-authentication required) */
-        ChangePasswordRequest: {
-            /** @description Username for re-authentication */
-            username: string;
-            /**
-             * Format: password
-             * @description Current password for re-authentication
-             */
-            current_password: string;
-            /**
-             * Format: password
-             * @description New password (minimum 8 characters)
-             */
-            new_password: string;
+    "/query-alerts/{uid}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                uid: string;
+            };
+            cookie?: never;
         };
-        /** @description Request for pre-login password change (no token required) */
-        PreLoginPasswordChangeRequest: {
-            /** @description Username */
-            username: string;
-            /**
-             * Format: password
-             * @description Current/temporary password
-             */
-            current_password: string;
-            /**
-             * Format: password
-             * @description New password (minimum 8 characters)
-             */
-            new_password: string;
+        /** Get query alert by UID (admin) */
+        get: operations["getQueryAlert"];
+        put?: never;
+        post?: never;
+        /**
+         * Delete query alert (admin)
+         * @description Hard-deletes the alert and the matches it recorded; the queries stay.
+         */
+        delete: operations["deleteQueryAlert"];
+        options?: never;
+        head?: never;
+        /**
+         * Update query alert (admin)
+         * @description Replaces the whole alert. Its evaluation resumes where it stopped; an alert
+         *     being enabled again resumes from now, ignoring the queries logged while it was
+         *     disabled.
+         */
+        patch: operations["updateQueryAlert"];
+        trace?: never;
+    };
+    "/audit": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        /** @description Request for admin password reset (web session required) */
-        ResetPasswordRequest: {
-            /**
-             * Format: password
-             * @description New password for the user (minimum 8 characters)
-             */
-            new_password: string;
+        /**
+         * List audit events
+         * @description Returns a list of audit log events.
+         *
+         *     Requires admin or viewer role.
+         */
+        get: operations["listAudit"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/servers/{uid}/connection": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        User: {
-            /**
-             * Format: uuid
-             * @description Unique identifier
-             */
-            uid: string;
-            /** @description Username */
-            username: string;
-            /** @description User roles */
-            roles: ("admin" | "viewer" | "connector")[];
-            /** @description Whether user bypasses rate limiting */
-            rate_limit_exempt: boolean;
-            /**
-             * Format: date-time
-             * @description Creation timestamp
-             */
-            created_at: string;
-            /**
-             * Format: date-time
-             * @description Last update timestamp
-             */
-            updated_at: string;
+        /**
+         * Get connection URL template for a database
+         * @description Returns a connection URL with `{DBBAT_KEY}` placeholder for the specified database.
+         *     - Admin callers: always 200.
+         *     - Non-admin callers: 200 if they have at least one active grant; 404 otherwise (to avoid leaking database existence).
+         *     - If the protocol proxy is disabled (resolved port = 0): returns 409.
+         */
+        get: operations["getDatabaseConnection"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/servers/{uid}/check-privileges": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        CreateUserRequest: {
-            /** @description Username */
-            username: string;
-            /** @description Password */
-            password: string;
-            /** @description User roles */
-            roles?: ("admin" | "viewer" | "connector")[];
+        get?: never;
+        put?: never;
+        /**
+         * Check what the stored credentials can do upstream (admin only)
+         * @description Logs in to the database target with its stored service credentials and
+         *     reports the account's upstream privileges, warning about every privilege beyond
+         *     what the proxy needs.
+         */
+        post: operations["checkServerPrivileges"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/servers/test": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
+        get?: never;
+        put?: never;
         /**
-         * @description Organizational grouping of users, used to scope grant definitions.
-         *     Distinct from `roles`, which are functional (admin/viewer/connector).
+         * Test a database configuration before saving it (admin only)
+         * @description Runs the same check as `POST /servers/{uid}/test` on a configuration that is not saved:
+         *     dials the target (through the `via_uid` bastion when set) and performs a real
+         *     protocol-level login with the given credentials. Nothing is stored, and the password is
+         *     never returned or logged.
+         *
+         *     The fields are validated as on create, so a configuration this accepts can be saved as is.
+         *     SSH bastions cannot be tested this way (their check pins the host key on the saved row):
+         *     create them, then use `POST /servers/{uid}/test`.
+         *
+         *     A failed check is still HTTP 200 with `ok: false`; `stage` and `code` tell which field is
+         *     wrong.
          */
-        UserGroup: {
-            /** Format: uuid */
-            uid: string;
-            name: string;
-            description: string;
-            /** @description UIDs of the users belonging to this group. */
-            member_uids: string[];
-            /** Format: uuid */
-            readonly created_by?: string | null;
-            /** Format: date-time */
-            readonly created_at: string;
+        post: operations["testDatabaseConnection"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/servers/{uid}/test": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        CreateUserGroupRequest: {
-            name: string;
-            description?: string;
-            /**
-             * @description Replaces the group's membership. On update, omit to leave
-             *     membership untouched; an explicit empty array empties the group.
-             */
-            member_uids?: string[];
+        get?: never;
+        put?: never;
+        /**
+         * Test that a server is actually reachable and usable (admin only)
+         * @description Validates a server row by dialing it for real, so provisioning is not write-and-hope.
+         *
+         *     - `protocol: ssh` rows: dials the bastion (through its own `via_uid` chain when set),
+         *       completes the SSH handshake with the stored key or password, and — on the first
+         *       success — pins the presented host key into `ssh_known_host_key`, so TOFU pinning
+         *       happens under admin supervision rather than implicitly on a user's first query.
+         *     - Database rows: dials the target (through the bastion when `via_uid` is set) and
+         *       performs a real protocol-level login with the stored credentials — PostgreSQL,
+         *       MySQL / MariaDB, MongoDB and Oracle are all probed. A protocol dbbat has no probe
+         *       for would be reported reachability-only, with code `auth_not_verified`.
+         *       An Oracle row with neither `oracle_service_name` nor `database_name` cannot be
+         *       probed at all and reports `stage: config`, `code: missing_config`.
+         *
+         *     A failed check is still HTTP 200 with `ok: false` — the staged result *is* the answer.
+         *     `stage` and `code` tell the admin which field is wrong. No credential or key material is
+         *     ever returned.
+         */
+        post: operations["testServerConnection"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/servers/{uid}/health": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        UpdateUserRequest: {
-            /** @description New password */
-            password?: string;
-            /** @description New roles (admin only) */
-            roles?: ("admin" | "viewer" | "connector")[];
+        /**
+         * Get the latest connectivity check of a database (admin only)
+         * @description Returns the outcome of the latest check of the database, run by the background
+         *     health checks or by `POST /servers/{uid}/test`, so admins see a broken
+         *     configuration before a user hits it. `404` until the database is first checked.
+         */
+        get: operations["getServerHealth"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/ssh-servers": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List SSH bastion servers (admin only)
+         * @description Returns SSH bastion rows (protocol `ssh`). These are excluded from the
+         *     regular database listing and every grantable/connectable target
+         *     context; they appear only here, for management and the "via SSH server"
+         *     selector. Secrets (private key, passphrase) are never returned.
+         */
+        get: operations["listSSHServers"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/parameters": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List all active parameters
+         * @description Returns all active global parameters. Admin-only. Optional `group_key` query filter.
+         */
+        get: operations["listParameters"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/parameters/{group}/{key}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /** Get a single parameter */
+        get: operations["getParameter"];
+        /** Create or update a parameter */
+        put: operations["setParameter"];
+        post?: never;
+        /** Delete a parameter */
+        delete: operations["deleteParameter"];
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/instance": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Get instance information
+         * @description Returns listen addresses, public endpoint settings, and resolved connection info. Any authenticated user can call this endpoint; non-admins receive only `listen` and `resolved`.
+         */
+        get: operations["getInstance"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/instance/public": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        /**
+         * Update public endpoint settings
+         * @description Atomically upserts all public.* parameters. Admin-only.
+         */
+        put: operations["updateInstancePublic"];
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+}
+export type webhooks = Record<string, never>;
+export interface components {
+    schemas: {
+        /**
+         * @description Number of results matching the filters, across all pages
+         * @example 200000
+         */
+        ListTotal: number;
+        /** @description API and build version information */
+        VersionInfo: {
             /**
-             * @description Replaces the user's group memberships wholesale (admin only).
-             *     Omit to leave membership untouched.
+             * @description API version (e.g., "v1")
+             * @example v1
              */
-            group_uids?: string[];
-        };
-        /** @description Full database details (admin only) */
-        Database: {
+            api_version: string;
             /**
-             * Format: uuid
-             * @description Unique identifier
+             * @description Build version
+             * @example 1.2.3
              */
-            uid: string;
-            /** @description Database configuration name */
-            name: string;
-            /** @description Description */
-            description?: string;
-            /** @description Target database host */
-            host?: string;
-            /** @description Target database port */
-            port?: number;
-            /** @description Target database name */
-            database_name?: string;
-            /** @description Target database username */
-            username?: string;
-            /** @description SSL mode (disable, prefer, require, etc.) */
-            ssl_mode?: string;
+            build_version: string;
             /**
-             * @description Server protocol (ssh = an SSH bastion, not a database target)
-             * @enum {string}
+             * @description Git commit hash
+             * @example abc1234
              */
-            protocol?: "postgresql" | "oracle" | "mysql" | "mariadb" | "mongodb" | "ssh";
-            /** @description Oracle SERVICE_NAME (Oracle only) */
-            oracle_service_name?: string;
-            /** @description Upstream MongoDB SCRAM authSource (MongoDB only; defaults to "admin") */
-            mongo_auth_source?: string;
+            build_commit: string;
             /**
-             * @description Whether this database appears in the grant-request dropdown for non-admin users
-             * @default true
+             * Format: date-time
+             * @description Build timestamp
+             * @example 2026-01-09T12:00:00Z
              */
-            listable: boolean;
+            build_time: string;
             /**
-             * Format: uuid
-             * @description User who created this configuration
+             * @description Run mode of the server. Empty string for production, "test" for test mode, "demo" for demo mode.
+             * @example
              */
-            created_by?: string | null;
+            run_mode: string;
+        };
+        LoginRequest: {
+            /** @description Username */
+            username: string;
             /**
-             * Format: uuid
-             * @description SSH server (bastion) UID to tunnel through; null for a direct dial
+             * Format: password
+             * @description Password
              */
-            via_uid?: string | null;
-            /** @description TOFU-pinned SSH bastion host key (read-only; ssh servers only) */
-            ssh_known_host_key?: string;
-            /** @description Present only when the create/update request set `test_connection: true` */
-            connection_test?: components["schemas"]["ConnectionTestResult"];
+            password: string;
         };
-        /**
-         * @description Staged outcome of a connectivity check. Carries no secret material: only the stage
-         *     reached, a machine-readable code, a human-readable message, and the bastion's public
-         *     host key.
-         */
-        ConnectionTestResult: {
-            /** @description Whether the check succeeded end to end */
-            ok: boolean;
+        LoginResponse: {
+            /** @description Web session token (use as Bearer token) */
+            token: string;
             /**
-             * @description How far the check got. On failure this is the stage that failed, which is what tells
-             *     the admin which field they got wrong:
-             *     - `config`: the row is unusable before any packet is sent (no credentials, unparseable key, via_uid cycle)
-             *     - `bastion_dial`: the SSH bastion could not be reached (DNS, routing, firewall)
-             *     - `bastion_auth`: the SSH handshake failed (host-key mismatch or rejected credentials)
-             *     - `target_dial`: the database target could not be reached (through the tunnel when via_uid is set)
-             *     - `target_auth`: the database handshake or login failed
-             * @enum {string}
+             * Format: date-time
+             * @description Token expiration time
              */
-            stage: "config" | "bastion_dial" | "bastion_auth" | "target_dial" | "target_auth";
+            expires_at: string;
+            user: components["schemas"]["AuthUser"];
+        };
+        /** @description User info returned in auth responses */
+        AuthUser: {
             /**
-             * @description Machine-readable classification within the stage; `ok` on success
-             * @enum {string}
+             * Format: uuid
+             * @description User UID
              */
-            code: "ok" | "dns_failure" | "timeout" | "unreachable" | "host_key_mismatch" | "auth_rejected" | "bad_private_key" | "no_auth_method" | "missing_config" | "via_cycle" | "via_not_ssh" | "handshake_failed" | "db_auth_failed" | "db_handshake_failed" | "auth_not_verified" | "internal_error";
-            /** @description Human-readable explanation, safe to display to an admin */
-            message: string;
-            /** @description True when this check performed the TOFU pin (first successful connect to a bastion) */
-            host_key_pinned?: boolean;
-            /** @description The bastion's public host key after the check (ssh servers only) */
-            ssh_known_host_key?: string;
+            uid: string;
+            /** @description Username */
+            username: string;
+            /** @description User roles */
+            roles: ("admin" | "viewer" | "connector")[];
+            /** @description Whether user must change their password */
+            password_change_required: boolean;
+        };
+        MeResponse: {
             /**
-             * Format: int64
-             * @description How long the check took, in milliseconds
+             * Format: uuid
+             * @description User UID
              */
-            duration_ms: number;
+            uid: string;
+            /** @description Username */
+            username: string;
+            /** @description User roles */
+            roles: ("admin" | "viewer" | "connector")[];
+            /** @description Whether user must change their password */
+            password_change_required: boolean;
+            session: components["schemas"]["SessionInfo"];
         };
-        /** @description Limited database info for non-admin users */
-        DatabaseLimited: {
+        /** @description Current session information */
+        SessionInfo: {
+            /**
+             * Format: date-time
+             * @description Session expiration time
+             */
+            expires_at?: string;
+            /**
+             * Format: date-time
+             * @description Session creation time
+             */
+            created_at?: string;
+        };
+        /** @description Request for authenticated password change (re-authentication required) */
+        ChangePasswordRequest: {
+            /** @description Username for re-authentication */
+            username: string;
+            /**
+             * Format: password
+             * @description Current password for re-authentication
+             */
+            current_password: string;
+            /**
+             * Format: password
+             * @description New password (minimum 8 characters)
+             */
+            new_password: string;
+        };
+        /** @description Request for pre-login password change (no token required) */
+        PreLoginPasswordChangeRequest: {
+            /** @description Username */
+            username: string;
+            /**
+             * Format: password
+             * @description Current/temporary password
+             */
+            current_password: string;
+            /**
+             * Format: password
+             * @description New password (minimum 8 characters)
+             */
+            new_password: string;
+        };
+        /** @description Request for admin password reset (web session required) */
+        ResetPasswordRequest: {
+            /**
+             * Format: password
+             * @description New password for the user (minimum 8 characters)
+             */
+            new_password: string;
+        };
+        User: {
             /**
              * Format: uuid
              * @description Unique identifier
              */
             uid: string;
-            /** @description Database configuration name */
-            name: string;
-            /** @description Description */
-            description?: string;
-        };
-        CreateDatabaseRequest: {
-            /** @description Unique name for this database configuration */
-            name: string;
-            /** @description Description */
-            description?: string;
-            /** @description Target database host */
-            host: string;
+            /** @description Username */
+            username: string;
+            /** @description User roles */
+            roles: ("admin" | "viewer" | "connector")[];
+            /** @description Whether user bypasses rate limiting */
+            rate_limit_exempt: boolean;
+            /** @description Override of the API rate limit, in requests per minute; absent when not overridden */
+            rate_limit_per_minute?: number;
             /**
-             * @description Target database port
-             * @default 5432
+             * Format: date-time
+             * @description Creation timestamp
              */
-            port: number;
-            /** @description Target database name */
-            database_name?: string;
-            /** @description Target database username */
-            username: string;
-            /** @description Target database password (encrypted at rest) */
-            password?: string;
+            created_at: string;
             /**
-             * @description SSL mode
-             * @default prefer
+             * Format: date-time
+             * @description Last update timestamp
              */
-            ssl_mode: string;
+            updated_at: string;
+        };
+        RateLimits: {
+            /** @description Whether API rate limiting is enabled */
+            enabled: boolean;
+            /** @description Default limit of authenticated requests */
+            requests_per_minute: number;
+            /** @description Limit of unauthenticated requests, per IP */
+            requests_per_minute_anon: number;
+            /** @description Requests allowed above the limit in short bursts */
+            burst: number;
+            /** @description Overrides of the limit, in requests per minute, by role */
+            roles: {
+                [key: string]: number;
+            };
+        };
+        UserRateLimit: {
+            /** @description Whether API rate limiting is enabled */
+            enabled: boolean;
+            /** @description Whether the user bypasses rate limiting */
+            exempt: boolean;
+            /** @description The user's own override; null when not overridden */
+            override: number | null;
+            /** @description Limit applied to the user */
+            requests_per_minute: number;
             /**
-             * @description Server protocol (ssh = an SSH bastion, not a database target)
-             * @default postgresql
+             * @description Where the limit applied comes from
              * @enum {string}
              */
-            protocol: "postgresql" | "oracle" | "mysql" | "mariadb" | "mongodb" | "ssh";
-            /** @description Oracle SERVICE_NAME (required for Oracle) */
-            oracle_service_name?: string;
-            /** @description Upstream MongoDB SCRAM authSource (MongoDB only; defaults to "admin") */
-            mongo_auth_source?: string;
+            source: "user" | "role" | "default";
+            /** @description Requests allowed above the limit in short bursts */
+            burst: number;
+            /** @description Requests made in the current window */
+            used: number;
+            /** @description Requests left in the current window */
+            remaining: number;
             /**
-             * @description Whether this database appears in the grant-request dropdown for non-admin users
-             * @default true
+             * Format: date-time
+             * @description When the current window ends
              */
-            listable: boolean;
+            reset_at: string;
+        };
+        SetRateLimitRequest: {
+            /** @description Limit, in requests per minute */
+            requests_per_minute: number;
+        };
+        CreateUserRequest: {
+            /** @description Username */
+            username: string;
+            /** @description Password */
+            password: string;
+            /** @description User roles */
+            roles?: ("admin" | "viewer" | "connector")[];
+        };
+        /**
+         * @description Organizational grouping of users, used to scope grant definitions.
+         *     Distinct from `roles`, which are functional (admin/viewer/connector).
+         */
+        UserGroup: {
+            /** Format: uuid */
+            uid: string;
+            name: string;
+            description: string;
+            /** @description UIDs of the users belonging to this group. */
+            member_uids: string[];
+            /** Format: uuid */
+            readonly created_by?: string | null;
+            /** Format: date-time */
+            readonly created_at: string;
+        };
+        /**
+         * @description The criteria a query must meet to match, all of them; named after the
+         *     query list's parameters. At least one is required.
+         */
+        QueryAlertFilter: {
+            /** Format: uuid */
+            user_id?: string;
+            /** Format: uuid */
+            database_id?: string;
+            /** @enum {string} */
+            access_level?: "read_only" | "read_write";
+            /** @description Case-insensitive substring of the SQL text */
+            sql?: string;
+            /** @description PostgreSQL regular expression matched case-insensitively against the SQL text */
+            sql_regex?: string;
+            has_error?: boolean;
+            min_duration_ms?: number;
+            max_duration_ms?: number;
+            /** Format: int64 */
+            min_rows_affected?: number;
+            /** Format: int64 */
+            max_rows_affected?: number;
+        };
+        QueryAlert: {
+            /** Format: uuid */
+            uid?: string;
+            name?: string;
+            description?: string;
+            filter?: components["schemas"]["QueryAlertFilter"];
+            /** Format: uri */
+            webhook_url?: string | null;
+            enabled?: boolean;
             /**
-             * Format: uuid
-             * @description SSH server (bastion) UID to tunnel through; null for a direct dial
+             * Format: int64
+             * @description Queries matched since the alert was created
              */
-            via_uid?: string | null;
-            /** @description SSH private key (PEM) for an ssh server; write-only, never returned */
-            ssh_private_key?: string;
-            /** @description Passphrase for the SSH private key; write-only, never returned */
-            ssh_passphrase?: string;
+            match_count?: number;
+            /** Format: date-time */
+            last_evaluated_at?: string | null;
+            /** Format: date-time */
+            last_matched_at?: string | null;
+            /** Format: uuid */
+            created_by?: string;
+            /** Format: date-time */
+            created_at?: string;
+            /** Format: date-time */
+            updated_at?: string;
+        };
+        CreateQueryAlertRequest: {
+            name: string;
+            description?: string;
+            filter: components["schemas"]["QueryAlertFilter"];
             /**
-             * @description Optional; defaults to false when omitted. When true, the API dials the newly created
-             *     row once and returns the staged outcome as `connection_test` in the response.
-             *     Never fatal: the row is created either way.
+             * Format: uri
+             * @description http or https URL each match is POSTed to; omit to only record the matches
              */
-            test_connection?: boolean;
+            webhook_url?: string | null;
+            /** @default true */
+            enabled?: boolean;
         };
-        UpdateDatabaseRequest: {
+        CreateUserGroupRequest: {
+            name: string;
+            description?: string;
+            /**
+             * @description Replaces the group's membership. On update, omit to leave
+             *     membership untouched; an explicit empty array empties the group.
+             */
+            member_uids?: string[];
+        };
+        UpdateUserRequest: {
+            /** @description New password */
+            password?: string;
+            /** @description New roles (admin only) */
+            roles?: ("admin" | "viewer" | "connector")[];
+            /**
+             * @description Replaces the user's group memberships wholesale (admin only).
+             *     Omit to leave membership untouched.
+             */
+            group_uids?: string[];
+        };
+        /** @description Full database details (admin only) */
+        Database: {
+            /**
+             * Format: uuid
+             * @description Unique identifier
+             */
+            uid: string;
+            /** @description Database configuration name */
+            name: string;
             /** @description Description */
             description?: string;
             /** @description Target database host */
@@ -1587,991 +2225,2897 @@ export interface components {
             database_name?: string;
             /** @description Target database username */
             username?: string;
-            /** @description Target database password (encrypted at rest) */
-            password?: string;
-            /** @description SSL mode */
+            /** @description Upstream username of read-only grants; absent when unset */
+            readonly_username?: string;
+            /** @description Reference to the password in a secrets manager; absent when the password is stored */
+            secret_ref?: string;
+            /** @description Read replicas the sessions of read-only grants are routed to; absent when unset */
+            read_replicas?: string[];
+            /** @description SSL mode (disable, prefer, require, etc.) */
             ssl_mode?: string;
+            /** @description PEM CA bundle verifying the upstream certificate */
+            ssl_root_cert?: string;
+            /** @description Defaults that pre-fill and bound the grants on the database */
+            grant_defaults?: components["schemas"]["GrantDefaults"];
+            /** @description Row quota of the database; absent when unset */
+            row_quota?: components["schemas"]["RowQuota"];
+            /** @description Result masking of the database; absent when unset */
+            result_masking?: components["schemas"]["ResultMasking"];
+            /** @description Query storage overrides of the database; absent when unset */
+            query_storage?: components["schemas"]["QueryStorageOverrides"];
+            /** @description Slow query threshold of the database; absent when unset */
+            slow_query_threshold_ms?: number;
+            /** @description Latest connectivity check of the database; absent until it is first checked, and on create/update responses */
+            health?: components["schemas"]["ServerHealth"];
             /**
-             * @description Server protocol
+             * @description Server protocol (ssh = an SSH bastion, not a database target)
              * @enum {string}
              */
             protocol?: "postgresql" | "oracle" | "mysql" | "mariadb" | "mongodb" | "ssh";
-            /** @description Oracle SERVICE_NAME */
+            /** @description Oracle SERVICE_NAME (Oracle only) */
             oracle_service_name?: string;
             /** @description Upstream MongoDB SCRAM authSource (MongoDB only; defaults to "admin") */
             mongo_auth_source?: string;
-            /** @description Whether this database appears in the grant-request dropdown for non-admin users */
-            listable?: boolean;
+            /**
+             * @description Whether this database appears in the grant-request dropdown for non-admin users
+             * @default true
+             */
+            listable: boolean;
             /**
              * Format: uuid
-             * @description SSH server (bastion) UID to tunnel through
+             * @description User who created this configuration
              */
-            via_uid?: string | null;
-            /** @description When true, removes the SSH tunnel (direct dial) */
-            clear_via_uid?: boolean;
-            /** @description SSH private key (PEM); write-only, never returned */
-            ssh_private_key?: string;
-            /** @description Passphrase for the SSH private key; write-only, never returned */
-            ssh_passphrase?: string;
+            created_by?: string | null;
             /**
-             * @description Optional; defaults to false when omitted. When true, the API dials the updated row
-             *     once and returns the staged outcome as `connection_test` in the response.
-             *     Never fatal.
+             * Format: uuid
+             * @description SSH server (bastion) UID to tunnel through; null for a direct dial
              */
-            test_connection?: boolean;
+            via_uid?: string | null;
+            /** @description TOFU-pinned SSH bastion host key (read-only; ssh servers only) */
+            ssh_known_host_key?: string;
+            /** @description Present only when the create/update request set `test_connection: true` */
+            connection_test?: components["schemas"]["ConnectionTestResult"];
         };
         /**
-         * @description Control types that can be applied to a grant:
-         *     - `read_only`: Enables PostgreSQL session read-only mode and blocks write queries
-         *     - `block_copy`: Blocks all COPY commands (both TO and FROM)
-         *     - `block_ddl`: Blocks DDL statements (CREATE, ALTER, DROP, TRUNCATE)
-         * @enum {string}
-         */
-        GrantControl: "read_only" | "block_copy" | "block_ddl";
-        /**
-         * @description A user-initiated request for a grant of a particular shape on a
-         *     particular database. Lifecycle: pending → approved/denied/cancelled.
-         *     On approval the system creates a real AccessGrant (referenced via
-         *     resulting_grant_id).
-         */
-        GrantRequest: {
-            /** Format: uuid */
-            uid: string;
-            /** Format: uuid */
-            user_id: string;
-            /** Format: uuid */
-            grant_definition_id: string;
-            /** Format: uuid */
-            database_id: string;
-            justification?: string;
-            /** @enum {string} */
-            status: "pending" | "approved" | "denied" | "cancelled" | "expired";
-            /** Format: date-time */
-            requested_at: string;
-            /** Format: date-time */
-            decided_at?: string | null;
-            /** Format: uuid */
-            decided_by?: string | null;
-            decision_reason?: string | null;
-            /** Format: uuid */
-            resulting_grant_id?: string | null;
-        };
-        CreateGrantRequestPayload: {
-            /** Format: uuid */
-            grant_definition_id: string;
-            /** Format: uuid */
-            database_id: string;
-            justification?: string;
-        };
-        /**
-         * @description Admin-managed template describing a *shape* of grant. Grant requests
-         *     (separate workflow) reference a definition; on approval a real
-         *     AccessGrant is built from the definition + the request's
-         *     user/database. Direct admin grant creation bypasses definitions.
+         * @description Staged outcome of a connectivity check. Carries no secret material: only the stage
+         *     reached, a machine-readable code, a human-readable message, and the bastion's public
+         *     host key.
          */
-        GrantDefinition: {
-            /** Format: uuid */
-            uid: string;
-            name: string;
-            description?: string;
-            /**
-             * Format: int64
-             * @description How long the resulting grant remains valid after approval.
-             */
-            duration_seconds: number;
-            controls: components["schemas"]["GrantControl"][];
-            /** Format: int64 */
-            max_query_counts?: number | null;
-            /** Format: int64 */
-            max_bytes_transferred?: number | null;
+        ConnectionTestResult: {
+            /** @description Whether the check succeeded end to end */
+            ok: boolean;
             /**
-             * @description When true, grant requests against this definition skip the pending/admin-approval
-             *     step and are approved (and the grant materialized) instantly at request time.
-             * @default false
+             * @description How far the check got. On failure this is the stage that failed, which is what tells
+             *     the admin which field they got wrong:
+             *     - `config`: the row is unusable before any packet is sent (no credentials, unparseable key, via_uid cycle,
+             *       secret_ref not fetched)
+             *     - `bastion_dial`: the SSH bastion could not be reached (DNS, routing, firewall)
+             *     - `bastion_auth`: the SSH handshake failed (host-key mismatch or rejected credentials)
+             *     - `target_dial`: the database target could not be reached (through the tunnel when via_uid is set)
+             *     - `target_auth`: the database handshake or login failed
+             * @enum {string}
              */
-            auto_approve: boolean;
+            stage: "config" | "bastion_dial" | "bastion_auth" | "target_dial" | "target_auth";
             /**
-             * @description Restricts this definition to members of these user groups. An empty
-             *     array means every user, which is how every pre-scoping definition
-             *     keeps behaving. A dangling uid (deleted group) matches nobody, so
-             *     the definition fails closed.
+             * @description Machine-readable classification within the stage; `ok` on success
+             * @enum {string}
              */
-            group_uids: string[];
+            code: "ok" | "dns_failure" | "timeout" | "unreachable" | "host_key_mismatch" | "auth_rejected" | "bad_private_key" | "no_auth_method" | "missing_config" | "secret_unavailable" | "via_cycle" | "via_not_ssh" | "handshake_failed" | "db_auth_failed" | "db_handshake_failed" | "auth_not_verified" | "privileges_not_checked" | "inspect_failed" | "internal_error";
+            /** @description Human-readable explanation, safe to display to an admin */
+            message: string;
+            /** @description True when this check performed the TOFU pin (first successful connect to a bastion) */
+            host_key_pinned?: boolean;
+            /** @description The bastion's public host key after the check (ssh servers only) */
+            ssh_known_host_key?: string;
             /**
-             * @description Restricts this definition to these databases. An empty array means
-             *     every database.
+             * Format: int64
+             * @description How long the check took, in milliseconds
              */
-            database_uids: string[];
-            /** @description Soft-deleted definitions have is_active=false; they remain referenced by historical grant requests. */
-            readonly is_active: boolean;
+            duration_ms: number;
+        };
+        /** @description Latest connectivity check of a database target, run by the background health
+         *     checks (every `DBB_HEALTH_CHECKS_INTERVAL`) or by `POST /servers/{uid}/test`.
+         *     `stage`, `code` and `message` are those of the check result.
+         *      */
+        ServerHealth: {
             /** Format: uuid */
-            readonly created_by: string;
+            server_id: string;
             /** Format: date-time */
-            readonly created_at: string;
-        };
-        CreateGrantDefinitionRequest: {
-            name: string;
-            description?: string;
-            /** Format: int64 */
-            duration_seconds: number;
-            /** @default [] */
-            controls: components["schemas"]["GrantControl"][];
-            /** Format: int64 */
-            max_query_counts?: number | null;
+            checked_at: string;
+            /** @description Whether the check succeeded end to end */
+            ok: boolean;
+            /** @enum {string} */
+            stage: "config" | "bastion_dial" | "bastion_auth" | "target_dial" | "target_auth";
+            /** @description Machine-readable classification within the stage; `ok` on success */
+            code: string;
+            /** @description Human-readable explanation, safe to display to an admin */
+            message: string;
             /** Format: int64 */
-            max_bytes_transferred?: number | null;
-            /**
-             * @description When true, grant requests against this definition skip the pending/admin-approval
-             *     step and are approved (and the grant materialized) instantly at request time.
-             * @default false
-             */
-            auto_approve: boolean;
-            /**
-             * @description Restricts this definition to members of these user groups. An empty
-             *     array means every user, which is how every pre-scoping definition
-             *     keeps behaving. A dangling uid (deleted group) matches nobody, so
-             *     the definition fails closed.
-             */
-            group_uids?: string[];
+            duration_ms: number;
             /**
-             * @description Restricts this definition to these databases. An empty array means
-             *     every database.
+             * Format: date-time
+             * @description When the database last passed a check; absent if never
              */
-            database_uids?: string[];
+            last_ok_at?: string;
+        };
+        /** @description A connectivity check result with the account's privileges */
+        PrivilegeCheckResult: components["schemas"]["ConnectionTestResult"] & {
+            privileges?: components["schemas"]["ServerPrivileges"];
+        };
+        /** @description What the stored service account can do upstream */
+        ServerPrivileges: {
+            /** @description The account the upstream sees the proxy as */
+            role: string;
+            /** @description Superuser (PostgreSQL) or ALL PRIVILEGES / SUPER on *.* (MySQL) */
+            superuser: boolean;
+            createdb: boolean;
+            createrole: boolean;
+            replication: boolean;
+            /** @description Row-level security does not apply (PostgreSQL only) */
+            bypass_rls: boolean;
+            /** @description Roles the account inherits from */
+            member_of: string[];
+            /** @description The account's GRANT statements (MySQL and MariaDB only) */
+            grants?: string[];
+            /** @description One explanation per privilege the proxy credential does not need */
+            warnings: string[];
         };
-        AccessGrant: {
+        /** @description Limited database info for non-admin users */
+        DatabaseLimited: {
             /**
              * Format: uuid
              * @description Unique identifier
              */
             uid: string;
+            /** @description Database configuration name */
+            name: string;
+            /** @description Description */
+            description?: string;
+        };
+        TestDatabaseConnectionRequest: {
             /**
-             * Format: uuid
-             * @description User UID
+             * @description Database protocol; ssh is refused
+             * @default postgresql
+             * @enum {string}
              */
-            user_id: string;
+            protocol?: "postgresql" | "oracle" | "mysql" | "mariadb" | "mongodb";
+            /** @description Target database host */
+            host: string;
+            /** @description Target database port */
+            port: number;
+            /** @description Target database name */
+            database_name?: string;
+            /** @description Target database username */
+            username: string;
+            /** @description Target database password; used for this check only */
+            password?: string;
+            /** @description Reference to the password in a secrets manager, instead of `password` */
+            secret_ref?: string;
             /**
-             * Format: uuid
-             * @description Database UID
+             * @description SSL mode
+             * @default prefer
              */
-            database_id: string;
-            /** @description List of controls applied. Empty array means full write access. */
-            controls: components["schemas"]["GrantControl"][];
+            ssl_mode?: string;
+            /** @description PEM CA bundle verifying the upstream certificate */
+            ssl_root_cert?: string;
+            /** @description Oracle SERVICE_NAME (required for Oracle) */
+            oracle_service_name?: string;
+            /** @description Upstream MongoDB SCRAM authSource (MongoDB only; defaults to "admin") */
+            mongo_auth_source?: string;
             /**
              * Format: uuid
-             * @description Admin who granted access
-             */
-            granted_by: string;
-            /**
-             * Format: date-time
-             * @description When access starts
-             */
-            starts_at: string;
-            /**
-             * Format: date-time
-             * @description When access expires
+             * @description SSH server (bastion) UID to tunnel through; null for a direct dial
              */
-            expires_at: string;
+            via_uid?: string | null;
+        };
+        CreateDatabaseRequest: {
+            /** @description Unique name for this database configuration */
+            name: string;
+            /** @description Description */
+            description?: string;
+            /** @description Target database host */
+            host: string;
             /**
-             * Format: date-time
-             * @description When access was revoked (null if active)
+             * @description Target database port
+             * @default 5432
              */
-            revoked_at?: string | null;
+            port: number;
+            /** @description Target database name */
+            database_name?: string;
+            /** @description Target database username */
+            username: string;
+            /** @description Target database password (encrypted at rest) */
+            password?: string;
             /**
-             * Format: uuid
-             * @description Admin who revoked access
+             * @description Upstream username read-only grants log in with, so the target's own permissions
+             *     back read_only. Set together with `readonly_password`; an empty string removes
+             *     the read-only credentials on update.
              */
-            revoked_by?: string | null;
+            readonly_username?: string;
+            /** @description Password of `readonly_username` (encrypted at rest) */
+            readonly_password?: string;
             /**
-             * Format: int64
-             * @description Maximum queries allowed (quota)
+             * @description Reference to the password of `username` in a secrets manager, fetched (and cached)
+             *     when the proxy connects upstream instead of storing `password`:
+             *     `vault://<mount>/<path>[#<key>]`, `aws-sm://<name or ARN>[#<key>]` or
+             *     `gcp-sm://projects/<p>/secrets/<s>[/versions/<v>][#<key>]`. Exclusive with
+             *     `password`; an empty string clears it on update. Not for ssh servers.
+             * @example vault://secret/dbbat/orders#password
              */
-            max_query_counts?: number | null;
+            secret_ref?: string;
             /**
-             * Format: int64
-             * @description Maximum bytes transferred (quota)
+             * @description Read replicas, `host` or `host:port` (the database's port when omitted). Sessions of
+             *     read-only grants are spread over them round-robin; write-capable sessions go to
+             *     `host`. Credentials, TLS and SSH tunnel are the primary's. PostgreSQL only; an empty
+             *     array clears them on update.
+             * @example [
+             *       "replica-1.internal",
+             *       "replica-2.internal:5433"
+             *     ]
              */
-            max_bytes_transferred?: number | null;
+            read_replicas?: string[];
             /**
-             * Format: int64
-             * @description Current query count
+             * @description SSL mode
+             * @default prefer
              */
-            query_count?: number;
+            ssl_mode: string;
+            /** @description PEM CA bundle verifying the upstream certificate */
+            ssl_root_cert?: string;
+            /** @description Defaults that pre-fill and bound the grants on the database */
+            grant_defaults?: components["schemas"]["GrantDefaults"];
+            /** @description Caps on the result rows retained for the database */
+            row_quota?: components["schemas"]["RowQuota"];
+            /** @description Columns whose values are masked in the captured result rows */
+            result_masking?: components["schemas"]["ResultMasking"];
+            /** @description Query storage and retention settings replacing the global ones for the database */
+            query_storage?: components["schemas"]["QueryStorageOverrides"];
             /**
-             * Format: int64
-             * @description Current bytes transferred
+             * @description Duration, in milliseconds, past which the plan of a statement is captured
+             *     with `EXPLAIN (FORMAT JSON)`; see `GET /queries/{uid}/plan`. PostgreSQL only.
              */
-            bytes_transferred?: number;
+            slow_query_threshold_ms?: number;
             /**
-             * Format: date-time
-             * @description Creation timestamp
+             * @description Server protocol (ssh = an SSH bastion, not a database target)
+             * @default postgresql
+             * @enum {string}
              */
-            created_at: string;
-        };
-        CreateGrantRequest: {
+            protocol: "postgresql" | "oracle" | "mysql" | "mariadb" | "mongodb" | "ssh";
+            /** @description Oracle SERVICE_NAME (required for Oracle) */
+            oracle_service_name?: string;
+            /** @description Upstream MongoDB SCRAM authSource (MongoDB only; defaults to "admin") */
+            mongo_auth_source?: string;
             /**
-             * Format: uuid
-             * @description User UID
+             * @description Whether this database appears in the grant-request dropdown for non-admin users
+             * @default true
              */
-            user_id: string;
+            listable: boolean;
             /**
              * Format: uuid
-             * @description Database UID
-             */
-            database_id: string;
-            /**
-             * @description List of controls to apply. Empty array means full write access.
-             * @default []
+             * @description SSH server (bastion) UID to tunnel through; null for a direct dial
              */
-            controls: components["schemas"]["GrantControl"][];
+            via_uid?: string | null;
+            /** @description SSH private key (PEM) for an ssh server; write-only, never returned */
+            ssh_private_key?: string;
+            /** @description Passphrase for the SSH private key; write-only, never returned */
+            ssh_passphrase?: string;
             /**
-             * Format: date-time
-             * @description When access starts
+             * @description Optional; defaults to false when omitted. When true, the API dials the newly created
+             *     row once and returns the staged outcome as `connection_test` in the response.
+             *     Never fatal: the row is created either way.
              */
-            starts_at: string;
+            test_connection?: boolean;
+        };
+        UpdateDatabaseRequest: {
+            /** @description Description */
+            description?: string;
+            /** @description Target database host */
+            host?: string;
+            /** @description Target database port */
+            port?: number;
+            /** @description Target database name */
+            database_name?: string;
+            /** @description Target database username */
+            username?: string;
+            /** @description Target database password (encrypted at rest) */
+            password?: string;
             /**
-             * Format: date-time
-             * @description When access expires (must be after starts_at)
+             * @description Upstream username read-only grants log in with, so the target's own permissions
+             *     back read_only. Set together with `readonly_password`; an empty string removes
+             *     the read-only credentials on update.
              */
-            expires_at: string;
+            readonly_username?: string;
+            /** @description Password of `readonly_username` (encrypted at rest) */
+            readonly_password?: string;
+            /**
+             * @description Reference to the password of `username` in a secrets manager, fetched (and cached)
+             *     when the proxy connects upstream instead of storing `password`:
+             *     `vault://<mount>/<path>[#<key>]`, `aws-sm://<name or ARN>[#<key>]` or
+             *     `gcp-sm://projects/<p>/secrets/<s>[/versions/<v>][#<key>]`. Exclusive with
+             *     `password`; an empty string clears it on update. Not for ssh servers.
+             * @example vault://secret/dbbat/orders#password
+             */
+            secret_ref?: string;
+            /**
+             * @description Read replicas, `host` or `host:port` (the database's port when omitted). Sessions of
+             *     read-only grants are spread over them round-robin; write-capable sessions go to
+             *     `host`. Credentials, TLS and SSH tunnel are the primary's. PostgreSQL only; an empty
+             *     array clears them on update.
+             * @example [
+             *       "replica-1.internal",
+             *       "replica-2.internal:5433"
+             *     ]
+             */
+            read_replicas?: string[];
+            /** @description SSL mode */
+            ssl_mode?: string;
+            /** @description PEM CA bundle verifying the upstream certificate */
+            ssl_root_cert?: string;
+            /** @description Defaults that pre-fill and bound the grants on the database */
+            grant_defaults?: components["schemas"]["GrantDefaults"];
+            /** @description Replaces the row quota; an empty object clears it */
+            row_quota?: components["schemas"]["RowQuota"];
+            /** @description Replaces the result masking; an empty object clears it */
+            result_masking?: components["schemas"]["ResultMasking"];
+            /** @description Replaces the query storage overrides; an empty object clears them */
+            query_storage?: components["schemas"]["QueryStorageOverrides"];
+            /** @description Replaces the slow query threshold; 0 clears it */
+            slow_query_threshold_ms?: number;
+            /**
+             * @description Server protocol
+             * @enum {string}
+             */
+            protocol?: "postgresql" | "oracle" | "mysql" | "mariadb" | "mongodb" | "ssh";
+            /** @description Oracle SERVICE_NAME */
+            oracle_service_name?: string;
+            /** @description Upstream MongoDB SCRAM authSource (MongoDB only; defaults to "admin") */
+            mongo_auth_source?: string;
+            /** @description Whether this database appears in the grant-request dropdown for non-admin users */
+            listable?: boolean;
+            /**
+             * Format: uuid
+             * @description SSH server (bastion) UID to tunnel through
+             */
+            via_uid?: string | null;
+            /** @description When true, removes the SSH tunnel (direct dial) */
+            clear_via_uid?: boolean;
+            /** @description SSH private key (PEM); write-only, never returned */
+            ssh_private_key?: string;
+            /** @description Passphrase for the SSH private key; write-only, never returned */
+            ssh_passphrase?: string;
+            /**
+             * @description Optional; defaults to false when omitted. When true, the API dials the updated row
+             *     once and returns the staged outcome as `connection_test` in the response.
+             *     Never fatal.
+             */
+            test_connection?: boolean;
+        };
+        /**
+         * @description Soft caps on the result rows retained for a database, compacted ones included. Proxies
+         *     keep capturing past them; the retention janitor then deletes the result rows of the
+         *     database's oldest queries until they fit, keeping the queries.
+         */
+        RowQuota: {
             /**
              * Format: int64
-             * @description Maximum queries allowed (quota)
+             * @description Maximum number of result rows retained
+             */
+            max_rows?: number;
+            /**
+             * Format: int64
+             * @description Maximum storage used by the result rows, compressed size for compacted ones
+             */
+            max_bytes?: number;
+        };
+        /**
+         * @description Columns of a database whose values are replaced by `{"$masked": true}` in the captured
+         *     result rows, before they are stored. Clients still receive the real values.
+         */
+        ResultMasking: {
+            /** @description Regular expressions matched case-insensitively against column names */
+            column_patterns?: string[];
+            /**
+             * @description Column names, optionally qualified as `table.column` or `schema.table.column` to
+             *     mask them only in the results of statements on that table
+             */
+            columns?: string[];
+        };
+        /**
+         * @description Query storage and retention settings replacing the global ones for a database. Unset
+         *     fields keep the global value. The retention overrides replace queries_days and rows_days
+         *     of the retention configuration; 0 keeps the database's queries or rows forever.
+         */
+        QueryStorageOverrides: {
+            /** @description Whether the proxies capture the result rows of the database's queries */
+            store_results?: boolean;
+            /** @description Maximum number of result rows captured per query */
+            max_result_rows?: number;
+            /**
+             * Format: int64
+             * @description Maximum size of the result rows captured per query
+             */
+            max_result_bytes?: number;
+            /** @description Days the database's queries are retained, with their result rows */
+            queries_days?: number;
+            /** @description Days the result rows of the database's queries are retained */
+            rows_days?: number;
+        };
+        /**
+         * @description A database's defaults for its grants. They pre-fill the fields a new grant omits and
+         *     bound every grant.
+         */
+        GrantDefaults: {
+            /** @description Controls every grant carries; the default access level (e.g. read_only) */
+            controls?: components["schemas"]["GrantControl"][];
+            /**
+             * Format: int64
+             * @description Default and maximum grant duration
+             */
+            duration_seconds?: number;
+            /**
+             * Format: int64
+             * @description Default and maximum query quota
              */
             max_query_counts?: number;
             /**
              * Format: int64
-             * @description Maximum bytes transferred (quota)
+             * @description Default and maximum byte quota
              */
             max_bytes_transferred?: number;
+            /** @enum {string} */
+            capture_mode?: "full" | "queries";
         };
-        APIKey: {
+        /**
+         * @description Control types that can be applied to a grant:
+         *     - `read_only`: Enables PostgreSQL session read-only mode and blocks write queries
+         *     - `block_copy`: Blocks all COPY commands (both TO and FROM)
+         *     - `block_ddl`: Blocks DDL statements (CREATE, ALTER, DROP, TRUNCATE)
+         * @enum {string}
+         */
+        GrantControl: "read_only" | "block_copy" | "block_ddl" | "allow_replication";
+        /**
+         * @description A write statement the PostgreSQL proxy holds, under the
+         *     `write_requires_approval` control, until an admin other than its user
+         *     approves or denies it. Lifecycle: pending → approved/denied, or
+         *     expired once the proxy stops waiting (approval timeout, client
+         *     cancel or disconnect).
+         */
+        StatementApproval: {
+            /** Format: uuid */
+            uid: string;
+            /** Format: uuid */
+            connection_id: string;
+            /** Format: uuid */
+            grant_id?: string;
+            /** Format: uuid */
+            user_id: string;
+            /** Format: uuid */
+            database_id: string;
+            sql_text: string;
+            /** @enum {string} */
+            status: "pending" | "approved" | "denied" | "expired";
+            /** Format: date-time */
+            requested_at: string;
             /**
-             * Format: uuid
-             * @description Unique identifier
+             * Format: date-time
+             * @description The proxy refuses the statement if it is still pending then.
              */
-            id: string;
+            expires_at: string;
+            /** Format: date-time */
+            decided_at?: string | null;
             /**
              * Format: uuid
-             * @description User UID
+             * @description The deciding admin; absent when expired.
              */
+            decided_by?: string | null;
+            decision_reason?: string | null;
+        };
+        /**
+         * @description A user-initiated request for a grant of a particular shape on a
+         *     particular database. Lifecycle: pending → approved/denied/cancelled.
+         *     On approval the system creates a real AccessGrant (referenced via
+         *     resulting_grant_id).
+         *
+         *     An extension request sets extends_grant_id instead of
+         *     grant_definition_id; on approval that grant's expiry is pushed back
+         *     and resulting_grant_id references it.
+         */
+        GrantRequest: {
+            /** Format: uuid */
+            uid: string;
+            /** Format: uuid */
             user_id: string;
-            /** @description Key name */
-            name: string;
-            /** @description Key prefix for identification */
-            key_prefix: string;
             /**
-             * Format: date-time
-             * @description When the key expires
+             * Format: uuid
+             * @description Absent on extension requests.
              */
-            expires_at?: string | null;
+            grant_definition_id?: string;
             /**
-             * Format: date-time
-             * @description When the key was last used
+             * Format: uuid
+             * @description The grant an extension request extends; absent otherwise.
              */
-            last_used_at?: string | null;
+            extends_grant_id?: string;
+            /** Format: uuid */
+            database_id: string;
+            justification?: string;
+            /** @enum {string} */
+            status: "pending" | "approved" | "denied" | "cancelled" | "expired";
+            /** Format: date-time */
+            requested_at: string;
+            /** Format: date-time */
+            decided_at?: string | null;
+            /** Format: uuid */
+            decided_by?: string | null;
+            decision_reason?: string | null;
+            /** Format: uuid */
+            resulting_grant_id?: string | null;
             /**
              * Format: int64
-             * @description Total requests made with this key
+             * @description Requested lifetime of the resulting grant; null means the
+             *     definition's duration. For an extension request, the time added
+             *     to the grant.
              */
-            request_count: number;
+            duration_seconds?: number | null;
+        };
+        CreateGrantRequestPayload: {
+            /** Format: uuid */
+            grant_definition_id: string;
+            /** Format: uuid */
+            database_id: string;
+            justification?: string;
             /**
-             * Format: date-time
-             * @description Creation timestamp
+             * Format: int64
+             * @description Lifetime of the resulting grant, at most the definition's duration.
+             *     Omitted takes the definition's duration.
              */
-            created_at: string;
+            duration_seconds?: number;
+        };
+        /**
+         * @description Admin-managed template describing a *shape* of grant. Grant requests
+         *     (separate workflow) reference a definition; on approval a real
+         *     AccessGrant is built from the definition + the request's
+         *     user/database. Direct admin grant creation bypasses definitions.
+         */
+        GrantDefinition: {
+            /** Format: uuid */
+            uid: string;
+            name: string;
+            description?: string;
             /**
-             * Format: date-time
-             * @description When the key was revoked
+             * Format: int64
+             * @description How long the resulting grant remains valid after approval.
              */
-            revoked_at?: string | null;
+            duration_seconds: number;
+            controls: components["schemas"]["GrantControl"][];
+            /** Format: int64 */
+            max_query_counts?: number | null;
+            /** Format: int64 */
+            max_bytes_transferred?: number | null;
+            /**
+             * @description When true, grant requests against this definition skip the pending/admin-approval
+             *     step and are approved (and the grant materialized) instantly at request time.
+             * @default false
+             */
+            auto_approve: boolean;
+            /**
+             * @description Restricts this definition to members of these user groups. An empty
+             *     array means every user, which is how every pre-scoping definition
+             *     keeps behaving. A dangling uid (deleted group) matches nobody, so
+             *     the definition fails closed.
+             */
+            group_uids: string[];
+            /**
+             * @description Restricts this definition to these databases. An empty array means
+             *     every database.
+             */
+            database_uids: string[];
+            /** @description Soft-deleted definitions have is_active=false; they remain referenced by historical grant requests. */
+            readonly is_active: boolean;
+            /** Format: uuid */
+            readonly created_by: string;
+            /** Format: date-time */
+            readonly created_at: string;
+        };
+        CreateGrantDefinitionRequest: {
+            name: string;
+            description?: string;
+            /** Format: int64 */
+            duration_seconds: number;
+            /** @default [] */
+            controls: components["schemas"]["GrantControl"][];
+            /** Format: int64 */
+            max_query_counts?: number | null;
+            /** Format: int64 */
+            max_bytes_transferred?: number | null;
+            /**
+             * @description When true, grant requests against this definition skip the pending/admin-approval
+             *     step and are approved (and the grant materialized) instantly at request time.
+             * @default false
+             */
+            auto_approve: boolean;
+            /**
+             * @description Restricts this definition to members of these user groups. An empty
+             *     array means every user, which is how every pre-scoping definition
+             *     keeps behaving. A dangling uid (deleted group) matches nobody, so
+             *     the definition fails closed.
+             */
+            group_uids?: string[];
+            /**
+             * @description Restricts this definition to these databases. An empty array means
+             *     every database.
+             */
+            database_uids?: string[];
+        };
+        AccessGrant: {
             /**
              * Format: uuid
-             * @description User who revoked the key
+             * @description Unique identifier
              */
-            revoked_by?: string | null;
+            uid: string;
+            /**
+             * Format: uuid
+             * @description User UID
+             */
+            user_id: string;
+            /**
+             * Format: uuid
+             * @description Database UID
+             */
+            database_id: string;
+            /** @description List of controls applied. Empty array means full write access. */
+            controls: components["schemas"]["GrantControl"][];
+            /**
+             * @description Tables the grant is restricted to, as `schema.table` or `schema.*`. Empty array means
+             *     every table. Enforced on PostgreSQL only.
+             * @example [
+             *       "public.orders",
+             *       "reporting.*"
+             *     ]
+             */
+            allowed_tables?: string[];
+            /**
+             * @description Client networks the grant may be used from. Empty array means any network. Enforced
+             *     on PostgreSQL only.
+             * @example [
+             *       "10.8.0.0/16"
+             *     ]
+             */
+            allowed_cidrs?: string[];
+            /**
+             * Format: uuid
+             * @description Admin who granted access
+             */
+            granted_by: string;
+            /**
+             * Format: date-time
+             * @description When access starts
+             */
+            starts_at: string;
+            /**
+             * Format: date-time
+             * @description When access expires
+             */
+            expires_at: string;
+            /**
+             * Format: date-time
+             * @description When access was revoked (null if active)
+             */
+            revoked_at?: string | null;
+            /**
+             * Format: uuid
+             * @description Admin who revoked access
+             */
+            revoked_by?: string | null;
+            /**
+             * Format: int64
+             * @description Maximum queries allowed (quota)
+             */
+            max_query_counts?: number | null;
+            /**
+             * Format: int64
+             * @description Maximum bytes transferred (quota)
+             */
+            max_bytes_transferred?: number | null;
+            /**
+             * Format: int64
+             * @description Maximum rows returned to the client (quota). Enforced on PostgreSQL only.
+             */
+            max_rows_returned?: number | null;
+            /**
+             * Format: int64
+             * @description Current query count
+             */
+            query_count?: number;
+            /**
+             * Format: int64
+             * @description Current bytes transferred
+             */
+            bytes_transferred?: number;
+            /**
+             * Format: int64
+             * @description Rows returned since the grant started
+             */
+            rows_returned?: number;
+            quota_remaining?: components["schemas"]["GrantQuotaRemaining"];
+            /**
+             * Format: date-time
+             * @description Creation timestamp
+             */
+            created_at: string;
+        };
+        /** @description One-time login issued on a grant for someone without a DBBat user */
+        ProxyCredential: {
+            /** Format: uuid */
+            uid: string;
+            /** Format: uuid */
+            grant_id: string;
+            /** @description Login name, starting with `pxc_` */
+            username: string;
+            /** @description Who the credential was handed to */
+            principal: string;
+            /**
+             * Format: uuid
+             * @description Administrator who issued the credential
+             */
+            issued_by: string;
+            /** Format: date-time */
+            expires_at: string;
+            /** Format: date-time */
+            used_at?: string | null;
+            /**
+             * Format: uuid
+             * @description Connection the credential opened
+             */
+            connection_id?: string | null;
+            /** Format: date-time */
+            revoked_at?: string | null;
+            /** Format: uuid */
+            revoked_by?: string | null;
+            /** Format: date-time */
+            created_at: string;
+            /** @enum {string} */
+            status: "pending" | "used" | "expired" | "revoked";
+        };
+        CreateProxyCredentialRequest: {
+            /** @description Who the credential is handed to (an email address, a ticket, a company) */
+            principal: string;
+            valid_minutes: number;
+        };
+        CreateProxyCredentialResponse: components["schemas"]["ProxyCredential"] & {
+            /** @description Only returned once */
+            password: string;
+            connection?: components["schemas"]["ConnectionInfo"];
+        };
+        /** @description Admin-only grant shape applied to many users and databases at once */
+        GrantTemplate: {
+            /** Format: uuid */
+            uid: string;
+            name: string;
+            description?: string;
+            /**
+             * Format: int64
+             * @description How long the grants remain valid after their start
+             */
+            duration_seconds: number;
+            /** @description Empty means the database's default controls */
+            controls: components["schemas"]["GrantControl"][];
+            allowed_tables: string[];
+            allowed_cidrs: string[];
+            /** Format: int64 */
+            max_query_counts?: number | null;
+            /** Format: int64 */
+            max_bytes_transferred?: number | null;
+            /** Format: int64 */
+            max_rows_returned?: number | null;
+            labels: {
+                [key: string]: string;
+            };
+            /** Format: uuid */
+            readonly created_by?: string;
+            /** Format: date-time */
+            readonly created_at: string;
+            /** Format: date-time */
+            readonly updated_at: string;
+        };
+        CreateGrantTemplateRequest: {
+            name: string;
+            description?: string;
+            /** Format: int64 */
+            duration_seconds: number;
+            /** @description Omitted or empty means the database's default controls */
+            controls?: components["schemas"]["GrantControl"][];
+            /** @description As for `POST /grants` */
+            allowed_tables?: string[];
+            /** @description As for `POST /grants` */
+            allowed_cidrs?: string[];
+            /** Format: int64 */
+            max_query_counts?: number;
+            /** Format: int64 */
+            max_bytes_transferred?: number;
+            /** Format: int64 */
+            max_rows_returned?: number;
+            labels?: {
+                [key: string]: string;
+            };
+        };
+        ApplyGrantTemplateRequest: {
+            user_ids: string[];
+            database_ids: string[];
+            /**
+             * Format: date-time
+             * @description When the grants start; defaults to now
+             */
+            starts_at?: string;
+            labels?: {
+                [key: string]: string;
+            };
+        };
+        CreateGrantRequest: {
+            /**
+             * Format: uuid
+             * @description User UID
+             */
+            user_id: string;
+            /**
+             * Format: uuid
+             * @description Database UID
+             */
+            database_id: string;
+            /**
+             * @description List of controls to apply. Empty array means full write access; omitted means the
+             *     database's default controls. Must include the database's default controls.
+             */
+            controls?: components["schemas"]["GrantControl"][];
+            /**
+             * @description Restricts the grant to these tables: `schema.table`, `schema.*` (every table of the
+             *     schema) or a bare `table` (in `public`). Names are matched as PostgreSQL stores them,
+             *     lower case unless quoted. Omitted or empty means every table. Only PostgreSQL
+             *     databases support it.
+             * @example [
+             *       "orders",
+             *       "reporting.*"
+             *     ]
+             */
+            allowed_tables?: string[];
+            /**
+             * @description Restricts the grant to clients connecting from these networks, given as CIDRs or
+             *     single IP addresses. Connections from elsewhere are refused before authentication
+             *     and audit-logged (`grant.client_refused`). Omitted or empty means any network. Only
+             *     PostgreSQL databases support it.
+             * @example [
+             *       "10.8.0.0/16",
+             *       "192.168.1.10"
+             *     ]
+             */
+            allowed_cidrs?: string[];
+            /**
+             * Format: date-time
+             * @description When access starts
+             */
+            starts_at: string;
+            /**
+             * Format: date-time
+             * @description When access expires (must be after starts_at). Defaults to starts_at plus the
+             *     database's default grant duration; required when it has none.
+             */
+            expires_at?: string;
+            /**
+             * Format: int64
+             * @description Maximum queries allowed (quota)
+             */
+            max_query_counts?: number;
+            /**
+             * Format: int64
+             * @description Maximum bytes transferred (quota)
+             */
+            max_bytes_transferred?: number;
+            /**
+             * Format: int64
+             * @description Maximum rows returned to the client (quota). Only PostgreSQL databases support it.
+             */
+            max_rows_returned?: number;
+        };
+        /** @description What is left of each quota of a grant, never below 0 */
+        GrantQuotaRemaining: {
+            /**
+             * Format: int64
+             * @description Queries left; null when the grant has no query quota
+             */
+            queries?: number | null;
+            /**
+             * Format: int64
+             * @description Bytes left; null when the grant has no byte quota
+             */
+            bytes_transferred?: number | null;
+            /**
+             * Format: int64
+             * @description Rows left; null when the grant has no row quota
+             */
+            rows_returned?: number | null;
+        };
+        APIKey: {
+            /**
+             * Format: uuid
+             * @description Unique identifier
+             */
+            id: string;
+            /**
+             * Format: uuid
+             * @description User UID
+             */
+            user_id: string;
+            /** @description Key name */
+            name: string;
+            /** @description Key prefix for identification */
+            key_prefix: string;
+            /**
+             * Format: date-time
+             * @description When the key expires
+             */
+            expires_at?: string | null;
+            /**
+             * Format: date-time
+             * @description When the key was last used
+             */
+            last_used_at?: string | null;
+            /**
+             * Format: int64
+             * @description Total requests made with this key
+             */
+            request_count: number;
+            /**
+             * Format: date-time
+             * @description Creation timestamp
+             */
+            created_at: string;
+            /**
+             * Format: date-time
+             * @description When the key was revoked
+             */
+            revoked_at?: string | null;
+            /**
+             * Format: uuid
+             * @description User who revoked the key
+             */
+            revoked_by?: string | null;
+            /** @description API routes the key is restricted to; null when it has every permission of its user */
+            scopes?: string[] | null;
+        };
+        CreateAPIKeyRequest: {
+            /** @description Key name */
+            name: string;
+            /**
+             * Format: date-time
+             * @description Optional expiration time
+             */
+            expires_at?: string;
+            /**
+             * @description Optional scopes restricting the key to some API routes, each
+             *     `read:<resource>` or `write:<resource>` (write covers read). The
+             *     resource is one of users, databases, grants, keys, connections,
+             *     queries, audit, settings, search, or `*` for all of them. Without
+             *     scopes, the key has every permission of its user. Scopes never
+             *     extend the user's permissions, and don't apply to the database
+             *     proxies.
+             */
+            scopes?: string[];
+        };
+        CreateAPIKeyResponse: {
+            /**
+             * Format: uuid
+             * @description Unique identifier
+             */
+            id: string;
+            /** @description Key name */
+            name: string;
+            /** @description Full API key (only shown once!) */
+            key: string;
+            /** @description Key prefix for identification */
+            key_prefix: string;
+            /**
+             * Format: date-time
+             * @description When the key expires
+             */
+            expires_at?: string | null;
+            /** @description API routes the key is restricted to; null when it has every permission of its user */
+            scopes?: string[] | null;
+            /**
+             * Format: date-time
+             * @description Creation timestamp
+             */
+            created_at: string;
+            /** @description Connection URLs for databases the key's owner has active grants on */
+            connections: components["schemas"]["ConnectionInfo"][];
+            /** @description True when there are more than 50 connections (list is truncated) */
+            connections_truncated: boolean;
+        };
+        DeviceAuthorizationRequest: {
+            /** @description Human-readable label for the requesting app (e.g. "stn on host"), shown on the consent page. dbbat extension. */
+            client_name?: string;
+            /** @description Accepted for OAuth compatibility but ignored. */
+            client_id?: string;
+        };
+        DeviceAuthorizationResponse: {
+            /** @description Secret code the client polls the token endpoint with. Never display or log this value. */
+            device_code: string;
+            /** @description Short human-checkable code the user enters/verifies in the browser (e.g. "WDJP-4KXR") */
+            user_code: string;
+            /** @description Browser URL the user opens and enters the user_code at */
+            verification_uri: string;
+            /** @description verification_uri with the user_code pre-filled — open this directly */
+            verification_uri_complete: string;
+            /** @description Seconds until the request expires */
+            expires_in: number;
+            /** @description Minimum seconds to wait between polls */
+            interval: number;
+        };
+        DeviceTokenRequest: {
+            /**
+             * @description Must be "urn:ietf:params:oauth:grant-type:device_code"
+             * @enum {string}
+             */
+            grant_type: "urn:ietf:params:oauth:grant-type:device_code";
+            device_code: string;
+            /** @description Accepted for OAuth compatibility but ignored. */
+            client_id?: string;
+        };
+        DeviceTokenResponse: {
+            /** @description The minted API key (dbb_...), delivered exactly once */
+            access_token: string;
+            /** @enum {string} */
+            token_type: "Bearer";
+        };
+        OAuthError: {
+            /** @description OAuth error code */
+            error: string;
+            error_description?: string;
+        };
+        DeviceConsentInfo: {
+            client_name: string;
+            user_code: string;
+            /** @enum {string} */
+            status: "pending" | "approved" | "denied";
+            /** Format: date-time */
+            expires_at: string;
+        };
+        DeviceConsentRequest: {
+            user_code: string;
+            approve: boolean;
+        };
+        /** @description What the client declared about itself at connect time (PostgreSQL
+         *     startup parameters, MySQL connection attributes, MongoDB hello
+         *     metadata, Oracle AUTH keys and Connect packet). Self-reported and unverified; absent when
+         *     the client declared nothing.
+         *      */
+        ClientInfo: {
+            /** @description Application name (PostgreSQL application_name, MySQL program_name, MongoDB application.name, Oracle AUTH_PROGRAM_NM) */
+            application_name?: string;
+            /** @description Client driver name, when the protocol declares one */
+            driver?: string;
+            /** @description Client driver version, when the protocol declares one */
+            driver_version?: string;
+            /** @description Client character encoding (PostgreSQL) */
+            client_encoding?: string;
+            /** @description Wire protocol version the client asked for (PostgreSQL "3.0" or "3.2", Oracle TNS version such as "318") */
+            protocol_version?: string;
+            /** @description Other declared settings, e.g. DateStyle or _os */
+            parameters?: {
+                [key: string]: string;
+            };
+        };
+        Connection: {
+            /**
+             * Format: uuid
+             * @description Unique identifier
+             */
+            uid: string;
+            /**
+             * Format: uuid
+             * @description User UID
+             */
+            user_id: string;
+            /**
+             * Format: uuid
+             * @description Database UID
+             */
+            database_id: string;
+            /**
+             * Format: uuid
+             * @description UID of the grant the connection was opened under
+             */
+            grant_id?: string;
+            /**
+             * @description Access level of that grant when the connection was opened
+             * @enum {string}
+             */
+            access_level?: "read_only" | "read_write";
+            /**
+             * Format: uuid
+             * @description UID of the dbbat instance that handled the connection
+             */
+            instance_id?: string;
+            /** @description Client IP address */
+            source_ip: string;
+            /**
+             * Format: date-time
+             * @description Connection start time
+             */
+            connected_at: string;
+            /**
+             * Format: date-time
+             * @description Last activity timestamp
+             */
+            last_activity_at: string;
+            /**
+             * Format: date-time
+             * @description Connection end time (null if still connected)
+             */
+            disconnected_at?: string | null;
+            /**
+             * @description Why the connection ended. Absent while connected, and when the proxy did not record it.
+             * @enum {string}
+             */
+            disconnect_reason?: "client_closed" | "client_error" | "upstream_closed" | "upstream_error" | "limit_exceeded" | "idle_timeout" | "max_duration" | "terminated";
+            /**
+             * Format: int64
+             * @description Number of queries executed
+             */
+            queries: number;
+            /**
+             * Format: int64
+             * @description Total bytes transferred
+             */
+            bytes_transferred: number;
+            /**
+             * Format: int64
+             * @description Rows returned to the client; counted on PostgreSQL only
+             */
+            rows_returned?: number;
+            client_info?: components["schemas"]["ClientInfo"];
+        };
+        Query: {
+            /**
+             * Format: uuid
+             * @description Unique identifier
+             */
+            uid: string;
+            /**
+             * Format: uuid
+             * @description Connection UID
+             */
+            connection_id: string;
+            /**
+             * Format: uuid
+             * @description UID of the user who ran the query
+             */
+            user_id?: string | null;
+            /**
+             * Format: uuid
+             * @description UID of the target database
+             */
+            database_id?: string | null;
+            /**
+             * Format: uuid
+             * @description UID of the grant the query ran under. Kept after the grant is revoked or expires, so the query stays attributable.
+             */
+            grant_id?: string | null;
+            /**
+             * @description Access level of the grant when the query ran
+             * @enum {string}
+             */
+            access_level?: "read_only" | "read_write";
+            /** @description SQL query text */
+            sql_text: string;
+            /**
+             * @description Fingerprint of the statement, shared by the statements of the same shape (see `GET /queries/stats`). Absent for queries logged before fingerprinting.
+             * @example 3f1c2a9b8d7e6f50
+             */
+            fingerprint?: string;
+            parameters?: components["schemas"]["QueryParameters"];
+            /**
+             * Format: date-time
+             * @description Execution timestamp
+             */
+            executed_at: string;
+            /**
+             * Format: double
+             * @description Execution duration in milliseconds
+             */
+            duration_ms?: number | null;
+            /**
+             * Format: double
+             * @description Time from the query being forwarded upstream to the first byte of its reply. PostgreSQL and Oracle only. Returned by the query detail endpoint, like the rest of the latency breakdown.
+             */
+            upstream_first_byte_ms?: number;
+            /**
+             * Format: double
+             * @description Time upstream took, from the query being forwarded to its last reply, minus the proxy's own work in between.
+             */
+            upstream_ms?: number;
+            /**
+             * Format: double
+             * @description Part of `upstream_ms` after the first byte: the result streaming, including the client reading it. PostgreSQL and Oracle only.
+             */
+            streaming_ms?: number;
+            /**
+             * Format: double
+             * @description Rest of `duration_ms`, spent in dbbat: access controls, result capture and masking. The whole duration of a refused query.
+             */
+            proxy_overhead_ms?: number;
+            /**
+             * Format: int64
+             * @description Number of rows affected
+             */
+            rows_affected?: number | null;
+            /** @description Error message if query failed */
+            error?: string | null;
+            /** @description Set when the query ran under the `dry_run` control: it was rolled back, so `rows_affected` and the captured rows (`RETURNING` included) show what it would have changed. */
+            dry_run?: boolean;
+            /** @description Whether the stored SQL text was truncated (its start and end are kept) */
+            sql_truncated?: boolean;
+            /**
+             * Format: int64
+             * @description Size in bytes of the original SQL text. Only set when truncated.
+             */
+            sql_text_bytes?: number;
+            /** @description Hex SHA-256 of the original SQL text. Only set when truncated, omitted from redacted views. */
+            sql_text_sha256?: string;
+            /**
+             * Format: int64
+             * @description Rows of the result spilled past the query storage limits, instead of being captured; download them with GET /queries/{uid}/result-url.
+             */
+            spilled_rows?: number;
+            /**
+             * Format: int64
+             * @description Number of identical consecutive statements folded into this query (1 when none was)
+             */
+            repeat_count?: number;
+            /**
+             * Format: date-time
+             * @description When the last folded repetition ran. Only set when repeat_count > 1.
+             */
+            last_executed_at?: string;
+        };
+        /** @description Signed URL downloading the spilled result of a query */
+        QueryResultURL: {
+            /** @description Download URL, needing no other authentication */
+            url: string;
+            /**
+             * Format: date-time
+             * @description When the URL stops working
+             */
+            expires_at: string;
+        };
+        /** @description Plan captured for a slow query, or why it could not be */
+        QueryPlan: {
+            /**
+             * Format: uuid
+             * @description Query explained; another one of the same fingerprint when the requested one was not
+             */
+            query_uid: string;
+            /** Format: uuid */
+            database_id: string;
+            /** @description Fingerprint of the explained statement */
+            fingerprint?: string;
+            /** @description Output of `EXPLAIN (FORMAT JSON)`; absent when the capture failed */
+            plan?: {
+                [key: string]: unknown;
+            }[];
+            /** @description Why the statement could not be explained; absent on success */
+            error?: string;
+            /** Format: date-time */
+            captured_at: string;
+        };
+        QueryStat: {
+            /** @example 3f1c2a9b8d7e6f50 */
+            fingerprint: string;
+            /** Format: uuid */
+            database_id: string | null;
+            /**
+             * @description The statement in its canonical form, literals replaced with `?`
+             * @example select * from orders where customer_id = ? and status in(?)
+             */
+            query: string;
+            /**
+             * Format: int64
+             * @description Executions, repeated statements folded into one query included
+             */
+            calls: number;
+            /**
+             * Format: int64
+             * @description Failed executions
+             */
+            errors: number;
+            /** Format: double */
+            total_duration_ms: number | null;
+            /** Format: double */
+            avg_duration_ms: number | null;
+            /**
+             * Format: double
+             * @description 95th percentile of the duration
+             */
+            p95_duration_ms: number | null;
+            /**
+             * Format: int64
+             * @description Rows affected or returned
+             */
+            total_rows: number;
+            /** Format: date-time */
+            first_executed_at: string;
+            /** Format: date-time */
+            last_executed_at: string;
+        };
+        /** @description Query parameter values (for prepared statements) */
+        QueryParameters: {
+            /** @description Decoded string representations */
+            values?: string[];
+            /** @description Base64-encoded raw bytes */
+            raw?: string[];
+            /** @description Format codes (0=text, 1=binary) */
+            format_codes?: number[];
+            /** @description PostgreSQL type OIDs */
+            type_oids?: number[];
+        };
+        QueryWithRows: components["schemas"]["Query"] & {
+            /** @description Result rows (from SELECT or COPY operations) */
+            rows?: components["schemas"]["QueryRow"][];
+        };
+        QueryRow: {
+            /** @description Row index */
+            row_number: number;
+            /** @description Row data as JSON object */
+            row_data: {
+                [key: string]: unknown;
+            };
+            /**
+             * Format: int64
+             * @description Row size in bytes
+             */
+            row_size_bytes: number;
+        };
+        /** @description Paginated query rows response */
+        QueryRowsResponse: {
+            /** @description Array of row objects */
+            rows: components["schemas"]["QueryRow"][];
+            /** @description Cursor for the next page (null if no more rows) */
+            next_cursor?: string | null;
+            /** @description Whether more rows are available */
+            has_more: boolean;
+            /**
+             * Format: int64
+             * @description Total number of rows for this query
+             */
+            total_rows: number;
+        };
+        AuditEvent: {
+            /**
+             * Format: uuid
+             * @description Unique identifier
+             */
+            uid: string;
+            /** @description Event type (e.g., user.created, grant.revoked) */
+            event_type: string;
+            /** @description Version of the event type's details schema (see `GET /audit/schema`). Omitted for events recorded before versioning. */
+            schema_version?: number;
+            /**
+             * Format: uuid
+             * @description User being acted upon
+             */
+            user_id?: string | null;
+            /**
+             * Format: uuid
+             * @description User performing the action
+             */
+            performed_by?: string | null;
+            /** @description Event-specific details */
+            details?: {
+                [key: string]: unknown;
+            };
+            /**
+             * Format: date-time
+             * @description Event timestamp
+             */
+            created_at: string;
+        };
+        /** @description Ready-to-paste connection URL for a database */
+        ConnectionInfo: {
+            /** Format: uuid */
+            database_uid: string;
+            database_name: string;
+            protocol: string;
+            /**
+             * @description "uri" or "ez-connect"
+             * @enum {string}
+             */
+            format: "uri" | "ez-connect";
+            /** @description Ready-to-paste connection URL */
+            url: string;
+        };
+        /** @description A runtime-editable key-value configuration entry */
+        GlobalParameter: {
+            /** Format: uuid */
+            uid: string;
+            group_key: string;
+            key: string;
+            value: string;
+            /** Format: date-time */
+            created_at: string;
+            /** Format: date-time */
+            updated_at: string;
+        };
+        SetParameterRequest: {
+            value: string;
+        };
+        /** @description Public endpoint advertisement settings. Covers two independent network paths: the *connection* host (host/pg_host/ora_host/ mysql_host/*_port — where SQL clients reach the PG/Oracle/MySQL proxies, via direct or TCP-load-balancer access) and the *Web UI* host (web_ui_url — where the browser and REST API are reached, behind an HTTP ingress / reverse proxy). These are typically two different DNS names on two different network paths. */
+        PublicEndpoints: {
+            /** @description Default public hostname for all protocols (the connection host, e.g. db.company.com) */
+            host?: string;
+            /** @description PostgreSQL-specific host override (empty = use host) */
+            pg_host?: string;
+            /** @description Oracle-specific host override (empty = use host) */
+            ora_host?: string;
+            /** @description MySQL-specific host override (empty = use host) */
+            mysql_host?: string;
+            /** @description MongoDB-specific host override (empty = use host) */
+            mongo_host?: string;
+            /** @description PostgreSQL port override (null = use local listen port) */
+            pg_port?: number | null;
+            /** @description Oracle port override (null = use local listen port) */
+            ora_port?: number | null;
+            /** @description MySQL port override (null = use local listen port) */
+            mysql_port?: number | null;
+            /** @description MongoDB port override (null = use local listen port) */
+            mongo_port?: number | null;
+            /** @description Web UI / public base URL override (e.g. https://dbbat.company.com), reached through an HTTP ingress / reverse proxy. Empty = fall back to the DBB_PUBLIC_URL environment variable. Used for Slack deep-links and other absolute-URL generation. Independent of `host`, which advertises the connection host instead. */
+            web_ui_url?: string;
+        };
+        /** @description Fully resolved connection advertisement values after fallback chains */
+        ResolvedEndpoints: {
+            pg_host: string;
+            /** @description 0 = protocol disabled */
+            pg_port: number;
+            ora_host: string;
+            ora_port: number;
+            mysql_host: string;
+            mysql_port: number;
+            mongo_host: string;
+            mongo_port: number;
+            /** @description Effective Web UI / public base URL (web_ui_url parameter, falling back to DBB_PUBLIC_URL) */
+            web_ui_url: string;
+        };
+        /** @description A dbbat instance registered in the storage database */
+        Instance: {
+            /** Format: uuid */
+            uid: string;
+            hostname: string;
+            version: string;
+            /** @description Address of each enabled listener (`api`, `pg`, `oracle`, `mysql`, `mongo`) */
+            listen: {
+                [key: string]: string;
+            };
+            /** Format: date-time */
+            started_at: string;
+            /** Format: date-time */
+            last_heartbeat_at: string;
+            /**
+             * Format: date-time
+             * @description Set when the instance shut down cleanly
+             */
+            stopped_at?: string;
+            /** @description Not stopped, with a heartbeat less than 90 seconds old */
+            live: boolean;
+        };
+        /** @description Instance information including listen addresses and public endpoint config */
+        InstanceInfo: {
+            /** @description Live listen addresses this process is bound to, straight from config (DBB_LISTEN_*). `api` is the HTTP listener (REST API + Web UI, meant for an HTTP reverse proxy / ingress); `pg`, `ora`, and `mysql` are TCP listeners (SQL client proxies, meant for a TCP load balancer). */
+            listen: {
+                /** @description PostgreSQL proxy TCP listen address */
+                pg: string;
+                /** @description Oracle proxy TCP listen address */
+                ora: string;
+                /** @description MySQL/MariaDB proxy TCP listen address */
+                mysql: string;
+                /** @description MongoDB proxy TCP listen address */
+                mongo?: string;
+                /** @description REST API / Web UI HTTP listen address */
+                api: string;
+            };
+            /** @description Only present for admin callers */
+            public?: components["schemas"]["PublicEndpoints"];
+            resolved: components["schemas"]["ResolvedEndpoints"];
+            /**
+             * Format: uuid
+             * @description UID of the instance that answered (see `GET /admin/instances`)
+             */
+            id?: string;
+        };
+        /** @description Standard error response */
+        Error: {
+            /**
+             * @description Machine-readable error code
+             * @enum {string}
+             */
+            code: "INTERNAL_ERROR" | "VALIDATION_ERROR" | "NOT_FOUND" | "UNAUTHORIZED" | "FORBIDDEN" | "INVALID_CREDENTIALS" | "PASSWORD_CHANGE_REQUIRED" | "WEAK_PASSWORD" | "RATE_LIMITED" | "DUPLICATE_NAME" | "TARGET_MATCHES_SELF" | "GRANT_EXPIRED" | "QUOTA_EXCEEDED" | "DRAINING";
+            /** @description Human-readable error message */
+            message: string;
+            /** @description Additional context */
+            detail?: string;
+            /** @description Seconds until retry (rate-limited responses only) */
+            retry_after?: number;
+        };
+        MessageResponse: {
+            /** @description Success message */
+            message: string;
+        };
+    };
+    responses: {
+        /** @description Invalid request */
+        BadRequest: {
+            headers: {
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+        /** @description Authentication required or invalid credentials */
+        Unauthorized: {
+            headers: {
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+        /** @description Too many failed authentication attempts */
+        AuthRateLimited: {
+            headers: {
+                /** @description Seconds until retry is allowed */
+                "Retry-After"?: number;
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+        /** @description Insufficient permissions or password change required */
+        Forbidden: {
+            headers: {
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+        /** @description User must change their initial password before accessing the API */
+        PasswordChangeRequired: {
+            headers: {
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+        /** @description Resource not found */
+        NotFound: {
+            headers: {
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+        /** @description Conflict with the current state (e.g. a resource with that name already exists) */
+        Conflict: {
+            headers: {
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+        /** @description Rate limit exceeded */
+        RateLimited: {
+            headers: {
+                /** @description Seconds until retry is allowed */
+                "Retry-After"?: number;
+                /** @description Maximum requests per minute */
+                "X-RateLimit-Limit"?: number;
+                /** @description Remaining requests in current window */
+                "X-RateLimit-Remaining"?: number;
+                /** @description Unix timestamp when limit resets */
+                "X-RateLimit-Reset"?: number;
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+        /** @description Internal server error */
+        InternalError: {
+            headers: {
+                [name: string]: unknown;
+            };
+            content: {
+                "application/json": components["schemas"]["Error"];
+            };
+        };
+    };
+    parameters: {
+        /** @description User UID */
+        UserUID: string;
+        /** @description Database UID */
+        DatabaseUID: string;
+        /** @description Grant UID */
+        GrantUID: string;
+        /** @description Connection UID */
+        ConnectionUID: string;
+        /** @description Query UID */
+        QueryUID: string;
+        /** @description Maximum number of results to return */
+        Limit: number;
+        /** @description Maximum number of results to return; every result when omitted */
+        PageLimit: number;
+        /** @description Number of results to skip for pagination */
+        Offset: number;
+    };
+    requestBodies: never;
+    headers: {
+        /** @description Number of results matching the filters, across all pages (same as `total`) */
+        TotalCount: number;
+        /**
+         * @description RFC 8288 links to the `first`, `prev`, `next` and `last` pages, when
+         *     the request sets a limit (or the list has a default one). They keep the
+         *     other query parameters.
+         * @example </api/v1/users?limit=50&offset=50>; rel="next"
+         */
+        PageLinks: string;
+    };
+    pathItems: never;
+}
+export type VersionInfo = components['schemas']['VersionInfo'];
+export type LoginRequest = components['schemas']['LoginRequest'];
+export type LoginResponse = components['schemas']['LoginResponse'];
+export type AuthUser = components['schemas']['AuthUser'];
+export type MeResponse = components['schemas']['MeResponse'];
+export type SessionInfo = components['schemas']['SessionInfo'];
+export type ChangePasswordRequest = components['schemas']['ChangePasswordRequest'];
+export type PreLoginPasswordChangeRequest = components['schemas']['PreLoginPasswordChangeRequest'];
+export type ResetPasswordRequest = components['schemas']['ResetPasswordRequest'];
+export type User = components['schemas']['User'];
+export type CreateUserRequest = components['schemas']['CreateUserRequest'];
+export type UserGroup = components['schemas']['UserGroup'];
+export type CreateUserGroupRequest = components['schemas']['CreateUserGroupRequest'];
+export type UpdateUserRequest = components['schemas']['UpdateUserRequest'];
+export type Database = components['schemas']['Database'];
+export type ConnectionTestResult = components['schemas']['ConnectionTestResult'];
+export type DatabaseLimited = components['schemas']['DatabaseLimited'];
+export type CreateDatabaseRequest = components['schemas']['CreateDatabaseRequest'];
+export type UpdateDatabaseRequest = components['schemas']['UpdateDatabaseRequest'];
+export type GrantControl = components['schemas']['GrantControl'];
+export type StatementApproval = components['schemas']['StatementApproval'];
+export type GrantRequest = components['schemas']['GrantRequest'];
+export type CreateGrantRequestPayload = components['schemas']['CreateGrantRequestPayload'];
+export type GrantDefinition = components['schemas']['GrantDefinition'];
+export type CreateGrantDefinitionRequest = components['schemas']['CreateGrantDefinitionRequest'];
+export type AccessGrant = components['schemas']['AccessGrant'];
+export type CreateGrantRequest = components['schemas']['CreateGrantRequest'];
+export type ApiKey = components['schemas']['APIKey'];
+export type CreateApiKeyRequest = components['schemas']['CreateAPIKeyRequest'];
+export type CreateApiKeyResponse = components['schemas']['CreateAPIKeyResponse'];
+export type DeviceAuthorizationRequest = components['schemas']['DeviceAuthorizationRequest'];
+export type DeviceAuthorizationResponse = components['schemas']['DeviceAuthorizationResponse'];
+export type DeviceTokenRequest = components['schemas']['DeviceTokenRequest'];
+export type DeviceTokenResponse = components['schemas']['DeviceTokenResponse'];
+export type OAuthError = components['schemas']['OAuthError'];
+export type DeviceConsentInfo = components['schemas']['DeviceConsentInfo'];
+export type DeviceConsentRequest = components['schemas']['DeviceConsentRequest'];
+export type Connection = components['schemas']['Connection'];
+export type Query = components['schemas']['Query'];
+export type QueryParameters = components['schemas']['QueryParameters'];
+export type QueryWithRows = components['schemas']['QueryWithRows'];
+export type QueryRow = components['schemas']['QueryRow'];
+export type QueryRowsResponse = components['schemas']['QueryRowsResponse'];
+export type AuditEvent = components['schemas']['AuditEvent'];
+export type ConnectionInfo = components['schemas']['ConnectionInfo'];
+export type GlobalParameter = components['schemas']['GlobalParameter'];
+export type SetParameterRequest = components['schemas']['SetParameterRequest'];
+export type PublicEndpoints = components['schemas']['PublicEndpoints'];
+export type ResolvedEndpoints = components['schemas']['ResolvedEndpoints'];
+export type InstanceInfo = components['schemas']['InstanceInfo'];
+export type Error = components['schemas']['Error'];
+export type MessageResponse = components['schemas']['MessageResponse'];
+export type ResponseBadRequest = components['responses']['BadRequest'];
+export type ResponseUnauthorized = components['responses']['Unauthorized'];
+export type ResponseAuthRateLimited = components['responses']['AuthRateLimited'];
+export type ResponseForbidden = components['responses']['Forbidden'];
+export type ResponsePasswordChangeRequired = components['responses']['PasswordChangeRequired'];
+export type ResponseNotFound = components['responses']['NotFound'];
+export type ResponseConflict = components['responses']['Conflict'];
+export type ResponseRateLimited = components['responses']['RateLimited'];
+export type ResponseInternalError = components['responses']['InternalError'];
+export type ParameterUserUid = components['parameters']['UserUID'];
+export type ParameterDatabaseUid = components['parameters']['DatabaseUID'];
+export type ParameterGrantUid = components['parameters']['GrantUID'];
+export type ParameterConnectionUid = components['parameters']['ConnectionUID'];
+export type ParameterQueryUid = components['parameters']['QueryUID'];
+export type ParameterLimit = components['parameters']['Limit'];
+export type ParameterOffset = components['parameters']['Offset'];
+export type $defs = Record<string, never>;
+export interface operations {
+    getHealth: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Service is healthy */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": {
+                        /** @example healthy */
+                        status?: string;
+                    };
+                };
+            };
+            /** @description Service is unhealthy */
+            503: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+        };
+    };
+    getVersion: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Version information */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["VersionInfo"];
+                };
+            };
+        };
+    };
+    login: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["LoginRequest"];
+            };
+        };
+        responses: {
+            /** @description Login successful */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["LoginResponse"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            /** @description Invalid credentials */
+            401: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            /** @description Password change required */
+            403: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            429: components["responses"]["AuthRateLimited"];
+        };
+    };
+    logout: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Logout successful */
+            204: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
+            401: components["responses"]["Unauthorized"];
+        };
+    };
+    getCurrentUser: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Current user and session info */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["MeResponse"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+        };
+    };
+    changePasswordPreLogin: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["PreLoginPasswordChangeRequest"];
+            };
+        };
+        responses: {
+            /** @description Password changed successfully */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["MessageResponse"];
+                };
+            };
+            /** @description Weak password */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": {
+                        /** @enum {string} */
+                        error: "weak_password";
+                        message: string;
+                    };
+                };
+            };
+            /** @description Invalid credentials */
+            401: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            429: components["responses"]["AuthRateLimited"];
+        };
+    };
+    listAuthProviders: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description List of auth providers */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": {
+                        providers?: {
+                            /** @enum {string} */
+                            type?: "password" | "slack" | "oidc";
+                            /** @description For password, false when local_login is disabled */
+                            enabled?: boolean;
+                            /** @description URL to initiate OAuth flow (only for OAuth providers) */
+                            authorize_url?: string;
+                            /** @description Sign-in button label (only for oidc) */
+                            display_name?: string;
+                        }[];
+                    };
+                };
+            };
+        };
+    };
+    initiateSlackAuth: {
+        parameters: {
+            query?: {
+                /** @description URL to redirect to after successful login */
+                redirect?: string;
+            };
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Redirect to Slack authorization */
+            302: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
+        };
+    };
+    slackAuthCallback: {
+        parameters: {
+            query?: {
+                code?: string;
+                state?: string;
+            };
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        CreateAPIKeyRequest: {
-            /** @description Key name */
-            name: string;
-            /**
-             * Format: date-time
-             * @description Optional expiration time
-             */
-            expires_at?: string;
+        requestBody?: never;
+        responses: {
+            /** @description Redirect to app with session token */
+            302: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
         };
-        CreateAPIKeyResponse: {
-            /**
-             * Format: uuid
-             * @description Unique identifier
-             */
-            id: string;
-            /** @description Key name */
-            name: string;
-            /** @description Full API key (only shown once!) */
-            key: string;
-            /** @description Key prefix for identification */
-            key_prefix: string;
-            /**
-             * Format: date-time
-             * @description When the key expires
-             */
-            expires_at?: string | null;
-            /**
-             * Format: date-time
-             * @description Creation timestamp
-             */
-            created_at: string;
-            /** @description Connection URLs for databases the key's owner has active grants on */
-            connections: components["schemas"]["ConnectionInfo"][];
-            /** @description True when there are more than 50 connections (list is truncated) */
-            connections_truncated: boolean;
+    };
+    initiateOIDCAuth: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        DeviceAuthorizationRequest: {
-            /** @description Human-readable label for the requesting app (e.g. "stn on host"), shown on the consent page. dbbat extension. */
-            client_name?: string;
-            /** @description Accepted for OAuth compatibility but ignored. */
-            client_id?: string;
+        requestBody?: never;
+        responses: {
+            /** @description Redirect to the identity provider, or to the login page with error=OAUTH_PROVIDER_ERROR when discovery fails */
+            302: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
         };
-        DeviceAuthorizationResponse: {
-            /** @description Secret code the client polls the token endpoint with. Never display or log this value. */
-            device_code: string;
-            /** @description Short human-checkable code the user enters/verifies in the browser (e.g. "WDJP-4KXR") */
-            user_code: string;
-            /** @description Browser URL the user opens and enters the user_code at */
-            verification_uri: string;
-            /** @description verification_uri with the user_code pre-filled — open this directly */
-            verification_uri_complete: string;
-            /** @description Seconds until the request expires */
-            expires_in: number;
-            /** @description Minimum seconds to wait between polls */
-            interval: number;
+    };
+    oidcAuthCallback: {
+        parameters: {
+            query?: {
+                code?: string;
+                state?: string;
+            };
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        DeviceTokenRequest: {
-            /**
-             * @description Must be "urn:ietf:params:oauth:grant-type:device_code"
-             * @enum {string}
-             */
-            grant_type: "urn:ietf:params:oauth:grant-type:device_code";
-            device_code: string;
-            /** @description Accepted for OAuth compatibility but ignored. */
-            client_id?: string;
+        requestBody?: never;
+        responses: {
+            /** @description Redirect to app with session token */
+            302: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
         };
-        DeviceTokenResponse: {
-            /** @description The minted API key (dbb_...), delivered exactly once */
-            access_token: string;
-            /** @enum {string} */
-            token_type: "Bearer";
+    };
+    deviceAuthorization: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        OAuthError: {
-            /** @description OAuth error code */
-            error: string;
-            error_description?: string;
+        requestBody?: {
+            content: {
+                "application/json": components["schemas"]["DeviceAuthorizationRequest"];
+            };
         };
-        DeviceConsentInfo: {
-            client_name: string;
-            user_code: string;
-            /** @enum {string} */
-            status: "pending" | "approved" | "denied";
-            /** Format: date-time */
-            expires_at: string;
+        responses: {
+            /** @description Device authorization request created */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["DeviceAuthorizationResponse"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        DeviceConsentRequest: {
-            user_code: string;
-            approve: boolean;
+    };
+    deviceToken: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        Connection: {
-            /**
-             * Format: uuid
-             * @description Unique identifier
-             */
-            uid: string;
-            /**
-             * Format: uuid
-             * @description User UID
-             */
-            user_id: string;
-            /**
-             * Format: uuid
-             * @description Database UID
-             */
-            database_id: string;
-            /** @description Client IP address */
-            source_ip: string;
-            /**
-             * Format: date-time
-             * @description Connection start time
-             */
-            connected_at: string;
-            /**
-             * Format: date-time
-             * @description Last activity timestamp
-             */
-            last_activity_at: string;
-            /**
-             * Format: date-time
-             * @description Connection end time (null if still connected)
-             */
-            disconnected_at?: string | null;
-            /**
-             * Format: int64
-             * @description Number of queries executed
-             */
-            queries: number;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["DeviceTokenRequest"];
+            };
+        };
+        responses: {
+            /** @description Authorization approved; access token issued */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["DeviceTokenResponse"];
+                };
+            };
             /**
-             * Format: int64
-             * @description Total bytes transferred
+             * @description Pending or errored, as an OAuth error object. `error` is one of
+             *     `authorization_pending`, `access_denied`, `expired_token`,
+             *     `unsupported_grant_type`, `invalid_request`.
              */
-            bytes_transferred: number;
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["OAuthError"];
+                };
+            };
+            500: components["responses"]["InternalError"];
+        };
+    };
+    getDeviceConsent: {
+        parameters: {
+            query: {
+                /** @description The user code shown to the user (case-insensitive, dashes optional) */
+                user_code: string;
+            };
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Device authorization consent details */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["DeviceConsentInfo"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            404: components["responses"]["NotFound"];
+        };
+    };
+    deviceConsent: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        Query: {
-            /**
-             * Format: uuid
-             * @description Unique identifier
-             */
-            uid: string;
-            /**
-             * Format: uuid
-             * @description Connection UID
-             */
-            connection_id: string;
-            /**
-             * Format: uuid
-             * @description UID of the user who ran the query, resolved from the connection. Only populated when listing queries.
-             */
-            user_id?: string | null;
-            /**
-             * Format: uuid
-             * @description UID of the target database, resolved from the connection. Only populated when listing queries.
-             */
-            database_id?: string | null;
-            /** @description SQL query text */
-            sql_text: string;
-            parameters?: components["schemas"]["QueryParameters"];
-            /**
-             * Format: date-time
-             * @description Execution timestamp
-             */
-            executed_at: string;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["DeviceConsentRequest"];
+            };
+        };
+        responses: {
+            /** @description Response recorded */
+            204: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            409: components["responses"]["Conflict"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    slackInteractions: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/x-www-form-urlencoded": {
+                    /** @description URL-encoded JSON interaction callback from Slack. */
+                    payload: string;
+                };
+            };
+        };
+        responses: {
             /**
-             * Format: double
-             * @description Execution duration in milliseconds
+             * @description Acknowledged. Returned for valid interactions (processed
+             *     asynchronously) and for ignored ones (unknown action, non-UUID
+             *     value) alike — an empty body so Slack shows the user nothing.
              */
-            duration_ms?: number | null;
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
             /**
-             * Format: int64
-             * @description Number of rows affected
+             * @description Signature verification failed — missing/invalid signature,
+             *     stale timestamp (>5 min), or oversized body. No body detail.
              */
-            rows_affected?: number | null;
-            /** @description Error message if query failed */
-            error?: string | null;
+            401: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
         };
-        /** @description Query parameter values (for prepared statements) */
-        QueryParameters: {
-            /** @description Decoded string representations */
-            values?: string[];
-            /** @description Base64-encoded raw bytes */
-            raw?: string[];
-            /** @description Format codes (0=text, 1=binary) */
-            format_codes?: number[];
-            /** @description PostgreSQL type OIDs */
-            type_oids?: number[];
+    };
+    listUsers: {
+        parameters: {
+            query?: {
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
+            header?: never;
+            path?: never;
+            cookie?: never;
         };
-        QueryWithRows: components["schemas"]["Query"] & {
-            /** @description Result rows (from SELECT or COPY operations) */
-            rows?: components["schemas"]["QueryRow"][];
+        requestBody?: never;
+        responses: {
+            /** @description List of users */
+            200: {
+                headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": {
+                        total?: components["schemas"]["ListTotal"];
+                        users?: components["schemas"]["User"][];
+                    };
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        QueryRow: {
-            /** @description Row index */
-            row_number: number;
-            /** @description Row data as JSON object */
-            row_data: {
-                [key: string]: unknown;
+    };
+    createUser: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateUserRequest"];
             };
-            /**
-             * Format: int64
-             * @description Row size in bytes
-             */
-            row_size_bytes: number;
         };
-        /** @description Paginated query rows response */
-        QueryRowsResponse: {
-            /** @description Array of row objects */
-            rows: components["schemas"]["QueryRow"][];
-            /** @description Cursor for the next page (null if no more rows) */
-            next_cursor?: string | null;
-            /** @description Whether more rows are available */
-            has_more: boolean;
-            /**
-             * Format: int64
-             * @description Total number of rows for this query
-             */
-            total_rows: number;
+        responses: {
+            /** @description User created successfully */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["User"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            409: components["responses"]["Conflict"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        AuditEvent: {
-            /**
-             * Format: uuid
-             * @description Unique identifier
-             */
-            uid: string;
-            /** @description Event type (e.g., user.created, grant.revoked) */
-            event_type: string;
-            /**
-             * Format: uuid
-             * @description User being acted upon
-             */
-            user_id?: string | null;
-            /**
-             * Format: uuid
-             * @description User performing the action
-             */
-            performed_by?: string | null;
-            /** @description Event-specific details */
-            details?: {
-                [key: string]: unknown;
+    };
+    getUser: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description User details, with the groups the user belongs to */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["User"] & {
+                        groups: components["schemas"]["UserGroup"][];
+                    };
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
+        };
+    };
+    updateUser: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
+            };
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["UpdateUserRequest"];
+            };
+        };
+        responses: {
+            /** @description User updated successfully */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["MessageResponse"];
+                };
             };
-            /**
-             * Format: date-time
-             * @description Event timestamp
-             */
-            created_at: string;
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            /** @description The update would remove the admin role from the last admin user */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        /** @description Ready-to-paste connection URL for a database */
-        ConnectionInfo: {
-            /** Format: uuid */
-            database_uid: string;
-            database_name: string;
-            protocol: string;
-            /**
-             * @description "uri" or "ez-connect"
-             * @enum {string}
-             */
-            format: "uri" | "ez-connect";
-            /** @description Ready-to-paste connection URL */
-            url: string;
+    };
+    deleteUser: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
+            };
+            cookie?: never;
         };
-        /** @description A runtime-editable key-value configuration entry */
-        GlobalParameter: {
-            /** Format: uuid */
-            uid: string;
-            group_key: string;
-            key: string;
-            value: string;
-            /** Format: date-time */
-            created_at: string;
-            /** Format: date-time */
-            updated_at: string;
+        requestBody?: never;
+        responses: {
+            /** @description User deleted successfully */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["MessageResponse"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            /** @description Deleting this user would leave the instance without any admin */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        SetParameterRequest: {
-            value: string;
+    };
+    changePassword: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
+            };
+            cookie?: never;
         };
-        /** @description Public endpoint advertisement settings. Covers two independent network paths: the *connection* host (host/pg_host/ora_host/ mysql_host/*_port — where SQL clients reach the PG/Oracle/MySQL proxies, via direct or TCP-load-balancer access) and the *Web UI* host (web_ui_url — where the browser and REST API are reached, behind an HTTP ingress / reverse proxy). These are typically two different DNS names on two different network paths. */
-        PublicEndpoints: {
-            /** @description Default public hostname for all protocols (the connection host, e.g. db.company.com) */
-            host?: string;
-            /** @description PostgreSQL-specific host override (empty = use host) */
-            pg_host?: string;
-            /** @description Oracle-specific host override (empty = use host) */
-            ora_host?: string;
-            /** @description MySQL-specific host override (empty = use host) */
-            mysql_host?: string;
-            /** @description MongoDB-specific host override (empty = use host) */
-            mongo_host?: string;
-            /** @description PostgreSQL port override (null = use local listen port) */
-            pg_port?: number | null;
-            /** @description Oracle port override (null = use local listen port) */
-            ora_port?: number | null;
-            /** @description MySQL port override (null = use local listen port) */
-            mysql_port?: number | null;
-            /** @description MongoDB port override (null = use local listen port) */
-            mongo_port?: number | null;
-            /** @description Web UI / public base URL override (e.g. https://dbbat.company.com), reached through an HTTP ingress / reverse proxy. Empty = fall back to the DBB_PUBLIC_URL environment variable. Used for Slack deep-links and other absolute-URL generation. Independent of `host`, which advertises the connection host instead. */
-            web_ui_url?: string;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["ChangePasswordRequest"];
+            };
         };
-        /** @description Fully resolved connection advertisement values after fallback chains */
-        ResolvedEndpoints: {
-            pg_host: string;
-            /** @description 0 = protocol disabled */
-            pg_port: number;
-            ora_host: string;
-            ora_port: number;
-            mysql_host: string;
-            mysql_port: number;
-            mongo_host: string;
-            mongo_port: number;
-            /** @description Effective Web UI / public base URL (web_ui_url parameter, falling back to DBB_PUBLIC_URL) */
-            web_ui_url: string;
+        responses: {
+            /** @description Password changed successfully */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["MessageResponse"];
+                };
+            };
+            /** @description Weak password */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": {
+                        /** @enum {string} */
+                        error: "weak_password";
+                        message: string;
+                    };
+                };
+            };
+            /** @description Invalid credentials */
+            401: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            /** @description Cannot change another user's password (non-admin) */
+            403: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["AuthRateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        /** @description Instance information including listen addresses and public endpoint config */
-        InstanceInfo: {
-            /** @description Live listen addresses this process is bound to, straight from config (DBB_LISTEN_*). `api` is the HTTP listener (REST API + Web UI, meant for an HTTP reverse proxy / ingress); `pg`, `ora`, and `mysql` are TCP listeners (SQL client proxies, meant for a TCP load balancer). */
-            listen: {
-                /** @description PostgreSQL proxy TCP listen address */
-                pg: string;
-                /** @description Oracle proxy TCP listen address */
-                ora: string;
-                /** @description MySQL/MariaDB proxy TCP listen address */
-                mysql: string;
-                /** @description MongoDB proxy TCP listen address */
-                mongo?: string;
-                /** @description REST API / Web UI HTTP listen address */
-                api: string;
+    };
+    resetPassword: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
             };
-            /** @description Only present for admin callers */
-            public?: components["schemas"]["PublicEndpoints"];
-            resolved: components["schemas"]["ResolvedEndpoints"];
+            cookie?: never;
         };
-        /** @description Standard error response */
-        Error: {
-            /**
-             * @description Machine-readable error code
-             * @enum {string}
-             */
-            code: "INTERNAL_ERROR" | "VALIDATION_ERROR" | "NOT_FOUND" | "UNAUTHORIZED" | "FORBIDDEN" | "INVALID_CREDENTIALS" | "PASSWORD_CHANGE_REQUIRED" | "WEAK_PASSWORD" | "RATE_LIMITED" | "DUPLICATE_NAME" | "TARGET_MATCHES_SELF" | "GRANT_EXPIRED" | "QUOTA_EXCEEDED";
-            /** @description Human-readable error message */
-            message: string;
-            /** @description Additional context */
-            detail?: string;
-            /** @description Seconds until retry (rate-limited responses only) */
-            retry_after?: number;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["ResetPasswordRequest"];
+            };
         };
-        MessageResponse: {
-            /** @description Success message */
-            message: string;
+        responses: {
+            /** @description Password reset successfully */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["MessageResponse"];
+                };
+            };
+            /** @description Invalid request or weak password */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"] | {
+                        /** @enum {string} */
+                        error: "weak_password";
+                        message: string;
+                    };
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            /** @description Not authorized (non-admin or API key authentication) */
+            403: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    responses: {
-        /** @description Invalid request */
-        BadRequest: {
-            headers: {
-                [name: string]: unknown;
-            };
-            content: {
-                "application/json": components["schemas"]["Error"];
+    getUserRateLimit: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
             };
+            cookie?: never;
         };
-        /** @description Authentication required or invalid credentials */
-        Unauthorized: {
-            headers: {
-                [name: string]: unknown;
-            };
-            content: {
-                "application/json": components["schemas"]["Error"];
+        requestBody?: never;
+        responses: {
+            /** @description Rate limit of the user */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["UserRateLimit"];
+                };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        /** @description Too many failed authentication attempts */
-        AuthRateLimited: {
-            headers: {
-                /** @description Seconds until retry is allowed */
-                "Retry-After"?: number;
-                [name: string]: unknown;
-            };
-            content: {
-                "application/json": components["schemas"]["Error"];
+    };
+    setUserRateLimit: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
             };
+            cookie?: never;
         };
-        /** @description Insufficient permissions or password change required */
-        Forbidden: {
-            headers: {
-                [name: string]: unknown;
-            };
+        requestBody: {
             content: {
-                "application/json": components["schemas"]["Error"];
+                "application/json": components["schemas"]["SetRateLimitRequest"];
             };
         };
-        /** @description User must change their initial password before accessing the API */
-        PasswordChangeRequired: {
-            headers: {
-                [name: string]: unknown;
-            };
-            content: {
-                "application/json": components["schemas"]["Error"];
+        responses: {
+            /** @description Rate limit of the user */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["UserRateLimit"];
+                };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        /** @description Resource not found */
-        NotFound: {
-            headers: {
-                [name: string]: unknown;
-            };
-            content: {
-                "application/json": components["schemas"]["Error"];
+    };
+    deleteUserRateLimit: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description User UID */
+                uid: components["parameters"]["UserUID"];
             };
+            cookie?: never;
         };
-        /** @description Conflict with the current state (e.g. a resource with that name already exists) */
-        Conflict: {
-            headers: {
-                [name: string]: unknown;
-            };
-            content: {
-                "application/json": components["schemas"]["Error"];
+        requestBody?: never;
+        responses: {
+            /** @description Rate limit of the user */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["UserRateLimit"];
+                };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        /** @description Rate limit exceeded */
-        RateLimited: {
-            headers: {
-                /** @description Seconds until retry is allowed */
-                "Retry-After"?: number;
-                /** @description Maximum requests per minute */
-                "X-RateLimit-Limit"?: number;
-                /** @description Remaining requests in current window */
-                "X-RateLimit-Remaining"?: number;
-                /** @description Unix timestamp when limit resets */
-                "X-RateLimit-Reset"?: number;
-                [name: string]: unknown;
-            };
-            content: {
-                "application/json": components["schemas"]["Error"];
+    };
+    getRateLimits: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Rate limits */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["RateLimits"];
+                };
             };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
-        /** @description Internal server error */
-        InternalError: {
-            headers: {
-                [name: string]: unknown;
+    };
+    setRoleRateLimit: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Role */
+                role: "admin" | "viewer" | "auditor" | "connector";
             };
+            cookie?: never;
+        };
+        requestBody: {
             content: {
-                "application/json": components["schemas"]["Error"];
+                "application/json": components["schemas"]["SetRateLimitRequest"];
             };
         };
-    };
-    parameters: {
-        /** @description User UID */
-        UserUID: string;
-        /** @description Database UID */
-        DatabaseUID: string;
-        /** @description Grant UID */
-        GrantUID: string;
-        /** @description Connection UID */
-        ConnectionUID: string;
-        /** @description Query UID */
-        QueryUID: string;
-        /** @description Maximum number of results to return */
-        Limit: number;
-        /** @description Number of results to skip for pagination */
-        Offset: number;
-    };
-    requestBodies: never;
-    headers: never;
-    pathItems: never;
-}
-export type VersionInfo = components['schemas']['VersionInfo'];
-export type LoginRequest = components['schemas']['LoginRequest'];
-export type LoginResponse = components['schemas']['LoginResponse'];
-export type AuthUser = components['schemas']['AuthUser'];
-export type MeResponse = components['schemas']['MeResponse'];
-export type SessionInfo = components['schemas']['SessionInfo'];
-export type ChangePasswordRequest = components['schemas']['ChangePasswordRequest'];
-export type PreLoginPasswordChangeRequest = components['schemas']['PreLoginPasswordChangeRequest'];
-export type ResetPasswordRequest = components['schemas']['ResetPasswordRequest'];
-export type User = components['schemas']['User'];
-export type CreateUserRequest = components['schemas']['CreateUserRequest'];
-export type UserGroup = components['schemas']['UserGroup'];
-export type CreateUserGroupRequest = components['schemas']['CreateUserGroupRequest'];
-export type UpdateUserRequest = components['schemas']['UpdateUserRequest'];
-export type Database = components['schemas']['Database'];
-export type ConnectionTestResult = components['schemas']['ConnectionTestResult'];
-export type DatabaseLimited = components['schemas']['DatabaseLimited'];
-export type CreateDatabaseRequest = components['schemas']['CreateDatabaseRequest'];
-export type UpdateDatabaseRequest = components['schemas']['UpdateDatabaseRequest'];
-export type GrantControl = components['schemas']['GrantControl'];
-export type GrantRequest = components['schemas']['GrantRequest'];
-export type CreateGrantRequestPayload = components['schemas']['CreateGrantRequestPayload'];
-export type GrantDefinition = components['schemas']['GrantDefinition'];
-export type CreateGrantDefinitionRequest = components['schemas']['CreateGrantDefinitionRequest'];
-export type AccessGrant = components['schemas']['AccessGrant'];
-export type CreateGrantRequest = components['schemas']['CreateGrantRequest'];
-export type ApiKey = components['schemas']['APIKey'];
-export type CreateApiKeyRequest = components['schemas']['CreateAPIKeyRequest'];
-export type CreateApiKeyResponse = components['schemas']['CreateAPIKeyResponse'];
-export type DeviceAuthorizationRequest = components['schemas']['DeviceAuthorizationRequest'];
-export type DeviceAuthorizationResponse = components['schemas']['DeviceAuthorizationResponse'];
-export type DeviceTokenRequest = components['schemas']['DeviceTokenRequest'];
-export type DeviceTokenResponse = components['schemas']['DeviceTokenResponse'];
-export type OAuthError = components['schemas']['OAuthError'];
-export type DeviceConsentInfo = components['schemas']['DeviceConsentInfo'];
-export type DeviceConsentRequest = components['schemas']['DeviceConsentRequest'];
-export type Connection = components['schemas']['Connection'];
-export type Query = components['schemas']['Query'];
-export type QueryParameters = components['schemas']['QueryParameters'];
-export type QueryWithRows = components['schemas']['QueryWithRows'];
-export type QueryRow = components['schemas']['QueryRow'];
-export type QueryRowsResponse = components['schemas']['QueryRowsResponse'];
-export type AuditEvent = components['schemas']['AuditEvent'];
-export type ConnectionInfo = components['schemas']['ConnectionInfo'];
-export type GlobalParameter = components['schemas']['GlobalParameter'];
-export type SetParameterRequest = components['schemas']['SetParameterRequest'];
-export type PublicEndpoints = components['schemas']['PublicEndpoints'];
-export type ResolvedEndpoints = components['schemas']['ResolvedEndpoints'];
-export type InstanceInfo = components['schemas']['InstanceInfo'];
-export type Error = components['schemas']['Error'];
-export type MessageResponse = components['schemas']['MessageResponse'];
-export type ResponseBadRequest = components['responses']['BadRequest'];
-export type ResponseUnauthorized = components['responses']['Unauthorized'];
-export type ResponseAuthRateLimited = components['responses']['AuthRateLimited'];
-export type ResponseForbidden = components['responses']['Forbidden'];
-export type ResponsePasswordChangeRequired = components['responses']['PasswordChangeRequired'];
-export type ResponseNotFound = components['responses']['NotFound'];
-export type ResponseConflict = components['responses']['Conflict'];
-export type ResponseRateLimited = components['responses']['RateLimited'];
-export type ResponseInternalError = components['responses']['InternalError'];
-export type ParameterUserUid = components['parameters']['UserUID'];
-export type ParameterDatabaseUid = components['parameters']['DatabaseUID'];
-export type ParameterGrantUid = components['parameters']['GrantUID'];
-export type ParameterConnectionUid = components['parameters']['ConnectionUID'];
-export type ParameterQueryUid = components['parameters']['QueryUID'];
-export type ParameterLimit = components['parameters']['Limit'];
-export type ParameterOffset = components['parameters']['Offset'];
-export type $defs = Record<string, never>;
-export interface operations {
-    getHealth: {
+        responses: {
+            /** @description Rate limits */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["RateLimits"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    deleteRoleRateLimit: {
         parameters: {
             query?: never;
             header?: never;
+            path: {
+                /** @description Role */
+                role: "admin" | "viewer" | "auditor" | "connector";
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Override removed */
+            204: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    listDatabases: {
+        parameters: {
+            query?: {
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
+            header?: never;
             path?: never;
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Service is healthy */
+            /** @description List of databases */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        /** @example healthy */
-                        status?: string;
+                        total?: components["schemas"]["ListTotal"];
+                        databases?: (components["schemas"]["Database"] | components["schemas"]["DatabaseLimited"])[];
                     };
                 };
             };
-            /** @description Service is unhealthy */
-            503: {
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    createDatabase: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateDatabaseRequest"];
+            };
+        };
+        responses: {
+            /** @description Database created successfully */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Error"];
+                    "application/json": components["schemas"]["Database"];
                 };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            409: components["responses"]["Conflict"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    getVersion: {
+    getDatabase: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Database UID */
+                uid: components["parameters"]["DatabaseUID"];
+            };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Version information */
+            /** @description Database details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["VersionInfo"];
+                    "application/json": components["schemas"]["Database"] | components["schemas"]["DatabaseLimited"];
                 };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
         };
     };
-    login: {
+    updateDatabase: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Database UID */
+                uid: components["parameters"]["DatabaseUID"];
+            };
             cookie?: never;
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["LoginRequest"];
+                "application/json": components["schemas"]["UpdateDatabaseRequest"];
             };
         };
         responses: {
-            /** @description Login successful */
+            /** @description Database updated successfully */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["LoginResponse"];
+                    "application/json": components["schemas"]["MessageResponse"];
                 };
             };
             400: components["responses"]["BadRequest"];
-            /** @description Invalid credentials */
-            401: {
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    deleteDatabase: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Database UID */
+                uid: components["parameters"]["DatabaseUID"];
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Database deleted successfully */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Error"];
+                    "application/json": components["schemas"]["MessageResponse"];
                 };
             };
-            /** @description Password change required */
-            403: {
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    listGrants: {
+        parameters: {
+            query?: {
+                /** @description Filter by user UID */
+                user_id?: string;
+                /** @description Filter by database UID */
+                database_id?: string;
+                /** @description Only return active (non-revoked, within time window) grants */
+                active_only?: boolean;
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description List of grants */
+            200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Error"];
+                    "application/json": {
+                        total?: components["schemas"]["ListTotal"];
+                        grants?: components["schemas"]["AccessGrant"][];
+                    };
                 };
             };
-            429: components["responses"]["AuthRateLimited"];
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    logout: {
+    createGrant: {
         parameters: {
             query?: never;
             header?: never;
             path?: never;
             cookie?: never;
         };
-        requestBody?: never;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateGrantRequest"];
+            };
+        };
         responses: {
-            /** @description Logout successful */
-            204: {
+            /** @description Grant created successfully */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content?: never;
+                content: {
+                    "application/json": components["schemas"]["AccessGrant"];
+                };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    getCurrentUser: {
+    getGrant: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
+            };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Current user and session info */
+            /** @description Grant details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MeResponse"];
+                    "application/json": components["schemas"]["AccessGrant"];
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
         };
     };
-    changePasswordPreLogin: {
+    revokeGrant: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
-            cookie?: never;
-        };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["PreLoginPasswordChangeRequest"];
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
             };
+            cookie?: never;
         };
+        requestBody?: never;
         responses: {
-            /** @description Password changed successfully */
+            /** @description Grant revoked successfully */
             200: {
                 headers: {
                     [name: string]: unknown;
@@ -2580,285 +5124,381 @@ export interface operations {
                     "application/json": components["schemas"]["MessageResponse"];
                 };
             };
-            /** @description Weak password */
-            400: {
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    createGrantTemplate: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateGrantTemplateRequest"];
+            };
+        };
+        responses: {
+            /** @description Template created */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": {
-                        /** @enum {string} */
-                        error: "weak_password";
-                        message: string;
-                    };
+                    "application/json": components["schemas"]["GrantTemplate"];
                 };
             };
-            /** @description Invalid credentials */
-            401: {
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            409: components["responses"]["Conflict"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    listGrantTemplates: {
+        parameters: {
+            query?: {
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description List of grant templates, ordered by name */
+            200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Error"];
+                    "application/json": {
+                        total?: components["schemas"]["ListTotal"];
+                        grant_templates?: components["schemas"]["GrantTemplate"][];
+                    };
                 };
             };
-            429: components["responses"]["AuthRateLimited"];
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            500: components["responses"]["InternalError"];
         };
     };
-    listAuthProviders: {
+    getGrantTemplate: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                uid: string;
+            };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description List of auth providers */
+            /** @description Template details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": {
-                        providers?: {
-                            /** @enum {string} */
-                            type?: "password" | "slack";
-                            enabled?: boolean;
-                            /** @description URL to initiate OAuth flow (only for OAuth providers) */
-                            authorize_url?: string;
-                        }[];
-                    };
+                    "application/json": components["schemas"]["GrantTemplate"];
                 };
             };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
         };
     };
-    initiateSlackAuth: {
+    updateGrantTemplate: {
         parameters: {
-            query?: {
-                /** @description URL to redirect to after successful login */
-                redirect?: string;
-            };
+            query?: never;
             header?: never;
-            path?: never;
+            path: {
+                uid: string;
+            };
             cookie?: never;
         };
-        requestBody?: never;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateGrantTemplateRequest"];
+            };
+        };
         responses: {
-            /** @description Redirect to Slack authorization */
-            302: {
+            /** @description Template updated */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content?: never;
+                content: {
+                    "application/json": components["schemas"]["GrantTemplate"];
+                };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            409: components["responses"]["Conflict"];
         };
     };
-    slackAuthCallback: {
+    deleteGrantTemplate: {
         parameters: {
-            query?: {
-                code?: string;
-                state?: string;
-            };
+            query?: never;
             header?: never;
-            path?: never;
+            path: {
+                uid: string;
+            };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Redirect to app with session token */
-            302: {
+            /** @description Template deleted */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content?: never;
+                content: {
+                    "application/json": components["schemas"]["MessageResponse"];
+                };
             };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
         };
     };
-    deviceAuthorization: {
+    applyGrantTemplate: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                uid: string;
+            };
             cookie?: never;
         };
-        requestBody?: {
+        requestBody: {
             content: {
-                "application/json": components["schemas"]["DeviceAuthorizationRequest"];
+                "application/json": components["schemas"]["ApplyGrantTemplateRequest"];
             };
         };
         responses: {
-            /** @description Device authorization request created */
+            /** @description Grants created */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["DeviceAuthorizationResponse"];
+                    "application/json": {
+                        grants?: components["schemas"]["AccessGrant"][];
+                    };
                 };
             };
             400: components["responses"]["BadRequest"];
-            429: components["responses"]["RateLimited"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
             500: components["responses"]["InternalError"];
         };
     };
-    deviceToken: {
+    requestGrantExtension: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
+            };
             cookie?: never;
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["DeviceTokenRequest"];
+                "application/json": {
+                    /** Format: int64 */
+                    duration_seconds: number;
+                    justification?: string;
+                };
             };
         };
         responses: {
-            /** @description Authorization approved; access token issued */
+            /** @description Extension request submitted */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["DeviceTokenResponse"];
+                    "application/json": components["schemas"]["GrantRequest"];
                 };
             };
-            /**
-             * @description Pending or errored, as an OAuth error object. `error` is one of
-             *     `authorization_pending`, `access_denied`, `expired_token`,
-             *     `unsupported_grant_type`, `invalid_request`.
-             */
-            400: {
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            /** @description The grant belongs to another user */
+            403: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["OAuthError"];
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            404: components["responses"]["NotFound"];
+            /** @description The grant is revoked or expired, or already has a pending extension request */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
                 };
             };
-            500: components["responses"]["InternalError"];
         };
     };
-    getDeviceConsent: {
+    createProxyCredential: {
         parameters: {
-            query: {
-                /** @description The user code shown to the user (case-insensitive, dashes optional) */
-                user_code: string;
-            };
+            query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
+            };
             cookie?: never;
         };
-        requestBody?: never;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateProxyCredentialRequest"];
+            };
+        };
         responses: {
-            /** @description Device authorization consent details */
+            /** @description Proxy credential issued */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["DeviceConsentInfo"];
+                    "application/json": components["schemas"]["CreateProxyCredentialResponse"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
+            /** @description The grant is revoked or expired */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
         };
     };
-    deviceConsent: {
+    listProxyCredentials: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
-            cookie?: never;
-        };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["DeviceConsentRequest"];
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
             };
+            cookie?: never;
         };
+        requestBody?: never;
         responses: {
-            /** @description Response recorded */
-            204: {
+            /** @description Proxy credentials of the grant */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content?: never;
+                content: {
+                    "application/json": {
+                        proxy_credentials: components["schemas"]["ProxyCredential"][];
+                    };
+                };
             };
-            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            409: components["responses"]["Conflict"];
-            500: components["responses"]["InternalError"];
         };
     };
-    slackInteractions: {
+    revokeProxyCredential: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
-            cookie?: never;
-        };
-        requestBody: {
-            content: {
-                "application/x-www-form-urlencoded": {
-                    /** @description URL-encoded JSON interaction callback from Slack. */
-                    payload: string;
-                };
+            path: {
+                /** @description Grant UID */
+                uid: components["parameters"]["GrantUID"];
+                credential_uid: string;
             };
+            cookie?: never;
         };
+        requestBody?: never;
         responses: {
-            /**
-             * @description Acknowledged. Returned for valid interactions (processed
-             *     asynchronously) and for ignored ones (unknown action, non-UUID
-             *     value) alike — an empty body so Slack shows the user nothing.
-             */
+            /** @description Proxy credential revoked */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content?: never;
+                content: {
+                    "application/json": components["schemas"]["MessageResponse"];
+                };
             };
-            /**
-             * @description Signature verification failed — missing/invalid signature,
-             *     stale timestamp (>5 min), or oversized body. No body detail.
-             */
-            401: {
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            /** @description The credential was already used or revoked */
+            409: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content?: never;
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
             };
         };
     };
-    listUsers: {
+    listUserGroups: {
         parameters: {
-            query?: never;
+            query?: {
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
             header?: never;
             path?: never;
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description List of users */
+            /** @description List of user groups */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        users?: components["schemas"]["User"][];
+                        total?: components["schemas"]["ListTotal"];
+                        user_groups?: components["schemas"]["UserGroup"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            429: components["responses"]["RateLimited"];
+            403: components["responses"]["Forbidden"];
             500: components["responses"]["InternalError"];
         };
     };
-    createUser: {
+    createUserGroup: {
         parameters: {
             query?: never;
             header?: never;
@@ -2867,152 +5507,156 @@ export interface operations {
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["CreateUserRequest"];
+                "application/json": components["schemas"]["CreateUserGroupRequest"];
+            };
+        };
+        responses: {
+            /** @description Group created */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["UserGroup"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            409: components["responses"]["Conflict"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    getUserGroup: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                uid: string;
             };
+            cookie?: never;
         };
+        requestBody?: never;
         responses: {
-            /** @description User created successfully */
+            /** @description Group details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["User"];
+                    "application/json": components["schemas"]["UserGroup"];
                 };
             };
-            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            409: components["responses"]["Conflict"];
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
+            404: components["responses"]["NotFound"];
         };
     };
-    getUser: {
+    deleteUserGroup: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description User UID */
-                uid: components["parameters"]["UserUID"];
+                uid: string;
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description User details, with the groups the user belongs to */
+            /** @description Group deleted */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["User"] & {
-                        groups: components["schemas"]["UserGroup"][];
-                    };
+                    "application/json": components["schemas"]["MessageResponse"];
                 };
             };
-            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            429: components["responses"]["RateLimited"];
         };
     };
-    updateUser: {
+    updateUserGroup: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description User UID */
-                uid: components["parameters"]["UserUID"];
+                uid: string;
             };
             cookie?: never;
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["UpdateUserRequest"];
+                "application/json": components["schemas"]["CreateUserGroupRequest"];
             };
         };
         responses: {
-            /** @description User updated successfully */
+            /** @description Group updated */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": components["schemas"]["UserGroup"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            /** @description The update would remove the admin role from the last admin user */
-            409: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
+            404: components["responses"]["NotFound"];
+            409: components["responses"]["Conflict"];
         };
     };
-    deleteUser: {
+    listUserGroupMembers: {
         parameters: {
-            query?: never;
+            query?: {
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
             header?: never;
             path: {
-                /** @description User UID */
-                uid: components["parameters"]["UserUID"];
+                uid: string;
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description User deleted successfully */
+            /** @description Members of the group */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": {
+                        total?: components["schemas"]["ListTotal"];
+                        users?: components["schemas"]["User"][];
+                    };
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            /** @description Deleting this user would leave the instance without any admin */
-            409: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
         };
     };
-    changePassword: {
+    addUserGroupMember: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description User UID */
-                uid: components["parameters"]["UserUID"];
+                uid: string;
+                user_uid: string;
             };
             cookie?: never;
         };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["ChangePasswordRequest"];
-            };
-        };
+        requestBody?: never;
         responses: {
-            /** @description Password changed successfully */
+            /** @description Member added */
             200: {
                 headers: {
                     [name: string]: unknown;
@@ -3021,59 +5665,24 @@ export interface operations {
                     "application/json": components["schemas"]["MessageResponse"];
                 };
             };
-            /** @description Weak password */
-            400: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": {
-                        /** @enum {string} */
-                        error: "weak_password";
-                        message: string;
-                    };
-                };
-            };
-            /** @description Invalid credentials */
-            401: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
-            /** @description Cannot change another user's password (non-admin) */
-            403: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            429: components["responses"]["AuthRateLimited"];
-            500: components["responses"]["InternalError"];
         };
     };
-    resetPassword: {
+    removeUserGroupMember: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description User UID */
-                uid: components["parameters"]["UserUID"];
+                uid: string;
+                user_uid: string;
             };
             cookie?: never;
         };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["ResetPasswordRequest"];
-            };
-        };
+        requestBody?: never;
         responses: {
-            /** @description Password reset successfully */
+            /** @description Member removed */
             200: {
                 headers: {
                     [name: string]: unknown;
@@ -3082,60 +5691,47 @@ export interface operations {
                     "application/json": components["schemas"]["MessageResponse"];
                 };
             };
-            /** @description Invalid request or weak password */
-            400: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"] | {
-                        /** @enum {string} */
-                        error: "weak_password";
-                        message: string;
-                    };
-                };
-            };
             401: components["responses"]["Unauthorized"];
-            /** @description Not authorized (non-admin or API key authentication) */
-            403: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
+            403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
         };
     };
-    listDatabases: {
+    listGrantDefinitions: {
         parameters: {
-            query?: never;
+            query?: {
+                /** @description Restrict to active definitions */
+                active_only?: boolean;
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
             header?: never;
             path?: never;
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description List of databases */
+            /** @description List of grant definitions */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        databases?: (components["schemas"]["Database"] | components["schemas"]["DatabaseLimited"])[];
+                        total?: components["schemas"]["ListTotal"];
+                        grant_definitions?: components["schemas"]["GrantDefinition"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            429: components["responses"]["RateLimited"];
             500: components["responses"]["InternalError"];
         };
     };
-    createDatabase: {
+    createGrantDefinition: {
         parameters: {
             query?: never;
             header?: never;
@@ -3144,72 +5740,62 @@ export interface operations {
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["CreateDatabaseRequest"];
+                "application/json": components["schemas"]["CreateGrantDefinitionRequest"];
             };
         };
         responses: {
-            /** @description Database created successfully */
+            /** @description Definition created */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Database"];
+                    "application/json": components["schemas"]["GrantDefinition"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             409: components["responses"]["Conflict"];
-            429: components["responses"]["RateLimited"];
             500: components["responses"]["InternalError"];
         };
     };
-    getDatabase: {
+    getGrantDefinition: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Database UID */
-                uid: components["parameters"]["DatabaseUID"];
+                uid: string;
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Database details */
+            /** @description Definition details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Database"] | components["schemas"]["DatabaseLimited"];
+                    "application/json": components["schemas"]["GrantDefinition"];
                 };
             };
-            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            429: components["responses"]["RateLimited"];
         };
     };
-    updateDatabase: {
+    deactivateGrantDefinition: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Database UID */
-                uid: components["parameters"]["DatabaseUID"];
+                uid: string;
             };
             cookie?: never;
         };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["UpdateDatabaseRequest"];
-            };
-        };
+        requestBody?: never;
         responses: {
-            /** @description Database updated successfully */
+            /** @description Definition deactivated */
             200: {
                 headers: {
                     [name: string]: unknown;
@@ -3218,50 +5804,51 @@ export interface operations {
                     "application/json": components["schemas"]["MessageResponse"];
                 };
             };
-            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
+            404: components["responses"]["NotFound"];
         };
     };
-    deleteDatabase: {
+    updateGrantDefinition: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Database UID */
-                uid: components["parameters"]["DatabaseUID"];
+                uid: string;
             };
             cookie?: never;
         };
-        requestBody?: never;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateGrantDefinitionRequest"];
+            };
+        };
         responses: {
-            /** @description Database deleted successfully */
+            /** @description Definition updated */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": components["schemas"]["GrantDefinition"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
+            404: components["responses"]["NotFound"];
         };
     };
-    listGrants: {
+    listStatementApprovals: {
         parameters: {
             query?: {
-                /** @description Filter by user UID */
+                status?: "pending" | "approved" | "denied" | "expired";
                 user_id?: string;
-                /** @description Filter by database UID */
                 database_id?: string;
-                /** @description Only return active (non-revoked, within time window) grants */
-                active_only?: boolean;
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
             };
             header?: never;
             path?: never;
@@ -3269,133 +5856,167 @@ export interface operations {
         };
         requestBody?: never;
         responses: {
-            /** @description List of grants */
+            /** @description List of statement approvals */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        grants?: components["schemas"]["AccessGrant"][];
+                        total?: components["schemas"]["ListTotal"];
+                        statement_approvals?: components["schemas"]["StatementApproval"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            429: components["responses"]["RateLimited"];
             500: components["responses"]["InternalError"];
         };
     };
-    createGrant: {
+    getStatementApproval: {
         parameters: {
             query?: never;
-            header?: never;
-            path?: never;
-            cookie?: never;
-        };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["CreateGrantRequest"];
+            header?: never;
+            path: {
+                uid: string;
             };
+            cookie?: never;
         };
+        requestBody?: never;
         responses: {
-            /** @description Grant created successfully */
+            /** @description Statement approval details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["AccessGrant"];
+                    "application/json": components["schemas"]["StatementApproval"];
                 };
             };
-            400: components["responses"]["BadRequest"];
-            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
+            404: components["responses"]["NotFound"];
         };
     };
-    getGrant: {
+    approveStatementApproval: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Grant UID */
-                uid: components["parameters"]["GrantUID"];
+                uid: string;
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Grant details */
+            /** @description Approved */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["AccessGrant"];
+                    "application/json": components["schemas"]["StatementApproval"];
+                };
+            };
+            /** @description Not an admin, or the approver is the statement's user */
+            403: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
                 };
             };
-            400: components["responses"]["BadRequest"];
-            401: components["responses"]["Unauthorized"];
-            403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            429: components["responses"]["RateLimited"];
+            /** @description Not pending, or expired */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
         };
     };
-    revokeGrant: {
+    denyStatementApproval: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Grant UID */
-                uid: components["parameters"]["GrantUID"];
+                uid: string;
             };
             cookie?: never;
         };
-        requestBody?: never;
+        requestBody?: {
+            content: {
+                "application/json": {
+                    reason?: string;
+                };
+            };
+        };
         responses: {
-            /** @description Grant revoked successfully */
+            /** @description Denied */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": components["schemas"]["StatementApproval"];
                 };
             };
             400: components["responses"]["BadRequest"];
-            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
+            404: components["responses"]["NotFound"];
+            /** @description Not pending, or expired */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
         };
     };
-    listUserGroups: {
+    listGrantRequests: {
         parameters: {
-            query?: never;
+            query?: {
+                status?: "pending" | "approved" | "denied" | "cancelled" | "expired";
+                user_id?: string;
+                database_id?: string;
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
             header?: never;
             path?: never;
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description List of user groups */
+            /** @description List of grant requests */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        user_groups?: components["schemas"]["UserGroup"][];
+                        total?: components["schemas"]["ListTotal"];
+                        grant_requests?: components["schemas"]["GrantRequest"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            403: components["responses"]["Forbidden"];
             500: components["responses"]["InternalError"];
         };
     };
-    createUserGroup: {
+    createGrantRequest: {
         parameters: {
             query?: never;
             header?: never;
@@ -3404,27 +6025,42 @@ export interface operations {
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["CreateUserGroupRequest"];
+                "application/json": components["schemas"]["CreateGrantRequestPayload"];
             };
         };
         responses: {
-            /** @description Group created */
+            /** @description Grant request submitted */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["UserGroup"];
+                    "application/json": components["schemas"]["GrantRequest"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            403: components["responses"]["Forbidden"];
-            409: components["responses"]["Conflict"];
-            500: components["responses"]["InternalError"];
+            /** @description The definition's group/database scope does not cover this user or database */
+            403: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            /** @description A pending request already exists for this user/database/definition */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
         };
     };
-    getUserGroup: {
+    getGrantRequest: {
         parameters: {
             query?: never;
             header?: never;
@@ -3435,21 +6071,20 @@ export interface operations {
         };
         requestBody?: never;
         responses: {
-            /** @description Group details */
+            /** @description Grant request details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["UserGroup"];
+                    "application/json": components["schemas"]["GrantRequest"];
                 };
             };
-            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
         };
     };
-    deleteUserGroup: {
+    approveGrantRequest: {
         parameters: {
             query?: never;
             header?: never;
@@ -3460,21 +6095,36 @@ export interface operations {
         };
         requestBody?: never;
         responses: {
-            /** @description Group deleted */
+            /** @description Approved; resulting grant included */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": {
+                        grant_request?: components["schemas"]["GrantRequest"];
+                        grant?: components["schemas"]["AccessGrant"];
+                    };
                 };
             };
-            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
+            /**
+             * @description Not pending, definition inactive, or the definition's scope no
+             *     longer covers the requester or the database (scope is re-checked
+             *     at approve time so a tightened scope hard-blocks pending requests).
+             */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
         };
     };
-    updateUserGroup: {
+    denyGrantRequest: {
         parameters: {
             query?: never;
             header?: never;
@@ -3483,112 +6133,82 @@ export interface operations {
             };
             cookie?: never;
         };
-        requestBody: {
+        requestBody?: {
             content: {
-                "application/json": components["schemas"]["CreateUserGroupRequest"];
+                "application/json": {
+                    reason?: string;
+                };
             };
         };
         responses: {
-            /** @description Group updated */
+            /** @description Denied */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["UserGroup"];
+                    "application/json": components["schemas"]["GrantRequest"];
                 };
             };
-            400: components["responses"]["BadRequest"];
-            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            409: components["responses"]["Conflict"];
-        };
-    };
-    listUserGroupMembers: {
-        parameters: {
-            query?: never;
-            header?: never;
-            path: {
-                uid: string;
-            };
-            cookie?: never;
-        };
-        requestBody?: never;
-        responses: {
-            /** @description Members of the group */
-            200: {
+            /** @description Not pending */
+            409: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": {
-                        users?: components["schemas"]["User"][];
-                    };
+                    "application/json": components["schemas"]["Error"];
                 };
             };
-            401: components["responses"]["Unauthorized"];
-            403: components["responses"]["Forbidden"];
-            404: components["responses"]["NotFound"];
         };
     };
-    addUserGroupMember: {
+    cancelGrantRequest: {
         parameters: {
             query?: never;
             header?: never;
             path: {
                 uid: string;
-                user_uid: string;
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Member added */
+            /** @description Cancelled */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": components["schemas"]["GrantRequest"];
                 };
             };
-            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-        };
-    };
-    removeUserGroupMember: {
-        parameters: {
-            query?: never;
-            header?: never;
-            path: {
-                uid: string;
-                user_uid: string;
-            };
-            cookie?: never;
-        };
-        requestBody?: never;
-        responses: {
-            /** @description Member removed */
-            200: {
+            /** @description Not pending */
+            409: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": components["schemas"]["Error"];
                 };
             };
-            401: components["responses"]["Unauthorized"];
-            403: components["responses"]["Forbidden"];
-            404: components["responses"]["NotFound"];
         };
     };
-    listGrantDefinitions: {
+    listAPIKeys: {
         parameters: {
             query?: {
-                /** @description Restrict to active definitions */
-                active_only?: boolean;
+                /** @description Return only this user's keys (admin only). Overrides the own-keys-by-default behavior; wins over all_users if both are set. */
+                user_id?: string;
+                /** @description Return every user's keys instead of just the caller's own (admin only; ignored for non-admins). */
+                all_users?: boolean;
+                /** @description Include revoked and expired keys */
+                include_all?: boolean;
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
             };
             header?: never;
             path?: never;
@@ -3596,135 +6216,123 @@ export interface operations {
         };
         requestBody?: never;
         responses: {
-            /** @description List of grant definitions */
+            /** @description List of API keys */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        grant_definitions?: components["schemas"]["GrantDefinition"][];
+                        total?: components["schemas"]["ListTotal"];
+                        keys?: components["schemas"]["APIKey"][];
                     };
                 };
             };
-            401: components["responses"]["Unauthorized"];
-            500: components["responses"]["InternalError"];
-        };
-    };
-    createGrantDefinition: {
-        parameters: {
-            query?: never;
-            header?: never;
-            path?: never;
-            cookie?: never;
-        };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["CreateGrantDefinitionRequest"];
-            };
-        };
-        responses: {
-            /** @description Definition created */
-            200: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["GrantDefinition"];
-                };
-            };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            403: components["responses"]["Forbidden"];
-            409: components["responses"]["Conflict"];
+            429: components["responses"]["RateLimited"];
             500: components["responses"]["InternalError"];
         };
     };
-    getGrantDefinition: {
+    createAPIKey: {
         parameters: {
             query?: never;
             header?: never;
-            path: {
-                uid: string;
-            };
+            path?: never;
             cookie?: never;
         };
-        requestBody?: never;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateAPIKeyRequest"];
+            };
+        };
         responses: {
-            /** @description Definition details */
-            200: {
+            /** @description API key created successfully */
+            201: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["GrantDefinition"];
+                    "application/json": components["schemas"]["CreateAPIKeyResponse"];
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            404: components["responses"]["NotFound"];
+            403: components["responses"]["Forbidden"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    deactivateGrantDefinition: {
+    getAPIKey: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                uid: string;
+                /** @description API key ID */
+                id: string;
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Definition deactivated */
+            /** @description API key details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": components["schemas"]["APIKey"];
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
         };
     };
-    updateGrantDefinition: {
+    revokeAPIKey: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                uid: string;
+                /** @description API key ID */
+                id: string;
             };
             cookie?: never;
         };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["CreateGrantDefinitionRequest"];
-            };
-        };
+        requestBody?: never;
         responses: {
-            /** @description Definition updated */
-            200: {
+            /** @description API key revoked successfully */
+            204: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content: {
-                    "application/json": components["schemas"]["GrantDefinition"];
-                };
+                content?: never;
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    listGrantRequests: {
+    listConnections: {
         parameters: {
             query?: {
-                status?: "pending" | "approved" | "denied" | "cancelled" | "expired";
+                /** @description Filter by user UID */
                 user_id?: string;
+                /** @description Filter by database UID */
                 database_id?: string;
+                /** @description Filter by the UID of the dbbat instance that handled the connection */
+                instance_id?: string;
+                /** @description Maximum number of results to return */
+                limit?: components["parameters"]["Limit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
             };
             header?: never;
             path?: never;
@@ -3732,282 +6340,337 @@ export interface operations {
         };
         requestBody?: never;
         responses: {
-            /** @description List of grant requests */
+            /** @description List of connections */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        grant_requests?: components["schemas"]["GrantRequest"][];
+                        total?: components["schemas"]["ListTotal"];
+                        connections?: components["schemas"]["Connection"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
+            429: components["responses"]["RateLimited"];
             500: components["responses"]["InternalError"];
         };
     };
-    createGrantRequest: {
+    getConnection: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
-            cookie?: never;
-        };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["CreateGrantRequestPayload"];
+            path: {
+                /** @description Connection UID */
+                uid: components["parameters"]["ConnectionUID"];
             };
+            cookie?: never;
         };
+        requestBody?: never;
         responses: {
-            /** @description Grant request submitted */
+            /** @description Connection details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["GrantRequest"];
+                    "application/json": components["schemas"]["Connection"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            /** @description The definition's group/database scope does not cover this user or database */
-            403: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
-            /** @description A pending request already exists for this user/database/definition */
-            409: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
+            404: components["responses"]["NotFound"];
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    getGrantRequest: {
+    terminateConnection: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                uid: string;
+                /** @description Connection UID */
+                uid: components["parameters"]["ConnectionUID"];
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Grant request details */
-            200: {
+            /** @description Session signaled to terminate */
+            204: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content: {
-                    "application/json": components["schemas"]["GrantRequest"];
-                };
+                content?: never;
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
+            409: components["responses"]["Conflict"];
+            500: components["responses"]["InternalError"];
         };
     };
-    approveGrantRequest: {
+    listQueries: {
         parameters: {
-            query?: never;
-            header?: never;
-            path: {
-                uid: string;
+            query?: {
+                /** @description Filter by connection UID */
+                connection_id?: string;
+                /** @description Filter by user UID */
+                user_id?: string;
+                /** @description Filter by database UID */
+                database_id?: string;
+                /** @description Filter by the UID of the grant the query ran under */
+                grant_id?: string;
+                /** @description Filter by the access level the query ran with */
+                access_level?: "read_only" | "read_write";
+                /** @description Only the queries matched by this query alert */
+                alert_id?: string;
+                /** @description Only the statements of this fingerprint (see `GET /queries/stats`) */
+                fingerprint?: string;
+                /** @description Filter on whether the query ran under the `dry_run` control */
+                dry_run?: boolean;
+                /** @description Filter by start time (RFC3339 format) */
+                start_time?: string;
+                /** @description Filter by end time (RFC3339 format) */
+                end_time?: string;
+                /** @description Only queries whose SQL text contains this string, case-insensitively. Requires
+                 *     the `sql:raw` permission. Truncated SQL text is only searched up to its cut.
+                 *      */
+                sql?: string;
+                /** @description Only queries whose SQL text matches this PostgreSQL regular expression,
+                 *     case-insensitively. Requires the `sql:raw` permission.
+                 *      */
+                sql_regex?: string;
+                /** @description Only failed (`true`) or successful (`false`) queries */
+                has_error?: boolean;
+                /** @description Only queries that took at least this many milliseconds */
+                min_duration_ms?: number;
+                /** @description Only queries that took at most this many milliseconds */
+                max_duration_ms?: number;
+                /** @description Only queries that affected or returned at least this many rows */
+                min_rows_affected?: number;
+                /** @description Only queries that affected or returned at most this many rows */
+                max_rows_affected?: number;
+                /** @description Maximum number of results to return */
+                limit?: components["parameters"]["Limit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
             };
+            header?: never;
+            path?: never;
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Approved; resulting grant included */
+            /** @description List of queries */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        grant_request?: components["schemas"]["GrantRequest"];
-                        grant?: components["schemas"]["AccessGrant"];
+                        total?: components["schemas"]["ListTotal"];
+                        queries?: components["schemas"]["Query"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            404: components["responses"]["NotFound"];
-            /**
-             * @description Not pending, definition inactive, or the definition's scope no
-             *     longer covers the requester or the database (scope is re-checked
-             *     at approve time so a tightened scope hard-blocks pending requests).
-             */
-            409: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    denyGrantRequest: {
+    getQueryStats: {
         parameters: {
-            query?: never;
-            header?: never;
-            path: {
-                uid: string;
+            query?: {
+                /** @description Filter by connection UID */
+                connection_id?: string;
+                /** @description Filter by user UID */
+                user_id?: string;
+                /** @description Filter by database UID */
+                database_id?: string;
+                /** @description Filter by the UID of the grant the query ran under */
+                grant_id?: string;
+                /** @description Filter by the access level the query ran with */
+                access_level?: "read_only" | "read_write";
+                /** @description Only the queries matched by this query alert */
+                alert_id?: string;
+                /** @description Only the statements of this fingerprint (see `GET /queries/stats`) */
+                fingerprint?: string;
+                /** @description Filter on whether the query ran under the `dry_run` control */
+                dry_run?: boolean;
+                /** @description Filter by start time (RFC3339 format) */
+                start_time?: string;
+                /** @description Filter by end time (RFC3339 format) */
+                end_time?: string;
+                /** @description Only queries whose SQL text contains this string, case-insensitively. Requires
+                 *     the `sql:raw` permission. Truncated SQL text is only searched up to its cut.
+                 *      */
+                sql?: string;
+                /** @description Only queries whose SQL text matches this PostgreSQL regular expression,
+                 *     case-insensitively. Requires the `sql:raw` permission.
+                 *      */
+                sql_regex?: string;
+                /** @description Only failed (`true`) or successful (`false`) queries */
+                has_error?: boolean;
+                /** @description Only queries that took at least this many milliseconds */
+                min_duration_ms?: number;
+                /** @description Only queries that took at most this many milliseconds */
+                max_duration_ms?: number;
+                /** @description Only queries that affected or returned at least this many rows */
+                min_rows_affected?: number;
+                /** @description Only queries that affected or returned at most this many rows */
+                max_rows_affected?: number;
+                /** @description Order of the statistics, by decreasing value */
+                sort?: "calls" | "total_duration" | "avg_duration" | "p95_duration" | "rows";
+                /** @description Maximum number of results to return */
+                limit?: components["parameters"]["Limit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
             };
+            header?: never;
+            path?: never;
             cookie?: never;
         };
-        requestBody?: {
-            content: {
-                "application/json": {
-                    reason?: string;
-                };
-            };
-        };
+        requestBody?: never;
         responses: {
-            /** @description Denied */
+            /** @description Statement statistics */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["GrantRequest"];
+                    "application/json": {
+                        total?: components["schemas"]["ListTotal"];
+                        stats?: components["schemas"]["QueryStat"][];
+                    };
                 };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            404: components["responses"]["NotFound"];
-            /** @description Not pending */
-            409: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
+            429: components["responses"]["RateLimited"];
+            500: components["responses"]["InternalError"];
         };
     };
-    cancelGrantRequest: {
+    streamQueries: {
         parameters: {
-            query?: never;
-            header?: never;
-            path: {
-                uid: string;
+            query?: {
+                /** @description Only stream the queries of this connection */
+                connection_id?: string;
+                /** @description Only stream the queries of this user */
+                user_id?: string;
+                /** @description Only stream the queries run on this database */
+                database_id?: string;
             };
+            header?: never;
+            path?: never;
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Cancelled */
+            /** @description Stream of `query` and `dropped` events */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["GrantRequest"];
+                    "text/event-stream": string;
                 };
             };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            404: components["responses"]["NotFound"];
-            /** @description Not pending */
-            409: {
-                headers: {
-                    [name: string]: unknown;
-                };
-                content: {
-                    "application/json": components["schemas"]["Error"];
-                };
-            };
         };
     };
-    listAPIKeys: {
+    getQuery: {
         parameters: {
-            query?: {
-                /** @description Return only this user's keys (admin only). Overrides the own-keys-by-default behavior; wins over all_users if both are set. */
-                user_id?: string;
-                /** @description Return every user's keys instead of just the caller's own (admin only; ignored for non-admins). */
-                all_users?: boolean;
-                /** @description Include revoked and expired keys */
-                include_all?: boolean;
-            };
+            query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
+            };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description List of API keys */
+            /** @description Query details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": {
-                        keys?: components["schemas"]["APIKey"][];
-                    };
+                    "application/json": components["schemas"]["Query"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
             429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
         };
     };
-    createAPIKey: {
+    getQueryPlan: {
         parameters: {
-            query?: never;
+            query?: {
+                /** @description Mask literal values in the query log even with the `sql:raw` permission. Users without it always get the redacted view. */
+                redact?: boolean;
+            };
             header?: never;
-            path?: never;
-            cookie?: never;
-        };
-        requestBody: {
-            content: {
-                "application/json": components["schemas"]["CreateAPIKeyRequest"];
+            path: {
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
             };
+            cookie?: never;
         };
+        requestBody?: never;
         responses: {
-            /** @description API key created successfully */
-            201: {
+            /** @description Query plan */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["CreateAPIKeyResponse"];
+                    "application/json": components["schemas"]["QueryPlan"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
             429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
         };
     };
-    getAPIKey: {
+    getQueryResultURL: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description API key ID */
-                id: string;
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description API key details */
+            /** @description Signed download URL */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["APIKey"];
+                    "application/json": components["schemas"]["QueryResultURL"];
                 };
             };
             400: components["responses"]["BadRequest"];
@@ -4017,110 +6680,112 @@ export interface operations {
             429: components["responses"]["RateLimited"];
         };
     };
-    revokeAPIKey: {
+    downloadQueryResult: {
         parameters: {
-            query?: never;
+            query: {
+                /** @description Expiry of the URL, in seconds since the epoch */
+                expires: number;
+                /** @description Signature of the URL */
+                signature: string;
+            };
             header?: never;
             path: {
-                /** @description API key ID */
-                id: string;
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description API key revoked successfully */
-            204: {
+            /** @description Spilled result */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
-                content?: never;
+                content: {
+                    "application/gzip": string;
+                };
             };
             400: components["responses"]["BadRequest"];
-            401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
         };
     };
-    listConnections: {
+    getQueryRows: {
         parameters: {
             query?: {
-                /** @description Filter by user UID */
-                user_id?: string;
-                /** @description Filter by database UID */
-                database_id?: string;
-                /** @description Maximum number of results to return */
-                limit?: components["parameters"]["Limit"];
-                /** @description Number of results to skip for pagination */
-                offset?: components["parameters"]["Offset"];
+                /** @description Pagination cursor from previous response */
+                cursor?: string;
+                /** @description Maximum number of rows to return */
+                limit?: number;
             };
             header?: never;
-            path?: never;
+            path: {
+                /** @description Query UID */
+                uid: components["parameters"]["QueryUID"];
+            };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description List of connections */
+            /** @description Query rows retrieved successfully */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": {
-                        connections?: components["schemas"]["Connection"][];
-                    };
+                    "application/json": components["schemas"]["QueryRowsResponse"];
+                };
+            };
+            /** @description Invalid cursor or limit */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
                 };
             };
             401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
             429: components["responses"]["RateLimited"];
-            500: components["responses"]["InternalError"];
         };
     };
-    getConnection: {
+    createQueryAlert: {
         parameters: {
             query?: never;
             header?: never;
-            path: {
-                /** @description Connection UID */
-                uid: components["parameters"]["ConnectionUID"];
-            };
+            path?: never;
             cookie?: never;
         };
-        requestBody?: never;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateQueryAlertRequest"];
+            };
+        };
         responses: {
-            /** @description Connection details */
+            /** @description Query alert created */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Connection"];
+                    "application/json": components["schemas"]["QueryAlert"];
                 };
             };
             400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
-            404: components["responses"]["NotFound"];
-            429: components["responses"]["RateLimited"];
+            403: components["responses"]["Forbidden"];
+            409: components["responses"]["Conflict"];
             500: components["responses"]["InternalError"];
         };
     };
-    listQueries: {
+    listQueryAlerts: {
         parameters: {
             query?: {
-                /** @description Filter by connection UID */
-                connection_id?: string;
-                /** @description Filter by user UID */
-                user_id?: string;
-                /** @description Filter by database UID */
-                database_id?: string;
-                /** @description Filter by start time (RFC3339 format) */
-                start_time?: string;
-                /** @description Filter by end time (RFC3339 format) */
-                end_time?: string;
-                /** @description Maximum number of results to return */
-                limit?: components["parameters"]["Limit"];
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
                 /** @description Number of results to skip for pagination */
                 offset?: components["parameters"]["Offset"];
             };
@@ -4130,90 +6795,105 @@ export interface operations {
         };
         requestBody?: never;
         responses: {
-            /** @description List of queries */
+            /** @description List of query alerts */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
-                        queries?: components["schemas"]["Query"][];
+                        total?: components["schemas"]["ListTotal"];
+                        query_alerts?: components["schemas"]["QueryAlert"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            429: components["responses"]["RateLimited"];
             500: components["responses"]["InternalError"];
         };
     };
-    getQuery: {
+    getQueryAlert: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Query UID */
-                uid: components["parameters"]["QueryUID"];
+                uid: string;
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Query details */
+            /** @description Query alert details */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Query"];
+                    "application/json": components["schemas"]["QueryAlert"];
                 };
             };
-            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            429: components["responses"]["RateLimited"];
         };
     };
-    getQueryRows: {
+    updateQueryAlert: {
         parameters: {
-            query?: {
-                /** @description Pagination cursor from previous response */
-                cursor?: string;
-                /** @description Maximum number of rows to return */
-                limit?: number;
-            };
+            query?: never;
             header?: never;
             path: {
-                /** @description Query UID */
-                uid: components["parameters"]["QueryUID"];
+                uid: string;
             };
             cookie?: never;
         };
-        requestBody?: never;
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateQueryAlertRequest"];
+            };
+        };
         responses: {
-            /** @description Query rows retrieved successfully */
+            /** @description Query alert updated */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["QueryRowsResponse"];
+                    "application/json": components["schemas"]["QueryAlert"];
                 };
             };
-            /** @description Invalid cursor or limit */
-            400: {
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            409: components["responses"]["Conflict"];
+        };
+    };
+    deleteQueryAlert: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                uid: string;
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Query alert deleted */
+            200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Error"];
+                    "application/json": components["schemas"]["MessageResponse"];
                 };
             };
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
-            429: components["responses"]["RateLimited"];
         };
     };
     listAudit: {
@@ -4243,14 +6923,18 @@ export interface operations {
             /** @description List of audit events */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
+                        total?: components["schemas"]["ListTotal"];
                         audit_events?: components["schemas"]["AuditEvent"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             429: components["responses"]["RateLimited"];
@@ -4293,6 +6977,34 @@ export interface operations {
             500: components["responses"]["InternalError"];
         };
     };
+    testDatabaseConnection: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["TestDatabaseConnectionRequest"];
+            };
+        };
+        responses: {
+            /** @description Connectivity check result (successful or failed) */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["ConnectionTestResult"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            500: components["responses"]["InternalError"];
+        };
+    };
     testServerConnection: {
         parameters: {
             query?: never;
@@ -4320,10 +7032,69 @@ export interface operations {
             500: components["responses"]["InternalError"];
         };
     };
-    listSSHServers: {
+    getServerHealth: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                uid: string;
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Latest check */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["ServerHealth"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    checkServerPrivileges: {
         parameters: {
             query?: never;
             header?: never;
+            path: {
+                uid: string;
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Privilege check result (successful or failed) */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["PrivilegeCheckResult"];
+                };
+            };
+            400: components["responses"]["BadRequest"];
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    listSSHServers: {
+        parameters: {
+            query?: {
+                /** @description Maximum number of results to return; every result when omitted */
+                limit?: components["parameters"]["PageLimit"];
+                /** @description Number of results to skip for pagination */
+                offset?: components["parameters"]["Offset"];
+            };
+            header?: never;
             path?: never;
             cookie?: never;
         };
@@ -4332,14 +7103,18 @@ export interface operations {
             /** @description List of SSH servers */
             200: {
                 headers: {
+                    "X-Total-Count"?: components["headers"]["TotalCount"];
+                    Link?: components["headers"]["PageLinks"];
                     [name: string]: unknown;
                 };
                 content: {
                     "application/json": {
+                        total?: components["schemas"]["ListTotal"];
                         servers?: components["schemas"]["Database"][];
                     };
                 };
             };
+            400: components["responses"]["BadRequest"];
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             500: components["responses"]["InternalError"];
@@ -63,6 +63,7 @@ const mainNavItems = [
   { title: "Grants", icon: Shield, href: "/grants" },
   { title: "Grant Definitions", icon: Shield, href: "/grant-definitions" },
   { title: "Grant Requests", icon: Shield, href: "/grant-requests" },
+  { title: "Statement Approvals", icon: Shield, href: "/statement-approvals" },
 ];
 
 const observabilityNavItems = [
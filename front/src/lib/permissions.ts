@@ -6,7 +6,7 @@
  * about permission limitations.
  */
 
-export type UserRole = 'admin' | 'viewer' | 'connector';
+export type UserRole = 'admin' | 'viewer' | 'auditor' | 'connector';
 
 /**
  * Check if a user has a specific role
@@ -32,9 +32,14 @@ export const hasAllRoles = (roles: string[] | undefined, requiredRoles: UserRole
   return requiredRoles.every(role => roles.includes(role));
 };
 
-// Navigation permissions
-export const canViewQueries = (roles: string[] | undefined): boolean => hasAnyRole(roles, ['admin', 'viewer']);
-export const canViewAudit = (roles: string[] | undefined): boolean => hasAnyRole(roles, ['admin', 'viewer']);
+// Navigation permissions (queries:read)
+export const canViewQueries = (roles: string[] | undefined): boolean =>
+  hasAnyRole(roles, ['admin', 'viewer', 'auditor']);
+export const canViewAudit = (roles: string[] | undefined): boolean => hasAnyRole(roles, ['admin', 'viewer', 'auditor']);
+
+// Result data: captured rows and connection dumps (rows:read). Auditors see
+// statements only.
+export const canViewQueryRows = (roles: string[] | undefined): boolean => hasAnyRole(roles, ['admin', 'viewer']);
 
 // Admin-only permissions
 export const canCreateUser = (roles: string[] | undefined): boolean => hasRole(roles, 'admin');
@@ -49,6 +54,7 @@ export const canResetPassword = (roles: string[] | undefined): boolean => hasRol
 export const canManageGrantDefinitions = (roles: string[] | undefined): boolean => hasRole(roles, 'admin');
 export const canManageUserGroups = (roles: string[] | undefined): boolean => hasRole(roles, 'admin');
 export const canApproveGrantRequest = (roles: string[] | undefined): boolean => hasRole(roles, 'admin');
+export const canApproveStatement = (roles: string[] | undefined): boolean => hasRole(roles, 'admin');
 export const canRequestGrant = (roles: string[] | undefined): boolean =>
   hasAnyRole(roles, ['admin', 'connector', 'viewer']);
 
@@ -19,6 +19,7 @@ import { Route as AuthenticatedSettingsIndexRouteImport } from './routes/_authen
 import { Route as AuthenticatedServersIndexRouteImport } from './routes/_authenticated/servers/index'
 import { Route as AuthenticatedQueriesIndexRouteImport } from './routes/_authenticated/queries/index'
 import { Route as AuthenticatedGrantsIndexRouteImport } from './routes/_authenticated/grants/index'
+import { Route as AuthenticatedStatementApprovalsIndexRouteImport } from './routes/_authenticated/statement-approvals/index'
 import { Route as AuthenticatedGrantRequestsIndexRouteImport } from './routes/_authenticated/grant-requests/index'
 import { Route as AuthenticatedGrantDefinitionsIndexRouteImport } from './routes/_authenticated/grant-definitions/index'
 import { Route as AuthenticatedDatabasesIndexRouteImport } from './routes/_authenticated/databases/index'
@@ -82,6 +83,12 @@ const AuthenticatedGrantsIndexRoute =
     path: '/grants/',
     getParentRoute: () => AuthenticatedRoute,
   } as any)
+const AuthenticatedStatementApprovalsIndexRoute =
+  AuthenticatedStatementApprovalsIndexRouteImport.update({
+    id: '/statement-approvals/',
+    path: '/statement-approvals/',
+    getParentRoute: () => AuthenticatedRoute,
+  } as any)
 const AuthenticatedGrantRequestsIndexRoute =
   AuthenticatedGrantRequestsIndexRouteImport.update({
     id: '/grant-requests/',
@@ -140,6 +147,7 @@ export interface FileRoutesByFullPath {
   '/connections/': typeof AuthenticatedConnectionsIndexRoute
   '/databases/': typeof AuthenticatedDatabasesIndexRoute
   '/grant-definitions/': typeof AuthenticatedGrantDefinitionsIndexRoute
+  '/statement-approvals/': typeof AuthenticatedStatementApprovalsIndexRoute
   '/grant-requests/': typeof AuthenticatedGrantRequestsIndexRoute
   '/grants/': typeof AuthenticatedGrantsIndexRoute
   '/queries/': typeof AuthenticatedQueriesIndexRoute
@@ -159,6 +167,7 @@ export interface FileRoutesByTo {
   '/connections': typeof AuthenticatedConnectionsIndexRoute
   '/databases': typeof AuthenticatedDatabasesIndexRoute
   '/grant-definitions': typeof AuthenticatedGrantDefinitionsIndexRoute
+  '/statement-approvals': typeof AuthenticatedStatementApprovalsIndexRoute
   '/grant-requests': typeof AuthenticatedGrantRequestsIndexRoute
   '/grants': typeof AuthenticatedGrantsIndexRoute
   '/queries': typeof AuthenticatedQueriesIndexRoute
@@ -180,6 +189,7 @@ export interface FileRoutesById {
   '/_authenticated/connections/': typeof AuthenticatedConnectionsIndexRoute
   '/_authenticated/databases/': typeof AuthenticatedDatabasesIndexRoute
   '/_authenticated/grant-definitions/': typeof AuthenticatedGrantDefinitionsIndexRoute
+  '/_authenticated/statement-approvals/': typeof AuthenticatedStatementApprovalsIndexRoute
   '/_authenticated/grant-requests/': typeof AuthenticatedGrantRequestsIndexRoute
   '/_authenticated/grants/': typeof AuthenticatedGrantsIndexRoute
   '/_authenticated/queries/': typeof AuthenticatedQueriesIndexRoute
@@ -201,6 +211,7 @@ export interface FileRouteTypes {
     | '/connections/'
     | '/databases/'
     | '/grant-definitions/'
+    | '/statement-approvals/'
     | '/grant-requests/'
     | '/grants/'
     | '/queries/'
@@ -220,6 +231,7 @@ export interface FileRouteTypes {
     | '/connections'
     | '/databases'
     | '/grant-definitions'
+    | '/statement-approvals'
     | '/grant-requests'
     | '/grants'
     | '/queries'
@@ -240,6 +252,7 @@ export interface FileRouteTypes {
     | '/_authenticated/connections/'
     | '/_authenticated/databases/'
     | '/_authenticated/grant-definitions/'
+    | '/_authenticated/statement-approvals/'
     | '/_authenticated/grant-requests/'
     | '/_authenticated/grants/'
     | '/_authenticated/queries/'
@@ -326,6 +339,13 @@ declare module '@tanstack/react-router' {
       preLoaderRoute: typeof AuthenticatedGrantsIndexRouteImport
       parentRoute: typeof AuthenticatedRoute
     }
+    '/_authenticated/statement-approvals/': {
+      id: '/_authenticated/statement-approvals/'
+      path: '/statement-approvals'
+      fullPath: '/statement-approvals/'
+      preLoaderRoute: typeof AuthenticatedStatementApprovalsIndexRouteImport
+      parentRoute: typeof AuthenticatedRoute
+    }
     '/_authenticated/grant-requests/': {
       id: '/_authenticated/grant-requests/'
       path: '/grant-requests'
@@ -395,6 +415,7 @@ interface AuthenticatedRouteChildren {
   AuthenticatedConnectionsIndexRoute: typeof AuthenticatedConnectionsIndexRoute
   AuthenticatedDatabasesIndexRoute: typeof AuthenticatedDatabasesIndexRoute
   AuthenticatedGrantDefinitionsIndexRoute: typeof AuthenticatedGrantDefinitionsIndexRoute
+  AuthenticatedStatementApprovalsIndexRoute: typeof AuthenticatedStatementApprovalsIndexRoute
   AuthenticatedGrantRequestsIndexRoute: typeof AuthenticatedGrantRequestsIndexRoute
   AuthenticatedGrantsIndexRoute: typeof AuthenticatedGrantsIndexRoute
   AuthenticatedQueriesIndexRoute: typeof AuthenticatedQueriesIndexRoute
@@ -415,6 +436,7 @@ const AuthenticatedRouteChildren: AuthenticatedRouteChildren = {
   AuthenticatedDatabasesIndexRoute: AuthenticatedDatabasesIndexRoute,
   AuthenticatedGrantDefinitionsIndexRoute:
     AuthenticatedGrantDefinitionsIndexRoute,
+  AuthenticatedStatementApprovalsIndexRoute: AuthenticatedStatementApprovalsIndexRoute,
   AuthenticatedGrantRequestsIndexRoute: AuthenticatedGrantRequestsIndexRoute,
   AuthenticatedGrantsIndexRoute: AuthenticatedGrantsIndexRoute,
   AuthenticatedQueriesIndexRoute: AuthenticatedQueriesIndexRoute,
@@ -186,6 +186,33 @@ function ConnectionDetailPage() {
               </dt>
               <dd>{formatBytes(connection.bytes_transferred)}</dd>
             </div>
+            <div>
+              <dt className="text-sm font-medium text-muted-foreground mb-1">
+                Application
+              </dt>
+              <dd>{connection.client_info?.application_name || "-"}</dd>
+            </div>
+            <div>
+              <dt className="text-sm font-medium text-muted-foreground mb-1">
+                Driver
+              </dt>
+              <dd>
+                {[
+                  connection.client_info?.driver,
+                  connection.client_info?.driver_version,
+                ]
+                  .filter(Boolean)
+                  .join(" ") || "-"}
+              </dd>
+            </div>
+            <div>
+              <dt className="text-sm font-medium text-muted-foreground mb-1">
+                Protocol Version
+              </dt>
+              <dd className="font-mono text-sm">
+                {connection.client_info?.protocol_version || "-"}
+              </dd>
+            </div>
           </dl>
         </CardContent>
       </Card>
@@ -65,6 +65,7 @@ const CONTROLS = [
   { value: "read_only", label: "Read Only" },
   { value: "block_copy", label: "Block COPY" },
   { value: "block_ddl", label: "Block DDL" },
+  { value: "allow_replication", label: "Allow Replication" },
 ];
 
 function formatDuration(seconds: number): string {
@@ -486,7 +487,7 @@ function DefinitionDialog({
       name,
       description,
       duration_seconds: durationSeconds,
-      controls: controls as ("read_only" | "block_copy" | "block_ddl")[],
+      controls: controls as ("read_only" | "block_copy" | "block_ddl" | "allow_replication")[],
       max_query_counts: maxQueries ? parseInt(maxQueries) : null,
       max_bytes_transferred: maxBytesValue
         ? parseInt(maxBytesValue) * unitMult
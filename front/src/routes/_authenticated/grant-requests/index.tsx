@@ -60,6 +60,20 @@ const STATUS_BADGE: Record<GrantRequest["status"], string> = {
   expired: "bg-muted text-muted-foreground",
 };
 
+function formatDuration(seconds: number): string {
+  if (seconds < 60) return `${seconds}s`;
+  const minutes = Math.floor(seconds / 60);
+  if (minutes < 60) return `${minutes}m`;
+  const hours = Math.floor(minutes / 60);
+  if (hours < 24) {
+    const rem = minutes % 60;
+    return rem ? `${hours}h ${rem}m` : `${hours}h`;
+  }
+  const days = Math.floor(hours / 24);
+  const rem = hours % 24;
+  return rem ? `${days}d ${rem}h` : `${days}d`;
+}
+
 function GrantRequestsPage() {
   const { user } = useAuth();
   const isAdmin = canApproveGrantRequest(user?.roles);
@@ -107,7 +121,7 @@ function GrantRequestsPage() {
   // Approve a pending request and, in the same action, flip its definition to
   // auto-approve so future requests against it skip review entirely.
   const approveAndEnableAutoApprove = (r: GrantRequest) => {
-    const def = defMap[r.grant_definition_id];
+    const def = r.grant_definition_id ? defMap[r.grant_definition_id] : undefined;
     if (!def) return;
     const body: CreateGrantDefinitionRequest = {
       name: def.name,
@@ -144,6 +158,17 @@ function GrantRequestsPage() {
       key: "definition",
       header: "Definition",
       cell: (r: GrantRequest) => {
+        if (!r.grant_definition_id) {
+          return (
+            <span
+              className="text-xs bg-amber-100 text-amber-700 dark:bg-amber-900/30 dark:text-amber-400 px-1.5 py-0.5 rounded"
+              data-testid={`request-extension-${r.uid}`}
+            >
+              extension
+              {r.duration_seconds ? ` +${formatDuration(r.duration_seconds)}` : ""}
+            </span>
+          );
+        }
         const def = defMap[r.grant_definition_id];
         return (
           <div className="flex items-center gap-1.5">
@@ -204,7 +229,8 @@ function GrantRequestsPage() {
                 >
                   <Check className="h-4 w-4 text-green-600" />
                 </Button>
-                {!defMap[r.grant_definition_id]?.auto_approve && (
+                {r.grant_definition_id &&
+                  !defMap[r.grant_definition_id]?.auto_approve && (
                   <Tooltip>
                     <TooltipTrigger asChild>
                       <Button
@@ -222,7 +248,8 @@ function GrantRequestsPage() {
                     </TooltipTrigger>
                     <TooltipContent>
                       Approve this request and enable auto-approve on "
-                      {defMap[r.grant_definition_id]?.name ?? "this definition"}
+                      {defMap[r.grant_definition_id]?.name ??
+                        "this definition"}
                       " so future requests skip review.
                     </TooltipContent>
                   </Tooltip>
@@ -6,6 +6,7 @@ import {
   useDatabases,
   useCreateGrant,
   useRevokeGrant,
+  useRequestGrantExtension,
   type AccessGrant,
 } from "@/api";
 import { DataTable, type Column } from "@/components/shared/DataTable";
@@ -51,7 +52,8 @@ import {
 } from "@/components/ui/select";
 import { Switch } from "@/components/ui/switch";
 import { Checkbox } from "@/components/ui/checkbox";
-import { Plus, Ban } from "lucide-react";
+import { Textarea } from "@/components/ui/textarea";
+import { Plus, Ban, CalendarClock } from "lucide-react";
 import { toast } from "sonner";
 import { formatDateTimeLocal, formatDateTime } from "@/lib/date-utils";
 import { UsageMeter } from "@/components/shared/UsageMeter";
@@ -62,6 +64,7 @@ const CONTROLS = [
   { value: "read_only", label: "Read Only", description: "Enable PostgreSQL read-only mode" },
   { value: "block_copy", label: "Block COPY", description: "Prevent COPY commands (data export/import)" },
   { value: "block_ddl", label: "Block DDL", description: "Prevent schema modifications (CREATE, ALTER, DROP)" },
+  { value: "allow_replication", label: "Allow Replication", description: "Permit PostgreSQL replication connections" },
 ] as const;
 
 // Helper to format control names for display
@@ -107,6 +110,7 @@ function GrantsPage() {
   const { data: databases } = useDatabases();
   const [isCreateOpen, setIsCreateOpen] = useState(false);
   const [revokeGrant, setRevokeGrant] = useState<AccessGrant | null>(null);
+  const [extendGrant, setExtendGrant] = useState<AccessGrant | null>(null);
 
   const previousSignatureRef = useRef<string | null>(null);
 
@@ -116,7 +120,7 @@ function GrantsPage() {
     const signature = newData
       .map(
         (g) =>
-          `${g.uid}:${g.revoked_at ?? ""}:${g.query_count ?? 0}:${g.bytes_transferred ?? 0}`,
+          `${g.uid}:${g.revoked_at ?? ""}:${g.query_count ?? 0}:${g.bytes_transferred ?? 0}:${g.rows_returned ?? 0}`,
       )
       .sort()
       .join("|");
@@ -220,6 +224,14 @@ function GrantsPage() {
             limit={g.max_bytes_transferred}
             format={formatBytes}
           />
+          {/* Rows are only counted on PostgreSQL: no meter without a row quota. */}
+          {g.max_rows_returned != null && (
+            <UsageMeter
+              used={g.rows_returned ?? 0}
+              limit={g.max_rows_returned}
+              unit="rows"
+            />
+          )}
         </div>
       ),
     },
@@ -228,21 +240,37 @@ function GrantsPage() {
       header: "",
       cell: (g) =>
         getStatus(g) === "active" && (
-          <PermissionButton
-            variant="ghost"
-            size="icon"
-            disabled={!canRevoke}
-            disabledReason={getDisabledReason("revoke-grant", user?.roles)}
-            enabledTooltip={getActionTooltip("revoke-grant")}
-            onClick={(e) => {
-              e.stopPropagation();
-              setRevokeGrant(g);
-            }}
-          >
-            <Ban className="h-4 w-4" />
-          </PermissionButton>
+          <div className="flex justify-end gap-1">
+            {g.user_id === user?.uid && (
+              <Button
+                variant="ghost"
+                size="icon"
+                title="Request an extension"
+                data-testid={`extend-grant-${g.uid}`}
+                onClick={(e) => {
+                  e.stopPropagation();
+                  setExtendGrant(g);
+                }}
+              >
+                <CalendarClock className="h-4 w-4" />
+              </Button>
+            )}
+            <PermissionButton
+              variant="ghost"
+              size="icon"
+              disabled={!canRevoke}
+              disabledReason={getDisabledReason("revoke-grant", user?.roles)}
+              enabledTooltip={getActionTooltip("revoke-grant")}
+              onClick={(e) => {
+                e.stopPropagation();
+                setRevokeGrant(g);
+              }}
+            >
+              <Ban className="h-4 w-4" />
+            </PermissionButton>
+          </div>
         ),
-      className: "w-10",
+      className: "w-20",
     },
   ];
 
@@ -302,6 +330,16 @@ function GrantsPage() {
         getDbName={getDbName}
         onClose={() => setRevokeGrant(null)}
       />
+
+      <Dialog open={!!extendGrant} onOpenChange={() => setExtendGrant(null)}>
+        {extendGrant && (
+          <ExtendGrantDialog
+            grant={extendGrant}
+            getDbName={getDbName}
+            onClose={() => setExtendGrant(null)}
+          />
+        )}
+      </Dialog>
     </div>
   );
 }
@@ -318,6 +356,8 @@ function CreateGrantDialog({
   const [userId, setUserId] = useState("");
   const [databaseId, setDatabaseId] = useState("");
   const [controls, setControls] = useState<string[]>([]);
+  const [allowedTables, setAllowedTables] = useState("");
+  const [allowedCidrs, setAllowedCidrs] = useState("");
   const [startsAt, setStartsAt] = useState(() => {
     const now = new Date();
     now.setSeconds(0, 0);
@@ -330,6 +370,7 @@ function CreateGrantDialog({
   });
   const [maxQueries, setMaxQueries] = useState<string>("");
   const [maxBytesValue, setMaxBytesValue] = useState<string>("");
+  const [maxRows, setMaxRows] = useState<string>("");
   const [bytesUnit, setBytesUnit] = useState<"KB" | "MB" | "GB">("MB");
 
   // Compute duration and validation
@@ -367,11 +408,20 @@ function CreateGrantDialog({
     createGrant.mutate({
       user_id: userId,
       database_id: databaseId,
-      controls: controls as ("read_only" | "block_copy" | "block_ddl")[],
+      controls: controls as ("read_only" | "block_copy" | "block_ddl" | "allow_replication")[],
+      allowed_tables: allowedTables
+        .split(",")
+        .map((t) => t.trim())
+        .filter(Boolean),
+      allowed_cidrs: allowedCidrs
+        .split(",")
+        .map((c) => c.trim())
+        .filter(Boolean),
       starts_at: new Date(startsAt).toISOString(),
       expires_at: new Date(expiresAt).toISOString(),
       max_query_counts: maxQueries ? parseInt(maxQueries) : undefined,
       max_bytes_transferred: maxBytesTransferred,
+      max_rows_returned: maxRows ? parseInt(maxRows) : undefined,
     });
   };
 
@@ -451,6 +501,32 @@ function CreateGrantDialog({
               ))}
             </div>
           </div>
+          <div className="space-y-2">
+            <Label htmlFor="allowedTables">Allowed Tables (Optional)</Label>
+            <Input
+              id="allowedTables"
+              placeholder="All tables"
+              value={allowedTables}
+              onChange={(e) => setAllowedTables(e.target.value)}
+            />
+            <p className="text-xs text-muted-foreground">
+              Comma-separated schema.table or schema.* entries. PostgreSQL
+              only.
+            </p>
+          </div>
+          <div className="space-y-2">
+            <Label htmlFor="allowedCidrs">Allowed Networks (Optional)</Label>
+            <Input
+              id="allowedCidrs"
+              placeholder="Any network"
+              value={allowedCidrs}
+              onChange={(e) => setAllowedCidrs(e.target.value)}
+            />
+            <p className="text-xs text-muted-foreground">
+              Comma-separated CIDRs or IP addresses clients must connect from.
+              PostgreSQL only.
+            </p>
+          </div>
           <div className="space-y-3">
             <Label>Quotas (Optional)</Label>
             <p className="text-sm text-muted-foreground">
@@ -492,6 +568,18 @@ function CreateGrantDialog({
                   </Select>
                 </div>
               </div>
+              <div className="space-y-2">
+                <Label htmlFor="maxRows">Max Rows Returned</Label>
+                <Input
+                  id="maxRows"
+                  type="number"
+                  min="1"
+                  placeholder="Unlimited"
+                  value={maxRows}
+                  onChange={(e) => setMaxRows(e.target.value)}
+                />
+                <p className="text-xs text-muted-foreground">PostgreSQL only.</p>
+              </div>
             </div>
           </div>
           <div className="space-y-3">
@@ -590,3 +678,88 @@ function RevokeGrantDialog({
     </AlertDialog>
   );
 }
+
+function ExtendGrantDialog({
+  grant,
+  getDbName,
+  onClose,
+}: {
+  grant: AccessGrant;
+  getDbName: (uid: string) => string;
+  onClose: () => void;
+}) {
+  // An extension can renew the grant at most once over.
+  const maxHours = Math.max(
+    1,
+    Math.floor(
+      (new Date(grant.expires_at).getTime() -
+        new Date(grant.starts_at).getTime()) /
+        3_600_000,
+    ),
+  );
+  const [hours, setHours] = useState(String(Math.min(2, maxHours)));
+  const [justification, setJustification] = useState("");
+
+  const requestExtension = useRequestGrantExtension({
+    onSuccess: () => {
+      toast.success("Extension requested — an admin will approve or deny it.");
+      onClose();
+    },
+    onError: (error) => toast.error(error.message),
+  });
+
+  const onSubmit = (e: React.FormEvent) => {
+    e.preventDefault();
+    requestExtension.mutate({
+      uid: grant.uid,
+      durationSeconds: Number(hours) * 3600,
+      justification,
+    });
+  };
+
+  return (
+    <DialogContent>
+      <form onSubmit={onSubmit}>
+        <DialogHeader>
+          <DialogTitle>Request an extension</DialogTitle>
+          <DialogDescription>
+            Your access to {getDbName(grant.database_id)} expires{" "}
+            {formatDateTime(grant.expires_at)}. Ask an admin for more time.
+          </DialogDescription>
+        </DialogHeader>
+        <div className="space-y-4 py-4">
+          <div className="space-y-2">
+            <Label htmlFor="extension-hours">Extra hours (max {maxHours})</Label>
+            <Input
+              id="extension-hours"
+              type="number"
+              min={1}
+              max={maxHours}
+              value={hours}
+              onChange={(e) => setHours(e.target.value)}
+              required
+              data-testid="extension-hours"
+            />
+          </div>
+          <div className="space-y-2">
+            <Label htmlFor="extension-justification">Justification</Label>
+            <Textarea
+              id="extension-justification"
+              value={justification}
+              onChange={(e) => setJustification(e.target.value)}
+              maxLength={1000}
+            />
+          </div>
+        </div>
+        <DialogFooter>
+          <Button type="button" variant="outline" onClick={onClose}>
+            Cancel
+          </Button>
+          <Button type="submit" disabled={requestExtension.isPending}>
+            Request extension
+          </Button>
+        </DialogFooter>
+      </form>
+    </DialogContent>
+  );
+}
@@ -23,7 +23,7 @@ import {
 import { ChevronLeft, ChevronRight, ChevronsLeft } from "lucide-react";
 import { format } from "date-fns";
 import { useAuth } from "@/contexts/AuthContext";
-import { canViewQueries } from "@/lib/permissions";
+import { canViewQueries, canViewQueryRows } from "@/lib/permissions";
 import { AccessDenied } from "@/components/shared/AccessDenied";
 import {
   useBreadcrumbItems,
@@ -80,9 +80,11 @@ function QueryDetailPage() {
   const [cursorStack, setCursorStack] = useState<string[]>([]);
   const [pageSize, setPageSize] = useState(DEFAULT_PAGE_SIZE);
 
+  const canSeeRows = canViewQueryRows(user?.roles);
   const { data: rowsData, isLoading: isLoadingRows } = useQueryRows(uid, {
     cursor,
     limit: pageSize,
+    enabled: canSeeRows,
   });
 
   const goNext = () => {
@@ -157,6 +159,11 @@ function QueryDetailPage() {
               ) : (
                 <Badge variant="secondary">Success</Badge>
               )}
+              {query.dry_run && (
+                <Badge variant="outline" className="ml-2">
+                  Dry run, rolled back
+                </Badge>
+              )}
             </div>
             <div>
               <div className="text-sm font-medium text-muted-foreground mb-1">
@@ -174,6 +181,18 @@ function QueryDetailPage() {
               </div>
               <div>{query.rows_affected ?? "-"}</div>
             </div>
+            {query.repeat_count != null && query.repeat_count > 1 && (
+              <div>
+                <div className="text-sm font-medium text-muted-foreground mb-1">
+                  Repeated
+                </div>
+                <div>
+                  {query.repeat_count.toLocaleString()} times
+                  {query.last_executed_at &&
+                    `, last ${format(new Date(query.last_executed_at), "PPpp")}`}
+                </div>
+              </div>
+            )}
             {query.error && (
               <div>
                 <div className="text-sm font-medium text-muted-foreground mb-1">
@@ -189,7 +208,19 @@ function QueryDetailPage() {
           <CardHeader>
             <CardTitle>SQL</CardTitle>
           </CardHeader>
-          <CardContent>
+          <CardContent className="space-y-2">
+            {query.sql_truncated && (
+              <div className="text-sm text-muted-foreground">
+                Truncated from {query.sql_text_bytes?.toLocaleString()} bytes
+                {query.sql_text_sha256 && (
+                  <>
+                    {" "}
+                    (SHA-256{" "}
+                    <span className="font-mono">{query.sql_text_sha256}</span>)
+                  </>
+                )}
+              </div>
+            )}
             <pre className="bg-muted p-4 rounded-md overflow-x-auto text-sm font-mono whitespace-pre-wrap">
               {query.sql_text}
             </pre>
@@ -223,111 +254,113 @@ function QueryDetailPage() {
         </Card>
       )}
 
-      <Card>
-        <CardHeader>
-          <CardTitle>
-            Result Rows
-            {totalRows > 0 && ` (${totalRows})`}
-          </CardTitle>
-        </CardHeader>
-        <CardContent>
-          {isLoadingRows ? (
-            <div className="flex justify-center py-8">
-              <LoadingSpinner />
-            </div>
-          ) : rowsData && rowsData.rows.length > 0 ? (
-            <div className="space-y-4">
-              <div className="overflow-x-auto">
-                <Table>
-                  <TableHeader>
-                    <TableRow>
-                      <TableHead className="w-16">#</TableHead>
-                      {Object.keys(rowsData.rows[0].row_data).map((key) => (
-                        <TableHead key={key}>{key}</TableHead>
-                      ))}
-                    </TableRow>
-                  </TableHeader>
-                  <TableBody>
-                    {rowsData.rows.map((row) => (
-                      <TableRow key={row.row_number}>
-                        <TableCell className="text-muted-foreground">
-                          {row.row_number}
-                        </TableCell>
-                        {Object.values(row.row_data).map((value, i) => (
-                          <TableCell key={i} className="font-mono text-sm">
-                            {formatValue(value)}
-                          </TableCell>
+      {canSeeRows && (
+        <Card>
+          <CardHeader>
+            <CardTitle>
+              Result Rows
+              {totalRows > 0 && ` (${totalRows})`}
+            </CardTitle>
+          </CardHeader>
+          <CardContent>
+            {isLoadingRows ? (
+              <div className="flex justify-center py-8">
+                <LoadingSpinner />
+              </div>
+            ) : rowsData && rowsData.rows.length > 0 ? (
+              <div className="space-y-4">
+                <div className="overflow-x-auto">
+                  <Table>
+                    <TableHeader>
+                      <TableRow>
+                        <TableHead className="w-16">#</TableHead>
+                        {Object.keys(rowsData.rows[0].row_data).map((key) => (
+                          <TableHead key={key}>{key}</TableHead>
                         ))}
                       </TableRow>
-                    ))}
-                  </TableBody>
-                </Table>
-              </div>
-
-              {/* Pagination controls */}
-              <div className="flex items-center justify-between">
-                <div className="flex items-center gap-2 text-sm text-muted-foreground">
-                  <span>Rows per page:</span>
-                  {PAGE_SIZE_OPTIONS.map((opt) => (
-                    <Button
-                      key={opt}
-                      variant={opt === pageSize ? "secondary" : "ghost"}
-                      size="sm"
-                      className="h-7 px-2"
-                      onClick={() => changePageSize(opt)}
-                    >
-                      {opt}
-                    </Button>
-                  ))}
+                    </TableHeader>
+                    <TableBody>
+                      {rowsData.rows.map((row) => (
+                        <TableRow key={row.row_number}>
+                          <TableCell className="text-muted-foreground">
+                            {row.row_number}
+                          </TableCell>
+                          {Object.values(row.row_data).map((value, i) => (
+                            <TableCell key={i} className="font-mono text-sm">
+                              {formatValue(value)}
+                            </TableCell>
+                          ))}
+                        </TableRow>
+                      ))}
+                    </TableBody>
+                  </Table>
                 </div>
 
-                <div className="flex items-center gap-4">
-                  {firstRowNum != null &&
-                    lastRowNum != null &&
-                    totalRows > 0 && (
-                      <span className="text-sm text-muted-foreground">
-                        Rows {firstRowNum}-{lastRowNum} of {totalRows}
-                      </span>
-                    )}
+                {/* Pagination controls */}
+                <div className="flex items-center justify-between">
+                  <div className="flex items-center gap-2 text-sm text-muted-foreground">
+                    <span>Rows per page:</span>
+                    {PAGE_SIZE_OPTIONS.map((opt) => (
+                      <Button
+                        key={opt}
+                        variant={opt === pageSize ? "secondary" : "ghost"}
+                        size="sm"
+                        className="h-7 px-2"
+                        onClick={() => changePageSize(opt)}
+                      >
+                        {opt}
+                      </Button>
+                    ))}
+                  </div>
 
-                  <div className="flex items-center gap-1">
-                    {hasPrevious && (
-                      <>
-                        <Button
-                          variant="outline"
-                          size="sm"
-                          onClick={goFirst}
-                          title="First page"
-                        >
-                          <ChevronsLeft className="h-4 w-4" />
-                        </Button>
-                        <Button
-                          variant="outline"
-                          size="sm"
-                          onClick={goPrevious}
-                        >
-                          <ChevronLeft className="h-4 w-4 mr-1" />
-                          Previous
+                  <div className="flex items-center gap-4">
+                    {firstRowNum != null &&
+                      lastRowNum != null &&
+                      totalRows > 0 && (
+                        <span className="text-sm text-muted-foreground">
+                          Rows {firstRowNum}-{lastRowNum} of {totalRows}
+                        </span>
+                      )}
+
+                    <div className="flex items-center gap-1">
+                      {hasPrevious && (
+                        <>
+                          <Button
+                            variant="outline"
+                            size="sm"
+                            onClick={goFirst}
+                            title="First page"
+                          >
+                            <ChevronsLeft className="h-4 w-4" />
+                          </Button>
+                          <Button
+                            variant="outline"
+                            size="sm"
+                            onClick={goPrevious}
+                          >
+                            <ChevronLeft className="h-4 w-4 mr-1" />
+                            Previous
+                          </Button>
+                        </>
+                      )}
+                      {hasNext && (
+                        <Button variant="outline" size="sm" onClick={goNext}>
+                          Next
+                          <ChevronRight className="h-4 w-4 ml-1" />
                         </Button>
-                      </>
-                    )}
-                    {hasNext && (
-                      <Button variant="outline" size="sm" onClick={goNext}>
-                        Next
-                        <ChevronRight className="h-4 w-4 ml-1" />
-                      </Button>
-                    )}
+                      )}
+                    </div>
                   </div>
                 </div>
               </div>
-            </div>
-          ) : (
-            <div className="text-center text-muted-foreground py-4">
-              No result rows
-            </div>
-          )}
-        </CardContent>
-      </Card>
+            ) : (
+              <div className="text-center text-muted-foreground py-4">
+                No result rows
+              </div>
+            )}
+          </CardContent>
+        </Card>
+      )}
     </div>
   );
 }
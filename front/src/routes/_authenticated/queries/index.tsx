@@ -1,12 +1,12 @@
-import { useRef, useCallback } from "react";
+import { useRef, useCallback, useState } from "react";
 import { createFileRoute, Link } from "@tanstack/react-router";
-import { useQueries, useUsers, useDatabases, type Query } from "@/api";
+import { useQueries, useQueryStream, useUsers, useDatabases, type Query } from "@/api";
 import { DataTable, type Column } from "@/components/shared/DataTable";
 import { PageHeader } from "@/components/shared/PageHeader";
 import { AdaptiveRefresh } from "@/components/shared/AdaptiveRefresh";
 import { Badge } from "@/components/ui/badge";
 import { Button } from "@/components/ui/button";
-import { X, ChevronLeft, ChevronRight } from "lucide-react";
+import { X, ChevronLeft, ChevronRight, Radio } from "lucide-react";
 import { formatDistanceToNow } from "date-fns";
 import { useAuth } from "@/contexts/AuthContext";
 import { canViewQueries } from "@/lib/permissions";
@@ -32,6 +32,8 @@ function QueriesPage() {
     before,
     limit: size,
   });
+  const [live, setLive] = useState(false);
+  const stream = useQueryStream({ connection_id }, { enabled: live });
   const { data: users } = useUsers();
   const { data: databases } = useDatabases();
 
@@ -72,6 +74,11 @@ function QueriesPage() {
           <span className="font-mono text-xs break-all line-clamp-2">
             {q.sql_text}
           </span>
+          {q.repeat_count != null && q.repeat_count > 1 && (
+            <span className="text-xs text-muted-foreground">
+              ×{q.repeat_count.toLocaleString()}
+            </span>
+          )}
         </div>
       ),
     },
@@ -152,6 +159,17 @@ function QueriesPage() {
         actions={
           <div className="flex items-center gap-4">
             {isFirstPage && (
+              <Button
+                variant={live ? "secondary" : "outline"}
+                size="sm"
+                onClick={() => setLive(!live)}
+                title="Show queries as they are logged"
+              >
+                <Radio className={`h-4 w-4 mr-1 ${live ? "text-red-500 animate-pulse" : ""}`} />
+                Live
+              </Button>
+            )}
+            {isFirstPage && !live && (
               <AdaptiveRefresh
                 onRefresh={handleRefresh}
                 storageKey="dbbat.autoRefresh.queries"
@@ -176,62 +194,72 @@ function QueriesPage() {
         }
       />
 
+      {live && (stream.error || stream.dropped > 0) && (
+        <p className="text-sm text-muted-foreground">
+          {stream.error
+            ? stream.error.message
+            : `${stream.dropped.toLocaleString()} queries were logged too fast to be shown.`}
+        </p>
+      )}
+
       <DataTable
         columns={columns}
-        data={queries ?? []}
-        isLoading={isLoading}
+        data={live ? stream.queries : queries ?? []}
+        isLoading={!live && isLoading}
         rowKey={(q) => q.uid}
-        emptyMessage="No queries recorded"
+        emptyMessage={live ? "Waiting for queries…" : "No queries recorded"}
         rowHref={(q) => `/queries/${q.uid}`}
       />
 
       {/* Pagination */}
-      <div className="flex items-center justify-between">
-        <div className="flex items-center gap-2 text-sm text-muted-foreground">
-          <span>Rows per page:</span>
-          {PAGE_SIZE_OPTIONS.map((opt) => (
-            <Button
-              key={opt}
-              variant={opt === size ? "secondary" : "ghost"}
-              size="sm"
-              className="h-7 px-2"
-              asChild
-            >
-              <Link
-                to="/queries"
-                search={{ connection_id, before: undefined, size: opt }}
+      {!live && (
+        <div className="flex items-center justify-between">
+          <div className="flex items-center gap-2 text-sm text-muted-foreground">
+            <span>Rows per page:</span>
+            {PAGE_SIZE_OPTIONS.map((opt) => (
+              <Button
+                key={opt}
+                variant={opt === size ? "secondary" : "ghost"}
+                size="sm"
+                className="h-7 px-2"
+                asChild
               >
-                {opt}
-              </Link>
-            </Button>
-          ))}
-        </div>
+                <Link
+                  to="/queries"
+                  search={{ connection_id, before: undefined, size: opt }}
+                >
+                  {opt}
+                </Link>
+              </Button>
+            ))}
+          </div>
 
-        <div className="flex items-center gap-2">
-          {!isFirstPage && (
-            <Button variant="outline" size="sm" asChild>
-              <Link
-                to="/queries"
-                search={{ connection_id, before: undefined, size }}
-              >
-                <ChevronLeft className="h-4 w-4 mr-1" />
-                Newer
-              </Link>
-            </Button>
-          )}
-          {hasMore && lastUid && (
-            <Button variant="outline" size="sm" asChild>
-              <Link
-                to="/queries"
-                search={{ connection_id, before: lastUid, size }}
-              >
-                Older
-                <ChevronRight className="h-4 w-4 ml-1" />
-              </Link>
-            </Button>
-          )}
+          <div className="flex items-center gap-2">
+            {!isFirstPage && (
+              <Button variant="outline" size="sm" asChild>
+                <Link
+                  to="/queries"
+                  search={{ connection_id, before: undefined, size }}
+                >
+                  <ChevronLeft className="h-4 w-4 mr-1" />
+                  Newer
+                </Link>
+              </Button>
+            )}
+            {hasMore && lastUid && (
+              <Button variant="outline" size="sm" asChild>
+                <Link
+                  to="/queries"
+                  search={{ connection_id, before: lastUid, size }}
+                >
+                  Older
+                  <ChevronRight className="h-4 w-4 ml-1" />
+                </Link>
+              </Button>
+            )}
+          </div>
         </div>
-      </div>
+      )}
     </div>
   );
 }
@@ -8,7 +8,10 @@ import {
   useDeleteDatabase,
   useSSHServers,
   useTestServerConnection,
+  useTestDatabaseConnection,
+  useCheckServerPrivileges,
   type ConnectionTestResult,
+  type PrivilegeCheckResult,
   type Database,
   type DatabaseLimited,
 } from "@/api";
@@ -60,6 +63,7 @@ import {
   AlertCircle,
   PlugZap,
   Loader2,
+  ShieldAlert,
 } from "lucide-react";
 import { toast } from "sonner";
 import { CopyableField } from "@/components/shared/CopyableField";
@@ -280,6 +284,28 @@ function ServersPage() {
           <span className="text-muted-foreground">-</span>
         ),
     },
+    {
+      key: "health",
+      header: "Health",
+      cell: (db) => {
+        const health = isFullDatabase(db) ? db.health : undefined;
+        if (!health) {
+          return <span className="text-muted-foreground">-</span>;
+        }
+        const klass = health.ok
+          ? "bg-green-100 text-green-700 dark:bg-green-900/30 dark:text-green-400"
+          : "bg-red-100 text-red-700 dark:bg-red-900/30 dark:text-red-400";
+        return (
+          <span
+            className={`inline-flex items-center rounded-full px-2 py-0.5 text-xs font-medium ${klass}`}
+            title={`${health.message} (checked ${new Date(health.checked_at).toLocaleString()})`}
+            data-testid={`database-health-${db.uid}`}
+          >
+            {health.ok ? "ok" : health.code}
+          </span>
+        );
+      },
+    },
     {
       key: "actions",
       header: "",
@@ -293,6 +319,7 @@ function ServersPage() {
               disabledReason={getDisabledReason("update-database", user?.roles)}
             />
           )}
+          {canUpdate && <CheckPrivilegesButton uid={db.uid} name={db.name} />}
           <PermissionButton
             variant="ghost"
             size="icon"
@@ -438,6 +465,97 @@ function TestConnectionButton({
   );
 }
 
+// CheckPrivilegesButton reports what the stored service account can do
+// upstream, and which of its privileges the proxy does not need.
+function CheckPrivilegesButton({ uid, name }: { uid: string; name: string }) {
+  const checkPrivileges = useCheckServerPrivileges(uid);
+  const [result, setResult] = useState<PrivilegeCheckResult | null>(null);
+  const privileges = result?.privileges;
+
+  return (
+    <>
+      <PermissionButton
+        data-testid={`database-privileges-${uid}`}
+        variant="ghost"
+        size="icon"
+        disabled={checkPrivileges.isPending}
+        enabledTooltip="Check the stored credentials' upstream privileges"
+        onClick={(e) => {
+          e.stopPropagation();
+          checkPrivileges.mutate(undefined, {
+            onSuccess: (res) => {
+              if (!res.ok) {
+                toast.error(describeTestResult(res));
+                return;
+              }
+              if (!res.privileges) {
+                toast.info(res.message);
+                return;
+              }
+              setResult(res);
+            },
+            onError: (error: Error) => toast.error(error.message),
+          });
+        }}
+      >
+        {checkPrivileges.isPending ? (
+          <Loader2 className="h-4 w-4 animate-spin" />
+        ) : (
+          <ShieldAlert className="h-4 w-4" />
+        )}
+      </PermissionButton>
+      <Dialog open={!!privileges} onOpenChange={(open) => !open && setResult(null)}>
+        <DialogContent className="max-w-lg" onClick={(e) => e.stopPropagation()}>
+          <DialogHeader>
+            <DialogTitle>Upstream privileges of {name}</DialogTitle>
+            <DialogDescription>
+              DBBat connects as <span className="font-mono">{privileges?.role}</span>.
+            </DialogDescription>
+          </DialogHeader>
+          {privileges && (
+            <div className="space-y-3 text-sm">
+              {privileges.warnings.length === 0 ? (
+                <Alert>
+                  <AlertDescription>
+                    No privilege beyond what the proxy needs.
+                  </AlertDescription>
+                </Alert>
+              ) : (
+                <Alert variant="destructive">
+                  <AlertCircle className="h-4 w-4" />
+                  <AlertDescription>
+                    <ul className="list-disc pl-4 space-y-1">
+                      {privileges.warnings.map((w) => (
+                        <li key={w}>{w}</li>
+                      ))}
+                    </ul>
+                  </AlertDescription>
+                </Alert>
+              )}
+              {privileges.member_of.length > 0 && (
+                <p>
+                  Member of:{" "}
+                  <span className="font-mono">{privileges.member_of.join(", ")}</span>
+                </p>
+              )}
+              {privileges.grants && privileges.grants.length > 0 && (
+                <pre className="max-h-48 overflow-auto rounded bg-muted p-2 font-mono text-xs">
+                  {privileges.grants.join("\n")}
+                </pre>
+              )}
+            </div>
+          )}
+          <DialogFooter>
+            <Button variant="outline" onClick={() => setResult(null)}>
+              Close
+            </Button>
+          </DialogFooter>
+        </DialogContent>
+      </Dialog>
+    </>
+  );
+}
+
 function CreateDatabaseDialog({ onClose }: { onClose: () => void }) {
   const [protocol, setProtocol] = useState<Protocol>("postgresql");
   const [name, setName] = useState("");
@@ -449,7 +567,10 @@ function CreateDatabaseDialog({ onClose }: { onClose: () => void }) {
   const [mongoAuthSource, setMongoAuthSource] = useState("");
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
+  const [secretRef, setSecretRef] = useState("");
   const [sslMode, setSslMode] = useState("prefer");
+  const [sslRootCert, setSslRootCert] = useState("");
+  const [readReplicas, setReadReplicas] = useState("");
   const [listable, setListable] = useState(true);
   const [viaUid, setViaUid] = useState<string>("");
   const [sshPrivateKey, setSshPrivateKey] = useState("");
@@ -468,6 +589,47 @@ function CreateDatabaseDialog({ onClose }: { onClose: () => void }) {
     },
   });
 
+  const testConnection = useTestDatabaseConnection();
+
+  // handleTestConnection logs in with the form's settings without saving
+  // them, so a typo shows up before the server is created.
+  const handleTestConnection = () => {
+    if (protocol === "ssh") return;
+    testConnection.mutate(
+      {
+        protocol,
+        host,
+        port: parseInt(port, 10),
+        database_name: protocol === "oracle" ? undefined : databaseName,
+        username,
+        password: secretRef ? undefined : password,
+        secret_ref: secretRef || undefined,
+        ssl_mode: protocol === "oracle" ? undefined : sslMode,
+        ssl_root_cert:
+          protocol !== "oracle" && sslMode !== "disable" && sslMode !== "prefer"
+            ? sslRootCert || undefined
+            : undefined,
+        oracle_service_name:
+          protocol === "oracle" ? oracleServiceName : undefined,
+        mongo_auth_source:
+          protocol === "mongodb" && mongoAuthSource
+            ? mongoAuthSource
+            : undefined,
+        via_uid: viaUid || undefined,
+      },
+      {
+        onSuccess: (result) => {
+          if (result.ok) {
+            toast.success(result.message);
+            return;
+          }
+          toast.error(describeTestResult(result));
+        },
+        onError: (error: Error) => toast.error(error.message),
+      }
+    );
+  };
+
   const handleSubmit = (e: React.FormEvent) => {
     e.preventDefault();
     if (isSSH) {
@@ -494,8 +656,13 @@ function CreateDatabaseDialog({ onClose }: { onClose: () => void }) {
       database_name:
         protocol === "oracle" ? oracleServiceName : databaseName,
       username,
-      password,
+      password: secretRef ? undefined : password,
+      secret_ref: secretRef || undefined,
       ssl_mode: protocol === "oracle" ? "" : sslMode,
+      ssl_root_cert:
+        protocol !== "oracle" && sslMode !== "disable" && sslMode !== "prefer"
+          ? sslRootCert || undefined
+          : undefined,
       protocol,
       oracle_service_name:
         protocol === "oracle" ? oracleServiceName : undefined,
@@ -503,6 +670,13 @@ function CreateDatabaseDialog({ onClose }: { onClose: () => void }) {
         protocol === "mongodb" && mongoAuthSource
           ? mongoAuthSource
           : undefined,
+      read_replicas:
+        protocol === "postgresql"
+          ? readReplicas
+              .split(",")
+              .map((r) => r.trim())
+              .filter(Boolean)
+          : undefined,
       listable,
       via_uid: viaUid || undefined,
     });
@@ -667,9 +841,25 @@ function CreateDatabaseDialog({ onClose }: { onClose: () => void }) {
               type="password"
               value={password}
               onChange={(e) => setPassword(e.target.value)}
-              required={!isSSH}
+              required={!isSSH && !secretRef}
+              disabled={!!secretRef}
             />
           </div>
+          {!isSSH && (
+            <div className="space-y-2">
+              <Label htmlFor="secretRef">Secret reference (optional)</Label>
+              <Input
+                id="secretRef"
+                value={secretRef}
+                onChange={(e) => setSecretRef(e.target.value)}
+                placeholder="vault://secret/dbbat/orders#password"
+              />
+              <p className="text-xs text-muted-foreground">
+                Fetch the password from Vault, AWS Secrets Manager or Google
+                Secret Manager when connecting, instead of storing it.
+              </p>
+            </div>
+          )}
           {isSSH && (
             <>
               <div className="space-y-2">
@@ -713,6 +903,39 @@ function CreateDatabaseDialog({ onClose }: { onClose: () => void }) {
               </Select>
             </div>
           )}
+          {!isSSH &&
+            protocol !== "oracle" &&
+            sslMode !== "disable" &&
+            sslMode !== "prefer" && (
+              <div className="space-y-2">
+                <Label htmlFor="sslRootCert">CA Certificate (PEM, optional)</Label>
+                <textarea
+                  id="sslRootCert"
+                  className="flex min-h-[96px] w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm font-mono shadow-sm focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                  value={sslRootCert}
+                  onChange={(e) => setSslRootCert(e.target.value)}
+                  placeholder="-----BEGIN CERTIFICATE-----"
+                />
+                <p className="text-xs text-muted-foreground">
+                  Verifies the upstream certificate. Empty uses the system roots.
+                </p>
+              </div>
+            )}
+          {protocol === "postgresql" && (
+            <div className="space-y-2">
+              <Label htmlFor="readReplicas">Read Replicas (optional)</Label>
+              <Input
+                id="readReplicas"
+                placeholder="replica-1.internal, replica-2.internal:5433"
+                value={readReplicas}
+                onChange={(e) => setReadReplicas(e.target.value)}
+              />
+              <p className="text-xs text-muted-foreground">
+                Comma-separated host or host:port. Read-only grants are spread
+                over them; an unreachable replica is skipped.
+              </p>
+            </div>
+          )}
           {!isSSH && (
             <div className="space-y-2">
               <Label htmlFor="viaUid">Via SSH server</Label>
@@ -743,6 +966,22 @@ function CreateDatabaseDialog({ onClose }: { onClose: () => void }) {
           <Button type="button" variant="outline" onClick={onClose}>
             Cancel
           </Button>
+          {!isSSH && (
+            <Button
+              type="button"
+              variant="outline"
+              data-testid="database-create-test"
+              disabled={!host || !port || !username || testConnection.isPending}
+              onClick={handleTestConnection}
+            >
+              {testConnection.isPending ? (
+                <Loader2 className="mr-2 h-4 w-4 animate-spin" />
+              ) : (
+                <PlugZap className="mr-2 h-4 w-4" />
+              )}
+              Test connection
+            </Button>
+          )}
           <Button type="submit" data-testid="database-create-submit" disabled={createDb.isPending}>
             Create
           </Button>
@@ -0,0 +1,247 @@
+import { useMemo, useState } from "react";
+import { createFileRoute } from "@tanstack/react-router";
+import { Check, X } from "lucide-react";
+import { toast } from "sonner";
+
+import {
+  useStatementApprovals,
+  useDatabases,
+  useUsers,
+  useApproveStatementApproval,
+  useDenyStatementApproval,
+  type StatementApproval,
+} from "@/api";
+import { PageHeader } from "@/components/shared/PageHeader";
+import { DataTable, type Column } from "@/components/shared/DataTable";
+import { Button } from "@/components/ui/button";
+import { Label } from "@/components/ui/label";
+import { Textarea } from "@/components/ui/textarea";
+import {
+  Dialog,
+  DialogContent,
+  DialogDescription,
+  DialogFooter,
+  DialogHeader,
+  DialogTitle,
+} from "@/components/ui/dialog";
+import { useAuth } from "@/contexts/AuthContext";
+import { canApproveStatement } from "@/lib/permissions";
+
+export const Route = createFileRoute("/_authenticated/statement-approvals/")({
+  component: StatementApprovalsPage,
+});
+
+const STATUS_BADGE: Record<StatementApproval["status"], string> = {
+  pending: "bg-yellow-100 text-yellow-700 dark:bg-yellow-900/30 dark:text-yellow-400",
+  approved: "bg-green-100 text-green-700 dark:bg-green-900/30 dark:text-green-400",
+  denied: "bg-red-100 text-red-700 dark:bg-red-900/30 dark:text-red-400",
+  expired: "bg-muted text-muted-foreground",
+};
+
+function StatementApprovalsPage() {
+  const { user } = useAuth();
+  const isAdmin = canApproveStatement(user?.roles);
+
+  const [tab, setTab] = useState<"pending" | "all">("pending");
+  const [denying, setDenying] = useState<StatementApproval | null>(null);
+
+  const { data: approvals = [], isLoading } = useStatementApprovals(
+    tab === "pending" ? { status: "pending" } : {}
+  );
+
+  const { data: users = [] } = useUsers();
+  const { data: databases = [] } = useDatabases();
+
+  const userMap = useMemo(
+    () => Object.fromEntries((users ?? []).map((u) => [u.uid, u.username])),
+    [users]
+  );
+  const dbMap = useMemo(
+    () => Object.fromEntries((databases ?? []).map((d) => [d.uid, d.name])),
+    [databases]
+  );
+
+  const approve = useApproveStatementApproval({
+    onSuccess: () => toast.success("Approved"),
+    onError: (e) => toast.error(e.message),
+  });
+
+  const columns: Column<StatementApproval>[] = [
+    {
+      key: "user",
+      header: "User",
+      cell: (a: StatementApproval) =>
+        userMap[a.user_id] ?? a.user_id.slice(0, 8),
+    },
+    {
+      key: "database",
+      header: "Database",
+      cell: (a: StatementApproval) =>
+        dbMap[a.database_id] ?? a.database_id.slice(0, 8),
+    },
+    {
+      key: "sql_text",
+      header: "Statement",
+      cell: (a: StatementApproval) => (
+        <code
+          className="text-xs whitespace-pre-wrap break-all"
+          data-testid={`statement-sql-${a.uid}`}
+        >
+          {a.sql_text}
+        </code>
+      ),
+    },
+    {
+      key: "status",
+      header: "Status",
+      cell: (a: StatementApproval) => (
+        <span
+          className={`text-xs px-1.5 py-0.5 rounded ${STATUS_BADGE[a.status]}`}
+          title={a.decision_reason ?? undefined}
+        >
+          {a.status}
+        </span>
+      ),
+    },
+    {
+      key: "requested_at",
+      header: "Requested",
+      cell: (a: StatementApproval) =>
+        new Date(a.requested_at).toLocaleString(),
+    },
+    {
+      key: "expires_at",
+      header: "Expires",
+      cell: (a: StatementApproval) =>
+        a.status === "pending" ? new Date(a.expires_at).toLocaleString() : "",
+    },
+    {
+      key: "actions",
+      header: "",
+      // A statement needs a second person: its own user cannot decide on it.
+      cell: (a: StatementApproval) =>
+        !isAdmin || a.status !== "pending" || a.user_id === user?.uid ? null : (
+          <div className="flex gap-1 justify-end">
+            <Button
+              size="sm"
+              variant="ghost"
+              onClick={() => approve.mutate(a.uid)}
+              data-testid={`approve-statement-${a.uid}`}
+              title="Approve"
+            >
+              <Check className="h-4 w-4 text-green-600" />
+            </Button>
+            <Button
+              size="sm"
+              variant="ghost"
+              onClick={() => setDenying(a)}
+              data-testid={`deny-statement-${a.uid}`}
+              title="Deny"
+            >
+              <X className="h-4 w-4 text-red-600" />
+            </Button>
+          </div>
+        ),
+    },
+  ];
+
+  return (
+    <div className="container mx-auto py-6">
+      <PageHeader
+        title="Statement Approvals"
+        description={
+          isAdmin
+            ? "Approve or deny write statements held by the write_requires_approval control."
+            : "Track your write statements awaiting an administrator's approval."
+        }
+      />
+
+      <div className="flex gap-2 mb-4">
+        <Button
+          size="sm"
+          variant={tab === "pending" ? "default" : "outline"}
+          onClick={() => setTab("pending")}
+        >
+          Pending
+        </Button>
+        <Button
+          size="sm"
+          variant={tab === "all" ? "default" : "outline"}
+          onClick={() => setTab("all")}
+        >
+          All
+        </Button>
+      </div>
+
+      <DataTable
+        columns={columns}
+        data={approvals}
+        isLoading={isLoading}
+        rowKey={(a: StatementApproval) => a.uid}
+        emptyMessage={
+          tab === "pending"
+            ? "No statements awaiting approval."
+            : "No statement approvals yet."
+        }
+      />
+
+      <DenyDialog approval={denying} onClose={() => setDenying(null)} />
+    </div>
+  );
+}
+
+function DenyDialog({
+  approval,
+  onClose,
+}: {
+  approval: StatementApproval | null;
+  onClose: () => void;
+}) {
+  const [reason, setReason] = useState("");
+
+  const deny = useDenyStatementApproval({
+    onSuccess: () => {
+      toast.success("Denied");
+      setReason("");
+      onClose();
+    },
+    onError: (e) => toast.error(e.message),
+  });
+
+  return (
+    <Dialog open={!!approval} onOpenChange={(o) => !o && onClose()}>
+      <DialogContent>
+        <DialogHeader>
+          <DialogTitle>Deny statement?</DialogTitle>
+          <DialogDescription>
+            The proxy refuses the statement. Optionally include a reason; the
+            user's client shows it in the error.
+          </DialogDescription>
+        </DialogHeader>
+        <div className="py-2">
+          <Label htmlFor="deny-statement-reason">Reason (optional)</Label>
+          <Textarea
+            id="deny-statement-reason"
+            value={reason}
+            onChange={(e) => setReason(e.target.value)}
+            maxLength={1000}
+            placeholder="Missing WHERE clause; please scope the update."
+          />
+        </div>
+        <DialogFooter>
+          <Button variant="outline" onClick={onClose}>
+            Cancel
+          </Button>
+          <Button
+            variant="destructive"
+            onClick={() =>
+              approval && deny.mutate({ uid: approval.uid, reason })
+            }
+          >
+            Deny
+          </Button>
+        </DialogFooter>
+      </DialogContent>
+    </Dialog>
+  );
+}
@@ -255,6 +255,7 @@ function UsersPage() {
 const ROLE_OPTIONS: { value: UserRole; label: string }[] = [
   { value: "admin", label: "Administrator" },
   { value: "viewer", label: "Viewer" },
+  { value: "auditor", label: "Auditor (SQL only)" },
   { value: "connector", label: "Connector" },
 ];
 
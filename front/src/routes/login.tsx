@@ -22,7 +22,7 @@ import {
   TooltipContent,
   TooltipTrigger,
 } from "@/components/ui/tooltip";
-import { Loader2, CheckCircle2, Gamepad2, LogOut } from "lucide-react";
+import { Loader2, CheckCircle2, Gamepad2, KeyRound, LogOut } from "lucide-react";
 
 export const Route = createFileRoute("/login")({
   component: LoginPage,
@@ -58,6 +58,8 @@ function oauthErrorMessage(code: string): string {
       return "Failed to create your account. Contact an administrator.";
     case "slack_not_linked":
       return "No account is linked to your Slack identity. Contact an administrator.";
+    case "OAUTH_NO_ROLE":
+      return "Your account has no DBBat role at your identity provider. Contact an administrator.";
     default:
       return "An error occurred during login. Please try again.";
   }
@@ -76,6 +78,7 @@ function LoginPage() {
   const { data: versionInfo } = useVersion();
   const { data: providers } = useAuthProviders();
   const slackProvider = providers?.find((p) => p.type === "slack" && p.enabled);
+  const oidcProvider = providers?.find((p) => p.type === "oidc" && p.enabled);
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
   const [newPassword, setNewPassword] = useState("");
@@ -404,8 +407,8 @@ function LoginPage() {
             </form>
           )}
 
-          {/* Slack OAuth button */}
-          {slackProvider && (
+          {/* SSO buttons */}
+          {(slackProvider || oidcProvider) && (
             <>
               <div className="relative my-4">
                 <div className="absolute inset-0 flex items-center">
@@ -418,17 +421,34 @@ function LoginPage() {
                 </div>
               </div>
 
-              <Button
-                variant="outline"
-                className="w-full"
-                onClick={() => {
-                  window.location.href = slackProvider.authorize_url!;
-                }}
-                data-testid="slack-login-button"
-              >
-                <SlackIcon className="mr-2 h-4 w-4" />
-                Sign in with Slack
-              </Button>
+              <div className="space-y-2">
+                {oidcProvider && (
+                  <Button
+                    variant="outline"
+                    className="w-full"
+                    onClick={() => {
+                      window.location.href = oidcProvider.authorize_url!;
+                    }}
+                    data-testid="oidc-login-button"
+                  >
+                    <KeyRound className="mr-2 h-4 w-4" />
+                    Sign in with {oidcProvider.display_name || "SSO"}
+                  </Button>
+                )}
+                {slackProvider && (
+                  <Button
+                    variant="outline"
+                    className="w-full"
+                    onClick={() => {
+                      window.location.href = slackProvider.authorize_url!;
+                    }}
+                    data-testid="slack-login-button"
+                  >
+                    <SlackIcon className="mr-2 h-4 w-4" />
+                    Sign in with Slack
+                  </Button>
+                )}
+              </div>
             </>
           )}
         </CardContent>
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Request ID propagation.
+const (
+	requestIDHeader     = "X-Request-ID"
+	contextKeyRequestID = "request_id"
+)
+
+// requestIDPattern bounds what an inbound X-Request-ID may contain before it
+// is echoed back and written to logs: IDs from upstream proxies (UUIDs,
+// trace IDs) pass through, anything else is replaced with a fresh UUID.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// accessLogFileMode is the permission used when creating an access log file.
+const accessLogFileMode = 0o640
+
+// newAccessLogger builds the logger access log entries are written to. An
+// empty output reuses the application logger; the returned closer is nil
+// unless a file was opened.
+func newAccessLogger(cfg config.AccessLogConfig, logger *slog.Logger) (*slog.Logger, io.Closer, error) {
+	var (
+		writer io.Writer
+		closer io.Closer
+	)
+
+	switch cfg.Output {
+	case "":
+		return logger, nil, nil
+	case "stdout":
+		writer = os.Stdout
+	case "stderr":
+		writer = os.Stderr
+	default:
+		f, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, accessLogFileMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open access log %s: %w", cfg.Output, err)
+		}
+
+		writer, closer = f, f
+	}
+
+	return slog.New(slog.NewJSONHandler(writer, nil)), closer, nil
+}
+
+// requestIDMiddleware assigns every request an ID — the caller's
+// X-Request-ID when it is well-formed, a new UUID otherwise — and echoes it in
+// the response so clients can quote it when reporting a problem.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(contextKeyRequestID, requestID)
+		c.Header(requestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// getRequestID returns the current request's ID, or "" outside the middleware.
+func getRequestID(c *gin.Context) string {
+	return c.GetString(contextKeyRequestID)
+}
+
+// loggingMiddleware writes one structured access log entry per request,
+// attributed to the authenticated principal (user and API key) so API usage
+// can be audited per caller. Successful requests are sampled according to
+// the access log configuration; errors are always logged.
+func (s *Server) loggingMiddleware() gin.HandlerFunc {
+	sampleRate := config.DefaultAccessLogSampleRate
+	if s.config != nil {
+		sampleRate = s.config.AccessLog.SampleRate
+	}
+
+	logger := s.accessLogger
+	if logger == nil {
+		logger = s.logger
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		query := c.Request.URL.RawQuery
+
+		c.Next()
+
+		statusCode := c.Writer.Status()
+		if !shouldLogRequest(statusCode, sampleRate) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", getRequestID(c)),
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("query", query),
+			slog.Int("status", statusCode),
+			slog.Int("response_size", max(c.Writer.Size(), 0)),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+
+		if user := getCurrentUser(c); user != nil {
+			attrs = append(attrs,
+				slog.String("user_uid", user.UID.String()),
+				slog.String("username", user.Username),
+				slog.String("auth_method", getAuthMethod(c)),
+			)
+		}
+
+		if apiKey := getCurrentAPIKey(c); apiKey != nil {
+			attrs = append(attrs, slog.String("api_key_id", apiKey.ID.String()))
+		}
+
+		logger.LogAttrs(c.Request.Context(), slog.LevelInfo, "API request", attrs...)
+	}
+}
+
+// shouldLogRequest applies access log sampling: error responses are always
+// logged, successful ones with probability sampleRate.
+func shouldLogRequest(statusCode int, sampleRate float64) bool {
+	if statusCode >= http.StatusBadRequest || sampleRate >= 1 {
+		return true
+	}
+
+	if sampleRate <= 0 {
+		return false
+	}
+
+	return rand.Float64() < sampleRate //nolint:gosec // sampling, not security-sensitive
+}
+
+// getCurrentAPIKey returns the API key (or web session) that authenticated
+// the request, or nil for Basic Auth and unauthenticated requests.
+func getCurrentAPIKey(c *gin.Context) *store.APIKey {
+	value, exists := c.Get(contextKeyAPIKey)
+	if !exists {
+		return nil
+	}
+
+	apiKey, _ := value.(*store.APIKey)
+
+	return apiKey
+}
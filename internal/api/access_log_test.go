@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// accessLogRouter returns a router with the access log middleware writing
+// JSON entries to buf, and an authenticated /ok plus a failing /fail route.
+func accessLogRouter(t *testing.T, buf *bytes.Buffer, sampleRate float64) *gin.Engine {
+	t.Helper()
+
+	s := &Server{
+		config:       &config.Config{AccessLog: config.AccessLogConfig{SampleRate: sampleRate}},
+		logger:       slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		accessLogger: slog.New(slog.NewJSONHandler(buf, nil)),
+	}
+
+	router := gin.New()
+	router.Use(requestIDMiddleware())
+	router.Use(s.loggingMiddleware())
+
+	user := &store.User{UID: uuid.New(), Username: "alice"}
+	apiKey := &store.APIKey{ID: uuid.New()}
+
+	router.GET("/ok", func(c *gin.Context) {
+		c.Set(contextKeyUser, user)
+		c.Set(contextKeyAPIKey, apiKey)
+		c.Set(contextKeyAuthMethod, authMethodAPIKey)
+		c.String(http.StatusOK, "hello")
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	return router
+}
+
+func decodeAccessLog(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestAccessLog_AttributesRequest(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	router := accessLogRouter(t, &buf, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/ok?x=1", nil)
+	req.Header.Set(requestIDHeader, "trace-123")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "trace-123", w.Header().Get(requestIDHeader))
+
+	entries := decodeAccessLog(t, &buf)
+	require.Len(t, entries, 1)
+
+	entry := entries[0]
+	assert.Equal(t, "trace-123", entry["request_id"])
+	assert.Equal(t, "/ok", entry["path"])
+	assert.Equal(t, "x=1", entry["query"])
+	assert.InDelta(t, http.StatusOK, entry["status"], 0)
+	assert.InDelta(t, len("hello"), entry["response_size"], 0)
+	assert.Equal(t, "alice", entry["username"])
+	assert.Equal(t, authMethodAPIKey, entry["auth_method"])
+	assert.NotEmpty(t, entry["user_uid"])
+	assert.NotEmpty(t, entry["api_key_id"])
+}
+
+func TestAccessLog_GeneratesRequestID(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	router := accessLogRouter(t, &buf, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(requestIDHeader, "bad id\nwith newline")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	_, err := uuid.Parse(w.Header().Get(requestIDHeader))
+	require.NoError(t, err, "malformed inbound IDs must be replaced")
+
+	entries := decodeAccessLog(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, w.Header().Get(requestIDHeader), entries[0]["request_id"])
+	assert.NotContains(t, entries[0], "username")
+}
+
+func TestAccessLog_SamplingKeepsErrors(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	router := accessLogRouter(t, &buf, 0)
+
+	for _, path := range []string{"/ok", "/ok", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	entries := decodeAccessLog(t, &buf)
+	require.Len(t, entries, 1, "only the error response should be logged at sample rate 0")
+	assert.Equal(t, "/fail", entries[0]["path"])
+}
+
+func TestShouldLogRequest(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, shouldLogRequest(http.StatusOK, 1))
+	assert.False(t, shouldLogRequest(http.StatusOK, 0))
+	assert.True(t, shouldLogRequest(http.StatusNotFound, 0))
+	assert.True(t, shouldLogRequest(http.StatusBadGateway, 0))
+}
+
+func TestNewAccessLogger_File(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	logger, closer, err := newAccessLogger(config.AccessLogConfig{Output: path}, slog.Default())
+	require.NoError(t, err)
+	require.NotNil(t, closer)
+
+	logger.Info("API request", slog.String("path", "/x"))
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"path":"/x"`)
+}
+
+func TestNewAccessLogger_DefaultsToAppLogger(t *testing.T) {
+	t.Parallel()
+
+	appLogger := slog.Default()
+
+	logger, closer, err := newAccessLogger(config.AccessLogConfig{}, appLogger)
+	require.NoError(t, err)
+	assert.Nil(t, closer)
+	assert.Same(t, appLogger, logger)
+
+	_, _, err = newAccessLogger(config.AccessLogConfig{Output: filepath.Join(t.TempDir(), "missing", "a.log")}, appLogger)
+	require.Error(t, err)
+}
@@ -1,11 +1,15 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/fclairamb/dbbat/internal/auth/ldap"
+	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/crypto"
 	"github.com/fclairamb/dbbat/internal/store"
 )
@@ -68,23 +72,54 @@ func (s *Server) handleLogin(c *gin.Context) {
 
 	// Look up user
 	user, err := s.store.GetUserByUsername(ctx, req.Username)
-	if err != nil {
-		s.authFailureTracker.recordFailure(req.Username)
-		writeError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "invalid credentials")
+	if err != nil && !errors.Is(err, store.ErrUserNotFound) {
+		writeInternalError(c, s.logger, err, "failed to look up user")
 		return
 	}
 
-	// Verify password
-	valid, err := crypto.VerifyPassword(user.PasswordHash, req.Password)
-	if err != nil || !valid {
-		s.authFailureTracker.recordFailure(req.Username)
-		writeError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "invalid credentials")
-		return
+	if s.isDirectoryLogin(user) {
+		user, err = s.directoryLogin(ctx, user, req.Username, req.Password)
+
+		switch {
+		case err == nil:
+		case errors.Is(err, errOAuthNoRole):
+			writeError(c, http.StatusForbidden, ErrCodeOAuthNoRole, "none of your directory groups grants a role")
+			return
+		case errors.Is(err, ldap.ErrInvalidCredentials), errors.Is(err, errOAuthUserNotLinked):
+			s.authFailureTracker.recordFailure(req.Username)
+			writeError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "invalid credentials")
+			return
+		default:
+			s.logger.ErrorContext(ctx, "LDAP authentication failed", slog.String("username", req.Username), slog.Any("error", err))
+			writeError(c, http.StatusBadGateway, ErrCodeOAuthProviderError, "the directory could not be reached")
+			return
+		}
+	} else {
+		// Verify password
+		valid := false
+		if user != nil {
+			valid, err = crypto.VerifyPassword(user.PasswordHash, req.Password)
+		}
+
+		if err != nil || !valid {
+			s.authFailureTracker.recordFailure(req.Username)
+			writeError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "invalid credentials")
+			return
+		}
 	}
 
 	// Reset failure count on successful login
 	s.authFailureTracker.resetFailures(req.Username)
 
+	// Checked once the password is verified, so the answer does not tell
+	// which accounts exist.
+	if !s.localLoginAllowed(user) {
+		writeError(c, http.StatusForbidden, ErrCodeLocalLoginDisabled, "password sign-in is disabled, sign in with SSO")
+		return
+	}
+
+	s.upgradePasswordHash(ctx, user, req.Password)
+
 	// Check if password change is required BEFORE creating a session
 	// Users with unchanged passwords cannot login - they must change password first
 	if !user.HasChangedPassword() {
@@ -117,6 +152,34 @@ func (s *Server) handleLogin(c *gin.Context) {
 	})
 }
 
+// localLoginMode returns who may sign in to the API with a local password.
+func (s *Server) localLoginMode() string {
+	if s.config == nil || s.config.LocalLogin == "" {
+		return config.LocalLoginEnabled
+	}
+
+	return s.config.LocalLogin
+}
+
+// localLoginAllowed reports whether the user may authenticate to the API
+// with their local password, through the login form or Basic Auth. With
+// SSO set up, local accounts can be kept as a break-glass path for admins.
+func (s *Server) localLoginAllowed(user *store.User) bool {
+	// Directory users sign in with their directory password, not a local one.
+	if user.PasswordHash == crypto.DirectoryPasswordHash {
+		return true
+	}
+
+	switch s.localLoginMode() {
+	case config.LocalLoginDisabled:
+		return false
+	case config.LocalLoginAdmins:
+		return user.IsAdmin()
+	default:
+		return true
+	}
+}
+
 // handleLogout revokes the current web session
 // POST /api/auth/logout
 func (s *Server) handleLogout(c *gin.Context) {
@@ -478,3 +541,20 @@ func (s *Server) handleResetPassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
 }
+
+// upgradePasswordHash rehashes the password a user just logged in with when
+// its stored hash uses another algorithm or other parameters than the
+// configured ones. A failure only delays the upgrade to the next login.
+func (s *Server) upgradePasswordHash(ctx context.Context, user *store.User, password string) {
+	upgraded, err := s.store.UpgradePasswordHash(ctx, user.UID, user.PasswordHash, password)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to upgrade password hash",
+			slog.String("user_id", user.UID.String()), slog.Any("error", err))
+
+		return
+	}
+
+	if upgraded {
+		s.logger.InfoContext(ctx, "password hash upgraded", slog.String("user_id", user.UID.String()))
+	}
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"crypto/rand"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/crypto"
 	"github.com/fclairamb/dbbat/internal/store"
 )
@@ -111,10 +111,8 @@ func (s *Server) handleDeviceAuthorization(c *gin.Context) {
 		return
 	}
 
-	details, _ := json.Marshal(map[string]interface{}{"client_name": clientName, "request_id": created.UID})
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType: "device_auth.requested",
-		Details:   details,
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		Payload: audit.DeviceAuthRequestedV1{ClientName: clientName, RequestID: created.UID},
 	})
 
 	displayCode := formatUserCode(created.UserCode)
@@ -279,16 +277,16 @@ func (s *Server) handleDeviceConsent(c *gin.Context) {
 		return
 	}
 
-	eventType := "device_auth.denied"
-	if req.Approve {
-		eventType = "device_auth.approved"
+	decision := audit.DeviceAuthApprovedV1{ClientName: deviceReq.ClientName, RequestID: deviceReq.UID, KeyPrefix: keyPrefix}
+
+	var payload audit.Payload = decision
+	if !req.Approve {
+		payload = audit.DeviceAuthDeniedV1(decision)
 	}
-	details, _ := json.Marshal(map[string]interface{}{"client_name": deviceReq.ClientName, "request_id": deviceReq.UID, "key_prefix": keyPrefix})
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   eventType,
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		UserID:      &currentUser.UID,
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload:     payload,
 	})
 
 	c.Status(http.StatusNoContent)
@@ -49,6 +49,10 @@ const (
 	ErrCodeOAuthUserNotLinked ErrorCode = "OAUTH_USER_NOT_LINKED"
 	// ErrCodeOAuthWrongWorkspace indicates the wrong OAuth workspace was used.
 	ErrCodeOAuthWrongWorkspace ErrorCode = "OAUTH_WRONG_WORKSPACE"
+	// ErrCodeOAuthNoRole indicates no role was mapped from the identity provider.
+	ErrCodeOAuthNoRole ErrorCode = "OAUTH_NO_ROLE"
+	// ErrCodeLocalLoginDisabled indicates password sign-in is disabled for the user.
+	ErrCodeLocalLoginDisabled ErrorCode = "LOCAL_LOGIN_DISABLED"
 	// ErrCodeDuplicateName indicates a resource with that name already exists.
 	ErrCodeDuplicateName ErrorCode = "DUPLICATE_NAME"
 	// ErrCodeTargetMatchesSelf indicates the target matches the storage database.
@@ -57,6 +61,8 @@ const (
 	ErrCodeGrantExpired ErrorCode = "GRANT_EXPIRED"
 	// ErrCodeQuotaExceeded indicates a usage quota was exceeded.
 	ErrCodeQuotaExceeded ErrorCode = "QUOTA_EXCEEDED"
+	// ErrCodeDraining indicates the instance is draining and refuses logins.
+	ErrCodeDraining ErrorCode = "DRAINING"
 )
 
 // ErrorBody is the standard error response structure.
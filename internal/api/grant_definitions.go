@@ -2,13 +2,13 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -80,22 +80,13 @@ func validateDefinitionRequest(req *CreateGrantDefinitionRequest) string {
 		return "duration_seconds must be at most 30 days (2592000)"
 	}
 
-	for _, control := range req.Controls {
-		valid := false
-
-		for _, vc := range store.ValidControls {
-			if control == vc {
-				valid = true
-
-				break
-			}
-		}
-
-		if !valid {
-			return "invalid control: " + control
-		}
+	controls, err := store.NormalizeControls(req.Controls)
+	if err != nil {
+		return err.Error()
 	}
 
+	req.Controls = controls
+
 	if req.MaxQueryCounts != nil && *req.MaxQueryCounts <= 0 {
 		return "max_query_counts must be > 0 or omitted"
 	}
@@ -159,20 +150,17 @@ func (s *Server) handleCreateGrantDefinition(c *gin.Context) {
 		return
 	}
 
-	details, _ := json.Marshal(map[string]any{
-		"grant_definition_uid": created.UID,
-		"name":                 created.Name,
-		"duration_seconds":     created.DurationSeconds,
-		"controls":             created.Controls,
-		"auto_approve":         created.AutoApprove,
-		"group_uids":           created.GroupUIDs,
-		"database_uids":        created.DatabaseUIDs,
-	})
-
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "grant_definition.created",
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.GrantDefinitionCreatedV1{
+			GrantDefinitionUID: created.UID,
+			Name:               created.Name,
+			DurationSeconds:    created.DurationSeconds,
+			Controls:           created.Controls,
+			AutoApprove:        created.AutoApprove,
+			GroupUIDs:          created.GroupUIDs,
+			DatabaseUIDs:       created.DatabaseUIDs,
+		},
 	})
 
 	successResponse(c, created)
@@ -185,6 +173,11 @@ func (s *Server) handleListGrantDefinitions(c *gin.Context) {
 
 	filter := store.GrantDefinitionFilter{}
 
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+
 	switch {
 	case !currentUser.IsAdmin():
 		filter.ActiveOnly = true
@@ -221,7 +214,7 @@ func (s *Server) handleListGrantDefinitions(c *gin.Context) {
 		defs = visible
 	}
 
-	successResponse(c, gin.H{"grant_definitions": defs})
+	pageResponse(c, p, len(defs), gin.H{"grant_definitions": slicePage(defs, p)})
 }
 
 // handleGetGrantDefinition — any authenticated user.
@@ -337,18 +330,15 @@ func (s *Server) handleUpdateGrantDefinition(c *gin.Context) {
 
 	currentUser := getCurrentUser(c)
 
-	details, _ := json.Marshal(map[string]any{
-		"grant_definition_uid": def.UID,
-		"name":                 def.Name,
-		"auto_approve":         def.AutoApprove,
-		"group_uids":           def.GroupUIDs,
-		"database_uids":        def.DatabaseUIDs,
-	})
-
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "grant_definition.updated",
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.GrantDefinitionUpdatedV1{
+			GrantDefinitionUID: def.UID,
+			Name:               def.Name,
+			AutoApprove:        def.AutoApprove,
+			GroupUIDs:          def.GroupUIDs,
+			DatabaseUIDs:       def.DatabaseUIDs,
+		},
 	})
 
 	successResponse(c, def)
@@ -377,14 +367,9 @@ func (s *Server) handleDeactivateGrantDefinition(c *gin.Context) {
 
 	currentUser := getCurrentUser(c)
 
-	details, _ := json.Marshal(map[string]any{
-		"grant_definition_uid": uid,
-	})
-
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "grant_definition.deactivated",
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload:     audit.GrantDefinitionDeactivatedV1{GrantDefinitionUID: uid},
 	})
 
 	successResponse(c, gin.H{"message": "grant definition deactivated"})
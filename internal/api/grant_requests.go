@@ -2,8 +2,8 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/notify"
 	"github.com/fclairamb/dbbat/internal/store"
 )
@@ -43,8 +44,16 @@ func (s *Server) notifyAsync(ev notify.GrantRequestEvent) {
 func (s *Server) loadEventContext(ctx context.Context, req *store.GrantRequest, decider *store.User) notify.GrantRequestEvent {
 	ev := notify.GrantRequestEvent{Request: req, Decider: decider}
 
-	if def, err := s.store.GetGrantDefinition(ctx, req.GrantDefinitionID); err == nil {
-		ev.Definition = def
+	if req.GrantDefinitionID != nil {
+		if def, err := s.store.GetGrantDefinition(ctx, *req.GrantDefinitionID); err == nil {
+			ev.Definition = def
+		}
+	}
+
+	if req.ExtendsGrantID != nil {
+		if grant, err := s.store.GetGrantByUID(ctx, *req.ExtendsGrantID); err == nil {
+			ev.Grant = grant
+		}
 	}
 
 	if db, err := s.store.GetServerByUID(ctx, req.DatabaseID); err == nil {
@@ -104,6 +113,17 @@ type CreateGrantRequestRequest struct {
 	GrantDefinitionID uuid.UUID `json:"grant_definition_id" binding:"required"`
 	DatabaseID        uuid.UUID `json:"database_id" binding:"required"`
 	Justification     string    `json:"justification"`
+	// DurationSeconds asks for less time than the definition's duration.
+	DurationSeconds *int64 `json:"duration_seconds"`
+}
+
+// RequestGrantExtensionRequest is the body for POST
+// /grants/:uid/extension-requests.
+type RequestGrantExtensionRequest struct {
+	// DurationSeconds is how much longer the grant should last, at most its
+	// original duration.
+	DurationSeconds int64  `json:"duration_seconds" binding:"required"`
+	Justification   string `json:"justification"`
 }
 
 // DenyGrantRequestRequest is the body for POST /grant-requests/:uid/deny.
@@ -185,6 +205,13 @@ func (s *Server) handleCreateGrantRequest(c *gin.Context) {
 		return
 	}
 
+	if req.DurationSeconds != nil && (*req.DurationSeconds <= 0 || *req.DurationSeconds > def.DurationSeconds) {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+			fmt.Sprintf("duration_seconds must be between 1 and the definition's %d", def.DurationSeconds))
+
+		return
+	}
+
 	if def.AutoApprove && req.Justification == "" {
 		writeError(c, http.StatusBadRequest, ErrCodeValidationError,
 			"justification is required for auto-approved grant definitions")
@@ -218,9 +245,10 @@ func (s *Server) handleCreateGrantRequest(c *gin.Context) {
 
 	created, err := s.store.CreateGrantRequest(ctx, &store.GrantRequest{
 		UserID:            currentUser.UID,
-		GrantDefinitionID: req.GrantDefinitionID,
+		GrantDefinitionID: &req.GrantDefinitionID,
 		DatabaseID:        req.DatabaseID,
 		Justification:     req.Justification,
+		DurationSeconds:   req.DurationSeconds,
 	})
 	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to create grant request")
@@ -228,17 +256,15 @@ func (s *Server) handleCreateGrantRequest(c *gin.Context) {
 		return
 	}
 
-	details, _ := json.Marshal(map[string]any{
-		"grant_request_uid":   created.UID,
-		"grant_definition_id": created.GrantDefinitionID,
-		"database_id":         created.DatabaseID,
-	})
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "grant_request.created",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		UserID:      &currentUser.UID,
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.GrantRequestCreatedV1{
+			GrantRequestUID:   created.UID,
+			GrantDefinitionID: req.GrantDefinitionID,
+			DatabaseID:        created.DatabaseID,
+			DurationSeconds:   created.DurationSeconds,
+		},
 	})
 
 	if def.AutoApprove {
@@ -272,6 +298,116 @@ func (s *Server) handleCreateGrantRequest(c *gin.Context) {
 	successResponse(c, created)
 }
 
+// handleRequestGrantExtension — the grant's user asks for more time on it.
+// The request goes through the grant request approval flow; on approval the
+// grant's expiry is pushed back rather than a new grant created.
+func (s *Server) handleRequestGrantExtension(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid grant UID")
+
+		return
+	}
+
+	var req RequestGrantExtensionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+
+		return
+	}
+
+	if len(req.Justification) > maxJustificationLen {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "justification too long")
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	ctx := c.Request.Context()
+
+	grant, err := s.store.GetGrantByUID(ctx, uid)
+	if err != nil {
+		if errors.Is(err, store.ErrGrantNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "grant not found")
+
+			return
+		}
+
+		writeInternalError(c, s.logger, err, "failed to get grant")
+
+		return
+	}
+
+	if grant.UserID != currentUser.UID {
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "only the grant's user can request its extension")
+
+		return
+	}
+
+	switch {
+	case grant.RevokedAt != nil:
+		writeError(c, http.StatusConflict, ErrCodeConflict, "grant has been revoked")
+
+		return
+	case !time.Now().Before(grant.ExpiresAt):
+		writeError(c, http.StatusConflict, ErrCodeConflict, "grant has expired, request a new one")
+
+		return
+	}
+
+	// An extension renews the grant at most once over.
+	maxDuration := int64(grant.ExpiresAt.Sub(grant.StartsAt) / time.Second)
+	if req.DurationSeconds <= 0 || req.DurationSeconds > maxDuration {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+			fmt.Sprintf("duration_seconds must be between 1 and the grant's %d", maxDuration))
+
+		return
+	}
+
+	pending, err := s.store.HasPendingExtension(ctx, grant.UID)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to check pending requests")
+
+		return
+	}
+
+	if pending {
+		writeError(c, http.StatusConflict, ErrCodeConflict, "a pending extension request already exists for this grant")
+
+		return
+	}
+
+	created, err := s.store.CreateGrantRequest(ctx, &store.GrantRequest{
+		UserID:          currentUser.UID,
+		ExtendsGrantID:  &grant.UID,
+		DatabaseID:      grant.DatabaseID,
+		Justification:   req.Justification,
+		DurationSeconds: &req.DurationSeconds,
+	})
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to create grant request")
+
+		return
+	}
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		UserID:      &currentUser.UID,
+		PerformedBy: &currentUser.UID,
+		Payload: audit.GrantRequestExtensionRequestedV1{
+			GrantRequestUID: created.UID,
+			GrantID:         grant.UID,
+			DatabaseID:      grant.DatabaseID,
+			DurationSeconds: req.DurationSeconds,
+		},
+	})
+
+	ev := s.loadEventContext(ctx, created, nil)
+	ev.Action = notify.GrantActionCreated
+	s.notifyAsync(ev)
+
+	successResponse(c, created)
+}
+
 // handleListGrantRequests — role-aware. Admins see all (filterable);
 // non-admins see only their own.
 func (s *Server) handleListGrantRequests(c *gin.Context) {
@@ -297,6 +433,12 @@ func (s *Server) handleListGrantRequests(c *gin.Context) {
 		}
 	}
 
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
+
 	requests, err := s.store.ListGrantRequests(c.Request.Context(), filter)
 	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to list grant requests")
@@ -304,7 +446,14 @@ func (s *Server) handleListGrantRequests(c *gin.Context) {
 		return
 	}
 
-	successResponse(c, gin.H{"grant_requests": requests})
+	total, err := s.store.CountGrantRequests(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count grant requests")
+
+		return
+	}
+
+	pageResponse(c, p, total, gin.H{"grant_requests": requests})
 }
 
 // handleGetGrantRequest — role-aware: requesters can fetch their own,
@@ -371,14 +520,15 @@ var ErrRequestOutOfScope = errors.New("grant request is out of the definition's
 
 // checkRequestInScope re-validates a pending request against its definition's
 // current group/database scope. A missing request or definition is left to the
-// store transition to report, so error mapping stays in one place.
+// store transition to report, so error mapping stays in one place. Extension
+// requests have no definition: they only extend a grant an admin already gave.
 func (s *Server) checkRequestInScope(ctx context.Context, uid uuid.UUID) error {
 	request, err := s.store.GetGrantRequest(ctx, uid)
-	if err != nil {
+	if err != nil || request.GrantDefinitionID == nil {
 		return nil //nolint:nilerr // the store transition reports not-found
 	}
 
-	def, err := s.store.GetGrantDefinition(ctx, request.GrantDefinitionID)
+	def, err := s.store.GetGrantDefinition(ctx, *request.GrantDefinitionID)
 	if err != nil {
 		return nil //nolint:nilerr // the store transition reports not-found
 	}
@@ -409,16 +559,20 @@ func (s *Server) approveGrantRequest(ctx context.Context, uid uuid.UUID, decider
 		return nil, err
 	}
 
-	details := decisionDetails(map[string]any{
-		"grant_request_uid":  request.UID,
-		"resulting_grant_id": grant.UID,
-	}, source)
+	// Live sessions read the grant's expiry at connect time: hand them the
+	// extended one so they are not cut at the old expiry.
+	if request.ExtendsGrantID != nil {
+		s.store.Revocations().Extend(grant.UID, grant.ExpiresAt)
+	}
 
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "grant_request.approved",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		UserID:      &request.UserID,
 		PerformedBy: &decider.UID,
-		Details:     details,
+		Payload: audit.GrantRequestApprovedV1{
+			GrantRequestUID:  request.UID,
+			ResultingGrantID: grant.UID,
+			Via:              decisionVia(source),
+		},
 	})
 
 	ev := s.loadEventContext(ctx, request, decider)
@@ -439,15 +593,13 @@ func (s *Server) autoApproveGrantRequest(ctx context.Context, request *store.Gra
 		return nil, err
 	}
 
-	details := decisionDetails(map[string]any{
-		"grant_request_uid":  updated.UID,
-		"resulting_grant_id": grant.UID,
-	}, decisionSourceAutoApprove)
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType: "grant_request.approved",
-		UserID:    &updated.UserID,
-		Details:   details,
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		UserID: &updated.UserID,
+		Payload: audit.GrantRequestApprovedV1{
+			GrantRequestUID:  updated.UID,
+			ResultingGrantID: grant.UID,
+			Via:              decisionVia(decisionSourceAutoApprove),
+		},
 	})
 
 	ev := s.loadEventContext(ctx, updated, nil)
@@ -466,16 +618,14 @@ func (s *Server) denyGrantRequest(ctx context.Context, uid uuid.UUID, decider *s
 		return nil, err
 	}
 
-	details := decisionDetails(map[string]any{
-		"grant_request_uid": updated.UID,
-		"reason":            reason,
-	}, source)
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "grant_request.denied",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		UserID:      &updated.UserID,
 		PerformedBy: &decider.UID,
-		Details:     details,
+		Payload: audit.GrantRequestDeniedV1{
+			GrantRequestUID: updated.UID,
+			Reason:          reason,
+			Via:             decisionVia(source),
+		},
 	})
 
 	ev := s.loadEventContext(ctx, updated, decider)
@@ -485,16 +635,14 @@ func (s *Server) denyGrantRequest(ctx context.Context, uid uuid.UUID, decider *s
 	return &decideOutcome{Request: updated, Event: ev, Action: notify.GrantActionDenied}, nil
 }
 
-// decisionDetails marshals audit details, adding `via` only for non-web
-// sources so existing UI-driven audit rows are unchanged.
-func decisionDetails(base map[string]any, source decisionSource) json.RawMessage {
-	if source != decisionSourceWeb {
-		base["via"] = string(source)
+// decisionVia is the `via` of decision audit events: set only for non-web
+// sources so UI-driven audit rows don't carry it.
+func decisionVia(source decisionSource) string {
+	if source == decisionSourceWeb {
+		return ""
 	}
 
-	details, _ := json.Marshal(base)
-
-	return details
+	return string(source)
 }
 
 // handleApproveGrantRequest — admin-only; flips pending → approved and
@@ -518,6 +666,8 @@ func (s *Server) handleApproveGrantRequest(c *gin.Context) {
 			writeError(c, http.StatusConflict, ErrCodeConflict, "grant request is not pending")
 		case errors.Is(err, store.ErrDefinitionInactive):
 			writeError(c, http.StatusConflict, ErrCodeConflict, "grant definition is no longer active")
+		case errors.Is(err, store.ErrGrantNotExtendable):
+			writeError(c, http.StatusConflict, ErrCodeConflict, "the grant to extend has been revoked")
 		case errors.Is(err, ErrRequestOutOfScope):
 			writeError(c, http.StatusConflict, ErrCodeConflict,
 				"the grant definition's scope no longer covers this user or database")
@@ -606,15 +756,10 @@ func (s *Server) handleCancelGrantRequest(c *gin.Context) {
 		return
 	}
 
-	details, _ := json.Marshal(map[string]any{
-		"grant_request_uid": updated.UID,
-	})
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "grant_request.cancelled",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		UserID:      &updated.UserID,
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload:     audit.GrantRequestCancelledV1{GrantRequestUID: updated.UID},
 	})
 
 	ev := s.loadEventContext(ctx, updated, currentUser)
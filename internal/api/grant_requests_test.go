@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -41,6 +42,8 @@ func grantRequestsRouter(server *Server) *gin.Engine {
 	router := gin.New()
 	router.Use(server.authMiddleware())
 	router.POST("/api/v1/grant-requests", server.handleCreateGrantRequest)
+	router.POST("/api/v1/grant-requests/:uid/approve", server.handleApproveGrantRequest)
+	router.POST("/api/v1/grants/:uid/extension-requests", server.handleRequestGrantExtension)
 
 	return router
 }
@@ -48,10 +51,16 @@ func grantRequestsRouter(server *Server) *gin.Engine {
 func postGrantRequest(t *testing.T, router *gin.Engine, token string, body map[string]any) (*httptest.ResponseRecorder, map[string]any) {
 	t.Helper()
 
+	return postJSON(t, router, "/api/v1/grant-requests", token, body)
+}
+
+func postJSON(t *testing.T, router *gin.Engine, path, token string, body map[string]any) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
 	payload, err := json.Marshal(body)
 	require.NoError(t, err)
 
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/grant-requests", bytes.NewReader(payload))
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 	w := httptest.NewRecorder()
@@ -163,3 +172,86 @@ func TestCreateGrantRequest_AutoApproveRequiresJustification(t *testing.T) { //n
 
 	require.Equal(t, http.StatusBadRequest, w.Code, "response body: %s", w.Body.String())
 }
+
+func TestCreateGrantRequest_DurationBeyondDefinition(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	suffix := "cgrd"
+
+	admin := createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	createTestUser(t, dataStore, "req-"+suffix, "reqpass123", []string{store.RoleConnector})
+	token := loginUser(t, server, "req-"+suffix, "reqpass123")
+
+	db := createTestDBEntry(t, dataStore, "duration-db-"+suffix, true)
+	def := createTestGrantDefinition(t, dataStore, *admin, "duration-def-"+suffix, false)
+
+	router := grantRequestsRouter(server)
+
+	w, _ := postGrantRequest(t, router, token, map[string]any{
+		"grant_definition_id": def.UID.String(),
+		"database_id":         db.UID.String(),
+		"duration_seconds":    def.DurationSeconds + 1,
+	})
+
+	require.Equal(t, http.StatusBadRequest, w.Code, "response body: %s", w.Body.String())
+
+	w, resp := postGrantRequest(t, router, token, map[string]any{
+		"grant_definition_id": def.UID.String(),
+		"database_id":         db.UID.String(),
+		"duration_seconds":    600,
+	})
+
+	require.Equal(t, http.StatusOK, w.Code, "response body: %s", w.Body.String())
+	require.InDelta(t, 600, resp["duration_seconds"], 0)
+}
+
+func TestRequestGrantExtension_RenewsGrantOnApproval(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	suffix := "rgex"
+	ctx := context.Background()
+
+	admin := createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	requester := createTestUser(t, dataStore, "req-"+suffix, "reqpass123", []string{store.RoleConnector})
+	createTestUser(t, dataStore, "other-"+suffix, "otherpass123", []string{store.RoleConnector})
+	adminToken := loginUser(t, server, "admin-"+suffix, "adminpass123")
+	token := loginUser(t, server, "req-"+suffix, "reqpass123")
+	otherToken := loginUser(t, server, "other-"+suffix, "otherpass123")
+
+	db := createTestDBEntry(t, dataStore, "extend-db-"+suffix, true)
+
+	now := time.Now()
+	grant, err := dataStore.CreateGrant(ctx, &store.Grant{
+		UserID:     requester.UID,
+		DatabaseID: db.UID,
+		GrantedBy:  admin.UID,
+		StartsAt:   now.Add(-time.Hour),
+		ExpiresAt:  now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	router := grantRequestsRouter(server)
+	path := "/api/v1/grants/" + grant.UID.String() + "/extension-requests"
+
+	// Only the grant's user can ask for more time, and at most its original
+	// two hours.
+	w, _ := postJSON(t, router, path, otherToken, map[string]any{"duration_seconds": 3600})
+	require.Equal(t, http.StatusForbidden, w.Code, "response body: %s", w.Body.String())
+
+	w, _ = postJSON(t, router, path, token, map[string]any{"duration_seconds": 3 * 3600})
+	require.Equal(t, http.StatusBadRequest, w.Code, "response body: %s", w.Body.String())
+
+	w, resp := postJSON(t, router, path, token, map[string]any{"duration_seconds": 3600, "justification": "migration overran"})
+	require.Equal(t, http.StatusOK, w.Code, "response body: %s", w.Body.String())
+	require.Equal(t, "pending", resp["status"])
+	require.Equal(t, grant.UID.String(), resp["extends_grant_id"])
+
+	w, _ = postJSON(t, router, path, token, map[string]any{"duration_seconds": 3600})
+	require.Equal(t, http.StatusConflict, w.Code, "a second pending extension should conflict")
+
+	requestUID, _ := resp["uid"].(string)
+	w, _ = postJSON(t, router, "/api/v1/grant-requests/"+requestUID+"/approve", adminToken, map[string]any{})
+	require.Equal(t, http.StatusOK, w.Code, "response body: %s", w.Body.String())
+
+	extended, err := dataStore.GetGrantByUID(ctx, grant.UID)
+	require.NoError(t, err)
+	require.WithinDuration(t, grant.ExpiresAt.Add(time.Hour), extended.ExpiresAt, time.Millisecond)
+}
@@ -0,0 +1,468 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// maxBulkGrants bounds the grants one template application creates, so a
+// mistyped list can't flood the grants table in a single transaction.
+const maxBulkGrants = 500
+
+// CreateGrantTemplateRequest is the body for POST /grant-templates.
+type CreateGrantTemplateRequest struct {
+	Name                string       `json:"name" binding:"required"`
+	Description         string       `json:"description"`
+	DurationSeconds     int64        `json:"duration_seconds" binding:"required"`
+	Controls            []string     `json:"controls"`       // See store.ParseControl
+	AllowedTables       []string     `json:"allowed_tables"` // See store.NormalizeAllowedTables
+	AllowedCIDRs        []string     `json:"allowed_cidrs"`  // See store.NormalizeAllowedCIDRs
+	MaxQueryCounts      *int64       `json:"max_query_counts"`
+	MaxBytesTransferred *int64       `json:"max_bytes_transferred"`
+	MaxRowsReturned     *int64       `json:"max_rows_returned"`
+	Labels              store.Labels `json:"labels"`
+}
+
+// UpdateGrantTemplateRequest is the body for PATCH /grant-templates/:uid.
+// Same shape as create: the whole template is replaced.
+type UpdateGrantTemplateRequest = CreateGrantTemplateRequest
+
+// ApplyGrantTemplateRequest is the body for POST /grant-templates/:uid/grants:
+// one grant is created per user and database pair.
+type ApplyGrantTemplateRequest struct {
+	UserIDs     []uuid.UUID `json:"user_ids" binding:"required"`
+	DatabaseIDs []uuid.UUID `json:"database_ids" binding:"required"`
+	// StartsAt defaults to now.
+	StartsAt *time.Time `json:"starts_at"`
+	// Labels are merged over the template's.
+	Labels store.Labels `json:"labels"`
+}
+
+func validateGrantTemplateRequest(req *CreateGrantTemplateRequest) string {
+	if req.Name == "" {
+		return "name is required"
+	}
+
+	if len(req.Name) > 64 {
+		return "name must be at most 64 characters"
+	}
+
+	if req.DurationSeconds <= 0 {
+		return "duration_seconds must be > 0"
+	}
+
+	controls, err := store.NormalizeControls(req.Controls)
+	if err != nil {
+		return err.Error()
+	}
+
+	req.Controls = controls
+
+	allowedTables, err := store.NormalizeAllowedTables(req.AllowedTables)
+	if err != nil {
+		return err.Error()
+	}
+
+	req.AllowedTables = allowedTables
+
+	allowedCIDRs, err := store.NormalizeAllowedCIDRs(req.AllowedCIDRs)
+	if err != nil {
+		return err.Error()
+	}
+
+	req.AllowedCIDRs = allowedCIDRs
+
+	if req.MaxQueryCounts != nil && *req.MaxQueryCounts <= 0 {
+		return "max_query_counts must be > 0 or omitted"
+	}
+
+	if req.MaxBytesTransferred != nil && *req.MaxBytesTransferred <= 0 {
+		return "max_bytes_transferred must be > 0 or omitted"
+	}
+
+	if req.MaxRowsReturned != nil && *req.MaxRowsReturned <= 0 {
+		return "max_rows_returned must be > 0 or omitted"
+	}
+
+	if err := req.Labels.Validate(); err != nil {
+		return err.Error()
+	}
+
+	return ""
+}
+
+// bindGrantTemplateRequest reads and validates a create or update body,
+// writing the error response and returning false when it is invalid.
+func bindGrantTemplateRequest(c *gin.Context, req *CreateGrantTemplateRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+
+		return false
+	}
+
+	if msg := validateGrantTemplateRequest(req); msg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, msg)
+
+		return false
+	}
+
+	return true
+}
+
+// handleCreateGrantTemplate — admin-only.
+func (s *Server) handleCreateGrantTemplate(c *gin.Context) {
+	var req CreateGrantTemplateRequest
+	if !bindGrantTemplateRequest(c, &req) {
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	ctx := c.Request.Context()
+
+	created, err := s.store.CreateGrantTemplate(ctx, &store.GrantTemplate{
+		Name:                req.Name,
+		Description:         req.Description,
+		DurationSeconds:     req.DurationSeconds,
+		Controls:            req.Controls,
+		AllowedTables:       req.AllowedTables,
+		AllowedCIDRs:        req.AllowedCIDRs,
+		MaxQueryCounts:      req.MaxQueryCounts,
+		MaxBytesTransferred: req.MaxBytesTransferred,
+		MaxRowsReturned:     req.MaxRowsReturned,
+		Labels:              req.Labels,
+		CreatedBy:           &currentUser.UID,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrGrantTemplateDuplicate) {
+			writeError(c, http.StatusConflict, ErrCodeDuplicateName, err.Error())
+
+			return
+		}
+
+		writeInternalError(c, s.logger, err, "failed to create grant template")
+
+		return
+	}
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		PerformedBy: &currentUser.UID,
+		Payload: audit.GrantTemplateCreatedV1{
+			GrantTemplateUID: created.UID,
+			Name:             created.Name,
+			DurationSeconds:  created.DurationSeconds,
+			Controls:         created.Controls,
+			AllowedTables:    created.AllowedTables,
+			AllowedCIDRs:     created.AllowedCIDRs,
+		},
+	})
+
+	successResponse(c, created)
+}
+
+// handleListGrantTemplates — admin-only.
+func (s *Server) handleListGrantTemplates(c *gin.Context) {
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+
+	templates, err := s.store.ListGrantTemplates(c.Request.Context())
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to list grant templates")
+
+		return
+	}
+
+	pageResponse(c, p, len(templates), gin.H{"grant_templates": slicePage(templates, p)})
+}
+
+// handleGetGrantTemplate — admin-only.
+func (s *Server) handleGetGrantTemplate(c *gin.Context) {
+	tpl, ok := s.grantTemplateFromParam(c)
+	if !ok {
+		return
+	}
+
+	successResponse(c, tpl)
+}
+
+// handleUpdateGrantTemplate — admin-only. The grants already created from
+// the template keep their shape.
+func (s *Server) handleUpdateGrantTemplate(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid grant template UID")
+
+		return
+	}
+
+	var req UpdateGrantTemplateRequest
+	if !bindGrantTemplateRequest(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	tpl := &store.GrantTemplate{
+		UID:                 uid,
+		Name:                req.Name,
+		Description:         req.Description,
+		DurationSeconds:     req.DurationSeconds,
+		Controls:            req.Controls,
+		AllowedTables:       req.AllowedTables,
+		AllowedCIDRs:        req.AllowedCIDRs,
+		MaxQueryCounts:      req.MaxQueryCounts,
+		MaxBytesTransferred: req.MaxBytesTransferred,
+		MaxRowsReturned:     req.MaxRowsReturned,
+		Labels:              req.Labels,
+	}
+
+	if err := s.store.UpdateGrantTemplate(ctx, tpl); err != nil {
+		switch {
+		case errors.Is(err, store.ErrGrantTemplateNotFound):
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "grant template not found")
+		case errors.Is(err, store.ErrGrantTemplateDuplicate):
+			writeError(c, http.StatusConflict, ErrCodeDuplicateName, err.Error())
+		default:
+			writeInternalError(c, s.logger, err, "failed to update grant template")
+		}
+
+		return
+	}
+
+	updated, err := s.store.GetGrantTemplate(ctx, uid)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to get grant template")
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		PerformedBy: &currentUser.UID,
+		Payload: audit.GrantTemplateUpdatedV1{
+			GrantTemplateUID: updated.UID,
+			Name:             updated.Name,
+			DurationSeconds:  updated.DurationSeconds,
+			Controls:         updated.Controls,
+			AllowedTables:    updated.AllowedTables,
+			AllowedCIDRs:     updated.AllowedCIDRs,
+		},
+	})
+
+	successResponse(c, updated)
+}
+
+// handleDeleteGrantTemplate — admin-only hard delete; the grants created from
+// the template stay.
+func (s *Server) handleDeleteGrantTemplate(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid grant template UID")
+
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := s.store.DeleteGrantTemplate(ctx, uid); err != nil {
+		if errors.Is(err, store.ErrGrantTemplateNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "grant template not found")
+
+			return
+		}
+
+		writeInternalError(c, s.logger, err, "failed to delete grant template")
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		PerformedBy: &currentUser.UID,
+		Payload:     audit.GrantTemplateDeletedV1{GrantTemplateUID: uid},
+	})
+
+	successResponse(c, gin.H{"message": "grant template deleted"})
+}
+
+// handleApplyGrantTemplate — admin-only. Creates one grant per user and
+// database pair, all or none, recorded as a single audit event.
+func (s *Server) handleApplyGrantTemplate(c *gin.Context) {
+	tpl, ok := s.grantTemplateFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req ApplyGrantTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+
+		return
+	}
+
+	req.UserIDs = uniqueUIDs(req.UserIDs)
+	req.DatabaseIDs = uniqueUIDs(req.DatabaseIDs)
+
+	if msg := validateApplyGrantTemplateRequest(&req); msg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, msg)
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	ctx := c.Request.Context()
+
+	startsAt := time.Now()
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+
+	grants, msg := s.buildTemplateGrants(ctx, tpl, &req, currentUser.UID, startsAt)
+	if msg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, msg)
+
+		return
+	}
+
+	created := make([]store.Grant, 0, len(grants))
+
+	err := s.store.WithTx(ctx, func(ctx context.Context, tx *store.Store) error {
+		grantUIDs := make([]uuid.UUID, 0, len(grants))
+
+		for _, grant := range grants {
+			result, err := tx.CreateGrant(ctx, grant)
+			if err != nil {
+				return err
+			}
+
+			created = append(created, *result)
+			grantUIDs = append(grantUIDs, result.UID)
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			PerformedBy: &currentUser.UID,
+			Payload: audit.GrantBulkCreatedV1{
+				GrantTemplateUID: tpl.UID,
+				TemplateName:     tpl.Name,
+				UserIDs:          req.UserIDs,
+				DatabaseIDs:      req.DatabaseIDs,
+				GrantUIDs:        grantUIDs,
+				StartsAt:         startsAt,
+				ExpiresAt:        grants[0].ExpiresAt,
+				Labels:           grants[0].Labels,
+			},
+		})
+	})
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to create grants")
+
+		return
+	}
+
+	successResponse(c, gin.H{"grants": created})
+}
+
+func validateApplyGrantTemplateRequest(req *ApplyGrantTemplateRequest) string {
+	if len(req.UserIDs) == 0 || len(req.DatabaseIDs) == 0 {
+		return "user_ids and database_ids must not be empty"
+	}
+
+	if n := len(req.UserIDs) * len(req.DatabaseIDs); n > maxBulkGrants {
+		return fmt.Sprintf("at most %d grants per call, got %d users × %d databases",
+			maxBulkGrants, len(req.UserIDs), len(req.DatabaseIDs))
+	}
+
+	if err := req.Labels.Validate(); err != nil {
+		return err.Error()
+	}
+
+	return ""
+}
+
+// buildTemplateGrants builds the grant of every pair, each filled with and
+// checked against its database's grant defaults as a single grant would be.
+// Returns a validation message, or "" when every grant is fine.
+func (s *Server) buildTemplateGrants(
+	ctx context.Context, tpl *store.GrantTemplate, req *ApplyGrantTemplateRequest, grantedBy uuid.UUID, startsAt time.Time,
+) ([]*store.Grant, string) {
+	for _, userID := range req.UserIDs {
+		if _, err := s.store.GetUserByUID(ctx, userID); err != nil {
+			return nil, "user does not exist: " + userID.String()
+		}
+	}
+
+	grants := make([]*store.Grant, 0, len(req.UserIDs)*len(req.DatabaseIDs))
+
+	for _, databaseID := range req.DatabaseIDs {
+		target, err := s.store.GetServerByUID(ctx, databaseID)
+		if err != nil {
+			return nil, "database does not exist: " + databaseID.String()
+		}
+
+		for _, userID := range req.UserIDs {
+			grant := store.BuildGrantFromTemplate(tpl, userID, databaseID, grantedBy, startsAt, req.Labels)
+			target.GrantDefaults.Fill(grant)
+
+			if msg := checkGrantTarget(target, grant); msg != "" {
+				return nil, target.Name + ": " + msg
+			}
+
+			grants = append(grants, grant)
+		}
+	}
+
+	return grants, ""
+}
+
+// grantTemplateFromParam loads the template named by the :uid parameter,
+// writing the error response and returning false when it can't.
+func (s *Server) grantTemplateFromParam(c *gin.Context) (*store.GrantTemplate, bool) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid grant template UID")
+
+		return nil, false
+	}
+
+	tpl, err := s.store.GetGrantTemplate(c.Request.Context(), uid)
+	if err != nil {
+		if errors.Is(err, store.ErrGrantTemplateNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "grant template not found")
+
+			return nil, false
+		}
+
+		writeInternalError(c, s.logger, err, "failed to get grant template")
+
+		return nil, false
+	}
+
+	return tpl, true
+}
+
+// uniqueUIDs drops the repeated UIDs of a list, keeping the first occurrence
+// of each in place.
+func uniqueUIDs(uids []uuid.UUID) []uuid.UUID {
+	unique := make([]uuid.UUID, 0, len(uids))
+
+	for _, uid := range uids {
+		if !slices.Contains(unique, uid) {
+			unique = append(unique, uid)
+		}
+	}
+
+	return unique
+}
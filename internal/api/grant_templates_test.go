@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestValidateGrantTemplateRequest(t *testing.T) {
+	t.Parallel()
+
+	zero := int64(0)
+
+	tests := []struct {
+		name    string
+		req     CreateGrantTemplateRequest
+		wantErr string
+	}{
+		{
+			name: "valid",
+			req: CreateGrantTemplateRequest{
+				Name:            "Analyst onboarding",
+				DurationSeconds: 3600,
+				Controls:        []string{"read_only"},
+				AllowedTables:   []string{"public.*"},
+			},
+		},
+		{
+			name:    "no name",
+			req:     CreateGrantTemplateRequest{DurationSeconds: 3600},
+			wantErr: "name is required",
+		},
+		{
+			name:    "name too long",
+			req:     CreateGrantTemplateRequest{Name: strings.Repeat("a", 65), DurationSeconds: 3600},
+			wantErr: "at most 64",
+		},
+		{
+			name:    "no duration",
+			req:     CreateGrantTemplateRequest{Name: "Onboarding"},
+			wantErr: "duration_seconds",
+		},
+		{
+			name:    "unknown control",
+			req:     CreateGrantTemplateRequest{Name: "Onboarding", DurationSeconds: 3600, Controls: []string{"read_mostly"}},
+			wantErr: "read_mostly",
+		},
+		{
+			name:    "zero quota",
+			req:     CreateGrantTemplateRequest{Name: "Onboarding", DurationSeconds: 3600, MaxRowsReturned: &zero},
+			wantErr: "max_rows_returned",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := validateGrantTemplateRequest(&tt.req)
+			if tt.wantErr == "" {
+				assert.Empty(t, got)
+			} else {
+				assert.Contains(t, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateApplyGrantTemplateRequest(t *testing.T) {
+	t.Parallel()
+
+	ids := func(n int) []uuid.UUID {
+		uids := make([]uuid.UUID, n)
+		for i := range uids {
+			uids[i] = uuid.New()
+		}
+
+		return uids
+	}
+
+	assert.Empty(t, validateApplyGrantTemplateRequest(&ApplyGrantTemplateRequest{UserIDs: ids(30), DatabaseIDs: ids(2)}))
+	assert.Contains(t, validateApplyGrantTemplateRequest(&ApplyGrantTemplateRequest{UserIDs: ids(3)}), "must not be empty")
+	assert.Contains(t, validateApplyGrantTemplateRequest(&ApplyGrantTemplateRequest{UserIDs: ids(101), DatabaseIDs: ids(5)}), "at most 500")
+
+	user := uuid.New()
+	assert.Equal(t, []uuid.UUID{user}, uniqueUIDs([]uuid.UUID{user, user}))
+}
+
+func TestApplyGrantTemplate(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	suffix := "gtapply"
+	ctx := context.Background()
+
+	admin := createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	alice := createTestUser(t, dataStore, "alice-"+suffix, "alicepass123", []string{store.RoleConnector})
+	bob := createTestUser(t, dataStore, "bob-"+suffix, "bobpass123", []string{store.RoleConnector})
+	adminToken := loginUser(t, server, "admin-"+suffix, "adminpass123")
+	aliceToken := loginUser(t, server, "alice-"+suffix, "alicepass123")
+
+	orders := createTestDBEntry(t, dataStore, "orders-"+suffix, true)
+	billing := createTestDBEntry(t, dataStore, "billing-"+suffix, true)
+
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.POST("/api/v1/grant-templates", server.requireAdmin(), server.handleCreateGrantTemplate)
+	router.POST("/api/v1/grant-templates/:uid/grants", server.requireAdmin(), server.handleApplyGrantTemplate)
+
+	w, resp := doJSON(t, router, http.MethodPost, "/api/v1/grant-templates", adminToken, map[string]any{
+		"name":             "Analyst onboarding",
+		"duration_seconds": 7 * 24 * 3600,
+		"controls":         []string{"read_only"},
+		"labels":           map[string]string{"team": "data"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	rawUID, ok := resp["uid"].(string)
+	require.True(t, ok, "response should carry a uid")
+
+	applyPath := "/api/v1/grant-templates/" + rawUID + "/grants"
+
+	// Templates are admin-only.
+	w, _ = doJSON(t, router, http.MethodPost, applyPath, aliceToken, map[string]any{
+		"user_ids":     []string{alice.UID.String()},
+		"database_ids": []string{orders.UID.String()},
+	})
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// An unknown user fails the whole call.
+	w, _ = doJSON(t, router, http.MethodPost, applyPath, adminToken, map[string]any{
+		"user_ids":     []string{alice.UID.String(), uuid.New().String()},
+		"database_ids": []string{orders.UID.String()},
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, resp = doJSON(t, router, http.MethodPost, applyPath, adminToken, map[string]any{
+		"user_ids":     []string{alice.UID.String(), bob.UID.String(), alice.UID.String()},
+		"database_ids": []string{orders.UID.String(), billing.UID.String()},
+		"labels":       map[string]string{"ticket": "ONB-42"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp["grants"], 4)
+
+	grants, err := dataStore.ListGrants(ctx, store.GrantFilter{UserID: &alice.UID})
+	require.NoError(t, err)
+	require.Len(t, grants, 2)
+
+	for _, grant := range grants {
+		assert.True(t, grant.HasControl(store.ControlReadOnly))
+		assert.Equal(t, store.Labels{"team": "data", "ticket": "ONB-42"}, grant.Labels)
+		assert.Equal(t, admin.UID, grant.GrantedBy)
+	}
+
+	// One audit event for the whole call.
+	bulkCreated, grantCreated := audit.EventGrantBulkCreated, audit.EventGrantCreated
+
+	events, err := dataStore.ListAuditEvents(ctx, store.AuditFilter{EventType: &bulkCreated})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	events, err = dataStore.ListAuditEvents(ctx, store.AuditFilter{EventType: &grantCreated})
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
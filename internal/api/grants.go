@@ -1,26 +1,39 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
-// CreateGrantRequest represents the request to create a grant
+// CreateGrantRequest represents the request to create a grant. Omitted
+// controls, expiry and quotas are taken from the database's grant defaults.
 type CreateGrantRequest struct {
-	UserID              uuid.UUID `json:"user_id" binding:"required"`
-	DatabaseID          uuid.UUID `json:"database_id" binding:"required"`
-	Controls            []string  `json:"controls"` // Array of controls: read_only, block_copy, block_ddl
-	StartsAt            time.Time `json:"starts_at" binding:"required"`
-	ExpiresAt           time.Time `json:"expires_at" binding:"required"`
-	MaxQueryCounts      *int64    `json:"max_query_counts"`
-	MaxBytesTransferred *int64    `json:"max_bytes_transferred"`
+	UserID              uuid.UUID    `json:"user_id" binding:"required"`
+	DatabaseID          uuid.UUID    `json:"database_id" binding:"required"`
+	Controls            []string     `json:"controls"`       // Array of controls, see store.ParseControl
+	AllowedTables       []string     `json:"allowed_tables"` // Table allowlist, see store.NormalizeAllowedTables
+	AllowedCIDRs        []string     `json:"allowed_cidrs"`  // Client network allowlist, see store.NormalizeAllowedCIDRs
+	StartsAt            time.Time    `json:"starts_at" binding:"required"`
+	ExpiresAt           time.Time    `json:"expires_at"`
+	MaxQueryCounts      *int64       `json:"max_query_counts"`
+	MaxBytesTransferred *int64       `json:"max_bytes_transferred"`
+	MaxRowsReturned     *int64       `json:"max_rows_returned"`
+	Labels              store.Labels `json:"labels"`
+}
+
+// SetGrantLabelsRequest represents the request to replace a grant's labels
+type SetGrantLabelsRequest struct {
+	Labels store.Labels `json:"labels" binding:"required"`
 }
 
 // handleCreateGrant creates a new access grant
@@ -31,30 +44,28 @@ func (s *Server) handleCreateGrant(c *gin.Context) {
 		return
 	}
 
+	if !validLabels(c, req.Labels) {
+		return
+	}
+
 	// Validate controls
-	for _, control := range req.Controls {
-		valid := false
-		for _, validControl := range store.ValidControls {
-			if control == validControl {
-				valid = true
-				break
-			}
-		}
-		if !valid {
-			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid control: "+control)
-			return
-		}
+	controls, err := store.NormalizeControls(req.Controls)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return
 	}
 
-	// Validate time window
-	if !req.StartsAt.Before(req.ExpiresAt) {
-		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "starts_at must be before expires_at")
+	req.Controls = controls
+
+	allowedTables, err := store.NormalizeAllowedTables(req.AllowedTables)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
 		return
 	}
 
-	// The target must be a database, never an SSH bastion (a dial path).
-	if target, err := s.store.GetServerByUID(c.Request.Context(), req.DatabaseID); err == nil && target.IsSSH() {
-		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "cannot grant access to an ssh server")
+	allowedCIDRs, err := store.NormalizeAllowedCIDRs(req.AllowedCIDRs)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
 		return
 	}
 
@@ -63,38 +74,105 @@ func (s *Server) handleCreateGrant(c *gin.Context) {
 		UserID:              req.UserID,
 		DatabaseID:          req.DatabaseID,
 		Controls:            req.Controls,
+		AllowedTables:       allowedTables,
+		AllowedCIDRs:        allowedCIDRs,
 		GrantedBy:           currentUser.UID,
 		StartsAt:            req.StartsAt,
 		ExpiresAt:           req.ExpiresAt,
 		MaxQueryCounts:      req.MaxQueryCounts,
 		MaxBytesTransferred: req.MaxBytesTransferred,
+		MaxRowsReturned:     req.MaxRowsReturned,
+		Labels:              req.Labels,
 	}
 
-	result, err := s.store.CreateGrant(c.Request.Context(), grant)
-	if err != nil {
-		writeInternalError(c, s.logger, err, "failed to create grant")
+	target, err := s.store.GetServerByUID(c.Request.Context(), req.DatabaseID)
+	if err == nil {
+		target.GrantDefaults.Fill(grant)
+	}
+
+	// Validate time window
+	if grant.ExpiresAt.IsZero() {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+			"expires_at is required (the database has no default grant duration)")
 		return
 	}
 
-	// Log audit event
-	details, _ := json.Marshal(map[string]interface{}{
-		"grant_uid":   result.UID,
-		"user_id":     result.UserID,
-		"database_id": result.DatabaseID,
-		"controls":    result.Controls,
-		"starts_at":   result.StartsAt,
-		"expires_at":  result.ExpiresAt,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "grant.created",
-		UserID:      &result.UserID,
-		PerformedBy: &currentUser.UID,
-		Details:     details,
+	if !grant.StartsAt.Before(grant.ExpiresAt) {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "starts_at must be before expires_at")
+		return
+	}
+
+	if target != nil {
+		if msg := checkGrantTarget(target, grant); msg != "" {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, msg)
+			return
+		}
+	}
+
+	var result *store.Grant
+
+	err = s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		var err error
+		if result, err = tx.CreateGrant(ctx, grant); err != nil {
+			return err
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			UserID:      &result.UserID,
+			PerformedBy: &currentUser.UID,
+			Payload: audit.GrantCreatedV1{
+				GrantUID:      result.UID,
+				UserID:        result.UserID,
+				DatabaseID:    result.DatabaseID,
+				Controls:      result.Controls,
+				AllowedTables: result.AllowedTables,
+				AllowedCIDRs:  result.AllowedCIDRs,
+				StartsAt:      result.StartsAt,
+				ExpiresAt:     result.ExpiresAt,
+				Labels:        result.Labels,
+			},
+		})
 	})
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to create grant")
+		return
+	}
 
 	successResponse(c, result)
 }
 
+// checkGrantTarget checks a grant against the database it targets, once its
+// defaults are filled. Returns a validation message, or "" when fine.
+func checkGrantTarget(target *store.Server, grant *store.Grant) string {
+	// The target must be a database, never an SSH bastion (a dial path).
+	if target.IsSSH() {
+		return "cannot grant access to an ssh server"
+	}
+
+	// A control the target's proxy cannot enforce would lock the user out.
+	if unsupported := store.UnsupportedControls(grant.Controls, target.Protocol); len(unsupported) > 0 {
+		return "controls not supported for " + target.Protocol + " databases: " + strings.Join(unsupported, ", ")
+	}
+
+	if grant.RestrictsTables() && !store.SupportsAllowedTables(target.Protocol) {
+		return "allowed_tables not supported for " + target.Protocol + " databases"
+	}
+
+	if grant.RestrictsClients() && !store.SupportsAllowedCIDRs(target.Protocol) {
+		return "allowed_cidrs not supported for " + target.Protocol + " databases"
+	}
+
+	if grant.MaxRowsReturned != nil && !store.SupportsMaxRowsReturned(target.Protocol) {
+		return "max_rows_returned not supported for " + target.Protocol + " databases"
+	}
+
+	if err := target.GrantDefaults.Check(grant); err != nil {
+		return err.Error()
+	}
+
+	return ""
+}
+
 // handleListGrants lists grants with optional filters based on user role
 func (s *Server) handleListGrants(c *gin.Context) {
 	currentUser := getCurrentUser(c)
@@ -117,8 +195,20 @@ func (s *Server) handleListGrants(c *gin.Context) {
 		filter.ActiveOnly = true
 	}
 
+	labels, ok := bindLabelSelector(c)
+	if !ok {
+		return
+	}
+	filter.Labels = labels
+
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
+
 	// Connector can only see their own grants
-	if !currentUser.IsAdmin() && !currentUser.IsViewer() {
+	if !currentUser.Can(store.PermissionQueriesRead) {
 		filter.UserID = &currentUser.UID
 	}
 
@@ -128,7 +218,13 @@ func (s *Server) handleListGrants(c *gin.Context) {
 		return
 	}
 
-	successResponse(c, gin.H{"grants": grants})
+	total, err := s.store.CountGrants(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count grants")
+		return
+	}
+
+	pageResponse(c, p, total, gin.H{"grants": grants})
 }
 
 // handleGetGrant retrieves a specific grant based on user role
@@ -148,7 +244,7 @@ func (s *Server) handleGetGrant(c *gin.Context) {
 	}
 
 	// Connector can only see their own grants
-	if !currentUser.IsAdmin() && !currentUser.IsViewer() {
+	if !currentUser.Can(store.PermissionQueriesRead) {
 		if grant.UserID != currentUser.UID {
 			writeError(c, http.StatusForbidden, ErrCodeForbidden, "no access to this grant")
 			return
@@ -158,6 +254,61 @@ func (s *Server) handleGetGrant(c *gin.Context) {
 	successResponse(c, grant)
 }
 
+// handleSetGrantLabels replaces the labels of a grant. Labels only organize
+// grants; they never change the access a grant gives.
+func (s *Server) handleSetGrantLabels(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid grant UID")
+		return
+	}
+
+	var req SetGrantLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+		return
+	}
+
+	if !validLabels(c, req.Labels) {
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+
+	err = s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		if err := tx.SetGrantLabels(ctx, uid, req.Labels); err != nil {
+			return err
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			PerformedBy: &currentUser.UID,
+			Payload:     audit.GrantLabelsUpdatedV1{GrantUID: uid, Labels: req.Labels},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrGrantNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "grant not found")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to update grant labels")
+		return
+	}
+
+	successResponse(c, gin.H{"message": "grant labels updated"})
+}
+
+// signalRevokedGrants tells live proxy sessions that the given grants were
+// revoked as a side effect of deleting their user or database.
+func (s *Server) signalRevokedGrants(ctx context.Context, uids []uuid.UUID) {
+	for _, uid := range uids {
+		if signaled := s.store.Revocations().Revoke(uid); signaled > 0 {
+			s.logger.InfoContext(ctx, "grant revoked by deletion: signaled live sessions",
+				slog.String("grant_uid", uid.String()),
+				slog.Int("sessions", signaled))
+		}
+	}
+}
+
 // handleRevokeGrant revokes a grant
 func (s *Server) handleRevokeGrant(c *gin.Context) {
 	uid, err := parseUIDParam(c)
@@ -167,7 +318,24 @@ func (s *Server) handleRevokeGrant(c *gin.Context) {
 	}
 
 	currentUser := getCurrentUser(c)
-	if err := s.store.RevokeGrant(c.Request.Context(), uid, currentUser.UID); err != nil {
+
+	err = s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		if err := tx.RevokeGrant(ctx, uid, currentUser.UID); err != nil {
+			return err
+		}
+
+		grant, _ := tx.GetGrantByUID(ctx, uid)
+		var userID *uuid.UUID
+		if grant != nil {
+			userID = &grant.UserID
+		}
+		return audit.Emit(ctx, tx, audit.Event{
+			UserID:      userID,
+			PerformedBy: &currentUser.UID,
+			Payload:     audit.GrantRevokedV1{GrantUID: uid},
+		})
+	})
+	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to revoke grant")
 		return
 	}
@@ -181,21 +349,5 @@ func (s *Server) handleRevokeGrant(c *gin.Context) {
 			slog.Int("sessions", signaled))
 	}
 
-	// Log audit event
-	grant, _ := s.store.GetGrantByUID(c.Request.Context(), uid)
-	var userID *uuid.UUID
-	if grant != nil {
-		userID = &grant.UserID
-	}
-	details, _ := json.Marshal(map[string]interface{}{
-		"grant_uid": uid,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "grant.revoked",
-		UserID:      userID,
-		PerformedBy: &currentUser.UID,
-		Details:     details,
-	})
-
 	successResponse(c, gin.H{"message": "grant revoked"})
 }
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+)
+
+// handleListInstances lists the dbbat instances sharing this storage
+// database: the live ones, or every registered one with all=true, so stopped
+// instances referenced by past connections can still be looked up.
+func (s *Server) handleListInstances(c *gin.Context) {
+	instances, err := s.store.ListInstances(c.Request.Context(), c.Query("all") == "true", time.Now())
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to list instances")
+		return
+	}
+
+	resp := gin.H{"instances": instances}
+	if id := s.store.InstanceID(); id != uuid.Nil {
+		resp["current_instance_id"] = id
+	}
+
+	successResponse(c, resp)
+}
+
+// handleDrain starts draining the instance serving the request: its proxies
+// stop accepting sessions, it refuses logins and fails its health check, and
+// it exits once its sessions ended or the drain timeout passed. Draining an
+// instance already draining is a no-op.
+func (s *Server) handleDrain(c *gin.Context) {
+	if shared.StartDraining() {
+		s.logger.InfoContext(c.Request.Context(), "drain requested",
+			slog.String("username", getCurrentUser(c).Username))
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":          "draining",
+		"draining_since":  shared.DrainingSince(),
+		"active_sessions": shared.ActiveSessions(),
+	})
+}
@@ -2,13 +2,13 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -16,6 +16,9 @@ import (
 type CreateAPIKeyRequest struct {
 	Name      string     `json:"name" binding:"required"`
 	ExpiresAt *time.Time `json:"expires_at"`
+	// Scopes restrict the key to some API routes ("read:queries",
+	// "write:grants"); none leaves it every permission of the user.
+	Scopes []string `json:"scopes"`
 }
 
 // CreateAPIKeyResponse represents the response when creating an API key
@@ -25,6 +28,7 @@ type CreateAPIKeyResponse struct {
 	Key                  string           `json:"key"` // Only returned once!
 	KeyPrefix            string           `json:"key_prefix"`
 	ExpiresAt            *time.Time       `json:"expires_at"`
+	Scopes               []string         `json:"scopes"`
 	CreatedAt            time.Time        `json:"created_at"`
 	Connections          []ConnectionInfo `json:"connections"`
 	ConnectionsTruncated bool             `json:"connections_truncated"`
@@ -41,27 +45,32 @@ func (s *Server) handleCreateAPIKey(c *gin.Context) {
 		return
 	}
 
+	scopes, err := store.NormalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return
+	}
+
 	currentUser := getCurrentUser(c)
 
 	// Create API key
-	apiKey, plainKey, err := s.store.CreateAPIKey(c.Request.Context(), currentUser.UID, req.Name, req.ExpiresAt, s.encryptionKey)
+	apiKey, plainKey, err := s.store.CreateScopedAPIKey(c.Request.Context(), currentUser.UID, req.Name, req.ExpiresAt, scopes, s.encryptionKey)
 	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to create API key")
 		return
 	}
 
 	// Log audit event
-	details, _ := json.Marshal(map[string]interface{}{
-		"key_name":   apiKey.Name,
-		"key_prefix": apiKey.KeyPrefix,
-		"user_id":    currentUser.UID,
-		"expires_at": apiKey.ExpiresAt,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "api_key.created",
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
 		UserID:      &currentUser.UID,
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.APIKeyCreatedV1{
+			KeyName:   apiKey.Name,
+			KeyPrefix: apiKey.KeyPrefix,
+			UserID:    currentUser.UID,
+			ExpiresAt: apiKey.ExpiresAt,
+			Scopes:    apiKey.Scopes,
+		},
 	})
 
 	connections, truncated := s.buildConnectionsForUser(c.Request.Context(), currentUser, plainKey)
@@ -73,6 +82,7 @@ func (s *Server) handleCreateAPIKey(c *gin.Context) {
 		Key:                  plainKey,
 		KeyPrefix:            apiKey.KeyPrefix,
 		ExpiresAt:            apiKey.ExpiresAt,
+		Scopes:               apiKey.Scopes,
 		CreatedAt:            apiKey.CreatedAt,
 		Connections:          connections,
 		ConnectionsTruncated: truncated,
@@ -114,13 +124,25 @@ func (s *Server) handleListAPIKeys(c *gin.Context) {
 		}
 	}
 
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
+
 	keys, err := s.store.ListAPIKeys(c.Request.Context(), filter)
 	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to list API keys")
 		return
 	}
 
-	successResponse(c, gin.H{"keys": keys})
+	total, err := s.store.CountAPIKeys(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count API keys")
+		return
+	}
+
+	pageResponse(c, p, total, gin.H{"keys": keys})
 }
 
 // handleGetAPIKey retrieves a specific API key
@@ -177,16 +199,14 @@ func (s *Server) handleRevokeAPIKey(c *gin.Context) {
 	}
 
 	// Log audit event
-	details, _ := json.Marshal(map[string]interface{}{
-		"key_name":   apiKey.Name,
-		"key_prefix": apiKey.KeyPrefix,
-		"revoked_by": currentUser.UID,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "api_key.revoked",
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
 		UserID:      &apiKey.UserID,
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.APIKeyRevokedV1{
+			KeyName:   apiKey.Name,
+			KeyPrefix: apiKey.KeyPrefix,
+			RevokedBy: currentUser.UID,
+		},
 	})
 
 	c.Status(http.StatusNoContent)
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// errInvalidLabelSelector is returned for a label query parameter that is not
+// of the key=value form.
+var errInvalidLabelSelector = errors.New("label filter must be of the form key=value")
+
+// parseLabelSelector reads the repeatable ?label=key=value query parameter of
+// list endpoints (e.g. ?label=env=prod&label=team=payments). Rows must carry
+// every given label to match.
+func parseLabelSelector(c *gin.Context) (store.Labels, error) {
+	values := c.QueryArray("label")
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	labels := make(store.Labels, len(values))
+
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, errInvalidLabelSelector
+		}
+
+		labels[key] = val
+	}
+
+	return labels, nil
+}
+
+// bindLabelSelector parses the label filter, writing a 400 and returning false
+// when it is malformed.
+func bindLabelSelector(c *gin.Context) (store.Labels, bool) {
+	labels, err := parseLabelSelector(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return nil, false
+	}
+
+	return labels, true
+}
+
+// validLabels writes a 400 and returns false when labels submitted in a
+// request body exceed the limits or carry a malformed key.
+func validLabels(c *gin.Context, labels store.Labels) bool {
+	if err := labels.Validate(); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		query   string
+		want    store.Labels
+		wantErr bool
+	}{
+		{name: "none", query: "", want: nil},
+		{name: "single", query: "label=env=prod", want: store.Labels{"env": "prod"}},
+		{
+			name:  "several",
+			query: "label=env=prod&label=team=payments",
+			want:  store.Labels{"env": "prod", "team": "payments"},
+		},
+		{name: "value with equals", query: "label=expr=a%3Db", want: store.Labels{"expr": "a=b"}},
+		{name: "empty value", query: "label=deprecated=", want: store.Labels{"deprecated": ""}},
+		{name: "missing equals", query: "label=env", wantErr: true},
+		{name: "missing key", query: "label==prod", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/v1/servers?"+tt.query, nil)
+
+			got, err := parseLabelSelector(c)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errInvalidLabelSelector)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/auth/ldap"
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// isDirectoryLogin reports whether a login is checked against the LDAP
+// directory: the user is a directory user, or has no local account yet.
+func (s *Server) isDirectoryLogin(user *store.User) bool {
+	return s.directory != nil && (user == nil || user.PasswordHash == crypto.DirectoryPasswordHash)
+}
+
+// directoryLogin signs a user in with their directory password. user is the
+// local account of that name, nil when there is none yet: it is then created
+// if the configuration allows it. With a role mapping, the roles of the user
+// are synced from their groups on every login.
+func (s *Server) directoryLogin(ctx context.Context, user *store.User, username, password string) (*store.User, error) {
+	entry, err := s.directory.Authenticate(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, managed := s.directoryRoles(entry.Groups)
+	if len(roles) == 0 {
+		return nil, errOAuthNoRole
+	}
+
+	if user != nil {
+		if managed {
+			if err := s.syncOAuthRoles(ctx, user, roles); err != nil {
+				return nil, err
+			}
+		}
+
+		return user, nil
+	}
+
+	if !s.config.LDAP.AutoCreateUsers {
+		return nil, errOAuthUserNotLinked
+	}
+
+	user, err = s.store.CreateUser(ctx, username, crypto.DirectoryPasswordHash, roles)
+	if err != nil {
+		return nil, fmt.Errorf("create user: %w", err)
+	}
+
+	// The directory owns the password: there is no initial password to change.
+	directoryHash := crypto.DirectoryPasswordHash
+	if err := s.store.UpdateUser(ctx, user.UID, store.UserUpdate{PasswordHash: &directoryHash}); err != nil {
+		return nil, fmt.Errorf("mark password changed: %w", err)
+	}
+
+	now := time.Now()
+	user.PasswordChangedAt = &now
+
+	if _, err := s.store.CreateUserIdentity(ctx, &store.UserIdentity{
+		UserID:      user.UID,
+		Provider:    store.IdentityTypeLDAP,
+		ProviderID:  entry.DN,
+		Email:       entry.Email,
+		DisplayName: entry.DisplayName,
+	}); err != nil {
+		return nil, fmt.Errorf("link identity: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "auto-created user from LDAP",
+		slog.String("username", username),
+		slog.String("dn", entry.DN),
+		slog.Any("roles", roles))
+
+	return user, nil
+}
+
+// directoryRoles returns the roles of a directory user from their groups,
+// and whether the configuration manages them (a role mapping is set).
+func (s *Server) directoryRoles(groups []string) ([]string, bool) {
+	// Validated by config.Load.
+	mapping, _ := s.config.LDAP.Roles()
+
+	var roles []string
+	for _, group := range groups {
+		roles = append(roles, mapping[strings.ToLower(ldap.GroupName(group))]...)
+	}
+
+	if len(roles) == 0 && s.config.LDAP.DefaultRole != "" {
+		roles = []string{s.config.LDAP.DefaultRole}
+	}
+
+	slices.Sort(roles)
+
+	return slices.Compact(roles), len(mapping) > 0
+}
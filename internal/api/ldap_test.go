@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fclairamb/dbbat/internal/auth/ldap"
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestDirectoryRoles(t *testing.T) {
+	t.Parallel()
+
+	server := &Server{config: &config.Config{LDAP: config.LDAPConfig{
+		DefaultRole: store.RoleConnector,
+		RoleMapping: "DBBat-Admins=admin,sre=viewer,sre=connector",
+	}}}
+
+	roles, managed := server.directoryRoles([]string{
+		"CN=sre,OU=Groups,DC=corp,DC=example",
+		"cn=dbbat-admins,ou=groups,dc=corp,dc=example",
+		"CN=other,OU=Groups,DC=corp,DC=example",
+	})
+	assert.Equal(t, []string{store.RoleAdmin, store.RoleConnector, store.RoleViewer}, roles)
+	assert.True(t, managed)
+
+	roles, _ = server.directoryRoles([]string{"CN=other,OU=Groups,DC=corp,DC=example"})
+	assert.Equal(t, []string{store.RoleConnector}, roles, "unmapped users get the default role")
+
+	server.config.LDAP.DefaultRole = ""
+	roles, _ = server.directoryRoles(nil)
+	assert.Empty(t, roles, "without a default role, unmapped users get none")
+
+	server.config.LDAP.RoleMapping = ""
+	server.config.LDAP.DefaultRole = store.RoleViewer
+	_, managed = server.directoryRoles(nil)
+	assert.False(t, managed, "without a mapping, roles are only set at creation")
+}
+
+func TestIsDirectoryLogin(t *testing.T) {
+	t.Parallel()
+
+	local := &store.User{PasswordHash: "$argon2id$v=19$m=8192,t=1,p=4$c2FsdA$aGFzaA"}
+	directoryUser := &store.User{PasswordHash: crypto.DirectoryPasswordHash}
+
+	server := &Server{}
+	assert.False(t, server.isDirectoryLogin(nil), "without a directory, unknown users are refused")
+	assert.False(t, server.isDirectoryLogin(directoryUser))
+
+	server.directory = &ldap.Directory{}
+	assert.True(t, server.isDirectoryLogin(nil), "unknown users may be created from the directory")
+	assert.True(t, server.isDirectoryLogin(directoryUser))
+	assert.False(t, server.isDirectoryLogin(local), "local users keep their local password")
+
+	server.config = &config.Config{LocalLogin: config.LocalLoginDisabled}
+	assert.True(t, server.localLoginAllowed(directoryUser), "directory users do not sign in with a local password")
+	assert.False(t, server.localLoginAllowed(local))
+}
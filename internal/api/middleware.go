@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"sync"
@@ -9,6 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
 )
 
 // Auth context keys
@@ -21,11 +23,16 @@ const (
 	authMethodWebSession = "web_session"
 )
 
-// authMiddleware validates Basic Auth or Bearer token credentials
+// authMiddleware validates Basic Auth, Bearer token or signed request credentials
 func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
+		if params, ok := strings.CutPrefix(authHeader, signatureScheme+" "); ok {
+			s.handleSignedAuth(c, params)
+			return
+		}
+
 		// Try Bearer token first
 		if strings.HasPrefix(authHeader, "Bearer ") {
 			s.handleBearerAuth(c, strings.TrimPrefix(authHeader, "Bearer "))
@@ -62,6 +69,13 @@ func (s *Server) handleBearerAuth(c *gin.Context, token string) {
 		_ = s.store.IncrementAPIKeyUsage(ctx, apiKey.ID)
 	}()
 
+	// Keys predating request signing get their secret on first use.
+	if apiKey.SigningData() == nil && !apiKey.IsWebSession() {
+		go func() {
+			_ = s.store.EnsureAPIKeySigningSecret(context.WithoutCancel(ctx), apiKey.ID, token, s.encryptionKey)
+		}()
+	}
+
 	// Determine auth method based on key type
 	authMethod := authMethodAPIKey
 	if apiKey.IsWebSession() {
@@ -72,6 +86,11 @@ func (s *Server) handleBearerAuth(c *gin.Context, token string) {
 	c.Set(contextKeyUser, user)
 	c.Set(contextKeyAPIKey, apiKey)
 	c.Set(contextKeyAuthMethod, authMethod)
+
+	if !checkAPIKeyScope(c, apiKey) {
+		return
+	}
+
 	c.Next()
 }
 
@@ -109,7 +128,7 @@ func (s *Server) handleBasicAuth(c *gin.Context) {
 	// Verify password (using cache if available)
 	var valid bool
 	if s.authCache != nil {
-		valid, err = s.authCache.VerifyPassword(c.Request.Context(), user.UID.String(), password, user.PasswordHash)
+		valid, err = s.authCache.VerifyUserPassword(c.Request.Context(), user.UID.String(), user.Username, password, user.PasswordHash)
 	} else {
 		// Fallback to direct verification if cache not initialized
 		valid, err = verifyPasswordDirect(user.PasswordHash, password)
@@ -126,12 +145,33 @@ func (s *Server) handleBasicAuth(c *gin.Context) {
 	// Reset failure count on successful login
 	s.authFailureTracker.resetFailures(username)
 
+	if !s.localLoginAllowed(user) {
+		writeError(c, http.StatusForbidden, ErrCodeLocalLoginDisabled, "password authentication is disabled, use an API key")
+		c.Abort()
+		return
+	}
+
+	s.upgradePasswordHash(c.Request.Context(), user, password)
+
 	// Store user in context
 	c.Set(contextKeyUser, user)
 	c.Set(contextKeyAuthMethod, authMethodBasic)
 	c.Next()
 }
 
+// refuseWhileDraining returns a middleware that refuses logins once the
+// instance is draining, so the load balancer sends them to another instance.
+func (s *Server) refuseWhileDraining() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if shared.DrainingSince() != nil {
+			writeError(c, http.StatusServiceUnavailable, ErrCodeDraining, "this instance is draining, retry on another instance")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // requireRole returns a middleware that ensures the user has the specified role
 func (s *Server) requireRole(role string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -150,12 +190,13 @@ func (s *Server) requireAdmin() gin.HandlerFunc {
 	return s.requireRole("admin")
 }
 
-// requireAdminOrViewer middleware ensures the user has either admin or viewer role
-func (s *Server) requireAdminOrViewer() gin.HandlerFunc {
+// requirePermission returns a middleware that ensures one of the user's roles
+// grants the permission
+func (s *Server) requirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user := getCurrentUser(c)
-		if user == nil || (!user.IsAdmin() && !user.IsViewer()) {
-			writeError(c, http.StatusForbidden, ErrCodeForbidden, "admin or viewer access required")
+		if user == nil || !user.Can(permission) {
+			writeError(c, http.StatusForbidden, ErrCodeForbidden, permission+" permission required")
 			c.Abort()
 			return
 		}
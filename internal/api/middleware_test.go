@@ -1,9 +1,13 @@
 package api
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
+
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -154,3 +158,42 @@ func TestUserHasChangedPassword(t *testing.T) {
 		}
 	})
 }
+
+func TestRequirePermission(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	s := &Server{}
+
+	tests := []struct {
+		roles      []string
+		permission string
+		want       int
+	}{
+		{[]string{store.RoleAdmin}, store.PermissionRowsRead, http.StatusOK},
+		{[]string{store.RoleViewer}, store.PermissionRowsRead, http.StatusOK},
+		{[]string{store.RoleAuditor}, store.PermissionQueriesRead, http.StatusOK},
+		{[]string{store.RoleAuditor}, store.PermissionRowsRead, http.StatusForbidden},
+		{[]string{store.RoleAuditor, store.RoleViewer}, store.PermissionRowsRead, http.StatusOK},
+		{[]string{store.RoleViewer}, store.PermissionRawSQLRead, http.StatusOK},
+		{[]string{store.RoleAuditor}, store.PermissionRawSQLRead, http.StatusForbidden},
+		{[]string{store.RoleConnector}, store.PermissionQueriesRead, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		router := gin.New()
+		router.GET("/", func(c *gin.Context) {
+			c.Set(contextKeyUser, &store.User{Roles: tt.roles})
+		}, s.requirePermission(tt.permission), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+		if w.Code != tt.want {
+			t.Errorf("roles %v, permission %s: status = %d, want %d", tt.roles, tt.permission, w.Code, tt.want)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
@@ -19,7 +20,9 @@ import (
 	"golang.org/x/text/transform"
 	"golang.org/x/text/unicode/norm"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/auth"
+	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/crypto"
 	"github.com/fclairamb/dbbat/internal/store"
 )
@@ -35,20 +38,27 @@ type authProviderInfo struct {
 	Type         string `json:"type"`
 	Enabled      bool   `json:"enabled"`
 	AuthorizeURL string `json:"authorize_url,omitempty"`
+	DisplayName  string `json:"display_name,omitempty"`
 }
 
 // handleAuthProviders returns which authentication methods are available.
 // GET /api/v1/auth/providers
 func (s *Server) handleAuthProviders(c *gin.Context) {
 	providers := make([]authProviderInfo, 0, 1+len(s.oauthProviders))
-	providers = append(providers, authProviderInfo{Type: "password", Enabled: true})
+	providers = append(providers, authProviderInfo{Type: "password", Enabled: s.localLoginMode() != config.LocalLoginDisabled || s.directory != nil})
 
 	for name := range s.oauthProviders {
-		providers = append(providers, authProviderInfo{
+		info := authProviderInfo{
 			Type:         name,
 			Enabled:      true,
 			AuthorizeURL: "/api/v1/auth/" + name,
-		})
+		}
+
+		if name == store.IdentityTypeOIDC {
+			info.DisplayName = s.config.OIDC.DisplayName
+		}
+
+		providers = append(providers, info)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"providers": providers})
@@ -64,6 +74,17 @@ func (s *Server) handleOAuthAuthorize(providerName string) gin.HandlerFunc {
 			return
 		}
 
+		// Resolve the provider's endpoints if it discovers them
+		if discoverer, ok := provider.(auth.OAuthDiscoverer); ok {
+			if err := discoverer.Discover(c.Request.Context()); err != nil {
+				s.logger.ErrorContext(c.Request.Context(), "OAuth provider discovery failed",
+					slog.String("provider", providerName),
+					slog.Any("error", err))
+				s.redirectWithError(c, ErrCodeOAuthProviderError)
+				return
+			}
+		}
+
 		// Generate random state token
 		stateToken, err := generateRandomState()
 		if err != nil {
@@ -154,6 +175,10 @@ func (s *Server) handleOAuthCallback(providerName string) gin.HandlerFunc {
 				s.redirectWithError(c, ErrCodeOAuthUserNotLinked)
 				return
 			}
+			if errors.Is(err, errOAuthNoRole) {
+				s.redirectWithError(c, ErrCodeOAuthNoRole)
+				return
+			}
 			s.redirectWithError(c, ErrCodeOAuthFailed)
 			return
 		}
@@ -179,22 +204,131 @@ func (s *Server) handleOAuthCallback(providerName string) gin.HandlerFunc {
 	}
 }
 
-// errOAuthUserNotLinked is returned when no local user could be found or created.
-var errOAuthUserNotLinked = errors.New("no linked account and auto-create disabled")
+var (
+	// errOAuthUserNotLinked is returned when no local user could be found or created.
+	errOAuthUserNotLinked = errors.New("no linked account and auto-create disabled")
+	// errOAuthNoRole is returned when none of the user's claims maps to a
+	// role and the provider has no default role.
+	errOAuthNoRole = errors.New("no role mapped from the identity provider")
+)
+
+// oauthUserPolicy returns whether users signing in through a provider are
+// created on first login.
+func (s *Server) oauthUserPolicy(providerName string) bool {
+	if s.config == nil {
+		return false
+	}
+
+	if providerName == store.IdentityTypeOIDC {
+		return s.config.OIDC.AutoCreateUsers
+	}
+
+	return s.config.SlackAuth.AutoCreateUsers
+}
+
+// oauthRoles returns the roles of a user signing in through a provider, and
+// whether the provider manages them: OIDC with a role mapping replaces the
+// user's roles on every login, others only pick the roles of new users.
+func (s *Server) oauthRoles(providerName string, oauthUser *auth.OAuthUser) ([]string, bool) {
+	if providerName != store.IdentityTypeOIDC {
+		role := s.config.SlackAuth.DefaultRole
+		if role == "" {
+			role = store.RoleConnector
+		}
+
+		return []string{role}, false
+	}
+
+	// Validated by config.Load.
+	mapping, _ := s.config.OIDC.Roles()
+
+	var roles []string
+	for _, group := range oauthUser.Groups {
+		roles = append(roles, mapping[group]...)
+	}
+
+	if len(roles) == 0 && s.config.OIDC.DefaultRole != "" {
+		roles = []string{s.config.OIDC.DefaultRole}
+	}
+
+	slices.Sort(roles)
+
+	return slices.Compact(roles), len(mapping) > 0
+}
+
+// syncOAuthRoles replaces the roles of a user signing in through a provider
+// that manages them. The last admin keeps the admin role, so a mapping
+// mistake cannot lock everyone out of the administration.
+func (s *Server) syncOAuthRoles(ctx context.Context, user *store.User, roles []string) error {
+	current := slices.Clone(user.Roles)
+	slices.Sort(current)
+
+	if slices.Equal(current, roles) {
+		return nil
+	}
+
+	if user.IsAdmin() && !slices.Contains(roles, store.RoleAdmin) {
+		adminCount, err := s.store.CountAdmins(ctx)
+		if err != nil {
+			return fmt.Errorf("count admins: %w", err)
+		}
+
+		if adminCount <= 1 {
+			s.logger.WarnContext(ctx, "identity provider roles would demote the last admin, keeping the admin role",
+				slog.String("username", user.Username))
+			roles = append(roles, store.RoleAdmin)
+		}
+	}
+
+	if err := s.store.UpdateUser(ctx, user.UID, store.UserUpdate{Roles: roles}); err != nil {
+		return fmt.Errorf("update roles: %w", err)
+	}
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		UserID:  &user.UID,
+		Payload: audit.UserUpdatedV1{UpdatedFields: audit.UserUpdatedFieldsV1{Roles: roles}},
+	})
+
+	user.Roles = roles
+
+	return nil
+}
 
 // findOrCreateOAuthUser resolves an OAuthUser to a local user.
 // Resolution order:
 //  1. Existing identity link (provider + provider_id)
 //  2. Match by email against existing usernames
 //  3. Auto-create if enabled
+//
+// When the provider manages roles, the resolved user's roles are synced.
 func (s *Server) findOrCreateOAuthUser(ctx context.Context, provider auth.OAuthProvider, oauthUser *auth.OAuthUser) (*store.User, error) {
 	providerName := provider.Name()
 
+	var roles []string
+	var managedRoles bool
+
+	if s.config != nil {
+		roles, managedRoles = s.oauthRoles(providerName, oauthUser)
+		if len(roles) == 0 {
+			return nil, errOAuthNoRole
+		}
+	}
+
+	resolved := func(user *store.User) (*store.User, error) {
+		if managedRoles {
+			if err := s.syncOAuthRoles(ctx, user, roles); err != nil {
+				return nil, err
+			}
+		}
+
+		return user, nil
+	}
+
 	// 1. Check if identity is already linked
 	user, err := s.store.GetUserByIdentity(ctx, providerName, oauthUser.ProviderID)
 	switch {
 	case err == nil:
-		return user, nil
+		return resolved(user)
 	case errors.Is(err, store.ErrIdentityNotFound):
 		// fall through to email / auto-create
 	case errors.Is(err, store.ErrUserNotFound):
@@ -218,7 +352,7 @@ func (s *Server) findOrCreateOAuthUser(ctx context.Context, provider auth.OAuthP
 			if linkErr := s.linkIdentity(ctx, user.UID, providerName, oauthUser); linkErr != nil {
 				return nil, fmt.Errorf("link identity: %w", linkErr)
 			}
-			return user, nil
+			return resolved(user)
 		}
 		if !errors.Is(err, store.ErrUserNotFound) {
 			return nil, fmt.Errorf("email lookup: %w", err)
@@ -226,15 +360,10 @@ func (s *Server) findOrCreateOAuthUser(ctx context.Context, provider auth.OAuthP
 	}
 
 	// 3. Auto-create if enabled
-	if s.config == nil || !s.config.SlackAuth.AutoCreateUsers {
+	if !s.oauthUserPolicy(providerName) {
 		return nil, errOAuthUserNotLinked
 	}
 
-	role := s.config.SlackAuth.DefaultRole
-	if role == "" {
-		role = store.RoleConnector
-	}
-
 	// Generate a unique username from the display name or email
 	username := s.generateUniqueUsername(ctx, oauthUser.DisplayName, oauthUser.Email)
 
@@ -249,7 +378,7 @@ func (s *Server) findOrCreateOAuthUser(ctx context.Context, provider auth.OAuthP
 		return nil, fmt.Errorf("hash password: %w", err)
 	}
 
-	user, err = s.store.CreateUser(ctx, username, passwordHash, []string{role})
+	user, err = s.store.CreateUser(ctx, username, passwordHash, roles)
 	if err != nil {
 		return nil, fmt.Errorf("create user: %w", err)
 	}
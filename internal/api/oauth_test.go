@@ -52,7 +52,8 @@ func TestFindOrCreateOAuthUser_OrphanIdentity(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	require.NoError(t, dataStore.DeleteUser(ctx, user.UID))
+	_, err = dataStore.DeleteUser(ctx, user.UID, uuid.Nil)
+	require.NoError(t, err)
 
 	// After DeleteUser the identity must already be gone (cascade fix).
 	// But even if it were orphaned (pre-fix DBs), findOrCreateOAuthUser must recover.
@@ -189,3 +190,96 @@ func TestCanonicalizeUsername(t *testing.T) {
 		})
 	}
 }
+
+func TestOAuthRoles(t *testing.T) {
+	t.Parallel()
+
+	server := &Server{config: &config.Config{
+		SlackAuth: config.SlackAuthConfig{DefaultRole: store.RoleViewer},
+		OIDC: config.OIDCConfig{
+			DefaultRole: store.RoleConnector,
+			RoleMapping: "dbbat-admins=admin,sre=viewer,sre=connector",
+		},
+	}}
+
+	roles, managed := server.oauthRoles(store.IdentityTypeSlack, &auth.OAuthUser{Groups: []string{"dbbat-admins"}})
+	assert.Equal(t, []string{store.RoleViewer}, roles)
+	assert.False(t, managed)
+
+	roles, managed = server.oauthRoles(store.IdentityTypeOIDC, &auth.OAuthUser{Groups: []string{"sre", "dbbat-admins", "other"}})
+	assert.Equal(t, []string{store.RoleAdmin, store.RoleConnector, store.RoleViewer}, roles)
+	assert.True(t, managed)
+
+	roles, _ = server.oauthRoles(store.IdentityTypeOIDC, &auth.OAuthUser{Groups: []string{"other"}})
+	assert.Equal(t, []string{store.RoleConnector}, roles, "unmapped users get the default role")
+
+	server.config.OIDC.DefaultRole = ""
+	roles, _ = server.oauthRoles(store.IdentityTypeOIDC, &auth.OAuthUser{})
+	assert.Empty(t, roles, "without a default role, unmapped users get none")
+}
+
+func TestLocalLoginAllowed(t *testing.T) {
+	t.Parallel()
+
+	admin := &store.User{Roles: []string{store.RoleAdmin}}
+	connector := &store.User{Roles: []string{store.RoleConnector}}
+
+	for _, tc := range []struct {
+		mode           string
+		admin, regular bool
+	}{
+		{"", true, true},
+		{config.LocalLoginEnabled, true, true},
+		{config.LocalLoginAdmins, true, false},
+		{config.LocalLoginDisabled, false, false},
+	} {
+		server := &Server{config: &config.Config{LocalLogin: tc.mode}}
+
+		assert.Equal(t, tc.admin, server.localLoginAllowed(admin), "admin, mode %q", tc.mode)
+		assert.Equal(t, tc.regular, server.localLoginAllowed(connector), "connector, mode %q", tc.mode)
+	}
+}
+
+func TestFindOrCreateOAuthUser_OIDCRoleSync(t *testing.T) {
+	t.Parallel()
+
+	server, dataStore := setupTestServer(t)
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+
+	server.config = &config.Config{
+		OIDC: config.OIDCConfig{
+			AutoCreateUsers: true,
+			RoleMapping:     "dbbat-viewers=viewer,dbbat-connectors=connector",
+		},
+	}
+
+	provider := &mockProvider{name: store.IdentityTypeOIDC}
+	oauthUser := &auth.OAuthUser{
+		ProviderID:  "sub-" + suffix,
+		Email:       "oidc-" + suffix + "@example.com",
+		DisplayName: "OIDC User " + suffix,
+		Groups:      []string{"dbbat-viewers"},
+	}
+
+	user, err := server.findOrCreateOAuthUser(ctx, provider, oauthUser)
+	require.NoError(t, err)
+	assert.Equal(t, []string{store.RoleViewer}, user.Roles)
+
+	// The identity provider is the source of truth on the next login.
+	oauthUser.Groups = []string{"dbbat-connectors"}
+
+	user, err = server.findOrCreateOAuthUser(ctx, provider, oauthUser)
+	require.NoError(t, err)
+	assert.Equal(t, []string{store.RoleConnector}, user.Roles)
+
+	stored, err := dataStore.GetUserByUID(ctx, user.UID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{store.RoleConnector}, stored.Roles)
+
+	// Without a mapped role nor a default one, the login is refused.
+	oauthUser.Groups = nil
+
+	_, err = server.findOrCreateOAuthUser(ctx, provider, oauthUser)
+	require.ErrorIs(t, err, errOAuthNoRole)
+}
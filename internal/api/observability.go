@@ -3,15 +3,19 @@ package api
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -33,28 +37,30 @@ func (s *Server) handleListConnections(c *gin.Context) {
 		}
 	}
 
-	if before := c.Query("before"); before != "" {
-		if uid, err := uuid.Parse(before); err == nil {
-			filter.BeforeUID = &uid
-		}
+	if appName := c.Query("application_name"); appName != "" {
+		filter.ApplicationName = &appName
 	}
 
-	if limit := c.Query("limit"); limit != "" {
-		if val, err := strconv.Atoi(limit); err == nil {
-			filter.Limit = val
+	if instanceID := c.Query("instance_id"); instanceID != "" {
+		if uid, err := uuid.Parse(instanceID); err == nil {
+			filter.InstanceID = &uid
 		}
-	} else {
-		filter.Limit = 100 // Default limit
 	}
 
-	if offset := c.Query("offset"); offset != "" {
-		if val, err := strconv.Atoi(offset); err == nil {
-			filter.Offset = val
+	if before := c.Query("before"); before != "" {
+		if uid, err := uuid.Parse(before); err == nil {
+			filter.BeforeUID = &uid
 		}
 	}
 
+	p, ok := pageFromRequest(c, defaultActivityPageLimit)
+	if !ok {
+		return
+	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
+
 	// Connector can only see their own connections
-	if !currentUser.IsAdmin() && !currentUser.IsViewer() {
+	if !currentUser.Can(store.PermissionQueriesRead) {
 		filter.UserID = &currentUser.UID
 	}
 
@@ -64,7 +70,13 @@ func (s *Server) handleListConnections(c *gin.Context) {
 		return
 	}
 
-	successResponse(c, gin.H{"connections": connections})
+	total, err := s.store.CountConnections(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count connections")
+		return
+	}
+
+	pageResponse(c, p, total, gin.H{"connections": connections})
 }
 
 // handleGetConnection retrieves a single connection based on user role.
@@ -88,7 +100,7 @@ func (s *Server) handleGetConnection(c *gin.Context) {
 
 	// Connector can only see their own connections. Report 404, not 403, so
 	// connectors can't learn that a connection they don't own exists.
-	if !currentUser.IsAdmin() && !currentUser.IsViewer() && conn.UserID != currentUser.UID {
+	if !currentUser.Can(store.PermissionQueriesRead) && conn.UserID != currentUser.UID {
 		writeError(c, http.StatusNotFound, ErrCodeNotFound, "connection not found")
 		return
 	}
@@ -96,11 +108,94 @@ func (s *Server) handleGetConnection(c *gin.Context) {
 	successResponse(c, conn)
 }
 
-// handleListQueries lists queries with optional filters
+// handleTerminateConnection forcefully ends a live proxy connection. The
+// session is signaled in-process and tears itself down, recording the
+// "terminated" disconnect reason; a connection served by another DBBat
+// instance cannot be reached and is reported as a conflict.
+func (s *Server) handleTerminateConnection(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid connection UID")
+		return
+	}
+
+	conn, err := s.store.GetConnectionByUID(c.Request.Context(), uid)
+	if err != nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "connection not found")
+		return
+	}
+
+	if conn.DisconnectedAt != nil {
+		writeError(c, http.StatusConflict, ErrCodeConflict, "connection already closed")
+		return
+	}
+
+	if !s.store.Terminations().Terminate(uid) {
+		writeError(c, http.StatusConflict, ErrCodeConflict, "connection is not live on this DBBat instance")
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+
+	s.logger.InfoContext(c.Request.Context(), "connection terminated: signaled live session",
+		slog.String("connection_uid", uid.String()),
+		slog.String("performed_by", currentUser.Username))
+
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
+		UserID:      &conn.UserID,
+		PerformedBy: &currentUser.UID,
+		Payload:     audit.ConnectionTerminatedV1{ConnectionUID: uid, DatabaseID: conn.DatabaseID},
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleListQueries lists queries with optional filters. Literal values are
+// masked for users without the sql:raw permission, or with ?redact=true.
 func (s *Server) handleListQueries(c *gin.Context) {
+	filter, ok := queryFilterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if before := c.Query("before"); before != "" {
+		if uid, err := uuid.Parse(before); err == nil {
+			filter.BeforeUID = &uid
+		}
+	}
+
+	p, ok := pageFromRequest(c, defaultActivityPageLimit)
+	if !ok {
+		return
+	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
+
+	queries, err := s.store.ListQueries(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to list queries")
+		return
+	}
+
+	total, err := s.store.CountQueries(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count queries")
+		return
+	}
+
+	if shouldRedactQueries(c) {
+		s.redactQueries(c.Request.Context(), queries)
+	}
+
+	pageResponse(c, p, total, gin.H{"queries": queries})
+}
+
+// queryFilterFromRequest parses the query log filters shared by the list and
+// the export. Malformed UIDs and timestamps are ignored, as the list always
+// did; it writes the error response and returns false on any other invalid
+// filter.
+func queryFilterFromRequest(c *gin.Context) (store.QueryFilter, bool) {
 	filter := store.QueryFilter{}
 
-	// Parse query parameters
 	if connectionID := c.Query("connection_id"); connectionID != "" {
 		if uid, err := uuid.Parse(connectionID); err == nil {
 			filter.ConnectionID = &uid
@@ -119,6 +214,39 @@ func (s *Server) handleListQueries(c *gin.Context) {
 		}
 	}
 
+	if grantID := c.Query("grant_id"); grantID != "" {
+		if uid, err := uuid.Parse(grantID); err == nil {
+			filter.GrantID = &uid
+		}
+	}
+
+	filter.Fingerprint = c.Query("fingerprint")
+
+	if alertID := c.Query("alert_id"); alertID != "" {
+		if uid, err := uuid.Parse(alertID); err == nil {
+			filter.AlertID = &uid
+		}
+	}
+
+	if accessLevel := c.Query("access_level"); accessLevel != "" {
+		if accessLevel != store.AccessLevelReadOnly && accessLevel != store.AccessLevelReadWrite {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "access_level must be read_only or read_write")
+			return filter, false
+		}
+
+		filter.AccessLevel = accessLevel
+	}
+
+	if dryRun := c.Query("dry_run"); dryRun != "" {
+		value, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "dry_run must be true or false")
+			return filter, false
+		}
+
+		filter.DryRun = &value
+	}
+
 	if startTime := c.Query("start_time"); startTime != "" {
 		if t, err := time.Parse(time.RFC3339, startTime); err == nil {
 			filter.StartTime = &t
@@ -131,36 +259,166 @@ func (s *Server) handleListQueries(c *gin.Context) {
 		}
 	}
 
-	if before := c.Query("before"); before != "" {
-		if uid, err := uuid.Parse(before); err == nil {
-			filter.BeforeUID = &uid
+	return filter, querySearchFromRequest(c, &filter)
+}
+
+// querySearchFromRequest parses the query log search filters: SQL text,
+// error presence, duration and rows affected ranges. Searching the SQL text
+// needs the sql:raw permission, as matches would otherwise reveal the
+// literal values redaction hides.
+func querySearchFromRequest(c *gin.Context, filter *store.QueryFilter) bool {
+	filter.SQLContains = c.Query("sql")
+	filter.SQLRegex = c.Query("sql_regex")
+
+	if filter.SQLContains != "" || filter.SQLRegex != "" {
+		if !getCurrentUser(c).Can(store.PermissionRawSQLRead) {
+			writeError(c, http.StatusForbidden, ErrCodeForbidden, "searching the SQL text requires the sql:raw permission")
+			return false
 		}
 	}
 
-	if limit := c.Query("limit"); limit != "" {
-		if val, err := strconv.Atoi(limit); err == nil {
-			filter.Limit = val
+	// PostgreSQL evaluates the expression; rejecting what Go cannot compile
+	// turns most typos into a 400 rather than a storage error.
+	if filter.SQLRegex != "" {
+		if _, err := regexp.Compile(filter.SQLRegex); err != nil {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "sql_regex is not a valid regular expression")
+			return false
 		}
-	} else {
-		filter.Limit = 100 // Default limit
 	}
 
-	if offset := c.Query("offset"); offset != "" {
-		if val, err := strconv.Atoi(offset); err == nil {
-			filter.Offset = val
+	if hasError := c.Query("has_error"); hasError != "" {
+		value, err := strconv.ParseBool(hasError)
+		if err != nil {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "has_error must be true or false")
+			return false
 		}
+
+		filter.HasError = &value
 	}
 
-	queries, err := s.store.ListQueries(c.Request.Context(), filter)
-	if err != nil {
-		writeInternalError(c, s.logger, err, "failed to list queries")
-		return
+	for _, param := range []struct {
+		name  string
+		value **float64
+	}{{"min_duration_ms", &filter.MinDuration}, {"max_duration_ms", &filter.MaxDuration}} {
+		if raw := c.Query(param.name); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil || value < 0 || math.IsNaN(value) {
+				writeError(c, http.StatusBadRequest, ErrCodeValidationError, param.name+" must be a non-negative number")
+				return false
+			}
+
+			*param.value = &value
+		}
+	}
+
+	for _, param := range []struct {
+		name  string
+		value **int64
+	}{{"min_rows_affected", &filter.MinRows}, {"max_rows_affected", &filter.MaxRows}} {
+		if raw := c.Query(param.name); raw != "" {
+			value, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || value < 0 {
+				writeError(c, http.StatusBadRequest, ErrCodeValidationError, param.name+" must be a non-negative integer")
+				return false
+			}
+
+			*param.value = &value
+		}
 	}
 
-	successResponse(c, gin.H{"queries": queries})
+	return true
 }
 
-// handleGetQuery retrieves a query without its result rows
+// queryStreamBuffer is the number of queries a live query stream holds for a
+// slow client before it starts dropping them.
+const queryStreamBuffer = 256
+
+// queryStreamKeepalive is how often an idle query stream sends a comment, so
+// proxies and load balancers do not close it.
+const queryStreamKeepalive = 15 * time.Second
+
+// handleStreamQueries pushes the queries logged by this instance's proxies as
+// server-sent events, as they are logged. Queries are filtered on user,
+// database and connection like the list, and redacted under the same rules.
+// A client that does not keep up gets a "dropped" event with the number of
+// queries it missed instead of stalling the proxies.
+func (s *Server) handleStreamQueries(c *gin.Context) {
+	var filters [3]*uuid.UUID
+
+	for i, name := range []string{"user_id", "database_id", "connection_id"} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+
+		uid, err := uuid.Parse(value)
+		if err != nil {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid "+name)
+			return
+		}
+
+		filters[i] = &uid
+	}
+
+	userID, databaseID, connectionID := filters[0], filters[1], filters[2]
+	redact := shouldRedactQueries(c)
+
+	// The stream outlives the server's write timeout.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	sub := s.store.QueryFeed().Subscribe(queryStreamBuffer)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx would otherwise buffer the stream
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	keepalive := time.NewTicker(queryStreamKeepalive)
+
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepalive.C:
+			if _, err := c.Writer.WriteString(": keepalive\n\n"); err != nil {
+				return
+			}
+		case query := <-sub.C:
+			if !uuidMatches(userID, query.UserID) || !uuidMatches(databaseID, query.DatabaseID) ||
+				(connectionID != nil && *connectionID != query.ConnectionID) {
+				continue
+			}
+
+			if n := sub.Dropped(); n > 0 {
+				c.SSEvent("dropped", gin.H{"count": n})
+			}
+
+			if redact {
+				queries := []store.Query{query}
+				s.redactQueries(ctx, queries)
+				query = queries[0]
+			}
+
+			c.SSEvent("query", query)
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+// uuidMatches reports whether value passes the optional filter want.
+func uuidMatches(want, value *uuid.UUID) bool {
+	return want == nil || (value != nil && *want == *value)
+}
+
+// handleGetQuery retrieves a query without its result rows. Users without
+// the sql:raw permission get it redacted, like the list.
 func (s *Server) handleGetQuery(c *gin.Context) {
 	uid, err := parseUIDParam(c)
 	if err != nil {
@@ -174,6 +432,12 @@ func (s *Server) handleGetQuery(c *gin.Context) {
 		return
 	}
 
+	if shouldRedactQueries(c) {
+		queries := []store.Query{*query}
+		s.redactQueries(c.Request.Context(), queries)
+		query = &queries[0]
+	}
+
 	successResponse(c, query)
 }
 
@@ -216,19 +480,11 @@ func (s *Server) handleListAudit(c *gin.Context) {
 		}
 	}
 
-	if limit := c.Query("limit"); limit != "" {
-		if val, err := strconv.Atoi(limit); err == nil {
-			filter.Limit = val
-		}
-	} else {
-		filter.Limit = 100 // Default limit
-	}
-
-	if offset := c.Query("offset"); offset != "" {
-		if val, err := strconv.Atoi(offset); err == nil {
-			filter.Offset = val
-		}
+	p, ok := pageFromRequest(c, defaultActivityPageLimit)
+	if !ok {
+		return
 	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
 
 	events, err := s.store.ListAuditEvents(c.Request.Context(), filter)
 	if err != nil {
@@ -236,7 +492,19 @@ func (s *Server) handleListAudit(c *gin.Context) {
 		return
 	}
 
-	successResponse(c, gin.H{"audit_events": events})
+	total, err := s.store.CountAuditEvents(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count audit events")
+		return
+	}
+
+	pageResponse(c, p, total, gin.H{"audit_events": events})
+}
+
+// handleGetAuditSchema serves the catalog of audit event types with the
+// fields of each schema version, for consumers parsing the audit log.
+func (s *Server) handleGetAuditSchema(c *gin.Context) {
+	successResponse(c, gin.H{"events": audit.Catalog()})
 }
 
 // handleGetQueryRows retrieves paginated rows for a specific query
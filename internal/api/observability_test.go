@@ -1,10 +1,14 @@
 package api
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -134,3 +138,187 @@ func TestGetConnection_InvalidUID(t *testing.T) { //nolint:paralleltest // share
 
 	require.Equal(t, http.StatusBadRequest, w.Code, "response body: %s", w.Body.String())
 }
+
+// TestTerminateConnection_SignalsLiveSession verifies an admin terminating a
+// connection flips the termination flag of its live session, and that a
+// connection with no live session on this instance is a conflict.
+func TestTerminateConnection_SignalsLiveSession(t *testing.T) { //nolint:paralleltest // shared database state
+	server, dataStore := setupTestServer(t)
+	suffix := "tcconn"
+
+	owner := createTestUser(t, dataStore, "owner-"+suffix, "ownerpass123", []string{store.RoleConnector})
+	createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	token := loginUser(t, server, "admin-"+suffix, "adminpass123")
+
+	db := createTestDBEntry(t, dataStore, "db-"+suffix, true)
+	live, err := dataStore.CreateConnection(t.Context(), owner.UID, db.UID, "10.1.1.4")
+	require.NoError(t, err)
+	stale, err := dataStore.CreateConnection(t.Context(), owner.UID, db.UID, "10.1.1.5")
+	require.NoError(t, err)
+
+	handle := dataStore.Terminations().Register(live.UID)
+	defer dataStore.Terminations().Deregister(live.UID, handle)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.DELETE("/api/v1/connections/:uid", server.requireAdmin(), server.handleTerminateConnection)
+
+	terminate := func(uid string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/connections/"+uid, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := terminate(live.UID.String())
+	require.Equal(t, http.StatusNoContent, w.Code, "response body: %s", w.Body.String())
+	require.True(t, handle.Terminated())
+
+	w = terminate(stale.UID.String())
+	require.Equal(t, http.StatusConflict, w.Code, "response body: %s", w.Body.String())
+
+	w = terminate("00000000-0000-0000-0000-000000000000")
+	require.Equal(t, http.StatusNotFound, w.Code, "response body: %s", w.Body.String())
+}
+
+// TestStreamQueries_PushesFilteredQueries verifies the live query stream
+// sends the queries logged after it opened, only for the filtered database.
+func TestStreamQueries_PushesFilteredQueries(t *testing.T) { //nolint:paralleltest // shared database state
+	server, dataStore := setupTestServer(t)
+	suffix := "qstream"
+
+	admin := createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	token := loginUser(t, server, "admin-"+suffix, "adminpass123")
+
+	watched := createTestDBEntry(t, dataStore, "db-"+suffix, true)
+	other := createTestDBEntry(t, dataStore, "other-"+suffix, true)
+
+	watchedConn, err := dataStore.CreateConnection(t.Context(), admin.UID, watched.UID, "10.1.1.6")
+	require.NoError(t, err)
+	otherConn, err := dataStore.CreateConnection(t.Context(), admin.UID, other.UID, "10.1.1.7")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.GET("/api/v1/queries/stream", server.requirePermission(store.PermissionQueriesRead), server.handleStreamQueries)
+
+	httpServer := httptest.NewServer(router)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		httpServer.URL+"/api/v1/queries/stream?database_id="+watched.UID.String(), http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	_, err = dataStore.CreateQuery(t.Context(), &store.Query{ConnectionID: otherConn.UID, SQLText: "SELECT 'other'"})
+	require.NoError(t, err)
+	logged, err := dataStore.CreateQuery(t.Context(), &store.Query{ConnectionID: watchedConn.UID, SQLText: "SELECT 'watched'"})
+	require.NoError(t, err)
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+
+		var got store.Query
+		require.NoError(t, json.Unmarshal([]byte(data), &got))
+		require.Equal(t, logged.UID, got.UID, "the other database's query must be filtered out")
+		require.Equal(t, "SELECT 'watched'", got.SQLText)
+
+		return
+	}
+
+	t.Fatalf("stream ended without a query: %v", scanner.Err())
+}
+
+// TestStreamQueries_RejectsInvalidFilter verifies a malformed filter is a
+// 400 rather than a stream of everything.
+func TestStreamQueries_RejectsInvalidFilter(t *testing.T) { //nolint:paralleltest // shared database state
+	server, dataStore := setupTestServer(t)
+
+	createTestUser(t, dataStore, "admin-qstreambad", "adminpass123", []string{store.RoleAdmin})
+	token := loginUser(t, server, "admin-qstreambad", "adminpass123")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.GET("/api/v1/queries/stream", server.handleStreamQueries)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/queries/stream?user_id=nope", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code, "response body: %s", w.Body.String())
+}
+
+// TestQuerySearchFromRequest verifies the query log search parameters are
+// parsed, and that SQL text search is kept to users allowed raw SQL.
+func TestQuerySearchFromRequest(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	admin := &store.User{Roles: []string{store.RoleAdmin}}
+	auditor := &store.User{Roles: []string{store.RoleAuditor}}
+
+	parse := func(user *store.User, query string) (store.QueryFilter, int, bool) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/queries?"+query, nil)
+		c.Set("current_user", user)
+
+		var filter store.QueryFilter
+		ok := querySearchFromRequest(c, &filter)
+
+		return filter, w.Code, ok
+	}
+
+	filter, _, ok := parse(admin, "sql=orders&sql_regex=%5Eselect&has_error=false"+
+		"&min_duration_ms=1.5&max_duration_ms=100&min_rows_affected=0&max_rows_affected=10")
+	require.True(t, ok)
+	require.Equal(t, "orders", filter.SQLContains)
+	require.Equal(t, "^select", filter.SQLRegex)
+	require.NotNil(t, filter.HasError)
+	require.False(t, *filter.HasError)
+	require.InDelta(t, 1.5, *filter.MinDuration, 0)
+	require.InDelta(t, 100.0, *filter.MaxDuration, 0)
+	require.Equal(t, int64(0), *filter.MinRows)
+	require.Equal(t, int64(10), *filter.MaxRows)
+
+	filter, _, ok = parse(auditor, "has_error=true&min_duration_ms=5")
+	require.True(t, ok)
+	require.True(t, *filter.HasError)
+	require.Nil(t, filter.MaxRows)
+
+	for _, query := range []string{"sql=orders", "sql_regex=orders"} {
+		_, code, ok := parse(auditor, query)
+		require.False(t, ok, query)
+		require.Equal(t, http.StatusForbidden, code, query)
+	}
+
+	for _, query := range []string{
+		"sql_regex=%28unclosed", "has_error=maybe", "min_duration_ms=fast", "max_duration_ms=-1",
+		"min_duration_ms=NaN", "min_rows_affected=1.5", "max_rows_affected=-3",
+	} {
+		_, code, ok := parse(admin, query)
+		require.False(t, ok, query)
+		require.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultActivityPageLimit is the page size of the logged activity lists
+	// (connections, queries, audit events) when the request sets no limit.
+	// The other lists return every entry then.
+	defaultActivityPageLimit = 100
+	// maxPageLimit bounds the limit a list request may set.
+	maxPageLimit = 1000
+	// totalCountHeader carries the number of entries of a paginated list.
+	totalCountHeader = "X-Total-Count"
+)
+
+// page is the slice of a list a request asks for.
+type page struct {
+	// Limit is the maximum number of entries to return; 0 returns them all.
+	Limit  int
+	Offset int
+}
+
+// pageFromRequest parses the limit and offset query parameters, limit
+// defaulting to defaultLimit. It writes the error response and returns false
+// when either is invalid.
+func pageFromRequest(c *gin.Context, defaultLimit int) (page, bool) {
+	p := page{Limit: defaultLimit}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+				"limit must be between 1 and "+strconv.Itoa(maxPageLimit))
+			return page{}, false
+		}
+
+		p.Limit = limit
+	}
+
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "offset must be a non-negative integer")
+			return page{}, false
+		}
+
+		p.Offset = offset
+	}
+
+	return p, true
+}
+
+// pageResponse writes a page of a list of total entries: body gains a total
+// field, the X-Total-Count header is set, and, when the list is bounded, a
+// Link header points to its first, previous, next and last pages.
+func pageResponse(c *gin.Context, p page, total int, body gin.H) {
+	c.Header(totalCountHeader, strconv.Itoa(total))
+
+	if p.Limit > 0 {
+		c.Writer.Header().Add("Link", pageLinks(c, p, total))
+	}
+
+	body["total"] = total
+	successResponse(c, body)
+}
+
+// pageLinks returns the RFC 8288 links to the pages around p, keeping the
+// other query parameters of the request.
+func pageLinks(c *gin.Context, p page, total int) string {
+	link := func(offset int, rel string) string {
+		u := *c.Request.URL
+		query := u.Query()
+		query.Set("limit", strconv.Itoa(p.Limit))
+		query.Set("offset", strconv.Itoa(offset))
+		u.RawQuery = query.Encode()
+
+		return "<" + u.RequestURI() + `>; rel="` + rel + `"`
+	}
+
+	lastOffset := 0
+	if total > 0 {
+		lastOffset = (total - 1) / p.Limit * p.Limit
+	}
+
+	links := []string{link(0, "first")}
+
+	if p.Offset > 0 {
+		links = append(links, link(max(p.Offset-p.Limit, 0), "prev"))
+	}
+
+	if p.Offset+p.Limit < total {
+		links = append(links, link(p.Offset+p.Limit, "next"))
+	}
+
+	links = append(links, link(lastOffset, "last"))
+
+	return strings.Join(links, ", ")
+}
+
+// slicePage returns the entries of p in items, for lists built in memory.
+func slicePage[T any](items []T, p page) []T {
+	if p.Offset >= len(items) {
+		return []T{}
+	}
+
+	items = items[p.Offset:]
+	if p.Limit > 0 && p.Limit < len(items) {
+		items = items[:p.Limit]
+	}
+
+	return items
+}
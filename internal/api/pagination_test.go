@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestPageFromRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query  string
+		want   page
+		wantOK bool
+	}{
+		{query: "", want: page{Limit: 100}, wantOK: true},
+		{query: "limit=20&offset=40", want: page{Limit: 20, Offset: 40}, wantOK: true},
+		{query: "limit=1000", want: page{Limit: 1000}, wantOK: true},
+		{query: "limit=0"},
+		{query: "limit=1001"},
+		{query: "limit=ten"},
+		{query: "offset=-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/connections?"+tt.query, nil)
+
+			got, ok := pageFromRequest(c, defaultActivityPageLimit)
+			require.Equal(t, tt.wantOK, ok)
+
+			if !ok {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	t.Parallel()
+
+	links := func(query string, p page, total int) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users?"+query, nil)
+
+		return pageLinks(c, p, total)
+	}
+
+	assert.Equal(t,
+		`</api/v1/users?label=team%3Dcore&limit=10&offset=0>; rel="first", `+
+			`</api/v1/users?label=team%3Dcore&limit=10&offset=10>; rel="prev", `+
+			`</api/v1/users?label=team%3Dcore&limit=10&offset=30>; rel="next", `+
+			`</api/v1/users?label=team%3Dcore&limit=10&offset=40>; rel="last"`,
+		links("label=team%3Dcore&limit=10&offset=20", page{Limit: 10, Offset: 20}, 45))
+
+	assert.Equal(t,
+		`</api/v1/users?limit=10&offset=0>; rel="first", </api/v1/users?limit=10&offset=0>; rel="last"`,
+		links("limit=10", page{Limit: 10}, 0), "an empty list has a single page")
+
+	assert.Contains(t, links("limit=10&offset=5", page{Limit: 10, Offset: 5}, 45),
+		`</api/v1/users?limit=10&offset=0>; rel="prev"`, "prev never goes below 0")
+}
+
+func TestSlicePage(t *testing.T) {
+	t.Parallel()
+
+	items := []int{1, 2, 3, 4, 5}
+
+	assert.Equal(t, items, slicePage(items, page{}))
+	assert.Equal(t, []int{3, 4}, slicePage(items, page{Limit: 2, Offset: 2}))
+	assert.Equal(t, []int{5}, slicePage(items, page{Limit: 2, Offset: 4}))
+	assert.Equal(t, []int{}, slicePage(items, page{Limit: 2, Offset: 5}))
+	assert.Equal(t, []int{}, slicePage([]int(nil), page{}), "never null in JSON")
+}
+
+func TestListUsers_Pagination(t *testing.T) {
+	t.Parallel()
+
+	server, dataStore := setupTestServer(t)
+	suffix := "pagination"
+
+	createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	for _, name := range []string{"a", "b", "c", "d"} {
+		createTestUser(t, dataStore, name+"-"+suffix, "userpass123", []string{store.RoleConnector})
+	}
+	token := loginUser(t, server, "admin-"+suffix, "adminpass123")
+
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.GET("/api/v1/users", server.handleListUsers)
+
+	// Unbounded by default, with the total still reported.
+	w, resp := doJSON(t, router, http.MethodGet, "/api/v1/users", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	total, ok := resp["total"].(float64)
+	require.True(t, ok)
+	all, ok := resp["users"].([]any)
+	require.True(t, ok)
+	require.Len(t, all, int(total))
+	require.GreaterOrEqual(t, len(all), 5)
+	assert.Equal(t, strconv.Itoa(len(all)), w.Header().Get(totalCountHeader), "X-Total-Count matches the body")
+	assert.Empty(t, w.Header().Get("Link"))
+
+	w, resp = doJSON(t, router, http.MethodGet, "/api/v1/users?limit=2&offset=1", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	users, ok := resp["users"].([]any)
+	require.True(t, ok)
+	require.Len(t, users, 2)
+	assert.Equal(t, all[1:3], users)
+	assert.Equal(t, total, resp["total"])
+	assert.Contains(t, w.Header().Get("Link"), `</api/v1/users?limit=2&offset=3>; rel="next"`)
+	assert.Contains(t, w.Header().Get("Link"), `</api/v1/users?limit=2&offset=0>; rel="prev"`)
+
+	w, _ = doJSON(t, router, http.MethodGet, "/api/v1/users?limit=5000", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/fclairamb/dbbat/internal/store"
 )
@@ -124,6 +125,8 @@ type instanceResolvedInfo struct {
 
 // instanceInfoResponse is the full GET /instance response.
 type instanceInfoResponse struct {
+	// ID is the instance that answered; see GET /admin/instances.
+	ID       *uuid.UUID           `json:"id,omitempty"`
 	Listen   instanceListenInfo   `json:"listen"`
 	Public   *instancePublicInfo  `json:"public,omitempty"`
 	Resolved instanceResolvedInfo `json:"resolved"`
@@ -177,6 +180,10 @@ func (s *Server) handleGetInstance(c *gin.Context) {
 		},
 	}
 
+	if id := s.store.InstanceID(); id != uuid.Nil {
+		resp.ID = &id
+	}
+
 	if isAdmin {
 		resp.Public = &instancePublicInfo{
 			Host:      pe.Host,
@@ -82,6 +82,8 @@ func setupTestServer(t *testing.T) (*Server, *store.Store) {
 		"grant_requests",
 		"access_grants",
 		"grant_definitions",
+		"server_health",
+		"grant_templates",
 		"audit_log",
 		"servers",
 		"user_group_members",
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// maxPreferenceValueBytes caps the size of a single preference value.
+const maxPreferenceValueBytes = 64 * 1024
+
+// preferenceKeyPattern restricts preference keys to short, URL-safe names
+// such as "pinned_databases" or "queries.columns".
+var preferenceKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// preferenceKeyParam returns the validated :key path parameter, writing a 400
+// and returning false when it is malformed.
+func preferenceKeyParam(c *gin.Context) (string, bool) {
+	key := c.Param("key")
+	if !preferenceKeyPattern.MatchString(key) {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+			"invalid preference key: use 1-64 lowercase letters, digits, '.', '_' or '-'")
+		return "", false
+	}
+
+	return key, true
+}
+
+// handleListPreferences returns all of the current user's preferences as a
+// key → value object.
+func (s *Server) handleListPreferences(c *gin.Context) {
+	prefs, err := s.store.ListUserPreferences(c.Request.Context(), getCurrentUser(c).UID)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to list preferences")
+		return
+	}
+
+	values := make(map[string]json.RawMessage, len(prefs))
+	for _, pref := range prefs {
+		values[pref.Key] = pref.Value
+	}
+
+	successResponse(c, gin.H{"preferences": values})
+}
+
+// handleGetPreference returns one of the current user's preferences.
+func (s *Server) handleGetPreference(c *gin.Context) {
+	key, ok := preferenceKeyParam(c)
+	if !ok {
+		return
+	}
+
+	pref, err := s.store.GetUserPreference(c.Request.Context(), getCurrentUser(c).UID, key)
+	if err != nil {
+		if errors.Is(err, store.ErrPreferenceNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "preference not found")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to get preference")
+		return
+	}
+
+	successResponse(c, pref)
+}
+
+// handleSetPreference stores the request body (any JSON value) under a key
+// for the current user, replacing any previous value.
+func (s *Server) handleSetPreference(c *gin.Context) {
+	key, ok := preferenceKeyParam(c)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPreferenceValueBytes))
+	if err != nil {
+		writeError(c, http.StatusRequestEntityTooLarge, ErrCodeValidationError, "preference value is too large (max 64 KiB)")
+		return
+	}
+
+	if !json.Valid(body) {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "preference value must be valid JSON")
+		return
+	}
+
+	pref, err := s.store.SetUserPreference(c.Request.Context(), getCurrentUser(c).UID, key, body)
+	if err != nil {
+		if errors.Is(err, store.ErrTooManyPreferences) {
+			writeError(c, http.StatusConflict, ErrCodeConflict, "preference limit reached; delete unused preferences first")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to set preference")
+		return
+	}
+
+	successResponse(c, pref)
+}
+
+// handleDeletePreference removes one of the current user's preferences.
+func (s *Server) handleDeletePreference(c *gin.Context) {
+	key, ok := preferenceKeyParam(c)
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteUserPreference(c.Request.Context(), getCurrentUser(c).UID, key); err != nil {
+		if errors.Is(err, store.ErrPreferenceNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "preference not found")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to delete preference")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestPreferenceKeyPattern(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{"pinned_databases", "queries.columns", "a", "filters-2"} {
+		assert.True(t, preferenceKeyPattern.MatchString(key), key)
+	}
+
+	for _, key := range []string{"", "Pinned", ".hidden", "a/b", strings.Repeat("a", 65)} {
+		assert.False(t, preferenceKeyPattern.MatchString(key), key)
+	}
+}
+
+func TestPreferencesEndpoints(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	suffix := "prefs"
+
+	createTestUser(t, dataStore, "alice-"+suffix, "alicepass123", []string{store.RoleConnector})
+	createTestUser(t, dataStore, "bob-"+suffix, "bobpass123", []string{store.RoleConnector})
+	aliceToken := loginUser(t, server, "alice-"+suffix, "alicepass123")
+	bobToken := loginUser(t, server, "bob-"+suffix, "bobpass123")
+
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.GET("/api/v1/preferences", server.handleListPreferences)
+	router.GET("/api/v1/preferences/:key", server.handleGetPreference)
+	router.PUT("/api/v1/preferences/:key", server.handleSetPreference)
+	router.DELETE("/api/v1/preferences/:key", server.handleDeletePreference)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := do(http.MethodPut, "/api/v1/preferences/pinned_databases", aliceToken, `["db-1","db-2"]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = do(http.MethodPut, "/api/v1/preferences/pinned_databases", aliceToken, `["db-3"]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = do(http.MethodGet, "/api/v1/preferences", aliceToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var list struct {
+		Preferences map[string]json.RawMessage `json:"preferences"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.JSONEq(t, `["db-3"]`, string(list.Preferences["pinned_databases"]))
+
+	// Preferences are private to their owner.
+	w = do(http.MethodGet, "/api/v1/preferences/pinned_databases", bobToken, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(http.MethodPut, "/api/v1/preferences/layout", aliceToken, `{not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodPut, "/api/v1/preferences/Bad%20Key", aliceToken, `1`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodPut, "/api/v1/preferences/huge", aliceToken, `"`+strings.Repeat("x", maxPreferenceValueBytes)+`"`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = do(http.MethodDelete, "/api/v1/preferences/pinned_databases", aliceToken, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = do(http.MethodDelete, "/api/v1/preferences/pinned_databases", aliceToken, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// maxPrincipalLength bounds the name of who a proxy credential is handed to.
+const maxPrincipalLength = 128
+
+// CreateProxyCredentialRequest is the body for POST
+// /grants/:uid/proxy-credentials.
+type CreateProxyCredentialRequest struct {
+	// Principal names who the credential is handed to: an email address, a
+	// ticket, a company.
+	Principal    string `json:"principal" binding:"required"`
+	ValidMinutes int    `json:"valid_minutes" binding:"required"`
+}
+
+// CreateProxyCredentialResponse is a new proxy credential with its password,
+// only returned once.
+type CreateProxyCredentialResponse struct {
+	*store.ProxyCredential
+	Password   string          `json:"password"`
+	Connection *ConnectionInfo `json:"connection,omitempty"`
+}
+
+func validateProxyCredentialRequest(req *CreateProxyCredentialRequest) string {
+	if req.Principal == "" {
+		return "principal is required"
+	}
+
+	if len(req.Principal) > maxPrincipalLength {
+		return fmt.Sprintf("principal must be at most %d characters", maxPrincipalLength)
+	}
+
+	if maxMinutes := int(store.MaxProxyCredentialValidity / time.Minute); req.ValidMinutes <= 0 || req.ValidMinutes > maxMinutes {
+		return fmt.Sprintf("valid_minutes must be between 1 and %d", maxMinutes)
+	}
+
+	return ""
+}
+
+// handleCreateProxyCredential issues a one-time proxy credential on a grant —
+// admin-only. Whoever it is handed to connects once, as the grant's user,
+// without a DBBat user of their own. The credential can't outlive the grant.
+func (s *Server) handleCreateProxyCredential(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid grant UID")
+		return
+	}
+
+	var req CreateProxyCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+		return
+	}
+
+	if msg := validateProxyCredentialRequest(&req); msg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, msg)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	grant, err := s.store.GetGrantByUID(ctx, uid)
+	if err != nil {
+		if errors.Is(err, store.ErrGrantNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "grant not found")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to get grant")
+		return
+	}
+
+	now := time.Now()
+
+	switch {
+	case grant.RevokedAt != nil:
+		writeError(c, http.StatusConflict, ErrCodeConflict, "grant has been revoked")
+		return
+	case !now.Before(grant.ExpiresAt):
+		writeError(c, http.StatusConflict, ErrCodeConflict, "grant has expired")
+		return
+	}
+
+	db, err := s.store.GetServerByUID(ctx, grant.DatabaseID)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to get database")
+		return
+	}
+
+	if db.Protocol != store.ProtocolPostgreSQL {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "proxy credentials are only supported on PostgreSQL databases")
+		return
+	}
+
+	expiresAt := now.Add(time.Duration(req.ValidMinutes) * time.Minute)
+	if expiresAt.After(grant.ExpiresAt) {
+		expiresAt = grant.ExpiresAt
+	}
+
+	currentUser := getCurrentUser(c)
+
+	var (
+		cred     *store.ProxyCredential
+		password string
+	)
+
+	err = s.store.WithTx(ctx, func(ctx context.Context, tx *store.Store) error {
+		var err error
+		if cred, password, err = tx.CreateProxyCredential(ctx, grant.UID, req.Principal, currentUser.UID, expiresAt); err != nil {
+			return err
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			UserID:      &grant.UserID,
+			PerformedBy: &currentUser.UID,
+			Payload: audit.ProxyCredentialCreatedV1{
+				CredentialUID: cred.UID,
+				GrantUID:      grant.UID,
+				DatabaseID:    grant.DatabaseID,
+				Username:      cred.Username,
+				Principal:     cred.Principal,
+				IssuedBy:      currentUser.UID,
+				ExpiresAt:     cred.ExpiresAt,
+			},
+		})
+	})
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to create proxy credential")
+		return
+	}
+
+	resp := CreateProxyCredentialResponse{ProxyCredential: cred, Password: password}
+
+	if s.config != nil {
+		pe, _ := s.store.GetPublicEndpoints(ctx)
+		endpoints := store.ResolvePublicEndpoints(pe, s.config)
+
+		if info, ok := BuildConnectionURL(db, &store.User{Username: cred.Username}, endpoints, password); ok {
+			resp.Connection = &info
+		}
+	}
+
+	successResponse(c, resp)
+}
+
+// handleListProxyCredentials lists the proxy credentials issued on a grant —
+// admin-only. Their passwords are never returned again.
+func (s *Server) handleListProxyCredentials(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid grant UID")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if _, err := s.store.GetGrantByUID(ctx, uid); err != nil {
+		if errors.Is(err, store.ErrGrantNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "grant not found")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to get grant")
+		return
+	}
+
+	creds, err := s.store.ListProxyCredentials(ctx, uid)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to list proxy credentials")
+		return
+	}
+
+	successResponse(c, gin.H{"proxy_credentials": creds})
+}
+
+// handleRevokeProxyCredential revokes a proxy credential that was not used
+// yet — admin-only. The connection of a used one is terminated instead.
+func (s *Server) handleRevokeProxyCredential(c *gin.Context) {
+	grantUID, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid grant UID")
+		return
+	}
+
+	credUID, err := uuid.Parse(c.Param("credential_uid"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid proxy credential UID")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	cred, err := s.store.GetProxyCredential(ctx, credUID)
+	if err != nil || cred.GrantID != grantUID {
+		if err == nil || errors.Is(err, store.ErrProxyCredentialNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "proxy credential not found")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to get proxy credential")
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+
+	err = s.store.WithTx(ctx, func(ctx context.Context, tx *store.Store) error {
+		if err := tx.RevokeProxyCredential(ctx, cred.UID, currentUser.UID); err != nil {
+			return err
+		}
+
+		var userID *uuid.UUID
+		if grant, _ := tx.GetGrantByUID(ctx, cred.GrantID); grant != nil {
+			userID = &grant.UserID
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			UserID:      userID,
+			PerformedBy: &currentUser.UID,
+			Payload: audit.ProxyCredentialRevokedV1{
+				CredentialUID: cred.UID,
+				GrantUID:      cred.GrantID,
+				Username:      cred.Username,
+				Principal:     cred.Principal,
+				IssuedBy:      cred.IssuedBy,
+			},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrProxyCredentialSpent) {
+			writeError(c, http.StatusConflict, ErrCodeConflict, "proxy credential was already used or revoked")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to revoke proxy credential")
+		return
+	}
+
+	successResponse(c, gin.H{"message": "proxy credential revoked"})
+}
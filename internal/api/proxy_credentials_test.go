@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestValidateProxyCredentialRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		req     CreateProxyCredentialRequest
+		wantErr string
+	}{
+		{
+			name: "valid",
+			req:  CreateProxyCredentialRequest{Principal: "jane@contractor.example", ValidMinutes: 60},
+		},
+		{
+			name:    "no principal",
+			req:     CreateProxyCredentialRequest{ValidMinutes: 60},
+			wantErr: "principal is required",
+		},
+		{
+			name:    "principal too long",
+			req:     CreateProxyCredentialRequest{Principal: strings.Repeat("a", 129), ValidMinutes: 60},
+			wantErr: "at most 128",
+		},
+		{
+			name:    "no validity",
+			req:     CreateProxyCredentialRequest{Principal: "jane"},
+			wantErr: "valid_minutes",
+		},
+		{
+			name:    "over a day",
+			req:     CreateProxyCredentialRequest{Principal: "jane", ValidMinutes: 1441},
+			wantErr: "between 1 and 1440",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := validateProxyCredentialRequest(&tt.req)
+			if tt.wantErr == "" {
+				assert.Empty(t, got)
+			} else {
+				assert.Contains(t, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProxyCredentialEndpoints(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	suffix := "pxcred"
+	ctx := context.Background()
+
+	admin := createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	alice := createTestUser(t, dataStore, "alice-"+suffix, "alicepass123", []string{store.RoleConnector})
+	adminToken := loginUser(t, server, "admin-"+suffix, "adminpass123")
+	aliceToken := loginUser(t, server, "alice-"+suffix, "alicepass123")
+
+	orders := createTestDBEntry(t, dataStore, "orders-"+suffix, true)
+
+	now := time.Now()
+	grant, err := dataStore.CreateGrant(ctx, &store.Grant{
+		UserID:     alice.UID,
+		DatabaseID: orders.UID,
+		Controls:   []string{store.ControlReadOnly},
+		GrantedBy:  admin.UID,
+		StartsAt:   now,
+		ExpiresAt:  now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.POST("/api/v1/grants/:uid/proxy-credentials", server.requireAdmin(), server.handleCreateProxyCredential)
+	router.GET("/api/v1/grants/:uid/proxy-credentials", server.requireAdmin(), server.handleListProxyCredentials)
+	router.DELETE("/api/v1/grants/:uid/proxy-credentials/:credential_uid", server.requireAdmin(), server.handleRevokeProxyCredential)
+
+	path := "/api/v1/grants/" + grant.UID.String() + "/proxy-credentials"
+	body := map[string]any{"principal": "jane@contractor.example", "valid_minutes": 24 * 60}
+
+	// Issuing credentials is admin-only.
+	w, _ := doJSON(t, router, http.MethodPost, path, aliceToken, body)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	w, resp := doJSON(t, router, http.MethodPost, path, adminToken, body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.NotEmpty(t, resp["password"])
+	assert.Equal(t, "jane@contractor.example", resp["principal"])
+	assert.Equal(t, store.ProxyCredentialStatusPending, resp["status"])
+
+	username, ok := resp["username"].(string)
+	require.True(t, ok, "response should carry a username")
+	assert.True(t, store.IsProxyCredentialUsername(username))
+
+	// The credential can't outlive the grant.
+	expiresAt, err := time.Parse(time.RFC3339Nano, resp["expires_at"].(string))
+	require.NoError(t, err)
+	assert.False(t, expiresAt.After(grant.ExpiresAt))
+
+	// Both the issuing admin and the principal are audit-logged.
+	created := audit.EventProxyCredentialCreated
+
+	events, err := dataStore.ListAuditEvents(ctx, store.AuditFilter{EventType: &created})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.NotNil(t, events[0].PerformedBy)
+	assert.Equal(t, admin.UID, *events[0].PerformedBy)
+	assert.Contains(t, string(events[0].Details), "jane@contractor.example")
+
+	w, resp = doJSON(t, router, http.MethodGet, path, adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp["proxy_credentials"], 1)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	credUID := resp["proxy_credentials"].([]any)[0].(map[string]any)["uid"].(string)
+
+	w, _ = doJSON(t, router, http.MethodDelete, path+"/"+credUID, adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w, _ = doJSON(t, router, http.MethodDelete, path+"/"+credUID, adminToken, nil)
+	require.Equal(t, http.StatusConflict, w.Code)
+
+	// No credential on a revoked grant.
+	require.NoError(t, dataStore.RevokeGrant(ctx, grant.UID, admin.UID))
+
+	w, _ = doJSON(t, router, http.MethodPost, path, adminToken, body)
+	require.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// maxQueryAlertNameLen bounds alert names so they stay readable in webhook
+// notifications.
+const maxQueryAlertNameLen = 64
+
+// CreateQueryAlertRequest is the body for POST /query-alerts.
+type CreateQueryAlertRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description"`
+	Filter      store.QueryAlertFilter `json:"filter"`
+	// WebhookURL receives a POST for each matched query; nil only records
+	// the matches.
+	WebhookURL *string `json:"webhook_url"`
+	// Enabled defaults to true.
+	Enabled *bool `json:"enabled"`
+}
+
+// UpdateQueryAlertRequest is the body for PATCH /query-alerts/:uid. Same
+// shape as create: the whole alert is replaced.
+type UpdateQueryAlertRequest = CreateQueryAlertRequest
+
+func validateQueryAlertRequest(req *CreateQueryAlertRequest) string {
+	if req.Name == "" {
+		return "name is required"
+	}
+
+	if len(req.Name) > maxQueryAlertNameLen {
+		return "name must be at most 64 characters"
+	}
+
+	f := req.Filter
+	if f.IsEmpty() {
+		return "filter must set at least one criterion"
+	}
+
+	if f.AccessLevel != "" && f.AccessLevel != store.AccessLevelReadOnly && f.AccessLevel != store.AccessLevelReadWrite {
+		return "filter.access_level must be read_only or read_write"
+	}
+
+	// PostgreSQL evaluates the expression, as for the query list's sql_regex.
+	if f.SQLRegex != "" {
+		if _, err := regexp.Compile(f.SQLRegex); err != nil {
+			return "filter.sql_regex is not a valid regular expression"
+		}
+	}
+
+	if (f.MinDurationMs != nil && *f.MinDurationMs < 0) || (f.MaxDurationMs != nil && *f.MaxDurationMs < 0) {
+		return "filter durations must not be negative"
+	}
+
+	if (f.MinRowsAffected != nil && *f.MinRowsAffected < 0) || (f.MaxRowsAffected != nil && *f.MaxRowsAffected < 0) {
+		return "filter rows affected must not be negative"
+	}
+
+	if req.WebhookURL != nil {
+		u, err := url.Parse(*req.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "webhook_url must be an http or https URL"
+		}
+	}
+
+	return ""
+}
+
+// checkQueryAlertTargets verifies the user and database an alert filters on
+// exist, so an alert can't silently match nothing. Returns a validation
+// message, or "" when fine.
+func (s *Server) checkQueryAlertTargets(ctx context.Context, f store.QueryAlertFilter) string {
+	if f.UserID != nil {
+		if _, err := s.store.GetUserByUID(ctx, *f.UserID); err != nil {
+			return "user does not exist: " + f.UserID.String()
+		}
+	}
+
+	if f.DatabaseID != nil {
+		if _, err := s.store.GetServerByUID(ctx, *f.DatabaseID); err != nil {
+			return "database does not exist: " + f.DatabaseID.String()
+		}
+	}
+
+	return ""
+}
+
+// bindQueryAlertRequest reads and validates a create or update body, writing
+// the error response and returning false when it is invalid.
+func (s *Server) bindQueryAlertRequest(c *gin.Context, req *CreateQueryAlertRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+
+		return false
+	}
+
+	if msg := validateQueryAlertRequest(req); msg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, msg)
+
+		return false
+	}
+
+	if msg := s.checkQueryAlertTargets(c.Request.Context(), req.Filter); msg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, msg)
+
+		return false
+	}
+
+	return true
+}
+
+// handleCreateQueryAlert — admin-only. The alert matches the queries logged
+// from its creation on.
+func (s *Server) handleCreateQueryAlert(c *gin.Context) {
+	var req CreateQueryAlertRequest
+	if !s.bindQueryAlertRequest(c, &req) {
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	ctx := c.Request.Context()
+
+	created, err := s.store.CreateQueryAlert(ctx, &store.QueryAlert{
+		Name:        req.Name,
+		Description: req.Description,
+		Filter:      req.Filter,
+		WebhookURL:  req.WebhookURL,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		CreatedBy:   &currentUser.UID,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrQueryAlertDuplicate) {
+			writeError(c, http.StatusConflict, ErrCodeDuplicateName, err.Error())
+
+			return
+		}
+
+		writeInternalError(c, s.logger, err, "failed to create query alert")
+
+		return
+	}
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		PerformedBy: &currentUser.UID,
+		Payload: audit.QueryAlertCreatedV1{
+			QueryAlertUID: created.UID,
+			Name:          created.Name,
+			Filter:        created.Filter,
+			Webhook:       created.WebhookURL != nil,
+			Enabled:       created.Enabled,
+		},
+	})
+
+	successResponse(c, created)
+}
+
+// handleListQueryAlerts — admin-only.
+func (s *Server) handleListQueryAlerts(c *gin.Context) {
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+
+	alerts, err := s.store.ListQueryAlerts(c.Request.Context())
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to list query alerts")
+
+		return
+	}
+
+	pageResponse(c, p, len(alerts), gin.H{"query_alerts": slicePage(alerts, p)})
+}
+
+// handleGetQueryAlert — admin-only.
+func (s *Server) handleGetQueryAlert(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid query alert UID")
+
+		return
+	}
+
+	alert, err := s.store.GetQueryAlert(c.Request.Context(), uid)
+	if err != nil {
+		if errors.Is(err, store.ErrQueryAlertNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "query alert not found")
+
+			return
+		}
+
+		writeInternalError(c, s.logger, err, "failed to get query alert")
+
+		return
+	}
+
+	successResponse(c, alert)
+}
+
+// handleUpdateQueryAlert — admin-only. The alert keeps matching from where
+// it stopped; one enabled again resumes from now.
+func (s *Server) handleUpdateQueryAlert(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid query alert UID")
+
+		return
+	}
+
+	var req UpdateQueryAlertRequest
+	if !s.bindQueryAlertRequest(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	alert := &store.QueryAlert{
+		UID:         uid,
+		Name:        req.Name,
+		Description: req.Description,
+		Filter:      req.Filter,
+		WebhookURL:  req.WebhookURL,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+
+	if err := s.store.UpdateQueryAlert(ctx, alert); err != nil {
+		switch {
+		case errors.Is(err, store.ErrQueryAlertNotFound):
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "query alert not found")
+		case errors.Is(err, store.ErrQueryAlertDuplicate):
+			writeError(c, http.StatusConflict, ErrCodeDuplicateName, err.Error())
+		default:
+			writeInternalError(c, s.logger, err, "failed to update query alert")
+		}
+
+		return
+	}
+
+	updated, err := s.store.GetQueryAlert(ctx, uid)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to get query alert")
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		PerformedBy: &currentUser.UID,
+		Payload: audit.QueryAlertUpdatedV1{
+			QueryAlertUID: updated.UID,
+			Name:          updated.Name,
+			Filter:        updated.Filter,
+			Webhook:       updated.WebhookURL != nil,
+			Enabled:       updated.Enabled,
+		},
+	})
+
+	successResponse(c, updated)
+}
+
+// handleDeleteQueryAlert — admin-only hard delete; the matches it recorded
+// go with it.
+func (s *Server) handleDeleteQueryAlert(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid query alert UID")
+
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := s.store.DeleteQueryAlert(ctx, uid); err != nil {
+		if errors.Is(err, store.ErrQueryAlertNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "query alert not found")
+
+			return
+		}
+
+		writeInternalError(c, s.logger, err, "failed to delete query alert")
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		PerformedBy: &currentUser.UID,
+		Payload:     audit.QueryAlertDeletedV1{QueryAlertUID: uid},
+	})
+
+	successResponse(c, gin.H{"message": "query alert deleted"})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestValidateQueryAlertRequest(t *testing.T) {
+	t.Parallel()
+
+	negative, slow := -1.0, 10000.0
+	webhook := func(u string) *string { return &u }
+
+	tests := []struct {
+		name    string
+		req     CreateQueryAlertRequest
+		wantErr string
+	}{
+		{
+			name: "valid",
+			req: CreateQueryAlertRequest{
+				Name:       "Slow queries",
+				Filter:     store.QueryAlertFilter{MinDurationMs: &slow},
+				WebhookURL: webhook("https://hooks.example.com/alerts"),
+			},
+		},
+		{
+			name:    "no name",
+			req:     CreateQueryAlertRequest{Filter: store.QueryAlertFilter{SQLContains: "delete"}},
+			wantErr: "name is required",
+		},
+		{
+			name:    "name too long",
+			req:     CreateQueryAlertRequest{Name: strings.Repeat("a", 65), Filter: store.QueryAlertFilter{SQLContains: "delete"}},
+			wantErr: "at most 64",
+		},
+		{
+			name:    "empty filter",
+			req:     CreateQueryAlertRequest{Name: "Everything"},
+			wantErr: "at least one criterion",
+		},
+		{
+			name:    "bad access level",
+			req:     CreateQueryAlertRequest{Name: "Writes", Filter: store.QueryAlertFilter{AccessLevel: "write"}},
+			wantErr: "access_level",
+		},
+		{
+			name:    "bad regex",
+			req:     CreateQueryAlertRequest{Name: "Deletes", Filter: store.QueryAlertFilter{SQLRegex: "(delete"}},
+			wantErr: "sql_regex",
+		},
+		{
+			name:    "negative duration",
+			req:     CreateQueryAlertRequest{Name: "Slow", Filter: store.QueryAlertFilter{MaxDurationMs: &negative}},
+			wantErr: "durations",
+		},
+		{
+			name: "bad webhook",
+			req: CreateQueryAlertRequest{
+				Name:       "Deletes",
+				Filter:     store.QueryAlertFilter{SQLContains: "delete"},
+				WebhookURL: webhook("ftp://hooks.example.com"),
+			},
+			wantErr: "webhook_url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := validateQueryAlertRequest(&tt.req)
+			if tt.wantErr == "" {
+				assert.Empty(t, got)
+			} else {
+				assert.Contains(t, got, tt.wantErr)
+			}
+		})
+	}
+}
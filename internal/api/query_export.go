@@ -0,0 +1,405 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/parquet"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// queryExportPageSize is the number of queries the export reads from the
+// store at a time.
+const queryExportPageSize = 1000
+
+// queryExportCSVHeader names the columns of a query history export; "rows"
+// is only present with include_rows.
+var queryExportCSVHeader = []string{
+	"uid", "executed_at", "last_executed_at", "repeat_count", "connection_id",
+	"user_id", "username", "database_id", "database_name", "grant_id", "access_level",
+	"sql_text", "fingerprint", "parameters", "duration_ms", "rows_affected", "error", "dry_run", "sql_truncated", "redacted",
+}
+
+// queryExportParquetColumns types the columns of queryExportCSVHeader for
+// Parquet exports; fields the CSV leaves empty are nulls.
+var queryExportParquetColumns = []parquet.Column{
+	{Name: "uid", Type: parquet.String},
+	{Name: "executed_at", Type: parquet.Timestamp},
+	{Name: "last_executed_at", Type: parquet.Timestamp, Optional: true},
+	{Name: "repeat_count", Type: parquet.Int64},
+	{Name: "connection_id", Type: parquet.String},
+	{Name: "user_id", Type: parquet.String, Optional: true},
+	{Name: "username", Type: parquet.String, Optional: true},
+	{Name: "database_id", Type: parquet.String, Optional: true},
+	{Name: "database_name", Type: parquet.String, Optional: true},
+	{Name: "grant_id", Type: parquet.String, Optional: true},
+	{Name: "access_level", Type: parquet.String, Optional: true},
+	{Name: "sql_text", Type: parquet.String},
+	{Name: "fingerprint", Type: parquet.String, Optional: true},
+	{Name: "parameters", Type: parquet.JSON, Optional: true},
+	{Name: "duration_ms", Type: parquet.Double, Optional: true},
+	{Name: "rows_affected", Type: parquet.Int64, Optional: true},
+	{Name: "error", Type: parquet.String, Optional: true},
+	{Name: "dry_run", Type: parquet.Boolean},
+	{Name: "sql_truncated", Type: parquet.Boolean},
+	{Name: "redacted", Type: parquet.Boolean},
+}
+
+// queryExportEncoder writes the queries of an export in one format. flush is
+// called after each page read from the store.
+type queryExportEncoder interface {
+	write(q *store.Query, username, databaseName string, rows *string) error
+	flush() error
+	close() error
+}
+
+// handleExportQueries streams the whole query history matching the list's
+// filters as a CSV or Parquet file, newest first, for access reviews that
+// would otherwise page through GET /queries. Queries are redacted under the
+// same rules as the list. With include_rows=true, which needs the rows:read
+// permission, each query carries its captured rows as a JSON array.
+func (s *Server) handleExportQueries(c *gin.Context) {
+	filter, ok := queryFilterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "parquet" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "format must be csv or parquet")
+		return
+	}
+
+	includeRows := c.Query("include_rows") == "true"
+	if includeRows && !getCurrentUser(c).Can(store.PermissionRowsRead) {
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "include_rows requires the rows:read permission")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// The first page is read before answering, so a failing store still gets
+	// an error response rather than an empty file.
+	filter.Limit = queryExportPageSize
+
+	queries, err := s.store.ListQueries(ctx, filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to export queries")
+		return
+	}
+
+	// A long export outlives the server's write timeout.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	var enc queryExportEncoder
+
+	fileName := "queries-" + time.Now().UTC().Format("20060102T150405Z")
+
+	if format == "parquet" {
+		c.Header("Content-Type", "application/vnd.apache.parquet")
+		c.Header("Content-Disposition", `attachment; filename="`+fileName+`.parquet"`)
+		enc = newQueryExportParquet(c.Writer, includeRows)
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+fileName+`.csv"`)
+		enc = newQueryExportCSV(c.Writer, includeRows)
+	}
+
+	c.Status(http.StatusOK)
+
+	// Headers are sent from here on: on error, the truncated file is all the
+	// client gets.
+	if s.writeQueryExport(c, enc, filter, queries, includeRows) == nil {
+		_ = enc.close()
+	}
+}
+
+// writeQueryExport writes queries, the first page of filter, and the pages
+// that follow to enc.
+func (s *Server) writeQueryExport(c *gin.Context, enc queryExportEncoder, filter store.QueryFilter, queries []store.Query, includeRows bool) error {
+	ctx := c.Request.Context()
+	redact := shouldRedactQueries(c)
+	names := newExportNames(s.store)
+
+	for len(queries) > 0 {
+		if redact {
+			s.redactQueries(ctx, queries)
+		}
+
+		for i := range queries {
+			var rows *string
+
+			if includeRows {
+				data, err := s.queryExportRows(ctx, queries[i].UID)
+				if err != nil {
+					s.logger.ErrorContext(ctx, "failed to export query rows",
+						slog.String("query_uid", queries[i].UID.String()), slog.Any("error", err))
+					return err
+				}
+
+				rows = &data
+			}
+
+			if err := enc.write(&queries[i], names.user(ctx, queries[i].UserID), names.database(ctx, queries[i].DatabaseID), rows); err != nil {
+				return err
+			}
+		}
+
+		if err := enc.flush(); err != nil || len(queries) < queryExportPageSize {
+			return err
+		}
+
+		filter.BeforeUID = &queries[len(queries)-1].UID
+
+		var err error
+		if queries, err = s.store.ListQueries(ctx, filter); err != nil {
+			s.logger.ErrorContext(ctx, "failed to export queries", slog.Any("error", err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryExportCSV writes an export as CSV, one line per query.
+type queryExportCSV struct {
+	w *csv.Writer
+}
+
+func newQueryExportCSV(w io.Writer, includeRows bool) *queryExportCSV {
+	header := queryExportCSVHeader
+	if includeRows {
+		header = append(header[:len(header):len(header)], "rows")
+	}
+
+	enc := &queryExportCSV{w: csv.NewWriter(w)}
+	_ = enc.w.Write(header) // buffered: errors surface on flush
+
+	return enc
+}
+
+func (e *queryExportCSV) write(q *store.Query, username, databaseName string, rows *string) error {
+	record := queryExportRecord(q, username, databaseName)
+	if rows != nil {
+		record = append(record, *rows)
+	}
+
+	return e.w.Write(record)
+}
+
+func (e *queryExportCSV) flush() error {
+	e.w.Flush()
+
+	return e.w.Error()
+}
+
+func (e *queryExportCSV) close() error {
+	return e.flush()
+}
+
+// queryExportParquet writes an export as Parquet, one row group per page.
+type queryExportParquet struct {
+	w *parquet.Writer
+}
+
+func newQueryExportParquet(w io.Writer, includeRows bool) *queryExportParquet {
+	columns := queryExportParquetColumns
+	if includeRows {
+		columns = append(columns[:len(columns):len(columns)], parquet.Column{Name: "rows", Type: parquet.JSON})
+	}
+
+	return &queryExportParquet{w: parquet.NewWriter(w, columns)}
+}
+
+func (e *queryExportParquet) write(q *store.Query, username, databaseName string, rows *string) error {
+	row := queryExportParquetRow(q, username, databaseName)
+	if rows != nil {
+		row = append(row, *rows)
+	}
+
+	return e.w.Write(row)
+}
+
+func (e *queryExportParquet) flush() error {
+	return e.w.Flush()
+}
+
+func (e *queryExportParquet) close() error {
+	return e.w.Close()
+}
+
+// queryExportRecord renders a query as a row of the export.
+func queryExportRecord(q *store.Query, username, databaseName string) []string {
+	optionalUID := func(uid *uuid.UUID) string {
+		if uid == nil {
+			return ""
+		}
+
+		return uid.String()
+	}
+
+	var lastExecutedAt, parameters, durationMs, rowsAffected, queryError string
+
+	if q.LastExecutedAt != nil {
+		lastExecutedAt = q.LastExecutedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	if q.Parameters != nil {
+		if data, err := json.Marshal(q.Parameters.Values); err == nil {
+			parameters = string(data)
+		}
+	}
+
+	if q.DurationMs != nil {
+		durationMs = strconv.FormatFloat(*q.DurationMs, 'f', -1, 64)
+	}
+
+	if q.RowsAffected != nil {
+		rowsAffected = strconv.FormatInt(*q.RowsAffected, 10)
+	}
+
+	if q.Error != nil {
+		queryError = *q.Error
+	}
+
+	return []string{
+		q.UID.String(), q.ExecutedAt.UTC().Format(time.RFC3339Nano), lastExecutedAt, strconv.FormatInt(q.RepeatCount, 10),
+		q.ConnectionID.String(), optionalUID(q.UserID), username, optionalUID(q.DatabaseID), databaseName,
+		optionalUID(q.GrantID), q.AccessLevel, q.SQLText, q.Fingerprint, parameters, durationMs, rowsAffected, queryError,
+		strconv.FormatBool(q.DryRun), strconv.FormatBool(q.SQLTruncated), strconv.FormatBool(q.Redacted),
+	}
+}
+
+// queryExportParquetRow renders a query as a row of a Parquet export, in the
+// order of queryExportParquetColumns.
+func queryExportParquetRow(q *store.Query, username, databaseName string) []any {
+	optionalUID := func(uid *uuid.UUID) any {
+		if uid == nil {
+			return nil
+		}
+
+		return uid.String()
+	}
+
+	optionalString := func(s string) any {
+		if s == "" {
+			return nil
+		}
+
+		return s
+	}
+
+	var lastExecutedAt, parameters, durationMs, rowsAffected, queryError any
+
+	if q.LastExecutedAt != nil {
+		lastExecutedAt = q.LastExecutedAt.UTC()
+	}
+
+	if q.Parameters != nil {
+		if data, err := json.Marshal(q.Parameters.Values); err == nil {
+			parameters = string(data)
+		}
+	}
+
+	if q.DurationMs != nil {
+		durationMs = *q.DurationMs
+	}
+
+	if q.RowsAffected != nil {
+		rowsAffected = *q.RowsAffected
+	}
+
+	if q.Error != nil {
+		queryError = *q.Error
+	}
+
+	return []any{
+		q.UID.String(), q.ExecutedAt.UTC(), lastExecutedAt, q.RepeatCount,
+		q.ConnectionID.String(), optionalUID(q.UserID), optionalString(username), optionalUID(q.DatabaseID), optionalString(databaseName),
+		optionalUID(q.GrantID), optionalString(q.AccessLevel), q.SQLText, optionalString(q.Fingerprint), parameters, durationMs, rowsAffected, queryError,
+		q.DryRun, q.SQLTruncated, q.Redacted,
+	}
+}
+
+// queryExportRows returns the captured rows of a query as a JSON array of
+// their data, compacted rows included.
+func (s *Server) queryExportRows(ctx context.Context, queryUID uuid.UUID) (string, error) {
+	data := []json.RawMessage{}
+	cursor := ""
+
+	for {
+		page, err := s.store.GetQueryRows(ctx, queryUID, cursor, store.MaxQueryRowsLimit)
+		if err != nil {
+			return "", err
+		}
+
+		for _, row := range page.Rows {
+			data = append(data, row.RowData)
+		}
+
+		if !page.HasMore {
+			break
+		}
+
+		cursor = page.NextCursor
+	}
+
+	out, err := json.Marshal(data)
+
+	return string(out), err
+}
+
+// exportNames resolves and caches the usernames and database names of an
+// export; deleted ones are left empty.
+type exportNames struct {
+	store     *store.Store
+	users     map[uuid.UUID]string
+	databases map[uuid.UUID]string
+}
+
+func newExportNames(s *store.Store) *exportNames {
+	return &exportNames{store: s, users: make(map[uuid.UUID]string), databases: make(map[uuid.UUID]string)}
+}
+
+func (n *exportNames) user(ctx context.Context, uid *uuid.UUID) string {
+	if uid == nil {
+		return ""
+	}
+
+	name, ok := n.users[*uid]
+	if !ok {
+		if user, err := n.store.GetUserByUID(ctx, *uid); err == nil {
+			name = user.Username
+		}
+
+		n.users[*uid] = name
+	}
+
+	return name
+}
+
+func (n *exportNames) database(ctx context.Context, uid *uuid.UUID) string {
+	if uid == nil {
+		return ""
+	}
+
+	name, ok := n.databases[*uid]
+	if !ok {
+		if db, err := n.store.GetServerByUID(ctx, *uid); err == nil {
+			name = db.Name
+		}
+
+		n.databases[*uid] = name
+	}
+
+	return name
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestQueryExportRecord(t *testing.T) {
+	t.Parallel()
+
+	userID, databaseID := uuid.New(), uuid.New()
+	duration, affected, queryError := 1.5, int64(3), "boom"
+
+	q := &store.Query{
+		UID:          uuid.New(),
+		ConnectionID: uuid.New(),
+		SQLText:      "SELECT $1",
+		Parameters:   &store.QueryParameters{Values: []string{"42"}},
+		ExecutedAt:   time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		DurationMs:   &duration,
+		RowsAffected: &affected,
+		Error:        &queryError,
+		RepeatCount:  1,
+		UserID:       &userID,
+		DatabaseID:   &databaseID,
+		AccessLevel:  store.AccessLevelReadOnly,
+		DryRun:       true,
+	}
+
+	record := queryExportRecord(q, "alice", "orders")
+	require.Len(t, record, len(queryExportCSVHeader))
+
+	got := make(map[string]string, len(record))
+	for i, name := range queryExportCSVHeader {
+		got[name] = record[i]
+	}
+
+	require.Equal(t, "2026-10-01T12:00:00Z", got["executed_at"])
+	require.Empty(t, got["last_executed_at"])
+	require.Equal(t, "alice", got["username"])
+	require.Equal(t, databaseID.String(), got["database_id"])
+	require.Equal(t, "orders", got["database_name"])
+	require.Empty(t, got["grant_id"])
+	require.Equal(t, `["42"]`, got["parameters"])
+	require.Equal(t, "1.5", got["duration_ms"])
+	require.Equal(t, "3", got["rows_affected"])
+	require.Equal(t, "boom", got["error"])
+	require.Equal(t, "true", got["dry_run"])
+	require.Equal(t, "false", got["redacted"])
+}
+
+func TestQueryExportParquetRow(t *testing.T) {
+	t.Parallel()
+
+	userID := uuid.New()
+	duration := 1.5
+
+	q := &store.Query{
+		UID:          uuid.New(),
+		ConnectionID: uuid.New(),
+		SQLText:      "SELECT 1",
+		ExecutedAt:   time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		DurationMs:   &duration,
+		RepeatCount:  2,
+		UserID:       &userID,
+	}
+
+	row := queryExportParquetRow(q, "alice", "")
+	require.Len(t, row, len(queryExportParquetColumns))
+
+	for i, column := range queryExportParquetColumns {
+		require.Equal(t, queryExportCSVHeader[i], column.Name)
+	}
+
+	got := make(map[string]any, len(row))
+	for i, column := range queryExportParquetColumns {
+		got[column.Name] = row[i]
+	}
+
+	require.Equal(t, q.ExecutedAt, got["executed_at"])
+	require.Nil(t, got["last_executed_at"])
+	require.Equal(t, int64(2), got["repeat_count"])
+	require.Equal(t, userID.String(), got["user_id"])
+	require.Equal(t, "alice", got["username"])
+	require.Nil(t, got["database_id"])
+	require.Nil(t, got["database_name"])
+	require.Equal(t, 1.5, got["duration_ms"])
+	require.Nil(t, got["rows_affected"])
+	require.Equal(t, false, got["dry_run"])
+
+	enc := newQueryExportParquet(io.Discard, true)
+	require.NoError(t, enc.write(q, "alice", "", new(string)))
+	require.NoError(t, enc.close())
+}
+
+// TestExportQueries verifies the export honors the list's filters, redacts
+// for users without sql:raw and keeps result rows to rows:read.
+func TestExportQueries(t *testing.T) { //nolint:paralleltest // shared database state
+	server, dataStore := setupTestServer(t)
+	suffix := "qexport"
+
+	admin := createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	adminToken := loginUser(t, server, "admin-"+suffix, "adminpass123")
+
+	createTestUser(t, dataStore, "auditor-"+suffix, "auditorpass123", []string{store.RoleAuditor})
+	auditorToken := loginUser(t, server, "auditor-"+suffix, "auditorpass123")
+
+	exported := createTestDBEntry(t, dataStore, "db-"+suffix, true)
+	other := createTestDBEntry(t, dataStore, "other-"+suffix, true)
+
+	conn, err := dataStore.CreateConnection(t.Context(), admin.UID, exported.UID, "10.1.1.8")
+	require.NoError(t, err)
+	otherConn, err := dataStore.CreateConnection(t.Context(), admin.UID, other.UID, "10.1.1.9")
+	require.NoError(t, err)
+
+	query, err := dataStore.CreateQuery(t.Context(), &store.Query{ConnectionID: conn.UID, SQLText: "SELECT 'secret'"})
+	require.NoError(t, err)
+	require.NoError(t, dataStore.StoreQueryRows(t.Context(), query.UID, []store.QueryRow{{RowNumber: 0, RowData: []byte(`{"n":1}`)}}))
+	_, err = dataStore.CreateQuery(t.Context(), &store.Query{ConnectionID: otherConn.UID, SQLText: "SELECT 'other'"})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.GET("/api/v1/queries/export", server.requirePermission(store.PermissionQueriesRead), server.handleExportQueries)
+
+	export := func(token, params string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/queries/export?database_id="+exported.UID.String()+params, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := export(adminToken, "&include_rows=true")
+	require.Equal(t, http.StatusOK, w.Code, "response body: %s", w.Body.String())
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2, "the other database's query must be filtered out")
+	require.Equal(t, "rows", records[0][len(records[0])-1])
+	require.Equal(t, query.UID.String(), records[1][0])
+	require.Equal(t, "db-"+suffix, records[1][8])
+	require.Equal(t, "SELECT 'secret'", records[1][11])
+	require.Equal(t, `[{"n":1}]`, records[1][len(records[1])-1])
+
+	w = export(auditorToken, "")
+	require.Equal(t, http.StatusOK, w.Code, "response body: %s", w.Body.String())
+	require.NotContains(t, w.Body.String(), "secret", "auditors get the export redacted")
+
+	w = export(auditorToken, "&include_rows=true")
+	require.Equal(t, http.StatusForbidden, w.Code, "response body: %s", w.Body.String())
+
+	w = export(adminToken, "&format=parquet&include_rows=true")
+	require.Equal(t, http.StatusOK, w.Code, "response body: %s", w.Body.String())
+	require.Equal(t, "application/vnd.apache.parquet", w.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(w.Body.String(), "PAR1"))
+	require.True(t, strings.HasSuffix(w.Body.String(), "PAR1"))
+	require.Contains(t, w.Body.String(), "SELECT 'secret'")
+	require.NotContains(t, w.Body.String(), "SELECT 'other'", "the other database's query must be filtered out")
+
+	w = export(adminToken, "&format=xml")
+	require.Equal(t, http.StatusBadRequest, w.Code, "response body: %s", w.Body.String())
+}
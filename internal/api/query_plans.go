@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// handleGetQueryPlan returns the plan captured for a slow query or, when the
+// query was not explained itself, the latest one of a statement of the same
+// fingerprint on its database. Plans show the literal values the planner
+// folded into conditions, so they are never redacted but need sql:raw.
+func (s *Server) handleGetQueryPlan(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid query UID")
+		return
+	}
+
+	if shouldRedactQueries(c) {
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "query plans show literal values and require the "+
+			store.PermissionRawSQLRead+" permission")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	query, err := s.store.GetQuery(ctx, uid)
+	if err != nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "query not found")
+		return
+	}
+
+	plan, err := s.store.GetQueryPlan(ctx, query)
+	if err != nil {
+		if errors.Is(err, store.ErrQueryPlanNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "no plan was captured for this query")
+			return
+		}
+
+		writeInternalError(c, s.logger, err, "failed to get query plan")
+
+		return
+	}
+
+	successResponse(c, plan)
+}
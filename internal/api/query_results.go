@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/spill"
+)
+
+// QueryResultURLResponse is a URL downloading the spilled result of a query,
+// and until when it does.
+type QueryResultURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// queryResultResource names the spilled result of a query in download
+// signatures.
+func queryResultResource(uid uuid.UUID) string {
+	return "query-result:" + uid.String()
+}
+
+// handleGetQueryResultURL returns a signed URL downloading the result a query
+// spilled past the query storage limits, as gzip-compressed JSON lines. On
+// S3, the URL points to the bucket; with the file target, to
+// handleDownloadQueryResult.
+func (s *Server) handleGetQueryResultURL(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid query UID")
+		return
+	}
+
+	query, err := s.store.GetQuery(c.Request.Context(), uid)
+	if err != nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "query not found")
+		return
+	}
+
+	if query.ResultObjectKey == nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "the result of this query was not spilled")
+		return
+	}
+
+	spiller := spill.Default()
+	if spiller == nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "result spilling is not enabled")
+		return
+	}
+
+	signed, expiresAt := spiller.URL(*query.ResultObjectKey)
+	if signed == "" {
+		expiresAt = expiresAt.Truncate(time.Second)
+		signed = buildAPIURL(c.Request, "/query-results/"+uid.String()+"?"+url.Values{
+			"expires":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+			"signature": {crypto.SignDownload(s.encryptionKey, queryResultResource(uid), expiresAt)},
+		}.Encode())
+	}
+
+	successResponse(c, QueryResultURLResponse{URL: signed, ExpiresAt: expiresAt})
+}
+
+// handleDownloadQueryResult serves a result spilled to the file target. It is
+// unauthenticated: the signature handleGetQueryResultURL put in the URL is.
+func (s *Server) handleDownloadQueryResult(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid query UID")
+		return
+	}
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || !crypto.VerifyDownload(s.encryptionKey, queryResultResource(uid), time.Unix(expires, 0),
+		time.Now(), c.Query("signature")) {
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "invalid or expired download signature")
+		return
+	}
+
+	query, err := s.store.GetQuery(c.Request.Context(), uid)
+	if err != nil || query.ResultObjectKey == nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "spilled result not found")
+		return
+	}
+
+	spiller := spill.Default()
+	if spiller == nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "result spilling is not enabled")
+		return
+	}
+
+	file, err := spiller.Open(*query.ResultObjectKey)
+	if err != nil {
+		if errors.Is(err, spill.ErrNotLocal) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "spilled results are downloaded from object storage")
+			return
+		}
+
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "spilled result not found")
+
+		return
+	}
+
+	defer func() { _ = file.Close() }()
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.jsonl.gz"`, uid))
+	c.Header("Content-Type", "application/gzip")
+	c.Status(http.StatusOK)
+
+	_, _ = io.Copy(c.Writer, file)
+}
+
+// buildAPIURL constructs an absolute URL to a v1 API path, combining the
+// request's scheme/host, mirroring buildFrontendURL.
+func buildAPIURL(r *http.Request, path string) string {
+	scheme := "https"
+	if r.TLS == nil {
+		if fwdProto := r.Header.Get("X-Forwarded-Proto"); fwdProto != "" {
+			scheme = fwdProto
+		} else {
+			scheme = "http"
+		}
+	}
+
+	return fmt.Sprintf("%s://%s/api/%s%s", scheme, r.Host, apiV1, path)
+}
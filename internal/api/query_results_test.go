@@ -0,0 +1,110 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/spill"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// TestQueryResultURL_File verifies a result spilled to the file target is
+// downloaded through the signed API URL, and only through it.
+func TestQueryResultURL_File(t *testing.T) { //nolint:paralleltest // shared database state, process-wide spiller
+	server, dataStore := setupTestServer(t)
+	suffix := "qspill"
+
+	spiller, err := spill.New(config.ResultSpillConfig{
+		Target:    config.ResultSpillFile,
+		Dir:       t.TempDir(),
+		Prefix:    "results/",
+		MaxBytes:  1024,
+		URLExpiry: "15m",
+	})
+	require.NoError(t, err)
+
+	spill.SetDefault(spiller)
+	t.Cleanup(func() { spill.SetDefault(nil) })
+
+	admin := createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	token := loginUser(t, server, "admin-"+suffix, "adminpass123")
+
+	db := createTestDBEntry(t, dataStore, "db-"+suffix, true)
+	conn, err := dataStore.CreateConnection(t.Context(), admin.UID, db.UID, "10.1.1.8")
+	require.NoError(t, err)
+
+	spilled, err := dataStore.CreateQuery(t.Context(), &store.Query{ConnectionID: conn.UID, SQLText: "SELECT * FROM big"})
+	require.NoError(t, err)
+	plain, err := dataStore.CreateQuery(t.Context(), &store.Query{ConnectionID: conn.UID, SQLText: "SELECT 1"})
+	require.NoError(t, err)
+
+	w, err := spiller.Begin()
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]byte(`{"id":1}`)))
+	key, err := spiller.Commit(context.Background(), w)
+	require.NoError(t, err)
+	require.NoError(t, dataStore.SetQueryResultObject(t.Context(), spilled.UID, key, 1))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authenticated := router.Group("/api/v1", server.authMiddleware())
+	authenticated.GET("/queries/:uid/result-url", server.requirePermission(store.PermissionRowsRead), server.handleGetQueryResultURL)
+	router.GET("/api/v1/query-results/:uid", server.handleDownloadQueryResult)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := get("/api/v1/queries/" + plain.UID.String() + "/result-url")
+	require.Equal(t, http.StatusNotFound, rec.Code, "a query without a spilled result")
+
+	rec = get("/api/v1/queries/" + spilled.UID.String() + "/result-url")
+	require.Equal(t, http.StatusOK, rec.Code, "response body: %s", rec.Body.String())
+
+	var resp QueryResultURLResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	signed, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	require.Equal(t, "/api/v1/query-results/"+spilled.UID.String(), signed.Path)
+
+	// The signature authorizes the download, without a token.
+	req := httptest.NewRequest(http.MethodGet, signed.RequestURI(), http.NoBody)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, "response body: %s", rec.Body.String())
+	require.Equal(t, "application/gzip", rec.Header().Get("Content-Type"))
+
+	gz, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":1}`, string(body))
+
+	// Another query's UID does not match the signature.
+	query := signed.Query()
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet,
+		"/api/v1/query-results/"+plain.UID.String()+"?"+query.Encode(), http.NoBody))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	query.Set("signature", "00")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, signed.Path+"?"+query.Encode(), http.NoBody))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// QueryStatResponse is a statement of GET /queries/stats, shown in its
+// fingerprint's canonical form, without literal values.
+type QueryStatResponse struct {
+	store.QueryStat
+	Query string `json:"query"`
+}
+
+// handleGetQueryStats aggregates the query log per statement fingerprint and
+// database, like pg_stat_statements does upstream: calls, errors, total,
+// average and 95th percentile durations, and rows. It takes the filters of
+// the query list. Queries logged before fingerprinting are left out.
+func (s *Server) handleGetQueryStats(c *gin.Context) {
+	queryFilter, ok := queryFilterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	filter := store.QueryStatsFilter{
+		QueryFilter: queryFilter,
+		Sort:        c.DefaultQuery("sort", store.QueryStatsSortCalls),
+	}
+
+	if !store.ValidQueryStatsSort(filter.Sort) {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "sort must be one of "+strings.Join([]string{
+			store.QueryStatsSortCalls, store.QueryStatsSortTotalDuration, store.QueryStatsSortAvgDuration,
+			store.QueryStatsSortP95Duration, store.QueryStatsSortRows,
+		}, ", "))
+
+		return
+	}
+
+	p, ok := pageFromRequest(c, defaultActivityPageLimit)
+	if !ok {
+		return
+	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
+
+	ctx := c.Request.Context()
+
+	stats, err := s.store.GetQueryStats(ctx, filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to aggregate queries")
+		return
+	}
+
+	total, err := s.store.CountQueryStats(ctx, filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count query statistics")
+		return
+	}
+
+	// The canonical text follows the quoting rules of each database's
+	// protocol, as the fingerprint did.
+	protocols := make(map[uuid.UUID]string)
+	response := make([]QueryStatResponse, len(stats))
+
+	for i, stat := range stats {
+		protocol := store.ProtocolPostgreSQL
+
+		if stat.DatabaseID != nil {
+			p, ok := protocols[*stat.DatabaseID]
+			if !ok {
+				p = store.ProtocolPostgreSQL
+				if srv, err := s.store.GetServerByUID(ctx, *stat.DatabaseID); err == nil {
+					p = srv.Protocol
+				}
+
+				protocols[*stat.DatabaseID] = p
+			}
+
+			protocol = p
+		}
+
+		response[i] = QueryStatResponse{
+			QueryStat: stat,
+			Query:     sqlnorm.FingerprintText(stat.SampleSQL, protocol),
+		}
+	}
+
+	pageResponse(c, p, total, gin.H{"stats": response})
+}
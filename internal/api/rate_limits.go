@@ -0,0 +1,199 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// RateLimitsResponse is the API rate limit configuration: the defaults of
+// the configuration file and the per-role overrides.
+type RateLimitsResponse struct {
+	Enabled               bool           `json:"enabled"`
+	RequestsPerMinute     int            `json:"requests_per_minute"`
+	RequestsPerMinuteAnon int            `json:"requests_per_minute_anon"`
+	Burst                 int            `json:"burst"`
+	Roles                 map[string]int `json:"roles"`
+}
+
+// UserRateLimitResponse is the API rate limit applied to a user and the state
+// of their current window.
+type UserRateLimitResponse struct {
+	Enabled bool `json:"enabled"`
+	Exempt  bool `json:"exempt"`
+	// Override is the user's own override; nil when not overridden.
+	Override *int `json:"override"`
+	// RequestsPerMinute is the limit applied, from Source: user, role or
+	// default.
+	RequestsPerMinute int       `json:"requests_per_minute"`
+	Source            string    `json:"source"`
+	Burst             int       `json:"burst"`
+	Used              int       `json:"used"`
+	Remaining         int       `json:"remaining"`
+	ResetAt           time.Time `json:"reset_at"`
+}
+
+// SetRateLimitRequest sets a rate limit override.
+type SetRateLimitRequest struct {
+	RequestsPerMinute int `json:"requests_per_minute" binding:"required,min=1"`
+}
+
+// handleGetRateLimits returns the rate limit defaults and the per-role
+// overrides. Admin only.
+func (s *Server) handleGetRateLimits(c *gin.Context) {
+	roles, err := s.store.GetRoleRateLimits(c.Request.Context())
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to get role rate limits")
+		return
+	}
+
+	response := RateLimitsResponse{Roles: roles}
+	if s.rateLimiter != nil {
+		settings := s.rateLimiter.Settings()
+		response.Enabled = settings.Enabled
+		response.RequestsPerMinute = settings.RequestsPerMinute
+		response.RequestsPerMinuteAnon = settings.RequestsPerMinuteAnon
+		response.Burst = settings.Burst
+	}
+
+	successResponse(c, response)
+}
+
+// handleSetRoleRateLimit overrides the rate limit of a role. Admin only.
+func (s *Server) handleSetRoleRateLimit(c *gin.Context) {
+	role := c.Param("role")
+	if !store.IsKnownRole(role) {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "unknown role: "+role)
+		return
+	}
+
+	var req SetRateLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+		return
+	}
+
+	if err := s.store.SetRoleRateLimit(c.Request.Context(), role, req.RequestsPerMinute); err != nil {
+		writeInternalError(c, s.logger, err, "failed to set role rate limit")
+		return
+	}
+
+	s.roleRateLimitChanged(c, role, &req.RequestsPerMinute)
+	s.handleGetRateLimits(c)
+}
+
+// handleDeleteRoleRateLimit removes the rate limit override of a role. Admin
+// only.
+func (s *Server) handleDeleteRoleRateLimit(c *gin.Context) {
+	role := c.Param("role")
+
+	if err := s.store.DeleteRoleRateLimit(c.Request.Context(), role); err != nil {
+		if errors.Is(err, store.ErrParameterNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "role has no rate limit override")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to delete role rate limit")
+		return
+	}
+
+	s.roleRateLimitChanged(c, role, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// roleRateLimitChanged applies a new role override to this instance right
+// away, other instances picking it up within roleLimitsTTL, and audits it.
+func (s *Server) roleRateLimitChanged(c *gin.Context, role string, requestsPerMinute *int) {
+	if s.rateLimiter != nil {
+		s.rateLimiter.InvalidateRoleLimits()
+	}
+
+	currentUser := getCurrentUser(c)
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
+		PerformedBy: &currentUser.UID,
+		Payload:     audit.RateLimitUpdatedV1{Role: role, RequestsPerMinute: requestsPerMinute},
+	})
+}
+
+// handleGetUserRateLimit returns the rate limit applied to a user and how much
+// of it they used. Users can read their own; admins anyone's.
+func (s *Server) handleGetUserRateLimit(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid user UID")
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	if uid != currentUser.UID && !currentUser.IsAdmin() {
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "cannot read another user's rate limit")
+		return
+	}
+
+	user, err := s.store.GetUserByUID(c.Request.Context(), uid)
+	if err != nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "user not found")
+		return
+	}
+
+	response := UserRateLimitResponse{Exempt: user.RateLimitExempt, Override: user.RateLimitPerMinute}
+	if s.rateLimiter != nil {
+		budget := s.rateLimiter.budget(c.Request.Context(), user)
+		response.Enabled = s.rateLimiter.Settings().Enabled
+		response.RequestsPerMinute = budget.Limit
+		response.Source = budget.Source
+		response.Burst = budget.Burst
+		response.Used = budget.Used
+		response.Remaining = budget.Remaining
+		response.ResetAt = budget.ResetAt
+	}
+
+	successResponse(c, response)
+}
+
+// handleSetUserRateLimit overrides the rate limit of a user. Admin only.
+func (s *Server) handleSetUserRateLimit(c *gin.Context) {
+	var req SetRateLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+		return
+	}
+
+	s.setUserRateLimit(c, &req.RequestsPerMinute)
+}
+
+// handleDeleteUserRateLimit removes the rate limit override of a user. Admin
+// only.
+func (s *Server) handleDeleteUserRateLimit(c *gin.Context) {
+	s.setUserRateLimit(c, nil)
+}
+
+func (s *Server) setUserRateLimit(c *gin.Context, requestsPerMinute *int) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid user UID")
+		return
+	}
+
+	if err := s.store.SetUserRateLimit(c.Request.Context(), uid, requestsPerMinute); err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "user not found")
+			return
+		}
+		writeInternalError(c, s.logger, err, "failed to set user rate limit")
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
+		UserID:      &uid,
+		PerformedBy: &currentUser.UID,
+		Payload:     audit.RateLimitUpdatedV1{UserUID: &uid, RequestsPerMinute: requestsPerMinute},
+	})
+
+	s.handleGetUserRateLimit(c)
+}
@@ -1,29 +1,47 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// roleLimitsTTL is how long the per-role overrides are reused before being
+// reloaded, so that overrides set on another DBBat instance apply here too.
+const roleLimitsTTL = 30 * time.Second
+
+// Sources of the limit applied to a user.
+const (
+	rateLimitSourceUser    = "user"
+	rateLimitSourceRole    = "role"
+	rateLimitSourceDefault = "default"
 )
 
 // RateLimiter implements a sliding window rate limiter
 type RateLimiter struct {
 	mu sync.RWMutex
 
-	// Configuration
-	enabled               bool
-	requestsPerMinute     int
-	requestsPerMinuteAnon int
-	burst                 int
+	// Configuration, replaced by Reconfigure
+	settings atomic.Pointer[config.RateLimitConfig]
 
 	// Sliding window storage: key -> list of request timestamps
 	windows map[string]*slidingWindow
+
+	// Per-role overrides of requestsPerMinute, loaded by loadRoleLimits and
+	// cached for roleLimitsTTL.
+	roleMu         sync.Mutex
+	loadRoleLimits func(context.Context) (map[string]int, error)
+	roleLimits     map[string]int
+	roleLimitsAt   time.Time
 }
 
 // slidingWindow tracks requests in a sliding time window
@@ -35,12 +53,9 @@ type slidingWindow struct {
 // NewRateLimiter creates a new rate limiter with the given configuration
 func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
 	rl := &RateLimiter{
-		enabled:               cfg.Enabled,
-		requestsPerMinute:     cfg.RequestsPerMinute,
-		requestsPerMinuteAnon: cfg.RequestsPerMinuteAnon,
-		burst:                 cfg.Burst,
-		windows:               make(map[string]*slidingWindow),
+		windows: make(map[string]*slidingWindow),
 	}
+	rl.settings.Store(&cfg)
 
 	// Start cleanup goroutine
 	go rl.cleanup()
@@ -48,6 +63,101 @@ func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
 	return rl
 }
 
+// Reconfigure applies new limits from the next request on. The requests
+// already counted in the windows stay counted.
+func (rl *RateLimiter) Reconfigure(cfg config.RateLimitConfig) {
+	rl.settings.Store(&cfg)
+}
+
+// Settings returns the limits in effect.
+func (rl *RateLimiter) Settings() config.RateLimitConfig {
+	return *rl.settings.Load()
+}
+
+// SetRoleLimitSource makes the limiter apply the per-role overrides load
+// returns.
+func (rl *RateLimiter) SetRoleLimitSource(load func(context.Context) (map[string]int, error)) {
+	rl.roleMu.Lock()
+	defer rl.roleMu.Unlock()
+
+	rl.loadRoleLimits = load
+	rl.roleLimitsAt = time.Time{}
+}
+
+// InvalidateRoleLimits makes the next request reload the per-role overrides.
+func (rl *RateLimiter) InvalidateRoleLimits() {
+	rl.roleMu.Lock()
+	defer rl.roleMu.Unlock()
+
+	rl.roleLimitsAt = time.Time{}
+}
+
+// currentRoleLimits returns the per-role overrides, reloading them when they
+// are older than roleLimitsTTL. A failed reload keeps the previous ones.
+func (rl *RateLimiter) currentRoleLimits(ctx context.Context) map[string]int {
+	rl.roleMu.Lock()
+	defer rl.roleMu.Unlock()
+
+	if rl.loadRoleLimits == nil || time.Since(rl.roleLimitsAt) < roleLimitsTTL {
+		return rl.roleLimits
+	}
+
+	if limits, err := rl.loadRoleLimits(ctx); err == nil {
+		rl.roleLimits = limits
+	}
+	rl.roleLimitsAt = time.Now()
+
+	return rl.roleLimits
+}
+
+// limitFor returns the requests per minute allowed to user and where the
+// limit comes from: the user's own override, the most generous override of
+// their roles, or the configured default.
+func (rl *RateLimiter) limitFor(ctx context.Context, user *store.User) (int, string) {
+	if user.RateLimitPerMinute != nil {
+		return *user.RateLimitPerMinute, rateLimitSourceUser
+	}
+
+	roleLimits := rl.currentRoleLimits(ctx)
+	limit := 0
+
+	for _, role := range user.Roles {
+		limit = max(limit, roleLimits[role])
+	}
+
+	if limit > 0 {
+		return limit, rateLimitSourceRole
+	}
+
+	return rl.Settings().RequestsPerMinute, rateLimitSourceDefault
+}
+
+// rateBudget is the state of a user's rate limit window.
+type rateBudget struct {
+	Limit     int
+	Source    string
+	Burst     int
+	Used      int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// budget returns the state of user's window, without recording a request.
+func (rl *RateLimiter) budget(ctx context.Context, user *store.User) rateBudget {
+	limit, source := rl.limitFor(ctx, user)
+	used, resetAt := rl.GetStats(&user.UID, "")
+	burst := rl.Settings().Burst
+
+	return rateBudget{
+		Limit:     limit,
+		Source:    source,
+		Burst:     burst,
+		Used:      used,
+		Remaining: max(limit+burst-used, 0),
+		ResetAt:   resetAt,
+	}
+}
+
 // cleanup periodically removes old entries from the windows map
 func (rl *RateLimiter) cleanup() {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -119,7 +229,7 @@ func (rl *RateLimiter) check(key string, limit int) (bool, int, time.Time) {
 	window.timestamps = window.timestamps[validStart:]
 
 	// Calculate effective limit with burst
-	effectiveLimit := limit + rl.burst
+	effectiveLimit := limit + rl.Settings().Burst
 
 	// Check if request is allowed
 	currentCount := len(window.timestamps)
@@ -147,7 +257,8 @@ func (rl *RateLimiter) check(key string, limit int) (bool, int, time.Time) {
 // Middleware returns a Gin middleware for rate limiting
 func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !rl.enabled {
+		settings := rl.Settings()
+		if !settings.Enabled {
 			c.Next()
 			return
 		}
@@ -165,11 +276,11 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 				return
 			}
 			key = "user:" + user.UID.String()
-			limit = rl.requestsPerMinute
+			limit, _ = rl.limitFor(c.Request.Context(), user)
 		} else {
 			// Unauthenticated request - rate limit by IP
 			key = "ip:" + c.ClientIP()
-			limit = rl.requestsPerMinuteAnon
+			limit = settings.RequestsPerMinuteAnon
 		}
 
 		allowed, remaining, resetTime := rl.check(key, limit)
@@ -203,7 +314,8 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 // It uses the authenticated user ID for rate limiting
 func (rl *RateLimiter) PostAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !rl.enabled {
+		settings := rl.Settings()
+		if !settings.Enabled {
 			c.Next()
 			return
 		}
@@ -223,7 +335,7 @@ func (rl *RateLimiter) PostAuthMiddleware() gin.HandlerFunc {
 		}
 
 		key := "user:" + user.UID.String()
-		limit := rl.requestsPerMinute
+		limit, _ := rl.limitFor(c.Request.Context(), user)
 
 		allowed, remaining, resetTime := rl.check(key, limit)
 
@@ -256,14 +368,15 @@ func (rl *RateLimiter) PostAuthMiddleware() gin.HandlerFunc {
 // It rate limits by IP for unauthenticated requests
 func (rl *RateLimiter) PreAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !rl.enabled {
+		settings := rl.Settings()
+		if !settings.Enabled {
 			c.Next()
 			return
 		}
 
 		// Rate limit by IP before authentication
 		key := "ip:" + c.ClientIP()
-		limit := rl.requestsPerMinuteAnon
+		limit := settings.RequestsPerMinuteAnon
 
 		allowed, remaining, resetTime := rl.check(key, limit)
 
@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/store"
@@ -327,3 +330,134 @@ func TestRateLimiter_DifferentIPs(t *testing.T) {
 		t.Errorf("IP2 request: status = %d, want %d", w.Code, http.StatusOK)
 	}
 }
+
+func TestRateLimiter_LimitFor(t *testing.T) {
+	t.Parallel()
+
+	rl := NewRateLimiter(config.RateLimitConfig{Enabled: true, RequestsPerMinute: 60})
+
+	var loads atomic.Int32
+	rl.SetRoleLimitSource(func(context.Context) (map[string]int, error) {
+		loads.Add(1)
+
+		return map[string]int{store.RoleViewer: 300, store.RoleAuditor: 120}, nil
+	})
+
+	ctx := context.Background()
+	override := 1000
+
+	tests := []struct {
+		name       string
+		user       *store.User
+		wantLimit  int
+		wantSource string
+	}{
+		{"default", &store.User{Roles: []string{store.RoleConnector}}, 60, rateLimitSourceDefault},
+		{"role", &store.User{Roles: []string{store.RoleConnector, store.RoleAuditor}}, 120, rateLimitSourceRole},
+		{"most generous role", &store.User{Roles: []string{store.RoleAuditor, store.RoleViewer}}, 300, rateLimitSourceRole},
+		{"user override", &store.User{Roles: []string{store.RoleViewer}, RateLimitPerMinute: &override}, 1000, rateLimitSourceUser},
+	}
+
+	for _, tt := range tests {
+		limit, source := rl.limitFor(ctx, tt.user)
+		if limit != tt.wantLimit || source != tt.wantSource {
+			t.Errorf("%s: limitFor() = %d, %q, want %d, %q", tt.name, limit, source, tt.wantLimit, tt.wantSource)
+		}
+	}
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("role limits loaded %d times, want once within the TTL", n)
+	}
+
+	rl.InvalidateRoleLimits()
+	rl.limitFor(ctx, &store.User{})
+
+	if n := loads.Load(); n != 2 {
+		t.Errorf("role limits loaded %d times after invalidation, want 2", n)
+	}
+}
+
+func TestRateLimiter_PostAuthMiddleware_RoleOverride(t *testing.T) {
+	t.Parallel()
+
+	rl := NewRateLimiter(config.RateLimitConfig{Enabled: true, RequestsPerMinute: 2})
+	rl.SetRoleLimitSource(func(context.Context) (map[string]int, error) {
+		return map[string]int{store.RoleViewer: 5}, nil
+	})
+
+	user := &store.User{UID: uuid.New(), Roles: []string{store.RoleViewer}}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("current_user", user)
+		c.Next()
+	})
+	router.Use(rl.PostAuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	for i := range 6 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "5" {
+			t.Errorf("Request %d: X-RateLimit-Limit = %q, want the role's 5", i, got)
+		}
+
+		want := http.StatusOK
+		if i == 5 {
+			want = http.StatusTooManyRequests
+		}
+
+		if w.Code != want {
+			t.Errorf("Request %d: status = %d, want %d", i, w.Code, want)
+		}
+	}
+
+	budget := rl.budget(context.Background(), user)
+	if budget.Used != 5 || budget.Remaining != 0 || budget.Source != rateLimitSourceRole {
+		t.Errorf("budget() = %+v, want 5 used, 0 remaining, from the role", budget)
+	}
+}
+
+func TestRateLimiter_Reconfigure(t *testing.T) {
+	t.Parallel()
+
+	rl := NewRateLimiter(config.RateLimitConfig{Enabled: false, RequestsPerMinuteAnon: 1})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(rl.PreAuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	request := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		return w.Code
+	}
+
+	for i := range 3 {
+		if code := request(); code != http.StatusOK {
+			t.Fatalf("Request %d while disabled: status = %d, want 200", i, code)
+		}
+	}
+
+	rl.Reconfigure(config.RateLimitConfig{Enabled: true, RequestsPerMinuteAnon: 1})
+
+	if code := request(); code != http.StatusOK {
+		t.Errorf("First request once enabled: status = %d, want 200", code)
+	}
+
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Errorf("Second request once enabled: status = %d, want 429", code)
+	}
+
+	if got := rl.Settings(); !got.Enabled || got.RequestsPerMinuteAnon != 1 {
+		t.Errorf("Settings() = %+v, want the reconfigured limits", got)
+	}
+}
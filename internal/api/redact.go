@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// shouldRedactQueries reports whether the query log must be served with its
+// literal values masked: always for users without the sql:raw permission,
+// and on request (?redact=true) for the others.
+func shouldRedactQueries(c *gin.Context) bool {
+	return c.Query("redact") == "true" || !getCurrentUser(c).Can(store.PermissionRawSQLRead)
+}
+
+// redactQueries replaces the literal values of each query's SQL text and
+// error with placeholders and drops its parameters. The quoting rules follow
+// the protocol of the database the query ran on.
+func (s *Server) redactQueries(ctx context.Context, queries []store.Query) {
+	protocols := make(map[uuid.UUID]string)
+
+	for i := range queries {
+		q := &queries[i]
+
+		databaseID := q.DatabaseID
+		if databaseID == nil {
+			if conn, err := s.store.GetConnectionByUID(ctx, q.ConnectionID); err == nil {
+				databaseID = &conn.DatabaseID
+			}
+		}
+
+		protocol := store.ProtocolPostgreSQL
+
+		if databaseID != nil {
+			p, ok := protocols[*databaseID]
+			if !ok {
+				if srv, err := s.store.GetServerByUID(ctx, *databaseID); err == nil {
+					p = srv.Protocol
+				} else {
+					s.logger.WarnContext(ctx, "failed to resolve query protocol for redaction",
+						slog.String("database_id", databaseID.String()), slog.Any("error", err))
+
+					p = store.ProtocolPostgreSQL
+				}
+
+				protocols[*databaseID] = p
+			}
+
+			protocol = p
+		}
+
+		redactQuery(q, protocol)
+	}
+}
+
+// redactQuery masks the literal values of one query.
+func redactQuery(q *store.Query, protocol string) {
+	q.SQLText = sqlnorm.Normalize(q.SQLText, protocol)
+	q.Parameters = nil
+	// The hash of the full text would let literal values be guessed.
+	q.SQLTextSHA256 = nil
+
+	if q.Error != nil {
+		// Error messages quote offending values with either quote character,
+		// which the MySQL rules both treat as strings.
+		redacted := sqlnorm.Normalize(*q.Error, store.ProtocolMySQL)
+		q.Error = &redacted
+	}
+
+	q.Redacted = true
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestRedactQuery(t *testing.T) {
+	t.Parallel()
+
+	errMsg := `invalid input syntax for type integer: "forty-two"`
+	query := &store.Query{
+		SQLText:    "INSERT INTO users (email, age) VALUES ('a@b.c', 42)",
+		Parameters: &store.QueryParameters{Values: []string{"secret"}},
+		Error:      &errMsg,
+	}
+	sum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	query.SQLTextSHA256 = &sum
+
+	redactQuery(query, store.ProtocolPostgreSQL)
+
+	if want := "INSERT INTO users (email, age) VALUES (?, ?)"; query.SQLText != want {
+		t.Errorf("SQLText = %q, want %q", query.SQLText, want)
+	}
+
+	if query.Parameters != nil {
+		t.Errorf("Parameters = %+v, want nil", query.Parameters)
+	}
+
+	if want := "invalid input syntax for type integer: ?"; *query.Error != want {
+		t.Errorf("Error = %q, want %q", *query.Error, want)
+	}
+
+	if query.SQLTextSHA256 != nil {
+		t.Errorf("SQLTextSHA256 = %q, want nil", *query.SQLTextSHA256)
+	}
+
+	if !query.Redacted {
+		t.Error("Redacted = false, want true")
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/cache"
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// ConfigReloader re-reads the configuration and applies the settings that can
+// change at runtime. It returns the names of the settings that changed, or
+// why the configuration was rejected, in which case nothing was applied.
+type ConfigReloader func(ctx context.Context) ([]string, error)
+
+// SetConfigReloader makes POST /admin/reload run reload.
+func (s *Server) SetConfigReloader(reload ConfigReloader) {
+	s.reloadConfig = reload
+}
+
+// Reconfigure applies the rate limits, auth cache and query storage limits of
+// a reloaded configuration to the API.
+func (s *Server) Reconfigure(cfg *config.Config) {
+	if s.rateLimiter != nil {
+		s.rateLimiter.Reconfigure(cfg.RateLimit)
+	}
+
+	if s.authCache != nil {
+		s.authCache.Reconfigure(cache.AuthCacheConfig{
+			Enabled:    cfg.AuthCache.Enabled,
+			TTLSeconds: cfg.AuthCache.TTLSeconds,
+			MaxSize:    cfg.AuthCache.MaxSize,
+		})
+	}
+
+	if s.queryStorage != nil {
+		s.queryStorage.Store(cfg.QueryStorage)
+	}
+}
+
+// handleReload re-reads the configuration of the instance serving the
+// request, like sending it SIGHUP. An invalid configuration is rejected and
+// the current one stays in effect.
+func (s *Server) handleReload(c *gin.Context) {
+	if s.reloadConfig == nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "configuration reload is not available")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	changed, err := s.reloadConfig(ctx)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "configuration rejected: "+err.Error())
+		return
+	}
+
+	s.logger.InfoContext(ctx, "configuration reload requested",
+		slog.String("username", getCurrentUser(c).Username),
+		slog.Any("changed", changed))
+
+	successResponse(c, gin.H{"changed": changed})
+}
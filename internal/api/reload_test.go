@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestHandleReload(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	reload := func(server *Server) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/admin/reload", http.NoBody)
+		c.Set(contextKeyUser, &store.User{Username: "admin", Roles: []string{store.RoleAdmin}})
+
+		server.handleReload(c)
+
+		return w
+	}
+
+	server := &Server{logger: slog.New(slog.DiscardHandler)}
+	assert.Equal(t, http.StatusNotFound, reload(server).Code, "without a reloader")
+
+	server.SetConfigReloader(func(context.Context) ([]string, error) {
+		return []string{"rate_limit"}, nil
+	})
+
+	w := reload(server)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"changed":["rate_limit"]`)
+
+	server.SetConfigReloader(func(context.Context) ([]string, error) {
+		return nil, errors.New("hash.algorithm: invalid value")
+	})
+
+	w = reload(server)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "hash.algorithm")
+}
+
+func TestServerReconfigure(t *testing.T) {
+	t.Parallel()
+
+	server := &Server{
+		rateLimiter:  NewRateLimiter(config.RateLimitConfig{RequestsPerMinute: 60}),
+		queryStorage: shared.NewQueryStorage(config.QueryStorageConfig{MaxResultRows: 100}),
+	}
+
+	server.Reconfigure(&config.Config{
+		RateLimit:    config.RateLimitConfig{Enabled: true, RequestsPerMinute: 10},
+		QueryStorage: config.QueryStorageConfig{MaxResultRows: 5},
+	})
+
+	assert.Equal(t, 10, server.rateLimiter.Settings().RequestsPerMinute)
+	assert.True(t, server.rateLimiter.Settings().Enabled)
+	assert.Equal(t, 5, server.queryStorage.Load().MaxResultRows)
+}
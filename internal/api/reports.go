@@ -0,0 +1,260 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Defaults of the access review parameters.
+const (
+	defaultAccessReviewDays         = 30
+	defaultAccessReviewQuotaPercent = 10
+)
+
+// AccessReviewGrant is a grant of the access review, with the path revoking
+// it (DELETE) as a shortcut.
+type AccessReviewGrant struct {
+	store.AccessReviewGrant
+	RevokePath string `json:"revoke_path"`
+}
+
+// AccessReviewResponse is the body of GET /reports/access-review.
+type AccessReviewResponse struct {
+	Days               int                 `json:"days"`
+	QuotaPercent       int                 `json:"quota_percent"`
+	Since              time.Time           `json:"since"`
+	Unused             []AccessReviewGrant `json:"unused"`
+	ReadOnlyCandidates []AccessReviewGrant `json:"read_only_candidates"`
+	UnapproachedQuotas []AccessReviewGrant `json:"unapproached_quotas"`
+}
+
+// handleGetAccessReview lists the active grants worth revoking or narrowing
+// for periodic access reviews: unused over the last days, write grants only
+// used for reads, and grants far below their quotas.
+func (s *Server) handleGetAccessReview(c *gin.Context) {
+	days, ok := positiveIntQuery(c, "days", defaultAccessReviewDays)
+	if !ok {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "days must be a positive integer")
+		return
+	}
+
+	quotaPercent, ok := positiveIntQuery(c, "quota_percent", defaultAccessReviewQuotaPercent)
+	if !ok || quotaPercent > 100 {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "quota_percent must be an integer between 1 and 100")
+		return
+	}
+
+	review, err := s.store.GetAccessReview(c.Request.Context(), store.AccessReviewFilter{
+		Since:           time.Now().AddDate(0, 0, -days),
+		QuotaUsageRatio: float64(quotaPercent) / 100,
+	})
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to build the access review")
+		return
+	}
+
+	successResponse(c, AccessReviewResponse{
+		Days:               days,
+		QuotaPercent:       quotaPercent,
+		Since:              review.Since,
+		Unused:             withRevokePaths(c, review.Unused),
+		ReadOnlyCandidates: withRevokePaths(c, review.ReadOnlyCandidates),
+		UnapproachedQuotas: withRevokePaths(c, review.UnapproachedQuotas),
+	})
+}
+
+// withRevokePaths adds their revoke path, in the request's API version, to
+// the grants.
+func withRevokePaths(c *gin.Context, grants []store.AccessReviewGrant) []AccessReviewGrant {
+	out := make([]AccessReviewGrant, len(grants))
+	for i, grant := range grants {
+		out[i] = AccessReviewGrant{
+			AccessReviewGrant: grant,
+			RevokePath:        "/api/" + getAPIVersion(c) + "/grants/" + grant.GrantUID.String(),
+		}
+	}
+
+	return out
+}
+
+// reportSignatureHeader carries the signature of a signed report body; see
+// crypto.SignReport.
+const reportSignatureHeader = "X-DBBat-Signature"
+
+// accessMatrixCSVHeader is the header row of the CSV access matrix.
+var accessMatrixCSVHeader = []string{
+	"as_of", "grant_uid", "user_uid", "username", "database_uid", "database_name", "protocol",
+	"access_level", "controls", "granted_by", "starts_at", "expires_at",
+}
+
+// AccessMatrixResponse is the JSON body of GET /reports/access-matrix.
+type AccessMatrixResponse struct {
+	At          time.Time                 `json:"at"`
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Entries     []store.AccessMatrixEntry `json:"entries"`
+	NextCursor  string                    `json:"next_cursor,omitempty"`
+}
+
+// handleGetAccessMatrix exports who had which access to which database at a
+// point in time, as JSON or CSV, for access attestations. The body is signed
+// (X-DBBat-Signature) so the evidence can be checked later with
+// POST /reports/verify. Pages follow the grant UIDs: next_cursor, also sent
+// as X-Next-Cursor, is the after parameter of the next page.
+func (s *Server) handleGetAccessMatrix(c *gin.Context) {
+	now := time.Now().UTC()
+	filter := store.AccessMatrixFilter{At: now}
+
+	if raw := c.Query("at"); raw != "" {
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "at must be an RFC 3339 timestamp")
+			return
+		}
+
+		filter.At = at.UTC()
+	}
+
+	if raw := c.Query("after"); raw != "" {
+		after, err := uuid.Parse(raw)
+		if err != nil {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid after cursor")
+			return
+		}
+
+		filter.AfterUID = &after
+	}
+
+	limit, ok := positiveIntQuery(c, "limit", store.DefaultAccessMatrixLimit)
+	if !ok || limit > store.MaxAccessMatrixLimit {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+			"limit must be an integer between 1 and "+strconv.Itoa(store.MaxAccessMatrixLimit))
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "format must be json or csv")
+		return
+	}
+
+	// One more entry than the page tells whether another page follows.
+	filter.Limit = limit + 1
+
+	entries, err := s.store.ListAccessMatrix(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to build the access matrix")
+		return
+	}
+
+	resp := AccessMatrixResponse{At: filter.At, GeneratedAt: now, Entries: entries}
+	if len(entries) > limit {
+		resp.Entries = entries[:limit]
+		resp.NextCursor = resp.Entries[limit-1].GrantUID.String()
+		c.Header("X-Next-Cursor", resp.NextCursor)
+	}
+
+	var (
+		body        []byte
+		contentType string
+	)
+
+	if format == "csv" {
+		body, err = accessMatrixCSV(resp)
+		contentType = "text/csv; charset=utf-8"
+
+		c.Header("Content-Disposition", `attachment; filename="access-matrix-`+filter.At.Format("20060102T150405Z")+`.csv"`)
+	} else {
+		body, err = json.Marshal(resp)
+		contentType = "application/json; charset=utf-8"
+	}
+
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to encode the access matrix")
+		return
+	}
+
+	c.Header(reportSignatureHeader, crypto.SignReport(s.encryptionKey, body))
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// accessMatrixCSV renders a page of the access matrix as CSV, one row per
+// grant. Every row carries the matrix's point in time so the file stands on
+// its own.
+func accessMatrixCSV(resp AccessMatrixResponse) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	if err := w.Write(accessMatrixCSVHeader); err != nil {
+		return nil, err
+	}
+
+	asOf := resp.At.Format(time.RFC3339)
+
+	for _, e := range resp.Entries {
+		if err := w.Write([]string{
+			asOf, e.GrantUID.String(), e.UserUID.String(), e.Username, e.DatabaseUID.String(), e.DatabaseName, e.Protocol,
+			e.AccessLevel, strings.Join(e.Controls, " "), e.GrantedBy,
+			e.StartsAt.UTC().Format(time.RFC3339), e.ExpiresAt.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+// maxVerifiedReportSize bounds the report bodies POST /reports/verify reads.
+const maxVerifiedReportSize = 64 << 20
+
+// handleVerifyReport checks that the request body is a report exported by
+// this instance, unchanged, given its X-DBBat-Signature.
+func (s *Server) handleVerifyReport(c *gin.Context) {
+	signature := c.GetHeader(reportSignatureHeader)
+	if signature == "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, reportSignatureHeader+" header is required")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxVerifiedReportSize+1))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "failed to read the report")
+		return
+	}
+
+	if len(body) > maxVerifiedReportSize {
+		writeError(c, http.StatusRequestEntityTooLarge, ErrCodeValidationError, "report too large")
+		return
+	}
+
+	successResponse(c, gin.H{"valid": crypto.VerifyReport(s.encryptionKey, body, signature)})
+}
+
+// positiveIntQuery parses an optional positive integer query parameter. ok
+// is false when it is set to anything else.
+func positiveIntQuery(c *gin.Context, name string, fallback int) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return fallback, true
+	}
+
+	val, err := strconv.Atoi(raw)
+	if err != nil || val <= 0 {
+		return 0, false
+	}
+
+	return val, true
+}
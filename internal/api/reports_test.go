@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestHandleGetAccessReviewInvalidParameters(t *testing.T) {
+	t.Parallel()
+
+	server := &Server{}
+
+	for _, query := range []string{"days=0", "days=abc", "quota_percent=0", "quota_percent=101", "quota_percent=-1"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/reports/access-review?"+query, nil)
+
+		server.handleGetAccessReview(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestHandleGetAccessMatrixInvalidParameters(t *testing.T) {
+	t.Parallel()
+
+	server := &Server{}
+
+	for _, query := range []string{"at=yesterday", "after=nope", "limit=0", "limit=10001", "format=xml"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/reports/access-matrix?"+query, nil)
+
+		server.handleGetAccessMatrix(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestAccessMatrixCSV(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	body, err := accessMatrixCSV(AccessMatrixResponse{
+		At: at,
+		Entries: []store.AccessMatrixEntry{{
+			GrantUID:     uuid.New(),
+			Username:     "alice",
+			DatabaseName: "orders, prod",
+			AccessLevel:  store.AccessLevelReadOnly,
+			Controls:     []string{store.ControlReadOnly, store.ControlBlockDDL},
+			StartsAt:     at.Add(-time.Hour),
+			ExpiresAt:    at.Add(time.Hour),
+		}},
+	})
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, accessMatrixCSVHeader, rows[0])
+	assert.Equal(t, "2026-10-01T12:00:00Z", rows[1][0])
+	assert.Equal(t, "orders, prod", rows[1][5])
+	assert.Equal(t, "read_only block_ddl", rows[1][8])
+}
+
+func TestHandleVerifyReport(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{7}, 32)
+	server := &Server{encryptionKey: key}
+	report := []byte("as_of,grant_uid\n")
+
+	verify := func(body []byte, signature string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/reports/verify", bytes.NewReader(body))
+		if signature != "" {
+			c.Request.Header.Set(reportSignatureHeader, signature)
+		}
+
+		server.handleVerifyReport(c)
+
+		return w
+	}
+
+	w := verify(report, crypto.SignReport(key, report))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"valid":true}`, w.Body.String())
+
+	w = verify(append(report, 'x'), crypto.SignReport(key, report))
+	assert.JSONEq(t, `{"valid":false}`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, verify(report, "").Code)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// scopeResources maps the first segment of the authenticated API routes to
+// the API key scope resource covering them (see store.APIKeyScopeResources).
+// Scoped keys are refused the routes of any other segment.
+var scopeResources = map[string]string{
+	"users":               "users",
+	"user-groups":         "users",
+	"rate-limits":         "users",
+	"preferences":         "users",
+	"servers":             "databases",
+	"ssh-servers":         "databases",
+	"grants":              "grants",
+	"grant-definitions":   "grants",
+	"grant-templates":     "grants",
+	"grant-requests":      "grants",
+	"statement-approvals": "grants",
+	"keys":                "keys",
+	"connections":         "connections",
+	"queries":             "queries",
+	"query-alerts":        "queries",
+	"audit":               "audit",
+	"reports":             "audit",
+	"parameters":          "settings",
+	"instance":            "settings",
+	"admin":               "settings",
+	"search":              "search",
+}
+
+// readOnlyRoutes are the routes that only read despite their method, keyed by
+// method and path below the API version.
+var readOnlyRoutes = map[string]bool{
+	http.MethodPost + " /reports/verify": true,
+}
+
+// routeScope returns the scope an API key needs to call a route, given its
+// method and full path ("/api/v1/grants/:uid"). The /auth routes need none,
+// so that any key can tell whose it is. ok is false when no scope covers the
+// route.
+func routeScope(method, fullPath string) (scope string, ok bool) {
+	rest, found := strings.CutPrefix(fullPath, "/api/")
+	if !found {
+		return "", false
+	}
+
+	// Drop the version segment.
+	if _, rest, found = strings.Cut(rest, "/"); !found {
+		return "", false
+	}
+
+	segment, _, _ := strings.Cut(rest, "/")
+	if segment == "auth" {
+		return "", true
+	}
+
+	resource, found := scopeResources[segment]
+	if !found {
+		return "", false
+	}
+
+	action := store.ScopeActionWrite
+	if method == http.MethodGet || method == http.MethodHead || readOnlyRoutes[method+" /"+rest] {
+		action = store.ScopeActionRead
+	}
+
+	return action + ":" + resource, true
+}
+
+// checkAPIKeyScope refuses the request when the scopes of the API key it
+// authenticated with don't cover the route. It reports whether the request
+// may go on.
+func checkAPIKeyScope(c *gin.Context, apiKey *store.APIKey) bool {
+	if !apiKey.IsScoped() {
+		return true
+	}
+
+	scope, ok := routeScope(c.Request.Method, c.FullPath())
+	if ok && (scope == "" || apiKey.HasScope(scope)) {
+		return true
+	}
+
+	msg := "API key scopes do not cover this route"
+	if ok {
+		msg = "API key lacks the " + scope + " scope"
+	}
+
+	writeError(c, http.StatusForbidden, ErrCodeForbidden, msg)
+	c.Abort()
+
+	return false
+}
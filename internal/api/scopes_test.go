@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestRouteScope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		method    string
+		fullPath  string
+		wantScope string
+		wantOK    bool
+	}{
+		{http.MethodGet, "/api/v1/queries/:uid", "read:queries", true},
+		{http.MethodGet, "/api/v1/query-alerts", "read:queries", true},
+		{http.MethodPost, "/api/v1/grants", "write:grants", true},
+		{http.MethodPost, "/api/v1/grants/:uid/proxy-credentials", "write:grants", true},
+		{http.MethodDelete, "/api/v1/connections/:uid", "write:connections", true},
+		{http.MethodPut, "/api/v1/ssh-servers/:uid", "write:databases", true},
+		{http.MethodPost, "/api/v1/reports/verify", "read:audit", true},
+		{http.MethodPost, "/api/v1/admin/drain", "write:settings", true},
+		{http.MethodGet, "/api/v2/search", "read:search", true},
+		{http.MethodGet, "/api/v1/auth/me", "", true},
+		{http.MethodGet, "/api/v1/unknown", "", false},
+		{http.MethodGet, "/api/docs", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.fullPath, func(t *testing.T) {
+			t.Parallel()
+
+			scope, ok := routeScope(tt.method, tt.fullPath)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantScope, scope)
+		})
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	ctx := context.Background()
+
+	admin := createTestUser(t, dataStore, "admin-scopes", "adminpass123", []string{store.RoleAdmin})
+
+	_, readQueries, err := dataStore.CreateScopedAPIKey(ctx, admin.UID, "audit bot", nil, []string{"read:queries"})
+	require.NoError(t, err)
+
+	_, unscoped, err := dataStore.CreateAPIKey(ctx, admin.UID, "everything", nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.GET("/api/v1/auth/me", server.handleMe)
+	router.GET("/api/v1/queries", server.requirePermission(store.PermissionQueriesRead), server.handleListQueries)
+	router.GET("/api/v1/users", server.handleListUsers)
+	router.GET("/api/v1/keys", server.handleListAPIKeys)
+
+	w, _ := doJSON(t, router, http.MethodGet, "/api/v1/queries", readQueries, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w, _ = doJSON(t, router, http.MethodGet, "/api/v1/auth/me", readQueries, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Admin or not, the key can't go beyond its scopes.
+	w, _ = doJSON(t, router, http.MethodGet, "/api/v1/users", readQueries, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "read:users")
+
+	w, _ = doJSON(t, router, http.MethodGet, "/api/v1/users", unscoped, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w, resp := doJSON(t, router, http.MethodGet, "/api/v1/keys", unscoped, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	scopes := map[string]any{}
+	for _, k := range resp["keys"].([]any) {
+		key := k.(map[string]any)
+		scopes[key["name"].(string)] = key["scopes"]
+	}
+
+	assert.Equal(t, []any{"read:queries"}, scopes["audit bot"])
+	assert.Nil(t, scopes["everything"])
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Search limits.
+const (
+	searchMinTermLength = 2
+	searchDefaultLimit  = 10
+	searchMaxLimit      = 50
+)
+
+// SearchResponse groups search matches by entity. Every key is always
+// present; entities the caller may not see come back empty.
+type SearchResponse struct {
+	Users       []store.User       `json:"users"`
+	Databases   any                `json:"databases"`
+	Grants      []store.Grant      `json:"grants"`
+	Connections []store.Connection `json:"connections"`
+	Queries     []store.Query      `json:"queries"`
+}
+
+// handleSearch searches users, databases, grants, connections and queries in
+// one call, for the UI's global search box. Results follow the same role
+// rules as the individual list endpoints: connectors only see themselves,
+// their own grants and connections, listable databases (limited view), and no
+// queries.
+func (s *Server) handleSearch(c *gin.Context) {
+	term := strings.TrimSpace(c.Query("q"))
+	if len(term) < searchMinTermLength {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "search term must be at least 2 characters")
+		return
+	}
+
+	limit := searchDefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		if val, err := strconv.Atoi(raw); err == nil && val > 0 {
+			limit = min(val, searchMaxLimit)
+		}
+	}
+
+	ctx := c.Request.Context()
+	currentUser := getCurrentUser(c)
+	privileged := currentUser.Can(store.PermissionQueriesRead)
+
+	filter := store.SearchFilter{Term: term, Limit: limit}
+	if !privileged {
+		filter.UserID = &currentUser.UID
+	}
+
+	var (
+		response SearchResponse
+		err      error
+	)
+
+	if response.Users, err = s.store.SearchUsers(ctx, filter); err != nil {
+		writeInternalError(c, s.logger, err, "failed to search users")
+		return
+	}
+
+	if response.Databases, err = s.searchDatabases(c, filter); err != nil {
+		writeInternalError(c, s.logger, err, "failed to search databases")
+		return
+	}
+
+	if response.Grants, err = s.store.SearchGrants(ctx, filter); err != nil {
+		writeInternalError(c, s.logger, err, "failed to search grants")
+		return
+	}
+
+	if response.Connections, err = s.store.SearchConnections(ctx, filter); err != nil {
+		writeInternalError(c, s.logger, err, "failed to search connections")
+		return
+	}
+
+	// Queries: admin/viewer only, like GET /queries. Matching the raw SQL
+	// text would let users without sql:raw probe literal values, so they get
+	// no query matches.
+	response.Queries = []store.Query{}
+	if privileged && currentUser.Can(store.PermissionRawSQLRead) {
+		if response.Queries, err = s.store.SearchQueries(ctx, filter); err != nil {
+			writeInternalError(c, s.logger, err, "failed to search queries")
+			return
+		}
+
+		if c.Query("redact") == "true" {
+			s.redactQueries(ctx, response.Queries)
+		}
+	}
+
+	successResponse(c, response)
+}
+
+// searchDatabases returns full database details for admins and the limited
+// view of listable databases for everyone else, like GET /servers.
+func (s *Server) searchDatabases(c *gin.Context, filter store.SearchFilter) (any, error) {
+	// Database visibility does not depend on grants: drop the user scope.
+	filter.UserID = nil
+
+	if getCurrentUser(c).IsAdmin() {
+		databases, err := s.store.SearchServers(c.Request.Context(), filter)
+		if err != nil {
+			return nil, err
+		}
+
+		response := make([]DatabaseResponse, len(databases))
+		for i, db := range databases {
+			response[i] = toDatabaseResponse(&db)
+		}
+
+		return response, nil
+	}
+
+	filter.ListableOnly = true
+
+	databases, err := s.store.SearchServers(c.Request.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]DatabaseLimitedResponse, len(databases))
+	for i, db := range databases {
+		response[i] = toDatabaseLimitedResponse(&db)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// searchNames extracts the given field from each entry of a search result
+// group.
+func searchNames(t *testing.T, resp map[string]any, group, field string) []string {
+	t.Helper()
+
+	entries, ok := resp[group].([]any)
+	require.True(t, ok, "%s must be a list", group)
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if name, ok := entry.(map[string]any)[field].(string); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func TestSearch_RoleFiltering(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	ctx := context.Background()
+	suffix := "srch"
+
+	admin := createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin, store.RoleConnector})
+	connector := createTestUser(t, dataStore, "conn-"+suffix, "connpass123", []string{store.RoleConnector})
+	other := createTestUser(t, dataStore, "other-"+suffix, "otherpass123", []string{store.RoleConnector})
+
+	visible := createTestDBEntry(t, dataStore, "visible-"+suffix, true)
+	createTestDBEntry(t, dataStore, "hidden-"+suffix, false)
+
+	for _, user := range []*store.User{connector, other} {
+		_, err := dataStore.CreateGrant(ctx, &store.Grant{
+			UserID:     user.UID,
+			DatabaseID: visible.UID,
+			GrantedBy:  admin.UID,
+			StartsAt:   time.Now().Add(-time.Hour),
+			ExpiresAt:  time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.GET("/api/v1/search", server.handleSearch)
+
+	search := func(token, term string) map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q="+term, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+		return resp
+	}
+
+	t.Run("admin sees everything", func(t *testing.T) {
+		resp := search(loginUser(t, server, "admin-"+suffix, "adminpass123"), suffix)
+
+		users := searchNames(t, resp, "users", "username")
+		assert.Contains(t, users, "conn-"+suffix)
+		assert.Contains(t, users, "other-"+suffix)
+
+		databases := searchNames(t, resp, "databases", "name")
+		assert.Contains(t, databases, "visible-"+suffix)
+		assert.Contains(t, databases, "hidden-"+suffix)
+
+		assert.Len(t, resp["grants"], 2)
+	})
+
+	t.Run("connector sees only own data", func(t *testing.T) {
+		resp := search(loginUser(t, server, "conn-"+suffix, "connpass123"), suffix)
+
+		assert.Equal(t, []string{"conn-" + suffix}, searchNames(t, resp, "users", "username"))
+
+		databases := searchNames(t, resp, "databases", "name")
+		assert.Contains(t, databases, "visible-"+suffix)
+		assert.NotContains(t, databases, "hidden-"+suffix)
+
+		grants, ok := resp["grants"].([]any)
+		require.True(t, ok)
+		require.Len(t, grants, 1)
+		assert.Equal(t, connector.UID.String(), grants[0].(map[string]any)["user_id"])
+
+		assert.Empty(t, resp["queries"])
+	})
+
+	t.Run("short term is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=a", nil)
+		req.Header.Set("Authorization", "Bearer "+loginUser(t, server, "admin-"+suffix, "adminpass123"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
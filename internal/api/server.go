@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"embed"
+	"io"
 	"io/fs"
 	"log/slog"
 	"net/http"
@@ -15,10 +17,14 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/fclairamb/dbbat/internal/auth"
+	"github.com/fclairamb/dbbat/internal/auth/ldap"
+	"github.com/fclairamb/dbbat/internal/auth/oidc"
 	"github.com/fclairamb/dbbat/internal/auth/slack"
 	"github.com/fclairamb/dbbat/internal/cache"
 	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/notify"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/selfobs"
 	"github.com/fclairamb/dbbat/internal/store"
 	"github.com/fclairamb/dbbat/internal/version"
 )
@@ -40,21 +46,45 @@ type Server struct {
 	authCache          *cache.AuthCache
 	config             *config.Config
 	oauthProviders     map[string]auth.OAuthProvider
+	// directory authenticates LDAP users; nil unless LDAP is configured.
+	directory *ldap.Directory
 	// notifier is the outbound Slack client; nil when notifications are
 	// disabled (no bot token configured).
 	notifier *notify.SlackNotifier
 	// socketCancel stops the Slack Socket Mode connection on shutdown; nil
 	// when Socket Mode is not running.
 	socketCancel context.CancelFunc
+	// accessLogger receives one entry per API request; it is the application
+	// logger unless a dedicated access log output is configured.
+	accessLogger *slog.Logger
+	// accessLogCloser closes the access log file on shutdown; nil unless the
+	// access log is written to a file.
+	accessLogCloser io.Closer
+	// nonceCache rejects replayed signed requests.
+	nonceCache *nonceCache
+	// apiMetrics counts requests per route for the SLO report.
+	apiMetrics *apiMetrics
+	// storageQueries records DBBat's own storage queries; nil unless self
+	// observability is enabled.
+	storageQueries *selfobs.Recorder
+	// queryStorage holds the query storage limits reported by the storage
+	// report, replaced on reload; nil without a configuration.
+	queryStorage *shared.QueryStorage
+	// reloadConfig re-reads the configuration for POST /admin/reload; nil
+	// until SetConfigReloader.
+	reloadConfig ConfigReloader
 }
 
 // NewServer creates a new API server.
 func NewServer(dataStore *store.Store, encryptionKey []byte, logger *slog.Logger, cfg *config.Config) *Server {
 	var rateLimiter *RateLimiter
 	var authCache *cache.AuthCache
+	var queryStorage *shared.QueryStorage
 
 	if cfg != nil {
+		queryStorage = shared.NewQueryStorage(cfg.QueryStorage)
 		rateLimiter = NewRateLimiter(cfg.RateLimit)
+		rateLimiter.SetRoleLimitSource(dataStore.GetRoleRateLimits)
 		authCache = cache.NewAuthCache(cache.AuthCacheConfig{
 			Enabled:    cfg.AuthCache.Enabled,
 			TTLSeconds: cfg.AuthCache.TTLSeconds,
@@ -76,6 +106,29 @@ func NewServer(dataStore *store.Store, encryptionKey []byte, logger *slog.Logger
 		logger.InfoContext(context.Background(), "Slack OAuth provider enabled")
 	}
 
+	if cfg != nil && cfg.OIDC.Enabled() {
+		oauthProviders[store.IdentityTypeOIDC] = oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			Scopes:       cfg.OIDC.ExtraScopes(),
+			RolesClaim:   cfg.OIDC.RolesClaim,
+		})
+		logger.InfoContext(context.Background(), "OIDC provider enabled", slog.String("issuer", cfg.OIDC.Issuer))
+	}
+
+	var directory *ldap.Directory
+	if cfg != nil && cfg.LDAP.Enabled() {
+		var err error
+		if directory, err = ldap.New(cfg.LDAP); err != nil {
+			// Checked at startup already; directory users cannot sign in.
+			logger.ErrorContext(context.Background(), "LDAP directory disabled", slog.Any("error", err))
+		} else {
+			authCache.SetDirectory(directory)
+			logger.InfoContext(context.Background(), "LDAP directory enabled", slog.String("url", cfg.LDAP.URL))
+		}
+	}
+
 	// Initialize Slack notifier (outbound only — distinct from OAuth above)
 	var notifier *notify.SlackNotifier
 	if cfg != nil {
@@ -93,6 +146,41 @@ func NewServer(dataStore *store.Store, encryptionKey []byte, logger *slog.Logger
 		}
 	}
 
+	accessLogger := logger
+
+	var accessLogCloser io.Closer
+
+	if cfg != nil {
+		var err error
+
+		accessLogger, accessLogCloser, err = newAccessLogger(cfg.AccessLog, logger)
+		if err != nil {
+			// Fall back to the application log rather than losing the entries.
+			logger.ErrorContext(context.Background(), "access log misconfigured", slog.Any("error", err))
+
+			accessLogger = logger
+		}
+	}
+
+	// Load already validated the skew; the default covers servers built
+	// without a configuration (tests).
+	signatureSkew := 5 * time.Minute
+	if cfg != nil {
+		if skew, err := cfg.APISignatureSkew(); err == nil && skew > 0 {
+			signatureSkew = skew
+		}
+	}
+
+	// Record the storage queries from now on; the storage database itself
+	// stays off limits to the proxies.
+	var storageQueries *selfobs.Recorder
+	if cfg != nil && cfg.SelfObservability.Enabled {
+		storageQueries = selfobs.NewRecorder(cfg.SelfObservability.MaxQueries)
+		dataStore.AddQueryHook(storageQueries)
+		logger.InfoContext(context.Background(), "storage query recording enabled",
+			slog.Int("max_queries", cfg.SelfObservability.MaxQueries))
+	}
+
 	return &Server{
 		store:              dataStore,
 		encryptionKey:      encryptionKey,
@@ -100,9 +188,16 @@ func NewServer(dataStore *store.Store, encryptionKey []byte, logger *slog.Logger
 		rateLimiter:        rateLimiter,
 		authFailureTracker: newAuthFailureTracker(),
 		authCache:          authCache,
+		directory:          directory,
 		config:             cfg,
 		oauthProviders:     oauthProviders,
 		notifier:           notifier,
+		accessLogger:       accessLogger,
+		accessLogCloser:    accessLogCloser,
+		nonceCache:         newNonceCache(signatureSkew),
+		apiMetrics:         newAPIMetrics(),
+		storageQueries:     storageQueries,
+		queryStorage:       queryStorage,
 	}
 }
 
@@ -143,11 +238,18 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		s.socketCancel()
 	}
 
+	var err error
 	if s.httpServer != nil {
-		return s.httpServer.Shutdown(ctx)
+		err = s.httpServer.Shutdown(ctx)
 	}
 
-	return nil
+	if s.accessLogCloser != nil {
+		if closeErr := s.accessLogCloser.Close(); closeErr != nil {
+			s.logger.ErrorContext(ctx, "failed to close access log", slog.Any("error", closeErr))
+		}
+	}
+
+	return err
 }
 
 // setupRouter configures the Gin router.
@@ -160,9 +262,21 @@ func (s *Server) setupRouter() *gin.Engine {
 	// Disable automatic trailing slash redirect to avoid loops with SPA routing
 	router.RedirectTrailingSlash = false
 
+	// Only honor X-Forwarded-For / X-Real-IP from configured reverse proxies.
+	// gin's default trusts every peer, which lets any client pick the IP that
+	// rate limiting and the audit log attribute its requests to.
+	if err := router.SetTrustedProxies(s.trustedProxies()); err != nil {
+		s.logger.ErrorContext(context.Background(), "invalid trusted proxies, trusting none", slog.Any("error", err))
+		_ = router.SetTrustedProxies(nil)
+	}
+
 	// Middleware
+	// Outside Recovery, so a handler panic is counted as the 500 it becomes.
+	router.Use(s.apiMetrics.middleware())
 	router.Use(gin.Recovery())
+	router.Use(requestIDMiddleware())
 	router.Use(s.loggingMiddleware())
+	router.Use(s.sourceIPMiddleware())
 
 	// Documentation endpoints (not versioned)
 	api := router.Group("/api")
@@ -173,7 +287,7 @@ func (s *Server) setupRouter() *gin.Engine {
 	}
 
 	// Versioned API endpoints
-	v1 := router.Group("/api/v1")
+	v1, authenticated := s.mountAPIVersion(router, apiV1)
 	{
 		// Health check and version info (unauthenticated)
 		v1.GET("/health", s.handleHealth)
@@ -181,14 +295,14 @@ func (s *Server) setupRouter() *gin.Engine {
 
 		// Auth endpoints (login and pre-login password change are unauthenticated)
 		auth := v1.Group("/auth")
-		auth.POST("/login", s.handleLogin)
+		auth.POST("/login", s.refuseWhileDraining(), s.handleLogin)
 		auth.PUT("/password", s.handlePreLoginPasswordChange)
 		auth.GET("/providers", s.handleAuthProviders)
 
 		// OAuth provider routes (unauthenticated)
 		for name := range s.oauthProviders {
-			auth.GET("/"+name, s.handleOAuthAuthorize(name))
-			auth.GET("/"+name+"/callback", s.handleOAuthCallback(name))
+			auth.GET("/"+name, s.refuseWhileDraining(), s.handleOAuthAuthorize(name))
+			auth.GET("/"+name+"/callback", s.refuseWhileDraining(), s.handleOAuthCallback(name))
 		}
 
 		// OAuth 2.0 Device Authorization Grant (RFC 8628): lets external
@@ -198,9 +312,9 @@ func (s *Server) setupRouter() *gin.Engine {
 		// endpoints; the token (poll) endpoint deliberately is not — see
 		// handleDeviceToken for why.
 		if s.rateLimiter != nil {
-			auth.POST("/device", s.rateLimiter.PreAuthMiddleware(), s.handleDeviceAuthorization)
+			auth.POST("/device", s.refuseWhileDraining(), s.rateLimiter.PreAuthMiddleware(), s.handleDeviceAuthorization)
 		} else {
-			auth.POST("/device", s.handleDeviceAuthorization)
+			auth.POST("/device", s.refuseWhileDraining(), s.handleDeviceAuthorization)
 		}
 		auth.POST("/device/token", s.handleDeviceToken)
 
@@ -214,16 +328,16 @@ func (s *Server) setupRouter() *gin.Engine {
 			v1.POST("/slack/interactions", s.handleSlackInteraction)
 		}
 
+		// Spilled result download for the file target (unauthenticated — the
+		// URL signature handed out by /queries/:uid/result-url is the
+		// authorization)
+		v1.GET("/query-results/:uid", s.handleDownloadQueryResult)
+
 		// Password change endpoint uses credential auth from body (not Bearer token)
 		v1.PUT("/users/:uid/password", s.handleChangePassword)
 
-		// All other routes require authentication
-		authenticated := v1.Group("")
-		authenticated.Use(s.authMiddleware())
-		// Add rate limiting after authentication (uses user ID for rate limiting)
-		if s.rateLimiter != nil {
-			authenticated.Use(s.rateLimiter.PostAuthMiddleware())
-		}
+		// All other routes require authentication (and are rate limited per
+		// user); see mountAPIVersion.
 		// Note: requirePasswordChanged middleware removed - users cannot login without
 		// changing their password first (enforced at login time, not here)
 		{
@@ -248,6 +362,16 @@ func (s *Server) setupRouter() *gin.Engine {
 			users.DELETE("/:uid", s.requireAdmin(), s.handleDeleteUser)
 			// Admin password reset (requires web session, not API key)
 			users.POST("/:uid/reset-password", s.requireAdmin(), s.handleResetPassword)
+			// API rate limit: users read their own, admins override anyone's
+			users.GET("/:uid/rate-limit", s.handleGetUserRateLimit)
+			users.PUT("/:uid/rate-limit", s.requireAdmin(), s.handleSetUserRateLimit)
+			users.DELETE("/:uid/rate-limit", s.requireAdmin(), s.handleDeleteUserRateLimit)
+
+			// API rate limit defaults and per-role overrides (admin-only)
+			rateLimits := authenticated.Group("/rate-limits")
+			rateLimits.GET("", s.requireAdmin(), s.handleGetRateLimits)
+			rateLimits.PUT("/roles/:role", s.requireAdmin(), s.handleSetRoleRateLimit)
+			rateLimits.DELETE("/roles/:role", s.requireAdmin(), s.handleDeleteRoleRateLimit)
 
 			// User group endpoints — organizational groupings used to scope
 			// grant definitions. Admin-only: membership is access-relevant.
@@ -270,10 +394,17 @@ func (s *Server) setupRouter() *gin.Engine {
 			databases.GET("/:uid", s.handleGetDatabase)
 			databases.PUT("/:uid", s.requireAdmin(), s.handleUpdateDatabase)
 			databases.DELETE("/:uid", s.requireAdmin(), s.handleDeleteDatabase)
+			databases.POST("/:uid/clone", s.requireAdmin(), s.handleCloneDatabase)
 			databases.GET("/:uid/connection", s.handleGetDatabaseConnection)
 			// Provisioning-time connectivity validation (admin): dial the row for
 			// real rather than trusting that it was typed correctly.
 			databases.POST("/:uid/test", s.requireAdmin(), s.handleTestServerConnection)
+			// The same check on a configuration not saved yet (admin)
+			databases.POST("/test", s.requireAdmin(), s.handleTestDatabaseConnection)
+			// Latest check, from the background health checks or /test (admin)
+			databases.GET("/:uid/health", s.requireAdmin(), s.handleGetServerHealth)
+			// What the stored service account can do upstream (admin)
+			databases.POST("/:uid/check-privileges", s.requireAdmin(), s.handleCheckServerPrivileges)
 
 			// SSH bastion management (admin). Kept on a separate path because a
 			// static /servers/ssh segment would conflict with /servers/:uid.
@@ -286,6 +417,11 @@ func (s *Server) setupRouter() *gin.Engine {
 			grants.GET("", s.handleListGrants)
 			grants.GET("/:uid", s.handleGetGrant)
 			grants.DELETE("/:uid", s.requireAdmin(), s.handleRevokeGrant)
+			grants.PUT("/:uid/labels", s.requireAdmin(), s.handleSetGrantLabels)
+			grants.POST("/:uid/extension-requests", s.handleRequestGrantExtension)
+			grants.POST("/:uid/proxy-credentials", s.requireAdmin(), s.handleCreateProxyCredential)
+			grants.GET("/:uid/proxy-credentials", s.requireAdmin(), s.handleListProxyCredentials)
+			grants.DELETE("/:uid/proxy-credentials/:credential_uid", s.requireAdmin(), s.handleRevokeProxyCredential)
 
 			// Grant definition endpoints — admin-managed templates that
 			// bound the shapes a user is allowed to request via the grant
@@ -298,6 +434,16 @@ func (s *Server) setupRouter() *gin.Engine {
 			grantDefs.PATCH("/:uid", s.requireAdmin(), s.handleUpdateGrantDefinition)
 			grantDefs.DELETE("/:uid", s.requireAdmin(), s.handleDeactivateGrantDefinition)
 
+			// Grant template endpoints — admin-only grant shapes applied to
+			// many users and databases at once. Users never see them.
+			grantTemplates := authenticated.Group("/grant-templates")
+			grantTemplates.POST("", s.requireAdmin(), s.handleCreateGrantTemplate)
+			grantTemplates.GET("", s.requireAdmin(), s.handleListGrantTemplates)
+			grantTemplates.GET("/:uid", s.requireAdmin(), s.handleGetGrantTemplate)
+			grantTemplates.PATCH("/:uid", s.requireAdmin(), s.handleUpdateGrantTemplate)
+			grantTemplates.DELETE("/:uid", s.requireAdmin(), s.handleDeleteGrantTemplate)
+			grantTemplates.POST("/:uid/grants", s.requireAdmin(), s.handleApplyGrantTemplate)
+
 			// Grant request endpoints — user self-service workflow.
 			// Approve/deny require admin; cancel is open to the requester
 			// (handler enforces ownership).
@@ -309,6 +455,15 @@ func (s *Server) setupRouter() *gin.Engine {
 			grantReqs.POST("/:uid/deny", s.requireAdmin(), s.handleDenyGrantRequest)
 			grantReqs.POST("/:uid/cancel", s.handleCancelGrantRequest)
 
+			// Statement approval endpoints — write statements the proxy holds
+			// under the write_requires_approval control. Users list their
+			// own; approve/deny require admin.
+			stmtApprovals := authenticated.Group("/statement-approvals")
+			stmtApprovals.GET("", s.handleListStatementApprovals)
+			stmtApprovals.GET("/:uid", s.handleGetStatementApproval)
+			stmtApprovals.POST("/:uid/approve", s.requireAdmin(), s.handleApproveStatementApproval)
+			stmtApprovals.POST("/:uid/deny", s.requireAdmin(), s.handleDenyStatementApproval)
+
 			// API Key endpoints
 			keys := authenticated.Group("/keys")
 			// Create and revoke require Web Session or Basic Auth (API keys cannot manage API keys)
@@ -318,17 +473,35 @@ func (s *Server) setupRouter() *gin.Engine {
 			keys.DELETE("/:id", s.requireWebSessionOrBasicAuth(), s.handleRevokeAPIKey)
 
 			// Observability endpoints
-			// Connections: admin/viewer see all, connector sees own only (filtered in handler)
+			// Connections: admin/viewer/auditor see all, connector sees own only (filtered in handler)
 			authenticated.GET("/connections", s.handleListConnections)
 			authenticated.GET("/connections/:uid", s.handleGetConnection)
-			authenticated.GET("/connections/:uid/dump", s.requireAdminOrViewer(), s.handleGetConnectionDump)
+			authenticated.DELETE("/connections/:uid", s.requireAdmin(), s.handleTerminateConnection)
+			// Dumps hold result data: rows:read (admin/viewer, not auditor)
+			authenticated.GET("/connections/:uid/dump", s.requirePermission(store.PermissionRowsRead), s.handleGetConnectionDump)
 			authenticated.DELETE("/connections/:uid/dump", s.requireAdmin(), s.handleDeleteConnectionDump)
-			// Queries: admin/viewer only
-			authenticated.GET("/queries", s.requireAdminOrViewer(), s.handleListQueries)
-			authenticated.GET("/queries/:uid", s.requireAdminOrViewer(), s.handleGetQuery)
-			authenticated.GET("/queries/:uid/rows", s.requireAdminOrViewer(), s.handleGetQueryRows)
-			// Audit: admin/viewer only
-			authenticated.GET("/audit", s.requireAdminOrViewer(), s.handleListAudit)
+			// Queries: statements need queries:read, captured rows rows:read
+			authenticated.GET("/queries", s.requirePermission(store.PermissionQueriesRead), s.handleListQueries)
+			authenticated.GET("/queries/stream", s.requirePermission(store.PermissionQueriesRead), s.handleStreamQueries)
+			authenticated.GET("/queries/export", s.requirePermission(store.PermissionQueriesRead), s.handleExportQueries)
+			authenticated.GET("/queries/stats", s.requirePermission(store.PermissionQueriesRead), s.handleGetQueryStats)
+			authenticated.GET("/queries/:uid", s.requirePermission(store.PermissionQueriesRead), s.handleGetQuery)
+			authenticated.GET("/queries/:uid/rows", s.requirePermission(store.PermissionRowsRead), s.handleGetQueryRows)
+			// Result spilled past the query storage limits: a signed download URL
+			authenticated.GET("/queries/:uid/result-url", s.requirePermission(store.PermissionRowsRead), s.handleGetQueryResultURL)
+			authenticated.GET("/queries/:uid/plan", s.requirePermission(store.PermissionQueriesRead), s.handleGetQueryPlan)
+
+			// Query alerts: saved query log filters, admin-only
+			queryAlerts := authenticated.Group("/query-alerts")
+			queryAlerts.POST("", s.requireAdmin(), s.handleCreateQueryAlert)
+			queryAlerts.GET("", s.requireAdmin(), s.handleListQueryAlerts)
+			queryAlerts.GET("/:uid", s.requireAdmin(), s.handleGetQueryAlert)
+			queryAlerts.PATCH("/:uid", s.requireAdmin(), s.handleUpdateQueryAlert)
+			queryAlerts.DELETE("/:uid", s.requireAdmin(), s.handleDeleteQueryAlert)
+
+			// Audit: admin/viewer/auditor
+			authenticated.GET("/audit", s.requirePermission(store.PermissionQueriesRead), s.handleListAudit)
+			authenticated.GET("/audit/schema", s.requirePermission(store.PermissionQueriesRead), s.handleGetAuditSchema)
 
 			// Global parameters (admin-only CRUD; GET /instance open to any authenticated user)
 			params := authenticated.Group("/parameters")
@@ -337,12 +510,51 @@ func (s *Server) setupRouter() *gin.Engine {
 			params.PUT("/:group/:key", s.requireAdmin(), s.handleSetParameter)
 			params.DELETE("/:group/:key", s.requireAdmin(), s.handleDeleteParameter)
 
+			// Per-user UI preferences (always the current user's own)
+			prefs := authenticated.Group("/preferences")
+			prefs.GET("", s.handleListPreferences)
+			prefs.GET("/:key", s.handleGetPreference)
+			prefs.PUT("/:key", s.handleSetPreference)
+			prefs.DELETE("/:key", s.handleDeletePreference)
+
+			// Global search across entities (role-filtered in handler)
+			authenticated.GET("/search", s.handleSearch)
+
+			// Storage database usage and growth (admin)
+			admin := authenticated.Group("/admin")
+			admin.GET("/storage", s.requireAdmin(), s.handleGetStorage)
+			// API availability and latency per route (admin)
+			admin.GET("/slo", s.requireAdmin(), s.handleGetSLO)
+			// DBBat's own storage queries, literals masked (admin)
+			admin.GET("/storage/queries", s.requireAdmin(), s.handleListStorageQueries)
+			// Running dbbat instances sharing the storage database (admin)
+			admin.GET("/instances", s.requireAdmin(), s.handleListInstances)
+			// Drain this instance before a deployment (admin)
+			admin.POST("/drain", s.requireAdmin(), s.handleDrain)
+			// Re-read this instance's configuration file (admin)
+			admin.POST("/reload", s.requireAdmin(), s.handleReload)
+
+			// Access review: unused and over-provisioned grants (admin)
+			reports := authenticated.Group("/reports")
+			reports.GET("/access-review", s.requireAdmin(), s.handleGetAccessReview)
+			// Signed access matrix for attestations (admin/viewer/auditor)
+			reports.GET("/access-matrix", s.requirePermission(store.PermissionQueriesRead), s.handleGetAccessMatrix)
+			reports.POST("/verify", s.requirePermission(store.PermissionQueriesRead), s.handleVerifyReport)
+
 			// Instance info
 			authenticated.GET("/instance", s.handleGetInstance)
 			authenticated.PUT("/instance/public", s.requireAdmin(), s.handleUpdateInstancePublic)
 		}
 	}
 
+	// v2 shares v1's middleware. Endpoints move here when their v1 contract
+	// has to change; the v1 route is then marked deprecated().
+	v2, _ := s.mountAPIVersion(router, apiV2)
+	{
+		v2.GET("/health", s.handleHealth)
+		v2.GET("/version", s.handleVersion)
+	}
+
 	// Frontend routes - serve the SPA (must be registered last when using NoRoute)
 	s.setupFrontendRoutes(router)
 
@@ -357,7 +569,58 @@ func (s *Server) handleHealth(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+	response := gin.H{
+		"status":          "healthy",
+		"storage_pool":    newStoragePoolResponse(s.store.PoolStats()),
+		"proxy_panics":    shared.PanicCounts(),
+		"active_sessions": shared.ActiveSessions(),
+	}
+
+	if replicaStatus := s.store.ReplicaStatus(); replicaStatus != store.ReplicaStatusNone {
+		response["storage_replica"] = replicaStatus
+	}
+
+	// A draining instance fails its health check, so the load balancer stops
+	// sending it traffic while its sessions end.
+	if since := shared.DrainingSince(); since != nil {
+		response["status"] = "draining"
+		response["draining_since"] = since
+
+		c.JSON(http.StatusServiceUnavailable, response)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// StoragePoolResponse reports storage connection pool usage in the health
+// check, so operators can spot pool exhaustion (a rising wait_count).
+type StoragePoolResponse struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// newStoragePoolResponse converts database/sql pool statistics.
+func newStoragePoolResponse(stats sql.DBStats) StoragePoolResponse {
+	return StoragePoolResponse{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
 }
 
 // handleVersion returns API and build version information.
@@ -368,11 +631,12 @@ func (s *Server) handleVersion(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"api_version":   "v1",
-		"build_version": version.Version,
-		"build_commit":  version.Commit,
-		"build_time":    version.GitTime,
-		"run_mode":      runMode,
+		"api_version":        getAPIVersion(c),
+		"supported_versions": supportedAPIVersions,
+		"build_version":      version.Version,
+		"build_commit":       version.Commit,
+		"build_time":         version.GitTime,
+		"run_mode":           runMode,
 	})
 }
 
@@ -412,27 +676,33 @@ func (s *Server) handleSwaggerUI(c *gin.Context) {
 	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
 }
 
-// loggingMiddleware logs HTTP requests.
-func (s *Server) loggingMiddleware() gin.HandlerFunc {
+// sourceIPMiddleware attaches the resolved client IP to the request context
+// so audit events logged by handlers record where the request came from.
+func (s *Server) sourceIPMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
-
+		c.Request = c.Request.WithContext(store.WithSourceIP(c.Request.Context(), c.ClientIP()))
 		c.Next()
+	}
+}
 
-		latency := time.Since(start)
-		statusCode := c.Writer.Status()
+// trustedProxies returns the configured reverse-proxy networks in the form
+// gin.Engine.SetTrustedProxies expects. nil (trust no proxy) when unset.
+func (s *Server) trustedProxies() []string {
+	if s.config == nil {
+		return nil
+	}
 
-		s.logger.InfoContext(c.Request.Context(), "API request",
-			slog.String("method", c.Request.Method),
-			slog.String("path", path),
-			slog.String("query", query),
-			slog.Int("status", statusCode),
-			slog.Duration("latency", latency),
-			slog.String("client_ip", c.ClientIP()),
-		)
+	networks, err := config.ParseCIDRList(s.config.TrustedProxies)
+	if err != nil || len(networks) == 0 {
+		return nil
+	}
+
+	proxies := make([]string, 0, len(networks))
+	for _, n := range networks {
+		proxies = append(proxies, n.String())
 	}
+
+	return proxies
 }
 
 // successResponse sends a success response.
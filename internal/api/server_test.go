@@ -8,6 +8,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
 )
 
 func TestMain(m *testing.M) {
@@ -187,3 +190,63 @@ func TestGetCurrentUser(t *testing.T) {
 		}
 	})
 }
+
+func TestSourceIPMiddleware_TrustedProxies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		trustedProxies string
+		remoteAddr     string
+		forwardedFor   string
+		want           string
+	}{
+		{
+			name:         "no trusted proxies ignores X-Forwarded-For",
+			remoteAddr:   "10.1.2.3:40000",
+			forwardedFor: "203.0.113.9",
+			want:         "10.1.2.3",
+		},
+		{
+			name:           "trusted proxy forwards client IP",
+			trustedProxies: "10.0.0.0/8",
+			remoteAddr:     "10.1.2.3:40000",
+			forwardedFor:   "203.0.113.9",
+			want:           "203.0.113.9",
+		},
+		{
+			name:           "untrusted peer cannot spoof",
+			trustedProxies: "10.0.0.0/8",
+			remoteAddr:     "198.51.100.4:40000",
+			forwardedFor:   "203.0.113.9",
+			want:           "198.51.100.4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{config: &config.Config{TrustedProxies: tt.trustedProxies}}
+
+			router := gin.New()
+			if err := router.SetTrustedProxies(s.trustedProxies()); err != nil {
+				t.Fatalf("SetTrustedProxies: %v", err)
+			}
+			router.Use(s.sourceIPMiddleware())
+			router.GET("/ip", func(c *gin.Context) {
+				c.String(http.StatusOK, store.SourceIPFromContext(c.Request.Context()))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,18 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/secrets"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -16,19 +20,40 @@ import (
 // protocol is "ssh", an SSH bastion). Password is optional for SSH rows that
 // authenticate with a private key.
 type CreateDatabaseRequest struct {
-	Name              string     `json:"name" binding:"required"`
-	Description       string     `json:"description"`
-	Host              string     `json:"host" binding:"required"`
-	Port              int        `json:"port"`
-	DatabaseName      string     `json:"database_name"`
-	Username          string     `json:"username" binding:"required"`
-	Password          string     `json:"password"`
-	SSLMode           string     `json:"ssl_mode"`
-	Protocol          string     `json:"protocol"`
-	OracleServiceName string     `json:"oracle_service_name"`
-	MongoAuthSource   string     `json:"mongo_auth_source"`
-	Listable          *bool      `json:"listable"`
-	ViaUID            *uuid.UUID `json:"via_uid"`
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	Host         string `json:"host" binding:"required"`
+	Port         int    `json:"port"`
+	DatabaseName string `json:"database_name"`
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password"`
+	// ReadOnlyUsername and ReadOnlyPassword are the upstream login of
+	// read-only grants; both or neither.
+	ReadOnlyUsername  string       `json:"readonly_username"`
+	ReadOnlyPassword  string       `json:"readonly_password"`
+	SecretRef         string       `json:"secret_ref"`    // Password in a secrets manager, instead of password
+	ReadReplicas      []string     `json:"read_replicas"` // host or host:port of the read replicas (PostgreSQL)
+	SSLMode           string       `json:"ssl_mode"`
+	SSLRootCert       string       `json:"ssl_root_cert"` // PEM CA bundle verifying the upstream certificate
+	Protocol          string       `json:"protocol"`
+	OracleServiceName string       `json:"oracle_service_name"`
+	MongoAuthSource   string       `json:"mongo_auth_source"`
+	Listable          *bool        `json:"listable"`
+	BlockedMessage    string       `json:"blocked_message" binding:"max=1024"`
+	ViaUID            *uuid.UUID   `json:"via_uid"`
+	Labels            store.Labels `json:"labels"`
+	// GrantDefaults pre-fill and bound the grants on the database.
+	GrantDefaults *store.GrantDefaults `json:"grant_defaults"`
+	// RowQuota caps the result rows retained for the database.
+	RowQuota *store.RowQuota `json:"row_quota"`
+	// ResultMasking names the columns masked in captured rows.
+	ResultMasking *store.ResultMasking `json:"result_masking"`
+	// QueryStorage overrides the query storage and retention settings for
+	// the database.
+	QueryStorage *store.QueryStorageOverrides `json:"query_storage"`
+	// SlowQueryThresholdMs captures the plan of the statements running
+	// longer (PostgreSQL only).
+	SlowQueryThresholdMs *int `json:"slow_query_threshold_ms" binding:"omitempty,min=1"`
 	// SSH bastion secrets (write-only, never returned).
 	SSHPrivateKey string `json:"ssh_private_key"`
 	SSHPassphrase string `json:"ssh_passphrase"`
@@ -40,18 +65,38 @@ type CreateDatabaseRequest struct {
 
 // UpdateDatabaseRequest represents the request to update a database
 type UpdateDatabaseRequest struct {
-	Description       *string    `json:"description"`
-	Host              *string    `json:"host"`
-	Port              *int       `json:"port"`
-	DatabaseName      *string    `json:"database_name"`
-	Username          *string    `json:"username"`
-	Password          *string    `json:"password"`
-	SSLMode           *string    `json:"ssl_mode"`
-	Protocol          *string    `json:"protocol"`
-	OracleServiceName *string    `json:"oracle_service_name"`
-	MongoAuthSource   *string    `json:"mongo_auth_source"`
-	Listable          *bool      `json:"listable"`
-	ViaUID            *uuid.UUID `json:"via_uid"`
+	Description  *string `json:"description"`
+	Host         *string `json:"host"`
+	Port         *int    `json:"port"`
+	DatabaseName *string `json:"database_name"`
+	Username     *string `json:"username"`
+	Password     *string `json:"password"`
+	// ReadOnlyUsername, when empty, removes the read-only credentials.
+	ReadOnlyUsername  *string      `json:"readonly_username"`
+	ReadOnlyPassword  *string      `json:"readonly_password"`
+	SecretRef         *string      `json:"secret_ref"`    // Empty clears it
+	ReadReplicas      []string     `json:"read_replicas"` // Non-nil replaces the read replicas
+	SSLMode           *string      `json:"ssl_mode"`
+	SSLRootCert       *string      `json:"ssl_root_cert"` // Empty clears it
+	Protocol          *string      `json:"protocol"`
+	OracleServiceName *string      `json:"oracle_service_name"`
+	MongoAuthSource   *string      `json:"mongo_auth_source"`
+	Listable          *bool        `json:"listable"`
+	BlockedMessage    *string      `json:"blocked_message" binding:"omitempty,max=1024"`
+	ViaUID            *uuid.UUID   `json:"via_uid"`
+	Labels            store.Labels `json:"labels"` // Non-nil replaces the labels
+	// GrantDefaults, when present, replaces the grant defaults; {} clears them.
+	GrantDefaults *store.GrantDefaults `json:"grant_defaults"`
+	// RowQuota, when present, replaces the row quota; {} clears it.
+	RowQuota *store.RowQuota `json:"row_quota"`
+	// ResultMasking, when present, replaces the result masking; {} clears it.
+	ResultMasking *store.ResultMasking `json:"result_masking"`
+	// QueryStorage, when present, replaces the query storage overrides; {}
+	// clears them.
+	QueryStorage *store.QueryStorageOverrides `json:"query_storage"`
+	// SlowQueryThresholdMs, when present, replaces the slow query threshold;
+	// 0 clears it.
+	SlowQueryThresholdMs *int `json:"slow_query_threshold_ms" binding:"omitempty,min=0"`
 	// ClearViaUID, when true, removes the SSH tunnel (direct dial). Distinct
 	// from an omitted via_uid, which leaves the tunnel unchanged.
 	ClearViaUID bool `json:"clear_via_uid"`
@@ -63,34 +108,70 @@ type UpdateDatabaseRequest struct {
 	TestConnection bool `json:"test_connection"`
 }
 
+// CloneDatabaseRequest represents the request to clone a database under a new
+// name. Omitted fields keep the source database's value; credentials are
+// always copied.
+type CloneDatabaseRequest struct {
+	Name         string  `json:"name" binding:"required"`
+	Description  *string `json:"description"`
+	Host         *string `json:"host"`
+	Port         *int    `json:"port"`
+	DatabaseName *string `json:"database_name"`
+	// TestConnection asks the API to validate the clone by actually dialing
+	// it once created. Opt-in, and never fatal.
+	TestConnection bool `json:"test_connection"`
+}
+
 // DatabaseResponse represents a database with full details (admin only)
 type DatabaseResponse struct {
-	UID               uuid.UUID  `json:"uid"`
-	Name              string     `json:"name"`
-	Description       string     `json:"description"`
-	Host              string     `json:"host,omitempty"`
-	Port              int        `json:"port,omitempty"`
-	DatabaseName      string     `json:"database_name,omitempty"`
-	Username          string     `json:"username,omitempty"`
-	SSLMode           string     `json:"ssl_mode,omitempty"`
-	Protocol          string     `json:"protocol,omitempty"`
-	OracleServiceName string     `json:"oracle_service_name,omitempty"`
-	MongoAuthSource   string     `json:"mongo_auth_source,omitempty"`
-	Listable          bool       `json:"listable"`
-	CreatedBy         *uuid.UUID `json:"created_by,omitempty"`
-	ViaUID            *uuid.UUID `json:"via_uid,omitempty"`
+	UID               uuid.UUID    `json:"uid"`
+	Name              string       `json:"name"`
+	Description       string       `json:"description"`
+	Host              string       `json:"host,omitempty"`
+	Port              int          `json:"port,omitempty"`
+	DatabaseName      string       `json:"database_name,omitempty"`
+	Username          string       `json:"username,omitempty"`
+	ReadOnlyUsername  string       `json:"readonly_username,omitempty"`
+	SecretRef         string       `json:"secret_ref,omitempty"`
+	ReadReplicas      []string     `json:"read_replicas,omitempty"`
+	SSLMode           string       `json:"ssl_mode,omitempty"`
+	SSLRootCert       string       `json:"ssl_root_cert,omitempty"`
+	Protocol          string       `json:"protocol,omitempty"`
+	OracleServiceName string       `json:"oracle_service_name,omitempty"`
+	MongoAuthSource   string       `json:"mongo_auth_source,omitempty"`
+	Listable          bool         `json:"listable"`
+	BlockedMessage    string       `json:"blocked_message,omitempty"`
+	Labels            store.Labels `json:"labels"`
+	CreatedBy         *uuid.UUID   `json:"created_by,omitempty"`
+	ViaUID            *uuid.UUID   `json:"via_uid,omitempty"`
+	// GrantDefaults are the database's grant defaults, absent when unset.
+	GrantDefaults *store.GrantDefaults `json:"grant_defaults,omitempty"`
+	// RowQuota is the database's row quota, absent when unset.
+	RowQuota *store.RowQuota `json:"row_quota,omitempty"`
+	// ResultMasking is the database's result masking, absent when unset.
+	ResultMasking *store.ResultMasking `json:"result_masking,omitempty"`
+	// QueryStorage is the database's query storage overrides, absent when
+	// unset.
+	QueryStorage *store.QueryStorageOverrides `json:"query_storage,omitempty"`
+	// SlowQueryThresholdMs is the database's slow query threshold, absent
+	// when unset.
+	SlowQueryThresholdMs *int `json:"slow_query_threshold_ms,omitempty"`
 	// SSHKnownHostKey is the TOFU-pinned bastion host key (read-only). Secrets
 	// (private key, passphrase) are never returned.
 	SSHKnownHostKey string `json:"ssh_known_host_key,omitempty"`
 	// ConnectionTest is present only when the request set test_connection.
 	ConnectionTest *ConnectionTestResponse `json:"connection_test,omitempty"`
+	// Health is the latest connectivity check of the database, absent until
+	// it is first checked. Not returned on create and update.
+	Health *store.ServerHealth `json:"health,omitempty"`
 }
 
 // DatabaseLimitedResponse represents a database with limited info (non-admin)
 type DatabaseLimitedResponse struct {
-	UID         uuid.UUID `json:"uid"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
+	UID         uuid.UUID    `json:"uid"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Labels      store.Labels `json:"labels"`
 }
 
 // handleCreateDatabase creates a new database configuration
@@ -101,6 +182,10 @@ func (s *Server) handleCreateDatabase(c *gin.Context) {
 		return
 	}
 
+	if !validLabels(c, req.Labels) {
+		return
+	}
+
 	// Check demo mode restrictions
 	if s.config != nil {
 		if errMsg := s.config.ValidateDemoTarget(req.Username, req.Password, req.Host, req.DatabaseName); errMsg != "" {
@@ -136,6 +221,35 @@ func (s *Server) handleCreateDatabase(c *gin.Context) {
 		return
 	}
 
+	if !validGrantDefaults(c, req.GrantDefaults, req.Protocol) || !validRowQuota(c, req.RowQuota) ||
+		!validResultMasking(c, req.ResultMasking) || !validQueryStorage(c, req.QueryStorage) ||
+		!validReadReplicas(c, &req.ReadReplicas, req.Protocol) ||
+		!validSlowQueryThreshold(c, req.SlowQueryThresholdMs, req.Protocol) {
+		return
+	}
+
+	if errMsg := validateCreateReadOnlyCredentials(&req, s.config != nil && s.config.IsDemoMode()); errMsg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, errMsg)
+		return
+	}
+
+	if errMsg := validateCreateSecretRef(&req, s.config != nil && s.config.IsDemoMode()); errMsg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, errMsg)
+		return
+	}
+
+	if req.RowQuota.IsZero() {
+		req.RowQuota = nil
+	}
+
+	if req.ResultMasking.IsZero() {
+		req.ResultMasking = nil
+	}
+
+	if req.QueryStorage.IsZero() {
+		req.QueryStorage = nil
+	}
+
 	currentUser := getCurrentUser(c)
 
 	var oracleServiceName *string
@@ -164,40 +278,52 @@ func (s *Server) handleCreateDatabase(c *gin.Context) {
 	}
 
 	db := &store.Server{
-		Name:              req.Name,
-		Description:       req.Description,
-		Host:              req.Host,
-		Port:              req.Port,
-		DatabaseName:      req.DatabaseName,
-		Username:          req.Username,
-		Password:          req.Password,
-		SSLMode:           req.SSLMode,
-		Protocol:          req.Protocol,
-		OracleServiceName: oracleServiceName,
-		ViaUID:            req.ViaUID,
-		ProtocolData:      protocolData,
-		Listable:          listable,
-		CreatedBy:         &currentUser.UID,
-	}
-
-	result, err := s.store.CreateServer(c.Request.Context(), db, s.encryptionKey)
+		Name:                 req.Name,
+		Description:          req.Description,
+		Host:                 req.Host,
+		Port:                 req.Port,
+		DatabaseName:         req.DatabaseName,
+		Username:             req.Username,
+		Password:             req.Password,
+		ReadOnlyUsername:     req.ReadOnlyUsername,
+		ReadOnlyPassword:     req.ReadOnlyPassword,
+		SecretRef:            req.SecretRef,
+		ReadReplicas:         req.ReadReplicas,
+		SSLMode:              req.SSLMode,
+		SSLRootCert:          req.SSLRootCert,
+		Protocol:             req.Protocol,
+		OracleServiceName:    oracleServiceName,
+		ViaUID:               req.ViaUID,
+		ProtocolData:         protocolData,
+		Listable:             listable,
+		BlockedMessage:       req.BlockedMessage,
+		GrantDefaults:        req.GrantDefaults,
+		RowQuota:             req.RowQuota,
+		ResultMasking:        req.ResultMasking,
+		QueryStorage:         req.QueryStorage,
+		SlowQueryThresholdMs: req.SlowQueryThresholdMs,
+		Labels:               req.Labels,
+		CreatedBy:            &currentUser.UID,
+	}
+
+	var result *store.Server
+
+	err := s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		var err error
+		if result, err = tx.CreateServer(ctx, db, s.encryptionKey); err != nil {
+			return err
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			PerformedBy: &currentUser.UID,
+			Payload:     audit.DatabaseCreatedV1{Name: result.Name, Host: result.Host, Port: result.Port},
+		})
+	})
 	if err != nil {
 		s.writeCreateServerError(c, err)
 		return
 	}
 
-	// Log audit event
-	details, _ := json.Marshal(map[string]interface{}{
-		"name": result.Name,
-		"host": result.Host,
-		"port": result.Port,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "database.created",
-		PerformedBy: &currentUser.UID,
-		Details:     details,
-	})
-
 	resp := toDatabaseResponse(result)
 	resp.ConnectionTest = s.maybeInlineConnectionTest(c, req.TestConnection, result.UID)
 
@@ -225,32 +351,59 @@ func (s *Server) writeCreateServerError(c *gin.Context, err error) {
 func (s *Server) handleListDatabases(c *gin.Context) {
 	currentUser := getCurrentUser(c)
 
+	labels, ok := bindLabelSelector(c)
+	if !ok {
+		return
+	}
+
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+	filter := store.ServerFilter{Labels: labels, Limit: p.Limit, Offset: p.Offset}
+
 	// Admin sees full details for every database, including non-listable ones.
 	if currentUser.IsAdmin() {
-		databases, err := s.store.ListServers(c.Request.Context())
+		databases, err := s.store.ListServers(c.Request.Context(), filter)
 		if err != nil {
 			writeInternalError(c, s.logger, err, "failed to list databases")
 			return
 		}
+		total, err := s.store.CountServers(c.Request.Context(), filter)
+		if err != nil {
+			writeInternalError(c, s.logger, err, "failed to count databases")
+			return
+		}
+		health, err := s.store.ListServerHealth(c.Request.Context())
+		if err != nil {
+			writeInternalError(c, s.logger, err, "failed to list database health")
+			return
+		}
 		response := make([]DatabaseResponse, len(databases))
 		for i, db := range databases {
 			response[i] = toDatabaseResponse(&db)
+			response[i].Health = health[db.UID]
 		}
-		successResponse(c, gin.H{"databases": response})
+		pageResponse(c, p, total, gin.H{"databases": response})
 		return
 	}
 
 	// Non-admin: only listable databases, limited response (no host/port/creds).
-	databases, err := s.store.ListListableServers(c.Request.Context())
+	databases, err := s.store.ListListableServers(c.Request.Context(), filter)
 	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to list databases")
 		return
 	}
+	total, err := s.store.CountListableServers(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count databases")
+		return
+	}
 	response := make([]DatabaseLimitedResponse, len(databases))
 	for i, db := range databases {
 		response[i] = toDatabaseLimitedResponse(&db)
 	}
-	successResponse(c, gin.H{"databases": response})
+	pageResponse(c, p, total, gin.H{"databases": response})
 }
 
 // handleGetDatabase retrieves a specific database based on user role
@@ -271,12 +424,16 @@ func (s *Server) handleGetDatabase(c *gin.Context) {
 
 	// Admin sees full details
 	if currentUser.IsAdmin() {
-		successResponse(c, toDatabaseResponse(db))
+		response := toDatabaseResponse(db)
+		if health, err := s.store.GetServerHealth(c.Request.Context(), uid); err == nil {
+			response.Health = health
+		}
+		successResponse(c, response)
 		return
 	}
 
-	// Viewer sees limited info
-	if currentUser.IsViewer() {
+	// Viewers and auditors see limited info
+	if currentUser.Can(store.PermissionQueriesRead) {
 		successResponse(c, toDatabaseLimitedResponse(db))
 		return
 	}
@@ -350,6 +507,71 @@ func (s *Server) validateDemoModeUpdate(db *store.Server, req UpdateDatabaseRequ
 	return ""
 }
 
+// handleCloneDatabase creates a copy of a database under a new name, with
+// optional host/port/database name overrides, so similar environments do not
+// need their credentials retyped.
+func (s *Server) handleCloneDatabase(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid database UID")
+		return
+	}
+
+	var req CloneDatabaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+		return
+	}
+
+	// In demo mode the copied credentials must stay pointed at the demo target.
+	if s.config != nil && s.config.IsDemoMode() && (req.Host != nil || req.DatabaseName != nil) {
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "cannot override host or database name in demo mode")
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	clone := store.ServerClone{
+		Name:         req.Name,
+		Description:  req.Description,
+		Host:         req.Host,
+		Port:         req.Port,
+		DatabaseName: req.DatabaseName,
+		CreatedBy:    &currentUser.UID,
+	}
+
+	var result *store.Server
+
+	err = s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		var err error
+		if result, err = tx.CloneServer(ctx, uid, clone, s.encryptionKey); err != nil {
+			return err
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			PerformedBy: &currentUser.UID,
+			Payload: audit.DatabaseClonedV1{
+				SourceUID: uid,
+				Name:      result.Name,
+				Host:      result.Host,
+				Port:      result.Port,
+			},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrServerNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "database not found")
+			return
+		}
+		s.writeCreateServerError(c, err)
+		return
+	}
+
+	resp := toDatabaseResponse(result)
+	resp.ConnectionTest = s.maybeInlineConnectionTest(c, req.TestConnection, result.UID)
+
+	successResponse(c, resp)
+}
+
 // handleUpdateDatabase updates a database
 func (s *Server) handleUpdateDatabase(c *gin.Context) {
 	uid, err := parseUIDParam(c)
@@ -364,6 +586,65 @@ func (s *Server) handleUpdateDatabase(c *gin.Context) {
 		return
 	}
 
+	if !validLabels(c, req.Labels) {
+		return
+	}
+
+	if req.SSLRootCert != nil {
+		if _, err := shared.ParseSSLRootCert(*req.SSLRootCert); err != nil {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+			return
+		}
+	}
+
+	if req.GrantDefaults != nil || req.ReadReplicas != nil || req.SlowQueryThresholdMs != nil {
+		protocol := req.Protocol
+		if protocol == nil {
+			current, err := s.store.GetServerByUID(c.Request.Context(), uid)
+			if err != nil {
+				writeError(c, http.StatusNotFound, ErrCodeNotFound, "database not found")
+				return
+			}
+			protocol = &current.Protocol
+		}
+
+		if !validGrantDefaults(c, req.GrantDefaults, *protocol) || !validReadReplicas(c, &req.ReadReplicas, *protocol) ||
+			!validSlowQueryThreshold(c, req.SlowQueryThresholdMs, *protocol) {
+			return
+		}
+	}
+
+	if !validRowQuota(c, req.RowQuota) || !validResultMasking(c, req.ResultMasking) ||
+		!validQueryStorage(c, req.QueryStorage) {
+		return
+	}
+
+	if req.ReadOnlyUsername != nil || req.ReadOnlyPassword != nil {
+		current, err := s.store.GetServerByUID(c.Request.Context(), uid)
+		if err != nil {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "database not found")
+			return
+		}
+
+		if errMsg := validateUpdateReadOnlyCredentials(current, req, s.config != nil && s.config.IsDemoMode()); errMsg != "" {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, errMsg)
+			return
+		}
+	}
+
+	if req.SecretRef != nil || req.Password != nil {
+		current, err := s.store.GetServerByUID(c.Request.Context(), uid)
+		if err != nil {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "database not found")
+			return
+		}
+
+		if errMsg := validateUpdateSecretRef(current, req, s.config != nil && s.config.IsDemoMode()); errMsg != "" {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, errMsg)
+			return
+		}
+	}
+
 	// Check demo mode restrictions if credentials are being updated
 	if s.config != nil && s.config.IsDemoMode() && (req.Username != nil || req.Password != nil || req.Host != nil || req.DatabaseName != nil) {
 		db, err := s.store.GetServerByUID(c.Request.Context(), uid)
@@ -378,24 +659,48 @@ func (s *Server) handleUpdateDatabase(c *gin.Context) {
 	}
 
 	updates := store.ServerUpdate{
-		Description:       req.Description,
-		Host:              req.Host,
-		Port:              req.Port,
-		DatabaseName:      req.DatabaseName,
-		Username:          req.Username,
-		Password:          req.Password,
-		SSLMode:           req.SSLMode,
-		Protocol:          req.Protocol,
-		OracleServiceName: req.OracleServiceName,
-		MongoAuthSource:   req.MongoAuthSource,
-		Listable:          req.Listable,
-		ViaUID:            req.ViaUID,
-		ClearViaUID:       req.ClearViaUID,
-		SSHPrivateKey:     req.SSHPrivateKey,
-		SSHPassphrase:     req.SSHPassphrase,
-	}
-
-	if err := s.store.UpdateServer(c.Request.Context(), uid, updates, s.encryptionKey); err != nil {
+		Description:          req.Description,
+		Host:                 req.Host,
+		Port:                 req.Port,
+		DatabaseName:         req.DatabaseName,
+		Username:             req.Username,
+		Password:             req.Password,
+		ReadOnlyUsername:     req.ReadOnlyUsername,
+		ReadOnlyPassword:     req.ReadOnlyPassword,
+		SecretRef:            req.SecretRef,
+		ReadReplicas:         req.ReadReplicas,
+		SSLMode:              req.SSLMode,
+		SSLRootCert:          req.SSLRootCert,
+		Protocol:             req.Protocol,
+		OracleServiceName:    req.OracleServiceName,
+		MongoAuthSource:      req.MongoAuthSource,
+		Listable:             req.Listable,
+		BlockedMessage:       req.BlockedMessage,
+		GrantDefaults:        req.GrantDefaults,
+		RowQuota:             req.RowQuota,
+		ResultMasking:        req.ResultMasking,
+		QueryStorage:         req.QueryStorage,
+		Labels:               req.Labels,
+		ViaUID:               req.ViaUID,
+		ClearViaUID:          req.ClearViaUID,
+		SSHPrivateKey:        req.SSHPrivateKey,
+		SSHPassphrase:        req.SSHPassphrase,
+		SlowQueryThresholdMs: req.SlowQueryThresholdMs,
+	}
+
+	currentUser := getCurrentUser(c)
+
+	err = s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		if err := tx.UpdateServer(ctx, uid, updates, s.encryptionKey); err != nil {
+			return err
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			PerformedBy: &currentUser.UID,
+			Payload:     audit.DatabaseUpdatedV1{DatabaseUID: uid, UpdatedFields: redactUpdateForAudit(req)},
+		})
+	})
+	if err != nil {
 		if errors.Is(err, store.ErrTargetMatchesStorage) {
 			writeError(c, http.StatusBadRequest, ErrCodeTargetMatchesSelf, "target database cannot match DBBat storage database")
 			return
@@ -412,18 +717,6 @@ func (s *Server) handleUpdateDatabase(c *gin.Context) {
 		return
 	}
 
-	// Log audit event
-	currentUser := getCurrentUser(c)
-	details, _ := json.Marshal(map[string]interface{}{
-		"database_uid":   uid,
-		"updated_fields": redactUpdateForAudit(req),
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "database.updated",
-		PerformedBy: &currentUser.UID,
-		Details:     details,
-	})
-
 	if test := s.maybeInlineConnectionTest(c, req.TestConnection, uid); test != nil {
 		successResponse(c, gin.H{"message": "database updated", "connection_test": test})
 
@@ -450,21 +743,39 @@ func (s *Server) handleDeleteDatabase(c *gin.Context) {
 		}
 	}
 
-	if err := s.store.DeleteServer(c.Request.Context(), uid); err != nil {
-		writeInternalError(c, s.logger, err, "failed to delete database")
+	currentUser := getCurrentUser(c)
+
+	var cascade *store.DeletionCascade
+
+	err = s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		var err error
+		if cascade, err = tx.DeleteServer(ctx, uid, currentUser.UID); err != nil {
+			return err
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			PerformedBy: &currentUser.UID,
+			Payload: audit.DatabaseDeletedV1{
+				DatabaseUID:       uid,
+				RevokedGrants:     len(cascade.RevokedGrants),
+				CancelledRequests: cascade.CancelledRequests,
+			},
+		})
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrServerNotFound):
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "database not found")
+		case errors.Is(err, store.ErrServerInUse):
+			writeError(c, http.StatusConflict, ErrCodeConflict, err.Error())
+		default:
+			writeInternalError(c, s.logger, err, "failed to delete database")
+		}
+
 		return
 	}
 
-	// Log audit event
-	currentUser := getCurrentUser(c)
-	details, _ := json.Marshal(map[string]interface{}{
-		"database_uid": uid,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "database.deleted",
-		PerformedBy: &currentUser.UID,
-		Details:     details,
-	})
+	s.signalRevokedGrants(c.Request.Context(), cascade.RevokedGrants)
 
 	successResponse(c, gin.H{"message": "database deleted"})
 }
@@ -535,21 +846,32 @@ func toDatabaseResponse(db *store.Server) DatabaseResponse {
 	}
 
 	return DatabaseResponse{
-		UID:               db.UID,
-		Name:              db.Name,
-		Description:       db.Description,
-		Host:              db.Host,
-		Port:              db.Port,
-		DatabaseName:      db.DatabaseName,
-		Username:          db.Username,
-		SSLMode:           db.SSLMode,
-		Protocol:          db.Protocol,
-		OracleServiceName: oracleServiceName,
-		MongoAuthSource:   mongoAuthSource,
-		Listable:          db.Listable,
-		CreatedBy:         db.CreatedBy,
-		ViaUID:            db.ViaUID,
-		SSHKnownHostKey:   knownHostKey,
+		UID:                  db.UID,
+		Name:                 db.Name,
+		Description:          db.Description,
+		Host:                 db.Host,
+		Port:                 db.Port,
+		DatabaseName:         db.DatabaseName,
+		Username:             db.Username,
+		ReadOnlyUsername:     db.ReadOnlyUsername,
+		SecretRef:            db.SecretRef,
+		ReadReplicas:         db.ReadReplicas,
+		SSLMode:              db.SSLMode,
+		SSLRootCert:          db.SSLRootCert,
+		Protocol:             db.Protocol,
+		OracleServiceName:    oracleServiceName,
+		MongoAuthSource:      mongoAuthSource,
+		Listable:             db.Listable,
+		BlockedMessage:       db.BlockedMessage,
+		Labels:               db.Labels,
+		CreatedBy:            db.CreatedBy,
+		ViaUID:               db.ViaUID,
+		GrantDefaults:        db.GrantDefaults,
+		RowQuota:             db.RowQuota,
+		ResultMasking:        db.ResultMasking,
+		QueryStorage:         db.QueryStorage,
+		SlowQueryThresholdMs: db.SlowQueryThresholdMs,
+		SSHKnownHostKey:      knownHostKey,
 	}
 }
 
@@ -558,16 +880,32 @@ func toDatabaseResponse(db *store.Server) DatabaseResponse {
 // context; they appear only here, for management and the "via SSH server"
 // selector.
 func (s *Server) handleListSSHServers(c *gin.Context) {
-	servers, err := s.store.ListSSHServers(c.Request.Context())
+	labels, ok := bindLabelSelector(c)
+	if !ok {
+		return
+	}
+
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+	filter := store.ServerFilter{Labels: labels, Limit: p.Limit, Offset: p.Offset}
+
+	servers, err := s.store.ListSSHServers(c.Request.Context(), filter)
 	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to list ssh servers")
 		return
 	}
+	total, err := s.store.CountSSHServers(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count ssh servers")
+		return
+	}
 	response := make([]DatabaseResponse, len(servers))
 	for i := range servers {
 		response[i] = toDatabaseResponse(&servers[i])
 	}
-	successResponse(c, gin.H{"servers": response})
+	pageResponse(c, p, total, gin.H{"servers": response})
 }
 
 // toDatabaseLimitedResponse converts a Server to a limited response (non-admin)
@@ -575,6 +913,7 @@ func toDatabaseLimitedResponse(db *store.Server) DatabaseLimitedResponse {
 	return DatabaseLimitedResponse{
 		UID:         db.UID,
 		Name:        db.Name,
+		Labels:      db.Labels,
 		Description: db.Description,
 	}
 }
@@ -604,52 +943,262 @@ func validateCreateProtocolFields(req *CreateDatabaseRequest) string {
 			req.SSLMode = "prefer"
 		}
 	}
+	if _, err := shared.ParseSSLRootCert(req.SSLRootCert); err != nil {
+		return err.Error()
+	}
 	return ""
 }
 
-// redactUpdateForAudit returns a copy of an update request safe to persist in
-// the audit log: the secret-bearing fields (database password, SSH private key,
-// SSH passphrase) are replaced by a boolean "this field was changed" marker.
-// The audit record needs to know *that* a credential was rotated, never what
-// it was rotated to.
-func redactUpdateForAudit(req UpdateDatabaseRequest) map[string]any {
-	out := map[string]any{}
+// validateCreateReadOnlyCredentials checks the read-only credentials of a
+// create request, returning an error message (empty when valid). They come
+// in pairs and stay out of demo mode, which pins the demo target's
+// credentials.
+func validateCreateReadOnlyCredentials(req *CreateDatabaseRequest, demoMode bool) string {
+	if req.ReadOnlyUsername == "" && req.ReadOnlyPassword == "" {
+		return ""
+	}
 
-	addPtr := func(key string, value any, set bool) {
-		if set {
-			out[key] = value
-		}
+	switch {
+	case req.Protocol == store.ProtocolSSH:
+		return "read-only credentials do not apply to ssh servers"
+	case demoMode:
+		return "read-only credentials cannot be set in demo mode"
+	case req.ReadOnlyUsername == "" || req.ReadOnlyPassword == "":
+		return "readonly_username and readonly_password must be set together"
 	}
 
-	addPtr("description", req.Description, req.Description != nil)
-	addPtr("host", req.Host, req.Host != nil)
-	addPtr("port", req.Port, req.Port != nil)
-	addPtr("database_name", req.DatabaseName, req.DatabaseName != nil)
-	addPtr("username", req.Username, req.Username != nil)
-	addPtr("ssl_mode", req.SSLMode, req.SSLMode != nil)
-	addPtr("protocol", req.Protocol, req.Protocol != nil)
-	addPtr("oracle_service_name", req.OracleServiceName, req.OracleServiceName != nil)
-	addPtr("mongo_auth_source", req.MongoAuthSource, req.MongoAuthSource != nil)
-	addPtr("listable", req.Listable, req.Listable != nil)
-	addPtr("via_uid", req.ViaUID, req.ViaUID != nil)
+	return ""
+}
 
-	if req.ClearViaUID {
-		out["clear_via_uid"] = true
+// validateUpdateReadOnlyCredentials checks the read-only credentials of an
+// update request against the current row, returning an error message (empty
+// when valid). A database without read-only credentials needs both; an
+// empty username removes them, password included.
+func validateUpdateReadOnlyCredentials(current *store.Server, req UpdateDatabaseRequest, demoMode bool) string {
+	protocol := current.Protocol
+	if req.Protocol != nil {
+		protocol = *req.Protocol
 	}
 
-	if req.Password != nil {
-		out["password_changed"] = true
+	clearing := req.ReadOnlyUsername != nil && *req.ReadOnlyUsername == ""
+
+	switch {
+	case clearing && req.ReadOnlyPassword != nil:
+		return "readonly_password cannot be set while removing readonly_username"
+	case clearing:
+		return ""
+	case protocol == store.ProtocolSSH:
+		return "read-only credentials do not apply to ssh servers"
+	case demoMode:
+		return "read-only credentials cannot be set in demo mode"
+	case req.ReadOnlyPassword != nil && *req.ReadOnlyPassword == "":
+		return "readonly_password cannot be empty"
+	case current.ReadOnlyUsername == "" && (req.ReadOnlyUsername == nil || req.ReadOnlyPassword == nil):
+		return "readonly_username and readonly_password must be set together"
+	}
+
+	return ""
+}
+
+// validateCreateSecretRef checks the secret_ref of a create request,
+// returning an error message (empty when valid). It replaces the password.
+func validateCreateSecretRef(req *CreateDatabaseRequest, demoMode bool) string {
+	if req.SecretRef != "" && req.Password != "" {
+		return "password and secret_ref cannot both be set"
+	}
+
+	return checkSecretRef(req.SecretRef, req.Protocol, demoMode)
+}
+
+// validateUpdateSecretRef checks the secret_ref and password of an update
+// request against the current row, returning an error message (empty when
+// valid). A password is only stored once the secret_ref is cleared.
+func validateUpdateSecretRef(current *store.Server, req UpdateDatabaseRequest, demoMode bool) string {
+	secretRef := current.SecretRef
+	if req.SecretRef != nil {
+		secretRef = *req.SecretRef
+	}
+
+	if secretRef != "" && req.Password != nil && *req.Password != "" {
+		return "password and secret_ref cannot both be set: clear secret_ref to store a password"
+	}
+
+	if req.SecretRef == nil {
+		return ""
+	}
+
+	protocol := current.Protocol
+	if req.Protocol != nil {
+		protocol = *req.Protocol
+	}
+
+	return checkSecretRef(*req.SecretRef, protocol, demoMode)
+}
+
+// checkSecretRef checks a secret_ref set on a server of protocol. Demo mode
+// pins the demo target's credentials, so it refuses them.
+func checkSecretRef(secretRef, protocol string, demoMode bool) string {
+	if secretRef == "" {
+		return ""
 	}
 
-	if req.SSHPrivateKey != nil {
-		out["ssh_private_key_changed"] = true
+	switch {
+	case protocol == store.ProtocolSSH:
+		return "secret_ref does not apply to ssh servers"
+	case demoMode:
+		return "secret_ref cannot be set in demo mode"
 	}
 
-	if req.SSHPassphrase != nil {
-		out["ssh_passphrase_changed"] = true
+	if _, err := secrets.ParseRef(secretRef); err != nil {
+		return "secret_ref: " + err.Error()
 	}
 
-	return out
+	return ""
+}
+
+// validGrantDefaults normalizes grant defaults in place and checks the
+// protocol's proxy enforces their controls, writing a 400 otherwise. Nil
+// defaults are valid.
+func validGrantDefaults(c *gin.Context, defaults *store.GrantDefaults, protocol string) bool {
+	if defaults == nil {
+		return true
+	}
+
+	if protocol == store.ProtocolSSH && !defaults.IsZero() {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "grant_defaults do not apply to ssh servers")
+		return false
+	}
+
+	if err := defaults.Normalize(); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return false
+	}
+
+	// Every grant carries the default controls: one the proxy cannot enforce
+	// would lock every user out.
+	if unsupported := store.UnsupportedControls(defaults.Controls, protocol); len(unsupported) > 0 {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+			"controls not supported for "+protocol+" databases: "+strings.Join(unsupported, ", "))
+		return false
+	}
+
+	return true
+}
+
+// validRowQuota checks a row quota, writing a 400 when it is malformed. A nil
+// quota is valid.
+func validRowQuota(c *gin.Context, quota *store.RowQuota) bool {
+	if quota == nil {
+		return true
+	}
+
+	if err := quota.Validate(); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// validQueryStorage checks query storage overrides, writing a 400 when they
+// are malformed. Nil overrides are valid.
+func validQueryStorage(c *gin.Context, overrides *store.QueryStorageOverrides) bool {
+	if overrides == nil {
+		return true
+	}
+
+	if err := overrides.Validate(); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// validReadReplicas normalizes a read replica list in place and checks the
+// protocol's proxy routes sessions to replicas, writing a 400 otherwise. Nil
+// and empty lists are valid.
+func validReadReplicas(c *gin.Context, replicas *[]string, protocol string) bool {
+	normalized, err := store.NormalizeReadReplicas(*replicas)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return false
+	}
+
+	if len(normalized) > 0 && !store.SupportsReadReplicas(protocol) {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+			"read_replicas not supported for "+protocol+" databases")
+		return false
+	}
+
+	*replicas = normalized
+
+	return true
+}
+
+// validSlowQueryThreshold checks the protocol supports capturing the plans
+// of slow queries, writing a 400 otherwise. A nil or zero threshold is valid.
+func validSlowQueryThreshold(c *gin.Context, thresholdMs *int, protocol string) bool {
+	if thresholdMs == nil || *thresholdMs == 0 || protocol == store.ProtocolPostgreSQL {
+		return true
+	}
+
+	writeError(c, http.StatusBadRequest, ErrCodeValidationError,
+		"slow_query_threshold_ms not supported for "+protocol+" databases")
+
+	return false
+}
+
+// validResultMasking checks a result masking, writing a 400 when it is
+// malformed. A nil masking is valid.
+func validResultMasking(c *gin.Context, masking *store.ResultMasking) bool {
+	if masking == nil {
+		return true
+	}
+
+	if err := masking.Validate(); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// redactUpdateForAudit returns the fields of an update request safe to persist
+// in the audit log: the secret-bearing fields (database passwords, SSH private
+// key, SSH passphrase) are replaced by a boolean "this field was changed"
+// marker. The audit record needs to know *that* a credential was rotated,
+// never what it was rotated to.
+func redactUpdateForAudit(req UpdateDatabaseRequest) audit.DatabaseUpdatedFieldsV1 {
+	return audit.DatabaseUpdatedFieldsV1{
+		Description:             req.Description,
+		Host:                    req.Host,
+		Port:                    req.Port,
+		DatabaseName:            req.DatabaseName,
+		Username:                req.Username,
+		ReadOnlyUsername:        req.ReadOnlyUsername,
+		SecretRef:               req.SecretRef,
+		SSLMode:                 req.SSLMode,
+		Protocol:                req.Protocol,
+		OracleServiceName:       req.OracleServiceName,
+		MongoAuthSource:         req.MongoAuthSource,
+		Listable:                req.Listable,
+		BlockedMessage:          req.BlockedMessage,
+		ViaUID:                  req.ViaUID,
+		Labels:                  req.Labels,
+		GrantDefaults:           req.GrantDefaults,
+		RowQuota:                req.RowQuota,
+		ResultMasking:           req.ResultMasking,
+		SlowQueryThresholdMs:    req.SlowQueryThresholdMs,
+		ReadReplicas:            req.ReadReplicas,
+		ClearViaUID:             req.ClearViaUID,
+		PasswordChanged:         req.Password != nil,
+		ReadOnlyPasswordChanged: req.ReadOnlyPassword != nil,
+		SSHPrivateKeyChanged:    req.SSHPrivateKey != nil,
+		SSHPassphraseChanged:    req.SSHPassphrase != nil,
+		SSLRootCertChanged:      req.SSLRootCert != nil,
+		QueryStorage:            req.QueryStorage,
+	}
 }
 
 // isSupportedProtocol reports whether the given protocol is one the proxy
@@ -2,7 +2,8 @@ package api
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/proxy/conncheck"
 	"github.com/fclairamb/dbbat/internal/store"
 )
@@ -77,12 +79,254 @@ func (s *Server) handleTestServerConnection(c *gin.Context) {
 	successResponse(c, toConnectionTestResponse(res))
 }
 
-// runConnectionCheck executes a bounded connectivity check against srv and logs
-// the outcome. Only the server uid, stage and code are logged — never
-// credentials or key material.
+// TestDatabaseConnectionRequest is a database target configuration to check
+// before it is saved. The fields match CreateDatabaseRequest's.
+type TestDatabaseConnectionRequest struct {
+	Protocol          string     `json:"protocol"`
+	Host              string     `json:"host" binding:"required"`
+	Port              int        `json:"port"`
+	DatabaseName      string     `json:"database_name"`
+	Username          string     `json:"username" binding:"required"`
+	Password          string     `json:"password"`
+	SecretRef         string     `json:"secret_ref"`
+	SSLMode           string     `json:"ssl_mode"`
+	SSLRootCert       string     `json:"ssl_root_cert"`
+	OracleServiceName string     `json:"oracle_service_name"`
+	MongoAuthSource   string     `json:"mongo_auth_source"`
+	ViaUID            *uuid.UUID `json:"via_uid"`
+}
+
+// handleTestDatabaseConnection checks a database target configuration that
+// is not saved yet: it dials the target (through via_uid's bastion when set)
+// and logs in with the given credentials, so a wrong password or host is
+// caught while the admin is still filling in the form. Nothing is stored
+// but the audit event.
+//
+// Like the test of a saved server, a failed check is still HTTP 200. SSH
+// bastions are checked once saved, as the check pins their host key.
+func (s *Server) handleTestDatabaseConnection(c *gin.Context) {
+	var req TestDatabaseConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid request: "+err.Error())
+
+		return
+	}
+
+	// Demo mode only lets the demo target be created: don't let any other be
+	// probed either.
+	if s.config != nil {
+		if errMsg := s.config.ValidateDemoTarget(req.Username, req.Password, req.Host, req.DatabaseName); errMsg != "" {
+			writeError(c, http.StatusForbidden, ErrCodeForbidden, errMsg)
+
+			return
+		}
+	}
+
+	if req.Protocol == "" {
+		req.Protocol = store.ProtocolPostgreSQL
+	}
+
+	if errMsg := validateTestDatabaseConnection(&req, s.config != nil && s.config.IsDemoMode()); errMsg != "" {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, errMsg)
+
+		return
+	}
+
+	srv := &store.Server{
+		Name:         "connection-test",
+		Host:         req.Host,
+		Port:         req.Port,
+		DatabaseName: req.DatabaseName,
+		Username:     req.Username,
+		Password:     req.Password,
+		SecretRef:    req.SecretRef,
+		SSLMode:      req.SSLMode,
+		SSLRootCert:  req.SSLRootCert,
+		Protocol:     req.Protocol,
+		ViaUID:       req.ViaUID,
+	}
+
+	if req.OracleServiceName != "" {
+		srv.OracleServiceName = &req.OracleServiceName
+	}
+
+	if req.MongoAuthSource != "" {
+		srv.ProtocolData = &store.ServerProtocolData{MongoDB: &store.MongoDatabaseData{AuthSource: req.MongoAuthSource}}
+	}
+
+	ctx := c.Request.Context()
+	res := conncheck.New(s.store, s.encryptionKey).WithTimeout(connCheckTimeout).Check(ctx, srv)
+
+	if s.logger != nil {
+		s.logger.InfoContext(ctx, "unsaved server connectivity check",
+			slog.String("protocol", srv.Protocol),
+			slog.Bool("ok", res.OK),
+			slog.String("stage", string(res.Stage)),
+			slog.String("code", string(res.Code)))
+	}
+
+	if currentUser := getCurrentUser(c); currentUser != nil {
+		_ = audit.Emit(ctx, s.store, audit.Event{
+			PerformedBy: &currentUser.UID,
+			Payload: audit.ServerConnectionTestedV1{
+				Host:     srv.Host,
+				Port:     srv.Port,
+				Protocol: srv.Protocol,
+				OK:       res.OK,
+				Stage:    string(res.Stage),
+				Code:     string(res.Code),
+			},
+		})
+	}
+
+	successResponse(c, toConnectionTestResponse(res))
+}
+
+// validateTestDatabaseConnection applies the create checks that matter to a
+// connectivity test, filling the same defaults. Returns an error message,
+// empty when valid.
+func validateTestDatabaseConnection(req *TestDatabaseConnectionRequest, demoMode bool) string {
+	switch {
+	case req.Protocol == store.ProtocolSSH:
+		return "ssh servers are tested once created, with POST /servers/{uid}/test"
+	case !isSupportedProtocol(req.Protocol):
+		return "protocol must be one of: postgresql, oracle, mysql, mariadb, mongodb"
+	case req.Port == 0:
+		return fmt.Sprintf("port is required (suggested default for %s: %d)", req.Protocol, defaultPortFor(req.Protocol))
+	}
+
+	create := CreateDatabaseRequest{
+		Protocol:          req.Protocol,
+		DatabaseName:      req.DatabaseName,
+		Password:          req.Password,
+		SecretRef:         req.SecretRef,
+		SSLMode:           req.SSLMode,
+		SSLRootCert:       req.SSLRootCert,
+		OracleServiceName: req.OracleServiceName,
+	}
+	if errMsg := validateCreateProtocolFields(&create); errMsg != "" {
+		return errMsg
+	}
+
+	if errMsg := validateCreateSecretRef(&create, demoMode); errMsg != "" {
+		return errMsg
+	}
+
+	req.SSLMode, req.OracleServiceName = create.SSLMode, create.OracleServiceName
+
+	return ""
+}
+
+// PrivilegeCheckResponse is the API shape of a privilege check: the staged
+// connectivity result, and the account's privileges when they could be read.
+type PrivilegeCheckResponse struct {
+	ConnectionTestResponse
+	Privileges *conncheck.Privileges `json:"privileges,omitempty"`
+}
+
+// handleCheckServerPrivileges logs in to a database target with its stored
+// service credentials and reports what the account can do upstream, warning
+// about every privilege beyond what the proxy needs. Like the connectivity
+// test, a failed login is still HTTP 200 with ok=false.
+func (s *Server) handleCheckServerPrivileges(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid server UID")
+
+		return
+	}
+
+	srv, err := s.store.GetServerByUID(c.Request.Context(), uid)
+	if err != nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "server not found")
+
+		return
+	}
+
+	if srv.IsSSH() {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "privileges can only be checked on database targets")
+
+		return
+	}
+
+	ctx := c.Request.Context()
+	res, privileges := conncheck.New(s.store, s.encryptionKey).WithTimeout(connCheckTimeout).CheckPrivileges(ctx, srv)
+
+	payload := audit.ServerPrivilegesCheckedV1{
+		ServerUID: srv.UID,
+		Protocol:  srv.Protocol,
+		OK:        res.OK,
+		Code:      string(res.Code),
+	}
+
+	if privileges != nil {
+		payload.Superuser = privileges.Superuser
+		payload.Warnings = len(privileges.Warnings)
+	}
+
+	if s.logger != nil {
+		s.logger.InfoContext(ctx, "server privilege check",
+			slog.String("server_uid", srv.UID.String()),
+			slog.String("protocol", srv.Protocol),
+			slog.Bool("ok", res.OK),
+			slog.String("code", string(res.Code)),
+			slog.Int("warnings", payload.Warnings))
+	}
+
+	if currentUser := getCurrentUser(c); currentUser != nil {
+		_ = audit.Emit(ctx, s.store, audit.Event{PerformedBy: &currentUser.UID, Payload: payload})
+	}
+
+	successResponse(c, PrivilegeCheckResponse{
+		ConnectionTestResponse: toConnectionTestResponse(res),
+		Privileges:             privileges,
+	})
+}
+
+// handleGetServerHealth returns the latest connectivity check of a database
+// target, run by the background health checks or from the API.
+func (s *Server) handleGetServerHealth(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid server UID")
+
+		return
+	}
+
+	if _, err := s.store.GetServerByUID(c.Request.Context(), uid); err != nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "server not found")
+
+		return
+	}
+
+	health, err := s.store.GetServerHealth(c.Request.Context(), uid)
+	if errors.Is(err, store.ErrServerHealthNotFound) {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "server not checked yet")
+
+		return
+	} else if err != nil {
+		writeInternalError(c, s.logger, err, "failed to get server health")
+
+		return
+	}
+
+	successResponse(c, health)
+}
+
+// runConnectionCheck executes a bounded connectivity check against srv, logs
+// the outcome and, for a database target, records it as the server's health.
+// Only the server uid, stage and code are logged — never credentials or key
+// material.
 func (s *Server) runConnectionCheck(ctx context.Context, srv *store.Server) conncheck.Result {
 	res := conncheck.New(s.store, s.encryptionKey).WithTimeout(connCheckTimeout).Check(ctx, srv)
 
+	if !srv.IsSSH() {
+		if err := s.store.RecordServerHealth(ctx, res.Health(srv.UID, time.Now())); err != nil && s.logger != nil {
+			s.logger.WarnContext(ctx, "failed to record server health",
+				slog.String("server_uid", srv.UID.String()), slog.Any("error", err))
+		}
+	}
+
 	if s.logger != nil {
 		s.logger.InfoContext(ctx, "server connectivity check",
 			slog.String("server_uid", srv.UID.String()),
@@ -104,18 +348,15 @@ func (s *Server) auditConnectionCheck(c *gin.Context, srv *store.Server, res con
 		return
 	}
 
-	details, _ := json.Marshal(map[string]any{
-		"server_uid": srv.UID,
-		"protocol":   srv.Protocol,
-		"ok":         res.OK,
-		"stage":      string(res.Stage),
-		"code":       string(res.Code),
-	})
-
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "server.connection_tested",
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.ServerConnectionTestedV1{
+			ServerUID: srv.UID,
+			Protocol:  srv.Protocol,
+			OK:        res.OK,
+			Stage:     string(res.Stage),
+			Code:      string(res.Code),
+		},
 	})
 }
 
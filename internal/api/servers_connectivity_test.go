@@ -22,6 +22,9 @@ func connCheckRouter(server *Server) *gin.Engine {
 	router := gin.New()
 	router.Use(server.authMiddleware())
 	router.POST("/api/v1/servers/:uid/test", server.requireAdmin(), server.handleTestServerConnection)
+	router.POST("/api/v1/servers/test", server.requireAdmin(), server.handleTestDatabaseConnection)
+	router.POST("/api/v1/servers/:uid/check-privileges", server.requireAdmin(), server.handleCheckServerPrivileges)
+	router.GET("/api/v1/servers/:uid/health", server.requireAdmin(), server.handleGetServerHealth)
 
 	return router
 }
@@ -55,10 +58,18 @@ func TestTestServerConnection_UnreachableTarget(t *testing.T) { //nolint:paralle
 	}, dbTestEncryptionKey)
 	require.NoError(t, err)
 
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/servers/"+created.UID.String()+"/test", nil)
+	healthPath := "/api/v1/servers/" + created.UID.String() + "/health"
+
+	req := httptest.NewRequest(http.MethodGet, healthPath, nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	w := httptest.NewRecorder()
 	connCheckRouter(server).ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code, "not checked yet")
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/servers/"+created.UID.String()+"/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	connCheckRouter(server).ServeHTTP(w, req)
 
 	require.Equal(t, http.StatusOK, w.Code, "a failed check is still a 200: the staged result is the answer")
 
@@ -72,6 +83,68 @@ func TestTestServerConnection_UnreachableTarget(t *testing.T) { //nolint:paralle
 
 	// The response must never echo the stored credentials.
 	assert.NotContains(t, w.Body.String(), "s3cr3t-pg-password")
+
+	// The check is recorded as the server's health.
+	req = httptest.NewRequest(http.MethodGet, healthPath, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	connCheckRouter(server).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var health store.ServerHealth
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.False(t, health.OK)
+	assert.Equal(t, "unreachable", health.Code)
+	assert.Nil(t, health.LastOKAt)
+}
+
+func TestTestDatabaseConnection_Unsaved(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	server.encryptionKey = dbTestEncryptionKey
+
+	createTestUser(t, dataStore, "admin-tdc1", "adminpass123", []string{store.RoleAdmin})
+	token := loginUser(t, server, "admin-tdc1", "adminpass123")
+	router := connCheckRouter(server)
+
+	host, port := closedTCPPort(t)
+
+	w, resp := doJSON(t, router, http.MethodPost, "/api/v1/servers/test", token, map[string]any{
+		"protocol": "postgresql", "host": host, "port": port,
+		"database_name": "app", "username": "app", "password": "s3cr3t-pg-password",
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, false, resp["ok"])
+	assert.Equal(t, "target_dial", resp["stage"])
+	assert.Equal(t, "unreachable", resp["code"])
+	assert.NotContains(t, w.Body.String(), "s3cr3t-pg-password")
+
+	// Nothing is saved.
+	servers, err := dataStore.ListServers(context.Background(), store.ServerFilter{})
+	require.NoError(t, err)
+
+	for _, srv := range servers {
+		assert.False(t, srv.Host == host && srv.Port == port, "the tested configuration was saved")
+	}
+
+	w, _ = doJSON(t, router, http.MethodPost, "/api/v1/servers/test", token, map[string]any{
+		"protocol": "ssh", "host": host, "port": port, "username": "ops", "password": "x",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestValidateTestDatabaseConnection(t *testing.T) {
+	t.Parallel()
+
+	req := &TestDatabaseConnectionRequest{Protocol: store.ProtocolOracle, Host: "db", Port: 1521, DatabaseName: "ORCL", Username: "app"}
+	assert.Empty(t, validateTestDatabaseConnection(req, false))
+	assert.Equal(t, "ORCL", req.OracleServiceName, "the service name defaults to the database name, as on create")
+
+	req = &TestDatabaseConnectionRequest{Protocol: store.ProtocolPostgreSQL, Host: "db", Port: 5432, DatabaseName: "app", Username: "app"}
+	assert.Empty(t, validateTestDatabaseConnection(req, false))
+	assert.Equal(t, "prefer", req.SSLMode)
+
+	assert.Contains(t, validateTestDatabaseConnection(&TestDatabaseConnectionRequest{Protocol: store.ProtocolMySQL, Host: "db", Username: "app"}, false), "port is required")
+	assert.Contains(t, validateTestDatabaseConnection(&TestDatabaseConnectionRequest{Protocol: "db2", Host: "db", Port: 1, Username: "app"}, false), "protocol must be")
 }
 
 func TestTestServerConnection_UnreachableBastion(t *testing.T) { //nolint:paralleltest // shared migration lock
@@ -153,6 +226,51 @@ func TestTestServerConnection_NonAdminForbidden(t *testing.T) { //nolint:paralle
 	assert.Equal(t, http.StatusForbidden, w.Code)
 }
 
+func TestCheckServerPrivileges_UnreachableTargetAndBastion(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	server.encryptionKey = dbTestEncryptionKey
+
+	createTestUser(t, dataStore, "admin-csp1", "adminpass123", []string{store.RoleAdmin})
+	token := loginUser(t, server, "admin-csp1", "adminpass123")
+
+	host, port := closedTCPPort(t)
+	target, err := dataStore.CreateServer(context.Background(), &store.Server{
+		Name: "unreachable-csp1", Host: host, Port: port,
+		DatabaseName: "app", Username: "app", Password: "s3cr3t-pg-password",
+		SSLMode: "disable", Protocol: store.ProtocolPostgreSQL, Listable: true,
+	}, dbTestEncryptionKey)
+	require.NoError(t, err)
+
+	bastion, err := dataStore.CreateServer(context.Background(), &store.Server{
+		Name: "bastion-csp1", Host: host, Port: port,
+		Username: "www-data", Password: "s3cr3t-ssh-password", Protocol: store.ProtocolSSH,
+	}, dbTestEncryptionKey)
+	require.NoError(t, err)
+
+	check := func(uid uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/servers/"+uid.String()+"/check-privileges", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		connCheckRouter(server).ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := check(target.UID)
+	require.Equal(t, http.StatusOK, w.Code, "a failed check is still a 200")
+
+	var res PrivilegeCheckResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	assert.False(t, res.OK)
+	assert.Equal(t, "target_dial", res.Stage)
+	assert.Nil(t, res.Privileges, "nothing was inspected")
+	assert.NotContains(t, w.Body.String(), "s3cr3t-pg-password")
+
+	w = check(bastion.UID)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "an SSH bastion has no database privileges")
+}
+
 // TestRedactUpdateForAudit is the security gate on the update audit record: a
 // rotated credential must be recorded as *having changed*, never as its value.
 func TestRedactUpdateForAudit(t *testing.T) {
@@ -178,11 +296,11 @@ func TestRedactUpdateForAudit(t *testing.T) {
 	assert.NotContains(t, rendered, "PRIVATE KEY")
 	assert.NotContains(t, rendered, passphrase)
 
-	assert.Equal(t, true, out["password_changed"])
-	assert.Equal(t, true, out["ssh_private_key_changed"])
-	assert.Equal(t, true, out["ssh_passphrase_changed"])
+	assert.True(t, out.PasswordChanged)
+	assert.True(t, out.SSHPrivateKeyChanged)
+	assert.True(t, out.SSHPassphraseChanged)
 
 	// Non-secret fields stay visible — the audit trail still says what changed.
 	assert.Contains(t, rendered, "db.internal")
-	assert.NotContains(t, out, "database_name")
+	assert.NotContains(t, rendered, "database_name")
 }
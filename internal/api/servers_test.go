@@ -124,7 +124,7 @@ func TestCreateDatabase_MongoDBProtocol(t *testing.T) { //nolint:paralleltest //
 	assert.Equal(t, store.ProtocolMongoDB, resp["protocol"], "response must echo the mongodb protocol")
 
 	// And the persisted row must carry the mongodb protocol too.
-	dbs, err := dataStore.ListServers(context.Background())
+	dbs, err := dataStore.ListServers(context.Background(), store.ServerFilter{})
 	require.NoError(t, err)
 	var found *store.Server
 	for i := range dbs {
@@ -185,3 +185,191 @@ func TestListDatabases_ConnectorSeesOnlyListable(t *testing.T) { //nolint:parall
 	assert.Contains(t, names, "visible-db-"+suffix)
 	assert.NotContains(t, names, "invisible-db-"+suffix)
 }
+
+func TestCloneDatabase(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	suffix := "clone"
+	server.encryptionKey = dbTestEncryptionKey
+
+	createTestUser(t, dataStore, "admin-"+suffix, "adminpass123", []string{store.RoleAdmin})
+	token := loginUser(t, server, "admin-"+suffix, "adminpass123")
+
+	source := createTestDBEntry(t, dataStore, "staging-01-"+suffix, true)
+
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.POST("/api/v1/servers/:uid/clone", server.handleCloneDatabase)
+
+	clone := func(uid string, payload map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/servers/"+uid+"/clone", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("copies credentials and applies overrides", func(t *testing.T) {
+		w := clone(source.UID.String(), map[string]any{
+			"name":          "staging-02-" + suffix,
+			"host":          "staging-02.example.com",
+			"database_name": "staging02",
+		})
+		require.Equalf(t, http.StatusOK, w.Code, "clone must succeed, got %d: %s", w.Code, w.Body.String())
+
+		var resp DatabaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.NotEqual(t, source.UID, resp.UID)
+		assert.Equal(t, "staging-02.example.com", resp.Host)
+		assert.Equal(t, "staging02", resp.DatabaseName)
+		assert.Equal(t, source.Port, resp.Port)
+		assert.Equal(t, source.Username, resp.Username)
+
+		// The password is re-encrypted under the clone's own UID.
+		cloned, err := dataStore.GetServerByUID(context.Background(), resp.UID)
+		require.NoError(t, err)
+		require.NoError(t, cloned.DecryptPassword(dbTestEncryptionKey))
+		assert.Equal(t, "dbpass", cloned.Password)
+	})
+
+	t.Run("name conflict", func(t *testing.T) {
+		w := clone(source.UID.String(), map[string]any{"name": source.Name})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		w := clone("00000000-0000-0000-0000-000000000001", map[string]any{"name": "ghost-" + suffix})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestValidateReadOnlyCredentials(t *testing.T) {
+	t.Parallel()
+
+	str := func(s string) *string { return &s }
+
+	for _, tc := range []struct {
+		name   string
+		req    CreateDatabaseRequest
+		demo   bool
+		wantOK bool
+	}{
+		{"none", CreateDatabaseRequest{Protocol: store.ProtocolPostgreSQL}, false, true},
+		{"pair", CreateDatabaseRequest{Protocol: store.ProtocolPostgreSQL, ReadOnlyUsername: "r", ReadOnlyPassword: "p"}, false, true},
+		{"username only", CreateDatabaseRequest{Protocol: store.ProtocolPostgreSQL, ReadOnlyUsername: "r"}, false, false},
+		{"password only", CreateDatabaseRequest{Protocol: store.ProtocolMySQL, ReadOnlyPassword: "p"}, false, false},
+		{"ssh", CreateDatabaseRequest{Protocol: store.ProtocolSSH, ReadOnlyUsername: "r", ReadOnlyPassword: "p"}, false, false},
+		{"demo", CreateDatabaseRequest{Protocol: store.ProtocolPostgreSQL, ReadOnlyUsername: "r", ReadOnlyPassword: "p"}, true, false},
+	} {
+		if got := validateCreateReadOnlyCredentials(&tc.req, tc.demo); (got == "") != tc.wantOK {
+			t.Errorf("create %s: validateCreateReadOnlyCredentials() = %q, want ok=%v", tc.name, got, tc.wantOK)
+		}
+	}
+
+	without := &store.Server{Protocol: store.ProtocolPostgreSQL}
+	with := &store.Server{Protocol: store.ProtocolPostgreSQL, ReadOnlyUsername: "reader"}
+
+	for _, tc := range []struct {
+		name    string
+		current *store.Server
+		req     UpdateDatabaseRequest
+		wantOK  bool
+	}{
+		{"add pair", without, UpdateDatabaseRequest{ReadOnlyUsername: str("r"), ReadOnlyPassword: str("p")}, true},
+		{"add username only", without, UpdateDatabaseRequest{ReadOnlyUsername: str("r")}, false},
+		{"add password only", without, UpdateDatabaseRequest{ReadOnlyPassword: str("p")}, false},
+		{"rotate password", with, UpdateDatabaseRequest{ReadOnlyPassword: str("p2")}, true},
+		{"rename", with, UpdateDatabaseRequest{ReadOnlyUsername: str("reader2")}, true},
+		{"empty password", with, UpdateDatabaseRequest{ReadOnlyPassword: str("")}, false},
+		{"remove", with, UpdateDatabaseRequest{ReadOnlyUsername: str("")}, true},
+		{"remove with password", with, UpdateDatabaseRequest{ReadOnlyUsername: str(""), ReadOnlyPassword: str("p")}, false},
+		{"to ssh", with, UpdateDatabaseRequest{Protocol: str(store.ProtocolSSH), ReadOnlyPassword: str("p")}, false},
+	} {
+		if got := validateUpdateReadOnlyCredentials(tc.current, tc.req, false); (got == "") != tc.wantOK {
+			t.Errorf("update %s: validateUpdateReadOnlyCredentials() = %q, want ok=%v", tc.name, got, tc.wantOK)
+		}
+	}
+}
+
+func TestValidateSecretRef(t *testing.T) {
+	t.Parallel()
+
+	str := func(s string) *string { return &s }
+
+	for _, tc := range []struct {
+		name   string
+		req    CreateDatabaseRequest
+		demo   bool
+		wantOK bool
+	}{
+		{"none", CreateDatabaseRequest{Protocol: store.ProtocolPostgreSQL, Password: "p"}, false, true},
+		{"vault", CreateDatabaseRequest{Protocol: store.ProtocolPostgreSQL, SecretRef: "vault://secret/orders"}, false, true},
+		{"with password", CreateDatabaseRequest{Protocol: store.ProtocolPostgreSQL, Password: "p", SecretRef: "vault://secret/orders"}, false, false},
+		{"unknown scheme", CreateDatabaseRequest{Protocol: store.ProtocolMySQL, SecretRef: "keyring://orders"}, false, false},
+		{"ssh", CreateDatabaseRequest{Protocol: store.ProtocolSSH, SecretRef: "vault://secret/bastion"}, false, false},
+		{"demo", CreateDatabaseRequest{Protocol: store.ProtocolPostgreSQL, SecretRef: "vault://secret/orders"}, true, false},
+	} {
+		if got := validateCreateSecretRef(&tc.req, tc.demo); (got == "") != tc.wantOK {
+			t.Errorf("create %s: validateCreateSecretRef() = %q, want ok=%v", tc.name, got, tc.wantOK)
+		}
+	}
+
+	stored := &store.Server{Protocol: store.ProtocolPostgreSQL}
+	referenced := &store.Server{Protocol: store.ProtocolPostgreSQL, SecretRef: "aws-sm://prod/orders"}
+
+	for _, tc := range []struct {
+		name    string
+		current *store.Server
+		req     UpdateDatabaseRequest
+		wantOK  bool
+	}{
+		{"set", stored, UpdateDatabaseRequest{SecretRef: str("aws-sm://prod/orders")}, true},
+		{"set invalid", stored, UpdateDatabaseRequest{SecretRef: str("prod/orders")}, false},
+		{"rotate password", stored, UpdateDatabaseRequest{Password: str("p2")}, true},
+		{"password kept referenced", referenced, UpdateDatabaseRequest{Password: str("p2")}, false},
+		{"clear for a password", referenced, UpdateDatabaseRequest{SecretRef: str(""), Password: str("p2")}, true},
+		{"to ssh", referenced, UpdateDatabaseRequest{Protocol: str(store.ProtocolSSH), SecretRef: str("aws-sm://prod/orders")}, false},
+	} {
+		if got := validateUpdateSecretRef(tc.current, tc.req, false); (got == "") != tc.wantOK {
+			t.Errorf("update %s: validateUpdateSecretRef() = %q, want ok=%v", tc.name, got, tc.wantOK)
+		}
+	}
+}
+
+func TestValidReadReplicas(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		replicas []string
+		protocol string
+		want     []string
+		wantOK   bool
+	}{
+		{"none", nil, store.ProtocolMySQL, nil, true},
+		{"clear", []string{}, store.ProtocolPostgreSQL, []string{}, true},
+		{"trimmed", []string{" replica-1 ", "replica-2:5433"}, store.ProtocolPostgreSQL, []string{"replica-1", "replica-2:5433"}, true},
+		{"malformed", []string{"replica-1:port"}, store.ProtocolPostgreSQL, nil, false},
+		{"unsupported protocol", []string{"replica-1"}, store.ProtocolMySQL, nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+
+			replicas := tc.replicas
+			ok := validReadReplicas(c, &replicas, tc.protocol)
+			require.Equal(t, tc.wantOK, ok)
+
+			if !ok {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				return
+			}
+
+			assert.Equal(t, tc.want, replicas)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// signatureScheme is the Authorization scheme of HMAC-signed requests:
+//
+//	Authorization: DBBat-HMAC-SHA256 key=<key prefix>, ts=<unix seconds>, nonce=<nonce>, sig=<hex>
+//
+// sig is the HMAC-SHA256, keyed with the full API key, of the string to sign
+// built by signingString.
+const signatureScheme = "DBBat-HMAC-SHA256"
+
+// maxSignatureNonceLength bounds the nonce so the replay cache stays small.
+const maxSignatureNonceLength = 128
+
+// maxSignedBodySize bounds the body of a signed request, read whole to be
+// hashed before the signature is checked. It is the largest body a route
+// takes: a report to verify.
+const maxSignedBodySize = maxVerifiedReportSize
+
+// Signed request errors, all reported to the client as 401.
+var (
+	errSignatureMalformed = errors.New("malformed signature authorization")
+	errSignatureSkew      = errors.New("request timestamp outside the allowed clock skew")
+	errSignatureReplayed  = errors.New("nonce already used")
+	errSignatureMismatch  = errors.New("signature mismatch")
+)
+
+// signedAuth holds the parameters of a DBBat-HMAC-SHA256 Authorization header.
+type signedAuth struct {
+	KeyPrefix string
+	Timestamp time.Time
+	Nonce     string
+	Signature []byte
+}
+
+// parseSignedAuth parses the parameters following the scheme name.
+func parseSignedAuth(params string) (*signedAuth, error) {
+	auth := &signedAuth{}
+
+	var rawTS string
+
+	for part := range strings.SplitSeq(params, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, errSignatureMalformed
+		}
+
+		switch name {
+		case "key":
+			auth.KeyPrefix = value
+		case "ts":
+			rawTS = value
+		case "nonce":
+			auth.Nonce = value
+		case "sig":
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return nil, errSignatureMalformed
+			}
+
+			auth.Signature = sig
+		default:
+			return nil, errSignatureMalformed
+		}
+	}
+
+	if len(auth.KeyPrefix) != store.APIKeyPrefixLength || auth.Nonce == "" ||
+		len(auth.Nonce) > maxSignatureNonceLength || len(auth.Signature) != sha256.Size {
+		return nil, errSignatureMalformed
+	}
+
+	ts, err := strconv.ParseInt(rawTS, 10, 64)
+	if err != nil {
+		return nil, errSignatureMalformed
+	}
+
+	auth.Timestamp = time.Unix(ts, 0)
+
+	return auth, nil
+}
+
+// signingString is what a signed request signs: the method, the request URI
+// (path and query, exactly as sent), the timestamp, the nonce and the hex
+// SHA-256 of the body, one per line.
+func signingString(method, requestURI string, ts int64, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	return strings.Join([]string{
+		method,
+		requestURI,
+		strconv.FormatInt(ts, 10),
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// signRequest computes the signature of a request with the given API key.
+func signRequest(key []byte, method, requestURI string, ts int64, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingString(method, requestURI, ts, nonce, body)))
+
+	return mac.Sum(nil)
+}
+
+// nonceCache remembers the nonces of accepted signed requests for as long as
+// their timestamp is within the skew window, so a captured request cannot be
+// replayed. It is per instance: behind a load balancer, a replay could only
+// succeed against another instance within the same window.
+type nonceCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	lastGC  time.Time
+	maxSkew time.Duration
+}
+
+func newNonceCache(maxSkew time.Duration) *nonceCache {
+	return &nonceCache{seen: make(map[string]time.Time), maxSkew: maxSkew}
+}
+
+// checkTimestamp verifies ts is within the allowed skew of now.
+func (n *nonceCache) checkTimestamp(ts, now time.Time) error {
+	if ts.Before(now.Add(-n.maxSkew)) || ts.After(now.Add(n.maxSkew)) {
+		return errSignatureSkew
+	}
+
+	return nil
+}
+
+// use records the nonce of a key, failing if it was already used. The entry
+// expires once ts falls out of the skew window, after which checkTimestamp
+// rejects the request anyway.
+func (n *nonceCache) use(keyPrefix, nonce string, ts, now time.Time) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if now.Sub(n.lastGC) > n.maxSkew {
+		for k, expiry := range n.seen {
+			if now.After(expiry) {
+				delete(n.seen, k)
+			}
+		}
+
+		n.lastGC = now
+	}
+
+	k := keyPrefix + ":" + nonce
+	if expiry, ok := n.seen[k]; ok && !now.After(expiry) {
+		return errSignatureReplayed
+	}
+
+	n.seen[k] = ts.Add(n.maxSkew)
+
+	return nil
+}
+
+// handleSignedAuth authenticates a DBBat-HMAC-SHA256 signed request. Unlike a
+// bearer token, the key never travels with the request, and a captured
+// request is only accepted once and only within the clock skew window.
+func (s *Server) handleSignedAuth(c *gin.Context, params string) {
+	if err := s.verifySignedRequest(c, params); err != nil {
+		s.logger.DebugContext(c.Request.Context(), "signed request rejected", "error", err)
+
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			writeError(c, http.StatusRequestEntityTooLarge, ErrCodeValidationError, "request body too large")
+			c.Abort()
+
+			return
+		}
+
+		writeError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid request signature")
+		c.Abort()
+
+		return
+	}
+
+	if apiKey, ok := c.Get(contextKeyAPIKey); ok && !checkAPIKeyScope(c, apiKey.(*store.APIKey)) {
+		return
+	}
+
+	c.Next()
+}
+
+// verifySignedRequest checks the signature and, on success, sets the
+// authenticated user in the context.
+func (s *Server) verifySignedRequest(c *gin.Context, params string) error {
+	ctx := c.Request.Context()
+	now := time.Now()
+
+	auth, err := parseSignedAuth(params)
+	if err != nil {
+		return err
+	}
+
+	if err := s.nonceCache.checkTimestamp(auth.Timestamp, now); err != nil {
+		return err
+	}
+
+	apiKey, err := s.store.GetAPIKeyByPrefix(ctx, auth.KeyPrefix)
+	if err != nil {
+		return err
+	}
+
+	if apiKey.IsWebSession() {
+		return errSignatureMalformed
+	}
+
+	if apiKey.IsRevoked() {
+		return store.ErrAPIKeyRevoked
+	}
+
+	if apiKey.IsExpired() {
+		return store.ErrAPIKeyExpired
+	}
+
+	secret, err := apiKey.SigningSecret(s.encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		if body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSignedBodySize)); err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := signRequest(secret, c.Request.Method, c.Request.URL.RequestURI(), auth.Timestamp.Unix(), auth.Nonce, body)
+	if !hmac.Equal(expected, auth.Signature) {
+		return errSignatureMismatch
+	}
+
+	// Only a valid signature consumes the nonce, so forged requests cannot
+	// burn the nonces of legitimate ones.
+	if err := s.nonceCache.use(auth.KeyPrefix, auth.Nonce, auth.Timestamp, now); err != nil {
+		return err
+	}
+
+	user, err := s.store.GetUserByUID(ctx, apiKey.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get key user: %w", err)
+	}
+
+	go func() {
+		_ = s.store.IncrementAPIKeyUsage(ctx, apiKey.ID)
+	}()
+
+	c.Set(contextKeyUser, user)
+	c.Set(contextKeyAPIKey, apiKey)
+	c.Set(contextKeyAuthMethod, authMethodAPIKey)
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestParseSignedAuth(t *testing.T) {
+	t.Parallel()
+
+	sig := strings.Repeat("ab", 32)
+
+	auth, err := parseSignedAuth("key=dbb_abcd, ts=1700000000, nonce=n1, sig=" + sig)
+	if err != nil {
+		t.Fatalf("parseSignedAuth() error = %v", err)
+	}
+
+	if auth.KeyPrefix != "dbb_abcd" || auth.Nonce != "n1" || auth.Timestamp.Unix() != 1700000000 {
+		t.Errorf("parseSignedAuth() = %+v", auth)
+	}
+
+	for _, params := range []string{
+		"",
+		"key=dbb_abcd, ts=1700000000, nonce=n1", // no signature
+		"key=dbb_abcd, ts=1700000000, sig=" + sig,                     // no nonce
+		"key=dbb, ts=1700000000, nonce=n1, sig=" + sig,                // short prefix
+		"key=dbb_abcd, ts=yesterday, nonce=n1, sig=" + sig,            // bad timestamp
+		"key=dbb_abcd, ts=1700000000, nonce=n1, sig=zz",               // bad hex
+		"key=dbb_abcd, ts=1700000000, nonce=n1, sig=abcd",             // short signature
+		"key=dbb_abcd, ts=1700000000, nonce=n1, sig=" + sig + ", x=1", // unknown parameter
+	} {
+		if _, err := parseSignedAuth(params); !errors.Is(err, errSignatureMalformed) {
+			t.Errorf("parseSignedAuth(%q) error = %v, want errSignatureMalformed", params, err)
+		}
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("dbb_0123456789abcdefghijklmnopqrstuv")
+	sig := signRequest(key, "POST", "/api/v1/grants?x=1", 1700000000, "n1", []byte(`{"a":1}`))
+
+	if got := signRequest(key, "POST", "/api/v1/grants?x=1", 1700000000, "n1", []byte(`{"a":1}`)); !bytes.Equal(got, sig) {
+		t.Error("signRequest() is not deterministic")
+	}
+
+	// Every signed element changes the signature.
+	for name, other := range map[string][]byte{
+		"method": signRequest(key, "PUT", "/api/v1/grants?x=1", 1700000000, "n1", []byte(`{"a":1}`)),
+		"path":   signRequest(key, "POST", "/api/v1/grants?x=2", 1700000000, "n1", []byte(`{"a":1}`)),
+		"ts":     signRequest(key, "POST", "/api/v1/grants?x=1", 1700000001, "n1", []byte(`{"a":1}`)),
+		"nonce":  signRequest(key, "POST", "/api/v1/grants?x=1", 1700000000, "n2", []byte(`{"a":1}`)),
+		"body":   signRequest(key, "POST", "/api/v1/grants?x=1", 1700000000, "n1", []byte(`{"a":2}`)),
+	} {
+		if bytes.Equal(other, sig) {
+			t.Errorf("changing the %s does not change the signature", name)
+		}
+	}
+}
+
+func TestNonceCache(t *testing.T) {
+	t.Parallel()
+
+	cache := newNonceCache(5 * time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	if err := cache.checkTimestamp(now.Add(-4*time.Minute), now); err != nil {
+		t.Errorf("checkTimestamp(within skew) error = %v", err)
+	}
+
+	if err := cache.checkTimestamp(now.Add(6*time.Minute), now); !errors.Is(err, errSignatureSkew) {
+		t.Errorf("checkTimestamp(ahead) error = %v, want errSignatureSkew", err)
+	}
+
+	if err := cache.checkTimestamp(now.Add(-6*time.Minute), now); !errors.Is(err, errSignatureSkew) {
+		t.Errorf("checkTimestamp(behind) error = %v, want errSignatureSkew", err)
+	}
+
+	if err := cache.use("dbb_abcd", "n1", now, now); err != nil {
+		t.Fatalf("use() error = %v", err)
+	}
+
+	if err := cache.use("dbb_abcd", "n1", now, now.Add(time.Minute)); !errors.Is(err, errSignatureReplayed) {
+		t.Errorf("use(replay) error = %v, want errSignatureReplayed", err)
+	}
+
+	if err := cache.use("dbb_efgh", "n1", now, now); err != nil {
+		t.Errorf("use(other key) error = %v", err)
+	}
+
+	// Once the timestamp left the window, the entry is collected.
+	later := now.Add(11 * time.Minute)
+	if err := cache.use("dbb_ijkl", "n1", later, later); err != nil {
+		t.Fatalf("use() error = %v", err)
+	}
+
+	if _, ok := cache.seen["dbb_abcd:n1"]; ok {
+		t.Error("expired nonce was not collected")
+	}
+}
+
+func TestSignedAuthBodyTooLarge(t *testing.T) { //nolint:paralleltest // shared migration lock
+	server, dataStore := setupTestServer(t)
+	server.encryptionKey = dbTestEncryptionKey
+
+	user := createTestUser(t, dataStore, "signed-body", "signedpass123", []string{store.RoleViewer})
+
+	apiKey, _, err := dataStore.CreateAPIKey(context.Background(), user.UID, "signer", nil, dbTestEncryptionKey)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	router := gin.New()
+	router.Use(server.authMiddleware())
+	router.POST("/api/v1/reports/verify", server.handleVerifyReport)
+
+	// The body is refused before the signature is even checked.
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/reports/verify",
+		bytes.NewReader(make([]byte, maxSignedBodySize+1)))
+	req.Header.Set("Authorization", fmt.Sprintf("%s key=%s, ts=%d, nonce=n1, sig=%s",
+		signatureScheme, apiKey.KeyPrefix, time.Now().Unix(), strings.Repeat("00", 32)))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+}
@@ -35,6 +35,7 @@ const (
 	msgNoLongerPending = "This request is no longer pending."
 	msgRequestNotFound = "That grant request no longer exists."
 	msgDefinitionGone  = "The grant definition is no longer active, so this request can't be approved."
+	msgGrantRevoked    = "The grant to extend has been revoked, so this request can't be approved."
 	msgOutOfScope      = "The grant definition's scope no longer covers this user or database, " +
 		"so this request can't be approved. Create a direct grant instead."
 	msgDecideFailed = "Something went wrong deciding this request. Try again from the dbbat UI."
@@ -314,6 +315,8 @@ func (s *Server) handleSlackDecisionError(
 		s.postEphemeral(ctx, responseURL, msgRequestNotFound)
 	case errors.Is(err, store.ErrDefinitionInactive):
 		s.postEphemeral(ctx, responseURL, msgDefinitionGone)
+	case errors.Is(err, store.ErrGrantNotExtendable):
+		s.postEphemeral(ctx, responseURL, msgGrantRevoked)
 	case errors.Is(err, ErrRequestOutOfScope):
 		s.postEphemeral(ctx, responseURL, msgOutOfScope)
 	default:
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Requests are counted per route in one-minute buckets kept for a day; the
+// SLO report aggregates them over each of sloWindows.
+const (
+	sloBucketWidth = time.Minute
+	sloBucketCount = 24 * 60
+)
+
+// defaultSLOObjective is the availability target the report compares each
+// window against when the objective parameter is not given.
+const defaultSLOObjective = 0.999
+
+// sloWindows are the rolling windows GET /admin/slo reports, by label.
+var sloWindows = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+// sloLatencyBounds are the upper bounds of the latency histogram buckets; a
+// last bucket holds the slower requests.
+var sloLatencyBounds = [...]time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// sloBucket counts the requests of one route during one minute.
+type sloBucket struct {
+	minute     int64
+	requests   int64
+	errors     int64
+	latencySum time.Duration
+	latencyMax time.Duration
+	histogram  [len(sloLatencyBounds) + 1]uint32
+}
+
+// routeMetrics is the bucket ring of one route.
+type routeMetrics struct {
+	mu      sync.Mutex
+	buckets [sloBucketCount]sloBucket
+}
+
+// apiMetrics tracks request outcomes and latency per API route, in memory and
+// since process start, for the SLO report.
+type apiMetrics struct {
+	mu     sync.RWMutex
+	routes map[string]*routeMetrics
+	now    func() time.Time
+}
+
+func newAPIMetrics() *apiMetrics {
+	return &apiMetrics{routes: make(map[string]*routeMetrics), now: time.Now}
+}
+
+// observe records one request. Server errors (5xx) count against
+// availability; client errors do not.
+func (m *apiMetrics) observe(route string, status int, latency time.Duration) {
+	m.mu.RLock()
+	rm, ok := m.routes[route]
+	m.mu.RUnlock()
+
+	if !ok {
+		m.mu.Lock()
+
+		if rm, ok = m.routes[route]; !ok {
+			rm = &routeMetrics{}
+			m.routes[route] = rm
+		}
+
+		m.mu.Unlock()
+	}
+
+	minute := m.now().Unix() / int64(sloBucketWidth/time.Second)
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	b := &rm.buckets[minute%sloBucketCount]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+
+	b.requests++
+	if status >= http.StatusInternalServerError {
+		b.errors++
+	}
+
+	b.latencySum += latency
+	b.latencyMax = max(b.latencyMax, latency)
+
+	slot := sort.Search(len(sloLatencyBounds), func(i int) bool { return latency <= sloLatencyBounds[i] })
+	b.histogram[slot]++
+}
+
+// middleware records every request matched to an API route. Unmatched paths
+// (the SPA, 404s) are not tracked, to keep the route set bounded.
+func (m *apiMetrics) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if !strings.HasPrefix(route, "/api/") {
+			return
+		}
+
+		m.observe(c.Request.Method+" "+route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// SLOWindowStats summarizes the requests of one rolling window.
+// Availability and latencies are omitted when there were no requests.
+type SLOWindowStats struct {
+	Requests       int64    `json:"requests"`
+	Errors         int64    `json:"errors"`
+	Availability   *float64 `json:"availability,omitempty"`
+	MeetsObjective *bool    `json:"meets_objective,omitempty"`
+	LatencyAvgMs   *float64 `json:"latency_avg_ms,omitempty"`
+	LatencyP95Ms   *float64 `json:"latency_p95_ms,omitempty"`
+	LatencyP99Ms   *float64 `json:"latency_p99_ms,omitempty"`
+}
+
+// SLORouteStats is the report of one route.
+type SLORouteStats struct {
+	Route   string                    `json:"route"`
+	Windows map[string]SLOWindowStats `json:"windows"`
+}
+
+// SLOResponse is the body of GET /admin/slo.
+type SLOResponse struct {
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Objective   float64                   `json:"objective"`
+	Overall     map[string]SLOWindowStats `json:"overall"`
+	Routes      []SLORouteStats           `json:"routes"`
+}
+
+// sloAccumulator sums buckets over a window.
+type sloAccumulator struct {
+	requests   int64
+	errors     int64
+	latencySum time.Duration
+	latencyMax time.Duration
+	histogram  [len(sloLatencyBounds) + 1]uint64
+}
+
+func (a *sloAccumulator) add(b *sloBucket) {
+	a.requests += b.requests
+	a.errors += b.errors
+	a.latencySum += b.latencySum
+	a.latencyMax = max(a.latencyMax, b.latencyMax)
+
+	for i, n := range b.histogram {
+		a.histogram[i] += uint64(n)
+	}
+}
+
+func (a *sloAccumulator) merge(o *sloAccumulator) {
+	a.requests += o.requests
+	a.errors += o.errors
+	a.latencySum += o.latencySum
+	a.latencyMax = max(a.latencyMax, o.latencyMax)
+
+	for i, n := range o.histogram {
+		a.histogram[i] += n
+	}
+}
+
+// quantile returns the upper bound of the histogram bucket holding the q-th
+// request, or the slowest request when it is past the last bound.
+func (a *sloAccumulator) quantile(q float64) time.Duration {
+	rank := uint64(q * float64(a.requests))
+
+	var seen uint64
+
+	for i, n := range a.histogram {
+		seen += n
+		if seen > rank {
+			if i < len(sloLatencyBounds) {
+				return min(sloLatencyBounds[i], a.latencyMax)
+			}
+
+			break
+		}
+	}
+
+	return a.latencyMax
+}
+
+func (a *sloAccumulator) stats(objective float64) SLOWindowStats {
+	st := SLOWindowStats{Requests: a.requests, Errors: a.errors}
+	if a.requests == 0 {
+		return st
+	}
+
+	availability := 1 - float64(a.errors)/float64(a.requests)
+	meets := availability >= objective
+	avg := durationMs(a.latencySum / time.Duration(a.requests))
+	p95 := durationMs(a.quantile(0.95))
+	p99 := durationMs(a.quantile(0.99))
+
+	st.Availability = &availability
+	st.MeetsObjective = &meets
+	st.LatencyAvgMs = &avg
+	st.LatencyP95Ms = &p95
+	st.LatencyP99Ms = &p99
+
+	return st
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// report aggregates every route over each window.
+func (m *apiMetrics) report(objective float64) SLOResponse {
+	now := m.now()
+	minute := now.Unix() / int64(sloBucketWidth/time.Second)
+
+	m.mu.RLock()
+	routes := make(map[string]*routeMetrics, len(m.routes))
+	for route, rm := range m.routes {
+		routes[route] = rm
+	}
+	m.mu.RUnlock()
+
+	overall := make([]sloAccumulator, len(sloWindows))
+	resp := SLOResponse{
+		GeneratedAt: now.UTC(),
+		Objective:   objective,
+		Overall:     make(map[string]SLOWindowStats, len(sloWindows)),
+		Routes:      make([]SLORouteStats, 0, len(routes)),
+	}
+
+	for route, rm := range routes {
+		windows := rm.aggregate(minute)
+
+		rs := SLORouteStats{Route: route, Windows: make(map[string]SLOWindowStats, len(sloWindows))}
+		for i, w := range sloWindows {
+			rs.Windows[w.label] = windows[i].stats(objective)
+			overall[i].merge(&windows[i])
+		}
+
+		resp.Routes = append(resp.Routes, rs)
+	}
+
+	for i, w := range sloWindows {
+		resp.Overall[w.label] = overall[i].stats(objective)
+	}
+
+	sort.Slice(resp.Routes, func(i, j int) bool { return resp.Routes[i].Route < resp.Routes[j].Route })
+
+	return resp
+}
+
+// aggregate sums the route's buckets over each of sloWindows, ending with the
+// current minute.
+func (rm *routeMetrics) aggregate(minute int64) []sloAccumulator {
+	windows := make([]sloAccumulator, len(sloWindows))
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	for i := range rm.buckets {
+		b := &rm.buckets[i]
+
+		age := time.Duration(minute-b.minute) * sloBucketWidth
+		if b.requests == 0 || age < 0 {
+			continue
+		}
+
+		for w := range sloWindows {
+			if age < sloWindows[w].duration {
+				windows[w].add(b)
+			}
+		}
+	}
+
+	return windows
+}
+
+// handleGetSLO reports API availability and latency per route over rolling
+// windows, so operators can alert on API health independently of the proxy.
+func (s *Server) handleGetSLO(c *gin.Context) {
+	objective := defaultSLOObjective
+
+	if raw := c.Query("objective"); raw != "" {
+		val, err := strconv.ParseFloat(raw, 64)
+		if err != nil || val <= 0 || val > 1 {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "objective must be a number in (0, 1]")
+			return
+		}
+
+		objective = val
+	}
+
+	successResponse(c, s.apiMetrics.report(objective))
+}
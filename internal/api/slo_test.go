@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIMetricsReport(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 15, 12, 0, 30, 0, time.UTC)
+	m := newAPIMetrics()
+	m.now = func() time.Time { return now }
+
+	const route = "GET /api/v1/queries"
+
+	// Two hours ago: only in the 24h window.
+	now = now.Add(-2 * time.Hour)
+	m.observe(route, http.StatusInternalServerError, 3*time.Second)
+
+	// Thirty minutes ago: in the 1h and 24h windows.
+	now = now.Add(90 * time.Minute)
+	m.observe(route, http.StatusBadGateway, 40*time.Millisecond)
+
+	// Now: in every window. Client errors do not count against availability.
+	now = now.Add(30 * time.Minute)
+	for range 8 {
+		m.observe(route, http.StatusOK, 5*time.Millisecond)
+	}
+
+	m.observe(route, http.StatusNotFound, 5*time.Millisecond)
+	m.observe("GET /api/v1/health", http.StatusOK, time.Millisecond)
+
+	resp := m.report(0.99)
+	require.Len(t, resp.Routes, 2)
+	assert.Equal(t, "GET /api/v1/health", resp.Routes[0].Route)
+
+	windows := resp.Routes[1].Windows
+
+	assert.Equal(t, int64(9), windows["5m"].Requests)
+	assert.Equal(t, int64(0), windows["5m"].Errors)
+	assert.InDelta(t, 1.0, *windows["5m"].Availability, 1e-9)
+	assert.True(t, *windows["5m"].MeetsObjective)
+	assert.InDelta(t, 5.0, *windows["5m"].LatencyP95Ms, 1e-9)
+
+	assert.Equal(t, int64(10), windows["1h"].Requests)
+	assert.Equal(t, int64(1), windows["1h"].Errors)
+	assert.InDelta(t, 0.9, *windows["1h"].Availability, 1e-9)
+	assert.False(t, *windows["1h"].MeetsObjective)
+
+	assert.Equal(t, int64(11), windows["24h"].Requests)
+	assert.Equal(t, int64(2), windows["24h"].Errors)
+	assert.InDelta(t, 3000.0, *windows["24h"].LatencyP99Ms, 1e-9, "past the last bound, the slowest request")
+
+	assert.Equal(t, int64(12), resp.Overall["24h"].Requests)
+	assert.Equal(t, int64(2), resp.Overall["24h"].Errors)
+}
+
+func TestAPIMetricsBucketsExpire(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	m := newAPIMetrics()
+	m.now = func() time.Time { return now }
+
+	m.observe("GET /api/v1/users", http.StatusOK, time.Millisecond)
+
+	// A day later the same ring slot is reused for the new minute.
+	now = now.Add(24 * time.Hour)
+	m.observe("GET /api/v1/users", http.StatusInternalServerError, time.Millisecond)
+
+	stats := m.report(defaultSLOObjective).Overall["24h"]
+	assert.Equal(t, int64(1), stats.Requests)
+	assert.Equal(t, int64(1), stats.Errors)
+
+	now = now.Add(25 * time.Hour)
+
+	stats = m.report(defaultSLOObjective).Overall["24h"]
+	assert.Equal(t, int64(0), stats.Requests)
+	assert.Nil(t, stats.Availability)
+}
+
+func TestAPIMetricsMiddleware(t *testing.T) {
+	t.Parallel()
+
+	m := newAPIMetrics()
+
+	router := gin.New()
+	router.Use(m.middleware())
+	router.Use(gin.Recovery())
+	router.GET("/api/v1/things/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/v1/boom", func(*gin.Context) { panic("boom") })
+	router.NoRoute(func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/api/v1/things/1", "/api/v1/things/2", "/api/v1/boom", "/app/queries"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	resp := m.report(defaultSLOObjective)
+	require.Len(t, resp.Routes, 2, "unmatched paths are not tracked")
+	assert.Equal(t, "GET /api/v1/boom", resp.Routes[0].Route)
+	assert.Equal(t, int64(1), resp.Routes[0].Windows["5m"].Errors, "a panic counts as a server error")
+	assert.Equal(t, "GET /api/v1/things/:id", resp.Routes[1].Route)
+	assert.Equal(t, int64(2), resp.Routes[1].Windows["5m"].Requests)
+}
+
+func TestHandleGetSLOInvalidObjective(t *testing.T) {
+	t.Parallel()
+
+	server := &Server{apiMetrics: newAPIMetrics()}
+
+	for _, objective := range []string{"abc", "0", "1.5"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/slo?objective="+objective, nil)
+
+		server.handleGetSLO(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, objective)
+	}
+}
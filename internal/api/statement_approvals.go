@@ -0,0 +1,195 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// DenyStatementApprovalRequest is the body for POST
+// /statement-approvals/:uid/deny.
+type DenyStatementApprovalRequest struct {
+	Reason string `json:"reason"`
+}
+
+// maxDecisionReasonLen bounds the reason of a denial, which is shown to the
+// statement's user.
+const maxDecisionReasonLen = 1000
+
+// handleListStatementApprovals — role-aware. Admins see all (filterable);
+// non-admins see only their own statements.
+func (s *Server) handleListStatementApprovals(c *gin.Context) {
+	currentUser := getCurrentUser(c)
+	filter := store.StatementApprovalFilter{}
+
+	if !currentUser.IsAdmin() {
+		filter.UserID = &currentUser.UID
+	} else if userID := c.Query("user_id"); userID != "" {
+		if uid, err := uuid.Parse(userID); err == nil {
+			filter.UserID = &uid
+		}
+	}
+
+	if status := c.Query("status"); status != "" {
+		s := store.StatementApprovalStatus(status)
+		filter.Status = &s
+	}
+
+	if databaseID := c.Query("database_id"); databaseID != "" {
+		if uid, err := uuid.Parse(databaseID); err == nil {
+			filter.DatabaseID = &uid
+		}
+	}
+
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+	filter.Limit, filter.Offset = p.Limit, p.Offset
+
+	approvals, err := s.store.ListStatementApprovals(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to list statement approvals")
+
+		return
+	}
+
+	total, err := s.store.CountStatementApprovals(c.Request.Context(), filter)
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to count statement approvals")
+
+		return
+	}
+
+	pageResponse(c, p, total, gin.H{"statement_approvals": approvals})
+}
+
+// handleGetStatementApproval — role-aware: users can fetch their own
+// statements, admins fetch anyone's.
+func (s *Server) handleGetStatementApproval(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid statement approval UID")
+
+		return
+	}
+
+	approval, err := s.store.GetStatementApproval(c.Request.Context(), uid)
+	if err != nil {
+		if errors.Is(err, store.ErrStatementApprovalNotFound) {
+			writeError(c, http.StatusNotFound, ErrCodeNotFound, "statement approval not found")
+
+			return
+		}
+
+		writeInternalError(c, s.logger, err, "failed to get statement approval")
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+
+	if !currentUser.IsAdmin() && approval.UserID != currentUser.UID {
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "no access to this statement approval")
+
+		return
+	}
+
+	successResponse(c, approval)
+}
+
+// handleApproveStatementApproval lets the held statement run. The approver
+// must be another admin than the statement's user.
+func (s *Server) handleApproveStatementApproval(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid statement approval UID")
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	ctx := c.Request.Context()
+
+	approval, err := s.store.ApproveStatementApproval(ctx, uid, currentUser.UID)
+	if err != nil {
+		s.writeStatementApprovalError(c, err, "failed to approve statement")
+
+		return
+	}
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		UserID:      &approval.UserID,
+		PerformedBy: &currentUser.UID,
+		Payload: audit.StatementApprovalApprovedV1{
+			StatementApprovalUID: approval.UID,
+			ConnectionID:         approval.ConnectionID,
+			DatabaseID:           approval.DatabaseID,
+		},
+	})
+
+	successResponse(c, approval)
+}
+
+// handleDenyStatementApproval refuses the held statement, with an optional
+// reason shown to its user.
+func (s *Server) handleDenyStatementApproval(c *gin.Context) {
+	uid, err := parseUIDParam(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "invalid statement approval UID")
+
+		return
+	}
+
+	var body DenyStatementApprovalRequest
+	_ = c.ShouldBindJSON(&body) // Reason is optional; ignore parse errors on empty body
+
+	if len(body.Reason) > maxDecisionReasonLen {
+		writeError(c, http.StatusBadRequest, ErrCodeValidationError, "reason too long")
+
+		return
+	}
+
+	currentUser := getCurrentUser(c)
+	ctx := c.Request.Context()
+
+	approval, err := s.store.DenyStatementApproval(ctx, uid, currentUser.UID, body.Reason)
+	if err != nil {
+		s.writeStatementApprovalError(c, err, "failed to deny statement")
+
+		return
+	}
+
+	_ = audit.Emit(ctx, s.store, audit.Event{
+		UserID:      &approval.UserID,
+		PerformedBy: &currentUser.UID,
+		Payload: audit.StatementApprovalDeniedV1{
+			StatementApprovalUID: approval.UID,
+			ConnectionID:         approval.ConnectionID,
+			DatabaseID:           approval.DatabaseID,
+			Reason:               body.Reason,
+		},
+	})
+
+	successResponse(c, approval)
+}
+
+// writeStatementApprovalError maps the errors of a statement approval
+// decision.
+func (s *Server) writeStatementApprovalError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, store.ErrStatementApprovalNotFound):
+		writeError(c, http.StatusNotFound, ErrCodeNotFound, "statement approval not found")
+	case errors.Is(err, store.ErrStatementApprovalNotPending):
+		writeError(c, http.StatusConflict, ErrCodeConflict, "statement is no longer awaiting approval")
+	case errors.Is(err, store.ErrSelfApproval):
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "a statement must be approved by another administrator than its user")
+	default:
+		writeInternalError(c, s.logger, err, msg)
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// storageProjectionDays are the horizons the storage report projects the
+// database size to.
+var storageProjectionDays = []int{30, 90, 365}
+
+// StorageProjection is the projected storage database size after Days days of
+// growth at the current rate.
+type StorageProjection struct {
+	Days          int   `json:"days"`
+	DatabaseBytes int64 `json:"database_bytes"`
+}
+
+// StorageResponse is the body of GET /admin/storage.
+type StorageResponse struct {
+	*store.StorageUsage
+	BytesPerDay int64               `json:"bytes_per_day"`
+	Projections []StorageProjection `json:"projections"`
+	// CapacityBytes echoes the capacity_bytes parameter; FullInDays is only
+	// set with it, and only when the database is growing.
+	CapacityBytes *int64   `json:"capacity_bytes,omitempty"`
+	FullInDays    *float64 `json:"full_in_days,omitempty"`
+	// QueryStorage is the current result capture configuration, the main
+	// lever on growth.
+	QueryStorage StorageCaptureSettings `json:"query_storage"`
+}
+
+// StorageCaptureSettings mirrors config.QueryStorageConfig.
+type StorageCaptureSettings struct {
+	StoreResults   bool  `json:"store_results"`
+	MaxResultRows  int   `json:"max_result_rows"`
+	MaxResultBytes int64 `json:"max_result_bytes"`
+	CompressRows   bool  `json:"compress_rows"`
+}
+
+// handleGetStorage reports the size and growth of dbbat's own storage
+// database, with linear projections so operators see when it will fill up.
+func (s *Server) handleGetStorage(c *gin.Context) {
+	var capacity *int64
+
+	if raw := c.Query("capacity_bytes"); raw != "" {
+		val, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || val <= 0 {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "capacity_bytes must be a positive integer")
+			return
+		}
+
+		capacity = &val
+	}
+
+	usage, err := s.store.GetStorageUsage(c.Request.Context(), time.Now())
+	if err != nil {
+		writeInternalError(c, s.logger, err, "failed to get storage usage")
+		return
+	}
+
+	resp := StorageResponse{
+		StorageUsage:  usage,
+		BytesPerDay:   usage.BytesPerDay(),
+		CapacityBytes: capacity,
+	}
+
+	for _, days := range storageProjectionDays {
+		resp.Projections = append(resp.Projections, StorageProjection{
+			Days:          days,
+			DatabaseBytes: usage.DatabaseBytes + int64(days)*resp.BytesPerDay,
+		})
+	}
+
+	if capacity != nil && resp.BytesPerDay > 0 {
+		days := max(float64(*capacity-usage.DatabaseBytes)/float64(resp.BytesPerDay), 0)
+		resp.FullInDays = &days
+	}
+
+	if s.queryStorage != nil {
+		queryStorage := s.queryStorage.Load()
+		resp.QueryStorage = StorageCaptureSettings{
+			StoreResults:   queryStorage.StoreResults,
+			MaxResultRows:  queryStorage.MaxResultRows,
+			MaxResultBytes: queryStorage.MaxResultBytes,
+			CompressRows:   queryStorage.CompressRows,
+		}
+	}
+
+	successResponse(c, resp)
+}
+
+// handleListStorageQueries lists the last queries dbbat ran against its own
+// storage database, newest first and with their literal values masked, in the
+// same shape as the query log.
+func (s *Server) handleListStorageQueries(c *gin.Context) {
+	if s.storageQueries == nil {
+		writeError(c, http.StatusNotFound, ErrCodeNotFound,
+			"storage query recording is disabled (DBB_SELF_OBSERVABILITY_ENABLED)")
+		return
+	}
+
+	limit := 100
+
+	if raw := c.Query("limit"); raw != "" {
+		val, err := strconv.Atoi(raw)
+		if err != nil || val <= 0 {
+			writeError(c, http.StatusBadRequest, ErrCodeValidationError, "limit must be a positive integer")
+			return
+		}
+
+		limit = val
+	}
+
+	successResponse(c, gin.H{"queries": s.storageQueries.Queries(limit)})
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fclairamb/dbbat/internal/selfobs"
+)
+
+func TestHandleGetStorageInvalidCapacity(t *testing.T) {
+	t.Parallel()
+
+	server := &Server{}
+
+	for _, capacity := range []string{"abc", "0", "-5"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/storage?capacity_bytes="+capacity, nil)
+
+		server.handleGetStorage(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, capacity)
+	}
+}
+
+func TestHandleListStorageQueries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		server *Server
+		query  string
+		want   int
+	}{
+		{"disabled", &Server{}, "", http.StatusNotFound},
+		{"invalid limit", &Server{storageQueries: selfobs.NewRecorder(10)}, "?limit=-1", http.StatusBadRequest},
+		{"enabled", &Server{storageQueries: selfobs.NewRecorder(10)}, "?limit=5", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/storage/queries"+tt.query, nil)
+
+			tt.server.handleListStorageQueries(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -1,13 +1,13 @@
 package api
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -119,16 +119,13 @@ func (s *Server) handleCreateUserGroup(c *gin.Context) {
 		}
 	}
 
-	details, _ := json.Marshal(map[string]any{
-		"user_group_uid": created.UID,
-		"name":           created.Name,
-		"member_uids":    req.MemberUIDs,
-	})
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "user_group.created",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.UserGroupCreatedV1{
+			UserGroupUID: created.UID,
+			Name:         created.Name,
+			MemberUIDs:   req.MemberUIDs,
+		},
 	})
 
 	resp, err := s.groupWithMembers(c, created)
@@ -144,6 +141,11 @@ func (s *Server) handleCreateUserGroup(c *gin.Context) {
 // handleListUserGroups — admin-only. Groups are an access-control surface, so
 // they stay behind the admin gate like grant definition management.
 func (s *Server) handleListUserGroups(c *gin.Context) {
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+
 	groups, err := s.store.ListUserGroups(c.Request.Context())
 	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to list user groups")
@@ -151,6 +153,8 @@ func (s *Server) handleListUserGroups(c *gin.Context) {
 		return
 	}
 
+	total := len(groups)
+	groups = slicePage(groups, p)
 	out := make([]*userGroupResponse, 0, len(groups))
 
 	for i := range groups {
@@ -164,7 +168,7 @@ func (s *Server) handleListUserGroups(c *gin.Context) {
 		out = append(out, resp)
 	}
 
-	successResponse(c, gin.H{"user_groups": out})
+	pageResponse(c, p, total, gin.H{"user_groups": out})
 }
 
 // handleGetUserGroup — admin-only; returns the group plus its members.
@@ -270,16 +274,13 @@ func (s *Server) handleUpdateUserGroup(c *gin.Context) {
 
 	currentUser := getCurrentUser(c)
 
-	details, _ := json.Marshal(map[string]any{
-		"user_group_uid": group.UID,
-		"name":           group.Name,
-		"member_uids":    req.MemberUIDs,
-	})
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "user_group.updated",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.UserGroupUpdatedV1{
+			UserGroupUID: group.UID,
+			Name:         group.Name,
+			MemberUIDs:   req.MemberUIDs,
+		},
 	})
 
 	resp, err := s.groupWithMembers(c, group)
@@ -318,12 +319,9 @@ func (s *Server) handleDeleteUserGroup(c *gin.Context) {
 
 	currentUser := getCurrentUser(c)
 
-	details, _ := json.Marshal(map[string]any{"user_group_uid": uid})
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "user_group.deleted",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload:     audit.UserGroupDeletedV1{UserGroupUID: uid},
 	})
 
 	successResponse(c, gin.H{"message": "user group deleted"})
@@ -381,16 +379,10 @@ func (s *Server) handleAddUserGroupMember(c *gin.Context) {
 
 	currentUser := getCurrentUser(c)
 
-	details, _ := json.Marshal(map[string]any{
-		"user_group_uid": groupUID,
-		"user_uid":       userUID,
-	})
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "user_group.member_added",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		UserID:      &userUID,
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload:     audit.UserGroupMemberAddedV1{UserGroupUID: groupUID, UserUID: userUID},
 	})
 
 	successResponse(c, gin.H{"message": "member added"})
@@ -427,16 +419,10 @@ func (s *Server) handleRemoveUserGroupMember(c *gin.Context) {
 
 	currentUser := getCurrentUser(c)
 
-	details, _ := json.Marshal(map[string]any{
-		"user_group_uid": groupUID,
-		"user_uid":       userUID,
-	})
-
-	_ = s.store.LogAuditEvent(ctx, &store.AuditEvent{
-		EventType:   "user_group.member_removed",
+	_ = audit.Emit(ctx, s.store, audit.Event{
 		UserID:      &userUID,
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload:     audit.UserGroupMemberRemovedV1{UserGroupUID: groupUID, UserUID: userUID},
 	})
 
 	successResponse(c, gin.H{"message": "member removed"})
@@ -451,6 +437,11 @@ func (s *Server) handleListUserGroupMembers(c *gin.Context) {
 		return
 	}
 
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+
 	ctx := c.Request.Context()
 
 	if _, err := s.store.GetUserGroup(ctx, uid); err != nil {
@@ -472,5 +463,5 @@ func (s *Server) handleListUserGroupMembers(c *gin.Context) {
 		return
 	}
 
-	successResponse(c, gin.H{"users": users})
+	pageResponse(c, p, len(users), gin.H{"users": slicePage(users, p)})
 }
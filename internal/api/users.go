@@ -1,7 +1,7 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"net/http"
 	"slices"
@@ -9,15 +9,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/crypto"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
 // CreateUserRequest represents the request to create a user
 type CreateUserRequest struct {
-	Username string   `json:"username" binding:"required"`
-	Password string   `json:"password" binding:"required"`
-	Roles    []string `json:"roles"`
+	Username string       `json:"username" binding:"required"`
+	Password string       `json:"password" binding:"required"`
+	Roles    []string     `json:"roles"`
+	Labels   store.Labels `json:"labels"`
 }
 
 // UpdateUserRequest represents the request to update a user
@@ -27,11 +29,14 @@ type UpdateUserRequest struct {
 	// GroupUIDs, when non-nil, replaces the user's group memberships
 	// wholesale. Admin-only, like Roles.
 	GroupUIDs []uuid.UUID `json:"group_uids"`
+	// Labels, when non-nil, replaces the user's labels. Admin-only.
+	Labels store.Labels `json:"labels"`
 }
 
 // setMongoVerifier derives and stores the user's MongoDB SCRAM-SHA-256 verifier
 // from their new plaintext password, so they can authenticate to the MongoDB
-// proxy with the driver-default SCRAM-SHA-256 instead of PLAIN. It is a
+// proxy with the driver-default SCRAM-SHA-256 instead of PLAIN (and to the
+// PostgreSQL proxy with SCRAM-SHA-256 instead of cleartext). It is a
 // best-effort optimisation layered on top of the Argon2id password hash: any
 // failure is logged but never fails the password change (PLAIN stays available).
 func (s *Server) setMongoVerifier(c *gin.Context, userID uuid.UUID, password string) {
@@ -49,6 +54,10 @@ func (s *Server) handleCreateUser(c *gin.Context) {
 		return
 	}
 
+	if !validLabels(c, req.Labels) {
+		return
+	}
+
 	// Hash password
 	passwordHash, err := crypto.HashPassword(req.Password)
 	if err != nil {
@@ -57,7 +66,29 @@ func (s *Server) handleCreateUser(c *gin.Context) {
 	}
 
 	// Create user
-	user, err := s.store.CreateUser(c.Request.Context(), req.Username, passwordHash, req.Roles)
+	currentUser := getCurrentUser(c)
+
+	var user *store.User
+
+	err = s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		var err error
+		if user, err = tx.CreateUser(ctx, req.Username, passwordHash, req.Roles); err != nil {
+			return err
+		}
+
+		if len(req.Labels) > 0 {
+			if err := tx.UpdateUser(ctx, user.UID, store.UserUpdate{Labels: req.Labels}); err != nil {
+				return err
+			}
+			user.Labels = req.Labels
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			UserID:      &user.UID,
+			PerformedBy: &currentUser.UID,
+			Payload:     audit.UserCreatedV1{Username: user.Username, Roles: user.Roles, Labels: user.Labels},
+		})
+	})
 	if err != nil {
 		if errors.Is(err, store.ErrUserNameConflict) {
 			writeError(c, http.StatusConflict, ErrCodeDuplicateName, err.Error())
@@ -70,19 +101,6 @@ func (s *Server) handleCreateUser(c *gin.Context) {
 	// Store the MongoDB SCRAM verifier so the user can use SCRAM-SHA-256.
 	s.setMongoVerifier(c, user.UID, req.Password)
 
-	// Log audit event
-	currentUser := getCurrentUser(c)
-	details, _ := json.Marshal(map[string]interface{}{
-		"username": user.Username,
-		"roles":    user.Roles,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "user.created",
-		UserID:      &user.UID,
-		PerformedBy: &currentUser.UID,
-		Details:     details,
-	})
-
 	successResponse(c, user)
 }
 
@@ -91,19 +109,42 @@ func (s *Server) handleCreateUser(c *gin.Context) {
 func (s *Server) handleListUsers(c *gin.Context) {
 	currentUser := getCurrentUser(c)
 
-	// Admins and viewers can see all users
-	if currentUser.IsAdmin() || currentUser.IsViewer() {
-		users, err := s.store.ListUsers(c.Request.Context())
+	labels, ok := bindLabelSelector(c)
+	if !ok {
+		return
+	}
+
+	p, ok := pageFromRequest(c, 0)
+	if !ok {
+		return
+	}
+
+	// Admins, viewers and auditors can see all users
+	if currentUser.Can(store.PermissionQueriesRead) {
+		filter := store.UserFilter{Labels: labels, Limit: p.Limit, Offset: p.Offset}
+
+		users, err := s.store.ListUsers(c.Request.Context(), filter)
 		if err != nil {
 			writeInternalError(c, s.logger, err, "failed to list users")
 			return
 		}
-		successResponse(c, gin.H{"users": users})
+
+		total, err := s.store.CountUsers(c.Request.Context(), filter)
+		if err != nil {
+			writeInternalError(c, s.logger, err, "failed to count users")
+			return
+		}
+
+		pageResponse(c, p, total, gin.H{"users": users})
 		return
 	}
 
 	// Others can only see themselves
-	successResponse(c, gin.H{"users": []any{currentUser}})
+	users := []*store.User{}
+	if currentUser.Labels.Contains(labels) {
+		users = append(users, currentUser)
+	}
+	pageResponse(c, p, len(users), gin.H{"users": slicePage(users, p)})
 }
 
 // handleGetUser retrieves a specific user
@@ -182,8 +223,13 @@ func (s *Server) handleUpdateUser(c *gin.Context) {
 		return
 	}
 
+	if !validLabels(c, req.Labels) {
+		return
+	}
+
 	updates := store.UserUpdate{
-		Roles: req.Roles,
+		Roles:  req.Roles,
+		Labels: req.Labels,
 	}
 
 	// Hash password if provided
@@ -213,29 +259,25 @@ func (s *Server) handleUpdateUser(c *gin.Context) {
 		s.setMongoVerifier(c, uid, *req.Password)
 	}
 
-	// Log audit event
-	details, _ := json.Marshal(map[string]interface{}{
-		"updated_fields": req,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "user.updated",
+	// Log audit event; the new password itself is never recorded
+	_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
 		UserID:      &uid,
 		PerformedBy: &currentUser.UID,
-		Details:     details,
+		Payload: audit.UserUpdatedV1{UpdatedFields: audit.UserUpdatedFieldsV1{
+			PasswordChanged: req.Password != nil,
+			Roles:           req.Roles,
+			GroupUIDs:       req.GroupUIDs,
+			Labels:          req.Labels,
+		}},
 	})
 
 	// Membership is access-relevant (it gates grant definitions), so record
 	// it as its own event rather than burying it in user.updated.
 	if req.GroupUIDs != nil {
-		groupDetails, _ := json.Marshal(map[string]interface{}{
-			"user_uid":   uid,
-			"group_uids": req.GroupUIDs,
-		})
-		_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-			EventType:   "user_group.membership_set",
+		_ = audit.Emit(c.Request.Context(), s.store, audit.Event{
 			UserID:      &uid,
 			PerformedBy: &currentUser.UID,
-			Details:     groupDetails,
+			Payload:     audit.UserGroupMembershipSetV1{UserUID: uid, GroupUIDs: req.GroupUIDs},
 		})
 	}
 
@@ -271,6 +313,11 @@ func (s *Server) checkSelfUpdateAllowed(
 		return false
 	}
 
+	if req.Labels != nil {
+		writeError(c, http.StatusForbidden, ErrCodeForbidden, "cannot change labels")
+		return false
+	}
+
 	return true
 }
 
@@ -365,21 +412,32 @@ func (s *Server) handleDeleteUser(c *gin.Context) {
 		}
 	}
 
-	if err := s.store.DeleteUser(c.Request.Context(), uid); err != nil {
+	var cascade *store.DeletionCascade
+
+	err = s.store.WithTx(c.Request.Context(), func(ctx context.Context, tx *store.Store) error {
+		var err error
+		if cascade, err = tx.DeleteUser(ctx, uid, currentUser.UID); err != nil {
+			return err
+		}
+
+		return audit.Emit(ctx, tx, audit.Event{
+			UserID:      &uid,
+			PerformedBy: &currentUser.UID,
+			Payload: audit.UserDeletedV1{
+				UserUID:           uid,
+				Username:          userToDelete.Username,
+				RevokedGrants:     len(cascade.RevokedGrants),
+				RevokedAPIKeys:    cascade.RevokedAPIKeys,
+				CancelledRequests: cascade.CancelledRequests,
+			},
+		})
+	})
+	if err != nil {
 		writeInternalError(c, s.logger, err, "failed to delete user")
 		return
 	}
 
-	// Log audit event
-	details, _ := json.Marshal(map[string]interface{}{
-		"user_uid": uid,
-	})
-	_ = s.store.LogAuditEvent(c.Request.Context(), &store.AuditEvent{
-		EventType:   "user.deleted",
-		UserID:      &uid,
-		PerformedBy: &currentUser.UID,
-		Details:     details,
-	})
+	s.signalRevokedGrants(c.Request.Context(), cascade.RevokedGrants)
 
 	successResponse(c, gin.H{"message": "user deleted"})
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API versions. Each is mounted under /api/<version>; v2 only carries the
+// unversioned basics until endpoints start moving there.
+const (
+	apiV1 = "v1"
+	apiV2 = "v2"
+)
+
+// supportedAPIVersions lists the mounted versions, oldest first.
+var supportedAPIVersions = []string{apiV1, apiV2}
+
+const (
+	// apiVersionHeader names the version serving a response. Clients may
+	// send it too, to assert the version they were written against.
+	apiVersionHeader = "API-Version"
+
+	contextKeyAPIVersion = "api_version"
+)
+
+// mountAPIVersion creates the /api/<version> group and its authenticated
+// subgroup with the shared middleware, so every version gets identical
+// authentication and rate limiting.
+func (s *Server) mountAPIVersion(router *gin.Engine, version string) (public, authenticated *gin.RouterGroup) {
+	public = router.Group("/api/"+version, apiVersionMiddleware(version))
+
+	authenticated = public.Group("")
+	authenticated.Use(s.authMiddleware())
+	// Add rate limiting after authentication (uses user ID for rate limiting)
+	if s.rateLimiter != nil {
+		authenticated.Use(s.rateLimiter.PostAuthMiddleware())
+	}
+
+	return public, authenticated
+}
+
+// apiVersionMiddleware records the version of the group serving the request
+// and echoes it in the API-Version response header. A request whose
+// API-Version header names another version is refused rather than silently
+// served with a different contract.
+func apiVersionMiddleware(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(apiVersionHeader, version)
+
+		if requested := c.GetHeader(apiVersionHeader); requested != "" {
+			negotiated, ok := negotiateAPIVersion(requested)
+			if !ok || negotiated != version {
+				writeError(c, http.StatusBadRequest, ErrCodeValidationError, fmt.Sprintf(
+					"%s %q does not match the requested path version %s", apiVersionHeader, requested, version))
+				c.Abort()
+
+				return
+			}
+		}
+
+		c.Set(contextKeyAPIVersion, version)
+		c.Next()
+	}
+}
+
+// negotiateAPIVersion maps a client-declared version ("v2", "V2" or "2") to
+// a supported one.
+func negotiateAPIVersion(requested string) (string, bool) {
+	version := strings.ToLower(strings.TrimSpace(requested))
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+
+	return version, slices.Contains(supportedAPIVersions, version)
+}
+
+// getAPIVersion returns the API version serving the request, so handlers
+// shared between versions can branch on it.
+func getAPIVersion(c *gin.Context) string {
+	if version := c.GetString(contextKeyAPIVersion); version != "" {
+		return version
+	}
+
+	return apiV1
+}
+
+// deprecation describes an endpoint slated for change or removal.
+type deprecation struct {
+	// Since is when the endpoint was deprecated.
+	Since time.Time
+	// Sunset, when set, is when the endpoint stops working.
+	Sunset time.Time
+	// Successor, when set, is the path of the replacement endpoint.
+	Successor string
+}
+
+// deprecated marks a route as deprecated with the Deprecation (RFC 9745) and
+// Sunset (RFC 8594) headers, plus a successor-version link when there is a
+// replacement. Register it before the handler:
+//
+//	users.GET("/:uid/old", deprecated(deprecation{...}), s.handleOld)
+func deprecated(d deprecation) gin.HandlerFunc {
+	deprecationValue := fmt.Sprintf("@%d", d.Since.Unix())
+
+	var sunsetValue string
+	if !d.Sunset.IsZero() {
+		sunsetValue = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	var linkValue string
+	if d.Successor != "" {
+		linkValue = fmt.Sprintf("<%s>; rel=\"successor-version\"", d.Successor)
+	}
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecationValue)
+
+		if sunsetValue != "" {
+			c.Header("Sunset", sunsetValue)
+		}
+
+		if linkValue != "" {
+			c.Header("Link", linkValue)
+		}
+
+		c.Next()
+	}
+}
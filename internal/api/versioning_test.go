@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateAPIVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		requested string
+		want      string
+		ok        bool
+	}{
+		{requested: "v1", want: apiV1, ok: true},
+		{requested: "V2", want: apiV2, ok: true},
+		{requested: " 2 ", want: apiV2, ok: true},
+		{requested: "v3", want: "v3", ok: false},
+		{requested: "latest", want: "vlatest", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := negotiateAPIVersion(tt.requested)
+		assert.Equal(t, tt.ok, ok, tt.requested)
+
+		if tt.ok {
+			assert.Equal(t, tt.want, got, tt.requested)
+		}
+	}
+}
+
+func TestAPIVersionMiddleware(t *testing.T) {
+	t.Parallel()
+
+	router := gin.New()
+	router.GET("/api/v2/ping", apiVersionMiddleware(apiV2), func(c *gin.Context) {
+		c.String(http.StatusOK, getAPIVersion(c))
+	})
+
+	do := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/ping", nil)
+		if header != "" {
+			req.Header.Set(apiVersionHeader, header)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := do("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, apiV2, w.Body.String())
+	assert.Equal(t, apiV2, w.Header().Get(apiVersionHeader))
+
+	assert.Equal(t, http.StatusOK, do("2").Code)
+	assert.Equal(t, http.StatusBadRequest, do("v1").Code)
+	assert.Equal(t, http.StatusBadRequest, do("v9").Code)
+}
+
+func TestDeprecated(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	router := gin.New()
+	router.GET("/old", deprecated(deprecation{Since: since, Sunset: sunset, Successor: "/api/v2/new"}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/soon", deprecated(deprecation{Since: since}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/old", nil))
+
+	assert.Equal(t, "@1790812800", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/new>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/soon", nil))
+
+	assert.Equal(t, "@1790812800", w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+	assert.Empty(t, w.Header().Get("Link"))
+}
@@ -0,0 +1,59 @@
+// Package audit defines the typed, versioned payloads of the audit log and
+// the emitter that records them.
+//
+// Every event type has one payload struct per schema version (UserCreatedV1,
+// …). The version only changes when a field is removed, renamed or changes
+// type; adding an optional field keeps it. The catalog of every event type and
+// version is served at GET /api/v1/audit/schema, so consumers of the audit log
+// (SIEM exports, compliance tooling) can rely on stable fields.
+//
+// Recorded events are also copied by the Pipeline to the configured sinks (a
+// JSON lines file, syslog, S3-compatible object storage), off the storage
+// database.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Payload is the typed details of one audit event type and schema version.
+type Payload interface {
+	EventType() string
+	SchemaVersion() int
+}
+
+// Event is one audit event to record.
+type Event struct {
+	Payload     Payload
+	UserID      *uuid.UUID // User the event is about, if any
+	PerformedBy *uuid.UUID // User who triggered it; nil for system actions
+}
+
+// Writer records audit log entries: the store, or a store transaction so the
+// event commits with the change it describes.
+type Writer interface {
+	LogAuditEvent(ctx context.Context, event *store.AuditEvent) error
+}
+
+// Emit serializes the event payload and records it with its type and schema
+// version.
+func Emit(ctx context.Context, w Writer, ev Event) error {
+	details, err := json.Marshal(ev.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s audit details: %w", ev.Payload.EventType(), err)
+	}
+
+	return w.LogAuditEvent(ctx, &store.AuditEvent{
+		EventType:     ev.Payload.EventType(),
+		SchemaVersion: ev.Payload.SchemaVersion(),
+		UserID:        ev.UserID,
+		PerformedBy:   ev.PerformedBy,
+		Details:       details,
+	})
+}
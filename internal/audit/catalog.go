@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventSchema describes the details of one event type at one schema version.
+type EventSchema struct {
+	EventType   string        `json:"event_type"`
+	Version     int           `json:"version"`
+	Description string        `json:"description"`
+	Fields      []FieldSchema `json:"fields"`
+}
+
+// FieldSchema describes one field of an event's details. Type is one of
+// string, integer, number, boolean, uuid, timestamp, json, array<T>,
+// map<T> (string keys) or object, whose own fields are listed in Fields.
+type FieldSchema struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Nullable bool          `json:"nullable,omitempty"` // May be null
+	Optional bool          `json:"optional,omitempty"` // May be absent
+	Fields   []FieldSchema `json:"fields,omitempty"`
+}
+
+// catalog lists every payload the emitter records, in the order the schema
+// is served. Superseded versions stay listed while they may still be found
+// in the audit log.
+var catalog = []struct {
+	payload     Payload
+	description string
+}{
+	{UserCreatedV1{}, "A user was created."},
+	{UserUpdatedV1{}, "A user's password, roles, groups or labels were updated."},
+	{UserDeletedV1{}, "A user was deleted, revoking their grants and API keys."},
+	{UserGroupCreatedV1{}, "A user group was created."},
+	{UserGroupUpdatedV1{}, "A user group was renamed or its members replaced."},
+	{UserGroupDeletedV1{}, "A user group was deleted."},
+	{UserGroupMemberAddedV1{}, "A user was added to a group."},
+	{UserGroupMemberRemovedV1{}, "A user was removed from a group."},
+	{UserGroupMembershipSetV1{}, "All the groups of a user were replaced."},
+	{DatabaseCreatedV1{}, "A database was registered."},
+	{DatabaseClonedV1{}, "A database was registered as a copy of another one."},
+	{DatabaseUpdatedV1{}, "A database was updated."},
+	{DatabaseDeletedV1{}, "A database was deleted, revoking its grants."},
+	{ServerConnectionTestedV1{}, "An administrator tested the connection to a database."},
+	{ServerPrivilegesCheckedV1{}, "An administrator checked the upstream privileges of a database's stored credentials."},
+	{ConnectionTerminatedV1{}, "An administrator terminated a live proxy connection."},
+	{APIKeyCreatedV1{}, "An API key was created."},
+	{APIKeyRevokedV1{}, "An API key was revoked."},
+	{DeviceAuthRequestedV1{}, "A CLI started a device authorization."},
+	{DeviceAuthApprovedV1{}, "A user approved a device authorization, issuing an API key."},
+	{DeviceAuthDeniedV1{}, "A user denied a device authorization."},
+	{GrantCreatedV1{}, "A grant was created."},
+	{GrantBulkCreatedV1{}, "A grant template was applied to several users and databases, creating one grant per pair."},
+	{GrantLabelsUpdatedV1{}, "The labels of a grant were replaced."},
+	{GrantRevokedV1{}, "A grant was revoked."},
+	{GrantClientRefusedV1{}, "A proxy connection was refused because it came from outside the networks the grant allows."},
+	{ProxyCredentialCreatedV1{}, "An administrator issued a one-time proxy credential on a grant for someone without a user."},
+	{ProxyCredentialUsedV1{}, "A one-time proxy credential opened its connection."},
+	{ProxyCredentialRevokedV1{}, "An administrator revoked a one-time proxy credential before it was used."},
+	{GrantRequestCreatedV1{}, "A user requested access through a grant definition."},
+	{GrantRequestExtensionRequestedV1{}, "A user requested an extension of one of their grants."},
+	{GrantRequestApprovedV1{}, "A grant request was approved, creating or extending a grant."},
+	{GrantRequestDeniedV1{}, "A grant request was denied."},
+	{GrantRequestCancelledV1{}, "A grant request was cancelled by its requester."},
+	{StatementApprovalApprovedV1{}, "An administrator approved a write statement held by the write_requires_approval control."},
+	{StatementApprovalDeniedV1{}, "An administrator denied a write statement held by the write_requires_approval control."},
+	{GrantDefinitionCreatedV1{}, "A grant definition was created."},
+	{GrantDefinitionUpdatedV1{}, "A grant definition was updated."},
+	{GrantDefinitionDeactivatedV1{}, "A grant definition was deactivated."},
+	{GrantTemplateCreatedV1{}, "A grant template was created."},
+	{GrantTemplateUpdatedV1{}, "A grant template was updated."},
+	{GrantTemplateDeletedV1{}, "A grant template was deleted."},
+	{QueryAlertCreatedV1{}, "A query alert was created."},
+	{QueryAlertUpdatedV1{}, "A query alert was updated, enabled or disabled."},
+	{QueryAlertDeletedV1{}, "A query alert was deleted."},
+	{RateLimitUpdatedV1{}, "The API rate limit override of a user or a role was set or removed."},
+}
+
+// Catalog returns the schema of every event type and version.
+func Catalog() []EventSchema {
+	schemas := make([]EventSchema, 0, len(catalog))
+
+	for _, entry := range catalog {
+		schemas = append(schemas, EventSchema{
+			EventType:   entry.payload.EventType(),
+			Version:     entry.payload.SchemaVersion(),
+			Description: entry.description,
+			Fields:      structFields(reflect.TypeOf(entry.payload)),
+		})
+	}
+
+	return schemas
+}
+
+var (
+	uuidType      = reflect.TypeOf(uuid.UUID{})
+	timeType      = reflect.TypeOf(time.Time{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	nullableKinds = map[reflect.Kind]bool{reflect.Pointer: true, reflect.Slice: true, reflect.Map: true}
+)
+
+// structFields describes the JSON fields of a struct type.
+func structFields(t reflect.Type) []FieldSchema {
+	fields := make([]FieldSchema, 0, t.NumField())
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		field := FieldSchema{
+			Name:     name,
+			Nullable: nullableKinds[f.Type.Kind()] && f.Type != rawJSONType,
+			Optional: strings.Contains(opts, "omitempty"),
+		}
+		field.Type, field.Fields = fieldType(f.Type)
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+func fieldType(t reflect.Type) (string, []FieldSchema) {
+	switch t {
+	case uuidType:
+		return "uuid", nil
+	case timeType:
+		return "timestamp", nil
+	case rawJSONType:
+		return "json", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return fieldType(t.Elem())
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+	case reflect.Float32, reflect.Float64:
+		return "number", nil
+	case reflect.Slice, reflect.Array:
+		elem, _ := fieldType(t.Elem())
+
+		return "array<" + elem + ">", nil
+	case reflect.Map:
+		elem, _ := fieldType(t.Elem())
+
+		return "map<" + elem + ">", nil
+	case reflect.Struct:
+		return "object", structFields(t)
+	default:
+		return "json", nil
+	}
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	seen := make(map[string]bool)
+
+	for _, schema := range Catalog() {
+		key := fmt.Sprintf("%s/v%d", schema.EventType, schema.Version)
+		assert.False(t, seen[key], "duplicate schema %s", key)
+		seen[key] = true
+
+		assert.Positive(t, schema.Version, schema.EventType)
+		assert.NotEmpty(t, schema.Description, schema.EventType)
+		assert.NotEmpty(t, schema.Fields, schema.EventType)
+
+		for _, field := range schema.Fields {
+			assert.NotEmpty(t, field.Name, schema.EventType)
+			assert.NotEmpty(t, field.Type, schema.EventType)
+		}
+	}
+}
+
+func TestCatalogFieldTypes(t *testing.T) {
+	t.Parallel()
+
+	var grantCreated EventSchema
+
+	for _, schema := range Catalog() {
+		if schema.EventType == EventGrantCreated {
+			grantCreated = schema
+		}
+	}
+
+	types := make(map[string]FieldSchema)
+	for _, field := range grantCreated.Fields {
+		types[field.Name] = field
+	}
+
+	assert.Equal(t, "uuid", types["grant_uid"].Type)
+	assert.Equal(t, "array<string>", types["controls"].Type)
+	assert.True(t, types["controls"].Nullable)
+	assert.Equal(t, "timestamp", types["expires_at"].Type)
+	assert.Equal(t, "map<string>", types["labels"].Type)
+}
+
+type recordingWriter struct {
+	events []*store.AuditEvent
+}
+
+func (w *recordingWriter) LogAuditEvent(_ context.Context, event *store.AuditEvent) error {
+	w.events = append(w.events, event)
+
+	return nil
+}
+
+func TestEmit(t *testing.T) {
+	t.Parallel()
+
+	w := &recordingWriter{}
+	user := uuid.New()
+	requestUID := uuid.New()
+
+	err := Emit(context.Background(), w, Event{
+		UserID:  &user,
+		Payload: GrantRequestDeniedV1{GrantRequestUID: requestUID, Reason: "no"},
+	})
+	require.NoError(t, err)
+	require.Len(t, w.events, 1)
+
+	ev := w.events[0]
+	assert.Equal(t, EventGrantRequestDenied, ev.EventType)
+	assert.Equal(t, 1, ev.SchemaVersion)
+	assert.Equal(t, &user, ev.UserID)
+	assert.Nil(t, ev.PerformedBy)
+
+	var details map[string]any
+	require.NoError(t, json.Unmarshal(ev.Details, &details))
+	assert.Equal(t, map[string]any{"grant_request_uid": requestUID.String(), "reason": "no"}, details)
+}
+
+func TestUserUpdatedNeverRecordsPassword(t *testing.T) {
+	t.Parallel()
+
+	blob, err := json.Marshal(UserUpdatedV1{UpdatedFields: UserUpdatedFieldsV1{PasswordChanged: true}})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"updated_fields":{"password_changed":true,"roles":null,"group_uids":null,"labels":null}}`, string(blob))
+}
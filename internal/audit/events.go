@@ -0,0 +1,605 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Audit event types.
+const (
+	EventUserCreated = "user.created"
+	EventUserUpdated = "user.updated"
+	EventUserDeleted = "user.deleted"
+
+	EventUserGroupCreated       = "user_group.created"
+	EventUserGroupUpdated       = "user_group.updated"
+	EventUserGroupDeleted       = "user_group.deleted"
+	EventUserGroupMemberAdded   = "user_group.member_added"
+	EventUserGroupMemberRemoved = "user_group.member_removed"
+	EventUserGroupMembershipSet = "user_group.membership_set"
+
+	EventDatabaseCreated = "database.created"
+	EventDatabaseCloned  = "database.cloned"
+	EventDatabaseUpdated = "database.updated"
+	EventDatabaseDeleted = "database.deleted"
+
+	EventServerConnectionTested  = "server.connection_tested"
+	EventServerPrivilegesChecked = "server.privileges_checked"
+
+	EventConnectionTerminated = "connection.terminated"
+
+	EventAPIKeyCreated = "api_key.created"
+	EventAPIKeyRevoked = "api_key.revoked"
+
+	EventDeviceAuthRequested = "device_auth.requested"
+	EventDeviceAuthApproved  = "device_auth.approved"
+	EventDeviceAuthDenied    = "device_auth.denied"
+
+	EventGrantCreated       = "grant.created"
+	EventGrantBulkCreated   = "grant.bulk_created"
+	EventGrantLabelsUpdated = "grant.labels_updated"
+	EventGrantRevoked       = "grant.revoked"
+	EventGrantClientRefused = "grant.client_refused"
+
+	EventProxyCredentialCreated = "proxy_credential.created"
+	EventProxyCredentialUsed    = "proxy_credential.used"
+	EventProxyCredentialRevoked = "proxy_credential.revoked"
+
+	EventGrantRequestCreated            = "grant_request.created"
+	EventGrantRequestExtensionRequested = "grant_request.extension_requested"
+	EventGrantRequestApproved           = "grant_request.approved"
+	EventGrantRequestDenied             = "grant_request.denied"
+	EventGrantRequestCancelled          = "grant_request.cancelled"
+
+	EventStatementApprovalApproved = "statement_approval.approved"
+	EventStatementApprovalDenied   = "statement_approval.denied"
+
+	EventGrantDefinitionCreated     = "grant_definition.created"
+	EventGrantDefinitionUpdated     = "grant_definition.updated"
+	EventGrantDefinitionDeactivated = "grant_definition.deactivated"
+
+	EventGrantTemplateCreated = "grant_template.created"
+	EventGrantTemplateUpdated = "grant_template.updated"
+	EventGrantTemplateDeleted = "grant_template.deleted"
+
+	EventQueryAlertCreated = "query_alert.created"
+	EventQueryAlertUpdated = "query_alert.updated"
+	EventQueryAlertDeleted = "query_alert.deleted"
+
+	EventRateLimitUpdated = "rate_limit.updated"
+)
+
+// UserCreatedV1 is the payload of user.created.
+type UserCreatedV1 struct {
+	Username string       `json:"username"`
+	Roles    []string     `json:"roles"`
+	Labels   store.Labels `json:"labels"`
+}
+
+func (UserCreatedV1) EventType() string  { return EventUserCreated }
+func (UserCreatedV1) SchemaVersion() int { return 1 }
+
+// UserUpdatedV1 is the payload of user.updated.
+type UserUpdatedV1 struct {
+	UpdatedFields UserUpdatedFieldsV1 `json:"updated_fields"`
+}
+
+// UserUpdatedFieldsV1 lists the fields of a user update. A null field was
+// left unchanged; a new password is only recorded as having changed.
+type UserUpdatedFieldsV1 struct {
+	PasswordChanged bool         `json:"password_changed,omitempty"`
+	Roles           []string     `json:"roles"`
+	GroupUIDs       []uuid.UUID  `json:"group_uids"`
+	Labels          store.Labels `json:"labels"`
+}
+
+func (UserUpdatedV1) EventType() string  { return EventUserUpdated }
+func (UserUpdatedV1) SchemaVersion() int { return 1 }
+
+// UserDeletedV1 is the payload of user.deleted. The username is recorded
+// because it can be reused by a new user once this one is deleted.
+type UserDeletedV1 struct {
+	UserUID           uuid.UUID `json:"user_uid"`
+	Username          string    `json:"username"`
+	RevokedGrants     int       `json:"revoked_grants"`
+	RevokedAPIKeys    int       `json:"revoked_api_keys"`
+	CancelledRequests int       `json:"cancelled_requests"`
+}
+
+func (UserDeletedV1) EventType() string  { return EventUserDeleted }
+func (UserDeletedV1) SchemaVersion() int { return 1 }
+
+// UserGroupCreatedV1 is the payload of user_group.created.
+type UserGroupCreatedV1 struct {
+	UserGroupUID uuid.UUID   `json:"user_group_uid"`
+	Name         string      `json:"name"`
+	MemberUIDs   []uuid.UUID `json:"member_uids"`
+}
+
+func (UserGroupCreatedV1) EventType() string  { return EventUserGroupCreated }
+func (UserGroupCreatedV1) SchemaVersion() int { return 1 }
+
+// UserGroupUpdatedV1 is the payload of user_group.updated.
+type UserGroupUpdatedV1 UserGroupCreatedV1
+
+func (UserGroupUpdatedV1) EventType() string  { return EventUserGroupUpdated }
+func (UserGroupUpdatedV1) SchemaVersion() int { return 1 }
+
+// UserGroupDeletedV1 is the payload of user_group.deleted.
+type UserGroupDeletedV1 struct {
+	UserGroupUID uuid.UUID `json:"user_group_uid"`
+}
+
+func (UserGroupDeletedV1) EventType() string  { return EventUserGroupDeleted }
+func (UserGroupDeletedV1) SchemaVersion() int { return 1 }
+
+// UserGroupMemberAddedV1 is the payload of user_group.member_added.
+type UserGroupMemberAddedV1 struct {
+	UserGroupUID uuid.UUID `json:"user_group_uid"`
+	UserUID      uuid.UUID `json:"user_uid"`
+}
+
+func (UserGroupMemberAddedV1) EventType() string  { return EventUserGroupMemberAdded }
+func (UserGroupMemberAddedV1) SchemaVersion() int { return 1 }
+
+// UserGroupMemberRemovedV1 is the payload of user_group.member_removed.
+type UserGroupMemberRemovedV1 UserGroupMemberAddedV1
+
+func (UserGroupMemberRemovedV1) EventType() string  { return EventUserGroupMemberRemoved }
+func (UserGroupMemberRemovedV1) SchemaVersion() int { return 1 }
+
+// UserGroupMembershipSetV1 is the payload of user_group.membership_set,
+// recorded when a user update replaces all of the user's groups.
+type UserGroupMembershipSetV1 struct {
+	UserUID   uuid.UUID   `json:"user_uid"`
+	GroupUIDs []uuid.UUID `json:"group_uids"`
+}
+
+func (UserGroupMembershipSetV1) EventType() string  { return EventUserGroupMembershipSet }
+func (UserGroupMembershipSetV1) SchemaVersion() int { return 1 }
+
+// DatabaseCreatedV1 is the payload of database.created.
+type DatabaseCreatedV1 struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+func (DatabaseCreatedV1) EventType() string  { return EventDatabaseCreated }
+func (DatabaseCreatedV1) SchemaVersion() int { return 1 }
+
+// DatabaseClonedV1 is the payload of database.cloned.
+type DatabaseClonedV1 struct {
+	SourceUID uuid.UUID `json:"source_uid"`
+	Name      string    `json:"name"`
+	Host      string    `json:"host"`
+	Port      int       `json:"port"`
+}
+
+func (DatabaseClonedV1) EventType() string  { return EventDatabaseCloned }
+func (DatabaseClonedV1) SchemaVersion() int { return 1 }
+
+// DatabaseUpdatedV1 is the payload of database.updated.
+type DatabaseUpdatedV1 struct {
+	DatabaseUID   uuid.UUID               `json:"database_uid"`
+	UpdatedFields DatabaseUpdatedFieldsV1 `json:"updated_fields"`
+}
+
+// DatabaseUpdatedFieldsV1 lists the fields of a database update; omitted
+// fields were left unchanged. Secrets (password, SSH key and passphrase) are
+// only recorded as having changed, never with their value.
+type DatabaseUpdatedFieldsV1 struct {
+	Description             *string              `json:"description,omitempty"`
+	Host                    *string              `json:"host,omitempty"`
+	Port                    *int                 `json:"port,omitempty"`
+	DatabaseName            *string              `json:"database_name,omitempty"`
+	Username                *string              `json:"username,omitempty"`
+	ReadOnlyUsername        *string              `json:"readonly_username,omitempty"`
+	SecretRef               *string              `json:"secret_ref,omitempty"`
+	SSLMode                 *string              `json:"ssl_mode,omitempty"`
+	Protocol                *string              `json:"protocol,omitempty"`
+	OracleServiceName       *string              `json:"oracle_service_name,omitempty"`
+	MongoAuthSource         *string              `json:"mongo_auth_source,omitempty"`
+	Listable                *bool                `json:"listable,omitempty"`
+	BlockedMessage          *string              `json:"blocked_message,omitempty"`
+	ViaUID                  *uuid.UUID           `json:"via_uid,omitempty"`
+	Labels                  store.Labels         `json:"labels,omitempty"`
+	GrantDefaults           *store.GrantDefaults `json:"grant_defaults,omitempty"`
+	RowQuota                *store.RowQuota      `json:"row_quota,omitempty"`
+	ResultMasking           *store.ResultMasking `json:"result_masking,omitempty"`
+	SlowQueryThresholdMs    *int                 `json:"slow_query_threshold_ms,omitempty"`
+	ReadReplicas            []string             `json:"read_replicas,omitempty"`
+	ClearViaUID             bool                 `json:"clear_via_uid,omitempty"`
+	PasswordChanged         bool                 `json:"password_changed,omitempty"`
+	ReadOnlyPasswordChanged bool                 `json:"readonly_password_changed,omitempty"`
+	SSHPrivateKeyChanged    bool                 `json:"ssh_private_key_changed,omitempty"`
+	SSHPassphraseChanged    bool                 `json:"ssh_passphrase_changed,omitempty"`
+	SSLRootCertChanged      bool                 `json:"ssl_root_cert_changed,omitempty"`
+	// QueryStorage is the replacement query storage overrides, {} when
+	// cleared.
+	QueryStorage *store.QueryStorageOverrides `json:"query_storage,omitempty"`
+}
+
+func (DatabaseUpdatedV1) EventType() string  { return EventDatabaseUpdated }
+func (DatabaseUpdatedV1) SchemaVersion() int { return 1 }
+
+// DatabaseDeletedV1 is the payload of database.deleted.
+type DatabaseDeletedV1 struct {
+	DatabaseUID       uuid.UUID `json:"database_uid"`
+	RevokedGrants     int       `json:"revoked_grants"`
+	CancelledRequests int       `json:"cancelled_requests"`
+}
+
+func (DatabaseDeletedV1) EventType() string  { return EventDatabaseDeleted }
+func (DatabaseDeletedV1) SchemaVersion() int { return 1 }
+
+// ServerConnectionTestedV1 is the payload of server.connection_tested. The
+// error message is left out: it can echo target internals that do not
+// belong in a durable record. A configuration tested before being saved has
+// the nil ServerUID, and its Host and Port set.
+type ServerConnectionTestedV1 struct {
+	ServerUID uuid.UUID `json:"server_uid"`
+	Host      string    `json:"host,omitempty"`
+	Port      int       `json:"port,omitempty"`
+	Protocol  string    `json:"protocol"`
+	OK        bool      `json:"ok"`
+	Stage     string    `json:"stage"`
+	Code      string    `json:"code"`
+}
+
+func (ServerConnectionTestedV1) EventType() string  { return EventServerConnectionTested }
+func (ServerConnectionTestedV1) SchemaVersion() int { return 1 }
+
+// ServerPrivilegesCheckedV1 is the payload of server.privileges_checked. Only
+// the number of warnings is kept; the report itself can be read again.
+type ServerPrivilegesCheckedV1 struct {
+	ServerUID uuid.UUID `json:"server_uid"`
+	Protocol  string    `json:"protocol"`
+	OK        bool      `json:"ok"`
+	Code      string    `json:"code"`
+	Superuser bool      `json:"superuser"`
+	Warnings  int       `json:"warnings"`
+}
+
+func (ServerPrivilegesCheckedV1) EventType() string  { return EventServerPrivilegesChecked }
+func (ServerPrivilegesCheckedV1) SchemaVersion() int { return 1 }
+
+// ConnectionTerminatedV1 is the payload of connection.terminated.
+type ConnectionTerminatedV1 struct {
+	ConnectionUID uuid.UUID `json:"connection_uid"`
+	DatabaseID    uuid.UUID `json:"database_id"`
+}
+
+func (ConnectionTerminatedV1) EventType() string  { return EventConnectionTerminated }
+func (ConnectionTerminatedV1) SchemaVersion() int { return 1 }
+
+// APIKeyCreatedV1 is the payload of api_key.created.
+type APIKeyCreatedV1 struct {
+	KeyName   string     `json:"key_name"`
+	KeyPrefix string     `json:"key_prefix"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Scopes    []string   `json:"scopes,omitempty"`
+}
+
+func (APIKeyCreatedV1) EventType() string  { return EventAPIKeyCreated }
+func (APIKeyCreatedV1) SchemaVersion() int { return 1 }
+
+// APIKeyRevokedV1 is the payload of api_key.revoked.
+type APIKeyRevokedV1 struct {
+	KeyName   string    `json:"key_name"`
+	KeyPrefix string    `json:"key_prefix"`
+	RevokedBy uuid.UUID `json:"revoked_by"`
+}
+
+func (APIKeyRevokedV1) EventType() string  { return EventAPIKeyRevoked }
+func (APIKeyRevokedV1) SchemaVersion() int { return 1 }
+
+// DeviceAuthRequestedV1 is the payload of device_auth.requested.
+type DeviceAuthRequestedV1 struct {
+	ClientName string    `json:"client_name"`
+	RequestID  uuid.UUID `json:"request_id"`
+}
+
+func (DeviceAuthRequestedV1) EventType() string  { return EventDeviceAuthRequested }
+func (DeviceAuthRequestedV1) SchemaVersion() int { return 1 }
+
+// DeviceAuthApprovedV1 is the payload of device_auth.approved.
+type DeviceAuthApprovedV1 struct {
+	ClientName string    `json:"client_name"`
+	RequestID  uuid.UUID `json:"request_id"`
+	KeyPrefix  string    `json:"key_prefix"` // Prefix of the API key issued to the device
+}
+
+func (DeviceAuthApprovedV1) EventType() string  { return EventDeviceAuthApproved }
+func (DeviceAuthApprovedV1) SchemaVersion() int { return 1 }
+
+// DeviceAuthDeniedV1 is the payload of device_auth.denied; its key prefix is
+// always empty.
+type DeviceAuthDeniedV1 DeviceAuthApprovedV1
+
+func (DeviceAuthDeniedV1) EventType() string  { return EventDeviceAuthDenied }
+func (DeviceAuthDeniedV1) SchemaVersion() int { return 1 }
+
+// GrantCreatedV1 is the payload of grant.created.
+type GrantCreatedV1 struct {
+	GrantUID      uuid.UUID    `json:"grant_uid"`
+	UserID        uuid.UUID    `json:"user_id"`
+	DatabaseID    uuid.UUID    `json:"database_id"`
+	Controls      []string     `json:"controls"`
+	AllowedTables []string     `json:"allowed_tables,omitempty"`
+	AllowedCIDRs  []string     `json:"allowed_cidrs,omitempty"`
+	StartsAt      time.Time    `json:"starts_at"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	Labels        store.Labels `json:"labels"`
+}
+
+func (GrantCreatedV1) EventType() string  { return EventGrantCreated }
+func (GrantCreatedV1) SchemaVersion() int { return 1 }
+
+// GrantBulkCreatedV1 is the payload of grant.bulk_created: a grant template
+// applied to every pair of the users and databases, in one event.
+type GrantBulkCreatedV1 struct {
+	GrantTemplateUID uuid.UUID    `json:"grant_template_uid"`
+	TemplateName     string       `json:"template_name"`
+	UserIDs          []uuid.UUID  `json:"user_ids"`
+	DatabaseIDs      []uuid.UUID  `json:"database_ids"`
+	GrantUIDs        []uuid.UUID  `json:"grant_uids"`
+	StartsAt         time.Time    `json:"starts_at"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	Labels           store.Labels `json:"labels"`
+}
+
+func (GrantBulkCreatedV1) EventType() string  { return EventGrantBulkCreated }
+func (GrantBulkCreatedV1) SchemaVersion() int { return 1 }
+
+// GrantLabelsUpdatedV1 is the payload of grant.labels_updated.
+type GrantLabelsUpdatedV1 struct {
+	GrantUID uuid.UUID    `json:"grant_uid"`
+	Labels   store.Labels `json:"labels"`
+}
+
+func (GrantLabelsUpdatedV1) EventType() string  { return EventGrantLabelsUpdated }
+func (GrantLabelsUpdatedV1) SchemaVersion() int { return 1 }
+
+// GrantRevokedV1 is the payload of grant.revoked.
+type GrantRevokedV1 struct {
+	GrantUID uuid.UUID `json:"grant_uid"`
+}
+
+func (GrantRevokedV1) EventType() string  { return EventGrantRevoked }
+func (GrantRevokedV1) SchemaVersion() int { return 1 }
+
+// GrantClientRefusedV1 is the payload of grant.client_refused: a proxy
+// connection came from outside the networks the grant allows.
+type GrantClientRefusedV1 struct {
+	GrantUID   uuid.UUID `json:"grant_uid"`
+	DatabaseID uuid.UUID `json:"database_id"`
+	SourceIP   string    `json:"source_ip"`
+}
+
+func (GrantClientRefusedV1) EventType() string  { return EventGrantClientRefused }
+func (GrantClientRefusedV1) SchemaVersion() int { return 1 }
+
+// ProxyCredentialCreatedV1 is the payload of proxy_credential.created. The
+// event is performed by the issuing administrator and is about the grant's
+// user; Principal names who the credential was handed to.
+type ProxyCredentialCreatedV1 struct {
+	CredentialUID uuid.UUID `json:"credential_uid"`
+	GrantUID      uuid.UUID `json:"grant_uid"`
+	DatabaseID    uuid.UUID `json:"database_id"`
+	Username      string    `json:"username"`
+	Principal     string    `json:"principal"`
+	IssuedBy      uuid.UUID `json:"issued_by"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (ProxyCredentialCreatedV1) EventType() string  { return EventProxyCredentialCreated }
+func (ProxyCredentialCreatedV1) SchemaVersion() int { return 1 }
+
+// ProxyCredentialUsedV1 is the payload of proxy_credential.used: the
+// principal opened the credential's connection. Like the creation, the event
+// is performed by the issuing administrator, on whose behalf it connected.
+type ProxyCredentialUsedV1 struct {
+	CredentialUID uuid.UUID `json:"credential_uid"`
+	GrantUID      uuid.UUID `json:"grant_uid"`
+	DatabaseID    uuid.UUID `json:"database_id"`
+	Username      string    `json:"username"`
+	Principal     string    `json:"principal"`
+	IssuedBy      uuid.UUID `json:"issued_by"`
+	SourceIP      string    `json:"source_ip"`
+}
+
+func (ProxyCredentialUsedV1) EventType() string  { return EventProxyCredentialUsed }
+func (ProxyCredentialUsedV1) SchemaVersion() int { return 1 }
+
+// ProxyCredentialRevokedV1 is the payload of proxy_credential.revoked.
+type ProxyCredentialRevokedV1 struct {
+	CredentialUID uuid.UUID `json:"credential_uid"`
+	GrantUID      uuid.UUID `json:"grant_uid"`
+	Username      string    `json:"username"`
+	Principal     string    `json:"principal"`
+	IssuedBy      uuid.UUID `json:"issued_by"`
+}
+
+func (ProxyCredentialRevokedV1) EventType() string  { return EventProxyCredentialRevoked }
+func (ProxyCredentialRevokedV1) SchemaVersion() int { return 1 }
+
+// GrantRequestCreatedV1 is the payload of grant_request.created.
+type GrantRequestCreatedV1 struct {
+	GrantRequestUID   uuid.UUID `json:"grant_request_uid"`
+	GrantDefinitionID uuid.UUID `json:"grant_definition_id"`
+	DatabaseID        uuid.UUID `json:"database_id"`
+	DurationSeconds   *int64    `json:"duration_seconds,omitempty"`
+}
+
+func (GrantRequestCreatedV1) EventType() string  { return EventGrantRequestCreated }
+func (GrantRequestCreatedV1) SchemaVersion() int { return 1 }
+
+// GrantRequestExtensionRequestedV1 is the payload of
+// grant_request.extension_requested.
+type GrantRequestExtensionRequestedV1 struct {
+	GrantRequestUID uuid.UUID `json:"grant_request_uid"`
+	GrantID         uuid.UUID `json:"grant_id"`
+	DatabaseID      uuid.UUID `json:"database_id"`
+	DurationSeconds int64     `json:"duration_seconds"`
+}
+
+func (GrantRequestExtensionRequestedV1) EventType() string {
+	return EventGrantRequestExtensionRequested
+}
+func (GrantRequestExtensionRequestedV1) SchemaVersion() int { return 1 }
+
+// GrantRequestApprovedV1 is the payload of grant_request.approved. Via is
+// set when the decision was not made in the web UI ("slack",
+// "auto_approve").
+type GrantRequestApprovedV1 struct {
+	GrantRequestUID  uuid.UUID `json:"grant_request_uid"`
+	ResultingGrantID uuid.UUID `json:"resulting_grant_id"`
+	Via              string    `json:"via,omitempty"`
+}
+
+func (GrantRequestApprovedV1) EventType() string  { return EventGrantRequestApproved }
+func (GrantRequestApprovedV1) SchemaVersion() int { return 1 }
+
+// GrantRequestDeniedV1 is the payload of grant_request.denied. Via is set as
+// for approvals.
+type GrantRequestDeniedV1 struct {
+	GrantRequestUID uuid.UUID `json:"grant_request_uid"`
+	Reason          string    `json:"reason"`
+	Via             string    `json:"via,omitempty"`
+}
+
+func (GrantRequestDeniedV1) EventType() string  { return EventGrantRequestDenied }
+func (GrantRequestDeniedV1) SchemaVersion() int { return 1 }
+
+// GrantRequestCancelledV1 is the payload of grant_request.cancelled.
+type GrantRequestCancelledV1 struct {
+	GrantRequestUID uuid.UUID `json:"grant_request_uid"`
+}
+
+func (GrantRequestCancelledV1) EventType() string  { return EventGrantRequestCancelled }
+func (GrantRequestCancelledV1) SchemaVersion() int { return 1 }
+
+// StatementApprovalApprovedV1 is the payload of statement_approval.approved.
+type StatementApprovalApprovedV1 struct {
+	StatementApprovalUID uuid.UUID `json:"statement_approval_uid"`
+	ConnectionID         uuid.UUID `json:"connection_id"`
+	DatabaseID           uuid.UUID `json:"database_id"`
+}
+
+func (StatementApprovalApprovedV1) EventType() string  { return EventStatementApprovalApproved }
+func (StatementApprovalApprovedV1) SchemaVersion() int { return 1 }
+
+// StatementApprovalDeniedV1 is the payload of statement_approval.denied.
+type StatementApprovalDeniedV1 struct {
+	StatementApprovalUID uuid.UUID `json:"statement_approval_uid"`
+	ConnectionID         uuid.UUID `json:"connection_id"`
+	DatabaseID           uuid.UUID `json:"database_id"`
+	Reason               string    `json:"reason"`
+}
+
+func (StatementApprovalDeniedV1) EventType() string  { return EventStatementApprovalDenied }
+func (StatementApprovalDeniedV1) SchemaVersion() int { return 1 }
+
+// GrantDefinitionCreatedV1 is the payload of grant_definition.created.
+type GrantDefinitionCreatedV1 struct {
+	GrantDefinitionUID uuid.UUID   `json:"grant_definition_uid"`
+	Name               string      `json:"name"`
+	DurationSeconds    int64       `json:"duration_seconds"`
+	Controls           []string    `json:"controls"`
+	AutoApprove        bool        `json:"auto_approve"`
+	GroupUIDs          []uuid.UUID `json:"group_uids"`
+	DatabaseUIDs       []uuid.UUID `json:"database_uids"`
+}
+
+func (GrantDefinitionCreatedV1) EventType() string  { return EventGrantDefinitionCreated }
+func (GrantDefinitionCreatedV1) SchemaVersion() int { return 1 }
+
+// GrantDefinitionUpdatedV1 is the payload of grant_definition.updated.
+type GrantDefinitionUpdatedV1 struct {
+	GrantDefinitionUID uuid.UUID   `json:"grant_definition_uid"`
+	Name               string      `json:"name"`
+	AutoApprove        bool        `json:"auto_approve"`
+	GroupUIDs          []uuid.UUID `json:"group_uids"`
+	DatabaseUIDs       []uuid.UUID `json:"database_uids"`
+}
+
+func (GrantDefinitionUpdatedV1) EventType() string  { return EventGrantDefinitionUpdated }
+func (GrantDefinitionUpdatedV1) SchemaVersion() int { return 1 }
+
+// GrantDefinitionDeactivatedV1 is the payload of grant_definition.deactivated.
+type GrantDefinitionDeactivatedV1 struct {
+	GrantDefinitionUID uuid.UUID `json:"grant_definition_uid"`
+}
+
+func (GrantDefinitionDeactivatedV1) EventType() string  { return EventGrantDefinitionDeactivated }
+func (GrantDefinitionDeactivatedV1) SchemaVersion() int { return 1 }
+
+// GrantTemplateCreatedV1 is the payload of grant_template.created.
+type GrantTemplateCreatedV1 struct {
+	GrantTemplateUID uuid.UUID `json:"grant_template_uid"`
+	Name             string    `json:"name"`
+	DurationSeconds  int64     `json:"duration_seconds"`
+	Controls         []string  `json:"controls"`
+	AllowedTables    []string  `json:"allowed_tables"`
+	AllowedCIDRs     []string  `json:"allowed_cidrs,omitempty"`
+}
+
+func (GrantTemplateCreatedV1) EventType() string  { return EventGrantTemplateCreated }
+func (GrantTemplateCreatedV1) SchemaVersion() int { return 1 }
+
+// GrantTemplateUpdatedV1 is the payload of grant_template.updated.
+type GrantTemplateUpdatedV1 GrantTemplateCreatedV1
+
+func (GrantTemplateUpdatedV1) EventType() string  { return EventGrantTemplateUpdated }
+func (GrantTemplateUpdatedV1) SchemaVersion() int { return 1 }
+
+// GrantTemplateDeletedV1 is the payload of grant_template.deleted.
+type GrantTemplateDeletedV1 struct {
+	GrantTemplateUID uuid.UUID `json:"grant_template_uid"`
+}
+
+func (GrantTemplateDeletedV1) EventType() string  { return EventGrantTemplateDeleted }
+func (GrantTemplateDeletedV1) SchemaVersion() int { return 1 }
+
+// QueryAlertCreatedV1 is the payload of query_alert.created.
+type QueryAlertCreatedV1 struct {
+	QueryAlertUID uuid.UUID              `json:"query_alert_uid"`
+	Name          string                 `json:"name"`
+	Filter        store.QueryAlertFilter `json:"filter"`
+	Webhook       bool                   `json:"webhook"`
+	Enabled       bool                   `json:"enabled"`
+}
+
+func (QueryAlertCreatedV1) EventType() string  { return EventQueryAlertCreated }
+func (QueryAlertCreatedV1) SchemaVersion() int { return 1 }
+
+// QueryAlertUpdatedV1 is the payload of query_alert.updated.
+type QueryAlertUpdatedV1 QueryAlertCreatedV1
+
+func (QueryAlertUpdatedV1) EventType() string  { return EventQueryAlertUpdated }
+func (QueryAlertUpdatedV1) SchemaVersion() int { return 1 }
+
+// QueryAlertDeletedV1 is the payload of query_alert.deleted.
+type QueryAlertDeletedV1 struct {
+	QueryAlertUID uuid.UUID `json:"query_alert_uid"`
+}
+
+func (QueryAlertDeletedV1) EventType() string  { return EventQueryAlertDeleted }
+func (QueryAlertDeletedV1) SchemaVersion() int { return 1 }
+
+// RateLimitUpdatedV1 is the payload of rate_limit.updated. It names either
+// the user or the role whose override changed; a null requests_per_minute
+// records the override's removal.
+type RateLimitUpdatedV1 struct {
+	UserUID           *uuid.UUID `json:"user_uid"`
+	Role              string     `json:"role,omitempty"`
+	RequestsPerMinute *int       `json:"requests_per_minute"`
+}
+
+func (RateLimitUpdatedV1) EventType() string  { return EventRateLimitUpdated }
+func (RateLimitUpdatedV1) SchemaVersion() int { return 1 }
@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// queueSize bounds the events waiting to be written to the sinks; beyond it
+// new ones are dropped rather than slowing down the API and proxies.
+const queueSize = 1000
+
+// Sink is a destination audit events are copied to, each as one JSON line.
+// Sinks are only used by the pipeline's worker goroutine.
+type Sink interface {
+	// Name identifies the sink in logs.
+	Name() string
+	// Write sends one event, encoded as line.
+	Write(ctx context.Context, entry *store.AuditLog, line []byte) error
+	// Flush sends the events a batching sink holds.
+	Flush(ctx context.Context) error
+	// Close flushes and releases the sink.
+	Close(ctx context.Context) error
+}
+
+// Pipeline copies every audit event logged to the audit_log table to the
+// configured sinks, so that copies exist off the storage database. It is a
+// store.AuditObserver: events are queued without blocking the caller and
+// written by a single background worker.
+type Pipeline struct {
+	sinks         []Sink
+	flushInterval time.Duration // 0 when no sink batches events
+	logger        *slog.Logger
+
+	queue    chan store.AuditLog
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewPipeline creates a pipeline writing to the sinks of cfg; Start begins
+// writing.
+func NewPipeline(cfg config.AuditConfig, logger *slog.Logger) (*Pipeline, error) {
+	var (
+		sinks         []Sink
+		flushInterval time.Duration
+	)
+
+	if cfg.File.Enabled() {
+		sink, err := NewFileSink(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, sink)
+	}
+
+	if cfg.Syslog.Enabled() {
+		sinks = append(sinks, NewSyslogSink(cfg.Syslog))
+	}
+
+	if cfg.S3.Enabled() {
+		interval, err := cfg.S3.Flush()
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit.s3.flush_interval: %w", err)
+		}
+
+		sinks = append(sinks, NewS3Sink(cfg.S3))
+		flushInterval = interval
+	}
+
+	return newPipeline(sinks, flushInterval, logger), nil
+}
+
+func newPipeline(sinks []Sink, flushInterval time.Duration, logger *slog.Logger) *Pipeline {
+	return &Pipeline{
+		sinks:         sinks,
+		flushInterval: flushInterval,
+		logger:        logger.With(slog.String("component", "audit_pipeline")),
+		queue:         make(chan store.AuditLog, queueSize),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Sinks returns the names of the pipeline's sinks.
+func (p *Pipeline) Sinks() []string {
+	names := make([]string, len(p.sinks))
+	for i, sink := range p.sinks {
+		names[i] = sink.Name()
+	}
+
+	return names
+}
+
+// Start runs the worker until Shutdown.
+func (p *Pipeline) Start() {
+	go p.run()
+}
+
+// AuditLogged implements store.AuditObserver.
+func (p *Pipeline) AuditLogged(entry *store.AuditLog) {
+	select {
+	case <-p.stop:
+	case p.queue <- *entry:
+	default:
+		p.logger.WarnContext(context.Background(), "audit queue full, dropping event",
+			slog.String("audit_uid", entry.UID.String()), slog.String("event_type", entry.EventType))
+	}
+}
+
+// Shutdown stops accepting events, waits for the queued ones to be written
+// and closes the sinks.
+func (p *Pipeline) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return fmt.Errorf("audit pipeline interrupted: %w", ctx.Err())
+	}
+
+	var errs []error
+
+	for _, sink := range p.sinks {
+		if err := sink.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("audit %s sink: %w", sink.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *Pipeline) run() {
+	defer close(p.done)
+
+	ctx := context.Background()
+
+	var tick <-chan time.Time
+
+	if p.flushInterval > 0 {
+		ticker := time.NewTicker(p.flushInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case entry := <-p.queue:
+			p.write(ctx, &entry)
+		case <-tick:
+			p.flush(ctx)
+		case <-p.stop:
+			for {
+				select {
+				case entry := <-p.queue:
+					p.write(ctx, &entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write sends one event to every sink. Failures are logged: the event is
+// still in the audit_log table, and a failing sink must not hold back the
+// others.
+func (p *Pipeline) write(ctx context.Context, entry *store.AuditLog) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		p.logger.WarnContext(ctx, "failed to encode audit event",
+			slog.String("audit_uid", entry.UID.String()), slog.Any("error", err))
+
+		return
+	}
+
+	for _, sink := range p.sinks {
+		if err := sink.Write(ctx, entry, line); err != nil {
+			p.logger.WarnContext(ctx, "failed to write audit event",
+				slog.String("sink", sink.Name()), slog.String("audit_uid", entry.UID.String()), slog.Any("error", err))
+		}
+	}
+}
+
+// flush sends the events held by batching sinks.
+func (p *Pipeline) flush(ctx context.Context) {
+	for _, sink := range p.sinks {
+		if err := sink.Flush(ctx); err != nil {
+			p.logger.WarnContext(ctx, "failed to flush audit events",
+				slog.String("sink", sink.Name()), slog.Any("error", err))
+		}
+	}
+}
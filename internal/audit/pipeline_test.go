@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+type recordingSink struct {
+	lines   []string
+	flushes int
+	closed  bool
+}
+
+func (r *recordingSink) Name() string { return "recording" }
+
+func (r *recordingSink) Write(_ context.Context, _ *store.AuditLog, line []byte) error {
+	r.lines = append(r.lines, string(line))
+
+	return nil
+}
+
+func (r *recordingSink) Flush(context.Context) error {
+	r.flushes++
+
+	return nil
+}
+
+func (r *recordingSink) Close(context.Context) error {
+	r.closed = true
+
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingSink{}
+	pipeline := newPipeline([]Sink{sink}, 0, slog.New(slog.DiscardHandler))
+	pipeline.Start()
+
+	entries := []*store.AuditLog{
+		{UID: uuid.New(), EventType: "user.created", Details: json.RawMessage(`{"username":"alice"}`), CreatedAt: time.Now()},
+		{UID: uuid.New(), EventType: "grant.revoked", CreatedAt: time.Now()},
+	}
+
+	for _, entry := range entries {
+		pipeline.AuditLogged(entry)
+	}
+
+	require.NoError(t, pipeline.Shutdown(context.Background()))
+
+	// The queued events are written before Shutdown returns.
+	require.Len(t, sink.lines, 2)
+	assert.True(t, sink.closed)
+
+	var got store.AuditLog
+	require.NoError(t, json.Unmarshal([]byte(sink.lines[0]), &got))
+	assert.Equal(t, entries[0].UID, got.UID)
+	assert.Equal(t, "user.created", got.EventType)
+	assert.JSONEq(t, `{"username":"alice"}`, string(got.Details))
+
+	// Events logged after shutdown are ignored.
+	pipeline.AuditLogged(entries[0])
+	assert.Len(t, sink.lines, 2)
+}
+
+func TestPipeline_Flush(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingSink{}
+	pipeline := newPipeline([]Sink{sink}, 10*time.Millisecond, slog.New(slog.DiscardHandler))
+	pipeline.Start()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, pipeline.Shutdown(context.Background()))
+
+	assert.Positive(t, sink.flushes, "batching sinks are flushed every flush interval")
+}
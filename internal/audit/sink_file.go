@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// backupTimeFormat suffixes rotated files; it sorts chronologically.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileSink appends events as JSON lines to a file, rotated by size.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+	now  func() time.Time
+}
+
+// NewFileSink opens, or creates, the file of cfg.
+func NewFileSink(cfg config.AuditFileConfig) (*FileSink, error) {
+	sink := &FileSink{
+		path:       cfg.Path,
+		maxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+		maxBackups: cfg.MaxBackups,
+		now:        time.Now,
+	}
+
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+
+	return sink, nil
+}
+
+// Name implements Sink.
+func (f *FileSink) Name() string {
+	return "file"
+}
+
+// Write implements Sink, rotating the file first when the line would grow it
+// past its maximum size.
+func (f *FileSink) Write(_ context.Context, _ *store.AuditLog, line []byte) error {
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+
+	if f.size > 0 && f.size+int64(len(line))+1 > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(append(line, '\n'))
+	f.size += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("failed to write audit file: %w", err)
+	}
+
+	return nil
+}
+
+// Flush implements Sink: lines are written as they come.
+func (f *FileSink) Flush(context.Context) error {
+	return nil
+}
+
+// Close implements Sink.
+func (f *FileSink) Close(context.Context) error {
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	if err != nil {
+		return fmt.Errorf("failed to close audit file: %w", err)
+	}
+
+	return nil
+}
+
+func (f *FileSink) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("failed to stat audit file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+
+	return nil
+}
+
+// rotate renames the current file with a timestamp suffix, starts a new one
+// and deletes the backups beyond the ones to keep.
+func (f *FileSink) rotate() error {
+	if err := f.Close(context.Background()); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.path, f.backupName(f.now())); err != nil {
+		return fmt.Errorf("failed to rotate audit file: %w", err)
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	return f.prune()
+}
+
+// backupName is the name path is rotated to at t: audit.jsonl becomes
+// audit-2026-10-15T08-30-00.000.jsonl.
+func (f *FileSink) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+
+	return strings.TrimSuffix(f.path, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+func (f *FileSink) prune() error {
+	if f.maxBackups <= 0 {
+		return nil
+	}
+
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext) + "-"
+
+	matches, err := filepath.Glob(base + "*" + ext)
+	if err != nil {
+		return fmt.Errorf("failed to list audit file backups: %w", err)
+	}
+
+	// Only files named by backupName are ours to delete.
+	var backups []string
+
+	for _, match := range matches {
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(match, base), ext)); err == nil {
+			backups = append(backups, match)
+		}
+	}
+
+	slices.Sort(backups)
+
+	for len(backups) > f.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("failed to delete audit file backup: %w", err)
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
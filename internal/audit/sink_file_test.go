@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+
+	// Unrelated files next to the audit file are never deleted.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "audit-notes.jsonl"), nil, 0o600))
+
+	sink, err := NewFileSink(config.AuditFileConfig{Path: path, MaxSizeMB: 1, MaxBackups: 2})
+	require.NoError(t, err)
+
+	sink.maxSize = 200 // bytes, to rotate quickly
+
+	clock := time.Date(2026, 10, 15, 8, 30, 0, 0, time.UTC)
+	sink.now = func() time.Time {
+		clock = clock.Add(time.Second)
+
+		return clock
+	}
+
+	line := []byte(`{"event_type":"user.created","details":"` + strings.Repeat("x", 40) + `"}`)
+
+	// Two lines fit in a file: 8 lines make 4 files, of which the current one
+	// and 2 backups are kept.
+	for range 8 {
+		require.NoError(t, sink.Write(context.Background(), nil, line))
+	}
+
+	require.NoError(t, sink.Close(context.Background()))
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(current), "\n"))
+
+	backups, err := filepath.Glob(filepath.Join(dir, "audit-2026-*.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "audit-2026-10-15T08-30-02.000.jsonl"),
+		filepath.Join(dir, "audit-2026-10-15T08-30-03.000.jsonl"),
+	}, backups)
+
+	assert.FileExists(t, filepath.Join(dir, "audit-notes.jsonl"))
+
+	// Reopening appends to the current file.
+	sink, err = NewFileSink(config.AuditFileConfig{Path: path, MaxSizeMB: 1})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(context.Background(), nil, line))
+	require.NoError(t, sink.Close(context.Background()))
+
+	current, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(current), "\n"))
+}
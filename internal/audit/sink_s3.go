@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/s3"
+	"github.com/fclairamb/dbbat/internal/sigv4"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+const (
+	s3UploadTimeout = 30 * time.Second
+	// s3MaxPending bounds the events kept while uploads fail; past it the
+	// pending ones are dropped.
+	s3MaxPending = 64 * 1024 * 1024
+)
+
+// ErrPendingOverflow is returned when events pending upload are dropped.
+var ErrPendingOverflow = errors.New("too many audit events pending upload")
+
+// S3Sink uploads the events received between two flushes as one JSON lines
+// object, named <prefix><yyyy>/<mm>/<dd>/<UUIDv7>.jsonl so that objects sort
+// by time. Requests are signed with AWS Signature Version 4, which S3,
+// S3-compatible stores and Google Cloud Storage's interoperability API
+// accept. A batch whose upload fails is retried with the next one.
+type S3Sink struct {
+	cfg    config.AuditS3Config
+	client *s3.Client
+	now    func() time.Time
+
+	pending bytes.Buffer
+}
+
+// NewS3Sink creates the sink of cfg.
+func NewS3Sink(cfg config.AuditS3Config) *S3Sink {
+	return &S3Sink{
+		cfg: cfg,
+		client: s3.NewClient(s3.Bucket{
+			Name:            cfg.Bucket,
+			Endpoint:        cfg.Endpoint,
+			Region:          cfg.Region,
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+		}, s3UploadTimeout),
+		now: time.Now,
+	}
+}
+
+// Name implements Sink.
+func (s *S3Sink) Name() string {
+	return "s3"
+}
+
+// Write implements Sink: the line waits for the next flush.
+func (s *S3Sink) Write(_ context.Context, _ *store.AuditLog, line []byte) error {
+	var err error
+
+	if s.pending.Len()+len(line) >= s3MaxPending {
+		err = fmt.Errorf("%w: dropped %d bytes of events", ErrPendingOverflow, s.pending.Len())
+		s.pending.Reset()
+	}
+
+	s.pending.Write(line)
+	s.pending.WriteByte('\n')
+
+	return err
+}
+
+// Flush implements Sink, uploading the pending events as one object.
+func (s *S3Sink) Flush(ctx context.Context) error {
+	if s.pending.Len() == 0 {
+		return nil
+	}
+
+	now := s.now().UTC()
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to name audit object: %w", err)
+	}
+
+	key := s.cfg.Prefix + now.Format("2006/01/02/") + id.String() + ".jsonl"
+
+	if err := s.put(ctx, key, s.pending.Bytes(), now); err != nil {
+		return err
+	}
+
+	s.pending.Reset()
+
+	return nil
+}
+
+// Close implements Sink, uploading the pending events.
+func (s *S3Sink) Close(ctx context.Context) error {
+	return s.Flush(ctx)
+}
+
+func (s *S3Sink) put(ctx context.Context, key string, body []byte, now time.Time) error {
+	err := s.client.Put(ctx, key, bytes.NewReader(body), int64(len(body)), sigv4.PayloadHash(body), "application/x-ndjson", now)
+	if err != nil {
+		return fmt.Errorf("failed to upload audit object: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/s3"
+)
+
+func TestS3Sink(t *testing.T) {
+	t.Parallel()
+
+	type upload struct {
+		method, path, auth, body string
+	}
+
+	uploads := make(chan upload, 2)
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploads <- upload{r.Method, r.URL.Path, r.Header.Get("Authorization"), string(body)}
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewS3Sink(config.AuditS3Config{
+		Endpoint:        server.URL,
+		Bucket:          "audit-archive",
+		Region:          "us-east-1",
+		Prefix:          "dbbat/",
+		AccessKeyID:     "AKIA",
+		SecretAccessKey: "secret",
+	})
+	sink.now = func() time.Time { return time.Date(2026, 10, 15, 8, 30, 0, 0, time.UTC) }
+
+	require.NoError(t, sink.Flush(context.Background()))
+	assert.Empty(t, uploads, "nothing to upload")
+
+	require.NoError(t, sink.Write(context.Background(), nil, []byte(`{"event_type":"a"}`)))
+
+	// A rejected upload is retried with the next batch.
+	status = http.StatusForbidden
+	require.ErrorIs(t, sink.Flush(context.Background()), s3.ErrStatus)
+	<-uploads
+
+	status = http.StatusOK
+	require.NoError(t, sink.Write(context.Background(), nil, []byte(`{"event_type":"b"}`)))
+	require.NoError(t, sink.Close(context.Background()))
+
+	got := <-uploads
+	assert.Equal(t, http.MethodPut, got.method)
+	assert.True(t, strings.HasPrefix(got.path, "/audit-archive/dbbat/2026/10/15/"), got.path)
+	assert.True(t, strings.HasSuffix(got.path, ".jsonl"), got.path)
+	assert.True(t, strings.HasPrefix(got.auth, "AWS4-HMAC-SHA256 Credential=AKIA/20261015/us-east-1/s3/aws4_request, "), got.auth)
+	assert.Equal(t, "{\"event_type\":\"a\"}\n{\"event_type\":\"b\"}\n", got.body)
+
+	require.NoError(t, sink.Flush(context.Background()))
+	assert.Empty(t, uploads, "uploaded events are not uploaded again")
+}
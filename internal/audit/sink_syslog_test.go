@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestSyslogSink_UDP(t *testing.T) {
+	t.Parallel()
+
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer server.Close()
+
+	sink := NewSyslogSink(config.AuditSyslogConfig{Address: server.LocalAddr().String(), Network: "udp", Tag: "dbbat"})
+	sink.hostname = "proxy-1"
+
+	entry := &store.AuditLog{EventType: "user.created", CreatedAt: time.Date(2026, 10, 15, 8, 30, 0, 123456789, time.UTC)}
+	line := []byte(`{"event_type":"user.created"}`)
+
+	require.NoError(t, sink.Write(context.Background(), entry, line))
+	require.NoError(t, sink.Close(context.Background()))
+
+	buf := make([]byte, 1024)
+
+	require.NoError(t, server.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := server.ReadFrom(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "<86>1 2026-10-15T08:30:00.123456Z proxy-1 dbbat "+sink.procID+` user.created - {"event_type":"user.created"}`,
+		string(buf[:n]))
+}
+
+func TestSyslogSink_TCP(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	received := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Octet-counting framing: the message length, a space, the message.
+		reader := bufio.NewReader(conn)
+
+		length, err := reader.ReadString(' ')
+		if err != nil {
+			return
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			return
+		}
+
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(reader, msg); err == nil {
+			received <- string(msg)
+		}
+	}()
+
+	sink := NewSyslogSink(config.AuditSyslogConfig{Address: listener.Addr().String(), Network: "tcp", Tag: "dbbat"})
+	sink.hostname = "proxy-1"
+
+	require.NoError(t, sink.Write(context.Background(), &store.AuditLog{EventType: "user.deleted"}, []byte(`{}`)))
+	require.NoError(t, sink.Close(context.Background()))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "<86>1 - proxy-1 dbbat "+sink.procID+" user.deleted - {}", msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no syslog message received")
+	}
+}
+
+func TestSyslogField(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "-", syslogField("", 32))
+	assert.Equal(t, "dbbat", syslogField("db bat", 32))
+	assert.Equal(t, "abc", syslogField("abcdef", 3))
+}
@@ -0,0 +1,196 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrMalformedPacket is returned when the server sends data that is not a
+// valid LDAP message.
+var ErrMalformedPacket = errors.New("malformed LDAP packet")
+
+// maxPacketSize bounds the messages read from the server.
+const maxPacketSize = 4 << 20
+
+// BER tags used by LDAP (RFC 4511). Every tag fits in one byte.
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	tagBindRequest       = 0x60
+	tagBindResponse      = 0x61
+	tagUnbindRequest     = 0x42
+	tagSearchRequest     = 0x63
+	tagSearchResultEntry = 0x64
+	tagSearchResultDone  = 0x65
+	tagSearchResultRef   = 0x73
+	tagExtendedRequest   = 0x77
+	tagExtendedResponse  = 0x78
+
+	tagSimpleAuth          = 0x80 // [0] in BindRequest
+	tagExtendedRequestName = 0x80 // [0] in ExtendedRequest
+)
+
+// element is a decoded BER element.
+type element struct {
+	tag     byte
+	content []byte
+}
+
+// encode returns a BER element of the given tag holding the concatenated
+// contents.
+func encode(tag byte, contents ...[]byte) []byte {
+	var length int
+	for _, c := range contents {
+		length += len(c)
+	}
+
+	out := append([]byte{tag}, encodeLength(length)...)
+	for _, c := range contents {
+		out = append(out, c...)
+	}
+
+	return out
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+
+	var digits []byte
+	for ; length > 0; length >>= 8 {
+		digits = append([]byte{byte(length)}, digits...)
+	}
+
+	return append([]byte{0x80 | byte(len(digits))}, digits...)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeInt(tag byte, v int64) []byte {
+	// Minimal two's complement: drop leading bytes that only repeat the sign.
+	b := []byte{byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32), byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	for len(b) > 1 && ((b[0] == 0 && b[1]&0x80 == 0) || (b[0] == 0xff && b[1]&0x80 != 0)) {
+		b = b[1:]
+	}
+
+	return encode(tag, b)
+}
+
+func encodeBool(v bool) []byte {
+	if v {
+		return encode(tagBoolean, []byte{0xff})
+	}
+
+	return encode(tagBoolean, []byte{0})
+}
+
+// decode splits the first element off data.
+func decode(data []byte) (element, []byte, error) {
+	if len(data) < 2 {
+		return element{}, nil, ErrMalformedPacket
+	}
+
+	tag, length, header := data[0], int(data[1]), 2
+
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(data) < 2+n {
+			return element{}, nil, ErrMalformedPacket
+		}
+
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+
+		header += n
+	}
+
+	if length < 0 || len(data)-header < length {
+		return element{}, nil, ErrMalformedPacket
+	}
+
+	return element{tag: tag, content: data[header : header+length]}, data[header+length:], nil
+}
+
+// children decodes the elements of a constructed element.
+func (e element) children() ([]element, error) {
+	var (
+		out  []element
+		rest = e.content
+	)
+
+	for len(rest) > 0 {
+		child, next, err := decode(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, child)
+		rest = next
+	}
+
+	return out, nil
+}
+
+// int decodes an INTEGER or ENUMERATED element.
+func (e element) int() (int64, error) {
+	if len(e.content) == 0 || len(e.content) > 8 {
+		return 0, ErrMalformedPacket
+	}
+
+	v := int64(int8(e.content[0]))
+	for _, b := range e.content[1:] {
+		v = v<<8 | int64(b)
+	}
+
+	return v, nil
+}
+
+// readPacket reads one complete BER element from r.
+func readPacket(r *bufio.Reader) (element, error) {
+	header := make([]byte, 2, 6)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return element{}, err
+	}
+
+	length := int(header[1])
+
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return element{}, ErrMalformedPacket
+		}
+
+		digits := make([]byte, n)
+		if _, err := io.ReadFull(r, digits); err != nil {
+			return element{}, err
+		}
+
+		length = 0
+		for _, b := range digits {
+			length = length<<8 | int(b)
+		}
+	}
+
+	if length > maxPacketSize {
+		return element{}, fmt.Errorf("%w: %d bytes", ErrMalformedPacket, length)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return element{}, err
+	}
+
+	return element{tag: header[0], content: content}, nil
+}
@@ -0,0 +1,230 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidFilter is returned when a search filter is not a valid RFC 4515
+// string filter.
+var ErrInvalidFilter = errors.New("invalid LDAP filter")
+
+// Filter tags (RFC 4511 4.5.1).
+const (
+	tagFilterAnd        = 0xa0
+	tagFilterOr         = 0xa1
+	tagFilterNot        = 0xa2
+	tagFilterEquality   = 0xa3
+	tagFilterSubstrings = 0xa4
+	tagFilterGreater    = 0xa5
+	tagFilterLess       = 0xa6
+	tagFilterPresent    = 0x87
+	tagFilterApprox     = 0xa8
+
+	tagSubstringInitial = 0x80
+	tagSubstringAny     = 0x81
+	tagSubstringFinal   = 0x82
+)
+
+// EscapeFilter escapes a value for use in a string filter, so that a user
+// name cannot change the structure of the filter it is inserted in.
+func EscapeFilter(value string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// compileFilter encodes a string filter such as
+// "(&(objectClass=person)(uid=jdoe))" in BER.
+func compileFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+
+	encoded, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if rest != "" {
+		return nil, fmt.Errorf("%w: trailing %q", ErrInvalidFilter, rest)
+	}
+
+	return encoded, nil
+}
+
+// parseFilter parses one parenthesized filter and returns what follows it.
+func parseFilter(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("%w: expected ( at %q", ErrInvalidFilter, s)
+	}
+
+	s = s[1:]
+	if s == "" {
+		return nil, "", fmt.Errorf("%w: unterminated", ErrInvalidFilter)
+	}
+
+	switch s[0] {
+	case '&', '|':
+		tag := byte(tagFilterAnd)
+		if s[0] == '|' {
+			tag = tagFilterOr
+		}
+
+		var parts [][]byte
+
+		rest := s[1:]
+		for strings.HasPrefix(rest, "(") {
+			part, next, err := parseFilter(rest)
+			if err != nil {
+				return nil, "", err
+			}
+
+			parts = append(parts, part)
+			rest = next
+		}
+
+		if len(parts) == 0 || !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("%w: bad filter list at %q", ErrInvalidFilter, rest)
+		}
+
+		return encode(tag, parts...), rest[1:], nil
+	case '!':
+		part, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("%w: bad negation at %q", ErrInvalidFilter, rest)
+		}
+
+		return encode(tagFilterNot, part), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("%w: unterminated %q", ErrInvalidFilter, s)
+	}
+
+	item, err := parseItem(s[:end])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return item, s[end+1:], nil
+}
+
+// parseItem parses a simple "attr op value" filter.
+func parseItem(item string) ([]byte, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFilter, item)
+	}
+
+	attr, value := item[:eq], item[eq+1:]
+
+	tag := byte(tagFilterEquality)
+
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = tagFilterGreater, attr[:len(attr)-1]
+	case '<':
+		tag, attr = tagFilterLess, attr[:len(attr)-1]
+	case '~':
+		tag, attr = tagFilterApprox, attr[:len(attr)-1]
+	}
+
+	if attr == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFilter, item)
+	}
+
+	if tag == tagFilterEquality && value == "*" {
+		return encodeString(tagFilterPresent, attr), nil
+	}
+
+	if tag == tagFilterEquality && strings.Contains(value, "*") {
+		return parseSubstrings(attr, value)
+	}
+
+	unescaped, err := unescapeFilter(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(tag, encodeString(tagOctetString, attr), encodeString(tagOctetString, unescaped)), nil
+}
+
+// parseSubstrings encodes a value with wildcards, such as "j*doe*".
+func parseSubstrings(attr, value string) ([]byte, error) {
+	chunks := strings.Split(value, "*")
+
+	var parts [][]byte
+
+	for i, chunk := range chunks {
+		if chunk == "" {
+			continue
+		}
+
+		unescaped, err := unescapeFilter(chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		tag := byte(tagSubstringAny)
+
+		switch i {
+		case 0:
+			tag = tagSubstringInitial
+		case len(chunks) - 1:
+			tag = tagSubstringFinal
+		}
+
+		parts = append(parts, encodeString(tag, unescaped))
+	}
+
+	return encode(tagFilterSubstrings, encodeString(tagOctetString, attr), encode(tagSequence, parts...)), nil
+}
+
+// unescapeFilter decodes the \XX escapes of a filter value.
+func unescapeFilter(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		if i+3 > len(value) {
+			return "", fmt.Errorf("%w: bad escape in %q", ErrInvalidFilter, value)
+		}
+
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("%w: bad escape in %q", ErrInvalidFilter, value)
+		}
+
+		b.Write(decoded)
+
+		i += 2
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,46 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeFilter(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "jdoe", EscapeFilter("jdoe"))
+	assert.Equal(t, `\2a\29\28uid=\5c\00`, EscapeFilter("*)(uid=\\\x00"))
+}
+
+func TestCompileFilter(t *testing.T) {
+	t.Parallel()
+
+	uid := encode(tagFilterEquality, encodeString(tagOctetString, "uid"), encodeString(tagOctetString, "jdoe"))
+
+	for filter, want := range map[string][]byte{
+		"(uid=jdoe)": uid,
+		"uid=jdoe":   uid,
+		"(&(objectClass=*)(uid=jdoe))": encode(tagFilterAnd,
+			encodeString(tagFilterPresent, "objectClass"),
+			uid),
+		"(|(!(uid=jdoe))(uid>=a))": encode(tagFilterOr,
+			encode(tagFilterNot, uid),
+			encode(tagFilterGreater, encodeString(tagOctetString, "uid"), encodeString(tagOctetString, "a"))),
+		"(cn=j*d*e)": encode(tagFilterSubstrings, encodeString(tagOctetString, "cn"), encode(tagSequence,
+			encodeString(tagSubstringInitial, "j"),
+			encodeString(tagSubstringAny, "d"),
+			encodeString(tagSubstringFinal, "e"))),
+		`(uid=\2a\28)`: encode(tagFilterEquality, encodeString(tagOctetString, "uid"), encodeString(tagOctetString, "*(")),
+	} {
+		got, err := compileFilter(filter)
+		require.NoError(t, err, filter)
+		assert.Equal(t, want, got, filter)
+	}
+
+	for _, filter := range []string{"(uid=jdoe", "(&)", "(=jdoe)", `(uid=\2)`, "(uid=a)(uid=b)", "(!(uid=a)"} {
+		_, err := compileFilter(filter)
+		assert.ErrorIs(t, err, ErrInvalidFilter, filter)
+	}
+}
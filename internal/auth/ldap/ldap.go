@@ -0,0 +1,453 @@
+// Package ldap authenticates users against an LDAP directory (OpenLDAP,
+// Active Directory...): the user's entry is searched with a service account,
+// then the password is checked with a bind as that entry.
+//
+// It implements the few LDAPv3 operations it needs (RFC 4511) rather than a
+// general purpose client.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the user is unknown to the
+	// directory or the password is wrong; the two are not told apart.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrAmbiguousUser is returned when the user filter matches several entries.
+	ErrAmbiguousUser = errors.New("user filter matches several entries")
+	// ErrOperationFailed is returned when the server answers an operation
+	// with an error result.
+	ErrOperationFailed = errors.New("LDAP operation failed")
+	// ErrInvalidURL is returned when the directory URL is not ldap:// or ldaps://.
+	ErrInvalidURL = errors.New("invalid LDAP URL")
+)
+
+const (
+	// defaultTimeout bounds each authentication when the context has no
+	// deadline.
+	defaultTimeout = 10 * time.Second
+
+	resultSuccess            = 0
+	resultInvalidCredentials = 49
+
+	startTLSOID = "1.3.6.1.4.1.1466.20037"
+)
+
+// Entry is the directory entry of an authenticated user.
+type Entry struct {
+	DN          string
+	Email       string
+	DisplayName string
+	// Groups are the DNs listed in the group attribute.
+	Groups []string
+}
+
+// Directory authenticates users against an LDAP server.
+type Directory struct {
+	cfg       config.LDAPConfig
+	address   string
+	tlsConfig *tls.Config
+	// implicitTLS is set for ldaps://.
+	implicitTLS bool
+}
+
+// New creates a Directory. It checks the URL and the user filter, and reads
+// the CA bundle if one is configured.
+func New(cfg config.LDAPConfig) (*Directory, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	d := &Directory{cfg: cfg, address: u.Host}
+
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			d.address = net.JoinHostPort(u.Hostname(), "389")
+		}
+	case "ldaps":
+		d.implicitTLS = true
+		if u.Port() == "" {
+			d.address = net.JoinHostPort(u.Hostname(), "636")
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidURL, cfg.URL)
+	}
+
+	if _, err := compileFilter(d.userFilter("check")); err != nil {
+		return nil, err
+	}
+
+	d.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: u.Hostname()}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LDAP CA file: %w", err)
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in LDAP CA file %s", cfg.CAFile)
+		}
+
+		d.tlsConfig.RootCAs = roots
+	}
+
+	return d, nil
+}
+
+// userFilter returns the user filter for a login name.
+func (d *Directory) userFilter(username string) string {
+	return strings.ReplaceAll(d.cfg.UserFilter, "{username}", EscapeFilter(username))
+}
+
+// VerifyPassword reports whether the directory accepts the password of a user.
+func (d *Directory) VerifyPassword(ctx context.Context, username, password string) (bool, error) {
+	if _, err := d.Authenticate(ctx, username, password); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Authenticate finds the entry of a user and binds as it with the password.
+func (d *Directory) Authenticate(ctx context.Context, username, password string) (*Entry, error) {
+	// An empty password would be an unauthenticated bind, which servers
+	// accept for any DN (RFC 4513 5.1.2).
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+
+	c, err := d.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	if d.cfg.BindDN != "" {
+		if err := c.bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			// Not the user's credentials: report a failure, not a denial.
+			if errors.Is(err, ErrInvalidCredentials) {
+				err = fmt.Errorf("%w: invalid credentials", ErrOperationFailed)
+			}
+
+			return nil, fmt.Errorf("service account bind: %w", err)
+		}
+	}
+
+	entry, err := c.searchUser(d.cfg.BaseDN, d.userFilter(username), d.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.bind(entry.DN, password); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// conn is a connection to the directory server.
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	msgID   int32
+}
+
+func (d *Directory) dial(ctx context.Context) (*conn, error) {
+	dialer := &net.Dialer{}
+
+	netConn, err := dialer.DialContext(ctx, "tcp", d.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	}
+
+	c := &conn{netConn: netConn, reader: bufio.NewReader(netConn)}
+
+	switch {
+	case d.implicitTLS:
+		c.upgrade(d.tlsConfig)
+	case d.cfg.StartTLS:
+		if err := c.startTLS(d.tlsConfig); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// upgrade wraps the connection in TLS.
+func (c *conn) upgrade(cfg *tls.Config) {
+	c.netConn = tls.Client(c.netConn, cfg)
+	c.reader = bufio.NewReader(c.netConn)
+}
+
+func (c *conn) close() {
+	// UnbindRequest: no response is expected.
+	_, _ = c.send([]byte{tagUnbindRequest, 0})
+	_ = c.netConn.Close()
+}
+
+// send sends an operation in a new message and returns the message ID.
+func (c *conn) send(op []byte) (int32, error) {
+	c.msgID++
+
+	if _, err := c.netConn.Write(encode(tagSequence, encodeInt(tagInteger, int64(c.msgID)), op)); err != nil {
+		return 0, fmt.Errorf("failed to send LDAP request: %w", err)
+	}
+
+	return c.msgID, nil
+}
+
+// roundTrip sends an operation and returns the protocol operation of the
+// response.
+func (c *conn) roundTrip(op []byte) (element, error) {
+	id, err := c.send(op)
+	if err != nil {
+		return element{}, err
+	}
+
+	return c.read(id)
+}
+
+// read returns the protocol operation of the next message, which must answer
+// the given message ID.
+func (c *conn) read(id int32) (element, error) {
+	packet, err := readPacket(c.reader)
+	if err != nil {
+		return element{}, fmt.Errorf("failed to read LDAP response: %w", err)
+	}
+
+	parts, err := packet.children()
+	if err != nil || packet.tag != tagSequence || len(parts) < 2 {
+		return element{}, ErrMalformedPacket
+	}
+
+	if got, err := parts[0].int(); err != nil || got != int64(id) {
+		return element{}, fmt.Errorf("%w: response to message %d, want %d", ErrMalformedPacket, got, id)
+	}
+
+	return parts[1], nil
+}
+
+// result checks an LDAPResult: resultCode, matchedDN, diagnosticMessage.
+func result(op element) error {
+	parts, err := op.children()
+	if err != nil || len(parts) < 3 {
+		return ErrMalformedPacket
+	}
+
+	code, err := parts[0].int()
+	if err != nil {
+		return err
+	}
+
+	switch code {
+	case resultSuccess:
+		return nil
+	case resultInvalidCredentials:
+		return ErrInvalidCredentials
+	default:
+		return fmt.Errorf("%w: result code %d: %s", ErrOperationFailed, code, parts[2].content)
+	}
+}
+
+func (c *conn) startTLS(cfg *tls.Config) error {
+	op, err := c.roundTrip(encode(tagExtendedRequest, encodeString(tagExtendedRequestName, startTLSOID)))
+	if err != nil {
+		return err
+	}
+
+	if op.tag != tagExtendedResponse {
+		return fmt.Errorf("%w: StartTLS answered with tag %#x", ErrMalformedPacket, op.tag)
+	}
+
+	if err := result(op); err != nil {
+		return fmt.Errorf("StartTLS: %w", err)
+	}
+
+	c.upgrade(cfg)
+
+	return nil
+}
+
+func (c *conn) bind(dn, password string) error {
+	op, err := c.roundTrip(encode(tagBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(tagSimpleAuth, password),
+	))
+	if err != nil {
+		return err
+	}
+
+	if op.tag != tagBindResponse {
+		return fmt.Errorf("%w: bind answered with tag %#x", ErrMalformedPacket, op.tag)
+	}
+
+	return result(op)
+}
+
+// searchUser searches the subtree for the single entry matching the filter,
+// with the attributes named by the configuration.
+func (c *conn) searchUser(baseDN, filter string, cfg config.LDAPConfig) (*Entry, error) {
+	compiled, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs [][]byte
+	for _, attr := range []string{cfg.EmailAttribute, cfg.NameAttribute, cfg.GroupAttribute} {
+		if attr != "" {
+			attrs = append(attrs, encodeString(tagOctetString, attr))
+		}
+	}
+
+	id, err := c.send(encode(tagSearchRequest,
+		encodeString(tagOctetString, baseDN),
+		encodeInt(tagEnumerated, 2), // wholeSubtree
+		encodeInt(tagEnumerated, 0), // neverDerefAliases
+		encodeInt(tagInteger, 2),    // sizeLimit: enough to detect ambiguity
+		encodeInt(tagInteger, int64(defaultTimeout/time.Second)),
+		encodeBool(false),
+		compiled,
+		encode(tagSequence, attrs...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+
+	for {
+		op, err := c.read(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.tag {
+		case tagSearchResultEntry:
+			entry, err := parseEntry(op, cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, entry)
+		case tagSearchResultRef:
+			// Referrals to other servers are not followed.
+		case tagSearchResultDone:
+			// sizeLimitExceeded (4) also means several entries matched.
+			if err := result(op); err != nil && len(entries) < 2 {
+				return nil, fmt.Errorf("search: %w", err)
+			}
+
+			switch len(entries) {
+			case 0:
+				return nil, ErrInvalidCredentials
+			case 1:
+				return entries[0], nil
+			default:
+				return nil, ErrAmbiguousUser
+			}
+		default:
+			return nil, fmt.Errorf("%w: search answered with tag %#x", ErrMalformedPacket, op.tag)
+		}
+	}
+}
+
+// parseEntry decodes a SearchResultEntry: objectName, then a sequence of
+// (type, set of values) attributes.
+func parseEntry(op element, cfg config.LDAPConfig) (*Entry, error) {
+	parts, err := op.children()
+	if err != nil || len(parts) != 2 {
+		return nil, ErrMalformedPacket
+	}
+
+	entry := &Entry{DN: string(parts[0].content)}
+
+	attributes, err := parts[1].children()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attribute := range attributes {
+		fields, err := attribute.children()
+		if err != nil || len(fields) != 2 {
+			return nil, ErrMalformedPacket
+		}
+
+		values, err := fields[1].children()
+		if err != nil || len(values) == 0 {
+			continue
+		}
+
+		// Attribute descriptions are case-insensitive.
+		switch name := string(fields[0].content); {
+		case strings.EqualFold(name, cfg.EmailAttribute):
+			entry.Email = string(values[0].content)
+		case strings.EqualFold(name, cfg.NameAttribute):
+			entry.DisplayName = string(values[0].content)
+		case strings.EqualFold(name, cfg.GroupAttribute):
+			for _, value := range values {
+				entry.Groups = append(entry.Groups, string(value.content))
+			}
+		}
+	}
+
+	return entry, nil
+}
+
+// GroupName returns the name of a group from its DN: the value of its first
+// RDN, as in "dbbat-admins" for "CN=dbbat-admins,OU=Groups,DC=corp". A value
+// that is not a DN is returned as is.
+func GroupName(dn string) string {
+	first := dn
+
+	// The first unescaped comma ends the RDN ("CN=Doe\, John,OU=...").
+	for i := 0; i < len(dn); i++ {
+		if dn[i] == '\\' {
+			i++
+		} else if dn[i] == ',' {
+			first = dn[:i]
+			break
+		}
+	}
+
+	_, value, ok := strings.Cut(first, "=")
+	if !ok {
+		return strings.TrimSpace(dn)
+	}
+
+	return strings.TrimSpace(strings.ReplaceAll(value, `\`, ""))
+}
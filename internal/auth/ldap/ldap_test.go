@@ -0,0 +1,233 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// fakeDirectory is a minimal LDAP server: binds check a DN/password table,
+// searches return the entries whose filter, compiled, matches the request.
+type fakeDirectory struct {
+	t         *testing.T
+	passwords map[string]string                // DN -> password
+	entries   map[string][]map[string][]string // filter -> entries, "dn" holds the DN
+}
+
+func (f *fakeDirectory) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		packet, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		parts, err := packet.children()
+		require.NoError(f.t, err)
+
+		id, err := parts[0].int()
+		require.NoError(f.t, err)
+
+		reply := func(op []byte) {
+			_, _ = conn.Write(encode(tagSequence, encodeInt(tagInteger, id), op))
+		}
+		ldapResult := func(tag byte, code int64) []byte {
+			return encode(tag, encodeInt(tagEnumerated, code), encodeString(tagOctetString, ""), encodeString(tagOctetString, ""))
+		}
+
+		switch op := parts[1]; op.tag {
+		case tagBindRequest:
+			fields, err := op.children()
+			require.NoError(f.t, err)
+
+			dn, password := string(fields[1].content), string(fields[2].content)
+			if want, ok := f.passwords[dn]; ok && want == password {
+				reply(ldapResult(tagBindResponse, resultSuccess))
+			} else {
+				reply(ldapResult(tagBindResponse, resultInvalidCredentials))
+			}
+		case tagSearchRequest:
+			fields, err := op.children()
+			require.NoError(f.t, err)
+
+			filter := encode(fields[6].tag, fields[6].content)
+
+			for text, entries := range f.entries {
+				compiled, err := compileFilter(text)
+				require.NoError(f.t, err)
+
+				if string(compiled) != string(filter) {
+					continue
+				}
+
+				for _, entry := range entries {
+					var attributes [][]byte
+
+					for name, values := range entry {
+						if name == "dn" {
+							continue
+						}
+
+						var encoded [][]byte
+						for _, v := range values {
+							encoded = append(encoded, encodeString(tagOctetString, v))
+						}
+
+						attributes = append(attributes, encode(tagSequence, encodeString(tagOctetString, name), encode(tagSet, encoded...)))
+					}
+
+					reply(encode(tagSearchResultEntry, encodeString(tagOctetString, entry["dn"][0]), encode(tagSequence, attributes...)))
+				}
+			}
+
+			reply(ldapResult(tagSearchResultDone, resultSuccess))
+		case tagUnbindRequest:
+			return
+		default:
+			f.t.Errorf("unexpected operation %#x", op.tag)
+			return
+		}
+	}
+}
+
+// newFakeDirectory starts a fake directory and returns its configuration.
+func newFakeDirectory(t *testing.T, f *fakeDirectory) config.LDAPConfig {
+	t.Helper()
+
+	f.t = t
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go f.serve(conn)
+		}
+	}()
+
+	return config.LDAPConfig{
+		URL:            "ldap://" + listener.Addr().String(),
+		BindDN:         "cn=svc,dc=example,dc=com",
+		BindPassword:   "svc-secret",
+		BaseDN:         "dc=example,dc=com",
+		UserFilter:     "(&(objectClass=person)(uid={username}))",
+		EmailAttribute: "mail",
+		NameAttribute:  "displayName",
+		GroupAttribute: "memberOf",
+	}
+}
+
+func TestDirectory_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	cfg := newFakeDirectory(t, &fakeDirectory{
+		passwords: map[string]string{
+			"cn=svc,dc=example,dc=com":             "svc-secret",
+			"uid=jdoe,ou=people,dc=example,dc=com": "jdoe-secret",
+		},
+		entries: map[string][]map[string][]string{
+			"(&(objectClass=person)(uid=jdoe))": {{
+				"dn":          {"uid=jdoe,ou=people,dc=example,dc=com"},
+				"mail":        {"jdoe@example.com"},
+				"displayName": {"Jane Doe"},
+				"MemberOf":    {"cn=dbbat-admins,ou=groups,dc=example,dc=com", "cn=sre,ou=groups,dc=example,dc=com"},
+			}},
+			"(&(objectClass=person)(uid=twin))": {
+				{"dn": {"uid=twin,ou=a,dc=example,dc=com"}},
+				{"dn": {"uid=twin,ou=b,dc=example,dc=com"}},
+			},
+		},
+	})
+
+	d, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	entry, err := d.Authenticate(ctx, "jdoe", "jdoe-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "uid=jdoe,ou=people,dc=example,dc=com", entry.DN)
+	assert.Equal(t, "jdoe@example.com", entry.Email)
+	assert.Equal(t, "Jane Doe", entry.DisplayName)
+	assert.Len(t, entry.Groups, 2)
+
+	_, err = d.Authenticate(ctx, "jdoe", "wrong")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = d.Authenticate(ctx, "jdoe", "")
+	require.ErrorIs(t, err, ErrInvalidCredentials, "an empty password must not reach an unauthenticated bind")
+
+	_, err = d.Authenticate(ctx, "nobody", "jdoe-secret")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = d.Authenticate(ctx, "twin", "secret")
+	require.ErrorIs(t, err, ErrAmbiguousUser)
+
+	// The login name is escaped: it cannot widen the filter to every user.
+	_, err = d.Authenticate(ctx, "*", "jdoe-secret")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	valid, err := d.VerifyPassword(ctx, "jdoe", "jdoe-secret")
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = d.VerifyPassword(ctx, "jdoe", "wrong")
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
+func TestDirectory_ServiceAccountRejected(t *testing.T) {
+	t.Parallel()
+
+	cfg := newFakeDirectory(t, &fakeDirectory{passwords: map[string]string{}})
+
+	d, err := New(cfg)
+	require.NoError(t, err)
+
+	_, err = d.VerifyPassword(context.Background(), "jdoe", "secret")
+	require.ErrorIs(t, err, ErrOperationFailed, "a bad service account is a failure, not a wrong password")
+	assert.False(t, errors.Is(err, ErrInvalidCredentials))
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	for url, wantErr := range map[string]error{
+		"ldap://ad.example.com":       nil,
+		"ldaps://ad.example.com:1636": nil,
+		"http://ad.example.com":       ErrInvalidURL,
+	} {
+		_, err := New(config.LDAPConfig{URL: url, BaseDN: "dc=example", UserFilter: "(uid={username})"})
+		assert.ErrorIs(t, err, wantErr, url)
+	}
+
+	_, err := New(config.LDAPConfig{URL: "ldap://ad.example.com", UserFilter: "(uid={username}"})
+	require.ErrorIs(t, err, ErrInvalidFilter)
+
+	_, err = New(config.LDAPConfig{URL: "ldap://ad.example.com", UserFilter: "(uid={username})", CAFile: "/nonexistent.pem"})
+	require.Error(t, err)
+}
+
+func TestGroupName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "dbbat-admins", GroupName("CN=dbbat-admins,OU=Groups,DC=corp,DC=example"))
+	assert.Equal(t, "Doe, John", GroupName(`CN=Doe\, John,OU=Groups,DC=corp`))
+	assert.Equal(t, "sre", GroupName("sre"))
+}
@@ -15,6 +15,13 @@ type OAuthProvider interface {
 	ExchangeCode(ctx context.Context, code, redirectURI string) (*OAuthUser, error)
 }
 
+// OAuthDiscoverer is implemented by providers whose endpoints are discovered
+// from the identity provider rather than fixed. Discover is called before
+// building an authorization URL and caches its result.
+type OAuthDiscoverer interface {
+	Discover(ctx context.Context) error
+}
+
 // OAuthUser represents normalized user info from any OAuth provider.
 type OAuthUser struct {
 	ProviderID  string          // Provider-specific user ID
@@ -23,5 +30,6 @@ type OAuthUser struct {
 	TeamID      string          // Optional workspace/org ID
 	TeamName    string          // Optional workspace/org name
 	AvatarURL   string          // Optional profile picture URL
+	Groups      []string        // Optional group or role claim values
 	RawData     json.RawMessage // Full provider response
 }
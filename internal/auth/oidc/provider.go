@@ -0,0 +1,390 @@
+// Package oidc implements sign-in through a generic OpenID Connect identity
+// provider with the authorization code flow.
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/auth"
+)
+
+var (
+	// ErrUnexpectedStatus is returned when the HTTP response has a non-200 status code.
+	ErrUnexpectedStatus = errors.New("unexpected status")
+	// ErrIssuerMismatch is returned when the discovery document or an ID
+	// token names another issuer than the configured one.
+	ErrIssuerMismatch = errors.New("issuer mismatch")
+	// ErrInvalidIDToken is returned when the ID token is malformed, expired
+	// or issued for another client.
+	ErrInvalidIDToken = errors.New("invalid ID token")
+	// ErrSubjectMismatch is returned when the userinfo endpoint describes
+	// another user than the ID token.
+	ErrSubjectMismatch = errors.New("userinfo subject mismatch")
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	// clockSkew is the leeway given to the expiry of ID tokens.
+	clockSkew = time.Minute
+)
+
+// Config configures a Provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scopes are requested on top of "openid email profile".
+	Scopes []string
+	// RolesClaim names the claim copied into OAuthUser.Groups; a dotted
+	// path reaches nested claims.
+	RolesClaim string
+}
+
+// Provider implements auth.OAuthProvider for an OpenID Connect identity
+// provider. Its endpoints are discovered on first use, so an identity
+// provider that is down when dbbat starts does not disable sign-in.
+type Provider struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	document *discoveryDocument
+}
+
+// discoveryDocument holds the fields of the provider metadata dbbat uses.
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// tokenResponse represents the token endpoint response.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// NewProvider creates a new OpenID Connect provider.
+func NewProvider(cfg Config) *Provider {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+
+	return &Provider{
+		cfg:        cfg,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// Name returns "oidc".
+func (p *Provider) Name() string {
+	return "oidc"
+}
+
+// Discover fetches the provider metadata from the issuer, once.
+func (p *Provider) Discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.document != nil {
+		return nil
+	}
+
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, p.cfg.Issuer+discoveryPath, "", &doc); err != nil {
+		return fmt.Errorf("discovery: %w", err)
+	}
+
+	if strings.TrimSuffix(doc.Issuer, "/") != p.cfg.Issuer {
+		return fmt.Errorf("discovery: %w: got %q, want %q", ErrIssuerMismatch, doc.Issuer, p.cfg.Issuer)
+	}
+
+	p.document = &doc
+
+	return nil
+}
+
+// AuthorizeURL builds the authorization URL. Discover must have succeeded.
+func (p *Provider) AuthorizeURL(state, redirectURI string) string {
+	p.mu.Lock()
+	doc := p.document
+	p.mu.Unlock()
+
+	if doc == nil {
+		return ""
+	}
+
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.cfg.ClientID},
+		"scope":         {strings.Join(append([]string{"openid", "email", "profile"}, p.cfg.Scopes...), " ")},
+		"redirect_uri":  {redirectURI},
+		"state":         {state},
+	}
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return doc.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// ExchangeCode exchanges an authorization code for user info.
+// It calls the token endpoint, checks the ID token, completes its claims
+// from the userinfo endpoint and returns a normalized OAuthUser.
+func (p *Provider) ExchangeCode(ctx context.Context, code, redirectURI string) (*auth.OAuthUser, error) {
+	if err := p.Discover(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	doc := p.document
+	p.mu.Unlock()
+
+	// 1. Exchange code for tokens.
+	token, err := p.exchangeToken(ctx, doc.TokenEndpoint, code, redirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange: %w", err)
+	}
+
+	// 2. Check the ID token.
+	claims, err := p.idTokenClaims(token.IDToken, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Complete the claims from userinfo: many providers only put groups
+	// and profile data there.
+	if doc.UserInfoEndpoint != "" && token.AccessToken != "" {
+		var userInfo map[string]any
+		if err := p.getJSON(ctx, doc.UserInfoEndpoint, token.AccessToken, &userInfo); err != nil {
+			return nil, fmt.Errorf("user info: %w", err)
+		}
+
+		if sub, _ := userInfo["sub"].(string); sub != claims["sub"] {
+			return nil, fmt.Errorf("%w: got %q", ErrSubjectMismatch, sub)
+		}
+
+		for name, value := range userInfo {
+			if _, ok := claims[name]; !ok {
+				claims[name] = value
+			}
+		}
+	}
+
+	return p.user(claims), nil
+}
+
+// user normalizes the claims of a signed-in user.
+func (p *Provider) user(claims map[string]any) *auth.OAuthUser {
+	str := func(name string) string {
+		s, _ := claims[name].(string)
+		return s
+	}
+
+	// An unverified address must not link the identity to the local
+	// account named after it.
+	email := str("email")
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		email = ""
+	}
+
+	displayName := str("name")
+	if displayName == "" {
+		displayName = str("preferred_username")
+	}
+
+	rawData, _ := json.Marshal(claims)
+
+	return &auth.OAuthUser{
+		ProviderID:  str("sub"),
+		Email:       email,
+		DisplayName: displayName,
+		AvatarURL:   str("picture"),
+		Groups:      claimStrings(claims, p.cfg.RolesClaim),
+		RawData:     rawData,
+	}
+}
+
+func (p *Provider) exchangeToken(ctx context.Context, tokenURL, code, redirectURI string) (*tokenResponse, error) {
+	data := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURI},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// client_secret_basic, the authentication method every provider supports.
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	body, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenResp tokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &tokenResp, nil
+}
+
+// idTokenClaims decodes the ID token and checks its issuer, audience and
+// expiry. Its signature is not checked: the token comes straight from the
+// token endpoint over TLS, which OpenID Connect Core (3.1.3.7) accepts in
+// place of the signature.
+func (p *Provider) idTokenClaims(idToken string, now time.Time) (map[string]any, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidIDToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: %w: got %q", ErrInvalidIDToken, ErrIssuerMismatch, iss)
+	}
+
+	if !containsString(claims["aud"], p.cfg.ClientID) {
+		return nil, fmt.Errorf("%w: issued for another client", ErrInvalidIDToken)
+	}
+
+	exp, _ := claims["exp"].(float64)
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// getJSON fetches a JSON document, with a bearer token if one is given.
+func (p *Provider) getJSON(ctx context.Context, target, accessToken string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	body, err := p.do(req)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Provider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w %d: %s", ErrUnexpectedStatus, resp.StatusCode, body)
+	}
+
+	return body, nil
+}
+
+// claimStrings returns the string values of a claim. The name is looked up
+// as is first, as namespaced claims ("https://example.com/groups") contain
+// dots, then as a dotted path into nested claims ("realm_access.roles").
+func claimStrings(claims map[string]any, name string) []string {
+	if name == "" {
+		return nil
+	}
+
+	value, ok := claims[name]
+	if !ok {
+		var current any = claims
+
+		for _, key := range strings.Split(name, ".") {
+			object, isObject := current.(map[string]any)
+			if !isObject {
+				return nil
+			}
+
+			current = object[key]
+		}
+
+		value = current
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+
+		for _, item := range v {
+			if s, isString := item.(string); isString {
+				values = append(values, s)
+			}
+		}
+
+		return values
+	default:
+		return nil
+	}
+}
+
+// containsString reports whether a claim is the given string or a list
+// holding it, as the "aud" claim can be either.
+func containsString(claim any, want string) bool {
+	switch v := claim.(type) {
+	case string:
+		return v == want
+	case []any:
+		for _, item := range v {
+			if item == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
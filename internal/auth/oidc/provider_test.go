@@ -0,0 +1,214 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unsignedJWT builds a JWT carrying the given claims, with a dummy signature.
+func unsignedJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+// newIdentityProvider starts a fake identity provider; idClaims and
+// userInfo are served as the ID token and userinfo of every sign-in.
+func newIdentityProvider(t *testing.T, idClaims func(issuer string) map[string]any, userInfo map[string]any) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(discoveryDocument{
+			Issuer:                srv.URL,
+			AuthorizationEndpoint: srv.URL + "/authorize",
+			TokenEndpoint:         srv.URL + "/token",
+			UserInfoEndpoint:      srv.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "my-id", id)
+		assert.Equal(t, "my-secret", secret)
+
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm failed: %v", err)
+			return
+		}
+		assert.Equal(t, "authorization_code", r.FormValue("grant_type"))
+		assert.Equal(t, "auth-code-123", r.FormValue("code"))
+		assert.Equal(t, "http://localhost/callback", r.FormValue("redirect_uri"))
+
+		_ = json.NewEncoder(w).Encode(tokenResponse{
+			AccessToken: "access-token",
+			IDToken:     unsignedJWT(t, idClaims(srv.URL)),
+			TokenType:   "Bearer",
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer access-token", r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(userInfo)
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func validClaims(issuer string) map[string]any {
+	return map[string]any{
+		"iss":   issuer,
+		"aud":   "my-id",
+		"sub":   "00u1abcd",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "jane@example.com",
+		"name":  "Jane Doe",
+	}
+}
+
+func TestProvider_Name(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "oidc", NewProvider(Config{}).Name())
+}
+
+func TestProvider_AuthorizeURL(t *testing.T) {
+	t.Parallel()
+
+	srv := newIdentityProvider(t, validClaims, nil)
+	p := NewProvider(Config{Issuer: srv.URL + "/", ClientID: "my-id", ClientSecret: "my-secret", Scopes: []string{"groups"}})
+
+	assert.Empty(t, p.AuthorizeURL("state123", "http://localhost/callback"), "no URL before discovery")
+	require.NoError(t, p.Discover(context.Background()))
+
+	u := p.AuthorizeURL("state123", "http://localhost/callback")
+	assert.Contains(t, u, srv.URL+"/authorize?")
+	assert.Contains(t, u, "client_id=my-id")
+	assert.Contains(t, u, "scope=openid+email+profile+groups")
+	assert.Contains(t, u, "state=state123")
+}
+
+func TestProvider_DiscoverIssuerMismatch(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(discoveryDocument{Issuer: "https://elsewhere.example.com"})
+	}))
+	t.Cleanup(srv.Close)
+
+	err := NewProvider(Config{Issuer: srv.URL}).Discover(context.Background())
+	require.ErrorIs(t, err, ErrIssuerMismatch)
+}
+
+func TestProvider_ExchangeCode(t *testing.T) {
+	t.Parallel()
+
+	srv := newIdentityProvider(t, validClaims, map[string]any{
+		"sub":     "00u1abcd",
+		"name":    "Ignored, the ID token wins",
+		"picture": "https://example.com/jane.png",
+		"groups":  []string{"dbbat-admins", "sre"},
+	})
+	p := NewProvider(Config{Issuer: srv.URL, ClientID: "my-id", ClientSecret: "my-secret", RolesClaim: "groups"})
+
+	user, err := p.ExchangeCode(context.Background(), "auth-code-123", "http://localhost/callback")
+	require.NoError(t, err)
+
+	assert.Equal(t, "00u1abcd", user.ProviderID)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.Equal(t, "Jane Doe", user.DisplayName)
+	assert.Equal(t, "https://example.com/jane.png", user.AvatarURL)
+	assert.Equal(t, []string{"dbbat-admins", "sre"}, user.Groups)
+	assert.Contains(t, string(user.RawData), `"groups"`)
+}
+
+func TestProvider_ExchangeCode_SubjectMismatch(t *testing.T) {
+	t.Parallel()
+
+	srv := newIdentityProvider(t, validClaims, map[string]any{"sub": "someone-else"})
+	p := NewProvider(Config{Issuer: srv.URL, ClientID: "my-id", ClientSecret: "my-secret"})
+
+	_, err := p.ExchangeCode(context.Background(), "auth-code-123", "http://localhost/callback")
+	require.ErrorIs(t, err, ErrSubjectMismatch)
+}
+
+func TestProvider_IDTokenClaims(t *testing.T) {
+	t.Parallel()
+
+	p := NewProvider(Config{Issuer: "https://idp.example.com", ClientID: "my-id"})
+	now := time.Now()
+
+	for name, tc := range map[string]struct {
+		mutate func(claims map[string]any)
+		valid  bool
+	}{
+		"valid":               {func(map[string]any) {}, true},
+		"audience list":       {func(c map[string]any) { c["aud"] = []string{"other", "my-id"} }, true},
+		"trailing slash":      {func(c map[string]any) { c["iss"] = "https://idp.example.com/" }, true},
+		"within clock skew":   {func(c map[string]any) { c["exp"] = now.Add(-30 * time.Second).Unix() }, true},
+		"expired":             {func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() }, false},
+		"other issuer":        {func(c map[string]any) { c["iss"] = "https://evil.example.com" }, false},
+		"other audience":      {func(c map[string]any) { c["aud"] = "other" }, false},
+		"no subject":          {func(c map[string]any) { delete(c, "sub") }, false},
+		"no expiry":           {func(c map[string]any) { delete(c, "exp") }, false},
+		"audience list other": {func(c map[string]any) { c["aud"] = []string{"other"} }, false},
+	} {
+		claims := validClaims("https://idp.example.com")
+		tc.mutate(claims)
+
+		_, err := p.idTokenClaims(unsignedJWT(t, claims), now)
+		if tc.valid {
+			assert.NoError(t, err, name)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidIDToken, name)
+		}
+	}
+
+	_, err := p.idTokenClaims("not-a-jwt", now)
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestProvider_UnverifiedEmail(t *testing.T) {
+	t.Parallel()
+
+	p := NewProvider(Config{})
+	user := p.user(map[string]any{"sub": "1", "email": "admin@example.com", "email_verified": false, "preferred_username": "jane"})
+
+	assert.Empty(t, user.Email)
+	assert.Equal(t, "jane", user.DisplayName)
+}
+
+func TestClaimStrings(t *testing.T) {
+	t.Parallel()
+
+	claims := map[string]any{
+		"groups":                     []any{"a", "b", 3},
+		"role":                       "admin",
+		"https://example.com/groups": []any{"namespaced"},
+		"realm_access":               map[string]any{"roles": []any{"nested"}},
+	}
+
+	assert.Equal(t, []string{"a", "b"}, claimStrings(claims, "groups"))
+	assert.Equal(t, []string{"admin"}, claimStrings(claims, "role"))
+	assert.Equal(t, []string{"namespaced"}, claimStrings(claims, "https://example.com/groups"))
+	assert.Equal(t, []string{"nested"}, claimStrings(claims, "realm_access.roles"))
+	assert.Nil(t, claimStrings(claims, "realm_access.missing.roles"))
+	assert.Nil(t, claimStrings(claims, ""))
+}
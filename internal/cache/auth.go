@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fclairamb/dbbat/internal/crypto"
@@ -14,15 +15,26 @@ import (
 // AuthCache provides caching for password verification results to avoid
 // expensive argon2id re-computation on every request.
 type AuthCache struct {
-	entries map[string]*cacheEntry
-	mu      sync.RWMutex
-	ttl     time.Duration
-	maxSize int
-	enabled bool
+	entries  map[string]*cacheEntry
+	mu       sync.RWMutex
+	settings atomic.Pointer[AuthCacheConfig]
+
+	// cleanupOnce starts the cleanup loop the first time the cache is enabled.
+	cleanupOnce sync.Once
 
 	// Stats for monitoring
 	hits   int64
 	misses int64
+
+	// directory checks the passwords of directory users; nil when none is
+	// configured.
+	directory PasswordDirectory
+}
+
+// PasswordDirectory checks the passwords of the users whose stored hash is
+// crypto.DirectoryPasswordHash, such as an LDAP directory.
+type PasswordDirectory interface {
+	VerifyPassword(ctx context.Context, username, password string) (bool, error)
 }
 
 type cacheEntry struct {
@@ -41,14 +53,12 @@ type AuthCacheConfig struct {
 func NewAuthCache(cfg AuthCacheConfig) *AuthCache {
 	cache := &AuthCache{
 		entries: make(map[string]*cacheEntry),
-		ttl:     time.Duration(cfg.TTLSeconds) * time.Second,
-		maxSize: cfg.MaxSize,
-		enabled: cfg.Enabled,
 	}
+	cache.settings.Store(&cfg)
 
-	if cache.enabled {
+	if cfg.Enabled {
 		// Start background cleanup goroutine
-		go cache.cleanupLoop()
+		cache.cleanupOnce.Do(func() { go cache.cleanupLoop() })
 
 		slog.InfoContext(context.Background(), "auth cache enabled",
 			slog.Int("ttl_seconds", cfg.TTLSeconds),
@@ -58,6 +68,24 @@ func NewAuthCache(cfg AuthCacheConfig) *AuthCache {
 	return cache
 }
 
+// Reconfigure applies a new configuration. The cached results are dropped,
+// so a shorter TTL or a disabled cache takes effect at once.
+func (c *AuthCache) Reconfigure(cfg AuthCacheConfig) {
+	c.mu.Lock()
+	c.settings.Store(&cfg)
+	c.entries = make(map[string]*cacheEntry)
+	c.mu.Unlock()
+
+	if cfg.Enabled {
+		c.cleanupOnce.Do(func() { go c.cleanupLoop() })
+	}
+}
+
+// ttl returns how long a verification result is reused.
+func (c *AuthCache) ttl() time.Duration {
+	return time.Duration(c.settings.Load().TTLSeconds) * time.Second
+}
+
 // computeKey generates a cache key from user identifier, password, and hash prefix.
 // Including the hash prefix ensures cache invalidation when password changes.
 func computeKey(userID, password, storedHash string) string {
@@ -77,7 +105,7 @@ func computeKey(userID, password, storedHash string) string {
 // VerifyPassword verifies a password, using cache if available.
 // userID should be a unique identifier for the user (e.g., UID).
 func (c *AuthCache) VerifyPassword(ctx context.Context, userID, password, storedHash string) (bool, error) {
-	if !c.enabled {
+	if !c.Enabled() {
 		return crypto.VerifyPassword(storedHash, password)
 	}
 
@@ -86,7 +114,7 @@ func (c *AuthCache) VerifyPassword(ctx context.Context, userID, password, stored
 	// Check cache
 	c.mu.RLock()
 	entry, found := c.entries[cacheKey]
-	if found && time.Since(entry.timestamp) < c.ttl {
+	if found && time.Since(entry.timestamp) < c.ttl() {
 		c.mu.RUnlock()
 		c.mu.Lock()
 		c.hits++
@@ -116,13 +144,68 @@ func (c *AuthCache) VerifyPassword(ctx context.Context, userID, password, stored
 	return valid, nil
 }
 
+// SetDirectory sets the directory checking the passwords of directory users.
+// Without one, they cannot authenticate with a password.
+func (c *AuthCache) SetDirectory(directory PasswordDirectory) {
+	c.directory = directory
+}
+
+// VerifyUserPassword verifies the password of a user: against the directory
+// for directory users, against the stored hash otherwise. Directory answers
+// are cached like hash verifications, sparing a round trip per connection.
+func (c *AuthCache) VerifyUserPassword(ctx context.Context, userID, username, password, storedHash string) (bool, error) {
+	if storedHash != crypto.DirectoryPasswordHash {
+		return c.VerifyPassword(ctx, userID, password, storedHash)
+	}
+
+	if c.directory == nil {
+		return false, nil
+	}
+
+	if !c.Enabled() {
+		return c.directory.VerifyPassword(ctx, username, password)
+	}
+
+	cacheKey := computeKey(userID, password, storedHash)
+
+	c.mu.RLock()
+	entry, found := c.entries[cacheKey]
+	c.mu.RUnlock()
+
+	if found && time.Since(entry.timestamp) < c.ttl() {
+		c.mu.Lock()
+		c.hits++
+		c.mu.Unlock()
+
+		slog.DebugContext(ctx, "auth cache hit", slog.String("auth_type", "directory"), slog.String("user_id", userID))
+
+		return entry.valid, nil
+	}
+
+	c.mu.Lock()
+	c.misses++
+	c.mu.Unlock()
+
+	slog.DebugContext(ctx, "auth cache miss", slog.String("auth_type", "directory"), slog.String("user_id", userID))
+
+	valid, err := c.directory.VerifyPassword(ctx, username, password)
+	if err != nil {
+		// An unreachable directory is not cached: the next attempt retries.
+		return false, err
+	}
+
+	c.set(cacheKey, valid)
+
+	return valid, nil
+}
+
 // set stores a verification result in the cache.
 func (c *AuthCache) set(key string, valid bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	// Evict oldest entries if at capacity
-	if len(c.entries) >= c.maxSize {
+	if len(c.entries) >= c.settings.Load().MaxSize {
 		c.evictOldest()
 	}
 
@@ -150,12 +233,11 @@ func (c *AuthCache) evictOldest() {
 	}
 }
 
-// cleanupLoop periodically removes expired entries.
+// cleanupLoop periodically removes expired entries, every half TTL of the
+// configuration in effect.
 func (c *AuthCache) cleanupLoop() {
-	ticker := time.NewTicker(c.ttl / 2)
-	defer ticker.Stop()
-
-	for range ticker.C {
+	for {
+		time.Sleep(max(c.ttl()/2, time.Second))
 		c.cleanup()
 	}
 }
@@ -166,8 +248,9 @@ func (c *AuthCache) cleanup() {
 	defer c.mu.Unlock()
 
 	now := time.Now()
+	ttl := c.ttl()
 	for key, entry := range c.entries {
-		if now.Sub(entry.timestamp) >= c.ttl {
+		if now.Sub(entry.timestamp) >= ttl {
 			delete(c.entries, key)
 		}
 	}
@@ -191,7 +274,7 @@ func (c *AuthCache) Clear() {
 
 // Enabled returns whether the cache is enabled.
 func (c *AuthCache) Enabled() bool {
-	return c.enabled
+	return c.settings.Load().Enabled
 }
 
 // computeKeyHash generates a cache key from plaintext key only.
@@ -206,7 +289,7 @@ func computeKeyHash(plainKey string) string {
 // keyID is used for logging only.
 // This method is suitable for API keys and web session keys.
 func (c *AuthCache) VerifyKey(ctx context.Context, keyID, plainKey, storedHash string) (bool, error) {
-	if !c.enabled {
+	if !c.Enabled() {
 		return crypto.VerifyPassword(storedHash, plainKey)
 	}
 
@@ -215,7 +298,7 @@ func (c *AuthCache) VerifyKey(ctx context.Context, keyID, plainKey, storedHash s
 	// Check cache
 	c.mu.RLock()
 	entry, found := c.entries[cacheKey]
-	if found && time.Since(entry.timestamp) < c.ttl {
+	if found && time.Since(entry.timestamp) < c.ttl() {
 		c.mu.RUnlock()
 		c.mu.Lock()
 		c.hits++
@@ -485,3 +485,109 @@ func TestComputeKeyHash(t *testing.T) {
 		t.Errorf("expected hash length of 64, got %d", len(hash1))
 	}
 }
+
+// fakeDirectory accepts one password per user and counts its calls.
+type fakeDirectory struct {
+	passwords map[string]string
+	calls     int
+	err       error
+}
+
+func (d *fakeDirectory) VerifyPassword(_ context.Context, username, password string) (bool, error) {
+	d.calls++
+
+	if d.err != nil {
+		return false, d.err
+	}
+
+	return d.passwords[username] == password, nil
+}
+
+func TestAuthCache_VerifyUserPassword(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cache := NewAuthCache(AuthCacheConfig{Enabled: true, TTLSeconds: 60, MaxSize: 100})
+
+	valid, err := cache.VerifyUserPassword(ctx, "user-1", "jdoe", "secret", crypto.DirectoryPasswordHash)
+	if err != nil || valid {
+		t.Fatalf("without a directory: valid = %v, err = %v, want false", valid, err)
+	}
+
+	directory := &fakeDirectory{passwords: map[string]string{"jdoe": "secret"}}
+	cache.SetDirectory(directory)
+
+	for range 2 {
+		valid, err = cache.VerifyUserPassword(ctx, "user-1", "jdoe", "secret", crypto.DirectoryPasswordHash)
+		if err != nil || !valid {
+			t.Fatalf("VerifyUserPassword() = %v, %v, want true", valid, err)
+		}
+	}
+
+	if directory.calls != 1 {
+		t.Errorf("directory called %d times, want 1 (second answer cached)", directory.calls)
+	}
+
+	directory.err = context.DeadlineExceeded
+
+	if _, err := cache.VerifyUserPassword(ctx, "user-1", "jdoe", "other", crypto.DirectoryPasswordHash); err == nil {
+		t.Error("expected the directory error")
+	}
+
+	directory.err = nil
+
+	if valid, _ := cache.VerifyUserPassword(ctx, "user-1", "jdoe", "other", crypto.DirectoryPasswordHash); valid {
+		t.Error("expected a wrong password to be rejected, the earlier error not being cached")
+	}
+
+	// Local users are not sent to the directory.
+	hash, err := crypto.HashPassword("local")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	calls := directory.calls
+
+	if valid, err := cache.VerifyUserPassword(ctx, "user-2", "local", "local", hash); err != nil || !valid {
+		t.Errorf("local user: valid = %v, err = %v, want true", valid, err)
+	}
+
+	if directory.calls != calls {
+		t.Error("a local user was checked against the directory")
+	}
+}
+
+func TestAuthCache_Reconfigure(t *testing.T) {
+	t.Parallel()
+
+	password := "testpassword"
+	hash, err := crypto.HashPassword(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	cache := NewAuthCache(AuthCacheConfig{Enabled: false, TTLSeconds: 60, MaxSize: 100})
+
+	cache.Reconfigure(AuthCacheConfig{Enabled: true, TTLSeconds: 60, MaxSize: 100})
+
+	for range 2 {
+		if _, err := cache.VerifyPassword(context.Background(), "user-123", password, hash); err != nil {
+			t.Fatalf("VerifyPassword failed: %v", err)
+		}
+	}
+
+	hits, misses, size := cache.Stats()
+	if hits != 1 || misses != 1 || size != 1 {
+		t.Errorf("once enabled: expected 1 hit, 1 miss, 1 entry; got %d hits, %d misses, %d entries", hits, misses, size)
+	}
+
+	cache.Reconfigure(AuthCacheConfig{Enabled: false, TTLSeconds: 60, MaxSize: 100})
+
+	if cache.Enabled() {
+		t.Error("expected the cache to be disabled")
+	}
+
+	if _, _, size := cache.Stats(); size != 0 {
+		t.Errorf("expected the entries to be dropped, got %d", size)
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -10,12 +11,17 @@ import (
 // RevocationHandle is held by a live proxy session for as long as it relies on
 // a particular grant. Its flag is flipped to true the instant that grant is
 // revoked, so the session's per-command check and its limit watchdog observe
-// the revocation without a database round-trip on every query.
+// the revocation without a database round-trip on every query. It also carries
+// the grant's new expiry when an extension is approved mid-session.
 //
 // All methods are nil-safe: a session that could not obtain a handle (e.g. a
-// nil registry in a test) treats itself as never-revoked.
+// nil registry in a test) treats itself as never-revoked and never-extended.
 type RevocationHandle struct {
 	revoked atomic.Bool
+
+	// extendedTo is the grant's extended expiry in Unix nanoseconds, 0 until
+	// an extension is approved.
+	extendedTo atomic.Int64
 }
 
 // Revoked reports whether the grant backing this handle has been revoked.
@@ -38,6 +44,30 @@ func (h *RevocationHandle) Flag() *atomic.Bool {
 	return &h.revoked
 }
 
+// ExpiresAt returns the grant's expiry: expiresAt, or the expiry of an
+// extension approved since if it is later.
+func (h *RevocationHandle) ExpiresAt(expiresAt time.Time) time.Time {
+	if h == nil {
+		return expiresAt
+	}
+
+	if extended := h.extendedTo.Load(); extended != 0 && time.Unix(0, extended).After(expiresAt) {
+		return time.Unix(0, extended)
+	}
+
+	return expiresAt
+}
+
+// Extension exposes the extended expiry (Unix nanoseconds, 0 when not
+// extended) so a limit watchdog can poll it. Returns nil for a nil handle.
+func (h *RevocationHandle) Extension() *atomic.Int64 {
+	if h == nil {
+		return nil
+	}
+
+	return &h.extendedTo
+}
+
 // RevocationRegistry is an in-process fan-out from the API's grant-revoke path
 // to the live proxy sessions that authenticated under those grants. It lets a
 // revocation take effect on already-established connections — blocking their
@@ -128,3 +158,25 @@ func (r *RevocationRegistry) Revoke(grantUID uuid.UUID) int {
 
 	return n
 }
+
+// Extend records expiresAt as the new expiry of grantUID on every live session
+// bound to it and returns the number of sessions signaled, so an extension
+// approved mid-session is not cut short by the expiry read at connect time.
+func (r *RevocationRegistry) Extend(grantUID uuid.UUID, expiresAt time.Time) int {
+	if r == nil || grantUID == uuid.Nil {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	set := r.sessions[grantUID]
+
+	n := 0
+	for h := range set {
+		h.extendedTo.Store(expiresAt.UnixNano())
+		n++
+	}
+
+	return n
+}
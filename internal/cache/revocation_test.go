@@ -3,6 +3,7 @@ package cache
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -126,6 +127,43 @@ func TestRevocationHandle_NilReceiver(t *testing.T) {
 	if h.Flag() != nil {
 		t.Fatal("nil handle Flag() should be nil")
 	}
+
+	if h.Extension() != nil {
+		t.Fatal("nil handle Extension() should be nil")
+	}
+}
+
+func TestRevocationRegistry_ExtendOnlyPushesExpiryBack(t *testing.T) {
+	t.Parallel()
+
+	r := NewRevocationRegistry()
+	grant := uuid.New()
+	h := r.Register(grant)
+	other := r.Register(uuid.New())
+
+	expiresAt := time.Now().Add(time.Minute)
+	if got := h.ExpiresAt(expiresAt); !got.Equal(expiresAt) {
+		t.Fatalf("ExpiresAt() before Extend = %v, want %v", got, expiresAt)
+	}
+
+	extended := expiresAt.Add(time.Hour)
+	if n := r.Extend(grant, extended); n != 1 {
+		t.Fatalf("Extend signaled %d sessions, want 1", n)
+	}
+
+	if got := h.ExpiresAt(expiresAt); !got.Equal(extended) {
+		t.Fatalf("ExpiresAt() after Extend = %v, want %v", got, extended)
+	}
+
+	// An extension never shortens a grant.
+	later := extended.Add(time.Hour)
+	if got := h.ExpiresAt(later); !got.Equal(later) {
+		t.Fatalf("ExpiresAt() past the extension = %v, want %v", got, later)
+	}
+
+	if got := other.ExpiresAt(expiresAt); !got.Equal(expiresAt) {
+		t.Fatalf("ExpiresAt() of another grant = %v, want %v", got, expiresAt)
+	}
 }
 
 func TestRevocationRegistry_ConcurrentRegisterRevokeDeregister(t *testing.T) {
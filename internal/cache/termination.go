@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// TerminationHandle is held by a live proxy session for as long as its
+// connection is open. Its flag is flipped to true when an administrator
+// terminates the connection, so the session's limit watchdog tears it down.
+//
+// All methods are nil-safe: a session that could not obtain a handle (e.g. a
+// nil registry in a test) treats itself as never-terminated.
+type TerminationHandle struct {
+	terminated atomic.Bool
+}
+
+// Terminated reports whether the connection was terminated by an administrator.
+func (h *TerminationHandle) Terminated() bool {
+	if h == nil {
+		return false
+	}
+
+	return h.terminated.Load()
+}
+
+// Flag exposes the underlying atomic flag so a limit watchdog can poll it.
+// Returns nil for a nil handle, which downstream guards treat as "nothing to
+// watch".
+func (h *TerminationHandle) Flag() *atomic.Bool {
+	if h == nil {
+		return nil
+	}
+
+	return &h.terminated
+}
+
+// TerminationRegistry is an in-process fan-out from the API's connection
+// termination path to the live proxy sessions, keyed by connection UID. Like
+// RevocationRegistry it carries no database state: Terminate flips the
+// session's flag and the session's LimitGuard watchdog does the rest,
+// recording the disconnect reason on its way out.
+type TerminationRegistry struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*TerminationHandle
+}
+
+// NewTerminationRegistry creates an empty registry.
+func NewTerminationRegistry() *TerminationRegistry {
+	return &TerminationRegistry{
+		sessions: make(map[uuid.UUID]*TerminationHandle),
+	}
+}
+
+// Register records the live session of connUID and returns its handle.
+// Deregister must be called when the session ends. Calling on a nil registry,
+// or with uuid.Nil (no connection record), still returns a usable
+// (never-terminated) handle so callers never have to nil-check the result.
+func (r *TerminationRegistry) Register(connUID uuid.UUID) *TerminationHandle {
+	h := &TerminationHandle{}
+
+	if r == nil || connUID == uuid.Nil {
+		return h
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[connUID] = h
+
+	return h
+}
+
+// Deregister drops a handle previously returned by Register. Safe to call with
+// a nil registry/handle or a handle that was never registered.
+func (r *TerminationRegistry) Deregister(connUID uuid.UUID, h *TerminationHandle) {
+	if r == nil || h == nil || connUID == uuid.Nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sessions[connUID] == h {
+		delete(r.sessions, connUID)
+	}
+}
+
+// Terminate flips the terminated flag of the live session of connUID and
+// reports whether one was found. It returns false when the connection is not
+// live in this process: already closed, or served by another DBBat instance.
+func (r *TerminationRegistry) Terminate(connUID uuid.UUID) bool {
+	if r == nil || connUID == uuid.Nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h := r.sessions[connUID]
+	if h == nil {
+		return false
+	}
+
+	h.terminated.Store(true)
+
+	return true
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTerminationRegistry_TerminateSignalsOnlyThatConnection(t *testing.T) {
+	t.Parallel()
+
+	r := NewTerminationRegistry()
+	connA := uuid.New()
+	connB := uuid.New()
+
+	ha := r.Register(connA)
+	hb := r.Register(connB)
+
+	if !r.Terminate(connA) {
+		t.Fatal("Terminate(connA) should find the live session")
+	}
+
+	if !ha.Terminated() {
+		t.Fatal("connA handle should be terminated")
+	}
+
+	if hb.Terminated() {
+		t.Fatal("connB handle must be unaffected by terminating connA")
+	}
+}
+
+func TestTerminationRegistry_DeregisteredSessionNotFound(t *testing.T) {
+	t.Parallel()
+
+	r := NewTerminationRegistry()
+	conn := uuid.New()
+
+	h := r.Register(conn)
+	r.Deregister(conn, h)
+
+	if r.Terminate(conn) {
+		t.Fatal("Terminate after Deregister should report no live session")
+	}
+
+	if h.Terminated() {
+		t.Fatal("deregistered handle must not be terminated")
+	}
+
+	if r.Terminate(uuid.New()) {
+		t.Fatal("Terminate(unknown) should report no live session")
+	}
+}
+
+func TestTerminationRegistry_NilSafety(t *testing.T) {
+	t.Parallel()
+
+	var r *TerminationRegistry
+
+	h := r.Register(uuid.New())
+	if h == nil {
+		t.Fatal("Register on nil registry returned nil handle")
+	}
+
+	r.Deregister(uuid.New(), h)
+
+	if r.Terminate(uuid.New()) {
+		t.Fatal("Terminate on nil registry should report no live session")
+	}
+
+	// uuid.Nil (no connection record) is never registered.
+	realReg := NewTerminationRegistry()
+	if hn := realReg.Register(uuid.Nil); hn == nil {
+		t.Fatal("Register(uuid.Nil) returned nil handle")
+	}
+
+	if realReg.Terminate(uuid.Nil) {
+		t.Fatal("Terminate(uuid.Nil) should report no live session")
+	}
+
+	var nilHandle *TerminationHandle
+	if nilHandle.Terminated() || nilHandle.Flag() != nil {
+		t.Fatal("nil handle should be never-terminated with no flag")
+	}
+}
@@ -1,15 +1,22 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf/parsers/json"
 	"github.com/knadh/koanf/parsers/toml/v2"
@@ -25,6 +32,14 @@ var (
 	ErrDSNRequired    = errors.New("DBB_DSN environment variable is required")
 	ErrKeyRequired    = errors.New("either DBB_KEY or DBB_KEYFILE must be set")
 	ErrInvalidKeySize = errors.New("encryption key must be 32 bytes")
+	ErrKeyCommand     = errors.New("key command failed")
+	ErrInvalidCIDR    = errors.New("invalid CIDR")
+	ErrInvalidRate    = errors.New("sample rate must be between 0 and 1")
+	ErrNotPositive    = errors.New("must be positive")
+	ErrNegative       = errors.New("must not be negative")
+	ErrInvalidURL     = errors.New("must be an http or https URL")
+	ErrInvalidValue   = errors.New("invalid value")
+	ErrNoTrustedPeers = errors.New("at least one trusted network is required")
 )
 
 // RunMode represents the application run mode.
@@ -49,6 +64,130 @@ type QueryStorageConfig struct {
 
 	// StoreResults enables/disables result storage globally.
 	StoreResults bool `koanf:"store_results"`
+
+	// CompactAfter is the age past which `dbbat db compact` folds the result
+	// rows of a query into a single compressed blob (e.g., "720h").
+	CompactAfter string `koanf:"compact_after"`
+
+	// MaxSQLBytes truncates the stored SQL text of longer queries, keeping
+	// their start and end (0 = no limit).
+	MaxSQLBytes int `koanf:"max_sql_bytes"`
+
+	// DedupWindow folds identical consecutive statements of a connection,
+	// within this window of the first one, into a single logged query with a
+	// repeat count (e.g., "10s"; empty = disabled).
+	DedupWindow string `koanf:"dedup_window"`
+
+	// CompressRows stores result rows zstd-compressed instead of as JSONB,
+	// trading the ability to query them in SQL for storage space.
+	CompressRows bool `koanf:"compress_rows"`
+}
+
+// CompactAge returns CompactAfter parsed.
+func (c QueryStorageConfig) CompactAge() (time.Duration, error) {
+	return time.ParseDuration(c.CompactAfter)
+}
+
+// DedupAge returns DedupWindow parsed, 0 when unset.
+func (c QueryStorageConfig) DedupAge() (time.Duration, error) {
+	return parseOptionalDuration(c.DedupWindow)
+}
+
+// Result spill targets.
+const (
+	ResultSpillS3   = "s3"
+	ResultSpillFile = "file"
+)
+
+// ResultSpillConfig configures where the proxies spill the results past the
+// query storage limits, as gzip-compressed JSON lines, instead of discarding
+// them. Any S3-compatible service works, as for the audit sink.
+type ResultSpillConfig struct {
+	// Target is "s3" or "file". Empty disables spilling.
+	Target string `koanf:"target"`
+
+	// Dir is the directory results are written to, for the file target.
+	// Every instance serving the API must see the files.
+	Dir string `koanf:"dir"`
+
+	// Bucket, Endpoint, Region, Prefix, AccessKeyID and SecretAccessKey
+	// address the bucket of the s3 target, like the audit sink's.
+	Bucket          string `koanf:"bucket"`
+	Endpoint        string `koanf:"endpoint"`
+	Region          string `koanf:"region"`
+	Prefix          string `koanf:"prefix"`
+	AccessKeyID     string `koanf:"access_key_id"`
+	SecretAccessKey string `koanf:"secret_access_key"`
+
+	// MaxBytes caps a spilled result, before compression: larger ones are
+	// discarded.
+	MaxBytes int64 `koanf:"max_bytes"`
+
+	// URLExpiry is how long the download URLs of spilled results are valid
+	// (e.g., "15m").
+	URLExpiry string `koanf:"url_expiry"`
+}
+
+// Enabled reports whether results are spilled.
+func (c ResultSpillConfig) Enabled() bool {
+	return c.Target != ""
+}
+
+// Expiry returns URLExpiry parsed.
+func (c ResultSpillConfig) Expiry() (time.Duration, error) {
+	return time.ParseDuration(c.URLExpiry)
+}
+
+// Default result spill settings.
+const (
+	DefaultResultSpillRegion    = "us-east-1"
+	DefaultResultSpillPrefix    = "results/"
+	DefaultResultSpillMaxBytes  = 1 << 30 // 1 GiB
+	DefaultResultSpillURLExpiry = "15m"
+)
+
+// maxResultSpillURLExpiry is the longest validity S3 accepts for presigned
+// URLs.
+const maxResultSpillURLExpiry = 7 * 24 * time.Hour
+
+// validate checks the settings of the configured target.
+func (c ResultSpillConfig) validate() error {
+	switch c.Target {
+	case "":
+		return nil
+	case ResultSpillFile:
+		if c.Dir == "" {
+			return fmt.Errorf("result_spill.dir: %w: required by the file target", ErrInvalidValue)
+		}
+	case ResultSpillS3:
+		if c.Bucket == "" {
+			return fmt.Errorf("result_spill.bucket: %w: required by the s3 target", ErrInvalidValue)
+		}
+
+		if c.Endpoint != "" {
+			if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("result_spill.endpoint: %w: %q", ErrInvalidURL, c.Endpoint)
+			}
+		}
+
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return fmt.Errorf("result_spill: %w: access_key_id and secret_access_key are required", ErrInvalidValue)
+		}
+	default:
+		return fmt.Errorf("result_spill.target: %w: %q (s3 or file)", ErrInvalidValue, c.Target)
+	}
+
+	if c.MaxBytes <= 0 {
+		return fmt.Errorf("result_spill.max_bytes: %w", ErrNotPositive)
+	}
+
+	if expiry, err := c.Expiry(); err != nil {
+		return fmt.Errorf("result_spill.url_expiry: %w", err)
+	} else if expiry <= 0 || expiry > maxResultSpillURLExpiry {
+		return fmt.Errorf("result_spill.url_expiry: %w: %s (up to 168h)", ErrInvalidValue, c.URLExpiry)
+	}
+
+	return nil
 }
 
 // RateLimitConfig holds configuration for API rate limiting.
@@ -68,6 +207,14 @@ type RateLimitConfig struct {
 
 // HashConfig holds password hashing configuration.
 type HashConfig struct {
+	// Algorithm hashes new passwords and API keys: argon2id, bcrypt or
+	// scrypt. Existing hashes keep verifying whatever their algorithm, and
+	// passwords are rehashed with this one on their next login.
+	Algorithm string `koanf:"algorithm"`
+
+	// BcryptCost is the bcrypt cost (4-31), used with the bcrypt algorithm.
+	BcryptCost int `koanf:"bcrypt_cost"`
+
 	// Preset is a named configuration preset (default, low, minimal).
 	Preset string `koanf:"preset"`
 
@@ -117,6 +264,148 @@ func (c SlackAuthConfig) Enabled() bool {
 	return c.ClientID != "" && c.ClientSecret != ""
 }
 
+// OIDCConfig configures sign-in to the API and web UI through a generic
+// OpenID Connect identity provider (Okta, Entra ID, Keycloak, Google...).
+type OIDCConfig struct {
+	// Issuer is the issuer URL of the identity provider; its endpoints are
+	// discovered from {issuer}/.well-known/openid-configuration.
+	Issuer       string `koanf:"issuer"`
+	ClientID     string `koanf:"client_id"`
+	ClientSecret string `koanf:"client_secret"`
+	// Scopes is a comma-separated list of extra scopes requested on top of
+	// "openid email profile" (e.g., "groups").
+	Scopes string `koanf:"scopes"`
+	// DisplayName labels the sign-in button of the web UI.
+	DisplayName     string `koanf:"display_name"`
+	AutoCreateUsers bool   `koanf:"auto_create_users"`
+	// DefaultRole is given to users none of whose claim values is mapped.
+	// Empty refuses them.
+	DefaultRole string `koanf:"default_role"`
+	// RolesClaim is the claim holding the user's groups or roles; a dotted
+	// path reaches nested claims (e.g., "realm_access.roles").
+	RolesClaim string `koanf:"roles_claim"`
+	// RoleMapping maps claim values to dbbat roles as a comma-separated list
+	// of value=role pairs (e.g., "dbbat-admins=admin,sre=viewer,sre=connector").
+	// When set, the roles of users signing in through OIDC are replaced by
+	// the mapped ones on every login.
+	RoleMapping string `koanf:"role_mapping"`
+}
+
+// Enabled returns true if OIDC is configured with an issuer, a client ID and
+// a client secret.
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != "" && c.ClientSecret != ""
+}
+
+// ExtraScopes returns the parsed Scopes.
+func (c OIDCConfig) ExtraScopes() []string {
+	var scopes []string
+
+	for _, scope := range strings.Split(c.Scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes
+}
+
+// Roles returns the parsed RoleMapping, claim value to roles.
+func (c OIDCConfig) Roles() (map[string][]string, error) {
+	return parseRoleMapping(c.RoleMapping)
+}
+
+// LDAPConfig configures authentication against an LDAP directory (OpenLDAP,
+// Active Directory...): users signing in to the API or connecting to a proxy
+// are checked with a bind as their directory entry.
+type LDAPConfig struct {
+	// URL of the directory server, ldap://host:389 or ldaps://host:636.
+	URL string `koanf:"url"`
+	// StartTLS upgrades an ldap:// connection to TLS before binding.
+	StartTLS bool `koanf:"start_tls"`
+	// CAFile is a PEM bundle verifying the server certificate instead of
+	// the system roots.
+	CAFile string `koanf:"ca_file"`
+	// BindDN and BindPassword are the service account searching for users;
+	// empty searches anonymously.
+	BindDN       string `koanf:"bind_dn"`
+	BindPassword string `koanf:"bind_password"`
+	// BaseDN is the subtree users are searched in.
+	BaseDN string `koanf:"base_dn"`
+	// UserFilter finds the entry of a user; {username} is replaced by the
+	// escaped login name.
+	UserFilter string `koanf:"user_filter"`
+	// EmailAttribute, NameAttribute and GroupAttribute name the attributes
+	// read from the user's entry.
+	EmailAttribute  string `koanf:"email_attribute"`
+	NameAttribute   string `koanf:"name_attribute"`
+	GroupAttribute  string `koanf:"group_attribute"`
+	AutoCreateUsers bool   `koanf:"auto_create_users"`
+	// DefaultRole is given to users none of whose groups is mapped. Empty
+	// refuses them.
+	DefaultRole string `koanf:"default_role"`
+	// RoleMapping maps groups to dbbat roles as a comma-separated list of
+	// group=role pairs, a group being named by its CN (e.g.,
+	// "dbbat-admins=admin,dba=connector"). When set, the roles of directory
+	// users are replaced by the mapped ones on every login to the API.
+	RoleMapping string `koanf:"role_mapping"`
+}
+
+// Enabled returns true if a directory URL and a base DN are configured.
+func (c LDAPConfig) Enabled() bool {
+	return c.URL != "" && c.BaseDN != ""
+}
+
+// Roles returns the parsed RoleMapping, lowercased group to roles: group
+// names compare case-insensitively, as the directory does.
+func (c LDAPConfig) Roles() (map[string][]string, error) {
+	mapping, err := parseRoleMapping(c.RoleMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	lowered := make(map[string][]string, len(mapping))
+	for group, roles := range mapping {
+		lowered[strings.ToLower(group)] = append(lowered[strings.ToLower(group)], roles...)
+	}
+
+	return lowered, nil
+}
+
+// parseRoleMapping parses a comma-separated list of value=role pairs.
+func parseRoleMapping(roleMapping string) (map[string][]string, error) {
+	mapping := make(map[string][]string)
+
+	for _, pair := range strings.Split(roleMapping, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		value, role, ok := strings.Cut(pair, "=")
+		value, role = strings.TrimSpace(value), strings.TrimSpace(role)
+
+		if !ok || value == "" || !validRoles[role] {
+			return nil, fmt.Errorf("%w: %q (value=role)", ErrInvalidValue, pair)
+		}
+
+		mapping[value] = append(mapping[value], role)
+	}
+
+	return mapping, nil
+}
+
+// validRoles are the roles a configuration may assign; they mirror the
+// store's role constants, which this package cannot import.
+var validRoles = map[string]bool{"admin": true, "viewer": true, "connector": true, "auditor": true}
+
+// Local login modes: who may authenticate to the API with a local username
+// and password (login form and Basic Auth).
+const (
+	LocalLoginEnabled  = "enabled"
+	LocalLoginAdmins   = "admins"
+	LocalLoginDisabled = "disabled"
+)
+
 // SlackNotifyConfig configures outbound Slack notifications for grant
 // request events. Distinct from SlackAuthConfig (login OIDC) so deployments
 // can enable one without the other.
@@ -165,6 +454,459 @@ func (c SlackNotifyConfig) Interactive() bool {
 	return c.BotToken != "" && (c.SigningSecret != "" || c.AppToken != "")
 }
 
+// AccessLogConfig holds configuration for the REST API access log.
+type AccessLogConfig struct {
+	// Output selects where access log entries are written: empty (the
+	// application log), "stdout", "stderr", or a file path (JSON lines,
+	// appended). A dedicated output lets API usage be shipped and audited
+	// separately from the application log.
+	Output string `koanf:"output"`
+
+	// SampleRate is the fraction (0-1) of successful requests that are
+	// logged. Requests answered with a 4xx/5xx status are always logged, so
+	// sampling only thins out routine traffic. Default is 1 (log everything).
+	SampleRate float64 `koanf:"sample_rate"`
+}
+
+// LineageConfig holds OpenLineage export configuration.
+type LineageConfig struct {
+	// URL is the OpenLineage HTTP endpoint events are POSTed to
+	// (e.g., "http://marquez:5000/api/v1/lineage"). Empty disables export.
+	URL string `koanf:"url"`
+
+	// APIKey, when set, is sent as a Bearer token with every event.
+	APIKey string `koanf:"api_key"`
+
+	// Namespace is the OpenLineage namespace of the jobs dbbat reports.
+	Namespace string `koanf:"namespace"`
+}
+
+// Enabled reports whether lineage export is configured.
+func (c LineageConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// SelfObservabilityConfig controls the recording of DBBat's own storage
+// queries.
+type SelfObservabilityConfig struct {
+	// Enabled records the queries DBBat runs against its storage database,
+	// with their literal values masked, for administrators to inspect.
+	Enabled bool `koanf:"enabled"`
+
+	// MaxQueries bounds the recorded queries kept in memory; the oldest are
+	// dropped first.
+	MaxQueries int `koanf:"max_queries"`
+}
+
+// RetentionConfig bounds how long logged activity is kept. A background
+// janitor deletes what is past it; zero values keep data forever.
+type RetentionConfig struct {
+	// QueriesDays deletes queries, with their result rows, executed more
+	// than this many days ago.
+	QueriesDays int `koanf:"queries_days"`
+
+	// RowsDays deletes only the result rows of queries executed more than
+	// this many days ago, keeping the queries.
+	RowsDays int `koanf:"rows_days"`
+
+	// ConnectionsDays deletes connections that ended more than this many
+	// days ago, once none of their queries is left.
+	ConnectionsDays int `koanf:"connections_days"`
+
+	// AuditDays deletes audit events logged more than this many days ago.
+	AuditDays int `koanf:"audit_days"`
+
+	// MaxBytes caps the storage used by result rows; past it, the rows of
+	// the oldest queries are deleted first.
+	MaxBytes int64 `koanf:"max_bytes"`
+
+	// Interval is the time between two janitor runs (e.g., "1h").
+	Interval string `koanf:"interval"`
+
+	// BatchSize bounds how many records each delete statement removes.
+	BatchSize int `koanf:"batch_size"`
+}
+
+// Default retention janitor settings.
+const (
+	DefaultRetentionInterval  = "1h"
+	DefaultRetentionBatchSize = 1000
+)
+
+// QueryAlertsConfig holds the background evaluation of query alerts, the
+// saved query log filters that record their matches and fire webhooks.
+type QueryAlertsConfig struct {
+	// Interval is the time between two evaluation passes (e.g., "30s").
+	Interval string `koanf:"interval"`
+}
+
+// DefaultQueryAlertsInterval is the default time between two query alert
+// evaluation passes.
+const DefaultQueryAlertsInterval = "30s"
+
+// RunInterval returns Interval parsed.
+func (c QueryAlertsConfig) RunInterval() (time.Duration, error) {
+	return time.ParseDuration(c.Interval)
+}
+
+// validate checks the interval is a positive duration.
+func (c QueryAlertsConfig) validate() error {
+	if interval, err := c.RunInterval(); err != nil {
+		return fmt.Errorf("query_alerts.interval: %w", err)
+	} else if interval <= 0 {
+		return fmt.Errorf("query_alerts.interval: %w", ErrNotPositive)
+	}
+
+	return nil
+}
+
+// HealthChecksConfig holds the background connectivity checks of the
+// database targets.
+type HealthChecksConfig struct {
+	// Interval is the time between two checks of a database (e.g., "5m");
+	// "0" disables the background checks.
+	Interval string `koanf:"interval"`
+}
+
+// DefaultHealthChecksInterval is the default time between two checks of a
+// database.
+const DefaultHealthChecksInterval = "5m"
+
+// RunInterval returns Interval parsed, 0 when the checks are disabled.
+func (c HealthChecksConfig) RunInterval() (time.Duration, error) {
+	return parseOptionalDuration(c.Interval)
+}
+
+// validate checks the interval is a non-negative duration.
+func (c HealthChecksConfig) validate() error {
+	if interval, err := c.RunInterval(); err != nil {
+		return fmt.Errorf("health_checks.interval: %w", err)
+	} else if interval < 0 {
+		return fmt.Errorf("health_checks.interval: %w", ErrNegative)
+	}
+
+	return nil
+}
+
+// Enabled reports whether any retention limit is set.
+func (c RetentionConfig) Enabled() bool {
+	return c.QueriesDays > 0 || c.RowsDays > 0 || c.ConnectionsDays > 0 || c.AuditDays > 0 || c.MaxBytes > 0
+}
+
+// RunInterval returns Interval parsed.
+func (c RetentionConfig) RunInterval() (time.Duration, error) {
+	return time.ParseDuration(c.Interval)
+}
+
+// validate checks the limits are not negative and the janitor settings are
+// positive.
+func (c RetentionConfig) validate() error {
+	for _, limit := range []struct {
+		name string
+		days int
+	}{
+		{"queries_days", c.QueriesDays},
+		{"rows_days", c.RowsDays},
+		{"connections_days", c.ConnectionsDays},
+		{"audit_days", c.AuditDays},
+	} {
+		if limit.days < 0 {
+			return fmt.Errorf("retention.%s: %w", limit.name, ErrNegative)
+		}
+	}
+
+	if c.MaxBytes < 0 {
+		return fmt.Errorf("retention.max_bytes: %w", ErrNegative)
+	}
+
+	if interval, err := c.RunInterval(); err != nil {
+		return fmt.Errorf("retention.interval: %w", err)
+	} else if interval <= 0 {
+		return fmt.Errorf("retention.interval: %w", ErrNotPositive)
+	}
+
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("retention.batch_size: %w", ErrNotPositive)
+	}
+
+	return nil
+}
+
+// AuditConfig holds the sinks audit events are copied to, besides the
+// audit_log table. Copies kept off the storage database survive anyone able
+// to rewrite it.
+type AuditConfig struct {
+	// File appends events to a local file.
+	File AuditFileConfig `koanf:"file"`
+
+	// Syslog sends events to a syslog server.
+	Syslog AuditSyslogConfig `koanf:"syslog"`
+
+	// S3 uploads batches of events to an S3-compatible bucket.
+	S3 AuditS3Config `koanf:"s3"`
+}
+
+// Enabled reports whether any audit sink is configured.
+func (c AuditConfig) Enabled() bool {
+	return c.File.Enabled() || c.Syslog.Enabled() || c.S3.Enabled()
+}
+
+// AuditFileConfig configures the audit file sink.
+type AuditFileConfig struct {
+	// Path is the file events are appended to, as JSON lines. Empty
+	// disables the sink.
+	Path string `koanf:"path"`
+
+	// MaxSizeMB rotates the file once it grows past this many megabytes: it
+	// is renamed with a timestamp suffix and a new one is started.
+	MaxSizeMB int `koanf:"max_size_mb"`
+
+	// MaxBackups is the number of rotated files kept, the oldest being
+	// deleted first (0 = keep them all).
+	MaxBackups int `koanf:"max_backups"`
+}
+
+// Enabled reports whether the file sink is configured.
+func (c AuditFileConfig) Enabled() bool {
+	return c.Path != ""
+}
+
+// AuditSyslogConfig configures the audit syslog sink.
+type AuditSyslogConfig struct {
+	// Address is the host:port of the syslog server. Empty disables the sink.
+	Address string `koanf:"address"`
+
+	// Network is "udp", "tcp" or "tls".
+	Network string `koanf:"network"`
+
+	// Tag is the APP-NAME of the messages.
+	Tag string `koanf:"tag"`
+}
+
+// Enabled reports whether the syslog sink is configured.
+func (c AuditSyslogConfig) Enabled() bool {
+	return c.Address != ""
+}
+
+// AuditS3Config configures the audit object storage sink. Any S3-compatible
+// service works: AWS S3, MinIO, or Google Cloud Storage through its
+// interoperability endpoint (https://storage.googleapis.com) with HMAC keys.
+type AuditS3Config struct {
+	// Bucket is the bucket objects are uploaded to. Empty disables the sink.
+	Bucket string `koanf:"bucket"`
+
+	// Endpoint is the base URL of the service; objects are then addressed
+	// path-style. Empty uses AWS S3 in Region.
+	Endpoint string `koanf:"endpoint"`
+
+	// Region is the region requests are signed for.
+	Region string `koanf:"region"`
+
+	// Prefix is prepended to the object keys.
+	Prefix string `koanf:"prefix"`
+
+	// AccessKeyID and SecretAccessKey sign the uploads.
+	AccessKeyID     string `koanf:"access_key_id"`
+	SecretAccessKey string `koanf:"secret_access_key"`
+
+	// FlushInterval is how often the events received since the last upload
+	// are uploaded as one object (e.g., "1m").
+	FlushInterval string `koanf:"flush_interval"`
+}
+
+// Enabled reports whether the object storage sink is configured.
+func (c AuditS3Config) Enabled() bool {
+	return c.Bucket != ""
+}
+
+// Flush returns FlushInterval parsed.
+func (c AuditS3Config) Flush() (time.Duration, error) {
+	return time.ParseDuration(c.FlushInterval)
+}
+
+// Default audit sink settings.
+const (
+	DefaultAuditFileMaxSizeMB   = 100
+	DefaultAuditFileMaxBackups  = 10
+	DefaultAuditSyslogNetwork   = "udp"
+	DefaultAuditSyslogTag       = "dbbat"
+	DefaultAuditS3Region        = "us-east-1"
+	DefaultAuditS3FlushInterval = "1m"
+)
+
+// validate checks the settings of the configured sinks.
+func (c AuditConfig) validate() error {
+	if c.File.Enabled() {
+		if c.File.MaxSizeMB <= 0 {
+			return fmt.Errorf("audit.file.max_size_mb: %w", ErrNotPositive)
+		}
+
+		if c.File.MaxBackups < 0 {
+			return fmt.Errorf("audit.file.max_backups: %w", ErrNegative)
+		}
+	}
+
+	if c.Syslog.Enabled() {
+		switch c.Syslog.Network {
+		case "udp", "tcp", "tls":
+		default:
+			return fmt.Errorf("audit.syslog.network: %w: %q (udp, tcp or tls)", ErrInvalidValue, c.Syslog.Network)
+		}
+
+		if _, _, err := net.SplitHostPort(c.Syslog.Address); err != nil {
+			return fmt.Errorf("audit.syslog.address: %w", err)
+		}
+	}
+
+	if c.S3.Enabled() {
+		if c.S3.Endpoint != "" {
+			if u, err := url.Parse(c.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("audit.s3.endpoint: %w: %q", ErrInvalidURL, c.S3.Endpoint)
+			}
+		}
+
+		if c.S3.AccessKeyID == "" || c.S3.SecretAccessKey == "" {
+			return fmt.Errorf("audit.s3: %w: access_key_id and secret_access_key are required", ErrInvalidValue)
+		}
+
+		if interval, err := c.S3.Flush(); err != nil {
+			return fmt.Errorf("audit.s3.flush_interval: %w", err)
+		} else if interval <= 0 {
+			return fmt.Errorf("audit.s3.flush_interval: %w", ErrNotPositive)
+		}
+	}
+
+	return nil
+}
+
+// DefaultSelfObservabilityMaxQueries is the default number of recorded
+// storage queries.
+const DefaultSelfObservabilityMaxQueries = 1000
+
+// DefaultLineageNamespace is the default OpenLineage job namespace.
+const DefaultLineageNamespace = "dbbat"
+
+// StoragePoolConfig sizes the connection pool to the DBBat storage database.
+// Every proxy session and API request draws from this pool, so deployments
+// with many concurrent sessions need more than the defaults.
+type StoragePoolConfig struct {
+	// MaxOpenConns caps the number of open storage connections.
+	MaxOpenConns int `koanf:"max_open_conns"`
+
+	// MaxIdleConns caps the number of connections kept open while idle.
+	MaxIdleConns int `koanf:"max_idle_conns"`
+
+	// ConnMaxLifetime recycles connections older than this duration
+	// (e.g., "5m"). Empty or "0" keeps connections indefinitely.
+	ConnMaxLifetime string `koanf:"conn_max_lifetime"`
+
+	// ConnMaxIdleTime closes connections idle for longer than this duration
+	// (e.g., "1m"). Empty or "0" disables idle expiry.
+	ConnMaxIdleTime string `koanf:"conn_max_idle_time"`
+}
+
+// DefaultReplicaMaxLag is the default storage replica lag tolerance.
+const DefaultReplicaMaxLag = "30s"
+
+// DefaultMigrationLockTimeout is the default wait for another instance's
+// migrations.
+const DefaultMigrationLockTimeout = "5m"
+
+// DefaultDrainTimeout is the default wait for the proxy sessions to end when
+// draining.
+const DefaultDrainTimeout = "1m"
+
+// DefaultAPISignatureMaxSkew is the default clock skew tolerated on signed
+// API requests.
+const DefaultAPISignatureMaxSkew = "5m"
+
+// Default storage pool settings.
+const (
+	DefaultStoragePoolMaxOpenConns    = 25
+	DefaultStoragePoolMaxIdleConns    = 25
+	DefaultStoragePoolConnMaxLifetime = "5m"
+)
+
+// Lifetime returns ConnMaxLifetime parsed, 0 when unset.
+func (c StoragePoolConfig) Lifetime() (time.Duration, error) {
+	return parseOptionalDuration(c.ConnMaxLifetime)
+}
+
+// IdleTime returns ConnMaxIdleTime parsed, 0 when unset.
+func (c StoragePoolConfig) IdleTime() (time.Duration, error) {
+	return parseOptionalDuration(c.ConnMaxIdleTime)
+}
+
+// ReplicaLag returns ReplicaMaxLag parsed, 0 when unset.
+func (c *Config) ReplicaLag() (time.Duration, error) {
+	return parseOptionalDuration(c.ReplicaMaxLag)
+}
+
+// MigrationLockWait returns MigrationLockTimeout parsed, 0 when unset.
+func (c *Config) MigrationLockWait() (time.Duration, error) {
+	return parseOptionalDuration(c.MigrationLockTimeout)
+}
+
+// DrainWait returns DrainTimeout parsed, 0 when unset.
+func (c *Config) DrainWait() (time.Duration, error) {
+	return parseOptionalDuration(c.DrainTimeout)
+}
+
+// APISignatureSkew returns APISignatureMaxSkew parsed, 0 when unset.
+func (c *Config) APISignatureSkew() (time.Duration, error) {
+	return parseOptionalDuration(c.APISignatureMaxSkew)
+}
+
+// parseOptionalDuration parses a duration, treating an empty string as 0.
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// SecretsConfig holds the secrets managers the upstream passwords of the
+// databases with a secret_ref are fetched from.
+type SecretsConfig struct {
+	// CacheTTL is how long a fetched secret is reused (e.g., "5m"); "0"
+	// fetches it on every upstream connection.
+	CacheTTL string `koanf:"cache_ttl"`
+
+	// VaultAddr and VaultToken reach HashiCorp Vault for vault:// references.
+	// Empty falls back to the VAULT_ADDR and VAULT_TOKEN environment variables.
+	VaultAddr  string `koanf:"vault_addr"`
+	VaultToken string `koanf:"vault_token"`
+
+	// AWSRegion is the region of the aws-sm:// references given by name
+	// rather than ARN. The credentials sign the requests to AWS Secrets
+	// Manager. Empty falls back to the AWS_REGION, AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+	AWSRegion          string `koanf:"aws_region"`
+	AWSAccessKeyID     string `koanf:"aws_access_key_id"`
+	AWSSecretAccessKey string `koanf:"aws_secret_access_key"`
+	AWSSessionToken    string `koanf:"aws_session_token"`
+}
+
+// DefaultSecretsCacheTTL is the default time a fetched secret is reused.
+const DefaultSecretsCacheTTL = "5m"
+
+// CacheDuration returns CacheTTL parsed, 0 when secrets are not cached.
+func (c SecretsConfig) CacheDuration() (time.Duration, error) {
+	return parseOptionalDuration(c.CacheTTL)
+}
+
+// validate checks the cache TTL is a non-negative duration.
+func (c SecretsConfig) validate() error {
+	if ttl, err := c.CacheDuration(); err != nil {
+		return fmt.Errorf("secrets.cache_ttl: %w", err)
+	} else if ttl < 0 {
+		return fmt.Errorf("secrets.cache_ttl: %w", ErrNegative)
+	}
+
+	return nil
+}
+
 // DumpConfig holds configuration for session packet dumps.
 type DumpConfig struct {
 	// Dir is the directory for dump files. Empty = disabled.
@@ -208,6 +950,123 @@ type PGConfig struct {
 	// Without this, clients with sslmode=prefer silently fall back to
 	// plaintext and credentials travel over the wire in the clear.
 	TLS TLSConfig `koanf:"tls"`
+
+	// BlockedMessage replaces the error text returned when a grant control
+	// (read-only, DDL, COPY) blocks a statement, e.g. to point users at where
+	// to request broader access. Servers can override it individually.
+	BlockedMessage string `koanf:"blocked_message"`
+
+	// PoolMode is how sessions use upstream connections: PoolModeSession
+	// gives each session a connection of its own, PoolModeTransaction lends
+	// sessions a pooled connection for the length of a transaction only.
+	PoolMode string `koanf:"pool_mode"`
+
+	// PoolSize caps the upstream connections opened by transaction pooling,
+	// per database and upstream role. Sessions wait for a connection beyond.
+	PoolSize int `koanf:"pool_size"`
+
+	// Listeners are additional listen addresses, on top of listen_pg, each
+	// with its own policy. Configuration file only.
+	Listeners []PGListenerConfig `koanf:"listeners"`
+}
+
+// PGListenerConfig is an additional PostgreSQL proxy listener, e.g. a port
+// only reachable from a VPN that serves a subset of the databases.
+type PGListenerConfig struct {
+	// Name identifies the listener in the logs; defaults to Addr.
+	Name string `koanf:"name"`
+
+	// Addr is the listen address, e.g. ":5444".
+	Addr string `koanf:"addr"`
+
+	// Databases restricts the listener to these DBBat database names. Empty
+	// serves every database.
+	Databases []string `koanf:"databases"`
+
+	// AllowedCIDRs is a comma-separated list of the networks clients may
+	// connect from. Empty accepts any client.
+	AllowedCIDRs string `koanf:"allowed_cidrs"`
+
+	// TLS replaces pg.tls on this listener when set.
+	TLS *TLSConfig `koanf:"tls"`
+}
+
+// DisplayName returns the name of the listener, or its address if unnamed.
+func (c PGListenerConfig) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+
+	return c.Addr
+}
+
+// AllowedNetworks returns the parsed AllowedCIDRs; nil accepts any client.
+func (c PGListenerConfig) AllowedNetworks() ([]*net.IPNet, error) {
+	return ParseCIDRList(c.AllowedCIDRs)
+}
+
+// TLSOrDefault returns the TLS settings of the listener, falling back to the
+// proxy-wide ones.
+func (c PGListenerConfig) TLSOrDefault(defaultTLS TLSConfig) TLSConfig {
+	if c.TLS != nil {
+		return *c.TLS
+	}
+
+	return defaultTLS
+}
+
+// PGConfig.PoolMode values.
+const (
+	PoolModeSession     = "session"
+	PoolModeTransaction = "transaction"
+)
+
+// DefaultPGPoolSize is the default PGConfig.PoolSize.
+const DefaultPGPoolSize = 20
+
+// TransactionPooling reports whether sessions share pooled upstream
+// connections, one transaction at a time.
+func (c PGConfig) TransactionPooling() bool {
+	return c.PoolMode == PoolModeTransaction
+}
+
+// validate checks the pooling settings.
+func (c PGConfig) validate() error {
+	switch c.PoolMode {
+	case PoolModeSession, PoolModeTransaction:
+	default:
+		return fmt.Errorf("pg.pool_mode: %w: %q (session or transaction)", ErrInvalidValue, c.PoolMode)
+	}
+
+	if c.PoolSize <= 0 {
+		return fmt.Errorf("pg.pool_size: %w", ErrNotPositive)
+	}
+
+	addrs := make(map[string]bool, len(c.Listeners))
+	names := make(map[string]bool, len(c.Listeners))
+
+	for i, listener := range c.Listeners {
+		if listener.Addr == "" {
+			return fmt.Errorf("pg.listeners[%d].addr: %w: empty", i, ErrInvalidValue)
+		}
+
+		if addrs[listener.Addr] {
+			return fmt.Errorf("pg.listeners[%d].addr: %w: %q is used twice", i, ErrInvalidValue, listener.Addr)
+		}
+
+		if names[listener.DisplayName()] {
+			return fmt.Errorf("pg.listeners[%d].name: %w: %q is used twice", i, ErrInvalidValue, listener.DisplayName())
+		}
+
+		addrs[listener.Addr] = true
+		names[listener.DisplayName()] = true
+
+		if _, err := listener.AllowedNetworks(); err != nil {
+			return fmt.Errorf("pg.listeners[%d].allowed_cidrs: %w", i, err)
+		}
+	}
+
+	return nil
 }
 
 // TLSConfig holds TLS server-side termination settings.
@@ -228,6 +1087,192 @@ type TLSConfig struct {
 	Disable bool `koanf:"disable"`
 }
 
+// ProxyProtocolConfig configures HAProxy PROXY protocol (v1/v2) support on
+// the database proxy listeners. When dbbat runs behind a TCP load balancer,
+// the balancer prepends a PROXY header carrying the real client address, so
+// connection records store the client's IP rather than the balancer's.
+type ProxyProtocolConfig struct {
+	// Enabled turns on PROXY header parsing on every proxy listener.
+	Enabled bool `koanf:"enabled"`
+
+	// TrustedCIDRs is a comma-separated list of networks allowed to send a
+	// PROXY header (e.g. "10.0.0.0/8,192.168.1.10/32"). Connections from a
+	// trusted peer must start with a header; connections from anywhere else
+	// are handled as direct clients and their bytes are never parsed as a
+	// header, so an untrusted client cannot spoof its address. Required when
+	// Enabled is set: Load refuses an empty list rather than trusting every
+	// peer.
+	TrustedCIDRs string `koanf:"trusted_cidrs"`
+}
+
+// TrustedNetworks returns the parsed TrustedCIDRs. Invalid entries are
+// rejected by Load, so they are skipped here.
+func (c ProxyProtocolConfig) TrustedNetworks() []*net.IPNet {
+	networks, _ := ParseCIDRList(c.TrustedCIDRs)
+
+	return networks
+}
+
+// SessionConfig bounds the proxy sessions of every protocol, so a forgotten
+// client does not hold a grant open for days.
+type SessionConfig struct {
+	// IdleTimeout ends sessions that exchanged nothing with the client, with
+	// no statement in flight, for this long (e.g., "30m"). Empty or "0"
+	// disables it.
+	IdleTimeout string `koanf:"idle_timeout"`
+
+	// MaxDuration ends sessions this long after they started, however busy
+	// (e.g., "12h"). Empty or "0" disables it.
+	MaxDuration string `koanf:"max_duration"`
+
+	// ApprovalTimeout is how long a write statement held by the
+	// write_requires_approval control waits for an admin's decision before it
+	// is refused (e.g., "5m"). Empty or "0" uses DefaultApprovalTimeout.
+	ApprovalTimeout string `koanf:"approval_timeout"`
+
+	// ExpiryWarning is how long before their grant expires PostgreSQL
+	// sessions are sent a NOTICE (e.g., "5m"). "0" disables it.
+	ExpiryWarning string `koanf:"expiry_warning"`
+
+	// QuotaWarnings are the percentages of a grant's query, data transfer
+	// and row quotas past which PostgreSQL sessions are sent a NOTICE,
+	// comma-separated (e.g., "80,95"). "0" disables them.
+	QuotaWarnings string `koanf:"quota_warnings"`
+}
+
+// DefaultApprovalTimeout is the default SessionConfig.ApprovalTimeout.
+const DefaultApprovalTimeout = 5 * time.Minute
+
+// DefaultExpiryWarning is the default SessionConfig.ExpiryWarning.
+const DefaultExpiryWarning = "5m"
+
+// DefaultQuotaWarnings is the default SessionConfig.QuotaWarnings.
+const DefaultQuotaWarnings = "80"
+
+// ApprovalWait returns ApprovalTimeout parsed, DefaultApprovalTimeout when
+// unset. Invalid values are rejected by Load.
+func (c SessionConfig) ApprovalWait() time.Duration {
+	if d, _ := parseOptionalDuration(c.ApprovalTimeout); d > 0 {
+		return d
+	}
+
+	return DefaultApprovalTimeout
+}
+
+// ExpiryWarningLead returns ExpiryWarning parsed, 0 when disabled. Invalid
+// values are rejected by Load.
+func (c SessionConfig) ExpiryWarningLead() time.Duration {
+	d, _ := parseOptionalDuration(c.ExpiryWarning)
+
+	return d
+}
+
+// QuotaWarningThresholds returns QuotaWarnings parsed, in increasing order
+// and without the 0 disabling them. Invalid values are rejected by Load.
+func (c SessionConfig) QuotaWarningThresholds() []int {
+	thresholds, _ := parseQuotaWarnings(c.QuotaWarnings)
+
+	return thresholds
+}
+
+// parseQuotaWarnings parses a comma-separated list of percentages, each
+// between 0 and 99. Blank entries and 0 are ignored.
+func parseQuotaWarnings(value string) ([]int, error) {
+	var thresholds []int
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		percent, err := strconv.Atoi(part)
+		if err != nil || percent < 0 || percent > 99 {
+			return nil, fmt.Errorf("%w: %q is not a percentage between 0 and 99", ErrInvalidValue, part)
+		}
+
+		if percent > 0 {
+			thresholds = append(thresholds, percent)
+		}
+	}
+
+	slices.Sort(thresholds)
+
+	return slices.Compact(thresholds), nil
+}
+
+// Timeouts returns IdleTimeout and MaxDuration parsed, 0 when unset. Invalid
+// values are rejected by Load, so they read as 0 here.
+func (c SessionConfig) Timeouts() (idle, maxDuration time.Duration) {
+	idle, _ = parseOptionalDuration(c.IdleTimeout)
+	maxDuration, _ = parseOptionalDuration(c.MaxDuration)
+
+	return idle, maxDuration
+}
+
+// validate checks the timeouts parse and are not negative.
+func (c SessionConfig) validate() error {
+	for _, timeout := range []struct {
+		name, value string
+	}{
+		{"idle_timeout", c.IdleTimeout},
+		{"max_duration", c.MaxDuration},
+		{"approval_timeout", c.ApprovalTimeout},
+		{"expiry_warning", c.ExpiryWarning},
+	} {
+		if d, err := parseOptionalDuration(timeout.value); err != nil {
+			return fmt.Errorf("session.%s: %w", timeout.name, err)
+		} else if d < 0 {
+			return fmt.Errorf("session.%s: %w", timeout.name, ErrNegative)
+		}
+	}
+
+	if _, err := parseQuotaWarnings(c.QuotaWarnings); err != nil {
+		return fmt.Errorf("session.quota_warnings: %w", err)
+	}
+
+	return nil
+}
+
+// ParseCIDRList parses a comma-separated list of CIDRs. A bare IP address is
+// accepted as a single-host network (/32 or /128). Blank entries are ignored.
+func ParseCIDRList(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidCIDR, part)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCIDR, part)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
 // Config holds the application configuration.
 type Config struct {
 	// Proxy listen address.
@@ -245,12 +1290,47 @@ type Config struct {
 	// REST API listen address.
 	ListenAPI string `koanf:"listen_api"`
 
+	// TrustedProxies is a comma-separated list of reverse-proxy networks
+	// (CIDRs or bare IPs) whose X-Forwarded-For / X-Real-IP headers the API
+	// honors when resolving client IPs for rate limiting, audit and logs.
+	// Empty trusts no proxy: the client IP is always the TCP peer address.
+	TrustedProxies string `koanf:"trusted_proxies"`
+
 	// PostgreSQL DSN for DBBat storage.
 	DSN string `koanf:"dsn"`
 
+	// ReplicaDSN optionally points at a read replica of the storage
+	// database. Heavy API listings (queries, connections, audit log) are
+	// read from it while it is reachable and within ReplicaMaxLag of the
+	// primary; everything else uses DSN.
+	ReplicaDSN string `koanf:"replica_dsn"`
+
+	// ReplicaMaxLag is the replication lag (e.g., "30s") beyond which reads
+	// fall back to the primary.
+	ReplicaMaxLag string `koanf:"replica_max_lag"`
+
+	// MigrationLockTimeout is how long an instance waits at startup while
+	// another one holds the migration lock (e.g., "5m").
+	MigrationLockTimeout string `koanf:"migration_lock_timeout"`
+
+	// DrainTimeout is how long a draining instance waits for its proxy
+	// sessions to end before closing them (e.g., "1m").
+	DrainTimeout string `koanf:"drain_timeout"`
+
+	// APISignatureMaxSkew is how far the timestamp of a signed API request
+	// may be from the server clock (e.g., "5m"). It also bounds how long
+	// nonces are remembered for replay detection.
+	APISignatureMaxSkew string `koanf:"api_signature_max_skew"`
+
 	// Base64-encoded encryption key (alternative to KeyFile).
 	Key string `koanf:"key"`
 
+	// KeyCommand is an external shell command printing the encryption key on
+	// its standard output (32 raw bytes or base64), e.g. a secret manager
+	// client or a device tool such as tpm2_unseal, so the key is never stored
+	// on disk. DBBat only runs the command. It takes precedence over KeyFile.
+	KeyCommand string `koanf:"key_command"`
+
 	// Path to file containing encryption key (alternative to Key).
 	KeyFile string `koanf:"keyfile"`
 
@@ -258,12 +1338,17 @@ type Config struct {
 	ConfigFile string `koanf:"-"`
 
 	// Encryption key for database credentials (32 bytes).
-	// Populated from Key or KeyFile after loading.
+	// Populated from Key, KeyCommand or KeyFile after loading.
 	EncryptionKey []byte `koanf:"-"`
 
 	// RunMode controls whether test data is provisioned on startup.
 	RunMode RunMode `koanf:"run_mode"`
 
+	// StrictPosture refuses to start when the startup posture check finds
+	// a weak setting (default admin password, plaintext proxy listener...)
+	// instead of only logging it.
+	StrictPosture bool `koanf:"strict_posture"`
+
 	// DemoTargetDB specifies the only allowed database target in demo mode.
 	// Format: "user:password@host/dbname" (e.g., "demo:demo@localhost/demo")
 	// Only applies when RunMode is "demo". If empty, defaults to "demo:demo@localhost/demo".
@@ -272,6 +1357,10 @@ type Config struct {
 	// QueryStorage holds query result storage configuration.
 	QueryStorage QueryStorageConfig `koanf:"query_storage"`
 
+	// ResultSpill holds where the results past the query storage limits
+	// are spilled.
+	ResultSpill ResultSpillConfig `koanf:"result_spill"`
+
 	// RateLimit holds rate limiting configuration.
 	RateLimit RateLimitConfig `koanf:"rate_limit"`
 
@@ -295,6 +1384,17 @@ type Config struct {
 	// SlackAuth holds Slack OAuth configuration.
 	SlackAuth SlackAuthConfig `koanf:"slack_auth"`
 
+	// OIDC holds OpenID Connect sign-in configuration.
+	OIDC OIDCConfig `koanf:"oidc"`
+
+	// LDAP holds LDAP directory authentication configuration.
+	LDAP LDAPConfig `koanf:"ldap"`
+
+	// LocalLogin controls password sign-in to the API once SSO is set up:
+	// "enabled" for everyone, "admins" as a break-glass path for admins
+	// only, or "disabled". Database connections are not affected.
+	LocalLogin string `koanf:"local_login"`
+
 	// SlackNotify holds outbound Slack notification configuration for
 	// grant request events.
 	SlackNotify SlackNotifyConfig `koanf:"slack_notify"`
@@ -315,12 +1415,50 @@ type Config struct {
 
 	// PG holds PostgreSQL proxy specific configuration.
 	PG PGConfig `koanf:"pg"`
+
+	// AccessLog holds REST API access log configuration.
+	AccessLog AccessLogConfig `koanf:"access_log"`
+
+	// StoragePool sizes the connection pool to the storage database.
+	StoragePool StoragePoolConfig `koanf:"storage_pool"`
+
+	// Lineage holds OpenLineage export configuration.
+	Lineage LineageConfig `koanf:"lineage"`
+
+	// SelfObservability holds the recording of DBBat's own storage queries.
+	SelfObservability SelfObservabilityConfig `koanf:"self_observability"`
+
+	// ProxyProtocol holds PROXY protocol settings shared by all proxy
+	// listeners.
+	ProxyProtocol ProxyProtocolConfig `koanf:"proxy_protocol"`
+
+	// Session holds the idle and maximum duration timeouts shared by all
+	// proxy listeners.
+	Session SessionConfig `koanf:"session"`
+
+	// Retention holds the purge policy of queries, connections and audit
+	// events.
+	Retention RetentionConfig `koanf:"retention"`
+
+	// QueryAlerts holds the evaluation of query alerts.
+	QueryAlerts QueryAlertsConfig `koanf:"query_alerts"`
+
+	// HealthChecks holds the background checks of the database targets.
+	HealthChecks HealthChecksConfig `koanf:"health_checks"`
+
+	// Secrets holds the secrets managers upstream passwords are fetched from.
+	Secrets SecretsConfig `koanf:"secrets"`
+
+	// Audit holds the sinks audit events are copied to.
+	Audit AuditConfig `koanf:"audit"`
 }
 
 // Default query storage limits.
 const (
 	DefaultMaxResultRows  = 100000
 	DefaultMaxResultBytes = 100 * 1024 * 1024 // 100MB
+	DefaultCompactAfter   = "720h"            // 30 days
+	DefaultMaxSQLBytes    = 1024 * 1024       // 1MB
 )
 
 // Default rate limiting settings.
@@ -331,11 +1469,16 @@ const (
 	DefaultRateLimitBurst   = 10
 )
 
+// DefaultAccessLogSampleRate logs every API request.
+const DefaultAccessLogSampleRate = 1.0
+
 // Default hash settings (matching current argon2id defaults).
 const (
-	DefaultHashMemoryMB = 64
-	DefaultHashTime     = 1
-	DefaultHashThreads  = 4
+	DefaultHashAlgorithm  = "argon2id"
+	DefaultHashBcryptCost = 12
+	DefaultHashMemoryMB   = 64
+	DefaultHashTime       = 1
+	DefaultHashThreads    = 4
 )
 
 // Default auth cache settings.
@@ -355,6 +1498,10 @@ const (
 	defaultKeyFilePerm = 0o600
 )
 
+// keyCommandTimeout bounds the key command, which may wait on a remote
+// service or a device.
+const keyCommandTimeout = 30 * time.Second
+
 // DefaultBaseURL is the default base URL path for the frontend.
 const DefaultBaseURL = "/app"
 
@@ -371,10 +1518,22 @@ func defaultConfig() Config {
 		ListenMongo:  ":27018",
 		BaseURL:      DefaultBaseURL,
 		LogLevel:     DefaultLogLevel,
+		PG: PGConfig{
+			PoolMode: PoolModeSession,
+			PoolSize: DefaultPGPoolSize,
+		},
 		QueryStorage: QueryStorageConfig{
 			MaxResultRows:  DefaultMaxResultRows,
 			MaxResultBytes: DefaultMaxResultBytes,
 			StoreResults:   true,
+			CompactAfter:   DefaultCompactAfter,
+			MaxSQLBytes:    DefaultMaxSQLBytes,
+		},
+		ResultSpill: ResultSpillConfig{
+			Region:    DefaultResultSpillRegion,
+			Prefix:    DefaultResultSpillPrefix,
+			MaxBytes:  DefaultResultSpillMaxBytes,
+			URLExpiry: DefaultResultSpillURLExpiry,
 		},
 		RateLimit: RateLimitConfig{
 			Enabled:               DefaultRateLimitEnabled,
@@ -383,9 +1542,11 @@ func defaultConfig() Config {
 			Burst:                 DefaultRateLimitBurst,
 		},
 		Hash: HashConfig{
-			MemoryMB: DefaultHashMemoryMB,
-			Time:     DefaultHashTime,
-			Threads:  DefaultHashThreads,
+			Algorithm:  DefaultHashAlgorithm,
+			BcryptCost: DefaultHashBcryptCost,
+			MemoryMB:   DefaultHashMemoryMB,
+			Time:       DefaultHashTime,
+			Threads:    DefaultHashThreads,
 		},
 		AuthCache: AuthCacheConfig{
 			Enabled:    DefaultAuthCacheEnabled,
@@ -396,6 +1557,21 @@ func defaultConfig() Config {
 			AutoCreateUsers: true,
 			DefaultRole:     "connector",
 		},
+		OIDC: OIDCConfig{
+			DisplayName:     "SSO",
+			AutoCreateUsers: true,
+			DefaultRole:     "connector",
+			RolesClaim:      "groups",
+		},
+		LDAP: LDAPConfig{
+			UserFilter:      "(uid={username})",
+			EmailAttribute:  "mail",
+			NameAttribute:   "displayName",
+			GroupAttribute:  "memberOf",
+			AutoCreateUsers: true,
+			DefaultRole:     "connector",
+		},
+		LocalLogin: LocalLoginEnabled,
 		SlackNotify: SlackNotifyConfig{
 			Channel: "#dbbat",
 		},
@@ -403,6 +1579,56 @@ func defaultConfig() Config {
 			MaxSize:   DefaultDumpMaxSize,
 			Retention: DefaultDumpRetention,
 		},
+		AccessLog: AccessLogConfig{
+			SampleRate: DefaultAccessLogSampleRate,
+		},
+		ReplicaMaxLag:        DefaultReplicaMaxLag,
+		MigrationLockTimeout: DefaultMigrationLockTimeout,
+		DrainTimeout:         DefaultDrainTimeout,
+		APISignatureMaxSkew:  DefaultAPISignatureMaxSkew,
+		Lineage: LineageConfig{
+			Namespace: DefaultLineageNamespace,
+		},
+		StoragePool: StoragePoolConfig{
+			MaxOpenConns:    DefaultStoragePoolMaxOpenConns,
+			MaxIdleConns:    DefaultStoragePoolMaxIdleConns,
+			ConnMaxLifetime: DefaultStoragePoolConnMaxLifetime,
+		},
+		SelfObservability: SelfObservabilityConfig{
+			MaxQueries: DefaultSelfObservabilityMaxQueries,
+		},
+		Retention: RetentionConfig{
+			Interval:  DefaultRetentionInterval,
+			BatchSize: DefaultRetentionBatchSize,
+		},
+		QueryAlerts: QueryAlertsConfig{
+			Interval: DefaultQueryAlertsInterval,
+		},
+		HealthChecks: HealthChecksConfig{
+			Interval: DefaultHealthChecksInterval,
+		},
+		Secrets: SecretsConfig{
+			CacheTTL: DefaultSecretsCacheTTL,
+		},
+		Session: SessionConfig{
+			ExpiryWarning: DefaultExpiryWarning,
+			QuotaWarnings: DefaultQuotaWarnings,
+		},
+		Audit: AuditConfig{
+			File: AuditFileConfig{
+				MaxSizeMB:  DefaultAuditFileMaxSizeMB,
+				MaxBackups: DefaultAuditFileMaxBackups,
+			},
+			Syslog: AuditSyslogConfig{
+				Network: DefaultAuditSyslogNetwork,
+				Tag:     DefaultAuditSyslogTag,
+			},
+			S3: AuditS3Config{
+				Region:        DefaultAuditS3Region,
+				Prefix:        "audit/",
+				FlushInterval: DefaultAuditS3FlushInterval,
+			},
+		},
 	}
 }
 
@@ -428,6 +1654,10 @@ func envTransform(k, v string) (string, any) {
 	if strings.HasPrefix(key, "query_storage_") {
 		return "query_storage." + strings.TrimPrefix(key, "query_storage_"), v
 	}
+	// result_spill_* -> result_spill.*
+	if strings.HasPrefix(key, "result_spill_") {
+		return "result_spill." + strings.TrimPrefix(key, "result_spill_"), v
+	}
 	// rate_limit_* -> rate_limit.*
 	if strings.HasPrefix(key, "rate_limit_") {
 		return "rate_limit." + strings.TrimPrefix(key, "rate_limit_"), v
@@ -444,6 +1674,14 @@ func envTransform(k, v string) (string, any) {
 	if strings.HasPrefix(key, "slack_auth_") {
 		return "slack_auth." + strings.TrimPrefix(key, "slack_auth_"), v
 	}
+	// oidc_* -> oidc.*
+	if strings.HasPrefix(key, "oidc_") {
+		return "oidc." + strings.TrimPrefix(key, "oidc_"), v
+	}
+	// ldap_* -> ldap.*
+	if strings.HasPrefix(key, "ldap_") {
+		return "ldap." + strings.TrimPrefix(key, "ldap_"), v
+	}
 	// slack_signing_secret -> slack_notify.signing_secret
 	// DBB_SLACK_SIGNING_SECRET is the canonical, documented name; the
 	// slack_notify_* prefix rule below keeps the legacy
@@ -471,6 +1709,61 @@ func envTransform(k, v string) (string, any) {
 	if strings.HasPrefix(key, "pg_tls_") {
 		return "pg.tls." + strings.TrimPrefix(key, "pg_tls_"), v
 	}
+	// pg_blocked_message -> pg.blocked_message
+	if key == "pg_blocked_message" {
+		return "pg.blocked_message", v
+	}
+	// pg_pool_* -> pg.pool_*
+	if strings.HasPrefix(key, "pg_pool_") {
+		return "pg." + strings.TrimPrefix(key, "pg_"), v
+	}
+	// storage_pool_* -> storage_pool.*
+	if strings.HasPrefix(key, "storage_pool_") {
+		return "storage_pool." + strings.TrimPrefix(key, "storage_pool_"), v
+	}
+	// access_log_* -> access_log.*
+	if strings.HasPrefix(key, "access_log_") {
+		return "access_log." + strings.TrimPrefix(key, "access_log_"), v
+	}
+	// lineage_* -> lineage.*
+	if strings.HasPrefix(key, "lineage_") {
+		return "lineage." + strings.TrimPrefix(key, "lineage_"), v
+	}
+	// self_observability_* -> self_observability.*
+	if strings.HasPrefix(key, "self_observability_") {
+		return "self_observability." + strings.TrimPrefix(key, "self_observability_"), v
+	}
+	// proxy_protocol_* -> proxy_protocol.*
+	if strings.HasPrefix(key, "proxy_protocol_") {
+		return "proxy_protocol." + strings.TrimPrefix(key, "proxy_protocol_"), v
+	}
+	// session_* -> session.*
+	if strings.HasPrefix(key, "session_") {
+		return "session." + strings.TrimPrefix(key, "session_"), v
+	}
+	// retention_* -> retention.*
+	if strings.HasPrefix(key, "retention_") {
+		return "retention." + strings.TrimPrefix(key, "retention_"), v
+	}
+	// query_alerts_* -> query_alerts.*
+	if strings.HasPrefix(key, "query_alerts_") {
+		return "query_alerts." + strings.TrimPrefix(key, "query_alerts_"), v
+	}
+	// health_checks_* -> health_checks.*
+	if strings.HasPrefix(key, "health_checks_") {
+		return "health_checks." + strings.TrimPrefix(key, "health_checks_"), v
+	}
+	// secrets_* -> secrets.*
+	if strings.HasPrefix(key, "secrets_") {
+		return "secrets." + strings.TrimPrefix(key, "secrets_"), v
+	}
+	// audit_file_* -> audit.file.*, audit_syslog_* -> audit.syslog.*,
+	// audit_s3_* -> audit.s3.*
+	for _, sink := range []string{"file", "syslog", "s3"} {
+		if prefix := "audit_" + sink + "_"; strings.HasPrefix(key, prefix) {
+			return "audit." + sink + "." + strings.TrimPrefix(key, prefix), v
+		}
+	}
 	return key, v
 }
 
@@ -532,14 +1825,155 @@ func Load(opts LoadOptions, cliOverrides ...func(*Config)) (*Config, error) {
 		return nil, ErrDSNRequired
 	}
 
-	// Load encryption key from Key or KeyFile
-	key, err := loadEncryptionKey(cfg.Key, cfg.KeyFile)
+	// Load encryption key from Key, KeyCommand or KeyFile
+	key, err := loadEncryptionKey(cfg.Key, cfg.KeyCommand, cfg.KeyFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load encryption key: %w", err)
 	}
 
 	cfg.EncryptionKey = key
 
+	if _, err := ParseCIDRList(cfg.ProxyProtocol.TrustedCIDRs); err != nil {
+		return nil, fmt.Errorf("proxy_protocol.trusted_cidrs: %w", err)
+	}
+
+	if cfg.ProxyProtocol.Enabled && len(cfg.ProxyProtocol.TrustedNetworks()) == 0 {
+		return nil, fmt.Errorf("proxy_protocol.trusted_cidrs: %w", ErrNoTrustedPeers)
+	}
+
+	if _, err := ParseCIDRList(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("trusted_proxies: %w", err)
+	}
+
+	if cfg.AccessLog.SampleRate < 0 || cfg.AccessLog.SampleRate > 1 {
+		return nil, fmt.Errorf("access_log.sample_rate: %w: got %g", ErrInvalidRate, cfg.AccessLog.SampleRate)
+	}
+
+	if _, err := cfg.ReplicaLag(); err != nil {
+		return nil, fmt.Errorf("replica_max_lag: %w", err)
+	}
+
+	if _, err := cfg.MigrationLockWait(); err != nil {
+		return nil, fmt.Errorf("migration_lock_timeout: %w", err)
+	}
+
+	if wait, err := cfg.DrainWait(); err != nil {
+		return nil, fmt.Errorf("drain_timeout: %w", err)
+	} else if wait < 0 {
+		return nil, fmt.Errorf("drain_timeout: %w", ErrNegative)
+	}
+
+	if cfg.Lineage.Enabled() {
+		if u, err := url.Parse(cfg.Lineage.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("lineage.url: %w: %q", ErrInvalidURL, cfg.Lineage.URL)
+		}
+	}
+
+	if skew, err := cfg.APISignatureSkew(); err != nil {
+		return nil, fmt.Errorf("api_signature_max_skew: %w", err)
+	} else if skew <= 0 {
+		return nil, fmt.Errorf("api_signature_max_skew: %w", ErrNotPositive)
+	}
+
+	if _, err := cfg.StoragePool.Lifetime(); err != nil {
+		return nil, fmt.Errorf("storage_pool.conn_max_lifetime: %w", err)
+	}
+
+	if _, err := cfg.StoragePool.IdleTime(); err != nil {
+		return nil, fmt.Errorf("storage_pool.conn_max_idle_time: %w", err)
+	}
+
+	if _, err := cfg.QueryStorage.CompactAge(); err != nil {
+		return nil, fmt.Errorf("query_storage.compact_after: %w", err)
+	}
+
+	if cfg.QueryStorage.MaxSQLBytes < 0 {
+		return nil, fmt.Errorf("query_storage.max_sql_bytes: %w", ErrNegative)
+	}
+
+	if window, err := cfg.QueryStorage.DedupAge(); err != nil {
+		return nil, fmt.Errorf("query_storage.dedup_window: %w", err)
+	} else if window < 0 {
+		return nil, fmt.Errorf("query_storage.dedup_window: %w", ErrNegative)
+	}
+
+	if err := cfg.ResultSpill.validate(); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Hash.Algorithm {
+	case "argon2id", "bcrypt", "scrypt":
+	default:
+		return nil, fmt.Errorf("hash.algorithm: %w: %q (argon2id, bcrypt or scrypt)", ErrInvalidValue, cfg.Hash.Algorithm)
+	}
+
+	if cfg.Hash.BcryptCost < 4 || cfg.Hash.BcryptCost > 31 {
+		return nil, fmt.Errorf("hash.bcrypt_cost: %w: %d (4-31)", ErrInvalidValue, cfg.Hash.BcryptCost)
+	}
+
+	if cfg.SelfObservability.Enabled && cfg.SelfObservability.MaxQueries <= 0 {
+		return nil, fmt.Errorf("self_observability.max_queries: %w", ErrNotPositive)
+	}
+
+	if err := cfg.Retention.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.QueryAlerts.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.HealthChecks.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Secrets.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Session.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.PG.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Audit.validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := cfg.OIDC.Roles(); err != nil {
+		return nil, fmt.Errorf("oidc.role_mapping: %w", err)
+	}
+
+	if cfg.OIDC.DefaultRole != "" && !validRoles[cfg.OIDC.DefaultRole] {
+		return nil, fmt.Errorf("oidc.default_role: %w: %q", ErrInvalidValue, cfg.OIDC.DefaultRole)
+	}
+
+	if _, err := cfg.LDAP.Roles(); err != nil {
+		return nil, fmt.Errorf("ldap.role_mapping: %w", err)
+	}
+
+	if cfg.LDAP.DefaultRole != "" && !validRoles[cfg.LDAP.DefaultRole] {
+		return nil, fmt.Errorf("ldap.default_role: %w: %q", ErrInvalidValue, cfg.LDAP.DefaultRole)
+	}
+
+	if cfg.LDAP.Enabled() && !strings.Contains(cfg.LDAP.UserFilter, "{username}") {
+		return nil, fmt.Errorf("ldap.user_filter: %w: %q has no {username}", ErrInvalidValue, cfg.LDAP.UserFilter)
+	}
+
+	switch cfg.LocalLogin {
+	case LocalLoginEnabled, LocalLoginAdmins:
+	case LocalLoginDisabled:
+		// Nobody could sign in to the API at all.
+		if !cfg.OIDC.Enabled() && !cfg.SlackAuth.Enabled() && !cfg.LDAP.Enabled() {
+			return nil, fmt.Errorf("local_login: %w: disabled without OIDC, Slack or LDAP sign-in", ErrInvalidValue)
+		}
+	default:
+		return nil, fmt.Errorf("local_login: %w: %q (enabled, admins or disabled)", ErrInvalidValue, cfg.LocalLogin)
+	}
+
 	// Parse redirects from DBB_REDIRECTS environment variable
 	cfg.Redirects = parseRedirects(os.Getenv("DBB_REDIRECTS"))
 
@@ -568,8 +2002,8 @@ func loadConfigFile(k *koanf.Koanf, path string) error {
 	return k.Load(file.Provider(path), parser)
 }
 
-// loadEncryptionKey loads the encryption key from base64 string, file, or default location.
-func loadEncryptionKey(keyStr, keyFile string) ([]byte, error) {
+// loadEncryptionKey loads the encryption key from base64 string, key command, file, or default location.
+func loadEncryptionKey(keyStr, keyCommand, keyFile string) ([]byte, error) {
 	// Try base64-encoded key first
 	if keyStr != "" {
 		key, err := base64.StdEncoding.DecodeString(keyStr)
@@ -584,6 +2018,11 @@ func loadEncryptionKey(keyStr, keyFile string) ([]byte, error) {
 		return key, nil
 	}
 
+	// Try key command
+	if keyCommand != "" {
+		return runKeyCommand(keyCommand)
+	}
+
 	// Try key file
 	if keyFile != "" {
 		key, err := os.ReadFile(keyFile)
@@ -602,6 +2041,46 @@ func loadEncryptionKey(keyStr, keyFile string) ([]byte, error) {
 	return loadOrCreateDefaultKey()
 }
 
+// runKeyCommand runs the key command through the shell and reads the key from
+// its output: either the 32 raw bytes, or their base64 encoding. Whatever
+// unwraps the key, a device tool or a secret manager, is up to the command;
+// the key is never written to disk.
+func runKeyCommand(command string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("%w: %w", ErrKeyCommand, err)
+		}
+
+		return nil, fmt.Errorf("%w: %w: %s", ErrKeyCommand, err, msg)
+	}
+
+	output := stdout.Bytes()
+	if len(output) == expectedKeySize {
+		return output, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: output is neither %d raw bytes nor base64", ErrInvalidKeySize, expectedKeySize)
+	}
+
+	if len(key) != expectedKeySize {
+		return nil, fmt.Errorf("%w: got %d bytes from key command", ErrInvalidKeySize, len(key))
+	}
+
+	return key, nil
+}
+
 // DefaultKeyFilePath returns the path to the default key file (~/.dbbat/key).
 func DefaultKeyFilePath() (string, error) {
 	homeDir, err := os.UserHomeDir()
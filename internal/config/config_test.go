@@ -1,11 +1,14 @@
 package config
 
 import (
+	"bytes"
 	"encoding/base64"
 	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 )
 
 // clearEnvVars unsets all DBB_ environment variables and uses t.Cleanup for restoration.
@@ -13,7 +16,7 @@ func clearEnvVars(t *testing.T) {
 	t.Helper()
 
 	envVars := []string{
-		"DBB_DSN", "DBB_KEY", "DBB_KEYFILE",
+		"DBB_DSN", "DBB_KEY", "DBB_KEYFILE", "DBB_KEY_COMMAND",
 		"DBB_LISTEN_PG", "DBB_LISTEN_API", "DBB_CONFIG",
 		"DBB_BASE_URL", "DBB_REDIRECTS",
 	}
@@ -164,6 +167,59 @@ func TestLoadWithKeyFile(t *testing.T) {
 	}
 }
 
+func TestLoadWithKeyCommand(t *testing.T) {
+	// Note: Can't use t.Parallel() since we manipulate environment variables
+
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	keyBase64 := base64.StdEncoding.EncodeToString(key)
+
+	tmpDir := t.TempDir()
+	rawKeyFile := filepath.Join(tmpDir, "raw")
+	if err := os.WriteFile(rawKeyFile, key, 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		wantErr error
+	}{
+		{name: "base64 output", command: "echo " + keyBase64},
+		{name: "raw output", command: "cat " + rawKeyFile},
+		{name: "command fails", command: "echo 'device locked' >&2; exit 1", wantErr: ErrKeyCommand},
+		{name: "wrong size", command: "echo " + base64.StdEncoding.EncodeToString([]byte("short")), wantErr: ErrInvalidKeySize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnvVars(t)
+			t.Setenv("DBB_DSN", "postgres://localhost/test")
+			t.Setenv("DBB_KEY_COMMAND", tt.command)
+
+			cfg, err := Load(LoadOptions{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if !bytes.Equal(cfg.EncryptionKey, key) {
+				t.Errorf("Load() EncryptionKey = %x, want %x", cfg.EncryptionKey, key)
+			}
+		})
+	}
+}
+
 func TestDefaultValues(t *testing.T) {
 	// Note: Can't use t.Parallel() since we manipulate environment variables
 
@@ -789,3 +845,953 @@ func TestLoadWithRedirects(t *testing.T) {
 		t.Errorf("Redirects[0].TargetHost = %v, want localhost:5173", cfg.Redirects[0].TargetHost)
 	}
 }
+
+func TestParseCIDRList(t *testing.T) {
+	t.Parallel()
+
+	networks, err := ParseCIDRList(" 10.0.0.0/8, 192.168.1.10 ,, 2001:db8::/32")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"10.0.0.0/8", "192.168.1.10/32", "2001:db8::/32"}
+	if len(networks) != len(want) {
+		t.Fatalf("got %d networks, want %d", len(networks), len(want))
+	}
+
+	for i, n := range networks {
+		if n.String() != want[i] {
+			t.Errorf("network %d: got %s, want %s", i, n.String(), want[i])
+		}
+	}
+
+	if _, err := ParseCIDRList("10.0.0.0/33"); !errors.Is(err, ErrInvalidCIDR) {
+		t.Errorf("expected ErrInvalidCIDR, got %v", err)
+	}
+}
+
+func TestLoadProxyProtocolEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_PROXY_PROTOCOL_ENABLED", "true")
+	t.Setenv("DBB_PROXY_PROTOCOL_TRUSTED_CIDRS", "10.0.0.0/8")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.ProxyProtocol.Enabled {
+		t.Error("expected proxy protocol to be enabled")
+	}
+
+	if got := cfg.ProxyProtocol.TrustedNetworks(); len(got) != 1 || got[0].String() != "10.0.0.0/8" {
+		t.Errorf("unexpected trusted networks: %v", got)
+	}
+
+	t.Setenv("DBB_PROXY_PROTOCOL_TRUSTED_CIDRS", "not-a-cidr")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidCIDR) {
+		t.Errorf("expected ErrInvalidCIDR, got %v", err)
+	}
+
+	t.Setenv("DBB_PROXY_PROTOCOL_TRUSTED_CIDRS", "")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNoTrustedPeers) {
+		t.Errorf("expected ErrNoTrustedPeers, got %v", err)
+	}
+}
+
+func TestLoadAccessLogEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.AccessLog.Output != "" || cfg.AccessLog.SampleRate != DefaultAccessLogSampleRate {
+		t.Errorf("unexpected access log defaults: %+v", cfg.AccessLog)
+	}
+
+	t.Setenv("DBB_ACCESS_LOG_OUTPUT", "stdout")
+	t.Setenv("DBB_ACCESS_LOG_SAMPLE_RATE", "0.25")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.AccessLog.Output != "stdout" || cfg.AccessLog.SampleRate != 0.25 {
+		t.Errorf("unexpected access log config: %+v", cfg.AccessLog)
+	}
+
+	t.Setenv("DBB_ACCESS_LOG_SAMPLE_RATE", "1.5")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("expected ErrInvalidRate, got %v", err)
+	}
+}
+
+func TestLoadStoragePoolEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_STORAGE_POOL_MAX_OPEN_CONNS", "100")
+	t.Setenv("DBB_STORAGE_POOL_CONN_MAX_IDLE_TIME", "30s")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.StoragePool.MaxOpenConns != 100 {
+		t.Errorf("expected max open conns 100, got %d", cfg.StoragePool.MaxOpenConns)
+	}
+
+	if cfg.StoragePool.MaxIdleConns != DefaultStoragePoolMaxIdleConns {
+		t.Errorf("expected default max idle conns, got %d", cfg.StoragePool.MaxIdleConns)
+	}
+
+	if d, _ := cfg.StoragePool.Lifetime(); d != 5*time.Minute {
+		t.Errorf("expected default lifetime 5m, got %v", d)
+	}
+
+	if d, _ := cfg.StoragePool.IdleTime(); d != 30*time.Second {
+		t.Errorf("expected idle time 30s, got %v", d)
+	}
+
+	t.Setenv("DBB_STORAGE_POOL_CONN_MAX_LIFETIME", "forever")
+
+	if _, err := Load(LoadOptions{}); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
+
+func TestLoadReplicaEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_REPLICA_DSN", "postgres://x:x@replica/x")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.ReplicaDSN != "postgres://x:x@replica/x" {
+		t.Errorf("unexpected replica DSN: %q", cfg.ReplicaDSN)
+	}
+
+	if d, _ := cfg.ReplicaLag(); d != 30*time.Second {
+		t.Errorf("expected default replica lag 30s, got %v", d)
+	}
+
+	t.Setenv("DBB_REPLICA_MAX_LAG", "soon")
+
+	if _, err := Load(LoadOptions{}); err == nil {
+		t.Error("expected an error for an invalid replica lag")
+	}
+}
+
+func TestLoadPGBlockedMessageEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_PG_BLOCKED_MESSAGE", "Read-only access, request write access at https://dbbat.example.com")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.PG.BlockedMessage != "Read-only access, request write access at https://dbbat.example.com" {
+		t.Errorf("PG.BlockedMessage = %q", cfg.PG.BlockedMessage)
+	}
+}
+
+func TestLoadPGPoolEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.PG.TransactionPooling() || cfg.PG.PoolSize != DefaultPGPoolSize {
+		t.Errorf("PG pool = %q/%d, want session mode and %d connections", cfg.PG.PoolMode, cfg.PG.PoolSize, DefaultPGPoolSize)
+	}
+
+	t.Setenv("DBB_PG_POOL_MODE", "transaction")
+	t.Setenv("DBB_PG_POOL_SIZE", "5")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.PG.TransactionPooling() || cfg.PG.PoolSize != 5 {
+		t.Errorf("PG pool = %q/%d, want transaction mode and 5 connections", cfg.PG.PoolMode, cfg.PG.PoolSize)
+	}
+
+	t.Setenv("DBB_PG_POOL_MODE", "statement")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Load() with pool mode statement error = %v, want %v", err, ErrInvalidValue)
+	}
+
+	t.Setenv("DBB_PG_POOL_MODE", "transaction")
+	t.Setenv("DBB_PG_POOL_SIZE", "0")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNotPositive) {
+		t.Errorf("Load() with pool size 0 error = %v, want %v", err, ErrNotPositive)
+	}
+}
+
+func TestLoadPGListeners(t *testing.T) {
+	clearEnvVars(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	load := func(content string) (*Config, error) {
+		t.Helper()
+
+		content = "dsn: postgres://x:x@localhost/x\nkey: AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n" + content
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		return Load(LoadOptions{ConfigFile: configFile})
+	}
+
+	cfg, err := load(`
+pg:
+  listeners:
+    - name: vpn
+      addr: ":5444"
+      databases: [prod, staging]
+      allowed_cidrs: 10.8.0.0/16, 192.168.1.10
+      tls:
+        disable: true
+    - addr: ":5445"
+`)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(cfg.PG.Listeners) != 2 {
+		t.Fatalf("PG listeners = %+v, want 2", cfg.PG.Listeners)
+	}
+
+	vpn, internal := cfg.PG.Listeners[0], cfg.PG.Listeners[1]
+
+	if vpn.DisplayName() != "vpn" || internal.DisplayName() != ":5445" {
+		t.Errorf("listener names = %q, %q, want vpn, :5445", vpn.DisplayName(), internal.DisplayName())
+	}
+
+	if len(vpn.Databases) != 2 || vpn.Databases[0] != "prod" || vpn.Databases[1] != "staging" {
+		t.Errorf("vpn databases = %v, want [prod staging]", vpn.Databases)
+	}
+
+	if networks, err := vpn.AllowedNetworks(); err != nil || len(networks) != 2 {
+		t.Errorf("vpn networks = %v, %v, want 2 networks", networks, err)
+	}
+
+	if !vpn.TLSOrDefault(cfg.PG.TLS).Disable {
+		t.Error("vpn TLS is enabled, want its own disabled TLS")
+	}
+
+	if internal.TLS != nil || internal.TLSOrDefault(TLSConfig{CertFile: "pg.crt"}).CertFile != "pg.crt" {
+		t.Errorf("unnamed listener TLS = %+v, want pg.tls", internal.TLS)
+	}
+
+	if _, err := load("pg:\n  listeners:\n    - addr: \":5444\"\n    - addr: \":5444\"\n"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Load() with a duplicate address error = %v, want %v", err, ErrInvalidValue)
+	}
+
+	if _, err := load("pg:\n  listeners:\n    - name: vpn\n"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Load() without an address error = %v, want %v", err, ErrInvalidValue)
+	}
+
+	if _, err := load("pg:\n  listeners:\n    - addr: \":5444\"\n      allowed_cidrs: 10.8.0.0/33\n"); !errors.Is(err, ErrInvalidCIDR) {
+		t.Errorf("Load() with an invalid network error = %v, want %v", err, ErrInvalidCIDR)
+	}
+}
+
+func TestLoadQueryStorageCompactAfterEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.QueryStorage.CompactAge(); d != 30*24*time.Hour {
+		t.Errorf("expected default compact age 720h, got %v", d)
+	}
+
+	t.Setenv("DBB_QUERY_STORAGE_COMPACT_AFTER", "168h")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.QueryStorage.CompactAge(); d != 7*24*time.Hour {
+		t.Errorf("expected compact age 168h, got %v", d)
+	}
+
+	t.Setenv("DBB_QUERY_STORAGE_COMPACT_AFTER", "a month")
+
+	if _, err := Load(LoadOptions{}); err == nil {
+		t.Error("expected an error for an invalid compact_after")
+	}
+}
+
+func TestLoadQueryStorageMaxSQLBytesEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.QueryStorage.MaxSQLBytes != DefaultMaxSQLBytes {
+		t.Errorf("expected default max SQL bytes %d, got %d", DefaultMaxSQLBytes, cfg.QueryStorage.MaxSQLBytes)
+	}
+
+	t.Setenv("DBB_QUERY_STORAGE_MAX_SQL_BYTES", "4096")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.QueryStorage.MaxSQLBytes != 4096 {
+		t.Errorf("expected max SQL bytes 4096, got %d", cfg.QueryStorage.MaxSQLBytes)
+	}
+
+	t.Setenv("DBB_QUERY_STORAGE_MAX_SQL_BYTES", "-1")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNegative) {
+		t.Errorf("expected ErrNegative for a negative max_sql_bytes, got %v", err)
+	}
+}
+
+func TestLoadQueryStorageDedupWindowEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.QueryStorage.DedupAge(); d != 0 {
+		t.Errorf("expected deduplication disabled by default, got %v", d)
+	}
+
+	t.Setenv("DBB_QUERY_STORAGE_DEDUP_WINDOW", "10s")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.QueryStorage.DedupAge(); d != 10*time.Second {
+		t.Errorf("expected dedup window 10s, got %v", d)
+	}
+
+	t.Setenv("DBB_QUERY_STORAGE_DEDUP_WINDOW", "-1s")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNegative) {
+		t.Errorf("expected ErrNegative for a negative dedup_window, got %v", err)
+	}
+
+	t.Setenv("DBB_QUERY_STORAGE_DEDUP_WINDOW", "often")
+
+	if _, err := Load(LoadOptions{}); err == nil {
+		t.Error("expected an error for an invalid dedup_window")
+	}
+}
+
+func TestLoadQueryStorageCompressRowsEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.QueryStorage.CompressRows {
+		t.Error("expected row compression disabled by default")
+	}
+
+	t.Setenv("DBB_QUERY_STORAGE_COMPRESS_ROWS", "true")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.QueryStorage.CompressRows {
+		t.Error("expected row compression enabled")
+	}
+}
+
+func TestLoadSelfObservabilityEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.SelfObservability.Enabled {
+		t.Error("expected self observability disabled by default")
+	}
+
+	t.Setenv("DBB_SELF_OBSERVABILITY_ENABLED", "true")
+	t.Setenv("DBB_SELF_OBSERVABILITY_MAX_QUERIES", "500")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.SelfObservability.Enabled || cfg.SelfObservability.MaxQueries != 500 {
+		t.Errorf("expected self observability enabled with 500 queries, got %+v", cfg.SelfObservability)
+	}
+
+	t.Setenv("DBB_SELF_OBSERVABILITY_MAX_QUERIES", "0")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNotPositive) {
+		t.Errorf("expected ErrNotPositive for max_queries 0, got %v", err)
+	}
+}
+
+func TestLoadHashAlgorithmEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Hash.Algorithm != "argon2id" || cfg.Hash.BcryptCost != DefaultHashBcryptCost {
+		t.Errorf("expected argon2id with bcrypt cost %d by default, got %+v", DefaultHashBcryptCost, cfg.Hash)
+	}
+
+	t.Setenv("DBB_HASH_ALGORITHM", "bcrypt")
+	t.Setenv("DBB_HASH_BCRYPT_COST", "10")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Hash.Algorithm != "bcrypt" || cfg.Hash.BcryptCost != 10 {
+		t.Errorf("expected bcrypt with cost 10, got %+v", cfg.Hash)
+	}
+
+	t.Setenv("DBB_HASH_BCRYPT_COST", "32")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for bcrypt cost 32, got %v", err)
+	}
+
+	t.Setenv("DBB_HASH_BCRYPT_COST", "10")
+	t.Setenv("DBB_HASH_ALGORITHM", "md5")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for algorithm md5, got %v", err)
+	}
+}
+
+func TestLoadMigrationLockTimeoutEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.MigrationLockWait(); d != 5*time.Minute {
+		t.Errorf("expected default migration lock timeout 5m, got %v", d)
+	}
+
+	t.Setenv("DBB_MIGRATION_LOCK_TIMEOUT", "later")
+
+	if _, err := Load(LoadOptions{}); err == nil {
+		t.Error("expected an error for an invalid migration lock timeout")
+	}
+}
+
+func TestLoadDrainTimeoutEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.DrainWait(); d != time.Minute {
+		t.Errorf("expected default drain timeout 1m, got %v", d)
+	}
+
+	t.Setenv("DBB_DRAIN_TIMEOUT", "5m")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.DrainWait(); d != 5*time.Minute {
+		t.Errorf("expected drain timeout 5m, got %v", d)
+	}
+
+	t.Setenv("DBB_DRAIN_TIMEOUT", "-1s")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNegative) {
+		t.Errorf("expected ErrNegative for a negative drain timeout, got %v", err)
+	}
+}
+
+func TestLoadAPISignatureMaxSkewEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.APISignatureSkew(); d != 5*time.Minute {
+		t.Errorf("expected default signature skew 5m, got %v", d)
+	}
+
+	t.Setenv("DBB_API_SIGNATURE_MAX_SKEW", "0")
+
+	if _, err := Load(LoadOptions{}); err == nil {
+		t.Error("expected an error for a zero signature skew")
+	}
+}
+
+func TestLoadLineageEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_LINEAGE_URL", "http://marquez:5000/api/v1/lineage")
+	t.Setenv("DBB_LINEAGE_API_KEY", "secret")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.Lineage.Enabled() || cfg.Lineage.APIKey != "secret" || cfg.Lineage.Namespace != DefaultLineageNamespace {
+		t.Errorf("unexpected lineage config: %+v", cfg.Lineage)
+	}
+
+	t.Setenv("DBB_LINEAGE_URL", "marquez:5000")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("expected ErrInvalidURL, got %v", err)
+	}
+}
+
+func TestLoadRetentionEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Retention.Enabled() {
+		t.Errorf("expected retention disabled by default, got %+v", cfg.Retention)
+	}
+
+	t.Setenv("DBB_RETENTION_QUERIES_DAYS", "90")
+	t.Setenv("DBB_RETENTION_MAX_BYTES", "1073741824")
+	t.Setenv("DBB_RETENTION_INTERVAL", "15m")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.Retention.Enabled() || cfg.Retention.QueriesDays != 90 || cfg.Retention.MaxBytes != 1<<30 {
+		t.Errorf("unexpected retention config: %+v", cfg.Retention)
+	}
+
+	if d, _ := cfg.Retention.RunInterval(); d != 15*time.Minute {
+		t.Errorf("expected interval 15m, got %v", d)
+	}
+
+	t.Setenv("DBB_RETENTION_AUDIT_DAYS", "-1")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNegative) {
+		t.Errorf("expected ErrNegative for negative audit_days, got %v", err)
+	}
+
+	t.Setenv("DBB_RETENTION_AUDIT_DAYS", "")
+	t.Setenv("DBB_RETENTION_INTERVAL", "0s")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNotPositive) {
+		t.Errorf("expected ErrNotPositive for a zero interval, got %v", err)
+	}
+}
+
+func TestLoadQueryAlertsEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.QueryAlerts.RunInterval(); d != 30*time.Second {
+		t.Errorf("expected default interval 30s, got %v", d)
+	}
+
+	t.Setenv("DBB_QUERY_ALERTS_INTERVAL", "1m")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.QueryAlerts.RunInterval(); d != time.Minute {
+		t.Errorf("expected interval 1m, got %v", d)
+	}
+
+	t.Setenv("DBB_QUERY_ALERTS_INTERVAL", "-5s")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNotPositive) {
+		t.Errorf("expected ErrNotPositive for a negative interval, got %v", err)
+	}
+}
+
+func TestLoadHealthChecksEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.HealthChecks.RunInterval(); d != 5*time.Minute {
+		t.Errorf("expected default interval 5m, got %v", d)
+	}
+
+	t.Setenv("DBB_HEALTH_CHECKS_INTERVAL", "0")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.HealthChecks.RunInterval(); d != 0 {
+		t.Errorf("expected disabled checks, got %v", d)
+	}
+
+	t.Setenv("DBB_HEALTH_CHECKS_INTERVAL", "-1m")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNegative) {
+		t.Errorf("expected ErrNegative for a negative interval, got %v", err)
+	}
+}
+
+func TestLoadSecretsEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_SECRETS_VAULT_ADDR", "https://vault.internal:8200")
+	t.Setenv("DBB_SECRETS_AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, _ := cfg.Secrets.CacheDuration(); d != 5*time.Minute {
+		t.Errorf("expected default cache TTL 5m, got %v", d)
+	}
+
+	if cfg.Secrets.VaultAddr != "https://vault.internal:8200" || cfg.Secrets.AWSSecretAccessKey != "wJalrXUtnFEMI" {
+		t.Errorf("expected the secrets settings from the environment, got %+v", cfg.Secrets)
+	}
+
+	t.Setenv("DBB_SECRETS_CACHE_TTL", "-1s")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNegative) {
+		t.Errorf("expected ErrNegative for a negative cache TTL, got %v", err)
+	}
+}
+
+func TestLoadAuditEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Audit.Enabled() {
+		t.Errorf("expected no audit sink by default, got %+v", cfg.Audit)
+	}
+
+	t.Setenv("DBB_AUDIT_FILE_PATH", "/var/log/dbbat/audit.jsonl")
+	t.Setenv("DBB_AUDIT_FILE_MAX_SIZE_MB", "50")
+	t.Setenv("DBB_AUDIT_SYSLOG_ADDRESS", "syslog.internal:6514")
+	t.Setenv("DBB_AUDIT_SYSLOG_NETWORK", "tls")
+	t.Setenv("DBB_AUDIT_S3_BUCKET", "audit-archive")
+	t.Setenv("DBB_AUDIT_S3_ACCESS_KEY_ID", "AKIA")
+	t.Setenv("DBB_AUDIT_S3_SECRET_ACCESS_KEY", "secret")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Audit.File.Path != "/var/log/dbbat/audit.jsonl" || cfg.Audit.File.MaxSizeMB != 50 || cfg.Audit.File.MaxBackups != DefaultAuditFileMaxBackups {
+		t.Errorf("unexpected audit file config: %+v", cfg.Audit.File)
+	}
+
+	if cfg.Audit.Syslog.Address != "syslog.internal:6514" || cfg.Audit.Syslog.Network != "tls" || cfg.Audit.Syslog.Tag != DefaultAuditSyslogTag {
+		t.Errorf("unexpected audit syslog config: %+v", cfg.Audit.Syslog)
+	}
+
+	if cfg.Audit.S3.Bucket != "audit-archive" || cfg.Audit.S3.SecretAccessKey != "secret" || cfg.Audit.S3.Region != DefaultAuditS3Region {
+		t.Errorf("unexpected audit s3 config: %+v", cfg.Audit.S3)
+	}
+
+	t.Setenv("DBB_AUDIT_SYSLOG_NETWORK", "unix")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for an unknown syslog network, got %v", err)
+	}
+
+	t.Setenv("DBB_AUDIT_SYSLOG_NETWORK", "udp")
+	t.Setenv("DBB_AUDIT_S3_SECRET_ACCESS_KEY", "")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for s3 without credentials, got %v", err)
+	}
+}
+
+func TestLoadSessionEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_SESSION_IDLE_TIMEOUT", "30m")
+	t.Setenv("DBB_SESSION_MAX_DURATION", "12h")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if idle, maxDuration := cfg.Session.Timeouts(); idle != 30*time.Minute || maxDuration != 12*time.Hour {
+		t.Errorf("Timeouts() = %s, %s, want 30m0s, 12h0m0s", idle, maxDuration)
+	}
+
+	if wait := cfg.Session.ApprovalWait(); wait != DefaultApprovalTimeout {
+		t.Errorf("ApprovalWait() = %s, want %s", wait, DefaultApprovalTimeout)
+	}
+
+	if lead := cfg.Session.ExpiryWarningLead(); lead != 5*time.Minute {
+		t.Errorf("ExpiryWarningLead() = %s, want 5m0s", lead)
+	}
+
+	if thresholds := cfg.Session.QuotaWarningThresholds(); !slices.Equal(thresholds, []int{80}) {
+		t.Errorf("QuotaWarningThresholds() = %v, want [80]", thresholds)
+	}
+
+	t.Setenv("DBB_SESSION_APPROVAL_TIMEOUT", "90s")
+	t.Setenv("DBB_SESSION_EXPIRY_WARNING", "0")
+	t.Setenv("DBB_SESSION_QUOTA_WARNINGS", "95, 80,95")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if wait := cfg.Session.ApprovalWait(); wait != 90*time.Second {
+		t.Errorf("ApprovalWait() = %s, want 1m30s", wait)
+	}
+
+	if lead := cfg.Session.ExpiryWarningLead(); lead != 0 {
+		t.Errorf("ExpiryWarningLead() = %s, want 0s", lead)
+	}
+
+	if thresholds := cfg.Session.QuotaWarningThresholds(); !slices.Equal(thresholds, []int{80, 95}) {
+		t.Errorf("QuotaWarningThresholds() = %v, want [80 95]", thresholds)
+	}
+
+	t.Setenv("DBB_SESSION_QUOTA_WARNINGS", "0")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if thresholds := cfg.Session.QuotaWarningThresholds(); len(thresholds) != 0 {
+		t.Errorf("QuotaWarningThresholds() = %v, want none", thresholds)
+	}
+
+	t.Setenv("DBB_SESSION_QUOTA_WARNINGS", "100")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Load() with a 100%% quota warning error = %v, want ErrInvalidValue", err)
+	}
+
+	t.Setenv("DBB_SESSION_QUOTA_WARNINGS", "80")
+
+	t.Setenv("DBB_SESSION_MAX_DURATION", "-1h")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrNegative) {
+		t.Errorf("expected ErrNegative, got %v", err)
+	}
+
+	t.Setenv("DBB_SESSION_MAX_DURATION", "")
+	t.Setenv("DBB_SESSION_IDLE_TIMEOUT", "soon")
+
+	if _, err := Load(LoadOptions{}); err == nil {
+		t.Error("expected an error for an invalid idle timeout")
+	}
+}
+
+func TestLoadOIDCEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_OIDC_ISSUER", "https://idp.example.com")
+	t.Setenv("DBB_OIDC_CLIENT_ID", "dbbat")
+	t.Setenv("DBB_OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("DBB_OIDC_SCOPES", "groups, offline_access")
+	t.Setenv("DBB_OIDC_ROLE_MAPPING", "dbbat-admins=admin, sre=viewer,sre=connector")
+	t.Setenv("DBB_LOCAL_LOGIN", "admins")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.OIDC.Enabled() || cfg.OIDC.RolesClaim != "groups" || cfg.LocalLogin != LocalLoginAdmins {
+		t.Errorf("unexpected OIDC config: %+v, local_login %q", cfg.OIDC, cfg.LocalLogin)
+	}
+
+	if scopes := cfg.OIDC.ExtraScopes(); len(scopes) != 2 || scopes[1] != "offline_access" {
+		t.Errorf("ExtraScopes() = %v", scopes)
+	}
+
+	roles, err := cfg.OIDC.Roles()
+	if err != nil {
+		t.Fatalf("Roles() error = %v", err)
+	}
+
+	if len(roles["dbbat-admins"]) != 1 || len(roles["sre"]) != 2 || roles["sre"][1] != "connector" {
+		t.Errorf("Roles() = %v", roles)
+	}
+
+	t.Setenv("DBB_OIDC_ROLE_MAPPING", "sre=root")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for an unknown role, got %v", err)
+	}
+
+	t.Setenv("DBB_OIDC_ROLE_MAPPING", "")
+	t.Setenv("DBB_OIDC_ISSUER", "")
+	t.Setenv("DBB_LOCAL_LOGIN", "disabled")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for local login disabled without SSO, got %v", err)
+	}
+}
+
+func TestLoadLDAPEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	t.Setenv("DBB_LDAP_URL", "ldaps://ad.corp.example.com")
+	t.Setenv("DBB_LDAP_BASE_DN", "DC=corp,DC=example,DC=com")
+	t.Setenv("DBB_LDAP_USER_FILTER", "(sAMAccountName={username})")
+	t.Setenv("DBB_LDAP_ROLE_MAPPING", "DBBat-Admins=admin,dba=connector")
+	t.Setenv("DBB_LOCAL_LOGIN", "disabled")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.LDAP.Enabled() || cfg.LDAP.GroupAttribute != "memberOf" || cfg.LDAP.DefaultRole != "connector" {
+		t.Errorf("unexpected LDAP config: %+v", cfg.LDAP)
+	}
+
+	roles, err := cfg.LDAP.Roles()
+	if err != nil {
+		t.Fatalf("Roles() error = %v", err)
+	}
+
+	if len(roles["dbbat-admins"]) != 1 || roles["dba"][0] != "connector" {
+		t.Errorf("Roles() = %v, want lowercased groups", roles)
+	}
+
+	t.Setenv("DBB_LDAP_USER_FILTER", "(sAMAccountName=jdoe)")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for a filter without {username}, got %v", err)
+	}
+}
+
+func TestLoadResultSpillEnv(t *testing.T) {
+	t.Setenv("DBB_DSN", "postgres://x:x@localhost/x")
+	t.Setenv("DBB_KEY", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	cfg, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.ResultSpill.Enabled() {
+		t.Error("expected result spilling to be disabled by default")
+	}
+
+	t.Setenv("DBB_RESULT_SPILL_TARGET", "s3")
+	t.Setenv("DBB_RESULT_SPILL_BUCKET", "results")
+	t.Setenv("DBB_RESULT_SPILL_ACCESS_KEY_ID", "AKID")
+	t.Setenv("DBB_RESULT_SPILL_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("DBB_RESULT_SPILL_URL_EXPIRY", "1h")
+
+	cfg, err = Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.ResultSpill.Bucket != "results" || cfg.ResultSpill.Region != DefaultResultSpillRegion {
+		t.Errorf("unexpected result spill config: %+v", cfg.ResultSpill)
+	}
+
+	if d, _ := cfg.ResultSpill.Expiry(); d != time.Hour {
+		t.Errorf("expected URL expiry 1h, got %v", d)
+	}
+
+	t.Setenv("DBB_RESULT_SPILL_URL_EXPIRY", "200h")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for an expiry past 7 days, got %v", err)
+	}
+
+	t.Setenv("DBB_RESULT_SPILL_URL_EXPIRY", "15m")
+	t.Setenv("DBB_RESULT_SPILL_TARGET", "file")
+
+	if _, err := Load(LoadOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue for the file target without a directory, got %v", err)
+	}
+}
@@ -7,10 +7,26 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
 )
 
+// Password hashing algorithms. Argon2id is the default; bcrypt and scrypt are
+// available for organizations that mandate them.
+const (
+	HashAlgorithmArgon2id = "argon2id"
+	HashAlgorithmBcrypt   = "bcrypt"
+	HashAlgorithmScrypt   = "scrypt"
+)
+
+// DirectoryPasswordHash is stored in place of a hash for users whose password
+// is checked by an external directory (LDAP). It never verifies locally and
+// is never rehashed.
+const DirectoryPasswordHash = "!directory"
+
 // DefaultArgon2Time is the default number of iterations.
 const DefaultArgon2Time uint32 = 1
 
@@ -20,8 +36,19 @@ const DefaultArgon2Memory uint32 = 8 * 1024
 // DefaultArgon2Threads is the default parallelism factor.
 const DefaultArgon2Threads uint8 = 4
 
+// DefaultBcryptCost is the default bcrypt cost (log2 of the rounds).
+const DefaultBcryptCost = 12
+
+// Default scrypt parameters: N = 2^15, r = 8, p = 1 (32 MB).
+const (
+	DefaultScryptLogN uint8 = 15
+	DefaultScryptR          = 8
+	DefaultScryptP          = 1
+)
+
 const (
 	argon2KeyLen = 32
+	scryptKeyLen = 32
 	saltLength   = 16
 )
 
@@ -33,31 +60,81 @@ var (
 
 // HashParams holds configurable parameters for password hashing.
 type HashParams struct {
-	MemoryKB uint32 // Memory in KB
-	Time     uint32 // Number of iterations
-	Threads  uint8  // Parallelism factor
+	Algorithm string // HashAlgorithmArgon2id (default when empty), HashAlgorithmBcrypt or HashAlgorithmScrypt
+
+	MemoryKB uint32 // Argon2id memory in KB
+	Time     uint32 // Argon2id number of iterations
+	Threads  uint8  // Argon2id parallelism factor
+
+	BcryptCost int // bcrypt cost (log2 of the rounds)
+
+	ScryptLogN uint8 // scrypt CPU/memory cost, as log2(N)
+	ScryptR    int   // scrypt block size
+	ScryptP    int   // scrypt parallelism
 }
 
 // DefaultHashParams returns the default hash parameters.
 func DefaultHashParams() HashParams {
 	return HashParams{
-		MemoryKB: DefaultArgon2Memory,
-		Time:     DefaultArgon2Time,
-		Threads:  DefaultArgon2Threads,
+		Algorithm:  HashAlgorithmArgon2id,
+		MemoryKB:   DefaultArgon2Memory,
+		Time:       DefaultArgon2Time,
+		Threads:    DefaultArgon2Threads,
+		BcryptCost: DefaultBcryptCost,
+		ScryptLogN: DefaultScryptLogN,
+		ScryptR:    DefaultScryptR,
+		ScryptP:    DefaultScryptP,
+	}
+}
+
+// currentHashParams is set from the configuration at startup; nil means
+// DefaultHashParams.
+var currentHashParams atomic.Pointer[HashParams]
+
+// SetHashParams sets the parameters HashPassword hashes with, and that
+// NeedsRehash compares stored hashes against.
+func SetHashParams(params HashParams) {
+	currentHashParams.Store(&params)
+}
+
+// CurrentHashParams returns the parameters HashPassword hashes with.
+func CurrentHashParams() HashParams {
+	if params := currentHashParams.Load(); params != nil {
+		return *params
 	}
+
+	return DefaultHashParams()
 }
 
-// HashPassword generates an Argon2id hash of the password using default parameters.
+// HashPassword hashes the password with the configured algorithm and parameters.
 func HashPassword(password string) (string, error) {
-	return HashPasswordWithParams(password, DefaultHashParams())
+	return HashPasswordWithParams(password, CurrentHashParams())
 }
 
-// HashPasswordWithParams generates an Argon2id hash of the password using provided parameters.
+// HashPasswordWithParams hashes the password with the algorithm and parameters provided.
 func HashPasswordWithParams(password string, params HashParams) (string, error) {
-	// Generate a random salt
-	salt := make([]byte, saltLength)
-	if _, err := rand.Read(salt); err != nil {
-		return "", fmt.Errorf("failed to generate salt: %w", err)
+	switch params.Algorithm {
+	case "", HashAlgorithmArgon2id:
+		return hashArgon2id(password, params)
+	case HashAlgorithmBcrypt:
+		// bcrypt only reads the first 72 bytes and refuses longer passwords.
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), params.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+
+		return string(hash), nil
+	case HashAlgorithmScrypt:
+		return hashScrypt(password, params)
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedHashAlgo, params.Algorithm)
+	}
+}
+
+func hashArgon2id(password string, params HashParams) (string, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
 	}
 
 	// Generate the hash
@@ -85,8 +162,87 @@ func HashPasswordWithParams(password string, params HashParams) (string, error)
 	), nil
 }
 
-// VerifyPassword verifies a password against an Argon2id hash.
+func hashScrypt(password string, params HashParams) (string, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := scrypt.Key([]byte(password), salt, 1<<params.ScryptLogN, params.ScryptR, params.ScryptP, scryptKeyLen)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	// Encode as: $scrypt$ln=15,r=8,p=1$<salt>$<hash>
+	return fmt.Sprintf(
+		"$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		params.ScryptLogN,
+		params.ScryptR,
+		params.ScryptP,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	return salt, nil
+}
+
+// HashAlgorithm returns the algorithm of an encoded hash, detected from its
+// prefix, or an empty string when it is not recognized.
+func HashAlgorithm(encodedHash string) string {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		return HashAlgorithmArgon2id
+	case strings.HasPrefix(encodedHash, "$2a$"),
+		strings.HasPrefix(encodedHash, "$2b$"),
+		strings.HasPrefix(encodedHash, "$2y$"):
+		return HashAlgorithmBcrypt
+	case strings.HasPrefix(encodedHash, "$scrypt$"):
+		return HashAlgorithmScrypt
+	default:
+		return ""
+	}
+}
+
+// VerifyPassword verifies a password against a hash, whichever supported
+// algorithm produced it.
 func VerifyPassword(encodedHash, password string) (bool, error) {
+	if encodedHash == DirectoryPasswordHash {
+		return false, nil
+	}
+
+	switch HashAlgorithm(encodedHash) {
+	case HashAlgorithmArgon2id:
+		return verifyArgon2id(encodedHash, password)
+	case HashAlgorithmBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("failed to verify bcrypt hash: %w", err)
+		}
+
+		return true, nil
+	case HashAlgorithmScrypt:
+		return verifyScrypt(encodedHash, password)
+	}
+
+	if strings.Count(encodedHash, "$") < 2 {
+		return false, ErrInvalidHashFormat
+	}
+
+	return false, ErrUnsupportedHashAlgo
+}
+
+func verifyArgon2id(encodedHash, password string) (bool, error) {
 	// Parse the encoded hash
 	parts := strings.Split(encodedHash, "$")
 
@@ -95,10 +251,6 @@ func VerifyPassword(encodedHash, password string) (bool, error) {
 		return false, ErrInvalidHashFormat
 	}
 
-	if parts[1] != "argon2id" {
-		return false, ErrUnsupportedHashAlgo
-	}
-
 	// Parse parameters
 	var version int
 
@@ -116,15 +268,9 @@ func VerifyPassword(encodedHash, password string) (bool, error) {
 		return false, fmt.Errorf("failed to parse parameters: %w", err)
 	}
 
-	// Decode salt and hash
-	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	salt, expectedHash, err := decodeSaltAndHash(parts[4], parts[5])
 	if err != nil {
-		return false, fmt.Errorf("failed to decode salt: %w", err)
-	}
-
-	expectedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
-	if err != nil {
-		return false, fmt.Errorf("failed to decode hash: %w", err)
+		return false, err
 	}
 
 	// Compute hash with provided password
@@ -138,9 +284,87 @@ func VerifyPassword(encodedHash, password string) (bool, error) {
 	)
 
 	// Constant-time comparison
-	if subtle.ConstantTimeCompare(computedHash, expectedHash) == 1 {
-		return true, nil
+	return subtle.ConstantTimeCompare(computedHash, expectedHash) == 1, nil
+}
+
+func verifyScrypt(encodedHash, password string) (bool, error) {
+	parts := strings.Split(encodedHash, "$")
+
+	const expectedParts = 5
+	if len(parts) != expectedParts {
+		return false, ErrInvalidHashFormat
 	}
 
-	return false, nil
+	var logN uint8
+
+	var r, p int
+
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &logN, &r, &p); err != nil {
+		return false, fmt.Errorf("failed to parse parameters: %w", err)
+	}
+
+	salt, expectedHash, err := decodeSaltAndHash(parts[3], parts[4])
+	if err != nil {
+		return false, err
+	}
+
+	computedHash, err := scrypt.Key([]byte(password), salt, 1<<logN, r, p, len(expectedHash))
+	if err != nil {
+		return false, fmt.Errorf("failed to compute scrypt hash: %w", err)
+	}
+
+	return subtle.ConstantTimeCompare(computedHash, expectedHash) == 1, nil
+}
+
+func decodeSaltAndHash(encodedSalt, encodedHash string) (salt, hash []byte, err error) {
+	salt, err = base64.RawStdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+
+	hash, err = base64.RawStdEncoding.DecodeString(encodedHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode hash: %w", err)
+	}
+
+	return salt, hash, nil
+}
+
+// NeedsRehash reports whether a hash was produced with another algorithm or
+// other parameters than the configured ones, so that it should be replaced
+// the next time its password is verified.
+func NeedsRehash(encodedHash string) bool {
+	return needsRehash(encodedHash, CurrentHashParams())
+}
+
+func needsRehash(encodedHash string, params HashParams) bool {
+	if encodedHash == DirectoryPasswordHash {
+		return false
+	}
+
+	algorithm := params.Algorithm
+	if algorithm == "" {
+		algorithm = HashAlgorithmArgon2id
+	}
+
+	if HashAlgorithm(encodedHash) != algorithm {
+		return true
+	}
+
+	parts := strings.Split(encodedHash, "$")
+
+	switch algorithm {
+	case HashAlgorithmArgon2id:
+		want := fmt.Sprintf("m=%d,t=%d,p=%d", params.MemoryKB, params.Time, params.Threads)
+
+		return len(parts) != 6 || parts[3] != want
+	case HashAlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+
+		return err != nil || cost != params.BcryptCost
+	default: // HashAlgorithmScrypt
+		want := fmt.Sprintf("ln=%d,r=%d,p=%d", params.ScryptLogN, params.ScryptR, params.ScryptP)
+
+		return len(parts) != 5 || parts[2] != want
+	}
 }
@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -59,6 +61,7 @@ func TestVerifyPassword(t *testing.T) {
 		{name: "empty password", hash: hash, password: "", want: false, wantErr: false},
 		{name: "invalid hash format", hash: "invalid", password: password, want: false, wantErr: true},
 		{name: "empty hash", hash: "", password: password, want: false, wantErr: true},
+		{name: "directory user", hash: DirectoryPasswordHash, password: DirectoryPasswordHash, want: false, wantErr: false},
 	}
 
 	for _, tt := range tests {
@@ -108,3 +111,112 @@ func TestHashPasswordUnique(t *testing.T) {
 		t.Error("Second hash should verify against original password")
 	}
 }
+
+// fastHashParams returns cheap parameters of each algorithm for tests.
+func fastHashParams(algorithm string) HashParams {
+	params := DefaultHashParams()
+	params.Algorithm = algorithm
+	params.MemoryKB = 1024
+	params.BcryptCost = 4
+	params.ScryptLogN = 10
+
+	return params
+}
+
+func TestHashPasswordAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		algorithm string
+		prefix    string
+	}{
+		{HashAlgorithmArgon2id, "$argon2id$"},
+		{HashAlgorithmBcrypt, "$2a$04$"},
+		{HashAlgorithmScrypt, "$scrypt$ln=10,r=8,p=1$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			t.Parallel()
+
+			hash, err := HashPasswordWithParams("s3cret", fastHashParams(tt.algorithm))
+			if err != nil {
+				t.Fatalf("HashPasswordWithParams() error = %v", err)
+			}
+
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("hash = %q, want prefix %q", hash, tt.prefix)
+			}
+
+			if got := HashAlgorithm(hash); got != tt.algorithm {
+				t.Errorf("HashAlgorithm() = %q, want %q", got, tt.algorithm)
+			}
+
+			if ok, err := VerifyPassword(hash, "s3cret"); err != nil || !ok {
+				t.Errorf("VerifyPassword(correct) = %v, %v; want true", ok, err)
+			}
+
+			if ok, err := VerifyPassword(hash, "wrong"); err != nil || ok {
+				t.Errorf("VerifyPassword(wrong) = %v, %v; want false", ok, err)
+			}
+		})
+	}
+}
+
+func TestHashPasswordUnsupportedAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	if _, err := HashPasswordWithParams("s3cret", HashParams{Algorithm: "md5"}); !errors.Is(err, ErrUnsupportedHashAlgo) {
+		t.Errorf("error = %v, want ErrUnsupportedHashAlgo", err)
+	}
+
+	if _, err := VerifyPassword("$1$salt$hash", "s3cret"); !errors.Is(err, ErrUnsupportedHashAlgo) {
+		t.Errorf("error = %v, want ErrUnsupportedHashAlgo", err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	t.Parallel()
+
+	hashes := make(map[string]string)
+
+	for _, algorithm := range []string{HashAlgorithmArgon2id, HashAlgorithmBcrypt, HashAlgorithmScrypt} {
+		hash, err := HashPasswordWithParams("s3cret", fastHashParams(algorithm))
+		if err != nil {
+			t.Fatalf("HashPasswordWithParams(%s) error = %v", algorithm, err)
+		}
+
+		hashes[algorithm] = hash
+	}
+
+	costlier := fastHashParams(HashAlgorithmBcrypt)
+	costlier.BcryptCost = 5
+
+	tests := []struct {
+		name   string
+		hash   string
+		params HashParams
+		want   bool
+	}{
+		{"same argon2id parameters", hashes[HashAlgorithmArgon2id], fastHashParams(HashAlgorithmArgon2id), false},
+		{"empty algorithm is argon2id", hashes[HashAlgorithmArgon2id], fastHashParams(""), false},
+		{"other argon2id parameters", hashes[HashAlgorithmArgon2id], DefaultHashParams(), true},
+		{"argon2id to bcrypt", hashes[HashAlgorithmArgon2id], fastHashParams(HashAlgorithmBcrypt), true},
+		{"same bcrypt cost", hashes[HashAlgorithmBcrypt], fastHashParams(HashAlgorithmBcrypt), false},
+		{"other bcrypt cost", hashes[HashAlgorithmBcrypt], costlier, true},
+		{"same scrypt parameters", hashes[HashAlgorithmScrypt], fastHashParams(HashAlgorithmScrypt), false},
+		{"scrypt to argon2id", hashes[HashAlgorithmScrypt], fastHashParams(HashAlgorithmArgon2id), true},
+		{"unrecognized hash", "invalid", fastHashParams(HashAlgorithmArgon2id), true},
+		{"directory user", DirectoryPasswordHash, fastHashParams(HashAlgorithmArgon2id), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := needsRehash(tt.hash, tt.params); got != tt.want {
+				t.Errorf("needsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportSignaturePrefix names the algorithm in report signatures.
+const reportSignaturePrefix = "hmac-sha256="
+
+// signingKey derives a signing key for purpose from the encryption key, so
+// signatures never reuse the key that encrypts credentials, nor each other's.
+func signingKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("dbbat:" + purpose))
+
+	return mac.Sum(nil)
+}
+
+// reportSigningKey derives the report signing key from the encryption key.
+func reportSigningKey(key []byte) []byte {
+	return signingKey(key, "report-signing")
+}
+
+// SignReport returns the signature of a report body, as
+// "hmac-sha256=<hex>". Only a holder of the encryption key can produce or
+// check it.
+func SignReport(key, body []byte) string {
+	mac := hmac.New(sha256.New, reportSigningKey(key))
+	mac.Write(body)
+
+	return reportSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyReport reports whether signature is SignReport's signature of body.
+func VerifyReport(key, body []byte, signature string) bool {
+	sum, ok := strings.CutPrefix(signature, reportSignaturePrefix)
+	if !ok {
+		return false
+	}
+
+	got, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, reportSigningKey(key))
+	mac.Write(body)
+
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// downloadSignature returns the signature of a download of resource
+// valid until expires.
+func downloadSignature(key []byte, resource string, expires time.Time) []byte {
+	mac := hmac.New(sha256.New, signingKey(key, "download-signing"))
+	mac.Write([]byte(resource + "\n" + strconv.FormatInt(expires.Unix(), 10)))
+
+	return mac.Sum(nil)
+}
+
+// SignDownload returns the hex signature authorizing anyone to download
+// resource until expires, for download URLs handed out by the API.
+func SignDownload(key []byte, resource string, expires time.Time) string {
+	return hex.EncodeToString(downloadSignature(key, resource, expires))
+}
+
+// VerifyDownload reports whether signature is SignDownload's signature of
+// resource and expires, and expires is not past at now.
+func VerifyDownload(key []byte, resource string, expires, now time.Time, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil || now.After(expires) {
+		return false
+	}
+
+	return hmac.Equal(got, downloadSignature(key, resource, expires))
+}
@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignReport(t *testing.T) {
+	t.Parallel()
+
+	key := generateKey()
+	body := []byte(`{"entries":[]}`)
+
+	signature := SignReport(key, body)
+	if !strings.HasPrefix(signature, "hmac-sha256=") {
+		t.Fatalf("SignReport() = %q, want an hmac-sha256= prefix", signature)
+	}
+
+	if !VerifyReport(key, body, signature) {
+		t.Error("VerifyReport() rejected its own signature")
+	}
+
+	if VerifyReport(key, []byte(`{"entries":[{}]}`), signature) {
+		t.Error("VerifyReport() accepted a tampered body")
+	}
+
+	if VerifyReport(generateKey(), body, signature) {
+		t.Error("VerifyReport() accepted another key's signature")
+	}
+
+	for _, bad := range []string{"", "sha256=00", "hmac-sha256=zz"} {
+		if VerifyReport(key, body, bad) {
+			t.Errorf("VerifyReport() accepted %q", bad)
+		}
+	}
+}
+
+func TestSignDownload(t *testing.T) {
+	t.Parallel()
+
+	key := generateKey()
+	now := time.Unix(1_760_000_000, 0)
+	expires := now.Add(15 * time.Minute)
+
+	signature := SignDownload(key, "query-result:1", expires)
+
+	if !VerifyDownload(key, "query-result:1", expires, now, signature) {
+		t.Error("VerifyDownload() rejected its own signature")
+	}
+
+	if VerifyDownload(key, "query-result:1", expires, expires.Add(time.Second), signature) {
+		t.Error("VerifyDownload() accepted an expired signature")
+	}
+
+	if VerifyDownload(key, "query-result:2", expires, now, signature) {
+		t.Error("VerifyDownload() accepted another resource")
+	}
+
+	if VerifyDownload(key, "query-result:1", expires.Add(time.Hour), now, signature) {
+		t.Error("VerifyDownload() accepted a pushed back expiry")
+	}
+
+	if VerifyDownload(generateKey(), "query-result:1", expires, now, signature) {
+		t.Error("VerifyDownload() accepted another key's signature")
+	}
+
+	if VerifyDownload(key, "query-result:1", expires, now, "zz") {
+		t.Error("VerifyDownload() accepted a malformed signature")
+	}
+}
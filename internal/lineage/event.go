@@ -0,0 +1,248 @@
+// Package lineage exports the tables read and written by proxied statements
+// as OpenLineage run events, so jobs going through dbbat appear in lineage
+// tools such as Marquez or DataHub.
+package lineage
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/store"
+	"github.com/fclairamb/dbbat/internal/version"
+)
+
+// OpenLineage spec and facet schemas the events conform to.
+const (
+	runEventSchemaURL    = "https://openlineage.io/spec/2-0-2/OpenLineage.json#/$defs/RunEvent"
+	sqlFacetSchemaURL    = "https://openlineage.io/spec/facets/1-1-0/SQLJobFacet.json#/$defs/SQLJobFacet"
+	jobTypeSchemaURL     = "https://openlineage.io/spec/facets/2-0-3/JobTypeJobFacet.json#/$defs/JobTypeJobFacet"
+	errorFacetSchemaURL  = "https://openlineage.io/spec/facets/1-0-1/ErrorMessageRunFacet.json#/$defs/ErrorMessageRunFacet"
+	outputStatsSchemaURL = "https://openlineage.io/spec/facets/1-0-2/OutputStatisticsOutputDatasetFacet.json#/$defs/OutputStatisticsOutputDatasetFacet"
+)
+
+// Event types used by dbbat: a logged query is a finished run.
+const (
+	EventTypeComplete = "COMPLETE"
+	EventTypeFail     = "FAIL"
+)
+
+// RunEvent is an OpenLineage run event.
+type RunEvent struct {
+	EventType string    `json:"eventType"` //nolint:tagliatelle // OpenLineage spec field name
+	EventTime time.Time `json:"eventTime"` //nolint:tagliatelle // OpenLineage spec field name
+	Run       Run       `json:"run"`
+	Job       Job       `json:"job"`
+	Inputs    []Dataset `json:"inputs"`
+	Outputs   []Dataset `json:"outputs"`
+	Producer  string    `json:"producer"`
+	SchemaURL string    `json:"schemaURL"` //nolint:tagliatelle // OpenLineage spec field name
+}
+
+// Run identifies one execution; dbbat uses the query UID (a UUIDv7, as the
+// spec recommends).
+type Run struct {
+	RunID  uuid.UUID      `json:"runId"` //nolint:tagliatelle // OpenLineage spec field name
+	Facets map[string]any `json:"facets,omitempty"`
+}
+
+// Job is the recurring process the run belongs to.
+type Job struct {
+	Namespace string         `json:"namespace"`
+	Name      string         `json:"name"`
+	Facets    map[string]any `json:"facets,omitempty"`
+}
+
+// Dataset is a table read or written by the run.
+type Dataset struct {
+	Namespace    string         `json:"namespace"`
+	Name         string         `json:"name"`
+	OutputFacets map[string]any `json:"outputFacets,omitempty"` //nolint:tagliatelle // OpenLineage spec field name
+}
+
+// facet is the envelope shared by all OpenLineage facets.
+type facet struct {
+	Producer  string `json:"_producer"`  //nolint:tagliatelle // OpenLineage spec field name
+	SchemaURL string `json:"_schemaURL"` //nolint:tagliatelle // OpenLineage spec field name
+}
+
+// producer identifies the dbbat build emitting the events.
+func producer() string {
+	return "https://github.com/fclairamb/dbbat/tree/" + version.Version
+}
+
+// querySource is what an event needs to know about where a query ran.
+type querySource struct {
+	Server          *store.Server
+	Username        string
+	ApplicationName string
+	SourceIP        string
+}
+
+// dialect returns the ExtractTables dialect of a server protocol, or "" for
+// protocols whose statements are not SQL.
+func dialect(protocol string) string {
+	switch protocol {
+	case store.ProtocolPostgreSQL:
+		return DialectPostgreSQL
+	case store.ProtocolMySQL, store.ProtocolMariaDB:
+		return DialectMySQL
+	case store.ProtocolOracle:
+		return DialectOracle
+	default:
+		return ""
+	}
+}
+
+// datasetNamespace follows the OpenLineage dataset naming spec:
+// postgres://host:port, mysql://host:port, oracle://host:port.
+func datasetNamespace(srv *store.Server) string {
+	scheme := "postgres"
+
+	switch srv.Protocol {
+	case store.ProtocolMySQL, store.ProtocolMariaDB:
+		scheme = "mysql"
+	case store.ProtocolOracle:
+		scheme = "oracle"
+	}
+
+	return scheme + "://" + net.JoinHostPort(srv.Host, strconv.Itoa(srv.Port))
+}
+
+// datasetName fully qualifies a table as the naming spec expects:
+// database.schema.table for PostgreSQL, database.table for MySQL and
+// service.schema.table for Oracle. Unqualified tables are assumed to live in
+// the default schema (public, or the upstream user's schema on Oracle).
+func datasetName(srv *store.Server, table string) string {
+	parts := strings.Split(table, ".")
+
+	switch srv.Protocol {
+	case store.ProtocolMySQL, store.ProtocolMariaDB:
+		if len(parts) == 1 {
+			return srv.DatabaseName + "." + table
+		}
+	case store.ProtocolOracle:
+		service := srv.DatabaseName
+		if srv.OracleServiceName != nil && *srv.OracleServiceName != "" {
+			service = *srv.OracleServiceName
+		}
+
+		switch len(parts) {
+		case 1:
+			return service + "." + strings.ToUpper(srv.Username) + "." + table
+		case 2:
+			return service + "." + table
+		}
+	default:
+		switch len(parts) {
+		case 1:
+			return srv.DatabaseName + ".public." + table
+		case 2:
+			return srv.DatabaseName + "." + table
+		}
+	}
+
+	return table
+}
+
+// jobName groups the runs of one client: the dbbat user, then the
+// application name the client declared, if any.
+func jobName(src querySource) string {
+	if src.ApplicationName == "" {
+		return src.Username
+	}
+
+	return src.Username + "/" + src.ApplicationName
+}
+
+// buildEvent turns a logged query into a run event, or returns nil when the
+// statement touches no identifiable table.
+func buildEvent(namespace string, query *store.Query, src querySource) *RunEvent {
+	d := dialect(src.Server.Protocol)
+	if d == "" {
+		return nil
+	}
+
+	tables := ExtractTables(query.SQLText, d)
+	if tables.Empty() {
+		return nil
+	}
+
+	prod := producer()
+
+	event := &RunEvent{
+		EventType: EventTypeComplete,
+		EventTime: query.ExecutedAt,
+		Run: Run{
+			RunID: query.UID,
+			Facets: map[string]any{
+				"dbbat": map[string]any{
+					"_producer":     prod,
+					"_schemaURL":    prod,
+					"connection_id": query.ConnectionID,
+					"user":          src.Username,
+					"database":      src.Server.Name,
+					"source_ip":     src.SourceIP,
+				},
+			},
+		},
+		Job: Job{
+			Namespace: namespace,
+			Name:      jobName(src),
+			Facets: map[string]any{
+				"sql": struct {
+					facet
+					Query string `json:"query"`
+				}{facet{prod, sqlFacetSchemaURL}, query.SQLText},
+				"jobType": struct {
+					facet
+					ProcessingType string `json:"processingType"` //nolint:tagliatelle // OpenLineage spec field name
+					Integration    string `json:"integration"`
+					JobType        string `json:"jobType"` //nolint:tagliatelle // OpenLineage spec field name
+				}{facet{prod, jobTypeSchemaURL}, "BATCH", "DBBAT", "QUERY"},
+			},
+		},
+		Inputs:    []Dataset{},
+		Outputs:   []Dataset{},
+		Producer:  prod,
+		SchemaURL: runEventSchemaURL,
+	}
+
+	if query.DurationMs != nil {
+		event.EventTime = query.ExecutedAt.Add(time.Duration(*query.DurationMs * float64(time.Millisecond)))
+	}
+
+	if query.Error != nil {
+		event.EventType = EventTypeFail
+		event.Run.Facets["errorMessage"] = struct {
+			facet
+			Message             string `json:"message"`
+			ProgrammingLanguage string `json:"programmingLanguage"` //nolint:tagliatelle // OpenLineage spec field name
+		}{facet{prod, errorFacetSchemaURL}, *query.Error, "SQL"}
+	}
+
+	ns := datasetNamespace(src.Server)
+
+	for _, table := range tables.Inputs {
+		event.Inputs = append(event.Inputs, Dataset{Namespace: ns, Name: datasetName(src.Server, table)})
+	}
+
+	for _, table := range tables.Outputs {
+		event.Outputs = append(event.Outputs, Dataset{Namespace: ns, Name: datasetName(src.Server, table)})
+	}
+
+	// The affected row count only belongs to a single written table.
+	if len(event.Outputs) == 1 && query.RowsAffected != nil && query.Error == nil {
+		event.Outputs[0].OutputFacets = map[string]any{
+			"outputStatistics": struct {
+				facet
+				RowCount int64 `json:"rowCount"` //nolint:tagliatelle // OpenLineage spec field name
+			}{facet{prod, outputStatsSchemaURL}, *query.RowsAffected},
+		}
+	}
+
+	return event
+}
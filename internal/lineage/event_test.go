@@ -0,0 +1,180 @@
+package lineage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func testSource() querySource {
+	return querySource{
+		Server: &store.Server{
+			Name:         "warehouse",
+			Host:         "db.internal",
+			Port:         5432,
+			DatabaseName: "analytics",
+			Protocol:     store.ProtocolPostgreSQL,
+		},
+		Username:        "etl",
+		ApplicationName: "nightly-load",
+	}
+}
+
+func TestBuildEvent(t *testing.T) {
+	t.Parallel()
+
+	duration := 1500.0
+	rows := int64(42)
+	query := &store.Query{
+		UID:          uuid.New(),
+		ConnectionID: uuid.New(),
+		SQLText:      "INSERT INTO mart.daily SELECT * FROM events",
+		ExecutedAt:   time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC),
+		DurationMs:   &duration,
+		RowsAffected: &rows,
+	}
+
+	event := buildEvent("dbbat", query, testSource())
+	if event == nil {
+		t.Fatal("buildEvent() = nil")
+	}
+
+	if event.EventType != EventTypeComplete || event.Run.RunID != query.UID {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	if want := query.ExecutedAt.Add(1500 * time.Millisecond); !event.EventTime.Equal(want) {
+		t.Errorf("EventTime = %v, want %v", event.EventTime, want)
+	}
+
+	if event.Job.Namespace != "dbbat" || event.Job.Name != "etl/nightly-load" {
+		t.Errorf("Job = %+v", event.Job)
+	}
+
+	if len(event.Inputs) != 1 || event.Inputs[0].Namespace != "postgres://db.internal:5432" ||
+		event.Inputs[0].Name != "analytics.public.events" {
+		t.Errorf("Inputs = %+v", event.Inputs)
+	}
+
+	if len(event.Outputs) != 1 || event.Outputs[0].Name != "analytics.mart.daily" ||
+		event.Outputs[0].OutputFacets["outputStatistics"] == nil {
+		t.Errorf("Outputs = %+v", event.Outputs)
+	}
+
+	failed := *query
+	msg := "permission denied"
+	failed.Error = &msg
+
+	if event := buildEvent("dbbat", &failed, testSource()); event.EventType != EventTypeFail ||
+		event.Run.Facets["errorMessage"] == nil || event.Outputs[0].OutputFacets != nil {
+		t.Errorf("failed query event = %+v", event)
+	}
+
+	noTables := *query
+	noTables.SQLText = "SELECT 1"
+
+	if event := buildEvent("dbbat", &noTables, testSource()); event != nil {
+		t.Errorf("buildEvent(no tables) = %+v, want nil", event)
+	}
+}
+
+func TestDatasetName(t *testing.T) {
+	t.Parallel()
+
+	service := "ORCLPDB1"
+
+	tests := []struct {
+		server store.Server
+		table  string
+		want   string
+	}{
+		{store.Server{Protocol: store.ProtocolPostgreSQL, DatabaseName: "app"}, "users", "app.public.users"},
+		{store.Server{Protocol: store.ProtocolPostgreSQL, DatabaseName: "app"}, "auth.users", "app.auth.users"},
+		{store.Server{Protocol: store.ProtocolPostgreSQL, DatabaseName: "app"}, "other.auth.users", "other.auth.users"},
+		{store.Server{Protocol: store.ProtocolMySQL, DatabaseName: "shop"}, "orders", "shop.orders"},
+		{store.Server{Protocol: store.ProtocolMySQL, DatabaseName: "shop"}, "crm.leads", "crm.leads"},
+		{store.Server{Protocol: store.ProtocolOracle, Username: "scott", OracleServiceName: &service}, "EMP", "ORCLPDB1.SCOTT.EMP"},
+		{store.Server{Protocol: store.ProtocolOracle, Username: "scott", OracleServiceName: &service}, "HR.EMP", "ORCLPDB1.HR.EMP"},
+	}
+
+	for _, tt := range tests {
+		if got := datasetName(&tt.server, tt.table); got != tt.want {
+			t.Errorf("datasetName(%s, %q) = %q, want %q", tt.server.Protocol, tt.table, got, tt.want)
+		}
+	}
+}
+
+func TestExporterSendsEvents(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan RunEvent, 2)
+
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		var event RunEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		received <- event
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer endpoint.Close()
+
+	exporter := NewExporter(nil, config.LineageConfig{
+		URL:       endpoint.URL,
+		APIKey:    "secret",
+		Namespace: "dbbat",
+	}, slog.New(slog.DiscardHandler))
+
+	// Pre-resolve the connection so the exporter needs no store.
+	connectionID := uuid.New()
+	src := testSource()
+	exporter.sources[connectionID] = &src
+
+	exporter.Start()
+
+	exporter.QueryLogged(&store.Query{UID: uuid.New(), ConnectionID: connectionID, SQLText: "SELECT 1"})
+	exporter.QueryLogged(&store.Query{UID: uuid.New(), ConnectionID: connectionID, SQLText: "SELECT * FROM users"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	// Only the statement reading a table produced an event.
+	select {
+	case event := <-received:
+		if len(event.Inputs) != 1 || event.Inputs[0].Name != "analytics.public.users" {
+			t.Errorf("received event inputs = %+v", event.Inputs)
+		}
+	default:
+		t.Fatal("no event received")
+	}
+
+	select {
+	case event := <-received:
+		t.Errorf("unexpected extra event: %+v", event)
+	default:
+	}
+}
@@ -0,0 +1,220 @@
+package lineage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+const (
+	// queueSize bounds the queries waiting to be exported; beyond it new
+	// ones are dropped rather than slowing down the proxies.
+	queueSize = 1000
+	// sourceCacheSize bounds the per-connection lookups kept by the worker.
+	sourceCacheSize = 1024
+	// sendAttempts and sendBackoff retry transient endpoint failures.
+	sendAttempts = 3
+	sendBackoff  = time.Second
+	sendTimeout  = 10 * time.Second
+)
+
+// ErrEndpointStatus is returned when the lineage endpoint rejects an event.
+var ErrEndpointStatus = errors.New("lineage endpoint returned an error status")
+
+// Exporter sends an OpenLineage event for each logged query that reads or
+// writes identifiable tables. It is a store.QueryObserver: queries are queued
+// without blocking the proxy and sent by a single background worker.
+type Exporter struct {
+	store  *store.Store
+	cfg    config.LineageConfig
+	client *http.Client
+	logger *slog.Logger
+
+	queue    chan store.Query
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	// sources caches the server and user of recent connections; only the
+	// worker goroutine touches it.
+	sources map[uuid.UUID]*querySource
+}
+
+// NewExporter creates an exporter; Start begins sending.
+func NewExporter(dataStore *store.Store, cfg config.LineageConfig, logger *slog.Logger) *Exporter {
+	return &Exporter{
+		store:   dataStore,
+		cfg:     cfg,
+		client:  &http.Client{Timeout: sendTimeout},
+		logger:  logger.With(slog.String("component", "lineage")),
+		queue:   make(chan store.Query, queueSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		sources: make(map[uuid.UUID]*querySource),
+	}
+}
+
+// Start runs the export worker until Shutdown.
+func (e *Exporter) Start() {
+	go e.run()
+}
+
+// QueryLogged implements store.QueryObserver.
+func (e *Exporter) QueryLogged(query *store.Query) {
+	select {
+	case <-e.stop:
+	case e.queue <- *query:
+	default:
+		e.logger.WarnContext(context.Background(), "lineage queue full, dropping event",
+			slog.String("query_uid", query.UID.String()))
+	}
+}
+
+// Shutdown stops accepting queries and waits for the queued ones to be sent.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stop) })
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("lineage export interrupted: %w", ctx.Err())
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ctx := context.Background()
+
+	for {
+		select {
+		case query := <-e.queue:
+			e.export(ctx, &query)
+		case <-e.stop:
+			for {
+				select {
+				case query := <-e.queue:
+					e.export(ctx, &query)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// export builds and sends the event of one query. Failures are logged: a
+// lost lineage event must never affect the proxied session.
+func (e *Exporter) export(ctx context.Context, query *store.Query) {
+	src, err := e.source(ctx, query.ConnectionID)
+	if err != nil {
+		e.logger.WarnContext(ctx, "failed to resolve lineage source",
+			slog.String("query_uid", query.UID.String()), slog.Any("error", err))
+
+		return
+	}
+
+	event := buildEvent(e.cfg.Namespace, query, *src)
+	if event == nil {
+		return
+	}
+
+	if err := e.send(ctx, event); err != nil {
+		e.logger.WarnContext(ctx, "failed to send lineage event",
+			slog.String("query_uid", query.UID.String()), slog.Any("error", err))
+	}
+}
+
+// source returns the server and user behind a connection.
+func (e *Exporter) source(ctx context.Context, connectionID uuid.UUID) (*querySource, error) {
+	if src, ok := e.sources[connectionID]; ok {
+		return src, nil
+	}
+
+	conn, err := e.store.GetConnectionByUID(ctx, connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	srv, err := e.store.GetServerByUID(ctx, conn.DatabaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server: %w", err)
+	}
+
+	user, err := e.store.GetUserByUID(ctx, conn.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	src := &querySource{Server: srv, Username: user.Username, SourceIP: conn.SourceIP}
+	if conn.ClientInfo != nil {
+		src.ApplicationName = conn.ClientInfo.ApplicationName
+	}
+
+	if len(e.sources) >= sourceCacheSize {
+		clear(e.sources)
+	}
+
+	e.sources[connectionID] = src
+
+	return src, nil
+}
+
+// send POSTs an event, retrying network errors and 5xx responses.
+func (e *Exporter) send(ctx context.Context, event *RunEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		retry, err := e.post(ctx, body)
+		if err == nil || !retry || attempt == sendAttempts {
+			return err
+		}
+
+		time.Sleep(sendBackoff * time.Duration(attempt))
+	}
+}
+
+// post sends one request, reporting whether a failure is worth retrying.
+func (e *Exporter) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if e.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.APIKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to post event: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("%w: %s", ErrEndpointStatus, resp.Status)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,556 @@
+package lineage
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Tables are the tables a statement reads (Inputs) and writes (Outputs), as
+// dotted names with the dialect's identifier case applied (PostgreSQL folds
+// unquoted names to lower case, Oracle to upper case).
+type Tables struct {
+	Inputs  []string
+	Outputs []string
+}
+
+// Empty reports whether no table was identified.
+func (t Tables) Empty() bool {
+	return len(t.Inputs) == 0 && len(t.Outputs) == 0
+}
+
+// Dialects accepted by ExtractTables.
+const (
+	DialectPostgreSQL = "postgresql"
+	DialectMySQL      = "mysql"
+	DialectOracle     = "oracle"
+)
+
+// ExtractTables finds the tables referenced by a SQL statement. It is a
+// keyword scanner, not a parser: it recognizes the table positions of SELECT,
+// TABLE, INSERT, UPDATE, DELETE, MERGE, CREATE TABLE, TRUNCATE and COPY, skips CTE
+// names, subqueries and table functions, and ignores system catalogs so
+// driver introspection does not show up as lineage. Statements it cannot make
+// sense of yield no tables rather than wrong ones.
+func ExtractTables(sql, dialect string) Tables {
+	s := &scanner{tokens: tokenize(sql, dialect), dialect: dialect, ctes: map[string]bool{}}
+	s.scan()
+
+	return Tables{Inputs: s.inputs, Outputs: s.outputs}
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokQuoted
+	tokPunct
+	tokOther
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// keyword reports whether the token is the given (upper-case) keyword.
+func (t token) keyword(kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+// tokenize splits SQL into words, quoted identifiers and punctuation,
+// dropping comments, string literals and numbers.
+func tokenize(sql, dialect string) []token {
+	var tokens []token
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"), c == '#' && dialect == DialectMySQL:
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+
+			i += end + 1
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+
+			i += end + 4
+		case c == '\'' || (c == '"' && dialect == DialectMySQL):
+			i = skipQuoted(sql, i, c)
+			tokens = append(tokens, token{kind: tokOther})
+		case c == '"' || c == '`':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				return tokens
+			}
+
+			tokens = append(tokens, token{kind: tokQuoted, text: sql[i+1 : i+1+end]})
+			i += end + 2
+		case c == '$' && dialect == DialectPostgreSQL:
+			// Dollar-quoted string: $tag$ ... $tag$. A bare $1 is a parameter.
+			tagEnd := strings.IndexByte(sql[i+1:], '$')
+			if tagEnd < 0 || !isIdentifier(sql[i+1:i+1+tagEnd]) {
+				i++
+
+				for i < len(sql) && sql[i] >= '0' && sql[i] <= '9' {
+					i++
+				}
+
+				tokens = append(tokens, token{kind: tokOther})
+
+				continue
+			}
+
+			tag := sql[i : i+tagEnd+2]
+
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				return tokens
+			}
+
+			i += len(tag) + end + len(tag)
+			tokens = append(tokens, token{kind: tokOther})
+		case isWordStart(c):
+			start := i
+			for i < len(sql) && isWordPart(sql[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokWord, text: sql[start:i]})
+		case c == '.' || c == ',' || c == '(' || c == ')' || c == ';':
+			tokens = append(tokens, token{kind: tokPunct, text: string(c)})
+			i++
+		case unicode.IsSpace(rune(c)):
+			i++
+		default:
+			// Numbers, operators, parameters: only their presence matters.
+			start := i
+			for i < len(sql) && !isWordStart(sql[i]) && !strings.ContainsRune(".,();'\"`$ \t\r\n", rune(sql[i])) {
+				i++
+			}
+
+			if i == start {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokOther})
+		}
+	}
+
+	return tokens
+}
+
+// skipQuoted returns the index after the string literal starting at i,
+// honoring doubled quotes and backslash escapes.
+func skipQuoted(sql string, i int, quote byte) int {
+	for i++; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+
+				continue
+			}
+
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || c >= '0' && c <= '9' || c == '$' || c == '#'
+}
+
+func isIdentifier(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isWordPart(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scanner walks the tokens of one or more statements.
+type scanner struct {
+	tokens  []token
+	pos     int
+	dialect string
+	ctes    map[string]bool
+	inputs  []string
+	outputs []string
+}
+
+func (s *scanner) peek(offset int) token {
+	if s.pos+offset < len(s.tokens) {
+		return s.tokens[s.pos+offset]
+	}
+
+	return token{kind: tokOther}
+}
+
+func (s *scanner) scan() {
+	// subquery tracks, for each open parenthesis, whether it holds a query.
+	// A FROM in any other group belongs to a function (EXTRACT(x FROM y),
+	// SUBSTRING(x FROM 1)).
+	var subquery []bool
+
+	for s.pos < len(s.tokens) {
+		tok := s.tokens[s.pos]
+		s.pos++
+
+		switch {
+		case tok.kind == tokPunct && tok.text == "(":
+			subquery = append(subquery, s.peek(0).keyword("SELECT") || s.peek(0).keyword("WITH") || s.peek(0).keyword("TABLE"))
+		case tok.kind == tokPunct && tok.text == ")":
+			if len(subquery) > 0 {
+				subquery = subquery[:len(subquery)-1]
+			}
+		case tok.kind == tokPunct && tok.text == ";":
+			subquery = subquery[:0]
+		case tok.keyword("WITH"):
+			s.collectCTEs()
+		case tok.keyword("FROM"):
+			if (len(subquery) > 0 && !subquery[len(subquery)-1]) || s.previous(2).keyword("DISTINCT") {
+				continue
+			}
+
+			s.tableList(&s.inputs)
+		case tok.keyword("JOIN"), tok.keyword("USING") && s.inMerge():
+			s.tableList(&s.inputs)
+		case tok.keyword("INTO"):
+			// INSERT INTO, MERGE INTO, REPLACE INTO.
+			s.table(&s.outputs)
+		case tok.keyword("UPDATE"):
+			// Not SELECT ... FOR UPDATE nor ON CONFLICT DO UPDATE.
+			if prev := s.previous(2); !prev.keyword("FOR") && !prev.keyword("DO") && !prev.keyword("KEY") {
+				s.table(&s.outputs)
+			}
+		case tok.keyword("DELETE"):
+			if s.peek(0).keyword("FROM") {
+				s.pos++
+			}
+
+			s.table(&s.outputs)
+		case tok.keyword("TRUNCATE"):
+			if s.peek(0).keyword("TABLE") {
+				s.pos++
+			}
+
+			s.tableList(&s.outputs)
+		case tok.keyword("CREATE"):
+			s.createTable()
+		case tok.keyword("COPY"):
+			s.copy()
+		case tok.keyword("TABLE") && s.startsQuery():
+			// TABLE name, shorthand for SELECT * FROM name.
+			s.table(&s.inputs)
+		}
+	}
+}
+
+// previous returns the token n positions before the current one (1 is the
+// token just consumed).
+func (s *scanner) previous(n int) token {
+	if s.pos-n >= 0 && s.pos-n < len(s.tokens) {
+		return s.tokens[s.pos-n]
+	}
+
+	return token{kind: tokOther}
+}
+
+// startsQuery reports whether the token just consumed starts a query: the
+// first of a statement or subquery, or the right side of a set operation.
+func (s *scanner) startsQuery() bool {
+	if s.pos < 2 {
+		return true
+	}
+
+	prev := s.previous(2)
+
+	return prev.text == ";" || prev.text == "(" || prev.keyword("UNION") || prev.keyword("INTERSECT") ||
+		prev.keyword("EXCEPT") || prev.keyword("ALL") || prev.keyword("DISTINCT")
+}
+
+// inMerge reports whether the current statement is a MERGE.
+func (s *scanner) inMerge() bool {
+	for i := s.pos - 1; i >= 0; i-- {
+		if s.tokens[i].kind == tokPunct && s.tokens[i].text == ";" {
+			return false
+		}
+
+		if s.tokens[i].keyword("MERGE") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectCTEs records the names of WITH [RECURSIVE] name [(cols)] AS (...),
+// so references to them are not taken for tables. It only looks ahead: the
+// CTE bodies are scanned like the rest of the statement.
+func (s *scanner) collectCTEs() {
+	start := s.pos
+	defer func() { s.pos = start }()
+
+	if s.peek(0).keyword("RECURSIVE") {
+		s.pos++
+	}
+
+	for {
+		name := s.peek(0)
+		if name.kind != tokWord && name.kind != tokQuoted {
+			return
+		}
+
+		s.ctes[s.normalize(name)] = true
+		s.pos++
+
+		if s.peek(0).text == "(" {
+			s.skipParens()
+		}
+
+		if !s.peek(0).keyword("AS") {
+			return
+		}
+
+		s.pos++
+
+		for s.peek(0).keyword("NOT") || s.peek(0).keyword("MATERIALIZED") {
+			s.pos++
+		}
+
+		if s.peek(0).text != "(" {
+			return
+		}
+
+		s.skipParens()
+
+		if s.peek(0).text != "," {
+			return
+		}
+
+		s.pos++
+	}
+}
+
+// skipParens steps over a parenthesized group starting at the current token.
+func (s *scanner) skipParens() {
+	depth := 0
+
+	for s.pos < len(s.tokens) {
+		tok := s.tokens[s.pos]
+		s.pos++
+
+		if tok.kind != tokPunct {
+			continue
+		}
+
+		switch tok.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// tableList reads comma-separated table references with optional aliases,
+// as in FROM a, b AS x, c y.
+func (s *scanner) tableList(into *[]string) {
+	for {
+		if s.peek(0).keyword("ONLY") || s.peek(0).keyword("LATERAL") {
+			s.pos++
+		}
+
+		parts := s.name()
+		if parts == nil {
+			return
+		}
+
+		// name(...) is a table function, not a table.
+		if s.peek(0).text == "(" {
+			s.skipParens()
+		} else {
+			s.record(into, parts)
+		}
+
+		// Optional alias.
+		if s.peek(0).keyword("AS") {
+			s.pos += 2
+		} else if next := s.peek(0); next.kind == tokQuoted || (next.kind == tokWord && !isClauseKeyword(next.text)) {
+			s.pos++
+		}
+
+		if s.peek(0).text != "," {
+			return
+		}
+
+		s.pos++
+	}
+}
+
+// table reads one table name and records it.
+func (s *scanner) table(into *[]string) {
+	s.record(into, s.name())
+}
+
+// name reads a possibly qualified name, returning its parts or nil when the
+// current token does not start one.
+func (s *scanner) name() []string {
+	var parts []string
+
+	for {
+		tok := s.peek(0)
+		if tok.kind != tokWord && tok.kind != tokQuoted {
+			break
+		}
+
+		if tok.kind == tokWord && len(parts) == 0 && isClauseKeyword(tok.text) {
+			break
+		}
+
+		parts = append(parts, s.normalize(tok))
+		s.pos++
+
+		if s.peek(0).text != "." {
+			break
+		}
+
+		s.pos++
+	}
+
+	return parts
+}
+
+// record adds a table unless it is a CTE or a system catalog.
+func (s *scanner) record(into *[]string, parts []string) {
+	if len(parts) == 0 {
+		return
+	}
+
+	name := strings.Join(parts, ".")
+	if (len(parts) == 1 && s.ctes[name]) || isSystemTable(parts, s.dialect) {
+		return
+	}
+
+	if !slices.Contains(*into, name) {
+		*into = append(*into, name)
+	}
+}
+
+// createTable handles CREATE [TEMP] TABLE [IF NOT EXISTS] name; the source of
+// a CREATE TABLE ... AS SELECT is picked up by the FROM that follows.
+func (s *scanner) createTable() {
+	for s.peek(0).keyword("OR") || s.peek(0).keyword("REPLACE") || s.peek(0).keyword("GLOBAL") ||
+		s.peek(0).keyword("LOCAL") || s.peek(0).keyword("TEMP") || s.peek(0).keyword("TEMPORARY") ||
+		s.peek(0).keyword("UNLOGGED") {
+		s.pos++
+	}
+
+	if !s.peek(0).keyword("TABLE") {
+		return
+	}
+
+	s.pos++
+
+	if s.peek(0).keyword("IF") && s.peek(1).keyword("NOT") && s.peek(2).keyword("EXISTS") {
+		s.pos += 3
+	}
+
+	s.table(&s.outputs)
+}
+
+// copy handles PostgreSQL COPY name [(cols)] FROM|TO: COPY FROM loads the
+// table, COPY TO reads it. COPY (query) TO is scanned as a query.
+func (s *scanner) copy() {
+	parts := s.name()
+	if parts == nil {
+		return
+	}
+
+	if s.peek(0).text == "(" {
+		s.skipParens()
+	}
+
+	if s.peek(0).keyword("FROM") {
+		s.record(&s.outputs, parts)
+	} else {
+		s.record(&s.inputs, parts)
+	}
+
+	// Step over the FROM/TO target (a file, STDIN or STDOUT).
+	s.pos += 2
+}
+
+// normalize applies the dialect's identifier case folding.
+func (s *scanner) normalize(tok token) string {
+	if tok.kind == tokQuoted {
+		return tok.text
+	}
+
+	switch s.dialect {
+	case DialectPostgreSQL:
+		return strings.ToLower(tok.text)
+	case DialectOracle:
+		return strings.ToUpper(tok.text)
+	default:
+		return tok.text
+	}
+}
+
+// clauseKeywords end a table reference: they cannot be table names or
+// aliases where this scanner looks for them.
+var clauseKeywords = map[string]bool{
+	"WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true, "LIMIT": true, "OFFSET": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "MINUS": true, "JOIN": true, "INNER": true,
+	"LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true, "NATURAL": true, "OUTER": true,
+	"ON": true, "USING": true, "SET": true, "VALUES": true, "SELECT": true, "RETURNING": true,
+	"WINDOW": true, "FETCH": true, "FOR": true, "WHEN": true, "DEFAULT": true, "TO": true,
+	"FROM": true, "AS": true, "WITH": true, "CONNECT": true, "START": true, "PARTITION": true,
+	"DUAL": true, "TABLESAMPLE": true, "STRAIGHT_JOIN": true, "FORCE": true, "IGNORE": true,
+	"LOCK": true, "INTO": true, "OUTFILE": true, "DUMPFILE": true,
+}
+
+func isClauseKeyword(word string) bool {
+	return clauseKeywords[strings.ToUpper(word)]
+}
+
+// isSystemTable reports catalog tables and views, which are metadata rather
+// than data.
+func isSystemTable(parts []string, dialect string) bool {
+	first := strings.ToLower(parts[0])
+
+	switch dialect {
+	case DialectPostgreSQL:
+		return first == "pg_catalog" || first == "information_schema" ||
+			(len(parts) == 1 && strings.HasPrefix(first, "pg_"))
+	case DialectMySQL:
+		return first == "information_schema" || first == "mysql" ||
+			first == "performance_schema" || first == "sys"
+	case DialectOracle:
+		return first == "sys" || first == "system" ||
+			(len(parts) == 1 && (strings.HasPrefix(first, "all_") || strings.HasPrefix(first, "user_") ||
+				strings.HasPrefix(first, "dba_") || strings.HasPrefix(first, "v$") || first == "dual"))
+	}
+
+	return false
+}
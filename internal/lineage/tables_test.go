@@ -0,0 +1,178 @@
+package lineage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractTables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dialect string
+		sql     string
+		inputs  []string
+		outputs []string
+	}{
+		{
+			name:    "simple select",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT * FROM Users WHERE id = $1",
+			inputs:  []string{"users"},
+		},
+		{
+			name:    "joins and aliases",
+			dialect: DialectPostgreSQL,
+			sql:     `SELECT o.id FROM sales.orders o JOIN "Customers" AS c ON c.id = o.customer_id LEFT JOIN items i USING (id)`,
+			inputs:  []string{"sales.orders", "Customers", "items"},
+		},
+		{
+			name:    "comma list",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT * FROM a x, b, c AS y WHERE x.id = b.id",
+			inputs:  []string{"a", "b", "c"},
+		},
+		{
+			name:    "insert select",
+			dialect: DialectPostgreSQL,
+			sql:     "INSERT INTO archive (id, total) SELECT id, total FROM orders WHERE created_at < now()",
+			inputs:  []string{"orders"},
+			outputs: []string{"archive"},
+		},
+		{
+			name:    "update from",
+			dialect: DialectPostgreSQL,
+			sql:     "UPDATE accounts SET balance = t.amount FROM transfers t WHERE t.account_id = accounts.id",
+			inputs:  []string{"transfers"},
+			outputs: []string{"accounts"},
+		},
+		{
+			name:    "delete",
+			dialect: DialectPostgreSQL,
+			sql:     "DELETE FROM sessions WHERE expires_at < now()",
+			outputs: []string{"sessions"},
+		},
+		{
+			name:    "table statement",
+			dialect: DialectPostgreSQL,
+			sql:     "TABLE audit.events UNION ALL (TABLE archived_events); ALTER TABLE ignored ADD COLUMN x int",
+			inputs:  []string{"audit.events", "archived_events"},
+		},
+		{
+			name:    "upsert",
+			dialect: DialectPostgreSQL,
+			sql:     "INSERT INTO counters (k, v) VALUES ('a', 1) ON CONFLICT (k) DO UPDATE SET v = counters.v + 1",
+			outputs: []string{"counters"},
+		},
+		{
+			name:    "select for update",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT * FROM jobs WHERE state = 'queued' FOR UPDATE SKIP LOCKED",
+			inputs:  []string{"jobs"},
+		},
+		{
+			name:    "cte",
+			dialect: DialectPostgreSQL,
+			sql:     "WITH recent AS (SELECT * FROM events WHERE ts > now() - interval '1 day') SELECT count(*) FROM recent JOIN users ON users.id = recent.user_id",
+			inputs:  []string{"events", "users"},
+		},
+		{
+			name:    "subquery",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT * FROM (SELECT id FROM a) sub WHERE id IN (SELECT a_id FROM b)",
+			inputs:  []string{"a", "b"},
+		},
+		{
+			name:    "functions with FROM",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT extract(year FROM created_at), substring(name FROM 1 FOR 3) FROM people WHERE a IS DISTINCT FROM b",
+			inputs:  []string{"people"},
+		},
+		{
+			name:    "table function",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT * FROM generate_series(1, 10) g",
+		},
+		{
+			name:    "catalogs ignored",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT * FROM pg_catalog.pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace JOIN information_schema.tables x ON true",
+		},
+		{
+			name:    "strings and comments",
+			dialect: DialectPostgreSQL,
+			sql:     "-- FROM ignored\nSELECT 'FROM fake', $$FROM dollar$$ /* JOIN nope */ FROM real_table",
+			inputs:  []string{"real_table"},
+		},
+		{
+			name:    "create table as",
+			dialect: DialectPostgreSQL,
+			sql:     "CREATE TABLE IF NOT EXISTS daily AS SELECT day, sum(x) FROM facts GROUP BY day",
+			inputs:  []string{"facts"},
+			outputs: []string{"daily"},
+		},
+		{
+			name:    "copy from",
+			dialect: DialectPostgreSQL,
+			sql:     "COPY staging (a, b) FROM STDIN WITH (FORMAT csv)",
+			outputs: []string{"staging"},
+		},
+		{
+			name:    "copy to",
+			dialect: DialectPostgreSQL,
+			sql:     "COPY staging TO STDOUT",
+			inputs:  []string{"staging"},
+		},
+		{
+			name:    "truncate",
+			dialect: DialectPostgreSQL,
+			sql:     "TRUNCATE TABLE a, b",
+			outputs: []string{"a", "b"},
+		},
+		{
+			name:    "mysql backticks",
+			dialect: DialectMySQL,
+			sql:     "SELECT * FROM `shop`.`Orders` WHERE note = \"FROM x\" # FROM comment",
+			inputs:  []string{"shop.Orders"},
+		},
+		{
+			name:    "mysql system schema",
+			dialect: DialectMySQL,
+			sql:     "SELECT * FROM information_schema.columns",
+		},
+		{
+			name:    "oracle merge",
+			dialect: DialectOracle,
+			sql:     "MERGE INTO hr.employees e USING staging s ON (e.id = s.id) WHEN MATCHED THEN UPDATE SET e.name = s.name",
+			inputs:  []string{"STAGING"},
+			outputs: []string{"HR.EMPLOYEES"},
+		},
+		{
+			name:    "oracle dual",
+			dialect: DialectOracle,
+			sql:     "SELECT sysdate FROM dual",
+		},
+		{
+			name:    "no tables",
+			dialect: DialectPostgreSQL,
+			sql:     "SET search_path TO app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ExtractTables(tt.sql, tt.dialect)
+
+			if !slices.Equal(got.Inputs, tt.inputs) {
+				t.Errorf("inputs = %q, want %q", got.Inputs, tt.inputs)
+			}
+
+			if !slices.Equal(got.Outputs, tt.outputs) {
+				t.Errorf("outputs = %q, want %q", got.Outputs, tt.outputs)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package migrations
 
 import (
 	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
 
 	"github.com/uptrace/bun/migrate"
 )
@@ -18,3 +21,21 @@ func init() {
 		panic(err)
 	}
 }
+
+// ErrNoUpFile is returned by UpSQL for a migration without an up file.
+var ErrNoUpFile = errors.New("migration has no up file")
+
+// UpSQL returns the content of a migration's up file, for printing the SQL
+// instead of running it.
+func UpSQL(m migrate.Migration) (string, error) {
+	base := "sql/" + m.Name + "_" + m.Comment
+
+	for _, path := range []string{base + ".up.sql", base + ".tx.up.sql"} {
+		content, err := fs.ReadFile(sqlMigrations, path)
+		if err == nil {
+			return string(content), nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrNoUpFile, m.Name)
+}
@@ -0,0 +1,5 @@
+-- Client IP of the API request that produced the audit event, resolved
+-- through the configured trusted proxies. NULL for events raised outside an
+-- HTTP request (Slack interactions over Socket Mode, background jobs) and for
+-- rows written before this column existed.
+ALTER TABLE audit_log ADD COLUMN source_ip inet;
@@ -0,0 +1,10 @@
+-- Per-user UI preferences (pinned databases, saved filters, column layouts…)
+-- stored server-side so they follow the user across browsers. Values are
+-- opaque JSON owned by the frontend.
+CREATE TABLE user_preferences (
+    user_id    uuid NOT NULL REFERENCES users(uid) ON DELETE CASCADE,
+    key        text NOT NULL,
+    value      jsonb NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, key)
+);
@@ -0,0 +1 @@
+ALTER TABLE servers DROP COLUMN blocked_message;
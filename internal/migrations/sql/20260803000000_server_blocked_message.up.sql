@@ -0,0 +1,4 @@
+-- Text returned to clients whose statement is blocked by a grant control
+-- (read-only, DDL, COPY), e.g. where to request broader access. NULL falls
+-- back to the proxy-wide DBB_PG_BLOCKED_MESSAGE.
+ALTER TABLE servers ADD COLUMN blocked_message text;
@@ -0,0 +1 @@
+ALTER TABLE connections DROP COLUMN client_info;
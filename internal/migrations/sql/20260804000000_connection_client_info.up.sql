@@ -0,0 +1,4 @@
+-- What the client declared about itself at connect time (application name,
+-- driver, encoding, ...). Self-reported and unverified; NULL for connections
+-- recorded before this column existed or whose protocol declares nothing.
+ALTER TABLE connections ADD COLUMN client_info jsonb;
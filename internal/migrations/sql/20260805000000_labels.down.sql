@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_access_grants_labels;
+DROP INDEX IF EXISTS idx_servers_labels;
+DROP INDEX IF EXISTS idx_users_labels;
+
+ALTER TABLE access_grants DROP COLUMN labels;
+ALTER TABLE servers DROP COLUMN labels;
+ALTER TABLE users DROP COLUMN labels;
@@ -0,0 +1,10 @@
+-- Free-form key=value labels (env=prod, team=payments) used to organize and
+-- filter users, servers and grants. GIN indexes serve the containment (@>)
+-- filter of the list endpoints.
+ALTER TABLE users ADD COLUMN labels jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE servers ADD COLUMN labels jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE access_grants ADD COLUMN labels jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_users_labels ON users USING gin (labels jsonb_path_ops);
+CREATE INDEX idx_servers_labels ON servers USING gin (labels jsonb_path_ops);
+CREATE INDEX idx_access_grants_labels ON access_grants USING gin (labels jsonb_path_ops);
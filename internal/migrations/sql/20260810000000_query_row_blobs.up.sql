@@ -0,0 +1,10 @@
+-- Compacted result rows: `dbbat db compact` folds the query_rows of old
+-- queries into one gzip-compressed JSON array per query. The rows API reads
+-- from here when a query has no query_rows left.
+CREATE TABLE query_row_blobs (
+    query_id UUID PRIMARY KEY REFERENCES queries(uid) ON DELETE CASCADE,
+    row_count INT NOT NULL,
+    raw_bytes BIGINT NOT NULL,              -- sum of row_size_bytes before compaction
+    data BYTEA NOT NULL,                    -- gzip of the JSON array of rows
+    compacted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+ALTER TABLE queries DROP COLUMN IF EXISTS sql_text_sha256;
+ALTER TABLE queries DROP COLUMN IF EXISTS sql_text_bytes;
+ALTER TABLE queries DROP COLUMN IF EXISTS sql_truncated;
@@ -0,0 +1,5 @@
+-- SQL text longer than query_storage.max_sql_bytes is stored truncated to its
+-- start and end; the original size and the hash of the full text are kept.
+ALTER TABLE queries ADD COLUMN sql_truncated BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE queries ADD COLUMN sql_text_bytes BIGINT;
+ALTER TABLE queries ADD COLUMN sql_text_sha256 TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE queries DROP COLUMN IF EXISTS last_executed_at;
+ALTER TABLE queries DROP COLUMN IF EXISTS repeat_count;
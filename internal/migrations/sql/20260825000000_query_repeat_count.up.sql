@@ -0,0 +1,4 @@
+-- Identical consecutive statements of a connection within
+-- query_storage.dedup_window are folded into a single query row.
+ALTER TABLE queries ADD COLUMN repeat_count BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE queries ADD COLUMN last_executed_at TIMESTAMPTZ;
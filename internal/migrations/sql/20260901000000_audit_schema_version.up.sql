@@ -0,0 +1,3 @@
+-- Version of the typed details schema of each audit event (internal/audit).
+-- Events recorded before versioning keep NULL.
+ALTER TABLE audit_log ADD COLUMN schema_version INTEGER;
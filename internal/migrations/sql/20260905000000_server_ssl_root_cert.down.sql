@@ -0,0 +1 @@
+ALTER TABLE servers DROP COLUMN IF EXISTS ssl_root_cert;
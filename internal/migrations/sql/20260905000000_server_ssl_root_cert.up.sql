@@ -0,0 +1,3 @@
+-- PEM CA bundle verifying the upstream certificate (verify-ca, verify-full,
+-- and require like libpq with a root certificate). NULL = system roots.
+ALTER TABLE servers ADD COLUMN ssl_root_cert TEXT;
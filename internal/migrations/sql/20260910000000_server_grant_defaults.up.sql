@@ -0,0 +1,3 @@
+-- Per-database grant defaults (controls, duration, quotas, capture mode):
+-- they pre-fill new grants and bound them. NULL = no defaults.
+ALTER TABLE servers ADD COLUMN grant_defaults JSONB;
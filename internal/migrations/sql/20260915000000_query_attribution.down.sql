@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_queries_grant_id;
+DROP INDEX IF EXISTS idx_queries_database_id;
+DROP INDEX IF EXISTS idx_queries_user_id;
+
+ALTER TABLE queries DROP COLUMN IF EXISTS access_level;
+ALTER TABLE queries DROP COLUMN IF EXISTS grant_id;
+ALTER TABLE queries DROP COLUMN IF EXISTS database_id;
+ALTER TABLE queries DROP COLUMN IF EXISTS user_id;
+
+ALTER TABLE connections DROP COLUMN IF EXISTS access_level;
+ALTER TABLE connections DROP COLUMN IF EXISTS grant_id;
@@ -0,0 +1,19 @@
+-- The grant a connection was authorized by, and its access level.
+ALTER TABLE connections ADD COLUMN grant_id UUID;
+ALTER TABLE connections ADD COLUMN access_level TEXT;
+
+-- Queries carry their connection's user, database, grant and access level,
+-- so the log filters on them without joins and keeps them once the grant is
+-- revoked. Existing queries get their user and database; their grant is not
+-- known.
+ALTER TABLE queries ADD COLUMN user_id UUID;
+ALTER TABLE queries ADD COLUMN database_id UUID;
+ALTER TABLE queries ADD COLUMN grant_id UUID;
+ALTER TABLE queries ADD COLUMN access_level TEXT;
+
+UPDATE queries q SET user_id = c.user_id, database_id = c.database_id
+FROM connections c WHERE q.connection_id = c.uid;
+
+CREATE INDEX idx_queries_user_id ON queries(user_id);
+CREATE INDEX idx_queries_database_id ON queries(database_id);
+CREATE INDEX idx_queries_grant_id ON queries(grant_id) WHERE grant_id IS NOT NULL;
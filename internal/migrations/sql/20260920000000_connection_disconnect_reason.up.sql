@@ -0,0 +1,4 @@
+-- Why a connection ended: the client or upstream closing it, a socket error,
+-- or a grant limit. NULL for open connections and those closed before it was
+-- recorded.
+ALTER TABLE connections ADD COLUMN disconnect_reason TEXT;
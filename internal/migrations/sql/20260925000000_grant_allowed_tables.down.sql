@@ -0,0 +1 @@
+ALTER TABLE access_grants DROP COLUMN IF EXISTS allowed_tables;
@@ -0,0 +1,3 @@
+-- Tables a grant is restricted to, as schema.table or schema.* entries.
+-- Empty means every table of the database.
+ALTER TABLE access_grants ADD COLUMN allowed_tables TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE grant_requests DROP COLUMN IF EXISTS duration_seconds;
@@ -0,0 +1,3 @@
+-- Duration the requester asked for, at most the definition's.
+-- NULL takes the definition's duration.
+ALTER TABLE grant_requests ADD COLUMN duration_seconds BIGINT;
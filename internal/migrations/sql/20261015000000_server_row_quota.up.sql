@@ -0,0 +1,4 @@
+-- Per-database caps on the retained result rows (max_rows, max_bytes): past
+-- them, the retention janitor deletes the rows of the oldest queries first.
+-- NULL = no cap.
+ALTER TABLE servers ADD COLUMN row_quota JSONB;
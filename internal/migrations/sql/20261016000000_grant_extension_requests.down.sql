@@ -0,0 +1,4 @@
+DELETE FROM grant_requests WHERE extends_grant_id IS NOT NULL;
+ALTER TABLE grant_requests DROP CONSTRAINT IF EXISTS grant_requests_definition_or_extension;
+ALTER TABLE grant_requests DROP COLUMN IF EXISTS extends_grant_id;
+ALTER TABLE grant_requests ALTER COLUMN grant_definition_id SET NOT NULL;
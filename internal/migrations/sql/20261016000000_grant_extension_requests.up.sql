@@ -0,0 +1,7 @@
+-- A grant request either asks for a new grant shaped by a definition, or for
+-- an extension of one of the requester's grants (extends_grant_id), which
+-- carries no definition.
+ALTER TABLE grant_requests ALTER COLUMN grant_definition_id DROP NOT NULL;
+ALTER TABLE grant_requests ADD COLUMN extends_grant_id UUID REFERENCES access_grants(uid);
+ALTER TABLE grant_requests ADD CONSTRAINT grant_requests_definition_or_extension
+    CHECK ((grant_definition_id IS NULL) <> (extends_grant_id IS NULL));
@@ -0,0 +1,4 @@
+-- Per-database masking of captured result rows (column_patterns, columns):
+-- the values of matching columns are replaced by a marker before storage.
+-- NULL = nothing masked.
+ALTER TABLE servers ADD COLUMN result_masking JSONB;
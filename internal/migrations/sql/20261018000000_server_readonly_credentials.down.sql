@@ -0,0 +1,2 @@
+ALTER TABLE servers DROP COLUMN IF EXISTS readonly_password_encrypted;
+ALTER TABLE servers DROP COLUMN IF EXISTS readonly_username;
@@ -0,0 +1,5 @@
+-- Optional upstream credentials for read-only grants, so read_only is backed
+-- by the target's own permissions. The password is encrypted like
+-- password_encrypted. NULL = read-only grants log in with username.
+ALTER TABLE servers ADD COLUMN readonly_username TEXT;
+ALTER TABLE servers ADD COLUMN readonly_password_encrypted BYTEA;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_connections_instance_id;
+ALTER TABLE connections DROP COLUMN IF EXISTS instance_id;
+DROP TABLE IF EXISTS instances;
@@ -0,0 +1,19 @@
+-- Running dbbat instances, registered at startup and kept alive by a
+-- heartbeat, so HA deployments can list them and tell which one handled a
+-- connection.
+CREATE TABLE instances (
+    uid UUID PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    version TEXT NOT NULL,
+    listen JSONB NOT NULL DEFAULT '{}',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    stopped_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_instances_last_heartbeat_at ON instances (last_heartbeat_at);
+
+-- No foreign key: connections outlive the instance rows they point to.
+ALTER TABLE connections ADD COLUMN instance_id UUID;
+
+CREATE INDEX idx_connections_instance_id ON connections (instance_id) WHERE instance_id IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE queries DROP COLUMN IF EXISTS proxy_overhead_ms;
+ALTER TABLE queries DROP COLUMN IF EXISTS streaming_ms;
+ALTER TABLE queries DROP COLUMN IF EXISTS upstream_ms;
+ALTER TABLE queries DROP COLUMN IF EXISTS upstream_first_byte_ms;
@@ -0,0 +1,7 @@
+-- Breakdown of the duration of a query between the upstream database and the
+-- proxy. Each part is NULL when the protocol does not expose it, and for
+-- queries logged before it was recorded.
+ALTER TABLE queries ADD COLUMN upstream_first_byte_ms NUMERIC(10,3);
+ALTER TABLE queries ADD COLUMN upstream_ms NUMERIC(10,3);
+ALTER TABLE queries ADD COLUMN streaming_ms NUMERIC(10,3);
+ALTER TABLE queries ADD COLUMN proxy_overhead_ms NUMERIC(10,3);
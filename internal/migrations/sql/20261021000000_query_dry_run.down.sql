@@ -0,0 +1 @@
+ALTER TABLE queries DROP COLUMN IF EXISTS dry_run;
@@ -0,0 +1,2 @@
+-- Queries run under the dry_run grant control, whose effects were rolled back.
+ALTER TABLE queries ADD COLUMN dry_run BOOLEAN NOT NULL DEFAULT FALSE;
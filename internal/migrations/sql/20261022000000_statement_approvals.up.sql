@@ -0,0 +1,23 @@
+-- Write statements held by the proxy under the write_requires_approval
+-- control until an admin approves or denies them.
+CREATE TABLE statement_approvals (
+    uid             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- No foreign key: connections are purged by retention.
+    connection_id   UUID NOT NULL,
+    grant_id        UUID NOT NULL REFERENCES access_grants(uid),
+    user_id         UUID NOT NULL REFERENCES users(uid),
+    database_id     UUID NOT NULL REFERENCES databases(uid),
+    sql_text        TEXT NOT NULL,
+    status          TEXT NOT NULL CHECK (status IN ('pending','approved','denied','expired')),
+    requested_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at      TIMESTAMPTZ NOT NULL,
+    decided_at      TIMESTAMPTZ,
+    decided_by      UUID REFERENCES users(uid),
+    decision_reason TEXT
+);
+
+CREATE INDEX statement_approvals_status_requested_idx
+    ON statement_approvals(status, requested_at);
+
+CREATE INDEX statement_approvals_user_idx
+    ON statement_approvals(user_id, requested_at);
@@ -0,0 +1,2 @@
+ALTER TABLE connections DROP COLUMN rows_returned;
+ALTER TABLE access_grants DROP COLUMN max_rows_returned;
@@ -0,0 +1,4 @@
+-- Quota on the rows a grant's sessions return to the client (PostgreSQL),
+-- counted per connection like bytes_transferred.
+ALTER TABLE access_grants ADD COLUMN max_rows_returned BIGINT;
+ALTER TABLE connections ADD COLUMN rows_returned BIGINT NOT NULL DEFAULT 0;
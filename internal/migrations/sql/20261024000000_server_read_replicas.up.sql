@@ -0,0 +1,3 @@
+-- Read replicas of a database target (PostgreSQL): the proxy routes the
+-- sessions of read-only grants to them, host or host:port each.
+ALTER TABLE servers ADD COLUMN read_replicas TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_queries_sql_text_trgm;
+DROP INDEX IF EXISTS idx_queries_rows_affected;
+DROP INDEX IF EXISTS idx_queries_duration_ms;
+DROP INDEX IF EXISTS idx_queries_errors;
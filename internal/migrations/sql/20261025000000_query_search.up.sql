@@ -0,0 +1,22 @@
+-- Indexes behind the query log search filters.
+CREATE INDEX idx_queries_errors ON queries(executed_at) WHERE error IS NOT NULL;
+CREATE INDEX idx_queries_duration_ms ON queries(duration_ms) WHERE duration_ms IS NOT NULL;
+CREATE INDEX idx_queries_rows_affected ON queries(rows_affected) WHERE rows_affected IS NOT NULL;
+
+-- SQL text search (ILIKE and regular expressions) uses a trigram index when
+-- pg_trgm can be installed; without it, the search still works by scanning.
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN insufficient_privilege OR undefined_file THEN
+    RAISE NOTICE 'pg_trgm unavailable, query SQL search will not be indexed';
+END
+$$;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX idx_queries_sql_text_trgm ON queries USING gin (sql_text gin_trgm_ops);
+    END IF;
+END
+$$;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS query_alert_matches;
+DROP TABLE IF EXISTS query_alerts;
@@ -0,0 +1,35 @@
+-- Query alerts: saved query log filters evaluated in the background. The
+-- cursor is the UID of the last query evaluated (query UIDs are UUIDv7, so
+-- time-ordered); matches are recorded, and POSTed to webhook_url when set.
+CREATE TABLE query_alerts (
+    uid               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name              text NOT NULL,
+    description       text NOT NULL DEFAULT '',
+    filter            jsonb NOT NULL DEFAULT '{}',
+    webhook_url       text,
+    enabled           boolean NOT NULL DEFAULT true,
+    cursor_uid        uuid NOT NULL,
+    match_count       bigint NOT NULL DEFAULT 0,
+    last_evaluated_at timestamptz,
+    last_matched_at   timestamptz,
+    created_by        uuid,
+    created_at        timestamptz NOT NULL DEFAULT now(),
+    updated_at        timestamptz NOT NULL DEFAULT now()
+);
+
+--bun:split
+
+CREATE UNIQUE INDEX query_alerts_name_uniq ON query_alerts (lower(name));
+
+--bun:split
+
+CREATE TABLE query_alert_matches (
+    alert_uid  uuid NOT NULL REFERENCES query_alerts(uid) ON DELETE CASCADE,
+    query_uid  uuid NOT NULL REFERENCES queries(uid) ON DELETE CASCADE,
+    matched_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (alert_uid, query_uid)
+);
+
+--bun:split
+
+CREATE INDEX query_alert_matches_query_idx ON query_alert_matches (query_uid);
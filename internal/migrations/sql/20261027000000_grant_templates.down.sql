@@ -0,0 +1 @@
+DROP TABLE IF EXISTS grant_templates;
@@ -0,0 +1,21 @@
+-- Grant templates: admin-only grant shapes applied to many users and
+-- databases at once. Unlike grant definitions, users never see them.
+CREATE TABLE grant_templates (
+    uid                   uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name                  text NOT NULL,
+    description           text NOT NULL DEFAULT '',
+    duration_seconds      bigint NOT NULL CHECK (duration_seconds > 0),
+    controls              text[] NOT NULL DEFAULT '{}',
+    allowed_tables        text[] NOT NULL DEFAULT '{}',
+    max_query_counts      bigint,
+    max_bytes_transferred bigint,
+    max_rows_returned     bigint,
+    labels                jsonb NOT NULL DEFAULT '{}',
+    created_by            uuid,
+    created_at            timestamptz NOT NULL DEFAULT now(),
+    updated_at            timestamptz NOT NULL DEFAULT now()
+);
+
+--bun:split
+
+CREATE UNIQUE INDEX grant_templates_name_uniq ON grant_templates (lower(name));
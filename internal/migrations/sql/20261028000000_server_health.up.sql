@@ -0,0 +1,12 @@
+-- Server health: the outcome of the latest connectivity check of each
+-- database target, run in the background or from the API.
+CREATE TABLE server_health (
+    server_id   uuid PRIMARY KEY REFERENCES servers(uid) ON DELETE CASCADE,
+    checked_at  timestamptz NOT NULL,
+    ok          boolean NOT NULL,
+    stage       text NOT NULL,
+    code        text NOT NULL,
+    message     text NOT NULL DEFAULT '',
+    duration_ms bigint NOT NULL,
+    last_ok_at  timestamptz
+);
@@ -0,0 +1,4 @@
+-- Reference to the upstream password in an external secrets manager
+-- (vault://, aws-sm://, gcp-sm://), resolved when the proxy connects.
+-- NULL = the password stored encrypted in password_encrypted.
+ALTER TABLE servers ADD COLUMN secret_ref TEXT;
@@ -0,0 +1,3 @@
+-- Per-user override of the API rate limit, in requests per minute.
+-- NULL = the override of the user's roles, or the configured default.
+ALTER TABLE users ADD COLUMN rate_limit_per_minute INTEGER;
@@ -0,0 +1,2 @@
+ALTER TABLE grant_templates DROP COLUMN IF EXISTS allowed_cidrs;
+ALTER TABLE access_grants DROP COLUMN IF EXISTS allowed_cidrs;
@@ -0,0 +1,3 @@
+-- Networks a grant may be used from, as CIDRs. Empty means any client.
+ALTER TABLE access_grants ADD COLUMN allowed_cidrs TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE grant_templates ADD COLUMN allowed_cidrs TEXT[] NOT NULL DEFAULT '{}';
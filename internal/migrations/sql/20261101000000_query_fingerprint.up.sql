@@ -0,0 +1,8 @@
+-- Fingerprint of each query's statement (literals and bind parameters
+-- replaced, unquoted identifiers lowercased), grouping the query log into
+-- per-statement statistics. Queries logged before it have none.
+ALTER TABLE queries ADD COLUMN fingerprint TEXT;
+
+--bun:split
+
+CREATE INDEX idx_queries_fingerprint ON queries(fingerprint, executed_at) WHERE fingerprint IS NOT NULL;
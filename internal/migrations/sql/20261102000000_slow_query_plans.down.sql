@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS query_plans;
+
+--bun:split
+
+ALTER TABLE servers DROP COLUMN IF EXISTS slow_query_threshold_ms;
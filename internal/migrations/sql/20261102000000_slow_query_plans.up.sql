@@ -0,0 +1,22 @@
+-- Slow query threshold of a database (PostgreSQL only): the statements
+-- running longer are explained in the background with its stored
+-- credentials, and their plan recorded in query_plans. NULL disables it.
+ALTER TABLE servers ADD COLUMN slow_query_threshold_ms INTEGER;
+
+--bun:split
+
+-- EXPLAIN (FORMAT JSON) output of a slow query, or why it could not be
+-- captured. Statements of the same fingerprint are explained once per
+-- cooldown, so most slow queries share the plan of an earlier one.
+CREATE TABLE query_plans (
+    query_uid   uuid PRIMARY KEY REFERENCES queries(uid) ON DELETE CASCADE,
+    database_id uuid NOT NULL,
+    fingerprint text,
+    plan        jsonb,
+    error       text,
+    captured_at timestamptz NOT NULL DEFAULT now()
+);
+
+--bun:split
+
+CREATE INDEX idx_query_plans_fingerprint ON query_plans(database_id, fingerprint, captured_at);
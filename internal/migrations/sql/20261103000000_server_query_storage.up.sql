@@ -0,0 +1,4 @@
+-- Per-database overrides of the query storage configuration (store_results,
+-- max_result_rows, max_result_bytes) and of the retention of its queries and
+-- result rows (queries_days, rows_days). NULL = the global configuration.
+ALTER TABLE servers ADD COLUMN query_storage JSONB;
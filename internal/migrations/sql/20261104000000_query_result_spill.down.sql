@@ -0,0 +1,5 @@
+ALTER TABLE queries DROP COLUMN IF EXISTS spilled_rows;
+
+--bun:split
+
+ALTER TABLE queries DROP COLUMN IF EXISTS result_object_key;
@@ -0,0 +1,7 @@
+-- Results spilled past the query storage limits (DBB_RESULT_SPILL_*): the
+-- key of their object and its row count. NULL = not spilled.
+ALTER TABLE queries ADD COLUMN result_object_key TEXT;
+
+--bun:split
+
+ALTER TABLE queries ADD COLUMN spilled_rows BIGINT;
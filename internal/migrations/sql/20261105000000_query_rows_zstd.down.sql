@@ -0,0 +1,10 @@
+-- Compressed rows cannot be restored in SQL: they are dropped.
+DELETE FROM query_rows WHERE row_data IS NULL;
+
+--bun:split
+
+ALTER TABLE query_rows ALTER COLUMN row_data SET NOT NULL;
+
+--bun:split
+
+ALTER TABLE query_rows DROP COLUMN IF EXISTS row_data_zstd;
@@ -0,0 +1,7 @@
+-- Result rows stored zstd-compressed (DBB_QUERY_STORAGE_COMPRESS_ROWS):
+-- row_data_zstd holds the compressed JSON and row_data is NULL.
+ALTER TABLE query_rows ADD COLUMN row_data_zstd BYTEA;
+
+--bun:split
+
+ALTER TABLE query_rows ALTER COLUMN row_data DROP NOT NULL;
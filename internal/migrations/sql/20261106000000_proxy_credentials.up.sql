@@ -0,0 +1,22 @@
+-- Proxy credentials: one-time logins an administrator issues on a grant for
+-- someone without a DBBat user (a contractor, a vendor's support engineer).
+-- The connection they open runs under the grant; principal names who it was
+-- handed to and issued_by the administrator who handed it.
+CREATE TABLE proxy_credentials (
+    uid           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    grant_id      uuid NOT NULL REFERENCES access_grants(uid) ON DELETE CASCADE,
+    username      text NOT NULL UNIQUE,
+    secret_hash   text NOT NULL,
+    principal     text NOT NULL,
+    issued_by     uuid NOT NULL REFERENCES users(uid),
+    expires_at    timestamptz NOT NULL,
+    used_at       timestamptz,
+    connection_id uuid,
+    revoked_at    timestamptz,
+    revoked_by    uuid,
+    created_at    timestamptz NOT NULL DEFAULT now()
+);
+
+--bun:split
+
+CREATE INDEX idx_proxy_credentials_grant ON proxy_credentials(grant_id, created_at);
@@ -0,0 +1,3 @@
+-- Scopes of an API key ("read:queries", "write:grants", ...): the API routes
+-- it may call, within its user's permissions. NULL = every route.
+ALTER TABLE api_keys ADD COLUMN scopes TEXT[];
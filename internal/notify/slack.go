@@ -71,8 +71,10 @@ type GrantRequestEvent struct {
 	Action     GrantAction
 	Request    *store.GrantRequest
 	Definition *store.GrantDefinition
-	Server     *store.Server
-	Requester  *store.User
+	// Grant is the grant an extension request extends; nil otherwise.
+	Grant     *store.Grant
+	Server    *store.Server
+	Requester *store.User
 	// Decider is set when Action is approved/denied/canceled.
 	Decider *store.User
 
@@ -267,7 +269,7 @@ func (n *SlackNotifier) PostThreadReply(ctx context.Context, channel, ts, text s
 func buildBlocks(ev GrantRequestEvent, publicURL string) []slack.Block {
 	header := slack.NewHeaderBlock(slack.NewTextBlockObject(
 		"plain_text",
-		fmt.Sprintf("%s Grant request — %s", statusEmoji(ev), userLabel(ev.Requester)),
+		fmt.Sprintf("%s %s — %s", statusEmoji(ev), requestTitle(ev), userLabel(ev.Requester)),
 		false,
 		false,
 	))
@@ -312,10 +314,29 @@ func mainSectionText(ev GrantRequestEvent) string {
 		durationText = formatDuration(ev.Definition.DurationSeconds)
 	}
 
-	mainText := fmt.Sprintf(
-		"*Server*: %s\n*Definition*: %s\n*Duration*: %s\n*Status*: %s",
-		dbName, defName, durationText, statusLabel(ev),
-	)
+	if ev.Request != nil && ev.Request.DurationSeconds != nil {
+		durationText = formatDuration(*ev.Request.DurationSeconds)
+	}
+
+	var mainText string
+
+	if isExtension(ev) {
+		// An extension has no definition: show the grant's current expiry.
+		expiry := "—"
+		if ev.Grant != nil {
+			expiry = ev.Grant.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")
+		}
+
+		mainText = fmt.Sprintf(
+			"*Server*: %s\n*Grant expires*: %s\n*Extension*: +%s\n*Status*: %s",
+			dbName, expiry, durationText, statusLabel(ev),
+		)
+	} else {
+		mainText = fmt.Sprintf(
+			"*Server*: %s\n*Definition*: %s\n*Duration*: %s\n*Status*: %s",
+			dbName, defName, durationText, statusLabel(ev),
+		)
+	}
 
 	if ev.Action != GrantActionCreated && ev.Decider != nil {
 		mainText += fmt.Sprintf("\n*%s by*: %s",
@@ -358,6 +379,9 @@ func mentionLine(ev GrantRequestEvent) string {
 	}
 
 	line := fmt.Sprintf("%s requested access on *%s* with *%s*.", requester, dbName, defName)
+	if isExtension(ev) {
+		line = fmt.Sprintf("%s requested an extension of their access on *%s*.", requester, dbName)
+	}
 
 	if len(ev.AdminSlackIDs) > 0 {
 		mentions := make([]string, 0, len(ev.AdminSlackIDs))
@@ -417,6 +441,20 @@ func userLabel(u *store.User) string {
 	return u.Username
 }
 
+// isExtension reports whether the event is about a grant extension request.
+func isExtension(ev GrantRequestEvent) bool {
+	return ev.Request != nil && ev.Request.ExtendsGrantID != nil
+}
+
+// requestTitle names the kind of request in the message header.
+func requestTitle(ev GrantRequestEvent) string {
+	if isExtension(ev) {
+		return "Grant extension request"
+	}
+
+	return "Grant request"
+}
+
 // isAutoApproved reports whether an approved event has no human decider —
 // i.e. the definition's AutoApprove policy decided it, not an admin.
 func isAutoApproved(ev GrantRequestEvent) bool {
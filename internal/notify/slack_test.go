@@ -12,6 +12,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/slack-go/slack"
@@ -598,3 +599,43 @@ func TestNotifier_SlackErrorIsLoggedNotReturned(t *testing.T) {
 		t.Errorf("expected one post attempt, got %d", fake.postCount())
 	}
 }
+
+func TestMainSectionText_RequestedDuration(t *testing.T) {
+	t.Parallel()
+
+	ev := sampleEvent(GrantActionCreated)
+	if text := mainSectionText(ev); !strings.Contains(text, "*Duration*: 1h") {
+		t.Errorf("main section = %q, want the definition's duration", text)
+	}
+
+	duration := int64(1800)
+	ev.Request.DurationSeconds = &duration
+
+	if text := mainSectionText(ev); !strings.Contains(text, "*Duration*: 30m") {
+		t.Errorf("main section = %q, want the requested duration", text)
+	}
+}
+
+func TestMainSectionText_Extension(t *testing.T) {
+	t.Parallel()
+
+	ev := sampleEvent(GrantActionCreated)
+	grantUID := uuid.New()
+	duration := int64(7200)
+	ev.Definition = nil
+	ev.Request.GrantDefinitionID = nil
+	ev.Request.ExtendsGrantID = &grantUID
+	ev.Request.DurationSeconds = &duration
+	ev.Grant = &store.Grant{UID: grantUID, ExpiresAt: time.Date(2026, 10, 15, 18, 0, 0, 0, time.UTC)}
+
+	text := mainSectionText(ev)
+	for _, want := range []string{"*Grant expires*: 2026-10-15 18:00 UTC", "*Extension*: +2h"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("main section = %q, want %q", text, want)
+		}
+	}
+
+	if line := mentionLine(ev); !strings.Contains(line, "requested an extension of their access") {
+		t.Errorf("mention line = %q, want an extension request", line)
+	}
+}
@@ -0,0 +1,443 @@
+// Package parquet writes flat Apache Parquet files: uncompressed, PLAIN
+// encoded, one data page per column chunk. It covers what exports need, so
+// their files load straight into DuckDB, Spark or a warehouse, without an
+// encoder dependency.
+//
+// Row groups are written as they are flushed, so a file can be streamed with
+// bounded memory; only the footer waits for Close.
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Errors returned by Writer.Write.
+var (
+	ErrColumnCount = errors.New("parquet: wrong number of values")
+	ErrValueType   = errors.New("parquet: value does not match the column type")
+	ErrNullValue   = errors.New("parquet: null value in a required column")
+)
+
+// magic starts and ends every Parquet file.
+const magic = "PAR1"
+
+// createdBy is recorded in the footer of the files.
+const createdBy = "dbbat"
+
+// Type is the type of a column.
+type Type int
+
+// Column types, with the Go type Writer.Write expects for them.
+const (
+	// String holds UTF-8 text (string).
+	String Type = iota
+	// JSON holds a JSON document (string).
+	JSON
+	// Int64 holds a signed 64-bit integer (int64).
+	Int64
+	// Double holds a 64-bit float (float64).
+	Double
+	// Boolean holds a boolean (bool).
+	Boolean
+	// Timestamp holds a UTC instant with microsecond precision (time.Time).
+	Timestamp
+)
+
+// Column describes a column of the file. Optional columns accept nil values.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Parquet physical types, repetitions, encodings and converted types, as
+// numbered by the format's Thrift definitions.
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+	convertedJSON            = 19
+
+	logicalString    = 1
+	logicalTimestamp = 8
+	logicalJSON      = 12
+	timeUnitMicros   = 2
+)
+
+func (t Type) physical() int32 {
+	switch t {
+	case Int64, Timestamp:
+		return physicalInt64
+	case Double:
+		return physicalDouble
+	case Boolean:
+		return physicalBoolean
+	default:
+		return physicalByteArray
+	}
+}
+
+// Writer writes rows to a Parquet file. It is not safe for concurrent use.
+type Writer struct {
+	w       io.Writer
+	columns []Column
+	chunks  []columnBuffer
+	rows    int64 // rows buffered for the next row group
+
+	offset    int64
+	numRows   int64
+	rowGroups []rowGroup
+	err       error
+}
+
+// columnBuffer holds the values of a column until its row group is flushed.
+type columnBuffer struct {
+	values   []byte // PLAIN encoded, but for booleans
+	booleans []bool
+	defined  []bool // per row, for optional columns
+}
+
+type rowGroup struct {
+	chunks    []columnChunk
+	totalSize int64
+	numRows   int64
+}
+
+type columnChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+// NewWriter returns a Writer writing a file of columns to w.
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	return &Writer{w: w, columns: columns, chunks: make([]columnBuffer, len(columns))}
+}
+
+// Write buffers a row, one value per column, in the column order.
+func (w *Writer) Write(row []any) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("%w: got %d, want %d", ErrColumnCount, len(row), len(w.columns))
+	}
+
+	// Check the whole row first, so a bad value leaves no partial row behind.
+	for i, value := range row {
+		if err := w.columns[i].check(value); err != nil {
+			return err
+		}
+	}
+
+	for i, value := range row {
+		chunk := &w.chunks[i]
+
+		if w.columns[i].Optional {
+			chunk.defined = append(chunk.defined, value != nil)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			chunk.values = binary.LittleEndian.AppendUint32(chunk.values, uint32(len(v)))
+			chunk.values = append(chunk.values, v...)
+		case int64:
+			chunk.values = binary.LittleEndian.AppendUint64(chunk.values, uint64(v))
+		case float64:
+			chunk.values = binary.LittleEndian.AppendUint64(chunk.values, math.Float64bits(v))
+		case bool:
+			chunk.booleans = append(chunk.booleans, v)
+		case time.Time:
+			chunk.values = binary.LittleEndian.AppendUint64(chunk.values, uint64(v.UnixMicro()))
+		}
+	}
+
+	w.rows++
+
+	return nil
+}
+
+// check reports whether value fits the column.
+func (c Column) check(value any) error {
+	if value == nil {
+		if !c.Optional {
+			return fmt.Errorf("%w: %s", ErrNullValue, c.Name)
+		}
+
+		return nil
+	}
+
+	var ok bool
+
+	switch c.Type {
+	case String, JSON:
+		_, ok = value.(string)
+	case Int64:
+		_, ok = value.(int64)
+	case Double:
+		_, ok = value.(float64)
+	case Boolean:
+		_, ok = value.(bool)
+	case Timestamp:
+		_, ok = value.(time.Time)
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: %s got %T", ErrValueType, c.Name, value)
+	}
+
+	return nil
+}
+
+// Flush writes the buffered rows as a row group. It does nothing when no row
+// was written since the last flush.
+func (w *Writer) Flush() error {
+	if w.err != nil || w.rows == 0 {
+		return w.err
+	}
+
+	if w.offset == 0 {
+		w.write([]byte(magic))
+	}
+
+	group := rowGroup{numRows: w.rows}
+
+	for i := range w.columns {
+		chunk := w.flushColumn(i)
+		group.chunks = append(group.chunks, chunk)
+		group.totalSize += chunk.size
+	}
+
+	w.rowGroups = append(w.rowGroups, group)
+	w.numRows += w.rows
+	w.rows = 0
+
+	return w.err
+}
+
+// flushColumn writes the buffered values of a column as one data page.
+func (w *Writer) flushColumn(i int) columnChunk {
+	column, buffer := w.columns[i], &w.chunks[i]
+
+	var page []byte
+
+	if column.Optional {
+		levels := encodeLevels(buffer.defined)
+		page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+		page = append(page, levels...)
+	}
+
+	if column.Type == Boolean {
+		page = append(page, packBooleans(buffer.booleans)...)
+	} else {
+		page = append(page, buffer.values...)
+	}
+
+	header := encodePageHeader(len(page), w.rows)
+	chunk := columnChunk{offset: w.offset, size: int64(len(header) + len(page)), numValues: w.rows}
+
+	w.write(header)
+	w.write(page)
+
+	buffer.values = buffer.values[:0]
+	buffer.booleans = buffer.booleans[:0]
+	buffer.defined = buffer.defined[:0]
+
+	return chunk
+}
+
+// Close flushes the buffered rows and writes the footer. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if w.offset == 0 {
+		w.write([]byte(magic))
+	}
+
+	footer := w.encodeFileMetaData()
+
+	w.write(footer)
+	w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	w.write([]byte(magic))
+
+	return w.err
+}
+
+// write writes p, keeping the first error.
+func (w *Writer) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	w.err = err
+}
+
+// encodeLevels encodes definition levels with the RLE/bit-packing hybrid,
+// as RLE runs of bit width 1.
+func encodeLevels(defined []bool) []byte {
+	var out []byte
+
+	for start := 0; start < len(defined); {
+		end := start
+		for end < len(defined) && defined[end] == defined[start] {
+			end++
+		}
+
+		out = binary.AppendUvarint(out, uint64(end-start)<<1)
+
+		if defined[start] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+
+		start = end
+	}
+
+	return out
+}
+
+// packBooleans PLAIN-encodes booleans, one bit each, least significant first.
+func packBooleans(values []bool) []byte {
+	out := make([]byte, (len(values)+7)/8)
+
+	for i, v := range values {
+		if v {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	return out
+}
+
+// encodePageHeader encodes the header of an uncompressed data page.
+func encodePageHeader(size int, numValues int64) []byte {
+	var t thriftWriter
+
+	t.i32(1, 0) // DATA_PAGE
+	t.i32(2, int32(size))
+	t.i32(3, int32(size))
+	t.beginStruct(5)
+	t.i32(1, int32(numValues))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.endStruct()
+
+	return t.end()
+}
+
+// encodeFileMetaData encodes the footer: the schema and where each column
+// chunk starts.
+func (w *Writer) encodeFileMetaData() []byte {
+	var t thriftWriter
+
+	t.i32(1, 1) // version
+	t.list(2, thriftStruct, 1+len(w.columns))
+
+	// The root of the schema holds the columns.
+	t.beginElement()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(w.columns)))
+	t.endStruct()
+
+	for _, column := range w.columns {
+		t.beginElement()
+		t.i32(1, column.Type.physical())
+
+		if column.Optional {
+			t.i32(3, repetitionOptional)
+		} else {
+			t.i32(3, repetitionRequired)
+		}
+
+		t.binary(4, column.Name)
+
+		switch column.Type {
+		case String:
+			t.i32(6, convertedUTF8)
+			t.beginStruct(10)
+			t.beginStruct(logicalString)
+			t.endStruct()
+			t.endStruct()
+		case JSON:
+			t.i32(6, convertedJSON)
+			t.beginStruct(10)
+			t.beginStruct(logicalJSON)
+			t.endStruct()
+			t.endStruct()
+		case Timestamp:
+			t.i32(6, convertedTimestampMicros)
+			t.beginStruct(10)
+			t.beginStruct(logicalTimestamp)
+			t.boolean(1, true) // isAdjustedToUTC
+			t.beginStruct(2)
+			t.beginStruct(timeUnitMicros)
+			t.endStruct()
+			t.endStruct()
+			t.endStruct()
+			t.endStruct()
+		case Int64, Double, Boolean:
+		}
+
+		t.endStruct()
+	}
+
+	t.i64(3, w.numRows)
+	t.list(4, thriftStruct, len(w.rowGroups))
+
+	for _, group := range w.rowGroups {
+		t.beginElement()
+		t.list(1, thriftStruct, len(group.chunks))
+
+		for i, chunk := range group.chunks {
+			column := w.columns[i]
+
+			t.beginElement()
+			t.i64(2, chunk.offset)
+			t.beginStruct(3)
+			t.i32(1, column.Type.physical())
+			t.list(2, thriftI32, 2)
+			t.element32(encodingPlain)
+			t.element32(encodingRLE)
+			t.list(3, thriftBinary, 1)
+			t.elementBinary(column.Name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+
+		t.i64(2, group.totalSize)
+		t.i64(3, group.numRows)
+		t.endStruct()
+	}
+
+	t.binary(6, createdBy)
+
+	return t.end()
+}
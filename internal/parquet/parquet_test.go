@@ -0,0 +1,257 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftReader decodes the Thrift compact protocol into maps of field id to
+// value, so the tests can check files against the format rather than against
+// the writer.
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.buf[r.pos]
+	r.pos++
+
+	return b
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Varint(r.buf[r.pos:])
+	r.pos += n
+
+	return v
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+
+	return v
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		v := string(r.buf[r.pos : r.pos+n])
+		r.pos += n
+
+		return v
+	case thriftList:
+		header := r.byte()
+		n := int(header >> 4)
+
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+
+	panic("unexpected thrift type")
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+
+	var last int16
+
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.varint())
+		}
+
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+}
+
+// readFile returns the footer of a Parquet file and the values of each
+// column, nil for nulls.
+func readFile(t *testing.T, data []byte) (map[int16]any, [][]any) {
+	t.Helper()
+
+	require.Equal(t, magic, string(data[:4]))
+	require.Equal(t, magic, string(data[len(data)-4:]))
+
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	footer := (&thriftReader{buf: data[footerStart : len(data)-8]}).readStruct()
+
+	schema := footer[2].([]any)
+	columns := make([][]any, len(schema)-1)
+
+	for _, group := range footer[4].([]any) {
+		for i, chunk := range group.(map[int16]any)[1].([]any) {
+			meta := chunk.(map[int16]any)[3].(map[int16]any)
+			element := schema[i+1].(map[int16]any)
+
+			r := &thriftReader{buf: data, pos: int(meta[9].(int64))}
+			page := r.readStruct()
+			numValues := int(page[5].(map[int16]any)[1].(int64))
+			body := data[r.pos : r.pos+int(page[2].(int64))]
+
+			defined := make([]bool, numValues)
+			for j := range defined {
+				defined[j] = true
+			}
+
+			if element[3].(int64) == repetitionOptional {
+				levelsLen := int(binary.LittleEndian.Uint32(body))
+				levels := &thriftReader{buf: body[4 : 4+levelsLen]}
+
+				for j := 0; j < numValues; {
+					run := int(levels.uvarint() >> 1)
+					value := levels.byte() == 1
+
+					for k := 0; k < run; k++ {
+						defined[j] = value
+						j++
+					}
+				}
+
+				body = body[4+levelsLen:]
+			}
+
+			bit := 0
+
+			for _, isDefined := range defined {
+				if !isDefined {
+					columns[i] = append(columns[i], nil)
+					continue
+				}
+
+				switch element[1].(int64) {
+				case physicalByteArray:
+					n := int(binary.LittleEndian.Uint32(body))
+					columns[i] = append(columns[i], string(body[4:4+n]))
+					body = body[4+n:]
+				case physicalInt64:
+					columns[i] = append(columns[i], int64(binary.LittleEndian.Uint64(body)))
+					body = body[8:]
+				case physicalDouble:
+					columns[i] = append(columns[i], math.Float64frombits(binary.LittleEndian.Uint64(body)))
+					body = body[8:]
+				case physicalBoolean:
+					columns[i] = append(columns[i], body[bit/8]&(1<<(bit%8)) != 0)
+					bit++
+				}
+			}
+		}
+	}
+
+	return footer, columns
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	columns := []Column{
+		{Name: "name", Type: String},
+		{Name: "doc", Type: JSON, Optional: true},
+		{Name: "count", Type: Int64},
+		{Name: "ratio", Type: Double, Optional: true},
+		{Name: "ok", Type: Boolean},
+		{Name: "at", Type: Timestamp, Optional: true},
+	}
+	at := time.Date(2026, 10, 1, 12, 0, 0, 123456000, time.UTC)
+
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf, columns)
+	require.NoError(t, w.Write([]any{"a", `{"n":1}`, int64(-3), 1.5, true, at}))
+	require.NoError(t, w.Write([]any{"b", nil, int64(0), nil, false, nil}))
+	require.NoError(t, w.Flush())
+	require.NoError(t, w.Write([]any{"", nil, int64(1 << 40), 0.25, true, at}))
+	require.NoError(t, w.Close())
+
+	footer, values := readFile(t, buf.Bytes())
+
+	assert.Equal(t, int64(3), footer[3])
+	assert.Len(t, footer[4], 2, "one row group per flush")
+	assert.Equal(t, createdBy, footer[6])
+
+	schema := footer[2].([]any)
+	require.Len(t, schema, 1+len(columns))
+	assert.Equal(t, int64(len(columns)), schema[0].(map[int16]any)[5])
+
+	for i, column := range columns {
+		assert.Equal(t, column.Name, schema[i+1].(map[int16]any)[4])
+	}
+
+	assert.Equal(t, int64(convertedTimestampMicros), schema[6].(map[int16]any)[6])
+
+	assert.Equal(t, []any{"a", "b", ""}, values[0])
+	assert.Equal(t, []any{`{"n":1}`, nil, nil}, values[1])
+	assert.Equal(t, []any{int64(-3), int64(0), int64(1 << 40)}, values[2])
+	assert.Equal(t, []any{1.5, nil, 0.25}, values[3])
+	assert.Equal(t, []any{true, false, true}, values[4])
+	assert.Equal(t, []any{at.UnixMicro(), nil, at.UnixMicro()}, values[5])
+}
+
+func TestWriterEmpty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	require.NoError(t, NewWriter(&buf, []Column{{Name: "name", Type: String}}).Close())
+
+	footer, values := readFile(t, buf.Bytes())
+	assert.Equal(t, int64(0), footer[3])
+	assert.Empty(t, footer[4])
+	assert.Nil(t, values[0])
+}
+
+func TestWriterRejectsBadRows(t *testing.T) {
+	t.Parallel()
+
+	w := NewWriter(&bytes.Buffer{}, []Column{{Name: "name", Type: String}, {Name: "count", Type: Int64}})
+
+	tests := []struct {
+		name    string
+		row     []any
+		wantErr error
+	}{
+		{"too few values", []any{"a"}, ErrColumnCount},
+		{"wrong type", []any{"a", 1}, ErrValueType},
+		{"null in required column", []any{nil, int64(1)}, ErrNullValue},
+	}
+
+	for _, tt := range tests {
+		err := w.Write(tt.row)
+		assert.True(t, errors.Is(err, tt.wantErr), "%s: got %v", tt.name, err)
+	}
+
+	assert.Zero(t, w.rows, "rejected rows must not be buffered")
+}
@@ -0,0 +1,165 @@
+// Package posture checks a deployment for weak settings at startup: the
+// default admin password, plaintext proxy listeners, directory connections
+// and audit syslog, cheap password hashes and demo data exposed to the
+// network.
+package posture
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Finding is a weak setting of the deployment.
+type Finding struct {
+	// Check names the check that found it (e.g., "default_admin_password").
+	Check string
+	// Message describes the weakness.
+	Message string
+	// Recommendation tells how to fix it.
+	Recommendation string
+}
+
+// Store is the part of the store the checks read.
+type Store interface {
+	GetUserByUsername(ctx context.Context, username string) (*store.User, error)
+	ListServers(ctx context.Context, filter store.ServerFilter) ([]store.Server, error)
+	MatchesStorageDSN(host string, port int, databaseName string) bool
+}
+
+// defaultAdminPassword is the password the admin user is created with.
+const defaultAdminPassword = "admin"
+
+// minHashMemoryKB is the Argon2id memory below which hashes are cheap to
+// brute-force, matching the "minimal" preset.
+const minHashMemoryKB = 16 * 1024
+
+// Check runs every check and returns the findings. Checks that cannot read
+// the store are logged and skipped rather than failing the startup.
+func Check(ctx context.Context, cfg *config.Config, s Store, logger *slog.Logger) []Finding {
+	findings := ConfigFindings(cfg)
+
+	// Demo mode signs in with admin/admin on purpose; it is only a finding
+	// when exposed, which ConfigFindings reports.
+	if cfg.RunMode != config.RunModeDemo {
+		if user, err := s.GetUserByUsername(ctx, "admin"); err == nil {
+			if valid, _ := crypto.VerifyPassword(user.PasswordHash, defaultAdminPassword); valid {
+				findings = append(findings, Finding{
+					Check:          "default_admin_password",
+					Message:        "the admin user still has the default password",
+					Recommendation: "change the admin password, or delete the admin user once other admins exist",
+				})
+			}
+		}
+	}
+
+	servers, err := s.ListServers(ctx, store.ServerFilter{})
+	if err != nil {
+		logger.WarnContext(ctx, "failed to check database configurations", slog.Any("error", err))
+		return findings
+	}
+
+	for _, db := range servers {
+		if s.MatchesStorageDSN(db.Host, db.Port, db.DatabaseName) {
+			findings = append(findings, Finding{
+				Check:          "storage_dsn_target",
+				Message:        fmt.Sprintf("database %q (%s:%d/%s) is the DBBat storage database", db.Name, db.Host, db.Port, db.DatabaseName),
+				Recommendation: "use a separate database for DBBat storage to prevent privilege escalation",
+			})
+		}
+	}
+
+	return findings
+}
+
+// ConfigFindings runs the checks that only read the configuration.
+func ConfigFindings(cfg *config.Config) []Finding {
+	var findings []Finding
+
+	type proxyListener struct {
+		name, addr string
+		tls        config.TLSConfig
+	}
+
+	listeners := []proxyListener{
+		{"PostgreSQL", cfg.ListenPG, cfg.PG.TLS},
+		{"MySQL", cfg.ListenMySQL, cfg.MySQL.TLS},
+		{"MongoDB", cfg.ListenMongo, cfg.Mongo.TLS},
+	}
+	for _, pgListener := range cfg.PG.Listeners {
+		listeners = append(listeners, proxyListener{"PostgreSQL " + pgListener.DisplayName(), pgListener.Addr, pgListener.TLSOrDefault(cfg.PG.TLS)})
+	}
+
+	for _, listener := range listeners {
+		if listener.addr != "" && listener.tls.Disable {
+			findings = append(findings, Finding{
+				Check:          "proxy_tls_disabled",
+				Message:        fmt.Sprintf("the %s listener (%s) does not offer TLS: passwords cross the network in the clear", listener.name, listener.addr),
+				Recommendation: "re-enable TLS termination on the listener, with a certificate of your own",
+			})
+		}
+	}
+
+	if cfg.LDAP.Enabled() && strings.HasPrefix(cfg.LDAP.URL, "ldap://") && !cfg.LDAP.StartTLS {
+		findings = append(findings, Finding{
+			Check:          "ldap_plaintext",
+			Message:        fmt.Sprintf("the LDAP directory (%s) is reached without TLS: directory passwords cross the network in the clear", cfg.LDAP.URL),
+			Recommendation: "use an ldaps:// URL, or enable DBB_LDAP_START_TLS",
+		})
+	}
+
+	if cfg.Audit.Syslog.Enabled() && cfg.Audit.Syslog.Network != "tls" && !IsLoopback(cfg.Audit.Syslog.Address) {
+		findings = append(findings, Finding{
+			Check:          "audit_syslog_plaintext",
+			Message:        fmt.Sprintf("audit events are sent to syslog (%s) without TLS: they can be read or forged on the network", cfg.Audit.Syslog.Address),
+			Recommendation: "set DBB_AUDIT_SYSLOG_NETWORK=tls, or relay through a local syslog daemon",
+		})
+	}
+
+	if !cfg.AuthCache.Enabled && cfg.Hash.Algorithm == "argon2id" && cfg.GetHashParams().MemoryKB < minHashMemoryKB {
+		findings = append(findings, Finding{
+			Check:          "weak_password_hashing",
+			Message:        "password hashes use minimal Argon2id parameters and the auth cache is disabled",
+			Recommendation: "use the default hash preset, and enable the auth cache if hashing is too slow",
+		})
+	}
+
+	if cfg.RunMode == config.RunModeDemo || cfg.RunMode == config.RunModeTest {
+		for _, addr := range []string{cfg.ListenAPI, cfg.ListenPG, cfg.ListenOracle, cfg.ListenMySQL, cfg.ListenMongo} {
+			if addr != "" && !IsLoopback(addr) {
+				findings = append(findings, Finding{
+					Check:          "exposed_" + string(cfg.RunMode) + "_mode",
+					Message:        fmt.Sprintf("%s mode, which drops all data on startup and uses known passwords, listens on %s", cfg.RunMode, addr),
+					Recommendation: "bind every listener to a loopback address (e.g., 127.0.0.1:4200) or leave " + string(cfg.RunMode) + " mode",
+				})
+
+				break
+			}
+		}
+	}
+
+	return findings
+}
+
+// IsLoopback reports whether a listen address only accepts local
+// connections. An empty host listens on every interface.
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
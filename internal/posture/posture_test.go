@@ -0,0 +1,149 @@
+package posture
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// fakeStore serves a single admin user and a list of servers.
+type fakeStore struct {
+	admin   *store.User
+	servers []store.Server
+	storage string // Name of the server matching the storage DSN
+}
+
+func (f *fakeStore) GetUserByUsername(_ context.Context, username string) (*store.User, error) {
+	if f.admin == nil || username != f.admin.Username {
+		return nil, store.ErrUserNotFound
+	}
+
+	return f.admin, nil
+}
+
+func (f *fakeStore) ListServers(_ context.Context, _ store.ServerFilter) ([]store.Server, error) {
+	return f.servers, nil
+}
+
+func (f *fakeStore) MatchesStorageDSN(_ string, _ int, databaseName string) bool {
+	return databaseName == f.storage
+}
+
+func checks(findings []Finding) []string {
+	names := make([]string, 0, len(findings))
+	for _, f := range findings {
+		names = append(names, f.Check)
+	}
+
+	return names
+}
+
+func secureConfig() *config.Config {
+	return &config.Config{
+		ListenAPI:   "127.0.0.1:4200",
+		ListenPG:    ":5434",
+		ListenMySQL: ":3307",
+		Hash:        config.HashConfig{Algorithm: "argon2id"},
+		AuthCache:   config.AuthCacheConfig{Enabled: true},
+	}
+}
+
+func TestConfigFindings(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, ConfigFindings(secureConfig()))
+
+	cfg := secureConfig()
+	cfg.PG.TLS.Disable = true
+	cfg.Mongo.TLS.Disable = true // The Mongo listener is not enabled
+	assert.Equal(t, []string{"proxy_tls_disabled"}, checks(ConfigFindings(cfg)))
+
+	cfg = secureConfig()
+	cfg.PG.Listeners = []config.PGListenerConfig{
+		{Name: "vpn", Addr: ":5444"},
+		{Name: "internal", Addr: ":5445", TLS: &config.TLSConfig{Disable: true}},
+	}
+	assert.Equal(t, []string{"proxy_tls_disabled"}, checks(ConfigFindings(cfg)), "only the listener without TLS")
+
+	cfg = secureConfig()
+	cfg.LDAP = config.LDAPConfig{URL: "ldap://ad.example.com", BaseDN: "DC=example"}
+	assert.Equal(t, []string{"ldap_plaintext"}, checks(ConfigFindings(cfg)))
+
+	cfg.LDAP.StartTLS = true
+	assert.Empty(t, ConfigFindings(cfg))
+
+	cfg = secureConfig()
+	cfg.Audit.Syslog = config.AuditSyslogConfig{Address: "siem.example.com:514", Network: "udp"}
+	assert.Equal(t, []string{"audit_syslog_plaintext"}, checks(ConfigFindings(cfg)))
+
+	cfg.Audit.Syslog.Address = "127.0.0.1:514"
+	assert.Empty(t, ConfigFindings(cfg), "a local syslog daemon")
+
+	cfg = secureConfig()
+	cfg.Hash.Preset = "minimal"
+	assert.Empty(t, ConfigFindings(cfg), "a minimal preset is acceptable with the auth cache")
+
+	cfg.AuthCache.Enabled = false
+	assert.Equal(t, []string{"weak_password_hashing"}, checks(ConfigFindings(cfg)))
+
+	cfg = secureConfig()
+	cfg.RunMode = config.RunModeDemo
+	assert.Equal(t, []string{"exposed_demo_mode"}, checks(ConfigFindings(cfg)))
+
+	cfg.ListenPG, cfg.ListenMySQL = "localhost:5434", ""
+	assert.Empty(t, ConfigFindings(cfg), "demo mode on loopback only")
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	defaultHash, err := crypto.HashPassword("admin")
+	require.NoError(t, err)
+
+	s := &fakeStore{
+		admin:   &store.User{Username: "admin", PasswordHash: defaultHash},
+		servers: []store.Server{{Name: "app", DatabaseName: "app"}, {Name: "self", DatabaseName: "dbbat"}},
+		storage: "dbbat",
+	}
+
+	assert.Equal(t, []string{"default_admin_password", "storage_dsn_target"}, checks(Check(ctx, secureConfig(), s, logger)))
+
+	cfg := secureConfig()
+	cfg.ListenAPI, cfg.ListenPG, cfg.ListenMySQL = "127.0.0.1:4200", "127.0.0.1:5434", ""
+	cfg.RunMode = config.RunModeDemo
+	assert.Equal(t, []string{"storage_dsn_target"}, checks(Check(ctx, cfg, s, logger)), "demo mode uses admin/admin on purpose")
+
+	changedHash, err := crypto.HashPassword("a much better password")
+	require.NoError(t, err)
+
+	s.admin.PasswordHash = changedHash
+	s.storage = ""
+	assert.Empty(t, Check(ctx, secureConfig(), s, logger))
+}
+
+func TestIsLoopback(t *testing.T) {
+	t.Parallel()
+
+	for addr, want := range map[string]bool{
+		"127.0.0.1:4200": true,
+		"[::1]:4200":     true,
+		"localhost:4200": true,
+		":4200":          false,
+		"0.0.0.0:4200":   false,
+		"10.0.0.5:4200":  false,
+		"dbbat:4200":     false,
+	} {
+		assert.Equal(t, want, IsLoopback(addr), addr)
+	}
+}
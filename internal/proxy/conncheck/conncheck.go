@@ -24,6 +24,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/secrets"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -89,9 +90,21 @@ const (
 	// CodeDBHandshakeFailed means the target was reachable but the protocol
 	// handshake did not complete (wrong port, TLS mismatch, not a database).
 	CodeDBHandshakeFailed Code = "db_handshake_failed"
+	// CodeSecretUnavailable means the password could not be fetched from the
+	// secrets manager the row's secret_ref points at.
+	CodeSecretUnavailable Code = "secret_unavailable"
 	// CodeMissingConfig means the row is missing a field the protocol needs
 	// before anything can be dialed (an Oracle row with no service name).
 	CodeMissingConfig Code = "missing_config"
+	// CodePrivilegesNotChecked means the credentials were accepted but DBBat
+	// cannot read the privileges of an account on this protocol.
+	CodePrivilegesNotChecked Code = "privileges_not_checked"
+	// CodeInspectFailed means the credentials were accepted but reading the
+	// account's privileges failed.
+	CodeInspectFailed Code = "inspect_failed"
+	// CodeExplainFailed means the credentials were accepted but the
+	// statement could not be explained.
+	CodeExplainFailed Code = "explain_failed"
 	// CodeUnsupported means no protocol-level probe exists for this protocol;
 	// reachability was verified but credentials were not.
 	CodeUnsupported Code = "auth_not_verified"
@@ -240,11 +253,34 @@ func (c *Checker) checkTarget(ctx context.Context, dialer *shared.Dialer, srv *s
 		}
 	}
 
+	res := c.runProbe(ctx, dialer, srv, probe)
+	if res.OK {
+		res.Message = "target reachable and the stored credentials were accepted"
+	}
+
+	return res
+}
+
+// runProbe decrypts the target's password and runs probe over the target's
+// transport, classifying a failure by the stage it happened at.
+func (c *Checker) runProbe(ctx context.Context, dialer *shared.Dialer, srv *store.Server, probe probe) Result {
 	// Decrypt the target's own password for the login probe. Bastion secrets are
 	// decrypted inside the shared dialer.
 	// An empty ciphertext means "no password stored" — a legitimate configuration
-	// (trust/peer auth), not a decryption failure.
-	if len(srv.PasswordEncrypted) > 0 {
+	// (trust/peer auth), not a decryption failure. A secret_ref is fetched
+	// from its secrets manager instead.
+	if srv.SecretRef != "" {
+		password, err := secrets.Resolve(ctx, srv.SecretRef)
+		if err != nil {
+			return Result{
+				Stage:   StageConfig,
+				Code:    CodeSecretUnavailable,
+				Message: "failed to fetch the database password from the secrets manager: " + err.Error(),
+			}
+		}
+
+		srv.Password = password
+	} else if len(srv.PasswordEncrypted) > 0 {
 		if err := srv.DecryptPassword(c.encryptionKey); err != nil {
 			return Result{
 				Stage:   StageConfig,
@@ -321,12 +357,7 @@ func (c *Checker) checkTarget(ctx context.Context, dialer *shared.Dialer, srv *s
 		return classifyTargetError(probeErr)
 	}
 
-	return Result{
-		OK:      true,
-		Stage:   StageTargetAuth,
-		Code:    CodeOK,
-		Message: "target reachable and the stored credentials were accepted",
-	}
+	return Result{OK: true, Stage: StageTargetAuth, Code: CodeOK}
 }
 
 // classifySSHError maps a bastion connect failure onto a stage + code. The
@@ -487,6 +518,22 @@ func classifyTargetError(err error) Result {
 		}
 	}
 
+	if errors.Is(err, errInspectFailed) {
+		return Result{
+			Stage:   StageTargetAuth,
+			Code:    CodeInspectFailed,
+			Message: "the stored credentials were accepted, but " + sanitize(err),
+		}
+	}
+
+	if errors.Is(err, errExplainFailed) {
+		return Result{
+			Stage:   StageTargetAuth,
+			Code:    CodeExplainFailed,
+			Message: "the stored credentials were accepted, but " + sanitize(err),
+		}
+	}
+
 	if res := classifyNetworkError(err, StageTargetAuth); res.Code != CodeInternal {
 		// A timeout mid-handshake is still a handshake problem, but the network
 		// classification is the more useful message.
@@ -0,0 +1,118 @@
+package conncheck
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// errExplainFailed marks an EXPLAIN that logged in but could not plan the
+// statement: the credentials are fine, the statement is not (a table the
+// account cannot read, a search_path the session had set, ...).
+var errExplainFailed = errors.New("the statement could not be explained")
+
+// Explain logs in to a PostgreSQL target with its stored credentials, like
+// Check, and returns the plan EXPLAIN (FORMAT JSON) prints for sql, with the
+// parameters it was run with. The statement is planned, never run, inside a
+// read-only transaction that is not committed. The result is OK when the
+// plan was read; the plan is nil otherwise.
+func (c *Checker) Explain(ctx context.Context, srv *store.Server, sql string, params *store.QueryParameters) (Result, json.RawMessage) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	dialer := shared.NewDialer()
+	defer dialer.Close()
+
+	var plan json.RawMessage
+
+	res := c.runProbe(ctx, dialer, srv, func(ctx context.Context, srv *store.Server, dial dialFunc) error {
+		p, err := explainPostgres(ctx, srv, dial, sql, params, c.timeout)
+		plan = p
+
+		return err
+	})
+	if res.OK {
+		res.Message = "the statement was explained with the stored credentials"
+	} else {
+		plan = nil
+	}
+
+	res.DurationMs = time.Since(start).Milliseconds()
+
+	return res, plan
+}
+
+// explainPostgres plans sql over a fresh login, bounded by timeout upstream
+// too, and returns the JSON plan.
+func explainPostgres(ctx context.Context, srv *store.Server, dial dialFunc, sql string, params *store.QueryParameters, timeout time.Duration) (json.RawMessage, error) {
+	conn, err := connectPostgres(ctx, srv, dial)
+	if err != nil {
+		return nil, err
+	}
+
+	// Closing the connection rolls the transaction back.
+	defer func() { _ = conn.Close(context.WithoutCancel(ctx)) }()
+
+	begin := fmt.Sprintf("BEGIN READ ONLY; SET LOCAL statement_timeout = %d", timeout.Milliseconds())
+	if _, err := conn.Exec(ctx, begin).ReadAll(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errExplainFailed, err)
+	}
+
+	values, formats, oids, err := explainParameters(params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errExplainFailed, err)
+	}
+
+	result := conn.ExecParams(ctx, "EXPLAIN (FORMAT JSON) "+sql, values, oids, formats, nil).Read()
+	if result.Err != nil {
+		return nil, fmt.Errorf("%w: %w", errExplainFailed, result.Err)
+	}
+
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 || !json.Valid(result.Rows[0][0]) {
+		return nil, fmt.Errorf("%w: unexpected EXPLAIN output", errExplainFailed)
+	}
+
+	return json.RawMessage(result.Rows[0][0]), nil
+}
+
+// explainParameters rebuilds the Bind parameters of a logged query: its raw
+// values, in their format, with the type OIDs of the Parse. NULL and empty
+// values were both logged empty, and are replayed as NULL, which plans alike.
+func explainParameters(params *store.QueryParameters) ([][]byte, []int16, []uint32, error) {
+	if params == nil || len(params.Raw) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	values := make([][]byte, len(params.Raw))
+	formats := make([]int16, len(params.Raw))
+	oids := make([]uint32, len(params.Raw))
+
+	for i, raw := range params.Raw {
+		if raw != "" {
+			value, err := base64.StdEncoding.DecodeString(raw)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("parameter $%d: %w", i+1, err)
+			}
+
+			values[i] = value
+		}
+
+		if i < len(params.FormatCodes) {
+			formats[i] = params.FormatCodes[i]
+		}
+
+		if i < len(params.TypeOIDs) {
+			oids[i] = params.TypeOIDs[i]
+		}
+	}
+
+	return values, formats, oids, nil
+}
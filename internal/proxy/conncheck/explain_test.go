@@ -0,0 +1,40 @@
+package conncheck
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestExplainParameters(t *testing.T) {
+	t.Parallel()
+
+	values, formats, oids, err := explainParameters(nil)
+	if err != nil || values != nil || formats != nil || oids != nil {
+		t.Fatalf("explainParameters(nil) = %v, %v, %v, %v, want nothing", values, formats, oids, err)
+	}
+
+	values, formats, oids, err = explainParameters(&store.QueryParameters{
+		Values:      []string{"42", "", "7"},
+		Raw:         []string{base64.StdEncoding.EncodeToString([]byte("42")), "", base64.StdEncoding.EncodeToString([]byte{0, 0, 0, 7})},
+		FormatCodes: []int16{0, 0, 1},
+		TypeOIDs:    []uint32{23},
+	})
+	if err != nil {
+		t.Fatalf("explainParameters() error = %v", err)
+	}
+
+	if string(values[0]) != "42" || values[1] != nil || !slices.Equal(values[2], []byte{0, 0, 0, 7}) {
+		t.Errorf("values = %q, want 42, NULL and a binary 7", values)
+	}
+
+	if !slices.Equal(formats, []int16{0, 0, 1}) || !slices.Equal(oids, []uint32{23, 0, 0}) {
+		t.Errorf("formats = %v, oids = %v, want [0 0 1] and [23 0 0]", formats, oids)
+	}
+
+	if _, _, _, err := explainParameters(&store.QueryParameters{Raw: []string{"not base64!"}}); err == nil {
+		t.Error("explainParameters() accepted a malformed raw value")
+	}
+}
@@ -0,0 +1,179 @@
+package conncheck
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// monitorConcurrency bounds the checks a monitor pass runs at once, so a
+// pass over many unreachable targets does not take DefaultTimeout each.
+const monitorConcurrency = 4
+
+// Health converts the result of a check of serverID into the server's
+// recorded health.
+func (r Result) Health(serverID uuid.UUID, checkedAt time.Time) *store.ServerHealth {
+	return &store.ServerHealth{
+		ServerID:   serverID,
+		CheckedAt:  checkedAt,
+		OK:         r.OK,
+		Stage:      string(r.Stage),
+		Code:       string(r.Code),
+		Message:    r.Message,
+		DurationMs: r.DurationMs,
+	}
+}
+
+// Monitor checks every database target in the background and records the
+// outcome as the server's health, so admins see a broken configuration
+// before a user hits it. Every instance runs one; a server another instance
+// (or an admin) checked less than half an interval ago is skipped.
+type Monitor struct {
+	store    *store.Store
+	checker  *Checker
+	interval time.Duration
+	logger   *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewMonitor creates a monitor checking each database target every interval;
+// Start begins checking.
+func NewMonitor(dataStore *store.Store, encryptionKey []byte, interval time.Duration, logger *slog.Logger) *Monitor {
+	return &Monitor{
+		store:    dataStore,
+		checker:  New(dataStore, encryptionKey),
+		interval: interval,
+		logger:   logger.With(slog.String("component", "health_checks")),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs a first pass right away, then one every interval until
+// Shutdown.
+func (m *Monitor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	go m.run(ctx)
+}
+
+// Shutdown stops the monitor, interrupting a pass in progress, and waits for
+// it to return.
+func (m *Monitor) Shutdown(ctx context.Context) error {
+	m.cancel()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("health check monitor shutdown interrupted: %w", ctx.Err())
+	}
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.checkAll(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAll checks the database targets not checked recently and records
+// their health. Failures to list or record are logged: the next pass tries
+// again.
+func (m *Monitor) checkAll(ctx context.Context, now time.Time) {
+	servers, err := m.store.ListServers(ctx, store.ServerFilter{})
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.ErrorContext(ctx, "failed to list servers", slog.Any("error", err))
+		}
+
+		return
+	}
+
+	previous, err := m.store.ListServerHealth(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.ErrorContext(ctx, "failed to list server health", slog.Any("error", err))
+		}
+
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	slots := make(chan struct{}, monitorConcurrency)
+
+	for i := range servers {
+		srv := &servers[i]
+
+		last := previous[srv.UID]
+		if last != nil && now.Sub(last.CheckedAt) < m.interval/2 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+
+			return
+		case slots <- struct{}{}:
+		}
+
+		wg.Go(func() {
+			defer func() { <-slots }()
+
+			m.check(ctx, srv, last)
+		})
+	}
+
+	wg.Wait()
+}
+
+// check runs one check and records it, logging when the server's health
+// changed since last.
+func (m *Monitor) check(ctx context.Context, srv *store.Server, last *store.ServerHealth) {
+	res := m.checker.Check(ctx, srv)
+	if ctx.Err() != nil {
+		// Interrupted by Shutdown: the timeout says nothing about the server.
+		return
+	}
+
+	if err := m.store.RecordServerHealth(ctx, res.Health(srv.UID, time.Now())); err != nil {
+		m.logger.ErrorContext(ctx, "failed to record server health",
+			slog.String("server_uid", srv.UID.String()), slog.Any("error", err))
+
+		return
+	}
+
+	switch {
+	case !res.OK && (last == nil || last.OK):
+		m.logger.WarnContext(ctx, "server health check failed",
+			slog.String("server_uid", srv.UID.String()),
+			slog.String("server", srv.Name),
+			slog.String("stage", string(res.Stage)),
+			slog.String("code", string(res.Code)),
+			slog.String("message", res.Message))
+	case res.OK && last != nil && !last.OK:
+		m.logger.InfoContext(ctx, "server health check recovered",
+			slog.String("server_uid", srv.UID.String()),
+			slog.String("server", srv.Name))
+	}
+}
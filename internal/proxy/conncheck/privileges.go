@@ -0,0 +1,286 @@
+package conncheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// errInspectFailed marks a privilege inspection that logged in but could not
+// read the account's privileges: the credentials are fine, the report is not.
+var errInspectFailed = errors.New("privileges could not be read")
+
+// Privileges is what the stored service account can do upstream. DBBat only
+// relays the SELECT and DML of its users, so anything beyond that is a
+// privilege the proxy credential does not need, listed in Warnings.
+type Privileges struct {
+	// Role is the account the upstream sees the proxy as.
+	Role        string `json:"role"`
+	Superuser   bool   `json:"superuser"`
+	CreateDB    bool   `json:"createdb"`
+	CreateRole  bool   `json:"createrole"`
+	Replication bool   `json:"replication"`
+	// BypassRLS is PostgreSQL only: row-level security is not applied.
+	BypassRLS bool `json:"bypass_rls"`
+	// MemberOf lists the roles the account inherits from.
+	MemberOf []string `json:"member_of"`
+	// Grants are the account's GRANT statements, as SHOW GRANTS prints them
+	// (MySQL and MariaDB only).
+	Grants []string `json:"grants,omitempty"`
+	// Warnings explain each privilege the proxy credential does not need.
+	Warnings []string `json:"warnings"`
+}
+
+// inspector logs in to the target over dial and reads the account's privileges.
+type inspector func(ctx context.Context, srv *store.Server, dial dialFunc) (*Privileges, error)
+
+// inspectorFor returns the privilege inspector for a protocol, or nil when
+// DBBat cannot read an account's privileges on it.
+func inspectorFor(protocol string) inspector {
+	switch protocol {
+	case store.ProtocolPostgreSQL:
+		return inspectPostgres
+	case store.ProtocolMySQL, store.ProtocolMariaDB:
+		return inspectMySQL
+	default:
+		return nil
+	}
+}
+
+// CheckPrivileges logs in to a database target with its stored credentials,
+// like Check, and reports what the account can do upstream. The result is
+// OK when the credentials were accepted, whatever the privileges; the
+// privileges are nil when the protocol has no inspector or the check failed.
+func (c *Checker) CheckPrivileges(ctx context.Context, srv *store.Server) (Result, *Privileges) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	dialer := shared.NewDialer()
+	defer dialer.Close()
+
+	var (
+		res        Result
+		privileges *Privileges
+	)
+
+	if inspect := inspectorFor(srv.Protocol); inspect == nil {
+		res = c.checkTarget(ctx, dialer, srv)
+		if res.OK {
+			res.Code = CodePrivilegesNotChecked
+			res.Message = "the target is reachable, but DBBat cannot read the privileges of a " + srv.Protocol + " account"
+		}
+	} else {
+		res = c.runProbe(ctx, dialer, srv, func(ctx context.Context, srv *store.Server, dial dialFunc) error {
+			p, err := inspect(ctx, srv, dial)
+			privileges = p
+
+			return err
+		})
+		if res.OK {
+			res.Message = fmt.Sprintf("the stored credentials were accepted; %d privilege warning(s)", len(privileges.Warnings))
+		} else {
+			privileges = nil
+		}
+	}
+
+	res.DurationMs = time.Since(start).Milliseconds()
+
+	return res, privileges
+}
+
+// pgPrivilegesQuery reads the current role's attributes and, comma-separated,
+// every role it is a member of, directly or not.
+const pgPrivilegesQuery = `SELECT r.rolname, r.rolsuper, r.rolcreatedb, r.rolcreaterole, r.rolreplication, r.rolbypassrls,
+	COALESCE((SELECT string_agg(m.rolname, ',' ORDER BY m.rolname) FROM pg_roles m
+		WHERE m.oid <> r.oid AND pg_has_role(r.oid, m.oid, 'MEMBER')), '')
+	FROM pg_roles r WHERE r.rolname = current_user`
+
+// inspectPostgres reads the role attributes and memberships of the account.
+func inspectPostgres(ctx context.Context, srv *store.Server, dial dialFunc) (*Privileges, error) {
+	conn, err := connectPostgres(ctx, srv, dial)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = conn.Close(context.WithoutCancel(ctx)) }()
+
+	result := conn.ExecParams(ctx, pgPrivilegesQuery, nil, nil, nil, nil).Read()
+	if result.Err != nil {
+		return nil, fmt.Errorf("%w: %w", errInspectFailed, result.Err)
+	}
+
+	if len(result.Rows) != 1 {
+		return nil, fmt.Errorf("%w: the current role was not found in pg_roles", errInspectFailed)
+	}
+
+	return postgresPrivileges(result.Rows[0]), nil
+}
+
+// postgresPrivileges builds the report from a row of pgPrivilegesQuery.
+func postgresPrivileges(row [][]byte) *Privileges {
+	isTrue := func(v []byte) bool { return string(v) == "t" }
+
+	p := &Privileges{
+		Role:        string(row[0]),
+		Superuser:   isTrue(row[1]),
+		CreateDB:    isTrue(row[2]),
+		CreateRole:  isTrue(row[3]),
+		Replication: isTrue(row[4]),
+		BypassRLS:   isTrue(row[5]),
+		MemberOf:    []string{},
+		Warnings:    []string{},
+	}
+
+	if len(row[6]) > 0 {
+		p.MemberOf = strings.Split(string(row[6]), ",")
+	}
+
+	if p.Superuser {
+		p.Warnings = append(p.Warnings, "the role is a superuser: it bypasses every permission check, so grants cannot restrict what proxied sessions do")
+	}
+
+	if p.CreateRole {
+		p.Warnings = append(p.Warnings, "the role has CREATEROLE: proxied sessions can create roles and grant them its privileges")
+	}
+
+	if p.CreateDB {
+		p.Warnings = append(p.Warnings, "the role has CREATEDB: proxied sessions can create databases")
+	}
+
+	if p.Replication {
+		p.Warnings = append(p.Warnings, "the role has REPLICATION: it can stream the whole cluster's changes")
+	}
+
+	if p.BypassRLS {
+		p.Warnings = append(p.Warnings, "the role has BYPASSRLS: row-level security policies do not apply to proxied sessions")
+	}
+
+	for _, role := range p.MemberOf {
+		switch role {
+		case "pg_read_server_files", "pg_write_server_files", "pg_execute_server_program":
+			p.Warnings = append(p.Warnings, "the role is a member of "+role+": proxied sessions can reach the database server's files or shell")
+		}
+	}
+
+	return p
+}
+
+// inspectMySQL reads the account and its grants.
+func inspectMySQL(ctx context.Context, srv *store.Server, dial dialFunc) (*Privileges, error) {
+	conn, err := connectMySQL(ctx, srv, dial)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	res, err := conn.Execute("SELECT CURRENT_USER()")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInspectFailed, err)
+	}
+
+	role, err := res.GetString(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInspectFailed, err)
+	}
+
+	if res, err = conn.Execute("SHOW GRANTS"); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInspectFailed, err)
+	}
+
+	grants := make([]string, 0, res.RowNumber())
+
+	for i := range res.RowNumber() {
+		grant, err := res.GetString(i, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInspectFailed, err)
+		}
+
+		grants = append(grants, grant)
+	}
+
+	return mysqlPrivileges(role, grants), nil
+}
+
+// mysqlPrivilegeWarnings are the global privileges a proxy account does not
+// need, and why they matter.
+var mysqlPrivilegeWarnings = map[string]string{
+	"SUPER":             "SUPER: it can change the server's configuration and kill other sessions",
+	"FILE":              "FILE: proxied sessions can read and write files on the database server",
+	"PROCESS":           "PROCESS: it can see every session's statements",
+	"SHUTDOWN":          "SHUTDOWN: proxied sessions can stop the server",
+	"RELOAD":            "RELOAD: it can flush logs, privileges and tables",
+	"CREATE USER":       "CREATE USER: proxied sessions can create accounts",
+	"CREATE ROLE":       "CREATE ROLE: proxied sessions can create roles",
+	"REPLICATION SLAVE": "REPLICATION SLAVE: it can stream the binary log of every database",
+}
+
+// mysqlPrivileges builds the report from the account's SHOW GRANTS lines.
+func mysqlPrivileges(role string, grants []string) *Privileges {
+	p := &Privileges{Role: role, MemberOf: []string{}, Grants: grants, Warnings: []string{}}
+	warned := make(map[string]bool)
+
+	warn := func(msg string) {
+		if !warned[msg] {
+			warned[msg] = true
+			p.Warnings = append(p.Warnings, msg)
+		}
+	}
+
+	for _, grant := range grants {
+		body := strings.TrimPrefix(grant, "GRANT ")
+
+		if strings.Contains(grant, "WITH GRANT OPTION") {
+			warn("the account has GRANT OPTION: proxied sessions can hand its privileges to other accounts")
+		}
+
+		privs, rest, found := strings.Cut(body, " ON ")
+		if !found {
+			// GRANT `role`@`host` TO `user`@`host`: a role membership
+			if role, _, ok := strings.Cut(body, " TO "); ok {
+				p.MemberOf = append(p.MemberOf, role)
+			}
+
+			continue
+		}
+
+		if object, _, _ := strings.Cut(rest, " TO "); strings.TrimSpace(object) != "*.*" {
+			continue // database or table privileges are the proxy's business
+		}
+
+		for _, priv := range strings.Split(privs, ",") {
+			priv, _, _ = strings.Cut(priv, "(") // column privileges
+			priv = strings.ToUpper(strings.TrimSpace(priv))
+
+			switch {
+			case priv == "ALL PRIVILEGES" || priv == "ALL":
+				p.Superuser, p.CreateDB, p.CreateRole, p.Replication = true, true, true, true
+				warn("the account has ALL PRIVILEGES on *.*: it is an administrator, so grants cannot restrict what proxied sessions do")
+			case priv == "SUPER":
+				p.Superuser = true
+			case priv == "CREATE":
+				p.CreateDB = true
+				warn("the account has CREATE on *.*: proxied sessions can create databases")
+			case priv == "CREATE USER" || priv == "CREATE ROLE":
+				p.CreateRole = true
+			case priv == "REPLICATION SLAVE":
+				p.Replication = true
+			case strings.HasSuffix(priv, "_ADMIN"):
+				warn("the account has " + priv + ": an administrative privilege proxied sessions do not need")
+			}
+
+			if why, ok := mysqlPrivilegeWarnings[priv]; ok {
+				warn("the account has " + why)
+			}
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,77 @@
+package conncheck
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPostgresPrivileges(t *testing.T) {
+	t.Parallel()
+
+	row := func(values ...string) [][]byte {
+		out := make([][]byte, len(values))
+		for i, v := range values {
+			out[i] = []byte(v)
+		}
+
+		return out
+	}
+
+	minimal := postgresPrivileges(row("app", "f", "f", "f", "f", "f", "app_readers"))
+	if minimal.Role != "app" || minimal.Superuser || len(minimal.Warnings) != 0 {
+		t.Errorf("minimal role = %+v, want no warnings", minimal)
+	}
+
+	if !slices.Equal(minimal.MemberOf, []string{"app_readers"}) {
+		t.Errorf("MemberOf = %v, want [app_readers]", minimal.MemberOf)
+	}
+
+	admin := postgresPrivileges(row("postgres", "t", "t", "t", "t", "t", "pg_read_all_data,pg_write_server_files"))
+	if !admin.Superuser || !admin.CreateDB || !admin.CreateRole || !admin.Replication || !admin.BypassRLS {
+		t.Errorf("superuser role attributes = %+v", admin)
+	}
+
+	// One warning per attribute, plus pg_write_server_files but not pg_read_all_data.
+	if len(admin.Warnings) != 6 {
+		t.Errorf("superuser role warnings = %q, want 6", admin.Warnings)
+	}
+}
+
+func TestMySQLPrivileges(t *testing.T) {
+	t.Parallel()
+
+	minimal := mysqlPrivileges("app@%", []string{
+		"GRANT USAGE ON *.* TO `app`@`%`",
+		"GRANT SELECT, INSERT, UPDATE, DELETE, CREATE ON `shop`.* TO `app`@`%`",
+		"GRANT `readers`@`%` TO `app`@`%`",
+	})
+	if minimal.Superuser || minimal.CreateDB || len(minimal.Warnings) != 0 {
+		t.Errorf("minimal account = %+v, want no warnings", minimal)
+	}
+
+	if !slices.Equal(minimal.MemberOf, []string{"`readers`@`%`"}) {
+		t.Errorf("MemberOf = %v, want the readers role", minimal.MemberOf)
+	}
+
+	root := mysqlPrivileges("root@localhost", []string{
+		"GRANT ALL PRIVILEGES ON *.* TO 'root'@'localhost' WITH GRANT OPTION",
+	})
+	if !root.Superuser || !root.CreateDB || !root.CreateRole || len(root.Warnings) != 2 {
+		t.Errorf("root account = %+v, want an administrator with 2 warnings", root)
+	}
+
+	ops := mysqlPrivileges("ops@%", []string{
+		"GRANT SELECT, FILE, PROCESS, CREATE USER ON *.* TO `ops`@`%`",
+		"GRANT BACKUP_ADMIN,SYSTEM_VARIABLES_ADMIN ON *.* TO `ops`@`%`",
+	})
+	if ops.Superuser || !ops.CreateRole {
+		t.Errorf("ops account = %+v, want CREATE USER but no superuser", ops)
+	}
+
+	for _, want := range []string{"FILE", "PROCESS", "CREATE USER", "BACKUP_ADMIN", "SYSTEM_VARIABLES_ADMIN"} {
+		if !slices.ContainsFunc(ops.Warnings, func(w string) bool { return strings.Contains(w, want) }) {
+			t.Errorf("ops account warnings = %q, missing %s", ops.Warnings, want)
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
 	"github.com/fclairamb/dbbat/internal/store"
 	"github.com/fclairamb/dbbat/internal/version"
 )
@@ -69,11 +70,22 @@ func probeAppName() string {
 // probePostgres opens a real startup+auth exchange with the upstream using the
 // same pgx stack the PostgreSQL proxy speaks, over the injected transport.
 func probePostgres(ctx context.Context, srv *store.Server, dial dialFunc) error {
+	conn, err := connectPostgres(ctx, srv, dial)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close(ctx)
+}
+
+// connectPostgres logs in to the upstream PostgreSQL over the injected
+// transport and returns the open connection.
+func connectPostgres(ctx context.Context, srv *store.Server, dial dialFunc) (*pgconn.PgConn, error) {
 	// pgconn.Config must come from ParseConfig; every field that matters is
 	// overridden below, so the environment cannot influence the probe.
 	cfg, err := pgconn.ParseConfig("postgres://")
 	if err != nil {
-		return fmt.Errorf("build postgres probe config: %w", err)
+		return nil, fmt.Errorf("build postgres probe config: %w", err)
 	}
 
 	cfg.Host = srv.Host
@@ -87,50 +99,59 @@ func probePostgres(ctx context.Context, srv *store.Server, dial dialFunc) error
 	cfg.DialFunc = func(dialCtx context.Context, _, _ string) (net.Conn, error) {
 		return dial(dialCtx)
 	}
-	cfg.TLSConfig = postgresTLSConfig(srv)
-
-	conn, err := pgconn.ConnectConfig(ctx, cfg)
-	if err != nil {
-		return err
+	if cfg.TLSConfig, err = postgresTLSConfig(srv); err != nil {
+		return nil, err
 	}
 
-	return conn.Close(ctx)
+	return pgconn.ConnectConfig(ctx, cfg)
 }
 
 // postgresTLSConfig mirrors libpq ssl_mode semantics for the probe. "prefer"
 // deliberately maps to nil (plaintext): pgconn cannot express opportunistic TLS
 // on a single config, and a probe that silently fell back would report a
 // misleading stage.
-func postgresTLSConfig(srv *store.Server) *tls.Config {
+func postgresTLSConfig(srv *store.Server) (*tls.Config, error) {
 	switch srv.SSLMode {
-	case "require":
-		return &tls.Config{InsecureSkipVerify: true}
-	case "verify-ca", "verify-full":
-		return &tls.Config{MinVersion: tls.VersionTLS12, ServerName: srv.Host}
+	case "require", "verify-ca", "verify-full":
+		return shared.UpstreamTLSConfig(srv)
 	default:
-		return nil
+		return nil, nil //nolint:nilnil // nil = plaintext
 	}
 }
 
 // probeMySQL logs in with go-mysql's client — the same library and the same
 // ConnectWithDialer entry point the MySQL proxy uses upstream.
 func probeMySQL(ctx context.Context, srv *store.Server, dial dialFunc) error {
+	conn, err := connectMySQL(ctx, srv, dial)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// connectMySQL logs in to the upstream MySQL or MariaDB over the injected
+// transport and returns the open connection.
+func connectMySQL(ctx context.Context, srv *store.Server, dial dialFunc) (*gomysqlclient.Conn, error) {
 	addr := net.JoinHostPort(srv.Host, strconv.Itoa(srv.Port))
 
 	dialer := func(dialCtx context.Context, _, _ string) (net.Conn, error) {
 		return dial(dialCtx)
 	}
 
-	conn, err := gomysqlclient.ConnectWithDialer(
+	return gomysqlclient.ConnectWithDialer(
 		ctx, "tcp", addr,
 		srv.Username, srv.Password, srv.DatabaseName,
 		dialer,
 		func(c *gomysqlclient.Conn) error {
 			switch srv.SSLMode {
-			case "require":
-				c.UseSSL(true)
-			case "verify-ca", "verify-full":
-				c.SetTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12, ServerName: srv.Host})
+			case "require", "verify-ca", "verify-full":
+				tlsConfig, err := shared.UpstreamTLSConfig(srv)
+				if err != nil {
+					return err
+				}
+
+				c.SetTLSConfig(tlsConfig)
 			}
 			// Same defense-in-depth as the proxy: never let an upstream ask the
 			// dbbat host to read local files.
@@ -140,11 +161,6 @@ func probeMySQL(ctx context.Context, srv *store.Server, dial dialFunc) error {
 			return nil
 		},
 	)
-	if err != nil {
-		return err
-	}
-
-	return conn.Close()
 }
 
 // probeMongo authenticates against the target with the mongo driver, pinned to
@@ -164,6 +180,17 @@ func probeMongo(ctx context.Context, srv *store.Server, dial dialFunc) error {
 			AuthSource: srv.MongoAuthSourceOrDefault(),
 		})
 
+	switch srv.SSLMode {
+	case "require", "verify-ca", "verify-full":
+		tlsConfig, err := shared.UpstreamTLSConfig(srv)
+		if err != nil {
+			return err
+		}
+
+		// The driver runs the handshake over the conn our dialer returns.
+		opts.SetTLSConfig(tlsConfig)
+	}
+
 	client, err := mongo.Connect(opts)
 	if err != nil {
 		return err
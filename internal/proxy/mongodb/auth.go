@@ -100,7 +100,10 @@ func (s *Session) authorizeAndEstablish(responseTo int32, user *store.User, user
 
 	s.database = db
 
-	grant, err := s.server.store.GetActiveGrant(s.ctx, user.UID, db.UID)
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	grant, err := s.server.store.GetActiveGrant(lookupCtx, user.UID, db.UID)
+	cancel()
+
 	if err != nil {
 		_ = s.replyOpMsg(responseTo, errorDoc(codeAuthenticationFailed, codeNameAuthenticationFailed, ErrNoActiveGrant.Error()))
 		time.Sleep(authFailDelay)
@@ -110,6 +113,13 @@ func (s *Session) authorizeAndEstablish(responseTo int32, user *store.User, user
 
 	s.grant = grant
 
+	if err := shared.CheckControls(grant, store.ProtocolMongoDB); err != nil {
+		_ = s.replyOpMsg(responseTo, errorDoc(codeAuthenticationFailed, codeNameAuthenticationFailed, err.Error()))
+		time.Sleep(authFailDelay)
+
+		return err
+	}
+
 	if err := checkQuotas(grant); err != nil {
 		_ = s.replyOpMsg(responseTo, errorDoc(codeAuthenticationFailed, codeNameAuthenticationFailed, err.Error()))
 		time.Sleep(authFailDelay)
@@ -127,8 +137,13 @@ func (s *Session) authorizeAndEstablish(responseTo int32, user *store.User, user
 func (s *Session) establishSession(responseTo int32, grant *store.Grant) error {
 	// Register the live session so an admin revoke can signal it.
 	s.revocation = s.server.store.Revocations().Register(grant.UID)
+
+	// A command awaiting its reply keeps the session from being idle.
+	idle, maxDuration := s.server.sessionConfig.Timeouts()
 	s.guard = shared.NewLimitGuard(grant, s.bytesFromClient, s.bytesToClient).
-		WithRevocation(s.revocation.Flag())
+		WithRevocation(s.revocation.Flag()).
+		WithExtension(s.revocation.Extension()).
+		WithSessionTimeouts(idle, maxDuration, s.hasPending)
 
 	if err := s.connectUpstream(); err != nil {
 		s.deregisterRevocation()
@@ -199,7 +214,10 @@ func (s *Session) verifyCredentials(username, password string) (*store.User, err
 		return s.authenticateAPIKey(username, password)
 	}
 
-	user, err := s.server.store.GetUserByUsername(s.ctx, username)
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	user, err := s.server.store.GetUserByUsername(lookupCtx, username)
+	cancel()
+
 	if err != nil {
 		return nil, ErrAuthenticationFailed
 	}
@@ -210,7 +228,7 @@ func (s *Session) verifyCredentials(username, password string) (*store.User, err
 	)
 
 	if s.server.authCache != nil {
-		valid, verr = s.server.authCache.VerifyPassword(s.ctx, user.UID.String(), password, user.PasswordHash)
+		valid, verr = s.server.authCache.VerifyUserPassword(s.ctx, user.UID.String(), user.Username, password, user.PasswordHash)
 	} else {
 		valid, verr = crypto.VerifyPassword(user.PasswordHash, password)
 	}
@@ -219,23 +237,34 @@ func (s *Session) verifyCredentials(username, password string) (*store.User, err
 		return nil, ErrAuthenticationFailed
 	}
 
+	shared.UpgradePasswordHash(s.ctx, s.logger, s.server.store, user, password)
+
 	return user, nil
 }
 
 // authenticateAPIKey validates a dbb_ API key and checks it belongs to the
 // authenticating user.
 func (s *Session) authenticateAPIKey(username, key string) (*store.User, error) {
-	verified, err := s.server.store.VerifyAPIKey(s.ctx, key)
+	// Bounds the key verification and user lookup below.
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	verified, err := s.server.store.VerifyAPIKey(lookupCtx, key)
 	if err != nil {
 		return nil, ErrAuthenticationFailed
 	}
 
-	user, err := s.server.store.GetUserByUsername(s.ctx, username)
+	user, err := s.server.store.GetUserByUsername(lookupCtx, username)
 	if err != nil || user.UID != verified.UserID {
 		return nil, ErrAuthenticationFailed
 	}
 
-	go func() { _ = s.server.store.IncrementAPIKeyUsage(context.Background(), verified.ID) }()
+	go func() {
+		ctx, cancel := shared.StoreContext(context.Background())
+		defer cancel()
+
+		_ = s.server.store.IncrementAPIKeyUsage(ctx, verified.ID)
+	}()
 
 	return user, nil
 }
@@ -256,7 +285,10 @@ func (s *Session) resolveDatabase(user *store.User, userDBHint string) (*store.S
 	}
 
 	if name != "" {
-		db, err := s.server.store.GetServerByName(s.ctx, name)
+		lookupCtx, cancel := shared.StoreContext(s.ctx)
+		defer cancel()
+
+		db, err := s.server.store.GetServerByName(lookupCtx, name)
 		if err != nil || db.Protocol != store.ProtocolMongoDB {
 			return nil, ErrDatabaseNotResolvable
 		}
@@ -270,7 +302,11 @@ func (s *Session) resolveDatabase(user *store.User, userDBHint string) (*store.S
 // resolveSingleGrantDatabase returns the database of the user's single active
 // MongoDB grant, or an error if there is not exactly one.
 func (s *Session) resolveSingleGrantDatabase(user *store.User) (*store.Server, error) {
-	grants, err := s.server.store.ListGrants(s.ctx, store.GrantFilter{UserID: &user.UID, ActiveOnly: true})
+	// Bounds the grant and database lookups below.
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	grants, err := s.server.store.ListGrants(lookupCtx, store.GrantFilter{UserID: &user.UID, ActiveOnly: true})
 	if err != nil {
 		return nil, ErrDatabaseNotResolvable
 	}
@@ -278,7 +314,7 @@ func (s *Session) resolveSingleGrantDatabase(user *store.User) (*store.Server, e
 	var resolved *store.Server
 
 	for i := range grants {
-		db, err := s.server.store.GetServerByUID(s.ctx, grants[i].DatabaseID)
+		db, err := s.server.store.GetServerByUID(lookupCtx, grants[i].DatabaseID)
 		if err != nil || db.Protocol != store.ProtocolMongoDB {
 			continue
 		}
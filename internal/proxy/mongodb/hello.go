@@ -4,6 +4,9 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
 )
 
 // Pinned wire-version window (contract §4). maxWireVersion 21 == MongoDB 7.0:
@@ -107,7 +110,10 @@ func (s *Session) supportedMechsFor(probe string) bson.A {
 // MongoDB SCRAM-SHA-256 verifier (populated on a password change after the
 // feature shipped) — driving whether hello advertises SCRAM-SHA-256.
 func (s *Session) userHasMongoVerifier(username string) bool {
-	user, err := s.server.store.GetUserByUsername(s.ctx, username)
+	ctx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	user, err := s.server.store.GetUserByUsername(ctx, username)
 	if err != nil {
 		return false
 	}
@@ -158,3 +164,47 @@ func buildInfoDoc() bson.D {
 		{Key: "ok", Value: 1.0},
 	}
 }
+
+// recordClientMetadata keeps the client metadata document of the first hello
+// that carries one. Per the handshake spec drivers send it only on the first
+// hello of a connection; later ones are ignored so they can't rewrite it.
+func (s *Session) recordClientMetadata(request bson.Raw) {
+	if s.clientInfo == nil {
+		s.clientInfo = helloClientInfo(request)
+	}
+}
+
+// helloClientInfo reads the handshake client metadata (client.application.name,
+// client.driver.name/version, client.os.type, client.platform) from a hello
+// request. Returns nil when the request has none.
+func helloClientInfo(request bson.Raw) *store.ClientInfo {
+	if _, ok := request.Lookup("client").DocumentOK(); !ok {
+		return nil
+	}
+
+	str := func(keys ...string) string {
+		value, _ := request.Lookup(append([]string{"client"}, keys...)...).StringValueOK()
+
+		return value
+	}
+
+	info := &store.ClientInfo{
+		ApplicationName: str("application", "name"),
+		Driver:          str("driver", "name"),
+		DriverVersion:   str("driver", "version"),
+	}
+
+	for key, value := range map[string]string{"os": str("os", "type"), "platform": str("platform")} {
+		if value == "" {
+			continue
+		}
+
+		if info.Parameters == nil {
+			info.Parameters = make(map[string]string)
+		}
+
+		info.Parameters[key] = value
+	}
+
+	return info
+}
@@ -102,3 +102,35 @@ func compressionList(doc bson.D) []string {
 
 	return nil
 }
+
+// TestHelloClientInfo verifies the handshake client metadata is captured from
+// the first hello only.
+func TestHelloClientInfo(t *testing.T) {
+	t.Parallel()
+
+	s := &Session{server: &Server{}, connID: 1}
+
+	s.recordClientMetadata(mongoRaw(t, bson.D{{Key: "hello", Value: 1}}))
+	assert.Nil(t, s.clientInfo, "hello without client metadata records nothing")
+
+	s.recordClientMetadata(mongoRaw(t, bson.D{
+		{Key: "hello", Value: 1},
+		{Key: "client", Value: bson.D{
+			{Key: "application", Value: bson.D{{Key: "name", Value: "mongosh 2.3.1"}}},
+			{Key: "driver", Value: bson.D{{Key: "name", Value: "nodejs|mongosh"}, {Key: "version", Value: "6.10.0"}}},
+			{Key: "os", Value: bson.D{{Key: "type", Value: "Linux"}}},
+			{Key: "platform", Value: "Node.js v20"},
+		}},
+	}))
+	s.recordClientMetadata(mongoRaw(t, bson.D{
+		{Key: "hello", Value: 1},
+		{Key: "client", Value: bson.D{{Key: "application", Value: bson.D{{Key: "name", Value: "spoofed"}}}}},
+	}))
+
+	assert.Equal(t, &store.ClientInfo{
+		ApplicationName: "mongosh 2.3.1",
+		Driver:          "nodejs|mongosh",
+		DriverVersion:   "6.10.0",
+		Parameters:      map[string]string{"os": "Linux", "platform": "Node.js v20"},
+	}, s.clientInfo)
+}
@@ -170,7 +170,7 @@ func setupFixtureWithDumpDir(ctx context.Context, t *testing.T, dumpDir string)
 		dumpCfg = config.DumpConfig{Dir: dumpDir, MaxSize: config.DefaultDumpMaxSize, Retention: config.DefaultDumpRetention}
 	}
 
-	proxy, err := NewServer(dataStore, encKey, queryStorage, dumpCfg, nil, config.MongoConfig{}, slog.Default())
+	proxy, err := NewServer(dataStore, encKey, queryStorage, dumpCfg, nil, config.MongoConfig{}, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
 	require.NoError(t, err)
 
 	go func() { _ = proxy.Start("127.0.0.1:0") }()
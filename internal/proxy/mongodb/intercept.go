@@ -18,7 +18,7 @@ type pendingQuery struct {
 	command    string
 	sqlText    string
 	params     *store.QueryParameters
-	start      time.Time
+	timer      shared.QueryTimer
 	moreToCome bool
 	// cursorID is the server cursor a getMore iterates (item 6); 0 for other
 	// commands. Used to drop the cursor→origin link once the cursor is drained.
@@ -54,6 +54,8 @@ func (s *Session) pumpClientToUpstream() error {
 // handleClientOpMsg parses, classifies, validates, forwards and registers one
 // client OP_MSG.
 func (s *Session) handleClientOpMsg(m *message) error {
+	start := time.Now()
+
 	parsed, err := parseOpMsg(m.body)
 	if err != nil {
 		return err
@@ -72,15 +74,18 @@ func (s *Session) handleClientOpMsg(m *message) error {
 	// command after a mid-session revoke/quota-hit fails cleanly rather than
 	// waiting for the watchdog's poll interval (parity with the MySQL proxy).
 	if s.revocation != nil && s.revocation.Revoked() {
-		return s.rejectCommand(m, cmd, body, moreToCome, shared.ErrGrantRevoked)
+		return s.rejectCommand(m, start, cmd, body, moreToCome, shared.ErrGrantRevoked)
 	}
 
+	// An extension approved mid-session pushes the expiry back.
+	s.grant.ExpiresAt = s.revocation.ExpiresAt(s.grant.ExpiresAt)
+
 	if qerr := checkQuotas(s.grant); qerr != nil {
-		return s.rejectCommand(m, cmd, body, moreToCome, qerr)
+		return s.rejectCommand(m, start, cmd, body, moreToCome, qerr)
 	}
 
 	if verr := shared.ValidateMongoCommand(cmd, dbName, body, s.database, s.grant); verr != nil {
-		return s.rejectCommand(m, cmd, body, moreToCome, verr)
+		return s.rejectCommand(m, start, cmd, body, moreToCome, verr)
 	}
 
 	// Register for result capture before forwarding so a fast upstream reply
@@ -89,7 +94,7 @@ func (s *Session) handleClientOpMsg(m *message) error {
 		command:    cmd,
 		sqlText:    buildSQLText(cmd, body),
 		params:     extractParams(body),
-		start:      time.Now(),
+		timer:      shared.StartQueryTimer(start),
 		moreToCome: moreToCome,
 	}
 
@@ -98,6 +103,8 @@ func (s *Session) handleClientOpMsg(m *message) error {
 		s.annotateGetMore(pq, body)
 	}
 
+	// Marked before registering: the reply may be timed as soon as it is.
+	pq.timer.Forwarded(time.Now())
 	s.registerPending(m.requestID, pq)
 
 	if err := s.forward(m); err != nil {
@@ -120,13 +127,13 @@ func (s *Session) handleClientOpMsg(m *message) error {
 // rejectCommand handles a blocked command: for moreToCome (fire-and-forget)
 // writes it is dropped silently (the client isn't listening); otherwise an
 // Unauthorized error reply is returned to the client. Either way it is logged.
-func (s *Session) rejectCommand(m *message, cmd string, body bson.Raw, moreToCome bool, verr error) error {
+func (s *Session) rejectCommand(m *message, start time.Time, cmd string, body bson.Raw, moreToCome bool, verr error) error {
 	s.logger.InfoContext(s.ctx, "MongoDB command blocked",
 		slog.String("command", cmd),
 		slog.String("db", lookupString(body, "$db")),
 		slog.Any("error", verr))
 
-	pq := &pendingQuery{command: cmd, sqlText: buildSQLText(cmd, body), params: extractParams(body), start: time.Now()}
+	pq := &pendingQuery{command: cmd, sqlText: buildSQLText(cmd, body), params: extractParams(body), timer: shared.StartQueryTimer(start)}
 	errStr := verr.Error()
 	s.recordQuery(pq, nil, nil, &errStr)
 
@@ -168,6 +175,14 @@ func (s *Session) takePending(responseTo int32) *pendingQuery {
 	return pq
 }
 
+// hasPending reports whether a command is awaiting its upstream reply.
+func (s *Session) hasPending() bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	return len(s.pending) > 0
+}
+
 // pendingCommand returns the command name of the in-flight query for responseTo
 // without removing it (a peek), or "" if none is registered.
 func (s *Session) pendingCommand(responseTo int32) string {
@@ -1,13 +1,17 @@
 package mongodb
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -15,10 +19,11 @@ import (
 // (e.g. a bulk insert body) doesn't bloat the queries table.
 const maxSQLTextLen = 8 * 1024
 
-// captureResult correlates an upstream reply to the query that produced it and
-// records the query log row with any captured cursor rows / rowsAffected
-// (phases 2–3). Called for every upstream→client message.
-func (s *Session) captureResult(m *message) {
+// captureResult correlates an upstream reply, read whole at received, to the
+// query that produced it and records the query log row with any captured
+// cursor rows / rowsAffected (phases 2–3). Called for every upstream→client
+// message.
+func (s *Session) captureResult(m *message, received time.Time) {
 	if m.opCode != opCodeMsg {
 		return
 	}
@@ -29,6 +34,8 @@ func (s *Session) captureResult(m *message) {
 		return
 	}
 
+	pq.timer.RepliedWhole(received)
+
 	parsed, err := parseOpMsg(m.body)
 	if err != nil {
 		s.recordQuery(pq, nil, nil, nil)
@@ -180,8 +187,8 @@ func rowsAffectedFrom(body bson.Raw) *int64 {
 // cursor.nextBatch as Extended JSON QueryRows, honoring the query-storage
 // limits.
 func (s *Session) captureCursorRows(body bson.Raw) []store.QueryRow {
-	q := s.server.queryStorage
-	if !q.StoreResults {
+	q := s.database.EffectiveQueryStorage(s.server.queryStorage.Load())
+	if !q.StoreResults || !s.database.StoresResults() {
 		return nil
 	}
 
@@ -204,6 +211,14 @@ func (s *Session) captureCursorRows(body bson.Raw) []store.QueryRow {
 	}
 
 	rows := make([]store.QueryRow, 0, len(values))
+	masker := s.masker.Get(s.database)
+
+	// ns is "database.collection": the collection is the table of the
+	// masking's collection.field entries.
+	var collections []string
+	if _, collection, ok := strings.Cut(lookupString(bson.Raw(cursor), "ns"), "."); ok && masker != nil {
+		collections = []string{collection}
+	}
 
 	var totalBytes int64
 
@@ -217,7 +232,7 @@ func (s *Session) captureCursorRows(body bson.Raw) []store.QueryRow {
 			continue
 		}
 
-		extJSON, err := bson.MarshalExtJSON(doc, false, false)
+		extJSON, err := bson.MarshalExtJSON(maskDocument(masker, doc, collections), false, false)
 		if err != nil {
 			s.logger.WarnContext(s.ctx, "MongoDB row encode failed; skipping", slog.Int("row", i), slog.Any("error", err))
 
@@ -240,6 +255,31 @@ func (s *Session) captureCursorRows(body bson.Raw) []store.QueryRow {
 	return rows
 }
 
+// maskDocument replaces the top-level fields of doc that masker masks with
+// shared.MaskedValue. The document is returned as is when nothing is masked.
+func maskDocument(masker *shared.ColumnMasker, doc bson.Raw, collections []string) any {
+	if masker == nil {
+		return doc
+	}
+
+	elements, err := doc.Elements()
+	if err != nil {
+		return doc
+	}
+
+	masked := make(bson.D, 0, len(elements))
+
+	for _, elem := range elements {
+		if masker.Masks(elem.Key(), collections) {
+			masked = append(masked, bson.E{Key: elem.Key(), Value: bson.D{{Key: "$masked", Value: true}}})
+		} else {
+			masked = append(masked, bson.E{Key: elem.Key(), Value: elem.Value()})
+		}
+	}
+
+	return masked
+}
+
 // recordQuery inserts a single query log row (asynchronously) with completion
 // fields, stores captured rows, and bumps connection + grant counters. Mirrors
 // the MySQL proxy's recordQuery.
@@ -252,38 +292,49 @@ func (s *Session) recordQuery(pq *pendingQuery, rows []store.QueryRow, rowsAffec
 	bytesTransferred := total - s.lastBytesSnapshot
 	s.lastBytesSnapshot = total
 
-	durationMs := float64(time.Since(pq.start).Microseconds()) / 1000.0
+	end := time.Now()
+	durationMs := float64(end.Sub(pq.timer.Start()).Microseconds()) / 1000.0
 
 	record := &store.Query{
 		ConnectionID: s.connection.UID,
 		SQLText:      pq.sqlText,
 		Parameters:   pq.params,
-		ExecutedAt:   pq.start,
+		ExecutedAt:   pq.timer.Start(),
 		DurationMs:   &durationMs,
+		QueryTiming:  pq.timer.Timing(end),
 		RowsAffected: rowsAffected,
 		Error:        queryError,
 	}
 
-	go func() {
-		created, err := s.server.store.CreateQuery(s.ctx, record)
+	s.server.logWrites.Go(func() {
+		// Detached from the session context, which ends with the session, but
+		// bounded like any other storage call.
+		ctx, cancel := shared.StoreContext(context.WithoutCancel(s.ctx))
+		defer cancel()
+
+		defer shared.RecoverPanic(ctx, s.logger, "mongodb query log")
+
+		record.Fingerprint = sqlnorm.Fingerprint(record.SQLText, store.ProtocolMongoDB)
+
+		created, err := s.server.store.CreateQuery(ctx, record)
 		if err != nil {
-			s.logger.ErrorContext(s.ctx, "create query log failed", slog.Any("error", err))
+			s.logger.ErrorContext(ctx, "create query log failed", slog.Any("error", err))
 
 			return
 		}
 
-		if len(rows) > 0 {
-			if err := s.server.store.StoreQueryRows(s.ctx, created.UID, rows); err != nil {
-				s.logger.ErrorContext(s.ctx, "store query rows failed", slog.Any("error", err))
+		if len(rows) > 0 && !created.Repeated {
+			if err := s.server.store.StoreQueryRows(ctx, created.UID, rows); err != nil {
+				s.logger.ErrorContext(ctx, "store query rows failed", slog.Any("error", err))
 			}
 		}
 
 		if bytesTransferred > 0 {
-			if err := s.server.store.IncrementConnectionStats(s.ctx, s.connection.UID, bytesTransferred); err != nil {
-				s.logger.DebugContext(s.ctx, "increment connection stats failed", slog.Any("error", err))
+			if err := s.server.store.IncrementConnectionStats(ctx, s.connection.UID, bytesTransferred, 0); err != nil {
+				s.logger.DebugContext(ctx, "increment connection stats failed", slog.Any("error", err))
 			}
 		}
-	}()
+	})
 
 	if s.grant != nil {
 		s.grant.QueryCount++
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 
 	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -91,7 +92,10 @@ func scramReply(payload string, done bool) bson.D {
 func (s *Session) scramCredentialLookup(username string) (scram.StoredCredentials, error) {
 	bareUser, hint := splitUserDBHint(username)
 
-	user, err := s.server.store.GetUserByUsername(s.ctx, bareUser)
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	user, err := s.server.store.GetUserByUsername(lookupCtx, bareUser)
+	cancel()
+
 	if err != nil {
 		return scram.StoredCredentials{}, ErrAuthenticationFailed
 	}
@@ -15,6 +15,7 @@ import (
 	"github.com/fclairamb/dbbat/internal/cache"
 	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/dump"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -25,8 +26,10 @@ import (
 type Server struct {
 	store         *store.Store
 	encryptionKey []byte
-	queryStorage  config.QueryStorageConfig
+	queryStorage  *shared.QueryStorage // Replaced by SetQueryStorage on reload
 	dumpConfig    config.DumpConfig
+	proxyProtocol config.ProxyProtocolConfig
+	sessionConfig config.SessionConfig // Idle and maximum duration timeouts of the sessions
 	authCache     *cache.AuthCache
 	logger        *slog.Logger
 
@@ -49,6 +52,9 @@ type Server struct {
 	listenerMu sync.Mutex
 	listener   net.Listener
 	wg         sync.WaitGroup
+	logWrites  shared.LogWrites // Pending query log writes, flushed on shutdown
+	stopOnce   sync.Once        // Closes the listener, on Drain or Shutdown
+	stopped    chan struct{}    // Closed once the listener is
 	shutdown   chan struct{}
 	ctx        context.Context //nolint:containedctx // Context is needed for the server lifecycle
 	cancel     context.CancelFunc
@@ -62,6 +68,8 @@ func NewServer(
 	dumpConfig config.DumpConfig,
 	authCache *cache.AuthCache,
 	mongoConfig config.MongoConfig,
+	proxyProtocol config.ProxyProtocolConfig,
+	sessionConfig config.SessionConfig,
 	logger *slog.Logger,
 ) (*Server, error) {
 	tlsConfig, err := loadTLSConfig(mongoConfig)
@@ -74,12 +82,15 @@ func NewServer(
 	return &Server{
 		store:         dataStore,
 		encryptionKey: encryptionKey,
-		queryStorage:  queryStorage,
+		queryStorage:  shared.NewQueryStorage(queryStorage),
 		dumpConfig:    dumpConfig,
+		proxyProtocol: proxyProtocol,
+		sessionConfig: sessionConfig,
 		authCache:     authCache,
 		tlsConfig:     tlsConfig,
 		serviceID:     bson.NewObjectID(),
 		logger:        logger,
+		stopped:       make(chan struct{}),
 		shutdown:      make(chan struct{}),
 		ctx:           ctx,
 		cancel:        cancel,
@@ -93,6 +104,10 @@ func (s *Server) Start(addr string) error {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
+	if s.proxyProtocol.Enabled {
+		listener = shared.NewProxyProtocolListener(listener, s.proxyProtocol.TrustedNetworks())
+	}
+
 	s.setListener(listener)
 	s.logger.InfoContext(s.ctx, "MongoDB proxy server listening", slog.String("addr", addr))
 
@@ -104,7 +119,7 @@ func (s *Server) Start(addr string) error {
 		conn, err := listener.Accept()
 		if err != nil {
 			select {
-			case <-s.shutdown:
+			case <-s.stopped:
 				return nil
 			default:
 				s.logger.ErrorContext(s.ctx, "MongoDB accept failed", slog.Any("error", err))
@@ -117,6 +132,9 @@ func (s *Server) Start(addr string) error {
 
 		go func() {
 			defer s.wg.Done()
+			defer shared.TrackSession(store.ProtocolMongoDB)()
+			defer shared.RecoverPanic(s.ctx, s.logger, "mongodb session")
+
 			s.handleConnection(conn)
 		}()
 	}
@@ -148,17 +166,38 @@ func (s *Server) getListener() net.Listener {
 	return s.listener
 }
 
+// SetQueryStorage replaces the query storage limits, from the next query on.
+func (s *Server) SetQueryStorage(cfg config.QueryStorageConfig) {
+	s.queryStorage.Store(cfg)
+}
+
+// Drain stops accepting connections and waits, until ctx is done, for the
+// sessions in progress to end. Shutdown ends the remaining ones.
+func (s *Server) Drain(ctx context.Context) error {
+	s.stopAccepting(ctx)
+
+	return shared.WaitSessions(ctx, &s.wg)
+}
+
+// stopAccepting closes the listener, once.
+func (s *Server) stopAccepting(ctx context.Context) {
+	s.stopOnce.Do(func() {
+		close(s.stopped)
+
+		if listener := s.getListener(); listener != nil {
+			if err := listener.Close(); err != nil {
+				s.logger.ErrorContext(ctx, "failed to close MongoDB listener", slog.Any("error", err))
+			}
+		}
+	})
+}
+
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopAccepting(ctx)
 	close(s.shutdown)
 	s.cancel()
 
-	if listener := s.getListener(); listener != nil {
-		if err := listener.Close(); err != nil {
-			s.logger.ErrorContext(ctx, "failed to close MongoDB listener", slog.Any("error", err))
-		}
-	}
-
 	done := make(chan struct{})
 
 	go func() {
@@ -166,16 +205,22 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		close(done)
 	}()
 
+	var err error
+
 	select {
 	case <-done:
 		s.logger.InfoContext(ctx, "MongoDB proxy server shutdown complete")
-
-		return nil
 	case <-ctx.Done():
 		s.logger.WarnContext(ctx, "MongoDB proxy server shutdown timeout")
 
-		return ctx.Err()
+		err = ctx.Err()
 	}
+
+	// Sessions log their queries in the background: wait for those writes
+	// before the caller closes the store.
+	s.logWrites.Flush(ctx, s.logger)
+
+	return err
 }
 
 func (s *Server) handleConnection(clientConn net.Conn) {
@@ -189,6 +234,13 @@ func (s *Server) handleConnection(clientConn net.Conn) {
 		slog.Any("remote_addr", clientConn.RemoteAddr()))
 
 	session := newSession(clientConn, s)
+
+	// Canceled when the session ends, so nothing started for it outlives it.
+	sessionCtx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	session.ctx = sessionCtx
+
 	if err := session.Run(); err != nil {
 		s.logger.InfoContext(s.ctx, "MongoDB session ended",
 			slog.Any("remote_addr", clientConn.RemoteAddr()),
@@ -75,6 +75,10 @@ type Session struct {
 	// upstream is the authenticated connection to the target MongoDB.
 	upstream *upstreamConn
 
+	// clientInfo is the driver metadata from the first hello, recorded on the
+	// connection once authenticated.
+	clientInfo *store.ClientInfo
+
 	// connection is the DBBat audit record (insert on auth, close on teardown).
 	connection *store.Connection
 
@@ -91,6 +95,10 @@ type Session struct {
 	guard *shared.LimitGuard
 	// revocation is signaled when this session's grant is revoked mid-flight.
 	revocation *cache.RevocationHandle
+	// termination is signaled when an admin terminates the connection.
+	termination *cache.TerminationHandle
+	// masker applies the database's result masking to captured documents.
+	masker shared.LazyColumnMasker
 
 	// pending correlates upstream replies to the query that produced them
 	// (phase 3). Keyed by the client requestID.
@@ -274,6 +282,8 @@ func (s *Session) dispatchPreAuthOpQuery(m *message) (bool, error) {
 	name := commandName(q.query)
 	switch name {
 	case "hello", "isMaster", "ismaster":
+		s.recordClientMetadata(q.query)
+
 		reply, err := buildOpReply(s.nextReplyID(), m.requestID, s.helloDoc(name, q.query))
 		if err != nil {
 			return false, err
@@ -302,6 +312,8 @@ func (s *Session) dispatchPreAuthOpMsg(m *message) (bool, error) {
 	name := commandName(body)
 	switch name {
 	case "hello", "isMaster", "ismaster":
+		s.recordClientMetadata(body)
+
 		return false, s.replyOpMsg(m.requestID, s.helloDoc(name, body))
 	case "ping":
 		return false, s.replyOpMsg(m.requestID, okDoc())
@@ -329,8 +341,8 @@ func (s *Session) dispatchPreAuthOpMsg(m *message) (bool, error) {
 func (s *Session) relay() error {
 	errCh := make(chan error, 2)
 
-	go func() { errCh <- s.pumpClientToUpstream() }()
-	go func() { errCh <- s.pumpUpstreamToClient() }()
+	go func() { errCh <- shared.Safe(s.ctx, s.logger, "mongodb relay", s.pumpClientToUpstream) }()
+	go func() { errCh <- shared.Safe(s.ctx, s.logger, "mongodb relay", s.pumpUpstreamToClient) }()
 
 	err := <-errCh
 
@@ -361,6 +373,8 @@ func (s *Session) pumpUpstreamToClient() error {
 			return err
 		}
 
+		received := time.Now()
+
 		outRaw := m.raw
 		if s.pendingCommand(m.responseTo) == "listDatabases" {
 			if rewritten, ok := s.filterListDatabasesReply(m); ok {
@@ -368,7 +382,7 @@ func (s *Session) pumpUpstreamToClient() error {
 			}
 		}
 
-		s.captureResult(m)
+		s.captureResult(m, received)
 		s.dumpPacket(dump.DirServerToClient, outRaw)
 
 		if err := s.writeClient(outRaw); err != nil {
@@ -447,7 +461,7 @@ func (s *Session) cumulativeClientBytes() int64 {
 
 // onLimitViolation force-closes both conns when the watchdog trips.
 func (s *Session) onLimitViolation(upstream *upstreamConn, clientConn io.Closer, err error) {
-	s.logger.WarnContext(s.ctx, "terminating MongoDB session: grant no longer valid mid-stream",
+	s.logger.WarnContext(s.ctx, "terminating MongoDB session: grant no longer valid or session timed out",
 		slog.Any("error", err))
 
 	if upstream != nil {
@@ -461,11 +475,14 @@ func (s *Session) onLimitViolation(upstream *upstreamConn, clientConn io.Closer,
 
 // recordConnection inserts the DBBat audit record (after auth).
 func (s *Session) recordConnection() error {
-	conn, err := s.server.store.CreateConnection(
-		s.ctx,
-		s.user.UID,
-		s.database.UID,
+	ctx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	conn, err := s.server.store.CreateGrantConnection(
+		ctx,
+		s.grant,
 		store.ExtractSourceIP(s.clientConn.RemoteAddr()),
+		s.clientInfo,
 	)
 	if err != nil {
 		return fmt.Errorf("create connection: %w", err)
@@ -473,6 +490,11 @@ func (s *Session) recordConnection() error {
 
 	s.connection = conn
 
+	// Register the connection so an admin can terminate it through the API;
+	// the watchdog, started after this, tears the session down.
+	s.termination = s.server.store.Terminations().Register(conn.UID)
+	s.guard.WithTermination(s.termination.Flag())
+
 	return nil
 }
 
@@ -490,20 +512,37 @@ func (s *Session) recordDisconnect() {
 		return
 	}
 
+	// Detached from the session context, which is canceled on shutdown.
+	ctx := context.WithoutCancel(s.ctx)
+
 	// Flush any client-side bytes not yet attributed to a query.
 	total := s.cumulativeClientBytes()
 	if delta := total - s.lastBytesSnapshot; delta > 0 {
 		s.lastBytesSnapshot = total
 
-		if err := s.server.store.IncrementConnectionBytes(s.ctx, s.connection.UID, delta); err != nil {
-			s.logger.DebugContext(s.ctx, "MongoDB trailing byte flush failed",
+		flushCtx, cancel := shared.StoreContext(ctx)
+		err := s.server.store.IncrementConnectionBytes(flushCtx, s.connection.UID, delta)
+		cancel()
+
+		if err != nil {
+			s.logger.DebugContext(ctx, "MongoDB trailing byte flush failed",
 				slog.Any("connection_id", s.connection.UID),
 				slog.Any("error", err))
 		}
 	}
 
-	if err := s.server.store.CloseConnection(s.ctx, s.connection.UID); err != nil {
-		s.logger.WarnContext(s.ctx, "MongoDB connection close failed",
+	s.server.store.Terminations().Deregister(s.connection.UID, s.termination)
+
+	var reason string
+	if s.termination.Terminated() {
+		reason = store.DisconnectReasonTerminated
+	}
+
+	err := shared.RetryStoreWrite(ctx, func(ctx context.Context) error {
+		return s.server.store.CloseConnection(ctx, s.connection.UID, reason)
+	})
+	if err != nil {
+		s.logger.WarnContext(ctx, "MongoDB connection close failed",
 			slog.Any("connection_id", s.connection.UID),
 			slog.Any("error", err))
 	}
@@ -73,8 +73,8 @@ func (u *upstreamConn) sendCommand(doc any) (bson.Raw, error) {
 // connectUpstream dials the target MongoDB and authenticates via SCRAM-SHA-256
 // using the stored (decrypted) credentials (contract §5).
 func (s *Session) connectUpstream() error {
-	if err := s.database.DecryptPassword(s.server.encryptionKey); err != nil {
-		return fmt.Errorf("decrypt upstream password: %w", err)
+	if err := shared.UseUpstreamCredentials(s.ctx, s.database, s.server.encryptionKey, s.grant.AccessLevel()); err != nil {
+		return fmt.Errorf("upstream credentials: %w", err)
 	}
 
 	addr := net.JoinHostPort(s.database.Host, strconv.Itoa(s.database.Port))
@@ -119,22 +119,16 @@ func (s *Session) dialUpstream() (net.Conn, error) {
 	}
 
 	switch s.database.SSLMode {
-	case "require":
-		tlsConn := tls.Client(conn, &tls.Config{
-			InsecureSkipVerify: true, // "require" = encrypt without cert verification
-			MinVersion:         tls.VersionTLS12,
-		})
-		if err := tlsConn.Handshake(); err != nil {
+	case "require", "verify-ca", "verify-full":
+		tlsConfig, err := shared.UpstreamTLSConfig(s.database)
+		if err != nil {
 			_ = conn.Close()
 
 			return nil, err
 		}
 
-		return tlsConn, nil
-
-	case "verify-ca", "verify-full":
-		tlsConn := tls.Client(conn, &tls.Config{MinVersion: tls.VersionTLS12, ServerName: s.database.Host})
-		if err := tlsConn.Handshake(); err != nil {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(s.ctx); err != nil {
 			_ = conn.Close()
 
 			return nil, err
@@ -103,7 +103,10 @@ func (p *dbbatAuthProvider) verifyCredentials(username, password string) error {
 		return p.authenticateAPIKey(username, password)
 	}
 
-	user, err := p.server.store.GetUserByUsername(p.server.ctx, username)
+	lookupCtx, cancel := shared.StoreContext(p.server.ctx)
+	user, err := p.server.store.GetUserByUsername(lookupCtx, username)
+	cancel()
+
 	if err != nil {
 		return gomysqlserver.ErrAccessDenied
 	}
@@ -114,7 +117,7 @@ func (p *dbbatAuthProvider) verifyCredentials(username, password string) error {
 	)
 
 	if p.server.authCache != nil {
-		valid, verr = p.server.authCache.VerifyPassword(p.server.ctx, user.UID.String(), password, user.PasswordHash)
+		valid, verr = p.server.authCache.VerifyUserPassword(p.server.ctx, user.UID.String(), user.Username, password, user.PasswordHash)
 	} else {
 		valid, verr = crypto.VerifyPassword(user.PasswordHash, password)
 	}
@@ -123,21 +126,32 @@ func (p *dbbatAuthProvider) verifyCredentials(username, password string) error {
 		return gomysqlserver.ErrAccessDenied
 	}
 
+	shared.UpgradePasswordHash(p.server.ctx, p.server.logger, p.server.store, user, password)
+
 	return nil
 }
 
 func (p *dbbatAuthProvider) authenticateAPIKey(username, key string) error {
-	verified, err := p.server.store.VerifyAPIKey(p.server.ctx, key)
+	// Bounds the key verification and user lookup below.
+	lookupCtx, cancel := shared.StoreContext(p.server.ctx)
+	defer cancel()
+
+	verified, err := p.server.store.VerifyAPIKey(lookupCtx, key)
 	if err != nil {
 		return gomysqlserver.ErrAccessDenied
 	}
 
-	user, err := p.server.store.GetUserByUsername(p.server.ctx, username)
+	user, err := p.server.store.GetUserByUsername(lookupCtx, username)
 	if err != nil || user.UID != verified.UserID {
 		return ErrAPIKeyOwnerMismatch
 	}
 
-	go func() { _ = p.server.store.IncrementAPIKeyUsage(context.Background(), verified.ID) }()
+	go func() {
+		ctx, cancel := shared.StoreContext(context.Background())
+		defer cancel()
+
+		_ = p.server.store.IncrementAPIKeyUsage(ctx, verified.ID)
+	}()
 
 	return nil
 }
@@ -187,7 +201,11 @@ func (h *dbbatAuthHandler) OnAuthSuccess(_ *gomysqlserver.Conn) error {
 		return ErrServerNotFound
 	}
 
-	db, err := s.server.store.GetServerByName(s.ctx, s.requestedDB)
+	// Bounds the database and grant lookups below.
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	db, err := s.server.store.GetServerByName(lookupCtx, s.requestedDB)
 	if err != nil {
 		return ErrServerNotFound
 	}
@@ -198,13 +216,17 @@ func (h *dbbatAuthHandler) OnAuthSuccess(_ *gomysqlserver.Conn) error {
 
 	s.database = db
 
-	grant, err := s.server.store.GetActiveGrant(s.ctx, s.user.UID, db.UID)
+	grant, err := s.server.store.GetActiveGrant(lookupCtx, s.user.UID, db.UID)
 	if err != nil {
 		return ErrNoActiveGrant
 	}
 
 	s.grant = grant
 
+	if err := shared.CheckControls(grant, db.Protocol); err != nil {
+		return err
+	}
+
 	if err := checkQuotas(grant); err != nil {
 		return err
 	}
@@ -216,9 +238,13 @@ func (h *dbbatAuthHandler) OnAuthSuccess(_ *gomysqlserver.Conn) error {
 
 	// Build the limit guard now that the grant is known. The command loop's
 	// watchdog (started in Run) uses it to terminate the session mid-query,
-	// including when the grant is revoked.
+	// including when the grant is revoked, and once the session timeouts are
+	// reached.
+	idle, maxDuration := s.server.sessionConfig.Timeouts()
 	s.guard = shared.NewLimitGuard(grant, s.bytesFromClient, s.bytesToClient).
-		WithRevocation(s.revocation.Flag())
+		WithRevocation(s.revocation.Flag()).
+		WithExtension(s.revocation.Extension()).
+		WithSessionTimeouts(idle, maxDuration, s.executing.Load)
 
 	s.authComplete = true
 
@@ -197,7 +197,7 @@ func setupFixtureWithDumpDir(ctx context.Context, t *testing.T, mysqlImage, dbPr
 	}
 
 	proxy, err := NewServer(dataStore, encryptionKey, queryStorage, dumpCfg,
-		nil, config.MySQLConfig{}, slog.Default())
+		nil, config.MySQLConfig{}, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
 	require.NoError(t, err)
 
 	go func() { _ = proxy.Start("127.0.0.1:0") }()
@@ -441,7 +441,7 @@ func TestIntegration_ReadOnlyGrant_BlocksWrite(t *testing.T) {
 		require.NoError(t, f.store.RevokeGrant(ctx, g.UID, user.UID))
 	}
 
-	databases, err := f.store.ListServers(ctx)
+	databases, err := f.store.ListServers(ctx, store.ServerFilter{})
 	require.NoError(t, err)
 	require.NotEmpty(t, databases)
 
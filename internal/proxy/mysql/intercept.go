@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"time"
@@ -8,6 +9,8 @@ import (
 	gomysql "github.com/go-mysql-org/go-mysql/mysql"
 
 	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/spill"
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -44,12 +47,12 @@ func (h *handler) UseDB(dbName string) error {
 	syntheticSQL := "USE " + dbName
 
 	if dbName == h.session.database.Name || dbName == h.session.database.DatabaseName {
-		h.recordQuery(syntheticSQL, nil, time.Now(), nil, nil, nil)
+		h.recordQuery(syntheticSQL, nil, shared.StartQueryTimer(time.Now()), nil, nil, nil, nil)
 
 		return nil
 	}
 
-	h.recordQuery(syntheticSQL, nil, time.Now(), nil, nil, ptrErrString(ErrSwitchDatabaseDenied))
+	h.recordQuery(syntheticSQL, nil, shared.StartQueryTimer(time.Now()), nil, nil, nil, ptrErrString(ErrSwitchDatabaseDenied))
 
 	return ErrSwitchDatabaseDenied
 }
@@ -72,18 +75,21 @@ func (h *handler) HandleFieldList(table string, wildcard string) ([]*gomysql.Fie
 // prepare itself (separate from each subsequent EXECUTE) so audit shows the
 // full lifecycle.
 func (h *handler) HandleStmtPrepare(query string) (int, int, any, error) {
-	start := time.Now()
+	timer := shared.StartQueryTimer(time.Now())
 	syntheticSQL := "PREPARE: " + query
 
+	timer.Forwarded(time.Now())
 	stmt, err := h.session.upstreamConn.Prepare(query)
+	timer.RepliedWhole(time.Now())
+
 	if err != nil {
 		errStr := err.Error()
-		h.recordQuery(syntheticSQL, nil, start, nil, nil, &errStr)
+		h.recordQuery(syntheticSQL, nil, timer, nil, nil, nil, &errStr)
 
 		return 0, 0, nil, err
 	}
 
-	h.recordQuery(syntheticSQL, nil, start, nil, nil, nil)
+	h.recordQuery(syntheticSQL, nil, timer, nil, nil, nil, nil)
 
 	return stmt.ParamNum(), stmt.ColumnNum(), stmt, nil
 }
@@ -135,35 +141,44 @@ func (h *handler) runIntercepted(
 	exec func() (*gomysql.Result, error),
 ) (*gomysql.Result, error) {
 	s := h.session
+	timer := shared.StartQueryTimer(time.Now())
 
 	// A grant revoked mid-session invalidates every subsequent command, even
 	// before the watchdog force-closes the connection.
 	if s.revocation.Revoked() {
 		errStr := shared.ErrGrantRevoked.Error()
-		h.recordQuery(sql, params, time.Now(), nil, nil, &errStr)
+		h.recordQuery(sql, params, timer, nil, nil, nil, &errStr)
 
 		return nil, shared.ErrGrantRevoked
 	}
 
+	// An extension approved mid-session pushes the expiry back.
+	s.grant.ExpiresAt = s.revocation.ExpiresAt(s.grant.ExpiresAt)
+
 	if err := checkQuotas(s.grant); err != nil {
 		errStr := err.Error()
-		h.recordQuery(sql, params, time.Now(), nil, nil, &errStr)
+		h.recordQuery(sql, params, timer, nil, nil, nil, &errStr)
 
 		return nil, err
 	}
 
 	if err := shared.ValidateMySQLQuery(sql, s.grant); err != nil {
 		errStr := err.Error()
-		h.recordQuery(sql, params, time.Now(), nil, nil, &errStr)
+		h.recordQuery(sql, params, timer, nil, nil, nil, &errStr)
 
 		return nil, err
 	}
 
-	start := time.Now()
+	// The client library reads the whole result before returning it.
+	timer.Forwarded(time.Now())
+	s.executing.Store(true)
 	result, err := exec()
+	s.executing.Store(false)
+	timer.RepliedWhole(time.Now())
+
 	if err != nil {
 		errStr := err.Error()
-		h.recordQuery(sql, params, start, nil, nil, &errStr)
+		h.recordQuery(sql, params, timer, nil, nil, nil, &errStr)
 
 		return result, err
 	}
@@ -175,9 +190,22 @@ func (h *handler) runIntercepted(
 		rowsAffected = &ra
 	}
 
-	capturedRows, _, _ := h.captureRows(result)
+	if maxRows, ok := s.grant.MaxRows(); ok && limitRows(result, maxRows) {
+		s.logger.InfoContext(s.ctx, "result truncated by max_rows control", slog.Int64("max_rows", maxRows))
+	}
 
-	h.recordQuery(sql, params, start, capturedRows, rowsAffected, nil)
+	capturedRows, _, truncated := h.captureRows(result)
+
+	// A result past the limits is spilled whole, when configured, instead of
+	// truncated.
+	var spilled *spill.Writer
+	if truncated {
+		if spilled = h.spillRows(result); spilled != nil {
+			capturedRows = nil
+		}
+	}
+
+	h.recordQuery(sql, params, timer, capturedRows, spilled, rowsAffected, nil)
 
 	return result, nil
 }
@@ -188,7 +216,8 @@ func (h *handler) runIntercepted(
 // quota checks reflect work just done.
 //
 // capturedRows is nil for non-SELECT queries, validation failures, and
-// synthetic USE/PREPARE entries.
+// synthetic USE/PREPARE entries; spilled is the result spilled past the query
+// storage limits, if any, which the log write uploads.
 //
 // bytes_transferred is computed from the wire-level CountingConn around the
 // client socket — it captures the COM_QUERY/COM_STMT_EXECUTE request, the
@@ -198,14 +227,19 @@ func (h *handler) runIntercepted(
 func (h *handler) recordQuery(
 	sql string,
 	params *store.QueryParameters,
-	start time.Time,
+	timer shared.QueryTimer,
 	capturedRows []store.QueryRow,
+	spilled *spill.Writer,
 	rowsAffected *int64,
 	queryError *string,
 ) {
 	s := h.session
 
 	if s.connection == nil {
+		if spilled != nil {
+			spilled.Discard()
+		}
+
 		return // pre-handshake or pre-connection-record; nothing to log against
 	}
 
@@ -213,38 +247,59 @@ func (h *handler) recordQuery(
 	bytesTransferred := total - s.lastBytesSnapshot
 	s.lastBytesSnapshot = total
 
-	durationMs := float64(time.Since(start).Microseconds()) / 1000.0
+	end := time.Now()
+	durationMs := float64(end.Sub(timer.Start()).Microseconds()) / 1000.0
 
 	record := &store.Query{
 		ConnectionID: s.connection.UID,
 		SQLText:      sql,
 		Parameters:   params,
-		ExecutedAt:   start,
+		ExecutedAt:   timer.Start(),
 		DurationMs:   &durationMs,
+		QueryTiming:  timer.Timing(end),
 		RowsAffected: rowsAffected,
 		Error:        queryError,
 	}
 
-	go func() {
-		created, err := s.server.store.CreateQuery(s.ctx, record)
+	s.server.logWrites.Go(func() {
+		// Detached from the session context, which ends with the session, but
+		// bounded like any other storage call.
+		ctx, cancel := shared.StoreContext(context.WithoutCancel(s.ctx))
+		defer cancel()
+
+		defer shared.RecoverPanic(ctx, s.logger, "mysql query log")
+
+		record.Fingerprint = sqlnorm.Fingerprint(record.SQLText, store.ProtocolMySQL)
+
+		if spilled != nil {
+			defer spilled.Discard()
+		}
+
+		created, err := s.server.store.CreateQuery(ctx, record)
 		if err != nil {
-			s.logger.ErrorContext(s.ctx, "create query log failed", slog.Any("error", err))
+			s.logger.ErrorContext(ctx, "create query log failed", slog.Any("error", err))
 
 			return
 		}
 
-		if len(capturedRows) > 0 {
-			if err := s.server.store.StoreQueryRows(s.ctx, created.UID, capturedRows); err != nil {
-				s.logger.ErrorContext(s.ctx, "store query rows failed", slog.Any("error", err))
+		if len(capturedRows) > 0 && !created.Repeated {
+			if err := s.server.store.StoreQueryRows(ctx, created.UID, capturedRows); err != nil {
+				s.logger.ErrorContext(ctx, "store query rows failed", slog.Any("error", err))
 			}
 		}
 
 		if bytesTransferred > 0 {
-			if err := s.server.store.IncrementConnectionStats(s.ctx, s.connection.UID, bytesTransferred); err != nil {
-				s.logger.DebugContext(s.ctx, "increment connection stats failed", slog.Any("error", err))
+			if err := s.server.store.IncrementConnectionStats(ctx, s.connection.UID, bytesTransferred, 0); err != nil {
+				s.logger.DebugContext(ctx, "increment connection stats failed", slog.Any("error", err))
 			}
 		}
-	}()
+
+		// Upload the spilled result last, with its own timeout, unless the
+		// query was folded into an identical previous one, like the rows
+		if spilled != nil && !created.Repeated {
+			shared.CommitSpill(context.WithoutCancel(s.ctx), s.logger, s.server.store, created.UID, spilled)
+		}
+	})
 
 	// In-session quota counters so the next checkQuotas() reflects this query.
 	if s.grant != nil {
@@ -8,6 +8,8 @@ import (
 
 	gomysql "github.com/go-mysql-org/go-mysql/mysql"
 
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/spill"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -25,13 +27,14 @@ func (h *handler) captureRows(result *gomysql.Result) ([]store.QueryRow, int64,
 		return nil, 0, false
 	}
 
-	q := h.session.server.queryStorage
-	if !q.StoreResults {
+	q := h.session.database.EffectiveQueryStorage(h.session.server.queryStorage.Load())
+	if !q.StoreResults || !h.session.database.StoresResults() {
 		return nil, 0, false
 	}
 
 	rs := result.Resultset
 	rows := make([]store.QueryRow, 0, len(rs.Values))
+	masked := maskedColumns(h.session.masker.Get(h.session.database), rs.Fields)
 
 	var totalBytes int64
 
@@ -44,7 +47,7 @@ func (h *handler) captureRows(result *gomysql.Result) ([]store.QueryRow, int64,
 			break
 		}
 
-		rowJSON, err := encodeRow(rs.Fields, row)
+		rowJSON, err := encodeRow(rs.Fields, row, masked)
 		if err != nil {
 			h.session.logger.WarnContext(h.session.ctx, "row encode failed; skipping",
 				slog.Int("row", rowIdx), slog.Any("error", err))
@@ -70,6 +73,80 @@ func (h *handler) captureRows(result *gomysql.Result) ([]store.QueryRow, int64,
 	return rows, totalBytes, truncated
 }
 
+// spillRows spills the whole of a result past the query storage limits. It
+// returns nil when spilling is disabled or fails.
+func (h *handler) spillRows(result *gomysql.Result) *spill.Writer {
+	w := shared.BeginSpill(h.session.ctx, h.session.logger, nil)
+	if w == nil {
+		return nil
+	}
+
+	rs := result.Resultset
+	masked := maskedColumns(h.session.masker.Get(h.session.database), rs.Fields)
+
+	for _, row := range rs.Values {
+		rowJSON, err := encodeRow(rs.Fields, row, masked)
+		if err != nil {
+			continue // Skipped, as captureRows does
+		}
+
+		if w = shared.SpillRow(h.session.ctx, h.session.logger, w, rowJSON); w == nil {
+			return nil
+		}
+	}
+
+	return w
+}
+
+// limitRows drops the rows of result beyond maxRows, enforcing the grant's
+// max_rows control. The text and binary row payloads sent to the client live
+// in RowDatas, the decoded ones in Values; both are cut. Reports whether rows
+// were dropped.
+func limitRows(result *gomysql.Result, maxRows int64) bool {
+	if result == nil || result.Resultset == nil || int64(len(result.RowDatas)) <= maxRows {
+		return false
+	}
+
+	result.RowDatas = result.RowDatas[:maxRows]
+
+	if int64(len(result.Values)) > maxRows {
+		result.Values = result.Values[:maxRows]
+	}
+
+	return true
+}
+
+// maskedColumns flags the result columns masker masks, matching both the
+// column's alias and its original name against its own table; nil when
+// nothing is masked.
+func maskedColumns(masker *shared.ColumnMasker, fields []*gomysql.Field) []bool {
+	if masker == nil {
+		return nil
+	}
+
+	masked := make([]bool, len(fields))
+
+	for i, f := range fields {
+		if f == nil {
+			continue
+		}
+
+		table := string(f.OrgTable)
+		if table == "" {
+			table = string(f.Table)
+		}
+
+		if len(f.Schema) > 0 {
+			table = string(f.Schema) + "." + table
+		}
+
+		tables := []string{table}
+		masked[i] = masker.Masks(string(f.Name), tables) || masker.Masks(string(f.OrgName), tables)
+	}
+
+	return masked
+}
+
 // encodeRow serializes a single MySQL result row to a JSON array, applying
 // type-aware coercions:
 //   - NULL → null
@@ -78,11 +155,18 @@ func (h *handler) captureRows(result *gomysql.Result) ([]store.QueryRow, int64,
 //   - everything else (varchar/text/enum/json/dates) → string
 //
 // JSON column type is detected by Field.Type and parsed if valid; invalid
-// JSON falls through to a plain string so the row is never lost.
-func encodeRow(fields []*gomysql.Field, row []gomysql.FieldValue) (json.RawMessage, error) {
+// JSON falls through to a plain string so the row is never lost. Columns
+// flagged in masked are stored as shared.MaskedValue.
+func encodeRow(fields []*gomysql.Field, row []gomysql.FieldValue, masked []bool) (json.RawMessage, error) {
 	values := make([]any, len(row))
 
 	for i, fv := range row {
+		if i < len(masked) && masked[i] {
+			values[i] = shared.MaskedValue
+
+			continue
+		}
+
 		var fieldType uint8
 		if i < len(fields) && fields[i] != nil {
 			fieldType = fields[i].Type
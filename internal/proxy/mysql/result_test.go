@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
 )
 
 func TestEncodeRow_Strings(t *testing.T) {
@@ -19,7 +22,7 @@ func TestEncodeRow_Strings(t *testing.T) {
 		gomysql.NewFieldValue(gomysql.FieldValueTypeString, 0, []byte("admin")),
 	}
 
-	got, err := encodeRow(fields, row)
+	got, err := encodeRow(fields, row, nil)
 	if err != nil {
 		t.Fatalf("encodeRow failed: %v", err)
 	}
@@ -43,7 +46,7 @@ func TestEncodeRow_Numbers(t *testing.T) {
 		gomysql.NewFieldValue(gomysql.FieldValueTypeFloat, 0, nil), // value=0 → 0.0
 	}
 
-	got, err := encodeRow(fields, row)
+	got, err := encodeRow(fields, row, nil)
 	if err != nil {
 		t.Fatalf("encodeRow failed: %v", err)
 	}
@@ -67,7 +70,7 @@ func TestEncodeRow_Null(t *testing.T) {
 		gomysql.NewFieldValue(gomysql.FieldValueTypeNull, 0, nil),
 	}
 
-	got, err := encodeRow(fields, row)
+	got, err := encodeRow(fields, row, nil)
 	if err != nil {
 		t.Fatalf("encodeRow failed: %v", err)
 	}
@@ -85,7 +88,7 @@ func TestEncodeRow_Blob_NonUTF8_Base64(t *testing.T) {
 		gomysql.NewFieldValue(gomysql.FieldValueTypeString, 0, []byte{0xff, 0xfe, 0x00, 0x01}),
 	}
 
-	got, err := encodeRow(fields, row)
+	got, err := encodeRow(fields, row, nil)
 	if err != nil {
 		t.Fatalf("encodeRow failed: %v", err)
 	}
@@ -112,7 +115,7 @@ func TestEncodeRow_Blob_UTF8_String(t *testing.T) {
 		gomysql.NewFieldValue(gomysql.FieldValueTypeString, 0, []byte("hello world")),
 	}
 
-	got, err := encodeRow(fields, row)
+	got, err := encodeRow(fields, row, nil)
 	if err != nil {
 		t.Fatalf("encodeRow failed: %v", err)
 	}
@@ -130,7 +133,7 @@ func TestEncodeRow_JSON_ParsedIfValid(t *testing.T) {
 		gomysql.NewFieldValue(gomysql.FieldValueTypeString, 0, []byte(`{"k":"v","n":42}`)),
 	}
 
-	got, err := encodeRow(fields, row)
+	got, err := encodeRow(fields, row, nil)
 	if err != nil {
 		t.Fatalf("encodeRow failed: %v", err)
 	}
@@ -154,7 +157,7 @@ func TestEncodeRow_JSON_FallsBackToStringIfInvalid(t *testing.T) {
 		gomysql.NewFieldValue(gomysql.FieldValueTypeString, 0, []byte(`{not json`)),
 	}
 
-	got, err := encodeRow(fields, row)
+	got, err := encodeRow(fields, row, nil)
 	if err != nil {
 		t.Fatalf("encodeRow failed: %v", err)
 	}
@@ -168,3 +171,56 @@ func TestEncodeRow_JSON_FallsBackToStringIfInvalid(t *testing.T) {
 		t.Errorf("expected raw string, got %q", decoded[0])
 	}
 }
+
+func TestLimitRows(t *testing.T) {
+	t.Parallel()
+
+	rs := &gomysql.Resultset{
+		RowDatas: []gomysql.RowData{gomysql.RowData("a"), gomysql.RowData("b"), gomysql.RowData("c")},
+		Values:   make([][]gomysql.FieldValue, 3),
+	}
+	result := &gomysql.Result{Resultset: rs}
+
+	if limitRows(result, 3) {
+		t.Error("limitRows() truncated a result within the limit")
+	}
+
+	if !limitRows(result, 2) {
+		t.Fatal("limitRows() did not truncate")
+	}
+
+	if len(rs.RowDatas) != 2 || len(rs.Values) != 2 {
+		t.Errorf("kept %d row payloads and %d values, want 2", len(rs.RowDatas), len(rs.Values))
+	}
+
+	if limitRows(&gomysql.Result{}, 1) {
+		t.Error("limitRows() truncated a result without rows")
+	}
+}
+
+func TestEncodeRow_Masked(t *testing.T) {
+	t.Parallel()
+
+	masker := shared.NewColumnMasker(&store.ResultMasking{Columns: []string{"users.email"}})
+	fields := []*gomysql.Field{
+		{Type: gomysql.MYSQL_TYPE_VARCHAR, Name: []byte("name"), Schema: []byte("app"), Table: []byte("u"), OrgTable: []byte("users")},
+		{Type: gomysql.MYSQL_TYPE_VARCHAR, Name: []byte("mail"), OrgName: []byte("email"), Schema: []byte("app"), Table: []byte("u"), OrgTable: []byte("users")},
+		{Type: gomysql.MYSQL_TYPE_VARCHAR, Name: []byte("email"), Table: []byte("contacts")},
+	}
+	row := []gomysql.FieldValue{
+		gomysql.NewFieldValue(gomysql.FieldValueTypeString, 0, []byte("alice")),
+		gomysql.NewFieldValue(gomysql.FieldValueTypeString, 0, []byte("alice@example.com")),
+		gomysql.NewFieldValue(gomysql.FieldValueTypeString, 0, []byte("bob@example.com")),
+	}
+
+	got, err := encodeRow(fields, row, maskedColumns(masker, fields))
+	if err != nil {
+		t.Fatalf("encodeRow failed: %v", err)
+	}
+
+	// The alias "mail" is masked through its original name; contacts.email is not users.email.
+	want := `["alice",{"$masked":true},"bob@example.com"]`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
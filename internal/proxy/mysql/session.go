@@ -67,6 +67,17 @@ type Session struct {
 	// revocation is signaled when this session's grant is revoked mid-flight,
 	// so the next command is rejected and the watchdog tears the session down.
 	revocation *cache.RevocationHandle
+
+	// termination is signaled when an admin terminates the connection through
+	// the API, so the watchdog tears the session down.
+	termination *cache.TerminationHandle
+
+	// executing is set while a statement runs upstream, so a slow statement
+	// does not count as an idle session.
+	executing atomic.Bool
+
+	// masker applies the database's result masking to captured rows.
+	masker shared.LazyColumnMasker
 }
 
 // cumulativeClientBytes returns the running total of bytes exchanged with
@@ -178,7 +189,7 @@ func (s *Session) Run() error {
 // Read/Write and safe to call twice (the deferred closeUpstream closes the same
 // conn).
 func (s *Session) onLimitViolation(upstreamConn, clientConn io.Closer, err error) {
-	s.logger.WarnContext(s.ctx, "terminating MySQL session: grant no longer valid mid-stream",
+	s.logger.WarnContext(s.ctx, "terminating MySQL session: grant no longer valid or session timed out",
 		slog.Any("error", err))
 
 	if upstreamConn != nil {
@@ -210,11 +221,14 @@ func (s *Session) commandLoop() error {
 }
 
 func (s *Session) recordConnection() error {
-	conn, err := s.server.store.CreateConnection(
-		s.ctx,
-		s.user.UID,
-		s.database.UID,
+	ctx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	conn, err := s.server.store.CreateGrantConnection(
+		ctx,
+		s.grant,
 		store.ExtractSourceIP(s.clientConn.RemoteAddr()),
+		attributesClientInfo(s.serverConn.Attributes()),
 	)
 	if err != nil {
 		return fmt.Errorf("create connection: %w", err)
@@ -222,6 +236,11 @@ func (s *Session) recordConnection() error {
 
 	s.connection = conn
 
+	// Register the connection so an admin can terminate it through the API;
+	// the watchdog, started after this, tears the session down.
+	s.termination = s.server.store.Terminations().Register(conn.UID)
+	s.guard.WithTermination(s.termination.Flag())
+
 	return nil
 }
 
@@ -248,19 +267,36 @@ func (s *Session) recordDisconnect() {
 	// Persisting them keeps the grant's recomputed BytesTransferred honest across
 	// reconnects. Bytes-only (IncrementConnectionBytes) so this teardown flush
 	// never inflates the query count.
+	// Detached from the session context, which is canceled on shutdown.
+	ctx := context.WithoutCancel(s.ctx)
+
 	total := s.cumulativeClientBytes()
 	if delta := total - s.lastBytesSnapshot; delta > 0 {
 		s.lastBytesSnapshot = total
 
-		if err := s.server.store.IncrementConnectionBytes(s.ctx, s.connection.UID, delta); err != nil {
-			s.logger.DebugContext(s.ctx, "MySQL trailing byte flush failed",
+		flushCtx, cancel := shared.StoreContext(ctx)
+		err := s.server.store.IncrementConnectionBytes(flushCtx, s.connection.UID, delta)
+		cancel()
+
+		if err != nil {
+			s.logger.DebugContext(ctx, "MySQL trailing byte flush failed",
 				slog.Any("connection_id", s.connection.UID),
 				slog.Any("error", err))
 		}
 	}
 
-	if err := s.server.store.CloseConnection(s.ctx, s.connection.UID); err != nil {
-		s.logger.WarnContext(s.ctx, "MySQL connection close failed",
+	s.server.store.Terminations().Deregister(s.connection.UID, s.termination)
+
+	var reason string
+	if s.termination.Terminated() {
+		reason = store.DisconnectReasonTerminated
+	}
+
+	err := shared.RetryStoreWrite(ctx, func(ctx context.Context) error {
+		return s.server.store.CloseConnection(ctx, s.connection.UID, reason)
+	})
+	if err != nil {
+		s.logger.WarnContext(ctx, "MySQL connection close failed",
 			slog.Any("connection_id", s.connection.UID),
 			slog.Any("error", err))
 	}
@@ -327,3 +363,31 @@ func (s *Session) closeDump() {
 
 	s.dumpWriter = nil
 }
+
+// attributesClientInfo maps the connection attributes a client sent in its
+// handshake response (libmysqlclient and most connectors send _client_name and
+// _client_version; program_name is set by the mysql CLI and by applications)
+// to the connection's client info. The remaining attributes (_os, _platform,
+// _pid, ...) are kept as parameters.
+func attributesClientInfo(attrs map[string]string) *store.ClientInfo {
+	info := &store.ClientInfo{
+		ApplicationName: attrs["program_name"],
+		Driver:          attrs["_client_name"],
+		DriverVersion:   attrs["_client_version"],
+	}
+
+	for key, value := range attrs {
+		switch key {
+		case "program_name", "_client_name", "_client_version":
+			continue
+		}
+
+		if info.Parameters == nil {
+			info.Parameters = make(map[string]string)
+		}
+
+		info.Parameters[key] = value
+	}
+
+	return info
+}
@@ -85,7 +85,7 @@ func TestRecordDisconnect_FlushesUnrecordedBytes(t *testing.T) {
 
 	// A completed query already persisted 200 bytes (and 1 query) and advanced
 	// the snapshot to 200.
-	require.NoError(t, dataStore.IncrementConnectionStats(ctx, conn.UID, 200))
+	require.NoError(t, dataStore.IncrementConnectionStats(ctx, conn.UID, 200, 0))
 
 	// The live counters now stand at 1000 cumulative client-side bytes, so 800
 	// bytes (trailing response / aborted-Execute request) remain unrecorded.
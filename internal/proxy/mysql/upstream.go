@@ -2,7 +2,6 @@ package mysql
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"net"
@@ -45,8 +44,8 @@ func buildUpstreamProgramName(username, clientProgramName string) string {
 // caching_sha2_password support we deliberately did NOT implement on the
 // server-facing side.
 func (s *Session) connectUpstream() error {
-	if err := s.database.DecryptPassword(s.server.encryptionKey); err != nil {
-		return fmt.Errorf("decrypt upstream password: %w", err)
+	if err := shared.UseUpstreamCredentials(s.ctx, s.database, s.server.encryptionKey, s.grant.AccessLevel()); err != nil {
+		return fmt.Errorf("upstream credentials: %w", err)
 	}
 
 	addr := net.JoinHostPort(s.database.Host, strconv.Itoa(s.database.Port))
@@ -88,10 +87,13 @@ func (s *Session) connectUpstream() error {
 // proxy to upload arbitrary files via `LOAD DATA LOCAL INFILE` mid-query.
 func (s *Session) applyUpstreamOptions(c *gomysqlclient.Conn) error {
 	switch s.database.SSLMode {
-	case "require":
-		c.UseSSL(true) // skip cert verification
-	case "verify-ca", "verify-full":
-		c.SetTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12, ServerName: s.database.Host})
+	case "require", "verify-ca", "verify-full":
+		tlsConfig, err := shared.UpstreamTLSConfig(s.database)
+		if err != nil {
+			return err
+		}
+
+		c.SetTLSConfig(tlsConfig)
 	case "", "disable", "prefer", "allow":
 		// plaintext upstream — also the path for "prefer"/"allow" since the
 		// client doesn't currently negotiate opportunistic TLS for MySQL
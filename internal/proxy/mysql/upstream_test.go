@@ -4,6 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fclairamb/dbbat/internal/store"
 	"github.com/fclairamb/dbbat/internal/version"
 )
 
@@ -63,3 +66,21 @@ func TestBuildUpstreamProgramName_MaxLength(t *testing.T) {
 		t.Errorf("buildUpstreamProgramName() = %q, want prefix %q", result, expectedPrefix)
 	}
 }
+
+func TestAttributesClientInfo(t *testing.T) {
+	t.Parallel()
+
+	info := attributesClientInfo(map[string]string{
+		"_client_name":    "libmysql",
+		"_client_version": "8.0.36",
+		"program_name":    "mysql",
+		"_os":             "Linux",
+	})
+
+	assert.Equal(t, &store.ClientInfo{
+		ApplicationName: "mysql",
+		Driver:          "libmysql",
+		DriverVersion:   "8.0.36",
+		Parameters:      map[string]string{"_os": "Linux"},
+	}, info)
+}
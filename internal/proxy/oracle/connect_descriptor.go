@@ -66,6 +66,16 @@ func parseConnectDescriptor(descriptor string) ConnectDescriptor {
 	return cd
 }
 
+// connectTNSVersion returns the TNS version a Connect packet payload
+// announces (its first two bytes, big-endian), or 0 when it is too short.
+func connectTNSVersion(payload []byte) uint16 {
+	if len(payload) < 2 {
+		return 0
+	}
+
+	return uint16(payload[0])<<8 | uint16(payload[1])
+}
+
 // extractConnectString extracts the connect descriptor string from a TNS Connect packet payload.
 // The connect descriptor starts after the TNS Connect header fields.
 func extractConnectString(payload []byte) string {
@@ -166,3 +166,9 @@ func TestRewriteServiceName_PadsShorterName(t *testing.T) {
 	assert.Len(t, result.Payload, len(pkt.Payload))
 	assert.Len(t, result.Raw, len(pkt.Raw))
 }
+
+func TestConnectTNSVersion(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, uint16(318), connectTNSVersion([]byte{0x01, 0x3e, 0x01, 0x2c}))
+	assert.Equal(t, uint16(0), connectTNSVersion([]byte{0x01}))
+}
@@ -279,7 +279,7 @@ func TestIntegration_ProxyPassthrough(t *testing.T) {
 		MaxResultBytes: 1048576,
 	}
 
-	proxy := NewServer(dataStore, encryptionKey, nil, queryStorage, config.DumpConfig{}, slog.Default())
+	proxy := NewServer(dataStore, encryptionKey, nil, queryStorage, config.DumpConfig{}, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
 	go func() { _ = proxy.Start(":0") }()
 	defer func() { _ = proxy.Shutdown(ctx) }()
 
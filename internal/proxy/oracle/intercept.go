@@ -1,6 +1,7 @@
 package oracle
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -9,7 +10,9 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/fclairamb/dbbat/internal/lineage"
 	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -32,12 +35,14 @@ type oracleQueryTracker struct {
 type pendingOracleQuery struct {
 	cursor         *trackedCursor
 	startTime      time.Time
+	timer          shared.QueryTimer
 	capturedBytes  int64
 	rowNumber      int
 	truncated      bool
 	queryUID       uuid.UUID // Set after query record is created in DB
 	queryPersisted bool      // True after query record is created
 	lastRow        []string  // Last captured row values (for continuation packet duplicate tracking)
+	tables         []string  // Tables of the cursor's SQL, for result masking
 }
 
 // newOracleQueryTracker creates a new query tracker.
@@ -50,6 +55,8 @@ func newOracleQueryTracker() *oracleQueryTracker {
 // handleOALL8 intercepts an OALL8 message: decodes SQL, checks access controls,
 // and begins tracking the query. Returns an error if the query should be blocked.
 func (s *session) handleOALL8(ttcPayload []byte) error {
+	start := time.Now()
+
 	result, err := decodeOALL8(ttcPayload)
 	if err != nil {
 		s.logger.WarnContext(s.ctx, "failed to decode OALL8", slog.Any("error", err))
@@ -90,7 +97,8 @@ func (s *session) handleOALL8(ttcPayload []byte) error {
 	// Start pending query and persist immediately
 	s.tracker.pendingQuery = &pendingOracleQuery{
 		cursor:    cursor,
-		startTime: time.Now(),
+		startTime: start,
+		timer:     shared.StartQueryTimer(start),
 	}
 	s.persistQueryRecord()
 
@@ -107,6 +115,8 @@ func (s *session) flushPendingQuery() {
 
 // handlePiggybackExec intercepts a v315+ piggyback execute-with-SQL message.
 func (s *session) handlePiggybackExec(ttcPayload []byte) error {
+	start := time.Now()
+
 	result, err := decodePiggybackExecSQL(ttcPayload)
 	if err != nil {
 		s.logger.DebugContext(s.ctx, "failed to decode piggyback exec", slog.Any("error", err))
@@ -139,7 +149,8 @@ func (s *session) handlePiggybackExec(ttcPayload []byte) error {
 	}
 	s.tracker.pendingQuery = &pendingOracleQuery{
 		cursor:    cursor,
-		startTime: time.Now(),
+		startTime: start,
+		timer:     shared.StartQueryTimer(start),
 	}
 	s.persistQueryRecord()
 
@@ -148,6 +159,8 @@ func (s *session) handlePiggybackExec(ttcPayload []byte) error {
 
 // handleJDBCExec intercepts a JDBC execute-with-SQL message (func=0x11, sub=0x69).
 func (s *session) handleJDBCExec(ttcPayload []byte) {
+	start := time.Now()
+
 	result, err := decodeExecSQL(ttcPayload)
 	if err != nil {
 		s.logger.DebugContext(s.ctx, "failed to decode JDBC exec", slog.Any("error", err))
@@ -169,7 +182,8 @@ func (s *session) handleJDBCExec(ttcPayload []byte) {
 	}
 	s.tracker.pendingQuery = &pendingOracleQuery{
 		cursor:    cursor,
-		startTime: time.Now(),
+		startTime: start,
+		timer:     shared.StartQueryTimer(start),
 	}
 	s.persistQueryRecord()
 }
@@ -239,9 +253,11 @@ func (s *session) handleOFETCH(ttcPayload []byte) {
 
 	// If no pending query, start one for the fetch (re-execution of cursor)
 	if s.tracker.pendingQuery == nil {
+		start := time.Now()
 		s.tracker.pendingQuery = &pendingOracleQuery{
 			cursor:    cursor,
-			startTime: time.Now(),
+			startTime: start,
+			timer:     shared.StartQueryTimer(start),
 		}
 	}
 }
@@ -259,13 +275,20 @@ func (s *session) captureRow(columns []columnDef, values []interface{}) {
 		return
 	}
 
-	if !s.queryStorage.StoreResults {
+	limits := s.database.EffectiveQueryStorage(s.queryStorage)
+	if !limits.StoreResults || !s.database.StoresResults() {
 		return
 	}
 
+	masker := s.masker.Get(s.database)
+
 	// Ensure the query record exists in the database
 	if !pending.queryPersisted {
 		s.persistQueryRecord()
+
+		if masker != nil && pending.cursor != nil {
+			pending.tables = shared.StatementTables(pending.cursor.sql, lineage.DialectOracle)
+		}
 	}
 
 	if pending.queryUID == uuid.Nil {
@@ -289,9 +312,11 @@ func (s *session) captureRow(columns []columnDef, values []interface{}) {
 		rowData[col.Name] = val
 	}
 
+	masker.MaskRow(rowData, pending.tables)
+
 	// Check limits
-	if pending.rowNumber >= s.queryStorage.MaxResultRows ||
-		pending.capturedBytes+rowSize > s.queryStorage.MaxResultBytes {
+	if pending.rowNumber >= limits.MaxResultRows ||
+		pending.capturedBytes+rowSize > limits.MaxResultBytes {
 		pending.truncated = true
 
 		return
@@ -312,7 +337,10 @@ func (s *session) captureRow(columns []columnDef, values []interface{}) {
 		RowSizeBytes: rowSize,
 	}
 
-	if err := s.store.StoreQueryRows(s.ctx, pending.queryUID, []store.QueryRow{row}); err != nil {
+	ctx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	if err := s.store.StoreQueryRows(ctx, pending.queryUID, []store.QueryRow{row}); err != nil {
 		s.logger.WarnContext(s.ctx, "failed to stream row", slog.Any("error", err))
 	}
 }
@@ -329,11 +357,15 @@ func (s *session) persistQueryRecord() {
 	query := &store.Query{
 		ConnectionID: s.connectionUID,
 		SQLText:      pending.cursor.sql,
+		Fingerprint:  sqlnorm.Fingerprint(pending.cursor.sql, store.ProtocolOracle),
 		ExecutedAt:   pending.startTime,
 		Parameters:   formatOracleBinds(pending.cursor.bindValues),
 	}
 
-	created, err := s.store.CreateQuery(s.ctx, query)
+	ctx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	created, err := s.store.CreateQuery(ctx, query)
 	if err != nil {
 		s.logger.ErrorContext(s.ctx, "failed to create query record", slog.Any("error", err))
 		return
@@ -361,7 +393,9 @@ func (s *session) completeQuery(rowsAffected *int64, queryError *string) {
 	bytesTransferred := total - s.lastBytesSnapshot
 	s.lastBytesSnapshot = total
 
-	duration := float64(time.Since(pending.startTime).Milliseconds())
+	end := time.Now()
+	duration := float64(end.Sub(pending.startTime).Microseconds()) / 1000.0
+	timing := pending.timer.Timing(end)
 
 	// Use captured row count as rows_affected if not provided by the caller.
 	if rowsAffected == nil && pending.rowNumber > 0 {
@@ -373,7 +407,9 @@ func (s *session) completeQuery(rowsAffected *int64, queryError *string) {
 	// Otherwise, create it now (no-result queries like DML).
 	if pending.queryPersisted && pending.queryUID != uuid.Nil {
 		// Update with duration, error, rows affected
-		go s.finalizeQuery(pending.queryUID, &duration, rowsAffected, queryError, bytesTransferred)
+		s.logWrites.Go(func() {
+			s.finalizeQuery(pending.queryUID, &duration, timing, rowsAffected, queryError, bytesTransferred)
+		})
 	} else if s.store != nil {
 		// Create the query record (no rows to stream)
 		query := &store.Query{
@@ -381,20 +417,30 @@ func (s *session) completeQuery(rowsAffected *int64, queryError *string) {
 			SQLText:      pending.cursor.sql,
 			ExecutedAt:   pending.startTime,
 			DurationMs:   &duration,
+			QueryTiming:  timing,
 			RowsAffected: rowsAffected,
 			Error:        queryError,
 			Parameters:   formatOracleBinds(pending.cursor.bindValues),
 		}
 
-		go func() {
-			if _, err := s.store.CreateQuery(s.ctx, query); err != nil {
-				s.logger.ErrorContext(s.ctx, "failed to log query", slog.Any("error", err))
+		s.logWrites.Go(func() {
+			// Detached from the session context, which ends with the session, but
+			// bounded like any other storage call.
+			ctx, cancel := shared.StoreContext(context.WithoutCancel(s.ctx))
+			defer cancel()
+
+			defer shared.RecoverPanic(ctx, s.logger, "oracle query log")
+
+			query.Fingerprint = sqlnorm.Fingerprint(query.SQLText, store.ProtocolOracle)
+
+			if _, err := s.store.CreateQuery(ctx, query); err != nil {
+				s.logger.ErrorContext(ctx, "failed to log query", slog.Any("error", err))
 			}
 
-			if err := s.store.IncrementConnectionStats(s.ctx, s.connectionUID, bytesTransferred); err != nil {
-				s.logger.ErrorContext(s.ctx, "failed to increment connection stats", slog.Any("error", err))
+			if err := s.store.IncrementConnectionStats(ctx, s.connectionUID, bytesTransferred, 0); err != nil {
+				s.logger.ErrorContext(ctx, "failed to increment connection stats", slog.Any("error", err))
 			}
-		}()
+		})
 	}
 
 	// Update local grant state for in-session quota checks
@@ -405,13 +451,26 @@ func (s *session) completeQuery(rowsAffected *int64, queryError *string) {
 }
 
 // finalizeQuery updates a query record with completion data (duration, error).
-func (s *session) finalizeQuery(queryUID uuid.UUID, duration *float64, rowsAffected *int64, queryError *string, bytesTransferred int64) {
-	if err := s.store.UpdateQueryCompletion(s.ctx, queryUID, duration, rowsAffected, queryError); err != nil {
-		s.logger.ErrorContext(s.ctx, "failed to finalize query", slog.Any("error", err))
+// It runs as a tracked log write, detached from the session context.
+func (s *session) finalizeQuery(queryUID uuid.UUID, duration *float64, timing store.QueryTiming, rowsAffected *int64, queryError *string, bytesTransferred int64) {
+	ctx := context.WithoutCancel(s.ctx)
+
+	defer shared.RecoverPanic(ctx, s.logger, "oracle query log")
+
+	// Completing a query is idempotent: retry it rather than leave the record
+	// without its duration.
+	err := shared.RetryStoreWrite(ctx, func(ctx context.Context) error {
+		return s.store.UpdateQueryCompletion(ctx, queryUID, duration, timing, rowsAffected, queryError)
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to finalize query", slog.Any("error", err))
 	}
 
-	if err := s.store.IncrementConnectionStats(s.ctx, s.connectionUID, bytesTransferred); err != nil {
-		s.logger.ErrorContext(s.ctx, "failed to increment connection stats", slog.Any("error", err))
+	statsCtx, cancel := shared.StoreContext(ctx)
+	defer cancel()
+
+	if err := s.store.IncrementConnectionStats(statsCtx, s.connectionUID, bytesTransferred, 0); err != nil {
+		s.logger.ErrorContext(ctx, "failed to increment connection stats", slog.Any("error", err))
 	}
 }
 
@@ -567,7 +626,8 @@ func (s *session) checkQuotas() error {
 		return nil
 	}
 
-	if !s.grant.ExpiresAt.IsZero() && !time.Now().Before(s.grant.ExpiresAt) {
+	// An extension approved mid-session pushes the expiry back.
+	if expiresAt := s.revocation.ExpiresAt(s.grant.ExpiresAt); !expiresAt.IsZero() && !time.Now().Before(expiresAt) {
 		return shared.ErrGrantExpired
 	}
 
@@ -6,7 +6,6 @@ import (
 	"log/slog"
 	"net"
 	"os"
-	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +13,7 @@ import (
 	"github.com/fclairamb/dbbat/internal/cache"
 	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/dump"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -22,8 +22,10 @@ type Server struct {
 	store         *store.Store
 	encryptionKey []byte
 	authCache     *cache.AuthCache
-	queryStorage  config.QueryStorageConfig
+	queryStorage  *shared.QueryStorage // Replaced by SetQueryStorage on reload
 	dumpConfig    config.DumpConfig
+	proxyProtocol config.ProxyProtocolConfig
+	sessionConfig config.SessionConfig // Idle and maximum duration timeouts of the sessions
 	logger        *slog.Logger
 	// listenerMu guards listener, which is written by Start and read
 	// concurrently by Addr/Shutdown (e.g. tests polling Addr while Start runs
@@ -32,6 +34,9 @@ type Server struct {
 	listener   net.Listener
 	listenAddr string
 	wg         sync.WaitGroup
+	logWrites  shared.LogWrites // Pending query log writes, flushed on shutdown
+	stopOnce   sync.Once        // Closes the listener, on Drain or Shutdown
+	stopped    chan struct{}    // Closed once the listener is
 	shutdown   chan struct{}
 	ctx        context.Context //nolint:containedctx
 	cancel     context.CancelFunc
@@ -44,6 +49,8 @@ func NewServer(
 	authCache *cache.AuthCache,
 	queryStorage config.QueryStorageConfig,
 	dumpConfig config.DumpConfig,
+	proxyProtocol config.ProxyProtocolConfig,
+	sessionConfig config.SessionConfig,
 	logger *slog.Logger,
 ) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -52,9 +59,12 @@ func NewServer(
 		store:         dataStore,
 		encryptionKey: encryptionKey,
 		authCache:     authCache,
-		queryStorage:  queryStorage,
+		queryStorage:  shared.NewQueryStorage(queryStorage),
 		dumpConfig:    dumpConfig,
+		proxyProtocol: proxyProtocol,
+		sessionConfig: sessionConfig,
 		logger:        logger.With("component", "oracle-proxy"),
+		stopped:       make(chan struct{}),
 		shutdown:      make(chan struct{}),
 		ctx:           ctx,
 		cancel:        cancel,
@@ -73,6 +83,10 @@ func (s *Server) Start(addr string) error {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
+	if s.proxyProtocol.Enabled {
+		listener = shared.NewProxyProtocolListener(listener, s.proxyProtocol.TrustedNetworks())
+	}
+
 	s.setListener(listener)
 	s.listenAddr = addr
 	s.logger.InfoContext(s.ctx, "Oracle proxy server listening", slog.String("addr", addr),
@@ -91,7 +105,7 @@ func (s *Server) Start(addr string) error {
 		conn, err := listener.Accept()
 		if err != nil {
 			select {
-			case <-s.shutdown:
+			case <-s.stopped:
 				return nil
 			default:
 				s.logger.ErrorContext(s.ctx, "failed to accept connection", slog.Any("error", err))
@@ -104,22 +118,47 @@ func (s *Server) Start(addr string) error {
 
 		go func() {
 			defer s.wg.Done()
+			defer shared.TrackSession(store.ProtocolOracle)()
+			defer shared.RecoverPanic(s.ctx, s.logger, "oracle session")
+
 			s.handleConnection(conn)
 		}()
 	}
 }
 
+// SetQueryStorage replaces the query storage limits of the sessions started
+// from now on; the sessions in progress keep theirs.
+func (s *Server) SetQueryStorage(cfg config.QueryStorageConfig) {
+	s.queryStorage.Store(cfg)
+}
+
+// Drain stops accepting connections and waits, until ctx is done, for the
+// sessions in progress to end. Shutdown ends the remaining ones.
+func (s *Server) Drain(ctx context.Context) error {
+	s.stopAccepting(ctx)
+
+	return shared.WaitSessions(ctx, &s.wg)
+}
+
+// stopAccepting closes the listener, once.
+func (s *Server) stopAccepting(ctx context.Context) {
+	s.stopOnce.Do(func() {
+		close(s.stopped)
+
+		if listener := s.getListener(); listener != nil {
+			if err := listener.Close(); err != nil {
+				s.logger.ErrorContext(ctx, "failed to close listener", slog.Any("error", err))
+			}
+		}
+	})
+}
+
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopAccepting(ctx)
 	close(s.shutdown)
 	s.cancel()
 
-	if listener := s.getListener(); listener != nil {
-		if err := listener.Close(); err != nil {
-			s.logger.ErrorContext(ctx, "failed to close listener", slog.Any("error", err))
-		}
-	}
-
 	done := make(chan struct{})
 
 	go func() {
@@ -127,16 +166,22 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		close(done)
 	}()
 
+	var err error
+
 	select {
 	case <-done:
 		s.logger.InfoContext(ctx, "Oracle proxy server shutdown complete")
-
-		return nil
 	case <-ctx.Done():
 		s.logger.WarnContext(ctx, "Oracle proxy server shutdown timeout")
 
-		return ctx.Err()
+		err = ctx.Err()
 	}
+
+	// Sessions log their queries in the background: wait for those writes
+	// before the caller closes the store.
+	s.logWrites.Flush(ctx, s.logger)
+
+	return err
 }
 
 // Addr returns the listener address, useful for tests with ":0" port.
@@ -167,13 +212,6 @@ func (s *Server) getListener() net.Listener {
 // handleConnection handles a single Oracle client connection.
 func (s *Server) handleConnection(clientConn net.Conn) {
 	defer func() {
-		if r := recover(); r != nil {
-			s.logger.ErrorContext(s.ctx, "Oracle session panic",
-				slog.Any("panic", r),
-				slog.String("stack", string(debug.Stack())),
-				slog.Any("remote_addr", clientConn.RemoteAddr()))
-		}
-
 		if err := clientConn.Close(); err != nil {
 			s.logger.ErrorContext(s.ctx, "failed to close client connection", slog.Any("error", err))
 		}
@@ -181,7 +219,13 @@ func (s *Server) handleConnection(clientConn net.Conn) {
 
 	s.logger.DebugContext(s.ctx, "New Oracle connection", slog.Any("remote_addr", clientConn.RemoteAddr()))
 
-	session := newSession(clientConn, s.store, s.encryptionKey, s.logger, s.ctx, s.authCache, s.queryStorage, s.dumpConfig)
+	// Canceled when the session ends, so nothing started for it outlives it.
+	sessionCtx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	session := newSession(clientConn, s.store, s.encryptionKey, s.logger, sessionCtx, s.authCache, s.queryStorage.Load(), s.dumpConfig)
+	session.logWrites = &s.logWrites
+	session.sessionConfig = s.sessionConfig
 	if err := session.run(); err != nil {
 		// Health check probes (NLB, etc.) connect and immediately close — log at debug level
 		errStr := err.Error()
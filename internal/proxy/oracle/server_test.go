@@ -16,7 +16,7 @@ import (
 func TestOracleServer_StartsAndAcceptsConnections(t *testing.T) {
 	t.Parallel()
 
-	srv := NewServer(nil, nil, nil, config.QueryStorageConfig{}, config.DumpConfig{}, slog.Default())
+	srv := NewServer(nil, nil, nil, config.QueryStorageConfig{}, config.DumpConfig{}, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
 	go func() { _ = srv.Start(":0") }()
 	defer func() { _ = srv.Shutdown(t.Context()) }()
 
@@ -31,7 +31,7 @@ func TestOracleServer_StartsAndAcceptsConnections(t *testing.T) {
 func TestOracleServer_GracefulShutdown(t *testing.T) {
 	t.Parallel()
 
-	srv := NewServer(nil, nil, nil, config.QueryStorageConfig{}, config.DumpConfig{}, slog.Default())
+	srv := NewServer(nil, nil, nil, config.QueryStorageConfig{}, config.DumpConfig{}, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
 	go func() { _ = srv.Start(":0") }()
 
 	require.Eventually(t, func() bool { return srv.Addr() != nil }, time.Second, 10*time.Millisecond)
@@ -52,7 +52,7 @@ func TestOracleServer_GracefulShutdown(t *testing.T) {
 func TestOracleServer_ConcurrentConnections(t *testing.T) {
 	t.Parallel()
 
-	srv := NewServer(nil, nil, nil, config.QueryStorageConfig{}, config.DumpConfig{}, slog.Default())
+	srv := NewServer(nil, nil, nil, config.QueryStorageConfig{}, config.DumpConfig{}, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
 	go func() { _ = srv.Start(":0") }()
 	defer func() { _ = srv.Shutdown(t.Context()) }()
 
@@ -10,6 +10,7 @@ import (
 	"log/slog"
 	"net"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -32,6 +33,8 @@ type session struct {
 	logger        *slog.Logger
 	ctx           context.Context //nolint:containedctx
 	authCache     *cache.AuthCache
+	logWrites     *shared.LogWrites    // Server-wide tracker of background query log writes
+	sessionConfig config.SessionConfig // Proxy-wide idle and maximum duration timeouts
 
 	// Connection metadata
 	serviceName   string
@@ -100,6 +103,10 @@ type session struct {
 	// directly from the client connection.
 	clientAuthPhase1Pkt *TNSPacket
 
+	// clientTNSVersion is the TNS protocol version of the client's Connect
+	// packet, 0 when it was too short to carry one.
+	clientTNSVersion uint16
+
 	// upstreamAuthResp is the parsed upstream AUTH Phase 1 challenge, set by
 	// beginUpstreamAuth. For OCI (wide-encoding) clients it is populated BEFORE
 	// dbbat challenges the client, so the client challenge can reuse the
@@ -146,6 +153,31 @@ type session struct {
 	// revocation is signaled when this session's grant is revoked mid-flight,
 	// so the next command is rejected and the watchdog tears the session down.
 	revocation *cache.RevocationHandle
+
+	// termination is signaled when an admin terminates the connection through
+	// the API, so the watchdog tears the session down.
+	termination *cache.TerminationHandle
+
+	// masker applies the database's result masking to captured rows.
+	masker shared.LazyColumnMasker
+}
+
+// clientInfo is what the client declared about itself in its AUTH packets:
+// AUTH_PROGRAM_NM, plus the SESSION_CLIENT_DRIVER_NAME / VERSION pair thin
+// drivers send in Phase 2, and the TNS version of its Connect packet.
+// Recorded on the connection.
+func (s *session) clientInfo() *store.ClientInfo {
+	info := &store.ClientInfo{
+		ApplicationName: clientDeclaredProgramName(s.clientAuthPhase1Pkt),
+		Driver:          authPacketValue(s.clientAuthPhase2Pkt, "SESSION_CLIENT_DRIVER_NAME"),
+		DriverVersion:   authPacketValue(s.clientAuthPhase2Pkt, "SESSION_CLIENT_VERSION"),
+	}
+
+	if s.clientTNSVersion != 0 {
+		info.ProtocolVersion = strconv.Itoa(int(s.clientTNSVersion))
+	}
+
+	return info
 }
 
 // cumulativeClientBytes returns the running total of bytes exchanged with
@@ -319,7 +351,10 @@ func (s *session) run() error {
 
 	// Step 7: Record connection
 	sourceIP := store.ExtractSourceIP(s.clientConn.RemoteAddr())
-	conn, err := s.store.CreateConnection(s.ctx, s.user.UID, s.database.UID, sourceIP)
+	storeCtx, cancel := shared.StoreContext(s.ctx)
+	conn, err := s.store.CreateGrantConnection(storeCtx, s.grant, sourceIP, s.clientInfo())
+	cancel()
+
 	if err == nil {
 		s.connectionUID = conn.UID
 	}
@@ -404,6 +439,8 @@ func authRejectFor(err error) (uint16, string) {
 
 // resolveDatabase parses the service name from the Connect payload and looks up the database.
 func (s *session) resolveDatabase(connectPayload []byte) error {
+	s.clientTNSVersion = connectTNSVersion(connectPayload)
+
 	connectStr := extractConnectString(connectPayload)
 	s.logger.DebugContext(s.ctx, "TNS Connect received",
 		slog.Int("payload_len", len(connectPayload)),
@@ -429,7 +466,11 @@ func (s *session) resolveDatabase(connectPayload []byte) error {
 
 	s.logger = s.logger.With("service_name", s.serviceName)
 
-	db, err := s.store.GetServerByName(s.ctx, s.serviceName)
+	// Bounds the database lookups below.
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	db, err := s.store.GetServerByName(lookupCtx, s.serviceName)
 	if err == nil {
 		s.database = db
 
@@ -440,7 +481,7 @@ func (s *session) resolveDatabase(connectPayload []byte) error {
 	// name may be shared by several dbbat databases (mutualized instance), in
 	// which case the true database can only be chosen once the username is
 	// known (AUTH Phase 1) — see disambiguateDatabase.
-	candidates, err := s.store.ListServersByOracleServiceName(s.ctx, s.serviceName)
+	candidates, err := s.store.ListServersByOracleServiceName(lookupCtx, s.serviceName)
 	if err != nil || len(candidates) == 0 {
 		s.sendRefuse(ORA12514, "database not found")
 
@@ -501,8 +542,12 @@ func (s *session) disambiguateDatabase(phase1Pkt *TNSPacket) error {
 		return fmt.Errorf("failed to parse AUTH Phase 1: %w", err)
 	}
 
+	// Bounds the user and grant lookups below.
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
 	// Oracle clients uppercase usernames — normalize like authenticateClient.
-	user, err := s.store.GetUserByUsername(s.ctx, strings.ToLower(username))
+	user, err := s.store.GetUserByUsername(lookupCtx, strings.ToLower(username))
 	if errors.Is(err, store.ErrUserNotFound) {
 		return fmt.Errorf("%w: %s", ErrUserNotFound, username)
 	} else if err != nil {
@@ -512,7 +557,7 @@ func (s *session) disambiguateDatabase(phase1Pkt *TNSPacket) error {
 	var matched []*store.Server
 
 	for i := range s.databaseCandidates {
-		if _, err := s.store.GetActiveGrant(s.ctx, user.UID, s.databaseCandidates[i].UID); err == nil {
+		if _, err := s.store.GetActiveGrant(lookupCtx, user.UID, s.databaseCandidates[i].UID); err == nil {
 			matched = append(matched, &s.databaseCandidates[i])
 		}
 	}
@@ -651,7 +696,10 @@ func (s *session) resolveAPIKeyFromPhase2(o5 *O5LogonServer, verifiers []*o5Logo
 				slog.Any("error", err))
 		}
 
-		apiKey, err := s.store.GetAPIKeyByID(s.ctx, primary.apiKeyID)
+		lookupCtx, cancel := shared.StoreContext(s.ctx)
+		apiKey, err := s.store.GetAPIKeyByID(lookupCtx, primary.apiKeyID)
+		cancel()
+
 		if err != nil {
 			return nil, fmt.Errorf("failed to load API key by ID: %w", err)
 		}
@@ -693,7 +741,10 @@ func (s *session) resolveAPIKeyFromPhase2(o5 *O5LogonServer, verifiers []*o5Logo
 			continue
 		}
 
-		apiKey, err := s.store.VerifyAPIKey(s.ctx, plainPassword)
+		verifyCtx, cancel := shared.StoreContext(s.ctx)
+		apiKey, err := s.store.VerifyAPIKey(verifyCtx, plainPassword)
+		cancel()
+
 		if err != nil {
 			s.logger.DebugContext(s.ctx, "AUTH Phase 2: candidate plaintext failed API key verification",
 				slog.String("key_prefix", verifier.keyPrefix))
@@ -721,7 +772,10 @@ func (s *session) resolveAPIKeyFromPhase2(o5 *O5LogonServer, verifiers []*o5Logo
 		if od := apiKey.OracleData(); od != nil && !od.UserSalt {
 			keyID, plain, encKey := apiKey.ID, plainPassword, s.encryptionKey
 			go func() {
-				if err := s.store.UpgradeAPIKeyO5LogonVerifiers(context.Background(), keyID, plain, encKey); err != nil {
+				ctx, cancel := shared.StoreContext(context.Background())
+				defer cancel()
+
+				if err := s.store.UpgradeAPIKeyO5LogonVerifiers(ctx, keyID, plain, encKey); err != nil {
 					s.logger.WarnContext(context.Background(), "failed to upgrade legacy O5LOGON verifiers to user salts",
 						slog.String("key_id", keyID.String()), slog.Any("error", err))
 				}
@@ -758,7 +812,11 @@ func (s *session) authenticateClient(phase1Pkt *TNSPacket) error {
 	s.username = strings.ToLower(username)
 
 	// Look up dbbat user
-	user, err := s.store.GetUserByUsername(s.ctx, s.username)
+	// Bounds the user and grant lookups below.
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	user, err := s.store.GetUserByUsername(lookupCtx, s.username)
 	if errors.Is(err, store.ErrUserNotFound) {
 		return fmt.Errorf("%w: %s", ErrUserNotFound, username)
 	} else if err != nil {
@@ -768,13 +826,17 @@ func (s *session) authenticateClient(phase1Pkt *TNSPacket) error {
 	s.user = user
 
 	// Check for active grant
-	grant, err := s.store.GetActiveGrant(s.ctx, user.UID, s.database.UID)
+	grant, err := s.store.GetActiveGrant(lookupCtx, user.UID, s.database.UID)
 	if err != nil {
 		return fmt.Errorf("%w: user=%s database=%s", ErrNoActiveGrant, username, s.database.Name)
 	}
 
 	s.grant = grant
 
+	if err := shared.CheckControls(grant, store.ProtocolOracle); err != nil {
+		return err
+	}
+
 	// Check quotas
 	if err := s.checkQuotas(); err != nil {
 		return err
@@ -871,7 +933,12 @@ func (s *session) authenticateClient(phase1Pkt *TNSPacket) error {
 	s.clientCombinedKey = o5.CombinedKey
 
 	// Increment usage asynchronously
-	go func() { _ = s.store.IncrementAPIKeyUsage(context.Background(), apiKey.ID) }()
+	go func() {
+		ctx, cancel := shared.StoreContext(context.Background())
+		defer cancel()
+
+		_ = s.store.IncrementAPIKeyUsage(ctx, apiKey.ID)
+	}()
 
 	// NOTE: AUTH OK is NOT sent here. It's sent in run() AFTER upstream auth completes,
 	// so the relay can immediately forward go-ora's post-auth messages to upstream.
@@ -983,7 +1050,10 @@ func (s *session) decryptVerifierData(key *store.APIKey) *o5LogonVerifierData {
 // verifier-bearing key is the single candidate — the pre-user-salt behavior,
 // where only that specific key can authenticate.
 func (s *session) loadO5LogonVerifiers(userID uuid.UUID) ([]*o5LogonVerifierData, error) {
-	keys, err := s.store.ListAPIKeys(s.ctx, store.APIKeyFilter{
+	ctx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	keys, err := s.store.ListAPIKeys(ctx, store.APIKeyFilter{
 		UserID:  &userID,
 		KeyType: strPtr(store.KeyTypeAPI),
 	})
@@ -1083,13 +1153,23 @@ func (s *session) proxyMessages() error {
 
 	s.revocation = s.store.Revocations().Register(grantUID)
 
+	// Register the connection so an admin can terminate it through the API.
+	// Deregistered in cleanup.
+	s.termination = s.store.Terminations().Register(s.connectionUID)
+
 	// Build the limit guard now that the grant is known, and run a watchdog to
 	// tear the session down if a limit is crossed (or the grant is revoked)
-	// while a query is blocked producing no traffic. The inline check in
-	// upstreamToClient handles the actively-streaming case with a clean TTC
-	// error frame.
+	// while a query is blocked producing no traffic, or once the session
+	// timeouts are reached. The inline check in upstreamToClient handles the
+	// actively-streaming case with a clean TTC error frame. The query tracker
+	// belongs to the relays, so only traffic tells an Oracle session is not
+	// idle.
+	idle, maxDuration := s.sessionConfig.Timeouts()
 	s.guard = shared.NewLimitGuard(s.grant, s.bytesFromClient, s.bytesToClient).
-		WithRevocation(s.revocation.Flag())
+		WithRevocation(s.revocation.Flag()).
+		WithExtension(s.revocation.Extension()).
+		WithTermination(s.termination.Flag()).
+		WithSessionTimeouts(idle, maxDuration, nil)
 
 	watchCtx, cancelWatch := context.WithCancel(s.ctx)
 	defer cancelWatch()
@@ -1100,12 +1180,12 @@ func (s *session) proxyMessages() error {
 
 	// Client → Upstream (with query interception)
 	go func() {
-		errChan <- s.clientToUpstream()
+		errChan <- shared.Safe(s.ctx, s.logger, "oracle relay", s.clientToUpstream)
 	}()
 
 	// Upstream → Client (with response interception)
 	go func() {
-		errChan <- s.upstreamToClient()
+		errChan <- shared.Safe(s.ctx, s.logger, "oracle relay", s.upstreamToClient)
 	}()
 
 	// Wait for either direction to close
@@ -1117,7 +1197,7 @@ func (s *session) proxyMessages() error {
 // is parked in a Read/Write so the session tears down. This is the only way to
 // terminate a query that is blocked producing no traffic (idle expiry).
 func (s *session) onLimitViolation(err error) {
-	s.logger.WarnContext(s.ctx, "terminating Oracle session: grant no longer valid mid-stream",
+	s.logger.WarnContext(s.ctx, "terminating Oracle session: grant no longer valid or session timed out",
 		slog.Any("error", err))
 
 	if s.upstreamConn != nil {
@@ -1154,6 +1234,10 @@ func (s *session) clientToUpstream() error {
 			}
 		}
 
+		if pending := s.tracker.pendingQuery; pending != nil {
+			pending.timer.Forwarded(time.Now())
+		}
+
 		// Forward to upstream
 		if err := writeTNSPacket(s.upstreamConn, pkt); err != nil {
 			return fmt.Errorf("upstream write error: %w", err)
@@ -1308,6 +1392,14 @@ func (s *session) upstreamToClient() error {
 // the response-decode path is recovered so the upstream packet is still
 // forwarded to the client and the session survives.
 func (s *session) interceptUpstreamMessage(pkt *TNSPacket) {
+	// The time spent here is the proxy's, not upstream's.
+	if pending := s.tracker.pendingQuery; pending != nil {
+		received := time.Now()
+		pending.timer.Replied(received)
+
+		defer func() { pending.timer.Processed(time.Since(received)) }()
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			s.logger.WarnContext(s.ctx, "recovered from panic intercepting upstream message",
@@ -1477,8 +1569,19 @@ func (s *session) cleanup() {
 		}
 	}
 
+	s.store.Terminations().Deregister(s.connectionUID, s.termination)
+
 	if s.connectionUID != uuid.Nil {
-		if err := s.store.CloseConnection(s.ctx, s.connectionUID); err != nil {
+		var reason string
+		if s.termination.Terminated() {
+			reason = store.DisconnectReasonTerminated
+		}
+
+		// Detached from the session context, which is canceled on shutdown.
+		err := shared.RetryStoreWrite(context.WithoutCancel(s.ctx), func(ctx context.Context) error {
+			return s.store.CloseConnection(ctx, s.connectionUID, reason)
+		})
+		if err != nil {
 			s.logger.ErrorContext(s.ctx, "failed to close connection record", slog.Any("error", err))
 		}
 	}
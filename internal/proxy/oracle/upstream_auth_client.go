@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
 	"github.com/fclairamb/dbbat/internal/version"
 )
 
@@ -87,6 +88,13 @@ func (s *session) oracleDbbatUsername() string {
 // be embedded as the "$appName" suffix of the upstream-facing program name.
 // Returns "" if pkt is nil or the key isn't present.
 func clientDeclaredProgramName(pkt *TNSPacket) string {
+	return authPacketValue(pkt, authKeyProgramNM)
+}
+
+// authPacketValue returns the value of a KV pair from a captured client AUTH
+// packet, in either KV encoding. Returns "" if pkt is nil or the key isn't
+// present.
+func authPacketValue(pkt *TNSPacket, key string) string {
 	if pkt == nil {
 		return ""
 	}
@@ -94,10 +102,10 @@ func clientDeclaredProgramName(pkt *TNSPacket) string {
 	payload := pkt.Payload
 
 	if payloadUsesWideKVEncoding(payload) {
-		return findKVByKeyBytesWide(payload, []byte(authKeyProgramNM))
+		return findKVByKeyBytesWide(payload, []byte(key))
 	}
 
-	return findKVByKeyBytes(payload, []byte(authKeyProgramNM))
+	return findKVByKeyBytes(payload, []byte(key))
 }
 
 // buildUpstreamProgramName composes the canonical dbbat-branded AUTH_PROGRAM_NM
@@ -139,8 +147,8 @@ func (s *session) beginUpstreamAuth() error {
 		return ErrUpstreamConnNotSet
 	}
 
-	if err := s.database.DecryptPassword(s.encryptionKey); err != nil {
-		return fmt.Errorf("decrypt database password: %w", err)
+	if err := shared.UseUpstreamCredentials(s.ctx, s.database, s.encryptionKey, s.upstreamAccessLevel()); err != nil {
+		return fmt.Errorf("database credentials: %w", err)
 	}
 
 	identity := defaultDriverIdentity()
@@ -166,6 +174,41 @@ func (s *session) beginUpstreamAuth() error {
 	return nil
 }
 
+// upstreamAccessLevel returns the access level whose upstream credentials the
+// session logs in with. OCI clients drive upstream AUTH before dbbat has
+// authenticated them: their grant is then looked up from the username of
+// their AUTH Phase 1, and read-only is assumed without one, since the login
+// is bound to fail.
+func (s *session) upstreamAccessLevel() string {
+	if s.grant != nil {
+		return s.grant.AccessLevel()
+	}
+
+	if s.clientAuthPhase1Pkt == nil {
+		return store.AccessLevelReadOnly
+	}
+
+	username, err := parseAuthPhase1(s.clientAuthPhase1Pkt.Payload)
+	if err != nil {
+		return store.AccessLevelReadOnly
+	}
+
+	lookupCtx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	user, err := s.store.GetUserByUsername(lookupCtx, strings.ToLower(username))
+	if err != nil {
+		return store.AccessLevelReadOnly
+	}
+
+	grant, err := s.store.GetActiveGrant(lookupCtx, user.UID, s.database.UID)
+	if err != nil {
+		return store.AccessLevelReadOnly
+	}
+
+	return grant.AccessLevel()
+}
+
 // finishUpstreamAuth derives the auth secrets from the challenge captured by
 // beginUpstreamAuth, sends AUTH Phase 2 upstream, and reads the AUTH OK.
 func (s *session) finishUpstreamAuth() error {
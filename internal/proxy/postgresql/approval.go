@@ -0,0 +1,286 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// approvalPollInterval is how often a held statement's approval is read back
+// from the store. Polling lets any instance's API decide on it.
+const approvalPollInterval = time.Second
+
+// statementDeniedError carries the reason an administrator gave for denying
+// a statement.
+type statementDeniedError struct {
+	reason string
+}
+
+func (e *statementDeniedError) Error() string {
+	if e.reason == "" {
+		return ErrStatementDenied.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrStatementDenied, e.reason)
+}
+
+func (e *statementDeniedError) Unwrap() error {
+	return ErrStatementDenied
+}
+
+// heldStatement is the write_requires_approval wait in progress, which a
+// CancelRequest of the client ends.
+type heldStatement struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc // nil when no statement is held
+}
+
+// set records the cancel function of the wait starting, or clears it.
+func (h *heldStatement) set(cancel context.CancelFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cancel = cancel
+}
+
+// cancelHeldStatement ends the approval wait in progress, reporting whether
+// there was one: upstream is then idle and has nothing to cancel.
+func (s *Session) cancelHeldStatement() bool {
+	s.held.mu.Lock()
+	defer s.held.mu.Unlock()
+
+	if s.held.cancel == nil {
+		return false
+	}
+
+	s.held.cancel()
+
+	return true
+}
+
+// awaitApproval holds a Query or Execute message whose statement needs an
+// administrator's approval under the write_requires_approval control, until
+// the statement is approved, denied, canceled or times out. The client is
+// told with a NOTICE. A statement not approved is dropped from the queries
+// in flight and the returned error refuses the message.
+//
+// It runs on the client→upstream goroutine without queryMu, so the session
+// keeps relaying upstream's messages and its limits keep being watched.
+func (s *Session) awaitApproval(msg pgproto3.FrontendMessage) error {
+	s.queryMu.Lock()
+	query := s.heldQuery(msg)
+	s.queryMu.Unlock()
+
+	if query == nil {
+		return nil
+	}
+
+	sqlText := query.sql
+	if query.batch != "" {
+		sqlText = query.batch
+	}
+
+	err := s.waitForApproval(sqlText)
+	if err != nil {
+		s.queryMu.Lock()
+		s.dropQuery(query)
+		s.queryMu.Unlock()
+	}
+
+	return err
+}
+
+// heldQuery returns the query msg just started when it awaits approval. The
+// caller holds queryMu.
+func (s *Session) heldQuery(msg pgproto3.FrontendMessage) *pendingQuery {
+	var query *pendingQuery
+
+	switch msg.(type) {
+	case *pgproto3.Query:
+		query = s.currentQuery
+	case *pgproto3.Execute:
+		if n := len(s.extendedState.pendingQueries); n > 0 {
+			query = s.extendedState.pendingQueries[n-1]
+		}
+	}
+
+	if query == nil || !query.awaitsApproval {
+		return nil
+	}
+
+	return query
+}
+
+// dropQuery forgets a query that was never forwarded. The caller holds
+// queryMu.
+func (s *Session) dropQuery(query *pendingQuery) {
+	if s.currentQuery == query {
+		s.currentQuery = nil
+
+		return
+	}
+
+	queries := s.extendedState.pendingQueries
+	if n := len(queries); n > 0 && queries[n-1] == query {
+		s.extendedState.pendingQueries = queries[:n-1]
+	}
+}
+
+// waitForApproval files the statement for approval and polls its decision.
+func (s *Session) waitForApproval(sqlText string) error {
+	timeout := s.sessionConfig.ApprovalWait()
+
+	approval, err := s.store.CreateStatementApproval(s.ctx, &store.StatementApproval{
+		ConnectionID: s.connectionUID,
+		GrantID:      s.grant.UID,
+		UserID:       s.user.UID,
+		DatabaseID:   s.database.UID,
+		SQLText:      sqlText,
+		ExpiresAt:    time.Now().Add(timeout),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request statement approval: %w", err)
+	}
+
+	logger := s.logger.With(slog.String("statement_approval_uid", approval.UID.String()))
+	logger.InfoContext(s.ctx, "statement awaiting approval", slog.Duration("timeout", timeout))
+
+	s.sendApprovalNotice(approval, timeout)
+
+	ctx, cancel := context.WithDeadline(s.relayCtx, approval.ExpiresAt)
+	defer cancel()
+
+	s.held.set(cancel)
+	defer s.held.set(nil)
+
+	ticker := time.NewTicker(approvalPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current, err := s.store.GetStatementApproval(ctx, approval.UID)
+			if err != nil {
+				logger.WarnContext(ctx, "failed to read statement approval", slog.Any("error", err))
+
+				continue
+			}
+
+			approval = current
+		case <-ctx.Done():
+			approval = s.expireApproval(approval)
+		}
+
+		switch approval.Status {
+		case store.StatementApprovalPending:
+			continue
+		case store.StatementApprovalApproved:
+			logger.InfoContext(s.ctx, "statement approved")
+
+			return nil
+		case store.StatementApprovalDenied:
+			logger.InfoContext(s.ctx, "statement denied")
+
+			reason := ""
+			if approval.DecisionReason != nil {
+				reason = *approval.DecisionReason
+			}
+
+			return &statementDeniedError{reason: reason}
+		default:
+			logger.InfoContext(s.ctx, "statement approval expired")
+
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrApprovalTimeout
+			}
+
+			return ErrApprovalCanceled
+		}
+	}
+}
+
+// expireApproval expires an approval the session stops waiting for. A
+// decision that landed in the meantime wins, and is returned instead.
+func (s *Session) expireApproval(approval *store.StatementApproval) *store.StatementApproval {
+	ctx := context.WithoutCancel(s.ctx)
+
+	expired, err := s.store.ExpireStatementApproval(ctx, approval.UID)
+	if err == nil {
+		return expired
+	}
+
+	if errors.Is(err, store.ErrStatementApprovalNotPending) {
+		if decided, getErr := s.store.GetStatementApproval(ctx, approval.UID); getErr == nil {
+			return decided
+		}
+	}
+
+	s.logger.WarnContext(ctx, "failed to expire statement approval",
+		slog.String("statement_approval_uid", approval.UID.String()), slog.Any("error", err))
+
+	fallback := *approval
+	fallback.Status = store.StatementApprovalExpired
+
+	return &fallback
+}
+
+// sendApprovalNotice tells the client its statement awaits approval. It is
+// written straight to the client connection, as the client→upstream
+// goroutine's errors are.
+func (s *Session) sendApprovalNotice(approval *store.StatementApproval, timeout time.Duration) {
+	notice := &pgproto3.NoticeResponse{
+		Severity:            "NOTICE",
+		SeverityUnlocalized: "NOTICE",
+		Code:                "00000", // successful_completion
+		Message:             "statement awaiting approval " + approval.UID.String(),
+		Detail: fmt.Sprintf("Your access grant requires an administrator to approve write statements "+
+			"(write_requires_approval control). The statement runs once approved, and is canceled if not approved within %s.", timeout),
+	}
+
+	buf, err := notice.Encode(nil)
+	if err != nil {
+		s.logger.ErrorContext(s.ctx, "failed to encode approval notice", slog.Any("error", err))
+
+		return
+	}
+
+	if _, err := s.clientConn.Write(buf); err != nil {
+		s.logger.WarnContext(s.ctx, "failed to write approval notice to client", slog.Any("error", err))
+	}
+}
+
+// sendExpiryNotice warns the client that its grant expires soon, and how to
+// get it extended. The limit watchdog calls it once per expiry; like the
+// approval notice, it is written straight to the client connection.
+func (s *Session) sendExpiryNotice(expiresAt time.Time) {
+	remaining := max(time.Until(expiresAt).Round(time.Second), 0)
+
+	notice := &pgproto3.NoticeResponse{
+		Severity:            "WARNING",
+		SeverityUnlocalized: "WARNING",
+		Code:                "01000", // warning
+		Message: fmt.Sprintf("access grant expires in %s, at %s",
+			remaining, expiresAt.UTC().Format(time.RFC3339)),
+		Detail: "The session is closed when the grant expires.",
+		Hint: fmt.Sprintf("Request an extension from the web interface or with POST /api/v1/grants/%s/extension-requests.",
+			s.grant.UID),
+	}
+
+	buf, err := notice.Encode(nil)
+	if err != nil {
+		s.logger.ErrorContext(s.ctx, "failed to encode expiry notice", slog.Any("error", err))
+
+		return
+	}
+
+	if _, err := s.clientConn.Write(buf); err != nil {
+		s.logger.WarnContext(s.ctx, "failed to write expiry notice to client", slog.Any("error", err))
+	}
+}
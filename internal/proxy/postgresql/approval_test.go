@@ -0,0 +1,130 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestHandleQuery_WriteRequiresApproval(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sql  string
+		held bool
+	}{
+		{sql: "SELECT * FROM users", held: false},
+		{sql: "UPDATE users SET name = 'x' WHERE id = 1", held: true},
+		{sql: "SELECT 1; DELETE FROM users", held: true},
+		{sql: "CREATE TABLE t (id int)", held: true},
+	}
+
+	for _, tt := range tests {
+		s := newTestSessionWithControls([]string{store.ControlWriteRequiresApproval})
+
+		msg := &pgproto3.Query{String: tt.sql}
+		if err := s.interceptClientMessage(msg); err != nil {
+			t.Fatalf("interceptClientMessage(%q) error = %v", tt.sql, err)
+		}
+
+		if got := s.heldQuery(msg) != nil; got != tt.held {
+			t.Errorf("heldQuery(%q) held = %v, want %v", tt.sql, got, tt.held)
+		}
+	}
+
+	s := newTestSession("write")
+
+	msg := &pgproto3.Query{String: "DELETE FROM users"}
+	if err := s.interceptClientMessage(msg); err != nil {
+		t.Fatalf("interceptClientMessage() error = %v", err)
+	}
+
+	if err := s.awaitApproval(msg); err != nil {
+		t.Errorf("awaitApproval() without the control error = %v", err)
+	}
+}
+
+func TestHandleExecute_WriteRequiresApproval(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSessionWithControls([]string{store.ControlWriteRequiresApproval})
+
+	for _, msg := range []pgproto3.FrontendMessage{
+		&pgproto3.Parse{Name: "read", Query: "SELECT * FROM users WHERE id = $1"},
+		&pgproto3.Parse{Name: "write", Query: "DELETE FROM users WHERE id = $1"},
+		&pgproto3.Bind{DestinationPortal: "r", PreparedStatement: "read"},
+		&pgproto3.Bind{DestinationPortal: "w", PreparedStatement: "write"},
+	} {
+		if err := s.interceptClientMessage(msg); err != nil {
+			t.Fatalf("interceptClientMessage(%T) error = %v", msg, err)
+		}
+	}
+
+	read := &pgproto3.Execute{Portal: "r"}
+	if err := s.interceptClientMessage(read); err != nil {
+		t.Fatalf("interceptClientMessage(Execute) error = %v", err)
+	}
+
+	if s.heldQuery(read) != nil {
+		t.Error("a read statement is held for approval")
+	}
+
+	write := &pgproto3.Execute{Portal: "w"}
+	if err := s.interceptClientMessage(write); err != nil {
+		t.Fatalf("interceptClientMessage(Execute) error = %v", err)
+	}
+
+	query := s.heldQuery(write)
+	if query == nil || query.sql != "DELETE FROM users WHERE id = $1" {
+		t.Fatalf("heldQuery() = %+v, want the DELETE", query)
+	}
+
+	s.dropQuery(query)
+
+	if n := len(s.extendedState.pendingQueries); n != 1 {
+		t.Errorf("pending queries after drop = %d, want 1", n)
+	}
+}
+
+func TestCancelHeldStatement(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSession("write")
+
+	if s.cancelHeldStatement() {
+		t.Error("cancelHeldStatement() = true with no statement held")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.held.set(cancel)
+
+	if !s.cancelHeldStatement() {
+		t.Error("cancelHeldStatement() = false with a statement held")
+	}
+
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Error("the approval wait was not canceled")
+	}
+}
+
+func TestStatementDeniedError(t *testing.T) {
+	t.Parallel()
+
+	err := error(&statementDeniedError{reason: "wrong table"})
+	if !errors.Is(err, ErrStatementDenied) {
+		t.Errorf("errors.Is(%v, ErrStatementDenied) = false", err)
+	}
+
+	clientErr := classifyQueryError(err)
+	if resp := clientErr.render("en", messageData{}); resp.Detail != "wrong table" {
+		t.Errorf("denied detail = %q, want the reason", resp.Detail)
+	}
+
+	if clientErr.blocked {
+		t.Error("a denied statement takes the blocked message")
+	}
+}
@@ -3,11 +3,14 @@ package postgresql
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"net"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgproto3"
 
+	"github.com/fclairamb/dbbat/internal/audit"
 	"github.com/fclairamb/dbbat/internal/crypto"
 	"github.com/fclairamb/dbbat/internal/proxy/shared"
 	"github.com/fclairamb/dbbat/internal/store"
@@ -24,7 +27,7 @@ func (s *Session) authenticate() error {
 	// Extract username and database from startup message
 	startup, ok := startupMsg.(*pgproto3.StartupMessage)
 	if !ok {
-		s.sendError("invalid startup message")
+		s.sendError(sqlStateProtocolViolation, msgInvalidStartup)
 
 		return ErrExpectedStartupMessage
 	}
@@ -32,27 +35,47 @@ func (s *Session) authenticate() error {
 	username := startup.Parameters["user"]
 	databaseName := startup.Parameters["database"]
 	s.clientApplicationName = startup.Parameters["application_name"]
+	s.clientLocale = clientLocale(startup.Parameters)
+	s.clientInfo = startupClientInfo(startup)
+	s.messageData = messageData{User: username, Database: databaseName}
 
 	if username == "" || databaseName == "" {
-		s.sendError("username and database required")
+		s.sendError(sqlStateInvalidAuthorization, msgMissingCredentials)
 
 		return ErrMissingCredentials
 	}
 
-	// Look up user
-	user, err := s.store.GetUserByUsername(s.ctx, username)
+	// Bounds the user, database and grant lookups below.
+	lookupCtx, cancelLookup := shared.StoreContext(s.ctx)
+	defer cancelLookup()
+
+	// Look up user, or the grant's user for a proxy credential
+	var user *store.User
+	if store.IsProxyCredentialUsername(username) {
+		user, err = s.lookupProxyCredential(lookupCtx, username)
+	} else {
+		user, err = s.store.GetUserByUsername(lookupCtx, username)
+	}
+
 	if err != nil {
-		s.sendError("authentication failed")
+		// Same error as a wrong password, so usernames can't be enumerated.
+		s.sendError(sqlStateInvalidPassword, msgAuthFailed)
 
 		return fmt.Errorf("user not found: %w", err)
 	}
 
 	s.user = user
 
+	if !s.listener.servesDatabase(databaseName) {
+		s.sendError(sqlStateInvalidAuthorization, msgListenerDatabase)
+
+		return fmt.Errorf("%w: %s", ErrDatabaseNotServed, databaseName)
+	}
+
 	// Look up database configuration
-	database, err := s.store.GetServerByName(s.ctx, databaseName)
+	database, err := s.store.GetServerByName(lookupCtx, databaseName)
 	if err != nil {
-		s.sendError("database not found")
+		s.sendError(sqlStateInvalidCatalogName, msgDatabaseNotFound)
 
 		return fmt.Errorf("database not found: %w", err)
 	}
@@ -60,32 +83,99 @@ func (s *Session) authenticate() error {
 	s.database = database
 
 	// Check for active grant
-	grant, err := s.store.GetActiveGrant(s.ctx, user.UID, database.UID)
+	var grant *store.Grant
+	if s.proxyCredential != nil {
+		grant, err = s.proxyCredentialGrant(lookupCtx)
+	} else {
+		grant, err = s.store.GetActiveGrant(lookupCtx, user.UID, database.UID)
+	}
+
 	if err != nil {
-		s.sendError("access denied: no valid grant")
+		s.sendError(sqlStateInsufficientPrivilege, msgNoGrant)
 
 		return fmt.Errorf("no active grant: %w", err)
 	}
 
 	s.grant = grant
 
+	if err := s.checkClientNetwork(lookupCtx); err != nil {
+		return err
+	}
+
+	if err := shared.CheckControls(grant, store.ProtocolPostgreSQL); err != nil {
+		clientErr := classifyQueryError(err)
+		clientErr.severity = "FATAL"
+		s.writeClientError(clientErr)
+
+		return err
+	}
+
+	if err := s.checkReplication(startup.Parameters["replication"]); err != nil {
+		return err
+	}
+
 	// Check quotas
 	if err := s.checkQuotas(); err != nil {
-		s.sendError(err.Error())
+		clientErr := classifyQueryError(err)
+		clientErr.severity = "FATAL"
+		s.writeClientError(clientErr)
 
 		return err
 	}
 
-	// Request password from client (cleartext for simplicity)
-	authRequest := &pgproto3.AuthenticationCleartextPassword{}
+	// SCRAM-SHA-256 when the user has a stored verifier, keeping the secret
+	// off the wire; cleartext otherwise. Proxy credentials are only stored
+	// hashed, so they always go in clear.
+	if s.proxyCredential != nil {
+		err = s.authenticateProxyCredential()
+	} else if srv := s.newClientSCRAMServer(lookupCtx); srv != nil {
+		err = s.authenticateSCRAM(srv)
+	} else {
+		err = s.authenticateCleartext()
+	}
 
-	buf, err := authRequest.Encode(nil)
 	if err != nil {
-		return fmt.Errorf("failed to encode auth request: %w", err)
+		return err
 	}
 
-	if _, err := s.clientConn.Write(buf); err != nil {
-		return fmt.Errorf("failed to send auth request: %w", err)
+	s.authenticated = true
+
+	return nil
+}
+
+// checkClientNetwork refuses a client connecting from outside the networks
+// its grant allows, before it is asked for a password. The attempt is
+// audit-logged with its source IP.
+func (s *Session) checkClientNetwork(ctx context.Context) error {
+	sourceIP := store.ExtractSourceIP(s.clientConn.RemoteAddr())
+	if s.grant.AllowsClientIP(net.ParseIP(sourceIP)) {
+		return nil
+	}
+
+	if err := audit.Emit(store.WithSourceIP(ctx, sourceIP), s.store, audit.Event{
+		Payload: audit.GrantClientRefusedV1{
+			GrantUID:   s.grant.UID,
+			DatabaseID: s.database.UID,
+			SourceIP:   sourceIP,
+		},
+		UserID:      &s.user.UID,
+		PerformedBy: &s.user.UID,
+	}); err != nil {
+		s.logger.ErrorContext(s.ctx, "failed to log refused client", slog.Any("error", err))
+	}
+
+	clientErr := newFatalError(sqlStateInvalidAuthorization, msgClientNotAllowed)
+	clientErr.cause = sourceIP
+	s.writeClientError(clientErr)
+
+	return fmt.Errorf("%w: %s", ErrClientNotAllowed, sourceIP)
+}
+
+// authenticateCleartext asks the client for its password in clear and checks
+// it as an API key or as the user's password.
+func (s *Session) authenticateCleartext() error {
+	if err := s.writeAuthRequest(&pgproto3.AuthenticationCleartextPassword{}); err != nil {
+		return err
 	}
 
 	// Receive password - read directly since PasswordMessage comes before frontend is set up
@@ -97,34 +187,185 @@ func (s *Session) authenticate() error {
 	// Try API key authentication if password looks like an API key
 	if isAPIKey(passwordMsg.Password) {
 		if err := s.authenticateWithAPIKey(passwordMsg.Password); err != nil {
-			s.sendError("authentication failed")
+			s.sendError(sqlStateInvalidPassword, msgAuthFailed)
 
 			return ErrInvalidPassword
 		}
 
-		s.authenticated = true
-
 		return nil
 	}
 
 	// Verify password (using cache if available)
 	var valid bool
 	if s.authCache != nil {
-		valid, err = s.authCache.VerifyPassword(s.ctx, user.UID.String(), passwordMsg.Password, user.PasswordHash)
+		valid, err = s.authCache.VerifyUserPassword(s.ctx, s.user.UID.String(), s.user.Username, passwordMsg.Password, s.user.PasswordHash)
 	} else {
-		valid, err = crypto.VerifyPassword(user.PasswordHash, passwordMsg.Password)
+		valid, err = crypto.VerifyPassword(s.user.PasswordHash, passwordMsg.Password)
 	}
 	if err != nil || !valid {
-		s.sendError("authentication failed")
+		s.sendError(sqlStateInvalidPassword, msgAuthFailed)
 
 		return ErrInvalidPassword
 	}
 
-	s.authenticated = true
+	shared.UpgradePasswordHash(s.ctx, s.logger, s.store, s.user, passwordMsg.Password)
+
+	return nil
+}
+
+// lookupProxyCredential finds the pending proxy credential of username and
+// returns the user of its grant, whom the session runs as.
+func (s *Session) lookupProxyCredential(ctx context.Context, username string) (*store.User, error) {
+	cred, err := s.store.GetPendingProxyCredential(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	grant, err := s.store.GetGrantByUID(ctx, cred.GrantID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.store.GetUserByUID(ctx, grant.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.proxyCredential = cred
+
+	return user, nil
+}
+
+// proxyCredentialGrant returns the grant of the session's proxy credential
+// when it is active and on the requested database.
+func (s *Session) proxyCredentialGrant(ctx context.Context) (*store.Grant, error) {
+	grant, err := s.store.GetGrantByUID(ctx, s.proxyCredential.GrantID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if grant.DatabaseID != s.database.UID || grant.RevokedAt != nil ||
+		now.Before(grant.StartsAt) || !now.Before(grant.ExpiresAt) {
+		return nil, store.ErrNoActiveGrant
+	}
+
+	return grant, nil
+}
+
+// authenticateProxyCredential asks the client for the secret of its proxy
+// credential in clear and consumes the credential: it can't open another
+// connection. Its use is audit-logged on behalf of the administrator who
+// issued it.
+func (s *Session) authenticateProxyCredential() error {
+	if err := s.writeAuthRequest(&pgproto3.AuthenticationCleartextPassword{}); err != nil {
+		return err
+	}
+
+	passwordMsg, err := s.receivePasswordMessage()
+	if err != nil {
+		return fmt.Errorf("failed to receive password: %w", err)
+	}
+
+	ctx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	if err := s.store.ConsumeProxyCredential(ctx, s.proxyCredential, passwordMsg.Password); err != nil {
+		s.sendError(sqlStateInvalidPassword, msgAuthFailed)
+
+		return fmt.Errorf("%w: %w", ErrInvalidPassword, err)
+	}
+
+	cred := s.proxyCredential
+	sourceIP := store.ExtractSourceIP(s.clientConn.RemoteAddr())
+
+	if err := audit.Emit(store.WithSourceIP(ctx, sourceIP), s.store, audit.Event{
+		Payload: audit.ProxyCredentialUsedV1{
+			CredentialUID: cred.UID,
+			GrantUID:      cred.GrantID,
+			DatabaseID:    s.database.UID,
+			Username:      cred.Username,
+			Principal:     cred.Principal,
+			IssuedBy:      cred.IssuedBy,
+			SourceIP:      sourceIP,
+		},
+		UserID:      &s.user.UID,
+		PerformedBy: &cred.IssuedBy,
+	}); err != nil {
+		s.logger.ErrorContext(s.ctx, "failed to log proxy credential use", slog.Any("error", err))
+	}
+
+	s.logger = s.logger.With(slog.String("proxy_credential", cred.Username), slog.String("principal", cred.Principal))
+
+	return nil
+}
+
+// linkProxyCredential records the connection a proxy credential opened.
+func (s *Session) linkProxyCredential() {
+	if s.proxyCredential == nil {
+		return
+	}
+
+	ctx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	if err := s.store.SetProxyCredentialConnection(ctx, s.proxyCredential.UID, s.connectionUID); err != nil {
+		s.logger.ErrorContext(s.ctx, "failed to link proxy credential to its connection", slog.Any("error", err))
+	}
+}
+
+// writeAuthRequest writes an authentication request to the client, before
+// the protocol backend is set up.
+func (s *Session) writeAuthRequest(msg pgproto3.BackendMessage) error {
+	buf, err := msg.Encode(nil)
+	if err != nil {
+		return fmt.Errorf("failed to encode auth request: %w", err)
+	}
+
+	if _, err := s.clientConn.Write(buf); err != nil {
+		return fmt.Errorf("failed to send auth request: %w", err)
+	}
 
 	return nil
 }
 
+// pgjdbcApplicationName is the application_name pgjdbc sends unless the
+// application sets its own.
+const pgjdbcApplicationName = "PostgreSQL JDBC Driver"
+
+// startupClientInfo extracts what the client declared about itself from its
+// startup message. PostgreSQL has no driver field: drivers identify
+// themselves through application_name (pgjdbc sends "PostgreSQL JDBC Driver",
+// recorded as its driver too) and their choice of parameters (DateStyle,
+// extra_float_digits, ...), which are kept as-is.
+func startupClientInfo(startup *pgproto3.StartupMessage) *store.ClientInfo {
+	params := startup.Parameters
+	info := &store.ClientInfo{
+		ApplicationName: params["application_name"],
+		ClientEncoding:  params["client_encoding"],
+		ProtocolVersion: fmt.Sprintf("%d.%d", startup.ProtocolVersion>>16, startup.ProtocolVersion&0xffff),
+	}
+
+	if info.ApplicationName == pgjdbcApplicationName {
+		info.Driver = "pgjdbc"
+	}
+
+	for key, value := range params {
+		switch key {
+		case "user", "database", "application_name", "client_encoding":
+			continue
+		}
+
+		if info.Parameters == nil {
+			info.Parameters = make(map[string]string)
+		}
+
+		info.Parameters[key] = value
+	}
+
+	return info
+}
+
 // isAPIKey checks if a password looks like a dbbat API key.
 func isAPIKey(password string) bool {
 	return len(password) >= store.APIKeyPrefixLength &&
@@ -133,7 +374,10 @@ func isAPIKey(password string) bool {
 
 // authenticateWithAPIKey verifies the password as an API key and checks ownership.
 func (s *Session) authenticateWithAPIKey(apiKey string) error {
-	verified, err := s.store.VerifyAPIKey(s.ctx, apiKey)
+	verifyCtx, cancel := shared.StoreContext(s.ctx)
+	defer cancel()
+
+	verified, err := s.store.VerifyAPIKey(verifyCtx, apiKey)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrAPIKeyVerifyFailed, err)
 	}
@@ -144,7 +388,23 @@ func (s *Session) authenticateWithAPIKey(apiKey string) error {
 	}
 
 	// Increment usage asynchronously
-	go func() { _ = s.store.IncrementAPIKeyUsage(context.Background(), verified.ID) }()
+	go func() {
+		ctx, cancel := shared.StoreContext(context.Background())
+		defer cancel()
+
+		_ = s.store.IncrementAPIKeyUsage(ctx, verified.ID)
+	}()
+
+	// Keys predating request signing get their secret on first use, which
+	// also lets them authenticate through SCRAM.
+	if verified.SigningData() == nil {
+		go func() {
+			ctx, cancel := shared.StoreContext(context.Background())
+			defer cancel()
+
+			_ = s.store.EnsureAPIKeySigningSecret(ctx, verified.ID, apiKey, s.encryptionKey)
+		}()
+	}
 
 	return nil
 }
@@ -160,7 +420,8 @@ func (s *Session) checkQuotas() error {
 		return shared.ErrGrantRevoked
 	}
 
-	if !s.grant.ExpiresAt.IsZero() && !time.Now().Before(s.grant.ExpiresAt) {
+	// An extension approved mid-session pushes the expiry back.
+	if expiresAt := s.revocation.ExpiresAt(s.grant.ExpiresAt); !expiresAt.IsZero() && !time.Now().Before(expiresAt) {
 		return shared.ErrGrantExpired
 	}
 
@@ -172,5 +433,9 @@ func (s *Session) checkQuotas() error {
 		return ErrDataLimitExceeded
 	}
 
+	if s.rowQuotaReached() {
+		return ErrRowLimitExceeded
+	}
+
 	return nil
 }
@@ -0,0 +1,38 @@
+package postgresql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestStartupClientInfo(t *testing.T) {
+	t.Parallel()
+
+	info := startupClientInfo(&pgproto3.StartupMessage{
+		ProtocolVersion: pgproto3.ProtocolVersion30,
+		Parameters: map[string]string{
+			"user":               "alice",
+			"database":           "prod",
+			"application_name":   "PostgreSQL JDBC Driver",
+			"client_encoding":    "UTF8",
+			"DateStyle":          "ISO",
+			"extra_float_digits": "2",
+		},
+	})
+
+	want := &store.ClientInfo{
+		ApplicationName: "PostgreSQL JDBC Driver",
+		Driver:          "pgjdbc",
+		ClientEncoding:  "UTF8",
+		ProtocolVersion: "3.0",
+		Parameters:      map[string]string{"DateStyle": "ISO", "extra_float_digits": "2"},
+	}
+
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("startupClientInfo() = %+v, want %+v", info, want)
+	}
+}
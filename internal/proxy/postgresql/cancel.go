@@ -0,0 +1,173 @@
+package postgresql
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// A CancelRequest is sent, in place of a StartupMessage, on a connection of
+// its own: 16 bytes with this magic version number.
+const (
+	pgCancelRequestCode   = 80877102
+	cancelRequestLength   = 16
+	cancelSecretKeyLength = 4
+)
+
+// cancelKeys maps the cancellation keys the proxy hands out in BackendKeyData
+// to the sessions they cancel. Clients get a key of the proxy's own instead of
+// upstream's: their CancelRequest reaches the proxy, which must route it to
+// the right upstream connection, and upstream's key does not leak. The zero
+// value is ready to use; a nil cancelKeys hands out upstream's key, for
+// sessions built without a server (unit tests).
+type cancelKeys struct {
+	mu       sync.Mutex
+	sessions map[uint32]*cancelTarget
+}
+
+// cancelTarget is a handed out key: its secret, and its session once that is
+// registered.
+type cancelTarget struct {
+	secretKey []byte
+	session   *Session
+}
+
+// newKey returns a new key, reserved until release. The session is attached
+// to it with register, once it is ready to be canceled.
+func (c *cancelKeys) newKey() (*pgproto3.BackendKeyData, error) {
+	secretKey := make([]byte, cancelSecretKeyLength)
+	if _, err := rand.Read(secretKey); err != nil {
+		return nil, fmt.Errorf("generate cancel key: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sessions == nil {
+		c.sessions = make(map[uint32]*cancelTarget)
+	}
+
+	var processID [4]byte
+
+	for {
+		if _, err := rand.Read(processID[:]); err != nil {
+			return nil, fmt.Errorf("generate cancel key: %w", err)
+		}
+
+		id := binary.BigEndian.Uint32(processID[:])
+		if _, taken := c.sessions[id]; taken || id == 0 {
+			continue
+		}
+
+		c.sessions[id] = &cancelTarget{secretKey: secretKey}
+
+		return &pgproto3.BackendKeyData{ProcessID: id, SecretKey: secretKey}, nil
+	}
+}
+
+// register attaches session to its key, so CancelRequests carrying it cancel
+// the session's queries.
+func (c *cancelKeys) register(key *pgproto3.BackendKeyData, session *Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if target := c.sessions[key.ProcessID]; target != nil {
+		target.session = session
+	}
+}
+
+// release forgets the key of a session that ended.
+func (c *cancelKeys) release(key *pgproto3.BackendKeyData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.sessions, key.ProcessID)
+}
+
+// lookup returns the session req cancels the queries of, nil when its key is
+// unknown or its session not registered yet.
+func (c *cancelKeys) lookup(req *pgproto3.CancelRequest) *Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.sessions[req.ProcessID]
+	if target == nil || subtle.ConstantTimeCompare(target.secretKey, req.SecretKey) != 1 {
+		return nil
+	}
+
+	return target.session
+}
+
+// clientBackendKey returns the BackendKeyData to give the client: one of the
+// proxy's, upstream's for a session without a server, nil when upstream gave
+// none.
+func (s *Session) clientBackendKey() (*pgproto3.BackendKeyData, error) {
+	if s.upstreamKey == nil {
+		return nil, nil //nolint:nilnil // No key is not an error: the client cannot cancel
+	}
+
+	if s.cancelKeys == nil {
+		return s.upstreamKey, nil
+	}
+
+	key, err := s.cancelKeys.newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	s.clientKey = key
+
+	return key, nil
+}
+
+// isCancelRequest reports whether the client opened its connection to send a
+// CancelRequest rather than a StartupMessage.
+func (s *Session) isCancelRequest() bool {
+	header, err := s.clientReader.Peek(8)
+	if err != nil {
+		return false
+	}
+
+	return binary.BigEndian.Uint32(header[0:4]) == cancelRequestLength &&
+		binary.BigEndian.Uint32(header[4:8]) == pgCancelRequestCode
+}
+
+// serveCancelRequest reads the client's CancelRequest and cancels the query
+// running on the upstream connection of the session its key was handed out
+// to, or the statement it holds for approval. As with PostgreSQL, the client
+// gets no reply either way.
+func (s *Session) serveCancelRequest() error {
+	buf := make([]byte, cancelRequestLength)
+	if _, err := io.ReadFull(s.clientReader, buf); err != nil {
+		return fmt.Errorf("read CancelRequest: %w", err)
+	}
+
+	req := &pgproto3.CancelRequest{}
+	if err := req.Decode(buf[4:]); err != nil {
+		return fmt.Errorf("decode CancelRequest: %w", err)
+	}
+
+	var target *Session
+	if s.cancelKeys != nil {
+		target = s.cancelKeys.lookup(req)
+	}
+
+	if target == nil {
+		s.logger.WarnContext(s.ctx, "CancelRequest with an unknown key", slog.Any("remote_addr", s.clientConn.RemoteAddr()))
+
+		return nil
+	}
+
+	// A statement held for approval has not reached upstream yet.
+	if !target.cancelHeldStatement() {
+		target.cancelUpstreamQuery("client request")
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package postgresql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestCancelKeys(t *testing.T) {
+	t.Parallel()
+
+	var keys cancelKeys
+
+	key, err := keys.newKey()
+	if err != nil {
+		t.Fatalf("newKey() error = %v", err)
+	}
+
+	other, err := keys.newKey()
+	if err != nil {
+		t.Fatalf("newKey() error = %v", err)
+	}
+
+	if key.ProcessID == other.ProcessID {
+		t.Errorf("newKey() handed out process ID %d twice", key.ProcessID)
+	}
+
+	req := &pgproto3.CancelRequest{ProcessID: key.ProcessID, SecretKey: key.SecretKey}
+
+	if keys.lookup(req) != nil {
+		t.Error("lookup() found a session before it was registered")
+	}
+
+	session := &Session{}
+	keys.register(key, session)
+
+	if got := keys.lookup(req); got != session {
+		t.Errorf("lookup() = %p, want the registered session %p", got, session)
+	}
+
+	wrongSecret := &pgproto3.CancelRequest{ProcessID: key.ProcessID, SecretKey: slices.Clone(key.SecretKey)}
+	wrongSecret.SecretKey[0] ^= 0xff
+
+	if keys.lookup(wrongSecret) != nil {
+		t.Error("lookup() matched a wrong secret key")
+	}
+
+	keys.release(key)
+
+	if keys.lookup(req) != nil {
+		t.Error("lookup() found a released key")
+	}
+}
+
+func TestSession_ServeCancelRequest(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan []byte, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 64)
+		n, _ := io.ReadAtLeast(conn, buf, 16)
+		received <- buf[:n]
+	}()
+
+	var keys cancelKeys
+
+	addr := listener.Addr().(*net.TCPAddr)
+	target := &Session{
+		database:    &store.Server{Host: "127.0.0.1", Port: addr.Port},
+		upstreamKey: &pgproto3.BackendKeyData{ProcessID: 4242, SecretKey: []byte{1, 2, 3, 4}},
+		cancelKeys:  &keys,
+		logger:      slog.New(slog.DiscardHandler),
+		ctx:         context.Background(),
+	}
+
+	clientKey, err := target.clientBackendKey()
+	if err != nil {
+		t.Fatalf("clientBackendKey() error = %v", err)
+	}
+
+	if clientKey.ProcessID == 4242 {
+		t.Fatal("clientBackendKey() handed out the upstream key")
+	}
+
+	keys.register(clientKey, target)
+
+	cancelReq, err := (&pgproto3.CancelRequest{ProcessID: clientKey.ProcessID, SecretKey: clientKey.SecretKey}).Encode(nil)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	client := &Session{
+		clientReader: bufio.NewReader(bytes.NewReader(cancelReq)),
+		cancelKeys:   &keys,
+		logger:       slog.New(slog.DiscardHandler),
+		ctx:          context.Background(),
+	}
+
+	if !client.isCancelRequest() {
+		t.Fatal("isCancelRequest() = false for a CancelRequest")
+	}
+
+	if err := client.serveCancelRequest(); err != nil {
+		t.Fatalf("serveCancelRequest() error = %v", err)
+	}
+
+	var got pgproto3.CancelRequest
+	if err := got.Decode((<-received)[4:]); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.ProcessID != 4242 || string(got.SecretKey) != "\x01\x02\x03\x04" {
+		t.Errorf("upstream CancelRequest = %+v, want the upstream backend's key", got)
+	}
+}
+
+func TestSession_IsCancelRequest(t *testing.T) {
+	t.Parallel()
+
+	startup, err := (&pgproto3.StartupMessage{
+		ProtocolVersion: pgproto3.ProtocolVersionNumber,
+		Parameters:      map[string]string{"user": "u", "database": "d"},
+	}).Encode(nil)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	s := &Session{clientReader: bufio.NewReader(bytes.NewReader(startup))}
+	if s.isCancelRequest() {
+		t.Error("isCancelRequest() = true for a StartupMessage")
+	}
+}
@@ -0,0 +1,118 @@
+package postgresql
+
+import (
+	"slices"
+	"strings"
+)
+
+// statementClass is what the grant controls need to know about the SQL of a
+// Query or Parse message. A multi-statement string gets the union of its
+// statements' classes.
+type statementClass struct {
+	// write statements modify data or the schema.
+	write bool
+	// ddl statements modify the schema.
+	ddl bool
+	// copy statements are COPY in either direction, copyOut ones COPY ... TO.
+	copy    bool
+	copyOut bool
+	// readOnlyBypass statements could lift the session's read-only mode.
+	readOnlyBypass bool
+	// passwordChange statements set a role's password.
+	passwordChange bool
+	// copyIn statements are COPY ... FROM.
+	copyIn bool
+	// transactionControl statements begin, end or prepare a transaction, or
+	// use savepoints.
+	transactionControl bool
+	// executePrepared statements EXECUTE or DEALLOCATE prepared statements.
+	executePrepared bool
+	// settingsOnly is set when every statement is a SET, RESET or SHOW.
+	settingsOnly bool
+}
+
+// classifySQL classifies sql from its PostgreSQL parse tree. Without the
+// parser (builds without cgo), or when it rejects sql, it falls back to the
+// keyword heuristics, which are stricter on some statements (keywords in
+// string literals) and miss others (data-modifying CTEs, later statements).
+func classifySQL(sql string) statementClass {
+	if class, ok := parseStatementClass(sql); ok {
+		return class
+	}
+
+	return heuristicStatementClass(sql)
+}
+
+// splitStatements splits the SQL of a Query message into its statements,
+// which upstream runs and completes one by one. Without the parser, or when
+// it rejects sql, the statements are split at the semicolons outside strings,
+// quoted identifiers and comments. Empty statements are dropped, unless sql
+// holds nothing else: upstream then still answers it.
+func splitStatements(sql string) []string {
+	statements, ok := parseStatements(sql)
+	if !ok {
+		statements = scanStatements(sql)
+	}
+
+	statements = slices.DeleteFunc(statements, func(stmt string) bool {
+		return len(statementHeads(stmt)) == 0
+	})
+
+	if len(statements) == 0 {
+		return []string{sql}
+	}
+
+	return statements
+}
+
+// union returns the class of a multi-statement string made of statements of
+// classes c and other.
+func (c statementClass) union(other statementClass) statementClass {
+	return statementClass{
+		write:              c.write || other.write,
+		ddl:                c.ddl || other.ddl,
+		copy:               c.copy || other.copy,
+		copyOut:            c.copyOut || other.copyOut,
+		readOnlyBypass:     c.readOnlyBypass || other.readOnlyBypass,
+		passwordChange:     c.passwordChange || other.passwordChange,
+		copyIn:             c.copyIn || other.copyIn,
+		transactionControl: c.transactionControl || other.transactionControl,
+		executePrepared:    c.executePrepared || other.executePrepared,
+		settingsOnly:       c.settingsOnly && other.settingsOnly,
+	}
+}
+
+// transactionKeywords are the first words of transaction control statements.
+var transactionKeywords = []string{"BEGIN", "START", "COMMIT", "END", "ROLLBACK", "ABORT", "SAVEPOINT", "RELEASE"}
+
+// heuristicStatementClass classifies sql by keyword prefixes and patterns.
+func heuristicStatementClass(sql string) statementClass {
+	class := statementClass{
+		write:          isWriteQuery(sql),
+		ddl:            isDDLQuery(sql),
+		copy:           isCopyQuery(sql),
+		copyOut:        isCopyOutQuery(sql),
+		readOnlyBypass: isReadOnlyBypassAttempt(sql),
+		passwordChange: isPasswordChangeQuery(sql),
+	}
+
+	class.copyIn = class.copy && !class.copyOut
+
+	heads := statementHeads(sql)
+	class.settingsOnly = len(heads) > 0
+
+	for _, head := range heads {
+		first, _, _ := strings.Cut(head, " ")
+
+		switch {
+		case slices.Contains(transactionKeywords, first), head == "PREPARE TRANSACTION":
+			class.transactionControl = true
+		case first == "EXECUTE", first == "DEALLOCATE":
+			class.executePrepared = true
+		}
+
+		class.settingsOnly = class.settingsOnly && (first == "SET" || first == "RESET" || first == "SHOW")
+	}
+
+	return class
+}
@@ -0,0 +1,17 @@
+//go:build !cgo
+
+package postgresql
+
+// sqlParserAvailable reports whether statements are classified by the
+// PostgreSQL parser; it needs cgo.
+const sqlParserAvailable = false
+
+// parseStatementClass always defers to the keyword heuristics.
+func parseStatementClass(string) (statementClass, bool) {
+	return statementClass{}, false
+}
+
+// parseStatements always defers to the keyword scanner.
+func parseStatements(string) ([]string, bool) {
+	return nil, false
+}
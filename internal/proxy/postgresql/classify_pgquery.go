@@ -0,0 +1,192 @@
+//go:build cgo
+
+package postgresql
+
+import (
+	"slices"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// sqlParserAvailable reports whether statements are classified by the
+// PostgreSQL parser; it needs cgo.
+const sqlParserAvailable = true
+
+// readOnlySettings are the settings whose change can lift the session's
+// read-only mode: directly, or by switching to a role with other defaults.
+var readOnlySettings = []string{"default_transaction_read_only", "transaction_read_only", "role", "session_authorization"}
+
+// ddlNodes are the schema-changing statements whose node name does not start
+// with Create, Alter or Drop.
+var ddlNodes = []protoreflect.Name{
+	"TruncateStmt", "IndexStmt", "ViewStmt", "RenameStmt", "RuleStmt", "DefineStmt", "CompositeTypeStmt",
+}
+
+// writeNodes are the statements, besides DML and DDL, that modify the
+// database or its permissions.
+var writeNodes = []protoreflect.Name{
+	"GrantStmt", "GrantRoleStmt", "CommentStmt", "SecLabelStmt", "RefreshMatViewStmt", "VacuumStmt",
+	"ClusterStmt", "ReindexStmt", "ImportForeignSchemaStmt",
+}
+
+// parseStatementClass classifies sql from its parse tree. Every node of every
+// statement is visited, so a data-modifying CTE, a write inside EXPLAIN
+// ANALYZE or a second statement are seen; string literals never are. ok is
+// false when the parser rejects sql.
+func parseStatementClass(sql string) (statementClass, bool) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return statementClass{}, false
+	}
+
+	class := statementClass{settingsOnly: len(tree.GetStmts()) > 0}
+
+	for _, stmt := range tree.GetStmts() {
+		walkNodes(stmt.ProtoReflect(), class.addNode)
+
+		switch stmt.GetStmt().GetNode().(type) {
+		case *pg_query.Node_VariableSetStmt, *pg_query.Node_VariableShowStmt:
+		default:
+			class.settingsOnly = false
+		}
+	}
+
+	return class, true
+}
+
+// parseStatements splits sql into its statements with the parser, which
+// keeps the semicolons of a BEGIN ATOMIC function body in the function. ok is
+// false when the parser rejects sql.
+func parseStatements(sql string) ([]string, bool) {
+	statements, err := pg_query.SplitWithParser(sql, true)
+	if err != nil {
+		return nil, false
+	}
+
+	return statements, true
+}
+
+// walkNodes calls visit on msg and every message below it.
+func walkNodes(msg protoreflect.Message, visit func(protoreflect.Message)) {
+	visit(msg)
+
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Message() == nil || fd.IsMap():
+		case fd.IsList():
+			list := v.List()
+			for i := range list.Len() {
+				walkNodes(list.Get(i).Message(), visit)
+			}
+		default:
+			walkNodes(v.Message(), visit)
+		}
+
+		return true
+	})
+}
+
+// addNode records what one parse tree node tells about the statement.
+func (c *statementClass) addNode(msg protoreflect.Message) {
+	name := msg.Descriptor().Name()
+
+	switch {
+	case strings.HasPrefix(string(name), "Create"), strings.HasPrefix(string(name), "Alter"),
+		strings.HasPrefix(string(name), "Drop"), slices.Contains(ddlNodes, name):
+		c.write, c.ddl = true, true
+	case slices.Contains(writeNodes, name):
+		c.write = true
+	}
+
+	switch node := msg.Interface().(type) {
+	case *pg_query.InsertStmt, *pg_query.UpdateStmt, *pg_query.DeleteStmt, *pg_query.MergeStmt:
+		c.write = true
+	case *pg_query.SelectStmt:
+		// SELECT ... INTO creates a table.
+		if node.GetIntoClause() != nil {
+			c.write, c.ddl = true, true
+		}
+	case *pg_query.DoStmt, *pg_query.CallStmt:
+		// Their body is opaque: assume the worst.
+		c.write, c.ddl = true, true
+	case *pg_query.CopyStmt:
+		c.copy = true
+		c.copyOut = c.copyOut || !node.GetIsFrom()
+		c.copyIn = c.copyIn || node.GetIsFrom()
+		c.write = c.write || node.GetIsFrom()
+	case *pg_query.TransactionStmt:
+		c.transactionControl = true
+	case *pg_query.ExecuteStmt, *pg_query.DeallocateStmt:
+		c.executePrepared = true
+	case *pg_query.AlterRoleStmt:
+		c.passwordChange = c.passwordChange || slices.ContainsFunc(node.GetOptions(), func(opt *pg_query.Node) bool {
+			return opt.GetDefElem().GetDefname() == "password"
+		})
+	case *pg_query.VariableSetStmt:
+		c.readOnlyBypass = c.readOnlyBypass || liftsReadOnly(node)
+	case *pg_query.DefElem:
+		// BEGIN READ WRITE, SET TRANSACTION READ WRITE and
+		// SET SESSION CHARACTERISTICS AS TRANSACTION READ WRITE.
+		if node.GetDefname() == "transaction_read_only" && !isTrueConst(node.GetArg()) {
+			c.readOnlyBypass = true
+		}
+	case *pg_query.DiscardStmt:
+		// DISCARD ALL runs RESET ALL.
+		c.readOnlyBypass = c.readOnlyBypass || node.GetTarget() == pg_query.DiscardMode_DISCARD_ALL
+	case *pg_query.FuncCall:
+		c.readOnlyBypass = c.readOnlyBypass || setsReadOnlySetting(node)
+	}
+}
+
+// liftsReadOnly reports whether a SET or RESET can lift the read-only mode.
+func liftsReadOnly(stmt *pg_query.VariableSetStmt) bool {
+	switch {
+	case stmt.GetKind() == pg_query.VariableSetKind_VAR_RESET_ALL:
+		return true
+	case !slices.Contains(readOnlySettings, stmt.GetName()):
+		return false
+	case stmt.GetName() == "role" || stmt.GetName() == "session_authorization":
+		// RESET ROLE goes back to the session user.
+		return stmt.GetKind() != pg_query.VariableSetKind_VAR_RESET
+	default:
+		return stmt.GetKind() != pg_query.VariableSetKind_VAR_SET_VALUE ||
+			len(stmt.GetArgs()) != 1 || !isTrueConst(stmt.GetArgs()[0])
+	}
+}
+
+// setsReadOnlySetting reports whether a function call is a set_config of a
+// read-only setting, or one whose setting name is not a constant.
+func setsReadOnlySetting(call *pg_query.FuncCall) bool {
+	names := call.GetFuncname()
+	if len(names) == 0 || names[len(names)-1].GetString_().GetSval() != "set_config" {
+		return false
+	}
+
+	args := call.GetArgs()
+	if len(args) == 0 {
+		return false
+	}
+
+	setting, ok := args[0].GetAConst().GetVal().(*pg_query.A_Const_Sval)
+
+	return !ok || slices.Contains(readOnlySettings, strings.ToLower(setting.Sval.GetSval()))
+}
+
+// isTrueConst reports whether node is a constant PostgreSQL reads as true.
+func isTrueConst(node *pg_query.Node) bool {
+	switch v := node.GetAConst().GetVal().(type) {
+	case *pg_query.A_Const_Ival:
+		return v.Ival.GetIval() != 0
+	case *pg_query.A_Const_Boolval:
+		return v.Boolval.GetBoolval()
+	case *pg_query.A_Const_Sval:
+		switch strings.ToLower(v.Sval.GetSval()) {
+		case "on", "true", "yes", "1":
+			return true
+		}
+	}
+
+	return false
+}
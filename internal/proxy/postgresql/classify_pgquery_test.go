@@ -0,0 +1,69 @@
+//go:build cgo
+
+package postgresql
+
+import "testing"
+
+func TestClassifySQL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sql  string
+		want statementClass
+	}{
+		{name: "select", sql: "SELECT * FROM users"},
+		{name: "keyword in a string", sql: "SELECT * FROM users WHERE notes LIKE '%SET ROLE%' OR notes = 'DROP TABLE users'"},
+		{name: "insert", sql: "INSERT INTO users (name) VALUES ('a')", want: statementClass{write: true}},
+		{name: "data-modifying CTE", sql: "WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d",
+			want: statementClass{write: true}},
+		{name: "second statement", sql: "SELECT 1; UPDATE users SET name = 'a'", want: statementClass{write: true}},
+		{name: "explain analyze", sql: "EXPLAIN ANALYZE INSERT INTO users DEFAULT VALUES", want: statementClass{write: true}},
+		{name: "select into", sql: "SELECT * INTO archive FROM users", want: statementClass{write: true, ddl: true}},
+		{name: "create index", sql: "CREATE INDEX idx ON users (name)", want: statementClass{write: true, ddl: true}},
+		{name: "truncate", sql: "TRUNCATE users", want: statementClass{write: true, ddl: true}},
+		{name: "grant", sql: "GRANT SELECT ON users TO bob", want: statementClass{write: true}},
+		{name: "do block", sql: "DO $$ BEGIN DELETE FROM users; END $$", want: statementClass{write: true, ddl: true}},
+		{name: "call", sql: "CALL purge_users()", want: statementClass{write: true, ddl: true}},
+		{name: "copy out", sql: "COPY (SELECT * FROM users) TO STDOUT", want: statementClass{copy: true, copyOut: true}},
+		{name: "copy in", sql: "COPY users FROM STDIN", want: statementClass{copy: true, copyIn: true, write: true}},
+		{name: "password", sql: "ALTER ROLE me WITH LOGIN PASSWORD 'x'",
+			want: statementClass{write: true, ddl: true, passwordChange: true}},
+		{name: "create user with password", sql: "CREATE USER bob PASSWORD 'x'", want: statementClass{write: true, ddl: true}},
+		{name: "read-only off", sql: "SET default_transaction_read_only = off",
+			want: statementClass{readOnlyBypass: true, settingsOnly: true}},
+		{name: "read-only on", sql: "SET SESSION default_transaction_read_only TO on", want: statementClass{settingsOnly: true}},
+		{name: "read-only default", sql: "SET default_transaction_read_only TO DEFAULT",
+			want: statementClass{readOnlyBypass: true, settingsOnly: true}},
+		{name: "set role", sql: "SET ROLE admin", want: statementClass{readOnlyBypass: true, settingsOnly: true}},
+		{name: "reset role", sql: "RESET ROLE", want: statementClass{settingsOnly: true}},
+		{name: "reset all", sql: "RESET ALL", want: statementClass{readOnlyBypass: true, settingsOnly: true}},
+		{name: "discard all", sql: "DISCARD ALL", want: statementClass{readOnlyBypass: true}},
+		{name: "begin read write", sql: "BEGIN ISOLATION LEVEL SERIALIZABLE, READ WRITE",
+			want: statementClass{readOnlyBypass: true, transactionControl: true}},
+		{name: "begin read only", sql: "BEGIN READ ONLY", want: statementClass{transactionControl: true}},
+		{name: "session characteristics", sql: "SET SESSION CHARACTERISTICS AS TRANSACTION READ WRITE",
+			want: statementClass{readOnlyBypass: true, settingsOnly: true}},
+		{name: "commit", sql: "UPDATE users SET name = 'a'; COMMIT", want: statementClass{write: true, transactionControl: true}},
+		{name: "prepare transaction", sql: "PREPARE TRANSACTION 'x'", want: statementClass{transactionControl: true}},
+		{name: "sql prepare", sql: "PREPARE q AS SELECT 1"},
+		{name: "execute", sql: "EXPLAIN EXECUTE q", want: statementClass{executePrepared: true}},
+		{name: "deallocate", sql: "DEALLOCATE ALL", want: statementClass{executePrepared: true}},
+		{name: "settings and show", sql: "SET work_mem = '64MB'; SHOW work_mem", want: statementClass{settingsOnly: true}},
+		{name: "setting then select", sql: "SET work_mem = '64MB'; SELECT 1"},
+		{name: "set_config", sql: "SELECT pg_catalog.set_config('Role', 'admin', false)", want: statementClass{readOnlyBypass: true}},
+		{name: "set_config of a parameter", sql: "SELECT set_config($1, $2, false)", want: statementClass{readOnlyBypass: true}},
+		{name: "set_config of another setting", sql: "SELECT set_config('work_mem', '64MB', false)"},
+		{name: "unparsable falls back to keywords", sql: "INSERT something", want: statementClass{write: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := classifySQL(tt.sql); got != tt.want {
+				t.Errorf("classifySQL(%q) = %+v, want %+v", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,587 @@
+package postgresql
+
+import (
+	"errors"
+	"strings"
+	"text/template"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+)
+
+// SQLSTATE codes DBBat reports to PostgreSQL clients, so drivers and tools
+// can react to the error class instead of parsing English text.
+const (
+	sqlStateConnectionFailure          = "08006" // connection_failure
+	sqlStateProtocolViolation          = "08P01" // protocol_violation
+	sqlStateReadOnlyTransaction        = "25006" // read_only_sql_transaction
+	sqlStateInvalidAuthorization       = "28000" // invalid_authorization_specification
+	sqlStateInvalidPassword            = "28P01" // invalid_password
+	sqlStateInvalidCatalogName         = "3D000" // invalid_catalog_name
+	sqlStateSyntaxOrAccessRule         = "42000" // syntax_error_or_access_rule_violation
+	sqlStateInsufficientPrivilege      = "42501" // insufficient_privilege
+	sqlStateConfigurationLimitExceeded = "53400" // configuration_limit_exceeded
+	sqlStateQueryCanceled              = "57014" // query_canceled
+	sqlStateAdminShutdown              = "57P01" // admin_shutdown
+	sqlStateIdleSessionTimeout         = "57P05" // idle_session_timeout
+)
+
+// messageID identifies a client-facing message in messageCatalog. It is also
+// the stable reason reported in the ROUTINE field, prefixed by routinePrefix.
+type messageID string
+
+// routinePrefix tells DBBat's errors apart from the upstream server's, whose
+// ROUTINE is the name of the PostgreSQL function that raised them.
+const routinePrefix = "dbbat_"
+
+// Client-facing messages.
+const (
+	msgInvalidStartup      messageID = "invalid_startup"
+	msgMissingCredentials  messageID = "missing_credentials"
+	msgAuthFailed          messageID = "auth_failed"
+	msgDatabaseNotFound    messageID = "database_not_found"
+	msgListenerDatabase    messageID = "listener_database"
+	msgNoGrant             messageID = "no_grant"
+	msgClientNotAllowed    messageID = "client_not_allowed"
+	msgReplicationDenied   messageID = "replication_denied"
+	msgInvalidReplication  messageID = "invalid_replication"
+	msgUpstreamUnavailable messageID = "upstream_unavailable"
+	msgPasswordChange      messageID = "password_change"
+	msgReadOnlyBypass      messageID = "read_only_bypass"
+	msgWriteNotPermitted   messageID = "write_not_permitted"
+	msgDDLNotPermitted     messageID = "ddl_not_permitted"
+	msgCopyNotPermitted    messageID = "copy_not_permitted"
+	msgCopyOutNotPermitted messageID = "copy_out_not_permitted"
+	msgTableNotPermitted   messageID = "table_not_permitted"
+	msgTableAllowlist      messageID = "table_allowlist"
+	msgDryRunNotPermitted  messageID = "dry_run_not_permitted"
+	msgStatementDenied     messageID = "statement_denied"
+	msgApprovalTimeout     messageID = "approval_timeout"
+	msgApprovalCanceled    messageID = "approval_canceled"
+	msgUnsupportedControl  messageID = "unsupported_control"
+	msgQueryQuota          messageID = "query_quota"
+	msgDataQuota           messageID = "data_quota"
+	msgRowQuota            messageID = "row_quota"
+	msgGrantExpired        messageID = "grant_expired"
+	msgGrantRevoked        messageID = "grant_revoked"
+	msgQueryAborted        messageID = "query_aborted"
+	msgQueryRejected       messageID = "query_rejected"
+	msgIdleTimeout         messageID = "idle_timeout"
+	msgMaxDuration         messageID = "max_duration"
+	msgTerminated          messageID = "terminated"
+)
+
+// messageText holds the text/template sources of one message. Templates are
+// executed with a messageData.
+type messageText struct {
+	Message string
+	Detail  string
+	Hint    string
+}
+
+// messageData is the data available to message templates.
+type messageData struct {
+	User     string
+	Database string
+	Cause    string
+}
+
+// defaultLocale is used when the client did not ask for a language we have.
+const defaultLocale = "en"
+
+// messageCatalog holds the message templates per language. Every language
+// must define every message; English is the reference.
+var messageCatalog = map[string]map[messageID]messageText{
+	"en": {
+		msgInvalidStartup: {Message: "invalid startup message"},
+		msgMissingCredentials: {
+			Message: "user and database are required",
+			Hint:    "Connect with your DBBat username and the DBBat name of the target database.",
+		},
+		msgAuthFailed: {
+			Message: `authentication failed for user "{{.User}}"`,
+			Hint:    "Use your DBBat password or a DBBat API key, not the target database's credentials.",
+		},
+		msgDatabaseNotFound: {
+			Message: `database "{{.Database}}" does not exist`,
+			Hint:    "Use the database name configured in DBBat, not the upstream database name.",
+		},
+		msgListenerDatabase: {
+			Message: `database "{{.Database}}" is not available on this port`,
+			Hint:    "Connect to the DBBat port that serves this database.",
+		},
+		msgNoGrant: {
+			Message: `user "{{.User}}" has no active access grant for database "{{.Database}}"`,
+			Hint:    "Request access from the DBBat web interface or ask an administrator.",
+		},
+		msgClientNotAllowed: {
+			Message: `access grant for database "{{.Database}}" does not allow connections from {{.Cause}}`,
+			Hint:    "Connect from one of the networks the access grant allows, such as the bastion.",
+		},
+		msgReplicationDenied: {
+			Message: `replication connections to database "{{.Database}}" are not allowed for user "{{.User}}"`,
+			Hint:    "Replication requires an access grant with the allow_replication control.",
+		},
+		msgInvalidReplication: {
+			Message: `invalid value for parameter "replication"`,
+			Hint:    `Valid values are true, false and database.`,
+		},
+		msgUpstreamUnavailable: {
+			Message: `could not connect to database "{{.Database}}"`,
+			Detail:  "The DBBat proxy could not reach the target database server.",
+		},
+		msgPasswordChange: {
+			Message: "password modification is not allowed through the proxy",
+			Detail:  "The credentials of the target database are managed by DBBat administrators.",
+			Hint:    "Change your DBBat password from the DBBat web interface.",
+		},
+		msgReadOnlyBypass: {
+			Message: "attempt to disable read-only mode is not permitted",
+			Detail:  "Your access grant is read-only and cannot be changed for this session.",
+			Hint:    "Request a read-write grant to modify data.",
+		},
+		msgWriteNotPermitted: {
+			Message: "write operations not permitted with read-only access",
+			Hint:    "Request a read-write grant to modify data.",
+		},
+		msgDDLNotPermitted: {
+			Message: "DDL operations not permitted",
+			Detail:  "Your access grant blocks schema modifications.",
+		},
+		msgCopyNotPermitted: {
+			Message: "COPY not permitted",
+			Detail:  "Your access grant blocks COPY commands.",
+		},
+		msgCopyOutNotPermitted: {
+			Message: "COPY TO not permitted",
+			Detail:  "Your access grant blocks data exports; COPY FROM is still allowed.",
+		},
+		msgTableNotPermitted: {
+			Message: "permission denied for table {{.Cause}}",
+			Detail:  "Your access grant only allows the tables it lists.",
+			Hint:    "Request a grant that includes this table.",
+		},
+		msgTableAllowlist: {
+			Message: "statement not permitted with a table allowlist",
+			Detail:  "Your access grant lists the tables you can use; search_path changes and DO blocks could reach others.",
+		},
+		msgDryRunNotPermitted: {
+			Message: "statement not permitted in dry-run mode",
+			Detail:  "Your access grant runs every statement in a transaction that is rolled back: transaction control, COPY FROM, EXECUTE and DEALLOCATE are not available.",
+			Hint:    "Request a grant without the dry_run control to commit changes.",
+		},
+		msgStatementDenied: {
+			Message: "statement denied by an administrator",
+			Detail:  "{{.Cause}}",
+		},
+		msgApprovalTimeout: {
+			Message: "canceling statement: no administrator approved it in time",
+			Hint:    "Ask an administrator to review the pending statements in DBBat, then run it again.",
+		},
+		msgApprovalCanceled: {
+			Message: "canceling statement due to user request",
+			Detail:  "The statement was awaiting approval.",
+		},
+		msgUnsupportedControl: {
+			Message: `access grant for database "{{.Database}}" cannot be enforced: {{.Cause}}`,
+			Hint:    "Ask an administrator to remove the unsupported controls from the grant.",
+		},
+		msgQueryQuota: {
+			Message: "query limit exceeded for this grant",
+			Hint:    "Request a new grant to run more queries.",
+		},
+		msgDataQuota: {
+			Message: "data transfer limit exceeded for this grant",
+			Hint:    "Request a new grant to transfer more data.",
+		},
+		msgRowQuota: {
+			Message: "returned rows limit exceeded for this grant",
+			Hint:    "Request a new grant to read more rows.",
+		},
+		msgGrantExpired: {
+			Message: "access grant expired",
+			Hint:    "Request a new grant to keep working on this database.",
+		},
+		msgGrantRevoked: {
+			Message: "access grant revoked by an administrator",
+			Hint:    "Request a new grant to keep working on this database.",
+		},
+		msgQueryAborted: {
+			Message: "canceling statement: {{.Cause}}",
+		},
+		msgQueryRejected: {Message: "{{.Cause}}"},
+		msgIdleTimeout: {
+			Message: "terminating connection due to idle-session timeout",
+			Hint:    "Reconnect to keep working on this database.",
+		},
+		msgMaxDuration: {
+			Message: "terminating connection: maximum session duration reached",
+			Hint:    "Reconnect to keep working on this database.",
+		},
+		msgTerminated: {Message: "terminating connection due to administrator command"},
+	},
+	"fr": {
+		msgInvalidStartup: {Message: "message de démarrage invalide"},
+		msgMissingCredentials: {
+			Message: "l'utilisateur et la base de données sont requis",
+			Hint:    "Connectez-vous avec votre nom d'utilisateur DBBat et le nom DBBat de la base cible.",
+		},
+		msgAuthFailed: {
+			Message: `échec de l'authentification pour l'utilisateur « {{.User}} »`,
+			Hint:    "Utilisez votre mot de passe DBBat ou une clé d'API DBBat, pas les identifiants de la base cible.",
+		},
+		msgDatabaseNotFound: {
+			Message: `la base de données « {{.Database}} » n'existe pas`,
+			Hint:    "Utilisez le nom de la base configuré dans DBBat, pas celui de la base amont.",
+		},
+		msgListenerDatabase: {
+			Message: `la base « {{.Database}} » n'est pas disponible sur ce port`,
+			Hint:    "Connectez-vous au port DBBat qui sert cette base.",
+		},
+		msgNoGrant: {
+			Message: `l'utilisateur « {{.User}} » n'a pas d'accès actif à la base « {{.Database}} »`,
+			Hint:    "Demandez un accès depuis l'interface web de DBBat ou auprès d'un administrateur.",
+		},
+		msgClientNotAllowed: {
+			Message: `l'accès à la base « {{.Database}} » n'autorise pas les connexions depuis {{.Cause}}`,
+			Hint:    "Connectez-vous depuis l'un des réseaux autorisés par l'accès, comme le bastion.",
+		},
+		msgReplicationDenied: {
+			Message: `les connexions de réplication à la base « {{.Database}} » ne sont pas autorisées pour l'utilisateur « {{.User}} »`,
+			Hint:    "La réplication nécessite un accès avec le contrôle allow_replication.",
+		},
+		msgInvalidReplication: {
+			Message: `valeur invalide pour le paramètre « replication »`,
+			Hint:    `Les valeurs valides sont true, false et database.`,
+		},
+		msgUpstreamUnavailable: {
+			Message: `impossible de se connecter à la base « {{.Database}} »`,
+			Detail:  "Le proxy DBBat n'a pas pu joindre le serveur de la base cible.",
+		},
+		msgPasswordChange: {
+			Message: "la modification de mot de passe n'est pas autorisée via le proxy",
+			Detail:  "Les identifiants de la base cible sont gérés par les administrateurs DBBat.",
+			Hint:    "Changez votre mot de passe DBBat depuis l'interface web de DBBat.",
+		},
+		msgReadOnlyBypass: {
+			Message: "la désactivation du mode lecture seule n'est pas autorisée",
+			Detail:  "Votre accès est en lecture seule et ne peut pas être modifié pour cette session.",
+			Hint:    "Demandez un accès en lecture-écriture pour modifier les données.",
+		},
+		msgWriteNotPermitted: {
+			Message: "écritures interdites avec un accès en lecture seule",
+			Hint:    "Demandez un accès en lecture-écriture pour modifier les données.",
+		},
+		msgDDLNotPermitted: {
+			Message: "opérations DDL interdites",
+			Detail:  "Votre accès bloque les modifications de schéma.",
+		},
+		msgCopyNotPermitted: {
+			Message: "COPY interdit",
+			Detail:  "Votre accès bloque les commandes COPY.",
+		},
+		msgCopyOutNotPermitted: {
+			Message: "COPY TO interdit",
+			Detail:  "Votre accès bloque les exports de données ; COPY FROM reste autorisé.",
+		},
+		msgTableNotPermitted: {
+			Message: "permission refusée pour la table {{.Cause}}",
+			Detail:  "Votre accès n'autorise que les tables qu'il liste.",
+			Hint:    "Demandez un accès incluant cette table.",
+		},
+		msgTableAllowlist: {
+			Message: "instruction interdite avec une liste de tables autorisées",
+			Detail:  "Votre accès liste les tables utilisables ; les changements de search_path et les blocs DO pourraient en atteindre d'autres.",
+		},
+		msgDryRunNotPermitted: {
+			Message: "instruction interdite en mode simulation",
+			Detail:  "Votre accès exécute chaque instruction dans une transaction annulée : le contrôle de transaction, COPY FROM, EXECUTE et DEALLOCATE ne sont pas disponibles.",
+			Hint:    "Demandez un accès sans le contrôle dry_run pour valider des modifications.",
+		},
+		msgStatementDenied: {
+			Message: "instruction refusée par un administrateur",
+			Detail:  "{{.Cause}}",
+		},
+		msgApprovalTimeout: {
+			Message: "annulation de l'instruction : aucun administrateur ne l'a approuvée à temps",
+			Hint:    "Demandez à un administrateur d'examiner les instructions en attente dans DBBat, puis relancez-la.",
+		},
+		msgApprovalCanceled: {
+			Message: "annulation de l'instruction à la demande de l'utilisateur",
+			Detail:  "L'instruction attendait une approbation.",
+		},
+		msgUnsupportedControl: {
+			Message: `l'accès à la base « {{.Database}} » ne peut pas être appliqué : {{.Cause}}`,
+			Hint:    "Demandez à un administrateur de retirer les contrôles non pris en charge de l'accès.",
+		},
+		msgQueryQuota: {
+			Message: "limite de requêtes atteinte pour cet accès",
+			Hint:    "Demandez un nouvel accès pour exécuter d'autres requêtes.",
+		},
+		msgDataQuota: {
+			Message: "limite de transfert de données atteinte pour cet accès",
+			Hint:    "Demandez un nouvel accès pour transférer davantage de données.",
+		},
+		msgRowQuota: {
+			Message: "limite de lignes renvoyées atteinte pour cet accès",
+			Hint:    "Demandez un nouvel accès pour lire davantage de lignes.",
+		},
+		msgGrantExpired: {
+			Message: "accès expiré",
+			Hint:    "Demandez un nouvel accès pour continuer à travailler sur cette base.",
+		},
+		msgGrantRevoked: {
+			Message: "accès révoqué par un administrateur",
+			Hint:    "Demandez un nouvel accès pour continuer à travailler sur cette base.",
+		},
+		msgQueryAborted: {
+			Message: "annulation de la requête : {{.Cause}}",
+		},
+		msgQueryRejected: {Message: "{{.Cause}}"},
+		msgIdleTimeout: {
+			Message: "fin de la connexion : session inactive trop longtemps",
+			Hint:    "Reconnectez-vous pour continuer à travailler sur cette base.",
+		},
+		msgMaxDuration: {
+			Message: "fin de la connexion : durée maximale de session atteinte",
+			Hint:    "Reconnectez-vous pour continuer à travailler sur cette base.",
+		},
+		msgTerminated: {Message: "fin de la connexion sur ordre d'un administrateur"},
+	},
+}
+
+// compiledMessage is a parsed messageText.
+type compiledMessage struct {
+	message, detail, hint *template.Template
+}
+
+// compiledCatalog is messageCatalog parsed once at startup; a malformed
+// template panics at init rather than on a client connection.
+var compiledCatalog = compileCatalog(messageCatalog)
+
+func compileCatalog(catalog map[string]map[messageID]messageText) map[string]map[messageID]compiledMessage {
+	compiled := make(map[string]map[messageID]compiledMessage, len(catalog))
+
+	for locale, messages := range catalog {
+		compiled[locale] = make(map[messageID]compiledMessage, len(messages))
+
+		for id, text := range messages {
+			name := locale + "." + string(id)
+			compiled[locale][id] = compiledMessage{
+				message: template.Must(template.New(name).Parse(text.Message)),
+				detail:  template.Must(template.New(name + ".detail").Parse(text.Detail)),
+				hint:    template.Must(template.New(name + ".hint").Parse(text.Hint)),
+			}
+		}
+	}
+
+	return compiled
+}
+
+// clientError is an error to report to the client: its SQLSTATE, severity
+// and the catalog message describing it.
+type clientError struct {
+	severity string
+	code     string
+	id       messageID
+	cause    string
+	// blocked marks a statement refused by a grant control, for which admins
+	// may configure the text returned (override).
+	blocked bool
+	// override, when set, replaces the message; the catalog message then
+	// moves to DETAIL so the reason is not lost.
+	override string
+}
+
+// newFatalError builds a connection-terminating error.
+func newFatalError(code string, id messageID) clientError {
+	return clientError{severity: "FATAL", code: code, id: id}
+}
+
+// classifyQueryError maps an error that rejected a client command to the
+// SQLSTATE and message reported to the client.
+func classifyQueryError(err error) clientError {
+	e := clientError{severity: "ERROR", cause: err.Error()}
+
+	switch {
+	case errors.Is(err, ErrPasswordChangeNotAllowed):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgPasswordChange
+	case errors.Is(err, ErrReadOnlyBypassAttempt):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgReadOnlyBypass
+	case errors.Is(err, ErrWriteNotPermitted):
+		e.code, e.id = sqlStateReadOnlyTransaction, msgWriteNotPermitted
+	case errors.Is(err, ErrDDLNotPermitted):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgDDLNotPermitted
+	case errors.Is(err, ErrCopyNotPermitted):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgCopyNotPermitted
+	case errors.Is(err, ErrCopyOutNotPermitted):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgCopyOutNotPermitted
+	case errors.Is(err, ErrTableNotPermitted):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgTableNotPermitted
+
+		var tableErr *tableNotPermittedError
+		if errors.As(err, &tableErr) {
+			e.cause = tableErr.table
+		}
+	case errors.Is(err, ErrTableAllowlistBypass):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgTableAllowlist
+	case errors.Is(err, ErrDryRunNotPermitted):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgDryRunNotPermitted
+	case errors.Is(err, ErrStatementDenied):
+		e.code, e.id, e.cause = sqlStateInsufficientPrivilege, msgStatementDenied, ""
+
+		var deniedErr *statementDeniedError
+		if errors.As(err, &deniedErr) {
+			e.cause = deniedErr.reason
+		}
+	case errors.Is(err, ErrApprovalTimeout):
+		e.code, e.id = sqlStateQueryCanceled, msgApprovalTimeout
+	case errors.Is(err, ErrApprovalCanceled):
+		e.code, e.id = sqlStateQueryCanceled, msgApprovalCanceled
+	case errors.Is(err, shared.ErrUnsupportedControl):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgUnsupportedControl
+	case errors.Is(err, ErrQueryLimitExceeded):
+		e.code, e.id = sqlStateConfigurationLimitExceeded, msgQueryQuota
+	case errors.Is(err, ErrDataLimitExceeded), errors.Is(err, shared.ErrByteQuotaExceeded):
+		e.code, e.id = sqlStateConfigurationLimitExceeded, msgDataQuota
+	case errors.Is(err, ErrRowLimitExceeded):
+		e.code, e.id = sqlStateConfigurationLimitExceeded, msgRowQuota
+	case errors.Is(err, shared.ErrGrantExpired):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgGrantExpired
+	case errors.Is(err, shared.ErrGrantRevoked):
+		e.code, e.id = sqlStateInsufficientPrivilege, msgGrantRevoked
+	default:
+		e.code, e.id = sqlStateSyntaxOrAccessRule, msgQueryRejected
+	}
+
+	switch e.id {
+	case msgPasswordChange, msgReadOnlyBypass, msgWriteNotPermitted, msgDDLNotPermitted, msgCopyNotPermitted,
+		msgCopyOutNotPermitted, msgTableNotPermitted, msgTableAllowlist, msgDryRunNotPermitted:
+		e.blocked = true
+	}
+
+	return e
+}
+
+// classifyAbortError maps the limit that cut off an in-flight query. Quota
+// exhaustion keeps its configuration_limit_exceeded class; a grant that
+// expired or was revoked mid-query reports the statement as canceled.
+func classifyAbortError(err error) clientError {
+	if errors.Is(err, shared.ErrByteQuotaExceeded) || errors.Is(err, ErrDataLimitExceeded) {
+		return clientError{severity: "ERROR", code: sqlStateConfigurationLimitExceeded, id: msgDataQuota, cause: err.Error()}
+	}
+
+	if errors.Is(err, ErrRowLimitExceeded) {
+		return clientError{severity: "ERROR", code: sqlStateConfigurationLimitExceeded, id: msgRowQuota, cause: err.Error()}
+	}
+
+	return clientError{severity: "ERROR", code: sqlStateQueryCanceled, id: msgQueryAborted, cause: err.Error()}
+}
+
+// sessionEndError maps a session timeout or an administrator's termination to
+// the FATAL error reported to the client; ok is false for any other error.
+func sessionEndError(err error) (clientError, bool) {
+	switch {
+	case errors.Is(err, shared.ErrSessionTerminated):
+		return newFatalError(sqlStateAdminShutdown, msgTerminated), true
+	case errors.Is(err, shared.ErrSessionIdle):
+		return newFatalError(sqlStateIdleSessionTimeout, msgIdleTimeout), true
+	case errors.Is(err, shared.ErrSessionMaxDuration):
+		return newFatalError(sqlStateAdminShutdown, msgMaxDuration), true
+	default:
+		return clientError{}, false
+	}
+}
+
+// grantEndError maps a grant that stopped being valid mid-session to the
+// FATAL error reported to the client before the connection closes.
+func grantEndError(err error) (clientError, bool) {
+	switch {
+	case errors.Is(err, shared.ErrGrantExpired):
+		return newFatalError(sqlStateInsufficientPrivilege, msgGrantExpired), true
+	case errors.Is(err, shared.ErrGrantRevoked):
+		return newFatalError(sqlStateInsufficientPrivilege, msgGrantRevoked), true
+	default:
+		return clientError{}, false
+	}
+}
+
+// render builds the ErrorResponse for e in the given locale, falling back to
+// English for unknown locales.
+func (e clientError) render(locale string, data messageData) *pgproto3.ErrorResponse {
+	messages, ok := compiledCatalog[locale]
+	if !ok {
+		messages = compiledCatalog[defaultLocale]
+	}
+
+	msg, ok := messages[e.id]
+	if !ok {
+		msg = compiledCatalog[defaultLocale][e.id]
+	}
+
+	data.Cause = e.cause
+
+	resp := &pgproto3.ErrorResponse{
+		Severity:            e.severity,
+		SeverityUnlocalized: e.severity,
+		Code:                e.code,
+		Message:             executeMessage(msg.message, data),
+		Detail:              executeMessage(msg.detail, data),
+		Hint:                executeMessage(msg.hint, data),
+		Routine:             routinePrefix + string(e.id),
+	}
+
+	if e.override != "" {
+		resp.Detail = resp.Message
+		resp.Message = e.override
+	}
+
+	return resp
+}
+
+// executeMessage renders a message template; a template error (which the
+// catalog tests rule out) degrades to the raw template text.
+func executeMessage(tmpl *template.Template, data messageData) string {
+	if tmpl == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return tmpl.Root.String()
+	}
+
+	return sb.String()
+}
+
+// clientLocale derives the message language from the client's startup
+// parameters: lc_messages, either sent directly or through
+// options="-c lc_messages=fr_FR". Only the language part is used.
+func clientLocale(params map[string]string) string {
+	value := params["lc_messages"]
+
+	if value == "" {
+		fields := strings.Fields(params["options"])
+		for i, field := range fields {
+			setting := strings.TrimPrefix(field, "--")
+			if field == "-c" && i+1 < len(fields) {
+				setting = fields[i+1]
+			}
+
+			if v, ok := strings.CutPrefix(setting, "lc_messages="); ok {
+				value = v
+			}
+		}
+	}
+
+	lang, _, _ := strings.Cut(value, "_")
+	lang, _, _ = strings.Cut(lang, ".")
+	lang = strings.ToLower(lang)
+
+	if _, ok := compiledCatalog[lang]; ok {
+		return lang
+	}
+
+	return defaultLocale
+}
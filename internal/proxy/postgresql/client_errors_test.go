@@ -0,0 +1,246 @@
+package postgresql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+)
+
+func TestMessageCatalogComplete(t *testing.T) {
+	t.Parallel()
+
+	data := messageData{User: "alice", Database: "prod", Cause: "boom"}
+
+	for locale, messages := range compiledCatalog {
+		if len(messages) != len(messageCatalog[defaultLocale]) {
+			t.Errorf("locale %q defines %d messages, want %d", locale, len(messages), len(messageCatalog[defaultLocale]))
+		}
+
+		for id := range messageCatalog[defaultLocale] {
+			msg, ok := messages[id]
+			if !ok {
+				t.Errorf("locale %q is missing message %q", locale, id)
+
+				continue
+			}
+
+			rendered := executeMessage(msg.message, data)
+			if rendered == "" || strings.Contains(rendered, "{{") {
+				t.Errorf("locale %q message %q rendered as %q", locale, id, rendered)
+			}
+		}
+	}
+}
+
+// TestMessageCatalogGuidance checks that every refusal tells the client why
+// or what to do about it, beyond the one-line message.
+func TestMessageCatalogGuidance(t *testing.T) {
+	t.Parallel()
+
+	refusals := []messageID{
+		msgPasswordChange, msgReadOnlyBypass, msgWriteNotPermitted, msgDDLNotPermitted, msgCopyNotPermitted,
+		msgCopyOutNotPermitted, msgTableNotPermitted, msgTableAllowlist, msgDryRunNotPermitted,
+		msgQueryQuota, msgDataQuota, msgRowQuota, msgGrantExpired, msgGrantRevoked,
+	}
+
+	for locale, messages := range messageCatalog {
+		for _, id := range refusals {
+			if messages[id].Detail == "" && messages[id].Hint == "" {
+				t.Errorf("locale %q message %q has neither a detail nor a hint", locale, id)
+			}
+		}
+	}
+}
+
+func TestClassifyQueryError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		code string
+		id   messageID
+	}{
+		{ErrWriteNotPermitted, sqlStateReadOnlyTransaction, msgWriteNotPermitted},
+		{ErrDDLNotPermitted, sqlStateInsufficientPrivilege, msgDDLNotPermitted},
+		{ErrCopyNotPermitted, sqlStateInsufficientPrivilege, msgCopyNotPermitted},
+		{ErrPasswordChangeNotAllowed, sqlStateInsufficientPrivilege, msgPasswordChange},
+		{ErrReadOnlyBypassAttempt, sqlStateInsufficientPrivilege, msgReadOnlyBypass},
+		{ErrQueryLimitExceeded, sqlStateConfigurationLimitExceeded, msgQueryQuota},
+		{fmt.Errorf("wrapped: %w", ErrDataLimitExceeded), sqlStateConfigurationLimitExceeded, msgDataQuota},
+		{ErrRowLimitExceeded, sqlStateConfigurationLimitExceeded, msgRowQuota},
+		{shared.ErrGrantExpired, sqlStateInsufficientPrivilege, msgGrantExpired},
+		{shared.ErrGrantRevoked, sqlStateInsufficientPrivilege, msgGrantRevoked},
+		{&statementDeniedError{reason: "wrong table"}, sqlStateInsufficientPrivilege, msgStatementDenied},
+		{ErrApprovalTimeout, sqlStateQueryCanceled, msgApprovalTimeout},
+		{ErrApprovalCanceled, sqlStateQueryCanceled, msgApprovalCanceled},
+		{fmt.Errorf("something else"), sqlStateSyntaxOrAccessRule, msgQueryRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			t.Parallel()
+
+			got := classifyQueryError(tt.err)
+			if got.code != tt.code || got.id != tt.id || got.severity != "ERROR" {
+				t.Errorf("classifyQueryError(%v) = %s/%s/%s, want ERROR/%s/%s",
+					tt.err, got.severity, got.code, got.id, tt.code, tt.id)
+			}
+		})
+	}
+}
+
+func TestClassifyAbortError(t *testing.T) {
+	t.Parallel()
+
+	if got := classifyAbortError(shared.ErrByteQuotaExceeded); got.code != sqlStateConfigurationLimitExceeded {
+		t.Errorf("byte quota abort code = %s, want %s", got.code, sqlStateConfigurationLimitExceeded)
+	}
+
+	if got := classifyAbortError(ErrRowLimitExceeded); got.code != sqlStateConfigurationLimitExceeded || got.id != msgRowQuota {
+		t.Errorf("row quota abort = %s/%s, want %s/%s", got.code, got.id, sqlStateConfigurationLimitExceeded, msgRowQuota)
+	}
+
+	for _, err := range []error{shared.ErrGrantExpired, shared.ErrGrantRevoked} {
+		got := classifyAbortError(err)
+		if got.code != sqlStateQueryCanceled {
+			t.Errorf("classifyAbortError(%v) code = %s, want %s", err, got.code, sqlStateQueryCanceled)
+		}
+
+		resp := got.render(defaultLocale, messageData{})
+		if resp.Message != "canceling statement: "+err.Error() {
+			t.Errorf("classifyAbortError(%v) message = %q", err, resp.Message)
+		}
+	}
+}
+
+func TestSessionEndError(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		err  error
+		code string
+	}{
+		{shared.ErrSessionIdle, sqlStateIdleSessionTimeout},
+		{shared.ErrSessionMaxDuration, sqlStateAdminShutdown},
+		{shared.ErrSessionTerminated, sqlStateAdminShutdown},
+	} {
+		got, ok := sessionEndError(tc.err)
+		if !ok || got.severity != "FATAL" || got.code != tc.code {
+			t.Errorf("sessionEndError(%v) = %+v, %v, want a FATAL %s", tc.err, got, ok, tc.code)
+		}
+	}
+
+	if _, ok := sessionEndError(shared.ErrGrantExpired); ok {
+		t.Error("sessionEndError(ErrGrantExpired) reported a session end")
+	}
+}
+
+func TestGrantEndError(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		err error
+		id  messageID
+	}{
+		{shared.ErrGrantExpired, msgGrantExpired},
+		{fmt.Errorf("watchdog: %w", shared.ErrGrantRevoked), msgGrantRevoked},
+	} {
+		got, ok := grantEndError(tc.err)
+		if !ok || got.severity != "FATAL" || got.code != sqlStateInsufficientPrivilege || got.id != tc.id {
+			t.Errorf("grantEndError(%v) = %+v, %v, want a FATAL %s", tc.err, got, ok, tc.id)
+		}
+	}
+
+	if _, ok := grantEndError(shared.ErrByteQuotaExceeded); ok {
+		t.Error("grantEndError(ErrByteQuotaExceeded) reported a grant end")
+	}
+}
+
+func TestClientErrorRender(t *testing.T) {
+	t.Parallel()
+
+	clientErr := newFatalError(sqlStateInsufficientPrivilege, msgNoGrant)
+	data := messageData{User: "alice", Database: "prod"}
+
+	resp := clientErr.render("en", data)
+	if resp.Severity != "FATAL" || resp.SeverityUnlocalized != "FATAL" || resp.Code != "42501" {
+		t.Errorf("render() = %s/%s, want FATAL/42501", resp.Severity, resp.Code)
+	}
+
+	if resp.Message != `user "alice" has no active access grant for database "prod"` {
+		t.Errorf("render() message = %q", resp.Message)
+	}
+
+	if resp.Hint == "" {
+		t.Error("render() hint is empty")
+	}
+
+	if resp.Routine != "dbbat_no_grant" {
+		t.Errorf("render() routine = %q, want dbbat_no_grant", resp.Routine)
+	}
+
+	if fr := clientErr.render("fr", data); !strings.Contains(fr.Message, "« alice »") {
+		t.Errorf("render(fr) message = %q", fr.Message)
+	}
+
+	if unknown := clientErr.render("xx", data); unknown.Message != resp.Message {
+		t.Errorf("render(xx) message = %q, want the English message", unknown.Message)
+	}
+}
+
+func TestClientLocale(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   string
+	}{
+		{"none", map[string]string{}, "en"},
+		{"lc_messages", map[string]string{"lc_messages": "fr_FR.UTF-8"}, "fr"},
+		{"options -c", map[string]string{"options": "-c search_path=app -c lc_messages=fr_CA"}, "fr"},
+		{"options --", map[string]string{"options": "--lc_messages=fr"}, "fr"},
+		{"unsupported", map[string]string{"lc_messages": "de_DE"}, "en"},
+		{"C locale", map[string]string{"lc_messages": "C"}, "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := clientLocale(tt.params); got != tt.want {
+				t.Errorf("clientLocale(%v) = %q, want %q", tt.params, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientErrorBlockedOverride(t *testing.T) {
+	t.Parallel()
+
+	if classifyQueryError(ErrQueryLimitExceeded).blocked {
+		t.Error("quota errors must not be marked blocked")
+	}
+
+	clientErr := classifyQueryError(ErrWriteNotPermitted)
+	if !clientErr.blocked {
+		t.Fatal("write errors must be marked blocked")
+	}
+
+	clientErr.override = "Read-only access: request write access at https://dbbat.example.com"
+
+	resp := clientErr.render(defaultLocale, messageData{})
+	if resp.Message != clientErr.override {
+		t.Errorf("render() message = %q, want the override", resp.Message)
+	}
+
+	if resp.Detail != "write operations not permitted with read-only access" {
+		t.Errorf("render() detail = %q, want the original message", resp.Detail)
+	}
+
+	if resp.Code != sqlStateReadOnlyTransaction {
+		t.Errorf("render() code = %q, want %q", resp.Code, sqlStateReadOnlyTransaction)
+	}
+}
@@ -0,0 +1,351 @@
+package postgresql
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// scramMechanismPlus is SCRAM-SHA-256 with channel binding, offered to
+// clients connected over the proxy's own TLS.
+const scramMechanismPlus = "SCRAM-SHA-256-PLUS"
+
+// scramCBindType is the one channel binding type PostgreSQL defines (RFC
+// 5929): a hash of the server certificate, which only the endpoint that
+// terminated TLS can know.
+const scramCBindType = "tls-server-end-point"
+
+// scramServerNonceLength is the random part the proxy adds to the client
+// nonce, in bytes (24 base64 characters).
+const scramServerNonceLength = 18
+
+// scramSecret is a secret a client may prove knowledge of: the user's
+// password, whose keys are stored, or one of their API keys, whose keys are
+// derived on demand from the plaintext key.
+type scramSecret struct {
+	storedKey []byte
+	serverKey []byte
+	apiKey    string // Plaintext API key; empty for the password
+}
+
+// keys returns the secret's StoredKey and ServerKey for the given salt and
+// iteration count, deriving and keeping them for an API key.
+func (sc *scramSecret) keys(salt []byte, iterations int) ([]byte, []byte) {
+	if sc.storedKey == nil {
+		salted := pbkdf2.Key([]byte(sc.apiKey), salt, iterations, sha256.Size, sha256.New)
+		sc.storedKey = sha256Sum(hmacSHA256(salted, []byte("Client Key")))
+		sc.serverKey = hmacSHA256(salted, []byte("Server Key"))
+	}
+
+	return sc.storedKey, sc.serverKey
+}
+
+// scramServer is the server side of a SCRAM-SHA-256 exchange with a client
+// (RFC 5802 / RFC 7677, as PostgreSQL speaks it). Every secret shares the
+// salt and iteration count sent in the server-first message, so the proof
+// can be checked against each of them in turn:
+//
+//	srv := newSCRAMServer(salt, iterations, cbindData, secrets)
+//	first, err := srv.serverFirstMessage(mechanism, clientFirst) // AuthenticationSASLContinue
+//	final, secret, err := srv.serverFinalMessage(clientFinal)   // AuthenticationSASLFinal
+type scramServer struct {
+	salt       []byte
+	iterations int
+	cbindData  []byte // tls-server-end-point data; nil without TLS
+	secrets    []scramSecret
+
+	// Persisted between messages to build the AuthMessage.
+	gs2Header       string
+	clientFirstBare string
+	serverFirst     string
+	nonce           string
+}
+
+func newSCRAMServer(salt []byte, iterations int, cbindData []byte, secrets []scramSecret) *scramServer {
+	return &scramServer{salt: salt, iterations: iterations, cbindData: cbindData, secrets: secrets}
+}
+
+// mechanisms returns the SASL mechanisms to offer, the channel-bound one
+// first when the client is on TLS.
+func (srv *scramServer) mechanisms() []string {
+	if srv.cbindData != nil {
+		return []string{scramMechanismPlus, scramMechanism}
+	}
+
+	return []string{scramMechanism}
+}
+
+// serverFirstMessage checks the client-first message sent with the chosen
+// mechanism and returns the server-first message (nonce, salt, iterations).
+func (srv *scramServer) serverFirstMessage(mechanism string, clientFirst []byte) ([]byte, error) {
+	cbindFlag, rest, ok := strings.Cut(string(clientFirst), ",")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing GS2 header", ErrSCRAMClientMessage)
+	}
+
+	authzID, bare, ok := strings.Cut(rest, ",")
+	if !ok || authzID != "" {
+		return nil, fmt.Errorf("%w: malformed GS2 header or authorization identity", ErrSCRAMClientMessage)
+	}
+
+	switch {
+	case mechanism == scramMechanismPlus && srv.cbindData != nil:
+		if cbindFlag != "p="+scramCBindType {
+			return nil, fmt.Errorf("%w: %s requires %s binding", ErrSCRAMChannelBinding, scramMechanismPlus, scramCBindType)
+		}
+	case mechanism == scramMechanism:
+		// "y" claims the server offered no channel binding: a downgrade when
+		// it did.
+		if cbindFlag != "n" && (cbindFlag != "y" || srv.cbindData != nil) {
+			return nil, fmt.Errorf("%w: unexpected channel binding flag %q", ErrSCRAMChannelBinding, cbindFlag)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrSCRAMUnsupportedMechanism, mechanism)
+	}
+
+	fields := splitSCRAMFields(bare)
+	if _, ok := fields["m"]; ok {
+		return nil, fmt.Errorf("%w: unsupported mandatory extension", ErrSCRAMClientMessage)
+	}
+
+	clientNonce := fields["r"]
+	if clientNonce == "" {
+		return nil, fmt.Errorf("%w: missing r= in client first", ErrSCRAMClientMessage)
+	}
+
+	serverNonce := make([]byte, scramServerNonceLength)
+	if _, err := rand.Read(serverNonce); err != nil {
+		return nil, fmt.Errorf("scram nonce: %w", err)
+	}
+
+	srv.gs2Header = cbindFlag + ",,"
+	srv.clientFirstBare = bare
+	srv.nonce = clientNonce + base64.StdEncoding.EncodeToString(serverNonce)
+	srv.serverFirst = "r=" + srv.nonce +
+		",s=" + base64.StdEncoding.EncodeToString(srv.salt) +
+		",i=" + strconv.Itoa(srv.iterations)
+
+	return []byte(srv.serverFirst), nil
+}
+
+// serverFinalMessage checks the client-final message's channel binding,
+// nonce and proof, and returns the server-final message along with the
+// secret the client proved knowledge of.
+func (srv *scramServer) serverFinalMessage(clientFinal []byte) ([]byte, *scramSecret, error) {
+	withoutProof, proofB64, ok := strings.Cut(string(clientFinal), ",p=")
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: missing p= in client final", ErrSCRAMClientMessage)
+	}
+
+	fields := splitSCRAMFields(withoutProof)
+
+	cbindInput, err := base64.StdEncoding.DecodeString(fields["c"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: channel binding: %w", ErrSCRAMClientMessage, err)
+	}
+
+	if want := append([]byte(srv.gs2Header), srv.cbindDataFor()...); !bytes.Equal(cbindInput, want) {
+		return nil, nil, ErrSCRAMChannelBinding
+	}
+
+	if fields["r"] != srv.nonce {
+		return nil, nil, fmt.Errorf("%w: nonce mismatch", ErrSCRAMClientMessage)
+	}
+
+	proof, err := base64.StdEncoding.DecodeString(proofB64)
+	if err != nil || len(proof) != sha256.Size {
+		return nil, nil, fmt.Errorf("%w: malformed proof", ErrSCRAMClientMessage)
+	}
+
+	authMessage := []byte(srv.clientFirstBare + "," + srv.serverFirst + "," + withoutProof)
+
+	for i := range srv.secrets {
+		secret := &srv.secrets[i]
+		storedKey, serverKey := secret.keys(srv.salt, srv.iterations)
+
+		clientKey := xorBytes(proof, hmacSHA256(storedKey, authMessage))
+		if !hmac.Equal(sha256Sum(clientKey), storedKey) {
+			continue
+		}
+
+		serverSignature := hmacSHA256(serverKey, authMessage)
+
+		return []byte("v=" + base64.StdEncoding.EncodeToString(serverSignature)), secret, nil
+	}
+
+	return nil, nil, ErrSCRAMClientProof
+}
+
+// cbindDataFor returns the channel binding data the client had to bind to:
+// the certificate hash for a "p" header, nothing otherwise.
+func (srv *scramServer) cbindDataFor() []byte {
+	if strings.HasPrefix(srv.gs2Header, "p=") {
+		return srv.cbindData
+	}
+
+	return nil
+}
+
+// tlsServerEndPoint returns the tls-server-end-point channel binding data of
+// a DER certificate (RFC 5929 §4.1): its hash with the certificate's
+// signature hash algorithm, SHA-256 standing in for MD5 and SHA-1.
+func tlsServerEndPoint(der []byte) ([]byte, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy certificate: %w", err)
+	}
+
+	switch cert.SignatureAlgorithm {
+	case x509.SHA384WithRSA, x509.SHA384WithRSAPSS, x509.ECDSAWithSHA384:
+		sum := sha512.Sum384(der)
+
+		return sum[:], nil
+	case x509.SHA512WithRSA, x509.SHA512WithRSAPSS, x509.ECDSAWithSHA512:
+		sum := sha512.Sum512(der)
+
+		return sum[:], nil
+	default:
+		return sha256Sum(der), nil
+	}
+}
+
+// maxSCRAMAPIKeys bounds the API keys a SCRAM proof is checked against, each
+// costing a PBKDF2 derivation. A user with more keys authenticates in clear.
+const maxSCRAMAPIKeys = 16
+
+// newClientSCRAMServer prepares a SCRAM-SHA-256 exchange for the session's
+// user, or returns nil when the client is to send its password in clear: the
+// user has no stored SCRAM verifier, or one of their API keys cannot be
+// checked through SCRAM (no stored secret, or too many keys), so offering
+// SCRAM would lock that key out.
+//
+// The verifier is the one stored for the MongoDB proxy: SCRAM-SHA-256
+// credentials do not depend on the protocol. API keys are checked with the
+// verifier's salt and iteration count, from their encrypted signing secret.
+func (s *Session) newClientSCRAMServer(ctx context.Context) *scramServer {
+	creds := s.user.MongoSCRAMCredentials()
+	if creds == nil || len(s.encryptionKey) == 0 {
+		return nil
+	}
+
+	aad := crypto.UserAAD(s.user.UID.String())
+
+	storedKey, err := crypto.Decrypt(creds.StoredKey, s.encryptionKey, aad)
+	if err != nil {
+		s.logger.WarnContext(s.ctx, "SCRAM verifier decrypt failed", slog.Any("error", err))
+
+		return nil
+	}
+
+	serverKey, err := crypto.Decrypt(creds.ServerKey, s.encryptionKey, aad)
+	if err != nil {
+		s.logger.WarnContext(s.ctx, "SCRAM verifier decrypt failed", slog.Any("error", err))
+
+		return nil
+	}
+
+	keyType := store.KeyTypeAPI
+
+	keys, err := s.store.ListAPIKeys(ctx, store.APIKeyFilter{UserID: &s.user.UID, KeyType: &keyType, Limit: maxSCRAMAPIKeys + 1})
+	if err != nil || len(keys) > maxSCRAMAPIKeys {
+		return nil
+	}
+
+	secrets := []scramSecret{{storedKey: storedKey, serverKey: serverKey}}
+
+	for i := range keys {
+		plainKey, err := keys[i].SigningSecret(s.encryptionKey)
+		if err != nil {
+			return nil
+		}
+
+		secrets = append(secrets, scramSecret{apiKey: string(plainKey)})
+	}
+
+	var cbindData []byte
+
+	if _, onTLS := s.clientConn.(*tls.Conn); onTLS {
+		cbindData, err = tlsServerEndPoint(s.tlsConfig.Certificates[0].Certificate[0])
+		if err != nil {
+			s.logger.WarnContext(s.ctx, "channel binding unavailable", slog.Any("error", err))
+		}
+	}
+
+	return newSCRAMServer(creds.Salt, creds.Iterations, cbindData, secrets)
+}
+
+// authenticateSCRAM runs a SCRAM-SHA-256 exchange with the client. A proof
+// made with an API key is then checked like a cleartext one (revocation,
+// expiry, usage).
+func (s *Session) authenticateSCRAM(srv *scramServer) error {
+	if err := s.writeAuthRequest(&pgproto3.AuthenticationSASL{AuthMechanisms: srv.mechanisms()}); err != nil {
+		return err
+	}
+
+	body, err := s.receiveAuthResponse()
+	if err != nil {
+		return fmt.Errorf("failed to receive SASL initial response: %w", err)
+	}
+
+	initial := &pgproto3.SASLInitialResponse{}
+	if err := initial.Decode(body); err != nil {
+		return s.failSCRAM(fmt.Errorf("%w: %w", ErrSCRAMClientMessage, err))
+	}
+
+	serverFirst, err := srv.serverFirstMessage(initial.AuthMechanism, initial.Data)
+	if err != nil {
+		return s.failSCRAM(err)
+	}
+
+	if err := s.writeAuthRequest(&pgproto3.AuthenticationSASLContinue{Data: serverFirst}); err != nil {
+		return err
+	}
+
+	body, err = s.receiveAuthResponse()
+	if err != nil {
+		return fmt.Errorf("failed to receive SASL response: %w", err)
+	}
+
+	response := &pgproto3.SASLResponse{}
+	if err := response.Decode(body); err != nil {
+		return s.failSCRAM(fmt.Errorf("%w: %w", ErrSCRAMClientMessage, err))
+	}
+
+	serverFinal, secret, err := srv.serverFinalMessage(response.Data)
+	if err != nil {
+		return s.failSCRAM(err)
+	}
+
+	if secret.apiKey != "" {
+		if err := s.authenticateWithAPIKey(secret.apiKey); err != nil {
+			return s.failSCRAM(err)
+		}
+	}
+
+	return s.writeAuthRequest(&pgproto3.AuthenticationSASLFinal{Data: serverFinal})
+}
+
+// failSCRAM reports a failed SCRAM exchange to the client like a wrong
+// password.
+func (s *Session) failSCRAM(err error) error {
+	s.logger.InfoContext(s.ctx, "SCRAM authentication failed", slog.Any("error", err))
+	s.sendError(sqlStateInvalidPassword, msgAuthFailed)
+
+	return fmt.Errorf("%w: %w", ErrInvalidPassword, err)
+}
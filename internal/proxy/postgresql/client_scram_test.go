@@ -0,0 +1,208 @@
+package postgresql
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const testSCRAMIterations = 4096
+
+var testSCRAMSalt = []byte("0123456789abcdef")
+
+// passwordSecret returns the stored SCRAM secret of a password.
+func passwordSecret(password string) scramSecret {
+	secret := scramSecret{apiKey: password}
+	storedKey, serverKey := secret.keys(testSCRAMSalt, testSCRAMIterations)
+
+	return scramSecret{storedKey: storedKey, serverKey: serverKey}
+}
+
+// runSCRAM authenticates with password against srv using the proxy's own
+// upstream SCRAM client, which speaks plain SCRAM-SHA-256.
+func runSCRAM(t *testing.T, srv *scramServer, password string) (*scramSecret, error) {
+	t.Helper()
+
+	client, err := newSCRAMClient(password)
+	if err != nil {
+		t.Fatalf("newSCRAMClient() error = %v", err)
+	}
+
+	serverFirst, err := srv.serverFirstMessage(scramMechanism, client.firstMessage())
+	if err != nil {
+		return nil, err
+	}
+
+	clientFinal, err := client.finalMessage(serverFirst)
+	if err != nil {
+		t.Fatalf("finalMessage() error = %v", err)
+	}
+
+	serverFinal, secret, err := srv.serverFinalMessage(clientFinal)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.verifyServerFinal(serverFinal); err != nil {
+		t.Fatalf("verifyServerFinal() error = %v", err)
+	}
+
+	return secret, nil
+}
+
+func TestSCRAMServer_Password(t *testing.T) {
+	t.Parallel()
+
+	srv := newSCRAMServer(testSCRAMSalt, testSCRAMIterations, nil, []scramSecret{passwordSecret("s3cret")})
+
+	secret, err := runSCRAM(t, srv, "s3cret")
+	if err != nil {
+		t.Fatalf("SCRAM exchange error = %v", err)
+	}
+
+	if secret.apiKey != "" {
+		t.Errorf("matched API key %q, want the password", secret.apiKey)
+	}
+}
+
+func TestSCRAMServer_APIKey(t *testing.T) {
+	t.Parallel()
+
+	const key = "dbb_0123456789abcdefghij"
+
+	srv := newSCRAMServer(testSCRAMSalt, testSCRAMIterations, nil,
+		[]scramSecret{passwordSecret("s3cret"), {apiKey: "dbb_other"}, {apiKey: key}})
+
+	secret, err := runSCRAM(t, srv, key)
+	if err != nil {
+		t.Fatalf("SCRAM exchange error = %v", err)
+	}
+
+	if secret.apiKey != key {
+		t.Errorf("matched API key %q, want %q", secret.apiKey, key)
+	}
+}
+
+func TestSCRAMServer_WrongPassword(t *testing.T) {
+	t.Parallel()
+
+	srv := newSCRAMServer(testSCRAMSalt, testSCRAMIterations, nil,
+		[]scramSecret{passwordSecret("s3cret"), {apiKey: "dbb_key"}})
+
+	if _, err := runSCRAM(t, srv, "guess"); !errors.Is(err, ErrSCRAMClientProof) {
+		t.Fatalf("SCRAM exchange error = %v, want ErrSCRAMClientProof", err)
+	}
+}
+
+func TestSCRAMServer_ClientFirst(t *testing.T) {
+	t.Parallel()
+
+	cbindData := []byte("certificate hash")
+
+	tests := []struct {
+		name        string
+		cbindData   []byte
+		mechanism   string
+		clientFirst string
+		want        error
+	}{
+		{name: "no binding", mechanism: scramMechanism, clientFirst: "n,,n=,r=abc"},
+		{name: "client supports binding", mechanism: scramMechanism, clientFirst: "y,,n=,r=abc"},
+		{name: "downgrade", cbindData: cbindData, mechanism: scramMechanism, clientFirst: "y,,n=,r=abc", want: ErrSCRAMChannelBinding},
+		{name: "plus", cbindData: cbindData, mechanism: scramMechanismPlus, clientFirst: "p=tls-server-end-point,,n=,r=abc"},
+		{name: "plus without TLS", mechanism: scramMechanismPlus, clientFirst: "p=tls-server-end-point,,n=,r=abc", want: ErrSCRAMUnsupportedMechanism},
+		{name: "plus with other binding", cbindData: cbindData, mechanism: scramMechanismPlus, clientFirst: "p=tls-unique,,n=,r=abc", want: ErrSCRAMChannelBinding},
+		{name: "binding without plus", cbindData: cbindData, mechanism: scramMechanism, clientFirst: "p=tls-server-end-point,,n=,r=abc", want: ErrSCRAMChannelBinding},
+		{name: "authzid", mechanism: scramMechanism, clientFirst: "n,a=admin,n=,r=abc", want: ErrSCRAMClientMessage},
+		{name: "missing nonce", mechanism: scramMechanism, clientFirst: "n,,n=", want: ErrSCRAMClientMessage},
+		{name: "mandatory extension", mechanism: scramMechanism, clientFirst: "n,,m=ext,n=,r=abc", want: ErrSCRAMClientMessage},
+		{name: "unknown mechanism", mechanism: "SCRAM-SHA-1", clientFirst: "n,,n=,r=abc", want: ErrSCRAMUnsupportedMechanism},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newSCRAMServer(testSCRAMSalt, testSCRAMIterations, tt.cbindData, nil)
+
+			_, err := srv.serverFirstMessage(tt.mechanism, []byte(tt.clientFirst))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("serverFirstMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSCRAMServer_ChannelBinding(t *testing.T) {
+	t.Parallel()
+
+	const gs2Header = "p=tls-server-end-point,,"
+
+	cbindData := []byte("certificate hash")
+
+	tests := []struct {
+		name      string
+		boundData []byte
+		want      error
+	}{
+		{name: "matching certificate", boundData: cbindData},
+		{name: "other certificate", boundData: []byte("intercepting proxy"), want: ErrSCRAMChannelBinding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newSCRAMServer(testSCRAMSalt, testSCRAMIterations, cbindData, []scramSecret{passwordSecret("s3cret")})
+
+			clientFirstBare := "n=,r=clientnonce"
+
+			serverFirst, err := srv.serverFirstMessage(scramMechanismPlus, []byte(gs2Header+clientFirstBare))
+			if err != nil {
+				t.Fatalf("serverFirstMessage() error = %v", err)
+			}
+
+			nonce, _, _ := strings.Cut(strings.TrimPrefix(string(serverFirst), "r="), ",")
+			withoutProof := "c=" + base64.StdEncoding.EncodeToString(append([]byte(gs2Header), tt.boundData...)) + ",r=" + nonce
+			authMessage := clientFirstBare + "," + string(serverFirst) + "," + withoutProof
+
+			salted := pbkdf2.Key([]byte("s3cret"), testSCRAMSalt, testSCRAMIterations, sha256.Size, sha256.New)
+			clientKey := hmacSHA256(salted, []byte("Client Key"))
+			proof := xorBytes(clientKey, hmacSHA256(sha256Sum(clientKey), []byte(authMessage)))
+
+			_, _, err = srv.serverFinalMessage([]byte(withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof)))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("serverFinalMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSServerEndPoint(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := generateSelfSignedTLS()
+	if err != nil {
+		t.Fatalf("generateSelfSignedTLS() error = %v", err)
+	}
+
+	der := cfg.Certificates[0].Certificate[0]
+
+	got, err := tlsServerEndPoint(der)
+	if err != nil {
+		t.Fatalf("tlsServerEndPoint() error = %v", err)
+	}
+
+	// The self-signed certificate is signed with SHA256WithRSA.
+	if want := sha256.Sum256(der); string(got) != string(want[:]) {
+		t.Errorf("tlsServerEndPoint() = %x, want %x", got, want)
+	}
+
+	if _, err := tlsServerEndPoint([]byte("not a certificate")); err == nil {
+		t.Error("tlsServerEndPoint() accepted garbage")
+	}
+}
@@ -0,0 +1,308 @@
+package postgresql
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/lineage"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+)
+
+// maskedText replaces masked values of text-like columns. Its text and binary
+// encodings are identical, so it is valid whatever the result format.
+var maskedText = []byte("****")
+
+// resultControls tracks the mask_pii and max_rows controls over the result
+// currently streamed to the client. Only the upstream→client goroutine
+// touches it.
+type resultControls struct {
+	// columns describes the current result; nil when it is unknown.
+	columns *piiColumns
+	// describingStmt is set while the server answers a Describe('S'): the
+	// next RowDescription describes later executions of describedStmt, not
+	// the current result.
+	describingStmt bool
+	describedStmt  *preparedStatement
+	rows           int64
+	truncated      bool
+}
+
+// piiColumns flags the PII-named columns of a result for mask_pii.
+type piiColumns struct {
+	mask []bool
+	oids []uint32
+}
+
+// newPIIColumns flags the PII-named columns of a row description.
+func newPIIColumns(fields []pgproto3.FieldDescription) *piiColumns {
+	cols := &piiColumns{mask: make([]bool, len(fields)), oids: make([]uint32, len(fields))}
+	for i, field := range fields {
+		cols.mask[i] = shared.IsPIIColumn(string(field.Name))
+		cols.oids[i] = field.DataTypeOID
+	}
+
+	return cols
+}
+
+// handleResultRowDescription records which columns mask_pii hides, either for
+// the current result or, when answering a statement Describe, for every later
+// execution of that statement (clients such as pgx skip the portal Describe
+// for statements they already know).
+func (s *Session) handleResultRowDescription(msg *pgproto3.RowDescription) {
+	if !s.grant.MasksPII() {
+		return
+	}
+
+	cols := newPIIColumns(msg.Fields)
+
+	if s.results.describingStmt {
+		if stmt := s.results.describedStmt; stmt != nil {
+			s.extendedState.mu.Lock()
+			stmt.resultColumns = cols
+			s.extendedState.mu.Unlock()
+		}
+
+		s.results.describingStmt, s.results.describedStmt = false, nil
+
+		return
+	}
+
+	s.results.columns = cols
+}
+
+// applyRowControls enforces mask_pii and max_rows on a DataRow. It reports
+// false when the row must not be forwarded.
+func (s *Session) applyRowControls(msg *pgproto3.DataRow) bool {
+	if maxRows, ok := s.grant.MaxRows(); ok {
+		if s.results.rows >= maxRows {
+			s.results.truncated = true
+
+			return false
+		}
+
+		s.results.rows++
+	}
+
+	if s.grant.MasksPII() {
+		s.maskDataRow(msg)
+	}
+
+	return true
+}
+
+// countReturnedRow counts a result row, a DataRow or the CopyData of a
+// COPY TO, against the grant's max_rows_returned quota. It reports false when
+// the quota is used up: the row is held back and the query aborted by
+// enforceStreamLimits.
+func (s *Session) countReturnedRow() bool {
+	if s.rowQuotaReached() {
+		s.rowQuotaExceeded = true
+
+		return false
+	}
+
+	s.unloggedRows++
+
+	return true
+}
+
+// rowQuotaReached reports whether the grant's max_rows_returned quota is used
+// up, counting the rows of the query in flight.
+func (s *Session) rowQuotaReached() bool {
+	maxRows := s.grant.MaxRowsReturned
+
+	return maxRows != nil && s.grant.RowsReturned+s.unloggedRows >= *maxRows
+}
+
+// throttleForward waits for the grant's max_bytes_per_second throttle before
+// a DataRow or CopyData message is forwarded, in either direction. Other
+// messages are small and go through at once: holding them back would only
+// delay errors and query completion.
+func (s *Session) throttleForward(msg any) error {
+	if s.throttle == nil {
+		return nil
+	}
+
+	// Wire size: type byte, length, then the payload.
+	size := 5
+
+	switch m := msg.(type) {
+	case *pgproto3.DataRow:
+		size += 2
+		for _, value := range m.Values {
+			size += 4 + len(value)
+		}
+	case *pgproto3.CopyData:
+		size += len(m.Data)
+	default:
+		return nil
+	}
+
+	if err := s.throttle.Wait(s.relayCtx, size); err != nil {
+		return fmt.Errorf("throttled forwarding interrupted: %w", err)
+	}
+
+	return nil
+}
+
+// maskDataRow replaces the values of PII columns: text-like columns read
+// "****", other types become NULL. When the result's columns are unknown,
+// every value is masked: the control fails closed.
+func (s *Session) maskDataRow(msg *pgproto3.DataRow) {
+	cols := s.results.columns
+	if cols == nil {
+		cols = s.executingStatementColumns()
+	}
+
+	for i, value := range msg.Values {
+		if value == nil {
+			continue
+		}
+
+		var oid uint32
+
+		if cols != nil {
+			if i < len(cols.mask) && !cols.mask[i] {
+				continue
+			}
+
+			oid = getTypeOID(cols.oids, i)
+		}
+
+		switch oid {
+		case 19, 25, 1042, 1043: // name, text, char, varchar
+			msg.Values[i] = maskedText
+		default:
+			msg.Values[i] = nil
+		}
+	}
+}
+
+// executingStatementColumns returns the columns recorded for the statement of
+// the oldest queued Execute, the one the server is answering.
+func (s *Session) executingStatementColumns() *piiColumns {
+	if len(s.extendedState.pendingQueries) == 0 || s.extendedState.pendingQueries[0].stmt == nil {
+		return nil
+	}
+
+	s.extendedState.mu.Lock()
+	defer s.extendedState.mu.Unlock()
+
+	return s.extendedState.pendingQueries[0].stmt.resultColumns
+}
+
+// finishResult closes the current result. When max_rows dropped rows it warns
+// the client with a NoticeResponse and rewrites the row count of the command
+// tag to the rows actually returned.
+func (s *Session) finishResult(msg *pgproto3.CommandComplete) {
+	if s.results.truncated && msg != nil {
+		maxRows, _ := s.grant.MaxRows()
+
+		s.clientBackend.Send(&pgproto3.NoticeResponse{
+			Severity:            "WARNING",
+			SeverityUnlocalized: "WARNING",
+			Code:                "01000", // warning
+			Message:             fmt.Sprintf("result truncated to %d rows", maxRows),
+			Detail:              "Your access grant limits the rows returned by a statement (max_rows control).",
+		})
+
+		if verb, _, ok := strings.Cut(string(msg.CommandTag), " "); ok && (verb == "SELECT" || verb == "FETCH") {
+			msg.CommandTag = []byte(verb + " " + strconv.FormatInt(s.results.rows, 10))
+		}
+
+		s.logger.InfoContext(s.ctx, "result truncated by max_rows control", slog.Int64("max_rows", maxRows))
+	}
+
+	s.results.columns = nil
+	s.results.rows = 0
+	s.results.truncated = false
+}
+
+// sendQuotaNotices warns the client, with a NoticeResponse sent ahead of the
+// ReadyForQuery of the query just logged, when the use of a quota of its grant
+// crossed one of the session.quota_warnings percentages, so that it is not
+// surprised when statements start being refused.
+func (s *Session) sendQuotaNotices() {
+	quotas := []struct {
+		name, unit  string
+		used        int64
+		limit       *int64
+		description string
+	}{
+		{"query", "queries", s.grant.QueryCount, s.grant.MaxQueryCounts, "have run"},
+		{"data transfer", "bytes", s.grant.BytesTransferred, s.grant.MaxBytesTransferred, "have been transferred"},
+		{"row", "result rows", s.grant.RowsReturned, s.grant.MaxRowsReturned, "have been returned"},
+	}
+
+	for _, quota := range quotas {
+		percent := s.quotaWarner.Crossed(quota.name, quota.used, quota.limit)
+		if percent == 0 {
+			continue
+		}
+
+		s.clientBackend.Send(&pgproto3.NoticeResponse{
+			Severity:            "WARNING",
+			SeverityUnlocalized: "WARNING",
+			Code:                "01000", // warning
+			Message:             fmt.Sprintf("dbbat: %d%% of %s quota used", percent, quota.name),
+			Detail: fmt.Sprintf("%d of the %d %s your access grant allows %s. Statements are refused once the quota is used up.",
+				quota.used, *quota.limit, quota.unit, quota.description),
+		})
+
+		s.logger.InfoContext(s.ctx, "quota warning sent", slog.String("quota", quota.name), slog.Int("percent", percent))
+	}
+}
+
+// tableAllowlistBypassPatterns detect statements that could reach tables
+// outside a grant's allowlist without naming them: search_path changes make
+// unqualified names resolve to another schema, and DO blocks hide their
+// statements in a string.
+var tableAllowlistBypassPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(?:SET|RESET)\s+(?:(?:SESSION|LOCAL)\s+)?search_path\b`),
+	regexp.MustCompile(`(?i)\bset_config\s*\(`),
+	regexp.MustCompile(`(?i)\bDO\s+(?:LANGUAGE\s+\w+\s+)?(?:\$|E?')`),
+}
+
+// tableNotPermittedError names the table refused by a grant's allowlist.
+type tableNotPermittedError struct {
+	table string
+}
+
+func (e *tableNotPermittedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrTableNotPermitted, e.table)
+}
+
+func (e *tableNotPermittedError) Unwrap() error {
+	return ErrTableNotPermitted
+}
+
+// checkAllowedTables refuses statements touching a table outside the grant's
+// allowlist. Tables are found by the lineage scanner, which ignores system
+// catalogs so clients can still introspect the database.
+func (s *Session) checkAllowedTables(sql string) error {
+	if !s.grant.RestrictsTables() {
+		return nil
+	}
+
+	for _, pattern := range tableAllowlistBypassPatterns {
+		if pattern.MatchString(sql) {
+			return ErrTableAllowlistBypass
+		}
+	}
+
+	tables := lineage.ExtractTables(sql, lineage.DialectPostgreSQL)
+
+	for _, table := range slices.Concat(tables.Inputs, tables.Outputs) {
+		if !s.grant.AllowsTable(table) {
+			return &tableNotPermittedError{table: table}
+		}
+	}
+
+	return nil
+}
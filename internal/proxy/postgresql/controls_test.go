@@ -0,0 +1,335 @@
+package postgresql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestIsCopyOutQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected bool
+	}{
+		{name: "COPY TO", sql: "COPY users TO STDOUT", expected: true},
+		{name: "COPY TO file", sql: "copy users (id, email) to '/tmp/out.csv' with (format csv)", expected: true},
+		{name: "COPY query TO", sql: "COPY (SELECT * FROM users WHERE a IN (SELECT 1)) TO STDOUT", expected: true},
+		{name: "COPY without space", sql: "COPY(SELECT 1) TO STDOUT", expected: true},
+		{name: "second statement", sql: "SELECT 1; COPY users TO STDOUT", expected: true},
+		{name: "after a string with a semicolon", sql: "SELECT 'a;b'; COPY users TO STDOUT", expected: true},
+		{name: "standard string ending in backslash", sql: `SELECT 'a\'; COPY users TO STDOUT`, expected: true},
+		{name: "after a dollar quote", sql: "SELECT $x$ ' $x$; COPY users TO STDOUT", expected: true},
+		{name: "COPY FROM", sql: "COPY users FROM STDIN", expected: false},
+		{name: "COPY FROM with TO option", sql: "COPY users FROM STDIN WITH (FORMAT csv); SELECT 1", expected: false},
+		{name: "COPY in a string", sql: "SELECT 'x; COPY users TO STDOUT'", expected: false},
+		{name: "COPY in an escape string", sql: `SELECT E'\'; COPY users TO STDOUT'`, expected: false},
+		{name: "COPY in a comment", sql: "SELECT 1 -- ; COPY users TO STDOUT", expected: false},
+		{name: "COPY in a block comment", sql: "SELECT 1 /* ; COPY users TO STDOUT */", expected: false},
+		{name: "COPY in a dollar quote", sql: "SELECT $$; COPY users TO STDOUT$$", expected: false},
+		{name: "SELECT", sql: "SELECT * FROM users WHERE note = $1", expected: false},
+		{name: "empty", sql: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isCopyOutQuery(tt.sql); got != tt.expected {
+				t.Errorf("isCopyOutQuery(%q) = %v, want %v", tt.sql, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleQuery_BlocksCopyOut(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		controls []string
+		sql      string
+		wantErr  error
+	}{
+		{"block_copy_out blocks COPY TO", []string{store.ControlBlockCopyOut}, "COPY users TO STDOUT",
+			ErrCopyOutNotPermitted},
+		{"block_copy_out allows COPY FROM", []string{store.ControlBlockCopyOut}, "COPY users FROM STDIN", nil},
+		{"mask_pii blocks COPY TO", []string{store.ControlMaskPII}, "COPY (SELECT email FROM users) TO STDOUT",
+			ErrCopyOutNotPermitted},
+		{"no control allows COPY TO", []string{}, "COPY users TO STDOUT", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newTestSessionWithControls(tt.controls)
+
+			if err := s.handleQuery(&pgproto3.Query{String: tt.sql}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("handleQuery() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := s.handleParse(&pgproto3.Parse{Query: tt.sql}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("handleParse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleQuery_TableAllowlist(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr error
+	}{
+		{"allowed table", "SELECT * FROM orders o JOIN public.customers c ON c.id = o.customer_id", nil},
+		{"allowed schema", "INSERT INTO reporting.daily SELECT * FROM orders", nil},
+		{"system catalog", "SELECT relname FROM pg_catalog.pg_class", nil},
+		{"no table", "SELECT 1", nil},
+		{"show search_path", "SHOW search_path", nil},
+		{"upsert", "INSERT INTO orders (id) VALUES (1) ON CONFLICT (id) DO NOTHING", nil},
+		{"other table", "SELECT * FROM orders JOIN secrets USING (id)", ErrTableNotPermitted},
+		{"other schema", "SELECT * FROM archive.orders", ErrTableNotPermitted},
+		{"write", "DELETE FROM audit_trail", ErrTableNotPermitted},
+		{"second statement", "SELECT 1; TABLE secrets", ErrTableNotPermitted},
+		{"search_path", "SET search_path TO archive", ErrTableAllowlistBypass},
+		{"set_config", "SELECT set_config('search_path', 'archive', false)", ErrTableAllowlistBypass},
+		{"do block", "DO $$ BEGIN PERFORM * FROM secrets; END $$", ErrTableAllowlistBypass},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newTestSessionWithControls(nil)
+			s.grant.AllowedTables = []string{"public.orders", "public.customers", "reporting.*"}
+
+			if err := s.handleQuery(&pgproto3.Query{String: tt.sql}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("handleQuery() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := s.handleParse(&pgproto3.Parse{Query: tt.sql}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("handleParse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClassifyQueryError_TableNotPermitted(t *testing.T) {
+	t.Parallel()
+
+	resp := classifyQueryError(&tableNotPermittedError{table: "archive.orders"}).render("en", messageData{})
+
+	if resp.Code != sqlStateInsufficientPrivilege || resp.Message != "permission denied for table archive.orders" {
+		t.Errorf("rendered error = %s %q", resp.Code, resp.Message)
+	}
+}
+
+func TestThrottleForward(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSessionWithControls([]string{"max_bytes_per_second:100"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.relayCtx = ctx
+
+	rate, _ := s.grant.MaxBytesPerSecond()
+	s.throttle = s.throttles.Acquire(s.grant.UID, rate)
+
+	// A row of 100 bytes of values is 111 bytes on the wire: the initial
+	// burst, then 110ms.
+	if err := s.throttleForward(&pgproto3.DataRow{Values: [][]byte{make([]byte, 100)}}); err != nil {
+		t.Fatalf("throttleForward(first row) error = %v", err)
+	}
+
+	cancel()
+
+	// The bucket is in debt: the next data message waits, until the relays
+	// stop.
+	if err := s.throttleForward(&pgproto3.CopyData{Data: make([]byte, 100)}); !errors.Is(err, context.Canceled) {
+		t.Errorf("throttleForward(CopyData) error = %v, want context.Canceled", err)
+	}
+
+	// Other messages are never held back.
+	if err := s.throttleForward(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}); err != nil {
+		t.Errorf("throttleForward(CommandComplete) error = %v", err)
+	}
+}
+
+func rowDescription(names ...string) *pgproto3.RowDescription {
+	fields := make([]pgproto3.FieldDescription, len(names))
+	for i, name := range names {
+		fields[i] = pgproto3.FieldDescription{Name: []byte(name), DataTypeOID: 25}
+	}
+
+	fields[len(fields)-1].DataTypeOID = 1082 // date
+
+	return &pgproto3.RowDescription{Fields: fields}
+}
+
+func TestMaskPII(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSessionWithControls([]string{store.ControlMaskPII})
+
+	s.handleResultRowDescription(rowDescription("id", "user_email", "birth_date"))
+
+	row := &pgproto3.DataRow{Values: [][]byte{[]byte("1"), []byte("a@b.c"), []byte("1990-01-01")}}
+	if !s.applyRowControls(row) {
+		t.Fatal("applyRowControls() dropped the row")
+	}
+
+	if string(row.Values[0]) != "1" || string(row.Values[1]) != "****" || row.Values[2] != nil {
+		t.Errorf("masked row = %q", row.Values)
+	}
+
+	// Columns of an unknown result are all masked.
+	s.finishResult(nil)
+
+	row = &pgproto3.DataRow{Values: [][]byte{[]byte("1"), nil}}
+	s.applyRowControls(row)
+
+	if row.Values[0] != nil || row.Values[1] != nil {
+		t.Errorf("row of unknown result = %q, want all NULL", row.Values)
+	}
+}
+
+func TestMaskPII_DescribedStatement(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSessionWithControls([]string{store.ControlMaskPII})
+
+	if err := s.handleParse(&pgproto3.Parse{Name: "s1", Query: "SELECT id, phone FROM users"}); err != nil {
+		t.Fatalf("handleParse() error = %v", err)
+	}
+
+	s.handleDescribe(&pgproto3.Describe{ObjectType: 'S', Name: "s1"})
+	s.handleParameterDescription(&pgproto3.ParameterDescription{})
+	s.handleResultRowDescription(rowDescription("id", "phone", "x"))
+
+	// Later executions skip Describe and reuse the statement's columns.
+	s.handleBind(&pgproto3.Bind{PreparedStatement: "s1"})
+
+	if err := s.handleExecute(&pgproto3.Execute{}); err != nil {
+		t.Fatalf("handleExecute() error = %v", err)
+	}
+
+	row := &pgproto3.DataRow{Values: [][]byte{[]byte("1"), []byte("+33 6"), []byte("x")}}
+	s.applyRowControls(row)
+
+	if string(row.Values[0]) != "1" || string(row.Values[1]) != "****" || string(row.Values[2]) != "x" {
+		t.Errorf("masked row = %q", row.Values)
+	}
+}
+
+func TestMaxRows(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+
+	s := newTestSessionWithControls([]string{"max_rows:2"})
+	s.clientBackend = pgproto3.NewBackend(&bytes.Buffer{}, &out)
+	s.logger = slog.New(slog.DiscardHandler)
+
+	forwarded := 0
+
+	for range 5 {
+		if s.applyRowControls(&pgproto3.DataRow{Values: [][]byte{[]byte("x")}}) {
+			forwarded++
+		}
+	}
+
+	if forwarded != 2 {
+		t.Errorf("forwarded %d rows, want 2", forwarded)
+	}
+
+	complete := &pgproto3.CommandComplete{CommandTag: []byte("SELECT 5")}
+	s.finishResult(complete)
+
+	if string(complete.CommandTag) != "SELECT 2" {
+		t.Errorf("CommandTag = %q, want SELECT 2", complete.CommandTag)
+	}
+
+	if err := s.clientBackend.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	msg, err := pgproto3.NewFrontend(&out, &bytes.Buffer{}).Receive()
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+
+	if notice, ok := msg.(*pgproto3.NoticeResponse); !ok || notice.Severity != "WARNING" {
+		t.Errorf("sent %#v, want a WARNING notice", msg)
+	}
+
+	// The next result starts a new count.
+	if !s.applyRowControls(&pgproto3.DataRow{}) {
+		t.Error("first row of the next result was dropped")
+	}
+}
+
+func TestQuotaNotices(t *testing.T) {
+	t.Parallel()
+
+	var (
+		out                  bytes.Buffer
+		fromClient, toClient atomic.Int64
+	)
+
+	maxQueries := int64(5)
+
+	s := newTestSessionWithControls(nil)
+	s.grant.MaxQueryCounts = &maxQueries
+	s.grant.QueryCount = 2
+	s.clientBackend = pgproto3.NewBackend(&bytes.Buffer{}, &out)
+	s.quotaWarner = shared.NewQuotaWarner([]int{80})
+	s.bytesFromClient, s.bytesToClient = &fromClient, &toClient
+	s.logger = slog.New(slog.DiscardHandler)
+	s.ctx = context.Background()
+
+	var notices []string
+
+	for range 3 {
+		if err := s.handleQuery(&pgproto3.Query{String: "SELECT 1"}); err != nil {
+			t.Fatalf("handleQuery() error = %v", err)
+		}
+
+		var outcome queryOutcome
+
+		s.trackUpstreamMessage(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}, &outcome)
+		s.trackUpstreamMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}, &outcome)
+
+		if err := s.clientBackend.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		for out.Len() > 0 {
+			msg, err := pgproto3.NewFrontend(&out, &bytes.Buffer{}).Receive()
+			if err != nil {
+				t.Fatalf("Receive() error = %v", err)
+			}
+
+			if notice, ok := msg.(*pgproto3.NoticeResponse); ok {
+				notices = append(notices, notice.Message)
+			}
+		}
+	}
+
+	// The fourth query of five crosses 80%; the fifth crosses nothing new.
+	if len(notices) != 1 || notices[0] != "dbbat: 80% of query quota used" {
+		t.Errorf("notices = %q, want one 80%% query quota warning", notices)
+	}
+}
@@ -0,0 +1,283 @@
+package postgresql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// copyBinarySignature starts every binary COPY stream.
+const copyBinarySignature = "PGCOPY\n\xff\r\n\x00"
+
+// maxColumnTypes bounds the result column types a session remembers for
+// binary COPY; past it, they are forgotten and learned again.
+const maxColumnTypes = 1024
+
+// errCopyBinary is returned when binary COPY data is malformed.
+var errCopyBinary = errors.New("malformed binary COPY data")
+
+// pgEpoch is the origin of PostgreSQL's binary dates and timestamps.
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// rememberColumnTypes records the type OIDs of a result's columns, by name.
+// Binary COPY data carries no types: they are looked up here, which covers
+// the clients that describe the columns before copying them (pgx's CopyFrom).
+func (s *Session) rememberColumnTypes(names []string, oids []uint32) {
+	if s.columnTypes == nil || len(s.columnTypes)+len(names) > maxColumnTypes {
+		s.columnTypes = make(map[string]uint32, len(names))
+	}
+
+	for i, name := range names {
+		s.columnTypes[name] = oids[i]
+	}
+}
+
+// copyColumnOIDs returns the type OIDs of the columns of a COPY, 0 when
+// unknown.
+func (s *Session) copyColumnOIDs(names []string) []uint32 {
+	oids := make([]uint32, len(names))
+	for i, name := range names {
+		oids[i] = s.columnTypes[name]
+	}
+
+	return oids
+}
+
+// parseCopyBinaryToRows decodes binary COPY data into query rows: a header,
+// then tuples of length-prefixed fields, then a -1 trailer.
+func (s *Session) parseCopyBinaryToRows(data []byte, maxRows int) []store.QueryRow {
+	tuples, err := parseCopyBinary(data)
+	if err != nil {
+		s.logger.WarnContext(s.ctx, "failed to parse binary COPY data", slog.Any("error", err),
+			slog.Int("rows_parsed", len(tuples)))
+	}
+
+	rows := make([]store.QueryRow, 0, min(len(tuples), maxRows))
+
+	for i, fields := range tuples {
+		if len(rows) >= maxRows {
+			s.logger.WarnContext(s.ctx, "COPY row capture truncated - row limit exceeded",
+				slog.Int("rows_captured", len(rows)),
+				slog.Int("max_rows", maxRows))
+			break
+		}
+
+		rowData := make(map[string]interface{}, len(fields))
+		rowSize := int64(0)
+
+		for j, field := range fields {
+			colName := fmt.Sprintf("col_%d", j)
+			if j < len(s.copyState.columnNames) {
+				colName = s.copyState.columnNames[j]
+			}
+
+			if field == nil {
+				rowData[colName] = nil
+				continue
+			}
+
+			rowSize += int64(len(field))
+			rowData[colName] = decodeBinaryColumnValue(field, getTypeOID(s.copyState.columnOIDs, j))
+		}
+
+		s.masker.Get(s.database).MaskRow(rowData, s.copyState.tables)
+
+		jsonData, err := json.Marshal(rowData)
+		if err != nil {
+			s.logger.ErrorContext(s.ctx, "failed to marshal COPY row", slog.Any("error", err), slog.Int("row", i))
+			continue
+		}
+
+		rows = append(rows, store.QueryRow{
+			RowNumber:    i + 1,
+			RowData:      jsonData,
+			RowSizeBytes: rowSize,
+		})
+	}
+
+	return rows
+}
+
+// parseCopyBinary splits binary COPY data into tuples of raw field values,
+// nil for NULL. On malformed data, it returns the tuples read before it.
+func parseCopyBinary(data []byte) ([][][]byte, error) {
+	if !bytes.HasPrefix(data, []byte(copyBinarySignature)) {
+		return nil, fmt.Errorf("%w: missing signature", errCopyBinary)
+	}
+
+	data = data[len(copyBinarySignature):]
+
+	// Flags field, then the header extension area and its length.
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: truncated header", errCopyBinary)
+	}
+
+	extension := binary.BigEndian.Uint32(data[4:8])
+	data = data[8:]
+
+	if uint64(extension) > uint64(len(data)) {
+		return nil, fmt.Errorf("%w: truncated header extension", errCopyBinary)
+	}
+
+	data = data[extension:]
+
+	var tuples [][][]byte
+
+	for {
+		if len(data) < 2 {
+			return tuples, fmt.Errorf("%w: missing trailer", errCopyBinary)
+		}
+
+		count := int16(binary.BigEndian.Uint16(data))
+		data = data[2:]
+
+		if count == -1 {
+			return tuples, nil
+		}
+
+		if count < 0 {
+			return tuples, fmt.Errorf("%w: negative field count %d", errCopyBinary, count)
+		}
+
+		fields := make([][]byte, count)
+
+		for i := range fields {
+			if len(data) < 4 {
+				return tuples, fmt.Errorf("%w: truncated field length", errCopyBinary)
+			}
+
+			length := int32(binary.BigEndian.Uint32(data))
+			data = data[4:]
+
+			if length == -1 {
+				continue // NULL
+			}
+
+			if length < 0 || int64(length) > int64(len(data)) {
+				return tuples, fmt.Errorf("%w: invalid field length %d", errCopyBinary, length)
+			}
+
+			fields[i] = data[:length:length]
+			data = data[length:]
+		}
+
+		tuples = append(tuples, fields)
+	}
+}
+
+// decodeBinaryColumnValue decodes a binary-format column value to what its
+// text form decodes to with decodeColumnValue. Values of an unknown type are
+// kept as text when they are valid UTF-8, base64 otherwise.
+func decodeBinaryColumnValue(data []byte, oid uint32) interface{} {
+	switch oid {
+	case 16: // bool
+		if len(data) == 1 {
+			return data[0] != 0
+		}
+
+	case 21: // int2
+		if len(data) == 2 {
+			return int64(int16(binary.BigEndian.Uint16(data)))
+		}
+
+	case 23: // int4
+		if len(data) == 4 {
+			return int64(int32(binary.BigEndian.Uint32(data)))
+		}
+
+	case 20: // int8
+		if len(data) == 8 {
+			return int64(binary.BigEndian.Uint64(data))
+		}
+
+	case 26: // oid
+		if len(data) == 4 {
+			return int64(binary.BigEndian.Uint32(data))
+		}
+
+	case 700: // float4
+		if len(data) == 4 {
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+		}
+
+	case 701: // float8
+		if len(data) == 8 {
+			return math.Float64frombits(binary.BigEndian.Uint64(data))
+		}
+
+	case 25, 1042, 1043, 19: // text, char, varchar, name
+		return string(data)
+
+	case 17: // bytea, as its text form
+		return `\x` + hex.EncodeToString(data)
+
+	case 114: // json
+		if json.Valid(data) {
+			return json.RawMessage(data)
+		}
+
+	case 3802: // jsonb: a version byte, then the text
+		if len(data) > 0 && data[0] == 1 && json.Valid(data[1:]) {
+			return json.RawMessage(data[1:])
+		}
+
+	case 2950: // uuid
+		if id, err := uuid.FromBytes(data); err == nil {
+			return id.String()
+		}
+
+	case 1082: // date: days since 2000-01-01
+		if len(data) == 4 {
+			switch days := int32(binary.BigEndian.Uint32(data)); days {
+			case math.MaxInt32:
+				return "infinity"
+			case math.MinInt32:
+				return "-infinity"
+			default:
+				return pgEpoch.AddDate(0, 0, int(days)).Format(time.DateOnly)
+			}
+		}
+
+	case 1114, 1184: // timestamp, timestamptz: microseconds since 2000-01-01
+		if len(data) == 8 {
+			return binaryTimestamp(int64(binary.BigEndian.Uint64(data)), oid == 1184)
+		}
+
+	default:
+		if utf8.Valid(data) {
+			return string(data)
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// binaryTimestamp formats the microseconds since pgEpoch of a binary
+// timestamp, with its UTC offset when withZone.
+func binaryTimestamp(micros int64, withZone bool) string {
+	switch micros {
+	case math.MaxInt64:
+		return "infinity"
+	case math.MinInt64:
+		return "-infinity"
+	}
+
+	t := time.UnixMicro(pgEpoch.UnixMicro() + micros).UTC()
+	if withZone {
+		return t.Format(time.RFC3339Nano)
+	}
+
+	return t.Format("2006-01-02T15:04:05.999999")
+}
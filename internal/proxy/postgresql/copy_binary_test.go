@@ -0,0 +1,148 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// copyBinary encodes tuples as binary COPY data, nil fields as NULL.
+func copyBinary(tuples ...[][]byte) []byte {
+	data := []byte(copyBinarySignature)
+	data = binary.BigEndian.AppendUint32(data, 0) // Flags
+	data = binary.BigEndian.AppendUint32(data, 0) // Header extension length
+
+	for _, fields := range tuples {
+		data = binary.BigEndian.AppendUint16(data, uint16(len(fields)))
+
+		for _, field := range fields {
+			if field == nil {
+				data = binary.BigEndian.AppendUint32(data, math.MaxUint32) // -1
+				continue
+			}
+
+			data = binary.BigEndian.AppendUint32(data, uint32(len(field)))
+			data = append(data, field...)
+		}
+	}
+
+	return binary.BigEndian.AppendUint16(data, math.MaxUint16) // -1 trailer
+}
+
+func TestParseCopyBinary(t *testing.T) {
+	t.Parallel()
+
+	data := copyBinary(
+		[][]byte{{0, 0, 0, 1}, []byte("alice")},
+		[][]byte{{0, 0, 0, 2}, nil},
+	)
+
+	tuples, err := parseCopyBinary(data)
+	if err != nil {
+		t.Fatalf("parseCopyBinary() error = %v", err)
+	}
+
+	if len(tuples) != 2 || string(tuples[0][1]) != "alice" || tuples[1][1] != nil {
+		t.Errorf("parseCopyBinary() = %q, want 2 tuples, the second with a NULL", tuples)
+	}
+
+	for name, bad := range map[string][]byte{
+		"no signature":    []byte("1\talice\n"),
+		"short header":    []byte(copyBinarySignature + "\x00\x00"),
+		"no trailer":      data[:len(data)-2],
+		"truncated field": data[:len(data)-6],
+	} {
+		if _, err := parseCopyBinary(bad); !errors.Is(err, errCopyBinary) {
+			t.Errorf("%s: parseCopyBinary() error = %v, want errCopyBinary", name, err)
+		}
+	}
+}
+
+func TestDecodeBinaryColumnValue(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		oid  uint32
+		want string // JSON of the decoded value
+	}{
+		{"bool", []byte{1}, 16, `true`},
+		{"int2", []byte{0xff, 0xfe}, 21, `-2`},
+		{"int4", []byte{0, 0, 1, 0}, 23, `256`},
+		{"int8", []byte{0, 0, 0, 0, 0, 0, 0, 42}, 20, `42`},
+		{"float8", binary.BigEndian.AppendUint64(nil, math.Float64bits(1.5)), 701, `1.5`},
+		{"text", []byte("héllo"), 25, `"héllo"`},
+		{"bytea", []byte{0xde, 0xad}, 17, `"\\xdead"`},
+		{"jsonb", []byte("\x01{\"a\":1}"), 3802, `{"a":1}`},
+		{"uuid", []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}, 2950, `"12345678-9abc-def0-1234-56789abcdef0"`},
+		{"date", []byte{0, 0, 0, 31}, 1082, `"2000-02-01"`},
+		{"timestamp", binary.BigEndian.AppendUint64(nil, 1_500_000), 1114, `"2000-01-01T00:00:01.5"`},
+		{"timestamptz", binary.BigEndian.AppendUint64(nil, 0), 1184, `"2000-01-01T00:00:00Z"`},
+		{"infinite timestamp", binary.BigEndian.AppendUint64(nil, math.MaxInt64), 1114, `"infinity"`},
+		{"unknown text", []byte("abc"), 0, `"abc"`},
+		{"unknown binary", []byte{0xff, 0x00}, 0, `"/wA="`},
+		{"wrong length", []byte{0, 1}, 23, `"AAE="`},
+	} {
+		got, err := json.Marshal(decodeBinaryColumnValue(tc.data, tc.oid))
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", tc.name, err)
+		}
+
+		if string(got) != tc.want {
+			t.Errorf("%s: decodeBinaryColumnValue() = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseCopyDataToRows_Binary(t *testing.T) {
+	t.Parallel()
+
+	s := &Session{
+		ctx:           context.Background(),
+		logger:        slog.New(slog.DiscardHandler),
+		queryStorage:  config.QueryStorageConfig{MaxResultRows: 100},
+		extendedState: &extendedQueryState{},
+	}
+
+	// A client describing the columns before copying them, like pgx's CopyFrom.
+	s.captureRowDescription(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+		{Name: []byte("id"), DataTypeOID: 23},
+		{Name: []byte("name"), DataTypeOID: 25},
+	}})
+
+	names := parseCopyColumnNames("COPY users (id, name) FROM STDIN (FORMAT binary)")
+	s.copyState = &copyState{
+		direction:   "in",
+		format:      1,
+		columnNames: names,
+		columnOIDs:  s.copyColumnOIDs(names),
+		dataChunks: [][]byte{copyBinary(
+			[][]byte{{0, 0, 0, 1}, []byte("alice")},
+			[][]byte{{0, 0, 0, 2}, nil},
+		)},
+	}
+
+	rows := s.parseCopyDataToRows()
+	if len(rows) != 2 {
+		t.Fatalf("parseCopyDataToRows() = %d rows, want 2", len(rows))
+	}
+
+	for i, want := range []string{`{"id":1,"name":"alice"}`, `{"id":2,"name":null}`} {
+		if string(rows[i].RowData) != want {
+			t.Errorf("row %d = %s, want %s", i, rows[i].RowData, want)
+		}
+	}
+
+	if rows[0].RowSizeBytes != 9 {
+		t.Errorf("row 0 size = %d, want 9", rows[0].RowSizeBytes)
+	}
+}
@@ -0,0 +1,190 @@
+package postgresql
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// ErrUpstreamDryRunMode is returned when the upstream fails to prepare the
+// dry_run statements.
+var ErrUpstreamDryRunMode = errors.New("upstream error preparing dry-run mode")
+
+// The statements the proxy prepares on the upstream connection of a dry_run
+// session to open and roll back its transactions. Clients cannot use names
+// starting with dryRunStatementPrefix.
+const (
+	dryRunStatementPrefix   = "dbbat_dry_run_"
+	dryRunBeginStatement    = dryRunStatementPrefix + "begin"
+	dryRunRollbackStatement = dryRunStatementPrefix + "rollback"
+)
+
+// dryRunState is the transaction wrapping of the dry_run control. The
+// client's commands run in a transaction the proxy begins before them and
+// rolls back after them: each simple Query (unless it only changes settings)
+// and FunctionCall, and each extended-protocol batch up to its Sync. Queries
+// thus report their rows affected and RETURNING rows, and commit nothing.
+//
+// The BEGIN and ROLLBACK are prepared statements the proxy binds and executes
+// in batches of their own: unlike a simple Query, they leave the client's
+// unnamed statement alone, and upstream does not skip them when a command of
+// the client fails. An explicit transaction rather than the implicit one of a
+// batch also makes upstream refuse the statements that commit on their own,
+// such as VACUUM or a procedure's COMMIT.
+type dryRunState struct {
+	// enabled is set once the statements are prepared.
+	enabled bool
+	// batchOpen is set while an extended-protocol batch of the client is
+	// wrapped. It belongs to the client→upstream goroutine.
+	batchOpen bool
+	// replies has one entry per ReadyForQuery expected from upstream, in
+	// order, true when it ends the replies to one of the proxy's batches.
+	// Guarded by Session.queryMu.
+	replies []bool
+}
+
+// prepareDryRun prepares the statements wrapping the client's commands under
+// the dry_run control, once the upstream connection is ready.
+func (s *Session) prepareDryRun() error {
+	s.upstreamFrontend.Send(&pgproto3.Parse{Name: dryRunBeginStatement, Query: "BEGIN"})
+	s.upstreamFrontend.Send(&pgproto3.Parse{Name: dryRunRollbackStatement, Query: "ROLLBACK"})
+	s.upstreamFrontend.Send(&pgproto3.Sync{})
+
+	if err := s.upstreamFrontend.Flush(); err != nil {
+		return fmt.Errorf("send Parse: %w", err)
+	}
+
+	var prepareErr error
+
+	for {
+		msg, err := s.upstreamFrontend.Receive()
+		if err != nil {
+			return fmt.Errorf("receive response: %w", err)
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.ErrorResponse:
+			prepareErr = fmt.Errorf("%w: %s", ErrUpstreamDryRunMode, m.Message)
+		case *pgproto3.ReadyForQuery:
+			s.dryRun.enabled = prepareErr == nil
+
+			return prepareErr
+		}
+	}
+}
+
+// checkDryRunStatementName refuses the client's Parse, Bind and Close of the
+// proxy's dry_run statements: running or replacing them would end the
+// transaction, and with it the dry run.
+func (s *Session) checkDryRunStatementName(msg pgproto3.FrontendMessage) error {
+	if !s.dryRun.enabled {
+		return nil
+	}
+
+	var name string
+
+	switch m := msg.(type) {
+	case *pgproto3.Parse:
+		name = m.Name
+	case *pgproto3.Bind:
+		name = m.PreparedStatement
+	case *pgproto3.Close:
+		if m.ObjectType == 'S' {
+			name = m.Name
+		}
+	}
+
+	if strings.HasPrefix(name, dryRunStatementPrefix) {
+		return fmt.Errorf("%w: prepared statement %q is reserved", ErrDryRunNotPermitted, name)
+	}
+
+	return nil
+}
+
+// sendUpstream queues a message of the client for upstream, within the
+// dry_run transaction when the session has one.
+func (s *Session) sendUpstream(msg pgproto3.FrontendMessage) {
+	if !s.dryRun.enabled {
+		s.upstreamFrontend.Send(msg)
+
+		return
+	}
+
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
+	switch msg.(type) {
+	case *pgproto3.Query, *pgproto3.FunctionCall:
+		_, isQuery := msg.(*pgproto3.Query)
+		wrap := !isQuery || (s.currentQuery != nil && s.currentQuery.dryRun)
+
+		if wrap {
+			s.sendDryRunBatch(dryRunBeginStatement)
+		}
+
+		s.upstreamFrontend.Send(msg)
+		s.dryRun.replies = append(s.dryRun.replies, false)
+
+		if wrap {
+			s.sendDryRunBatch(dryRunRollbackStatement)
+		}
+	case *pgproto3.Parse, *pgproto3.Bind, *pgproto3.Describe, *pgproto3.Execute, *pgproto3.Close:
+		if !s.dryRun.batchOpen {
+			s.sendDryRunBatch(dryRunBeginStatement)
+			s.dryRun.batchOpen = true
+		}
+
+		s.upstreamFrontend.Send(msg)
+	case *pgproto3.Sync:
+		s.upstreamFrontend.Send(msg)
+		s.dryRun.replies = append(s.dryRun.replies, false)
+
+		if s.dryRun.batchOpen {
+			s.sendDryRunBatch(dryRunRollbackStatement)
+			s.dryRun.batchOpen = false
+		}
+	default:
+		s.upstreamFrontend.Send(msg)
+	}
+}
+
+// sendDryRunBatch queues a batch executing one of the dry_run statements.
+func (s *Session) sendDryRunBatch(stmt string) {
+	s.upstreamFrontend.Send(&pgproto3.Bind{PreparedStatement: stmt})
+	s.upstreamFrontend.Send(&pgproto3.Execute{})
+	s.upstreamFrontend.Send(&pgproto3.Sync{})
+	s.dryRun.replies = append(s.dryRun.replies, true)
+}
+
+// filterDryRunReply hides the replies to the proxy's dry_run batches from the
+// client, and reports the client's own ReadyForQuery as idle: the transaction
+// its commands ran in is the proxy's, and is over by the time the client
+// sends more. Asynchronous messages are always forwarded. It reports whether
+// msg is to be forwarded; the caller holds queryMu.
+func (s *Session) filterDryRunReply(msg pgproto3.BackendMessage) bool {
+	if len(s.dryRun.replies) == 0 {
+		return true
+	}
+
+	proxyBatch := s.dryRun.replies[0]
+
+	switch m := msg.(type) {
+	case *pgproto3.ReadyForQuery:
+		s.dryRun.replies = s.dryRun.replies[1:]
+
+		if !proxyBatch {
+			m.TxStatus = 'I'
+		}
+	case *pgproto3.ParameterStatus, *pgproto3.NotificationResponse:
+		return true
+	case *pgproto3.ErrorResponse:
+		if proxyBatch {
+			s.logger.WarnContext(s.ctx, "dry-run transaction statement failed", slog.String("error", m.Message))
+		}
+	}
+
+	return !proxyBatch
+}
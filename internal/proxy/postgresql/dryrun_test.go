@@ -0,0 +1,275 @@
+package postgresql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestStatementHeads(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{sql: "begin", want: []string{"BEGIN"}},
+		{sql: "UPDATE users SET a = 1; commit;", want: []string{"UPDATE USERS", "COMMIT"}},
+		{sql: "prepare /* c */ transaction 'x'", want: []string{"PREPARE TRANSACTION"}},
+		{sql: "SELECT 'a; COMMIT' -- ; ROLLBACK", want: []string{"SELECT"}},
+		{sql: "SELECT $$;END$$; SHOW x", want: []string{"SELECT", "SHOW X"}},
+		{sql: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := statementHeads(tt.sql); !slices.Equal(got, tt.want) {
+			t.Errorf("statementHeads(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestHeuristicStatementClass_DryRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                                                      string
+		sql                                                       string
+		transactionControl, executePrepared, copyIn, settingsOnly bool
+	}{
+		{name: "rollback", sql: "DELETE FROM users; ROLLBACK", transactionControl: true},
+		{name: "prepare transaction", sql: "PREPARE TRANSACTION 'x'", transactionControl: true},
+		{name: "sql prepare", sql: "PREPARE q AS SELECT 1"},
+		{name: "execute", sql: "EXECUTE q", executePrepared: true},
+		{name: "deallocate", sql: "DEALLOCATE ALL", executePrepared: true},
+		{name: "copy in", sql: "COPY users FROM STDIN", copyIn: true},
+		{name: "settings", sql: "SET work_mem = '64MB'; SHOW work_mem", settingsOnly: true},
+		{name: "setting then select", sql: "SET work_mem = '64MB'; SELECT 1"},
+		{name: "keyword in a string", sql: "SELECT 'COMMIT'"},
+	}
+
+	for _, tt := range tests {
+		got := heuristicStatementClass(tt.sql)
+		if got.transactionControl != tt.transactionControl || got.executePrepared != tt.executePrepared ||
+			got.copyIn != tt.copyIn || got.settingsOnly != tt.settingsOnly {
+			t.Errorf("%s: heuristicStatementClass(%q) = %+v", tt.name, tt.sql, got)
+		}
+	}
+}
+
+func TestCheckStatement_DryRun(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSessionWithControls([]string{store.ControlDryRun})
+
+	for _, sql := range []string{"BEGIN", "UPDATE users SET a = 1; COMMIT", "SAVEPOINT a", "COPY users FROM STDIN", "EXECUTE q"} {
+		if _, err := s.checkStatement(sql); !errors.Is(err, ErrDryRunNotPermitted) {
+			t.Errorf("checkStatement(%q) error = %v, want %v", sql, err, ErrDryRunNotPermitted)
+		}
+	}
+
+	for _, sql := range []string{"UPDATE users SET a = 1 RETURNING *", "CREATE TABLE t (id int)", "COPY users TO STDOUT"} {
+		if _, err := s.checkStatement(sql); err != nil {
+			t.Errorf("checkStatement(%q) error = %v", sql, err)
+		}
+	}
+
+	if _, err := newTestSession("write").checkStatement("BEGIN"); err != nil {
+		t.Errorf("checkStatement(BEGIN) without dry_run error = %v", err)
+	}
+}
+
+func TestCheckDryRunStatementName(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSessionWithControls([]string{store.ControlDryRun})
+	s.dryRun.enabled = true
+
+	refused := []pgproto3.FrontendMessage{
+		&pgproto3.Parse{Name: dryRunBeginStatement, Query: "SELECT 1"},
+		&pgproto3.Bind{PreparedStatement: dryRunRollbackStatement},
+		&pgproto3.Close{ObjectType: 'S', Name: dryRunRollbackStatement},
+	}
+	for _, msg := range refused {
+		if err := s.checkDryRunStatementName(msg); !errors.Is(err, ErrDryRunNotPermitted) {
+			t.Errorf("checkDryRunStatementName(%+v) error = %v, want %v", msg, err, ErrDryRunNotPermitted)
+		}
+	}
+
+	allowed := []pgproto3.FrontendMessage{
+		&pgproto3.Parse{Name: "stmt", Query: "SELECT 1"},
+		&pgproto3.Bind{DestinationPortal: dryRunRollbackStatement, PreparedStatement: "stmt"},
+		&pgproto3.Close{ObjectType: 'P', Name: dryRunRollbackStatement},
+	}
+	for _, msg := range allowed {
+		if err := s.checkDryRunStatementName(msg); err != nil {
+			t.Errorf("checkDryRunStatementName(%+v) error = %v", msg, err)
+		}
+	}
+}
+
+// newDryRunSession returns a dry_run session whose upstream messages are
+// written to the returned buffer.
+func newDryRunSession() (*Session, *bytes.Buffer) {
+	var upstream bytes.Buffer
+
+	s := newTestSessionWithControls([]string{store.ControlDryRun})
+	s.dryRun.enabled = true
+	s.upstreamFrontend = pgproto3.NewFrontend(bytes.NewReader(nil), &upstream)
+	s.logger = slog.New(slog.DiscardHandler)
+	s.ctx = context.Background()
+
+	return s, &upstream
+}
+
+// sentUpstream decodes the messages a session sent upstream, as their type
+// followed by the statement bound or the query run.
+func sentUpstream(t *testing.T, s *Session, upstream *bytes.Buffer) []string {
+	t.Helper()
+
+	if err := s.upstreamFrontend.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	backend := pgproto3.NewBackend(upstream, io.Discard)
+
+	var sent []string
+
+	for {
+		msg, err := backend.Receive()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return sent
+		}
+
+		if err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.Bind:
+			sent = append(sent, "Bind "+m.PreparedStatement)
+		case *pgproto3.Query:
+			sent = append(sent, "Query "+m.String)
+		default:
+			sent = append(sent, typeName(msg))
+		}
+	}
+}
+
+// typeName names the other messages of sentUpstream.
+func typeName(msg pgproto3.FrontendMessage) string {
+	switch msg.(type) {
+	case *pgproto3.Parse:
+		return "Parse"
+	case *pgproto3.Execute:
+		return "Execute"
+	case *pgproto3.Sync:
+		return "Sync"
+	default:
+		return "other"
+	}
+}
+
+func TestSendUpstream_DryRunQuery(t *testing.T) {
+	t.Parallel()
+
+	s, upstream := newDryRunSession()
+
+	for _, sql := range []string{"UPDATE users SET a = 1 RETURNING id", "SET work_mem = '64MB'"} {
+		if err := s.interceptClientMessage(&pgproto3.Query{String: sql}); err != nil {
+			t.Fatalf("interceptClientMessage(%q) error = %v", sql, err)
+		}
+
+		s.sendUpstream(&pgproto3.Query{String: sql})
+	}
+
+	want := []string{
+		"Bind " + dryRunBeginStatement, "Execute", "Sync",
+		"Query UPDATE users SET a = 1 RETURNING id",
+		"Bind " + dryRunRollbackStatement, "Execute", "Sync",
+		"Query SET work_mem = '64MB'",
+	}
+	if got := sentUpstream(t, s, upstream); !slices.Equal(got, want) {
+		t.Errorf("sent upstream %q, want %q", got, want)
+	}
+
+	if want := []bool{true, false, true, false}; !slices.Equal(s.dryRun.replies, want) {
+		t.Errorf("expected replies = %v, want %v", s.dryRun.replies, want)
+	}
+}
+
+func TestSendUpstream_DryRunBatch(t *testing.T) {
+	t.Parallel()
+
+	s, upstream := newDryRunSession()
+
+	for _, msg := range []pgproto3.FrontendMessage{
+		&pgproto3.Parse{Name: "s", Query: "DELETE FROM users"},
+		&pgproto3.Bind{PreparedStatement: "s"},
+		&pgproto3.Execute{},
+		&pgproto3.Sync{},
+	} {
+		if err := s.interceptClientMessage(msg); err != nil {
+			t.Fatalf("interceptClientMessage(%T) error = %v", msg, err)
+		}
+
+		s.sendUpstream(msg)
+	}
+
+	want := []string{
+		"Bind " + dryRunBeginStatement, "Execute", "Sync",
+		"Parse", "Bind s", "Execute", "Sync",
+		"Bind " + dryRunRollbackStatement, "Execute", "Sync",
+	}
+	if got := sentUpstream(t, s, upstream); !slices.Equal(got, want) {
+		t.Errorf("sent upstream %q, want %q", got, want)
+	}
+
+	if len(s.extendedState.pendingQueries) != 1 || !s.extendedState.pendingQueries[0].dryRun {
+		t.Error("the executed statement was not queued as a dry-run query")
+	}
+}
+
+func TestFilterDryRunReply(t *testing.T) {
+	t.Parallel()
+
+	s, _ := newDryRunSession()
+	s.dryRun.replies = []bool{true, false, true}
+
+	replies := []struct {
+		msg     pgproto3.BackendMessage
+		forward bool
+	}{
+		{&pgproto3.BindComplete{}, false},
+		{&pgproto3.CommandComplete{CommandTag: []byte("BEGIN")}, false},
+		{&pgproto3.ReadyForQuery{TxStatus: 'T'}, false},
+		{&pgproto3.CommandComplete{CommandTag: []byte("UPDATE 3")}, true},
+		{&pgproto3.ReadyForQuery{TxStatus: 'T'}, true},
+		{&pgproto3.BindComplete{}, false},
+		{&pgproto3.CommandComplete{CommandTag: []byte("ROLLBACK")}, false},
+		{&pgproto3.ParameterStatus{Name: "application_name", Value: "psql"}, true},
+		{&pgproto3.ReadyForQuery{TxStatus: 'I'}, false},
+		{&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}, true},
+	}
+
+	for i, reply := range replies {
+		if got := s.filterDryRunReply(reply.msg); got != reply.forward {
+			t.Errorf("reply %d (%T): filterDryRunReply() = %v, want %v", i, reply.msg, got, reply.forward)
+		}
+	}
+
+	if status := replies[4].msg.(*pgproto3.ReadyForQuery).TxStatus; status != 'I' {
+		t.Errorf("client ReadyForQuery status = %c, want I", status)
+	}
+
+	if len(s.dryRun.replies) != 0 {
+		t.Errorf("expected replies left = %v", s.dryRun.replies)
+	}
+}
@@ -5,18 +5,55 @@ import "errors"
 // Authentication and authorization errors.
 var (
 	ErrExpectedStartupMessage   = errors.New("expected StartupMessage")
+	ErrExpectedPasswordMessage  = errors.New("expected PasswordMessage")
+	ErrInvalidMessageLength     = errors.New("invalid message length")
 	ErrMissingCredentials       = errors.New("missing username or database")
 	ErrInvalidPassword          = errors.New("invalid password")
 	ErrQueryLimitExceeded       = errors.New("query limit exceeded")
 	ErrDataLimitExceeded        = errors.New("data transfer limit exceeded")
+	ErrRowLimitExceeded         = errors.New("returned rows limit exceeded")
 	ErrWriteNotPermitted        = errors.New("write operations not permitted with read-only access")
 	ErrPasswordChangeNotAllowed = errors.New("password modification is not allowed through the proxy")
 	ErrReadOnlyBypassAttempt    = errors.New("attempt to disable read-only mode is not permitted: " +
 		"your access grant is read-only and cannot be changed for this session")
 	ErrDDLNotPermitted  = errors.New("DDL operations not permitted: your access grant blocks schema modifications")
 	ErrCopyNotPermitted = errors.New("COPY not permitted: your access grant blocks COPY commands")
+	// ErrCopyOutNotPermitted is returned for COPY ... TO under block_copy_out
+	// or mask_pii (exported rows cannot be masked).
+	ErrCopyOutNotPermitted = errors.New("COPY TO not permitted: your access grant blocks data exports")
+	// ErrTableNotPermitted is returned, wrapped in a tableNotPermittedError,
+	// for statements touching a table outside the grant's allowlist.
+	ErrTableNotPermitted = errors.New("table not permitted by access grant")
+	// ErrTableAllowlistBypass is returned under a table allowlist for
+	// statements whose tables cannot be checked (DO blocks) or that change
+	// how unqualified names resolve (search_path).
+	ErrTableAllowlistBypass = errors.New("statement not permitted: your access grant restricts the tables you can use")
+	// ErrDryRunNotPermitted is returned under dry_run for statements that
+	// would escape, end or break the transaction the proxy rolls back.
+	ErrDryRunNotPermitted = errors.New("statement not permitted: your access grant rolls back every statement")
+	// ErrStatementDenied is returned, wrapped in a statementDeniedError, for
+	// a write statement an administrator denied under
+	// write_requires_approval.
+	ErrStatementDenied = errors.New("statement denied by an administrator")
+	// ErrApprovalTimeout is returned for a write statement nobody approved
+	// before the approval timeout.
+	ErrApprovalTimeout = errors.New("statement not approved in time")
+	// ErrApprovalCanceled is returned for a write statement the client
+	// canceled while it awaited approval.
+	ErrApprovalCanceled = errors.New("statement canceled while awaiting approval")
+
+	// ErrDatabaseNotServed is returned when the client asks for a database
+	// outside the databases of the listener it connected to.
+	ErrDatabaseNotServed = errors.New("database not served by this listener")
+	// ErrClientNotAllowed is returned when the client connects from outside
+	// the networks its access grant allows.
+	ErrClientNotAllowed = errors.New("client network not allowed by access grant")
+
+	ErrReplicationNotAllowed  = errors.New("replication connections not allowed by access grant")
+	ErrInvalidReplicationMode = errors.New("invalid replication startup parameter")
 
 	ErrUpstreamAuthFailed  = errors.New("upstream authentication failed")
+	ErrClientDisconnected  = errors.New("client disconnected")
 	ErrAPIKeyOwnerMismatch = errors.New("API key does not belong to user")
 	ErrAPIKeyVerifyFailed  = errors.New("API key verification failed")
 
@@ -38,4 +75,11 @@ var (
 	ErrSCRAMServerSignature      = errors.New("SCRAM server signature mismatch")
 	ErrSCRAMUnexpectedMessage    = errors.New("unexpected SASL message from upstream")
 	ErrSCRAMMalformedMessage     = errors.New("malformed SCRAM message from upstream")
+
+	// Client SCRAM/SASL errors raised when a client authenticates to the
+	// proxy with SCRAM-SHA-256(-PLUS).
+	ErrSCRAMUnsupportedMechanism = errors.New("client chose a SASL mechanism the proxy did not offer")
+	ErrSCRAMClientMessage        = errors.New("malformed SCRAM message from client")
+	ErrSCRAMChannelBinding       = errors.New("SCRAM channel binding mismatch")
+	ErrSCRAMClientProof          = errors.New("SCRAM client proof matches no credential")
 )
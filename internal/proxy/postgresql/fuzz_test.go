@@ -0,0 +1,224 @@
+package postgresql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// The fuzz targets below cover the code that parses client bytes by hand.
+// `go test` runs their seed corpus; fuzz one with e.g.
+//
+//	go test -run '^$' -fuzz FuzzReceiveStartupMessage ./internal/proxy/postgresql/
+
+// fuzzSession returns a session reading data as the client's bytes.
+func fuzzSession(data []byte) *Session {
+	return &Session{
+		ctx:          context.Background(),
+		logger:       slog.New(slog.DiscardHandler),
+		clientReader: bufio.NewReader(bytes.NewReader(data)),
+		queryStorage: config.QueryStorageConfig{MaxResultRows: 100},
+	}
+}
+
+// frame prefixes body with its 4-byte length (length included), optionally
+// after a message type byte.
+func frame(msgType byte, body []byte) []byte {
+	var buf []byte
+	if msgType != 0 {
+		buf = append(buf, msgType)
+	}
+
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(body)+4))
+
+	return append(buf, body...)
+}
+
+func FuzzReceiveStartupMessage(f *testing.F) {
+	startup, err := (&pgproto3.StartupMessage{
+		ProtocolVersion: pgproto3.ProtocolVersionNumber,
+		Parameters:      map[string]string{"user": "alice", "database": "app"},
+	}).Encode(nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(startup)
+	f.Add([]byte{0, 0, 0, 0})
+	f.Add([]byte{0, 0, 0, 3})
+	f.Add([]byte{0x7f, 0xff, 0xff, 0xff})
+	f.Add(frame(0, []byte{0, 3, 0, 0, 'u', 's', 'e', 'r'}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := fuzzSession(data).receiveStartupMessage()
+		if err == nil && msg == nil {
+			t.Fatal("nil message without error")
+		}
+	})
+}
+
+func FuzzReceivePasswordMessage(f *testing.F) {
+	f.Add(frame('p', []byte("secret\x00")))
+	f.Add(frame('p', nil))
+	f.Add([]byte{'p', 0, 0, 0, 0})
+	f.Add([]byte{'p', 0xff, 0xff, 0xff, 0xff})
+	f.Add(frame('Q', []byte("SELECT 1\x00")))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := fuzzSession(data).receivePasswordMessage()
+		if err != nil {
+			return
+		}
+
+		if len(msg.Password)+5 > len(data) {
+			t.Fatalf("password of %d bytes read from %d input bytes", len(msg.Password), len(data))
+		}
+	})
+}
+
+func TestReceiveMessagesRejectBadLengths(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		receive func(*Session) error
+		data    []byte
+		want    error
+	}{
+		{"startup too short", receiveStartup, []byte{0, 0, 0, 3}, ErrInvalidMessageLength},
+		{"startup too long", receiveStartup, []byte{0x7f, 0xff, 0xff, 0xff}, ErrInvalidMessageLength},
+		{"password empty", receivePassword, []byte{'p', 0, 0, 0, 4}, ErrInvalidMessageLength},
+		{"password negative", receivePassword, []byte{'p', 0, 0, 0, 0}, ErrInvalidMessageLength},
+		{"password too long", receivePassword, []byte{'p', 0x7f, 0xff, 0xff, 0xff}, ErrInvalidMessageLength},
+		{"not a password", receivePassword, frame('Q', []byte("SELECT 1\x00")), ErrExpectedPasswordMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.receive(fuzzSession(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func receiveStartup(s *Session) error {
+	_, err := s.receiveStartupMessage()
+	return err
+}
+
+func receivePassword(s *Session) error {
+	_, err := s.receivePasswordMessage()
+	return err
+}
+
+func FuzzParseCopyDataToRows(f *testing.F) {
+	f.Add([]byte("1\talice\n2\t\\N\n\\.\n"), "COPY users (id, name) TO STDOUT")
+	f.Add([]byte("a\\tb\\\\c\\n\n"), "COPY t FROM STDIN")
+	f.Add([]byte("\t\t\t\n"), "COPY t (\"a,b\", c) TO STDOUT")
+	f.Add([]byte("\\"), "COPY (SELECT 1) TO STDOUT")
+
+	f.Fuzz(func(t *testing.T, data []byte, sql string) {
+		s := fuzzSession(nil)
+		s.copyState = &copyState{
+			direction:   "out",
+			columnNames: parseCopyColumnNames(sql),
+			dataChunks:  [][]byte{data},
+		}
+
+		for _, row := range s.parseCopyDataToRows() {
+			if !json.Valid(row.RowData) {
+				t.Fatalf("invalid row JSON: %q", row.RowData)
+			}
+		}
+	})
+}
+
+func FuzzParseCopyBinary(f *testing.F) {
+	f.Add(copyBinary([][]byte{{0, 0, 0, 1}, []byte("alice")}, [][]byte{nil, {1}}), []byte{0, 0, 0, 23, 0, 0, 0, 25})
+	f.Add([]byte(copyBinarySignature+"\x00\x00\x00\x00\xff\xff\xff\xff"), []byte{})
+	f.Add([]byte("PGCOPY"), []byte{0, 0, 0x0b, 0x86})
+
+	f.Fuzz(func(t *testing.T, data, oidBytes []byte) {
+		oids := make([]uint32, len(oidBytes)/4)
+		for i := range oids {
+			oids[i] = binary.BigEndian.Uint32(oidBytes[i*4:])
+		}
+
+		s := fuzzSession(nil)
+		s.copyState = &copyState{
+			direction:  "out",
+			format:     1,
+			columnOIDs: oids,
+			dataChunks: [][]byte{data},
+		}
+
+		for _, row := range s.parseCopyDataToRows() {
+			if !json.Valid(row.RowData) {
+				t.Fatalf("invalid row JSON: %q", row.RowData)
+			}
+		}
+	})
+}
+
+func FuzzBindParameters(f *testing.F) {
+	bind, err := (&pgproto3.Bind{
+		ParameterFormatCodes: []int16{1},
+		Parameters:           [][]byte{{0, 0, 0, 42}, nil, []byte("text")},
+	}).Encode(nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	// Decode takes the message body, without type byte and length.
+	f.Add(bind[5:], []byte{0, 0, 0, 23, 0, 0, 0, 25})
+	f.Add([]byte{0, 0, 0, 1, 0, 1, 0, 1, 0, 0, 0, 8, 1, 2, 3, 4, 5, 6, 7, 8, 0, 0}, []byte{0, 0, 0, 20})
+	f.Add([]byte{0, 0, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 0, 0}, []byte{})
+
+	f.Fuzz(func(t *testing.T, body, oidBytes []byte) {
+		msg := &pgproto3.Bind{}
+		if err := msg.Decode(body); err != nil {
+			return
+		}
+
+		oids := make([]uint32, len(oidBytes)/4)
+		for i := range oids {
+			oids[i] = binary.BigEndian.Uint32(oidBytes[i*4:])
+		}
+
+		params := bindParameters(msg, oids)
+		if params == nil {
+			if len(msg.Parameters) != 0 {
+				t.Fatalf("nil parameters for %d bound values", len(msg.Parameters))
+			}
+
+			return
+		}
+
+		if len(params.Values) != len(msg.Parameters) {
+			t.Fatalf("%d values for %d bound parameters", len(params.Values), len(msg.Parameters))
+		}
+	})
+}
+
+func FuzzDecodeBinaryParameter(f *testing.F) {
+	for _, oid := range []uint32{16, 17, 20, 21, 23, 25, 700, 701, 1043, 0} {
+		f.Add([]byte{0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}, oid)
+		f.Add([]byte{1}, oid)
+	}
+
+	f.Fuzz(func(_ *testing.T, data []byte, oid uint32) {
+		_ = decodeBinaryParameter(data, oid)
+	})
+}
@@ -302,7 +302,7 @@ func setupFixtureWith(ctx context.Context, t *testing.T, opts fixtureOpts) *fixt
 		}
 	}
 
-	proxy, err := NewServer(dataStore, encKey, queryStorage, dumpCfg, nil, config.PGConfig{}, slog.Default())
+	proxy, err := NewServer(dataStore, encKey, queryStorage, dumpCfg, nil, config.PGConfig{}, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
 	require.NoError(t, err)
 
 	go func() { _ = proxy.Start("127.0.0.1:0") }()
@@ -423,6 +423,39 @@ func TestIntegration_ProxyAuth_APIKey(t *testing.T) {
 	assert.Equal(t, 1, got)
 }
 
+// TestIntegration_ProxyAuth_ProxyCredential connects once with a one-time
+// proxy credential, as the grant's user, and checks it can't connect again.
+func TestIntegration_ProxyAuth_ProxyCredential(t *testing.T) {
+	ctx := context.Background()
+	f := setupFixture(ctx, t)
+
+	grants, err := f.store.ListGrants(ctx, store.GrantFilter{UserID: &f.user.UID, ActiveOnly: true})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+
+	cred, secret, err := f.store.CreateProxyCredential(ctx, grants[0].UID, "jane@contractor.example", f.user.UID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	_, err = f.connect(ctx, cred.Username, "wrongsecret")
+	require.Error(t, err, "wrong secret must fail")
+
+	conn, err := f.connect(ctx, cred.Username, secret)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close(context.Background()) }()
+
+	var got int
+	require.NoError(t, conn.QueryRow(ctx, "SELECT 1").Scan(&got))
+	assert.Equal(t, 1, got)
+
+	require.Eventually(t, func() bool {
+		used, err := f.store.GetProxyCredential(ctx, cred.UID)
+		return err == nil && used.ConnectionID != nil
+	}, 5*time.Second, 50*time.Millisecond, "proxy credential never linked to its connection")
+
+	_, err = f.connect(ctx, cred.Username, secret)
+	require.Error(t, err, "a proxy credential opens a single connection")
+}
+
 // TestIntegration_WrongPassword verifies a bad password is refused.
 func TestIntegration_WrongPassword(t *testing.T) {
 	ctx := context.Background()
@@ -1,6 +1,7 @@
 package postgresql
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
@@ -17,10 +18,13 @@ import (
 	"github.com/jackc/pgx/v5/pgproto3"
 
 	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/spill"
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
-// readOnlyBypassPatterns contains regex patterns that detect attempts to disable read-only mode.
+// readOnlyBypassPatterns contains regex patterns that detect attempts to disable read-only mode,
+// used when the SQL parser is unavailable (see classifySQL).
 var readOnlyBypassPatterns = []*regexp.Regexp{
 	// SET [SESSION] default_transaction_read_only (=|TO) (off|false|0)
 	regexp.MustCompile(`(?i)\bSET\s+(?:SESSION\s+)?default_transaction_read_only\s*(?:=|TO)\s*(?:off|false|0)\b`),
@@ -35,8 +39,12 @@ var readOnlyBypassPatterns = []*regexp.Regexp{
 	regexp.MustCompile(`(?i)\bSET\s+ROLE\b`),
 }
 
+// readOnlyModePattern matches the READ ONLY mode of a transaction.
+var readOnlyModePattern = regexp.MustCompile(`(?i)\bREAD\s+ONLY\b`)
+
 // handleQuery intercepts and logs queries - returns nil if query was handled.
 func (s *Session) handleQuery(query *pgproto3.Query) error {
+	start := time.Now()
 	sqlText := query.String
 
 	// Check quotas before executing query
@@ -44,68 +52,168 @@ func (s *Session) handleQuery(query *pgproto3.Query) error {
 		return err
 	}
 
-	// Always block password changes regardless of controls
-	if isPasswordChangeQuery(sqlText) {
-		return ErrPasswordChangeNotAllowed
+	// Each statement is checked on its own, so that none hides behind
+	// another, and logged as its own query.
+	statements := splitStatements(sqlText)
+	class := statementClass{settingsOnly: true}
+
+	for _, stmt := range statements {
+		stmtClass, err := s.checkStatement(stmt)
+		if err != nil {
+			return err
+		}
+
+		class = class.union(stmtClass)
 	}
 
-	// Control: read_only bypass prevention
-	if s.grant.IsReadOnly() && isReadOnlyBypassAttempt(sqlText) {
-		return ErrReadOnlyBypassAttempt
+	// Start tracking query for logging
+	s.currentQuery = &pendingQuery{
+		sql:          statements[0],
+		next:         statements[1:],
+		startTime:    start,
+		timer:        shared.StartQueryTimer(start),
+		capturedRows: make([]store.QueryRow, 0), // Initialize for capture
+		// Settings are not rolled back under dry_run, so that they last.
+		dryRun:         s.dryRun.enabled && !class.settingsOnly,
+		awaitsApproval: s.grant.WriteRequiresApproval() && class.write,
 	}
 
-	// Control: read_only write prevention (defense-in-depth)
-	if s.grant.IsReadOnly() && isWriteQuery(sqlText) {
-		return ErrWriteNotPermitted
+	if len(statements) > 1 {
+		s.currentQuery.batch = sqlText
 	}
 
-	// Control: block_ddl (only check if not already read_only, since read_only blocks DDL at PG level)
-	if !s.grant.IsReadOnly() && s.grant.ShouldBlockDDL() && isDDLQuery(sqlText) {
-		return ErrDDLNotPermitted
+	// The statements are logged as the client sent them.
+	if s.forcesReadOnlyTransactions() {
+		if forwarded, changed := readOnlyTransactions(statements); changed {
+			query.String = forwarded
+		}
 	}
 
-	// Control: block_copy
-	if s.grant.ShouldBlockCopy() && isCopyQuery(sqlText) {
-		return ErrCopyNotPermitted
+	return nil
+}
+
+// forcesReadOnlyTransactions reports whether the transactions the client
+// begins are made read-only. Besides the session's
+// default_transaction_read_only, which a statement the controls miss could
+// lift, read_only grants get BEGIN READ ONLY.
+func (s *Session) forcesReadOnlyTransactions() bool {
+	return s.grant.IsReadOnly() && s.replication != replicationPhysical
+}
+
+// readOnlyTransactions appends READ ONLY to the statements beginning a
+// transaction (BEGIN and START TRANSACTION) which do not have it, after a line
+// break in case they end with a comment. It returns the statements joined
+// back, and whether any changed.
+func readOnlyTransactions(statements []string) (string, bool) {
+	changed := false
+	forwarded := slices.Clone(statements)
+
+	for i, stmt := range forwarded {
+		heads := statementHeads(stmt)
+		if len(heads) == 0 || readOnlyModePattern.MatchString(stmt) {
+			continue
+		}
+
+		if first, _, _ := strings.Cut(heads[0], " "); first == "BEGIN" || heads[0] == "START TRANSACTION" {
+			forwarded[i] = stmt + "\nREAD ONLY"
+			changed = true
+		}
 	}
 
-	// Start tracking query for logging
+	return strings.Join(forwarded, ";\n"), changed
+}
+
+// nextStatement logs the statement of a multi-statement Query upstream just
+// completed, and starts tracking the next one, timed from now: upstream runs
+// it as soon as it is done with the previous one. The caller holds queryMu.
+func (s *Session) nextStatement(outcome *queryOutcome) {
+	done := s.currentQuery
+
+	s.logQuery(outcome.rowsAffected, outcome.queryError, s.takeBytesTransferred())
+	s.copyState = nil
+	*outcome = queryOutcome{}
+
+	now := time.Now()
+
 	s.currentQuery = &pendingQuery{
-		sql:          sqlText,
-		startTime:    time.Now(),
-		capturedRows: make([]store.QueryRow, 0), // Initialize for capture
+		sql:          done.next[0],
+		next:         done.next[1:],
+		batch:        done.batch,
+		startTime:    now,
+		timer:        shared.StartQueryTimer(now),
+		capturedRows: make([]store.QueryRow, 0),
+		dryRun:       done.dryRun,
 	}
+	s.currentQuery.timer.Forwarded(now)
+}
 
-	return nil
+// takeBytesTransferred returns the client-side bytes exchanged since the
+// previous query was logged (or the session started), and starts counting
+// the next query's. It runs on the upstream→client goroutine.
+func (s *Session) takeBytesTransferred() int64 {
+	// Wire-level diff: captures the query text the client sent, the
+	// response framing, error packets, and pre-first-query auth bytes —
+	// everything a row-summed counter would miss.
+	total := s.bytesFromClient.Load() + s.bytesToClient.Load()
+	bytesTransferred := total - s.lastBytesSnapshot
+	s.lastBytesSnapshot = total
+
+	return bytesTransferred
 }
 
-// handleParse handles Parse messages (prepared statement creation) for Extended Query Protocol.
-func (s *Session) handleParse(msg *pgproto3.Parse) error {
-	sqlText := msg.Query
+// checkStatement applies the grant controls to the SQL of a Query or Parse
+// message, which may hold several statements, and returns its class.
+func (s *Session) checkStatement(sqlText string) (statementClass, error) {
+	class := classifySQL(sqlText)
 
 	// Always block password changes regardless of controls
-	if isPasswordChangeQuery(sqlText) {
-		return ErrPasswordChangeNotAllowed
+	if class.passwordChange {
+		return class, ErrPasswordChangeNotAllowed
 	}
 
 	// Control: read_only bypass prevention
-	if s.grant.IsReadOnly() && isReadOnlyBypassAttempt(sqlText) {
-		return ErrReadOnlyBypassAttempt
+	if s.grant.IsReadOnly() && class.readOnlyBypass {
+		return class, ErrReadOnlyBypassAttempt
 	}
 
-	// Control: read_only write prevention at Parse time (defense-in-depth)
-	if s.grant.IsReadOnly() && isWriteQuery(sqlText) {
-		return ErrWriteNotPermitted
+	// Control: read_only write prevention (defense-in-depth)
+	if s.grant.IsReadOnly() && class.write {
+		return class, ErrWriteNotPermitted
 	}
 
 	// Control: block_ddl (only check if not already read_only, since read_only blocks DDL at PG level)
-	if !s.grant.IsReadOnly() && s.grant.ShouldBlockDDL() && isDDLQuery(sqlText) {
-		return ErrDDLNotPermitted
+	if !s.grant.IsReadOnly() && s.grant.ShouldBlockDDL() && class.ddl {
+		return class, ErrDDLNotPermitted
 	}
 
 	// Control: block_copy
-	if s.grant.ShouldBlockCopy() && isCopyQuery(sqlText) {
-		return ErrCopyNotPermitted
+	if s.grant.ShouldBlockCopy() && class.copy {
+		return class, ErrCopyNotPermitted
+	}
+
+	// Control: block_copy_out. mask_pii implies it: COPY rows are not masked.
+	if (s.grant.ShouldBlockCopyOut() || s.grant.MasksPII()) && class.copyOut {
+		return class, ErrCopyOutNotPermitted
+	}
+
+	// Control: dry_run. Transaction control would end the proxy's
+	// transaction, EXECUTE and DEALLOCATE could run or drop the statements
+	// ending it, and COPY FROM data would reach upstream after it ended.
+	if s.grant.DryRun() && (class.transactionControl || class.executePrepared || class.copyIn) {
+		return class, ErrDryRunNotPermitted
+	}
+
+	// Table allowlist
+	return class, s.checkAllowedTables(sqlText)
+}
+
+// handleParse handles Parse messages (prepared statement creation) for Extended Query Protocol.
+func (s *Session) handleParse(msg *pgproto3.Parse) error {
+	sqlText := msg.Query
+
+	class, err := s.checkStatement(sqlText)
+	if err != nil {
+		return err
 	}
 
 	// Store the prepared statement with type OIDs. The OID slice is copied
@@ -113,10 +221,17 @@ func (s *Session) handleParse(msg *pgproto3.Parse) error {
 	s.extendedState.mu.Lock()
 	s.extendedState.preparedStatements[msg.Name] = &preparedStatement{
 		sql:      sqlText,
+		write:    class.write,
 		typeOIDs: slices.Clone(msg.ParameterOIDs),
 	}
 	s.extendedState.mu.Unlock()
 
+	if s.forcesReadOnlyTransactions() {
+		if forwarded, changed := readOnlyTransactions(splitStatements(sqlText)); changed {
+			msg.Query = forwarded
+		}
+	}
+
 	return nil
 }
 
@@ -151,6 +266,9 @@ func (s *Session) handleParameterDescription(msg *pgproto3.ParameterDescription)
 	s.extendedState.pendingDescribes = s.extendedState.pendingDescribes[1:]
 
 	stmt := s.extendedState.preparedStatements[name]
+
+	// The RowDescription or NoData that follows describes the statement.
+	s.results.describingStmt, s.results.describedStmt = true, stmt
 	if stmt == nil {
 		return
 	}
@@ -176,37 +294,7 @@ func (s *Session) handleBind(msg *pgproto3.Bind) {
 	}
 	s.extendedState.mu.Unlock()
 
-	// Build parameters structure
-	var params *store.QueryParameters
-	if len(msg.Parameters) > 0 {
-		params = &store.QueryParameters{
-			Values:      make([]string, len(msg.Parameters)),
-			Raw:         make([]string, len(msg.Parameters)),
-			FormatCodes: make([]int16, len(msg.Parameters)),
-			TypeOIDs:    typeOIDs,
-		}
-
-		for i, param := range msg.Parameters {
-			// Determine format code (see PostgreSQL protocol spec)
-			formatCode := int16(0)
-			if len(msg.ParameterFormatCodes) == 1 {
-				formatCode = msg.ParameterFormatCodes[0] // All params same format
-			} else if i < len(msg.ParameterFormatCodes) {
-				formatCode = msg.ParameterFormatCodes[i]
-			}
-
-			params.FormatCodes[i] = formatCode
-			params.Raw[i] = base64.StdEncoding.EncodeToString(param)
-
-			if formatCode == 0 {
-				// Text format - value is directly usable
-				params.Values[i] = string(param)
-			} else {
-				// Binary format - decode based on type OID
-				params.Values[i] = decodeBinaryParameter(param, getTypeOID(typeOIDs, i))
-			}
-		}
-	}
+	params := bindParameters(msg, typeOIDs)
 
 	s.extendedState.portals[msg.DestinationPortal] = &portalState{
 		stmtName:   msg.PreparedStatement,
@@ -214,8 +302,48 @@ func (s *Session) handleBind(msg *pgproto3.Bind) {
 	}
 }
 
+// bindParameters decodes the parameter values of a Bind message, typed by
+// the statement's parameter OIDs.
+func bindParameters(msg *pgproto3.Bind, typeOIDs []uint32) *store.QueryParameters {
+	if len(msg.Parameters) == 0 {
+		return nil
+	}
+
+	params := &store.QueryParameters{
+		Values:      make([]string, len(msg.Parameters)),
+		Raw:         make([]string, len(msg.Parameters)),
+		FormatCodes: make([]int16, len(msg.Parameters)),
+		TypeOIDs:    typeOIDs,
+	}
+
+	for i, param := range msg.Parameters {
+		// Determine format code (see PostgreSQL protocol spec)
+		formatCode := int16(0)
+		if len(msg.ParameterFormatCodes) == 1 {
+			formatCode = msg.ParameterFormatCodes[0] // All params same format
+		} else if i < len(msg.ParameterFormatCodes) {
+			formatCode = msg.ParameterFormatCodes[i]
+		}
+
+		params.FormatCodes[i] = formatCode
+		params.Raw[i] = base64.StdEncoding.EncodeToString(param)
+
+		if formatCode == 0 {
+			// Text format - value is directly usable
+			params.Values[i] = string(param)
+		} else {
+			// Binary format - decode based on type OID
+			params.Values[i] = decodeBinaryParameter(param, getTypeOID(typeOIDs, i))
+		}
+	}
+
+	return params
+}
+
 // handleExecute handles Execute messages (query execution) for Extended Query Protocol.
 func (s *Session) handleExecute(msg *pgproto3.Execute) error {
+	start := time.Now()
+
 	// Check quotas before executing
 	if err := s.checkQuotas(); err != nil {
 		return err
@@ -242,10 +370,14 @@ func (s *Session) handleExecute(msg *pgproto3.Execute) error {
 
 	// Queue the query for logging (will be popped on CommandComplete)
 	query := &pendingQuery{
-		sql:          sqlText,
-		startTime:    time.Now(),
-		parameters:   portal.parameters,
-		capturedRows: make([]store.QueryRow, 0), // Initialize for capture
+		sql:            sqlText,
+		startTime:      start,
+		timer:          shared.StartQueryTimer(start),
+		parameters:     portal.parameters,
+		capturedRows:   make([]store.QueryRow, 0), // Initialize for capture
+		stmt:           stmt,
+		dryRun:         s.dryRun.enabled,
+		awaitsApproval: s.grant.WriteRequiresApproval() && stmt != nil && stmt.write,
 	}
 	s.extendedState.pendingQueries = append(s.extendedState.pendingQueries, query)
 
@@ -264,7 +396,8 @@ func (s *Session) handleClose(msg *pgproto3.Close) {
 	}
 }
 
-// isWriteQuery checks if a query is a write operation.
+// isWriteQuery checks if a query is a write operation, by its first keyword.
+// It is one of the heuristics classifySQL falls back to without the parser.
 func isWriteQuery(sql string) bool {
 	return shared.IsWriteQuery(sql)
 }
@@ -280,6 +413,236 @@ func isCopyQuery(sql string) bool {
 	return strings.HasPrefix(upper, "COPY ")
 }
 
+// isCopyOutQuery reports whether any statement of sql is a COPY ... TO
+// (including COPY (query) TO). Unlike the prefix checks it scans every
+// statement of a multi-statement Query, skipping strings, quoted identifiers
+// and comments, so an export cannot hide behind a leading SELECT.
+func isCopyOutQuery(sql string) bool {
+	const (
+		stmtStart = iota // expecting the first word of a statement
+		stmtCopy         // in a COPY statement, before its direction
+		stmtOther
+	)
+
+	state, depth := stmtStart, 0
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			i = skipSQLQuoted(sql, i, c, false)
+		case c == '$':
+			i = skipDollarQuoted(sql, i)
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(sql)
+			}
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth = max(depth-1, 0)
+			i++
+		case c == ';':
+			state, depth = stmtStart, 0
+			i++
+		case isSQLWordByte(c):
+			start := i
+			for i < len(sql) && isSQLWordByte(sql[i]) {
+				i++
+			}
+
+			word := strings.ToUpper(sql[start:i])
+
+			// E'...' strings honor backslash escapes.
+			if word == "E" && i < len(sql) && sql[i] == '\'' {
+				i = skipSQLQuoted(sql, i, '\'', true)
+
+				continue
+			}
+
+			switch {
+			case state == stmtStart && word == "COPY":
+				state = stmtCopy
+			case state == stmtStart:
+				state = stmtOther
+			case state == stmtCopy && depth == 0 && word == "TO":
+				return true
+			case state == stmtCopy && depth == 0 && word == "FROM":
+				state = stmtOther
+			}
+		default:
+			i++
+		}
+	}
+
+	return false
+}
+
+// statementHeads returns the first two words of every statement of sql,
+// upper-cased and separated by a space. Like isCopyOutQuery, it skips
+// strings, quoted identifiers and comments.
+func statementHeads(sql string) []string {
+	var (
+		heads []string
+		words int // words of the current statement seen so far
+	)
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			i = skipSQLQuoted(sql, i, c, false)
+		case c == '$':
+			i = skipDollarQuoted(sql, i)
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(sql)
+			}
+		case c == ';':
+			words = 0
+			i++
+		case isSQLWordByte(c):
+			start := i
+			for i < len(sql) && isSQLWordByte(sql[i]) {
+				i++
+			}
+
+			word := strings.ToUpper(sql[start:i])
+
+			// E'...' strings honor backslash escapes.
+			if word == "E" && i < len(sql) && sql[i] == '\'' {
+				i = skipSQLQuoted(sql, i, '\'', true)
+
+				continue
+			}
+
+			switch words {
+			case 0:
+				heads = append(heads, word)
+			case 1:
+				heads[len(heads)-1] += " " + word
+			}
+
+			words++
+		default:
+			i++
+		}
+	}
+
+	return heads
+}
+
+// scanStatements splits sql at the semicolons ending its statements. Like
+// isCopyOutQuery, it skips strings, quoted identifiers and comments; the
+// statements are trimmed, and may be empty.
+func scanStatements(sql string) []string {
+	var (
+		statements []string
+		start      int // of the current statement
+	)
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			i = skipSQLQuoted(sql, i, c, false)
+		case c == '$':
+			i = skipDollarQuoted(sql, i)
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(sql)
+			}
+		case c == ';':
+			statements = append(statements, strings.TrimSpace(sql[start:i]))
+			i++
+			start = i
+		case (c == 'E' || c == 'e') && i+1 < len(sql) && sql[i+1] == '\'' && (i == 0 || !isSQLWordByte(sql[i-1])):
+			// E'...' strings honor backslash escapes.
+			i = skipSQLQuoted(sql, i+1, '\'', true)
+		default:
+			i++
+		}
+	}
+
+	return append(statements, strings.TrimSpace(sql[start:]))
+}
+
+// skipSQLQuoted returns the index just past the quoted section opened at i.
+// A doubled quote is an escaped quote; backslashes escape only when asked.
+func skipSQLQuoted(sql string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+
+				continue
+			}
+
+			return j + 1
+		}
+	}
+
+	return len(sql)
+}
+
+// skipDollarQuoted returns the index just past the $tag$...$tag$ string opened
+// at i, or i+1 when the $ does not open one (e.g. a $1 parameter).
+func skipDollarQuoted(sql string, i int) int {
+	end := i + 1
+	for end < len(sql) && (isSQLWordByte(sql[end]) && (sql[end] < '0' || sql[end] > '9' || end > i+1)) {
+		end++
+	}
+
+	if end >= len(sql) || sql[end] != '$' {
+		return i + 1
+	}
+
+	tag := sql[i : end+1]
+
+	if closing := strings.Index(sql[end+1:], tag); closing >= 0 {
+		return end + 1 + closing + len(tag)
+	}
+
+	return len(sql)
+}
+
+func isSQLWordByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
 // isReadOnlyBypassAttempt checks if a query attempts to disable read-only mode.
 func isReadOnlyBypassAttempt(sql string) bool {
 	for _, pattern := range readOnlyBypassPatterns {
@@ -317,7 +680,8 @@ func (s *Session) logQuery(rowsAffected *int64, queryError *string, bytesTransfe
 		return
 	}
 
-	duration := float64(time.Since(s.currentQuery.startTime).Milliseconds())
+	end := time.Now()
+	duration := float64(end.Sub(s.currentQuery.startTime).Microseconds()) / 1000.0
 
 	query := &store.Query{
 		ConnectionID: s.connectionUID,
@@ -325,8 +689,10 @@ func (s *Session) logQuery(rowsAffected *int64, queryError *string, bytesTransfe
 		Parameters:   s.currentQuery.parameters,
 		ExecutedAt:   s.currentQuery.startTime,
 		DurationMs:   &duration,
+		QueryTiming:  s.currentQuery.timer.Timing(end),
 		RowsAffected: rowsAffected,
 		Error:        queryError,
+		DryRun:       s.currentQuery.dryRun,
 	}
 
 	// Set COPY metadata if this was a COPY operation
@@ -346,48 +712,99 @@ func (s *Session) logQuery(rowsAffected *int64, queryError *string, bytesTransfe
 		capturedRows = s.currentQuery.capturedRows
 	}
 
+	rowsReturned := s.unloggedRows
+	s.unloggedRows = 0
+
+	// The spilled result, if any, now belongs to the log write.
+	spilled := s.currentQuery.spill
+	s.currentQuery.spill = nil
+
 	// Persist asynchronously so the proxy isn't blocked on the store write.
-	s.persistQueryAsync(query, capturedRows, bytesTransferred)
+	s.persistQueryAsync(query, capturedRows, spilled, bytesTransferred, rowsReturned)
 
 	// Update local grant state for in-session quota checks
 	s.grant.QueryCount++
 	s.grant.BytesTransferred += bytesTransferred
+	s.grant.RowsReturned += rowsReturned
 }
 
-// persistQueryAsync writes the query log row, its captured rows, and the
-// connection byte increment in a background goroutine. It is a no-op when there
+// persistQueryAsync writes the query log row, its captured or spilled rows,
+// and the connection byte and row increments in a background goroutine. It is a no-op when there
 // is no connection record to write against — the mid-stream abort path
 // (persistAbortedQuery) reuses logQuery purely for its in-memory grant
 // accounting in unit contexts that have no store.
-func (s *Session) persistQueryAsync(query *store.Query, capturedRows []store.QueryRow, bytesTransferred int64) {
+func (s *Session) persistQueryAsync(query *store.Query, capturedRows []store.QueryRow, spilled *spill.Writer, bytesTransferred, rowsReturned int64) {
 	if s.store == nil || s.connectionUID == uuid.Nil {
+		if spilled != nil {
+			spilled.Discard()
+		}
+
 		return
 	}
 
-	go func() {
-		createdQuery, err := s.store.CreateQuery(s.ctx, query)
+	s.logWrites.Go(func() {
+		// Detached from the session context, which ends with the session, but
+		// bounded like any other storage call.
+		ctx, cancel := shared.StoreContext(context.WithoutCancel(s.ctx))
+		defer cancel()
+
+		defer shared.RecoverPanic(ctx, s.logger, "postgresql query log")
+
+		query.Fingerprint = sqlnorm.Fingerprint(query.SQLText, store.ProtocolPostgreSQL)
+
+		if spilled != nil {
+			defer spilled.Discard()
+		}
+
+		createdQuery, err := s.store.CreateQuery(ctx, query)
 		if err != nil {
-			s.logger.ErrorContext(s.ctx, "failed to log query", slog.Any("error", err))
+			s.logger.ErrorContext(ctx, "failed to log query", slog.Any("error", err))
 			return
 		}
 
-		// Store captured result rows
-		if len(capturedRows) > 0 {
+		// Store captured result rows, unless the query was folded into an
+		// identical previous one, which already has them
+		if len(capturedRows) > 0 && !createdQuery.Repeated {
 			// Assign row numbers
 			for i := range capturedRows {
 				capturedRows[i].RowNumber = i + 1
 			}
 
-			if err := s.store.StoreQueryRows(s.ctx, createdQuery.UID, capturedRows); err != nil {
-				s.logger.ErrorContext(s.ctx, "failed to store query rows", slog.Any("error", err))
+			if err := s.store.StoreQueryRows(ctx, createdQuery.UID, capturedRows); err != nil {
+				s.logger.ErrorContext(ctx, "failed to store query rows", slog.Any("error", err))
 			}
 		}
 
 		// Update connection stats
-		if err := s.store.IncrementConnectionStats(s.ctx, s.connectionUID, bytesTransferred); err != nil {
-			s.logger.ErrorContext(s.ctx, "failed to increment connection stats", slog.Any("error", err))
+		if err := s.store.IncrementConnectionStats(ctx, s.connectionUID, bytesTransferred, rowsReturned); err != nil {
+			s.logger.ErrorContext(ctx, "failed to increment connection stats", slog.Any("error", err))
+		}
+
+		// Upload the spilled result last, with its own timeout, unless the
+		// query was folded into an identical previous one, like the rows
+		if spilled != nil && !createdQuery.Repeated {
+			shared.CommitSpill(context.WithoutCancel(s.ctx), s.logger, s.store, createdQuery.UID, spilled)
 		}
-	}()
+	})
+}
+
+// discardSpills removes the results spilled by the queries still in flight
+// when the session ends, which are never logged.
+func (s *Session) discardSpills() {
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
+	queries := []*pendingQuery{s.currentQuery}
+	if s.extendedState != nil {
+		queries = append(queries, s.extendedState.pendingQueries...)
+	}
+
+	for _, query := range queries {
+		if query != nil && query.spill != nil {
+			query.spill.Discard()
+			query.spill = nil
+		}
+	}
 }
 
 // copyFormatToString converts COPY format byte to string.
@@ -461,8 +878,9 @@ func getTypeOID(oids []uint32, idx int) uint32 {
 	return 0 // Unknown
 }
 
-// convertDataRow converts a DataRow to a QueryRow with JSON data.
-func (s *Session) convertDataRow(values [][]byte, columnNames []string, columnOIDs []uint32) store.QueryRow {
+// convertDataRow converts a DataRow to a QueryRow with JSON data, masking the
+// columns of the database's result masking. tables are those of the statement.
+func (s *Session) convertDataRow(values [][]byte, columnNames []string, columnOIDs []uint32, tables []string) store.QueryRow {
 	rowData := make(map[string]interface{})
 	rowSize := int64(0)
 
@@ -488,6 +906,8 @@ func (s *Session) convertDataRow(values [][]byte, columnNames []string, columnOI
 		rowData[columnName] = decodeColumnValue(val, oid)
 	}
 
+	s.masker.Get(s.database).MaskRow(rowData, tables)
+
 	jsonData, err := json.Marshal(rowData)
 	if err != nil {
 		// Fallback: store error message
@@ -502,19 +922,20 @@ func (s *Session) convertDataRow(values [][]byte, columnNames []string, columnOI
 
 // captureCopyData captures a COPY data chunk, respecting storage limits.
 func (s *Session) captureCopyData(data []byte) {
-	if s.copyState == nil || s.copyState.truncated || !s.queryStorage.StoreResults {
+	limits := s.database.EffectiveQueryStorage(s.queryStorage)
+	if s.copyState == nil || s.copyState.truncated || !limits.StoreResults || !s.database.StoresResults() {
 		return
 	}
 
 	dataSize := int64(len(data))
 
 	// Check if this chunk would exceed limits
-	if s.copyState.totalBytes+dataSize > s.queryStorage.MaxResultBytes {
+	if s.copyState.totalBytes+dataSize > limits.MaxResultBytes {
 		s.copyState.truncated = true
 		s.copyState.dataChunks = nil // Discard captured data
 		s.logger.WarnContext(s.ctx, "COPY data capture truncated - byte limit exceeded",
 			slog.Int64("total_bytes", s.copyState.totalBytes),
-			slog.Int64("max_bytes", s.queryStorage.MaxResultBytes))
+			slog.Int64("max_bytes", limits.MaxResultBytes))
 		return
 	}
 
@@ -528,8 +949,17 @@ func (s *Session) captureCopyData(data []byte) {
 // - "COPY table (col1, col2) TO STDOUT" -> ["col1", "col2"]
 // - "COPY table TO STDOUT" -> nil (all columns)
 func parseCopyColumnNames(sql string) []string {
-	// Find the column list between parentheses before TO/FROM
-	upper := strings.ToUpper(sql)
+	// Find the column list between parentheses before TO/FROM. Only ASCII
+	// letters are upper-cased so indexes into upper stay valid in sql
+	// (strings.ToUpper rewrites invalid UTF-8 into longer sequences).
+	upperBytes := []byte(sql)
+	for i, c := range upperBytes {
+		if c >= 'a' && c <= 'z' {
+			upperBytes[i] = c - 'a' + 'A'
+		}
+	}
+
+	upper := string(upperBytes)
 	toIdx := strings.Index(upper, " TO ")
 	fromIdx := strings.Index(upper, " FROM ")
 
@@ -572,20 +1002,25 @@ func parseCopyColumnNames(sql string) []string {
 	return result
 }
 
-// parseCopyDataToRows parses text/CSV format COPY data into query rows.
+// parseCopyDataToRows parses COPY data into query rows.
 // Format 0 = text (tab-separated), Format 1 = binary
 func (s *Session) parseCopyDataToRows() []store.QueryRow {
-	if s.copyState == nil || len(s.copyState.dataChunks) == 0 || s.copyState.format == 1 {
-		// No data or binary format (not parsed)
+	if s.copyState == nil || len(s.copyState.dataChunks) == 0 {
 		return nil
 	}
 
+	maxRows := s.database.EffectiveQueryStorage(s.queryStorage).MaxResultRows
+
 	// Concatenate all chunks
 	var fullData []byte
 	for _, chunk := range s.copyState.dataChunks {
 		fullData = append(fullData, chunk...)
 	}
 
+	if s.copyState.format == 1 {
+		return s.parseCopyBinaryToRows(fullData, maxRows)
+	}
+
 	// Split into lines (COPY text format uses \n as row separator)
 	lines := strings.Split(string(fullData), "\n")
 	rows := make([]store.QueryRow, 0, len(lines))
@@ -597,10 +1032,10 @@ func (s *Session) parseCopyDataToRows() []store.QueryRow {
 		}
 
 		// Check max rows limit
-		if len(rows) >= s.queryStorage.MaxResultRows {
+		if len(rows) >= maxRows {
 			s.logger.WarnContext(s.ctx, "COPY row capture truncated - row limit exceeded",
 				slog.Int("rows_captured", len(rows)),
-				slog.Int("max_rows", s.queryStorage.MaxResultRows))
+				slog.Int("max_rows", maxRows))
 			break
 		}
 
@@ -623,6 +1058,8 @@ func (s *Session) parseCopyDataToRows() []store.QueryRow {
 			}
 		}
 
+		s.masker.Get(s.database).MaskRow(rowData, s.copyState.tables)
+
 		jsonData, err := json.Marshal(rowData)
 		if err != nil {
 			s.logger.ErrorContext(s.ctx, "failed to marshal COPY row", slog.Any("error", err), slog.Int("row", i))
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"slices"
 	"testing"
 
 	"github.com/jackc/pgx/v5/pgproto3"
@@ -1513,7 +1514,7 @@ func TestConvertDataRow(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			row := s.convertDataRow(tt.values, tt.columnNames, tt.columnOIDs)
+			row := s.convertDataRow(tt.values, tt.columnNames, tt.columnOIDs, nil)
 
 			// Check that RowData is valid JSON
 			if len(row.RowData) == 0 {
@@ -1556,7 +1557,7 @@ func TestConvertDataRow_TypeDecoding(t *testing.T) {
 	columnNames := []string{"int_col", "float_col", "bool_col", "text_col"}
 	columnOIDs := []uint32{23, 701, 16, 25} // int4, float8, bool, text
 
-	row := s.convertDataRow(values, columnNames, columnOIDs)
+	row := s.convertDataRow(values, columnNames, columnOIDs, nil)
 
 	var data map[string]interface{}
 	if err := json.Unmarshal(row.RowData, &data); err != nil {
@@ -1779,3 +1780,179 @@ func TestCopyFormatToString(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"single", "SELECT 1", []string{"SELECT 1"}},
+		{"trailing semicolon", "SELECT 1;", []string{"SELECT 1"}},
+		{"two statements", "SELECT 1; DELETE FROM x;", []string{"SELECT 1", "DELETE FROM x"}},
+		{"semicolon in string", "SELECT ';'; SELECT 2", []string{"SELECT ';'", "SELECT 2"}},
+		{"semicolon in identifier", `SELECT 1 AS ";"; SELECT 2`, []string{`SELECT 1 AS ";"`, "SELECT 2"}},
+		{"semicolon in dollar quotes", "DO $$ BEGIN PERFORM 1; END $$; SELECT 2", []string{"DO $$ BEGIN PERFORM 1; END $$", "SELECT 2"}},
+		{"empty statements", "SELECT 1;; ;SELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"trailing comment", "SELECT 1; -- done", []string{"SELECT 1"}},
+		{"empty", "", []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := splitStatements(tt.sql)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"two statements", "SELECT 1; DELETE FROM x", []string{"SELECT 1", "DELETE FROM x"}},
+		{"semicolon in comments", "SELECT 1 /* ; */; -- ;\nSELECT 2", []string{"SELECT 1 /* ; */", "-- ;\nSELECT 2"}},
+		{"escape string", `SELECT E'\';'; SELECT 2`, []string{`SELECT E'\';'`, "SELECT 2"}},
+		{"unparsable", "SELEC 1; DROP TABLE x", []string{"SELEC 1", "DROP TABLE x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := scanStatements(tt.sql)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("scanStatements(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleQuery_MultiStatement(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		controls  []string
+		sql       string
+		expectErr error
+	}{
+		{
+			name:      "read_only blocks a later write",
+			controls:  []string{store.ControlReadOnly},
+			sql:       "SELECT 1; DELETE FROM x;",
+			expectErr: ErrWriteNotPermitted,
+		},
+		{
+			name:      "block_ddl blocks a later DDL",
+			controls:  []string{store.ControlBlockDDL},
+			sql:       "SELECT 1; DROP TABLE x",
+			expectErr: ErrDDLNotPermitted,
+		},
+		{
+			name:      "block_ddl blocks a DDL after an unparsable statement",
+			controls:  []string{store.ControlBlockDDL},
+			sql:       "SELEC 1; DROP TABLE x",
+			expectErr: ErrDDLNotPermitted,
+		},
+		{
+			name:      "read_only allows reads",
+			controls:  []string{store.ControlReadOnly},
+			sql:       "SELECT 1; SELECT 2",
+			expectErr: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newTestSessionWithControls(tt.controls)
+
+			if err := s.handleQuery(&pgproto3.Query{String: tt.sql}); !errors.Is(err, tt.expectErr) {
+				t.Errorf("handleQuery() error = %v, want %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestReadOnlyTransactions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		statements  []string
+		want        string
+		wantChanged bool
+	}{
+		{"begin", []string{"BEGIN"}, "BEGIN\nREAD ONLY", true},
+		{"begin isolation level", []string{"begin isolation level serializable"}, "begin isolation level serializable\nREAD ONLY", true},
+		{"start transaction", []string{"START TRANSACTION"}, "START TRANSACTION\nREAD ONLY", true},
+		{"trailing comment", []string{"BEGIN -- tx"}, "BEGIN -- tx\nREAD ONLY", true},
+		{"already read only", []string{"BEGIN READ ONLY"}, "BEGIN READ ONLY", false},
+		{"in a batch", []string{"BEGIN", "SELECT 1"}, "BEGIN\nREAD ONLY;\nSELECT 1", true},
+		{"select", []string{"SELECT 1"}, "SELECT 1", false},
+		{"commit", []string{"COMMIT"}, "COMMIT", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, changed := readOnlyTransactions(tt.statements)
+			if got != tt.want || changed != tt.wantChanged {
+				t.Errorf("readOnlyTransactions(%q) = %q, %v, want %q, %v", tt.statements, got, changed, tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestHandleQuery_ReadOnlyTransactions(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSession("read")
+	query := &pgproto3.Query{String: "BEGIN;"}
+
+	if err := s.handleQuery(query); err != nil {
+		t.Fatalf("handleQuery() error = %v", err)
+	}
+
+	if query.String != "BEGIN\nREAD ONLY" {
+		t.Errorf("forwarded SQL = %q, want BEGIN READ ONLY", query.String)
+	}
+
+	if s.currentQuery.sql != "BEGIN" {
+		t.Errorf("logged SQL = %q, want the client's BEGIN", s.currentQuery.sql)
+	}
+
+	parse := &pgproto3.Parse{Query: "START TRANSACTION"}
+	if err := s.handleParse(parse); err != nil {
+		t.Fatalf("handleParse() error = %v", err)
+	}
+
+	if parse.Query != "START TRANSACTION\nREAD ONLY" {
+		t.Errorf("forwarded Parse SQL = %q, want START TRANSACTION READ ONLY", parse.Query)
+	}
+
+	// Write grants begin their transactions as they ask.
+	s = newTestSession("write")
+	query = &pgproto3.Query{String: "BEGIN"}
+
+	if err := s.handleQuery(query); err != nil {
+		t.Fatalf("handleQuery() error = %v", err)
+	}
+
+	if query.String != "BEGIN" {
+		t.Errorf("forwarded SQL under a write grant = %q, want BEGIN", query.String)
+	}
+}
@@ -89,10 +89,21 @@ func TestSession_Revocation_DisconnectsLiveSession(t *testing.T) {
 
 	go s.guard.Watch(context.Background(), 5*time.Millisecond, s.onLimitViolation)
 
-	// Revoke after the watchdog is running: it must trip and tear both conns
-	// down without waiting for a client query.
+	// Revoke after the watchdog is running: it must trip, tell the client why
+	// and tear both conns down without waiting for a client query.
 	reg.Revoke(grant.UID)
 
+	_ = clientTestEnd.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	msg, err := pgproto3.NewFrontend(clientTestEnd, clientTestEnd).Receive()
+	if err != nil {
+		t.Fatalf("client conn: Receive() error = %v, want the revocation error", err)
+	}
+
+	if errResp, ok := msg.(*pgproto3.ErrorResponse); !ok || errResp.Severity != "FATAL" || errResp.Code != sqlStateInsufficientPrivilege {
+		t.Fatalf("client conn: Receive() = %+v, want a FATAL %s", msg, sqlStateInsufficientPrivilege)
+	}
+
 	assertPeerClosed(t, clientTestEnd, "client conn")
 	assertPeerClosed(t, upstreamTestEnd, "upstream conn")
 }
@@ -256,3 +267,220 @@ func TestSession_ProxyUpstreamToClient_ByteLimitAbort(t *testing.T) {
 		t.Fatal("timed out waiting for client ErrorResponse")
 	}
 }
+
+// TestSession_ProxyUpstreamToClient_RowLimitAbort drives the real
+// upstream→client relay: a grant with max_rows_returned must forward the rows
+// left in its quota, then abort the query with SQLSTATE 53400.
+func TestSession_ProxyUpstreamToClient_RowLimitAbort(t *testing.T) {
+	t.Parallel()
+
+	clientProxyEnd, clientTestEnd := net.Pipe()
+	upstreamProxyEnd, upstreamTestEnd := net.Pipe()
+
+	var fromClient, toClient atomic.Int64
+
+	countedClient := shared.NewCountingConn(clientProxyEnd, &fromClient, &toClient)
+
+	maxRows := int64(5)
+	grant := &store.Grant{
+		MaxRowsReturned: &maxRows,
+		RowsReturned:    2, // Returned by earlier sessions
+		ExpiresAt:       time.Now().Add(time.Hour),
+	}
+
+	s := &Session{
+		clientConn:       countedClient,
+		clientBackend:    pgproto3.NewBackend(countedClient, countedClient),
+		upstreamFrontend: pgproto3.NewFrontend(upstreamProxyEnd, upstreamProxyEnd),
+		grant:            grant,
+		bytesFromClient:  &fromClient,
+		bytesToClient:    &toClient,
+		guard:            shared.NewLimitGuard(grant, &fromClient, &toClient),
+		currentQuery:     &pendingQuery{sql: "SELECT * FROM big"},
+		extendedState: &extendedQueryState{
+			preparedStatements: make(map[string]*preparedStatement),
+			portals:            make(map[string]*portalState),
+		},
+		logger: slog.New(slog.DiscardHandler),
+		ctx:    context.Background(),
+	}
+
+	go func() {
+		msgs := []pgproto3.BackendMessage{
+			&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{{Name: []byte("col")}}},
+		}
+		for range 10 {
+			msgs = append(msgs, &pgproto3.DataRow{Values: [][]byte{[]byte("row")}})
+		}
+
+		for _, msg := range msgs {
+			buf, err := msg.Encode(nil)
+			if err != nil {
+				return
+			}
+
+			if _, err := upstreamTestEnd.Write(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	type clientResult struct {
+		errResp *pgproto3.ErrorResponse
+		rows    int
+	}
+
+	resCh := make(chan clientResult, 1)
+
+	go func() {
+		fe := pgproto3.NewFrontend(clientTestEnd, clientTestEnd)
+
+		var res clientResult
+
+		for {
+			msg, err := fe.Receive()
+			if err != nil {
+				resCh <- res
+
+				return
+			}
+
+			switch m := msg.(type) {
+			case *pgproto3.DataRow:
+				res.rows++
+			case *pgproto3.ErrorResponse:
+				res.errResp = m
+			}
+		}
+	}()
+
+	relayErr := s.proxyUpstreamToClient()
+
+	_ = upstreamTestEnd.Close()
+	_ = upstreamProxyEnd.Close()
+	_ = clientTestEnd.Close()
+	_ = clientProxyEnd.Close()
+
+	if !errors.Is(relayErr, ErrRowLimitExceeded) {
+		t.Fatalf("proxyUpstreamToClient() = %v, want ErrRowLimitExceeded", relayErr)
+	}
+
+	if grant.RowsReturned != maxRows {
+		t.Fatalf("grant.RowsReturned = %d, want %d (the aborted query's rows must be attributed)", grant.RowsReturned, maxRows)
+	}
+
+	if err := s.checkQuotas(); !errors.Is(err, ErrRowLimitExceeded) {
+		t.Fatalf("checkQuotas() after the abort = %v, want ErrRowLimitExceeded", err)
+	}
+
+	select {
+	case res := <-resCh:
+		if res.rows != 3 {
+			t.Fatalf("client received %d rows, want the 3 left in the quota", res.rows)
+		}
+
+		if res.errResp == nil || res.errResp.Code != "53400" {
+			t.Fatalf("ErrorResponse = %+v, want code 53400", res.errResp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for client ErrorResponse")
+	}
+}
+
+// TestSession_IdleTimeout_DisconnectsWithErrorResponse drives the guard
+// watchdog → onLimitViolation seam for a session timeout: an idle session
+// gets a FATAL idle_session_timeout ErrorResponse, then both conns close.
+func TestSession_IdleTimeout_DisconnectsWithErrorResponse(t *testing.T) {
+	t.Parallel()
+
+	clientProxyEnd, clientTestEnd := net.Pipe()
+	upstreamProxyEnd, upstreamTestEnd := net.Pipe()
+
+	var from, to atomic.Int64
+
+	grant := &store.Grant{UID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	s := &Session{
+		clientConn:   clientProxyEnd,
+		upstreamConn: upstreamProxyEnd,
+		grant:        grant,
+		extendedState: &extendedQueryState{
+			preparedStatements: make(map[string]*preparedStatement),
+			portals:            make(map[string]*portalState),
+		},
+		logger: slog.New(slog.DiscardHandler),
+		ctx:    context.Background(),
+	}
+	s.guard = shared.NewLimitGuard(grant, &from, &to).WithSessionTimeouts(20*time.Millisecond, 0, s.queryInFlight)
+
+	go s.guard.Watch(context.Background(), 5*time.Millisecond, s.onLimitViolation)
+
+	_ = clientTestEnd.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	msg, err := pgproto3.NewFrontend(clientTestEnd, clientTestEnd).Receive()
+	if err != nil {
+		t.Fatalf("Receive() = %v, want an ErrorResponse", err)
+	}
+
+	errResp, ok := msg.(*pgproto3.ErrorResponse)
+	if !ok || errResp.Severity != "FATAL" || errResp.Code != sqlStateIdleSessionTimeout {
+		t.Fatalf("client got %#v, want a FATAL %s ErrorResponse", msg, sqlStateIdleSessionTimeout)
+	}
+
+	assertPeerClosed(t, clientTestEnd, "client conn")
+	assertPeerClosed(t, upstreamTestEnd, "upstream conn")
+
+	if reason := s.disconnectReason.Load(); reason == nil || *reason != store.DisconnectReasonIdleTimeout {
+		t.Errorf("disconnect reason = %v, want %s", reason, store.DisconnectReasonIdleTimeout)
+	}
+}
+
+// An admin terminating the connection ends the session with a FATAL
+// admin_shutdown and records the reason.
+func TestSession_Terminated_DisconnectsWithErrorResponse(t *testing.T) {
+	t.Parallel()
+
+	clientProxyEnd, clientTestEnd := net.Pipe()
+	upstreamProxyEnd, upstreamTestEnd := net.Pipe()
+
+	var from, to atomic.Int64
+
+	grant := &store.Grant{UID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+	registry := cache.NewTerminationRegistry()
+	connUID := uuid.New()
+
+	s := &Session{
+		clientConn:   clientProxyEnd,
+		upstreamConn: upstreamProxyEnd,
+		grant:        grant,
+		termination:  registry.Register(connUID),
+		logger:       slog.New(slog.DiscardHandler),
+		ctx:          context.Background(),
+	}
+	s.guard = shared.NewLimitGuard(grant, &from, &to).WithTermination(s.termination.Flag())
+
+	go s.guard.Watch(context.Background(), 5*time.Millisecond, s.onLimitViolation)
+
+	if !registry.Terminate(connUID) {
+		t.Fatal("Terminate() did not find the live session")
+	}
+
+	_ = clientTestEnd.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	msg, err := pgproto3.NewFrontend(clientTestEnd, clientTestEnd).Receive()
+	if err != nil {
+		t.Fatalf("Receive() = %v, want an ErrorResponse", err)
+	}
+
+	errResp, ok := msg.(*pgproto3.ErrorResponse)
+	if !ok || errResp.Severity != "FATAL" || errResp.Code != sqlStateAdminShutdown {
+		t.Fatalf("client got %#v, want a FATAL %s ErrorResponse", msg, sqlStateAdminShutdown)
+	}
+
+	assertPeerClosed(t, clientTestEnd, "client conn")
+	assertPeerClosed(t, upstreamTestEnd, "upstream conn")
+
+	if reason := s.disconnectReason.Load(); reason == nil || *reason != store.DisconnectReasonTerminated {
+		t.Errorf("disconnect reason = %v, want %s", reason, store.DisconnectReasonTerminated)
+	}
+}
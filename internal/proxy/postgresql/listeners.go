@@ -0,0 +1,84 @@
+package postgresql
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// listenerPolicy is what a proxy listener lets its clients reach: the main
+// listener (listen_pg) allows everything, the ones of pg.listeners may
+// restrict the databases, the client networks and use their own TLS.
+type listenerPolicy struct {
+	name string
+	addr string
+	// tlsConfig terminates client TLS; nil refuses SSLRequest.
+	tlsConfig *tls.Config
+	// databases are the DBBat database names served; nil serves them all.
+	databases map[string]bool
+	// allowedNetworks are the networks clients may connect from; nil
+	// accepts any client.
+	allowedNetworks []*net.IPNet
+}
+
+// newListenerPolicy builds the policy of an additional listener. Its TLS
+// settings default to the proxy-wide ones, whose tls.Config is reused rather
+// than generating another self-signed certificate.
+func newListenerPolicy(cfg config.PGListenerConfig, defaultTLS config.TLSConfig, defaultTLSConfig *tls.Config) (*listenerPolicy, error) {
+	networks, err := cfg.AllowedNetworks()
+	if err != nil {
+		return nil, fmt.Errorf("listener %s: %w", cfg.DisplayName(), err)
+	}
+
+	policy := &listenerPolicy{
+		name:            cfg.DisplayName(),
+		addr:            cfg.Addr,
+		tlsConfig:       defaultTLSConfig,
+		allowedNetworks: networks,
+	}
+
+	if cfg.TLS != nil && *cfg.TLS != defaultTLS {
+		policy.tlsConfig, err = loadTLS(config.PGConfig{TLS: *cfg.TLS})
+		if err != nil {
+			return nil, fmt.Errorf("listener %s TLS setup: %w", policy.name, err)
+		}
+	}
+
+	if len(cfg.Databases) > 0 {
+		policy.databases = make(map[string]bool, len(cfg.Databases))
+		for _, name := range cfg.Databases {
+			policy.databases[name] = true
+		}
+	}
+
+	return policy, nil
+}
+
+// servesDatabase reports whether clients of the listener may use the DBBat
+// database name. A nil policy serves every database.
+func (p *listenerPolicy) servesDatabase(name string) bool {
+	return p == nil || p.databases == nil || p.databases[name]
+}
+
+// acceptsClient reports whether a client connecting from addr may use the
+// listener.
+func (p *listenerPolicy) acceptsClient(addr net.Addr) bool {
+	if p == nil || p.allowedNetworks == nil {
+		return true
+	}
+
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	for _, network := range p.allowedNetworks {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,134 @@
+package postgresql
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+func TestListenerPolicy(t *testing.T) {
+	t.Parallel()
+
+	defaultTLS := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	policy, err := newListenerPolicy(config.PGListenerConfig{
+		Name:         "vpn",
+		Addr:         ":5444",
+		Databases:    []string{"prod"},
+		AllowedCIDRs: "10.8.0.0/16",
+	}, config.TLSConfig{}, defaultTLS)
+	if err != nil {
+		t.Fatalf("newListenerPolicy() error = %v", err)
+	}
+
+	if policy.tlsConfig != defaultTLS {
+		t.Error("listener without TLS settings does not reuse the proxy-wide TLS config")
+	}
+
+	if !policy.servesDatabase("prod") || policy.servesDatabase("staging") {
+		t.Error("servesDatabase() does not follow the listener databases")
+	}
+
+	if !policy.acceptsClient(&net.TCPAddr{IP: net.ParseIP("10.8.3.4"), Port: 50000}) {
+		t.Error("acceptsClient() refused a client of an allowed network")
+	}
+
+	if policy.acceptsClient(&net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 50000}) {
+		t.Error("acceptsClient() accepted a client outside the allowed networks")
+	}
+
+	var unrestricted *listenerPolicy
+	if !unrestricted.servesDatabase("staging") || !unrestricted.acceptsClient(&net.UnixAddr{}) {
+		t.Error("a nil policy must allow everything")
+	}
+
+	plaintext, err := newListenerPolicy(config.PGListenerConfig{
+		Addr: ":5445",
+		TLS:  &config.TLSConfig{Disable: true},
+	}, config.TLSConfig{}, defaultTLS)
+	if err != nil {
+		t.Fatalf("newListenerPolicy() error = %v", err)
+	}
+
+	if plaintext.tlsConfig != nil || plaintext.name != ":5445" {
+		t.Errorf("plaintext listener = %q with TLS %v, want :5445 without TLS", plaintext.name, plaintext.tlsConfig)
+	}
+
+	if _, err := newListenerPolicy(config.PGListenerConfig{
+		Addr: ":5446",
+		TLS:  &config.TLSConfig{CertFile: "only-cert.pem"},
+	}, config.TLSConfig{}, defaultTLS); err == nil {
+		t.Error("newListenerPolicy() accepted a certificate without a key")
+	}
+}
+
+func TestServer_AdditionalListeners(t *testing.T) {
+	t.Parallel()
+
+	server, err := NewServer(nil, nil, config.QueryStorageConfig{}, config.DumpConfig{}, nil, config.PGConfig{
+		TLS: config.TLSConfig{Disable: true},
+		Listeners: []config.PGListenerConfig{
+			{Name: "internal", Addr: "127.0.0.1:0", AllowedCIDRs: "127.0.0.0/8"},
+			{Name: "vpn", Addr: "127.0.0.1:0", AllowedCIDRs: "10.8.0.0/16"},
+		},
+	}, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	go func() { _ = server.Start("127.0.0.1:0") }()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = server.Shutdown(ctx)
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for server.ListenerAddr("vpn") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("additional listeners never started")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// sslResponse sends an SSLRequest and returns the proxy's answer.
+	sslResponse := func(addr net.Addr) ([]byte, error) {
+		conn, err := net.DialTimeout("tcp", addr.String(), 2*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = conn.Close() }()
+
+		_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+		if _, err := conn.Write(makeSSLRequest()); err != nil {
+			return nil, err
+		}
+
+		resp := make([]byte, 1)
+		_, err = io.ReadFull(conn, resp)
+
+		return resp, err
+	}
+
+	for _, addr := range []net.Addr{server.Addr(), server.ListenerAddr("internal")} {
+		if resp, err := sslResponse(addr); err != nil || resp[0] != 'N' {
+			t.Errorf("SSLRequest on %s = %q, %v, want 'N'", addr, resp, err)
+		}
+	}
+
+	// The vpn listener only accepts 10.8.0.0/16: the connection from
+	// 127.0.0.1 is closed unanswered.
+	if resp, err := sslResponse(server.ListenerAddr("vpn")); err == nil {
+		t.Errorf("SSLRequest on the vpn listener = %q, want the connection closed", resp)
+	}
+}
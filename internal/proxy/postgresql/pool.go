@@ -0,0 +1,974 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// ErrPoolClosed is returned when a session needs an upstream connection
+// from the transaction pool after the proxy started shutting down.
+var ErrPoolClosed = errors.New("upstream connection pool closed")
+
+// poolIdleTimeout is how long a pooled upstream connection stays open
+// without being lent to a session.
+const poolIdleTimeout = 5 * time.Minute
+
+// poolReapInterval is how often the idle pooled connections are checked
+// against poolIdleTimeout.
+const poolReapInterval = time.Minute
+
+// setApplicationNameQuery gives a pooled connection the application_name of
+// the session it is lent to, as a parameter: it comes from the client.
+const setApplicationNameQuery = "SELECT pg_catalog.set_config('application_name', $1, false)"
+
+// discardSessionQuery clears what a session changed with SQL on a pooled
+// connection before another session borrows it: settings, the role included,
+// temporary tables, LISTEN, advisory locks, cursors and prepared statements.
+const discardSessionQuery = "DISCARD ALL"
+
+// pooledConnIDs numbers the pooledConns, telling whether a pooled connection
+// changes sessions.
+var pooledConnIDs atomic.Uint64
+
+// poolKey identifies the upstream connections sessions can share: same
+// database, same upstream role, same read-only setting.
+type poolKey struct {
+	database uuid.UUID
+	username string
+	readOnly bool
+}
+
+// pooledBackend is an upstream connection of the transaction pool, with the
+// session state it carries over from one session to the next.
+type pooledBackend struct {
+	key      poolKey
+	conn     net.Conn
+	frontend *pgproto3.Frontend          // The proxy's own exchanges: authentication and state syncs
+	target   *store.Server               // The primary, or the read replica dialed
+	cancel   *pgproto3.BackendKeyData    // Upstream's cancellation key of the connection
+	params   []*pgproto3.ParameterStatus // Reported by upstream after authenticating
+	appName  string                      // application_name of the connection; empty when unknown
+
+	// startupAppName is the application_name the connection was opened
+	// with, which discardSessionQuery restores.
+	startupAppName string
+
+	// lentTo is the id of the pooledConn the connection was last lent to;
+	// zero until it is.
+	lentTo uint64
+
+	// statements are the prepared statements upstream holds, by name.
+	statements map[string]*pgproto3.Parse
+
+	idleSince time.Time
+}
+
+// backendSet is the pooled connections of a poolKey.
+type backendSet struct {
+	idle    []*pooledBackend
+	open    int                   // Connections open, idle or lent
+	waiters []chan *pooledBackend // Sessions waiting for a connection; nil wakes them to open one
+}
+
+// upstreamPool is the transaction pool of the PostgreSQL proxy: sessions
+// borrow an upstream connection for a transaction, and give it back once
+// upstream reports them idle, so that many sessions share few connections.
+type upstreamPool struct {
+	size int // Connections open per poolKey at most
+
+	mu     sync.Mutex
+	sets   map[poolKey]*backendSet
+	closed bool
+}
+
+// newUpstreamPool creates a transaction pool opening at most size upstream
+// connections per database, role and read-only setting.
+func newUpstreamPool(size int) *upstreamPool {
+	return &upstreamPool{size: size, sets: make(map[poolKey]*backendSet)}
+}
+
+// set returns the connections of key. The caller holds mu.
+func (p *upstreamPool) set(key poolKey) *backendSet {
+	set := p.sets[key]
+	if set == nil {
+		set = &backendSet{}
+		p.sets[key] = set
+	}
+
+	return set
+}
+
+// acquire lends an idle connection of key, opens one with open when the
+// pool has room, or waits for one to be given back until ctx is done or stop
+// closed.
+func (p *upstreamPool) acquire(
+	ctx context.Context,
+	key poolKey,
+	stop <-chan struct{},
+	open func() (*pooledBackend, error),
+) (*pooledBackend, error) {
+	for {
+		p.mu.Lock()
+
+		if p.closed {
+			p.mu.Unlock()
+
+			return nil, ErrPoolClosed
+		}
+
+		set := p.set(key)
+
+		if n := len(set.idle); n > 0 {
+			backend := set.idle[n-1]
+			set.idle = set.idle[:n-1]
+			p.mu.Unlock()
+
+			return backend, nil
+		}
+
+		if set.open < p.size {
+			set.open++
+			p.mu.Unlock()
+
+			backend, err := open()
+			if err != nil {
+				p.forget(key)
+
+				return nil, err
+			}
+
+			backend.key = key
+
+			return backend, nil
+		}
+
+		wait := make(chan *pooledBackend, 1)
+		set.waiters = append(set.waiters, wait)
+		p.mu.Unlock()
+
+		select {
+		case backend := <-wait:
+			if backend != nil {
+				return backend, nil
+			}
+			// A connection was closed: there is room to open one.
+		case <-ctx.Done():
+			p.abandon(set, wait)
+
+			return nil, fmt.Errorf("waiting for an upstream connection: %w", ctx.Err())
+		case <-stop:
+			p.abandon(set, wait)
+
+			return nil, net.ErrClosed
+		}
+	}
+}
+
+// abandon withdraws a waiter of set, passing on what it was handed meanwhile.
+func (p *upstreamPool) abandon(set *backendSet, wait chan *pooledBackend) {
+	p.mu.Lock()
+	set.waiters = slices.DeleteFunc(set.waiters, func(w chan *pooledBackend) bool { return w == wait })
+	p.mu.Unlock()
+
+	select {
+	case backend := <-wait:
+		if backend != nil {
+			p.release(backend)
+		} else {
+			p.mu.Lock()
+			p.wake(set, nil)
+			p.mu.Unlock()
+		}
+	default:
+	}
+}
+
+// wake hands backend to the first session waiting on set, nil telling it to
+// open a connection. It reports whether a session was waiting. The caller
+// holds mu.
+func (p *upstreamPool) wake(set *backendSet, backend *pooledBackend) bool {
+	if len(set.waiters) == 0 {
+		return false
+	}
+
+	wait := set.waiters[0]
+	set.waiters = set.waiters[1:]
+	wait <- backend
+
+	return true
+}
+
+// release gives back a connection upstream reported idle, lending it to a
+// waiting session if any.
+func (p *upstreamPool) release(backend *pooledBackend) {
+	_ = backend.conn.SetDeadline(time.Time{})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		p.closeLocked(backend)
+
+		return
+	}
+
+	set := p.set(backend.key)
+	if p.wake(set, backend) {
+		return
+	}
+
+	backend.idleSince = time.Now()
+	set.idle = append(set.idle, backend)
+}
+
+// discard closes a connection that cannot be lent again: its session left
+// in the middle of a transaction, or it failed.
+func (p *upstreamPool) discard(backend *pooledBackend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closeLocked(backend)
+}
+
+// closeLocked closes a connection and makes room for another. The caller
+// holds mu.
+func (p *upstreamPool) closeLocked(backend *pooledBackend) {
+	_ = backend.conn.Close()
+
+	p.forgetLocked(backend.key)
+}
+
+// forget makes room for another connection of key, one having failed to
+// open or been closed.
+func (p *upstreamPool) forget(key poolKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.forgetLocked(key)
+}
+
+// forgetLocked is forget, the caller holding mu.
+func (p *upstreamPool) forgetLocked(key poolKey) {
+	set := p.set(key)
+	set.open--
+
+	if !p.wake(set, nil) && set.open == 0 {
+		delete(p.sets, key)
+	}
+}
+
+// closeIdle closes the connections idle since before cutoff, and returns
+// how many it closed.
+func (p *upstreamPool) closeIdle(cutoff time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	closed := 0
+
+	for key, set := range p.sets {
+		kept := set.idle[:0]
+
+		for _, backend := range set.idle {
+			if backend.idleSince.Before(cutoff) {
+				_ = backend.conn.Close()
+				set.open--
+				closed++
+
+				continue
+			}
+
+			kept = append(kept, backend)
+		}
+
+		set.idle = kept
+
+		if set.open == 0 && len(set.waiters) == 0 {
+			delete(p.sets, key)
+		}
+	}
+
+	return closed
+}
+
+// close closes the idle connections and refuses to lend any more. The lent
+// ones are closed as they are given back.
+func (p *upstreamPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+
+	for _, set := range p.sets {
+		for _, backend := range set.idle {
+			_ = backend.conn.Close()
+		}
+
+		set.idle = nil
+	}
+}
+
+// runPoolReaper periodically closes the pooled connections left idle for
+// poolIdleTimeout.
+func (s *Server) runPoolReaper() {
+	ticker := time.NewTicker(poolReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if closed := s.pool.closeIdle(time.Now().Add(-poolIdleTimeout)); closed > 0 {
+				s.logger.DebugContext(s.ctx, "closed idle pooled upstream connections", slog.Int("count", closed))
+			}
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// connectPooled sets the session up on the transaction pool: its upstream
+// connection is a pooledConn, borrowing a pooled connection whenever the
+// client sends something, and giving it back once upstream is idle. The
+// client is greeted with the parameters of a pooled connection.
+func (s *Session) connectPooled() error {
+	key := poolKey{database: s.database.UID, username: s.database.Username, readOnly: s.grant.IsReadOnly()}
+	appName := buildApplicationName(s.user.Username, s.clientApplicationName)
+
+	conn := newPooledConn(s.ctx, s.logger, s.pool, key, appName, func() (*pooledBackend, error) {
+		return s.openPooledBackend(key, appName)
+	})
+
+	backend, err := s.pool.acquire(s.ctx, conn.key, conn.stop, conn.open)
+	if err != nil {
+		return err
+	}
+
+	startup := &upstreamStartup{backendKeyData: backend.cancel}
+
+	for _, param := range backend.params {
+		// The connection may have been opened for another client.
+		if param.Name == "application_name" {
+			param = &pgproto3.ParameterStatus{Name: param.Name, Value: conn.appName}
+		}
+
+		startup.paramStatus = append(startup.paramStatus, param)
+	}
+
+	conn.localAddr, conn.remoteAddr = backend.conn.LocalAddr(), backend.conn.RemoteAddr()
+	s.pool.release(backend)
+
+	s.upstreamConn = conn
+	s.upstreamFrontend = pgproto3.NewFrontend(conn, conn)
+	s.upstreamKey = backend.cancel // Cancels address the connection lent at the time instead
+
+	return s.completeStartup(startup)
+}
+
+// openPooledBackend opens an upstream connection for the transaction pool,
+// authenticated, and read-only when the pool's is.
+func (s *Session) openPooledBackend(key poolKey, appName string) (*pooledBackend, error) {
+	conn, target, err := s.dialUpstream()
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := s.startPooledBackend(conn, target, key, appName)
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	s.logger.DebugContext(s.ctx, "opened pooled upstream connection", slog.String("target", replicaAddr(target)))
+
+	return backend, nil
+}
+
+// startPooledBackend runs the startup of a new pooled connection.
+func (s *Session) startPooledBackend(conn net.Conn, target *store.Server, key poolKey, appName string) (*pooledBackend, error) {
+	if err := s.sendStartupMessage(conn); err != nil {
+		return nil, err
+	}
+
+	frontend := pgproto3.NewFrontend(conn, conn)
+
+	startup, err := s.handleUpstreamAuth(frontend)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.readOnly {
+		if err := setSessionReadOnly(frontend); err != nil {
+			return nil, fmt.Errorf("failed to set read-only mode: %w", err)
+		}
+	}
+
+	return &pooledBackend{
+		key:            key,
+		conn:           conn,
+		frontend:       frontend,
+		target:         target,
+		cancel:         startup.backendKeyData,
+		params:         startup.paramStatus,
+		appName:        appName,
+		startupAppName: appName,
+		statements:     make(map[string]*pgproto3.Parse),
+	}, nil
+}
+
+// reset clears the SQL-level state the session the connection was lent to
+// left on it, and sets it read-only again when its key is.
+func (b *pooledBackend) reset() error {
+	b.frontend.Send(&pgproto3.Query{String: discardSessionQuery})
+
+	if err := b.frontend.Flush(); err != nil {
+		return fmt.Errorf("reset pooled upstream connection: %w", err)
+	}
+
+	var failure *pgproto3.ErrorResponse
+
+	for ready := false; !ready; {
+		msg, err := b.frontend.Receive()
+		if err != nil {
+			return fmt.Errorf("reset pooled upstream connection: %w", err)
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.ErrorResponse:
+			failure = m
+		case *pgproto3.ReadyForQuery:
+			ready = true
+		}
+	}
+
+	if failure != nil {
+		return fmt.Errorf("reset pooled upstream connection: %s", failure.Message)
+	}
+
+	clear(b.statements)
+	b.appName = b.startupAppName
+
+	if b.key.readOnly {
+		if err := setSessionReadOnly(b.frontend); err != nil {
+			return fmt.Errorf("failed to set read-only mode: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// pooledConn is the upstream connection of a session under transaction
+// pooling. Writes borrow a pooled connection when the session has none,
+// first giving it the session's state: its prepared statements and
+// application_name. Reads follow upstream's replies, and give the
+// connection back once it reports being idle (ReadyForQuery outside a
+// transaction) with no reply pending and no extended-protocol batch open.
+//
+// Only the state pooledConn knows about carries over: what a session changes
+// with SQL (SET, LISTEN, advisory locks, temporary tables, WITH HOLD cursors,
+// PREPARE) is discarded before the connection it ran on is lent to another
+// session, whose grant may not allow it.
+type pooledConn struct {
+	id     uint64 // See pooledBackend.lentTo
+	pool   *upstreamPool
+	key    poolKey
+	open   func() (*pooledBackend, error)
+	ctx    context.Context //nolint:containedctx // The session's, bounding the waits for a connection
+	logger *slog.Logger
+
+	appName    string
+	localAddr  net.Addr
+	remoteAddr net.Addr
+
+	// stop is closed once the session tears down, ending a wait for a
+	// connection.
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// writes and statements, the session's prepared statements by name,
+	// belong to the writer; reads to the reader.
+	writes     wireScanner
+	reads      wireScanner
+	statements map[string]*pgproto3.Parse
+
+	// mu guards the connection lent and the state of its replies. cond is
+	// signaled when a connection is lent, or the reader is to stop waiting
+	// for one.
+	mu           sync.Mutex
+	cond         *sync.Cond
+	backend      *pooledBackend
+	pending      int  // ReadyForQuery messages expected: one per Query, FunctionCall and Sync
+	batchOpen    bool // Extended-protocol messages were sent since the last Sync
+	txStatus     byte // Of the last ReadyForQuery
+	terminated   bool // The client sent Terminate
+	closed       bool
+	readDeadline time.Time
+}
+
+// newPooledConn creates the upstream connection of a session borrowing
+// connections of key from pool, opening them with open.
+func newPooledConn(
+	ctx context.Context,
+	logger *slog.Logger,
+	pool *upstreamPool,
+	key poolKey,
+	appName string,
+	open func() (*pooledBackend, error),
+) *pooledConn {
+	conn := &pooledConn{
+		id:         pooledConnIDs.Add(1),
+		pool:       pool,
+		key:        key,
+		open:       open,
+		ctx:        ctx,
+		logger:     logger,
+		appName:    appName,
+		statements: make(map[string]*pgproto3.Parse),
+		stop:       make(chan struct{}),
+	}
+	conn.cond = sync.NewCond(&conn.mu)
+
+	return conn
+}
+
+// Write sends messages of the session upstream, borrowing a connection when
+// it has none. Terminate is not sent: pooled connections outlive sessions.
+func (c *pooledConn) Write(p []byte) (int, error) {
+	var messages []wireMessage
+
+	c.writes.scan(p, keepFrontendMessage, func(msg wireMessage) { messages = append(messages, msg) })
+
+	if len(messages) == 1 && messages[0].typ == 'X' {
+		c.terminate()
+
+		return len(p), nil
+	}
+
+	c.mu.Lock()
+
+	if c.backend == nil {
+		c.mu.Unlock()
+
+		backend, err := c.borrow()
+		if err != nil {
+			return 0, err
+		}
+
+		c.mu.Lock()
+
+		if c.closed {
+			c.mu.Unlock()
+			c.pool.release(backend)
+
+			return 0, net.ErrClosed
+		}
+
+		c.backend, c.txStatus = backend, 'I'
+		c.cond.Broadcast()
+	}
+
+	backend := c.backend
+
+	for _, msg := range messages {
+		c.track(backend, msg)
+	}
+
+	c.mu.Unlock()
+
+	n, err := backend.conn.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("pooled upstream connection: %w", err)
+	}
+
+	return n, nil
+}
+
+// borrow gets a connection from the pool, clears the state another session
+// left on it, and gives it the session's state.
+func (c *pooledConn) borrow() (*pooledBackend, error) {
+	backend, err := c.pool.acquire(c.ctx, c.key, c.stop, c.open)
+	if err != nil {
+		return nil, err
+	}
+
+	if backend.lentTo != 0 && backend.lentTo != c.id {
+		if err := backend.reset(); err != nil {
+			c.pool.discard(backend)
+
+			return nil, err
+		}
+	}
+
+	backend.lentTo = c.id
+
+	if err := c.syncState(backend); err != nil {
+		c.pool.discard(backend)
+
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+// syncBatch is a batch syncState sends: setting application_name, or
+// preparing a statement.
+type syncBatch struct {
+	stmt   *pgproto3.Parse // nil for application_name
+	failed bool
+}
+
+// syncState sets the session's application_name on a borrowed connection,
+// and prepares the session's statements it lacks, each in a batch of its
+// own so that one failing (a table dropped since) leaves the others alone.
+// Replies are not forwarded: the client already got them when its own
+// messages were first sent.
+func (c *pooledConn) syncState(backend *pooledBackend) error {
+	var batches []*syncBatch
+
+	frontend := backend.frontend
+
+	if backend.appName != c.appName {
+		// Replaces the unnamed statement, prepared again below.
+		frontend.Send(&pgproto3.Parse{Query: setApplicationNameQuery})
+		frontend.Send(&pgproto3.Bind{Parameters: [][]byte{[]byte(c.appName)}})
+		frontend.Send(&pgproto3.Execute{})
+		frontend.Send(&pgproto3.Sync{})
+		batches = append(batches, &syncBatch{})
+		delete(backend.statements, "")
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.statements)) {
+		stmt := c.statements[name]
+		if sameStatement(backend.statements[name], stmt) {
+			continue
+		}
+
+		if name != "" {
+			frontend.Send(&pgproto3.Close{ObjectType: 'S', Name: name})
+		}
+
+		frontend.Send(stmt)
+		frontend.Send(&pgproto3.Sync{})
+		batches = append(batches, &syncBatch{stmt: stmt})
+		delete(backend.statements, name)
+	}
+
+	if len(batches) == 0 {
+		return nil
+	}
+
+	if err := frontend.Flush(); err != nil {
+		return fmt.Errorf("sync pooled upstream connection: %w", err)
+	}
+
+	for i := 0; i < len(batches); {
+		msg, err := frontend.Receive()
+		if err != nil {
+			return fmt.Errorf("sync pooled upstream connection: %w", err)
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.ErrorResponse:
+			c.logger.WarnContext(c.ctx, "failed to restore session state on a pooled upstream connection",
+				slog.String("error", m.Message))
+			batches[i].failed = true
+		case *pgproto3.ReadyForQuery:
+			i++
+		}
+	}
+
+	for _, batch := range batches {
+		switch {
+		case batch.failed:
+		case batch.stmt == nil:
+			backend.appName = c.appName
+		default:
+			backend.statements[batch.stmt.Name] = batch.stmt
+		}
+	}
+
+	return nil
+}
+
+// sameStatement reports whether two Parse messages prepare the same
+// statement.
+func sameStatement(a, b *pgproto3.Parse) bool {
+	return a != nil && b != nil && a.Query == b.Query && slices.Equal(a.ParameterOIDs, b.ParameterOIDs)
+}
+
+// track accounts for a message sent on the connection lent: the replies it
+// calls for, and the prepared statements it creates or closes. The caller
+// holds mu.
+func (c *pooledConn) track(backend *pooledBackend, msg wireMessage) {
+	switch msg.typ {
+	case 'Q', 'F':
+		c.pending++
+	case 'S':
+		c.pending++
+		c.batchOpen = false
+	case 'P':
+		c.batchOpen = true
+
+		var parse pgproto3.Parse
+		if parse.Decode(msg.body) == nil {
+			c.statements[parse.Name] = &parse
+			backend.statements[parse.Name] = &parse
+		}
+	case 'C':
+		c.batchOpen = true
+
+		var closeMsg pgproto3.Close
+		if closeMsg.Decode(msg.body) == nil && closeMsg.ObjectType == 'S' {
+			delete(c.statements, closeMsg.Name)
+			delete(backend.statements, closeMsg.Name)
+		}
+	case 'B', 'D', 'E':
+		c.batchOpen = true
+	}
+}
+
+// idle reports whether the connection lent can be given back. The caller
+// holds mu.
+func (c *pooledConn) idle() bool {
+	return c.pending == 0 && !c.batchOpen && c.txStatus == 'I'
+}
+
+// Read receives upstream's replies, waiting for the session to borrow a
+// connection when it has none.
+func (c *pooledConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+
+	for c.backend == nil {
+		switch {
+		case c.terminated:
+			c.mu.Unlock()
+
+			return 0, io.EOF
+		case c.closed:
+			c.mu.Unlock()
+
+			return 0, net.ErrClosed
+		case !c.readDeadline.IsZero() && !time.Now().Before(c.readDeadline):
+			c.mu.Unlock()
+
+			return 0, os.ErrDeadlineExceeded
+		}
+
+		c.cond.Wait()
+	}
+
+	backend := c.backend
+	c.mu.Unlock()
+
+	n, err := backend.conn.Read(p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reads.scan(p[:n], keepReadyForQuery, func(msg wireMessage) {
+		if msg.typ != 'Z' || len(msg.body) == 0 {
+			return
+		}
+
+		c.pending = max(c.pending-1, 0)
+		c.txStatus = msg.body[0]
+	})
+
+	if err != nil {
+		if c.terminated {
+			return n, io.EOF
+		}
+
+		return n, fmt.Errorf("pooled upstream connection: %w", err)
+	}
+
+	if c.backend == backend && c.idle() {
+		c.backend = nil
+		c.pool.release(backend)
+	}
+
+	return n, nil
+}
+
+// terminate ends the session on the client's Terminate: reads report EOF,
+// as upstream closing the connection would.
+func (c *pooledConn) terminate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.terminated = true
+	c.dropBackend()
+	c.cond.Broadcast()
+}
+
+// dropBackend closes the connection lent, if any: the session leaves with
+// replies pending or a transaction open, which closing rolls back. The
+// caller holds mu.
+func (c *pooledConn) dropBackend() {
+	if c.backend != nil {
+		c.pool.discard(c.backend)
+		c.backend = nil
+	}
+}
+
+// interrupt ends a wait for a connection.
+func (c *pooledConn) interrupt() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// Close ends the session's use of the pool.
+func (c *pooledConn) Close() error {
+	c.interrupt()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	c.dropBackend()
+	c.cond.Broadcast()
+
+	return nil
+}
+
+// cancelTarget returns the key and target of the connection lent, to cancel
+// the query it runs; nil when the session has none. The connection may be
+// given back, and lent to another session, before the cancel reaches it: as
+// with other poolers, a cancel sent as a query completes can hit the next
+// one.
+func (c *pooledConn) cancelTarget() (*pgproto3.BackendKeyData, *store.Server) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.backend == nil {
+		return nil, nil
+	}
+
+	return c.backend.cancel, c.backend.target
+}
+
+// LocalAddr returns the local address of the first connection lent.
+func (c *pooledConn) LocalAddr() net.Addr { return c.localAddr }
+
+// RemoteAddr returns the remote address of the first connection lent.
+func (c *pooledConn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+// SetDeadline sets the read and write deadlines.
+func (c *pooledConn) SetDeadline(t time.Time) error {
+	_ = c.SetReadDeadline(t)
+
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline bounds the reads, the wait for a connection included.
+func (c *pooledConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDeadline = t
+
+	if c.backend != nil {
+		_ = c.backend.conn.SetReadDeadline(t)
+	}
+
+	c.cond.Broadcast()
+
+	if wait := time.Until(t); !t.IsZero() && wait > 0 {
+		time.AfterFunc(wait, c.cond.Broadcast)
+	}
+
+	return nil
+}
+
+// SetWriteDeadline bounds the writes. A deadline already past also ends a
+// wait for a connection, for good: it is how the session tears down.
+func (c *pooledConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.backend != nil {
+		_ = c.backend.conn.SetWriteDeadline(t)
+	}
+
+	if !t.IsZero() && !t.After(time.Now()) {
+		c.interrupt()
+	}
+
+	return nil
+}
+
+// wireMessage is a message of the protocol stream, its body only kept for
+// the types asked for.
+type wireMessage struct {
+	typ  byte
+	body []byte
+}
+
+// keepFrontendMessage keeps the bodies pooledConn decodes from the client's
+// messages: Parse and Close.
+func keepFrontendMessage(typ byte) bool { return typ == 'P' || typ == 'C' }
+
+// keepReadyForQuery keeps the bodies of ReadyForQuery messages.
+func keepReadyForQuery(typ byte) bool { return typ == 'Z' }
+
+// wireScanner follows the message boundaries of a protocol stream read or
+// written in chunks of any size.
+type wireScanner struct {
+	header  [5]byte
+	headerN int    // Header bytes of the current message scanned
+	left    int    // Body bytes of the current message not scanned yet
+	keep    bool   // Whether the current message's body is kept
+	body    []byte // Body of the current message scanned so far, when kept
+}
+
+// scan feeds p to the scanner, passing each message completed to onMessage,
+// with its body when keep asks for it.
+func (w *wireScanner) scan(p []byte, keep func(typ byte) bool, onMessage func(wireMessage)) {
+	for {
+		if w.headerN < len(w.header) {
+			if len(p) == 0 {
+				return
+			}
+
+			n := copy(w.header[w.headerN:], p)
+			w.headerN += n
+			p = p[n:]
+
+			if w.headerN < len(w.header) {
+				return
+			}
+
+			w.left = max(int(binary.BigEndian.Uint32(w.header[1:]))-4, 0)
+			w.keep = keep(w.header[0])
+		}
+
+		n := min(w.left, len(p))
+		if w.keep {
+			w.body = append(w.body, p[:n]...)
+		}
+
+		w.left -= n
+		p = p[n:]
+
+		if w.left > 0 {
+			return
+		}
+
+		onMessage(wireMessage{typ: w.header[0], body: w.body})
+		w.headerN, w.body = 0, nil
+	}
+}
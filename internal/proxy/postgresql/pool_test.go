@@ -0,0 +1,377 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// fakePoolUpstream is an upstream answering the messages pooled sessions
+// send, and recording the statements it is asked to prepare and the simple
+// queries it runs.
+type fakePoolUpstream struct {
+	mu       sync.Mutex
+	prepared []string // "name: query" of each Parse received
+	queries  []string
+	opened   int
+}
+
+// open returns a pooled connection to the fake upstream.
+func (f *fakePoolUpstream) open() (*pooledBackend, error) {
+	client, server := net.Pipe()
+
+	f.mu.Lock()
+	f.opened++
+	f.mu.Unlock()
+
+	go f.serve(server)
+
+	return &pooledBackend{
+		conn:           client,
+		frontend:       pgproto3.NewFrontend(client, client),
+		cancel:         &pgproto3.BackendKeyData{ProcessID: 1, SecretKey: []byte{1, 2, 3, 4}},
+		appName:        "initial",
+		startupAppName: "initial",
+		statements:     make(map[string]*pgproto3.Parse),
+	}, nil
+}
+
+func (f *fakePoolUpstream) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	backend := pgproto3.NewBackend(conn, conn)
+	status := byte('I')
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.Query:
+			f.mu.Lock()
+			f.queries = append(f.queries, m.String)
+			f.mu.Unlock()
+
+			switch m.String {
+			case "BEGIN":
+				status = 'T'
+			case "COMMIT":
+				status = 'I'
+			}
+
+			backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(m.String)})
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: status})
+		case *pgproto3.Parse:
+			f.mu.Lock()
+			f.prepared = append(f.prepared, m.Name+": "+m.Query)
+			f.mu.Unlock()
+
+			backend.Send(&pgproto3.ParseComplete{})
+		case *pgproto3.Close:
+			backend.Send(&pgproto3.CloseComplete{})
+		case *pgproto3.Bind:
+			backend.Send(&pgproto3.BindComplete{})
+		case *pgproto3.Execute:
+			backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+		case *pgproto3.Sync:
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: status})
+		}
+
+		if err := backend.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// takePrepared returns the statements prepared since the previous call.
+func (f *fakePoolUpstream) takePrepared() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	prepared := f.prepared
+	f.prepared = nil
+
+	return prepared
+}
+
+// takeQueries returns the simple queries run since the previous call.
+func (f *fakePoolUpstream) takeQueries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	queries := f.queries
+	f.queries = nil
+
+	return queries
+}
+
+// pooledClient is a session's side of a pooledConn.
+type pooledClient struct {
+	t        *testing.T
+	conn     *pooledConn
+	frontend *pgproto3.Frontend
+}
+
+func newPooledClient(t *testing.T, pool *upstreamPool, upstream *fakePoolUpstream, appName string) *pooledClient {
+	t.Helper()
+
+	conn := newPooledConn(context.Background(), slog.New(slog.DiscardHandler), pool, poolKey{username: "app"}, appName, upstream.open)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &pooledClient{t: t, conn: conn, frontend: pgproto3.NewFrontend(conn, conn)}
+}
+
+// send sends msgs, and returns the transaction status of the last
+// ReadyForQuery of the replies.
+func (c *pooledClient) send(msgs ...pgproto3.FrontendMessage) byte {
+	c.t.Helper()
+
+	ready := 0
+
+	for _, msg := range msgs {
+		c.frontend.Send(msg)
+
+		switch msg.(type) {
+		case *pgproto3.Query, *pgproto3.Sync:
+			ready++
+		}
+	}
+
+	if err := c.frontend.Flush(); err != nil {
+		c.t.Fatalf("Flush() error = %v", err)
+	}
+
+	var status byte
+
+	for ready > 0 {
+		msg, err := c.frontend.Receive()
+		if err != nil {
+			c.t.Fatalf("Receive() error = %v", err)
+		}
+
+		if m, ok := msg.(*pgproto3.ReadyForQuery); ok {
+			status = m.TxStatus
+			ready--
+		}
+	}
+
+	return status
+}
+
+func TestPooledConn_SharesConnectionsBetweenTransactions(t *testing.T) {
+	t.Parallel()
+
+	upstream := &fakePoolUpstream{}
+	pool := newUpstreamPool(1)
+
+	first := newPooledClient(t, pool, upstream, "first")
+	second := newPooledClient(t, pool, upstream, "second")
+
+	if status := first.send(&pgproto3.Parse{Name: "s1", Query: "SELECT 1"}, &pgproto3.Sync{}); status != 'I' {
+		t.Fatalf("status after Parse = %c, want I", status)
+	}
+
+	if status := first.send(&pgproto3.Query{String: "BEGIN"}); status != 'T' {
+		t.Fatalf("status after BEGIN = %c, want T", status)
+	}
+
+	// The only connection is in the first session's transaction: the second
+	// session waits for the COMMIT.
+	done := make(chan byte)
+
+	go func() { done <- second.send(&pgproto3.Parse{Name: "s1", Query: "SELECT 2"}, &pgproto3.Sync{}) }()
+
+	select {
+	case <-done:
+		t.Fatal("second session got the connection of an open transaction")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if status := first.send(&pgproto3.Query{String: "COMMIT"}); status != 'I' {
+		t.Fatalf("status after COMMIT = %c, want I", status)
+	}
+
+	if status := <-done; status != 'I' {
+		t.Fatalf("second session status = %c, want I", status)
+	}
+
+	upstream.takePrepared()
+
+	// Back on the connection, the first session gets its s1 back, and the
+	// application_name setting replaced the unnamed statement.
+	first.send(&pgproto3.Bind{PreparedStatement: "s1"}, &pgproto3.Execute{}, &pgproto3.Sync{})
+
+	want := []string{": " + setApplicationNameQuery, "s1: SELECT 1"}
+	if got := upstream.takePrepared(); !slices.Equal(got, want) {
+		t.Errorf("prepared on return = %q, want %q", got, want)
+	}
+
+	// Nothing to restore the next time.
+	first.send(&pgproto3.Bind{PreparedStatement: "s1"}, &pgproto3.Execute{}, &pgproto3.Sync{})
+
+	if got := upstream.takePrepared(); len(got) != 0 {
+		t.Errorf("prepared on reuse = %q, want none", got)
+	}
+
+	if upstream.opened != 1 {
+		t.Errorf("opened %d upstream connections, want 1", upstream.opened)
+	}
+}
+
+func TestPooledConn_ResetsConnectionBetweenSessions(t *testing.T) {
+	t.Parallel()
+
+	upstream := &fakePoolUpstream{}
+	pool := newUpstreamPool(1)
+
+	first := newPooledClient(t, pool, upstream, "app")
+	second := newPooledClient(t, pool, upstream, "app")
+
+	first.send(&pgproto3.Query{String: "SET ROLE admin"})
+	first.send(&pgproto3.Query{String: "SELECT 1"})
+
+	// The session gets its own state back.
+	if got, want := upstream.takeQueries(), []string{"SET ROLE admin", "SELECT 1"}; !slices.Equal(got, want) {
+		t.Errorf("queries of the first session = %q, want %q", got, want)
+	}
+
+	// Another session never sees it.
+	second.send(&pgproto3.Query{String: "SELECT 2"})
+
+	if got, want := upstream.takeQueries(), []string{discardSessionQuery, "SELECT 2"}; !slices.Equal(got, want) {
+		t.Errorf("queries of the second session = %q, want %q", got, want)
+	}
+}
+
+func TestPooledConn_DiscardsConnectionLeftInTransaction(t *testing.T) {
+	t.Parallel()
+
+	upstream := &fakePoolUpstream{}
+	pool := newUpstreamPool(1)
+
+	first := newPooledClient(t, pool, upstream, "app")
+	first.send(&pgproto3.Query{String: "BEGIN"})
+
+	if key, _ := first.conn.cancelTarget(); key == nil {
+		t.Error("cancelTarget() = nil within a transaction")
+	}
+
+	_ = first.conn.Close()
+
+	second := newPooledClient(t, pool, upstream, "app")
+	if status := second.send(&pgproto3.Query{String: "SELECT 1"}); status != 'I' {
+		t.Errorf("status on a new connection = %c, want I", status)
+	}
+
+	if upstream.opened != 2 {
+		t.Errorf("opened %d upstream connections, want 2", upstream.opened)
+	}
+
+	if key, _ := second.conn.cancelTarget(); key != nil {
+		t.Error("cancelTarget() of an idle session returned a key")
+	}
+}
+
+func TestPooledConn_TerminateEndsReads(t *testing.T) {
+	t.Parallel()
+
+	upstream := &fakePoolUpstream{}
+	client := newPooledClient(t, newUpstreamPool(1), upstream, "app")
+
+	client.send(&pgproto3.Query{String: "SELECT 1"})
+	client.frontend.Send(&pgproto3.Terminate{})
+
+	if err := client.frontend.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if _, err := client.conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("Read() after Terminate error = %v, want EOF", err)
+	}
+}
+
+func TestUpstreamPool_AcquireStops(t *testing.T) {
+	t.Parallel()
+
+	upstream := &fakePoolUpstream{}
+	pool := newUpstreamPool(1)
+
+	held, err := pool.acquire(context.Background(), poolKey{}, nil, upstream.open)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := pool.acquire(ctx, poolKey{}, nil, upstream.open); err == nil {
+		t.Error("acquire() on a full pool did not stop with its context")
+	}
+
+	pool.release(held)
+
+	if closed := pool.closeIdle(time.Now().Add(time.Minute)); closed != 1 {
+		t.Errorf("closeIdle() = %d, want 1", closed)
+	}
+
+	pool.close()
+
+	if _, err := pool.acquire(context.Background(), poolKey{}, nil, upstream.open); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("acquire() on a closed pool error = %v, want %v", err, ErrPoolClosed)
+	}
+}
+
+func TestWireScanner(t *testing.T) {
+	t.Parallel()
+
+	var stream []byte
+
+	for _, msg := range []pgproto3.FrontendMessage{
+		&pgproto3.Parse{Name: "s1", Query: "SELECT 1"},
+		&pgproto3.Sync{},
+		&pgproto3.Close{ObjectType: 'S', Name: "s1"},
+	} {
+		var err error
+
+		stream, err = msg.Encode(stream)
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+
+	for _, chunk := range []int{1, 3, len(stream)} {
+		var (
+			scanner wireScanner
+			types   []byte
+			parse   pgproto3.Parse
+		)
+
+		for rest := stream; len(rest) > 0; {
+			n := min(chunk, len(rest))
+			scanner.scan(rest[:n], keepFrontendMessage, func(msg wireMessage) {
+				types = append(types, msg.typ)
+
+				if msg.typ == 'P' {
+					if err := parse.Decode(msg.body); err != nil {
+						t.Errorf("Parse.Decode() error = %v", err)
+					}
+				}
+			})
+			rest = rest[n:]
+		}
+
+		if string(types) != "PSC" || parse.Name != "s1" || parse.Query != "SELECT 1" {
+			t.Errorf("chunks of %d: scanned %q, Parse %q/%q", chunk, types, parse.Name, parse.Query)
+		}
+	}
+}
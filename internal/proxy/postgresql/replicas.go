@@ -0,0 +1,204 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// replicaCheckInterval is how often the read replicas found down are dialed
+// again; sessions skip them meanwhile.
+const replicaCheckInterval = 10 * time.Second
+
+// replicaCheckTimeout bounds the dial of a read replica health check.
+const replicaCheckTimeout = 5 * time.Second
+
+// replicaKey identifies a read replica of a database.
+type replicaKey struct {
+	database uuid.UUID
+	addr     string
+}
+
+// replicaRouter spreads the sessions of read-only grants over the read
+// replicas of their database, round-robin, skipping the replicas a session
+// failed to reach until a health check reaches them again. The zero value is
+// ready to use.
+type replicaRouter struct {
+	mu   sync.Mutex
+	next map[uuid.UUID]int            // Round-robin cursor of each database
+	down map[replicaKey]*store.Server // Replicas found down, with the target to check
+}
+
+// replicaAddr returns the host:port a replica target is dialed at.
+func replicaAddr(target *store.Server) string {
+	return net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+}
+
+// candidates returns the read replicas of db not found down, starting with
+// the one whose turn it is. It returns none when db has no read replica, or
+// when they are all down.
+func (r *replicaRouter) candidates(db *store.Server) []*store.Server {
+	targets := db.ReadReplicaTargets()
+	if len(targets) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next == nil {
+		r.next = make(map[uuid.UUID]int)
+	}
+
+	start := r.next[db.UID] % len(targets)
+	r.next[db.UID] = (start + 1) % len(targets)
+
+	healthy := make([]*store.Server, 0, len(targets))
+
+	for i := range targets {
+		target := targets[(start+i)%len(targets)]
+		if _, down := r.down[replicaKey{db.UID, replicaAddr(target)}]; !down {
+			healthy = append(healthy, target)
+		}
+	}
+
+	return healthy
+}
+
+// markDown records a read replica a session failed to reach.
+func (r *replicaRouter) markDown(target *store.Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.down == nil {
+		r.down = make(map[replicaKey]*store.Server)
+	}
+
+	r.down[replicaKey{target.UID, replicaAddr(target)}] = target
+}
+
+// markUp puts a read replica back into rotation.
+func (r *replicaRouter) markUp(target *store.Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.down, replicaKey{target.UID, replicaAddr(target)})
+}
+
+// downReplicas returns the read replicas found down.
+func (r *replicaRouter) downReplicas() []*store.Server {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	targets := make([]*store.Server, 0, len(r.down))
+	for _, target := range r.down {
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// runReplicaChecks periodically dials the read replicas found down, putting
+// back into rotation those reached again.
+func (s *Server) runReplicaChecks() {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, target := range s.replicas.downReplicas() {
+				s.checkReplica(target)
+			}
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// checkReplica dials a read replica found down, and puts it back into
+// rotation once it answers.
+func (s *Server) checkReplica(target *store.Server) {
+	ctx, cancel := context.WithTimeout(s.ctx, replicaCheckTimeout)
+	defer cancel()
+
+	conn, err := shared.DialUpstream(ctx, s.store, s.encryptionKey, target)
+	if err != nil {
+		s.logger.DebugContext(ctx, "read replica still down",
+			slog.String("database", target.Name), slog.String("replica", replicaAddr(target)), slog.Any("error", err))
+
+		return
+	}
+
+	_ = conn.Close()
+
+	s.replicas.markUp(target)
+	s.logger.InfoContext(ctx, "read replica back up",
+		slog.String("database", target.Name), slog.String("replica", replicaAddr(target)))
+}
+
+// dialUpstream opens the session's upstream connection, TLS negotiated. The
+// sessions of read-only grants go to a read replica of the database when it
+// has some; a replica that cannot be reached is marked down and the next one
+// tried, the primary last. It returns the target dialed.
+func (s *Session) dialUpstream() (net.Conn, *store.Server, error) {
+	for _, replica := range s.readReplicas() {
+		conn, err := s.dialTarget(replica)
+		if err == nil {
+			s.logger.DebugContext(s.ctx, "routed to read replica", slog.String("replica", replicaAddr(replica)))
+
+			return conn, replica, nil
+		}
+
+		s.logger.WarnContext(s.ctx, "read replica unreachable, skipping it",
+			slog.String("replica", replicaAddr(replica)), slog.Any("error", err))
+		s.replicas.markDown(replica)
+	}
+
+	conn, err := s.dialTarget(s.database)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, s.database, nil
+}
+
+// readReplicas returns the read replicas the session may be routed to, in
+// the order to try them: none unless its grant is read-only.
+func (s *Session) readReplicas() []*store.Server {
+	if s.replicas == nil || s.replication != replicationNone || s.grant.AccessLevel() != store.AccessLevelReadOnly {
+		return nil
+	}
+
+	return s.replicas.candidates(s.database)
+}
+
+// dialTarget connects to target (directly, or tunneled through an SSH
+// bastion when the server row's via_uid is set) and negotiates TLS per its
+// ssl_mode (libpq semantics). TLS must be settled before any StartupMessage:
+// Postgres expects the SSLRequest preamble on a fresh connection, not
+// interleaved with protocol traffic.
+func (s *Session) dialTarget(target *store.Server) (net.Conn, error) {
+	conn, err := shared.DialUpstream(s.ctx, s.store, s.encryptionKey, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to upstream: %w", err)
+	}
+
+	upgraded, err := negotiateUpstreamSSL(s.ctx, conn, target)
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("upstream SSL negotiation: %w", err)
+	}
+
+	return upgraded, nil
+}
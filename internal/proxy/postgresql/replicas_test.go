@@ -0,0 +1,144 @@
+package postgresql
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestReplicaRouter_Candidates(t *testing.T) {
+	t.Parallel()
+
+	var router replicaRouter
+
+	db := &store.Server{UID: uuid.New(), Host: "primary", Port: 5432, ReadReplicas: []string{"r1", "r2", "r3"}}
+
+	hosts := func() []string {
+		var got []string
+		for _, target := range router.candidates(db) {
+			got = append(got, target.Host)
+		}
+
+		return got
+	}
+
+	for _, want := range []string{"r1", "r2", "r3", "r1"} {
+		if got := hosts(); len(got) != 3 || got[0] != want {
+			t.Fatalf("candidates() = %v, want 3 starting with %s", got, want)
+		}
+	}
+
+	down := db.ReadReplicaTargets()[1]
+	router.markDown(down)
+
+	for range 3 {
+		for _, host := range hosts() {
+			if host == "r2" {
+				t.Fatal("candidates() returned a replica found down")
+			}
+		}
+	}
+
+	if got := router.downReplicas(); len(got) != 1 || got[0].Host != "r2" {
+		t.Errorf("downReplicas() = %v, want r2", got)
+	}
+
+	router.markUp(down)
+
+	if got := hosts(); len(got) != 3 {
+		t.Errorf("candidates() after markUp = %v, want 3 replicas", got)
+	}
+
+	if got := router.candidates(&store.Server{UID: uuid.New(), Host: "alone"}); got != nil {
+		t.Errorf("candidates() without replicas = %v, want none", got)
+	}
+}
+
+func TestSession_DialUpstream_ReadReplicas(t *testing.T) {
+	t.Parallel()
+
+	listen := func() (net.Listener, int) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+
+		t.Cleanup(func() { _ = listener.Close() })
+
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+
+				_ = conn.Close()
+			}
+		}()
+
+		return listener, listener.Addr().(*net.TCPAddr).Port
+	}
+
+	_, primaryPort := listen()
+	_, replicaPort := listen()
+
+	// A replica nobody listens on any more.
+	dead, deadPort := listen()
+	_ = dead.Close()
+
+	var router replicaRouter
+
+	db := &store.Server{
+		UID:     uuid.New(),
+		Host:    "127.0.0.1",
+		Port:    primaryPort,
+		SSLMode: "disable",
+		ReadReplicas: []string{
+			"127.0.0.1:" + strconv.Itoa(deadPort),
+			"127.0.0.1:" + strconv.Itoa(replicaPort),
+		},
+	}
+
+	dial := func(accessLevel string) int {
+		s := newTestSession(accessLevel)
+		s.ctx = context.Background()
+		s.logger = slog.New(slog.DiscardHandler)
+		s.database = db
+		s.replicas = &router
+
+		conn, target, err := s.dialUpstream()
+		if err != nil {
+			t.Fatalf("dialUpstream() error = %v", err)
+		}
+
+		_ = conn.Close()
+
+		return target.Port
+	}
+
+	if port := dial("write"); port != primaryPort {
+		t.Errorf("write session dialed port %d, want the primary's %d", port, primaryPort)
+	}
+
+	for range 3 {
+		if port := dial("read"); port != replicaPort {
+			t.Errorf("read session dialed port %d, want the live replica's %d", port, replicaPort)
+		}
+	}
+
+	if down := router.downReplicas(); len(down) != 1 || down[0].Port != deadPort {
+		t.Errorf("downReplicas() = %v, want the dead replica", down)
+	}
+
+	router.markDown(db.ReadReplicaTargets()[1])
+
+	if port := dial("read"); port != primaryPort {
+		t.Errorf("read session with every replica down dialed port %d, want the primary's %d", port, primaryPort)
+	}
+}
@@ -0,0 +1,74 @@
+package postgresql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Replication modes a client can request through the "replication" startup
+// parameter.
+type replicationMode int
+
+const (
+	// replicationNone is a regular SQL session.
+	replicationNone replicationMode = iota
+	// replicationPhysical (replication=true) starts a physical walsender,
+	// which only accepts replication commands.
+	replicationPhysical
+	// replicationLogical (replication=database) starts a logical walsender
+	// bound to the database, which also accepts SQL.
+	replicationLogical
+)
+
+// parseReplicationMode interprets the "replication" startup parameter like
+// the server does: "database" for logical replication, or a boolean.
+func parseReplicationMode(value string) (replicationMode, error) {
+	switch strings.ToLower(value) {
+	case "", "false", "off", "no", "0":
+		return replicationNone, nil
+	case "true", "on", "yes", "1":
+		return replicationPhysical, nil
+	case "database":
+		return replicationLogical, nil
+	default:
+		return replicationNone, fmt.Errorf("%w: %q", ErrInvalidReplicationMode, value)
+	}
+}
+
+// startupValue is the "replication" startup parameter to send upstream for
+// this mode, or "" for a regular session.
+func (m replicationMode) startupValue() string {
+	switch m {
+	case replicationPhysical:
+		return "true"
+	case replicationLogical:
+		return "database"
+	default:
+		return ""
+	}
+}
+
+// checkReplication refuses replication connections unless the grant allows
+// them. Without this, the replication parameter would be dropped, the client
+// would get a plain SQL session, and its replication commands would fail
+// upstream with a confusing syntax error. A grant with a table allowlist or
+// dry_run never allows them: the replication stream is not inspected, and
+// its commands cannot run in the dry_run transaction.
+func (s *Session) checkReplication(value string) error {
+	mode, err := parseReplicationMode(value)
+	if err != nil {
+		s.sendError(sqlStateProtocolViolation, msgInvalidReplication)
+
+		return err
+	}
+
+	if mode != replicationNone && (!s.grant.AllowsReplication() || s.grant.RestrictsTables() || s.grant.DryRun()) {
+		s.sendError(sqlStateInsufficientPrivilege, msgReplicationDenied)
+
+		return ErrReplicationNotAllowed
+	}
+
+	s.replication = mode
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestParseReplicationMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    replicationMode
+		wantErr bool
+	}{
+		{value: "", want: replicationNone},
+		{value: "false", want: replicationNone},
+		{value: "OFF", want: replicationNone},
+		{value: "true", want: replicationPhysical},
+		{value: "1", want: replicationPhysical},
+		{value: "database", want: replicationLogical},
+		{value: "maybe", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseReplicationMode(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReplicationMode(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseReplicationMode(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckReplication(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		value    string
+		controls []string
+		tables   []string
+		wantErr  error
+		wantCode string
+		wantMode replicationMode
+	}{
+		{name: "regular session", value: "", wantMode: replicationNone},
+		{name: "denied by default", value: "true", wantErr: ErrReplicationNotAllowed, wantCode: "42501"},
+		{name: "logical denied by default", value: "database", wantErr: ErrReplicationNotAllowed, wantCode: "42501"},
+		{
+			name:     "allowed by grant",
+			value:    "database",
+			controls: []string{store.ControlAllowReplication},
+			wantMode: replicationLogical,
+		},
+		{
+			name:     "denied under a table allowlist",
+			value:    "database",
+			controls: []string{store.ControlAllowReplication},
+			tables:   []string{"public.orders"},
+			wantErr:  ErrReplicationNotAllowed,
+			wantCode: "42501",
+		},
+		{name: "invalid value", value: "maybe", wantErr: ErrInvalidReplicationMode, wantCode: "08P01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			proxyEnd, clientEnd := net.Pipe()
+			defer func() { _ = clientEnd.Close() }()
+
+			s := &Session{
+				clientConn: proxyEnd,
+				grant:      &store.Grant{Controls: tt.controls, AllowedTables: tt.tables},
+				logger:     slog.New(slog.DiscardHandler),
+				ctx:        context.Background(),
+			}
+
+			errCh := make(chan *pgproto3.ErrorResponse, 1)
+
+			go func() {
+				msg, err := pgproto3.NewFrontend(clientEnd, clientEnd).Receive()
+				if errResp, ok := msg.(*pgproto3.ErrorResponse); ok && err == nil {
+					errCh <- errResp
+				}
+
+				close(errCh)
+			}()
+
+			err := s.checkReplication(tt.value)
+			_ = proxyEnd.Close()
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkReplication(%q) error = %v, want %v", tt.value, err, tt.wantErr)
+			}
+
+			errResp := <-errCh
+			if tt.wantCode == "" {
+				if errResp != nil {
+					t.Errorf("checkReplication(%q) sent an error: %+v", tt.value, errResp)
+				}
+
+				if s.replication != tt.wantMode {
+					t.Errorf("s.replication = %v, want %v", s.replication, tt.wantMode)
+				}
+
+				return
+			}
+
+			if errResp == nil || errResp.Code != tt.wantCode || errResp.Severity != "FATAL" {
+				t.Errorf("checkReplication(%q) sent %+v, want FATAL %s", tt.value, errResp, tt.wantCode)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/fclairamb/dbbat/internal/cache"
 	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/dump"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -20,21 +21,39 @@ import (
 type Server struct {
 	store         *store.Store
 	encryptionKey []byte
-	queryStorage  config.QueryStorageConfig
+	queryStorage  *shared.QueryStorage // Replaced by SetQueryStorage on reload
 	dumpConfig    config.DumpConfig
-	authCache     *cache.AuthCache
-	logger        *slog.Logger
+	proxyProtocol config.ProxyProtocolConfig
+	sessionConfig config.SessionConfig // Idle and maximum duration timeouts of the sessions
+	// blockedMessage is the proxy-wide text for statements blocked by a
+	// grant control; see config.PGConfig.BlockedMessage.
+	blockedMessage string
+	authCache      *cache.AuthCache
+	logger         *slog.Logger
 
 	// tlsConfig terminates client TLS at the proxy. nil when TLS is
 	// disabled — sessions then refuse SSLRequest with 'N' as before.
 	tlsConfig *tls.Config
 
-	// listenerMu guards listener, which is written by Start and read
-	// concurrently by Addr/Shutdown (e.g. tests polling Addr while Start runs
-	// in a goroutine).
-	listenerMu sync.Mutex
-	listener   net.Listener
+	// extraPolicies are the policies of the pg.listeners, opened by Start
+	// next to the main listener.
+	extraPolicies []*listenerPolicy
+
+	// listenerMu guards listener and extraListeners, which are written by
+	// Start and read concurrently by Addr/Shutdown (e.g. tests polling Addr
+	// while Start runs in a goroutine).
+	listenerMu     sync.Mutex
+	listener       net.Listener
+	extraListeners map[string]net.Listener // By listener name
+
 	wg         sync.WaitGroup
+	logWrites  shared.LogWrites // Pending query log writes, flushed on shutdown
+	throttles  shared.Throttles // max_bytes_per_second throttles of the grants with live sessions
+	cancelKeys cancelKeys       // Cancellation keys handed out to the clients of live sessions
+	replicas   replicaRouter    // Read replica rotation and health of the databases
+	pool       *upstreamPool    // Transaction pool of upstream connections; nil when sessions have their own
+	stopOnce   sync.Once        // Closes the listeners, on Drain or Shutdown
+	stopped    chan struct{}    // Closed once the listeners are
 	shutdown   chan struct{}
 	ctx        context.Context //nolint:containedctx // Context is needed for the server lifecycle
 	cancel     context.CancelFunc
@@ -48,6 +67,8 @@ func NewServer(
 	dumpConfig config.DumpConfig,
 	authCache *cache.AuthCache,
 	pgConfig config.PGConfig,
+	proxyProtocol config.ProxyProtocolConfig,
+	sessionConfig config.SessionConfig,
 	logger *slog.Logger,
 ) (*Server, error) {
 	tlsConfig, err := loadTLS(pgConfig)
@@ -55,32 +76,87 @@ func NewServer(
 		return nil, fmt.Errorf("PostgreSQL proxy TLS setup: %w", err)
 	}
 
+	extraPolicies := make([]*listenerPolicy, 0, len(pgConfig.Listeners))
+
+	for _, listenerConfig := range pgConfig.Listeners {
+		policy, err := newListenerPolicy(listenerConfig, pgConfig.TLS, tlsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("PostgreSQL proxy %w", err)
+		}
+
+		extraPolicies = append(extraPolicies, policy)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
+	var pool *upstreamPool
+	if pgConfig.TransactionPooling() {
+		pool = newUpstreamPool(pgConfig.PoolSize)
+	}
+
 	return &Server{
-		store:         dataStore,
-		encryptionKey: encryptionKey,
-		queryStorage:  queryStorage,
-		dumpConfig:    dumpConfig,
-		authCache:     authCache,
-		tlsConfig:     tlsConfig,
-		logger:        logger,
-		shutdown:      make(chan struct{}),
-		ctx:           ctx,
-		cancel:        cancel,
+		store:          dataStore,
+		encryptionKey:  encryptionKey,
+		queryStorage:   shared.NewQueryStorage(queryStorage),
+		dumpConfig:     dumpConfig,
+		proxyProtocol:  proxyProtocol,
+		sessionConfig:  sessionConfig,
+		blockedMessage: pgConfig.BlockedMessage,
+		authCache:      authCache,
+		tlsConfig:      tlsConfig,
+		extraPolicies:  extraPolicies,
+		pool:           pool,
+		logger:         logger,
+		stopped:        make(chan struct{}),
+		shutdown:       make(chan struct{}),
+		ctx:            ctx,
+		cancel:         cancel,
 	}, nil
 }
 
-// Start starts the proxy server.
+// Start starts the proxy server on addr and on the additional listeners of
+// the configuration, each accepting connections in a loop of its own. It
+// returns once the server is shut down.
 func (s *Server) Start(addr string) error {
-	listener, err := net.Listen("tcp", addr)
+	listener, err := s.listen(addr)
 	if err != nil {
-		return fmt.Errorf("failed to listen: %w", err)
+		return err
+	}
+
+	extraListeners := make(map[string]net.Listener, len(s.extraPolicies))
+
+	for _, policy := range s.extraPolicies {
+		extra, err := s.listen(policy.addr)
+		if err != nil {
+			_ = listener.Close()
+			for _, l := range extraListeners {
+				_ = l.Close()
+			}
+
+			return fmt.Errorf("listener %s: %w", policy.name, err)
+		}
+
+		extraListeners[policy.name] = extra
 	}
 
-	s.setListener(listener)
+	s.setListeners(listener, extraListeners)
 	s.logger.InfoContext(s.ctx, "Proxy server listening", slog.String("addr", addr))
 
+	for _, policy := range s.extraPolicies {
+		s.logger.InfoContext(s.ctx, "Proxy server listening",
+			slog.String("listener", policy.name),
+			slog.String("addr", policy.addr),
+			slog.Int("databases", len(policy.databases)),
+			slog.Int("allowed_networks", len(policy.allowedNetworks)),
+			slog.Bool("tls", policy.tlsConfig != nil))
+
+		go s.acceptLoop(extraListeners[policy.name], policy)
+	}
+
+	if !sqlParserAvailable {
+		s.logger.WarnContext(s.ctx, "SQL parser unavailable (built without cgo): grant controls classify statements by keywords")
+	}
+
 	// Start dump cleanup goroutine if dumps are enabled
 	if s.dumpConfig.Dir != "" {
 		if err := os.MkdirAll(s.dumpConfig.Dir, 0o755); err != nil {
@@ -90,13 +166,41 @@ func (s *Server) Start(addr string) error {
 		}
 	}
 
-	// Accept connections
+	go s.runReplicaChecks()
+
+	if s.pool != nil {
+		go s.runPoolReaper()
+	}
+
+	s.acceptLoop(listener, &listenerPolicy{name: "main", addr: addr, tlsConfig: s.tlsConfig})
+
+	return nil
+}
+
+// listen opens a listener on addr, decoding PROXY protocol headers when
+// enabled.
+func (s *Server) listen(addr string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	if s.proxyProtocol.Enabled {
+		listener = shared.NewProxyProtocolListener(listener, s.proxyProtocol.TrustedNetworks())
+	}
+
+	return listener, nil
+}
+
+// acceptLoop serves the connections of listener under policy until the
+// server is shut down.
+func (s *Server) acceptLoop(listener net.Listener, policy *listenerPolicy) {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
 			select {
-			case <-s.shutdown:
-				return nil
+			case <-s.stopped:
+				return
 			default:
 				s.logger.ErrorContext(s.ctx, "failed to accept connection", slog.Any("error", err))
 
@@ -108,7 +212,10 @@ func (s *Server) Start(addr string) error {
 
 		go func() {
 			defer s.wg.Done()
-			s.handleConnection(conn)
+			defer shared.TrackSession(store.ProtocolPostgreSQL)()
+			defer shared.RecoverPanic(s.ctx, s.logger, "postgresql session")
+
+			s.handleConnection(conn, policy)
 		}()
 	}
 }
@@ -125,11 +232,26 @@ func (s *Server) Addr() net.Addr {
 	return listener.Addr()
 }
 
-// setListener stores the active listener under the guard.
-func (s *Server) setListener(l net.Listener) {
+// ListenerAddr returns the bound address of the additional listener name, or
+// nil if it is not listening.
+func (s *Server) ListenerAddr(name string) net.Addr {
 	s.listenerMu.Lock()
 	defer s.listenerMu.Unlock()
-	s.listener = l
+
+	listener, ok := s.extraListeners[name]
+	if !ok {
+		return nil
+	}
+
+	return listener.Addr()
+}
+
+// setListeners stores the active listeners under the guard.
+func (s *Server) setListeners(main net.Listener, extra map[string]net.Listener) {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	s.listener = main
+	s.extraListeners = extra
 }
 
 // getListener reads the active listener under the guard.
@@ -140,17 +262,49 @@ func (s *Server) getListener() net.Listener {
 	return s.listener
 }
 
+// SetQueryStorage replaces the query storage limits of the sessions started
+// from now on; the sessions in progress keep theirs.
+func (s *Server) SetQueryStorage(cfg config.QueryStorageConfig) {
+	s.queryStorage.Store(cfg)
+}
+
+// Drain stops accepting connections and waits, until ctx is done, for the
+// sessions in progress to end. Shutdown ends the remaining ones.
+func (s *Server) Drain(ctx context.Context) error {
+	s.stopAccepting(ctx)
+
+	return shared.WaitSessions(ctx, &s.wg)
+}
+
+// stopAccepting closes the listeners, once.
+func (s *Server) stopAccepting(ctx context.Context) {
+	s.stopOnce.Do(func() {
+		close(s.stopped)
+
+		s.listenerMu.Lock()
+		listeners := make([]net.Listener, 0, 1+len(s.extraListeners))
+		if s.listener != nil {
+			listeners = append(listeners, s.listener)
+		}
+		for _, listener := range s.extraListeners {
+			listeners = append(listeners, listener)
+		}
+		s.listenerMu.Unlock()
+
+		for _, listener := range listeners {
+			if err := listener.Close(); err != nil {
+				s.logger.ErrorContext(ctx, "failed to close listener", slog.Any("error", err))
+			}
+		}
+	})
+}
+
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopAccepting(ctx)
 	close(s.shutdown)
 	s.cancel()
 
-	if listener := s.getListener(); listener != nil {
-		if err := listener.Close(); err != nil {
-			s.logger.ErrorContext(ctx, "failed to close listener", slog.Any("error", err))
-		}
-	}
-
 	// Wait for all connections to finish (with timeout from context)
 	done := make(chan struct{})
 
@@ -159,29 +313,61 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		close(done)
 	}()
 
+	var err error
+
 	select {
 	case <-done:
 		s.logger.InfoContext(ctx, "Proxy server shutdown complete")
-
-		return nil
 	case <-ctx.Done():
 		s.logger.WarnContext(ctx, "Proxy server shutdown timeout")
 
-		return ctx.Err()
+		err = ctx.Err()
 	}
+
+	if s.pool != nil {
+		s.pool.close()
+	}
+
+	// Sessions log their queries in the background: wait for those writes
+	// before the caller closes the store.
+	s.logWrites.Flush(ctx, s.logger)
+
+	return err
 }
 
-// handleConnection handles a single client connection.
-func (s *Server) handleConnection(clientConn net.Conn) {
+// handleConnection handles a single client connection accepted by the
+// listener of policy.
+func (s *Server) handleConnection(clientConn net.Conn, policy *listenerPolicy) {
 	defer func() {
 		if err := clientConn.Close(); err != nil {
 			s.logger.ErrorContext(s.ctx, "failed to close client connection", slog.Any("error", err))
 		}
 	}()
 
-	s.logger.DebugContext(s.ctx, "New connection", slog.Any("remote_addr", clientConn.RemoteAddr()))
+	s.logger.DebugContext(s.ctx, "New connection", slog.Any("remote_addr", clientConn.RemoteAddr()), slog.String("listener", policy.name))
+
+	// Refused before TLS, like a firewall would: the client only sees the
+	// connection close.
+	if !policy.acceptsClient(clientConn.RemoteAddr()) {
+		s.logger.WarnContext(s.ctx, "Connection refused by listener policy",
+			slog.String("listener", policy.name), slog.Any("remote_addr", clientConn.RemoteAddr()))
+
+		return
+	}
 
-	session := NewSession(clientConn, s.store, s.encryptionKey, s.logger, s.ctx, s.queryStorage, s.dumpConfig, s.authCache, s.tlsConfig)
+	// Canceled when the session ends, so nothing started for it outlives it.
+	sessionCtx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	session := NewSession(clientConn, s.store, s.encryptionKey, s.logger, sessionCtx, s.queryStorage.Load(), s.dumpConfig, s.authCache, policy.tlsConfig)
+	session.listener = policy
+	session.blockedMessage = s.blockedMessage
+	session.sessionConfig = s.sessionConfig
+	session.logWrites = &s.logWrites
+	session.throttles = &s.throttles
+	session.cancelKeys = &s.cancelKeys
+	session.replicas = &s.replicas
+	session.pool = s.pool
 	if err := session.Run(); err != nil {
 		s.logger.ErrorContext(s.ctx, "Session error", slog.Any("error", err), slog.Any("remote_addr", clientConn.RemoteAddr()))
 	}
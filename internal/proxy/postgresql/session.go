@@ -10,6 +10,7 @@ import (
 	"log/slog"
 	"net"
 	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -20,7 +21,9 @@ import (
 	"github.com/fclairamb/dbbat/internal/cache"
 	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/dump"
+	"github.com/fclairamb/dbbat/internal/lineage"
 	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/spill"
 	"github.com/fclairamb/dbbat/internal/store"
 )
 
@@ -29,19 +32,39 @@ type pendingQuery struct {
 	sql        string
 	startTime  time.Time
 	parameters *store.QueryParameters
+	timer      shared.QueryTimer
 
 	// Result capture state
 	columnNames   []string         // From RowDescription
 	columnOIDs    []uint32         // Type OIDs for decoding
+	tables        []string         // Tables of sql, for result masking; set with the columns when masking
 	capturedRows  []store.QueryRow // Accumulated result rows
 	capturedBytes int64            // Total bytes captured
 	rowNumber     int              // Current row counter
 	truncated     bool             // True if limits exceeded
+	spill         *spill.Writer    // Rows past the limits, when spilled
+
+	// stmt is the executed prepared statement; nil for simple queries.
+	stmt *preparedStatement
+	// dryRun is set when the query runs in a transaction the proxy rolls
+	// back, under the dry_run control.
+	dryRun bool
+	// awaitsApproval is set when the query is a write held for an
+	// administrator's approval, under the write_requires_approval control.
+	awaitsApproval bool
+
+	// next are the statements of a multi-statement Query message left after
+	// sql, each logged as its own query once upstream completes the previous
+	// one. batch is the whole message then, which approvals are filed for.
+	next  []string
+	batch string
 }
 
 // preparedStatement tracks a prepared statement with its type information.
 type preparedStatement struct {
 	sql string
+	// write is set when the statement modifies data or the schema.
+	write bool
 	// typeOIDs are the parameter types the *client* declared in Parse. Most
 	// modern drivers (pgx included) leave this empty and let the server infer
 	// the types.
@@ -50,6 +73,9 @@ type preparedStatement struct {
 	// ParameterDescription answering a Describe('S'). They are the fallback
 	// used to decode binary bind parameters when typeOIDs is empty.
 	resolvedOIDs []uint32
+	// resultColumns are the mask_pii columns of the statement's result, set
+	// from the RowDescription answering a Describe('S').
+	resultColumns *piiColumns
 }
 
 // portalState tracks a portal with its bound parameters.
@@ -63,6 +89,8 @@ type copyState struct {
 	direction   string // "out" (COPY TO) or "in" (COPY FROM)
 	format      byte   // 0=text, 1=binary
 	columnNames []string
+	columnOIDs  []uint32 // Type OIDs of the columns, for binary COPY; 0 when unknown
+	tables      []string // Tables of the COPY statement, for result masking
 	dataChunks  [][]byte // Raw CopyData chunks
 	totalBytes  int64
 	truncated   bool
@@ -73,10 +101,11 @@ type extendedQueryState struct {
 	// mu guards preparedStatements and pendingDescribes, which are touched from
 	// both proxy goroutines: the client→upstream one on Parse/Bind/Execute/
 	// Close/Describe, and the upstream→client one on ParameterDescription.
+	// It is taken after Session.queryMu, never before.
 	mu                 sync.Mutex
 	preparedStatements map[string]*preparedStatement // stmt name -> prepared statement
 	portals            map[string]*portalState       // portal name -> portal state
-	pendingQueries     []*pendingQuery               // Queue for multiple Execute before Sync
+	pendingQueries     []*pendingQuery               // Queue for multiple Execute before Sync; guarded by Session.queryMu
 	// pendingDescribes is a FIFO of statement names for which the client sent
 	// Describe('S', name) and the server has not yet answered with a
 	// ParameterDescription. The server answers describes in order, so the head
@@ -98,24 +127,68 @@ type Session struct {
 	dumpWriter    *dump.Writer
 	authCache     *cache.AuthCache
 	tlsConfig     *tls.Config // nil when TLS is disabled
+	// listener is the policy of the listener that accepted the session; nil
+	// allows everything.
+	listener *listenerPolicy
 
 	// Session state
-	user                   *store.User
-	database               *store.Server
-	grant                  *store.Grant
-	connectionUID          uuid.UUID
-	clientBackend          *pgproto3.Backend  // To communicate with client (we're the server)
-	upstreamFrontend       *pgproto3.Frontend // To communicate with upstream (we're the client)
-	authenticated          bool
-	bufferedParamStatus    []*pgproto3.ParameterStatus // Buffer for ParameterStatus during upstream auth
-	bufferedBackendKeyData *pgproto3.BackendKeyData    // Buffer for BackendKeyData during upstream auth
-	currentQuery           *pendingQuery               // Track query in progress for logging
-	extendedState          *extendedQueryState         // State for Extended Query Protocol
-	clientApplicationName  string                      // application_name provided by the client
-	copyState              *copyState                  // Track COPY operation in progress
-	upstreamSCRAM          *scramClient                // SCRAM-SHA-256 state for upstream SASL auth
-	guard                  *shared.LimitGuard          // Mid-stream time/bandwidth limit enforcement
-	revocation             *cache.RevocationHandle     // Signaled when this session's grant is revoked mid-flight
+	user                  *store.User
+	database              *store.Server
+	grant                 *store.Grant
+	proxyCredential       *store.ProxyCredential // One-time credential the client logged in with; nil for users
+	connectionUID         uuid.UUID
+	clientBackend         *pgproto3.Backend  // To communicate with client (we're the server)
+	upstreamFrontend      *pgproto3.Frontend // To communicate with upstream (we're the client)
+	authenticated         bool
+	extendedState         *extendedQueryState      // State for Extended Query Protocol
+	clientApplicationName string                   // application_name provided by the client
+	clientInfo            *store.ClientInfo        // Client-declared startup parameters, recorded on the connection
+	clientLocale          string                   // Language of client-facing error messages
+	messageData           messageData              // User/database names for client-facing error messages
+	blockedMessage        string                   // Proxy-wide text for statements blocked by a grant control
+	sessionConfig         config.SessionConfig     // Proxy-wide idle and maximum duration timeouts
+	logWrites             *shared.LogWrites        // Server-wide tracker of background query log writes
+	replication           replicationMode          // Replication mode requested by the client (and allowed by its grant)
+	results               resultControls           // mask_pii/max_rows state of the result being streamed
+	masker                shared.LazyColumnMasker  // The database's result masking, applied to captured rows
+	upstreamSCRAM         *scramClient             // SCRAM-SHA-256 state for upstream SASL auth
+	guard                 *shared.LimitGuard       // Mid-stream time/bandwidth limit enforcement
+	throttles             *shared.Throttles        // Server-wide max_bytes_per_second throttles, shared per grant
+	throttle              *shared.Throttle         // The grant's max_bytes_per_second throttle; nil when unthrottled
+	revocation            *cache.RevocationHandle  // Signaled when this session's grant is revoked mid-flight
+	termination           *cache.TerminationHandle // Signaled when an admin terminates this connection
+	upstreamKey           *pgproto3.BackendKeyData // Upstream backend's cancellation key, for CancelRequest
+	cancelKeys            *cancelKeys              // Server-wide cancellation keys handed out to clients
+	replicas              *replicaRouter           // Server-wide read replica rotation; nil routes every session to the primary
+	pool                  *upstreamPool            // Server-wide transaction pool; nil gives the session an upstream connection of its own
+	clientKey             *pgproto3.BackendKeyData // The cancellation key handed out to the client; nil when upstream's
+	dryRun                dryRunState              // Transaction wrapping of the dry_run control
+	held                  heldStatement            // Approval wait of the write_requires_approval control
+
+	// relayCtx is canceled once either relay stops, releasing the other one
+	// from a throttle wait.
+	relayCtx context.Context //nolint:containedctx // Shared by the two relay goroutines
+
+	// disconnectReason is the store.DisconnectReason* value of whatever ended
+	// the session first: the relay that returned, or the limit watchdog.
+	disconnectReason atomic.Pointer[string]
+
+	// queryMu guards the in-flight query state, which both relay goroutines
+	// touch: the client→upstream one starts queries (checking the grant's
+	// quotas) and feeds COPY FROM data, the upstream→client one captures
+	// results, tracks COPY and completes queries (adding them to the grant's
+	// QueryCount and BytesTransferred, which queryMu guards too). Each
+	// goroutine holds it while processing a message and releases it before
+	// relaying the message, so neither blocks on the other's socket. A
+	// pendingQuery's own fields belong to the upstream→client goroutine once
+	// the query is published here.
+	queryMu      sync.Mutex
+	currentQuery *pendingQuery // Query being completed (simple protocol, or popped from pendingQueries)
+	copyState    *copyState    // COPY operation in progress
+	// columnTypes are the type OIDs of the result columns seen so far, by
+	// name, to decode binary COPY. Only touched from the upstream→client
+	// goroutine.
+	columnTypes map[string]uint32
 
 	// Wire-level byte counters for the client-facing socket. Reads count as
 	// bytes-from-client (queries the client sent), writes count as
@@ -132,6 +205,23 @@ type Session struct {
 	// Only mutated from the upstream→client goroutine where logQuery runs,
 	// so no atomic needed.
 	lastBytesSnapshot int64
+
+	// quotaWarner tells when to warn the client that its grant's quotas are
+	// nearly used up; nil when the warnings are disabled. Guarded by queryMu.
+	quotaWarner *shared.QuotaWarner
+
+	// txStatus is the transaction status of the last ReadyForQuery relayed
+	// to the client: 'I' when idle, 'T' in a transaction block, 'E' in a
+	// failed one, 0 before the first query. The ReadyForQuery the proxy sends
+	// after refusing a statement reports it. Guarded by queryMu.
+	txStatus byte
+
+	// unloggedRows counts the result rows forwarded since the previous query
+	// was logged, which adds them to the grant's RowsReturned.
+	// rowQuotaExceeded is set once a row is held back because the grant's
+	// max_rows_returned quota is used up. Both are guarded by queryMu.
+	unloggedRows     int64
+	rowQuotaExceeded bool
 }
 
 // NewSession creates a new session.
@@ -185,6 +275,12 @@ func (s *Session) Run() error {
 		return fmt.Errorf("SSL negotiation failed: %w", err)
 	}
 
+	// A CancelRequest comes alone on a connection of its own, in place of
+	// the StartupMessage (after TLS negotiation, with recent clients).
+	if s.isCancelRequest() {
+		return s.serveCancelRequest()
+	}
+
 	// Create backend for client (we're the server to the client). Read side
 	// uses the buffered reader so any bytes peeked during negotiation are
 	// still in scope.
@@ -197,17 +293,26 @@ func (s *Session) Run() error {
 
 	// Connect to upstream (this creates s.upstreamFrontend)
 	if err := s.connectUpstream(); err != nil {
+		// Upstream authentication errors were already forwarded verbatim.
+		if !errors.Is(err, ErrUpstreamAuthFailed) {
+			s.sendError(sqlStateConnectionFailure, msgUpstreamUnavailable)
+		}
+
 		return fmt.Errorf("upstream connection failed: %w", err)
 	}
 
 	// Create connection record
 	sourceIP := store.ExtractSourceIP(s.clientConn.RemoteAddr())
 
-	conn, err := s.store.CreateConnection(s.ctx, s.user.UID, s.database.UID, sourceIP)
+	storeCtx, cancel := shared.StoreContext(s.ctx)
+	conn, err := s.store.CreateGrantConnection(storeCtx, s.grant, sourceIP, s.clientInfo)
+	cancel()
+
 	if err != nil {
 		s.logger.ErrorContext(s.ctx, "failed to create connection record", slog.Any("error", err))
 	} else {
 		s.connectionUID = conn.UID
+		s.linkProxyCredential()
 	}
 
 	s.logger = s.logger.With("connection_uid", s.connectionUID)
@@ -250,48 +355,199 @@ func (s *Session) proxyMessages() error {
 	// deregistered in cleanup.
 	s.revocation = s.store.Revocations().Register(s.grant.UID)
 
+	// Register the connection so an admin can terminate it through the API.
+	// Deregistered in cleanup.
+	s.termination = s.store.Terminations().Register(s.connectionUID)
+
+	// Let the client's CancelRequests reach the session. Released in cleanup.
+	if s.clientKey != nil {
+		s.cancelKeys.register(s.clientKey, s)
+	}
+
 	// Build the limit guard once the grant is known, then run a watchdog that
 	// tears the session down if a limit is crossed (or the grant is revoked)
 	// while a query is blocked producing no traffic (the inline check in
 	// proxyUpstreamToClient handles the actively-streaming case with a clean
-	// error frame).
+	// error frame), or once the session timeouts are reached. A query in
+	// flight keeps the session from being idle.
+	idle, maxDuration := s.sessionConfig.Timeouts()
 	s.guard = shared.NewLimitGuard(s.grant, s.bytesFromClient, s.bytesToClient).
-		WithRevocation(s.revocation.Flag())
+		WithRevocation(s.revocation.Flag()).
+		WithExtension(s.revocation.Extension()).
+		WithTermination(s.termination.Flag()).
+		WithSessionTimeouts(idle, maxDuration, s.queryInFlight).
+		WithExpiryWarning(s.sessionConfig.ExpiryWarningLead(), s.sendExpiryNotice)
+	s.quotaWarner = shared.NewQuotaWarner(s.sessionConfig.QuotaWarningThresholds())
+
+	if rate, ok := s.grant.MaxBytesPerSecond(); ok {
+		s.throttle = s.throttles.Acquire(s.grant.UID, rate)
+	}
+
+	relayCtx, stopRelays := context.WithCancel(s.ctx)
+	defer stopRelays()
 
-	watchCtx, cancelWatch := context.WithCancel(s.ctx)
-	defer cancelWatch()
+	s.relayCtx = relayCtx
 
-	go s.guard.Watch(watchCtx, shared.DefaultLimitPollInterval, s.onLimitViolation)
+	go s.guard.Watch(relayCtx, shared.DefaultLimitPollInterval, s.onLimitViolation)
 
 	// Channel to receive errors from goroutines
 	errChan := make(chan error, 2)
 
 	// Client to upstream
 	go func() {
-		err := s.proxyClientToUpstream()
-		errChan <- err
+		errChan <- shared.Safe(s.ctx, s.logger, "postgresql relay", s.proxyClientToUpstream)
 	}()
 
 	// Upstream to client
 	go func() {
-		err := s.proxyUpstreamToClient()
-		errChan <- err
+		errChan <- shared.Safe(s.ctx, s.logger, "postgresql relay", s.proxyUpstreamToClient)
 	}()
 
 	// Wait for either direction to close or error
 	err := <-errChan
 
+	// A client gone mid-query abandons it, but upstream only notices when it
+	// writes results: a long query or a lock wait would keep running. Cancel
+	// it like the client's driver would have.
+	clientGone := s.clientDisconnected()
+	if clientGone && s.queryInFlight() {
+		s.cancelUpstreamQuery("client disconnected")
+	}
+
+	// Unblock the other relay, parked on its socket or in a throttle wait, so
+	// the session ends now rather than when the other peer happens to close.
+	stopRelays()
+
+	now := time.Now()
+	_ = s.clientConn.SetDeadline(now)
+	_ = s.upstreamConn.SetDeadline(now)
+
+	<-errChan
+
+	// The relays are done: log the query the client walked away from.
+	if clientGone {
+		s.persistAbortedQuery(ErrClientDisconnected)
+	}
+
 	return err
 }
 
+// endSession records why the session ends, unless an earlier cause already
+// did.
+func (s *Session) endSession(reason string) {
+	s.disconnectReason.CompareAndSwap(nil, &reason)
+}
+
+// clientDisconnected reports whether the session ends because of the client.
+func (s *Session) clientDisconnected() bool {
+	reason := s.disconnectReason.Load()
+
+	return reason != nil && (*reason == store.DisconnectReasonClientClosed || *reason == store.DisconnectReasonClientError)
+}
+
+// queryInFlight reports whether upstream is executing a query or a COPY.
+func (s *Session) queryInFlight() bool {
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
+	return s.getCurrentPendingQuery() != nil || s.copyState != nil
+}
+
+// sessionEndWriteTimeout bounds the write of the ErrorResponse telling the
+// client its session timed out or was terminated, so a client that stopped
+// reading does not hold the teardown.
+const sessionEndWriteTimeout = 5 * time.Second
+
+// upstreamCancelTimeout bounds the connection carrying a CancelRequest.
+const upstreamCancelTimeout = 5 * time.Second
+
+// cancelUpstreamQuery cancels the query running on the session's upstream
+// backend the way libpq's PQcancel does: a CancelRequest carrying the
+// backend's key, on a new connection that upstream closes without a reply.
+// Best effort: upstream gives no acknowledgement either way. reason is logged.
+func (s *Session) cancelUpstreamQuery(reason string) {
+	key, target := s.upstreamKey, s.database
+	if pooled, ok := s.upstreamConn.(*pooledConn); ok {
+		key, target = pooled.cancelTarget()
+	}
+
+	if key == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.ctx), upstreamCancelTimeout)
+	defer cancel()
+
+	conn, err := shared.DialUpstream(ctx, s.store, s.encryptionKey, target)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to connect upstream to cancel the query", slog.Any("error", err))
+
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	buf, err := (&pgproto3.CancelRequest{ProcessID: key.ProcessID, SecretKey: key.SecretKey}).Encode(nil)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to encode cancel request", slog.Any("error", err))
+
+		return
+	}
+
+	if _, err := conn.Write(buf); err != nil {
+		s.logger.WarnContext(ctx, "failed to send cancel request upstream", slog.Any("error", err))
+
+		return
+	}
+
+	// Upstream closes the connection once it has signaled the backend; wait
+	// for it so the cancel lands before the session's own connection closes.
+	_, _ = conn.Read(make([]byte, 1))
+
+	s.logger.InfoContext(ctx, "canceled the upstream query", slog.String("reason", reason))
+}
+
 // onLimitViolation is invoked by the limit watchdog when a time/bandwidth limit
 // is crossed. It force-closes both conns, unblocking whichever relay goroutine
 // is parked in a Read/Write so the session tears down. The idle case (a query
 // blocked without producing traffic) can only be terminated this way — there is
 // no message boundary at which to inject a clean ErrorResponse.
+//
+// A session timeout is reached between statements (or mid-statement for the
+// maximum duration), so the client is first told why with a FATAL
+// ErrorResponse, as PostgreSQL does for its own idle_session_timeout. So is a
+// grant that expires or is revoked: a client warned by sendExpiryNotice then
+// sees the expiry it was told about rather than a dropped connection.
 func (s *Session) onLimitViolation(err error) {
-	s.logger.WarnContext(s.ctx, "terminating session: grant no longer valid mid-stream",
-		slog.Any("error", err))
+	if endErr, ok := sessionEndError(err); ok {
+		s.logger.InfoContext(s.ctx, "terminating session", slog.Any("reason", err))
+
+		switch {
+		case errors.Is(err, shared.ErrSessionTerminated):
+			s.endSession(store.DisconnectReasonTerminated)
+		case errors.Is(err, shared.ErrSessionIdle):
+			s.endSession(store.DisconnectReasonIdleTimeout)
+		default:
+			s.endSession(store.DisconnectReasonMaxDuration)
+		}
+
+		if s.clientConn != nil {
+			_ = s.clientConn.SetWriteDeadline(time.Now().Add(sessionEndWriteTimeout))
+			s.writeClientError(endErr)
+		}
+	} else {
+		s.logger.WarnContext(s.ctx, "terminating session: grant no longer valid mid-stream",
+			slog.Any("error", err))
+
+		s.endSession(store.DisconnectReasonLimitExceeded)
+
+		if grantErr, ok := grantEndError(err); ok && s.clientConn != nil {
+			_ = s.clientConn.SetWriteDeadline(time.Now().Add(sessionEndWriteTimeout))
+			s.writeClientError(grantErr)
+		}
+	}
 
 	if s.upstreamConn != nil {
 		_ = s.upstreamConn.Close()
@@ -308,85 +564,151 @@ func (s *Session) proxyClientToUpstream() error {
 		msg, err := s.clientBackend.Receive()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
+				s.endSession(store.DisconnectReasonClientClosed)
+
 				return nil
 			}
 
+			s.endSession(store.DisconnectReasonClientError)
+
 			return fmt.Errorf("failed to receive from client: %w", err)
 		}
 
 		s.logger.InfoContext(s.ctx, "received message from client", slog.Any("message", msg))
 
-		// Handle query interception for Simple and Extended Query Protocols
-		var interceptErr error
-
-		switch m := msg.(type) {
-		case *pgproto3.Query:
-			interceptErr = s.handleQuery(m)
-		case *pgproto3.Parse:
-			interceptErr = s.handleParse(m)
-		case *pgproto3.Bind:
-			s.handleBind(m)
-		case *pgproto3.Execute:
-			interceptErr = s.handleExecute(m)
-		case *pgproto3.Describe:
-			s.handleDescribe(m)
-		case *pgproto3.Close:
-			s.handleClose(m)
-		case *pgproto3.CopyData:
-			// Client sending COPY data to server (COPY FROM)
-			if s.copyState != nil && s.copyState.direction == "in" {
-				s.captureCopyData(m.Data)
-			}
-		case *pgproto3.CopyDone:
-			// Client finished sending COPY data
-			if s.copyState != nil && s.copyState.direction == "in" {
-				s.logger.InfoContext(s.ctx, "COPY IN done (from client)", slog.Int64("total_bytes", s.copyState.totalBytes), slog.Bool("truncated", s.copyState.truncated))
-			}
-		case *pgproto3.CopyFail:
-			// Client aborted COPY
-			if s.copyState != nil {
-				s.logger.WarnContext(s.ctx, "COPY failed", slog.String("message", m.Message))
-				s.copyState = nil
-			}
+		// Upstream closes its side once it gets Terminate: claim the
+		// disconnect for the client before that EOF can.
+		if _, ok := msg.(*pgproto3.Terminate); ok {
+			s.endSession(store.DisconnectReasonClientClosed)
 		}
 
-		if interceptErr != nil {
+		if interceptErr := s.interceptClientMessage(msg); interceptErr != nil {
 			s.sendQueryError(interceptErr)
 
 			continue
 		}
 
+		if approvalErr := s.awaitApproval(msg); approvalErr != nil {
+			s.sendQueryError(approvalErr)
+
+			continue
+		}
+
+		if err := s.throttleForward(msg); err != nil {
+			return err
+		}
+
+		s.markForwarded(msg)
+
 		// Forward message to upstream
-		s.upstreamFrontend.Send(msg)
+		s.sendUpstream(msg)
 
 		if err := s.upstreamFrontend.Flush(); err != nil {
+			s.endSession(store.DisconnectReasonUpstreamError)
+
 			return fmt.Errorf("failed to send to upstream: %w", err)
 		}
 	}
 }
 
-// sendQueryError sends a query error to the client.
-func (s *Session) sendQueryError(queryErr error) {
-	errMsg := &pgproto3.ErrorResponse{
-		Severity: "ERROR",
-		Code:     "42000",
-		Message:  queryErr.Error(),
+// markForwarded marks the query a Query or Execute message carries as
+// forwarded upstream, for its latency breakdown. It is called before the
+// message is sent, so that no reply can be timed before it.
+func (s *Session) markForwarded(msg pgproto3.FrontendMessage) {
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
+	var query *pendingQuery
+
+	switch msg.(type) {
+	case *pgproto3.Query:
+		query = s.currentQuery
+	case *pgproto3.Execute:
+		if n := len(s.extendedState.pendingQueries); n > 0 {
+			query = s.extendedState.pendingQueries[n-1]
+		}
 	}
 
-	errBuf, encodeErr := errMsg.Encode(nil)
-	if encodeErr != nil {
-		s.logger.ErrorContext(s.ctx, "failed to encode error message", slog.Any("error", encodeErr))
+	if query != nil {
+		query.timer.Forwarded(time.Now())
+	}
+}
 
-		return
+// answeredQuery returns the query upstream is replying to: the first queued
+// Execute, else the current query.
+func (s *Session) answeredQuery() *pendingQuery {
+	if len(s.extendedState.pendingQueries) > 0 {
+		return s.extendedState.pendingQueries[0]
 	}
 
-	if _, err := s.clientConn.Write(errBuf); err != nil {
-		s.logger.ErrorContext(s.ctx, "failed to write error to client", slog.Any("error", err))
+	return s.currentQuery
+}
 
+// interceptClientMessage applies the grant controls to a message from the
+// client and tracks the queries and COPY FROM data it carries. A non-nil
+// error refuses the message.
+func (s *Session) interceptClientMessage(msg pgproto3.FrontendMessage) error {
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
+	if err := s.checkDryRunStatementName(msg); err != nil {
+		return err
+	}
+
+	switch m := msg.(type) {
+	case *pgproto3.Query:
+		return s.handleQuery(m)
+	case *pgproto3.Parse:
+		return s.handleParse(m)
+	case *pgproto3.Bind:
+		s.handleBind(m)
+	case *pgproto3.Execute:
+		return s.handleExecute(m)
+	case *pgproto3.Describe:
+		s.handleDescribe(m)
+	case *pgproto3.Close:
+		s.handleClose(m)
+	case *pgproto3.CopyData:
+		// Client sending COPY data to server (COPY FROM)
+		if s.copyState != nil && s.copyState.direction == "in" {
+			s.captureCopyData(m.Data)
+		}
+	case *pgproto3.CopyDone:
+		// Client finished sending COPY data
+		if s.copyState != nil && s.copyState.direction == "in" {
+			s.logger.InfoContext(s.ctx, "COPY IN done (from client)", slog.Int64("total_bytes", s.copyState.totalBytes), slog.Bool("truncated", s.copyState.truncated))
+		}
+	case *pgproto3.CopyFail:
+		// Client aborted COPY
+		if s.copyState != nil {
+			s.logger.WarnContext(s.ctx, "COPY failed", slog.String("message", m.Message))
+			s.copyState = nil
+		}
+	}
+
+	return nil
+}
+
+// sendQueryError sends a query error to the client, with the SQLSTATE
+// matching its class (see classifyQueryError). Statements blocked by a grant
+// control get the admin-configured message, the database's own taking
+// precedence over the proxy-wide one.
+func (s *Session) sendQueryError(queryErr error) {
+	clientErr := classifyQueryError(queryErr)
+	if clientErr.blocked {
+		clientErr.override = s.blockedMessage
+		if s.database != nil && s.database.BlockedMessage != "" {
+			clientErr.override = s.database.BlockedMessage
+		}
+	}
+
+	if !s.writeClientError(clientErr) {
 		return
 	}
 
-	readyMsg := &pgproto3.ReadyForQuery{TxStatus: 'I'}
+	// The refused statement never reached upstream: its transaction, if any,
+	// is still open.
+	readyMsg := &pgproto3.ReadyForQuery{TxStatus: s.transactionStatus()}
 
 	readyBuf, encodeErr := readyMsg.Encode(nil)
 	if encodeErr != nil {
@@ -400,6 +722,19 @@ func (s *Session) sendQueryError(queryErr error) {
 	}
 }
 
+// transactionStatus returns the transaction status upstream last reported to
+// the client, idle before the first query.
+func (s *Session) transactionStatus() byte {
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
+	if s.txStatus == 0 {
+		return 'I'
+	}
+
+	return s.txStatus
+}
+
 // getCurrentPendingQuery returns the query that should receive result data.
 // For Simple Query Protocol: s.currentQuery
 // For Extended Query Protocol: last item in pendingQueries (most recent Execute).
@@ -420,184 +755,287 @@ func (s *Session) getCurrentPendingQuery() *pendingQuery {
 // captureRowDescription records the result column metadata of the current or
 // pending query so DataRow values can later be decoded and named.
 func (s *Session) captureRowDescription(msg *pgproto3.RowDescription) {
+	columnNames := make([]string, len(msg.Fields))
+	columnOIDs := make([]uint32, len(msg.Fields))
+
+	for i, field := range msg.Fields {
+		columnNames[i] = string(field.Name)
+		columnOIDs[i] = field.DataTypeOID
+	}
+
+	s.rememberColumnTypes(columnNames, columnOIDs)
+
 	query := s.getCurrentPendingQuery()
 	if query == nil {
 		return
 	}
 
-	query.columnNames = make([]string, len(msg.Fields))
-	query.columnOIDs = make([]uint32, len(msg.Fields))
+	query.columnNames = columnNames
+	query.columnOIDs = columnOIDs
 
-	for i, field := range msg.Fields {
-		query.columnNames[i] = string(field.Name)
-		query.columnOIDs[i] = field.DataTypeOID
+	if s.masker.Get(s.database) != nil {
+		query.tables = shared.StatementTables(query.sql, lineage.DialectPostgreSQL)
 	}
 }
 
 // proxyUpstreamToClient proxies messages from upstream to client.
-//
-//nolint:gocognit,cyclop // Protocol handling with many message types inherently has high complexity
 func (s *Session) proxyUpstreamToClient() error {
-	var rowsAffected *int64
-	var queryError *string
+	var outcome queryOutcome
 
 	for {
 		msg, err := s.upstreamFrontend.Receive()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
+				s.endSession(store.DisconnectReasonUpstreamClosed)
+
 				return nil
 			}
 
+			s.endSession(store.DisconnectReasonUpstreamError)
+
 			return fmt.Errorf("failed to receive from upstream: %w", err)
 		}
 
-		// Capture query result data for logging
-		switch m := msg.(type) {
-		case *pgproto3.ParameterDescription:
-			// Server-resolved bind parameter types for the statement the client
-			// most recently described. Recorded so binary bind values can be
-			// decoded even when the client declared no types in Parse.
-			s.handleParameterDescription(m)
-
-		case *pgproto3.RowDescription:
-			s.captureRowDescription(m)
-
-		case *pgproto3.CommandComplete:
-			// Parse rows affected from CommandTag (e.g., "UPDATE 5")
-			rowsAffected = parseRowsAffected(string(m.CommandTag))
-			// Pop from pending queue if using Extended Query Protocol
-			if len(s.extendedState.pendingQueries) > 0 {
-				s.currentQuery = s.extendedState.pendingQueries[0]
-				s.extendedState.pendingQueries = s.extendedState.pendingQueries[1:]
+		if s.trackUpstreamMessage(msg, &outcome) {
+			if err := s.forwardToClient(msg); err != nil {
+				return err
 			}
+		}
 
-		case *pgproto3.ErrorResponse:
-			// Capture error message
-			errMsg := m.Message
-			queryError = &errMsg
-			// Pop from pending queue if using Extended Query Protocol
-			if len(s.extendedState.pendingQueries) > 0 {
-				s.currentQuery = s.extendedState.pendingQueries[0]
-				s.extendedState.pendingQueries = s.extendedState.pendingQueries[1:]
-			}
+		// Enforce time/bandwidth/row limits mid-stream. While a query is in
+		// flight, re-check after every message: the moment the running total
+		// crosses the grant's byte or row quota or the grant expires, abort with
+		// a clean ErrorResponse + ReadyForQuery instead of streaming the rest of
+		// a potentially huge result. Cheap (two atomic loads + a time compare).
+		if verr := s.enforceStreamLimits(); verr != nil {
+			s.endSession(store.DisconnectReasonLimitExceeded)
 
-		case *pgproto3.DataRow:
-			// Compute the row's payload size for the result-capture limits
-			// only — wire-level bytes_transferred is tracked via the
-			// CountingConn around clientConn, not field-summed here.
-			rowSize := int64(0)
-			for _, val := range m.Values {
-				rowSize += int64(len(val))
-			}
+			return verr
+		}
+	}
+}
 
-			// Capture row data if enabled and within limits
-			query := s.getCurrentPendingQuery()
-			if query != nil && s.queryStorage.StoreResults && !query.truncated {
-				// Check if this row would exceed limits
-				if query.rowNumber >= s.queryStorage.MaxResultRows ||
-					query.capturedBytes+rowSize > s.queryStorage.MaxResultBytes {
-					// Limits exceeded - discard all captured rows and stop capturing
-					query.truncated = true
-					query.capturedRows = nil // Discard all previously captured rows
-					s.logger.WarnContext(s.ctx, "result capture refused - limits exceeded",
-						slog.Int("rows_captured", query.rowNumber),
-						slog.Int64("bytes_captured", query.capturedBytes),
-						slog.Int("max_rows", s.queryStorage.MaxResultRows),
-						slog.Int64("max_bytes", s.queryStorage.MaxResultBytes))
-				} else {
-					row := s.convertDataRow(m.Values, query.columnNames, query.columnOIDs)
-					query.capturedRows = append(query.capturedRows, row)
-					query.capturedBytes += rowSize
-					query.rowNumber++
-				}
-			}
+// forwardToClient relays a message from upstream to the client.
+func (s *Session) forwardToClient(msg pgproto3.BackendMessage) error {
+	if err := s.throttleForward(msg); err != nil {
+		return err
+	}
 
-		case *pgproto3.CopyOutResponse:
-			// Server is starting a COPY TO operation (sending data to client)
-			s.copyState = &copyState{
-				direction: "out",
-				format:    m.OverallFormat,
-			}
-			// Extract column names from the current query if available
-			if s.currentQuery != nil {
-				s.copyState.columnNames = parseCopyColumnNames(s.currentQuery.sql)
-			}
-			s.logger.InfoContext(s.ctx, "COPY OUT started", slog.Int("format", int(m.OverallFormat)))
+	s.clientBackend.Send(msg)
 
-		case *pgproto3.CopyInResponse:
-			// Server is ready for a COPY FROM operation (receiving data from client)
-			s.copyState = &copyState{
-				direction: "in",
-				format:    m.OverallFormat,
-			}
-			// Extract column names from the current query if available
-			if s.currentQuery != nil {
-				s.copyState.columnNames = parseCopyColumnNames(s.currentQuery.sql)
-			}
-			s.logger.InfoContext(s.ctx, "COPY IN started", slog.Int("format", int(m.OverallFormat)))
-
-		case *pgproto3.CopyData:
-			// Server sending COPY data to client (COPY TO).
-			// Wire bytes are counted by the CountingConn — no manual
-			// addition here.
-			if s.copyState != nil && s.copyState.direction == "out" {
-				s.captureCopyData(m.Data)
-			}
+	if err := s.clientBackend.Flush(); err != nil {
+		s.endSession(store.DisconnectReasonClientError)
 
-		case *pgproto3.CopyDone:
-			// COPY operation complete - finalize capture
-			if s.copyState != nil && s.copyState.direction == "out" {
-				s.logger.InfoContext(s.ctx, "COPY OUT done", slog.Int64("total_bytes", s.copyState.totalBytes), slog.Bool("truncated", s.copyState.truncated))
-			}
+		return fmt.Errorf("failed to send to client: %w", err)
+	}
+
+	return nil
+}
 
-		case *pgproto3.ReadyForQuery:
-			// Query complete - log it
-			if s.currentQuery != nil {
-				// Wire-level diff: cumulative client-side bytes since the
-				// previous query end (or session start). Captures the query
-				// text the client sent, the response framing, error
-				// packets, and pre-first-query auth bytes — everything the
-				// row-summed counter previously missed.
-				total := s.bytesFromClient.Load() + s.bytesToClient.Load()
-				bytesTransferred := total - s.lastBytesSnapshot
-				s.lastBytesSnapshot = total
-
-				s.logQuery(rowsAffected, queryError, bytesTransferred)
-				s.currentQuery = nil
-				s.copyState = nil // Reset copy state
-				rowsAffected = nil
-				queryError = nil
+// queryOutcome accumulates the outcome of the query being completed, until
+// its ReadyForQuery logs it.
+type queryOutcome struct {
+	rowsAffected *int64
+	queryError   *string
+}
+
+// trackUpstreamMessage updates the in-flight query state for a message from
+// upstream, before it is forwarded: result capture, COPY tracking, query
+// completion and logging. It reports whether the message is to be forwarded
+// (a row dropped by a grant control, or a reply to the proxy's own dry_run
+// statements, is not).
+//
+//nolint:gocognit,cyclop // Protocol handling with many message types inherently has high complexity
+func (s *Session) trackUpstreamMessage(msg pgproto3.BackendMessage, outcome *queryOutcome) bool {
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
+	if !s.filterDryRunReply(msg) {
+		return false
+	}
+
+	// The time spent here is the proxy's, not upstream's.
+	if query := s.answeredQuery(); query != nil {
+		received := time.Now()
+		query.timer.Replied(received)
+
+		defer func() { query.timer.Processed(time.Since(received)) }()
+	}
+
+	switch m := msg.(type) {
+	case *pgproto3.ParameterDescription:
+		// Server-resolved bind parameter types for the statement the client
+		// most recently described. Recorded so binary bind values can be
+		// decoded even when the client declared no types in Parse.
+		s.handleParameterDescription(m)
+
+	case *pgproto3.RowDescription:
+		s.captureRowDescription(m)
+		s.handleResultRowDescription(m)
+
+	case *pgproto3.NoData:
+		s.results.describingStmt, s.results.describedStmt = false, nil
+
+	case *pgproto3.CommandComplete:
+		// Controls may rewrite the tag, so finish the result first.
+		s.finishResult(m)
+		// Parse rows affected from CommandTag (e.g., "UPDATE 5")
+		outcome.rowsAffected = parseRowsAffected(string(m.CommandTag))
+		// Pop from pending queue if using Extended Query Protocol, or move
+		// on to the next statement of a multi-statement Query
+		if len(s.extendedState.pendingQueries) > 0 {
+			s.currentQuery = s.extendedState.pendingQueries[0]
+			s.extendedState.pendingQueries = s.extendedState.pendingQueries[1:]
+		} else if s.currentQuery != nil && len(s.currentQuery.next) > 0 {
+			s.nextStatement(outcome)
+		}
+
+	case *pgproto3.ErrorResponse:
+		s.finishResult(nil)
+		// Capture error message
+		errMsg := m.Message
+		outcome.queryError = &errMsg
+		// Pop from pending queue if using Extended Query Protocol
+		if len(s.extendedState.pendingQueries) > 0 {
+			s.currentQuery = s.extendedState.pendingQueries[0]
+			s.extendedState.pendingQueries = s.extendedState.pendingQueries[1:]
+		}
+
+	case *pgproto3.DataRow:
+		// Grant controls come first: dropped rows are neither forwarded
+		// nor captured, and masked values are never stored.
+		if !s.applyRowControls(m) || !s.countReturnedRow() {
+			return false
+		}
+
+		// Compute the row's payload size for the result-capture limits
+		// only — wire-level bytes_transferred is tracked via the
+		// CountingConn around clientConn, not field-summed here.
+		rowSize := int64(0)
+		for _, val := range m.Values {
+			rowSize += int64(len(val))
+		}
+
+		// Capture row data if enabled and within limits
+		query := s.getCurrentPendingQuery()
+		limits := s.database.EffectiveQueryStorage(s.queryStorage)
+		if query != nil && limits.StoreResults && s.database.StoresResults() && !query.truncated {
+			// Check if this row would exceed limits
+			if query.rowNumber >= limits.MaxResultRows ||
+				query.capturedBytes+rowSize > limits.MaxResultBytes {
+				// Limits exceeded - spill the result when configured, and
+				// discard all captured rows either way
+				query.truncated = true
+				query.spill = shared.BeginSpill(s.ctx, s.logger, query.capturedRows)
+				query.capturedRows = nil
+				s.logger.WarnContext(s.ctx, "result capture refused - limits exceeded",
+					slog.Int("rows_captured", query.rowNumber),
+					slog.Int64("bytes_captured", query.capturedBytes),
+					slog.Int("max_rows", limits.MaxResultRows),
+					slog.Int64("max_bytes", limits.MaxResultBytes),
+					slog.Bool("spilled", query.spill != nil))
+			} else {
+				row := s.convertDataRow(m.Values, query.columnNames, query.columnOIDs, query.tables)
+				query.capturedRows = append(query.capturedRows, row)
+				query.capturedBytes += rowSize
+				query.rowNumber++
 			}
 		}
 
-		// Forward message to client (send as backend message to client)
-		s.clientBackend.Send(msg)
+		if query != nil && query.spill != nil {
+			row := s.convertDataRow(m.Values, query.columnNames, query.columnOIDs, query.tables)
+			query.spill = shared.SpillRow(s.ctx, s.logger, query.spill, row.RowData)
+		}
 
-		if err := s.clientBackend.Flush(); err != nil {
-			return fmt.Errorf("failed to send to client: %w", err)
+	case *pgproto3.CopyOutResponse:
+		// Server is starting a COPY TO operation (sending data to client)
+		s.copyState = &copyState{
+			direction: "out",
+			format:    m.OverallFormat,
 		}
+		// Extract column names from the current query if available
+		if s.currentQuery != nil {
+			s.copyState.columnNames = parseCopyColumnNames(s.currentQuery.sql)
+			s.copyState.columnOIDs = s.copyColumnOIDs(s.copyState.columnNames)
+			s.copyState.tables = shared.StatementTables(s.currentQuery.sql, lineage.DialectPostgreSQL)
+		}
+		s.logger.InfoContext(s.ctx, "COPY OUT started", slog.Int("format", int(m.OverallFormat)))
 
-		// Enforce time/bandwidth limits mid-stream. While a query is in flight,
-		// re-check after every forwarded message: the moment the running total
-		// crosses the grant's byte quota or the grant expires, abort with a
-		// clean ErrorResponse + ReadyForQuery instead of streaming the rest of a
-		// potentially huge result. Cheap (two atomic loads + a time compare).
-		if verr := s.enforceStreamLimits(); verr != nil {
-			return verr
+	case *pgproto3.CopyInResponse:
+		// Server is ready for a COPY FROM operation (receiving data from client)
+		s.copyState = &copyState{
+			direction: "in",
+			format:    m.OverallFormat,
+		}
+		// Extract column names from the current query if available
+		if s.currentQuery != nil {
+			s.copyState.columnNames = parseCopyColumnNames(s.currentQuery.sql)
+			s.copyState.columnOIDs = s.copyColumnOIDs(s.copyState.columnNames)
+			s.copyState.tables = shared.StatementTables(s.currentQuery.sql, lineage.DialectPostgreSQL)
+		}
+		s.logger.InfoContext(s.ctx, "COPY IN started", slog.Int("format", int(m.OverallFormat)))
+
+	case *pgproto3.CopyData:
+		// Server sending COPY data to client (COPY TO).
+		// Wire bytes are counted by the CountingConn — no manual
+		// addition here.
+		if s.copyState != nil && s.copyState.direction == "out" {
+			// PostgreSQL sends a row per CopyData message.
+			if !s.countReturnedRow() {
+				return false
+			}
+
+			s.captureCopyData(m.Data)
+		}
+
+	case *pgproto3.CopyDone:
+		// COPY operation complete - finalize capture
+		if s.copyState != nil && s.copyState.direction == "out" {
+			s.logger.InfoContext(s.ctx, "COPY OUT done", slog.Int64("total_bytes", s.copyState.totalBytes), slog.Bool("truncated", s.copyState.truncated))
+		}
+
+	case *pgproto3.ReadyForQuery:
+		s.finishResult(nil)
+		s.txStatus = m.TxStatus
+
+		// Query complete - log it. The statements left after a failed one
+		// never ran; without a failure, upstream completed fewer statements
+		// than were split, so the last one logged holds them all.
+		if s.currentQuery != nil {
+			if len(s.currentQuery.next) > 0 && outcome.queryError == nil {
+				s.currentQuery.sql = strings.Join(append([]string{s.currentQuery.sql}, s.currentQuery.next...), ";\n")
+			}
+
+			s.logQuery(outcome.rowsAffected, outcome.queryError, s.takeBytesTransferred())
+			s.sendQuotaNotices()
+			s.currentQuery = nil
+			s.copyState = nil // Reset copy state
+			*outcome = queryOutcome{}
 		}
 	}
+
+	return true
 }
 
 // enforceStreamLimits aborts the in-flight query when a time/bandwidth limit
-// has been crossed, sending the client a clean error frame first. It returns
-// the abort reason, or nil when no query is in flight or the grant is still
-// within bounds.
+// has been crossed, or a row held back by the row quota, sending the client a
+// clean error frame first. It returns the abort reason, or nil when no query
+// is in flight or the grant is still within bounds.
 func (s *Session) enforceStreamLimits() error {
-	if s.getCurrentPendingQuery() == nil {
+	s.queryMu.Lock()
+	inFlight := s.getCurrentPendingQuery() != nil
+	rowQuotaExceeded := s.rowQuotaExceeded
+	s.queryMu.Unlock()
+
+	if !inFlight {
 		return nil
 	}
 
 	verr := s.guard.Check()
+	if verr == nil && rowQuotaExceeded {
+		verr = ErrRowLimitExceeded
+	}
+
 	if verr != nil {
 		s.abortStream(verr)
 	}
@@ -606,7 +1044,8 @@ func (s *Session) enforceStreamLimits() error {
 }
 
 // abortStream cuts an in-flight query off at a message boundary, sending the
-// client a real ErrorResponse (SQLSTATE 53400, configuration_limit_exceeded) and
+// client a real ErrorResponse (SQLSTATE 53400, configuration_limit_exceeded, for
+// an exhausted quota; 57014, query_canceled, for an expired or revoked grant) and
 // a ReadyForQuery so it observes a failed query rather than a bare connection
 // reset. The session then tears down (the grant is exhausted/expired, so every
 // subsequent command would be refused anyway).
@@ -614,22 +1053,7 @@ func (s *Session) abortStream(cause error) {
 	s.logger.WarnContext(s.ctx, "aborting in-flight query: grant limit reached",
 		slog.Any("error", cause))
 
-	errMsg := &pgproto3.ErrorResponse{
-		Severity: "ERROR",
-		Code:     "53400", // configuration_limit_exceeded
-		Message:  cause.Error(),
-	}
-
-	errBuf, encodeErr := errMsg.Encode(nil)
-	if encodeErr != nil {
-		s.logger.ErrorContext(s.ctx, "failed to encode limit error", slog.Any("error", encodeErr))
-
-		return
-	}
-
-	if _, err := s.clientConn.Write(errBuf); err != nil {
-		s.logger.ErrorContext(s.ctx, "failed to write limit error to client", slog.Any("error", err))
-
+	if !s.writeClientError(classifyAbortError(cause)) {
 		return
 	}
 
@@ -657,6 +1081,9 @@ func (s *Session) abortStream(cause error) {
 // the end of abortStream, once the error frame has been written (so the frame's
 // bytes are included in the attribution).
 func (s *Session) persistAbortedQuery(cause error) {
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
 	query := s.getCurrentPendingQuery()
 	if query == nil {
 		return
@@ -674,8 +1101,7 @@ func (s *Session) persistAbortedQuery(cause error) {
 
 	// Extended Query Protocol leaves the in-flight query in the pending queue
 	// (currentQuery is nil until CommandComplete/ErrorResponse pops it); logQuery
-	// persists s.currentQuery, so promote it. The session is tearing down, so
-	// mutating currentQuery here is safe.
+	// persists s.currentQuery, so promote it.
 	if s.currentQuery == nil {
 		s.currentQuery = query
 	}
@@ -686,10 +1112,22 @@ func (s *Session) persistAbortedQuery(cause error) {
 
 // cleanup closes connections and updates records.
 func (s *Session) cleanup() {
+	if s.clientKey != nil {
+		s.cancelKeys.release(s.clientKey)
+	}
+
 	if s.grant != nil && s.revocation != nil {
 		s.store.Revocations().Deregister(s.grant.UID, s.revocation)
 	}
 
+	s.store.Terminations().Deregister(s.connectionUID, s.termination)
+
+	if s.throttle != nil {
+		s.throttles.Release(s.grant.UID)
+	}
+
+	s.discardSpills()
+
 	if s.dumpWriter != nil {
 		if err := s.dumpWriter.Close(); err != nil {
 			s.logger.ErrorContext(s.ctx, "failed to close dump writer", slog.Any("error", err))
@@ -697,7 +1135,16 @@ func (s *Session) cleanup() {
 	}
 
 	if s.connectionUID != uuid.Nil {
-		if err := s.store.CloseConnection(s.ctx, s.connectionUID); err != nil {
+		var reason string
+		if r := s.disconnectReason.Load(); r != nil {
+			reason = *r
+		}
+
+		// Detached from the session context, which is canceled on shutdown.
+		err := shared.RetryStoreWrite(context.WithoutCancel(s.ctx), func(ctx context.Context) error {
+			return s.store.CloseConnection(ctx, s.connectionUID, reason)
+		})
+		if err != nil {
 			s.logger.ErrorContext(s.ctx, "failed to close connection record", slog.Any("error", err))
 		}
 	}
@@ -709,24 +1156,30 @@ func (s *Session) cleanup() {
 	}
 }
 
-// sendError sends an error to the client and closes the connection.
-func (s *Session) sendError(message string) {
-	errMsg := &pgproto3.ErrorResponse{
-		Severity: "FATAL",
-		Code:     "28000", // invalid_authorization_specification
-		Message:  message,
-	}
+// sendError sends a FATAL error to the client before the connection closes.
+func (s *Session) sendError(code string, id messageID) {
+	s.writeClientError(newFatalError(code, id))
+}
+
+// writeClientError renders clientErr in the client's language and writes it
+// as an ErrorResponse. It reports whether the write succeeded.
+func (s *Session) writeClientError(clientErr clientError) bool {
+	errMsg := clientErr.render(s.clientLocale, s.messageData)
 
 	buf, err := errMsg.Encode(nil)
 	if err != nil {
 		s.logger.ErrorContext(s.ctx, "failed to encode error message", slog.Any("error", err))
 
-		return
+		return false
 	}
 
 	if _, err := s.clientConn.Write(buf); err != nil {
 		s.logger.ErrorContext(s.ctx, "failed to write error to client", slog.Any("error", err))
+
+		return false
 	}
+
+	return true
 }
 
 // Magic version numbers that identify the optional pre-StartupMessage frames
@@ -845,6 +1298,14 @@ func (s *Session) handleGSSRequest() error {
 	return nil
 }
 
+// Bounds on the messages read by hand before pgproto3 takes over, so a
+// hostile length prefix cannot make the proxy allocate arbitrary memory.
+// maxStartupMessageLength matches PostgreSQL's MAX_STARTUP_PACKET_LENGTH.
+const (
+	maxStartupMessageLength  = 10000
+	maxPasswordMessageLength = 64 * 1024
+)
+
 // receiveStartupMessage receives the startup message from the client. By the
 // time this is called, negotiateSSL has already consumed any SSLRequest
 // preamble — the bytes here are guaranteed to be the StartupMessage proper.
@@ -855,6 +1316,9 @@ func (s *Session) receiveStartupMessage() (pgproto3.FrontendMessage, error) {
 	}
 
 	length := int(lengthBuf[0])<<24 | int(lengthBuf[1])<<16 | int(lengthBuf[2])<<8 | int(lengthBuf[3])
+	if length < 8 || length > maxStartupMessageLength {
+		return nil, fmt.Errorf("%w: startup message of %d bytes", ErrInvalidMessageLength, length)
+	}
 
 	msgBuf := make([]byte, length)
 	copy(msgBuf, lengthBuf)
@@ -875,26 +1339,46 @@ func (s *Session) receiveStartupMessage() (pgproto3.FrontendMessage, error) {
 // go through clientReader so any bytes pipelined by the client during the
 // startup phase are still seen here.
 func (s *Session) receivePasswordMessage() (*pgproto3.PasswordMessage, error) {
+	dataBuf, err := s.receiveAuthResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	password := &pgproto3.PasswordMessage{}
+	// Null-terminated password string
+	password.Password = string(dataBuf[:len(dataBuf)-1])
+
+	return password, nil
+}
+
+// receiveAuthResponse receives the body of a client authentication response
+// ('p'): a PasswordMessage, SASLInitialResponse or SASLResponse, which only
+// the authentication exchange in progress tells apart.
+func (s *Session) receiveAuthResponse() ([]byte, error) {
 	typeBuf := make([]byte, 1)
 	if _, err := io.ReadFull(s.clientReader, typeBuf); err != nil {
 		return nil, err
 	}
 
+	if typeBuf[0] != 'p' {
+		return nil, fmt.Errorf("%w: got message type %q", ErrExpectedPasswordMessage, typeBuf[0])
+	}
+
 	lengthBuf := make([]byte, 4)
 	if _, err := io.ReadFull(s.clientReader, lengthBuf); err != nil {
 		return nil, err
 	}
 
+	// At least the length itself and the password's terminating NUL.
 	length := int(lengthBuf[0])<<24 | int(lengthBuf[1])<<16 | int(lengthBuf[2])<<8 | int(lengthBuf[3])
+	if length < 5 || length > maxPasswordMessageLength {
+		return nil, fmt.Errorf("%w: password message of %d bytes", ErrInvalidMessageLength, length)
+	}
 
 	dataBuf := make([]byte, length-4)
 	if _, err := io.ReadFull(s.clientReader, dataBuf); err != nil {
 		return nil, err
 	}
 
-	password := &pgproto3.PasswordMessage{}
-	// Null-terminated password string
-	password.Password = string(dataBuf[:len(dataBuf)-1])
-
-	return password, nil
+	return dataBuf, nil
 }
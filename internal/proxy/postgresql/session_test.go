@@ -0,0 +1,286 @@
+package postgresql
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// TestSession_RelayStateConcurrency drives both relay halves against one
+// session at once, as proxyClientToUpstream and proxyUpstreamToClient do.
+// Run with -race: it fails on any unsynchronized access to the in-flight
+// query state or the grant's counters.
+func TestSession_RelayStateConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var fromClient, toClient atomic.Int64
+
+	s := &Session{
+		grant:           &store.Grant{ExpiresAt: time.Now().Add(time.Hour)},
+		queryStorage:    config.QueryStorageConfig{StoreResults: true, MaxResultRows: 1000, MaxResultBytes: 1 << 20},
+		bytesFromClient: &fromClient,
+		bytesToClient:   &toClient,
+		extendedState: &extendedQueryState{
+			preparedStatements: make(map[string]*preparedStatement),
+			portals:            make(map[string]*portalState),
+		},
+		logger: slog.New(slog.DiscardHandler),
+		ctx:    context.Background(),
+	}
+
+	const rounds = 1000
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		for range rounds {
+			fromClient.Add(16)
+
+			_ = s.interceptClientMessage(&pgproto3.Query{String: "COPY t FROM STDIN"})
+			_ = s.interceptClientMessage(&pgproto3.CopyData{Data: []byte("1\ta\n")})
+			_ = s.interceptClientMessage(&pgproto3.Parse{Query: "SELECT 1"})
+			_ = s.interceptClientMessage(&pgproto3.Bind{})
+			_ = s.interceptClientMessage(&pgproto3.Execute{})
+			_ = s.interceptClientMessage(&pgproto3.CopyDone{})
+		}
+	})
+
+	wg.Go(func() {
+		var outcome queryOutcome
+
+		for range rounds {
+			toClient.Add(16)
+
+			s.trackUpstreamMessage(&pgproto3.CopyInResponse{}, &outcome)
+			s.trackUpstreamMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{{Name: []byte("n")}}}, &outcome)
+			s.trackUpstreamMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("1")}}, &outcome)
+			s.trackUpstreamMessage(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}, &outcome)
+			s.trackUpstreamMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}, &outcome)
+		}
+	})
+
+	wg.Wait()
+
+	// The interleaving decides how many queries completed; one more run in
+	// order must be counted.
+	count := s.grant.QueryCount
+
+	var outcome queryOutcome
+
+	toClient.Add(16)
+
+	if err := s.interceptClientMessage(&pgproto3.Query{String: "SELECT 1"}); err != nil {
+		t.Fatalf("interceptClientMessage() error = %v", err)
+	}
+
+	s.trackUpstreamMessage(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}, &outcome)
+	s.trackUpstreamMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}, &outcome)
+
+	if s.grant.QueryCount != count+1 {
+		t.Errorf("QueryCount = %d, want %d", s.grant.QueryCount, count+1)
+	}
+}
+
+func TestSession_EndSessionKeepsFirstReason(t *testing.T) {
+	t.Parallel()
+
+	s := &Session{}
+
+	if s.clientDisconnected() {
+		t.Fatal("clientDisconnected() = true before the session ended")
+	}
+
+	s.endSession(store.DisconnectReasonClientClosed)
+	s.endSession(store.DisconnectReasonUpstreamError) // the other relay, unblocked
+
+	if got := *s.disconnectReason.Load(); got != store.DisconnectReasonClientClosed {
+		t.Errorf("disconnect reason = %q, want %q", got, store.DisconnectReasonClientClosed)
+	}
+
+	if !s.clientDisconnected() {
+		t.Error("clientDisconnected() = false after the client closed")
+	}
+}
+
+func TestSession_CancelUpstreamQuery(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan []byte, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 64)
+		n, _ := io.ReadAtLeast(conn, buf, 16)
+		received <- buf[:n]
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	s := &Session{
+		database:    &store.Server{Host: "127.0.0.1", Port: addr.Port},
+		upstreamKey: &pgproto3.BackendKeyData{ProcessID: 4242, SecretKey: []byte{1, 2, 3, 4}},
+		logger:      slog.New(slog.DiscardHandler),
+		ctx:         context.Background(),
+	}
+
+	s.cancelUpstreamQuery("test")
+
+	var got pgproto3.CancelRequest
+	if err := got.Decode((<-received)[4:]); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.ProcessID != 4242 || string(got.SecretKey) != "\x01\x02\x03\x04" {
+		t.Errorf("CancelRequest = %+v, want the upstream backend's key", got)
+	}
+}
+
+func TestSession_SendExpiryNotice(t *testing.T) {
+	t.Parallel()
+
+	clientSide, proxySide := net.Pipe()
+	t.Cleanup(func() {
+		_ = clientSide.Close()
+		_ = proxySide.Close()
+	})
+
+	grant := &store.Grant{UID: uuid.New(), ExpiresAt: time.Now().Add(5 * time.Minute)}
+	s := &Session{
+		grant:      grant,
+		clientConn: proxySide,
+		logger:     slog.New(slog.DiscardHandler),
+		ctx:        context.Background(),
+	}
+
+	go s.sendExpiryNotice(grant.ExpiresAt)
+
+	frontend := pgproto3.NewFrontend(clientSide, clientSide)
+
+	msg, err := frontend.Receive()
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+
+	notice, ok := msg.(*pgproto3.NoticeResponse)
+	if !ok {
+		t.Fatalf("Receive() = %T, want a NoticeResponse", msg)
+	}
+
+	if notice.Severity != "WARNING" || !strings.Contains(notice.Message, "access grant expires in") {
+		t.Errorf("notice = %s %q, want a WARNING announcing the expiry", notice.Severity, notice.Message)
+	}
+
+	if !strings.Contains(notice.Hint, "/api/v1/grants/"+grant.UID.String()+"/extension-requests") {
+		t.Errorf("notice hint = %q, want the extension request endpoint", notice.Hint)
+	}
+}
+
+// TestSession_MultiStatementQuery checks that each statement of a
+// multi-statement Query is logged as its own query, when upstream completes it.
+func TestSession_MultiStatementQuery(t *testing.T) {
+	t.Parallel()
+
+	var fromClient, toClient atomic.Int64
+
+	s := &Session{
+		grant:           &store.Grant{ExpiresAt: time.Now().Add(time.Hour)},
+		bytesFromClient: &fromClient,
+		bytesToClient:   &toClient,
+		extendedState: &extendedQueryState{
+			preparedStatements: make(map[string]*preparedStatement),
+			portals:            make(map[string]*portalState),
+		},
+		logger: slog.New(slog.DiscardHandler),
+		ctx:    context.Background(),
+	}
+
+	if err := s.interceptClientMessage(&pgproto3.Query{String: "SELECT 1; DELETE FROM x; SELECT 3"}); err != nil {
+		t.Fatalf("interceptClientMessage() error = %v", err)
+	}
+
+	first := s.currentQuery
+
+	var outcome queryOutcome
+
+	s.trackUpstreamMessage(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}, &outcome)
+
+	if s.grant.QueryCount != 1 {
+		t.Errorf("QueryCount after the first statement = %d, want 1", s.grant.QueryCount)
+	}
+
+	if s.currentQuery == first || s.currentQuery.sql != "DELETE FROM x" {
+		t.Fatalf("current statement = %q, want the second one", s.currentQuery.sql)
+	}
+
+	if s.currentQuery.startTime.Before(first.startTime) {
+		t.Error("the second statement is timed from before the first one")
+	}
+
+	s.trackUpstreamMessage(&pgproto3.ErrorResponse{Message: "relation \"x\" does not exist"}, &outcome)
+	s.trackUpstreamMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}, &outcome)
+
+	// The third statement never ran.
+	if s.grant.QueryCount != 2 {
+		t.Errorf("QueryCount = %d, want 2", s.grant.QueryCount)
+	}
+
+	if s.currentQuery != nil {
+		t.Errorf("currentQuery = %q after ReadyForQuery, want nil", s.currentQuery.sql)
+	}
+}
+
+// TestSession_TransactionStatus checks that the session follows the
+// transaction status upstream reports to the client.
+func TestSession_TransactionStatus(t *testing.T) {
+	t.Parallel()
+
+	var fromClient, toClient atomic.Int64
+
+	s := &Session{
+		grant:           &store.Grant{ExpiresAt: time.Now().Add(time.Hour)},
+		bytesFromClient: &fromClient,
+		bytesToClient:   &toClient,
+		extendedState: &extendedQueryState{
+			preparedStatements: make(map[string]*preparedStatement),
+			portals:            make(map[string]*portalState),
+		},
+		logger: slog.New(slog.DiscardHandler),
+		ctx:    context.Background(),
+	}
+
+	if got := s.transactionStatus(); got != 'I' {
+		t.Errorf("transactionStatus() before any query = %q, want 'I'", got)
+	}
+
+	var outcome queryOutcome
+
+	for _, status := range []byte{'T', 'E', 'I'} {
+		s.trackUpstreamMessage(&pgproto3.ReadyForQuery{TxStatus: status}, &outcome)
+
+		if got := s.transactionStatus(); got != status {
+			t.Errorf("transactionStatus() = %q, want %q", got, status)
+		}
+	}
+}
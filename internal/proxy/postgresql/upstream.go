@@ -11,6 +11,7 @@ import (
 	"github.com/jackc/pgx/v5/pgproto3"
 
 	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
 	"github.com/fclairamb/dbbat/internal/version"
 )
 
@@ -19,28 +20,31 @@ var ErrUpstreamReadOnlyMode = errors.New("upstream error setting read-only mode"
 
 // connectUpstream connects to the upstream PostgreSQL server.
 func (s *Session) connectUpstream() error {
-	// Decrypt database password
-	if err := s.database.DecryptPassword(s.encryptionKey); err != nil {
-		return fmt.Errorf("failed to decrypt database password: %w", err)
+	// Log in with the credentials of the grant's access level. Walsenders
+	// need the REPLICATION attribute, which a read-only role should not have.
+	accessLevel := s.grant.AccessLevel()
+	if s.replication != replicationNone {
+		accessLevel = store.AccessLevelReadWrite
 	}
 
-	// Connect to upstream (directly, or tunneled through an SSH bastion when the
-	// server row's via_uid is set).
-	conn, err := shared.DialUpstream(s.ctx, s.store, s.encryptionKey, s.database)
-	if err != nil {
-		return fmt.Errorf("failed to connect to upstream: %w", err)
+	if err := shared.UseUpstreamCredentials(s.ctx, s.database, s.encryptionKey, accessLevel); err != nil {
+		return fmt.Errorf("failed to get database credentials: %w", err)
+	}
+
+	// Transaction pooling lends the session pooled connections instead.
+	// Replication connections carry a single stream, and are never pooled.
+	if s.pool != nil && s.replication == replicationNone {
+		return s.connectPooled()
 	}
 
-	// Negotiate TLS with the upstream per ssl_mode (libpq semantics). Must
-	// happen before any StartupMessage — Postgres expects the SSLRequest
-	// preamble on a fresh connection, not interleaved with protocol traffic.
-	upgraded, err := negotiateUpstreamSSL(s.ctx, conn, s.database.Host, s.database.SSLMode)
+	conn, target, err := s.dialUpstream()
 	if err != nil {
-		_ = conn.Close()
-		return fmt.Errorf("upstream SSL negotiation: %w", err)
+		return err
 	}
-	conn = upgraded
 
+	// From now on the session talks to target, a read replica or the
+	// primary: cancel requests and dumps address it.
+	s.database = target
 	s.upstreamConn = conn
 
 	// Create frontend for upstream (we act as client to upstream)
@@ -52,7 +56,69 @@ func (s *Session) connectUpstream() error {
 	}
 
 	// Handle upstream authentication
-	return s.handleUpstreamAuth(upstreamFrontend)
+	startup, err := s.handleUpstreamAuth(upstreamFrontend)
+	if err != nil {
+		return err
+	}
+
+	// Upstream is ready, save the frontend for later use
+	s.upstreamFrontend = upstreamFrontend
+	s.upstreamKey = startup.backendKeyData
+
+	// Enforce read-only mode at the database level if grant has read_only
+	// control. A physical walsender accepts no SQL, and cannot write anyway.
+	if s.grant.IsReadOnly() && s.replication != replicationPhysical {
+		if err := setSessionReadOnly(upstreamFrontend); err != nil {
+			return fmt.Errorf("failed to set read-only mode: %w", err)
+		}
+	}
+
+	return s.completeStartup(startup)
+}
+
+// completeStartup prepares the dry_run statements when the grant has the
+// control, then tells the client it is authenticated, with the messages
+// upstream sent after authenticating.
+func (s *Session) completeStartup(startup *upstreamStartup) error {
+	if s.grant.DryRun() {
+		if err := s.prepareDryRun(); err != nil {
+			return fmt.Errorf("failed to prepare dry-run mode: %w", err)
+		}
+	}
+
+	// Send authentication success to client
+	if err := s.sendToClient(&pgproto3.AuthenticationOk{}); err != nil {
+		return fmt.Errorf("failed to send auth ok: %w", err)
+	}
+
+	// Forward buffered ParameterStatus messages
+	s.logger.DebugContext(s.ctx, "forwarding ParameterStatus messages to client", slog.Int("count", len(startup.paramStatus)))
+	for _, ps := range startup.paramStatus {
+		s.logger.DebugContext(s.ctx, "forwarding ParameterStatus", slog.String("name", ps.Name), slog.String("value", ps.Value))
+		if err := s.sendToClient(ps); err != nil {
+			return fmt.Errorf("failed to forward parameter status: %w", err)
+		}
+	}
+
+	// Send a BackendKeyData (required by JDBC and other clients): the
+	// proxy's own, so that the client's CancelRequests come to it.
+	clientKey, err := s.clientBackendKey()
+	if err != nil {
+		return err
+	}
+
+	if clientKey != nil {
+		if err := s.sendToClient(clientKey); err != nil {
+			return fmt.Errorf("failed to forward backend key data: %w", err)
+		}
+	}
+
+	// Forward ready message
+	if err := s.sendToClient(&pgproto3.ReadyForQuery{TxStatus: 'I'}); err != nil {
+		return fmt.Errorf("failed to forward ready message: %w", err)
+	}
+
+	return nil
 }
 
 // sendStartupMessage sends the startup message to upstream.
@@ -66,6 +132,10 @@ func (s *Session) sendStartupMessage(conn net.Conn) error {
 		},
 	}
 
+	if value := s.replication.startupValue(); value != "" {
+		startupMsg.Parameters["replication"] = value
+	}
+
 	buf, err := startupMsg.Encode(nil)
 	if err != nil {
 		return fmt.Errorf("failed to encode startup message: %w", err)
@@ -78,21 +148,32 @@ func (s *Session) sendStartupMessage(conn net.Conn) error {
 	return nil
 }
 
-// handleUpstreamAuth handles the authentication flow with upstream.
-func (s *Session) handleUpstreamAuth(upstreamFrontend *pgproto3.Frontend) error {
+// upstreamStartup buffers the messages upstream sends after authenticating,
+// until its ReadyForQuery lets them be forwarded to the client in order.
+type upstreamStartup struct {
+	paramStatus    []*pgproto3.ParameterStatus
+	backendKeyData *pgproto3.BackendKeyData
+}
+
+// handleUpstreamAuth handles the authentication flow with upstream, until
+// its first ReadyForQuery. It returns the messages upstream sent after
+// authenticating.
+func (s *Session) handleUpstreamAuth(upstreamFrontend *pgproto3.Frontend) (*upstreamStartup, error) {
+	var startup upstreamStartup
+
 	for {
 		msg, err := upstreamFrontend.Receive()
 		if err != nil {
-			return fmt.Errorf("failed to receive from upstream: %w", err)
+			return nil, fmt.Errorf("failed to receive from upstream: %w", err)
 		}
 
-		done, err := s.processUpstreamAuthMessage(msg, upstreamFrontend)
+		done, err := s.processUpstreamAuthMessage(msg, upstreamFrontend, &startup)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if done {
-			return nil
+			return &startup, nil
 		}
 	}
 }
@@ -118,6 +199,7 @@ func (s *Session) sendToClient(msg pgproto3.BackendMessage) error {
 func (s *Session) processUpstreamAuthMessage(
 	msg pgproto3.BackendMessage,
 	upstreamFrontend *pgproto3.Frontend,
+	startup *upstreamStartup,
 ) (bool, error) {
 	switch typedMsg := msg.(type) {
 	case *pgproto3.AuthenticationOk:
@@ -166,14 +248,14 @@ func (s *Session) processUpstreamAuthMessage(
 			Name:  typedMsg.Name,
 			Value: typedMsg.Value,
 		}
-		s.bufferedParamStatus = append(s.bufferedParamStatus, paramCopy)
+		startup.paramStatus = append(startup.paramStatus, paramCopy)
 
 		return false, nil
 
 	case *pgproto3.BackendKeyData:
 		// Buffer BackendKeyData - must be sent after ParameterStatus messages
 		// Make a copy since pgproto3 reuses the same struct
-		s.bufferedBackendKeyData = &pgproto3.BackendKeyData{
+		startup.backendKeyData = &pgproto3.BackendKeyData{
 			ProcessID: typedMsg.ProcessID,
 			SecretKey: typedMsg.SecretKey,
 		}
@@ -181,46 +263,7 @@ func (s *Session) processUpstreamAuthMessage(
 		return false, nil
 
 	case *pgproto3.ReadyForQuery:
-		// Upstream is ready, save the frontend for later use
-		s.upstreamFrontend = upstreamFrontend
-
-		// Enforce read-only mode at the database level if grant has read_only control
-		if s.grant.IsReadOnly() {
-			if err := s.setSessionReadOnly(); err != nil {
-				return false, fmt.Errorf("failed to set read-only mode: %w", err)
-			}
-		}
-
-		// Send authentication success to client
-		if err := s.sendToClient(&pgproto3.AuthenticationOk{}); err != nil {
-			return false, fmt.Errorf("failed to send auth ok: %w", err)
-		}
-
-		// Forward buffered ParameterStatus messages
-		s.logger.DebugContext(s.ctx, "forwarding ParameterStatus messages to client", slog.Int("count", len(s.bufferedParamStatus)))
-		for _, ps := range s.bufferedParamStatus {
-			s.logger.DebugContext(s.ctx, "forwarding ParameterStatus", slog.String("name", ps.Name), slog.String("value", ps.Value))
-			if err := s.sendToClient(ps); err != nil {
-				return false, fmt.Errorf("failed to forward parameter status: %w", err)
-			}
-		}
-
-		s.bufferedParamStatus = nil // Clear buffer
-
-		// Forward BackendKeyData (required by JDBC and other clients)
-		if s.bufferedBackendKeyData != nil {
-			if err := s.sendToClient(s.bufferedBackendKeyData); err != nil {
-				return false, fmt.Errorf("failed to forward backend key data: %w", err)
-			}
-
-			s.bufferedBackendKeyData = nil
-		}
-
-		// Forward ready message
-		if err := s.sendToClient(typedMsg); err != nil {
-			return false, fmt.Errorf("failed to forward ready message: %w", err)
-		}
-
+		// Upstream is ready: the caller takes over.
 		return true, nil
 
 	case *pgproto3.ErrorResponse:
@@ -331,21 +374,21 @@ func buildApplicationName(username, clientAppName string) string {
 
 // setSessionReadOnly sets the upstream session to read-only mode.
 // This enforces read-only access at the PostgreSQL level for defense-in-depth.
-func (s *Session) setSessionReadOnly() error {
+func setSessionReadOnly(upstreamFrontend *pgproto3.Frontend) error {
 	// Send SET SESSION command to upstream database
 	query := &pgproto3.Query{
 		String: "SET SESSION default_transaction_read_only = on;",
 	}
 
-	s.upstreamFrontend.Send(query)
+	upstreamFrontend.Send(query)
 
-	if err := s.upstreamFrontend.Flush(); err != nil {
+	if err := upstreamFrontend.Flush(); err != nil {
 		return fmt.Errorf("send SET SESSION: %w", err)
 	}
 
 	// Read response from upstream
 	for {
-		msg, err := s.upstreamFrontend.Receive()
+		msg, err := upstreamFrontend.Receive()
 		if err != nil {
 			return fmt.Errorf("receive response: %w", err)
 		}
@@ -6,6 +6,9 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
+
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/store"
 )
 
 // upstreamSSLRequest is the 8-byte SSLRequest preamble (length=8, magic
@@ -23,22 +26,26 @@ var upstreamSSLRequest = func() []byte {
 // ssl_mode. Mirrors libpq sslmode semantics:
 //
 //   - disable:                  plaintext only, no probe sent.
-//   - allow / prefer / "":      probe; on 'S' upgrade with cert verification
-//     skipped, on 'N' continue plaintext.
-//   - require:                  probe; on 'S' upgrade with cert verification
-//     skipped, on 'N' fail.
-//   - verify-ca / verify-full:  probe; on 'S' upgrade with full cert + hostname
-//     verification, on 'N' fail. (Go stdlib doesn't cleanly express
-//     "verify CA but not hostname", so verify-ca is treated as verify-full —
-//     stricter than libpq, but safer.)
+//   - allow / prefer / "":      probe; on 'S' upgrade, on 'N' continue
+//     plaintext.
+//   - require / verify-ca / verify-full: probe; on 'S' upgrade, on 'N' fail.
+//
+// The certificate checks of each mode (none, chain, chain + hostname) are
+// those of shared.UpstreamTLSConfig, against the database's ssl_root_cert.
 //
 // On error the original conn is left open; the caller is responsible for
 // closing it.
-func negotiateUpstreamSSL(ctx context.Context, conn net.Conn, host, mode string) (net.Conn, error) {
-	if mode == "disable" {
+func negotiateUpstreamSSL(ctx context.Context, conn net.Conn, db *store.Server) (net.Conn, error) {
+	if db.SSLMode == "disable" {
 		return conn, nil
 	}
 
+	// Build the config first: an invalid CA bundle fails before any I/O.
+	tlsConfig, err := shared.UpstreamTLSConfig(db)
+	if err != nil {
+		return nil, err
+	}
+
 	if _, err := conn.Write(upstreamSSLRequest); err != nil {
 		return nil, fmt.Errorf("send SSLRequest: %w", err)
 	}
@@ -50,14 +57,14 @@ func negotiateUpstreamSSL(ctx context.Context, conn net.Conn, host, mode string)
 
 	switch resp[0] {
 	case 'S':
-		tlsConn := tls.Client(conn, upstreamTLSConfig(host, mode))
+		tlsConn := tls.Client(conn, tlsConfig)
 		if err := tlsConn.HandshakeContext(ctx); err != nil {
 			return nil, fmt.Errorf("upstream TLS handshake: %w", err)
 		}
 		return tlsConn, nil
 	case 'N':
-		if upstreamTLSRequired(mode) {
-			return nil, fmt.Errorf("%w: ssl_mode=%s", ErrUpstreamTLSRequired, mode)
+		if upstreamTLSRequired(db.SSLMode) {
+			return nil, fmt.Errorf("%w: ssl_mode=%s", ErrUpstreamTLSRequired, db.SSLMode)
 		}
 		return conn, nil
 	default:
@@ -73,18 +80,3 @@ func upstreamTLSRequired(mode string) bool {
 	}
 	return false
 }
-
-// upstreamTLSConfig builds a tls.Config for an upstream connection at the
-// given ssl_mode. verify-ca/verify-full both verify the cert chain and the
-// hostname (see negotiateUpstreamSSL doc); other modes accept any cert.
-func upstreamTLSConfig(host, mode string) *tls.Config {
-	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
-	switch mode {
-	case "verify-ca", "verify-full":
-		cfg.ServerName = host
-	default:
-		// require/prefer parity with libpq: encrypt without authenticating the server.
-		cfg.InsecureSkipVerify = true
-	}
-	return cfg
-}
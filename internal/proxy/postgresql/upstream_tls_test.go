@@ -3,11 +3,14 @@ package postgresql
 import (
 	"context"
 	"crypto/tls"
+	"encoding/pem"
 	"errors"
 	"io"
 	"net"
 	"testing"
 	"time"
+
+	"github.com/fclairamb/dbbat/internal/store"
 )
 
 var errSSLRequestMismatch = errors.New("SSLRequest preamble mismatch")
@@ -44,7 +47,7 @@ func TestNegotiateUpstreamSSL_DisableSkipsProbe(t *testing.T) {
 		probe <- err
 	}()
 
-	out, err := negotiateUpstreamSSL(context.Background(), clientSide, "example.com", "disable")
+	out, err := negotiateUpstreamSSL(context.Background(), clientSide, &store.Server{Host: "example.com", SSLMode: "disable"})
 	if err != nil {
 		t.Fatalf("disable: unexpected error: %v", err)
 	}
@@ -71,7 +74,7 @@ func TestNegotiateUpstreamSSL_PreferFallsBackToPlaintext(t *testing.T) {
 		_, _ = serverSide.Write([]byte{'N'})
 	}()
 
-	out, err := negotiateUpstreamSSL(context.Background(), clientSide, "example.com", "prefer")
+	out, err := negotiateUpstreamSSL(context.Background(), clientSide, &store.Server{Host: "example.com", SSLMode: "prefer"})
 	if err != nil {
 		t.Fatalf("prefer + N: unexpected error: %v", err)
 	}
@@ -98,7 +101,7 @@ func TestNegotiateUpstreamSSL_RequireFailsOnDeny(t *testing.T) {
 				_, _ = serverSide.Write([]byte{'N'})
 			}()
 
-			_, err := negotiateUpstreamSSL(context.Background(), clientSide, "example.com", mode)
+			_, err := negotiateUpstreamSSL(context.Background(), clientSide, &store.Server{Host: "example.com", SSLMode: mode})
 			if !errors.Is(err, ErrUpstreamTLSRequired) {
 				t.Fatalf("%s + N: expected ErrUpstreamTLSRequired, got %v", mode, err)
 			}
@@ -120,7 +123,7 @@ func TestNegotiateUpstreamSSL_UnexpectedResponseByte(t *testing.T) {
 		_, _ = serverSide.Write([]byte{'X'})
 	}()
 
-	_, err := negotiateUpstreamSSL(context.Background(), clientSide, "example.com", "prefer")
+	_, err := negotiateUpstreamSSL(context.Background(), clientSide, &store.Server{Host: "example.com", SSLMode: "prefer"})
 	if !errors.Is(err, ErrUpstreamSSLResponse) {
 		t.Fatalf("expected ErrUpstreamSSLResponse, got %v", err)
 	}
@@ -152,7 +155,7 @@ func TestNegotiateUpstreamSSL_AcceptUpgradesToTLS(t *testing.T) {
 		serverErr <- tlsConn.Handshake()
 	}()
 
-	out, err := negotiateUpstreamSSL(context.Background(), clientSide, "example.com", "require")
+	out, err := negotiateUpstreamSSL(context.Background(), clientSide, &store.Server{Host: "example.com", SSLMode: "require"})
 	if err != nil {
 		t.Fatalf("require + S: handshake failed: %v", err)
 	}
@@ -170,21 +173,47 @@ func TestNegotiateUpstreamSSL_AcceptUpgradesToTLS(t *testing.T) {
 	}
 }
 
-func TestUpstreamTLSConfig_ServerNameSetForVerifyModes(t *testing.T) {
+// TestNegotiateUpstreamSSL_VerifyCAIgnoresHostname checks the ssl_root_cert
+// bundle is used, and that verify-ca accepts a certificate issued for another
+// host name where verify-full refuses it.
+func TestNegotiateUpstreamSSL_VerifyCAIgnoresHostname(t *testing.T) {
 	t.Parallel()
 
-	cfg := upstreamTLSConfig("example.com", "verify-full")
-	if cfg.ServerName != "example.com" || cfg.InsecureSkipVerify {
-		t.Fatalf("verify-full: ServerName=%q InsecureSkipVerify=%v", cfg.ServerName, cfg.InsecureSkipVerify)
+	tlsConf, err := generateSelfSignedTLS()
+	if err != nil {
+		t.Fatalf("generate cert: %v", err)
 	}
 
-	cfg = upstreamTLSConfig("example.com", "verify-ca")
-	if cfg.ServerName != "example.com" || cfg.InsecureSkipVerify {
-		t.Fatalf("verify-ca: ServerName=%q InsecureSkipVerify=%v", cfg.ServerName, cfg.InsecureSkipVerify)
-	}
+	rootCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsConf.Certificates[0].Certificate[0]}))
+
+	for mode, wantOK := range map[string]bool{"verify-ca": true, "verify-full": false} {
+		t.Run(mode, func(t *testing.T) {
+			t.Parallel()
+
+			clientSide, serverSide := net.Pipe()
+			defer func() { _ = clientSide.Close() }()
 
-	cfg = upstreamTLSConfig("example.com", "require")
-	if !cfg.InsecureSkipVerify {
-		t.Fatalf("require: expected InsecureSkipVerify=true")
+			go func() {
+				defer func() { _ = serverSide.Close() }()
+				if err := readSSLRequest(serverSide); err != nil {
+					return
+				}
+				if _, err := serverSide.Write([]byte{'S'}); err != nil {
+					return
+				}
+				_ = tls.Server(serverSide, tlsConf).Handshake()
+			}()
+
+			// The certificate is issued for localhost.
+			db := &store.Server{Host: "example.com", SSLMode: mode, SSLRootCert: rootCert}
+
+			_, err := negotiateUpstreamSSL(context.Background(), clientSide, db)
+			if wantOK && err != nil {
+				t.Fatalf("%s: handshake failed: %v", mode, err)
+			}
+			if !wantOK && err == nil {
+				t.Fatalf("%s: expected a host name verification error", mode)
+			}
+		})
 	}
 }
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// ErrUnsupportedControl is returned at login when the grant carries a control
+// this proxy cannot enforce. Sessions fail closed rather than silently
+// ignoring a restriction.
+var ErrUnsupportedControl = errors.New("grant control not supported by this database type")
+
+// CheckControls refuses grants carrying controls the proxy of protocol does
+// not enforce (e.g. mask_pii on a MySQL database), table and client network
+// allowlists included.
+func CheckControls(grant *store.Grant, protocol string) error {
+	unsupported := store.UnsupportedControls(grant.Controls, protocol)
+	if grant.RestrictsTables() && !store.SupportsAllowedTables(protocol) {
+		unsupported = append(unsupported, "allowed_tables")
+	}
+
+	if grant.RestrictsClients() && !store.SupportsAllowedCIDRs(protocol) {
+		unsupported = append(unsupported, "allowed_cidrs")
+	}
+
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupportedControl, strings.Join(unsupported, ", "))
+	}
+
+	return nil
+}
+
+// piiTerms are the column-name words identifying personal data for the
+// mask_pii control. Multi-word terms match consecutive name segments.
+var piiTerms = [][]string{
+	{"email"}, {"e", "mail"}, {"mail"},
+	{"phone"}, {"telephone"}, {"mobile"}, {"msisdn"},
+	{"ssn"}, {"social", "security"}, {"passport"}, {"national", "id"}, {"tax", "id"},
+	{"iban"}, {"card", "number"}, {"credit", "card"}, {"cc", "number"}, {"cvv"},
+	{"birthdate"}, {"birthday"}, {"birth", "date"}, {"date", "of", "birth"}, {"dob"},
+	{"address"}, {"street"}, {"postcode"}, {"zip", "code"}, {"zipcode"},
+	{"firstname"}, {"lastname"}, {"surname"}, {"first", "name"}, {"last", "name"}, {"full", "name"},
+	{"ip"},
+}
+
+// IsPIIColumn reports whether a result column name identifies personal data.
+// Names are split into lower-case words on underscores, spaces, dashes, dots
+// and camelCase boundaries, so user_email, EMAIL and homePhone all match.
+func IsPIIColumn(name string) bool {
+	words := columnWords(name)
+
+	for _, term := range piiTerms {
+		for i := 0; i+len(term) <= len(words); i++ {
+			if slices.Equal(words[i:i+len(term)], term) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// columnWords splits a column name into lower-case words.
+func columnWords(name string) []string {
+	var (
+		words []string
+		word  strings.Builder
+		prev  rune
+	)
+
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			word.WriteRune(unicode.ToLower(r))
+		}
+
+		prev = r
+	}
+
+	flush()
+
+	return words
+}
@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestIsPIIColumn(t *testing.T) {
+	t.Parallel()
+
+	pii := []string{
+		"email", "EMAIL", "user_email", "emailAddress", "e_mail", "phone", "homePhone", "mobile_number",
+		"ssn", "social_security_number", "passport_no", "date_of_birth", "birth_date", "dob",
+		"billing_address", "street", "zip_code", "first_name", "LastName", "surname", "iban",
+		"credit_card", "card_number", "client_ip", "tax_id",
+	}
+	for _, name := range pii {
+		if !IsPIIColumn(name) {
+			t.Errorf("IsPIIColumn(%q) = false, want true", name)
+		}
+	}
+
+	notPII := []string{"id", "created_at", "name", "mailbox_size", "phonetic", "ipsum", "status", "birthplace_id", "zip"}
+	for _, name := range notPII {
+		if IsPIIColumn(name) {
+			t.Errorf("IsPIIColumn(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestCheckControls(t *testing.T) {
+	t.Parallel()
+
+	grant := &store.Grant{Controls: []string{store.ControlReadOnly, store.ControlMaskPII}}
+
+	if err := CheckControls(grant, store.ProtocolPostgreSQL); err != nil {
+		t.Errorf("CheckControls(postgresql) error = %v", err)
+	}
+
+	if err := CheckControls(grant, store.ProtocolOracle); !errors.Is(err, ErrUnsupportedControl) {
+		t.Errorf("CheckControls(oracle) error = %v, want %v", err, ErrUnsupportedControl)
+	}
+
+	restricted := &store.Grant{AllowedTables: []string{"public.orders"}}
+
+	if err := CheckControls(restricted, store.ProtocolPostgreSQL); err != nil {
+		t.Errorf("CheckControls(postgresql, allowed_tables) error = %v", err)
+	}
+
+	if err := CheckControls(restricted, store.ProtocolMySQL); !errors.Is(err, ErrUnsupportedControl) {
+		t.Errorf("CheckControls(mysql, allowed_tables) error = %v, want %v", err, ErrUnsupportedControl)
+	}
+}
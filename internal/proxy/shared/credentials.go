@@ -0,0 +1,28 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fclairamb/dbbat/internal/secrets"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// UseUpstreamCredentials sets srv's Username and Password to those a grant
+// of accessLevel logs in upstream with, as store.Server.UseUpstreamCredentials
+// does, except that the main password of a server with a secret_ref is
+// fetched from its secrets manager. Read-only credentials are always stored.
+func UseUpstreamCredentials(ctx context.Context, srv *store.Server, encryptionKey []byte, accessLevel string) error {
+	if srv.SecretRef == "" || srv.UsesReadOnlyCredentials(accessLevel) {
+		return srv.UseUpstreamCredentials(encryptionKey, accessLevel)
+	}
+
+	password, err := secrets.Resolve(ctx, srv.SecretRef)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the password from the secrets manager: %w", err)
+	}
+
+	srv.Password = password
+
+	return nil
+}
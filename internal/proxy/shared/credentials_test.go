@@ -0,0 +1,60 @@
+package shared
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/secrets"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+//nolint:paralleltest // Replaces the process-wide secrets resolver
+func TestUseUpstreamCredentials_SecretRef(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"data":{"password":"fromvault"}}}`))
+	}))
+	defer vault.Close()
+
+	resolver, err := secrets.NewResolver(config.SecretsConfig{VaultAddr: vault.URL, VaultToken: "hvs.token"})
+	require.NoError(t, err)
+
+	secrets.SetDefault(resolver)
+	t.Cleanup(func() {
+		fallback, _ := secrets.NewResolver(config.SecretsConfig{})
+		secrets.SetDefault(fallback)
+	})
+
+	key := make([]byte, 32)
+	srv := &store.Server{
+		Username:         "writer",
+		SecretRef:        "vault://secret/dbbat/orders",
+		ReadOnlyUsername: "reader",
+	}
+
+	srv.ReadOnlyPasswordEncrypted, err = crypto.Encrypt([]byte("readerpass"), key, crypto.ServerAAD(srv.UID.String()))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	readWrite := *srv
+	require.NoError(t, UseUpstreamCredentials(ctx, &readWrite, key, store.AccessLevelReadWrite))
+	assert.Equal(t, "writer", readWrite.Username)
+	assert.Equal(t, "fromvault", readWrite.Password)
+
+	// Read-only credentials are stored, whatever the secret_ref.
+	readOnly := *srv
+	require.NoError(t, UseUpstreamCredentials(ctx, &readOnly, key, store.AccessLevelReadOnly))
+	assert.Equal(t, "reader", readOnly.Username)
+	assert.Equal(t, "readerpass", readOnly.Password)
+
+	missing := *srv
+	missing.SecretRef = "aws-sm://prod/orders"
+	require.Error(t, UseUpstreamCredentials(ctx, &missing, key, store.AccessLevelReadWrite))
+}
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainState is process-wide, like the panic counts: once draining starts,
+// the proxies stop accepting sessions, the API refuses logins and its health
+// check reports the sessions left.
+var drainState = struct {
+	once      sync.Once
+	requested chan struct{}
+	since     atomic.Pointer[time.Time]
+}{requested: make(chan struct{})}
+
+// activeSessions counts the proxy sessions in progress per protocol.
+var activeSessions = struct {
+	sync.Mutex
+	byProtocol map[string]int64
+}{byProtocol: make(map[string]int64)}
+
+// StartDraining marks the process as draining and wakes up DrainRequested.
+// It reports false when the process already was.
+func StartDraining() bool {
+	started := false
+
+	drainState.once.Do(func() {
+		now := time.Now()
+		drainState.since.Store(&now)
+		close(drainState.requested)
+
+		started = true
+	})
+
+	return started
+}
+
+// DrainRequested is closed once StartDraining is called.
+func DrainRequested() <-chan struct{} {
+	return drainState.requested
+}
+
+// DrainingSince returns when the process started draining, nil when it is
+// not draining.
+func DrainingSince() *time.Time {
+	return drainState.since.Load()
+}
+
+// TrackSession counts a session of protocol as active until the returned
+// function is called. It must be deferred directly:
+//
+//	defer shared.TrackSession("postgresql")()
+func TrackSession(protocol string) func() {
+	activeSessions.Lock()
+	activeSessions.byProtocol[protocol]++
+	activeSessions.Unlock()
+
+	return func() {
+		activeSessions.Lock()
+		activeSessions.byProtocol[protocol]--
+		activeSessions.Unlock()
+	}
+}
+
+// ActiveSessions returns the number of proxy sessions in progress per
+// protocol, omitting the protocols without any.
+func ActiveSessions() map[string]int64 {
+	activeSessions.Lock()
+	defer activeSessions.Unlock()
+
+	counts := make(map[string]int64, len(activeSessions.byProtocol))
+	for protocol, n := range activeSessions.byProtocol {
+		if n > 0 {
+			counts[protocol] = n
+		}
+	}
+
+	return counts
+}
+
+// WaitSessions waits for the sessions of wg to end, until ctx is done.
+func WaitSessions(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("sessions still active: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,90 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTrackSession(t *testing.T) {
+	t.Parallel()
+
+	// Protocols of their own, so tests tracking real ones do not interfere.
+	doneA := TrackSession("test track a")
+	doneB := TrackSession("test track a")
+	doneC := TrackSession("test track b")
+
+	counts := ActiveSessions()
+	if counts["test track a"] != 2 || counts["test track b"] != 1 {
+		t.Errorf("ActiveSessions() = %v, want 2 test track a and 1 test track b", counts)
+	}
+
+	doneA()
+	doneC()
+
+	counts = ActiveSessions()
+	if counts["test track a"] != 1 {
+		t.Errorf("ActiveSessions()[test track a] = %d, want 1", counts["test track a"])
+	}
+
+	if _, ok := counts["test track b"]; ok {
+		t.Errorf("ActiveSessions() lists test track b without sessions: %v", counts)
+	}
+
+	doneB()
+}
+
+func TestWaitSessions(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := WaitSessions(ctx, &wg); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitSessions() with an active session = %v, want DeadlineExceeded", err)
+	}
+
+	wg.Done()
+
+	if err := WaitSessions(context.Background(), &wg); err != nil {
+		t.Errorf("WaitSessions() without sessions = %v, want nil", err)
+	}
+}
+
+func TestStartDraining(t *testing.T) {
+	t.Parallel()
+
+	select {
+	case <-DrainRequested():
+		t.Fatal("DrainRequested() closed before StartDraining()")
+	default:
+	}
+
+	if DrainingSince() != nil {
+		t.Fatal("DrainingSince() set before StartDraining()")
+	}
+
+	if !StartDraining() {
+		t.Error("StartDraining() = false, want true the first time")
+	}
+
+	if StartDraining() {
+		t.Error("StartDraining() = true, want false once draining")
+	}
+
+	select {
+	case <-DrainRequested():
+	default:
+		t.Error("DrainRequested() not closed after StartDraining()")
+	}
+
+	if DrainingSince() == nil {
+		t.Error("DrainingSince() = nil after StartDraining()")
+	}
+}
@@ -23,6 +23,15 @@ var (
 	// ErrGrantRevoked indicates the grant backing the session was revoked
 	// (by an admin, via the API) while the connection was still live.
 	ErrGrantRevoked = errors.New("grant revoked")
+	// ErrSessionIdle indicates the session exchanged nothing with the client
+	// for longer than the proxy's idle timeout.
+	ErrSessionIdle = errors.New("session idle timeout")
+	// ErrSessionMaxDuration indicates the session outlived the proxy's
+	// maximum session duration.
+	ErrSessionMaxDuration = errors.New("session maximum duration reached")
+	// ErrSessionTerminated indicates an administrator terminated the
+	// connection via the API.
+	ErrSessionTerminated = errors.New("session terminated by an administrator")
 )
 
 // DefaultLimitPollInterval is how often the watchdog re-evaluates limits when
@@ -50,6 +59,18 @@ type LimitGuard struct {
 	maxBytes  *int64
 	expiresAt time.Time
 
+	// idleTimeout and maxDuration are the proxy-wide session timeouts, 0 when
+	// disabled; see WithSessionTimeouts. busy, when set, reports a statement
+	// in flight, which keeps the session from being idle. startedAt is when
+	// the session started; lastBytes and lastActive track the client traffic
+	// and are only touched by Watch.
+	idleTimeout time.Duration
+	maxDuration time.Duration
+	busy        func() bool
+	startedAt   time.Time
+	lastBytes   int64
+	lastActive  time.Time
+
 	// revoked, when non-nil, is the session's shared revocation flag. It is
 	// flipped to true by the API's grant-revoke path (via the store's
 	// RevocationRegistry). Checked on the data path so a revoked grant blocks
@@ -57,6 +78,25 @@ type LimitGuard struct {
 	// there is no revocation to watch.
 	revoked *atomic.Bool
 
+	// extendedTo, when non-nil, is the grant's extended expiry in Unix
+	// nanoseconds (0 until extended), set by the API's grant extension
+	// approval path (via the store's RevocationRegistry). A later value
+	// supersedes expiresAt.
+	extendedTo *atomic.Int64
+
+	// terminated, when non-nil, is the session's termination flag, flipped by
+	// the API's connection termination path (via the store's
+	// TerminationRegistry). Only Watch checks it: the session is torn down,
+	// not just its next query refused.
+	terminated *atomic.Bool
+
+	// warnLead and warn, set by WithExpiryWarning, announce the grant's
+	// expiry ahead of time. warnedFor is the expiry last announced, so an
+	// extension gets an announcement of its own. Only touched by Watch.
+	warnLead  time.Duration
+	warn      func(expiresAt time.Time)
+	warnedFor time.Time
+
 	// now is the clock, injectable for deterministic tests. Defaults to
 	// time.Now.
 	now func() time.Time
@@ -96,6 +136,78 @@ func (g *LimitGuard) WithRevocation(revoked *atomic.Bool) *LimitGuard {
 	return g
 }
 
+// WithExtension attaches the session's extended expiry to the guard so an
+// extension approved mid-session pushes the expiry back. Returns the guard for
+// fluent construction; a nil value is a no-op.
+func (g *LimitGuard) WithExtension(extendedTo *atomic.Int64) *LimitGuard {
+	if g == nil {
+		return g
+	}
+
+	g.extendedTo = extendedTo
+
+	return g
+}
+
+// WithTermination attaches the session's termination flag to the guard so
+// Watch ends the session when an administrator terminates it. Returns the
+// guard for fluent construction; a nil flag is a no-op.
+func (g *LimitGuard) WithTermination(terminated *atomic.Bool) *LimitGuard {
+	if g == nil {
+		return g
+	}
+
+	g.terminated = terminated
+
+	return g
+}
+
+// WithExpiryWarning makes Watch call warn once the grant expires within lead,
+// so the client can be told before its session ends; warn is called again
+// for the new expiry of an extension. lead <= 0 or a nil warn disables it.
+// Returns the guard for fluent construction.
+func (g *LimitGuard) WithExpiryWarning(lead time.Duration, warn func(expiresAt time.Time)) *LimitGuard {
+	if g == nil || lead <= 0 {
+		return g
+	}
+
+	g.warnLead = lead
+	g.warn = warn
+
+	return g
+}
+
+// WithSessionTimeouts makes Watch end the session once it has exchanged no
+// byte with the client for idle, or once it has lasted maxDuration; 0
+// disables either. busy, which may be nil, reports whether a statement is in
+// flight: a session waiting on a slow statement is not idle. Both timeouts
+// count from now. Returns the guard for fluent construction.
+func (g *LimitGuard) WithSessionTimeouts(idle, maxDuration time.Duration, busy func() bool) *LimitGuard {
+	if g == nil {
+		return g
+	}
+
+	g.idleTimeout = idle
+	g.maxDuration = maxDuration
+	g.busy = busy
+	g.startedAt = g.now()
+	g.lastActive = g.startedAt
+	g.lastBytes = g.liveBytes()
+
+	return g
+}
+
+// expiry returns the grant's expiry, pushed back by an approved extension.
+func (g *LimitGuard) expiry() time.Time {
+	if g.extendedTo != nil {
+		if extended := g.extendedTo.Load(); extended != 0 && time.Unix(0, extended).After(g.expiresAt) {
+			return time.Unix(0, extended)
+		}
+	}
+
+	return g.expiresAt
+}
+
 // liveBytes returns this session's cumulative client-side bytes so far.
 func (g *LimitGuard) liveBytes() int64 {
 	var total int64
@@ -129,14 +241,83 @@ func (g *LimitGuard) Check() error {
 		return ErrByteQuotaExceeded
 	}
 
-	if !g.expiresAt.IsZero() && !g.now().Before(g.expiresAt) {
+	if expiresAt := g.expiry(); !expiresAt.IsZero() && !g.now().Before(expiresAt) {
 		return ErrGrantExpired
 	}
 
 	return nil
 }
 
-// Watch polls Check on a ticker until a limit is crossed or ctx is canceled.
+// checkSession reports the session timeout that has been reached, if any.
+// Unlike Check, it tracks the session's activity, so only Watch calls it.
+func (g *LimitGuard) checkSession() error {
+	if g.idleTimeout <= 0 && g.maxDuration <= 0 {
+		return nil
+	}
+
+	now := g.now()
+
+	if g.maxDuration > 0 && now.Sub(g.startedAt) >= g.maxDuration {
+		return ErrSessionMaxDuration
+	}
+
+	if g.idleTimeout <= 0 {
+		return nil
+	}
+
+	if bytes := g.liveBytes(); bytes != g.lastBytes || (g.busy != nil && g.busy()) {
+		g.lastBytes = bytes
+		g.lastActive = now
+
+		return nil
+	}
+
+	if now.Sub(g.lastActive) >= g.idleTimeout {
+		return ErrSessionIdle
+	}
+
+	return nil
+}
+
+// warnExpiry calls warn when the grant's expiry, not yet announced, is within
+// the warning lead. Only Watch calls it.
+func (g *LimitGuard) warnExpiry() {
+	if g.warn == nil {
+		return
+	}
+
+	expiresAt := g.expiry()
+	if expiresAt.IsZero() || expiresAt.Equal(g.warnedFor) || g.now().Before(expiresAt.Add(-g.warnLead)) {
+		return
+	}
+
+	g.warnedFor = expiresAt
+	g.warn(expiresAt)
+}
+
+// watchCheck is the check Watch runs: an administrator's termination, the
+// grant's limits, then the session timeouts. A session within bounds is
+// warned of its grant's upcoming expiry.
+func (g *LimitGuard) watchCheck() error {
+	if g.terminated != nil && g.terminated.Load() {
+		return ErrSessionTerminated
+	}
+
+	if err := g.Check(); err != nil {
+		return err
+	}
+
+	if err := g.checkSession(); err != nil {
+		return err
+	}
+
+	g.warnExpiry()
+
+	return nil
+}
+
+// Watch polls the termination flag, Check, then the session timeouts, on a ticker until a limit is
+// crossed or ctx is canceled.
 // On the first violation it invokes onViolation with the offending error and
 // returns; onViolation is never called more than once. interval <= 0 falls back
 // to DefaultLimitPollInterval.
@@ -153,7 +334,9 @@ func (g *LimitGuard) Watch(ctx context.Context, interval time.Duration, onViolat
 	// Nothing to enforce — avoid spinning a pointless ticker for the lifetime
 	// of the session. A revocation flag is itself something to watch, so keep
 	// polling whenever one is attached even if the grant carries no limits.
-	if g.maxBytes == nil && g.expiresAt.IsZero() && g.revoked == nil {
+	// Terminations and session timeouts are enforced here alone.
+	if g.maxBytes == nil && g.expiresAt.IsZero() && g.revoked == nil && g.terminated == nil &&
+		g.idleTimeout <= 0 && g.maxDuration <= 0 {
 		return
 	}
 
@@ -163,7 +346,7 @@ func (g *LimitGuard) Watch(ctx context.Context, interval time.Duration, onViolat
 
 	// Immediate check so an already-exhausted/expired grant is caught without
 	// waiting a full interval.
-	if err := g.Check(); err != nil {
+	if err := g.watchCheck(); err != nil {
 		onViolation(err)
 
 		return
@@ -177,7 +360,7 @@ func (g *LimitGuard) Watch(ctx context.Context, interval time.Duration, onViolat
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := g.Check(); err != nil {
+			if err := g.watchCheck(); err != nil {
 				onViolation(err)
 
 				return
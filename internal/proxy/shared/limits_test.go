@@ -78,6 +78,30 @@ func TestLimitGuard_Check_Expiry(t *testing.T) {
 	}
 }
 
+func TestLimitGuard_Check_Extension(t *testing.T) {
+	t.Parallel()
+
+	grant := &store.Grant{
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	var extendedTo atomic.Int64
+
+	g := NewLimitGuard(grant, &atomic.Int64{}, &atomic.Int64{}).WithExtension(&extendedTo)
+	g.setNow(func() time.Time { return grant.ExpiresAt.Add(time.Second) })
+
+	if err := g.Check(); !errors.Is(err, ErrGrantExpired) {
+		t.Fatalf("Check() after expiry = %v, want ErrGrantExpired", err)
+	}
+
+	// An extension approved mid-session pushes the expiry back.
+	extendedTo.Store(grant.ExpiresAt.Add(time.Hour).UnixNano())
+
+	if err := g.Check(); err != nil {
+		t.Fatalf("Check() after extension = %v, want nil", err)
+	}
+}
+
 func TestLimitGuard_Check_NoLimitsNeverTrips(t *testing.T) {
 	t.Parallel()
 
@@ -315,3 +339,185 @@ func TestLimitGuard_Watch_NoLimitsReturnsImmediately(t *testing.T) {
 		t.Fatal("Watch with no limits did not return")
 	}
 }
+
+func TestLimitGuard_CheckSession_Idle(t *testing.T) {
+	t.Parallel()
+
+	var from, to atomic.Int64
+
+	now := time.Unix(0, 0)
+	busy := false
+
+	g := NewLimitGuard(nil, &from, &to)
+	g.setNow(func() time.Time { return now })
+	g.WithSessionTimeouts(time.Minute, 0, func() bool { return busy })
+
+	now = now.Add(50 * time.Second)
+	if err := g.checkSession(); err != nil {
+		t.Fatalf("checkSession() before the idle timeout = %v, want nil", err)
+	}
+
+	// Client traffic restarts the idle clock.
+	from.Add(10)
+	if err := g.checkSession(); err != nil {
+		t.Fatalf("checkSession() with traffic = %v, want nil", err)
+	}
+
+	now = now.Add(50 * time.Second)
+	if err := g.checkSession(); err != nil {
+		t.Fatalf("checkSession() 50s after traffic = %v, want nil", err)
+	}
+
+	// So does a statement in flight, however silent.
+	busy = true
+	now = now.Add(time.Hour)
+	if err := g.checkSession(); err != nil {
+		t.Fatalf("checkSession() with a statement in flight = %v, want nil", err)
+	}
+
+	busy = false
+	now = now.Add(time.Minute)
+	if err := g.checkSession(); !errors.Is(err, ErrSessionIdle) {
+		t.Fatalf("checkSession() after the idle timeout = %v, want ErrSessionIdle", err)
+	}
+}
+
+func TestLimitGuard_CheckSession_MaxDuration(t *testing.T) {
+	t.Parallel()
+
+	var from atomic.Int64
+
+	now := time.Unix(0, 0)
+
+	g := NewLimitGuard(nil, &from, &atomic.Int64{})
+	g.setNow(func() time.Time { return now })
+	g.WithSessionTimeouts(time.Minute, time.Hour, nil)
+
+	// A busy session still ends at its maximum duration.
+	for range 59 {
+		now = now.Add(time.Minute)
+		from.Add(1)
+
+		if err := g.checkSession(); err != nil {
+			t.Fatalf("checkSession() at %s = %v, want nil", now.Sub(time.Unix(0, 0)), err)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	from.Add(1)
+
+	if err := g.checkSession(); !errors.Is(err, ErrSessionMaxDuration) {
+		t.Fatalf("checkSession() at the maximum duration = %v, want ErrSessionMaxDuration", err)
+	}
+}
+
+func TestLimitGuard_Watch_FiresOnIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	g := NewLimitGuard(nil, &atomic.Int64{}, &atomic.Int64{}).WithSessionTimeouts(20*time.Millisecond, 0, nil)
+
+	got := make(chan error, 1)
+
+	go g.Watch(context.Background(), 5*time.Millisecond, func(err error) {
+		got <- err
+	})
+
+	select {
+	case err := <-got:
+		if !errors.Is(err, ErrSessionIdle) {
+			t.Fatalf("Watch onViolation = %v, want ErrSessionIdle", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch did not fire onViolation for the idle timeout")
+	}
+}
+
+func TestLimitGuard_Watch_FiresOnTermination(t *testing.T) {
+	t.Parallel()
+
+	var terminated atomic.Bool
+
+	g := NewLimitGuard(nil, &atomic.Int64{}, &atomic.Int64{}).WithTermination(&terminated)
+
+	if err := g.Check(); err != nil {
+		t.Fatalf("Check() = %v before termination, want nil", err)
+	}
+
+	got := make(chan error, 1)
+
+	go g.Watch(context.Background(), 5*time.Millisecond, func(err error) {
+		got <- err
+	})
+
+	terminated.Store(true)
+
+	select {
+	case err := <-got:
+		if !errors.Is(err, ErrSessionTerminated) {
+			t.Fatalf("Watch onViolation = %v, want ErrSessionTerminated", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch did not fire onViolation for the termination")
+	}
+}
+
+func TestLimitGuard_WarnExpiry(t *testing.T) {
+	t.Parallel()
+
+	grant := &store.Grant{
+		ExpiresAt: time.Unix(3600, 0),
+	}
+
+	var (
+		extendedTo atomic.Int64
+		warnings   []time.Time
+	)
+
+	now := time.Unix(0, 0)
+
+	g := NewLimitGuard(grant, &atomic.Int64{}, &atomic.Int64{}).
+		WithExtension(&extendedTo).
+		WithExpiryWarning(5*time.Minute, func(expiresAt time.Time) { warnings = append(warnings, expiresAt) })
+	g.setNow(func() time.Time { return now })
+
+	if err := g.watchCheck(); err != nil || len(warnings) != 0 {
+		t.Fatalf("watchCheck() an hour before expiry = %v, warned %v, want no warning", err, warnings)
+	}
+
+	// Within the lead: warned once.
+	now = time.Unix(3600-299, 0)
+	for range 2 {
+		if err := g.watchCheck(); err != nil {
+			t.Fatalf("watchCheck() before expiry = %v, want nil", err)
+		}
+	}
+
+	if len(warnings) != 1 || !warnings[0].Equal(grant.ExpiresAt) {
+		t.Fatalf("warnings = %v, want one for %v", warnings, grant.ExpiresAt)
+	}
+
+	// An extension gets a warning of its own once its expiry nears.
+	extendedTo.Store(time.Unix(7200, 0).UnixNano())
+
+	if err := g.watchCheck(); err != nil || len(warnings) != 1 {
+		t.Fatalf("watchCheck() after extension = %v, warned %v, want no new warning", err, warnings)
+	}
+
+	now = time.Unix(7200-60, 0)
+	if err := g.watchCheck(); err != nil || len(warnings) != 2 || !warnings[1].Equal(time.Unix(7200, 0)) {
+		t.Fatalf("watchCheck() before the extended expiry = %v, warned %v, want a second warning", err, warnings)
+	}
+}
+
+func TestLimitGuard_WarnExpiry_Disabled(t *testing.T) {
+	t.Parallel()
+
+	grant := &store.Grant{ExpiresAt: time.Now().Add(time.Minute)}
+
+	g := NewLimitGuard(grant, &atomic.Int64{}, &atomic.Int64{}).
+		WithExpiryWarning(0, func(time.Time) { t.Error("warned with a zero lead") })
+
+	if err := g.watchCheck(); err != nil {
+		t.Fatalf("watchCheck() = %v, want nil", err)
+	}
+}
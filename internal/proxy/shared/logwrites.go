@@ -0,0 +1,75 @@
+package shared
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// LogWrites tracks the background goroutines that persist query logs, so a
+// graceful shutdown can wait for them before the store is closed. The zero
+// value is ready to use.
+type LogWrites struct {
+	wg      sync.WaitGroup
+	pending atomic.Int64
+}
+
+// Go runs fn in a tracked goroutine. A nil LogWrites runs it untracked, for
+// sessions built without a server (unit tests).
+func (w *LogWrites) Go(fn func()) {
+	if w == nil {
+		go fn()
+
+		return
+	}
+
+	w.wg.Add(1)
+	w.pending.Add(1)
+
+	go func() {
+		defer w.wg.Done()
+		defer w.pending.Add(-1)
+
+		fn()
+	}()
+}
+
+// Pending returns the number of writes still running.
+func (w *LogWrites) Pending() int64 {
+	return w.pending.Load()
+}
+
+// Flush waits for the running writes until ctx is done and reports how many
+// completed and how many were still running (and will be lost once the store
+// closes).
+func (w *LogWrites) Flush(ctx context.Context, logger *slog.Logger) (flushed, dropped int64) {
+	waiting := w.pending.Load()
+	if waiting == 0 {
+		return 0, 0
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		dropped = w.pending.Load()
+	}
+
+	flushed = max(waiting-dropped, 0)
+
+	if dropped > 0 {
+		logger.WarnContext(ctx, "query logs dropped at shutdown",
+			slog.Int64("flushed", flushed), slog.Int64("dropped", dropped))
+	} else {
+		logger.InfoContext(ctx, "query logs flushed at shutdown", slog.Int64("flushed", flushed))
+	}
+
+	return flushed, dropped
+}
@@ -0,0 +1,90 @@
+package shared
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLogWritesFlush(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.DiscardHandler)
+
+	t.Run("waits for pending writes", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			writes LogWrites
+			done   atomic.Int64
+		)
+
+		for range 3 {
+			writes.Go(func() {
+				time.Sleep(20 * time.Millisecond)
+				done.Add(1)
+			})
+		}
+
+		flushed, dropped := writes.Flush(context.Background(), logger)
+		if flushed != 3 || dropped != 0 {
+			t.Errorf("Flush() = %d flushed, %d dropped, want 3, 0", flushed, dropped)
+		}
+
+		if done.Load() != 3 {
+			t.Errorf("%d writes completed, want 3", done.Load())
+		}
+	})
+
+	t.Run("reports writes still running at the deadline", func(t *testing.T) {
+		t.Parallel()
+
+		var writes LogWrites
+
+		release := make(chan struct{})
+		defer close(release)
+
+		writes.Go(func() {})
+		writes.Go(func() { <-release })
+
+		// Let the quick write finish.
+		for writes.Pending() > 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		flushed, dropped := writes.Flush(ctx, logger)
+		if flushed != 0 || dropped != 1 {
+			t.Errorf("Flush() = %d flushed, %d dropped, want 0, 1", flushed, dropped)
+		}
+	})
+
+	t.Run("nothing pending", func(t *testing.T) {
+		t.Parallel()
+
+		var writes LogWrites
+
+		if flushed, dropped := writes.Flush(context.Background(), logger); flushed != 0 || dropped != 0 {
+			t.Errorf("Flush() = %d flushed, %d dropped, want 0, 0", flushed, dropped)
+		}
+	})
+
+	t.Run("nil tracker still runs the write", func(t *testing.T) {
+		t.Parallel()
+
+		var writes *LogWrites
+
+		ran := make(chan struct{})
+		writes.Go(func() { close(ran) })
+
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatal("write never ran")
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package shared
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fclairamb/dbbat/internal/lineage"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// maskedValue is stored in place of a masked column's value.
+type maskedValue struct {
+	Masked bool `json:"$masked"`
+}
+
+// MaskedValue replaces the value of a masked column in captured rows. It
+// encodes as {"$masked":true}, which no scalar column value can be.
+var MaskedValue any = maskedValue{Masked: true}
+
+// ColumnMasker decides which result columns of a database are masked before
+// captured rows are stored, per its store.ResultMasking. A nil masker masks
+// nothing.
+type ColumnMasker struct {
+	patterns []*regexp.Regexp
+	// columns are the lower-cased dotted parts of each masked column.
+	columns [][]string
+}
+
+// NewColumnMasker compiles a database's result masking; nil when it masks
+// nothing. Patterns that do not compile, which the API refuses, are skipped.
+func NewColumnMasker(masking *store.ResultMasking) *ColumnMasker {
+	if masking.IsZero() {
+		return nil
+	}
+
+	m := &ColumnMasker{}
+
+	for _, pattern := range masking.ColumnPatterns {
+		if re, err := regexp.Compile("(?i)" + pattern); err == nil {
+			m.patterns = append(m.patterns, re)
+		}
+	}
+
+	for _, column := range masking.Columns {
+		m.columns = append(m.columns, strings.Split(strings.ToLower(column), "."))
+	}
+
+	return m
+}
+
+// Masks reports whether column is masked in the result of a statement reading
+// tables. A table.column entry matches when one of tables ends with its table
+// part, so users.email matches the tables users and public.users.
+func (m *ColumnMasker) Masks(column string, tables []string) bool {
+	if m == nil {
+		return false
+	}
+
+	for _, re := range m.patterns {
+		if re.MatchString(column) {
+			return true
+		}
+	}
+
+	column = strings.ToLower(column)
+
+	for _, parts := range m.columns {
+		if parts[len(parts)-1] != column {
+			continue
+		}
+
+		qualifier := parts[:len(parts)-1]
+		if len(qualifier) == 0 {
+			return true
+		}
+
+		for _, table := range tables {
+			tableParts := strings.Split(strings.ToLower(table), ".")
+			if len(tableParts) >= len(qualifier) && slices.Equal(tableParts[len(tableParts)-len(qualifier):], qualifier) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MaskRow replaces the masked values of a captured row, keyed by column name.
+func (m *ColumnMasker) MaskRow(row map[string]any, tables []string) {
+	if m == nil {
+		return
+	}
+
+	for column := range row {
+		if m.Masks(column, tables) {
+			row[column] = MaskedValue
+		}
+	}
+}
+
+// StatementTables returns the tables a statement reads or writes, which the
+// table.column entries of a result masking are matched against.
+func StatementTables(sql, dialect string) []string {
+	tables := lineage.ExtractTables(sql, dialect)
+
+	return append(tables.Inputs, tables.Outputs...)
+}
+
+// LazyColumnMasker compiles the masker of a session's database on first use:
+// sessions learn their database during login, before any row is captured.
+type LazyColumnMasker struct {
+	once   sync.Once
+	masker *ColumnMasker
+}
+
+// Get returns the masker of db, compiled on the first call.
+func (l *LazyColumnMasker) Get(db *store.Server) *ColumnMasker {
+	l.once.Do(func() {
+		if db != nil {
+			l.masker = NewColumnMasker(db.ResultMasking)
+		}
+	})
+
+	return l.masker
+}
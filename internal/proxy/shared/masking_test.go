@@ -0,0 +1,85 @@
+package shared
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestColumnMasker_Masks(t *testing.T) {
+	t.Parallel()
+
+	m := NewColumnMasker(&store.ResultMasking{
+		ColumnPatterns: []string{"^ssn$", "password"},
+		Columns:        []string{"phone", "users.email", "billing.cards.number"},
+	})
+
+	for _, tc := range []struct {
+		column string
+		tables []string
+		want   bool
+	}{
+		{"ssn", nil, true},
+		{"SSN", nil, true},
+		{"ssn_hint", nil, false},
+		{"password_hash", nil, true},
+		{"phone", []string{"contacts"}, true},
+		{"Phone", nil, true},
+		{"email", []string{"users"}, true},
+		{"email", []string{"public.users"}, true},
+		{"email", []string{"PUBLIC.USERS"}, true},
+		{"email", []string{"accounts"}, false},
+		{"email", []string{"superusers"}, false},
+		{"email", nil, false},
+		{"number", []string{"billing.cards"}, true},
+		{"number", []string{"cards"}, false},
+		{"number", []string{"archive.cards"}, false},
+		{"name", []string{"users"}, false},
+	} {
+		if got := m.Masks(tc.column, tc.tables); got != tc.want {
+			t.Errorf("Masks(%q, %v) = %v, want %v", tc.column, tc.tables, got, tc.want)
+		}
+	}
+}
+
+func TestColumnMasker_Nil(t *testing.T) {
+	t.Parallel()
+
+	for _, masking := range []*store.ResultMasking{nil, {}} {
+		m := NewColumnMasker(masking)
+		if m != nil {
+			t.Fatalf("NewColumnMasker(%+v) = %+v, want nil", masking, m)
+		}
+
+		if m.Masks("ssn", nil) {
+			t.Error("a nil masker masks a column")
+		}
+
+		row := map[string]any{"ssn": "123"}
+		m.MaskRow(row, nil)
+
+		if row["ssn"] != "123" {
+			t.Errorf("a nil masker changed the row: %v", row)
+		}
+	}
+}
+
+func TestColumnMasker_MaskRow(t *testing.T) {
+	t.Parallel()
+
+	m := NewColumnMasker(&store.ResultMasking{Columns: []string{"users.email"}})
+	row := map[string]any{"id": 1, "email": "alice@example.com"}
+
+	m.MaskRow(row, []string{"public.users"})
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	want := `{"email":{"$masked":true},"id":1}`
+	if string(data) != want {
+		t.Errorf("masked row = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,296 @@
+package shared
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PROXY protocol errors. A trusted peer that fails to send a well-formed
+// header has its connection refused rather than silently attributed to the
+// load balancer's address.
+var (
+	// ErrProxyHeaderMissing indicates a trusted peer sent bytes that do not
+	// start with a PROXY v1 or v2 signature.
+	ErrProxyHeaderMissing = errors.New("proxy protocol: header missing")
+	// ErrProxyHeaderInvalid indicates a PROXY header was present but could
+	// not be parsed.
+	ErrProxyHeaderInvalid = errors.New("proxy protocol: invalid header")
+)
+
+// proxyHeaderTimeout bounds how long a trusted peer has to deliver its PROXY
+// header. Load balancers send it immediately on connect, so this only trips
+// on misconfigured peers (e.g. a balancer not actually sending headers).
+const proxyHeaderTimeout = 10 * time.Second
+
+// proxyV1MaxLen is the maximum length of a v1 header line, CRLF included.
+const proxyV1MaxLen = 107
+
+// proxyV2Signature is the fixed 12-byte prefix of every v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ProxyProtocolListener wraps a net.Listener so connections from trusted
+// peers are expected to start with a HAProxy PROXY protocol (v1 or v2)
+// header. The header is consumed before any protocol bytes reach the session,
+// and RemoteAddr/LocalAddr report the addresses it carried.
+//
+// Parsing is lazy — it happens on the first Read, RemoteAddr or LocalAddr
+// call, from the session goroutine — so a slow peer never stalls the accept
+// loop.
+type ProxyProtocolListener struct {
+	net.Listener
+	trusted []*net.IPNet
+}
+
+// NewProxyProtocolListener wraps l. trusted lists the peers that may send a
+// header; nil or empty trusts no peer, so every connection is handled as a
+// direct client.
+func NewProxyProtocolListener(l net.Listener, trusted []*net.IPNet) *ProxyProtocolListener {
+	return &ProxyProtocolListener{Listener: l, trusted: trusted}
+}
+
+// Accept implements net.Listener.
+func (l *ProxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.isTrusted(conn.RemoteAddr()) {
+		return conn, nil
+	}
+
+	return &proxyProtocolConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// isTrusted reports whether addr may send a PROXY header.
+func (l *ProxyProtocolListener) isTrusted(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	for _, network := range l.trusted {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// proxyProtocolConn strips the PROXY header from a trusted connection.
+type proxyProtocolConn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+// Read implements net.Conn, consuming the header on first use.
+func (c *proxyProtocolConn) Read(p []byte) (int, error) {
+	c.once.Do(c.readHeader)
+
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	return c.reader.Read(p)
+}
+
+// RemoteAddr returns the client address from the header, or the peer address
+// when the header carried none (v1 UNKNOWN, v2 LOCAL) or failed to parse.
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address from the header, falling back to
+// the socket's local address.
+func (c *proxyProtocolConn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+
+	return c.Conn.LocalAddr()
+}
+
+// readHeader parses the PROXY header under a read deadline.
+func (c *proxyProtocolConn) readHeader() {
+	_ = c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	defer func() { _ = c.Conn.SetReadDeadline(time.Time{}) }()
+
+	c.remoteAddr, c.localAddr, c.err = ReadProxyHeader(c.reader)
+}
+
+// ReadProxyHeader consumes a PROXY protocol v1 or v2 header from r and returns
+// the source and destination addresses it carries. Both are nil for headers
+// that carry no address (v1 "UNKNOWN", v2 LOCAL command, or a non-TCP family).
+func ReadProxyHeader(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrProxyHeaderMissing, err)
+	}
+
+	switch first[0] {
+	case 'P':
+		return readProxyV1(r)
+	case proxyV2Signature[0]:
+		return readProxyV2(r)
+	default:
+		return nil, nil, ErrProxyHeaderMissing
+	}
+}
+
+// readProxyV1 parses a human-readable v1 header:
+//
+//	PROXY TCP4 192.0.2.1 198.51.100.1 56324 5432\r\n
+func readProxyV1(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	var line []byte
+
+	for len(line) < proxyV1MaxLen {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrProxyHeaderInvalid, err)
+		}
+
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, nil, fmt.Errorf("%w: v1 header not terminated by CRLF", ErrProxyHeaderInvalid)
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, nil, fmt.Errorf("%w: malformed v1 header", ErrProxyHeaderInvalid)
+	}
+
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil, nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported v1 protocol %q", ErrProxyHeaderInvalid, fields[1])
+	}
+
+	if len(fields) != 6 {
+		return nil, nil, fmt.Errorf("%w: malformed v1 header", ErrProxyHeaderInvalid)
+	}
+
+	src, err := parseV1Addr(fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dst, err := parseV1Addr(fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return src, dst, nil
+}
+
+// parseV1Addr builds a TCP address from a v1 header's textual IP and port.
+func parseV1Addr(host, port string) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: bad address %q", ErrProxyHeaderInvalid, host)
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad port %q", ErrProxyHeaderInvalid, port)
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+// v2 command and address family constants (high/low nibbles of bytes 13/14).
+const (
+	proxyV2Version    = 0x2
+	proxyV2CmdLocal   = 0x0
+	proxyV2CmdProxy   = 0x1
+	proxyV2FamilyInet = 0x1
+	proxyV2FamilyIP6  = 0x2
+	proxyV2HeaderLen  = 16
+)
+
+// readProxyV2 parses a binary v2 header. TLVs following the addresses are
+// skipped.
+func readProxyV2(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	header := make([]byte, proxyV2HeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrProxyHeaderInvalid, err)
+	}
+
+	if !bytes.Equal(header[:len(proxyV2Signature)], proxyV2Signature) {
+		return nil, nil, ErrProxyHeaderMissing
+	}
+
+	if header[12]>>4 != proxyV2Version {
+		return nil, nil, fmt.Errorf("%w: unsupported v2 version %d", ErrProxyHeaderInvalid, header[12]>>4)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrProxyHeaderInvalid, err)
+	}
+
+	switch header[12] & 0x0f {
+	case proxyV2CmdLocal:
+		// Health checks from the balancer itself: keep the socket addresses.
+		return nil, nil, nil
+	case proxyV2CmdProxy:
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported v2 command %d", ErrProxyHeaderInvalid, header[12]&0x0f)
+	}
+
+	var ipLen int
+
+	switch header[13] >> 4 {
+	case proxyV2FamilyInet:
+		ipLen = net.IPv4len
+	case proxyV2FamilyIP6:
+		ipLen = net.IPv6len
+	default:
+		// AF_UNSPEC / AF_UNIX: nothing usable as a client IP.
+		return nil, nil, nil
+	}
+
+	if len(payload) < 2*ipLen+4 {
+		return nil, nil, fmt.Errorf("%w: v2 address block too short", ErrProxyHeaderInvalid)
+	}
+
+	src := &net.TCPAddr{
+		IP:   net.IP(append([]byte(nil), payload[:ipLen]...)),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLen:])),
+	}
+	dst := &net.TCPAddr{
+		IP:   net.IP(append([]byte(nil), payload[ipLen:2*ipLen]...)),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLen+2:])),
+	}
+
+	return src, dst, nil
+}
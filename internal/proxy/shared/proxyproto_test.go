@@ -0,0 +1,211 @@
+package shared
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// proxyV2Header builds a v2 PROXY header for a TCP-over-IPv4 connection.
+func proxyV2Header(t *testing.T, src, dst *net.TCPAddr) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.Write(proxyV2Signature)
+	buf.WriteByte(0x21) // version 2, PROXY command
+	buf.WriteByte(0x11) // AF_INET, STREAM
+	_ = binary.Write(&buf, binary.BigEndian, uint16(12))
+	buf.Write(src.IP.To4())
+	buf.Write(dst.IP.To4())
+	_ = binary.Write(&buf, binary.BigEndian, uint16(src.Port))
+	_ = binary.Write(&buf, binary.BigEndian, uint16(dst.Port))
+
+	return buf.Bytes()
+}
+
+func TestReadProxyHeader_V1(t *testing.T) {
+	t.Parallel()
+
+	r := bufio.NewReader(strings.NewReader("PROXY TCP4 192.0.2.1 198.51.100.1 56324 5432\r\nrest"))
+
+	src, dst, err := ReadProxyHeader(r)
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1:56324", src.String())
+	assert.Equal(t, "198.51.100.1:5432", dst.String())
+
+	rest, _ := io.ReadAll(r)
+	assert.Equal(t, "rest", string(rest), "bytes after the header must be left for the session")
+}
+
+func TestReadProxyHeader_V1Unknown(t *testing.T) {
+	t.Parallel()
+
+	src, dst, err := ReadProxyHeader(bufio.NewReader(strings.NewReader("PROXY UNKNOWN\r\n")))
+	require.NoError(t, err)
+	assert.Nil(t, src)
+	assert.Nil(t, dst)
+}
+
+func TestReadProxyHeader_V2(t *testing.T) {
+	t.Parallel()
+
+	header := proxyV2Header(t,
+		&net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 40000},
+		&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5434})
+	r := bufio.NewReader(bytes.NewReader(append(header, 'x')))
+
+	src, dst, err := ReadProxyHeader(r)
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7:40000", src.String())
+	assert.Equal(t, "10.0.0.1:5434", dst.String())
+
+	b, err := r.ReadByte()
+	require.NoError(t, err)
+	assert.Equal(t, byte('x'), b)
+}
+
+func TestReadProxyHeader_V2Local(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	buf.Write(proxyV2Signature)
+	buf.WriteByte(0x20) // version 2, LOCAL command
+	buf.WriteByte(0x00)
+	_ = binary.Write(&buf, binary.BigEndian, uint16(0))
+
+	src, dst, err := ReadProxyHeader(bufio.NewReader(&buf))
+	require.NoError(t, err)
+	assert.Nil(t, src)
+	assert.Nil(t, dst)
+}
+
+func TestReadProxyHeader_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"no header", "\x00\x00\x00\x08\x04\xd2\x16\x2f", ErrProxyHeaderMissing},
+		{"empty", "", ErrProxyHeaderMissing},
+		{"v1 no CRLF", "PROXY TCP4 1.2.3.4 5.6.7.8 1 2\n", ErrProxyHeaderInvalid},
+		{"v1 bad ip", "PROXY TCP4 nope 5.6.7.8 1 2\r\n", ErrProxyHeaderInvalid},
+		{"v1 bad port", "PROXY TCP4 1.2.3.4 5.6.7.8 70000 2\r\n", ErrProxyHeaderInvalid},
+		{"v1 bad proto", "PROXY UDP4 1.2.3.4 5.6.7.8 1 2\r\n", ErrProxyHeaderInvalid},
+		{"v1 too long", "PROXY " + strings.Repeat("A", 200), ErrProxyHeaderInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := ReadProxyHeader(bufio.NewReader(strings.NewReader(tt.input)))
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
+
+// loopback trusts the test client, which always dials from 127.0.0.1.
+func loopback(t *testing.T) []*net.IPNet {
+	t.Helper()
+
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+
+	return []*net.IPNet{network}
+}
+
+// dialThroughListener accepts one connection on a PROXY-aware listener after
+// the client writes payload, returning the server-side conn.
+func dialThroughListener(t *testing.T, trusted []*net.IPNet, payload []byte) net.Conn {
+	t.Helper()
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	listener := NewProxyProtocolListener(inner, trusted)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	_, err = client.Write(payload)
+	require.NoError(t, err)
+
+	server, err := listener.Accept()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = server.Close() })
+
+	return server
+}
+
+func TestProxyProtocolListener_TrustedPeerUsesHeaderAddress(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte("PROXY TCP4 192.0.2.55 127.0.0.1 51000 5434\r\nhello")
+	server := dialThroughListener(t, loopback(t), payload)
+
+	assert.Equal(t, "192.0.2.55:51000", server.RemoteAddr().String())
+
+	buf := make([]byte, 5)
+	_, err := io.ReadFull(server, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+}
+
+func TestProxyProtocolListener_UntrustedPeerIsNotParsed(t *testing.T) {
+	t.Parallel()
+
+	_, onlyElsewhere, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	// A client outside the trusted range cannot spoof its address: the
+	// "header" reaches the session as ordinary protocol bytes.
+	payload := []byte("PROXY TCP4 192.0.2.55 127.0.0.1 51000 5434\r\n")
+	server := dialThroughListener(t, []*net.IPNet{onlyElsewhere}, payload)
+
+	host, _, err := net.SplitHostPort(server.RemoteAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+
+	buf := make([]byte, len(payload))
+	_, err = io.ReadFull(server, buf)
+	require.NoError(t, err)
+	assert.Equal(t, payload, buf)
+}
+
+func TestProxyProtocolListener_EmptyTrustListTrustsNoPeer(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte("PROXY TCP4 192.0.2.55 127.0.0.1 51000 5434\r\n")
+	server := dialThroughListener(t, nil, payload)
+
+	host, _, err := net.SplitHostPort(server.RemoteAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+
+	buf := make([]byte, len(payload))
+	_, err = io.ReadFull(server, buf)
+	require.NoError(t, err)
+	assert.Equal(t, payload, buf)
+}
+
+func TestProxyProtocolListener_TrustedPeerWithoutHeaderIsRejected(t *testing.T) {
+	t.Parallel()
+
+	server := dialThroughListener(t, loopback(t), []byte{0, 0, 0, 8, 4, 210, 22, 47})
+
+	_, err := server.Read(make([]byte, 8))
+	require.ErrorIs(t, err, ErrProxyHeaderMissing)
+}
@@ -0,0 +1,31 @@
+package shared
+
+import (
+	"sync/atomic"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// QueryStorage holds the query storage limits of a proxy. A configuration
+// reload replaces them; sessions started afterwards use the new ones.
+type QueryStorage struct {
+	cfg atomic.Pointer[config.QueryStorageConfig]
+}
+
+// NewQueryStorage returns a holder of cfg.
+func NewQueryStorage(cfg config.QueryStorageConfig) *QueryStorage {
+	q := &QueryStorage{}
+	q.cfg.Store(&cfg)
+
+	return q
+}
+
+// Load returns the limits in effect.
+func (q *QueryStorage) Load() config.QueryStorageConfig {
+	return *q.cfg.Load()
+}
+
+// Store replaces the limits.
+func (q *QueryStorage) Store(cfg config.QueryStorageConfig) {
+	q.cfg.Store(&cfg)
+}
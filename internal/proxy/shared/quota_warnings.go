@@ -0,0 +1,45 @@
+package shared
+
+// QuotaWarner tells when the use of a grant's quotas crosses the percentages
+// clients are warned at, so each threshold is announced once per session.
+// It belongs to the goroutine completing queries.
+type QuotaWarner struct {
+	thresholds []int
+	// warned is the highest threshold announced per quota.
+	warned map[string]int
+}
+
+// NewQuotaWarner creates a warner for thresholds, percentages in increasing
+// order. It returns nil, which warns of nothing, without thresholds.
+func NewQuotaWarner(thresholds []int) *QuotaWarner {
+	if len(thresholds) == 0 {
+		return nil
+	}
+
+	return &QuotaWarner{thresholds: thresholds, warned: make(map[string]int)}
+}
+
+// Crossed returns the highest threshold the use of quota, which allows limit,
+// crossed since it was last announced, 0 when none did. A nil limit has no
+// quota; a used-up quota is refused, not warned of.
+func (w *QuotaWarner) Crossed(quota string, used int64, limit *int64) int {
+	if w == nil || limit == nil || *limit <= 0 || used >= *limit {
+		return 0
+	}
+
+	crossed := 0
+
+	for _, threshold := range w.thresholds {
+		if used*100 >= *limit*int64(threshold) {
+			crossed = threshold
+		}
+	}
+
+	if crossed <= w.warned[quota] {
+		return 0
+	}
+
+	w.warned[quota] = crossed
+
+	return crossed
+}
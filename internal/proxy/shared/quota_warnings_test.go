@@ -0,0 +1,39 @@
+package shared
+
+import "testing"
+
+func TestQuotaWarner(t *testing.T) {
+	t.Parallel()
+
+	limit := int64(100)
+	w := NewQuotaWarner([]int{80, 95})
+
+	steps := []struct {
+		used int64
+		want int
+	}{
+		{79, 0},
+		{80, 80},
+		{85, 0},  // already announced
+		{99, 95}, // crossed 95
+		{100, 0}, // used up: refused, not warned of
+	}
+
+	for _, step := range steps {
+		if got := w.Crossed("queries", step.used, &limit); got != step.want {
+			t.Errorf("Crossed(queries, %d) = %d, want %d", step.used, got, step.want)
+		}
+	}
+
+	if got := w.Crossed("bytes", 96, &limit); got != 95 {
+		t.Errorf("Crossed(bytes, 96) = %d, want 95: quotas are announced separately", got)
+	}
+
+	if got := w.Crossed("rows", 99, nil); got != 0 {
+		t.Errorf("Crossed() without a quota = %d, want 0", got)
+	}
+
+	if got := NewQuotaWarner(nil).Crossed("queries", 99, &limit); got != 0 {
+		t.Errorf("Crossed() without thresholds = %d, want 0", got)
+	}
+}
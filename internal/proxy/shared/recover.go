@@ -0,0 +1,71 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// ErrPanic wraps a panic recovered from a session goroutine, so the session
+// ends with an error instead of taking the whole proxy process down.
+var ErrPanic = errors.New("panic in proxy session")
+
+// panicCounts tallies recovered panics per component since process start.
+var panicCounts = struct {
+	sync.Mutex
+	byComponent map[string]int64
+}{byComponent: make(map[string]int64)}
+
+// RecoverPanic recovers a panic in the calling goroutine, logs it with its
+// stack trace and counts it. It must be deferred directly:
+//
+//	defer shared.RecoverPanic(ctx, logger, "postgresql session")
+func RecoverPanic(ctx context.Context, logger *slog.Logger, component string) {
+	if r := recover(); r != nil {
+		logPanic(ctx, logger, component, r)
+	}
+}
+
+// Safe runs fn, turning a panic into an ErrPanic error. Relay goroutines use
+// it so that a panic ends their session like any other I/O error.
+func Safe(ctx context.Context, logger *slog.Logger, component string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic(ctx, logger, component, r)
+			err = fmt.Errorf("%w: %s: %v", ErrPanic, component, r)
+		}
+	}()
+
+	return fn()
+}
+
+// PanicCounts returns the number of recovered panics per component.
+func PanicCounts() map[string]int64 {
+	panicCounts.Lock()
+	defer panicCounts.Unlock()
+
+	counts := make(map[string]int64, len(panicCounts.byComponent))
+	for component, n := range panicCounts.byComponent {
+		counts[component] = n
+	}
+
+	return counts
+}
+
+func logPanic(ctx context.Context, logger *slog.Logger, component string, r any) {
+	panicCounts.Lock()
+	panicCounts.byComponent[component]++
+	panicCounts.Unlock()
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger.ErrorContext(ctx, "recovered panic",
+		slog.String("component", component),
+		slog.Any("panic", r),
+		slog.String("stack", string(debug.Stack())))
+}
@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestSafe(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	logger := slog.New(slog.DiscardHandler)
+	errIO := errors.New("connection reset")
+
+	tests := []struct {
+		name      string
+		component string
+		fn        func() error
+		want      error
+		panics    int64
+	}{
+		{"nil", "test safe nil", func() error { return nil }, nil, 0},
+		{"error", "test safe error", func() error { return errIO }, errIO, 0},
+		{"panic", "test safe panic", func() error { panic("boom") }, ErrPanic, 1},
+		{"nil map write", "test safe runtime", func() error {
+			var m map[string]int
+			m["x"] = 1
+
+			return nil
+		}, ErrPanic, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Safe(ctx, logger, tt.component, tt.fn)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("Safe() error = %v, want %v", err, tt.want)
+			}
+
+			if got := PanicCounts()[tt.component]; got != tt.panics {
+				t.Errorf("PanicCounts()[%q] = %d, want %d", tt.component, got, tt.panics)
+			}
+		})
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	t.Parallel()
+
+	const component = "test recover"
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer RecoverPanic(context.Background(), slog.New(slog.DiscardHandler), component)
+
+		panic("boom")
+	}()
+
+	<-done
+
+	if got := PanicCounts()[component]; got != 1 {
+		t.Errorf("PanicCounts()[%q] = %d, want 1", component, got)
+	}
+}
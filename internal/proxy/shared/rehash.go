@@ -0,0 +1,31 @@
+package shared
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// UpgradePasswordHash rehashes the password of a user who just logged in
+// with it, in the background, when its stored hash uses another algorithm
+// or other parameters than the configured ones.
+func UpgradePasswordHash(ctx context.Context, logger *slog.Logger, dataStore *store.Store, user *store.User, password string) {
+	if dataStore == nil || !crypto.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	uid, currentHash := user.UID, user.PasswordHash
+
+	go func() {
+		defer RecoverPanic(ctx, logger, "password rehash")
+
+		storeCtx, cancel := StoreContext(context.WithoutCancel(ctx))
+		defer cancel()
+
+		if _, err := dataStore.UpgradePasswordHash(storeCtx, uid, currentHash, password); err != nil {
+			logger.WarnContext(ctx, "failed to upgrade password hash", slog.Any("error", err))
+		}
+	}()
+}
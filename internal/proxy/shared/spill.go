@@ -0,0 +1,79 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/spill"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// BeginSpill starts spilling a result past the query storage limits with the
+// process-wide spiller, beginning with the rows captured so far. It returns
+// nil when spilling is disabled or fails: the result is then discarded, as
+// without spilling.
+func BeginSpill(ctx context.Context, logger *slog.Logger, captured []store.QueryRow) *spill.Writer {
+	spiller := spill.Default()
+	if spiller == nil {
+		return nil
+	}
+
+	w, err := spiller.Begin()
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to spill result", slog.Any("error", err))
+		return nil
+	}
+
+	for _, row := range captured {
+		if w = SpillRow(ctx, logger, w, row.RowData); w == nil {
+			return nil
+		}
+	}
+
+	return w
+}
+
+// SpillRow appends a row to w. On failure, or once the result grows past
+// the spill size cap, w is discarded and SpillRow returns nil.
+func SpillRow(ctx context.Context, logger *slog.Logger, w *spill.Writer, row json.RawMessage) *spill.Writer {
+	if err := w.Write(row); err != nil {
+		logger.WarnContext(ctx, "result spill abandoned", slog.Int64("rows_spilled", w.Rows()), slog.Any("error", err))
+		w.Discard()
+
+		return nil
+	}
+
+	return w
+}
+
+// CommitSpill uploads the result of w and records it on its query, from the
+// goroutine logging the query. ctx must not be bounded by StoreTimeout: the
+// upload has a timeout of its own.
+func CommitSpill(ctx context.Context, logger *slog.Logger, dataStore *store.Store, queryUID uuid.UUID, w *spill.Writer) {
+	spiller := spill.Default()
+	if spiller == nil {
+		w.Discard()
+		return
+	}
+
+	rows := w.Rows()
+
+	key, err := spiller.Commit(ctx, w)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to upload spilled result",
+			slog.String("query_uid", queryUID.String()), slog.Any("error", err))
+
+		return
+	}
+
+	storeCtx, cancel := StoreContext(ctx)
+	defer cancel()
+
+	if err := dataStore.SetQueryResultObject(storeCtx, queryUID, key, rows); err != nil {
+		logger.ErrorContext(ctx, "failed to record spilled result",
+			slog.String("query_uid", queryUID.String()), slog.String("key", key), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,56 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// StoreTimeout bounds each storage call made on behalf of a proxy session, so
+// a slow or unreachable storage database fails the call (and the login or
+// log write behind it) instead of hanging the session indefinitely.
+const StoreTimeout = 10 * time.Second
+
+// Retries of idempotent storage writes: attempts in total, and the delay
+// before the first retry (doubled for each following one).
+const (
+	storeWriteAttempts     = 3
+	storeWriteRetryBackoff = 200 * time.Millisecond
+)
+
+// StoreContext derives the context of one storage call from ctx, bounded by
+// StoreTimeout.
+func StoreContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, StoreTimeout)
+}
+
+// RetryStoreWrite runs an idempotent storage write, each attempt bounded by
+// StoreTimeout, retrying failures with a backoff until it succeeds, the
+// attempts are exhausted or ctx is done. Only writes that can safely be
+// applied twice (closing a connection, completing a query) may use it.
+func RetryStoreWrite(ctx context.Context, write func(ctx context.Context) error) error {
+	backoff := storeWriteRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		callCtx, cancel := StoreContext(ctx)
+		err := write(callCtx)
+
+		cancel()
+
+		if err == nil || attempt == storeWriteAttempts || errors.Is(err, context.Canceled) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
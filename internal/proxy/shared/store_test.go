@@ -0,0 +1,64 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryStoreWrite(t *testing.T) {
+	t.Parallel()
+
+	errStorage := errors.New("storage unavailable")
+
+	tests := []struct {
+		name      string
+		failures  int
+		cancel    bool
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "first attempt succeeds", failures: 0, wantCalls: 1},
+		{name: "succeeds after a retry", failures: 1, wantCalls: 2},
+		{name: "gives up after the last attempt", failures: 5, wantErr: errStorage, wantCalls: storeWriteAttempts},
+		{name: "no retry once canceled", failures: 5, cancel: true, wantErr: errStorage, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			calls := 0
+
+			err := RetryStoreWrite(ctx, func(callCtx context.Context) error {
+				calls++
+
+				if deadline, ok := callCtx.Deadline(); !ok || time.Until(deadline) > StoreTimeout {
+					t.Errorf("call context deadline = %v, %v; want within %v", deadline, ok, StoreTimeout)
+				}
+
+				if tt.cancel {
+					cancel()
+				}
+
+				if calls <= tt.failures {
+					return errStorage
+				}
+
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("RetryStoreWrite() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("write called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
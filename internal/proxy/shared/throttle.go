@@ -0,0 +1,142 @@
+package shared
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Throttle is a token bucket capping the bytes per second forwarded by the
+// sessions sharing it. The bucket holds one second of traffic, so a burst up
+// to the rate goes through at once. A message larger than what is left still
+// goes through, putting the bucket in debt: the following messages wait until
+// it is repaid, which keeps the average at the rate.
+type Throttle struct {
+	rate float64 // bytes per second
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// now is the clock, injectable for deterministic tests. Defaults to
+	// time.Now.
+	now func() time.Time
+}
+
+// NewThrottle returns a throttle at bytesPerSecond, starting with a full
+// bucket.
+func NewThrottle(bytesPerSecond int64) *Throttle {
+	return &Throttle{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// Wait takes n bytes from the bucket, blocking while it is in debt. It
+// returns ctx's error if ctx is done first; the bytes are taken either way.
+// A nil throttle never blocks.
+func (t *Throttle) Wait(ctx context.Context, n int) error {
+	if t == nil || n <= 0 {
+		return nil
+	}
+
+	delay := t.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes n bytes from the bucket and returns how long the caller must
+// wait for the bucket to be out of debt.
+func (t *Throttle) reserve(n int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.tokens = min(t.rate, t.tokens+now.Sub(t.last).Seconds()*t.rate)
+	t.last = now
+	t.tokens -= float64(n)
+
+	if t.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-t.tokens / t.rate * float64(time.Second))
+}
+
+// Throttles shares one Throttle between the live sessions of a grant, so
+// opening more connections does not multiply the grant's bandwidth. The zero
+// value is ready to use; a nil Throttles gives every session its own throttle,
+// for sessions built without a server (unit tests).
+type Throttles struct {
+	mu      sync.Mutex
+	byGrant map[uuid.UUID]*grantThrottle
+}
+
+// grantThrottle is the throttle of a grant with its live session count.
+type grantThrottle struct {
+	throttle *Throttle
+	sessions int
+}
+
+// Acquire returns the throttle of grantUID at bytesPerSecond, created for the
+// grant's first live session. Release must be called when the session ends.
+// Grant controls never change, so every session of a grant asks for the same
+// rate.
+func (t *Throttles) Acquire(grantUID uuid.UUID, bytesPerSecond int64) *Throttle {
+	if t == nil {
+		return NewThrottle(bytesPerSecond)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.byGrant == nil {
+		t.byGrant = make(map[uuid.UUID]*grantThrottle)
+	}
+
+	entry := t.byGrant[grantUID]
+	if entry == nil {
+		entry = &grantThrottle{throttle: NewThrottle(bytesPerSecond)}
+		t.byGrant[grantUID] = entry
+	}
+
+	entry.sessions++
+
+	return entry.throttle
+}
+
+// Release drops a session's hold on the throttle of grantUID, forgetting the
+// throttle with the grant's last session.
+func (t *Throttles) Release(grantUID uuid.UUID) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := t.byGrant[grantUID]
+	if entry == nil {
+		return
+	}
+
+	entry.sessions--
+	if entry.sessions <= 0 {
+		delete(t.byGrant, grantUID)
+	}
+}
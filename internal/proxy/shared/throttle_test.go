@@ -0,0 +1,89 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestThrottle_Reserve(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	th := NewThrottle(1000)
+	th.last = now
+	th.now = func() time.Time { return now }
+
+	// The first second of traffic goes through at once.
+	if d := th.reserve(600); d != 0 {
+		t.Errorf("reserve(600) = %s, want 0", d)
+	}
+
+	if d := th.reserve(400); d != 0 {
+		t.Errorf("reserve(400) = %s, want 0", d)
+	}
+
+	// Then the bucket is in debt.
+	if d := th.reserve(500); d != 500*time.Millisecond {
+		t.Errorf("reserve(500) on an empty bucket = %s, want 500ms", d)
+	}
+
+	// The debt is repaid over time, and the bucket never holds more than a
+	// second of traffic.
+	now = now.Add(10 * time.Second)
+
+	if d := th.reserve(1500); d != 500*time.Millisecond {
+		t.Errorf("reserve(1500) after idling = %s, want 500ms", d)
+	}
+}
+
+func TestThrottle_Wait(t *testing.T) {
+	t.Parallel()
+
+	var nilThrottle *Throttle
+	if err := nilThrottle.Wait(context.Background(), 1<<30); err != nil {
+		t.Errorf("nil throttle Wait() = %v", err)
+	}
+
+	th := NewThrottle(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := th.Wait(ctx, 3600); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() with a canceled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestThrottles(t *testing.T) {
+	t.Parallel()
+
+	var throttles Throttles
+
+	grantUID := uuid.New()
+
+	first := throttles.Acquire(grantUID, 1000)
+	if second := throttles.Acquire(grantUID, 1000); second != first {
+		t.Error("sessions of the same grant got different throttles")
+	}
+
+	if other := throttles.Acquire(uuid.New(), 1000); other == first {
+		t.Error("sessions of different grants share a throttle")
+	}
+
+	throttles.Release(grantUID)
+
+	if again := throttles.Acquire(grantUID, 1000); again != first {
+		t.Error("throttle forgotten while a session still holds it")
+	}
+
+	throttles.Release(grantUID)
+	throttles.Release(grantUID)
+
+	if fresh := throttles.Acquire(grantUID, 1000); fresh == first {
+		t.Error("throttle kept after the grant's last session")
+	}
+}
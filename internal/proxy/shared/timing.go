@@ -0,0 +1,106 @@
+package shared
+
+import (
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// QueryTimer measures where the time of a query goes, for the latency
+// breakdown of its log entry (store.QueryTiming). Proxies mark when the query
+// is forwarded upstream and when its replies come back, and report the time
+// they spend on each reply, so that it is not counted against upstream.
+//
+// It is not safe for concurrent use: proxies call it under the lock guarding
+// the query it belongs to.
+type QueryTimer struct {
+	start      time.Time // received from the client
+	forwarded  time.Time // forwarded upstream
+	firstReply time.Time // first reply from upstream arrived
+	lastReply  time.Time // last reply from upstream arrived
+	whole      bool      // the reply was read whole: no first byte to tell
+
+	// processed is the time spent by the proxy on the replies, and
+	// streamWork the part of it spent before the last one arrived.
+	processed  time.Duration
+	streamWork time.Duration
+}
+
+// StartQueryTimer starts timing a query received from the client at start.
+func StartQueryTimer(start time.Time) QueryTimer {
+	return QueryTimer{start: start}
+}
+
+// Start returns when the query was received from the client.
+func (t *QueryTimer) Start() time.Time {
+	return t.start
+}
+
+// Forwarded marks the query forwarded upstream at at. Only the first call
+// counts: a query forwarded in several messages is timed from the first.
+func (t *QueryTimer) Forwarded(at time.Time) {
+	if t.forwarded.IsZero() {
+		t.forwarded = at
+	}
+}
+
+// Replied marks a reply message from upstream arrived at at, before the proxy
+// works on it.
+func (t *QueryTimer) Replied(at time.Time) {
+	if t.firstReply.IsZero() {
+		t.firstReply = at
+	}
+
+	t.lastReply = at
+	t.streamWork = t.processed
+}
+
+// RepliedWhole marks the whole reply from upstream read at at, for protocols
+// where the proxy only gets it once complete: upstream time is then known, but
+// not the time to its first byte.
+func (t *QueryTimer) RepliedWhole(at time.Time) {
+	t.lastReply = at
+	t.whole = true
+}
+
+// Processed counts d spent by the proxy on a reply.
+func (t *QueryTimer) Processed(d time.Duration) {
+	t.processed += d
+}
+
+// Timing returns the breakdown of the query, completed at end. Whatever the
+// upstream parts do not account for is proxy overhead, so the parts add up
+// to the duration.
+func (t *QueryTimer) Timing(end time.Time) store.QueryTiming {
+	var (
+		timing   store.QueryTiming
+		upstream time.Duration
+	)
+
+	switch {
+	case t.forwarded.IsZero() || t.lastReply.IsZero():
+		// Refused by the proxy, or no reply (yet): nothing to ascribe upstream.
+	case t.whole:
+		upstream = max(t.lastReply.Sub(t.forwarded), 0)
+		timing.UpstreamMs = durationMs(upstream)
+	default:
+		firstByte := max(t.firstReply.Sub(t.forwarded), 0)
+		streaming := max(t.lastReply.Sub(t.firstReply)-t.streamWork, 0)
+		upstream = firstByte + streaming
+
+		timing.UpstreamFirstByteMs = durationMs(firstByte)
+		timing.StreamingMs = durationMs(streaming)
+		timing.UpstreamMs = durationMs(upstream)
+	}
+
+	timing.ProxyOverheadMs = durationMs(max(end.Sub(t.start)-upstream, 0))
+
+	return timing
+}
+
+// durationMs converts d to fractional milliseconds, as durations are logged.
+func durationMs(d time.Duration) *float64 {
+	ms := float64(d.Microseconds()) / 1000.0
+
+	return &ms
+}
@@ -0,0 +1,77 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryTimer(t *testing.T) {
+	t.Parallel()
+
+	at := func(ms int) time.Time { return time.Unix(0, 0).Add(time.Duration(ms) * time.Millisecond) }
+
+	t.Run("streamed reply", func(t *testing.T) {
+		t.Parallel()
+
+		timer := StartQueryTimer(at(0))
+		timer.Forwarded(at(2))
+		timer.Forwarded(at(5)) // a later message of the same query
+		timer.Replied(at(12))  // first byte after 10ms
+		timer.Processed(3 * time.Millisecond)
+		timer.Replied(at(20))
+		timer.Processed(1 * time.Millisecond)
+		timer.Replied(at(30)) // last reply: 18ms streaming minus 4ms of proxy work
+
+		timing := timer.Timing(at(31))
+
+		want := map[string]float64{"first byte": 10, "streaming": 14, "upstream": 24, "overhead": 7}
+		got := map[string]*float64{
+			"first byte": timing.UpstreamFirstByteMs,
+			"streaming":  timing.StreamingMs,
+			"upstream":   timing.UpstreamMs,
+			"overhead":   timing.ProxyOverheadMs,
+		}
+
+		for part, ms := range want {
+			if got[part] == nil || *got[part] != ms {
+				t.Errorf("%s = %v, want %v", part, got[part], ms)
+			}
+		}
+	})
+
+	t.Run("whole reply", func(t *testing.T) {
+		t.Parallel()
+
+		timer := StartQueryTimer(at(0))
+		timer.Forwarded(at(1))
+		timer.RepliedWhole(at(9))
+
+		timing := timer.Timing(at(10))
+		if timing.UpstreamFirstByteMs != nil || timing.StreamingMs != nil {
+			t.Errorf("first byte and streaming are unknown for a whole reply, got %v, %v", timing.UpstreamFirstByteMs, timing.StreamingMs)
+		}
+
+		if timing.UpstreamMs == nil || *timing.UpstreamMs != 8 {
+			t.Errorf("upstream = %v, want 8", timing.UpstreamMs)
+		}
+
+		if timing.ProxyOverheadMs == nil || *timing.ProxyOverheadMs != 2 {
+			t.Errorf("overhead = %v, want 2", timing.ProxyOverheadMs)
+		}
+	})
+
+	t.Run("refused query", func(t *testing.T) {
+		t.Parallel()
+
+		timer := StartQueryTimer(at(0))
+
+		timing := timer.Timing(at(4))
+		if timing.UpstreamMs != nil {
+			t.Errorf("upstream = %v, want nil for a query never forwarded", *timing.UpstreamMs)
+		}
+
+		if timing.ProxyOverheadMs == nil || *timing.ProxyOverheadMs != 4 {
+			t.Errorf("overhead = %v, want 4", timing.ProxyOverheadMs)
+		}
+	})
+}
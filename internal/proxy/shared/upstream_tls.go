@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// ErrInvalidSSLRootCert is returned when a server's ssl_root_cert holds no
+// PEM-encoded certificate.
+var ErrInvalidSSLRootCert = errors.New("ssl_root_cert contains no PEM certificate")
+
+var errNoPeerCertificate = errors.New("upstream presented no certificate")
+
+// ParseSSLRootCert parses a PEM CA bundle. An empty bundle returns a nil
+// pool, meaning the system roots.
+func ParseSSLRootCert(bundle string) (*x509.CertPool, error) {
+	if bundle == "" {
+		return nil, nil //nolint:nilnil // nil pool = system roots, as in tls.Config
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(bundle)) {
+		return nil, ErrInvalidSSLRootCert
+	}
+
+	return pool, nil
+}
+
+// UpstreamTLSVerifies reports whether the server's certificate is verified
+// for its ssl_mode. Like libpq, require verifies the chain as verify-ca does
+// when a root certificate is configured.
+func UpstreamTLSVerifies(srv *store.Server) bool {
+	switch srv.SSLMode {
+	case "verify-ca", "verify-full":
+		return true
+	case "require":
+		return srv.SSLRootCert != ""
+	}
+
+	return false
+}
+
+// UpstreamTLSConfig builds the TLS configuration of a connection to srv,
+// following libpq's sslmode semantics:
+//
+//   - verify-full: the chain is verified against ssl_root_cert (the system
+//     roots when empty) and the certificate must match the host.
+//   - verify-ca, and require with an ssl_root_cert: the chain is verified,
+//     the host name is not.
+//   - other modes: the connection is encrypted without authenticating the
+//     server.
+//
+// The host is always sent as SNI, which managed services route on.
+func UpstreamTLSConfig(srv *store.Server) (*tls.Config, error) {
+	roots, err := ParseSSLRootCert(srv.SSLRootCert)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: srv.Host, RootCAs: roots}
+
+	switch {
+	case srv.SSLMode == "verify-full":
+		// Standard verification: chain and host name.
+	case UpstreamTLSVerifies(srv):
+		// crypto/tls cannot verify the chain without the host name, so
+		// verification is done in VerifyConnection instead.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyChain(cs, roots)
+		}
+	default:
+		cfg.InsecureSkipVerify = true
+	}
+
+	return cfg, nil
+}
+
+// verifyChain verifies the peer certificate chain against roots, ignoring
+// the host name.
+func verifyChain(cs tls.ConnectionState, roots *x509.CertPool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errNoPeerCertificate
+	}
+
+	opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(opts)
+
+	return err
+}
@@ -0,0 +1,151 @@
+package shared
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// selfSignedCert returns a self-signed CA certificate for host and its PEM
+// encoding.
+func selfSignedCert(t *testing.T, host string) (*x509.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestParseSSLRootCert(t *testing.T) {
+	t.Parallel()
+
+	_, caPEM := selfSignedCert(t, "db.internal")
+
+	pool, err := ParseSSLRootCert("")
+	if err != nil || pool != nil {
+		t.Fatalf("empty bundle = (%v, %v), want (nil, nil)", pool, err)
+	}
+
+	if _, err := ParseSSLRootCert("not a certificate"); !errors.Is(err, ErrInvalidSSLRootCert) {
+		t.Fatalf("garbage bundle error = %v, want ErrInvalidSSLRootCert", err)
+	}
+
+	if pool, err := ParseSSLRootCert(caPEM); err != nil || pool == nil {
+		t.Fatalf("valid bundle = (%v, %v), want a pool", pool, err)
+	}
+}
+
+func TestUpstreamTLSConfig_Modes(t *testing.T) {
+	t.Parallel()
+
+	_, caPEM := selfSignedCert(t, "db.internal")
+
+	tests := []struct {
+		name               string
+		mode               string
+		rootCert           string
+		wantSkipVerify     bool
+		wantVerifyCallback bool
+	}{
+		{name: "require", mode: "require", wantSkipVerify: true},
+		{name: "require with root cert", mode: "require", rootCert: caPEM, wantSkipVerify: true, wantVerifyCallback: true},
+		{name: "verify-ca", mode: "verify-ca", wantSkipVerify: true, wantVerifyCallback: true},
+		{name: "verify-full", mode: "verify-full", rootCert: caPEM},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := UpstreamTLSConfig(&store.Server{Host: "db.internal", SSLMode: tt.mode, SSLRootCert: tt.rootCert})
+			if err != nil {
+				t.Fatalf("UpstreamTLSConfig() error = %v", err)
+			}
+
+			if cfg.ServerName != "db.internal" {
+				t.Errorf("ServerName = %q, want db.internal", cfg.ServerName)
+			}
+			if cfg.MinVersion != tls.VersionTLS12 {
+				t.Errorf("MinVersion = %x, want TLS 1.2", cfg.MinVersion)
+			}
+			if cfg.InsecureSkipVerify != tt.wantSkipVerify {
+				t.Errorf("InsecureSkipVerify = %v, want %v", cfg.InsecureSkipVerify, tt.wantSkipVerify)
+			}
+			if (cfg.VerifyConnection != nil) != tt.wantVerifyCallback {
+				t.Errorf("VerifyConnection set = %v, want %v", cfg.VerifyConnection != nil, tt.wantVerifyCallback)
+			}
+			if (cfg.RootCAs != nil) != (tt.rootCert != "") {
+				t.Errorf("RootCAs set = %v, want %v", cfg.RootCAs != nil, tt.rootCert != "")
+			}
+		})
+	}
+}
+
+func TestUpstreamTLSConfig_InvalidRootCert(t *testing.T) {
+	t.Parallel()
+
+	_, err := UpstreamTLSConfig(&store.Server{Host: "db.internal", SSLMode: "verify-full", SSLRootCert: "garbage"})
+	if !errors.Is(err, ErrInvalidSSLRootCert) {
+		t.Fatalf("UpstreamTLSConfig() error = %v, want ErrInvalidSSLRootCert", err)
+	}
+}
+
+func TestUpstreamTLSConfig_VerifyCAChecksChainNotHost(t *testing.T) {
+	t.Parallel()
+
+	cert, caPEM := selfSignedCert(t, "db.internal")
+	other, _ := selfSignedCert(t, "db.internal")
+
+	// The host differs from the certificate's name: verify-ca must not care.
+	cfg, err := UpstreamTLSConfig(&store.Server{Host: "10.0.0.5", SSLMode: "verify-ca", SSLRootCert: caPEM})
+	if err != nil {
+		t.Fatalf("UpstreamTLSConfig() error = %v", err)
+	}
+
+	if err := cfg.VerifyConnection(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}); err != nil {
+		t.Errorf("trusted certificate rejected: %v", err)
+	}
+
+	if err := cfg.VerifyConnection(tls.ConnectionState{PeerCertificates: []*x509.Certificate{other}}); err == nil {
+		t.Error("certificate from an untrusted CA accepted")
+	}
+
+	if err := cfg.VerifyConnection(tls.ConnectionState{}); err == nil {
+		t.Error("connection without a peer certificate accepted")
+	}
+}
@@ -0,0 +1,220 @@
+// Package queryalerts evaluates query alerts, the saved query log filters
+// admins define: every instance periodically matches the queries logged since
+// each alert's last pass, records the matches, and POSTs them to the alert's
+// webhook.
+package queryalerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+const (
+	// settleDelay is how far behind the current time alerts are evaluated,
+	// so the queries of every instance are committed, and their completion
+	// recorded, before they are matched. A query completing later than that
+	// (Oracle logs them at start) is matched on what was logged by then.
+	settleDelay = 10 * time.Second
+	// passLimit bounds the matches an alert records per pass; the rest wait
+	// for the next one.
+	passLimit = 100
+	// sendAttempts and sendBackoff retry transient webhook failures.
+	sendAttempts = 3
+	sendBackoff  = time.Second
+	sendTimeout  = 10 * time.Second
+)
+
+// ErrWebhookStatus is returned when an alert webhook rejects a match.
+var ErrWebhookStatus = errors.New("query alert webhook returned an error status")
+
+// Payload is the JSON body POSTed to an alert's webhook for each match.
+type Payload struct {
+	Alert     AlertRef    `json:"alert"`
+	Query     store.Query `json:"query"`
+	MatchedAt time.Time   `json:"matched_at"`
+}
+
+// AlertRef identifies the alert a payload comes from.
+type AlertRef struct {
+	UID  uuid.UUID `json:"uid"`
+	Name string    `json:"name"`
+}
+
+// Evaluator runs the evaluation passes in the background. Matches are
+// claimed by the instance that moves an alert's cursor, so each is recorded
+// and sent once whatever the number of instances; a webhook that stays down
+// through the retries misses the match, which remains listed.
+type Evaluator struct {
+	store    *store.Store
+	interval time.Duration
+	client   *http.Client
+	logger   *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewEvaluator creates an evaluator running a pass every interval; Start
+// begins evaluating.
+func NewEvaluator(dataStore *store.Store, interval time.Duration, logger *slog.Logger) *Evaluator {
+	return &Evaluator{
+		store:    dataStore,
+		interval: interval,
+		client:   &http.Client{Timeout: sendTimeout},
+		logger:   logger.With(slog.String("component", "query_alerts")),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the evaluation passes until Shutdown.
+func (e *Evaluator) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+
+	go e.run(ctx)
+}
+
+// Shutdown stops the evaluator, interrupting a pass in progress, and waits
+// for it to return.
+func (e *Evaluator) Shutdown(ctx context.Context) error {
+	e.cancel()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("query alert evaluator shutdown interrupted: %w", ctx.Err())
+	}
+}
+
+func (e *Evaluator) run(ctx context.Context) {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.evaluate(ctx, time.Now().Add(-settleDelay))
+		}
+	}
+}
+
+// evaluate runs one pass over the enabled alerts, matching the queries logged
+// before until. Failures are logged: the alert is evaluated again from where
+// it stopped at the next pass.
+func (e *Evaluator) evaluate(ctx context.Context, until time.Time) {
+	alerts, err := e.store.ListQueryAlerts(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.logger.ErrorContext(ctx, "failed to list query alerts", slog.Any("error", err))
+		}
+
+		return
+	}
+
+	for i := range alerts {
+		alert := &alerts[i]
+		if !alert.Enabled {
+			continue
+		}
+
+		matches, err := e.store.EvaluateQueryAlert(ctx, alert, until, passLimit)
+		if err != nil {
+			if ctx.Err() == nil {
+				e.logger.ErrorContext(ctx, "failed to evaluate query alert",
+					slog.String("alert_uid", alert.UID.String()), slog.Any("error", err))
+			}
+
+			continue
+		}
+
+		if len(matches) == 0 {
+			continue
+		}
+
+		e.logger.InfoContext(ctx, "query alert matched",
+			slog.String("alert_uid", alert.UID.String()),
+			slog.String("alert", alert.Name),
+			slog.Int("queries", len(matches)))
+
+		if alert.WebhookURL == nil {
+			continue
+		}
+
+		for j := range matches {
+			payload := &Payload{
+				Alert:     AlertRef{UID: alert.UID, Name: alert.Name},
+				Query:     matches[j],
+				MatchedAt: *alert.LastMatchedAt,
+			}
+
+			if err := e.send(ctx, *alert.WebhookURL, payload); err != nil {
+				e.logger.WarnContext(ctx, "failed to send query alert webhook",
+					slog.String("alert_uid", alert.UID.String()),
+					slog.String("query_uid", matches[j].UID.String()),
+					slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// send POSTs a payload, retrying network errors and 5xx responses.
+func (e *Evaluator) send(ctx context.Context, url string, payload *Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		retry, err := e.post(ctx, url, body)
+		if err == nil || !retry || attempt == sendAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook retry interrupted: %w", ctx.Err())
+		case <-time.After(sendBackoff * time.Duration(attempt)):
+		}
+	}
+}
+
+// post sends one request, reporting whether a failure is worth retrying.
+func (e *Evaluator) post(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to post match: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("%w: %s", ErrWebhookStatus, resp.Status)
+	}
+
+	return false, nil
+}
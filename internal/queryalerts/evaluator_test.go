@@ -0,0 +1,80 @@
+package queryalerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestEvaluatorSend(t *testing.T) {
+	t.Parallel()
+
+	// webhook answers with statuses in turn, the last one repeated.
+	webhook := func(statuses ...int) (*httptest.Server, *atomic.Int32, chan Payload) {
+		calls := &atomic.Int32{}
+		received := make(chan Payload, sendAttempts)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := int(calls.Add(1))
+
+			if r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+			}
+
+			var payload Payload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Errorf("decode payload: %v", err)
+			}
+
+			received <- payload
+
+			w.WriteHeader(statuses[min(n, len(statuses))-1])
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv, calls, received
+	}
+
+	e := NewEvaluator(nil, time.Minute, slog.New(slog.DiscardHandler))
+	payload := &Payload{
+		Alert:     AlertRef{UID: uuid.New(), Name: "Deletes"},
+		Query:     store.Query{UID: uuid.New(), SQLText: "DELETE FROM orders"},
+		MatchedAt: time.Now(),
+	}
+
+	// A 5xx is retried.
+	srv, calls, received := webhook(http.StatusBadGateway, http.StatusNoContent)
+
+	if err := e.send(context.Background(), srv.URL, payload); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("send() made %d calls, want 2", calls.Load())
+	}
+
+	if got := <-received; got.Alert.Name != "Deletes" || got.Query.SQLText != "DELETE FROM orders" {
+		t.Errorf("webhook received %+v", got)
+	}
+
+	// A 4xx is not.
+	srv, calls, _ = webhook(http.StatusNotFound)
+
+	if err := e.send(context.Background(), srv.URL, payload); !errors.Is(err, ErrWebhookStatus) {
+		t.Errorf("send() error = %v, want %v", err, ErrWebhookStatus)
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("send() made %d calls on a 404, want 1", calls.Load())
+	}
+}
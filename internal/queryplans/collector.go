@@ -0,0 +1,252 @@
+// Package queryplans captures the plans of slow queries: every instance
+// watches the queries its PostgreSQL proxy logs and, for those running past
+// their database's slow query threshold, runs EXPLAIN (FORMAT JSON) with the
+// database's stored credentials and records the plan alongside the query.
+package queryplans
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/proxy/conncheck"
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+const (
+	// feedBuffer is how many logged queries wait to be looked at; past it
+	// the feed drops them, slow or not.
+	feedBuffer = 1024
+	// queueSize is how many slow queries wait to be explained; past it they
+	// are skipped.
+	queueSize = 16
+	// refreshInterval is how often the slow query thresholds are reloaded.
+	refreshInterval = time.Minute
+	// cooldown is how long a statement is not explained again on the same
+	// database: its later slow executions show the plan captured then.
+	cooldown = 5 * time.Minute
+	// explainTimeout bounds the login and the EXPLAIN of one statement.
+	explainTimeout = 10 * time.Second
+)
+
+// explainableCommands are the statements EXPLAIN accepts, by first keyword.
+var explainableCommands = map[string]bool{
+	"select": true, "with": true, "values": true, "table": true,
+	"insert": true, "update": true, "delete": true, "merge": true,
+}
+
+// Collector captures the plans of the slow queries logged by this process.
+// Plans are captured one at a time, so a burst of slow queries, or an
+// upstream slow to log in to, cannot pile up connections to the database.
+type Collector struct {
+	store   *store.Store
+	checker *conncheck.Checker
+	logger  *slog.Logger
+
+	thresholds map[uuid.UUID]time.Duration
+	explained  map[string]time.Time // When each database and fingerprint was last queued
+	queue      chan store.Query
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCollector creates a collector; Start begins capturing.
+func NewCollector(dataStore *store.Store, encryptionKey []byte, logger *slog.Logger) *Collector {
+	return &Collector{
+		store:      dataStore,
+		checker:    conncheck.New(dataStore, encryptionKey).WithTimeout(explainTimeout),
+		logger:     logger.With(slog.String("component", "query_plans")),
+		thresholds: make(map[uuid.UUID]time.Duration),
+		explained:  make(map[string]time.Time),
+		queue:      make(chan store.Query, queueSize),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start watches the logged queries until Shutdown.
+func (c *Collector) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	sub := c.store.QueryFeed().Subscribe(feedBuffer)
+	explained := make(chan struct{})
+
+	go func() {
+		defer close(explained)
+
+		c.explainQueued(ctx)
+	}()
+
+	go func() {
+		defer close(c.done)
+		defer func() { <-explained }()
+		defer sub.Close()
+
+		c.watch(ctx, sub)
+	}()
+}
+
+// Shutdown stops the collector, interrupting a capture in progress, and
+// waits for it to return.
+func (c *Collector) Shutdown(ctx context.Context) error {
+	c.cancel()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("query plan collector shutdown interrupted: %w", ctx.Err())
+	}
+}
+
+// watch queues the slow queries of the feed until ctx is done.
+func (c *Collector) watch(ctx context.Context, sub *store.QuerySubscription) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	c.refresh(ctx, time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.refresh(ctx, now)
+
+			if dropped := sub.Dropped(); dropped > 0 {
+				c.logger.WarnContext(ctx, "queries missed by the slow query plan capture", slog.Int64("dropped", dropped))
+			}
+		case query := <-sub.C:
+			c.offer(ctx, &query, time.Now())
+		}
+	}
+}
+
+// refresh reloads the thresholds and forgets the statements out of their
+// cooldown. A failed reload keeps the previous thresholds.
+func (c *Collector) refresh(ctx context.Context, now time.Time) {
+	for key, at := range c.explained {
+		if now.Sub(at) >= cooldown {
+			delete(c.explained, key)
+		}
+	}
+
+	thresholds, err := c.store.SlowQueryThresholds(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.ErrorContext(ctx, "failed to load slow query thresholds", slog.Any("error", err))
+		}
+
+		return
+	}
+
+	c.thresholds = thresholds
+}
+
+// offer queues query when it is slow, explainable and its statement was not
+// queued on its database during the cooldown.
+func (c *Collector) offer(ctx context.Context, query *store.Query, now time.Time) {
+	if query.DatabaseID == nil {
+		return
+	}
+
+	threshold, ok := c.thresholds[*query.DatabaseID]
+	if !ok || !slow(query, threshold) || !explainable(query.SQLText) {
+		return
+	}
+
+	key := query.DatabaseID.String() + "/" + query.Fingerprint
+	if at, ok := c.explained[key]; ok && now.Sub(at) < cooldown {
+		return
+	}
+
+	select {
+	case c.queue <- *query:
+		c.explained[key] = now
+	default:
+		c.logger.DebugContext(ctx, "slow query plan capture busy, query skipped",
+			slog.String("query_uid", query.UID.String()))
+	}
+}
+
+// explainQueued captures the plans of the queued queries until ctx is done.
+func (c *Collector) explainQueued(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case query := <-c.queue:
+			c.explain(ctx, &query)
+		}
+	}
+}
+
+// explain captures and records the plan of query, or why it could not be
+// captured.
+func (c *Collector) explain(ctx context.Context, query *store.Query) {
+	plan := &store.QueryPlan{
+		QueryUID:    query.UID,
+		DatabaseID:  *query.DatabaseID,
+		Fingerprint: query.Fingerprint,
+	}
+
+	srv, err := c.store.GetServerByUID(ctx, *query.DatabaseID)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.WarnContext(ctx, "failed to load the database of a slow query",
+				slog.String("query_uid", query.UID.String()), slog.Any("error", err))
+		}
+
+		return
+	}
+
+	res, output := c.checker.Explain(ctx, srv, query.SQLText, query.Parameters)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if res.OK {
+		plan.Plan = output
+	} else {
+		plan.Error = &res.Message
+
+		c.logger.InfoContext(ctx, "failed to explain a slow query",
+			slog.String("query_uid", query.UID.String()),
+			slog.String("code", string(res.Code)),
+			slog.String("message", res.Message))
+	}
+
+	if err := c.store.SaveQueryPlan(ctx, plan); err != nil && ctx.Err() == nil {
+		c.logger.ErrorContext(ctx, "failed to save a query plan",
+			slog.String("query_uid", query.UID.String()), slog.Any("error", err))
+	}
+}
+
+// slow reports whether query ran past threshold and is worth explaining: it
+// succeeded, was not rolled back by the dry_run control and its text is
+// complete.
+func slow(query *store.Query, threshold time.Duration) bool {
+	if query.DurationMs == nil || query.Error != nil || query.DryRun || query.SQLTruncated || query.CopyFormat != nil {
+		return false
+	}
+
+	return time.Duration(*query.DurationMs*float64(time.Millisecond)) >= threshold
+}
+
+// explainable reports whether sql is a statement EXPLAIN accepts.
+func explainable(sql string) bool {
+	text := strings.TrimLeft(sqlnorm.FingerprintText(sql, store.ProtocolPostgreSQL), "( ")
+
+	keyword := text
+	if end := strings.IndexAny(text, " (;"); end >= 0 {
+		keyword = text[:end]
+	}
+
+	return explainableCommands[keyword]
+}
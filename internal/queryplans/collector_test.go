@@ -0,0 +1,62 @@
+package queryplans
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestExplainable(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"SELECT * FROM users WHERE id = $1":    true,
+		"/* app */ select(1)":                  true,
+		"(SELECT 1) UNION (SELECT 2)":          true,
+		"WITH t AS (SELECT 1) SELECT * FROM t": true,
+		"update accounts set balance = 0":      true,
+		"TABLE users":                          true,
+		"COPY users TO STDOUT":                 false,
+		"CREATE INDEX ON users(email)":         false,
+		"EXPLAIN ANALYZE SELECT 1":             false,
+		"SET search_path = app":                false,
+		"select_count()":                       false,
+		"":                                     false,
+	}
+
+	for sql, want := range tests {
+		if got := explainable(sql); got != want {
+			t.Errorf("explainable(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestSlow(t *testing.T) {
+	t.Parallel()
+
+	duration := func(ms float64) *float64 { return &ms }
+	queryError := "canceled"
+	copyFormat := "text"
+
+	tests := []struct {
+		name  string
+		query store.Query
+		want  bool
+	}{
+		{name: "past the threshold", query: store.Query{DurationMs: duration(250)}, want: true},
+		{name: "at the threshold", query: store.Query{DurationMs: duration(200)}, want: true},
+		{name: "under the threshold", query: store.Query{DurationMs: duration(199.9)}},
+		{name: "not measured", query: store.Query{}},
+		{name: "failed", query: store.Query{DurationMs: duration(900), Error: &queryError}},
+		{name: "dry run", query: store.Query{DurationMs: duration(900), DryRun: true}},
+		{name: "truncated", query: store.Query{DurationMs: duration(900), SQLTruncated: true}},
+		{name: "copy", query: store.Query{DurationMs: duration(900), CopyFormat: &copyFormat}},
+	}
+
+	for _, tt := range tests {
+		if got := slow(&tt.query, 200*time.Millisecond); got != tt.want {
+			t.Errorf("slow() of a query %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package s3 uploads objects to a bucket of AWS S3, an S3-compatible store
+// or Google Cloud Storage's interoperability API, and presigns their
+// download URLs, with AWS Signature Version 4 (see internal/sigv4).
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/sigv4"
+)
+
+// ErrStatus is returned when the object storage rejects a request.
+var ErrStatus = errors.New("object storage returned an error status")
+
+// Bucket addresses a bucket and the credentials signing its requests.
+type Bucket struct {
+	Name string
+	// Endpoint is the base URL of the service; objects are then addressed
+	// path-style. Empty uses AWS S3 in Region, virtual-hosted-style.
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Client stores the objects of a bucket.
+type Client struct {
+	bucket Bucket
+	http   *http.Client
+}
+
+// NewClient creates a client of bucket, its requests bounded by timeout.
+func NewClient(bucket Bucket, timeout time.Duration) *Client {
+	return &Client{
+		bucket: bucket,
+		http:   &http.Client{Timeout: timeout},
+	}
+}
+
+// ObjectURL addresses key path-style on a custom endpoint, and
+// virtual-hosted-style on AWS.
+func (c *Client) ObjectURL(key string) string {
+	if c.bucket.Endpoint == "" {
+		return "https://" + c.bucket.Name + ".s3." + c.bucket.Region + ".amazonaws.com/" + key
+	}
+
+	return strings.TrimSuffix(c.bucket.Endpoint, "/") + "/" + c.bucket.Name + "/" + key
+}
+
+func (c *Client) credentials() sigv4.Credentials {
+	return sigv4.Credentials{
+		AccessKeyID:     c.bucket.AccessKeyID,
+		SecretAccessKey: c.bucket.SecretAccessKey,
+	}
+}
+
+// Put uploads the size bytes of body, whose hex SHA-256 is payloadHash, as
+// the object of key, signing the request at now.
+func (c *Client) Put(
+	ctx context.Context,
+	key string,
+	body io.Reader,
+	size int64,
+	payloadHash, contentType string,
+	now time.Time,
+) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.ObjectURL(key), body)
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	sigv4.Sign(req, payloadHash, "s3", c.bucket.Region, c.credentials(), now)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload object: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%w: %s", ErrStatus, resp.Status)
+	}
+
+	return nil
+}
+
+// Presign returns a URL downloading the object of key until now+expires;
+// empty when the object URL is invalid.
+func (c *Client) Presign(key string, now time.Time, expires time.Duration) string {
+	u, err := url.Parse(c.ObjectURL(key))
+	if err != nil {
+		return ""
+	}
+
+	return sigv4.Presign(u, "s3", c.bucket.Region, c.credentials(), now, expires)
+}
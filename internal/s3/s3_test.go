@@ -0,0 +1,77 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/sigv4"
+)
+
+func TestClient_ObjectURL(t *testing.T) {
+	t.Parallel()
+
+	aws := NewClient(Bucket{Name: "archive", Region: "eu-west-3"}, time.Second)
+	assert.Equal(t, "https://archive.s3.eu-west-3.amazonaws.com/a/b.jsonl", aws.ObjectURL("a/b.jsonl"))
+
+	custom := NewClient(Bucket{Name: "archive", Endpoint: "https://minio.example.com/"}, time.Second)
+	assert.Equal(t, "https://minio.example.com/archive/a/b.jsonl", custom.ObjectURL("a/b.jsonl"))
+}
+
+func TestClient_Put(t *testing.T) {
+	t.Parallel()
+
+	type upload struct {
+		method, path, auth, contentType, body string
+	}
+
+	uploads := make(chan upload, 2)
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploads <- upload{r.Method, r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("Content-Type"), string(body)}
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := NewClient(Bucket{
+		Name:            "archive",
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIA",
+		SecretAccessKey: "secret",
+	}, time.Second)
+	now := time.Date(2026, 10, 15, 8, 30, 0, 0, time.UTC)
+	body := []byte("hello\n")
+
+	require.NoError(t, client.Put(context.Background(), "a/b.txt", strings.NewReader(string(body)), int64(len(body)),
+		sigv4.PayloadHash(body), "text/plain", now))
+
+	got := <-uploads
+	assert.Equal(t, upload{
+		method:      http.MethodPut,
+		path:        "/archive/a/b.txt",
+		auth:        got.auth,
+		contentType: "text/plain",
+		body:        "hello\n",
+	}, got)
+	assert.True(t, strings.HasPrefix(got.auth, "AWS4-HMAC-SHA256 Credential=AKIA/20261015/us-east-1/s3/aws4_request, "), got.auth)
+
+	status = http.StatusForbidden
+	require.ErrorIs(t, client.Put(context.Background(), "a/b.txt", strings.NewReader(string(body)), int64(len(body)),
+		sigv4.PayloadHash(body), "text/plain", now), ErrStatus)
+	<-uploads
+
+	url := client.Presign("a/b.txt", now, time.Hour)
+	assert.True(t, strings.HasPrefix(url, server.URL+"/archive/a/b.txt?"), url)
+	assert.Contains(t, url, "X-Amz-Expires=3600")
+}
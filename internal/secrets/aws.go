@@ -0,0 +1,129 @@
+package secrets
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/sigv4"
+)
+
+// awsProvider reads secrets from AWS Secrets Manager, signing its requests
+// with static credentials.
+type awsProvider struct {
+	region string
+	creds  sigv4.Credentials
+	client *http.Client
+	// endpoint overrides https://secretsmanager.<region>.amazonaws.com, in
+	// tests.
+	endpoint string
+	now      func() time.Time
+}
+
+func newAWSProvider(cfg config.SecretsConfig, client *http.Client) *awsProvider {
+	return &awsProvider{
+		region: cmp.Or(cfg.AWSRegion, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")),
+		creds: sigv4.Credentials{
+			AccessKeyID:     cmp.Or(cfg.AWSAccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID")),
+			SecretAccessKey: cmp.Or(cfg.AWSSecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY")),
+			SessionToken:    cmp.Or(cfg.AWSSessionToken, os.Getenv("AWS_SESSION_TOKEN")),
+		},
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// fetch calls GetSecretValue on the current version of the secret, in the
+// region of its ARN, or the configured one for a name.
+func (p *awsProvider) fetch(ctx context.Context, ref Ref) (string, error) {
+	if p.creds.AccessKeyID == "" || p.creds.SecretAccessKey == "" {
+		return "", fmt.Errorf("%w: no AWS credentials (DBB_SECRETS_AWS_ACCESS_KEY_ID, DBB_SECRETS_AWS_SECRET_ACCESS_KEY)",
+			ErrNotConfigured)
+	}
+
+	region := arnRegion(ref.Path)
+	if region == "" {
+		region = p.region
+	}
+
+	if region == "" {
+		return "", fmt.Errorf("%w: no AWS region (DBB_SECRETS_AWS_REGION)", ErrNotConfigured)
+	}
+
+	payload, err := json.Marshal(map[string]string{"SecretId": ref.Path})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the request: %w", err)
+	}
+
+	endpoint := cmp.Or(p.endpoint, "https://secretsmanager."+region+".amazonaws.com/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create AWS Secrets Manager request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, sigv4.PayloadHash(payload), "secretsmanager", region, p.creds, p.now())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach AWS Secrets Manager: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"`
+		Type         string `json:"__type"`
+		Message      string `json:"message"`
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+
+		// Errors are 400s told apart by their type, e.g.
+		// "com.amazonaws.secretsmanager#ResourceNotFoundException".
+		if strings.HasSuffix(body.Type, "ResourceNotFoundException") {
+			return "", ErrSecretNotFound
+		}
+
+		return "", fmt.Errorf("%w: %s: %s %s", ErrFetchStatus, resp.Status, body.Type, body.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode the AWS Secrets Manager response: %w", err)
+	}
+
+	if body.SecretString != "" || body.SecretBinary == "" {
+		return body.SecretString, nil
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(body.SecretBinary)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the binary secret: %w", err)
+	}
+
+	return string(secret), nil
+}
+
+// arnRegion returns the region of a secret ARN,
+// arn:<partition>:secretsmanager:<region>:<account>:secret:<name>, and ""
+// for a name.
+func arnRegion(secretID string) string {
+	parts := strings.SplitN(secretID, ":", 5)
+	if len(parts) < 5 || parts[0] != "arn" {
+		return ""
+	}
+
+	return parts[3]
+}
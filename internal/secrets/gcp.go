@@ -0,0 +1,160 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcpSecretManagerURL = "https://secretmanager.googleapis.com/v1/"
+	// gcpMetadataTokenURL hands out access tokens of the service account
+	// attached to the instance, Cloud Run service or GKE workload.
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	// gcpTokenMargin renews a token this long before it expires.
+	gcpTokenMargin = time.Minute
+)
+
+var errBadGCPName = errors.New("want gcp-sm://projects/<project>/secrets/<secret>[/versions/<version>]")
+
+// gcpProvider reads secrets from Google Secret Manager. It authenticates
+// with the GOOGLE_OAUTH_ACCESS_TOKEN environment variable when set, and with
+// the attached service account through the metadata server otherwise.
+type gcpProvider struct {
+	client *http.Client
+	// apiURL and tokenURL override the Google endpoints, in tests.
+	apiURL   string
+	tokenURL string
+	now      func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func newGCPProvider(client *http.Client) *gcpProvider {
+	return &gcpProvider{
+		client:   client,
+		apiURL:   gcpSecretManagerURL,
+		tokenURL: gcpMetadataTokenURL,
+		now:      time.Now,
+	}
+}
+
+// gcpVersionName returns the secret version path names, the latest version
+// when it names none.
+func gcpVersionName(path string) (string, error) {
+	parts := strings.Split(path, "/")
+
+	switch {
+	case len(parts) == 4 && parts[0] == "projects" && parts[2] == "secrets":
+		return path + "/versions/latest", nil
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "secrets" && parts[4] == "versions":
+		return path, nil
+	default:
+		return "", errBadGCPName
+	}
+}
+
+// fetch accesses the secret version.
+func (p *gcpProvider) fetch(ctx context.Context, ref Ref) (string, error) {
+	name, err := gcpVersionName(ref.Path)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+name+":access", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Secret Manager request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Secret Manager: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkStatus(resp); err != nil {
+		return "", err
+	}
+
+	var body struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode the Secret Manager response: %w", err)
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the secret payload: %w", err)
+	}
+
+	return string(secret), nil
+}
+
+// accessToken returns the token authenticating the requests, fetching a new
+// one from the metadata server when the last one is about to expire.
+func (p *gcpProvider) accessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && p.now().Add(gcpTokenMargin).Before(p.expiresAt) {
+		return p.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.tokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create metadata server request: %w", err)
+	}
+
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: no GOOGLE_OAUTH_ACCESS_TOKEN, and the metadata server is unreachable: %w",
+			ErrNotConfigured, err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkStatus(resp); err != nil {
+		return "", fmt.Errorf("metadata server token: %w", err)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode the metadata server token: %w", err)
+	}
+
+	p.token = body.AccessToken
+	p.expiresAt = p.now().Add(time.Duration(body.ExpiresIn) * time.Second)
+
+	return p.token, nil
+}
@@ -0,0 +1,243 @@
+// Package secrets fetches the upstream passwords of the databases that keep
+// them in an external secrets manager rather than encrypted in the DBBat
+// database. A database's secret_ref names the secret:
+//
+//	vault://<mount>/<path>[#<key>]           HashiCorp Vault KV version 2; key defaults to "password"
+//	aws-sm://<name or ARN>[#<key>]           AWS Secrets Manager
+//	gcp-sm://projects/<p>/secrets/<s>[/versions/<v>][#<key>]  Google Secret Manager
+//
+// With a key, the secret is a JSON object and the password is its key field,
+// as in the secrets RDS and Cloud SQL manage; without, the secret is the
+// password. Fetched secrets are cached for the configured TTL.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// Secret reference schemes.
+const (
+	SchemeVault = "vault"
+	SchemeAWS   = "aws-sm"
+	SchemeGCP   = "gcp-sm"
+)
+
+// defaultVaultKey is the field of a Vault secret holding the password when
+// the reference names none.
+const defaultVaultKey = "password"
+
+// fetchTimeout bounds one request to a secrets manager.
+const fetchTimeout = 10 * time.Second
+
+var (
+	// ErrInvalidRef is returned for a secret reference that cannot be parsed.
+	ErrInvalidRef = errors.New("invalid secret reference")
+	// ErrNotConfigured is returned when the secrets manager of a reference
+	// has no address or credentials configured.
+	ErrNotConfigured = errors.New("secrets manager not configured")
+	// ErrSecretNotFound is returned when the secrets manager has no such
+	// secret, or the secret has no such key.
+	ErrSecretNotFound = errors.New("secret not found")
+	// ErrFetchStatus is returned when the secrets manager answers with an
+	// error status.
+	ErrFetchStatus = errors.New("secrets manager returned an error status")
+	// ErrNotString is returned when the key of a secret is not a string.
+	ErrNotString = errors.New("secret key is not a string")
+)
+
+// Ref is a parsed secret reference.
+type Ref struct {
+	Scheme string
+	// Path names the secret within its secrets manager.
+	Path string
+	// Key is the field of a JSON secret holding the password; empty when the
+	// secret is the password.
+	Key string
+}
+
+// String returns the reference as written.
+func (r Ref) String() string {
+	if r.Key == "" {
+		return r.Scheme + "://" + r.Path
+	}
+
+	return r.Scheme + "://" + r.Path + "#" + r.Key
+}
+
+// ParseRef parses a secret reference, <scheme>://<path>[#<key>].
+func ParseRef(raw string) (Ref, error) {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return Ref{}, fmt.Errorf("%w: %q: want <scheme>://<path>[#<key>]", ErrInvalidRef, raw)
+	}
+
+	path, key, _ := strings.Cut(rest, "#")
+	ref := Ref{Scheme: scheme, Path: path, Key: key}
+
+	if path == "" {
+		return Ref{}, fmt.Errorf("%w: %q: no secret path", ErrInvalidRef, raw)
+	}
+
+	switch scheme {
+	case SchemeVault:
+		if mount, secret, _ := strings.Cut(path, "/"); mount == "" || secret == "" {
+			return Ref{}, fmt.Errorf("%w: %q: want vault://<mount>/<path>", ErrInvalidRef, raw)
+		}
+
+		if ref.Key == "" {
+			ref.Key = defaultVaultKey
+		}
+	case SchemeAWS:
+	case SchemeGCP:
+		if _, err := gcpVersionName(path); err != nil {
+			return Ref{}, fmt.Errorf("%w: %q: %w", ErrInvalidRef, raw, err)
+		}
+	default:
+		return Ref{}, fmt.Errorf("%w: %q: unknown scheme %q (want %s, %s or %s)",
+			ErrInvalidRef, raw, scheme, SchemeVault, SchemeAWS, SchemeGCP)
+	}
+
+	return ref, nil
+}
+
+// provider fetches secrets from one secrets manager.
+type provider interface {
+	// fetch returns the secret ref.Path names, as stored.
+	fetch(ctx context.Context, ref Ref) (string, error)
+}
+
+type cachedSecret struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Resolver fetches secrets from the secrets managers, caching them for a TTL
+// so that every upstream connection does not cost a request.
+type Resolver struct {
+	providers map[string]provider
+	ttl       time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedSecret
+}
+
+// NewResolver creates a resolver with the secrets managers of cfg.
+func NewResolver(cfg config.SecretsConfig) (*Resolver, error) {
+	ttl, err := cfg.CacheDuration()
+	if err != nil {
+		return nil, fmt.Errorf("secrets cache TTL: %w", err)
+	}
+
+	return newResolver(cfg, ttl), nil
+}
+
+func newResolver(cfg config.SecretsConfig, ttl time.Duration) *Resolver {
+	client := &http.Client{Timeout: fetchTimeout}
+
+	return &Resolver{
+		providers: map[string]provider{
+			SchemeVault: newVaultProvider(cfg, client),
+			SchemeAWS:   newAWSProvider(cfg, client),
+			SchemeGCP:   newGCPProvider(client),
+		},
+		ttl:   ttl,
+		now:   time.Now,
+		cache: make(map[string]cachedSecret),
+	}
+}
+
+// Resolve returns the password raw refers to, from the cache when it was
+// fetched less than the TTL ago.
+func (r *Resolver) Resolve(ctx context.Context, raw string) (string, error) {
+	ref, err := ParseRef(raw)
+	if err != nil {
+		return "", err
+	}
+
+	key := ref.String()
+
+	r.mu.Lock()
+	cached, ok := r.cache[key]
+	r.mu.Unlock()
+
+	if ok && r.now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	secret, err := r.providers[ref.Scheme].fetch(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+
+	value, err := secretField(secret, ref.Key)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.cache[key] = cachedSecret{value: value, expiresAt: r.now().Add(r.ttl)}
+		r.mu.Unlock()
+	}
+
+	return value, nil
+}
+
+// secretField returns the key field of the JSON object secret, or secret
+// itself when key is empty.
+func secretField(secret, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot read key %q: %w", key, err)
+	}
+
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%w: no key %q", ErrSecretNotFound, key)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrNotString, key)
+	}
+
+	return s, nil
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultResolver = newResolver(config.SecretsConfig{}, 0)
+)
+
+// SetDefault makes r the process-wide resolver Resolve uses, shared by all
+// the proxies; main sets it from the configuration.
+func SetDefault(r *Resolver) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultResolver = r
+}
+
+// Resolve returns the password raw refers to, using the process-wide
+// resolver.
+func Resolve(ctx context.Context, raw string) (string, error) {
+	defaultMu.RLock()
+	r := defaultResolver
+	defaultMu.RUnlock()
+
+	return r.Resolve(ctx, raw)
+}
@@ -0,0 +1,279 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+func TestParseRef(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		raw     string
+		want    Ref
+		wantErr string
+	}{
+		{raw: "vault://secret/dbbat/orders", want: Ref{Scheme: SchemeVault, Path: "secret/dbbat/orders", Key: "password"}},
+		{raw: "vault://kv/orders#pass", want: Ref{Scheme: SchemeVault, Path: "kv/orders", Key: "pass"}},
+		{raw: "aws-sm://prod/orders", want: Ref{Scheme: SchemeAWS, Path: "prod/orders"}},
+		{
+			raw:  "aws-sm://arn:aws:secretsmanager:eu-west-1:123456789012:secret:rds-orders-AbCdEf#password",
+			want: Ref{Scheme: SchemeAWS, Path: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:rds-orders-AbCdEf", Key: "password"},
+		},
+		{raw: "gcp-sm://projects/acme/secrets/orders", want: Ref{Scheme: SchemeGCP, Path: "projects/acme/secrets/orders"}},
+		{raw: "gcp-sm://projects/acme/secrets/orders/versions/3", want: Ref{Scheme: SchemeGCP, Path: "projects/acme/secrets/orders/versions/3"}},
+		{raw: "s3cr3t", wantErr: "want <scheme>://"},
+		{raw: "vault://secret", wantErr: "want vault://<mount>/<path>"},
+		{raw: "aws-sm://", wantErr: "no secret path"},
+		{raw: "gcp-sm://acme/orders", wantErr: "projects/<project>"},
+		{raw: "azure-kv://orders", wantErr: "unknown scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseRef(tt.raw)
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, ErrInvalidRef)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSecretField(t *testing.T) {
+	t.Parallel()
+
+	got, err := secretField(`{"username":"app","password":"s3cr3t"}`, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", got)
+
+	got, err = secretField("s3cr3t", "")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", got)
+
+	_, err = secretField(`{"username":"app"}`, "password")
+	require.ErrorIs(t, err, ErrSecretNotFound)
+
+	_, err = secretField(`{"port":5432}`, "port")
+	require.ErrorIs(t, err, ErrNotString)
+
+	_, err = secretField("s3cr3t", "password")
+	require.Error(t, err)
+}
+
+// fakeProvider counts its fetches.
+type fakeProvider struct {
+	secret  string
+	fetches atomic.Int32
+}
+
+func (p *fakeProvider) fetch(context.Context, Ref) (string, error) {
+	p.fetches.Add(1)
+
+	return p.secret, nil
+}
+
+func TestResolver_Cache(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeProvider{secret: `{"password":"s3cr3t"}`}
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	r := newResolver(config.SecretsConfig{}, 5*time.Minute)
+	r.providers[SchemeAWS] = fake
+	r.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	for range 3 {
+		got, err := r.Resolve(ctx, "aws-sm://prod/orders#password")
+		require.NoError(t, err)
+		assert.Equal(t, "s3cr3t", got)
+	}
+
+	assert.Equal(t, int32(1), fake.fetches.Load(), "fetched once within the TTL")
+
+	now = now.Add(6 * time.Minute)
+
+	_, err := r.Resolve(ctx, "aws-sm://prod/orders#password")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fake.fetches.Load(), "fetched again past the TTL")
+
+	// Without a TTL every call fetches.
+	uncached := newResolver(config.SecretsConfig{}, 0)
+	uncached.providers[SchemeAWS] = fake
+
+	_, _ = uncached.Resolve(ctx, "aws-sm://prod/orders#password")
+	_, _ = uncached.Resolve(ctx, "aws-sm://prod/orders#password")
+	assert.Equal(t, int32(4), fake.fetches.Load())
+}
+
+func TestVaultProvider(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "hvs.token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+
+			return
+		}
+
+		if r.URL.Path != "/v1/secret/data/dbbat/orders" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data":{"data":{"password":"s3cr3t"},"metadata":{"version":2}}}`))
+	}))
+	defer server.Close()
+
+	r := newResolver(config.SecretsConfig{VaultAddr: server.URL, VaultToken: "hvs.token"}, 0)
+	ctx := context.Background()
+
+	got, err := r.Resolve(ctx, "vault://secret/dbbat/orders")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", got)
+
+	_, err = r.Resolve(ctx, "vault://secret/dbbat/billing")
+	require.ErrorIs(t, err, ErrSecretNotFound)
+
+	denied := newResolver(config.SecretsConfig{VaultAddr: server.URL, VaultToken: "wrong"}, 0)
+
+	_, err = denied.Resolve(ctx, "vault://secret/dbbat/orders")
+	require.ErrorIs(t, err, ErrFetchStatus)
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func TestAWSProvider(t *testing.T) {
+	t.Parallel()
+
+	type call struct {
+		target, auth, secretID string
+	}
+
+	calls := make(chan call, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SecretID string `json:"SecretId"`
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		calls <- call{r.Header.Get("X-Amz-Target"), r.Header.Get("Authorization"), body.SecretID}
+
+		if body.SecretID == "prod/missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"Name":"prod/orders","SecretString":"{\"username\":\"app\",\"password\":\"s3cr3t\"}"}`))
+	}))
+	defer server.Close()
+
+	r := newResolver(config.SecretsConfig{
+		AWSRegion:          "us-east-1",
+		AWSAccessKeyID:     "AKIA",
+		AWSSecretAccessKey: "secret",
+	}, 0)
+
+	aws, ok := r.providers[SchemeAWS].(*awsProvider)
+	require.True(t, ok)
+
+	aws.endpoint = server.URL
+
+	ctx := context.Background()
+
+	got, err := r.Resolve(ctx, "aws-sm://arn:aws:secretsmanager:eu-west-1:123456789012:secret:prod/orders#password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", got)
+
+	c := <-calls
+	assert.Equal(t, "secretsmanager.GetSecretValue", c.target)
+	assert.Equal(t, "arn:aws:secretsmanager:eu-west-1:123456789012:secret:prod/orders", c.secretID)
+	assert.Contains(t, c.auth, "/eu-west-1/secretsmanager/aws4_request", "signed for the region of the ARN")
+
+	_, err = r.Resolve(ctx, "aws-sm://prod/missing")
+	require.ErrorIs(t, err, ErrSecretNotFound)
+
+	unconfigured := newResolver(config.SecretsConfig{AWSRegion: "us-east-1", AWSAccessKeyID: "AKIA"}, 0)
+
+	if _, err := unconfigured.Resolve(ctx, "aws-sm://prod/orders"); !strings.Contains(err.Error(), "AWS credentials") {
+		t.Errorf("Resolve() without a secret key error = %v, want no AWS credentials", err)
+	}
+}
+
+func TestGCPProvider(t *testing.T) {
+	t.Parallel()
+
+	var tokenRequests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests.Add(1)
+
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"access_token":"ya29.token","expires_in":3599,"token_type":"Bearer"}`))
+		case "/v1/projects/acme/secrets/orders/versions/latest:access":
+			if r.Header.Get("Authorization") != "Bearer ya29.token" {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			// base64("s3cr3t")
+			_, _ = w.Write([]byte(`{"name":"projects/acme/secrets/orders/versions/3","payload":{"data":"czNjcjN0"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r := newResolver(config.SecretsConfig{}, 0)
+
+	gcp, ok := r.providers[SchemeGCP].(*gcpProvider)
+	require.True(t, ok)
+
+	gcp.apiURL = server.URL + "/v1/"
+	gcp.tokenURL = server.URL + "/token"
+
+	ctx := context.Background()
+
+	for range 2 {
+		got, err := r.Resolve(ctx, "gcp-sm://projects/acme/secrets/orders")
+		require.NoError(t, err)
+		assert.Equal(t, "s3cr3t", got)
+	}
+
+	assert.Equal(t, int32(1), tokenRequests.Load(), "the token is reused until it expires")
+
+	_, err := r.Resolve(ctx, "gcp-sm://projects/acme/secrets/billing")
+	require.ErrorIs(t, err, ErrSecretNotFound)
+}
@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// errorBodyLimit bounds the part of an error response kept in the error.
+const errorBodyLimit = 512
+
+// vaultProvider reads secrets from a HashiCorp Vault KV version 2 engine.
+type vaultProvider struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+func newVaultProvider(cfg config.SecretsConfig, client *http.Client) *vaultProvider {
+	return &vaultProvider{
+		addr:   strings.TrimSuffix(cmp.Or(cfg.VaultAddr, os.Getenv("VAULT_ADDR")), "/"),
+		token:  cmp.Or(cfg.VaultToken, os.Getenv("VAULT_TOKEN")),
+		client: client,
+	}
+}
+
+// fetch reads the latest version of the secret at <mount>/<path>, returning
+// its fields as a JSON object.
+func (p *vaultProvider) fetch(ctx context.Context, ref Ref) (string, error) {
+	if p.addr == "" {
+		return "", fmt.Errorf("%w: no Vault address (DBB_SECRETS_VAULT_ADDR)", ErrNotConfigured)
+	}
+
+	mount, path, _ := strings.Cut(ref.Path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.addr+"/v1/"+mount+"/data/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Vault request: %w", err)
+	}
+
+	if p.token != "" {
+		req.Header.Set("X-Vault-Token", p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Vault: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkStatus(resp); err != nil {
+		return "", err
+	}
+
+	var body struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode the Vault response: %w", err)
+	}
+
+	if len(body.Data.Data) == 0 || string(body.Data.Data) == "null" {
+		return "", ErrSecretNotFound
+	}
+
+	return string(body.Data.Data), nil
+}
+
+// checkStatus turns a 404 into ErrSecretNotFound and another error status
+// into ErrFetchStatus, with the start of the response body.
+func checkStatus(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrSecretNotFound
+	case resp.StatusCode >= http.StatusBadRequest:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+
+		return fmt.Errorf("%w: %s: %s", ErrFetchStatus, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
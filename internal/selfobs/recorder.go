@@ -0,0 +1,96 @@
+// Package selfobs records the queries DBBat runs against its own storage
+// database, so operators can debug the storage load with the query log UI.
+//
+// The storage database itself is never exposed: its DSN still cannot be
+// registered as a proxy target, and only the recorded statements, with their
+// literal values masked, are served to administrators.
+package selfobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Recorder is a bun.QueryHook keeping the last storage queries in memory.
+// Literal values are masked as soon as a query is recorded, since storage
+// queries carry password hashes and encrypted credentials.
+type Recorder struct {
+	mu      sync.Mutex
+	queries []store.Query // Ring buffer, next is the oldest once full
+	next    int
+	full    bool
+}
+
+// NewRecorder returns a recorder keeping the last size queries.
+func NewRecorder(size int) *Recorder {
+	return &Recorder{queries: make([]store.Query, max(size, 1))}
+}
+
+// BeforeQuery implements bun.QueryHook.
+func (r *Recorder) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery implements bun.QueryHook.
+func (r *Recorder) AfterQuery(_ context.Context, event *bun.QueryEvent) {
+	duration := float64(time.Since(event.StartTime).Microseconds()) / 1000
+
+	query := store.Query{
+		UID:        uuid.Must(uuid.NewV7()),
+		SQLText:    sqlnorm.Normalize(event.Query, store.ProtocolPostgreSQL),
+		ExecutedAt: event.StartTime,
+		DurationMs: &duration,
+		Redacted:   true,
+	}
+
+	if event.Result != nil {
+		if n, err := event.Result.RowsAffected(); err == nil {
+			query.RowsAffected = &n
+		}
+	}
+
+	// No rows is how lookups report a miss, not a failure.
+	if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+		// Same masking as for the redacted query log.
+		msg := sqlnorm.Normalize(event.Err.Error(), store.ProtocolMySQL)
+		query.Error = &msg
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queries[r.next] = query
+	r.next = (r.next + 1) % len(r.queries)
+	r.full = r.full || r.next == 0
+}
+
+// Queries returns up to limit recorded queries, newest first.
+func (r *Recorder) Queries(limit int) []store.Query {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.queries)
+	}
+
+	if limit > 0 {
+		count = min(count, limit)
+	}
+
+	queries := make([]store.Query, 0, count)
+	for i := 1; i <= count; i++ {
+		queries = append(queries, r.queries[(r.next-i+len(r.queries))%len(r.queries)])
+	}
+
+	return queries
+}
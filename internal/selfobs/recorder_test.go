@@ -0,0 +1,80 @@
+package selfobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+type rowsResult int64
+
+func (r rowsResult) LastInsertId() (int64, error) { return 0, nil }
+func (r rowsResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func record(r *Recorder, event *bun.QueryEvent) {
+	if event.StartTime.IsZero() {
+		event.StartTime = time.Now()
+	}
+
+	ctx := r.BeforeQuery(context.Background(), event)
+	r.AfterQuery(ctx, event)
+}
+
+func TestRecorderMasksLiterals(t *testing.T) {
+	t.Parallel()
+
+	r := NewRecorder(10)
+	record(r, &bun.QueryEvent{
+		Query:  `UPDATE "users" SET "password_hash" = '$argon2id$secret' WHERE ("uid" = 'a5b1c0de-0000-0000-0000-000000000000')`,
+		Result: rowsResult(1),
+	})
+	record(r, &bun.QueryEvent{
+		Query: `SELECT * FROM "users" WHERE ("username" = 'nobody')`,
+		Err:   sql.ErrNoRows,
+	})
+	record(r, &bun.QueryEvent{
+		Query: `INSERT INTO "users" ("username") VALUES ('alice')`,
+		Err:   errors.New(`duplicate key value violates unique constraint: Key (username)=('alice') already exists`),
+	})
+
+	queries := r.Queries(0)
+	require.Len(t, queries, 3)
+
+	failed, missed, updated := queries[0], queries[1], queries[2]
+
+	assert.Equal(t, `UPDATE "users" SET "password_hash" = ? WHERE ("uid" = ?)`, updated.SQLText)
+	assert.True(t, updated.Redacted)
+	require.NotNil(t, updated.RowsAffected)
+	assert.Equal(t, int64(1), *updated.RowsAffected)
+
+	assert.Nil(t, missed.Error, "no rows is not an error")
+
+	require.NotNil(t, failed.Error)
+	assert.NotContains(t, *failed.Error, "alice")
+}
+
+func TestRecorderKeepsLastQueries(t *testing.T) {
+	t.Parallel()
+
+	r := NewRecorder(3)
+	assert.Empty(t, r.Queries(0))
+
+	for i := range 5 {
+		record(r, &bun.QueryEvent{Query: fmt.Sprintf(`SELECT "c%d" FROM "t"`, i)})
+	}
+
+	var got []string
+	for _, q := range r.Queries(0) {
+		got = append(got, q.SQLText)
+	}
+
+	assert.Equal(t, []string{`SELECT "c4" FROM "t"`, `SELECT "c3" FROM "t"`, `SELECT "c2" FROM "t"`}, got)
+	assert.Len(t, r.Queries(2), 2)
+}
@@ -0,0 +1,219 @@
+// Package spill offloads the results past the query storage limits: instead
+// of being discarded, the rows of such a result are written, as gzip-compressed
+// JSON lines, to an S3-compatible bucket or a local directory, and the query
+// record points to the object.
+package spill
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+var (
+	// ErrTooLarge is returned when a result grows past the spill size cap.
+	ErrTooLarge = errors.New("spilled result too large")
+	// ErrNotLocal is returned when opening the objects of a remote target,
+	// which are downloaded from their signed URL instead.
+	ErrNotLocal = errors.New("spilled results are not stored locally")
+)
+
+// target stores the spilled objects.
+type target interface {
+	put(ctx context.Context, key string, body io.Reader, size int64, payloadHash string) error
+	// presign returns a URL downloading key until now plus expires, "" when
+	// the target cannot sign URLs.
+	presign(key string, now time.Time, expires time.Duration) string
+}
+
+// Spiller writes spilled results to their target.
+type Spiller struct {
+	target   target
+	prefix   string
+	maxBytes int64
+	expiry   time.Duration
+	now      func() time.Time
+}
+
+// New creates the spiller of cfg, nil when spilling is disabled.
+//
+//nolint:nilnil // nil spiller is the documented "disabled" sentinel
+func New(cfg config.ResultSpillConfig) (*Spiller, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	expiry, err := cfg.Expiry()
+	if err != nil {
+		return nil, fmt.Errorf("invalid result spill URL expiry: %w", err)
+	}
+
+	s := &Spiller{
+		prefix:   cfg.Prefix,
+		maxBytes: cfg.MaxBytes,
+		expiry:   expiry,
+		now:      time.Now,
+	}
+
+	switch cfg.Target {
+	case config.ResultSpillFile:
+		s.target = fileTarget{dir: cfg.Dir}
+	case config.ResultSpillS3:
+		s.target = newS3Target(cfg)
+	default:
+		return nil, fmt.Errorf("%w: result spill target %q", config.ErrInvalidValue, cfg.Target)
+	}
+
+	return s, nil
+}
+
+// Begin starts spilling a result.
+func (s *Spiller) Begin() (*Writer, error) {
+	file, err := os.CreateTemp("", "dbbat-spill-*.jsonl.gz")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+
+	w := &Writer{file: file, hash: sha256.New(), maxBytes: s.maxBytes}
+	w.gz = gzip.NewWriter(io.MultiWriter(file, w.hash))
+
+	return w, nil
+}
+
+// Commit uploads the result of w and returns its key. w is discarded either
+// way.
+func (s *Spiller) Commit(ctx context.Context, w *Writer) (string, error) {
+	defer w.Discard()
+
+	if err := w.gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress spilled result: %w", err)
+	}
+
+	size, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", fmt.Errorf("failed to read spill file: %w", err)
+	}
+
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read spill file: %w", err)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", fmt.Errorf("failed to name spilled result: %w", err)
+	}
+
+	key := s.prefix + s.now().UTC().Format("2006/01/02/") + id.String() + ".jsonl.gz"
+
+	if err := s.target.put(ctx, key, w.file, size, hex.EncodeToString(w.hash.Sum(nil))); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// URL returns a URL downloading the object of key, and until when it does.
+// The URL is empty for the file target, whose objects the API serves itself
+// (see Open).
+func (s *Spiller) URL(key string) (string, time.Time) {
+	now := s.now()
+
+	return s.target.presign(key, now, s.expiry), now.Add(s.expiry)
+}
+
+// Expiry is how long download URLs are valid.
+func (s *Spiller) Expiry() time.Duration {
+	return s.expiry
+}
+
+// Open opens the object of key, for the file target.
+func (s *Spiller) Open(key string) (*os.File, error) {
+	ft, ok := s.target.(fileTarget)
+	if !ok {
+		return nil, ErrNotLocal
+	}
+
+	return ft.open(key)
+}
+
+// Writer accumulates a spilled result in a temporary file.
+type Writer struct {
+	file     *os.File
+	gz       *gzip.Writer
+	hash     hash.Hash
+	maxBytes int64
+
+	rows  int64
+	bytes int64
+}
+
+// Write appends the JSON of a row. It returns ErrTooLarge once the result
+// grows past the size cap: the writer must then be discarded.
+func (w *Writer) Write(row []byte) error {
+	if w.bytes+int64(len(row))+1 > w.maxBytes {
+		return fmt.Errorf("%w: over %d bytes", ErrTooLarge, w.maxBytes)
+	}
+
+	if _, err := w.gz.Write(row); err != nil {
+		return fmt.Errorf("failed to write spilled row: %w", err)
+	}
+
+	if _, err := w.gz.Write([]byte{'\n'}); err != nil {
+		return fmt.Errorf("failed to write spilled row: %w", err)
+	}
+
+	w.rows++
+	w.bytes += int64(len(row)) + 1
+
+	return nil
+}
+
+// Rows is the number of rows written.
+func (w *Writer) Rows() int64 {
+	return w.rows
+}
+
+// Discard removes the temporary file. It is safe to call more than once.
+func (w *Writer) Discard() {
+	if w.file == nil {
+		return
+	}
+
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
+	w.file = nil
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultSpiller *Spiller
+)
+
+// SetDefault makes s the process-wide spiller the proxies and the API use;
+// main sets it from the configuration. nil disables spilling.
+func SetDefault(s *Spiller) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultSpiller = s
+}
+
+// Default returns the process-wide spiller, nil when spilling is disabled.
+func Default() *Spiller {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultSpiller
+}
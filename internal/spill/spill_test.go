@@ -0,0 +1,162 @@
+package spill
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/sigv4"
+)
+
+// readLines decompresses a spilled result into its lines.
+func readLines(t *testing.T, r io.Reader) []string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(r)
+	require.NoError(t, err)
+
+	var lines []string
+
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	require.NoError(t, scanner.Err())
+
+	return lines
+}
+
+func TestNew_Disabled(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(config.ResultSpillConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, s)
+}
+
+func TestSpiller_File(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(config.ResultSpillConfig{
+		Target:    config.ResultSpillFile,
+		Dir:       t.TempDir(),
+		Prefix:    "results/",
+		MaxBytes:  1024,
+		URLExpiry: "15m",
+	})
+	require.NoError(t, err)
+
+	s.now = func() time.Time { return time.Date(2026, 10, 15, 8, 30, 0, 0, time.UTC) }
+
+	w, err := s.Begin()
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]byte(`{"id":1}`)))
+	require.NoError(t, w.Write([]byte(`{"id":2}`)))
+	assert.Equal(t, int64(2), w.Rows())
+
+	tmp := w.file.Name()
+
+	key, err := s.Commit(context.Background(), w)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "results/2026/10/15/"), key)
+	assert.True(t, strings.HasSuffix(key, ".jsonl.gz"), key)
+
+	_, err = os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err), "the temporary file is removed")
+
+	file, err := s.Open(key)
+	require.NoError(t, err)
+
+	defer func() { _ = file.Close() }()
+
+	assert.Equal(t, []string{`{"id":1}`, `{"id":2}`}, readLines(t, file))
+
+	url, expires := s.URL(key)
+	assert.Empty(t, url, "the file target does not sign URLs")
+	assert.Equal(t, s.now().Add(15*time.Minute), expires)
+}
+
+func TestWriter_TooLarge(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(config.ResultSpillConfig{
+		Target:    config.ResultSpillFile,
+		Dir:       t.TempDir(),
+		MaxBytes:  10,
+		URLExpiry: "15m",
+	})
+	require.NoError(t, err)
+
+	w, err := s.Begin()
+	require.NoError(t, err)
+
+	defer w.Discard()
+
+	require.NoError(t, w.Write([]byte(`{"a":1}`)))
+	require.ErrorIs(t, w.Write([]byte(`{"a":2}`)), ErrTooLarge)
+	assert.Equal(t, int64(1), w.Rows())
+}
+
+func TestSpiller_S3(t *testing.T) {
+	t.Parallel()
+
+	type upload struct {
+		method, path, hash, contentType string
+		body                            []byte
+	}
+
+	uploads := make(chan upload, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploads <- upload{r.Method, r.URL.Path, r.Header.Get("X-Amz-Content-Sha256"), r.Header.Get("Content-Type"), body}
+	}))
+	defer server.Close()
+
+	s, err := New(config.ResultSpillConfig{
+		Target:          config.ResultSpillS3,
+		Endpoint:        server.URL,
+		Bucket:          "results",
+		Region:          "us-east-1",
+		Prefix:          "dbbat/",
+		AccessKeyID:     "AKIA",
+		SecretAccessKey: "secret",
+		MaxBytes:        1024,
+		URLExpiry:       "1h",
+	})
+	require.NoError(t, err)
+
+	w, err := s.Begin()
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]byte(`{"id":1}`)))
+
+	key, err := s.Commit(context.Background(), w)
+	require.NoError(t, err)
+
+	got := <-uploads
+	assert.Equal(t, http.MethodPut, got.method)
+	assert.Equal(t, "/results/"+key, got.path)
+	assert.Equal(t, "application/gzip", got.contentType)
+	assert.Equal(t, sigv4.PayloadHash(got.body), got.hash, "signed over the compressed body")
+	assert.Equal(t, []string{`{"id":1}`}, readLines(t, strings.NewReader(string(got.body))))
+
+	url, _ := s.URL(key)
+	assert.True(t, strings.HasPrefix(url, server.URL+"/results/"+key+"?"), url)
+	assert.Contains(t, url, "X-Amz-Expires=3600")
+	assert.Contains(t, url, "X-Amz-Signature=")
+
+	_, err = s.Open(key)
+	require.ErrorIs(t, err, ErrNotLocal)
+}
@@ -0,0 +1,104 @@
+package spill
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/s3"
+)
+
+// s3UploadTimeout bounds the upload of one spilled result.
+const s3UploadTimeout = 10 * time.Minute
+
+// s3Target stores the spilled results in a bucket.
+type s3Target struct {
+	client *s3.Client
+	now    func() time.Time
+}
+
+func newS3Target(cfg config.ResultSpillConfig) *s3Target {
+	return &s3Target{
+		client: s3.NewClient(s3.Bucket{
+			Name:            cfg.Bucket,
+			Endpoint:        cfg.Endpoint,
+			Region:          cfg.Region,
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+		}, s3UploadTimeout),
+		now: time.Now,
+	}
+}
+
+func (t *s3Target) put(ctx context.Context, key string, body io.Reader, size int64, payloadHash string) error {
+	if err := t.client.Put(ctx, key, body, size, payloadHash, "application/gzip", t.now()); err != nil {
+		return fmt.Errorf("failed to upload spilled result: %w", err)
+	}
+
+	return nil
+}
+
+func (t *s3Target) presign(key string, now time.Time, expires time.Duration) string {
+	return t.client.Presign(key, now, expires)
+}
+
+// fileTarget stores the spilled results under a directory, which every
+// instance serving the API must see.
+type fileTarget struct {
+	dir string
+}
+
+// path returns the file of key. Keys are generated by Commit and never
+// leave the directory.
+func (t fileTarget) path(key string) string {
+	return filepath.Join(t.dir, filepath.FromSlash(key))
+}
+
+func (t fileTarget) put(_ context.Context, key string, body io.Reader, _ int64, _ string) error {
+	path := t.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create spill directory: %w", err)
+	}
+
+	// Written aside then renamed, so a download never sees a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".spill-*")
+	if err != nil {
+		return fmt.Errorf("failed to create spilled result: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("failed to write spilled result: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write spilled result: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write spilled result: %w", err)
+	}
+
+	return nil
+}
+
+func (fileTarget) presign(string, time.Time, time.Duration) string {
+	return ""
+}
+
+func (t fileTarget) open(key string) (*os.File, error) {
+	file, err := os.Open(t.path(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spilled result: %w", err)
+	}
+
+	return file, nil
+}
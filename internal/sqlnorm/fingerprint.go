@@ -0,0 +1,202 @@
+package sqlnorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Fingerprint identifies the shape of a statement: two statements differing
+// only by their literal values, bind parameters, comments, whitespace or the
+// case of their unquoted identifiers and keywords share it. It is the first
+// 16 hex digits of the SHA-256 of FingerprintText.
+func Fingerprint(sql, protocol string) string {
+	sum := sha256.Sum256([]byte(FingerprintText(sql, protocol)))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// FingerprintText returns the canonical form of a statement that Fingerprint
+// hashes: literals and bind parameters replaced with Placeholder, lists of
+// placeholders in parentheses collapsed to one, comments dropped, unquoted
+// words lowercased (except in MongoDB commands, whose keys are case
+// sensitive) and tokens separated by single spaces.
+func FingerprintText(sql, protocol string) string {
+	tokens := fingerprintTokens(Normalize(sql, protocol), protocol)
+	tokens = collapsePlaceholderLists(tokens)
+
+	for len(tokens) > 0 && tokens[len(tokens)-1] == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	var b strings.Builder
+
+	for i, tok := range tokens {
+		if i > 0 && spaceBetween(tokens[i-1], tok) {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(tok)
+	}
+
+	return b.String()
+}
+
+// fingerprintTokens splits normalized SQL into the tokens of its
+// fingerprint, dropping comments and replacing bind parameters.
+func fingerprintTokens(sql, protocol string) []string {
+	mysql := protocol == store.ProtocolMySQL || protocol == store.ProtocolMariaDB
+	mongo := protocol == store.ProtocolMongoDB
+
+	var tokens []string
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case isSpace(c):
+			i++
+		case c == '-' && strings.HasPrefix(sql[i:], "--"), c == '#' && mysql:
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 4
+			}
+
+			i += end + 4
+		case c == '"' || c == '`':
+			// Quoted identifiers (and MongoDB keys) keep their case.
+			end := skipString(sql, i, c, mongo)
+			tokens = append(tokens, sql[i:end])
+			i = end
+		case c == '$' && protocol == store.ProtocolPostgreSQL && i+1 < len(sql) && isDigit(sql[i+1]):
+			for i++; i < len(sql) && isDigit(sql[i]); i++ {
+			}
+
+			tokens = append(tokens, Placeholder)
+		case c == ':' && protocol == store.ProtocolOracle && i+1 < len(sql) && isWordStart(sql[i+1]):
+			for i++; i < len(sql) && isWordPart(sql[i]); i++ {
+			}
+
+			tokens = append(tokens, Placeholder)
+		case c == ':' && protocol == store.ProtocolOracle && strings.HasPrefix(sql[i+1:], Placeholder):
+			// A positional bind (:1), whose number Normalize replaced.
+			i += 1 + len(Placeholder)
+			tokens = append(tokens, Placeholder)
+		case isWordPart(c):
+			start := i
+			for i < len(sql) && isWordPart(sql[i]) {
+				i++
+			}
+
+			word := sql[start:i]
+			if !mongo {
+				word = strings.ToLower(word)
+			}
+
+			tokens = append(tokens, word)
+		case isOperatorChar(c):
+			// The run stops before comments and before the sign of a
+			// literal (a>-1), which foldUnaryMinus drops.
+			start := i
+			for i < len(sql) && isOperatorChar(sql[i]) && !strings.HasPrefix(sql[i:], "--") && !strings.HasPrefix(sql[i:], "/*") &&
+				(i == start || !strings.HasPrefix(sql[i:], "-"+Placeholder)) {
+				i++
+			}
+
+			tokens = append(tokens, sql[start:i])
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+
+	return foldUnaryMinus(tokens)
+}
+
+// foldUnaryMinus drops the sign of negative literals, so that -1 and 1
+// fingerprint alike.
+func foldUnaryMinus(tokens []string) []string {
+	out := tokens[:0]
+
+	for i, tok := range tokens {
+		if tok == "-" && i+1 < len(tokens) && tokens[i+1] == Placeholder &&
+			(len(out) == 0 || !isOperand(out[len(out)-1])) {
+			continue
+		}
+
+		out = append(out, tok)
+	}
+
+	return out
+}
+
+// collapsePlaceholderLists replaces "( ?, ?, ... )" with "( ? )", so that
+// IN lists and VALUES rows of any length fingerprint alike.
+func collapsePlaceholderLists(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+
+		if tokens[i] != "(" {
+			continue
+		}
+
+		end := i + 1
+		for end+1 < len(tokens) && tokens[end] == Placeholder && tokens[end+1] == "," {
+			end += 2
+		}
+
+		if end > i+1 && end+1 < len(tokens) && tokens[end] == Placeholder && tokens[end+1] == ")" {
+			out = append(out, Placeholder)
+			i = end
+		}
+	}
+
+	return out
+}
+
+// spaceBetween reports whether the canonical text separates two tokens with
+// a space: not inside parentheses, before separators or around dots and
+// casts.
+func spaceBetween(prev, next string) bool {
+	switch prev {
+	case "(", "[", ".", "::":
+		return false
+	}
+
+	switch next {
+	case "(", ")", "[", "]", ",", ";", ".", "::":
+		return false
+	}
+
+	return true
+}
+
+// isOperand reports whether tok ends an operand, after which a minus sign is
+// a subtraction rather than the sign of a literal.
+func isOperand(tok string) bool {
+	switch tok {
+	case ")", "]", Placeholder:
+		return true
+	}
+
+	return isWordPart(tok[0]) || tok[0] == '"' || tok[0] == '`'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|:", c) >= 0
+}
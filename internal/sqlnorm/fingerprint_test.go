@@ -0,0 +1,98 @@
+package sqlnorm
+
+import (
+	"testing"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestFingerprintText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		protocol string
+		sql      string
+		want     string
+	}{
+		{
+			name:     "literals, case and whitespace",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      "SELECT *\n  FROM Users\tWHERE email='a@b.c' AND age > -42;",
+			want:     "select * from users where email = ? and age > ?",
+		},
+		{
+			name:     "quoted identifiers keep their case",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      `SELECT "UserName", t.Col FROM "Accounts" t WHERE t.id::text = $1`,
+			want:     `select "UserName", t.col from "Accounts" t where t.id::text = ?`,
+		},
+		{
+			name:     "lists collapsed",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      "SELECT * FROM t WHERE id IN (1, 2, 3) AND f(x, 4) > 0",
+			want:     "select * from t where id in(?) and f(x, ?) > ?",
+		},
+		{
+			name:     "comments dropped",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      "/* app:web */ SELECT 1 -- trailing",
+			want:     "select ?",
+		},
+		{
+			name:     "mysql",
+			protocol: store.ProtocolMySQL,
+			sql:      "INSERT INTO `Logs` (a, b) VALUES (?, \"x\") # note",
+			want:     "insert into `Logs`(a, b) values(?)",
+		},
+		{
+			name:     "oracle binds",
+			protocol: store.ProtocolOracle,
+			sql:      "UPDATE EMP SET SAL = :sal WHERE EMPNO = :1",
+			want:     "update emp set sal = ? where empno = ?",
+		},
+		{
+			name:     "mongodb keys keep their case",
+			protocol: store.ProtocolMongoDB,
+			sql:      `{"find": "users", "filter": {"lastName": "Doe", "age": {"$gt": 30}}}`,
+			want:     `{ "find" : ?, "filter" : { "lastName" : ?, "age" : { "$gt" : ? } } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := FingerprintText(tt.sql, tt.protocol); got != tt.want {
+				t.Errorf("FingerprintText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	same := []string{
+		"SELECT * FROM users WHERE id = 1",
+		"select *   from USERS where id=42",
+		"SELECT * FROM users WHERE id = $1",
+		"SELECT * FROM users WHERE id = -7; -- retry",
+		"SELECT * FROM users WHERE id=-7",
+	}
+
+	want := Fingerprint(same[0], store.ProtocolPostgreSQL)
+	if len(want) != 16 {
+		t.Fatalf("Fingerprint() = %q, want 16 hex digits", want)
+	}
+
+	for _, sql := range same[1:] {
+		if got := Fingerprint(sql, store.ProtocolPostgreSQL); got != want {
+			t.Errorf("Fingerprint(%q) = %s, want %s", sql, got, want)
+		}
+	}
+
+	if Fingerprint("SELECT * FROM orders WHERE id = 1", store.ProtocolPostgreSQL) == want {
+		t.Error("statements on different tables share a fingerprint")
+	}
+}
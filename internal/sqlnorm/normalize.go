@@ -0,0 +1,276 @@
+// Package sqlnorm replaces the literal values of SQL statements (and of the
+// JSON commands logged for MongoDB) with placeholders, so statements can be
+// shown without the data they carry, and fingerprints them so statements of
+// the same shape can be aggregated.
+package sqlnorm
+
+import (
+	"strings"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Placeholder replaces every literal value.
+const Placeholder = "?"
+
+// Normalize returns sql with its string, number and dollar-quoted literals
+// replaced by Placeholder. Identifiers, keywords, comments and bind
+// parameters ($1, :name, ?) are kept as is. The protocol selects the quoting
+// rules: MySQL and MariaDB treat double quotes as strings and honor
+// backslash escapes, Oracle supports q'[...]' quoting, and MongoDB commands
+// keep their document keys while string values are replaced.
+func Normalize(sql, protocol string) string {
+	mysql := protocol == store.ProtocolMySQL || protocol == store.ProtocolMariaDB
+	mongo := protocol == store.ProtocolMongoDB
+
+	var b strings.Builder
+
+	b.Grow(len(sql))
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"), c == '#' && mysql:
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+
+			b.WriteString(sql[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 4
+			}
+
+			b.WriteString(sql[i : i+end+4])
+			i += end + 4
+		case c == '"' && mongo:
+			end := skipString(sql, i, c, true)
+			if isJSONKey(sql[end:]) {
+				b.WriteString(sql[i:end])
+			} else {
+				b.WriteString(Placeholder)
+			}
+
+			i = end
+		case c == '\'' || (c == '"' && mysql):
+			i = skipString(sql, i, c, mysql)
+			b.WriteString(Placeholder)
+		case c == '"' || c == '`':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				end = len(sql) - i - 2
+			}
+
+			b.WriteString(sql[i : i+end+2])
+			i += end + 2
+		case c == '$' && protocol == store.ProtocolPostgreSQL:
+			if end, ok := skipDollarQuoted(sql, i); ok {
+				b.WriteString(Placeholder)
+				i = end
+
+				continue
+			}
+
+			// A bind parameter ($1) is kept with its number.
+			start := i
+			for i++; i < len(sql) && isDigit(sql[i]); i++ {
+			}
+
+			b.WriteString(sql[start:i])
+		case isWordStart(c):
+			start := i
+			for i < len(sql) && isWordPart(sql[i]) {
+				i++
+			}
+
+			word := sql[start:i]
+
+			// Prefixed strings: E'..', N'..', X'..', B'..', U&'..', q'[..]'.
+			if i < len(sql) && sql[i] == '\'' && isStringPrefix(word) {
+				i = skipString(sql, i, '\'', mysql || strings.EqualFold(word, "e"))
+				b.WriteString(Placeholder)
+
+				continue
+			}
+
+			if strings.EqualFold(word, "u") && strings.HasPrefix(sql[i:], "&'") {
+				i = skipString(sql, i+1, '\'', false)
+				b.WriteString(Placeholder)
+
+				continue
+			}
+
+			if strings.EqualFold(word, "q") && protocol == store.ProtocolOracle && i+1 < len(sql) && sql[i] == '\'' {
+				i = skipOracleQuoted(sql, i)
+				b.WriteString(Placeholder)
+
+				continue
+			}
+
+			b.WriteString(word)
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			i = skipNumber(sql, i)
+			b.WriteString(Placeholder)
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// skipString returns the index after the string literal starting at i,
+// honoring doubled quotes and, when backslash is set, backslash escapes.
+func skipString(sql string, i int, quote byte, backslash bool) int {
+	for i++; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+
+				continue
+			}
+
+			return i + 1
+		}
+	}
+
+	return len(sql)
+}
+
+// skipDollarQuoted returns the index after the $tag$...$tag$ string starting
+// at i, or false when i starts no such string.
+func skipDollarQuoted(sql string, i int) (int, bool) {
+	tagEnd := strings.IndexByte(sql[i+1:], '$')
+	if tagEnd < 0 {
+		return 0, false
+	}
+
+	tag := sql[i : i+tagEnd+2]
+	if !isDollarTag(tag[1 : len(tag)-1]) {
+		return 0, false
+	}
+
+	end := strings.Index(sql[i+len(tag):], tag)
+	if end < 0 {
+		return len(sql), true
+	}
+
+	return i + len(tag) + end + len(tag), true
+}
+
+// skipOracleQuoted returns the index after the q'<d>...<d>' string whose
+// quote is at i; bracket delimiters close with their counterpart.
+func skipOracleQuoted(sql string, i int) int {
+	closing := sql[i+1]
+
+	switch closing {
+	case '[':
+		closing = ']'
+	case '(':
+		closing = ')'
+	case '{':
+		closing = '}'
+	case '<':
+		closing = '>'
+	}
+
+	end := strings.Index(sql[i+2:], string(closing)+"'")
+	if end < 0 {
+		return len(sql)
+	}
+
+	return i + 2 + end + 2
+}
+
+// skipNumber returns the index after the numeric literal starting at i:
+// integers, decimals, exponents and 0x hexadecimal.
+func skipNumber(sql string, i int) int {
+	if strings.HasPrefix(sql[i:], "0x") || strings.HasPrefix(sql[i:], "0X") {
+		for i += 2; i < len(sql) && isHexDigit(sql[i]); i++ {
+		}
+
+		return i
+	}
+
+	for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.') {
+		i++
+	}
+
+	if i < len(sql) && (sql[i] == 'e' || sql[i] == 'E') {
+		j := i + 1
+		if j < len(sql) && (sql[j] == '+' || sql[j] == '-') {
+			j++
+		}
+
+		if j < len(sql) && isDigit(sql[j]) {
+			for i = j; i < len(sql) && isDigit(sql[i]); i++ {
+			}
+		}
+	}
+
+	return i
+}
+
+// isStringPrefix reports whether word, directly followed by a quote, makes
+// a prefixed string literal rather than an identifier.
+func isStringPrefix(word string) bool {
+	switch strings.ToLower(word) {
+	case "e", "n", "x", "b":
+		return true
+	default:
+		// MySQL character set introducers: _utf8mb4'...'.
+		return strings.HasPrefix(word, "_")
+	}
+}
+
+// isJSONKey reports whether rest, the text following a JSON string, makes
+// that string an object key.
+func isJSONKey(rest string) bool {
+	return strings.HasPrefix(strings.TrimLeft(rest, " \t\r\n"), ":")
+}
+
+// isDollarTag reports whether name can be a dollar-quote tag: empty or an
+// identifier without dollar signs.
+func isDollarTag(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	if isDigit(name[0]) {
+		return false
+	}
+
+	for i := range len(name) {
+		if name[i] == '$' || !isWordPart(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || isDigit(c) || c == '$'
+}
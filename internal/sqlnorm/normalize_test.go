@@ -0,0 +1,95 @@
+package sqlnorm
+
+import (
+	"testing"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		protocol string
+		sql      string
+		want     string
+	}{
+		{
+			name:     "strings and numbers",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      "SELECT * FROM users WHERE email = 'a@b.c' AND age > 42 AND score < -1.5e3",
+			want:     "SELECT * FROM users WHERE email = ? AND age > ? AND score < -?",
+		},
+		{
+			name:     "identifiers with digits kept",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      `SELECT t1.col2, "Table 3".x FROM t1 JOIN "Table 3" ON true`,
+			want:     `SELECT t1.col2, "Table 3".x FROM t1 JOIN "Table 3" ON true`,
+		},
+		{
+			name:     "bind parameters kept",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      "UPDATE a SET b = $1 WHERE c = $2 AND d = 'it''s'",
+			want:     "UPDATE a SET b = $1 WHERE c = $2 AND d = ?",
+		},
+		{
+			name:     "prefixed and dollar-quoted strings",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      `SELECT E'a\'b', x'ff', U&'d\0061t', $$secret$$, $tag$ 'x' $tag$`,
+			want:     "SELECT ?, ?, ?, ?, ?",
+		},
+		{
+			name:     "comments kept",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      "SELECT 1 -- trailing 'note'\n/* block 2 */ FROM t",
+			want:     "SELECT ? -- trailing 'note'\n/* block 2 */ FROM t",
+		},
+		{
+			name:     "mysql double quotes and escapes",
+			protocol: store.ProtocolMySQL,
+			sql:      "SELECT `col1` FROM t WHERE a = \"x\\\"y\" AND b = 'z' AND c = 0x1F # 5",
+			want:     "SELECT `col1` FROM t WHERE a = ? AND b = ? AND c = ? # 5",
+		},
+		{
+			name:     "mysql introducer",
+			protocol: store.ProtocolMariaDB,
+			sql:      "SELECT _utf8mb4'abc', ?",
+			want:     "SELECT ?, ?",
+		},
+		{
+			name:     "oracle q quoting",
+			protocol: store.ProtocolOracle,
+			sql:      "SELECT q'[it's]', :name FROM dual WHERE n = 3",
+			want:     "SELECT ?, :name FROM dual WHERE n = ?",
+		},
+		{
+			name:     "mongodb command",
+			protocol: store.ProtocolMongoDB,
+			sql:      `find {"find": "users", "filter": {"email": "a@b.c", "age": {"$gt": {"$numberInt": "42"}}}}`,
+			want:     `find {"find": ?, "filter": {"email": ?, "age": {"$gt": {"$numberInt": ?}}}}`,
+		},
+		{
+			name:     "in list",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      "SELECT * FROM t WHERE id IN (1, 2, .5)",
+			want:     "SELECT * FROM t WHERE id IN (?, ?, ?)",
+		},
+		{
+			name:     "unterminated string",
+			protocol: store.ProtocolPostgreSQL,
+			sql:      "SELECT 'oops",
+			want:     "SELECT ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Normalize(tt.sql, tt.protocol); got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
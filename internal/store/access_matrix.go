@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Access matrix page sizes.
+const (
+	DefaultAccessMatrixLimit = 1000
+	MaxAccessMatrixLimit     = 10000
+)
+
+// AccessMatrixFilter selects a page of the access matrix.
+type AccessMatrixFilter struct {
+	// At is the point in time the matrix describes.
+	At time.Time
+	// AfterUID is the cursor: return entries whose grant UID is greater.
+	AfterUID *uuid.UUID
+	Limit    int
+}
+
+// AccessMatrixEntry is a grant in effect at the matrix's point in time: a
+// user's access to a database.
+type AccessMatrixEntry struct {
+	GrantUID     uuid.UUID `bun:"grant_uid" json:"grant_uid"`
+	UserUID      uuid.UUID `bun:"user_uid" json:"user_uid"`
+	Username     string    `bun:"username" json:"username"`
+	DatabaseUID  uuid.UUID `bun:"database_uid" json:"database_uid"`
+	DatabaseName string    `bun:"database_name" json:"database_name"`
+	Protocol     string    `bun:"protocol" json:"protocol"`
+	// AccessLevel is the grant's AccessLevelReadOnly or AccessLevelReadWrite;
+	// Controls details it.
+	AccessLevel string    `bun:"-" json:"access_level"`
+	Controls    []string  `bun:"controls,array" json:"controls"`
+	GrantedBy   string    `bun:"granted_by" json:"granted_by"`
+	StartsAt    time.Time `bun:"starts_at" json:"starts_at"`
+	ExpiresAt   time.Time `bun:"expires_at" json:"expires_at"`
+}
+
+// ListAccessMatrix returns the grants in effect at filter.At, ordered by grant
+// UID for stable pagination. A grant is in effect when it had started, had
+// not expired and was not revoked yet; the users and databases deleted since
+// are still listed.
+func (s *Store) ListAccessMatrix(ctx context.Context, filter AccessMatrixFilter) ([]AccessMatrixEntry, error) {
+	entries := []AccessMatrixEntry{}
+
+	q := s.db.NewSelect().
+		TableExpr("access_grants AS ag").
+		Join("JOIN users AS u ON u.uid = ag.user_id").
+		Join("JOIN servers AS d ON d.uid = ag.database_id").
+		Join("LEFT JOIN users AS gb ON gb.uid = ag.granted_by").
+		ColumnExpr("ag.uid AS grant_uid, ag.user_id AS user_uid, u.username").
+		ColumnExpr("ag.database_id AS database_uid, d.name AS database_name, d.protocol").
+		ColumnExpr("ag.controls, COALESCE(gb.username, '') AS granted_by, ag.starts_at, ag.expires_at").
+		Where("ag.starts_at <= ?", filter.At).
+		Where("ag.expires_at > ?", filter.At).
+		Where("ag.revoked_at IS NULL OR ag.revoked_at > ?", filter.At)
+
+	if filter.AfterUID != nil {
+		q = q.Where("ag.uid > ?", *filter.AfterUID)
+	}
+
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	if err := q.OrderExpr("ag.uid ASC").Scan(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to list the access matrix: %w", err)
+	}
+
+	for i := range entries {
+		entries[i].AccessLevel = (&AccessGrant{Controls: entries[i].Controls}).AccessLevel()
+	}
+
+	return entries, nil
+}
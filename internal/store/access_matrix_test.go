@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestListAccessMatrix(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, err := store.CreateUser(ctx, "matrixadmin", "hash", []string{RoleAdmin})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	now := time.Now()
+
+	grant := func(suffix string, controls []string, startsAt, expiresAt time.Time) uuid.UUID {
+		t.Helper()
+
+		user, database := createTestUserAndDatabase(t, ctx, store, "matrix_"+suffix)
+
+		created, err := store.CreateGrant(ctx, &Grant{
+			UserID:     user.UID,
+			DatabaseID: database.UID,
+			Controls:   controls,
+			GrantedBy:  admin.UID,
+			StartsAt:   startsAt,
+			ExpiresAt:  expiresAt,
+		})
+		if err != nil {
+			t.Fatalf("CreateGrant() error = %v", err)
+		}
+
+		return created.UID
+	}
+
+	reader := grant("reader", []string{ControlReadOnly}, now.Add(-2*time.Hour), now.Add(time.Hour))
+	writer := grant("writer", []string{}, now.Add(-2*time.Hour), now.Add(time.Hour))
+	grant("future", []string{}, now.Add(time.Hour), now.Add(2*time.Hour))
+	grant("expired", []string{}, now.Add(-3*time.Hour), now.Add(-2*time.Hour))
+
+	revoked := grant("revoked", []string{}, now.Add(-2*time.Hour), now.Add(time.Hour))
+	if err := store.RevokeGrant(ctx, revoked, admin.UID); err != nil {
+		t.Fatalf("RevokeGrant() error = %v", err)
+	}
+
+	levels := func(filter AccessMatrixFilter) map[uuid.UUID]string {
+		t.Helper()
+
+		entries, err := store.ListAccessMatrix(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListAccessMatrix() error = %v", err)
+		}
+
+		out := map[uuid.UUID]string{}
+		for _, e := range entries {
+			if e.GrantedBy != "matrixadmin" {
+				t.Errorf("entry %s GrantedBy = %q, want matrixadmin", e.GrantUID, e.GrantedBy)
+			}
+
+			out[e.GrantUID] = e.AccessLevel
+		}
+
+		return out
+	}
+
+	// Now: the revoked grant is gone.
+	got := levels(AccessMatrixFilter{At: now.Add(time.Minute)})
+	if len(got) != 2 || got[reader] != AccessLevelReadOnly || got[writer] != AccessLevelReadWrite {
+		t.Errorf("matrix now = %v, want %s read_only and %s read_write", got, reader, writer)
+	}
+
+	// An hour ago, the revoked grant was still in effect.
+	if got := levels(AccessMatrixFilter{At: now.Add(-time.Hour)}); len(got) != 3 || got[revoked] == "" {
+		t.Errorf("matrix an hour ago = %v, want 3 grants including %s", got, revoked)
+	}
+
+	// Pages follow the grant UIDs.
+	first, err := store.ListAccessMatrix(ctx, AccessMatrixFilter{At: now.Add(-time.Hour), Limit: 2})
+	if err != nil {
+		t.Fatalf("ListAccessMatrix() error = %v", err)
+	}
+
+	rest, err := store.ListAccessMatrix(ctx, AccessMatrixFilter{At: now.Add(-time.Hour), AfterUID: &first[1].GrantUID})
+	if err != nil {
+		t.Fatalf("ListAccessMatrix() error = %v", err)
+	}
+
+	if len(first) != 2 || len(rest) != 1 || rest[0].GrantUID.String() <= first[1].GrantUID.String() {
+		t.Errorf("pages = %d then %d entries, want 2 then 1 in UID order", len(first), len(rest))
+	}
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// writeStatementPattern is the PostgreSQL regular expression the access review
+// reads a logged statement as a write with. It matches the keywords anywhere
+// in the text, so it errs on the side of a write: a grant is only reported as
+// read-only material when none of its statements looks like one.
+const writeStatementPattern = `\m(insert|update|delete|merge|upsert|replace|create|alter|drop|truncate|grant|revoke|copy|load|call|do)\M`
+
+// AccessReviewFilter parameterizes the access review.
+type AccessReviewFilter struct {
+	// Since starts the review window; only grants active over the whole
+	// window are reviewed.
+	Since time.Time
+	// QuotaUsageRatio is the share of a quota under which it is reported as
+	// never approached, e.g. 0.1 for 10%.
+	QuotaUsageRatio float64
+}
+
+// AccessReviewGrant is a grant reported by the access review, with its usage.
+type AccessReviewGrant struct {
+	GrantUID            uuid.UUID `bun:"grant_uid" json:"grant_uid"`
+	UserUID             uuid.UUID `bun:"user_uid" json:"user_uid"`
+	Username            string    `bun:"username" json:"username"`
+	DatabaseUID         uuid.UUID `bun:"database_uid" json:"database_uid"`
+	DatabaseName        string    `bun:"database_name" json:"database_name"`
+	Controls            []string  `bun:"controls,array" json:"controls"`
+	StartsAt            time.Time `bun:"starts_at" json:"starts_at"`
+	ExpiresAt           time.Time `bun:"expires_at" json:"expires_at"`
+	MaxQueryCounts      *int64    `bun:"max_query_counts" json:"max_query_counts,omitempty"`
+	MaxBytesTransferred *int64    `bun:"max_bytes_transferred" json:"max_bytes_transferred,omitempty"`
+	MaxRowsReturned     *int64    `bun:"max_rows_returned" json:"max_rows_returned,omitempty"`
+
+	// Connections, Queries and WriteQueries count the grant's use over the
+	// review window.
+	Connections  int64 `bun:"connections" json:"connections"`
+	Queries      int64 `bun:"queries" json:"queries"`
+	WriteQueries int64 `bun:"write_queries" json:"write_queries"`
+	// LastUsedAt is the last activity of the grant's connections, whenever it
+	// was; nil when the grant was never used.
+	LastUsedAt *time.Time `bun:"last_used_at" json:"last_used_at"`
+
+	// QueryCount, BytesTransferred and RowsReturned are the grant's use since
+	// it started, set for the grants reported for their quotas.
+	QueryCount       int64 `bun:"-" json:"query_count"`
+	BytesTransferred int64 `bun:"-" json:"bytes_transferred"`
+	RowsReturned     int64 `bun:"-" json:"rows_returned"`
+}
+
+// AccessReview lists active grants worth revoking or narrowing.
+type AccessReview struct {
+	Since time.Time `json:"since"`
+	// Unused grants had no connection over the window.
+	Unused []AccessReviewGrant `json:"unused"`
+	// ReadOnlyCandidates are write grants (without read_only) whose
+	// statements over the window all were reads.
+	ReadOnlyCandidates []AccessReviewGrant `json:"read_only_candidates"`
+	// UnapproachedQuotas are used grants whose every quota is used below
+	// the filter's ratio since the grant started.
+	UnapproachedQuotas []AccessReviewGrant `json:"unapproached_quotas"`
+}
+
+// GetAccessReview builds the access review of the grants active now and since
+// filter.Since. Usage is matched like the grant counters: the connections of
+// the grant's user to its database.
+func (s *Store) GetAccessReview(ctx context.Context, filter AccessReviewFilter) (*AccessReview, error) {
+	var grants []AccessReviewGrant
+
+	err := s.db.NewSelect().
+		TableExpr("access_grants AS ag").
+		Join("JOIN users AS u ON u.uid = ag.user_id").
+		Join("JOIN servers AS d ON d.uid = ag.database_id").
+		ColumnExpr("ag.uid AS grant_uid, ag.user_id AS user_uid, u.username, ag.database_id AS database_uid").
+		ColumnExpr("d.name AS database_name, ag.controls, ag.starts_at, ag.expires_at").
+		ColumnExpr("ag.max_query_counts, ag.max_bytes_transferred, ag.max_rows_returned").
+		ColumnExpr(`(SELECT count(*) FROM connections AS c
+			WHERE c.user_id = ag.user_id AND c.database_id = ag.database_id
+			AND c.connected_at >= ?) AS connections`, filter.Since).
+		ColumnExpr(`(SELECT coalesce(sum(q.repeat_count), 0) FROM queries AS q JOIN connections AS c ON c.uid = q.connection_id
+			WHERE c.user_id = ag.user_id AND c.database_id = ag.database_id
+			AND q.executed_at >= ?) AS queries`, filter.Since).
+		ColumnExpr(`(SELECT coalesce(sum(q.repeat_count), 0) FROM queries AS q JOIN connections AS c ON c.uid = q.connection_id
+			WHERE c.user_id = ag.user_id AND c.database_id = ag.database_id
+			AND q.executed_at >= ? AND q.sql_text ~* ?) AS write_queries`, filter.Since, writeStatementPattern).
+		ColumnExpr(`(SELECT max(c.last_activity_at) FROM connections AS c
+			WHERE c.user_id = ag.user_id AND c.database_id = ag.database_id
+			AND c.connected_at >= ag.starts_at) AS last_used_at`).
+		Where("ag.revoked_at IS NULL").
+		Where("ag.starts_at <= ?", filter.Since).
+		Where("ag.expires_at > NOW()").
+		OrderExpr("u.username ASC, d.name ASC, ag.starts_at ASC").
+		Scan(ctx, &grants)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review grants: %w", err)
+	}
+
+	review := &AccessReview{
+		Since:              filter.Since,
+		Unused:             []AccessReviewGrant{},
+		ReadOnlyCandidates: []AccessReviewGrant{},
+		UnapproachedQuotas: []AccessReviewGrant{},
+	}
+
+	for _, grant := range grants {
+		switch {
+		case grant.Connections == 0:
+			review.Unused = append(review.Unused, grant)
+		case grant.Queries > 0 && grant.WriteQueries == 0 && !slices.Contains(grant.Controls, ControlReadOnly):
+			review.ReadOnlyCandidates = append(review.ReadOnlyCandidates, grant)
+		}
+
+		// An unused grant is reported as such, not for its quotas too.
+		if grant.Connections == 0 ||
+			(grant.MaxQueryCounts == nil && grant.MaxBytesTransferred == nil && grant.MaxRowsReturned == nil) {
+			continue
+		}
+
+		counters := AccessGrant{
+			UserID:     grant.UserUID,
+			DatabaseID: grant.DatabaseUID,
+			StartsAt:   grant.StartsAt,
+			ExpiresAt:  grant.ExpiresAt,
+		}
+		if err := s.populateGrantCounters(ctx, &counters); err != nil {
+			return nil, err
+		}
+
+		grant.QueryCount, grant.BytesTransferred = counters.QueryCount, counters.BytesTransferred
+		grant.RowsReturned = counters.RowsReturned
+
+		if belowQuota(grant.QueryCount, grant.MaxQueryCounts, filter.QuotaUsageRatio) &&
+			belowQuota(grant.BytesTransferred, grant.MaxBytesTransferred, filter.QuotaUsageRatio) &&
+			belowQuota(grant.RowsReturned, grant.MaxRowsReturned, filter.QuotaUsageRatio) {
+			review.UnapproachedQuotas = append(review.UnapproachedQuotas, grant)
+		}
+	}
+
+	return review, nil
+}
+
+// belowQuota reports whether used is under ratio of quota; an unset quota
+// never is approached.
+func belowQuota(used int64, quota *int64, ratio float64) bool {
+	return quota == nil || float64(used) < float64(*quota)*ratio
+}
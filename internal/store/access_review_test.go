@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGetAccessReview(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, err := store.CreateUser(ctx, "reviewadmin", "hash", []string{RoleAdmin})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	now := time.Now()
+	since := now.Add(-30 * 24 * time.Hour)
+
+	// grant creates a grant that started before the review window, used by
+	// the given statements.
+	grant := func(suffix string, controls []string, maxQueries *int64, statements ...string) uuid.UUID {
+		t.Helper()
+
+		user, database := createTestUserAndDatabase(t, ctx, store, "review_"+suffix)
+
+		created, err := store.CreateGrant(ctx, &Grant{
+			UserID:         user.UID,
+			DatabaseID:     database.UID,
+			Controls:       controls,
+			GrantedBy:      admin.UID,
+			StartsAt:       since.Add(-time.Hour),
+			ExpiresAt:      now.Add(24 * time.Hour),
+			MaxQueryCounts: maxQueries,
+		})
+		if err != nil {
+			t.Fatalf("CreateGrant() error = %v", err)
+		}
+
+		if len(statements) == 0 {
+			return created.UID
+		}
+
+		conn, err := store.CreateConnection(ctx, user.UID, database.UID, "127.0.0.1")
+		if err != nil {
+			t.Fatalf("CreateConnection() error = %v", err)
+		}
+
+		for _, sql := range statements {
+			if _, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: sql, ExecutedAt: now}); err != nil {
+				t.Fatalf("CreateQuery() error = %v", err)
+			}
+		}
+
+		return created.UID
+	}
+
+	bigQuota, smallQuota := int64(1000), int64(2)
+
+	unused := grant("unused", []string{}, &bigQuota)
+	reader := grant("reader", []string{}, nil, "SELECT * FROM orders", "SELECT updated_at FROM orders")
+	writer := grant("writer", []string{}, nil, "SELECT 1", "WITH x AS (SELECT 1) UPDATE orders SET paid = true")
+	grant("readonly", []string{ControlReadOnly}, &smallQuota, "SELECT 1")
+	quota := grant("quota", []string{ControlReadOnly}, &bigQuota, "SELECT 1")
+
+	review, err := store.GetAccessReview(ctx, AccessReviewFilter{Since: since, QuotaUsageRatio: 0.1})
+	if err != nil {
+		t.Fatalf("GetAccessReview() error = %v", err)
+	}
+
+	uids := func(grants []AccessReviewGrant) []uuid.UUID {
+		var out []uuid.UUID
+		for _, g := range grants {
+			out = append(out, g.GrantUID)
+		}
+
+		return out
+	}
+
+	if got := uids(review.Unused); len(got) != 1 || got[0] != unused {
+		t.Errorf("Unused = %v, want [%s]", got, unused)
+	}
+
+	if got := uids(review.ReadOnlyCandidates); len(got) != 1 || got[0] != reader {
+		t.Errorf("ReadOnlyCandidates = %v, want [%s] (not %s)", got, reader, writer)
+	}
+
+	if got := review.UnapproachedQuotas; len(got) != 1 || got[0].GrantUID != quota || got[0].QueryCount != 1 {
+		t.Errorf("UnapproachedQuotas = %+v, want only %s with 1 query", got, quota)
+	}
+}
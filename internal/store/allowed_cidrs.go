@@ -0,0 +1,92 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidAllowedCIDR is returned when an entry of a grant's client network
+// allowlist is malformed or duplicated.
+var ErrInvalidAllowedCIDR = errors.New("invalid allowed CIDR")
+
+// allowedCIDRsProtocols lists the protocols whose proxy enforces client
+// network allowlists.
+var allowedCIDRsProtocols = []string{ProtocolPostgreSQL}
+
+// NormalizeAllowedCIDRs validates a client network allowlist and returns it in
+// canonical CIDR form: host bits cleared, a bare IP address becoming a
+// single-host network (/32 or /128). Each network may appear once.
+func NormalizeAllowedCIDRs(cidrs []string) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(cidrs))
+
+	for _, entry := range cidrs {
+		entry = strings.TrimSpace(entry)
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q must be a CIDR (10.0.0.0/8) or an IP address", ErrInvalidAllowedCIDR, entry)
+			}
+
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q must be a CIDR (10.0.0.0/8) or an IP address", ErrInvalidAllowedCIDR, entry)
+		}
+
+		name := network.String()
+		if slices.Contains(normalized, name) {
+			return nil, fmt.Errorf("%w: %q given more than once", ErrInvalidAllowedCIDR, name)
+		}
+
+		normalized = append(normalized, name)
+	}
+
+	return normalized, nil
+}
+
+// SupportsAllowedCIDRs reports whether the proxy of protocol enforces client
+// network allowlists.
+func SupportsAllowedCIDRs(protocol string) bool {
+	return slices.Contains(allowedCIDRsProtocols, protocol)
+}
+
+// RestrictsClients returns true if the grant can only be used from the
+// networks it lists.
+func (g *AccessGrant) RestrictsClients() bool {
+	return len(g.AllowedCIDRs) > 0
+}
+
+// AllowsClientIP reports whether a client connecting from ip may use the
+// grant. A grant without an allowlist allows every client; an unparsable
+// entry matches nothing.
+func (g *AccessGrant) AllowsClientIP(ip net.IP) bool {
+	if !g.RestrictsClients() {
+		return true
+	}
+
+	if ip == nil {
+		return false
+	}
+
+	for _, cidr := range g.AllowedCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
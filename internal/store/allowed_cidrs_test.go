@@ -0,0 +1,83 @@
+package store
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeAllowedCIDRs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cidrs   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "nil", cidrs: nil, want: nil},
+		{name: "empty", cidrs: []string{}, want: []string{}},
+		{name: "networks", cidrs: []string{"10.8.0.0/16", " 2001:db8::/32 "}, want: []string{"10.8.0.0/16", "2001:db8::/32"}},
+		{name: "host bits cleared", cidrs: []string{"10.8.3.4/16"}, want: []string{"10.8.0.0/16"}},
+		{name: "bare addresses", cidrs: []string{"192.168.1.10", "::1"}, want: []string{"192.168.1.10/32", "::1/128"}},
+		{name: "duplicate after normalization", cidrs: []string{"10.8.0.0/16", "10.8.1.0/16"}, wantErr: true},
+		{name: "empty entry", cidrs: []string{""}, wantErr: true},
+		{name: "bad prefix", cidrs: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "hostname", cidrs: []string{"bastion.example.com"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NormalizeAllowedCIDRs(tt.cidrs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeAllowedCIDRs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if !errors.Is(err, ErrInvalidAllowedCIDR) {
+					t.Errorf("NormalizeAllowedCIDRs() error = %v, want %v", err, ErrInvalidAllowedCIDR)
+				}
+
+				return
+			}
+
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("NormalizeAllowedCIDRs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessGrant_AllowsClientIP(t *testing.T) {
+	t.Parallel()
+
+	grant := &AccessGrant{AllowedCIDRs: []string{"10.8.0.0/16", "2001:db8::/32"}}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.8.3.4", want: true},
+		{ip: "::ffff:10.8.3.4", want: true},
+		{ip: "2001:db8::7", want: true},
+		{ip: "10.9.0.1", want: false},
+		{ip: "127.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := grant.AllowsClientIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("AllowsClientIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if grant.AllowsClientIP(nil) {
+		t.Error("grant with allowlist accepted an unknown client address")
+	}
+
+	if !(&AccessGrant{}).AllowsClientIP(nil) {
+		t.Error("grant without allowlist refused a client")
+	}
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidAllowedTable is returned when an entry of a grant's table
+// allowlist is malformed or duplicated.
+var ErrInvalidAllowedTable = errors.New("invalid allowed table")
+
+// DefaultTableSchema is the schema of unqualified table names, in allowlist
+// entries and in statements alike. Proxies enforcing an allowlist must keep
+// the session's search_path from pointing elsewhere.
+const DefaultTableSchema = "public"
+
+// allowedTablesProtocols lists the protocols whose proxy enforces table
+// allowlists.
+var allowedTablesProtocols = []string{ProtocolPostgreSQL}
+
+// NormalizeAllowedTables validates a table allowlist and returns it in
+// canonical schema.table form, bare table names being qualified with
+// DefaultTableSchema. An entry is schema.table, schema.* (every table of the
+// schema), table or *; names are matched as the database stores them, so
+// unquoted PostgreSQL names must be written in lower case. Each entry may
+// appear once.
+func NormalizeAllowedTables(tables []string) ([]string, error) {
+	if tables == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(tables))
+
+	for _, entry := range tables {
+		parts := strings.Split(strings.TrimSpace(entry), ".")
+		if len(parts) == 1 {
+			parts = []string{DefaultTableSchema, parts[0]}
+		}
+
+		if len(parts) != 2 || !validTableName(parts[0]) || (parts[1] != "*" && !validTableName(parts[1])) {
+			return nil, fmt.Errorf("%w: %q must be written schema.table, schema.* or table", ErrInvalidAllowedTable, entry)
+		}
+
+		name := parts[0] + "." + parts[1]
+		if slices.Contains(normalized, name) {
+			return nil, fmt.Errorf("%w: %q given more than once", ErrInvalidAllowedTable, name)
+		}
+
+		normalized = append(normalized, name)
+	}
+
+	return normalized, nil
+}
+
+// validTableName reports whether name can be one part of an allowlist entry.
+func validTableName(name string) bool {
+	return name != "" && name != "*" && !strings.ContainsFunc(name, unicode.IsSpace)
+}
+
+// SupportsAllowedTables reports whether the proxy of protocol enforces table
+// allowlists.
+func SupportsAllowedTables(protocol string) bool {
+	return slices.Contains(allowedTablesProtocols, protocol)
+}
+
+// RestrictsTables returns true if the grant only allows the tables it lists
+func (g *AccessGrant) RestrictsTables() bool {
+	return len(g.AllowedTables) > 0
+}
+
+// AllowsTable reports whether the grant gives access to a table named as in a
+// statement: table, schema.table or database.schema.table, an unqualified
+// name being in DefaultTableSchema. A grant without an allowlist allows every
+// table.
+func (g *AccessGrant) AllowsTable(table string) bool {
+	if !g.RestrictsTables() {
+		return true
+	}
+
+	parts := strings.Split(table, ".")
+
+	var schema, name string
+
+	switch len(parts) {
+	case 1:
+		schema, name = DefaultTableSchema, parts[0]
+	case 2, 3:
+		schema, name = parts[len(parts)-2], parts[len(parts)-1]
+	default:
+		return false
+	}
+
+	return slices.Contains(g.AllowedTables, schema+"."+name) || slices.Contains(g.AllowedTables, schema+".*")
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeAllowedTables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		tables  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "nil", tables: nil, want: nil},
+		{name: "empty", tables: []string{}, want: []string{}},
+		{name: "qualified", tables: []string{"sales.orders", " sales.* "}, want: []string{"sales.orders", "sales.*"}},
+		{name: "bare name", tables: []string{"orders"}, want: []string{"public.orders"}},
+		{name: "duplicate through default schema", tables: []string{"orders", "public.orders"}, wantErr: true},
+		{name: "empty entry", tables: []string{""}, wantErr: true},
+		{name: "every schema", tables: []string{"*.orders"}, wantErr: true},
+		{name: "bare wildcard", tables: []string{"*"}, want: []string{"public.*"}},
+		{name: "three parts", tables: []string{"db.sales.orders"}, wantErr: true},
+		{name: "whitespace", tables: []string{"sales.order lines"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NormalizeAllowedTables(tt.tables)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeAllowedTables() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if !errors.Is(err, ErrInvalidAllowedTable) {
+					t.Errorf("NormalizeAllowedTables() error = %v, want %v", err, ErrInvalidAllowedTable)
+				}
+
+				return
+			}
+
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("NormalizeAllowedTables() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessGrant_AllowsTable(t *testing.T) {
+	t.Parallel()
+
+	grant := &AccessGrant{AllowedTables: []string{"public.orders", "reporting.*"}}
+
+	tests := []struct {
+		table string
+		want  bool
+	}{
+		{table: "orders", want: true},
+		{table: "public.orders", want: true},
+		{table: "shop.public.orders", want: true},
+		{table: "reporting.daily", want: true},
+		{table: "customers", want: false},
+		{table: "sales.orders", want: false},
+		{table: "Orders", want: false},
+		{table: "a.b.c.d", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := grant.AllowsTable(tt.table); got != tt.want {
+			t.Errorf("AllowsTable(%q) = %v, want %v", tt.table, got, tt.want)
+		}
+	}
+
+	if !(&AccessGrant{}).AllowsTable("anything") {
+		t.Error("grant without allowlist refused a table")
+	}
+}
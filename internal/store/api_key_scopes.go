@@ -0,0 +1,91 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidAPIKeyScope is returned when an API key scope is malformed or
+// names an unknown resource.
+var ErrInvalidAPIKeyScope = errors.New("invalid API key scope")
+
+// API key scope actions: read covers the GET routes of a resource, write the
+// others. A write scope covers the read one too.
+const (
+	ScopeActionRead  = "read"
+	ScopeActionWrite = "write"
+	// ScopeResourceAll stands for every resource: "read:*" makes a read-only
+	// key.
+	ScopeResourceAll = "*"
+)
+
+// APIKeyScopeResources are the resources API key scopes name, each covering
+// a group of API routes (see routeScope in internal/api).
+var APIKeyScopeResources = []string{
+	"users",       // users, user groups, rate limits, preferences
+	"databases",   // servers and SSH servers
+	"grants",      // grants, grant definitions, templates and requests, statement approvals
+	"keys",        // API keys
+	"connections", // connections and their dumps
+	"queries",     // query log, rows, plans and alerts
+	"audit",       // audit log and reports
+	"settings",    // parameters, instance settings and administration
+	"search",      // global search
+}
+
+// NormalizeAPIKeyScopes validates API key scopes, each action:resource, and
+// returns them in lower case, sorted and without duplicates. No scope at all
+// returns nil: the key keeps every permission of its user.
+func NormalizeAPIKeyScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(scopes))
+
+	for _, entry := range scopes {
+		scope := strings.ToLower(strings.TrimSpace(entry))
+
+		action, resource, ok := strings.Cut(scope, ":")
+		if !ok || (action != ScopeActionRead && action != ScopeActionWrite) ||
+			(resource != ScopeResourceAll && !slices.Contains(APIKeyScopeResources, resource)) {
+			return nil, fmt.Errorf("%w: %q must be read:<resource> or write:<resource>, the resource one of %s or *",
+				ErrInvalidAPIKeyScope, entry, strings.Join(APIKeyScopeResources, ", "))
+		}
+
+		normalized = append(normalized, scope)
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized), nil
+}
+
+// IsScoped reports whether the key is restricted to its scopes.
+func (k *APIKey) IsScoped() bool {
+	return len(k.Scopes) > 0
+}
+
+// HasScope reports whether the key may call the routes of scope, an
+// action:resource pair. An unscoped key may call every route, and the
+// requests of any key are still limited by its user's roles.
+func (k *APIKey) HasScope(scope string) bool {
+	if !k.IsScoped() {
+		return true
+	}
+
+	action, resource, _ := strings.Cut(scope, ":")
+
+	for _, granted := range k.Scopes {
+		grantedAction, grantedResource, _ := strings.Cut(granted, ":")
+
+		if (grantedResource == resource || grantedResource == ScopeResourceAll) &&
+			(grantedAction == action || grantedAction == ScopeActionWrite) {
+			return true
+		}
+	}
+
+	return false
+}
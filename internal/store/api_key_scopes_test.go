@@ -0,0 +1,83 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeAPIKeyScopes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		scopes  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "nil", scopes: nil, want: nil},
+		{name: "empty", scopes: []string{}, want: nil},
+		{name: "sorted and lower case", scopes: []string{"write:grants", " READ:Queries "}, want: []string{"read:queries", "write:grants"}},
+		{name: "duplicates", scopes: []string{"read:queries", "read:queries"}, want: []string{"read:queries"}},
+		{name: "every resource", scopes: []string{"read:*"}, want: []string{"read:*"}},
+		{name: "unknown resource", scopes: []string{"read:everything"}, wantErr: true},
+		{name: "unknown action", scopes: []string{"delete:grants"}, wantErr: true},
+		{name: "no action", scopes: []string{"grants"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NormalizeAPIKeyScopes(tt.scopes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeAPIKeyScopes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if !errors.Is(err, ErrInvalidAPIKeyScope) {
+					t.Errorf("NormalizeAPIKeyScopes() error = %v, want %v", err, ErrInvalidAPIKeyScope)
+				}
+
+				return
+			}
+
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("NormalizeAPIKeyScopes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKey_HasScope(t *testing.T) {
+	t.Parallel()
+
+	key := &APIKey{Scopes: []string{"read:queries", "write:grants"}}
+
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{scope: "read:queries", want: true},
+		{scope: "write:queries", want: false},
+		{scope: "read:grants", want: true},
+		{scope: "write:grants", want: true},
+		{scope: "read:connections", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := key.HasScope(tt.scope); got != tt.want {
+			t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+
+	readOnly := &APIKey{Scopes: []string{"read:*"}}
+	if !readOnly.HasScope("read:audit") || readOnly.HasScope("write:users") {
+		t.Errorf("read:* key: HasScope(read:audit) = %v, HasScope(write:users) = %v, want true, false",
+			readOnly.HasScope("read:audit"), readOnly.HasScope("write:users"))
+	}
+
+	if !(&APIKey{}).HasScope("write:users") {
+		t.Error("unscoped key refused a scope")
+	}
+}
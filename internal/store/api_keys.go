@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/uptrace/bun"
 
 	"github.com/fclairamb/dbbat/internal/crypto"
 )
@@ -34,6 +35,7 @@ var (
 	ErrAPIKeyRevoked  = errors.New("API key has been revoked")
 	ErrAPIKeyExpired  = errors.New("API key has expired")
 	ErrAPIKeyTooShort = errors.New("API key too short")
+	ErrAPIKeyUnsigned = errors.New("API key has no signing secret")
 )
 
 // generateKey generates a new random key with the given prefix
@@ -73,6 +75,13 @@ func generateWebKey() (string, string, error) {
 // If encryptionKey is provided (non-nil), an O5LOGON verifier is computed
 // and stored for Oracle proxy authentication.
 func (s *Store) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, expiresAt *time.Time, encryptionKey ...[]byte) (*APIKey, string, error) {
+	return s.CreateScopedAPIKey(ctx, userID, name, expiresAt, nil, encryptionKey...)
+}
+
+// CreateScopedAPIKey creates a new API key restricted to scopes, as returned
+// by NormalizeAPIKeyScopes; nil scopes leave it every permission of its user,
+// like CreateAPIKey.
+func (s *Store) CreateScopedAPIKey(ctx context.Context, userID uuid.UUID, name string, expiresAt *time.Time, scopes []string, encryptionKey ...[]byte) (*APIKey, string, error) {
 	// Generate the key
 	plainKey, prefix, err := generateAPIKey()
 	if err != nil {
@@ -92,14 +101,19 @@ func (s *Store) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string,
 		KeyPrefix: prefix,
 		KeyType:   KeyTypeAPI,
 		ExpiresAt: expiresAt,
+		Scopes:    scopes,
 		CreatedAt: time.Now(),
 	}
 
-	// Generate O5LOGON verifiers if encryption key is available
+	// Generate O5LOGON verifiers and the signing secret if encryption key is available
 	if len(encryptionKey) > 0 && len(encryptionKey[0]) > 0 {
 		if err := s.attachO5LogonVerifiers(ctx, apiKey, plainKey, encryptionKey[0]); err != nil {
 			return nil, "", err
 		}
+
+		if err := apiKey.storeSigningSecret(plainKey, encryptionKey[0]); err != nil {
+			return nil, "", err
+		}
 	}
 
 	_, err = s.db.NewInsert().
@@ -154,11 +168,15 @@ func (s *Store) CreateAPIKeyWithValue(ctx context.Context, userID uuid.UUID, nam
 		CreatedAt: time.Now(),
 	}
 
-	// Generate O5LOGON verifiers if encryption key is available
+	// Generate O5LOGON verifiers and the signing secret if encryption key is available
 	if len(encryptionKey) > 0 && len(encryptionKey[0]) > 0 {
 		if err := s.attachO5LogonVerifiers(ctx, apiKey, plainKey, encryptionKey[0]); err != nil {
 			return nil, err
 		}
+
+		if err := apiKey.storeSigningSecret(plainKey, encryptionKey[0]); err != nil {
+			return nil, err
+		}
 	}
 
 	_, err = s.db.NewInsert().
@@ -239,13 +257,89 @@ func (k *APIKey) storeO5LogonVerifiers(plainKey string, encryptionKey, salt6949,
 	}
 
 	// Both verifier types live together in the protocol-specific jsonb column.
-	k.ProtocolData = &ProtocolData{
-		Oracle: &OracleAPIKeyData{
-			O5LogonSalt6949:      salt6949,
-			O5LogonVerifier6949:  encVerifier,
-			O5LogonSalt18453:     salt18453,
-			O5LogonVerifier18453: encVerifier18453,
-		},
+	if k.ProtocolData == nil {
+		k.ProtocolData = &ProtocolData{}
+	}
+
+	k.ProtocolData.Oracle = &OracleAPIKeyData{
+		O5LogonSalt6949:      salt6949,
+		O5LogonVerifier6949:  encVerifier,
+		O5LogonSalt18453:     salt18453,
+		O5LogonVerifier18453: encVerifier18453,
+	}
+
+	return nil
+}
+
+// storeSigningSecret encrypts the plaintext key with the dbbat master key
+// (AAD bound to the key prefix) so signed API requests can be verified.
+func (k *APIKey) storeSigningSecret(plainKey string, encryptionKey []byte) error {
+	secret, err := crypto.Encrypt([]byte(plainKey), encryptionKey, crypto.APIKeyAAD(k.KeyPrefix))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt signing secret: %w", err)
+	}
+
+	if k.ProtocolData == nil {
+		k.ProtocolData = &ProtocolData{}
+	}
+
+	k.ProtocolData.Signing = &SigningAPIKeyData{Secret: secret}
+
+	return nil
+}
+
+// SigningSecret returns the key used to sign requests with this API key.
+// Returns ErrAPIKeyUnsigned for keys created without an encryption key or
+// not used since signing was introduced.
+func (k *APIKey) SigningSecret(encryptionKey []byte) ([]byte, error) {
+	sd := k.SigningData()
+	if sd == nil || len(sd.Secret) == 0 {
+		return nil, ErrAPIKeyUnsigned
+	}
+
+	secret, err := crypto.Decrypt(sd.Secret, encryptionKey, crypto.APIKeyAAD(k.KeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// EnsureAPIKeySigningSecret gives a key created before request signing its
+// signing secret, so it can sign requests without being rotated. Called
+// best-effort on a successful bearer authentication, while the plaintext key
+// is at hand; a no-op without an encryption key or when the key already has a
+// secret.
+func (s *Store) EnsureAPIKeySigningSecret(ctx context.Context, keyID uuid.UUID, plainKey string, encryptionKey []byte) error {
+	if len(encryptionKey) == 0 {
+		return nil
+	}
+
+	apiKey, err := s.GetAPIKeyByID(ctx, keyID)
+	if err != nil {
+		return err
+	}
+
+	if apiKey.SigningData() != nil || apiKey.KeyType != KeyTypeAPI {
+		return nil
+	}
+
+	if err := apiKey.storeSigningSecret(plainKey, encryptionKey); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(apiKey.ProtocolData)
+	if err != nil {
+		return fmt.Errorf("failed to encode API key protocol data: %w", err)
+	}
+
+	_, err = s.db.NewUpdate().
+		Model((*APIKey)(nil)).
+		Set("protocol_data = ?::jsonb", string(encoded)).
+		Where("id = ?", keyID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to persist signing secret: %w", err)
 	}
 
 	return nil
@@ -420,7 +514,35 @@ func (s *Store) VerifyAPIKey(ctx context.Context, plainKey string) (*APIKey, err
 // ListAPIKeys retrieves API keys with optional filters
 func (s *Store) ListAPIKeys(ctx context.Context, filter APIKeyFilter) ([]APIKey, error) {
 	var keys []APIKey
-	q := s.db.NewSelect().Model(&keys)
+
+	err := s.selectAPIKeys(&keys, filter).Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	if keys == nil {
+		keys = []APIKey{}
+	}
+	return keys, nil
+}
+
+// CountAPIKeys returns the number of API keys matching the filter, regardless
+// of its Limit and Offset.
+func (s *Store) CountAPIKeys(ctx context.Context, filter APIKeyFilter) (int, error) {
+	var keys []APIKey
+
+	n, err := s.selectAPIKeys(&keys, filter).Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectAPIKeys builds the select of the API keys matching filter into dest,
+// newest first.
+func (s *Store) selectAPIKeys(dest *[]APIKey, filter APIKeyFilter) *bun.SelectQuery {
+	q := s.db.NewSelect().Model(dest)
 
 	if filter.UserID != nil {
 		q = q.Where("user_id = ?", *filter.UserID)
@@ -436,25 +558,7 @@ func (s *Store) ListAPIKeys(ctx context.Context, filter APIKeyFilter) ([]APIKey,
 		q = q.Where("(expires_at IS NULL OR expires_at > ?)", time.Now())
 	}
 
-	q = q.Order("created_at DESC")
-
-	if filter.Limit > 0 {
-		q = q.Limit(filter.Limit)
-	}
-
-	if filter.Offset > 0 {
-		q = q.Offset(filter.Offset)
-	}
-
-	err := q.Scan(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list API keys: %w", err)
-	}
-
-	if keys == nil {
-		keys = []APIKey{}
-	}
-	return keys, nil
+	return paginate(q.Order("created_at DESC"), filter.Limit, filter.Offset)
 }
 
 // RevokeAPIKey revokes an API key
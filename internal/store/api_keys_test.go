@@ -203,6 +203,62 @@ func TestAPIKeyProtocolDataRoundTrip(t *testing.T) {
 	}
 }
 
+func TestAPIKeySigningSecret(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "signinguser", "hash", []string{RoleConnector})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	encKey := bytes.Repeat([]byte{0x42}, 32)
+
+	created, plainKey, err := store.CreateAPIKey(ctx, user.UID, "Signing Key", nil, encKey)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	fetched, err := store.GetAPIKeyByPrefix(ctx, created.KeyPrefix)
+	if err != nil {
+		t.Fatalf("GetAPIKeyByPrefix() error = %v", err)
+	}
+
+	secret, err := fetched.SigningSecret(encKey)
+	if err != nil {
+		t.Fatalf("SigningSecret() error = %v", err)
+	}
+	if string(secret) != plainKey {
+		t.Error("SigningSecret() does not match the plaintext key")
+	}
+
+	// A key created without an encryption key gets its secret on first use,
+	// keeping its Oracle material (none here) untouched.
+	legacy, legacyKey, err := store.CreateAPIKey(ctx, user.UID, "Legacy Key", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey(no enc) error = %v", err)
+	}
+	if _, err := legacy.SigningSecret(encKey); !errors.Is(err, ErrAPIKeyUnsigned) {
+		t.Fatalf("SigningSecret(legacy) error = %v, want ErrAPIKeyUnsigned", err)
+	}
+
+	if err := store.EnsureAPIKeySigningSecret(ctx, legacy.ID, legacyKey, encKey); err != nil {
+		t.Fatalf("EnsureAPIKeySigningSecret() error = %v", err)
+	}
+
+	upgraded, err := store.GetAPIKeyByID(ctx, legacy.ID)
+	if err != nil {
+		t.Fatalf("GetAPIKeyByID() error = %v", err)
+	}
+
+	if secret, err := upgraded.SigningSecret(encKey); err != nil || string(secret) != legacyKey {
+		t.Errorf("SigningSecret(upgraded) = %q, %v", secret, err)
+	}
+	if upgraded.OracleData() != nil {
+		t.Errorf("upgraded OracleData() = %+v, want nil", upgraded.OracleData())
+	}
+}
+
 func TestVerifyAPIKey(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -4,17 +4,46 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/uptrace/bun"
 )
 
-// LogAuditEvent creates a new audit log entry
+// sourceIPContextKey is the context key carrying the client IP of the
+// request on whose behalf store writes are performed.
+type sourceIPContextKey struct{}
+
+// WithSourceIP returns a copy of ctx carrying the client IP that audit events
+// logged under it are attributed to. The API sets it once per request, so
+// individual handlers don't have to thread the IP into every event.
+func WithSourceIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, sourceIPContextKey{}, ip)
+}
+
+// SourceIPFromContext returns the client IP set by WithSourceIP, or "".
+func SourceIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(sourceIPContextKey{}).(string)
+
+	return ip
+}
+
+// LogAuditEvent creates a new audit log entry. When the event carries no
+// SourceIP, the one attached to ctx (if any) is recorded.
 func (s *Store) LogAuditEvent(ctx context.Context, event *AuditEvent) error {
 	logEntry := &AuditLog{
-		UID:         newUIDv7(), // Generate UUIDv7 for time-ordered inserts
-		EventType:   event.EventType,
-		UserID:      event.UserID,
-		PerformedBy: event.PerformedBy,
-		Details:     event.Details,
-		CreatedAt:   time.Now(),
+		UID:           newUIDv7(), // Generate UUIDv7 for time-ordered inserts
+		EventType:     event.EventType,
+		SchemaVersion: event.SchemaVersion,
+		UserID:        event.UserID,
+		PerformedBy:   event.PerformedBy,
+		Details:       event.Details,
+		SourceIP:      event.SourceIP,
+		CreatedAt:     time.Now(),
+	}
+
+	if logEntry.SourceIP == nil {
+		if ip := SourceIPFromContext(ctx); ip != "" {
+			logEntry.SourceIP = &ip
+		}
 	}
 
 	_, err := s.db.NewInsert().
@@ -23,13 +52,51 @@ func (s *Store) LogAuditEvent(ctx context.Context, event *AuditEvent) error {
 	if err != nil {
 		return fmt.Errorf("failed to log audit event: %w", err)
 	}
+
+	switch {
+	case s.auditTx != nil:
+		*s.auditTx = append(*s.auditTx, logEntry)
+	case s.audit != nil:
+		s.audit.AuditLogged(logEntry)
+	}
+
 	return nil
 }
 
-// ListAuditEvents retrieves audit events with optional filters
+// ListAuditEvents retrieves audit events with optional filters.
+// Served by the storage replica when one is configured and healthy.
 func (s *Store) ListAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error) {
 	var events []AuditLog
-	q := s.db.NewSelect().Model(&events)
+
+	err := s.scanReadOnly(ctx, s.selectAuditEvents(&events, filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	if events == nil {
+		events = []AuditLog{}
+	}
+	return events, nil
+}
+
+// CountAuditEvents returns the number of audit events matching the filter,
+// its BeforeUID cursor included, regardless of its Limit and Offset.
+// Served by the storage replica when one is configured and healthy.
+func (s *Store) CountAuditEvents(ctx context.Context, filter AuditFilter) (int, error) {
+	var events []AuditLog
+
+	n, err := s.countReadOnly(ctx, s.selectAuditEvents(&events, filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectAuditEvents builds the select of the audit events matching filter
+// into dest, newest first.
+func (s *Store) selectAuditEvents(dest *[]AuditLog, filter AuditFilter) *bun.SelectQuery {
+	q := s.db.NewSelect().Model(dest)
 
 	if filter.EventType != nil {
 		q = q.Where("event_type = ?", *filter.EventType)
@@ -55,23 +122,5 @@ func (s *Store) ListAuditEvents(ctx context.Context, filter AuditFilter) ([]Audi
 		q = q.Where("uid < ?", *filter.BeforeUID)
 	}
 
-	q = q.Order("uid DESC")
-
-	if filter.Limit > 0 {
-		q = q.Limit(filter.Limit)
-	}
-
-	if filter.Offset > 0 {
-		q = q.Offset(filter.Offset)
-	}
-
-	err := q.Scan(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list audit events: %w", err)
-	}
-
-	if events == nil {
-		events = []AuditLog{}
-	}
-	return events, nil
+	return paginate(q.Order("uid DESC"), filter.Limit, filter.Offset)
 }
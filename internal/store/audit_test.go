@@ -3,6 +3,8 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -241,3 +243,70 @@ func TestAuditEventFields(t *testing.T) {
 		t.Errorf("details[\"count\"] = %v, want 42", parsedDetails["count"])
 	}
 }
+
+func TestLogAuditEvent_SourceIPFromContext(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := WithSourceIP(context.Background(), "203.0.113.9")
+
+	if err := store.LogAuditEvent(ctx, &AuditEvent{EventType: "source_ip_test"}); err != nil {
+		t.Fatalf("LogAuditEvent() error = %v", err)
+	}
+
+	eventType := "source_ip_test"
+	events, err := store.ListAuditEvents(context.Background(), AuditFilter{EventType: &eventType})
+	if err != nil {
+		t.Fatalf("ListAuditEvents() error = %v", err)
+	}
+
+	if len(events) != 1 || events[0].SourceIP == nil || *events[0].SourceIP != "203.0.113.9" {
+		t.Fatalf("expected one event with source_ip 203.0.113.9, got %+v", events)
+	}
+}
+
+type auditRecorder struct {
+	entries []string
+}
+
+func (r *auditRecorder) AuditLogged(entry *AuditLog) {
+	r.entries = append(r.entries, entry.EventType)
+}
+
+func TestLogAuditEvent_Observer(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	recorder := &auditRecorder{}
+	store.SetAuditObserver(recorder)
+
+	if err := store.LogAuditEvent(ctx, &AuditEvent{EventType: "observed"}); err != nil {
+		t.Fatalf("LogAuditEvent() error = %v", err)
+	}
+
+	// Events of a rolled back transaction never happened.
+	_ = store.WithTx(ctx, func(ctx context.Context, tx *Store) error {
+		if err := tx.LogAuditEvent(ctx, &AuditEvent{EventType: "rolled_back"}); err != nil {
+			t.Fatalf("LogAuditEvent() error = %v", err)
+		}
+
+		return errors.New("roll back")
+	})
+
+	err := store.WithTx(ctx, func(ctx context.Context, tx *Store) error {
+		if err := tx.LogAuditEvent(ctx, &AuditEvent{EventType: "committed"}); err != nil {
+			return err
+		}
+
+		if len(recorder.entries) != 1 {
+			t.Errorf("observer notified before commit: %v", recorder.entries)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	if want := []string{"observed", "committed"}; !slices.Equal(recorder.entries, want) {
+		t.Errorf("observed %v, want %v", recorder.entries, want)
+	}
+}
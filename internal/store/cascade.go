@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Deletion semantics, per relation referencing a user or a server:
+//
+//   - access grants: revoked (revoked_by = the deleting admin), never removed
+//   - API keys (users only): revoked, never removed
+//   - pending grant requests: cancelled with a reason
+//   - identities, group memberships, preferences (users only): removed
+//   - connections, queries, audit events: retained untouched
+//
+// Users and servers are soft-deleted, so the retained history keeps pointing
+// at the deleted row, which serves as the snapshot of who/what it was.
+
+// cancelReasonUserDeleted and cancelReasonServerDeleted are recorded as the
+// decision_reason of pending grant requests cancelled by a deletion.
+const (
+	cancelReasonUserDeleted   = "user deleted"
+	cancelReasonServerDeleted = "database deleted"
+)
+
+// DeletionCascade reports what a user or server deletion did to the rows that
+// referenced it.
+type DeletionCascade struct {
+	// RevokedGrants lists grants that were still active. Callers signal them
+	// to the revocation registry so live sessions drop immediately.
+	RevokedGrants     []uuid.UUID
+	RevokedAPIKeys    int
+	CancelledRequests int
+}
+
+// revokeActiveGrants revokes every still-active grant whose column equals id
+// and returns their UIDs.
+func revokeActiveGrants(
+	ctx context.Context, tx bun.Tx, column string, id, revokedBy uuid.UUID, now time.Time,
+) ([]uuid.UUID, error) {
+	var uids []uuid.UUID
+
+	if err := tx.NewUpdate().
+		Model((*AccessGrant)(nil)).
+		Set("revoked_at = ?", now).
+		Set("revoked_by = ?", actorOrNull(revokedBy)).
+		Where("? = ?", bun.Ident(column), id).
+		Where("revoked_at IS NULL").
+		Returning("uid").
+		Scan(ctx, &uids); err != nil {
+		return nil, fmt.Errorf("failed to revoke grants: %w", err)
+	}
+
+	return uids, nil
+}
+
+// cancelPendingGrantRequests cancels every pending grant request whose column
+// equals id and returns how many were cancelled.
+func cancelPendingGrantRequests(
+	ctx context.Context, tx bun.Tx, column string, id, decidedBy uuid.UUID, reason string, now time.Time,
+) (int, error) {
+	result, err := tx.NewUpdate().
+		Model((*GrantRequest)(nil)).
+		Set("status = ?", GrantRequestCancelled).
+		Set("decided_at = ?", now).
+		Set("decided_by = ?", actorOrNull(decidedBy)).
+		Set("decision_reason = ?", reason).
+		Where("? = ?", bun.Ident(column), id).
+		Where("status = ?", GrantRequestPending).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel grant requests: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// actorOrNull maps uuid.Nil (no acting user, e.g. a CLI or test caller) to
+// NULL so it does not violate the users foreign key.
+func actorOrNull(uid uuid.UUID) *uuid.UUID {
+	if uid == uuid.Nil {
+		return nil
+	}
+
+	return &uid
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDeleteUserCascade(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, user, db, def := setupRequestFixtures(t, ctx, store, "cascade_user")
+
+	now := time.Now()
+	grant, err := store.CreateGrant(ctx, &Grant{
+		UserID:     user.UID,
+		DatabaseID: db.UID,
+		GrantedBy:  admin.UID,
+		StartsAt:   now,
+		ExpiresAt:  now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateGrant() error = %v", err)
+	}
+
+	apiKey, _, err := store.CreateAPIKey(ctx, user.UID, "cascade", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
+		UserID:            user.UID,
+		GrantDefinitionID: &def.UID,
+		DatabaseID:        db.UID,
+	})
+	if err != nil {
+		t.Fatalf("CreateGrantRequest() error = %v", err)
+	}
+
+	conn, err := store.CreateConnection(ctx, user.UID, db.UID, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateConnection() error = %v", err)
+	}
+
+	cascade, err := store.DeleteUser(ctx, user.UID, admin.UID)
+	if err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	if len(cascade.RevokedGrants) != 1 || cascade.RevokedGrants[0] != grant.UID {
+		t.Errorf("RevokedGrants = %v, want [%s]", cascade.RevokedGrants, grant.UID)
+	}
+
+	if cascade.RevokedAPIKeys != 1 {
+		t.Errorf("RevokedAPIKeys = %d, want 1", cascade.RevokedAPIKeys)
+	}
+
+	if cascade.CancelledRequests != 1 {
+		t.Errorf("CancelledRequests = %d, want 1", cascade.CancelledRequests)
+	}
+
+	t.Run("grant revoked by the deleting admin", func(t *testing.T) {
+		got, err := store.GetGrantByUID(ctx, grant.UID)
+		if err != nil {
+			t.Fatalf("GetGrantByUID() error = %v", err)
+		}
+
+		if got.RevokedAt == nil || got.RevokedBy == nil || *got.RevokedBy != admin.UID {
+			t.Errorf("grant revoked_at = %v, revoked_by = %v, want revoked by %s", got.RevokedAt, got.RevokedBy, admin.UID)
+		}
+	})
+
+	t.Run("API key revoked", func(t *testing.T) {
+		got, err := store.GetAPIKeyByID(ctx, apiKey.ID)
+		if err != nil {
+			t.Fatalf("GetAPIKeyByID() error = %v", err)
+		}
+
+		if !got.IsRevoked() {
+			t.Error("API key should be revoked")
+		}
+	})
+
+	t.Run("pending request cancelled", func(t *testing.T) {
+		got, err := store.GetGrantRequest(ctx, req.UID)
+		if err != nil {
+			t.Fatalf("GetGrantRequest() error = %v", err)
+		}
+
+		if got.Status != GrantRequestCancelled {
+			t.Errorf("status = %q, want %q", got.Status, GrantRequestCancelled)
+		}
+
+		if got.DecisionReason == nil || *got.DecisionReason != cancelReasonUserDeleted {
+			t.Errorf("decision_reason = %v, want %q", got.DecisionReason, cancelReasonUserDeleted)
+		}
+	})
+
+	t.Run("connection history retained", func(t *testing.T) {
+		got, err := store.GetConnectionByUID(ctx, conn.UID)
+		if err != nil {
+			t.Fatalf("GetConnectionByUID() error = %v", err)
+		}
+
+		if got.UserID != user.UID {
+			t.Errorf("connection user_id = %s, want %s", got.UserID, user.UID)
+		}
+	})
+}
+
+func TestDeleteServerCascade(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, user, db, def := setupRequestFixtures(t, ctx, store, "cascade_server")
+
+	now := time.Now()
+	grant, err := store.CreateGrant(ctx, &Grant{
+		UserID:     user.UID,
+		DatabaseID: db.UID,
+		GrantedBy:  admin.UID,
+		StartsAt:   now,
+		ExpiresAt:  now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateGrant() error = %v", err)
+	}
+
+	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
+		UserID:            user.UID,
+		GrantDefinitionID: &def.UID,
+		DatabaseID:        db.UID,
+	})
+	if err != nil {
+		t.Fatalf("CreateGrantRequest() error = %v", err)
+	}
+
+	cascade, err := store.DeleteServer(ctx, db.UID, admin.UID)
+	if err != nil {
+		t.Fatalf("DeleteServer() error = %v", err)
+	}
+
+	if len(cascade.RevokedGrants) != 1 || cascade.RevokedGrants[0] != grant.UID {
+		t.Errorf("RevokedGrants = %v, want [%s]", cascade.RevokedGrants, grant.UID)
+	}
+
+	if _, err := store.GetActiveGrant(ctx, user.UID, db.UID); !errors.Is(err, ErrNoActiveGrant) {
+		t.Errorf("GetActiveGrant() error = %v, want %v", err, ErrNoActiveGrant)
+	}
+
+	got, err := store.GetGrantRequest(ctx, req.UID)
+	if err != nil {
+		t.Fatalf("GetGrantRequest() error = %v", err)
+	}
+
+	if got.Status != GrantRequestCancelled {
+		t.Errorf("status = %q, want %q", got.Status, GrantRequestCancelled)
+	}
+}
+
+func TestDeleteServerInUseAsTunnel(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+	key := testEncryptionKey()
+
+	bastion := makeSSHServer(t, s, key, "bastion-in-use", "pk")
+
+	target, err := s.CreateServer(ctx, &Server{
+		Name: "tunneled-in-use", Host: "10.0.0.5", Port: 5432, DatabaseName: "app",
+		Username: "u", Password: "p", Protocol: ProtocolPostgreSQL,
+		ViaUID: &bastion.UID,
+	}, key)
+	if err != nil {
+		t.Fatalf("CreateServer(via ssh) error = %v", err)
+	}
+
+	if _, err := s.DeleteServer(ctx, bastion.UID, uuid.Nil); !errors.Is(err, ErrServerInUse) {
+		t.Fatalf("DeleteServer(bastion) error = %v, want %v", err, ErrServerInUse)
+	}
+
+	// Once nothing tunnels through it any more, the bastion can go.
+	if _, err := s.DeleteServer(ctx, target.UID, uuid.Nil); err != nil {
+		t.Fatalf("DeleteServer(target) error = %v", err)
+	}
+
+	if _, err := s.DeleteServer(ctx, bastion.UID, uuid.Nil); err != nil {
+		t.Errorf("DeleteServer(bastion) error = %v", err)
+	}
+}
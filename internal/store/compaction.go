@@ -0,0 +1,177 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// CompactionResult reports what a CompactQueryRows pass did.
+type CompactionResult struct {
+	Queries int `json:"queries"`
+	Rows    int `json:"rows"`
+	// RawBytes is the summed row_size_bytes of the compacted rows,
+	// CompressedBytes the size of the blobs that replaced them.
+	RawBytes        int64 `json:"raw_bytes"`
+	CompressedBytes int64 `json:"compressed_bytes"`
+}
+
+// CompactQueryRows folds the query_rows of up to limit queries executed before
+// the cutoff into one compressed blob per query, oldest first. Each query is
+// compacted in its own transaction, so an interrupted pass loses nothing.
+// Callers loop until a pass compacts no query.
+func (s *Store) CompactQueryRows(ctx context.Context, before time.Time, limit int) (*CompactionResult, error) {
+	var queryUIDs []uuid.UUID
+
+	if err := s.db.NewSelect().
+		Model((*Query)(nil)).
+		Column("q.uid").
+		Where("q.executed_at < ?", before).
+		Where("EXISTS (SELECT 1 FROM query_rows qr WHERE qr.query_id = q.uid)").
+		Order("q.executed_at ASC").
+		Limit(limit).
+		Scan(ctx, &queryUIDs); err != nil {
+		return nil, fmt.Errorf("failed to list queries to compact: %w", err)
+	}
+
+	result := &CompactionResult{}
+
+	for _, queryUID := range queryUIDs {
+		if err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			return compactQuery(ctx, tx, queryUID, result)
+		}); err != nil {
+			return result, fmt.Errorf("failed to compact query %s: %w", queryUID, err)
+		}
+	}
+
+	return result, nil
+}
+
+// compactQuery moves the query_rows of one query into its blob, merging with
+// a blob left by an earlier pass if rows were stored after it.
+func compactQuery(ctx context.Context, tx bun.Tx, queryUID uuid.UUID, result *CompactionResult) error {
+	var models []QueryRowModel
+	if err := tx.NewSelect().
+		Model(&models).
+		Where("query_id = ?", queryUID).
+		Order("row_number ASC").
+		For("UPDATE").
+		Scan(ctx); err != nil {
+		return fmt.Errorf("failed to get query rows: %w", err)
+	}
+
+	if len(models) == 0 {
+		return nil // compacted concurrently
+	}
+
+	rows, err := loadRowBlob(ctx, tx, queryUID)
+	if err != nil {
+		return err
+	}
+
+	var rawBytes int64
+	for i := range models {
+		row, err := models[i].toRow()
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, row)
+		rawBytes += row.RowSizeBytes
+	}
+
+	data, err := encodeRowBlob(rows)
+	if err != nil {
+		return err
+	}
+
+	blob := &QueryRowBlob{QueryID: queryUID, RowCount: len(rows), Data: data, CompactedAt: time.Now()}
+	for _, row := range rows {
+		blob.RawBytes += row.RowSizeBytes
+	}
+
+	if _, err := tx.NewInsert().
+		Model(blob).
+		On("CONFLICT (query_id) DO UPDATE").
+		Set("row_count = EXCLUDED.row_count").
+		Set("raw_bytes = EXCLUDED.raw_bytes").
+		Set("data = EXCLUDED.data").
+		Set("compacted_at = EXCLUDED.compacted_at").
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store row blob: %w", err)
+	}
+
+	if _, err := tx.NewDelete().
+		Model((*QueryRowModel)(nil)).
+		Where("query_id = ?", queryUID).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete compacted rows: %w", err)
+	}
+
+	result.Queries++
+	result.Rows += len(models)
+	result.RawBytes += rawBytes
+	result.CompressedBytes += int64(len(data))
+
+	return nil
+}
+
+// loadRowBlob returns the compacted rows of a query, or nil if it has none.
+func loadRowBlob(ctx context.Context, db bun.IDB, queryUID uuid.UUID) ([]QueryRow, error) {
+	blob := &QueryRowBlob{}
+	if err := db.NewSelect().
+		Model(blob).
+		Where("query_id = ?", queryUID).
+		Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to get row blob: %w", err)
+	}
+
+	return decodeRowBlob(blob.Data)
+}
+
+func encodeRowBlob(rows []QueryRow) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(rows); err != nil {
+		return nil, fmt.Errorf("failed to encode row blob: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress row blob: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeRowBlob(data []byte) ([]QueryRow, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress row blob: %w", err)
+	}
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress row blob: %w", err)
+	}
+
+	var rows []QueryRow
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode row blob: %w", err)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRowBlobRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	rows := []QueryRow{
+		{RowNumber: 1, RowData: json.RawMessage(`{"id":1,"name":"Alice"}`), RowSizeBytes: 23},
+		{RowNumber: 2, RowData: json.RawMessage(`{"id":2,"name":null}`), RowSizeBytes: 20},
+	}
+
+	data, err := encodeRowBlob(rows)
+	if err != nil {
+		t.Fatalf("encodeRowBlob() error = %v", err)
+	}
+
+	got, err := decodeRowBlob(data)
+	if err != nil {
+		t.Fatalf("decodeRowBlob() error = %v", err)
+	}
+
+	if len(got) != len(rows) {
+		t.Fatalf("decodeRowBlob() returned %d rows, want %d", len(got), len(rows))
+	}
+
+	for i := range rows {
+		if got[i].RowNumber != rows[i].RowNumber || string(got[i].RowData) != string(rows[i].RowData) ||
+			got[i].RowSizeBytes != rows[i].RowSizeBytes {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], rows[i])
+		}
+	}
+
+	if _, err := decodeRowBlob([]byte("not gzip")); err == nil {
+		t.Error("decodeRowBlob() should fail on garbage")
+	}
+}
+
+func TestCompactQueryRows(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "compact")
+	now := time.Now()
+
+	createWithRows := func(executedAt time.Time, count int) *Query {
+		t.Helper()
+
+		query, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: "SELECT * FROM t", ExecutedAt: executedAt})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+
+		rows := make([]QueryRow, count)
+		for i := range rows {
+			data := fmt.Sprintf(`{"id": %d, "name": "row %d"}`, i, i)
+			rows[i] = QueryRow{RowNumber: i, RowData: json.RawMessage(data), RowSizeBytes: int64(len(data))}
+		}
+
+		if err := store.StoreQueryRows(ctx, query.UID, rows); err != nil {
+			t.Fatalf("StoreQueryRows() error = %v", err)
+		}
+
+		return query
+	}
+
+	old := createWithRows(now.Add(-60*24*time.Hour), 150)
+	recent := createWithRows(now.Add(-time.Hour), 3)
+
+	before, err := store.GetQueryRows(ctx, old.UID, "", 100)
+	if err != nil {
+		t.Fatalf("GetQueryRows() error = %v", err)
+	}
+
+	result, err := store.CompactQueryRows(ctx, now.Add(-30*24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("CompactQueryRows() error = %v", err)
+	}
+
+	if result.Queries != 1 || result.Rows != 150 {
+		t.Errorf("CompactQueryRows() = %+v, want 1 query and 150 rows", result)
+	}
+
+	if result.CompressedBytes <= 0 || result.CompressedBytes >= result.RawBytes {
+		t.Errorf("CompressedBytes = %d, want in (0, %d)", result.CompressedBytes, result.RawBytes)
+	}
+
+	again, err := store.CompactQueryRows(ctx, now.Add(-30*24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("CompactQueryRows() second pass error = %v", err)
+	}
+
+	if again.Queries != 0 {
+		t.Errorf("second pass compacted %d queries, want 0", again.Queries)
+	}
+
+	t.Run("compacted rows paginate like stored ones", func(t *testing.T) {
+		first, err := store.GetQueryRows(ctx, old.UID, "", 100)
+		if err != nil {
+			t.Fatalf("GetQueryRows() error = %v", err)
+		}
+
+		if first.TotalRows != 150 || len(first.Rows) != 100 || !first.HasMore {
+			t.Fatalf("first page: total=%d rows=%d has_more=%v", first.TotalRows, len(first.Rows), first.HasMore)
+		}
+
+		if first.NextCursor != before.NextCursor {
+			t.Errorf("NextCursor = %q, want %q", first.NextCursor, before.NextCursor)
+		}
+
+		second, err := store.GetQueryRows(ctx, old.UID, first.NextCursor, 100)
+		if err != nil {
+			t.Fatalf("GetQueryRows(next) error = %v", err)
+		}
+
+		if len(second.Rows) != 50 || second.HasMore || second.Rows[0].RowNumber != 100 {
+			t.Errorf("second page: rows=%d has_more=%v first=%d", len(second.Rows), second.HasMore, second.Rows[0].RowNumber)
+		}
+	})
+
+	t.Run("GetQueryWithRows reads the blob", func(t *testing.T) {
+		got, err := store.GetQueryWithRows(ctx, old.UID)
+		if err != nil {
+			t.Fatalf("GetQueryWithRows() error = %v", err)
+		}
+
+		if len(got.Rows) != 150 {
+			t.Errorf("GetQueryWithRows() returned %d rows, want 150", len(got.Rows))
+		}
+	})
+
+	t.Run("recent query untouched", func(t *testing.T) {
+		count, err := store.db.NewSelect().Model((*QueryRowModel)(nil)).Where("query_id = ?", recent.UID).Count(ctx)
+		if err != nil {
+			t.Fatalf("count error = %v", err)
+		}
+
+		if count != 3 {
+			t.Errorf("recent query has %d query_rows, want 3", count)
+		}
+	})
+}
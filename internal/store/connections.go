@@ -5,23 +5,56 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Client info bounds: clients choose these values, so cap what they can make
+// us store per connection.
+const (
+	maxClientInfoValueLen   = 256
+	maxClientInfoParameters = 32
 )
 
 // CreateConnection creates a new connection record
 func (s *Store) CreateConnection(ctx context.Context, userID, databaseID uuid.UUID, sourceIP string) (*Connection, error) {
-	conn := &Connection{
-		UID:              newUIDv7(), // Generate UUIDv7 for time-ordered inserts
-		UserID:           userID,
-		DatabaseID:       databaseID,
-		SourceIP:         sourceIP,
-		ConnectedAt:      time.Now(),
-		LastActivityAt:   time.Now(),
-		Queries:          0,
-		BytesTransferred: 0,
+	return s.insertConnection(ctx, &Connection{UserID: userID, DatabaseID: databaseID, SourceIP: sourceIP})
+}
+
+// CreateGrantConnection creates the record of a connection authorized by
+// grant, carrying what the client declared about itself. Values are
+// truncated and the number of extra parameters capped; a nil or empty info
+// stores NULL.
+func (s *Store) CreateGrantConnection(
+	ctx context.Context, grant *Grant, sourceIP string, info *ClientInfo,
+) (*Connection, error) {
+	grantID := grant.UID
+
+	return s.insertConnection(ctx, &Connection{
+		UserID:      grant.UserID,
+		DatabaseID:  grant.DatabaseID,
+		GrantID:     &grantID,
+		AccessLevel: grant.AccessLevel(),
+		SourceIP:    sourceIP,
+		ClientInfo:  boundClientInfo(info),
+	})
+}
+
+// insertConnection inserts a new connection record.
+func (s *Store) insertConnection(ctx context.Context, conn *Connection) (*Connection, error) {
+	conn.UID = newUIDv7() // Generate UUIDv7 for time-ordered inserts
+
+	if s.instanceID != uuid.Nil {
+		instanceID := s.instanceID
+		conn.InstanceID = &instanceID
 	}
+	conn.ConnectedAt = time.Now()
+	conn.LastActivityAt = conn.ConnectedAt
 
 	_, err := s.db.NewInsert().
 		Model(conn).
@@ -33,14 +66,66 @@ func (s *Store) CreateConnection(ctx context.Context, userID, databaseID uuid.UU
 	return conn, nil
 }
 
-// CloseConnection sets the disconnected_at timestamp
-func (s *Store) CloseConnection(ctx context.Context, uid uuid.UUID) error {
+// boundClientInfo returns a copy of info with every value truncated to
+// maxClientInfoValueLen and at most maxClientInfoParameters parameters (in
+// key order), or nil when there is nothing to store.
+func boundClientInfo(info *ClientInfo) *ClientInfo {
+	if info == nil {
+		return nil
+	}
+
+	bounded := &ClientInfo{
+		ApplicationName: truncateClientValue(info.ApplicationName),
+		Driver:          truncateClientValue(info.Driver),
+		DriverVersion:   truncateClientValue(info.DriverVersion),
+		ClientEncoding:  truncateClientValue(info.ClientEncoding),
+		ProtocolVersion: truncateClientValue(info.ProtocolVersion),
+	}
+
+	keys := slices.Sorted(maps.Keys(info.Parameters))
+	for _, key := range keys[:min(len(keys), maxClientInfoParameters)] {
+		if bounded.Parameters == nil {
+			bounded.Parameters = make(map[string]string)
+		}
+
+		bounded.Parameters[truncateClientValue(key)] = truncateClientValue(info.Parameters[key])
+	}
+
+	if bounded.ApplicationName == "" && bounded.Driver == "" && bounded.DriverVersion == "" &&
+		bounded.ClientEncoding == "" && bounded.ProtocolVersion == "" && bounded.Parameters == nil {
+		return nil
+	}
+
+	return bounded
+}
+
+// truncateClientValue cuts a client-declared value to maxClientInfoValueLen
+// bytes without splitting a UTF-8 sequence.
+func truncateClientValue(value string) string {
+	if len(value) <= maxClientInfoValueLen {
+		return value
+	}
+
+	value = value[:maxClientInfoValueLen]
+	for !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+
+	return value
+}
+
+// CloseConnection sets the disconnected_at timestamp and the reason the
+// connection ended (one of the DisconnectReason* values, or "" if unknown).
+func (s *Store) CloseConnection(ctx context.Context, uid uuid.UUID, reason string) error {
+	s.queryDedup.forget(uid)
+
 	now := time.Now()
 	result, err := s.db.NewUpdate().
 		Model((*Connection)(nil)).
 		Where("uid = ?", uid).
 		Where("disconnected_at IS NULL").
 		Set("disconnected_at = ?", now).
+		Set("disconnect_reason = NULLIF(?, '')", reason).
 		Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to close connection: %w", err)
@@ -71,13 +156,16 @@ func (s *Store) UpdateConnectionActivity(ctx context.Context, uid uuid.UUID) err
 	return nil
 }
 
-// IncrementConnectionStats increments the query count by 1 and adds bytes to bytes_transferred
-func (s *Store) IncrementConnectionStats(ctx context.Context, uid uuid.UUID, bytes int64) error {
+// IncrementConnectionStats increments the query count by 1, adds bytes to
+// bytes_transferred and rows to rows_returned. Only the PostgreSQL proxy counts
+// returned rows; the others pass 0.
+func (s *Store) IncrementConnectionStats(ctx context.Context, uid uuid.UUID, bytes, rows int64) error {
 	_, err := s.db.NewUpdate().
 		Model((*Connection)(nil)).
 		Where("uid = ?", uid).
 		Set("queries = queries + 1").
 		Set("bytes_transferred = bytes_transferred + ?", bytes).
+		Set("rows_returned = rows_returned + ?", rows).
 		Set("last_activity_at = ?", time.Now()).
 		Exec(ctx)
 	if err != nil {
@@ -111,7 +199,7 @@ func (s *Store) GetConnectionByUID(ctx context.Context, uid uuid.UUID) (*Connect
 	conn := &Connection{}
 	err := s.db.NewSelect().
 		Model(conn).
-		ColumnExpr("uid, user_id, database_id, source_ip::text, connected_at, last_activity_at, disconnected_at, queries, bytes_transferred").
+		ColumnExpr("uid, user_id, database_id, source_ip::text, connected_at, last_activity_at, disconnected_at, queries, bytes_transferred, rows_returned, client_info, instance_id").
 		Where("uid = ?", uid).
 		Scan(ctx)
 	if err != nil {
@@ -123,12 +211,42 @@ func (s *Store) GetConnectionByUID(ctx context.Context, uid uuid.UUID) (*Connect
 	return conn, nil
 }
 
-// ListConnections retrieves connections with optional filters
+// ListConnections retrieves connections with optional filters.
+// Served by the storage replica when one is configured and healthy.
 func (s *Store) ListConnections(ctx context.Context, filter ConnectionFilter) ([]Connection, error) {
 	var connections []Connection
+
+	err := s.scanReadOnly(ctx, s.selectConnections(&connections, filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list connections: %w", err)
+	}
+
+	if connections == nil {
+		connections = []Connection{}
+	}
+	return connections, nil
+}
+
+// CountConnections returns the number of connections matching the filter,
+// its BeforeUID cursor included, regardless of its Limit and Offset.
+// Served by the storage replica when one is configured and healthy.
+func (s *Store) CountConnections(ctx context.Context, filter ConnectionFilter) (int, error) {
+	var connections []Connection
+
+	n, err := s.countReadOnly(ctx, s.selectConnections(&connections, filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count connections: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectConnections builds the select of the connections matching filter
+// into dest, newest first.
+func (s *Store) selectConnections(dest *[]Connection, filter ConnectionFilter) *bun.SelectQuery {
 	q := s.db.NewSelect().
-		Model(&connections).
-		ColumnExpr("uid, user_id, database_id, source_ip::text, connected_at, last_activity_at, disconnected_at, queries, bytes_transferred")
+		Model(dest).
+		ColumnExpr("uid, user_id, database_id, source_ip::text, connected_at, last_activity_at, disconnected_at, queries, bytes_transferred, rows_returned, client_info, instance_id")
 
 	if filter.UserID != nil {
 		q = q.Where("user_id = ?", *filter.UserID)
@@ -138,27 +256,17 @@ func (s *Store) ListConnections(ctx context.Context, filter ConnectionFilter) ([
 		q = q.Where("database_id = ?", *filter.DatabaseID)
 	}
 
-	if filter.BeforeUID != nil {
-		q = q.Where("uid < ?", *filter.BeforeUID)
+	if filter.ApplicationName != nil {
+		q = q.Where("client_info->>'application_name' = ?", *filter.ApplicationName)
 	}
 
-	q = q.Order("uid DESC")
-
-	if filter.Limit > 0 {
-		q = q.Limit(filter.Limit)
+	if filter.InstanceID != nil {
+		q = q.Where("instance_id = ?", *filter.InstanceID)
 	}
 
-	if filter.Offset > 0 {
-		q = q.Offset(filter.Offset)
-	}
-
-	err := q.Scan(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list connections: %w", err)
+	if filter.BeforeUID != nil {
+		q = q.Where("uid < ?", *filter.BeforeUID)
 	}
 
-	if connections == nil {
-		connections = []Connection{}
-	}
-	return connections, nil
+	return paginate(q.Order("uid DESC"), filter.Limit, filter.Offset)
 }
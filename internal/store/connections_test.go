@@ -6,6 +6,8 @@ import (
 	"net"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -54,6 +56,79 @@ func TestCreateConnection(t *testing.T) {
 			t.Errorf("CreateConnection() conn.SourceIP = %q, want prefix %q", conn.SourceIP, "::1")
 		}
 	})
+
+	t.Run("create connection with client info", func(t *testing.T) {
+		grant, err := store.CreateGrant(ctx, &Grant{
+			UserID:     user.UID,
+			DatabaseID: database.UID,
+			Controls:   []string{ControlReadOnly},
+			GrantedBy:  user.UID,
+			StartsAt:   time.Now(),
+			ExpiresAt:  time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("CreateGrant() error = %v", err)
+		}
+
+		appName := "PostgreSQL JDBC Driver"
+		conn, err := store.CreateGrantConnection(ctx, grant, "10.0.0.9", &ClientInfo{
+			ApplicationName: appName,
+			ClientEncoding:  "UTF8",
+		})
+		if err != nil {
+			t.Fatalf("CreateGrantConnection() error = %v", err)
+		}
+
+		if conn.UserID != user.UID || conn.DatabaseID != database.UID {
+			t.Errorf("CreateGrantConnection() user/database = %s/%s, want %s/%s", conn.UserID, conn.DatabaseID, user.UID, database.UID)
+		}
+		if conn.GrantID == nil || *conn.GrantID != grant.UID || conn.AccessLevel != AccessLevelReadOnly {
+			t.Errorf("CreateGrantConnection() grant = %v/%q, want %s/%q", conn.GrantID, conn.AccessLevel, grant.UID, AccessLevelReadOnly)
+		}
+
+		conns, err := store.ListConnections(ctx, ConnectionFilter{ApplicationName: &appName})
+		if err != nil {
+			t.Fatalf("ListConnections() error = %v", err)
+		}
+
+		if len(conns) != 1 || conns[0].UID != conn.UID {
+			t.Fatalf("ListConnections(application_name) = %+v, want the JDBC connection", conns)
+		}
+
+		if conns[0].ClientInfo == nil || conns[0].ClientInfo.ClientEncoding != "UTF8" {
+			t.Errorf("ListConnections() client info = %+v", conns[0].ClientInfo)
+		}
+	})
+}
+
+func TestBoundClientInfo(t *testing.T) {
+	t.Parallel()
+
+	if got := boundClientInfo(&ClientInfo{}); got != nil {
+		t.Errorf("boundClientInfo(empty) = %+v, want nil", got)
+	}
+
+	params := make(map[string]string)
+	for i := range maxClientInfoParameters + 10 {
+		params[strings.Repeat("k", i+1)] = "v"
+	}
+
+	got := boundClientInfo(&ClientInfo{
+		ApplicationName: strings.Repeat("é", maxClientInfoValueLen),
+		Parameters:      params,
+	})
+
+	if len(got.ApplicationName) > maxClientInfoValueLen || !utf8.ValidString(got.ApplicationName) {
+		t.Errorf("ApplicationName not truncated cleanly: %d bytes", len(got.ApplicationName))
+	}
+
+	if len(got.Parameters) != maxClientInfoParameters {
+		t.Errorf("len(Parameters) = %d, want %d", len(got.Parameters), maxClientInfoParameters)
+	}
+
+	if got := boundClientInfo(&ClientInfo{ProtocolVersion: "3.0"}); got == nil || got.ProtocolVersion != "3.0" {
+		t.Errorf("boundClientInfo(protocol only) = %+v, want the protocol version kept", got)
+	}
 }
 
 func TestCloseConnection(t *testing.T) {
@@ -68,7 +143,7 @@ func TestCloseConnection(t *testing.T) {
 	}
 
 	t.Run("close open connection", func(t *testing.T) {
-		err := store.CloseConnection(ctx, conn.UID)
+		err := store.CloseConnection(ctx, conn.UID, DisconnectReasonClientClosed)
 		if err != nil {
 			t.Fatalf("CloseConnection() error = %v", err)
 		}
@@ -86,6 +161,9 @@ func TestCloseConnection(t *testing.T) {
 				if c.DisconnectedAt == nil {
 					t.Error("conn.DisconnectedAt should not be nil after close")
 				}
+				if c.DisconnectReason != DisconnectReasonClientClosed {
+					t.Errorf("conn.DisconnectReason = %q, want %q", c.DisconnectReason, DisconnectReasonClientClosed)
+				}
 				break
 			}
 		}
@@ -95,14 +173,14 @@ func TestCloseConnection(t *testing.T) {
 	})
 
 	t.Run("close already closed connection", func(t *testing.T) {
-		err := store.CloseConnection(ctx, conn.UID)
+		err := store.CloseConnection(ctx, conn.UID, "")
 		if !errors.Is(err, ErrConnectionNotFound) {
 			t.Errorf("CloseConnection() error = %v, want %v", err, ErrConnectionNotFound)
 		}
 	})
 
 	t.Run("close non-existing connection", func(t *testing.T) {
-		err := store.CloseConnection(ctx, uuid.New())
+		err := store.CloseConnection(ctx, uuid.New(), "")
 		if !errors.Is(err, ErrConnectionNotFound) {
 			t.Errorf("CloseConnection() error = %v, want %v", err, ErrConnectionNotFound)
 		}
@@ -224,6 +302,16 @@ func TestListConnections(t *testing.T) {
 			t.Errorf("ListConnections() len = %d, want 1", len(conns))
 		}
 	})
+
+	t.Run("count ignores limit and offset", func(t *testing.T) {
+		n, err := store.CountConnections(ctx, ConnectionFilter{UserID: &user1.UID, Limit: 1, Offset: 1})
+		if err != nil {
+			t.Fatalf("CountConnections() error = %v", err)
+		}
+		if n != 2 {
+			t.Errorf("CountConnections() = %d, want 2", n)
+		}
+	})
 }
 
 func TestIncrementConnectionStats(t *testing.T) {
@@ -246,7 +334,7 @@ func TestIncrementConnectionStats(t *testing.T) {
 	}
 
 	t.Run("increment stats", func(t *testing.T) {
-		err := store.IncrementConnectionStats(ctx, conn.UID, 1024)
+		err := store.IncrementConnectionStats(ctx, conn.UID, 1024, 10)
 		if err != nil {
 			t.Fatalf("IncrementConnectionStats() error = %v", err)
 		}
@@ -274,14 +362,17 @@ func TestIncrementConnectionStats(t *testing.T) {
 		if found.BytesTransferred != 1024 {
 			t.Errorf("conn.BytesTransferred = %d, want 1024", found.BytesTransferred)
 		}
+		if found.RowsReturned != 10 {
+			t.Errorf("conn.RowsReturned = %d, want 10", found.RowsReturned)
+		}
 	})
 
 	t.Run("multiple increments accumulate", func(t *testing.T) {
-		err := store.IncrementConnectionStats(ctx, conn.UID, 2048)
+		err := store.IncrementConnectionStats(ctx, conn.UID, 2048, 0)
 		if err != nil {
 			t.Fatalf("IncrementConnectionStats() error = %v", err)
 		}
-		err = store.IncrementConnectionStats(ctx, conn.UID, 512)
+		err = store.IncrementConnectionStats(ctx, conn.UID, 512, 0)
 		if err != nil {
 			t.Fatalf("IncrementConnectionStats() error = %v", err)
 		}
@@ -354,7 +445,7 @@ func TestIncrementConnectionBytes(t *testing.T) {
 
 	t.Run("accumulates and coexists with IncrementConnectionStats", func(t *testing.T) {
 		// A completed query bumps both counters...
-		if err := store.IncrementConnectionStats(ctx, conn.UID, 1000); err != nil {
+		if err := store.IncrementConnectionStats(ctx, conn.UID, 1000, 0); err != nil {
 			t.Fatalf("IncrementConnectionStats() error = %v", err)
 		}
 		// ...then an aborted-query byte flush adds bytes only.
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidControl is returned when a grant control is unknown, malformed or
+// duplicated.
+var ErrInvalidControl = errors.New("invalid control")
+
+// controlSpec describes one entry of the control vocabulary.
+type controlSpec struct {
+	// parameterized controls are written name:N with N a positive integer.
+	parameterized bool
+	// protocols lists the protocols whose proxy enforces the control; nil
+	// means every protocol.
+	protocols []string
+}
+
+// controlSpecs is the control vocabulary. A control is only added here once
+// every protocol it lists actually enforces it: proxies refuse sessions whose
+// grant carries a control they cannot honor.
+var controlSpecs = map[string]controlSpec{
+	ControlReadOnly:         {},
+	ControlBlockCopy:        {},
+	ControlBlockDDL:         {},
+	ControlAllowReplication: {},
+	ControlBlockCopyOut:     {protocols: []string{ProtocolPostgreSQL, ProtocolMySQL, ProtocolMariaDB}},
+	ControlMaskPII:          {protocols: []string{ProtocolPostgreSQL}},
+	ControlMaxRows: {
+		parameterized: true,
+		protocols:     []string{ProtocolPostgreSQL, ProtocolMySQL, ProtocolMariaDB},
+	},
+	ControlMaxBytesPerSecond: {
+		parameterized: true,
+		protocols:     []string{ProtocolPostgreSQL},
+	},
+	ControlDryRun:                {protocols: []string{ProtocolPostgreSQL}},
+	ControlWriteRequiresApproval: {protocols: []string{ProtocolPostgreSQL}},
+}
+
+// controlAliases maps alternate spellings to their canonical control.
+var controlAliases = map[string]string{
+	"no_ddl":      ControlBlockDDL,
+	"no_copy":     ControlBlockCopy,
+	"no_copy_out": ControlBlockCopyOut,
+}
+
+// ParseControl splits a control into its canonical name and its parameter
+// (0 for controls that take none), resolving aliases.
+func ParseControl(control string) (string, int64, error) {
+	name, param, hasParam := strings.Cut(strings.TrimSpace(control), ":")
+	if canonical, ok := controlAliases[name]; ok {
+		name = canonical
+	}
+
+	spec, ok := controlSpecs[name]
+	if !ok {
+		return "", 0, fmt.Errorf("%w: %q", ErrInvalidControl, control)
+	}
+
+	if !spec.parameterized {
+		if hasParam {
+			return "", 0, fmt.Errorf("%w: %q takes no value", ErrInvalidControl, name)
+		}
+
+		return name, 0, nil
+	}
+
+	value, err := strconv.ParseInt(param, 10, 64)
+	if !hasParam || err != nil || value <= 0 {
+		return "", 0, fmt.Errorf("%w: %q must be written %s:N with N a positive integer", ErrInvalidControl, control, name)
+	}
+
+	return name, value, nil
+}
+
+// NormalizeControls validates controls and returns them in canonical form
+// (aliases resolved, parameters re-formatted). Each control may appear once.
+func NormalizeControls(controls []string) ([]string, error) {
+	if controls == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(controls))
+	seen := make(map[string]bool, len(controls))
+
+	for _, control := range controls {
+		name, value, err := ParseControl(control)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %q given more than once", ErrInvalidControl, name)
+		}
+
+		seen[name] = true
+
+		if controlSpecs[name].parameterized {
+			name += ":" + strconv.FormatInt(value, 10)
+		}
+
+		normalized = append(normalized, name)
+	}
+
+	return normalized, nil
+}
+
+// UnsupportedControls returns the controls the proxy of protocol cannot
+// enforce. Unknown controls are always reported.
+func UnsupportedControls(controls []string, protocol string) []string {
+	var unsupported []string
+
+	for _, control := range controls {
+		name, _, err := ParseControl(control)
+		if err != nil {
+			unsupported = append(unsupported, control)
+
+			continue
+		}
+
+		if protocols := controlSpecs[name].protocols; protocols != nil && !slices.Contains(protocols, protocol) {
+			unsupported = append(unsupported, control)
+		}
+	}
+
+	return unsupported
+}
+
+// rowsReturnedProtocols lists the protocols whose proxy counts the rows it
+// returns against max_rows_returned.
+var rowsReturnedProtocols = []string{ProtocolPostgreSQL}
+
+// SupportsMaxRowsReturned reports whether the proxy of protocol enforces
+// max_rows_returned.
+func SupportsMaxRowsReturned(protocol string) bool {
+	return slices.Contains(rowsReturnedProtocols, protocol)
+}
+
+// controlValue returns the parameter of a parameterized control of the grant.
+func (g *AccessGrant) controlValue(name string) (int64, bool) {
+	for _, control := range g.Controls {
+		if parsed, value, err := ParseControl(control); err == nil && parsed == name {
+			return value, true
+		}
+	}
+
+	return 0, false
+}
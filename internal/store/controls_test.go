@@ -0,0 +1,131 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeControls(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		controls []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "nil", controls: nil, want: nil},
+		{name: "empty", controls: []string{}, want: []string{}},
+		{name: "canonical", controls: []string{"read_only", "mask_pii"}, want: []string{"read_only", "mask_pii"}},
+		{name: "aliases", controls: []string{"no_ddl", "no_copy_out"}, want: []string{"block_ddl", "block_copy_out"}},
+		{name: "max_rows", controls: []string{"max_rows:0100"}, want: []string{"max_rows:100"}},
+		{name: "unknown", controls: []string{"read_write"}, wantErr: true},
+		{name: "max_rows without value", controls: []string{"max_rows"}, wantErr: true},
+		{name: "max_rows zero", controls: []string{"max_rows:0"}, wantErr: true},
+		{name: "max_rows not a number", controls: []string{"max_rows:ten"}, wantErr: true},
+		{name: "value on a flag", controls: []string{"read_only:1"}, wantErr: true},
+		{name: "duplicate through alias", controls: []string{"block_ddl", "no_ddl"}, wantErr: true},
+		{name: "duplicate max_rows", controls: []string{"max_rows:1", "max_rows:2"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NormalizeControls(tt.controls)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeControls() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if !errors.Is(err, ErrInvalidControl) {
+					t.Errorf("NormalizeControls() error = %v, want %v", err, ErrInvalidControl)
+				}
+
+				return
+			}
+
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("NormalizeControls() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedControls(t *testing.T) {
+	t.Parallel()
+
+	controls := []string{"read_only", "mask_pii", "max_rows:10", "block_copy_out", "max_bytes_per_second:1024", "dry_run", "write_requires_approval"}
+
+	tests := []struct {
+		protocol string
+		want     []string
+	}{
+		{ProtocolPostgreSQL, nil},
+		{ProtocolMySQL, []string{"mask_pii", "max_bytes_per_second:1024", "dry_run", "write_requires_approval"}},
+		{ProtocolMongoDB, []string{"mask_pii", "max_rows:10", "block_copy_out", "max_bytes_per_second:1024", "dry_run", "write_requires_approval"}},
+	}
+
+	for _, tt := range tests {
+		if got := UnsupportedControls(controls, tt.protocol); !slices.Equal(got, tt.want) {
+			t.Errorf("UnsupportedControls(%s) = %q, want %q", tt.protocol, got, tt.want)
+		}
+	}
+}
+
+func TestSupportsMaxRowsReturned(t *testing.T) {
+	t.Parallel()
+
+	if !SupportsMaxRowsReturned(ProtocolPostgreSQL) {
+		t.Error("SupportsMaxRowsReturned(postgresql) = false")
+	}
+
+	if SupportsMaxRowsReturned(ProtocolMySQL) {
+		t.Error("SupportsMaxRowsReturned(mysql) = true")
+	}
+}
+
+func TestGrantQuotaRemaining(t *testing.T) {
+	t.Parallel()
+
+	maxQueries, maxRows := int64(10), int64(100)
+
+	grant := &AccessGrant{MaxQueryCounts: &maxQueries, MaxRowsReturned: &maxRows, QueryCount: 12, RowsReturned: 40}
+	grant.fillQuotaRemaining()
+
+	remaining := grant.QuotaRemaining
+	if remaining.Queries == nil || *remaining.Queries != 0 {
+		t.Errorf("remaining queries = %v, want 0", remaining.Queries)
+	}
+
+	if remaining.RowsReturned == nil || *remaining.RowsReturned != 60 {
+		t.Errorf("remaining rows = %v, want 60", remaining.RowsReturned)
+	}
+
+	if remaining.BytesTransferred != nil {
+		t.Errorf("remaining bytes = %d, want no quota", *remaining.BytesTransferred)
+	}
+}
+
+func TestGrantControlAccessors(t *testing.T) {
+	t.Parallel()
+
+	grant := &AccessGrant{Controls: []string{"no_ddl", "mask_pii", "max_rows:500", "max_bytes_per_second:65536"}}
+
+	if !grant.ShouldBlockDDL() || !grant.MasksPII() || grant.ShouldBlockCopyOut() || grant.DryRun() || grant.WriteRequiresApproval() {
+		t.Errorf("unexpected accessors for %q", grant.Controls)
+	}
+
+	if rows, ok := grant.MaxRows(); !ok || rows != 500 {
+		t.Errorf("MaxRows() = %d, %v, want 500, true", rows, ok)
+	}
+
+	if rate, ok := grant.MaxBytesPerSecond(); !ok || rate != 65536 {
+		t.Errorf("MaxBytesPerSecond() = %d, %v, want 65536, true", rate, ok)
+	}
+
+	if _, ok := (&AccessGrant{}).MaxRows(); ok {
+		t.Error("MaxRows() of a grant without max_rows reported a limit")
+	}
+}
@@ -25,13 +25,22 @@ var (
 	ErrServerViaNotSSH = errors.New("via_uid must reference an ssh server")
 	// ErrServerViaCycle is returned when a via_uid chain loops back on itself.
 	ErrServerViaCycle = errors.New("via_uid chain forms a cycle")
+	// ErrServerInUse is returned when deleting an SSH server that other
+	// servers still tunnel through (via_uid).
+	ErrServerInUse = errors.New("server is used as a tunnel by other servers")
 	// ErrServerNameConflict is returned when creating or renaming a server to a
 	// name that is already taken (violates the servers_name_key unique constraint).
 	ErrServerNameConflict = errors.New("a server with this name already exists")
+	// ErrInvalidLabels is returned when labels exceed the limits or carry a
+	// malformed key.
+	ErrInvalidLabels = errors.New("invalid labels")
 	// ErrUserNameConflict is returned when creating a user whose username is
 	// already taken by an active (non-soft-deleted) user (violates the
 	// users_username_active_uq unique index).
 	ErrUserNameConflict = errors.New("a user with this username already exists")
+	// ErrQueryPlanNotFound is returned when neither a query nor a statement
+	// of the same fingerprint had its plan captured.
+	ErrQueryPlanNotFound = errors.New("query plan not found")
 )
 
 // isUniqueViolation reports whether err is a PostgreSQL unique-constraint
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Capture modes of a database.
+const (
+	// CaptureModeFull records statements and, when result storage is
+	// enabled, their result rows. It is the default.
+	CaptureModeFull = "full"
+	// CaptureModeQueries records statements but never their result rows.
+	CaptureModeQueries = "queries"
+)
+
+var (
+	// ErrInvalidGrantDefaults is returned when a database's grant defaults
+	// are malformed.
+	ErrInvalidGrantDefaults = errors.New("invalid grant defaults")
+	// ErrGrantExceedsDefaults is returned when a grant is less restricted
+	// than its database's grant defaults allow.
+	ErrGrantExceedsDefaults = errors.New("grant exceeds the database's grant defaults")
+)
+
+// GrantDefaults are a database's settings for the grants on it. They pre-fill
+// the fields a new grant omits and bound every grant: a grant carries at
+// least the default controls (with parameters no higher), lasts no longer
+// than the default duration and has quotas no higher than the default ones.
+// The capture mode applies to every session on the database.
+type GrantDefaults struct {
+	Controls            []string `json:"controls,omitempty"`
+	DurationSeconds     int64    `json:"duration_seconds,omitempty"`
+	MaxQueryCounts      *int64   `json:"max_query_counts,omitempty"`
+	MaxBytesTransferred *int64   `json:"max_bytes_transferred,omitempty"`
+	CaptureMode         string   `json:"capture_mode,omitempty"`
+}
+
+// IsZero reports whether the defaults set nothing.
+func (d *GrantDefaults) IsZero() bool {
+	return d == nil || (len(d.Controls) == 0 && d.DurationSeconds == 0 &&
+		d.MaxQueryCounts == nil && d.MaxBytesTransferred == nil && d.CaptureMode == "")
+}
+
+// Normalize validates the defaults and puts their controls in canonical form.
+func (d *GrantDefaults) Normalize() error {
+	controls, err := NormalizeControls(d.Controls)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidGrantDefaults, err)
+	}
+
+	d.Controls = controls
+
+	switch {
+	case d.DurationSeconds < 0:
+		return fmt.Errorf("%w: duration_seconds must not be negative", ErrInvalidGrantDefaults)
+	case d.MaxQueryCounts != nil && *d.MaxQueryCounts <= 0:
+		return fmt.Errorf("%w: max_query_counts must be positive", ErrInvalidGrantDefaults)
+	case d.MaxBytesTransferred != nil && *d.MaxBytesTransferred <= 0:
+		return fmt.Errorf("%w: max_bytes_transferred must be positive", ErrInvalidGrantDefaults)
+	}
+
+	switch d.CaptureMode {
+	case "", CaptureModeFull, CaptureModeQueries:
+	default:
+		return fmt.Errorf("%w: capture_mode must be %q or %q", ErrInvalidGrantDefaults, CaptureModeFull, CaptureModeQueries)
+	}
+
+	return nil
+}
+
+// Fill sets the fields the grant omits from the defaults: controls when nil,
+// the expiry when zero, and the quotas when nil.
+func (d *GrantDefaults) Fill(grant *Grant) {
+	if d == nil {
+		return
+	}
+
+	if grant.Controls == nil && d.Controls != nil {
+		grant.Controls = append([]string{}, d.Controls...)
+	}
+
+	if grant.ExpiresAt.IsZero() && d.DurationSeconds > 0 {
+		grant.ExpiresAt = grant.StartsAt.Add(time.Duration(d.DurationSeconds) * time.Second)
+	}
+
+	if grant.MaxQueryCounts == nil {
+		grant.MaxQueryCounts = d.MaxQueryCounts
+	}
+
+	if grant.MaxBytesTransferred == nil {
+		grant.MaxBytesTransferred = d.MaxBytesTransferred
+	}
+}
+
+// Check returns ErrGrantExceedsDefaults when the grant is less restricted
+// than the defaults allow.
+func (d *GrantDefaults) Check(grant *Grant) error {
+	if d == nil {
+		return nil
+	}
+
+	for _, control := range d.Controls {
+		name, limit, _ := ParseControl(control)
+
+		value, ok := grant.controlValue(name)
+		switch {
+		case !controlSpecs[name].parameterized && !grant.HasControl(name):
+			return fmt.Errorf("%w: control %s is required", ErrGrantExceedsDefaults, name)
+		case controlSpecs[name].parameterized && (!ok || value > limit):
+			return fmt.Errorf("%w: control %s is required", ErrGrantExceedsDefaults, control)
+		}
+	}
+
+	if maxDuration := time.Duration(d.DurationSeconds) * time.Second; maxDuration > 0 &&
+		grant.ExpiresAt.Sub(grant.StartsAt) > maxDuration {
+		return fmt.Errorf("%w: the grant may last at most %s", ErrGrantExceedsDefaults, maxDuration)
+	}
+
+	if exceedsQuota(grant.MaxQueryCounts, d.MaxQueryCounts) {
+		return fmt.Errorf("%w: max_query_counts may be at most %d", ErrGrantExceedsDefaults, *d.MaxQueryCounts)
+	}
+
+	if exceedsQuota(grant.MaxBytesTransferred, d.MaxBytesTransferred) {
+		return fmt.Errorf("%w: max_bytes_transferred may be at most %d", ErrGrantExceedsDefaults, *d.MaxBytesTransferred)
+	}
+
+	return nil
+}
+
+// Clamp restricts the grant to the defaults: it adds the missing default
+// controls, lowers the parameters, expiry and quotas above them. Used where
+// the grant's shape comes from elsewhere (a grant definition) and rejecting
+// it would serve no one.
+func (d *GrantDefaults) Clamp(grant *Grant) {
+	if d == nil {
+		return
+	}
+
+	for _, control := range d.Controls {
+		name, limit, _ := ParseControl(control)
+		grant.Controls = clampControl(grant.Controls, name, limit)
+	}
+
+	if maxDuration := time.Duration(d.DurationSeconds) * time.Second; maxDuration > 0 &&
+		grant.ExpiresAt.Sub(grant.StartsAt) > maxDuration {
+		grant.ExpiresAt = grant.StartsAt.Add(maxDuration)
+	}
+
+	if exceedsQuota(grant.MaxQueryCounts, d.MaxQueryCounts) {
+		grant.MaxQueryCounts = d.MaxQueryCounts
+	}
+
+	if exceedsQuota(grant.MaxBytesTransferred, d.MaxBytesTransferred) {
+		grant.MaxBytesTransferred = d.MaxBytesTransferred
+	}
+}
+
+// clampControl returns controls carrying control name, with a parameter no
+// higher than limit for parameterized controls.
+func clampControl(controls []string, name string, limit int64) []string {
+	parameterized := controlSpecs[name].parameterized
+
+	for i, control := range controls {
+		parsed, value, err := ParseControl(control)
+		if err != nil || parsed != name {
+			continue
+		}
+
+		if parameterized && value > limit {
+			controls[i] = name + ":" + strconv.FormatInt(limit, 10)
+		}
+
+		return controls
+	}
+
+	if parameterized {
+		name += ":" + strconv.FormatInt(limit, 10)
+	}
+
+	return append(controls, name)
+}
+
+// exceedsQuota reports whether a grant quota is above the default one; an
+// unlimited (nil) quota exceeds any default.
+func exceedsQuota(quota, limit *int64) bool {
+	return limit != nil && (quota == nil || *quota > *limit)
+}
+
+// StoresResults reports whether the result rows of the server's queries may
+// be recorded, per its capture mode.
+func (s *Server) StoresResults() bool {
+	return s == nil || s.GrantDefaults == nil || s.GrantDefaults.CaptureMode != CaptureModeQueries
+}
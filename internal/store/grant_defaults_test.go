@@ -0,0 +1,196 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func ptrInt64(v int64) *int64 { return &v }
+
+func TestGrantDefaults_Normalize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		defaults GrantDefaults
+		wantErr  bool
+	}{
+		{name: "empty", defaults: GrantDefaults{}},
+		{name: "complete", defaults: GrantDefaults{
+			Controls:            []string{"no_ddl", "max_rows:100"},
+			DurationSeconds:     3600,
+			MaxQueryCounts:      ptrInt64(10),
+			MaxBytesTransferred: ptrInt64(1024),
+			CaptureMode:         CaptureModeQueries,
+		}},
+		{name: "unknown control", defaults: GrantDefaults{Controls: []string{"read_write"}}, wantErr: true},
+		{name: "negative duration", defaults: GrantDefaults{DurationSeconds: -1}, wantErr: true},
+		{name: "zero query quota", defaults: GrantDefaults{MaxQueryCounts: ptrInt64(0)}, wantErr: true},
+		{name: "negative byte quota", defaults: GrantDefaults{MaxBytesTransferred: ptrInt64(-1)}, wantErr: true},
+		{name: "unknown capture mode", defaults: GrantDefaults{CaptureMode: "none"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.defaults.Normalize()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidGrantDefaults) {
+				t.Errorf("Normalize() error = %v, want %v", err, ErrInvalidGrantDefaults)
+			}
+		})
+	}
+}
+
+func TestGrantDefaults_Fill(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	defaults := &GrantDefaults{
+		Controls:        []string{ControlReadOnly},
+		DurationSeconds: 3600,
+		MaxQueryCounts:  ptrInt64(100),
+	}
+
+	grant := &Grant{StartsAt: start}
+	defaults.Fill(grant)
+
+	if !slices.Equal(grant.Controls, []string{ControlReadOnly}) {
+		t.Errorf("Controls = %q, want [read_only]", grant.Controls)
+	}
+	if want := start.Add(time.Hour); !grant.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", grant.ExpiresAt, want)
+	}
+	if grant.MaxQueryCounts == nil || *grant.MaxQueryCounts != 100 {
+		t.Errorf("MaxQueryCounts = %v, want 100", grant.MaxQueryCounts)
+	}
+	if grant.MaxBytesTransferred != nil {
+		t.Errorf("MaxBytesTransferred = %v, want nil", *grant.MaxBytesTransferred)
+	}
+
+	// Given fields are kept, an explicit empty control list included.
+	given := &Grant{StartsAt: start, ExpiresAt: start.Add(time.Minute), Controls: []string{}, MaxQueryCounts: ptrInt64(5)}
+	defaults.Fill(given)
+
+	if len(given.Controls) != 0 || !given.ExpiresAt.Equal(start.Add(time.Minute)) || *given.MaxQueryCounts != 5 {
+		t.Errorf("Fill() overwrote given fields: %+v", given)
+	}
+
+	var none *GrantDefaults
+	none.Fill(given) // nil defaults leave the grant alone
+}
+
+func TestGrantDefaults_Check(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	defaults := &GrantDefaults{
+		Controls:        []string{ControlReadOnly, "max_rows:100"},
+		DurationSeconds: 3600,
+		MaxQueryCounts:  ptrInt64(100),
+	}
+
+	valid := func() *Grant {
+		return &Grant{
+			Controls:       []string{ControlReadOnly, "max_rows:50"},
+			StartsAt:       start,
+			ExpiresAt:      start.Add(time.Hour),
+			MaxQueryCounts: ptrInt64(100),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*Grant)
+		wantErr bool
+	}{
+		{name: "within defaults", mutate: func(*Grant) {}},
+		{name: "missing control", mutate: func(g *Grant) { g.Controls = []string{"max_rows:50"} }, wantErr: true},
+		{name: "missing parameterized control", mutate: func(g *Grant) { g.Controls = []string{ControlReadOnly} }, wantErr: true},
+		{name: "parameter above default", mutate: func(g *Grant) { g.Controls[1] = "max_rows:500" }, wantErr: true},
+		{name: "too long", mutate: func(g *Grant) { g.ExpiresAt = start.Add(2 * time.Hour) }, wantErr: true},
+		{name: "quota above default", mutate: func(g *Grant) { g.MaxQueryCounts = ptrInt64(101) }, wantErr: true},
+		{name: "unlimited quota", mutate: func(g *Grant) { g.MaxQueryCounts = nil }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			grant := valid()
+			tt.mutate(grant)
+
+			err := defaults.Check(grant)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrGrantExceedsDefaults) {
+				t.Errorf("Check() error = %v, want %v", err, ErrGrantExceedsDefaults)
+			}
+		})
+	}
+}
+
+func TestGrantDefaults_Clamp(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	defaults := &GrantDefaults{
+		Controls:            []string{ControlReadOnly, "max_rows:100"},
+		DurationSeconds:     3600,
+		MaxBytesTransferred: ptrInt64(1024),
+	}
+
+	grant := &Grant{
+		Controls:       []string{"max_rows:500", ControlBlockCopy},
+		StartsAt:       start,
+		ExpiresAt:      start.Add(24 * time.Hour),
+		MaxQueryCounts: ptrInt64(10),
+	}
+	defaults.Clamp(grant)
+
+	if want := []string{"max_rows:100", ControlBlockCopy, ControlReadOnly}; !slices.Equal(grant.Controls, want) {
+		t.Errorf("Controls = %q, want %q", grant.Controls, want)
+	}
+	if want := start.Add(time.Hour); !grant.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", grant.ExpiresAt, want)
+	}
+	if *grant.MaxQueryCounts != 10 {
+		t.Errorf("MaxQueryCounts = %d, want 10 (no default)", *grant.MaxQueryCounts)
+	}
+	if grant.MaxBytesTransferred == nil || *grant.MaxBytesTransferred != 1024 {
+		t.Errorf("MaxBytesTransferred = %v, want 1024", grant.MaxBytesTransferred)
+	}
+
+	if err := defaults.Check(grant); err != nil {
+		t.Errorf("Check() after Clamp() = %v, want nil", err)
+	}
+}
+
+func TestServer_StoresResults(t *testing.T) {
+	t.Parallel()
+
+	var none *Server
+	if !none.StoresResults() {
+		t.Error("nil server: StoresResults() = false, want true")
+	}
+
+	if !(&Server{}).StoresResults() {
+		t.Error("no defaults: StoresResults() = false, want true")
+	}
+
+	if !(&Server{GrantDefaults: &GrantDefaults{CaptureMode: CaptureModeFull}}).StoresResults() {
+		t.Error("full capture: StoresResults() = false, want true")
+	}
+
+	if (&Server{GrantDefaults: &GrantDefaults{CaptureMode: CaptureModeQueries}}).StoresResults() {
+		t.Error("queries capture: StoresResults() = true, want false")
+	}
+}
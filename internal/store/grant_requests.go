@@ -22,13 +22,19 @@ var ErrInvalidTransition = errors.New("grant request not pending")
 // referenced definition has been deactivated between request and approval.
 var ErrDefinitionInactive = errors.New("grant definition is no longer active")
 
+// ErrGrantNotExtendable is returned by ApproveGrantRequest if the grant an
+// extension request names has been revoked since.
+var ErrGrantNotExtendable = errors.New("grant has been revoked")
+
 // CreateGrantRequest inserts a new pending request.
 func (s *Store) CreateGrantRequest(ctx context.Context, req *GrantRequest) (*GrantRequest, error) {
 	result := &GrantRequest{
 		UserID:            req.UserID,
 		GrantDefinitionID: req.GrantDefinitionID,
+		ExtendsGrantID:    req.ExtendsGrantID,
 		DatabaseID:        req.DatabaseID,
 		Justification:     req.Justification,
+		DurationSeconds:   req.DurationSeconds,
 		Status:            GrantRequestPending,
 		RequestedAt:       time.Now(),
 	}
@@ -67,7 +73,30 @@ func (s *Store) GetGrantRequest(ctx context.Context, uid uuid.UUID) (*GrantReque
 func (s *Store) ListGrantRequests(ctx context.Context, filter GrantRequestFilter) ([]GrantRequest, error) {
 	var requests []GrantRequest
 
-	q := s.db.NewSelect().Model(&requests)
+	if err := s.selectGrantRequests(&requests, filter).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("list grant requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// CountGrantRequests returns the number of grant requests matching the filter,
+// regardless of its Limit and Offset.
+func (s *Store) CountGrantRequests(ctx context.Context, filter GrantRequestFilter) (int, error) {
+	var requests []GrantRequest
+
+	n, err := s.selectGrantRequests(&requests, filter).Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("count grant requests: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectGrantRequests builds the select of the grant requests matching filter into dest,
+// newest first.
+func (s *Store) selectGrantRequests(dest *[]GrantRequest, filter GrantRequestFilter) *bun.SelectQuery {
+	q := s.db.NewSelect().Model(dest)
 
 	if filter.UserID != nil {
 		q = q.Where("user_id = ?", *filter.UserID)
@@ -81,21 +110,7 @@ func (s *Store) ListGrantRequests(ctx context.Context, filter GrantRequestFilter
 		q = q.Where("status = ?", *filter.Status)
 	}
 
-	q = q.Order("requested_at DESC")
-
-	if filter.Limit > 0 {
-		q = q.Limit(filter.Limit)
-	}
-
-	if filter.Offset > 0 {
-		q = q.Offset(filter.Offset)
-	}
-
-	if err := q.Scan(ctx); err != nil {
-		return nil, fmt.Errorf("list grant requests: %w", err)
-	}
-
-	return requests, nil
+	return paginate(q.Order("requested_at DESC"), filter.Limit, filter.Offset)
 }
 
 // HasPendingRequest checks whether a user already has an open request for
@@ -116,15 +131,33 @@ func (s *Store) HasPendingRequest(ctx context.Context, userID, definitionID, dat
 	return count > 0, nil
 }
 
+// HasPendingExtension checks whether a grant already has an open extension
+// request.
+func (s *Store) HasPendingExtension(ctx context.Context, grantID uuid.UUID) (bool, error) {
+	count, err := s.db.NewSelect().
+		Model((*GrantRequest)(nil)).
+		Where("extends_grant_id = ?", grantID).
+		Where("status = ?", GrantRequestPending).
+		Count(ctx)
+	if err != nil {
+		return false, fmt.Errorf("check pending extension: %w", err)
+	}
+
+	return count > 0, nil
+}
+
 // ApproveGrantRequest atomically transitions a pending request to approved
 // and creates the resulting AccessGrant from the linked definition. The
-// caller (admin) is captured in decided_by + grant.granted_by.
+// caller (admin) is captured in decided_by + grant.granted_by. An extension
+// request instead pushes back the expiry of the grant it names, which is
+// returned as the resulting grant.
 //
 // Returns:
 //   - the resulting grant, the updated request, nil on success
 //   - ErrGrantRequestNotFound if the request doesn't exist
 //   - ErrInvalidTransition if the request isn't pending
 //   - ErrDefinitionInactive if the linked definition was deactivated
+//   - ErrGrantNotExtendable if the grant to extend was revoked
 //
 // Wrapped in a transaction so a partial failure (request flipped, grant
 // not created) can't leak.
@@ -172,19 +205,19 @@ func (s *Store) approveGrantRequestTx(
 			return ErrInvalidTransition
 		}
 
-		def := new(GrantDefinition)
-		if err := tx.NewSelect().Model(def).Where("uid = ?", req.GrantDefinitionID).For("UPDATE").Scan(ctx); err != nil {
-			return fmt.Errorf("select definition: %w", err)
-		}
+		var (
+			newGrant *Grant
+			err      error
+		)
 
-		if !def.IsActive {
-			return ErrDefinitionInactive
+		if req.ExtendsGrantID != nil {
+			newGrant, err = extendGrantTx(ctx, tx, req)
+		} else {
+			newGrant, err = createRequestedGrantTx(ctx, tx, req, grantedBy)
 		}
 
-		newGrant := BuildGrantFromDefinition(def, req.UserID, req.DatabaseID, grantedBy, time.Now())
-
-		if _, err := tx.NewInsert().Model(newGrant).Returning("*").Exec(ctx); err != nil {
-			return fmt.Errorf("create grant: %w", err)
+		if err != nil {
+			return err
 		}
 
 		now := time.Now()
@@ -212,6 +245,80 @@ func (s *Store) approveGrantRequestTx(
 	return grant, request, nil
 }
 
+// createRequestedGrantTx materializes the grant of an approved request from
+// its definition.
+func createRequestedGrantTx(ctx context.Context, tx bun.Tx, req *GrantRequest, grantedBy uuid.UUID) (*Grant, error) {
+	def := new(GrantDefinition)
+	if err := tx.NewSelect().Model(def).Where("uid = ?", req.GrantDefinitionID).For("UPDATE").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("select definition: %w", err)
+	}
+
+	if !def.IsActive {
+		return nil, ErrDefinitionInactive
+	}
+
+	newGrant := BuildGrantFromDefinition(def, req.UserID, req.DatabaseID, grantedBy, time.Now())
+
+	// The requester may have asked for less time than the definition
+	// allows.
+	if req.DurationSeconds != nil && *req.DurationSeconds < def.DurationSeconds {
+		newGrant.ExpiresAt = newGrant.StartsAt.Add(time.Duration(*req.DurationSeconds) * time.Second)
+	}
+
+	// The definition is shared across databases; the target's own
+	// defaults still bound what it grants.
+	target := new(Server)
+	if err := tx.NewSelect().Model(target).Column("grant_defaults").Where("uid = ?", req.DatabaseID).Scan(ctx); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("select database: %w", err)
+	}
+
+	target.GrantDefaults.Clamp(newGrant)
+
+	if _, err := tx.NewInsert().Model(newGrant).Returning("*").Exec(ctx); err != nil {
+		return nil, fmt.Errorf("create grant: %w", err)
+	}
+
+	return newGrant, nil
+}
+
+// extendGrantTx pushes back the expiry of the grant an approved extension
+// request names by the requested duration, counted from its current expiry
+// or from now if it has already expired. The database's default maximum
+// duration bounds the extension like it bounds a new grant.
+func extendGrantTx(ctx context.Context, tx bun.Tx, req *GrantRequest) (*Grant, error) {
+	grant := new(Grant)
+	if err := tx.NewSelect().Model(grant).Where("uid = ?", *req.ExtendsGrantID).For("UPDATE").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("select grant: %w", err)
+	}
+
+	if grant.RevokedAt != nil {
+		return nil, ErrGrantNotExtendable
+	}
+
+	extension := time.Duration(*req.DurationSeconds) * time.Second
+
+	target := new(Server)
+	if err := tx.NewSelect().Model(target).Column("grant_defaults").Where("uid = ?", grant.DatabaseID).Scan(ctx); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("select database: %w", err)
+	}
+
+	if defaults := target.GrantDefaults; defaults != nil && defaults.DurationSeconds > 0 {
+		extension = min(extension, time.Duration(defaults.DurationSeconds)*time.Second)
+	}
+
+	if now := time.Now(); grant.ExpiresAt.Before(now) {
+		grant.ExpiresAt = now
+	}
+
+	grant.ExpiresAt = grant.ExpiresAt.Add(extension)
+
+	if _, err := tx.NewUpdate().Model(grant).Column("expires_at").WherePK().Exec(ctx); err != nil {
+		return nil, fmt.Errorf("extend grant: %w", err)
+	}
+
+	return grant, nil
+}
+
 // DenyGrantRequest atomically transitions pending → denied with an
 // optional reason.
 func (s *Store) DenyGrantRequest(ctx context.Context, uid, decidedBy uuid.UUID, reason string) (*GrantRequest, error) {
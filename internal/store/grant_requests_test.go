@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -54,7 +55,7 @@ func TestCreateGrantRequest(t *testing.T) {
 
 	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
 		UserID:            user.UID,
-		GrantDefinitionID: def.UID,
+		GrantDefinitionID: &def.UID,
 		DatabaseID:        db.UID,
 		Justification:     "investigating bug 1234",
 	})
@@ -79,7 +80,7 @@ func TestApproveGrantRequest_CreatesGrant(t *testing.T) {
 
 	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
 		UserID:            user.UID,
-		GrantDefinitionID: def.UID,
+		GrantDefinitionID: &def.UID,
 		DatabaseID:        db.UID,
 	})
 	if err != nil {
@@ -118,6 +119,38 @@ func TestApproveGrantRequest_CreatesGrant(t *testing.T) {
 	}
 }
 
+func TestApproveGrantRequest_RequestedDuration(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, user, db, def := setupRequestFixtures(t, ctx, store, "duration")
+
+	duration := def.DurationSeconds / 2
+
+	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
+		UserID:            user.UID,
+		GrantDefinitionID: &def.UID,
+		DatabaseID:        db.UID,
+		DurationSeconds:   &duration,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.DurationSeconds == nil || *req.DurationSeconds != duration {
+		t.Fatalf("request duration = %v, want %d", req.DurationSeconds, duration)
+	}
+
+	grant, _, err := store.ApproveGrantRequest(ctx, req.UID, admin.UID)
+	if err != nil {
+		t.Fatalf("ApproveGrantRequest: %v", err)
+	}
+
+	if got := grant.ExpiresAt.Sub(grant.StartsAt); got != time.Duration(duration)*time.Second {
+		t.Errorf("grant lasts %s, want %ds", got, duration)
+	}
+}
+
 func TestAutoApproveGrantRequest_CreatesGrantWithNoDecider(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -137,7 +170,7 @@ func TestAutoApproveGrantRequest_CreatesGrantWithNoDecider(t *testing.T) {
 
 	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
 		UserID:            user.UID,
-		GrantDefinitionID: def.UID,
+		GrantDefinitionID: &def.UID,
 		DatabaseID:        db.UID,
 		Justification:     "auto-approved routine access",
 	})
@@ -184,7 +217,7 @@ func TestApproveGrantRequest_DefinitionInactive(t *testing.T) {
 
 	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
 		UserID:            user.UID,
-		GrantDefinitionID: def.UID,
+		GrantDefinitionID: &def.UID,
 		DatabaseID:        db.UID,
 	})
 	if err != nil {
@@ -208,7 +241,7 @@ func TestDenyGrantRequest(t *testing.T) {
 
 	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
 		UserID:            user.UID,
-		GrantDefinitionID: def.UID,
+		GrantDefinitionID: &def.UID,
 		DatabaseID:        db.UID,
 	})
 	if err != nil {
@@ -237,7 +270,7 @@ func TestCancelGrantRequest(t *testing.T) {
 
 	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
 		UserID:            user.UID,
-		GrantDefinitionID: def.UID,
+		GrantDefinitionID: &def.UID,
 		DatabaseID:        db.UID,
 	})
 	if err != nil {
@@ -271,7 +304,7 @@ func TestHasPendingRequest(t *testing.T) {
 
 	if _, err := store.CreateGrantRequest(ctx, &GrantRequest{
 		UserID:            user.UID,
-		GrantDefinitionID: def.UID,
+		GrantDefinitionID: &def.UID,
 		DatabaseID:        db.UID,
 	}); err != nil {
 		t.Fatal(err)
@@ -286,3 +319,75 @@ func TestHasPendingRequest(t *testing.T) {
 		t.Error("expected pending request after create")
 	}
 }
+
+func TestApproveGrantRequest_ExtendsGrant(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, user, db, _ := setupRequestFixtures(t, ctx, store, "extend")
+
+	now := time.Now()
+
+	grant, err := store.CreateGrant(ctx, &Grant{
+		UserID:     user.UID,
+		DatabaseID: db.UID,
+		GrantedBy:  admin.UID,
+		StartsAt:   now.Add(-time.Hour),
+		ExpiresAt:  now.Add(10 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("CreateGrant: %v", err)
+	}
+
+	duration := int64(3600)
+
+	req, err := store.CreateGrantRequest(ctx, &GrantRequest{
+		UserID:          user.UID,
+		ExtendsGrantID:  &grant.UID,
+		DatabaseID:      db.UID,
+		DurationSeconds: &duration,
+	})
+	if err != nil {
+		t.Fatalf("CreateGrantRequest: %v", err)
+	}
+
+	if pending, err := store.HasPendingExtension(ctx, grant.UID); err != nil || !pending {
+		t.Fatalf("HasPendingExtension = %v, %v; want true", pending, err)
+	}
+
+	extended, updated, err := store.ApproveGrantRequest(ctx, req.UID, admin.UID)
+	if err != nil {
+		t.Fatalf("ApproveGrantRequest: %v", err)
+	}
+
+	if extended.UID != grant.UID {
+		t.Errorf("extended grant = %v, want the existing %v", extended.UID, grant.UID)
+	}
+
+	if want := grant.ExpiresAt.Add(time.Hour); extended.ExpiresAt.Sub(want).Abs() > time.Millisecond {
+		t.Errorf("expires_at = %v, want %v", extended.ExpiresAt, want)
+	}
+
+	if updated.ResultingGrantID == nil || *updated.ResultingGrantID != grant.UID {
+		t.Error("resulting_grant_id not linked to the extended grant")
+	}
+
+	// A revoked grant can no longer be extended.
+	req, err = store.CreateGrantRequest(ctx, &GrantRequest{
+		UserID:          user.UID,
+		ExtendsGrantID:  &grant.UID,
+		DatabaseID:      db.UID,
+		DurationSeconds: &duration,
+	})
+	if err != nil {
+		t.Fatalf("CreateGrantRequest: %v", err)
+	}
+
+	if err := store.RevokeGrant(ctx, grant.UID, admin.UID); err != nil {
+		t.Fatalf("RevokeGrant: %v", err)
+	}
+
+	if _, _, err := store.ApproveGrantRequest(ctx, req.UID, admin.UID); !errors.Is(err, ErrGrantNotExtendable) {
+		t.Errorf("approve after revoke err = %v, want ErrGrantNotExtendable", err)
+	}
+}
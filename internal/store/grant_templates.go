@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrGrantTemplateNotFound is returned when a grant template lookup misses.
+var ErrGrantTemplateNotFound = errors.New("grant template not found")
+
+// ErrGrantTemplateDuplicate is returned when a grant template name collides
+// (case insensitively) with an existing template.
+var ErrGrantTemplateDuplicate = errors.New("grant template with this name already exists")
+
+// BuildGrantFromTemplate assembles a grant from a template for one user and
+// database, starting at startsAt. Extra labels are merged over the
+// template's. An empty control list is left nil, so the database's grant
+// defaults fill it as they would for a grant created without controls.
+func BuildGrantFromTemplate(tpl *GrantTemplate, userID, databaseID, grantedBy uuid.UUID, startsAt time.Time, labels Labels) *Grant {
+	var controls []string
+	if len(tpl.Controls) > 0 {
+		controls = append([]string(nil), tpl.Controls...)
+	}
+
+	grantLabels := Labels{}
+	maps.Copy(grantLabels, tpl.Labels)
+	maps.Copy(grantLabels, labels)
+
+	return &Grant{
+		UserID:              userID,
+		DatabaseID:          databaseID,
+		Controls:            controls,
+		AllowedTables:       append([]string(nil), tpl.AllowedTables...),
+		AllowedCIDRs:        append([]string(nil), tpl.AllowedCIDRs...),
+		GrantedBy:           grantedBy,
+		StartsAt:            startsAt,
+		ExpiresAt:           startsAt.Add(time.Duration(tpl.DurationSeconds) * time.Second),
+		MaxQueryCounts:      tpl.MaxQueryCounts,
+		MaxBytesTransferred: tpl.MaxBytesTransferred,
+		MaxRowsReturned:     tpl.MaxRowsReturned,
+		Labels:              grantLabels,
+	}
+}
+
+// CreateGrantTemplate inserts a new template.
+func (s *Store) CreateGrantTemplate(ctx context.Context, tpl *GrantTemplate) (*GrantTemplate, error) {
+	now := time.Now()
+
+	result := &GrantTemplate{
+		Name:                tpl.Name,
+		Description:         tpl.Description,
+		DurationSeconds:     tpl.DurationSeconds,
+		Controls:            tpl.Controls,
+		AllowedTables:       tpl.AllowedTables,
+		AllowedCIDRs:        tpl.AllowedCIDRs,
+		MaxQueryCounts:      tpl.MaxQueryCounts,
+		MaxBytesTransferred: tpl.MaxBytesTransferred,
+		MaxRowsReturned:     tpl.MaxRowsReturned,
+		Labels:              tpl.Labels,
+		CreatedBy:           tpl.CreatedBy,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	result.fillEmpty()
+
+	if _, err := s.db.NewInsert().Model(result).Returning("*").Exec(ctx); err != nil {
+		if isUniqueViolation(err, "grant_templates_name_uniq") {
+			return nil, ErrGrantTemplateDuplicate
+		}
+
+		return nil, fmt.Errorf("create grant template: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetGrantTemplate fetches a template by UID.
+func (s *Store) GetGrantTemplate(ctx context.Context, uid uuid.UUID) (*GrantTemplate, error) {
+	tpl := new(GrantTemplate)
+
+	if err := s.db.NewSelect().Model(tpl).Where("uid = ?", uid).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGrantTemplateNotFound
+		}
+
+		return nil, fmt.Errorf("get grant template: %w", err)
+	}
+
+	return tpl, nil
+}
+
+// ListGrantTemplates returns every template, name-ordered.
+func (s *Store) ListGrantTemplates(ctx context.Context) ([]GrantTemplate, error) {
+	templates := []GrantTemplate{}
+
+	if err := s.db.NewSelect().Model(&templates).Order("name ASC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("list grant templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// UpdateGrantTemplate mutates the editable fields of a template. The grants
+// already created from it are left as they are.
+func (s *Store) UpdateGrantTemplate(ctx context.Context, tpl *GrantTemplate) error {
+	tpl.fillEmpty()
+	tpl.UpdatedAt = time.Now()
+
+	res, err := s.db.NewUpdate().
+		Model(tpl).
+		Column("name", "description", "duration_seconds", "controls", "allowed_tables", "allowed_cidrs",
+			"max_query_counts", "max_bytes_transferred", "max_rows_returned", "labels", "updated_at").
+		Where("uid = ?", tpl.UID).
+		Exec(ctx)
+	if err != nil {
+		if isUniqueViolation(err, "grant_templates_name_uniq") {
+			return ErrGrantTemplateDuplicate
+		}
+
+		return fmt.Errorf("update grant template: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrGrantTemplateNotFound
+	}
+
+	return nil
+}
+
+// DeleteGrantTemplate hard-deletes a template. Grants don't reference their
+// template, so they stay.
+func (s *Store) DeleteGrantTemplate(ctx context.Context, uid uuid.UUID) error {
+	res, err := s.db.NewDelete().
+		Model((*GrantTemplate)(nil)).
+		Where("uid = ?", uid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("delete grant template: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrGrantTemplateNotFound
+	}
+
+	return nil
+}
+
+// fillEmpty replaces nil lists and labels with empty ones, as the columns
+// are NOT NULL.
+func (t *GrantTemplate) fillEmpty() {
+	if t.Controls == nil {
+		t.Controls = []string{}
+	}
+
+	if t.AllowedTables == nil {
+		t.AllowedTables = []string{}
+	}
+
+	if t.AllowedCIDRs == nil {
+		t.AllowedCIDRs = []string{}
+	}
+
+	if t.Labels == nil {
+		t.Labels = Labels{}
+	}
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildGrantFromTemplate(t *testing.T) {
+	t.Parallel()
+
+	maxRows := int64(500)
+	tpl := &GrantTemplate{
+		DurationSeconds: 3600,
+		Controls:        []string{},
+		AllowedTables:   []string{"public.*"},
+		MaxRowsReturned: &maxRows,
+		Labels:          Labels{"team": "data", "source": "template"},
+	}
+
+	userID, databaseID, adminID := uuid.New(), uuid.New(), uuid.New()
+	startsAt := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	grant := BuildGrantFromTemplate(tpl, userID, databaseID, adminID, startsAt, Labels{"source": "onboarding"})
+
+	if grant.UserID != userID || grant.DatabaseID != databaseID || grant.GrantedBy != adminID {
+		t.Errorf("BuildGrantFromTemplate() = %+v, want the given user, database and granter", grant)
+	}
+
+	if !grant.ExpiresAt.Equal(startsAt.Add(time.Hour)) {
+		t.Errorf("ExpiresAt = %v, want %v", grant.ExpiresAt, startsAt.Add(time.Hour))
+	}
+
+	// No control: the database's defaults apply.
+	if grant.Controls != nil {
+		t.Errorf("Controls = %v, want nil", grant.Controls)
+	}
+
+	if grant.MaxRowsReturned == nil || *grant.MaxRowsReturned != 500 || !grant.RestrictsTables() {
+		t.Errorf("BuildGrantFromTemplate() = %+v, want the template's quota and tables", grant)
+	}
+
+	if grant.Labels["team"] != "data" || grant.Labels["source"] != "onboarding" {
+		t.Errorf("Labels = %v, want the template's overridden by the given ones", grant.Labels)
+	}
+
+	if tpl.Labels["source"] != "template" {
+		t.Errorf("template labels modified: %v", tpl.Labels)
+	}
+}
+
+func TestGrantTemplates(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin := createTestAdmin(t, ctx, store, "tpl")
+
+	created, err := store.CreateGrantTemplate(ctx, &GrantTemplate{
+		Name:            "Analyst onboarding",
+		DurationSeconds: 30 * 24 * 3600,
+		Controls:        []string{ControlReadOnly},
+		CreatedBy:       &admin.UID,
+	})
+	if err != nil {
+		t.Fatalf("CreateGrantTemplate() error = %v", err)
+	}
+
+	if created.AllowedTables == nil || created.Labels == nil {
+		t.Errorf("CreateGrantTemplate() = %+v, want empty tables and labels", created)
+	}
+
+	if _, err := store.CreateGrantTemplate(ctx, &GrantTemplate{Name: "analyst ONBOARDING", DurationSeconds: 60}); !errors.Is(err, ErrGrantTemplateDuplicate) {
+		t.Errorf("CreateGrantTemplate() with a taken name error = %v, want %v", err, ErrGrantTemplateDuplicate)
+	}
+
+	created.Labels = Labels{"team": "data"}
+	created.DurationSeconds = 7 * 24 * 3600
+
+	if err := store.UpdateGrantTemplate(ctx, created); err != nil {
+		t.Fatalf("UpdateGrantTemplate() error = %v", err)
+	}
+
+	got, err := store.GetGrantTemplate(ctx, created.UID)
+	if err != nil {
+		t.Fatalf("GetGrantTemplate() error = %v", err)
+	}
+
+	if got.DurationSeconds != 7*24*3600 || got.Labels["team"] != "data" || len(got.Controls) != 1 {
+		t.Errorf("GetGrantTemplate() = %+v, want the updated template", got)
+	}
+
+	templates, err := store.ListGrantTemplates(ctx)
+	if err != nil || len(templates) != 1 {
+		t.Errorf("ListGrantTemplates() = %v, %v, want the template", templates, err)
+	}
+
+	if err := store.DeleteGrantTemplate(ctx, created.UID); err != nil {
+		t.Fatalf("DeleteGrantTemplate() error = %v", err)
+	}
+
+	if _, err := store.GetGrantTemplate(ctx, created.UID); !errors.Is(err, ErrGrantTemplateNotFound) {
+		t.Errorf("GetGrantTemplate() after delete error = %v, want %v", err, ErrGrantTemplateNotFound)
+	}
+
+	if err := store.UpdateGrantTemplate(ctx, created); !errors.Is(err, ErrGrantTemplateNotFound) {
+		t.Errorf("UpdateGrantTemplate() after delete error = %v, want %v", err, ErrGrantTemplateNotFound)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/uptrace/bun"
 )
 
 // BuildGrantFromDefinition assembles an AccessGrant from a GrantDefinition
@@ -40,15 +41,29 @@ func (s *Store) CreateGrant(ctx context.Context, grant *Grant) (*Grant, error) {
 		controls = []string{}
 	}
 
+	allowedTables := grant.AllowedTables
+	if allowedTables == nil {
+		allowedTables = []string{}
+	}
+
+	allowedCIDRs := grant.AllowedCIDRs
+	if allowedCIDRs == nil {
+		allowedCIDRs = []string{}
+	}
+
 	result := &AccessGrant{
 		UserID:              grant.UserID,
 		DatabaseID:          grant.DatabaseID,
 		Controls:            controls,
+		AllowedTables:       allowedTables,
+		AllowedCIDRs:        allowedCIDRs,
 		GrantedBy:           grant.GrantedBy,
 		StartsAt:            grant.StartsAt,
 		ExpiresAt:           grant.ExpiresAt,
 		MaxQueryCounts:      grant.MaxQueryCounts,
 		MaxBytesTransferred: grant.MaxBytesTransferred,
+		MaxRowsReturned:     grant.MaxRowsReturned,
+		Labels:              grant.Labels,
 		CreatedAt:           time.Now(),
 	}
 
@@ -62,6 +77,8 @@ func (s *Store) CreateGrant(ctx context.Context, grant *Grant) (*Grant, error) {
 
 	result.QueryCount = 0
 	result.BytesTransferred = 0
+	result.RowsReturned = 0
+	result.fillQuotaRemaining()
 	return result, nil
 }
 
@@ -114,7 +131,40 @@ func (s *Store) GetGrantByUID(ctx context.Context, uid uuid.UUID) (*Grant, error
 // ListGrants retrieves grants with optional filters
 func (s *Store) ListGrants(ctx context.Context, filter GrantFilter) ([]Grant, error) {
 	var grants []AccessGrant
-	q := s.db.NewSelect().Model(&grants)
+
+	err := s.selectGrants(&grants, filter).Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list grants: %w", err)
+	}
+
+	if grants == nil {
+		grants = []AccessGrant{}
+	}
+	for i := range grants {
+		if err := s.populateGrantCounters(ctx, &grants[i]); err != nil {
+			return nil, err
+		}
+	}
+	return grants, nil
+}
+
+// CountGrants returns the number of grants matching the filter, regardless of
+// its Limit and Offset.
+func (s *Store) CountGrants(ctx context.Context, filter GrantFilter) (int, error) {
+	var grants []AccessGrant
+
+	n, err := s.selectGrants(&grants, filter).Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count grants: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectGrants builds the select of the grants matching filter into dest,
+// newest first.
+func (s *Store) selectGrants(dest *[]AccessGrant, filter GrantFilter) *bun.SelectQuery {
+	q := s.db.NewSelect().Model(dest)
 
 	if filter.UserID != nil {
 		q = q.Where("user_id = ?", *filter.UserID)
@@ -130,25 +180,16 @@ func (s *Store) ListGrants(ctx context.Context, filter GrantFilter) ([]Grant, er
 			Where("expires_at > NOW()")
 	}
 
-	err := q.Order("created_at DESC").Scan(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list grants: %w", err)
-	}
+	q = whereLabels(q, "labels", filter.Labels)
 
-	if grants == nil {
-		grants = []AccessGrant{}
-	}
-	for i := range grants {
-		if err := s.populateGrantCounters(ctx, &grants[i]); err != nil {
-			return nil, err
-		}
-	}
-	return grants, nil
+	return paginate(q.Order("created_at DESC"), filter.Limit, filter.Offset)
 }
 
-// populateGrantCounters fills the transient QueryCount and BytesTransferred
-// fields of g by aggregating from the queries and connections tables within
-// the grant's effective time window: [StartsAt, min(ExpiresAt, RevokedAt)).
+// populateGrantCounters fills the transient QueryCount, BytesTransferred,
+// RowsReturned and QuotaRemaining fields of g by aggregating from the queries
+// and connections tables within the grant's effective time window:
+// [StartsAt, min(ExpiresAt, RevokedAt)). A folded query counts once per
+// execution, as in the proxy sessions.
 func (s *Store) populateGrantCounters(ctx context.Context, g *AccessGrant) error {
 	upper := g.ExpiresAt
 	if g.RevokedAt != nil && g.RevokedAt.Before(upper) {
@@ -157,7 +198,7 @@ func (s *Store) populateGrantCounters(ctx context.Context, g *AccessGrant) error
 
 	var queryCount int64
 	err := s.db.NewSelect().
-		ColumnExpr("COUNT(*)").
+		ColumnExpr("COALESCE(SUM(q.repeat_count), 0)").
 		TableExpr("queries AS q").
 		Join("JOIN connections AS c ON q.connection_id = c.uid").
 		Where("c.user_id = ?", g.UserID).
@@ -169,21 +210,48 @@ func (s *Store) populateGrantCounters(ctx context.Context, g *AccessGrant) error
 		return fmt.Errorf("failed to aggregate grant query count: %w", err)
 	}
 
-	var bytesTransferred int64
+	var bytesTransferred, rowsReturned int64
 	err = s.db.NewSelect().
 		ColumnExpr("COALESCE(SUM(bytes_transferred), 0)").
+		ColumnExpr("COALESCE(SUM(rows_returned), 0)").
 		Model((*Connection)(nil)).
 		Where("user_id = ?", g.UserID).
 		Where("database_id = ?", g.DatabaseID).
 		Where("connected_at >= ?", g.StartsAt).
 		Where("connected_at < ?", upper).
-		Scan(ctx, &bytesTransferred)
+		Scan(ctx, &bytesTransferred, &rowsReturned)
 	if err != nil {
 		return fmt.Errorf("failed to aggregate grant bytes transferred: %w", err)
 	}
 
 	g.QueryCount = queryCount
 	g.BytesTransferred = bytesTransferred
+	g.RowsReturned = rowsReturned
+	g.fillQuotaRemaining()
+	return nil
+}
+
+// SetGrantLabels replaces the labels of a grant. Labels are the only part of a
+// grant that can change after creation.
+func (s *Store) SetGrantLabels(ctx context.Context, uid uuid.UUID, labels Labels) error {
+	result, err := s.db.NewUpdate().
+		Model((*AccessGrant)(nil)).
+		Set("labels = ?::jsonb", labels.jsonb()).
+		Where("uid = ?", uid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update grant labels: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrGrantNotFound
+	}
+
 	return nil
 }
 
@@ -380,13 +380,15 @@ func TestGrantCounters_PopulatedFromQueriesAndConnections(t *testing.T) {
 	admin, _ := store.CreateUser(ctx, "countersadmin", "hash", []string{RoleAdmin, RoleConnector})
 
 	now := time.Now()
+	maxRows := int64(100)
 	grant, err := store.CreateGrant(ctx, &Grant{
-		UserID:     user.UID,
-		DatabaseID: database.UID,
-		Controls:   []string{},
-		GrantedBy:  admin.UID,
-		StartsAt:   now.Add(-time.Hour),
-		ExpiresAt:  now.Add(time.Hour),
+		UserID:          user.UID,
+		DatabaseID:      database.UID,
+		Controls:        []string{},
+		GrantedBy:       admin.UID,
+		StartsAt:        now.Add(-time.Hour),
+		ExpiresAt:       now.Add(time.Hour),
+		MaxRowsReturned: &maxRows,
 	})
 	if err != nil {
 		t.Fatalf("CreateGrant() error = %v", err)
@@ -406,7 +408,7 @@ func TestGrantCounters_PopulatedFromQueriesAndConnections(t *testing.T) {
 		}); err != nil {
 			t.Fatalf("CreateQuery() error = %v", err)
 		}
-		if err := store.IncrementConnectionStats(ctx, conn.UID, queryBytes); err != nil {
+		if err := store.IncrementConnectionStats(ctx, conn.UID, queryBytes, 10); err != nil {
 			t.Fatalf("IncrementConnectionStats() error = %v", err)
 		}
 	}
@@ -421,6 +423,12 @@ func TestGrantCounters_PopulatedFromQueriesAndConnections(t *testing.T) {
 	if got.BytesTransferred != 3*queryBytes {
 		t.Errorf("BytesTransferred = %d, want %d", got.BytesTransferred, 3*queryBytes)
 	}
+	if got.RowsReturned != 30 {
+		t.Errorf("RowsReturned = %d, want 30", got.RowsReturned)
+	}
+	if remaining := got.QuotaRemaining.RowsReturned; remaining == nil || *remaining != 70 {
+		t.Errorf("QuotaRemaining.RowsReturned = %v, want 70", remaining)
+	}
 
 	active, err := store.GetActiveGrant(ctx, user.UID, database.UID)
 	if err != nil {
@@ -466,7 +474,7 @@ func TestGrantCounters_TimeWindowExcludesOutOfRange(t *testing.T) {
 	}); err != nil {
 		t.Fatalf("CreateQuery() error = %v", err)
 	}
-	if err := store.IncrementConnectionStats(ctx, conn.UID, 500); err != nil {
+	if err := store.IncrementConnectionStats(ctx, conn.UID, 500, 0); err != nil {
 		t.Fatalf("IncrementConnectionStats() error = %v", err)
 	}
 
@@ -514,7 +522,7 @@ func TestGrantCounters_BoundedByRevokedAt(t *testing.T) {
 	}); err != nil {
 		t.Fatalf("CreateQuery() error = %v", err)
 	}
-	if err := store.IncrementConnectionStats(ctx, preConn.UID, 100); err != nil {
+	if err := store.IncrementConnectionStats(ctx, preConn.UID, 100, 0); err != nil {
 		t.Fatalf("IncrementConnectionStats() error = %v", err)
 	}
 
@@ -537,7 +545,7 @@ func TestGrantCounters_BoundedByRevokedAt(t *testing.T) {
 	}); err != nil {
 		t.Fatalf("CreateQuery() error = %v", err)
 	}
-	if err := store.IncrementConnectionStats(ctx, postConn.UID, 999); err != nil {
+	if err := store.IncrementConnectionStats(ctx, postConn.UID, 999, 0); err != nil {
 		t.Fatalf("IncrementConnectionStats() error = %v", err)
 	}
 
@@ -593,7 +601,7 @@ func TestGrantBytesRecompute_IncludesAbortedQueryBytes(t *testing.T) {
 	}); err != nil {
 		t.Fatalf("CreateQuery() error = %v", err)
 	}
-	if err := store.IncrementConnectionStats(ctx, conn.UID, 300); err != nil {
+	if err := store.IncrementConnectionStats(ctx, conn.UID, 300, 0); err != nil {
 		t.Fatalf("IncrementConnectionStats() error = %v", err)
 	}
 	if err := store.IncrementConnectionBytes(ctx, conn.UID, 700); err != nil {
@@ -651,7 +659,7 @@ func TestListGrants_PopulatesCountersForEach(t *testing.T) {
 	if _, err := store.CreateQuery(ctx, &Query{ConnectionID: conn1.UID, SQLText: "x", ExecutedAt: now}); err != nil {
 		t.Fatalf("CreateQuery() error = %v", err)
 	}
-	if err := store.IncrementConnectionStats(ctx, conn1.UID, 100); err != nil {
+	if err := store.IncrementConnectionStats(ctx, conn1.UID, 100, 0); err != nil {
 		t.Fatalf("IncrementConnectionStats() error = %v", err)
 	}
 
@@ -660,7 +668,7 @@ func TestListGrants_PopulatesCountersForEach(t *testing.T) {
 		if _, err := store.CreateQuery(ctx, &Query{ConnectionID: conn2.UID, SQLText: "y", ExecutedAt: now}); err != nil {
 			t.Fatalf("CreateQuery() error = %v", err)
 		}
-		if err := store.IncrementConnectionStats(ctx, conn2.UID, 250); err != nil {
+		if err := store.IncrementConnectionStats(ctx, conn2.UID, 250, 0); err != nil {
 			t.Fatalf("IncrementConnectionStats() error = %v", err)
 		}
 	}
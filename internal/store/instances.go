@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// InstanceHeartbeatInterval is how often a running instance refreshes
+	// its heartbeat.
+	InstanceHeartbeatInterval = 30 * time.Second
+	// InstanceLiveWindow is how old a heartbeat may be for its instance to
+	// still count as live: a few missed heartbeats, not one.
+	InstanceLiveWindow = 3 * InstanceHeartbeatInterval
+)
+
+// RegisterInstance records a starting instance and stamps its UID on the
+// connections this store creates from then on. Hostname, Version and Listen
+// are taken from inst; the UID and timestamps are set here.
+func (s *Store) RegisterInstance(ctx context.Context, inst *Instance) error {
+	inst.UID = newUIDv7()
+	inst.StartedAt = time.Now()
+	inst.LastHeartbeatAt = inst.StartedAt
+
+	if inst.Listen == nil {
+		inst.Listen = map[string]string{}
+	}
+
+	if _, err := s.db.NewInsert().Model(inst).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register instance: %w", err)
+	}
+
+	s.instanceID = inst.UID
+
+	return nil
+}
+
+// InstanceID returns the UID of this process's instance, uuid.Nil before
+// RegisterInstance.
+func (s *Store) InstanceID() uuid.UUID {
+	return s.instanceID
+}
+
+// HeartbeatInstance refreshes the heartbeat of an instance.
+func (s *Store) HeartbeatInstance(ctx context.Context, uid uuid.UUID) error {
+	_, err := s.db.NewUpdate().
+		Model((*Instance)(nil)).
+		Set("last_heartbeat_at = ?", time.Now()).
+		Where("uid = ?", uid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to refresh instance heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// StopInstance records that an instance shut down.
+func (s *Store) StopInstance(ctx context.Context, uid uuid.UUID) error {
+	_, err := s.db.NewUpdate().
+		Model((*Instance)(nil)).
+		Set("stopped_at = ?", time.Now()).
+		Where("uid = ?", uid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to stop instance: %w", err)
+	}
+
+	return nil
+}
+
+// ListInstances lists instances, most recently started first. Only live ones
+// are returned unless all is set; stopped and silent instances are kept so
+// past connections can still be traced to them.
+func (s *Store) ListInstances(ctx context.Context, all bool, now time.Time) ([]Instance, error) {
+	var instances []Instance
+
+	liveSince := now.Add(-InstanceLiveWindow)
+	q := s.db.NewSelect().Model(&instances).Order("started_at DESC")
+
+	if !all {
+		q = q.Where("stopped_at IS NULL").Where("last_heartbeat_at >= ?", liveSince)
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	if instances == nil {
+		instances = []Instance{}
+	}
+
+	for i := range instances {
+		instances[i].Live = instances[i].StoppedAt == nil && !instances[i].LastHeartbeatAt.Before(liveSince)
+	}
+
+	return instances, nil
+}
+
+// InstanceHeartbeat keeps the registration of this process's instance alive
+// until Shutdown, which records the instance as stopped.
+type InstanceHeartbeat struct {
+	store    *Store
+	uid      uuid.UUID
+	interval time.Duration
+	logger   *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartInstanceHeartbeat refreshes the heartbeat of the registered instance
+// every interval.
+func (s *Store) StartInstanceHeartbeat(interval time.Duration, logger *slog.Logger) *InstanceHeartbeat {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h := &InstanceHeartbeat{
+		store:    s,
+		uid:      s.instanceID,
+		interval: interval,
+		logger:   logger.With(slog.String("component", "instance")),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go h.run(ctx)
+
+	return h
+}
+
+// Shutdown stops the heartbeat and records the instance as stopped, so it
+// leaves the live list at once rather than after InstanceLiveWindow.
+func (h *InstanceHeartbeat) Shutdown(ctx context.Context) error {
+	h.cancel()
+
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		return fmt.Errorf("instance heartbeat shutdown interrupted: %w", ctx.Err())
+	}
+
+	return h.store.StopInstance(ctx, h.uid)
+}
+
+func (h *InstanceHeartbeat) run(ctx context.Context) {
+	defer close(h.done)
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A missed heartbeat is retried at the next interval; the live
+		// window tolerates a few.
+		if err := h.store.HeartbeatInstance(ctx, h.uid); err != nil && ctx.Err() == nil {
+			h.logger.WarnContext(ctx, "Instance heartbeat failed", slog.Any("error", err))
+		}
+	}
+}
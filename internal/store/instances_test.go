@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestInstances(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	if s.InstanceID() != uuid.Nil {
+		t.Fatalf("InstanceID() = %s before registration, want uuid.Nil", s.InstanceID())
+	}
+
+	inst := &Instance{Hostname: "dbbat-0", Version: "1.2.3", Listen: map[string]string{"api": ":4200", "pg": ":5434"}}
+	if err := s.RegisterInstance(ctx, inst); err != nil {
+		t.Fatalf("RegisterInstance() error = %v", err)
+	}
+
+	if s.InstanceID() != inst.UID {
+		t.Errorf("InstanceID() = %s, want %s", s.InstanceID(), inst.UID)
+	}
+
+	t.Run("connections carry the instance", func(t *testing.T) {
+		user, database := createTestUserAndDatabase(t, ctx, s, "instance")
+
+		conn, err := s.CreateConnection(ctx, user.UID, database.UID, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("CreateConnection() error = %v", err)
+		}
+
+		if conn.InstanceID == nil || *conn.InstanceID != inst.UID {
+			t.Fatalf("conn.InstanceID = %v, want %s", conn.InstanceID, inst.UID)
+		}
+
+		conns, err := s.ListConnections(ctx, ConnectionFilter{InstanceID: &inst.UID})
+		if err != nil {
+			t.Fatalf("ListConnections() error = %v", err)
+		}
+
+		if len(conns) != 1 || conns[0].UID != conn.UID {
+			t.Errorf("ListConnections(instance) = %d connections, want only %s", len(conns), conn.UID)
+		}
+	})
+
+	t.Run("live instances", func(t *testing.T) {
+		instances, err := s.ListInstances(ctx, false, time.Now())
+		if err != nil {
+			t.Fatalf("ListInstances() error = %v", err)
+		}
+
+		if len(instances) != 1 || !instances[0].Live || instances[0].Listen["pg"] != ":5434" {
+			t.Fatalf("ListInstances() = %+v, want the registered instance, live", instances)
+		}
+
+		// Past the live window without a heartbeat, the instance is silent.
+		later := time.Now().Add(InstanceLiveWindow + time.Minute)
+
+		if instances, _ = s.ListInstances(ctx, false, later); len(instances) != 0 {
+			t.Errorf("ListInstances() later = %d instances, want 0", len(instances))
+		}
+
+		if instances, _ = s.ListInstances(ctx, true, later); len(instances) != 1 || instances[0].Live {
+			t.Errorf("ListInstances(all) later = %+v, want the instance, not live", instances)
+		}
+	})
+
+	t.Run("heartbeat and shutdown", func(t *testing.T) {
+		h := s.StartInstanceHeartbeat(10*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		time.Sleep(50 * time.Millisecond)
+
+		if err := h.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+
+		instances, err := s.ListInstances(ctx, true, time.Now())
+		if err != nil {
+			t.Fatalf("ListInstances() error = %v", err)
+		}
+
+		if len(instances) != 1 || instances[0].StoppedAt == nil || instances[0].Live {
+			t.Fatalf("ListInstances(all) = %+v, want the instance stopped", instances)
+		}
+
+		if !instances[0].LastHeartbeatAt.After(inst.LastHeartbeatAt) {
+			t.Errorf("LastHeartbeatAt = %s, want after %s", instances[0].LastHeartbeatAt, inst.LastHeartbeatAt)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/uptrace/bun"
+)
+
+// Label limits. Keys follow the usual label shape (letters, digits, and
+// ".", "_", "-", "/" inside) so they stay usable as query parameters.
+const (
+	MaxLabels        = 32
+	MaxLabelKeyLen   = 63
+	MaxLabelValueLen = 255
+)
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+
+// Labels are free-form key=value tags used to organize users, servers and
+// grants (e.g. env=prod, team=payments). Stored as a jsonb object.
+type Labels map[string]string
+
+// Validate checks the number of labels and the shape of each key and value.
+func (l Labels) Validate() error {
+	if len(l) > MaxLabels {
+		return fmt.Errorf("%w: at most %d labels", ErrInvalidLabels, MaxLabels)
+	}
+
+	for key, value := range l {
+		if len(key) > MaxLabelKeyLen || !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: invalid key %q", ErrInvalidLabels, key)
+		}
+
+		if len(value) > MaxLabelValueLen {
+			return fmt.Errorf("%w: value of %q longer than %d characters", ErrInvalidLabels, key, MaxLabelValueLen)
+		}
+	}
+
+	return nil
+}
+
+// Contains reports whether l carries every label of selector, mirroring the
+// jsonb containment filter of the list queries.
+func (l Labels) Contains(selector Labels) bool {
+	for key, value := range selector {
+		if got, ok := l[key]; !ok || got != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// jsonb encodes the labels for a jsonb parameter; nil encodes as an empty
+// object so the column never holds a JSON null.
+func (l Labels) jsonb() string {
+	if l == nil {
+		return "{}"
+	}
+
+	b, _ := json.Marshal(l) // a map[string]string always encodes
+
+	return string(b)
+}
+
+// whereLabels keeps rows whose labels column contains every given label.
+func whereLabels(q *bun.SelectQuery, column string, labels Labels) *bun.SelectQuery {
+	if len(labels) == 0 {
+		return q
+	}
+
+	return q.Where("? @> ?::jsonb", bun.Ident(column), labels.jsonb())
+}
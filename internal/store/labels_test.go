@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLabelsValidate(t *testing.T) {
+	t.Parallel()
+
+	tooMany := Labels{}
+	for i := range MaxLabels + 1 {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+
+	tests := []struct {
+		name    string
+		labels  Labels
+		wantErr bool
+	}{
+		{name: "nil", labels: nil},
+		{name: "simple", labels: Labels{"env": "prod", "team": "payments"}},
+		{name: "prefixed key", labels: Labels{"example.com/owner": "alice"}},
+		{name: "empty value", labels: Labels{"deprecated": ""}},
+		{name: "empty key", labels: Labels{"": "x"}, wantErr: true},
+		{name: "key with equals", labels: Labels{"a=b": "x"}, wantErr: true},
+		{name: "key with trailing dash", labels: Labels{"env-": "x"}, wantErr: true},
+		{name: "key too long", labels: Labels{strings.Repeat("k", MaxLabelKeyLen+1): "x"}, wantErr: true},
+		{name: "value too long", labels: Labels{"env": strings.Repeat("v", MaxLabelValueLen+1)}, wantErr: true},
+		{name: "too many", labels: tooMany, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.labels.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidLabels) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrInvalidLabels)
+			}
+		})
+	}
+}
+
+func TestLabelsContains(t *testing.T) {
+	t.Parallel()
+
+	labels := Labels{"env": "prod", "team": "payments"}
+
+	if !labels.Contains(nil) {
+		t.Error("every label set should contain an empty selector")
+	}
+
+	if !labels.Contains(Labels{"env": "prod"}) {
+		t.Error("should contain env=prod")
+	}
+
+	if labels.Contains(Labels{"env": "staging"}) {
+		t.Error("should not contain env=staging")
+	}
+
+	if labels.Contains(Labels{"env": "prod", "region": "eu"}) {
+		t.Error("should not contain a missing key")
+	}
+}
+
+func TestListFiltersByLabels(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, user, db, _ := setupRequestFixtures(t, ctx, store, "labels")
+
+	if err := store.UpdateUser(ctx, user.UID, UserUpdate{Labels: Labels{"team": "payments"}}); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+
+	labels := Labels{"env": "prod"}
+	if err := store.UpdateServer(ctx, db.UID, ServerUpdate{Labels: labels}, testEncryptionKey()); err != nil {
+		t.Fatalf("UpdateServer() error = %v", err)
+	}
+
+	now := time.Now()
+	grant, err := store.CreateGrant(ctx, &Grant{
+		UserID:     user.UID,
+		DatabaseID: db.UID,
+		GrantedBy:  admin.UID,
+		StartsAt:   now,
+		ExpiresAt:  now.Add(time.Hour),
+		Labels:     Labels{"ticket": "OPS-42"},
+	})
+	if err != nil {
+		t.Fatalf("CreateGrant() error = %v", err)
+	}
+
+	t.Run("users", func(t *testing.T) {
+		users, err := store.ListUsers(ctx, UserFilter{Labels: Labels{"team": "payments"}})
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+
+		if len(users) != 1 || users[0].UID != user.UID {
+			t.Errorf("ListUsers(team=payments) = %v, want only %s", users, user.Username)
+		}
+	})
+
+	t.Run("servers", func(t *testing.T) {
+		servers, err := store.ListServers(ctx, ServerFilter{Labels: labels})
+		if err != nil {
+			t.Fatalf("ListServers() error = %v", err)
+		}
+
+		if len(servers) != 1 || servers[0].UID != db.UID {
+			t.Errorf("ListServers(env=prod) returned %d servers, want only %s", len(servers), db.Name)
+		}
+
+		servers, err = store.ListServers(ctx, ServerFilter{Labels: Labels{"env": "staging"}})
+		if err != nil {
+			t.Fatalf("ListServers() error = %v", err)
+		}
+
+		if len(servers) != 0 {
+			t.Errorf("ListServers(env=staging) returned %d servers, want 0", len(servers))
+		}
+	})
+
+	t.Run("grants", func(t *testing.T) {
+		if err := store.SetGrantLabels(ctx, grant.UID, Labels{"ticket": "OPS-43"}); err != nil {
+			t.Fatalf("SetGrantLabels() error = %v", err)
+		}
+
+		grants, err := store.ListGrants(ctx, GrantFilter{Labels: Labels{"ticket": "OPS-43"}})
+		if err != nil {
+			t.Fatalf("ListGrants() error = %v", err)
+		}
+
+		if len(grants) != 1 || grants[0].UID != grant.UID {
+			t.Errorf("ListGrants(ticket=OPS-43) returned %d grants, want only %s", len(grants), grant.UID)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migrationLockKey identifies dbbat's migration advisory lock. Any constant
+// works as long as every instance uses the same one.
+const migrationLockKey int64 = 0x64626261746d6967 // "dbbatmig" in ASCII
+
+// DefaultMigrationLockTimeout is how long an instance waits for another one
+// to finish migrating, used when Options leaves it unset.
+const DefaultMigrationLockTimeout = 5 * time.Minute
+
+// migrationLockPollInterval is how often a waiting instance retries the lock.
+const migrationLockPollInterval = time.Second
+
+// ErrMigrationLockTimeout is returned when another instance held the
+// migration lock for longer than the configured timeout.
+var ErrMigrationLockTimeout = errors.New("timed out waiting for another instance to finish migrations")
+
+// withMigrationLock runs fn while holding a session-level advisory lock, so
+// instances starting together migrate one after the other: the first applies
+// the migrations, the others then find nothing pending. The lock lives on a
+// dedicated connection and is released with it, even if the process dies.
+func (s *Store) withMigrationLock(ctx context.Context, fn func() error) error {
+	conn, err := s.pool.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection for the migration lock: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	tryLock := func() (bool, error) {
+		var acquired bool
+		if err := conn.NewRaw("SELECT pg_try_advisory_lock(?)", migrationLockKey).Scan(ctx, &acquired); err != nil {
+			return false, fmt.Errorf("failed to take the migration lock: %w", err)
+		}
+
+		return acquired, nil
+	}
+
+	acquired, err := tryLock()
+	if err != nil {
+		return err
+	}
+
+	if !acquired {
+		timeout := s.migrationLockTimeout
+		slog.InfoContext(ctx, "Waiting for another instance to finish migrations", slog.Duration("timeout", timeout))
+
+		deadline := time.Now().Add(timeout)
+		ticker := time.NewTicker(migrationLockPollInterval)
+		defer ticker.Stop()
+
+		for !acquired {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			if acquired, err = tryLock(); err != nil {
+				return err
+			}
+
+			if !acquired && time.Now().After(deadline) {
+				return fmt.Errorf("%w (waited %s)", ErrMigrationLockTimeout, timeout)
+			}
+		}
+
+		slog.InfoContext(ctx, "Migration lock acquired")
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock(?)", migrationLockKey); err != nil {
+			slog.WarnContext(ctx, "Failed to release the migration lock", slog.Any("error", err))
+		}
+	}()
+
+	return fn()
+}
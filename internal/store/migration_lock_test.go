@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithMigrationLock(t *testing.T) {
+	s := setupTestStoreNoCleanup(t)
+	ctx := context.Background()
+
+	s.migrationLockTimeout = 1500 * time.Millisecond
+
+	// Another instance holds the lock on its own session.
+	holder, err := s.pool.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn() error = %v", err)
+	}
+	defer func() { _ = holder.Close() }()
+
+	if _, err := holder.ExecContext(ctx, "SELECT pg_advisory_lock(?)", migrationLockKey); err != nil {
+		t.Fatalf("pg_advisory_lock error = %v", err)
+	}
+
+	ran := false
+	err = s.withMigrationLock(ctx, func() error {
+		ran = true
+		return nil
+	})
+
+	if !errors.Is(err, ErrMigrationLockTimeout) {
+		t.Fatalf("withMigrationLock() error = %v, want %v", err, ErrMigrationLockTimeout)
+	}
+
+	if ran {
+		t.Fatal("fn ran without the lock")
+	}
+
+	// Released while we wait: the waiter proceeds.
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		_, _ = holder.ExecContext(ctx, "SELECT pg_advisory_unlock(?)", migrationLockKey)
+	}()
+
+	if err := s.withMigrationLock(ctx, func() error {
+		ran = true
+		return nil
+	}); err != nil {
+		t.Fatalf("withMigrationLock() error = %v", err)
+	}
+
+	if !ran {
+		t.Error("fn did not run once the lock was released")
+	}
+
+	// And the lock is free again afterwards.
+	var acquired bool
+	if err := holder.NewRaw("SELECT pg_try_advisory_lock(?)", migrationLockKey).Scan(ctx, &acquired); err != nil {
+		t.Fatalf("pg_try_advisory_lock error = %v", err)
+	}
+
+	if !acquired {
+		t.Error("migration lock still held after withMigrationLock returned")
+	}
+
+	_, _ = holder.ExecContext(ctx, "SELECT pg_advisory_unlock(?)", migrationLockKey)
+}
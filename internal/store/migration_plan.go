@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/uptrace/bun/migrate"
+
+	"github.com/fclairamb/dbbat/internal/migrations"
+)
+
+// Bookkeeping tables of the bun migrator (its defaults).
+const (
+	migrationsTable     = "bun_migrations"
+	migrationLocksTable = "bun_migration_locks"
+)
+
+// migrationLockRow mirrors the bun migrator's unexported lock model, so the
+// printed bookkeeping DDL matches what Migrator.Init would create.
+type migrationLockRow struct {
+	ID        int64  `bun:",pk,autoincrement"`
+	TableName string `bun:",unique"`
+}
+
+// PendingMigration is a migration not yet applied to the storage database.
+type PendingMigration struct {
+	Name    string // Timestamp, as recorded in bun_migrations
+	Comment string
+	SQL     string
+}
+
+// MigrationPlan is what Migrate would do, computed without changing anything.
+type MigrationPlan struct {
+	// Init creates the migration bookkeeping tables; empty when they exist.
+	Init []string
+	// GroupID is the migration group the pending migrations would form.
+	GroupID int64
+	Pending []PendingMigration
+	// record holds, per pending migration, the statement marking it applied.
+	record []string
+}
+
+// PlanMigrations lists the pending migrations with their SQL. It only reads,
+// so it is safe against a database whose DDL goes through change management.
+// Open the Store with Options.SkipMigrations for that.
+func (s *Store) PlanMigrations(ctx context.Context) (*MigrationPlan, error) {
+	var exists bool
+	if err := s.pool.NewRaw("SELECT to_regclass(?) IS NOT NULL", migrationsTable).Scan(ctx, &exists); err != nil {
+		return nil, fmt.Errorf("failed to check migration table: %w", err)
+	}
+
+	plan := &MigrationPlan{GroupID: 1}
+
+	var pending migrate.MigrationSlice
+
+	if exists {
+		migrator := migrate.NewMigrator(s.pool, migrations.Migrations)
+
+		ms, err := migrator.MigrationsWithStatus(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get migration status: %w", err)
+		}
+
+		plan.GroupID = ms.LastGroupID() + 1
+		pending = ms.Unapplied()
+	} else {
+		plan.Init = []string{
+			s.pool.NewCreateTable().
+				Model((*migrate.Migration)(nil)).
+				ModelTableExpr(migrationsTable).
+				IfNotExists().
+				String(),
+			s.pool.NewCreateTable().
+				Model((*migrationLockRow)(nil)).
+				ModelTableExpr(migrationLocksTable).
+				IfNotExists().
+				String(),
+		}
+		pending = migrations.Migrations.Sorted()
+	}
+
+	for _, m := range pending {
+		upSQL, err := migrations.UpSQL(m)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Pending = append(plan.Pending, PendingMigration{Name: m.Name, Comment: m.Comment, SQL: upSQL})
+		plan.record = append(plan.record, s.pool.NewInsert().
+			Model(&migrate.Migration{Name: m.Name, GroupID: plan.GroupID}).
+			ModelTableExpr(migrationsTable).
+			String())
+	}
+
+	return plan, nil
+}
+
+// SQL renders the plan as a script a DBA can review and apply with psql. Each
+// migration is followed by the insert recording it, so dbbat sees it as
+// applied and runs no DDL itself at the next start.
+func (p *MigrationPlan) SQL() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- dbbat storage migrations: %d pending (group %d)\n", len(p.Pending), p.GroupID)
+	b.WriteString("-- Apply with: psql \"$DSN\" -v ON_ERROR_STOP=1 --single-transaction -f <this file>\n")
+
+	for _, stmt := range p.Init {
+		b.WriteString("\n" + stmt + ";\n")
+	}
+
+	for i, m := range p.Pending {
+		fmt.Fprintf(&b, "\n-- Migration %s_%s\n\n", m.Name, m.Comment)
+		b.WriteString(strings.TrimRight(m.SQL, "\n") + "\n\n")
+		b.WriteString(p.record[i] + ";\n")
+	}
+
+	return b.String()
+}
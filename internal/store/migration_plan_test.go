@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fclairamb/dbbat/internal/migrations"
+)
+
+func TestPlanMigrations(t *testing.T) {
+	dsn := setupPostgresContainer(t)
+	ctx := context.Background()
+
+	// Plan against a database of its own: it must start without any schema.
+	admin, err := New(ctx, dsn, Options{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer admin.Close()
+
+	if _, err := admin.pool.ExecContext(ctx, "DROP DATABASE IF EXISTS dbbat_plan_test"); err != nil {
+		t.Fatalf("drop database: %v", err)
+	}
+
+	if _, err := admin.pool.ExecContext(ctx, "CREATE DATABASE dbbat_plan_test"); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+
+	planDSN := strings.Replace(dsn, "/dbbat_test?", "/dbbat_plan_test?", 1)
+
+	empty, err := New(ctx, planDSN, Options{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("New(SkipMigrations) error = %v", err)
+	}
+	defer empty.Close()
+
+	plan, err := empty.PlanMigrations(ctx)
+	if err != nil {
+		t.Fatalf("PlanMigrations() error = %v", err)
+	}
+
+	if len(plan.Init) != 2 || plan.GroupID != 1 {
+		t.Errorf("plan on an empty database: %d init statements, group %d; want 2, 1", len(plan.Init), plan.GroupID)
+	}
+
+	if want := len(migrations.Migrations.Sorted()); len(plan.Pending) != want {
+		t.Fatalf("pending = %d, want %d", len(plan.Pending), want)
+	}
+
+	var tables int
+	if err := empty.pool.NewRaw("SELECT count(*) FROM pg_tables WHERE schemaname = 'public'").Scan(ctx, &tables); err != nil {
+		t.Fatalf("count tables: %v", err)
+	}
+
+	if tables != 0 {
+		t.Fatalf("planning created %d tables, want none", tables)
+	}
+
+	// Applying the printed script by hand must leave nothing for Migrate.
+	if _, err := empty.pool.ExecContext(ctx, plan.SQL()); err != nil {
+		t.Fatalf("applying the plan SQL: %v", err)
+	}
+
+	migrated, err := New(ctx, planDSN)
+	if err != nil {
+		t.Fatalf("New() after applying the plan: %v", err)
+	}
+	defer migrated.Close()
+
+	status, err := migrated.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus() error = %v", err)
+	}
+
+	for _, m := range status {
+		if m.MigratedAt.IsZero() {
+			t.Errorf("migration %s not recorded as applied", m.Name)
+		}
+	}
+
+	again, err := migrated.PlanMigrations(ctx)
+	if err != nil {
+		t.Fatalf("PlanMigrations() error = %v", err)
+	}
+
+	if len(again.Pending) != 0 || len(again.Init) != 0 || again.GroupID != 2 {
+		t.Errorf("plan after applying: %d pending, %d init, group %d; want 0, 0, 2",
+			len(again.Pending), len(again.Init), again.GroupID)
+	}
+}
+
+func TestMigrationPlanSQL(t *testing.T) {
+	t.Parallel()
+
+	plan := &MigrationPlan{
+		GroupID: 3,
+		Pending: []PendingMigration{{Name: "20260101000000", Comment: "things", SQL: "CREATE TABLE things (id int);\n"}},
+		record:  []string{"INSERT INTO bun_migrations (name, group_id) VALUES ('20260101000000', 3)"},
+	}
+
+	got := plan.SQL()
+
+	for _, want := range []string{
+		"1 pending (group 3)",
+		"-- Migration 20260101000000_things",
+		"CREATE TABLE things (id int);\n\nINSERT INTO bun_migrations",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SQL() missing %q:\n%s", want, got)
+		}
+	}
+}
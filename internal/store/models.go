@@ -3,6 +3,7 @@ package store
 import (
 	"encoding/json"
 	"net"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,20 +15,87 @@ const (
 	RoleAdmin     = "admin"
 	RoleViewer    = "viewer"
 	RoleConnector = "connector"
+	// RoleAuditor is a viewer that sees statements but never result data:
+	// no captured rows, no session dumps.
+	RoleAuditor = "auditor"
 )
 
-// Control constants for grant restrictions
+// Permissions granted by roles, checked with User.Can.
+const (
+	// PermissionQueriesRead covers the observability data of every user:
+	// connections, query log (SQL text, parameters, timings) and audit log.
+	PermissionQueriesRead = "queries:read"
+	// PermissionRowsRead covers result data: captured query rows and
+	// connection dumps.
+	PermissionRowsRead = "rows:read"
+	// PermissionRawSQLRead covers the literal values of logged statements;
+	// without it the query log is served redacted (see internal/sqlnorm).
+	PermissionRawSQLRead = "sql:raw"
+)
+
+// rolePermissions maps each role to the permissions it grants. Connectors
+// only see their own connections and grants, which needs no permission.
+var rolePermissions = map[string][]string{
+	RoleAdmin:   {PermissionQueriesRead, PermissionRowsRead, PermissionRawSQLRead},
+	RoleViewer:  {PermissionQueriesRead, PermissionRowsRead, PermissionRawSQLRead},
+	RoleAuditor: {PermissionQueriesRead},
+}
+
+// IsKnownRole reports whether role is one of the role constants.
+func IsKnownRole(role string) bool {
+	_, ok := rolePermissions[role]
+
+	return ok || role == RoleConnector
+}
+
+// Control constants for grant restrictions. See controls.go for the
+// vocabulary, aliases and per-protocol support.
 const (
 	ControlReadOnly  = "read_only"
 	ControlBlockCopy = "block_copy"
 	ControlBlockDDL  = "block_ddl"
+	// ControlAllowReplication lets the grant's user open PostgreSQL
+	// replication connections (replication=true|database), which are
+	// refused otherwise.
+	ControlAllowReplication = "allow_replication"
+	// ControlBlockCopyOut blocks exporting data to the client or the server
+	// filesystem (COPY ... TO) while still allowing COPY ... FROM.
+	ControlBlockCopyOut = "block_copy_out"
+	// ControlMaskPII masks result columns whose name identifies personal
+	// data (email, phone, ssn, ...).
+	ControlMaskPII = "mask_pii"
+	// ControlMaxRows caps the rows returned by a single statement; it is
+	// written max_rows:N.
+	ControlMaxRows = "max_rows"
+	// ControlMaxBytesPerSecond throttles the result rows and COPY data
+	// forwarded for the grant; it is written max_bytes_per_second:N.
+	ControlMaxBytesPerSecond = "max_bytes_per_second"
+	// ControlDryRun runs every statement in a transaction that is rolled
+	// back, so that writes can be reviewed without being committed.
+	ControlDryRun = "dry_run"
+	// ControlWriteRequiresApproval holds write statements until an admin
+	// other than the grant's user approves them.
+	ControlWriteRequiresApproval = "write_requires_approval"
 )
 
-// ValidControls lists all valid control values
+// Access levels of a grant, recorded on its connections and queries.
+const (
+	AccessLevelReadOnly  = "read_only"  // The grant carries read_only
+	AccessLevelReadWrite = "read_write" // It does not
+)
+
+// ValidControls lists all valid control names
 var ValidControls = []string{
 	ControlReadOnly,
 	ControlBlockCopy,
 	ControlBlockDDL,
+	ControlAllowReplication,
+	ControlBlockCopyOut,
+	ControlMaskPII,
+	ControlMaxRows,
+	ControlMaxBytesPerSecond,
+	ControlDryRun,
+	ControlWriteRequiresApproval,
 }
 
 // User represents a DBBat user
@@ -48,6 +116,10 @@ type User struct {
 	// APIKey.ProtocolData — rather than protocol-specific user columns.
 	// nil until first needed (populated lazily at API key creation).
 	ProtocolData *UserProtocolData `bun:"protocol_data,type:jsonb,nullzero" json:"-"`
+	Labels       Labels            `bun:"labels,type:jsonb,nullzero,notnull,default:'{}'" json:"labels"`
+	// RateLimitPerMinute overrides the API rate limit of the user's roles and
+	// the configured default; nil when not overridden.
+	RateLimitPerMinute *int `bun:"rate_limit_per_minute" json:"rate_limit_per_minute,omitempty"`
 }
 
 // UserProtocolData is the per-protocol material attached to a user, stored as
@@ -63,6 +135,8 @@ type UserProtocolData struct {
 // keeps the cleartext password off the wire) instead of being forced onto
 // authMechanism=PLAIN. Populated lazily whenever the user's password is set
 // after this feature shipped; absent otherwise (PLAIN stays the fallback).
+// SCRAM-SHA-256 credentials don't depend on the protocol, so the PostgreSQL
+// proxy verifies its SCRAM clients against the same verifier.
 type MongoUserData struct {
 	SCRAMSHA256 *MongoSCRAMCredentials `json:"scram_sha256,omitempty"`
 }
@@ -150,10 +224,29 @@ func (u *User) IsConnector() bool {
 	return u.HasRole(RoleConnector)
 }
 
+// Can returns true if one of the user's roles grants the permission
+func (u *User) Can(permission string) bool {
+	for _, r := range u.Roles {
+		if slices.Contains(rolePermissions[r], permission) {
+			return true
+		}
+	}
+	return false
+}
+
 // UserUpdate represents fields that can be updated
 type UserUpdate struct {
 	PasswordHash *string
 	Roles        []string
+	Labels       Labels // Non-nil replaces the labels; an empty map clears them
+}
+
+// UserFilter narrows ListUsers queries.
+type UserFilter struct {
+	// Labels keeps users carrying every one of these labels.
+	Labels Labels
+	Limit  int
+	Offset int
 }
 
 // Protocol constants for database connections
@@ -195,8 +288,24 @@ type Server struct {
 	Username          string `bun:"username,notnull" json:"username"`
 	Password          string `bun:"-" json:"-"`                          // Decrypted, not stored
 	PasswordEncrypted []byte `bun:"password_encrypted,notnull" json:"-"` // Encrypted form
+	// ReadOnlyUsername, when set, is the upstream login of read-only grants,
+	// so the target's own permissions back read_only; empty logs every grant
+	// in as Username.
+	ReadOnlyUsername          string `bun:"readonly_username,nullzero" json:"readonly_username,omitempty"`
+	ReadOnlyPassword          string `bun:"-" json:"-"`                           // Decrypted, not stored
+	ReadOnlyPasswordEncrypted []byte `bun:"readonly_password_encrypted" json:"-"` // Encrypted form
+	// SecretRef, when set, points at the password of Username in an external
+	// secrets manager (vault://, aws-sm:// or gcp-sm://); it is fetched when
+	// connecting upstream instead of decrypting PasswordEncrypted.
+	SecretRef string `bun:"secret_ref,nullzero" json:"secret_ref,omitempty"`
+	// ReadReplicas lists the read replicas, host or host:port, the sessions
+	// of read-only grants are routed to; empty sends every session to Host.
+	ReadReplicas []string `bun:"read_replicas,array,notnull,default:'{}'" json:"read_replicas,omitempty"`
 	// SSLMode is meaningful for database targets only; nullable for SSH bastions.
-	SSLMode           string  `bun:"ssl_mode" json:"ssl_mode"`
+	SSLMode string `bun:"ssl_mode" json:"ssl_mode"`
+	// SSLRootCert is a PEM CA bundle the upstream certificate is verified
+	// against in the verifying ssl_modes; empty uses the system roots.
+	SSLRootCert       string  `bun:"ssl_root_cert,nullzero" json:"ssl_root_cert,omitempty"`
 	Protocol          string  `bun:"protocol,notnull,default:'postgresql'" json:"protocol"`
 	OracleServiceName *string `bun:"oracle_service_name" json:"oracle_service_name,omitempty"`
 	// ViaUID references an SSH server row to tunnel through; nil = direct dial.
@@ -206,10 +315,25 @@ type Server struct {
 	// mirroring User.ProtocolData — rather than a dedicated column per setting.
 	ProtocolData *ServerProtocolData `bun:"protocol_data,type:jsonb,nullzero" json:"-"`
 	Listable     bool                `bun:"listable,notnull" json:"listable"`
-	CreatedBy    *uuid.UUID          `bun:"created_by,type:uuid" json:"created_by"`
-	CreatedAt    time.Time           `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
-	UpdatedAt    time.Time           `bun:"updated_at,notnull,default:current_timestamp" json:"updated_at"`
-	DeletedAt    *time.Time          `bun:"deleted_at,soft_delete" json:"-"`
+	// BlockedMessage is returned to clients whose statement a grant control
+	// blocked; empty falls back to the proxy-wide message.
+	BlockedMessage string `bun:"blocked_message,nullzero" json:"blocked_message,omitempty"`
+	// SlowQueryThresholdMs is the duration past which the statements run on
+	// the database have their plan captured (PostgreSQL only); nil when unset.
+	SlowQueryThresholdMs *int `bun:"slow_query_threshold_ms" json:"slow_query_threshold_ms,omitempty"`
+	// QueryStorage overrides the global query storage and retention settings
+	// for the database; nil when unset.
+	QueryStorage *QueryStorageOverrides `bun:"query_storage,type:jsonb,nullzero" json:"query_storage,omitempty"`
+	// GrantDefaults pre-fill and bound the grants on the database; nil when
+	// unset.
+	GrantDefaults *GrantDefaults `bun:"grant_defaults,type:jsonb,nullzero" json:"grant_defaults,omitempty"`
+	RowQuota      *RowQuota      `bun:"row_quota,type:jsonb,nullzero" json:"row_quota,omitempty"`           // Caps the retained result rows; nil when unset
+	ResultMasking *ResultMasking `bun:"result_masking,type:jsonb,nullzero" json:"result_masking,omitempty"` // Columns masked in captured rows; nil when unset
+	Labels        Labels         `bun:"labels,type:jsonb,nullzero,notnull,default:'{}'" json:"labels"`
+	CreatedBy     *uuid.UUID     `bun:"created_by,type:uuid" json:"created_by"`
+	CreatedAt     time.Time      `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
+	UpdatedAt     time.Time      `bun:"updated_at,notnull,default:current_timestamp" json:"updated_at"`
+	DeletedAt     *time.Time     `bun:"deleted_at,soft_delete" json:"-"`
 }
 
 // ServerProtocolData is per-protocol material attached to a server, stored
@@ -275,18 +399,45 @@ type ServerUpdate struct {
 	DatabaseName      *string
 	Username          *string
 	Password          *string // Plaintext password to encrypt
+	ReadOnlyUsername  *string // Empty string removes the read-only credentials
+	ReadOnlyPassword  *string // Plaintext read-only password to encrypt
+	SecretRef         *string // Empty string clears the reference
 	SSLMode           *string
+	SSLRootCert       *string // Empty string clears the bundle
 	Protocol          *string
 	OracleServiceName *string
 	MongoAuthSource   *string
 	Listable          *bool
-	ViaUID            *uuid.UUID // Set to tunnel through an SSH server
-	ClearViaUID       bool       // When true, clears via_uid (direct dial)
+	BlockedMessage    *string        // Empty string clears the override
+	GrantDefaults     *GrantDefaults // Non-nil replaces the defaults; a zero value clears them
+	RowQuota          *RowQuota      // Non-nil replaces the row quota; a zero value clears it
+	ResultMasking     *ResultMasking // Non-nil replaces the result masking; a zero value clears it
+	Labels            Labels         // Non-nil replaces the labels; an empty map clears them
+	ReadReplicas      []string       // Non-nil replaces the read replicas; an empty slice clears them
+	ViaUID            *uuid.UUID     // Set to tunnel through an SSH server
+	ClearViaUID       bool           // When true, clears via_uid (direct dial)
+	// SlowQueryThresholdMs, when present, replaces the slow query
+	// threshold; 0 clears it.
+	SlowQueryThresholdMs *int
+	// QueryStorage, when present, replaces the query storage overrides; a
+	// zero value clears them.
+	QueryStorage *QueryStorageOverrides
 	// SSH secrets (plaintext, to encrypt). Set on SSH server rows.
 	SSHPrivateKey *string
 	SSHPassphrase *string
 }
 
+// ServerClone holds the overrides applied when cloning a server. Nil fields
+// keep the source server's value.
+type ServerClone struct {
+	Name         string
+	Description  *string
+	Host         *string
+	Port         *int
+	DatabaseName *string
+	CreatedBy    *uuid.UUID
+}
+
 // Connection represents a connection through the proxy
 type Connection struct {
 	bun.BaseModel `bun:"table:connections,alias:c"`
@@ -300,15 +451,97 @@ type Connection struct {
 	DisconnectedAt   *time.Time `bun:"disconnected_at" json:"disconnected_at"`
 	Queries          int64      `bun:"queries,notnull,default:0" json:"queries"`
 	BytesTransferred int64      `bun:"bytes_transferred,notnull,default:0" json:"bytes_transferred"`
+	// RowsReturned counts the result rows sent to the client (PostgreSQL).
+	RowsReturned int64 `bun:"rows_returned,notnull,default:0" json:"rows_returned"`
+	// ClientInfo is what the client declared about itself at connect time.
+	ClientInfo *ClientInfo `bun:"client_info,type:jsonb,nullzero" json:"client_info,omitempty"`
+	// GrantID and AccessLevel record the grant the connection was authorized
+	// by. Absent for connections recorded before they were.
+	GrantID     *uuid.UUID `bun:"grant_id,type:uuid" json:"grant_id,omitempty"`
+	AccessLevel string     `bun:"access_level,nullzero" json:"access_level,omitempty"`
+	// DisconnectReason is why the connection ended, one of the
+	// DisconnectReason* values; empty while open or when not recorded.
+	DisconnectReason string `bun:"disconnect_reason,nullzero" json:"disconnect_reason,omitempty"`
+	// InstanceID is the dbbat instance that handled the connection. Absent
+	// for connections recorded before instances were.
+	InstanceID *uuid.UUID `bun:"instance_id,type:uuid" json:"instance_id,omitempty"`
+}
+
+// ServerHealth is the outcome of the latest connectivity check of a database
+// target, run in the background or from the API. Stage and Code are the
+// conncheck ones; Message is safe to show to an admin.
+type ServerHealth struct {
+	bun.BaseModel `bun:"table:server_health,alias:sh"`
+
+	ServerID   uuid.UUID `bun:"server_id,pk,type:uuid" json:"server_id"`
+	CheckedAt  time.Time `bun:"checked_at,notnull" json:"checked_at"`
+	OK         bool      `bun:"ok,notnull" json:"ok"`
+	Stage      string    `bun:"stage,notnull" json:"stage"`
+	Code       string    `bun:"code,notnull" json:"code"`
+	Message    string    `bun:"message,notnull" json:"message"`
+	DurationMs int64     `bun:"duration_ms,notnull" json:"duration_ms"`
+	// LastOKAt is when the server last passed a check, absent if never.
+	LastOKAt *time.Time `bun:"last_ok_at" json:"last_ok_at,omitempty"`
+}
+
+// Instance is a running (or past) dbbat process, registered at startup and
+// kept alive by a heartbeat.
+type Instance struct {
+	bun.BaseModel `bun:"table:instances,alias:i"`
+
+	UID      uuid.UUID `bun:"uid,pk,type:uuid" json:"uid"` // UUIDv7 set in Go
+	Hostname string    `bun:"hostname,notnull" json:"hostname"`
+	Version  string    `bun:"version,notnull" json:"version"`
+	// Listen maps each enabled listener ("api", "pg", "oracle", "mysql",
+	// "mongo") to its address.
+	Listen          map[string]string `bun:"listen,type:jsonb,notnull" json:"listen"`
+	StartedAt       time.Time         `bun:"started_at,notnull" json:"started_at"`
+	LastHeartbeatAt time.Time         `bun:"last_heartbeat_at,notnull" json:"last_heartbeat_at"`
+	StoppedAt       *time.Time        `bun:"stopped_at" json:"stopped_at,omitempty"`
+	// Live is set by ListInstances: the instance has not stopped and its
+	// last heartbeat is recent.
+	Live bool `bun:"-" json:"live"`
+}
+
+// Reasons a proxied connection ended, recorded by CloseConnection.
+const (
+	DisconnectReasonClientClosed   = "client_closed"   // the client closed the connection
+	DisconnectReasonClientError    = "client_error"    // reading from or writing to the client failed
+	DisconnectReasonUpstreamClosed = "upstream_closed" // the upstream server closed the connection
+	DisconnectReasonUpstreamError  = "upstream_error"  // reading from or writing to upstream failed
+	DisconnectReasonLimitExceeded  = "limit_exceeded"  // the grant expired, was revoked or ran out of quota
+	DisconnectReasonIdleTimeout    = "idle_timeout"    // the session was idle for longer than the proxy allows
+	DisconnectReasonMaxDuration    = "max_duration"    // the session reached the proxy's maximum duration
+	DisconnectReasonTerminated     = "terminated"      // an administrator terminated the connection via the API
+)
+
+// ClientInfo describes the client program behind a connection, as declared
+// by the client during the protocol handshake: PostgreSQL startup parameters,
+// MySQL connection attributes, MongoDB hello metadata, Oracle AUTH keys. It is
+// self-reported, so it tells admins which tools are in use, not who is using
+// them.
+type ClientInfo struct {
+	ApplicationName string `json:"application_name,omitempty"`
+	Driver          string `json:"driver,omitempty"`
+	DriverVersion   string `json:"driver_version,omitempty"`
+	ClientEncoding  string `json:"client_encoding,omitempty"`
+	// ProtocolVersion is the wire protocol version the client asked for
+	// (PostgreSQL "3.0", Oracle TNS "318").
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+	// Parameters holds the other declared settings (PostgreSQL DateStyle,
+	// MySQL _os, MongoDB platform, ...).
+	Parameters map[string]string `json:"parameters,omitempty"`
 }
 
 // ConnectionFilter represents filters for listing connections
 type ConnectionFilter struct {
-	UserID     *uuid.UUID
-	DatabaseID *uuid.UUID
-	BeforeUID  *uuid.UUID // Cursor: return connections with UID < this value
-	Limit      int
-	Offset     int
+	UserID          *uuid.UUID
+	DatabaseID      *uuid.UUID
+	ApplicationName *string    // Client-declared application name (exact match)
+	InstanceID      *uuid.UUID // dbbat instance that handled the connection
+	BeforeUID       *uuid.UUID // Cursor: return connections with UID < this value
+	Limit           int
+	Offset          int
 }
 
 // QueryParameters stores parameter values for prepared statements
@@ -333,11 +566,62 @@ type Query struct {
 	Error         *string          `bun:"error" json:"error"`
 	CopyFormat    *string          `bun:"copy_format" json:"copy_format,omitempty"`       // 'text', 'csv', 'binary', or nil for non-COPY
 	CopyDirection *string          `bun:"copy_direction" json:"copy_direction,omitempty"` // 'in', 'out', or nil for non-COPY
+	// DryRun marks a query run under the dry_run control: its effects, rows
+	// affected and returned rows included, were rolled back.
+	DryRun bool `bun:"dry_run,notnull,default:false" json:"dry_run,omitempty"`
+	// Fingerprint groups the statements of the same shape (see
+	// sqlnorm.Fingerprint); set by the proxy, absent for queries logged
+	// before it was.
+	Fingerprint string `bun:"fingerprint,nullzero" json:"fingerprint,omitempty"`
+
+	QueryTiming
+
+	// SQLTruncated marks SQL text cut to the configured maximum size: it then
+	// holds the start and end of the statement, SQLTextBytes the original size
+	// and SQLTextSHA256 the hash of the full text.
+	SQLTruncated  bool    `bun:"sql_truncated,notnull,default:false" json:"sql_truncated,omitempty"`
+	SQLTextBytes  *int64  `bun:"sql_text_bytes" json:"sql_text_bytes,omitempty"`
+	SQLTextSHA256 *string `bun:"sql_text_sha256" json:"sql_text_sha256,omitempty"`
+
+	// ResultObjectKey names the object the result was spilled to, past the
+	// query storage limits, and SpilledRows counts its rows. The key is only
+	// handed out through a signed download URL.
+	ResultObjectKey *string `bun:"result_object_key" json:"-"`
+	SpilledRows     *int64  `bun:"spilled_rows" json:"spilled_rows,omitempty"`
+
+	// RepeatCount counts the identical consecutive statements folded into this
+	// query (1 when none was), LastExecutedAt is when the last one ran.
+	RepeatCount    int64      `bun:"repeat_count,notnull,default:1" json:"repeat_count"`
+	LastExecutedAt *time.Time `bun:"last_executed_at" json:"last_executed_at,omitempty"`
+
+	// Copied from the connection when the query is logged, so the log is
+	// filtered on them without joins and keeps them once the grant is revoked.
+	// GrantID and AccessLevel are absent for queries logged before they were.
+	UserID      *uuid.UUID `bun:"user_id,type:uuid" json:"user_id,omitempty"`
+	DatabaseID  *uuid.UUID `bun:"database_id,type:uuid" json:"database_id,omitempty"`
+	GrantID     *uuid.UUID `bun:"grant_id,type:uuid" json:"grant_id,omitempty"`
+	AccessLevel string     `bun:"access_level,nullzero" json:"access_level,omitempty"`
+
+	// Redacted is set by the API when literal values were replaced with
+	// placeholders and parameters dropped; not stored in DB.
+	Redacted bool `bun:"-" json:"redacted,omitempty"`
+
+	// Repeated is set by CreateQuery when the statement was folded into the
+	// previous query of its connection instead of inserted; not stored in DB.
+	Repeated bool `bun:"-" json:"-"`
+}
 
-	// Joined fields populated only by ListQueries (via a JOIN on connections);
-	// not stored on the queries table itself.
-	UserID     *uuid.UUID `bun:"user_id,scanonly" json:"user_id,omitempty"`
-	DatabaseID *uuid.UUID `bun:"database_id,scanonly" json:"database_id,omitempty"`
+// QueryTiming breaks the duration of a query down, to tell a slow database
+// from a slow proxy: UpstreamMs is the time upstream took, from the query
+// being forwarded to its last reply (UpstreamFirstByteMs to the first one,
+// StreamingMs from the first to the last, minus the proxy's own work in
+// between), and ProxyOverheadMs the rest of the duration, spent in the
+// proxy. A part is nil when the protocol does not expose it.
+type QueryTiming struct {
+	UpstreamFirstByteMs *float64 `bun:"upstream_first_byte_ms,type:numeric(10,3)" json:"upstream_first_byte_ms,omitempty"`
+	UpstreamMs          *float64 `bun:"upstream_ms,type:numeric(10,3)" json:"upstream_ms,omitempty"`
+	StreamingMs         *float64 `bun:"streaming_ms,type:numeric(10,3)" json:"streaming_ms,omitempty"`
+	ProxyOverheadMs     *float64 `bun:"proxy_overhead_ms,type:numeric(10,3)" json:"proxy_overhead_ms,omitempty"`
 }
 
 // QueryRowModel represents a single row from query results or COPY data
@@ -347,8 +631,23 @@ type QueryRowModel struct {
 	UID          uuid.UUID       `bun:"uid,pk,type:uuid" json:"uid"` // UUIDv7 set in Go
 	QueryID      uuid.UUID       `bun:"query_id,notnull,type:uuid" json:"query_id"`
 	RowNumber    int             `bun:"row_number,notnull" json:"row_number"`
-	RowData      json.RawMessage `bun:"row_data,notnull,type:jsonb" json:"row_data"`
+	RowData      json.RawMessage `bun:"row_data,type:jsonb,nullzero" json:"row_data"`
 	RowSizeBytes int64           `bun:"row_size_bytes,notnull" json:"row_size_bytes"`
+	// RowDataZstd holds the zstd-compressed JSON of the row instead of
+	// RowData when rows are stored compressed; see toRow.
+	RowDataZstd []byte `bun:"row_data_zstd,nullzero" json:"-"`
+}
+
+// QueryRowBlob holds the result rows of one query after compaction: a
+// gzip-compressed JSON array of QueryRow, replacing its query_rows.
+type QueryRowBlob struct {
+	bun.BaseModel `bun:"table:query_row_blobs,alias:qrb"`
+
+	QueryID     uuid.UUID `bun:"query_id,pk,type:uuid"`
+	RowCount    int       `bun:"row_count,notnull"`
+	RawBytes    int64     `bun:"raw_bytes,notnull"`
+	Data        []byte    `bun:"data,notnull"`
+	CompactedAt time.Time `bun:"compacted_at,notnull,default:current_timestamp"`
 }
 
 // QueryRow is an alias for API compatibility (without bun.BaseModel for simpler usage)
@@ -369,13 +668,92 @@ type QueryFilter struct {
 	ConnectionID *uuid.UUID
 	UserID       *uuid.UUID
 	DatabaseID   *uuid.UUID
+	GrantID      *uuid.UUID
+	AccessLevel  string
+	DryRun       *bool
 	StartTime    *time.Time
 	EndTime      *time.Time
+	AlertID      *uuid.UUID // Only the queries matched by this query alert
+	Fingerprint  string     // Only the statements of this fingerprint
+	SQLContains  string     // Case-insensitive substring of the SQL text
+	SQLRegex     string     // Case-insensitive POSIX regular expression matched against the SQL text
+	HasError     *bool      // Only failed (true) or successful (false) queries
+	MinDuration  *float64   // Milliseconds, inclusive
+	MaxDuration  *float64   // Milliseconds, inclusive
+	MinRows      *int64     // Rows affected, inclusive
+	MaxRows      *int64     // Rows affected, inclusive
 	BeforeUID    *uuid.UUID // Cursor: return queries with UID < this value (for stable pagination)
+	AfterUID     *uuid.UUID // Cursor: return queries with UID > this value, oldest first (for following the log)
 	Limit        int
 	Offset       int
 }
 
+// QueryAlert is a saved query log filter. A background evaluator matches the
+// queries logged after CursorUID against it, records each match, and POSTs
+// it to WebhookURL when set.
+type QueryAlert struct {
+	bun.BaseModel `bun:"table:query_alerts,alias:qa"`
+
+	UID             uuid.UUID        `bun:"uid,pk,type:uuid,default:gen_random_uuid()" json:"uid"`
+	Name            string           `bun:"name,notnull" json:"name"`
+	Description     string           `bun:"description,notnull,default:''" json:"description"`
+	Filter          QueryAlertFilter `bun:"filter,type:jsonb,notnull" json:"filter"`
+	WebhookURL      *string          `bun:"webhook_url" json:"webhook_url"`
+	Enabled         bool             `bun:"enabled,notnull,default:true" json:"enabled"`
+	CursorUID       uuid.UUID        `bun:"cursor_uid,notnull,type:uuid" json:"-"` // Last query evaluated
+	MatchCount      int64            `bun:"match_count,notnull,default:0" json:"match_count"`
+	LastEvaluatedAt *time.Time       `bun:"last_evaluated_at" json:"last_evaluated_at"`
+	LastMatchedAt   *time.Time       `bun:"last_matched_at" json:"last_matched_at"`
+	CreatedBy       *uuid.UUID       `bun:"created_by,type:uuid" json:"created_by,omitempty"`
+	CreatedAt       time.Time        `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
+	UpdatedAt       time.Time        `bun:"updated_at,notnull,default:current_timestamp" json:"updated_at"`
+}
+
+// QueryAlertFilter is the part of a QueryFilter a query alert saves. Field
+// names follow the query parameters of the query list.
+type QueryAlertFilter struct {
+	UserID          *uuid.UUID `json:"user_id,omitempty"`
+	DatabaseID      *uuid.UUID `json:"database_id,omitempty"`
+	AccessLevel     string     `json:"access_level,omitempty"`
+	SQLContains     string     `json:"sql,omitempty"`
+	SQLRegex        string     `json:"sql_regex,omitempty"`
+	HasError        *bool      `json:"has_error,omitempty"`
+	MinDurationMs   *float64   `json:"min_duration_ms,omitempty"`
+	MaxDurationMs   *float64   `json:"max_duration_ms,omitempty"`
+	MinRowsAffected *int64     `json:"min_rows_affected,omitempty"`
+	MaxRowsAffected *int64     `json:"max_rows_affected,omitempty"`
+}
+
+// IsEmpty reports whether the filter matches every query.
+func (f QueryAlertFilter) IsEmpty() bool {
+	return f == QueryAlertFilter{}
+}
+
+// QueryFilter returns the query list filter the alert filter stands for.
+func (f QueryAlertFilter) QueryFilter() QueryFilter {
+	return QueryFilter{
+		UserID:      f.UserID,
+		DatabaseID:  f.DatabaseID,
+		AccessLevel: f.AccessLevel,
+		SQLContains: f.SQLContains,
+		SQLRegex:    f.SQLRegex,
+		HasError:    f.HasError,
+		MinDuration: f.MinDurationMs,
+		MaxDuration: f.MaxDurationMs,
+		MinRows:     f.MinRowsAffected,
+		MaxRows:     f.MaxRowsAffected,
+	}
+}
+
+// QueryAlertMatch records a query matched by a query alert.
+type QueryAlertMatch struct {
+	bun.BaseModel `bun:"table:query_alert_matches,alias:qam"`
+
+	AlertUID  uuid.UUID `bun:"alert_uid,pk,type:uuid"`
+	QueryUID  uuid.UUID `bun:"query_uid,pk,type:uuid"`
+	MatchedAt time.Time `bun:"matched_at,notnull,default:current_timestamp"`
+}
+
 // AccessGrant represents an access grant
 type AccessGrant struct {
 	bun.BaseModel `bun:"table:access_grants,alias:ag"`
@@ -383,7 +761,9 @@ type AccessGrant struct {
 	UID                 uuid.UUID  `bun:"uid,pk,type:uuid,default:gen_random_uuid()" json:"uid"`
 	UserID              uuid.UUID  `bun:"user_id,notnull,type:uuid" json:"user_id"`
 	DatabaseID          uuid.UUID  `bun:"database_id,notnull,type:uuid" json:"database_id"`
-	Controls            []string   `bun:"controls,array" json:"controls"` // Array of controls: read_only, block_copy, block_ddl
+	Controls            []string   `bun:"controls,array" json:"controls"`             // Array of controls: read_only, block_ddl, mask_pii, max_rows:N, ...
+	AllowedTables       []string   `bun:"allowed_tables,array" json:"allowed_tables"` // schema.table or schema.* entries; empty allows every table
+	AllowedCIDRs        []string   `bun:"allowed_cidrs,array" json:"allowed_cidrs"`   // Networks clients may connect from; empty allows any client
 	GrantedBy           uuid.UUID  `bun:"granted_by,notnull,type:uuid" json:"granted_by"`
 	StartsAt            time.Time  `bun:"starts_at,notnull" json:"starts_at"`
 	ExpiresAt           time.Time  `bun:"expires_at,notnull" json:"expires_at"`
@@ -391,17 +771,49 @@ type AccessGrant struct {
 	RevokedBy           *uuid.UUID `bun:"revoked_by,type:uuid" json:"revoked_by"`
 	MaxQueryCounts      *int64     `bun:"max_query_counts" json:"max_query_counts"`
 	MaxBytesTransferred *int64     `bun:"max_bytes_transferred" json:"max_bytes_transferred"`
+	MaxRowsReturned     *int64     `bun:"max_rows_returned" json:"max_rows_returned"` // Result rows sent to the client, PostgreSQL only
+	Labels              Labels     `bun:"labels,type:jsonb,nullzero,notnull,default:'{}'" json:"labels"`
 	CreatedAt           time.Time  `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
 
 	// Computed fields (not stored in DB)
-	QueryCount       int64 `bun:"-" json:"query_count"`
-	BytesTransferred int64 `bun:"-" json:"bytes_transferred"`
+	QueryCount       int64               `bun:"-" json:"query_count"`
+	BytesTransferred int64               `bun:"-" json:"bytes_transferred"`
+	RowsReturned     int64               `bun:"-" json:"rows_returned"`
+	QuotaRemaining   GrantQuotaRemaining `bun:"-" json:"quota_remaining"`
+}
+
+// GrantQuotaRemaining is what is left of each quota of a grant; a nil field
+// has no quota.
+type GrantQuotaRemaining struct {
+	Queries          *int64 `json:"queries"`
+	BytesTransferred *int64 `json:"bytes_transferred"`
+	RowsReturned     *int64 `json:"rows_returned"`
+}
+
+// fillQuotaRemaining computes QuotaRemaining from the quotas and counters.
+func (g *AccessGrant) fillQuotaRemaining() {
+	g.QuotaRemaining = GrantQuotaRemaining{
+		Queries:          remainingQuota(g.MaxQueryCounts, g.QueryCount),
+		BytesTransferred: remainingQuota(g.MaxBytesTransferred, g.BytesTransferred),
+		RowsReturned:     remainingQuota(g.MaxRowsReturned, g.RowsReturned),
+	}
+}
+
+// remainingQuota returns what is left of quota once used, never negative.
+func remainingQuota(quota *int64, used int64) *int64 {
+	if quota == nil {
+		return nil
+	}
+
+	remaining := max(*quota-used, 0)
+
+	return &remaining
 }
 
 // HasControl checks if the grant has a specific control enabled
 func (g *AccessGrant) HasControl(control string) bool {
 	for _, c := range g.Controls {
-		if c == control {
+		if c == control || controlAliases[c] == control {
 			return true
 		}
 	}
@@ -413,6 +825,16 @@ func (g *AccessGrant) IsReadOnly() bool {
 	return g.HasControl(ControlReadOnly)
 }
 
+// AccessLevel returns the grant's access level, AccessLevelReadOnly or
+// AccessLevelReadWrite.
+func (g *AccessGrant) AccessLevel() string {
+	if g.IsReadOnly() {
+		return AccessLevelReadOnly
+	}
+
+	return AccessLevelReadWrite
+}
+
 // ShouldBlockCopy returns true if COPY commands should be blocked
 func (g *AccessGrant) ShouldBlockCopy() bool {
 	return g.HasControl(ControlBlockCopy)
@@ -423,6 +845,42 @@ func (g *AccessGrant) ShouldBlockDDL() bool {
 	return g.HasControl(ControlBlockDDL)
 }
 
+// AllowsReplication returns true if replication connections are permitted
+func (g *AccessGrant) AllowsReplication() bool {
+	return g.HasControl(ControlAllowReplication)
+}
+
+// ShouldBlockCopyOut returns true if COPY ... TO exports should be blocked
+func (g *AccessGrant) ShouldBlockCopyOut() bool {
+	return g.HasControl(ControlBlockCopyOut)
+}
+
+// MasksPII returns true if personal-data columns must be masked in results
+func (g *AccessGrant) MasksPII() bool {
+	return g.HasControl(ControlMaskPII)
+}
+
+// MaxRows returns the max_rows:N limit of the grant, if any
+func (g *AccessGrant) MaxRows() (int64, bool) {
+	return g.controlValue(ControlMaxRows)
+}
+
+// MaxBytesPerSecond returns the max_bytes_per_second:N limit of the grant, if any
+func (g *AccessGrant) MaxBytesPerSecond() (int64, bool) {
+	return g.controlValue(ControlMaxBytesPerSecond)
+}
+
+// DryRun returns true if statements must be rolled back instead of committed
+func (g *AccessGrant) DryRun() bool {
+	return g.HasControl(ControlDryRun)
+}
+
+// WriteRequiresApproval checks if the grant holds write statements for
+// approval.
+func (g *AccessGrant) WriteRequiresApproval() bool {
+	return g.HasControl(ControlWriteRequiresApproval)
+}
+
 // Grant is an alias for backward compatibility
 type Grant = AccessGrant
 
@@ -511,6 +969,28 @@ type GrantDefinitionFilter struct {
 	ActiveOnly bool
 }
 
+// GrantTemplate is an admin-only grant shape applied to many users and
+// databases in one call, for onboarding. Unlike a GrantDefinition it is
+// never offered to users: it only saves admins from repeating a grant.
+type GrantTemplate struct {
+	bun.BaseModel `bun:"table:grant_templates,alias:gt"`
+
+	UID                 uuid.UUID  `bun:"uid,pk,type:uuid,default:gen_random_uuid()" json:"uid"`
+	Name                string     `bun:"name,notnull" json:"name"`
+	Description         string     `bun:"description,notnull,default:''" json:"description"`
+	DurationSeconds     int64      `bun:"duration_seconds,notnull" json:"duration_seconds"`
+	Controls            []string   `bun:"controls,array,notnull,default:'{}'" json:"controls"`
+	AllowedTables       []string   `bun:"allowed_tables,array,notnull,default:'{}'" json:"allowed_tables"`
+	AllowedCIDRs        []string   `bun:"allowed_cidrs,array,notnull,default:'{}'" json:"allowed_cidrs"`
+	MaxQueryCounts      *int64     `bun:"max_query_counts" json:"max_query_counts"`
+	MaxBytesTransferred *int64     `bun:"max_bytes_transferred" json:"max_bytes_transferred"`
+	MaxRowsReturned     *int64     `bun:"max_rows_returned" json:"max_rows_returned"`
+	Labels              Labels     `bun:"labels,type:jsonb,nullzero,notnull,default:'{}'" json:"labels"`
+	CreatedBy           *uuid.UUID `bun:"created_by,type:uuid" json:"created_by,omitempty"`
+	CreatedAt           time.Time  `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
+	UpdatedAt           time.Time  `bun:"updated_at,notnull,default:current_timestamp" json:"updated_at"`
+}
+
 // UserGroup is an organizational grouping of users (data-analysts, SRE, …),
 // deliberately kept apart from User.Roles, which are functional
 // (admin/viewer/connector). Groups exist to scope grant definitions.
@@ -551,12 +1031,17 @@ const (
 // shape (definition) on a particular database. Admins approve or deny.
 // On approval the system materializes a real AccessGrant from the
 // definition + the request's user/database.
+//
+// An extension request instead names one of the requester's grants in
+// ExtendsGrantID and carries no definition: on approval that grant's
+// expiry is pushed back by DurationSeconds.
 type GrantRequest struct {
 	bun.BaseModel `bun:"table:grant_requests,alias:gr"`
 
 	UID               uuid.UUID          `bun:"uid,pk,type:uuid,default:gen_random_uuid()" json:"uid"`
 	UserID            uuid.UUID          `bun:"user_id,notnull,type:uuid" json:"user_id"`
-	GrantDefinitionID uuid.UUID          `bun:"grant_definition_id,notnull,type:uuid" json:"grant_definition_id"`
+	GrantDefinitionID *uuid.UUID         `bun:"grant_definition_id,type:uuid" json:"grant_definition_id,omitempty"`
+	ExtendsGrantID    *uuid.UUID         `bun:"extends_grant_id,type:uuid" json:"extends_grant_id,omitempty"`
 	DatabaseID        uuid.UUID          `bun:"database_id,notnull,type:uuid" json:"database_id"`
 	Justification     string             `bun:"justification,notnull,default:''" json:"justification"`
 	Status            GrantRequestStatus `bun:"status,notnull" json:"status"`
@@ -566,12 +1051,60 @@ type GrantRequest struct {
 	DecisionReason    *string            `bun:"decision_reason" json:"decision_reason,omitempty"`
 	ResultingGrantID  *uuid.UUID         `bun:"resulting_grant_id,type:uuid" json:"resulting_grant_id,omitempty"`
 
+	// DurationSeconds is the duration the requester asked for, at most the
+	// definition's; nil takes the definition's. Extension requests always
+	// set it.
+	DurationSeconds *int64 `bun:"duration_seconds" json:"duration_seconds,omitempty"`
+
 	// Slack bookkeeping — populated by the notifier (Spec 04). JSON-omitted
 	// because the API has no need to expose Slack message coordinates.
 	SlackChannel   *string `bun:"slack_channel" json:"-"`
 	SlackMessageTS *string `bun:"slack_message_ts" json:"-"`
 }
 
+// StatementApprovalStatus enumerates the lifecycle states of a statement
+// approval.
+type StatementApprovalStatus string
+
+// Lifecycle states for statement approvals. Keep these constants matching
+// the DB CHECK constraint values exactly.
+const (
+	StatementApprovalPending  StatementApprovalStatus = "pending"
+	StatementApprovalApproved StatementApprovalStatus = "approved"
+	StatementApprovalDenied   StatementApprovalStatus = "denied"
+	StatementApprovalExpired  StatementApprovalStatus = "expired"
+)
+
+// StatementApproval is a write statement the proxy holds, under the
+// write_requires_approval control, until an admin other than its user
+// approves or denies it. A statement still pending at ExpiresAt is expired
+// by the proxy and refused.
+type StatementApproval struct {
+	bun.BaseModel `bun:"table:statement_approvals,alias:sa"`
+
+	UID            uuid.UUID               `bun:"uid,pk,type:uuid,default:gen_random_uuid()" json:"uid"`
+	ConnectionID   uuid.UUID               `bun:"connection_id,notnull,type:uuid" json:"connection_id"`
+	GrantID        uuid.UUID               `bun:"grant_id,notnull,type:uuid" json:"grant_id"`
+	UserID         uuid.UUID               `bun:"user_id,notnull,type:uuid" json:"user_id"`
+	DatabaseID     uuid.UUID               `bun:"database_id,notnull,type:uuid" json:"database_id"`
+	SQLText        string                  `bun:"sql_text,notnull" json:"sql_text"`
+	Status         StatementApprovalStatus `bun:"status,notnull" json:"status"`
+	RequestedAt    time.Time               `bun:"requested_at,notnull,default:current_timestamp" json:"requested_at"`
+	ExpiresAt      time.Time               `bun:"expires_at,notnull" json:"expires_at"`
+	DecidedAt      *time.Time              `bun:"decided_at" json:"decided_at,omitempty"`
+	DecidedBy      *uuid.UUID              `bun:"decided_by,type:uuid" json:"decided_by,omitempty"`
+	DecisionReason *string                 `bun:"decision_reason" json:"decision_reason,omitempty"`
+}
+
+// StatementApprovalFilter narrows ListStatementApprovals queries.
+type StatementApprovalFilter struct {
+	UserID     *uuid.UUID
+	DatabaseID *uuid.UUID
+	Status     *StatementApprovalStatus
+	Limit      int
+	Offset     int
+}
+
 // GrantRequestFilter narrows ListGrantRequests queries.
 type GrantRequestFilter struct {
 	UserID     *uuid.UUID
@@ -586,18 +1119,34 @@ type GrantFilter struct {
 	UserID     *uuid.UUID
 	DatabaseID *uuid.UUID
 	ActiveOnly bool
+	Labels     Labels // Keeps grants carrying every one of these labels
+	Limit      int
+	Offset     int
+}
+
+// ServerFilter narrows ListServers, ListListableServers and ListSSHServers
+// queries.
+type ServerFilter struct {
+	// Labels keeps servers carrying every one of these labels.
+	Labels Labels
+	Limit  int
+	Offset int
 }
 
 // AuditLog represents an audit log entry
 type AuditLog struct {
 	bun.BaseModel `bun:"table:audit_log,alias:al"`
 
-	UID         uuid.UUID       `bun:"uid,pk,type:uuid" json:"uid"` // UUIDv7 set in Go
-	EventType   string          `bun:"event_type,notnull" json:"event_type"`
-	UserID      *uuid.UUID      `bun:"user_id,type:uuid" json:"user_id"`
-	PerformedBy *uuid.UUID      `bun:"performed_by,type:uuid" json:"performed_by"`
-	Details     json.RawMessage `bun:"details,type:jsonb" json:"details"`
-	CreatedAt   time.Time       `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
+	UID       uuid.UUID `bun:"uid,pk,type:uuid" json:"uid"` // UUIDv7 set in Go
+	EventType string    `bun:"event_type,notnull" json:"event_type"`
+	// SchemaVersion is the version of the event type's details schema (see
+	// internal/audit); 0 for events recorded before versioning.
+	SchemaVersion int             `bun:"schema_version,nullzero" json:"schema_version,omitempty"`
+	UserID        *uuid.UUID      `bun:"user_id,type:uuid" json:"user_id"`
+	PerformedBy   *uuid.UUID      `bun:"performed_by,type:uuid" json:"performed_by"`
+	Details       json.RawMessage `bun:"details,type:jsonb" json:"details"`
+	SourceIP      *string         `bun:"source_ip,type:inet" json:"source_ip,omitempty"`
+	CreatedAt     time.Time       `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
 }
 
 // AuditEvent is an alias for backward compatibility
@@ -645,6 +1194,9 @@ type APIKey struct {
 	CreatedAt    time.Time  `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
 	RevokedAt    *time.Time `bun:"revoked_at" json:"revoked_at"`
 	RevokedBy    *uuid.UUID `bun:"revoked_by,type:uuid" json:"revoked_by"`
+	// Scopes restrict the API routes the key may call (see HasScope); nil
+	// leaves it every permission of its user.
+	Scopes []string `bun:"scopes,array" json:"scopes"`
 	// ProtocolData holds protocol-specific material (Oracle O5LOGON verifiers,
 	// etc.) in a single jsonb column rather than dedicated per-protocol columns.
 	// nil when the key has no protocol-specific data.
@@ -655,7 +1207,16 @@ type APIKey struct {
 // single jsonb column so protocol-specific fields don't proliferate as table
 // columns. Absent protocols are omitted.
 type ProtocolData struct {
-	Oracle *OracleAPIKeyData `json:"oracle,omitempty"`
+	Oracle  *OracleAPIKeyData  `json:"oracle,omitempty"`
+	Signing *SigningAPIKeyData `json:"signing,omitempty"`
+}
+
+// SigningAPIKeyData lets the API verify HMAC-signed requests made with the
+// key, which needs the key itself rather than its hash. Secret is the
+// plaintext key encrypted with the dbbat master key (AAD-bound to the key
+// prefix).
+type SigningAPIKeyData struct {
+	Secret []byte `json:"secret"`
 }
 
 // OracleAPIKeyData is the Oracle O5LOGON verifier material derived from the API
@@ -687,6 +1248,16 @@ func (k *APIKey) OracleData() *OracleAPIKeyData {
 	return k.ProtocolData.Oracle
 }
 
+// SigningData returns the key's request signing material, or nil if it has
+// none.
+func (k *APIKey) SigningData() *SigningAPIKeyData {
+	if k.ProtocolData == nil {
+		return nil
+	}
+
+	return k.ProtocolData.Signing
+}
+
 // IsExpired returns true if the API key has expired
 func (k *APIKey) IsExpired() bool {
 	if k.ExpiresAt == nil {
@@ -713,6 +1284,8 @@ func (k *APIKey) IsWebSession() bool {
 // Identity provider constants
 const (
 	IdentityTypeSlack = "slack"
+	IdentityTypeOIDC  = "oidc"
+	IdentityTypeLDAP  = "ldap"
 )
 
 // UserIdentity represents a link between a user and an external identity provider
@@ -765,3 +1338,14 @@ type GlobalParameter struct {
 	UpdatedAt time.Time  `bun:"updated_at,notnull,default:current_timestamp" json:"updated_at"`
 	DeletedAt *time.Time `bun:"deleted_at,soft_delete" json:"-"`
 }
+
+// UserPreference is one per-user UI preference. Value is opaque JSON owned by
+// the frontend (pinned databases, saved filters, column layouts, …).
+type UserPreference struct {
+	bun.BaseModel `bun:"table:user_preferences,alias:up"`
+
+	UserID    uuid.UUID       `bun:"user_id,pk,type:uuid" json:"-"`
+	Key       string          `bun:"key,pk" json:"key"`
+	Value     json.RawMessage `bun:"value,notnull,type:jsonb" json:"value"`
+	UpdatedAt time.Time       `bun:"updated_at,notnull,default:current_timestamp" json:"updated_at"`
+}
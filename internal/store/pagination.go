@@ -0,0 +1,18 @@
+package store
+
+import "github.com/uptrace/bun"
+
+// paginate bounds q to limit rows after skipping offset; zero values leave
+// it unbounded. The Count* functions count the same select, which ignores
+// both.
+func paginate(q *bun.SelectQuery, limit, offset int) *bun.SelectQuery {
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	if offset > 0 {
+		q = q.Offset(offset)
+	}
+
+	return q
+}
@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+
+	"github.com/fclairamb/dbbat/internal/crypto"
+)
+
+// Proxy credential constants
+const (
+	// ProxyCredentialPrefix starts the username of every proxy credential, so
+	// the proxies tell them from user names before looking anything up.
+	ProxyCredentialPrefix = "pxc_"
+	// proxyCredentialUsernameLength is the length of a proxy credential
+	// username, prefix included.
+	proxyCredentialUsernameLength = 16
+	// MaxProxyCredentialValidity bounds how long a proxy credential can wait
+	// for its connection.
+	MaxProxyCredentialValidity = 24 * time.Hour
+)
+
+// Proxy credential status values, derived from the timestamps.
+const (
+	ProxyCredentialStatusPending = "pending"
+	ProxyCredentialStatusUsed    = "used"
+	ProxyCredentialStatusExpired = "expired"
+	ProxyCredentialStatusRevoked = "revoked"
+)
+
+// Proxy credential errors
+var (
+	ErrProxyCredentialNotFound = errors.New("proxy credential not found")
+	// ErrProxyCredentialSpent is returned when a proxy credential was already
+	// used, revoked or has expired.
+	ErrProxyCredentialSpent = errors.New("proxy credential already used, revoked or expired")
+)
+
+// ProxyCredential is a one-time login an administrator issued on a grant for
+// someone without a DBBat user. Its single connection runs under the grant,
+// as the grant's user; Principal names who the credential was handed to.
+type ProxyCredential struct {
+	bun.BaseModel `bun:"table:proxy_credentials,alias:pc"`
+
+	UID          uuid.UUID  `bun:"uid,pk,type:uuid,default:gen_random_uuid()" json:"uid"`
+	GrantID      uuid.UUID  `bun:"grant_id,notnull,type:uuid" json:"grant_id"`
+	Username     string     `bun:"username,notnull" json:"username"`
+	SecretHash   string     `bun:"secret_hash,notnull" json:"-"`
+	Principal    string     `bun:"principal,notnull" json:"principal"`
+	IssuedBy     uuid.UUID  `bun:"issued_by,notnull,type:uuid" json:"issued_by"`
+	ExpiresAt    time.Time  `bun:"expires_at,notnull" json:"expires_at"`
+	UsedAt       *time.Time `bun:"used_at" json:"used_at"`
+	ConnectionID *uuid.UUID `bun:"connection_id,type:uuid" json:"connection_id"`
+	RevokedAt    *time.Time `bun:"revoked_at" json:"revoked_at"`
+	RevokedBy    *uuid.UUID `bun:"revoked_by,type:uuid" json:"revoked_by"`
+	CreatedAt    time.Time  `bun:"created_at,notnull,default:current_timestamp" json:"created_at"`
+
+	// Computed field (not stored in DB)
+	Status string `bun:"-" json:"status"`
+}
+
+// fillStatus computes Status from the timestamps.
+func (c *ProxyCredential) fillStatus(now time.Time) {
+	switch {
+	case c.UsedAt != nil:
+		c.Status = ProxyCredentialStatusUsed
+	case c.RevokedAt != nil:
+		c.Status = ProxyCredentialStatusRevoked
+	case !now.Before(c.ExpiresAt):
+		c.Status = ProxyCredentialStatusExpired
+	default:
+		c.Status = ProxyCredentialStatusPending
+	}
+}
+
+// IsProxyCredentialUsername reports whether a login name is the username of
+// a proxy credential rather than of a user.
+func IsProxyCredentialUsername(username string) bool {
+	return strings.HasPrefix(username, ProxyCredentialPrefix)
+}
+
+// CreateProxyCredential issues a proxy credential on a grant, valid until
+// expiresAt. Returns the credential and its plain text secret (only shown
+// once).
+func (s *Store) CreateProxyCredential(ctx context.Context, grantID uuid.UUID, principal string, issuedBy uuid.UUID, expiresAt time.Time) (*ProxyCredential, string, error) {
+	username, _, err := generateKey(ProxyCredentialPrefix)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate proxy credential username: %w", err)
+	}
+
+	secret, _, err := generateKey("")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate proxy credential secret: %w", err)
+	}
+
+	secretHash, err := crypto.HashPassword(secret)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to hash proxy credential secret: %w", err)
+	}
+
+	cred := &ProxyCredential{
+		GrantID:    grantID,
+		Username:   username[:proxyCredentialUsernameLength],
+		SecretHash: secretHash,
+		Principal:  principal,
+		IssuedBy:   issuedBy,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  time.Now(),
+	}
+
+	if _, err := s.db.NewInsert().Model(cred).Returning("*").Exec(ctx); err != nil {
+		return nil, "", fmt.Errorf("failed to create proxy credential: %w", err)
+	}
+
+	cred.fillStatus(time.Now())
+
+	return cred, secret, nil
+}
+
+// ListProxyCredentials returns the proxy credentials issued on a grant, the
+// latest first.
+func (s *Store) ListProxyCredentials(ctx context.Context, grantID uuid.UUID) ([]ProxyCredential, error) {
+	creds := []ProxyCredential{}
+
+	err := s.db.NewSelect().
+		Model(&creds).
+		Where("pc.grant_id = ?", grantID).
+		Order("pc.created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proxy credentials: %w", err)
+	}
+
+	now := time.Now()
+	for i := range creds {
+		creds[i].fillStatus(now)
+	}
+
+	return creds, nil
+}
+
+// GetProxyCredential retrieves a proxy credential by UID.
+func (s *Store) GetProxyCredential(ctx context.Context, uid uuid.UUID) (*ProxyCredential, error) {
+	cred := new(ProxyCredential)
+
+	err := s.db.NewSelect().Model(cred).Where("pc.uid = ?", uid).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProxyCredentialNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get proxy credential: %w", err)
+	}
+
+	cred.fillStatus(time.Now())
+
+	return cred, nil
+}
+
+// GetPendingProxyCredential retrieves the proxy credential of a username,
+// as long as it can still be used.
+func (s *Store) GetPendingProxyCredential(ctx context.Context, username string) (*ProxyCredential, error) {
+	cred := new(ProxyCredential)
+
+	err := s.db.NewSelect().
+		Model(cred).
+		Where("pc.username = ?", username).
+		Where("pc.used_at IS NULL").
+		Where("pc.revoked_at IS NULL").
+		Where("pc.expires_at > NOW()").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProxyCredentialNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get proxy credential: %w", err)
+	}
+
+	cred.fillStatus(time.Now())
+
+	return cred, nil
+}
+
+// ConsumeProxyCredential checks the secret of a proxy credential and marks
+// it used. The update is conditioned on the credential still being pending,
+// so of two concurrent logins with it, the second gets
+// ErrProxyCredentialSpent.
+func (s *Store) ConsumeProxyCredential(ctx context.Context, cred *ProxyCredential, secret string) error {
+	valid, err := crypto.VerifyPassword(cred.SecretHash, secret)
+	if err != nil || !valid {
+		return ErrProxyCredentialNotFound
+	}
+
+	now := time.Now()
+
+	result, err := s.db.NewUpdate().
+		Model((*ProxyCredential)(nil)).
+		Set("used_at = ?", now).
+		Where("uid = ?", cred.UID).
+		Where("used_at IS NULL").
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", now).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to consume proxy credential: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrProxyCredentialSpent
+	}
+
+	cred.UsedAt = &now
+	cred.fillStatus(now)
+
+	return nil
+}
+
+// SetProxyCredentialConnection links a used proxy credential to the
+// connection it opened.
+func (s *Store) SetProxyCredentialConnection(ctx context.Context, uid, connectionID uuid.UUID) error {
+	_, err := s.db.NewUpdate().
+		Model((*ProxyCredential)(nil)).
+		Set("connection_id = ?", connectionID).
+		Where("uid = ?", uid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to set proxy credential connection: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeProxyCredential revokes a proxy credential that was not used yet.
+// The connection of a used one is terminated instead.
+func (s *Store) RevokeProxyCredential(ctx context.Context, uid, revokedBy uuid.UUID) error {
+	result, err := s.db.NewUpdate().
+		Model((*ProxyCredential)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Set("revoked_by = ?", revokedBy).
+		Where("uid = ?", uid).
+		Where("used_at IS NULL").
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to revoke proxy credential: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrProxyCredentialSpent
+	}
+
+	return nil
+}
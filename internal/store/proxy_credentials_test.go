@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestIsProxyCredentialUsername(t *testing.T) {
+	t.Parallel()
+
+	if !IsProxyCredentialUsername("pxc_abcdefghijkl") {
+		t.Error("IsProxyCredentialUsername(pxc_...) = false, want true")
+	}
+
+	if IsProxyCredentialUsername("alice") {
+		t.Error("IsProxyCredentialUsername(alice) = true, want false")
+	}
+}
+
+func TestProxyCredentials(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	user, database := createTestUserAndDatabase(t, ctx, store, "pxc")
+	admin := createTestAdmin(t, ctx, store, "pxc")
+
+	now := time.Now()
+
+	grant, err := store.CreateGrant(ctx, &Grant{
+		UserID:     user.UID,
+		DatabaseID: database.UID,
+		Controls:   []string{ControlReadOnly},
+		GrantedBy:  admin.UID,
+		StartsAt:   now,
+		ExpiresAt:  now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateGrant() error = %v", err)
+	}
+
+	cred, secret, err := store.CreateProxyCredential(ctx, grant.UID, "jane@contractor.example", admin.UID, now.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("CreateProxyCredential() error = %v", err)
+	}
+
+	if !IsProxyCredentialUsername(cred.Username) || len(cred.Username) != proxyCredentialUsernameLength {
+		t.Errorf("Username = %q, want a %d-character proxy credential username", cred.Username, proxyCredentialUsernameLength)
+	}
+
+	if secret == "" || cred.SecretHash == secret || cred.Status != ProxyCredentialStatusPending {
+		t.Errorf("CreateProxyCredential() = %+v, %q, want a pending credential with a hashed secret", cred, secret)
+	}
+
+	pending, err := store.GetPendingProxyCredential(ctx, cred.Username)
+	if err != nil {
+		t.Fatalf("GetPendingProxyCredential() error = %v", err)
+	}
+
+	if err := store.ConsumeProxyCredential(ctx, pending, "wrong"); !errors.Is(err, ErrProxyCredentialNotFound) {
+		t.Errorf("ConsumeProxyCredential(wrong secret) error = %v, want ErrProxyCredentialNotFound", err)
+	}
+
+	if err := store.ConsumeProxyCredential(ctx, pending, secret); err != nil {
+		t.Fatalf("ConsumeProxyCredential() error = %v", err)
+	}
+
+	// One connection only.
+	if err := store.ConsumeProxyCredential(ctx, cred, secret); !errors.Is(err, ErrProxyCredentialSpent) {
+		t.Errorf("ConsumeProxyCredential() twice error = %v, want ErrProxyCredentialSpent", err)
+	}
+
+	if _, err := store.GetPendingProxyCredential(ctx, cred.Username); !errors.Is(err, ErrProxyCredentialNotFound) {
+		t.Errorf("GetPendingProxyCredential(used) error = %v, want ErrProxyCredentialNotFound", err)
+	}
+
+	connectionID := uuid.New()
+	if err := store.SetProxyCredentialConnection(ctx, cred.UID, connectionID); err != nil {
+		t.Fatalf("SetProxyCredentialConnection() error = %v", err)
+	}
+
+	used, err := store.GetProxyCredential(ctx, cred.UID)
+	if err != nil {
+		t.Fatalf("GetProxyCredential() error = %v", err)
+	}
+
+	if used.Status != ProxyCredentialStatusUsed || used.ConnectionID == nil || *used.ConnectionID != connectionID {
+		t.Errorf("GetProxyCredential() = %+v, want used by the connection", used)
+	}
+
+	if err := store.RevokeProxyCredential(ctx, cred.UID, admin.UID); !errors.Is(err, ErrProxyCredentialSpent) {
+		t.Errorf("RevokeProxyCredential(used) error = %v, want ErrProxyCredentialSpent", err)
+	}
+
+	// A revoked credential can't be used.
+	other, otherSecret, err := store.CreateProxyCredential(ctx, grant.UID, "joe@contractor.example", admin.UID, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("CreateProxyCredential() error = %v", err)
+	}
+
+	if err := store.RevokeProxyCredential(ctx, other.UID, admin.UID); err != nil {
+		t.Fatalf("RevokeProxyCredential() error = %v", err)
+	}
+
+	if err := store.ConsumeProxyCredential(ctx, other, otherSecret); !errors.Is(err, ErrProxyCredentialSpent) {
+		t.Errorf("ConsumeProxyCredential(revoked) error = %v, want ErrProxyCredentialSpent", err)
+	}
+
+	creds, err := store.ListProxyCredentials(ctx, grant.UID)
+	if err != nil {
+		t.Fatalf("ListProxyCredentials() error = %v", err)
+	}
+
+	if len(creds) != 2 || creds[0].UID != other.UID || creds[0].Status != ProxyCredentialStatusRevoked {
+		t.Errorf("ListProxyCredentials() = %+v, want the revoked credential first", creds)
+	}
+}
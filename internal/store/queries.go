@@ -2,12 +2,15 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
@@ -35,33 +38,147 @@ type QueryRowsCursor struct {
 	Offset int64 `json:"offset"`
 }
 
-// CreateQuery creates a new query record
+// CreateQuery creates a new query record. When query deduplication is enabled
+// and the statement repeats the previous query of its connection, that query
+// is updated instead and returned with Repeated set: callers must then not
+// attach result rows to it.
 func (s *Store) CreateQuery(ctx context.Context, query *Query) (*Query, error) {
-	result := &Query{
-		UID:          newUIDv7(), // Generate UUIDv7 for time-ordered inserts
-		ConnectionID: query.ConnectionID,
-		SQLText:      query.SQLText,
-		Parameters:   query.Parameters,
-		ExecutedAt:   query.ExecutedAt,
-		DurationMs:   query.DurationMs,
-		RowsAffected: query.RowsAffected,
-		Error:        query.Error,
+	if s.queryDedup == nil {
+		return s.insertQuery(ctx, query)
 	}
 
+	last := s.queryDedup.acquire(query.ConnectionID)
+	defer last.mu.Unlock()
+
+	if s.queryDedup.repeats(last, query) {
+		folded, err := s.foldRepeatedQuery(ctx, last.uid, query.ExecutedAt)
+		if err == nil {
+			return folded, nil
+		}
+
+		// The previous query may have been purged meanwhile: log this one anew.
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	result, err := s.insertQuery(ctx, query)
+	if err != nil {
+		last.reset()
+
+		return nil, err
+	}
+
+	last.remember(result)
+
+	return result, nil
+}
+
+// insertQuery inserts a new query record.
+func (s *Store) insertQuery(ctx context.Context, query *Query) (*Query, error) {
+	result := &Query{
+		UID:           newUIDv7(), // Generate UUIDv7 for time-ordered inserts
+		ConnectionID:  query.ConnectionID,
+		SQLText:       query.SQLText,
+		Parameters:    query.Parameters,
+		Fingerprint:   query.Fingerprint,
+		ExecutedAt:    query.ExecutedAt,
+		DurationMs:    query.DurationMs,
+		RowsAffected:  query.RowsAffected,
+		Error:         query.Error,
+		CopyFormat:    query.CopyFormat,
+		CopyDirection: query.CopyDirection,
+		DryRun:        query.DryRun,
+		QueryTiming:   query.QueryTiming,
+		RepeatCount:   1,
+	}
+
+	truncateSQLText(result, s.maxSQLTextBytes)
+
 	if result.ExecutedAt.IsZero() {
 		result.ExecutedAt = time.Now()
 	}
 
+	// Attribution comes from the connection row, so no proxy can forget it.
 	_, err := s.db.NewInsert().
 		Model(result).
+		Value("user_id", "(SELECT user_id FROM connections WHERE uid = ?)", result.ConnectionID).
+		Value("database_id", "(SELECT database_id FROM connections WHERE uid = ?)", result.ConnectionID).
+		Value("grant_id", "(SELECT grant_id FROM connections WHERE uid = ?)", result.ConnectionID).
+		Value("access_level", "(SELECT access_level FROM connections WHERE uid = ?)", result.ConnectionID).
+		Returning("user_id, database_id, grant_id, access_level").
 		Exec(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create query: %w", err)
 	}
 
+	if s.observer != nil {
+		s.observer.QueryLogged(result)
+	}
+
+	s.queryFeed.publish(result)
+
 	return result, nil
 }
 
+// foldRepeatedQuery counts one more execution, at executedAt, of the query
+// uid.
+func (s *Store) foldRepeatedQuery(ctx context.Context, uid uuid.UUID, executedAt time.Time) (*Query, error) {
+	if executedAt.IsZero() {
+		executedAt = time.Now()
+	}
+
+	folded := &Query{}
+
+	err := s.db.NewUpdate().
+		Model(folded).
+		Set("repeat_count = repeat_count + 1").
+		Set("last_executed_at = ?", executedAt).
+		Where("uid = ?", uid).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fold repeated query: %w", err)
+	}
+
+	folded.Repeated = true
+
+	return folded, nil
+}
+
+// sqlTruncationMarker replaces the middle of a truncated SQL text. It is a
+// comment, so the kept start and end still read (and normalize) as SQL.
+const sqlTruncationMarker = "\n/* ... %d bytes truncated ... */\n"
+
+// truncateSQLText cuts the SQL text of q when it exceeds maxBytes, keeping
+// its first and last halves on UTF-8 boundaries, and records the original
+// size and the SHA-256 of the full text.
+func truncateSQLText(q *Query, maxBytes int) {
+	if maxBytes <= 0 || len(q.SQLText) <= maxBytes {
+		return
+	}
+
+	full := q.SQLText
+	sum := sha256.Sum256([]byte(full))
+	hash := hex.EncodeToString(sum[:])
+	size := int64(len(full))
+
+	head := full[:maxBytes/2]
+	for len(head) > 0 && !utf8.RuneStart(full[len(head)]) {
+		head = head[:len(head)-1]
+	}
+
+	tailStart := len(full) - (maxBytes - maxBytes/2)
+	for tailStart < len(full) && !utf8.RuneStart(full[tailStart]) {
+		tailStart++
+	}
+
+	q.SQLText = head + fmt.Sprintf(sqlTruncationMarker, tailStart-len(head)) + full[tailStart:]
+	q.SQLTruncated = true
+	q.SQLTextBytes = &size
+	q.SQLTextSHA256 = &hash
+}
+
 // StoreQueryRows stores result rows for a query
 func (s *Store) StoreQueryRows(ctx context.Context, queryUID uuid.UUID, rows []QueryRow) error {
 	if len(rows) == 0 {
@@ -71,13 +188,7 @@ func (s *Store) StoreQueryRows(ctx context.Context, queryUID uuid.UUID, rows []Q
 	// Convert QueryRow to QueryRowModel for bun model
 	resultRows := make([]QueryRowModel, len(rows))
 	for i, row := range rows {
-		resultRows[i] = QueryRowModel{
-			UID:          newUIDv7(), // Generate UUIDv7 for each row
-			QueryID:      queryUID,
-			RowNumber:    row.RowNumber,
-			RowData:      row.RowData,
-			RowSizeBytes: row.RowSizeBytes,
-		}
+		resultRows[i] = newQueryRowModel(queryUID, row, s.compressRows)
 	}
 
 	_, err := s.db.NewInsert().
@@ -90,8 +201,25 @@ func (s *Store) StoreQueryRows(ctx context.Context, queryUID uuid.UUID, rows []Q
 	return nil
 }
 
-// UpdateQueryCompletion updates a query with duration, rows affected, and error.
-func (s *Store) UpdateQueryCompletion(ctx context.Context, uid uuid.UUID, durationMs *float64, rowsAffected *int64, queryError *string) error {
+// SetQueryResultObject records the object a query's result was spilled to,
+// and its row count.
+func (s *Store) SetQueryResultObject(ctx context.Context, uid uuid.UUID, key string, rows int64) error {
+	_, err := s.db.NewUpdate().
+		Model((*Query)(nil)).
+		Set("result_object_key = ?", key).
+		Set("spilled_rows = ?", rows).
+		Where("uid = ?", uid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to set query result object: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateQueryCompletion updates a query with duration, its breakdown, rows
+// affected, and error.
+func (s *Store) UpdateQueryCompletion(ctx context.Context, uid uuid.UUID, durationMs *float64, timing QueryTiming, rowsAffected *int64, queryError *string) error {
 	q := s.db.NewUpdate().
 		Model((*Query)(nil)).
 		Where("uid = ?", uid)
@@ -100,6 +228,22 @@ func (s *Store) UpdateQueryCompletion(ctx context.Context, uid uuid.UUID, durati
 		q = q.Set("duration_ms = ?", *durationMs)
 	}
 
+	if timing.UpstreamFirstByteMs != nil {
+		q = q.Set("upstream_first_byte_ms = ?", *timing.UpstreamFirstByteMs)
+	}
+
+	if timing.UpstreamMs != nil {
+		q = q.Set("upstream_ms = ?", *timing.UpstreamMs)
+	}
+
+	if timing.StreamingMs != nil {
+		q = q.Set("streaming_ms = ?", *timing.StreamingMs)
+	}
+
+	if timing.ProxyOverheadMs != nil {
+		q = q.Set("proxy_overhead_ms = ?", *timing.ProxyOverheadMs)
+	}
+
 	if rowsAffected != nil {
 		q = q.Set("rows_affected = ?", *rowsAffected)
 	}
@@ -113,24 +257,85 @@ func (s *Store) UpdateQueryCompletion(ctx context.Context, uid uuid.UUID, durati
 	return err
 }
 
-// ListQueries retrieves queries with optional filters
+// ListQueries retrieves queries with optional filters.
+// Served by the storage replica when one is configured and healthy.
 func (s *Store) ListQueries(ctx context.Context, filter QueryFilter) ([]Query, error) {
 	var queries []Query
+
+	err := s.scanReadOnly(ctx, s.selectQueries(&queries, filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queries: %w", err)
+	}
+
+	if queries == nil {
+		queries = []Query{}
+	}
+	return queries, nil
+}
+
+// CountQueries returns the number of queries matching the filter, its
+// cursors included, regardless of its Limit and Offset.
+// Served by the storage replica when one is configured and healthy.
+func (s *Store) CountQueries(ctx context.Context, filter QueryFilter) (int, error) {
+	var queries []Query
+
+	n, err := s.countReadOnly(ctx, s.selectQueries(&queries, filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count queries: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectQueries builds the select of the queries matching filter into dest.
+func (s *Store) selectQueries(dest *[]Query, filter QueryFilter) *bun.SelectQuery {
 	q := s.db.NewSelect().
-		Model(&queries).
-		ColumnExpr("q.uid, q.connection_id, q.sql_text, q.parameters, q.executed_at, q.duration_ms, q.rows_affected, q.error, c.user_id, c.database_id").
-		Join("JOIN connections c ON q.connection_id = c.uid")
+		Model(dest).
+		ColumnExpr("q.uid, q.connection_id, q.sql_text, q.parameters, q.executed_at, q.duration_ms, q.rows_affected, q.error").
+		ColumnExpr("q.copy_format, q.copy_direction, q.dry_run, q.sql_truncated, q.sql_text_bytes, q.sql_text_sha256").
+		ColumnExpr("q.repeat_count, q.last_executed_at, q.fingerprint").
+		ColumnExpr("q.user_id, q.database_id, q.grant_id, q.access_level")
+
+	q = whereQueries(q, filter)
+
+	if filter.BeforeUID != nil {
+		q = q.Where("q.uid < ?", *filter.BeforeUID)
+	}
+
+	if filter.AfterUID != nil {
+		q = q.Where("q.uid > ?", *filter.AfterUID).Order("q.uid ASC")
+	} else {
+		q = q.Order("q.uid DESC")
+	}
+
+	return paginate(q, filter.Limit, filter.Offset)
+}
 
+// whereQueries restricts q, a select from queries aliased q, to the queries
+// matching filter, its cursors and pagination aside.
+func whereQueries(q *bun.SelectQuery, filter QueryFilter) *bun.SelectQuery {
 	if filter.ConnectionID != nil {
 		q = q.Where("q.connection_id = ?", *filter.ConnectionID)
 	}
 
 	if filter.UserID != nil {
-		q = q.Where("c.user_id = ?", *filter.UserID)
+		q = q.Where("q.user_id = ?", *filter.UserID)
 	}
 
 	if filter.DatabaseID != nil {
-		q = q.Where("c.database_id = ?", *filter.DatabaseID)
+		q = q.Where("q.database_id = ?", *filter.DatabaseID)
+	}
+
+	if filter.GrantID != nil {
+		q = q.Where("q.grant_id = ?", *filter.GrantID)
+	}
+
+	if filter.AccessLevel != "" {
+		q = q.Where("q.access_level = ?", filter.AccessLevel)
+	}
+
+	if filter.DryRun != nil {
+		q = q.Where("q.dry_run = ?", *filter.DryRun)
 	}
 
 	if filter.StartTime != nil {
@@ -141,29 +346,47 @@ func (s *Store) ListQueries(ctx context.Context, filter QueryFilter) ([]Query, e
 		q = q.Where("q.executed_at <= ?", *filter.EndTime)
 	}
 
-	if filter.BeforeUID != nil {
-		q = q.Where("q.uid < ?", *filter.BeforeUID)
+	if filter.Fingerprint != "" {
+		q = q.Where("q.fingerprint = ?", filter.Fingerprint)
 	}
 
-	q = q.Order("q.uid DESC")
+	if filter.AlertID != nil {
+		q = q.Where("EXISTS (SELECT 1 FROM query_alert_matches m WHERE m.query_uid = q.uid AND m.alert_uid = ?)", *filter.AlertID)
+	}
 
-	if filter.Limit > 0 {
-		q = q.Limit(filter.Limit)
+	if filter.SQLContains != "" {
+		q = q.Where("q.sql_text ILIKE ?", "%"+likeEscaper.Replace(filter.SQLContains)+"%")
 	}
 
-	if filter.Offset > 0 {
-		q = q.Offset(filter.Offset)
+	if filter.SQLRegex != "" {
+		q = q.Where("q.sql_text ~* ?", filter.SQLRegex)
 	}
 
-	err := q.Scan(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list queries: %w", err)
+	if filter.HasError != nil {
+		if *filter.HasError {
+			q = q.Where("q.error IS NOT NULL")
+		} else {
+			q = q.Where("q.error IS NULL")
+		}
 	}
 
-	if queries == nil {
-		queries = []Query{}
+	if filter.MinDuration != nil {
+		q = q.Where("q.duration_ms >= ?", *filter.MinDuration)
 	}
-	return queries, nil
+
+	if filter.MaxDuration != nil {
+		q = q.Where("q.duration_ms <= ?", *filter.MaxDuration)
+	}
+
+	if filter.MinRows != nil {
+		q = q.Where("q.rows_affected >= ?", *filter.MinRows)
+	}
+
+	if filter.MaxRows != nil {
+		q = q.Where("q.rows_affected <= ?", *filter.MaxRows)
+	}
+
+	return q
 }
 
 // GetQueryWithRows retrieves a query with its result rows
@@ -193,14 +416,25 @@ func (s *Store) GetQueryWithRows(ctx context.Context, uid uuid.UUID) (*QueryWith
 		return nil, fmt.Errorf("failed to get query rows: %w", err)
 	}
 
-	// Convert to QueryRow
-	result.Rows = make([]QueryRow, len(resultRows))
-	for i, row := range resultRows {
-		result.Rows[i] = QueryRow{
-			RowNumber:    row.RowNumber,
-			RowData:      row.RowData,
-			RowSizeBytes: row.RowSizeBytes,
+	if len(resultRows) == 0 {
+		// The rows of an old query may have been compacted into a blob
+		compacted, err := loadRowBlob(ctx, s.db, uid)
+		if err != nil {
+			return nil, err
 		}
+
+		result.Rows = compacted
+		if result.Rows == nil {
+			result.Rows = []QueryRow{}
+		}
+
+		return result, nil
+	}
+
+	// Convert to QueryRow
+	result.Rows, err = toRows(resultRows)
+	if err != nil {
+		return nil, err
 	}
 
 	return result, nil
@@ -260,17 +494,37 @@ func (s *Store) GetQueryRows(ctx context.Context, queryUID uuid.UUID, cursor str
 		return nil, fmt.Errorf("failed to count query rows: %w", err)
 	}
 
-	// Query rows with offset and a buffer for checking hasMore
-	var resultRows []QueryRowModel
-	err = s.db.NewSelect().
-		Model(&resultRows).
-		Where("query_id = ?", queryUID).
-		Order("row_number ASC").
-		Offset(int(offset)).
-		Limit(limit + 1). // Fetch one extra to check if there are more
-		Scan(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get query rows: %w", err)
+	var resultRows []QueryRow
+
+	if totalRows == 0 {
+		// The rows of an old query may have been compacted into a blob
+		compacted, err := loadRowBlob(ctx, s.db, queryUID)
+		if err != nil {
+			return nil, err
+		}
+
+		totalRows = len(compacted)
+		if offset < int64(len(compacted)) {
+			resultRows = compacted[offset:min(int(offset)+limit+1, len(compacted))]
+		}
+	} else {
+		// Query rows with offset and a buffer for checking hasMore
+		var models []QueryRowModel
+		err = s.db.NewSelect().
+			Model(&models).
+			Where("query_id = ?", queryUID).
+			Order("row_number ASC").
+			Offset(int(offset)).
+			Limit(limit + 1). // Fetch one extra to check if there are more
+			Scan(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get query rows: %w", err)
+		}
+
+		resultRows, err = toRows(models)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Build result with data size limit enforcement
@@ -295,11 +549,7 @@ func (s *Store) GetQueryRows(ctx context.Context, queryUID uuid.UUID, cursor str
 			break
 		}
 
-		result.Rows = append(result.Rows, QueryRow{
-			RowNumber:    row.RowNumber,
-			RowData:      row.RowData,
-			RowSizeBytes: row.RowSizeBytes,
-		})
+		result.Rows = append(result.Rows, row)
 		currentDataSize += rowSize
 	}
 
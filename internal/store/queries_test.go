@@ -2,8 +2,12 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -83,6 +87,233 @@ func TestCreateQuery(t *testing.T) {
 	})
 }
 
+func TestQueryTiming(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "timing")
+	ms := func(v float64) *float64 { return &v }
+
+	created, err := store.CreateQuery(ctx, &Query{
+		ConnectionID: conn.UID,
+		SQLText:      "SELECT pg_sleep(1)",
+		ExecutedAt:   time.Now(),
+		DurationMs:   ms(1002.5),
+		QueryTiming:  QueryTiming{UpstreamFirstByteMs: ms(1000.25), UpstreamMs: ms(1001), StreamingMs: ms(0.75), ProxyOverheadMs: ms(1.5)},
+	})
+	if err != nil {
+		t.Fatalf("CreateQuery() error = %v", err)
+	}
+
+	got, err := store.GetQuery(ctx, created.UID)
+	if err != nil {
+		t.Fatalf("GetQuery() error = %v", err)
+	}
+
+	if got.UpstreamFirstByteMs == nil || *got.UpstreamFirstByteMs != 1000.25 ||
+		got.StreamingMs == nil || *got.StreamingMs != 0.75 ||
+		got.ProxyOverheadMs == nil || *got.ProxyOverheadMs != 1.5 {
+		t.Errorf("GetQuery() timing = %+v, want the logged breakdown", got.QueryTiming)
+	}
+
+	// Oracle logs a query before it completes, then completes it.
+	pending, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: "SELECT * FROM dual", ExecutedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("CreateQuery() error = %v", err)
+	}
+
+	if err := store.UpdateQueryCompletion(ctx, pending.UID, ms(3), QueryTiming{UpstreamMs: ms(2), ProxyOverheadMs: ms(1)}, nil, nil); err != nil {
+		t.Fatalf("UpdateQueryCompletion() error = %v", err)
+	}
+
+	got, err = store.GetQuery(ctx, pending.UID)
+	if err != nil {
+		t.Fatalf("GetQuery() error = %v", err)
+	}
+
+	if got.UpstreamMs == nil || *got.UpstreamMs != 2 || got.UpstreamFirstByteMs != nil {
+		t.Errorf("completed query timing = %+v, want upstream 2ms and no first byte", got.QueryTiming)
+	}
+}
+
+func TestCreateQueryTruncatesSQLText(t *testing.T) {
+	store := setupTestStore(t)
+	store.maxSQLTextBytes = 64
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "truncate")
+	sqlText := "SELECT * FROM t WHERE id IN (" + strings.Repeat("1, ", 1000) + "2)"
+
+	created, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: sqlText, ExecutedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("CreateQuery() error = %v", err)
+	}
+
+	queries, err := store.ListQueries(ctx, QueryFilter{ConnectionID: &conn.UID})
+	if err != nil || len(queries) != 1 {
+		t.Fatalf("ListQueries() = %d queries, error %v", len(queries), err)
+	}
+
+	got := queries[0]
+	if got.UID != created.UID || !got.SQLTruncated || got.SQLText != created.SQLText {
+		t.Errorf("listed query = %+v, want the truncated query %+v", got, created)
+	}
+
+	if got.SQLTextBytes == nil || *got.SQLTextBytes != int64(len(sqlText)) {
+		t.Errorf("SQLTextBytes = %v, want %d", got.SQLTextBytes, len(sqlText))
+	}
+}
+
+func TestCreateQueryFoldsRepeatedStatements(t *testing.T) {
+	store := setupTestStore(t)
+	store.queryDedup = newQueryDedup(time.Minute)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "dedup")
+	start := time.Now()
+	duration := 0.1
+	logQuery := func(sqlText string, offset time.Duration) *Query {
+		t.Helper()
+
+		created, err := store.CreateQuery(ctx, &Query{
+			ConnectionID: conn.UID,
+			SQLText:      sqlText,
+			ExecutedAt:   start.Add(offset),
+			DurationMs:   &duration,
+		})
+		if err != nil {
+			t.Fatalf("CreateQuery(%q) error = %v", sqlText, err)
+		}
+
+		return created
+	}
+
+	first := logQuery("SELECT 1", 0)
+	if first.Repeated || first.RepeatCount != 1 {
+		t.Fatalf("first query: Repeated = %v, RepeatCount = %d", first.Repeated, first.RepeatCount)
+	}
+
+	for i := 1; i <= 3; i++ {
+		folded := logQuery("SELECT 1", time.Duration(i)*time.Second)
+		if !folded.Repeated || folded.UID != first.UID || folded.RepeatCount != int64(i+1) {
+			t.Fatalf("repeat %d: Repeated = %v, UID = %v, RepeatCount = %d", i, folded.Repeated, folded.UID, folded.RepeatCount)
+		}
+	}
+
+	// Another statement in between, then the window expiring, start new rows.
+	logQuery("SELECT 2", 4*time.Second)
+	logQuery("SELECT 1", 5*time.Second)
+	logQuery("SELECT 1", 2*time.Minute)
+
+	queries, err := store.ListQueries(ctx, QueryFilter{ConnectionID: &conn.UID})
+	if err != nil {
+		t.Fatalf("ListQueries() error = %v", err)
+	}
+
+	if len(queries) != 4 {
+		t.Fatalf("ListQueries() = %d queries, want 4", len(queries))
+	}
+
+	oldest := queries[len(queries)-1]
+	if oldest.RepeatCount != 4 || oldest.LastExecutedAt == nil {
+		t.Errorf("folded query: RepeatCount = %d, LastExecutedAt = %v", oldest.RepeatCount, oldest.LastExecutedAt)
+	}
+}
+
+func TestGrantQueryCountIncludesRepeats(t *testing.T) {
+	store := setupTestStore(t)
+	store.queryDedup = newQueryDedup(time.Minute)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "dedupquota")
+	admin := createTestAdmin(t, ctx, store, "dedupquota")
+
+	start := time.Now().Add(-time.Minute)
+	maxQueries := int64(10)
+
+	grant, err := store.CreateGrant(ctx, &Grant{
+		UserID:         conn.UserID,
+		DatabaseID:     conn.DatabaseID,
+		Controls:       []string{},
+		GrantedBy:      admin.UID,
+		StartsAt:       start,
+		ExpiresAt:      start.Add(time.Hour),
+		MaxQueryCounts: &maxQueries,
+	})
+	if err != nil {
+		t.Fatalf("CreateGrant() error = %v", err)
+	}
+
+	for i := range 3 {
+		if _, err := store.CreateQuery(ctx, &Query{
+			ConnectionID: conn.UID,
+			SQLText:      "SELECT 1",
+			ExecutedAt:   start.Add(time.Duration(i+1) * time.Second),
+		}); err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+	}
+
+	// The three executions fold into one row, and still count three.
+	got, err := store.GetGrantByUID(ctx, grant.UID)
+	if err != nil {
+		t.Fatalf("GetGrantByUID() error = %v", err)
+	}
+
+	if got.QueryCount != 3 {
+		t.Errorf("QueryCount = %d, want 3", got.QueryCount)
+	}
+}
+
+func TestTruncateSQLText(t *testing.T) {
+	t.Parallel()
+
+	long := "SELECT " + strings.Repeat("x", 100) + " FROM t"
+	sum := sha256.Sum256([]byte(long))
+
+	tests := []struct {
+		name     string
+		sql      string
+		maxBytes int
+		want     string
+	}{
+		{"no limit", long, 0, long},
+		{"short enough", "SELECT 1", 8, "SELECT 1"},
+		{"head and tail kept", long, 20, "SELECT xxx\n/* ... 94 bytes truncated ... */\nxxx FROM t"},
+		// "é" is 2 bytes: neither half may split it.
+		{"utf-8 boundaries", "ééééé", 5, "é\n/* ... 6 bytes truncated ... */\né"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &Query{SQLText: tt.sql}
+			truncateSQLText(q, tt.maxBytes)
+
+			if q.SQLText != tt.want {
+				t.Errorf("SQLText = %q, want %q", q.SQLText, tt.want)
+			}
+
+			if truncated := q.SQLText != tt.sql; q.SQLTruncated != truncated {
+				t.Errorf("SQLTruncated = %v, want %v", q.SQLTruncated, truncated)
+			}
+
+			if !q.SQLTruncated {
+				return
+			}
+
+			if *q.SQLTextBytes != int64(len(tt.sql)) {
+				t.Errorf("SQLTextBytes = %d, want %d", *q.SQLTextBytes, len(tt.sql))
+			}
+
+			if tt.sql == long && *q.SQLTextSHA256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("SQLTextSHA256 = %s, want the hash of the full text", *q.SQLTextSHA256)
+			}
+		})
+	}
+}
+
 func TestStoreQueryRows(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -140,6 +371,39 @@ func TestStoreQueryRows(t *testing.T) {
 	})
 }
 
+func TestSetQueryResultObject(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "spill")
+
+	created, err := store.CreateQuery(ctx, &Query{
+		ConnectionID: conn.UID,
+		SQLText:      "SELECT * FROM big_table",
+		ExecutedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("CreateQuery() error = %v", err)
+	}
+
+	if err := store.SetQueryResultObject(ctx, created.UID, "results/2026/10/15/x.jsonl.gz", 250000); err != nil {
+		t.Fatalf("SetQueryResultObject() error = %v", err)
+	}
+
+	got, err := store.GetQuery(ctx, created.UID)
+	if err != nil {
+		t.Fatalf("GetQuery() error = %v", err)
+	}
+
+	if got.ResultObjectKey == nil || *got.ResultObjectKey != "results/2026/10/15/x.jsonl.gz" {
+		t.Errorf("ResultObjectKey = %v, want the spilled object", got.ResultObjectKey)
+	}
+
+	if got.SpilledRows == nil || *got.SpilledRows != 250000 {
+		t.Errorf("SpilledRows = %v, want 250000", got.SpilledRows)
+	}
+}
+
 func TestListQueries(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -155,11 +419,13 @@ func TestListQueries(t *testing.T) {
 		{ConnectionID: conn2.UID, SQLText: "SELECT 3", ExecutedAt: now},
 	}
 
+	created := make([]*Query, 0, len(queries))
 	for _, q := range queries {
-		_, err := store.CreateQuery(ctx, q)
+		query, err := store.CreateQuery(ctx, q)
 		if err != nil {
 			t.Fatalf("CreateQuery() error = %v", err)
 		}
+		created = append(created, query)
 	}
 
 	t.Run("list all", func(t *testing.T) {
@@ -214,7 +480,21 @@ func TestListQueries(t *testing.T) {
 		}
 	})
 
-	t.Run("resolves user and database via the connection join", func(t *testing.T) {
+	t.Run("after cursor returns newer queries oldest first", func(t *testing.T) {
+		result, err := store.ListQueries(ctx, QueryFilter{AfterUID: &created[0].UID})
+		if err != nil {
+			t.Fatalf("ListQueries() error = %v", err)
+		}
+		if len(result) != 2 {
+			t.Fatalf("ListQueries() len = %d, want 2", len(result))
+		}
+		if result[0].UID != created[1].UID || result[1].UID != created[2].UID {
+			t.Errorf("ListQueries() order = [%s %s], want [%s %s]",
+				result[0].UID, result[1].UID, created[1].UID, created[2].UID)
+		}
+	})
+
+	t.Run("copies user and database from the connection", func(t *testing.T) {
 		result, err := store.ListQueries(ctx, QueryFilter{ConnectionID: &conn1.UID})
 		if err != nil {
 			t.Fatalf("ListQueries() error = %v", err)
@@ -231,6 +511,108 @@ func TestListQueries(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("filter by grant and access level", func(t *testing.T) {
+		grant, err := store.CreateGrant(ctx, &Grant{
+			UserID:     conn2.UserID,
+			DatabaseID: conn2.DatabaseID,
+			GrantedBy:  conn2.UserID,
+			StartsAt:   now,
+			ExpiresAt:  now.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("CreateGrant() error = %v", err)
+		}
+
+		grantConn, err := store.CreateGrantConnection(ctx, grant, "127.0.0.1", nil)
+		if err != nil {
+			t.Fatalf("CreateGrantConnection() error = %v", err)
+		}
+
+		logged, err := store.CreateQuery(ctx, &Query{ConnectionID: grantConn.UID, SQLText: "DELETE FROM t"})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+		if logged.GrantID == nil || *logged.GrantID != grant.UID || logged.AccessLevel != AccessLevelReadWrite {
+			t.Errorf("CreateQuery() grant = %v/%q, want %s/%q", logged.GrantID, logged.AccessLevel, grant.UID, AccessLevelReadWrite)
+		}
+
+		// Revoking the grant leaves the attribution in place.
+		if err := store.RevokeGrant(ctx, grant.UID, conn2.UserID); err != nil {
+			t.Fatalf("RevokeGrant() error = %v", err)
+		}
+
+		result, err := store.ListQueries(ctx, QueryFilter{GrantID: &grant.UID, AccessLevel: AccessLevelReadWrite})
+		if err != nil {
+			t.Fatalf("ListQueries() error = %v", err)
+		}
+		if len(result) != 1 || result[0].UID != logged.UID {
+			t.Fatalf("ListQueries(grant) = %+v, want the DELETE", result)
+		}
+
+		result, err = store.ListQueries(ctx, QueryFilter{GrantID: &grant.UID, AccessLevel: AccessLevelReadOnly})
+		if err != nil {
+			t.Fatalf("ListQueries() error = %v", err)
+		}
+		if len(result) != 0 {
+			t.Errorf("ListQueries(grant, read_only) len = %d, want 0", len(result))
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		conn := createTestConnection(t, ctx, store, "listqsearch")
+		fast, slow := 1.5, 250.0
+		few, many := int64(1), int64(5000)
+		failure := "relation does not exist"
+
+		logged := make([]uuid.UUID, 0, 3)
+		for _, q := range []*Query{
+			{ConnectionID: conn.UID, SQLText: "SELECT * FROM orders WHERE note = '50%_off'", DurationMs: &fast, RowsAffected: &few},
+			{ConnectionID: conn.UID, SQLText: "UPDATE Orders SET paid = true", DurationMs: &slow, RowsAffected: &many},
+			{ConnectionID: conn.UID, SQLText: "SELECT * FROM missing", DurationMs: &fast, Error: &failure},
+		} {
+			query, err := store.CreateQuery(ctx, q)
+			if err != nil {
+				t.Fatalf("CreateQuery() error = %v", err)
+			}
+			logged = append(logged, query.UID)
+		}
+
+		hasError, noError := true, false
+		minDuration, minRows, maxRows := 100.0, int64(1), int64(10)
+
+		tests := []struct {
+			name   string
+			filter QueryFilter
+			want   []uuid.UUID
+		}{
+			{name: "substring", filter: QueryFilter{SQLContains: "orders"}, want: []uuid.UUID{logged[1], logged[0]}},
+			{name: "substring is literal", filter: QueryFilter{SQLContains: "50%_"}, want: []uuid.UUID{logged[0]}},
+			{name: "regex", filter: QueryFilter{SQLRegex: "^update\\s+orders"}, want: []uuid.UUID{logged[1]}},
+			{name: "failed", filter: QueryFilter{HasError: &hasError}, want: []uuid.UUID{logged[2]}},
+			{name: "succeeded", filter: QueryFilter{HasError: &noError}, want: []uuid.UUID{logged[1], logged[0]}},
+			{name: "slow", filter: QueryFilter{MinDuration: &minDuration}, want: []uuid.UUID{logged[1]}},
+			{name: "rows range", filter: QueryFilter{MinRows: &minRows, MaxRows: &maxRows}, want: []uuid.UUID{logged[0]}},
+		}
+
+		for _, tt := range tests {
+			tt.filter.ConnectionID = &conn.UID
+
+			result, err := store.ListQueries(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListQueries(%s) error = %v", tt.name, err)
+			}
+
+			got := make([]uuid.UUID, 0, len(result))
+			for _, q := range result {
+				got = append(got, q.UID)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ListQueries(%s) = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
 }
 
 func TestGetQueryWithRows(t *testing.T) {
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ErrQueryAlertNotFound is returned when a query alert lookup misses.
+var ErrQueryAlertNotFound = errors.New("query alert not found")
+
+// ErrQueryAlertDuplicate is returned when a query alert name collides (case
+// insensitively) with an existing alert.
+var ErrQueryAlertDuplicate = errors.New("query alert with this name already exists")
+
+// errQueryAlertEvaluated is returned within an evaluation transaction when
+// another instance moved the alert's cursor first.
+var errQueryAlertEvaluated = errors.New("query alert evaluated concurrently")
+
+// CreateQueryAlert inserts a new alert. It only matches the queries logged
+// from its creation on.
+func (s *Store) CreateQueryAlert(ctx context.Context, alert *QueryAlert) (*QueryAlert, error) {
+	now := time.Now()
+
+	result := &QueryAlert{
+		Name:        alert.Name,
+		Description: alert.Description,
+		Filter:      alert.Filter,
+		WebhookURL:  alert.WebhookURL,
+		Enabled:     alert.Enabled,
+		CursorUID:   uidV7Floor(now),
+		CreatedBy:   alert.CreatedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if _, err := s.db.NewInsert().Model(result).Returning("*").Exec(ctx); err != nil {
+		if isUniqueViolation(err, "query_alerts_name_uniq") {
+			return nil, ErrQueryAlertDuplicate
+		}
+
+		return nil, fmt.Errorf("create query alert: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetQueryAlert fetches an alert by UID.
+func (s *Store) GetQueryAlert(ctx context.Context, uid uuid.UUID) (*QueryAlert, error) {
+	alert := new(QueryAlert)
+
+	if err := s.db.NewSelect().Model(alert).Where("uid = ?", uid).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQueryAlertNotFound
+		}
+
+		return nil, fmt.Errorf("get query alert: %w", err)
+	}
+
+	return alert, nil
+}
+
+// ListQueryAlerts returns every alert, name-ordered.
+func (s *Store) ListQueryAlerts(ctx context.Context) ([]QueryAlert, error) {
+	alerts := []QueryAlert{}
+
+	if err := s.db.NewSelect().Model(&alerts).Order("name ASC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("list query alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// UpdateQueryAlert mutates the editable fields of an alert. An alert being
+// enabled again resumes from now rather than matching the queries logged
+// while it was disabled.
+func (s *Store) UpdateQueryAlert(ctx context.Context, alert *QueryAlert) error {
+	alert.UpdatedAt = time.Now()
+
+	res, err := s.db.NewUpdate().
+		Model(alert).
+		Column("name", "description", "filter", "webhook_url", "enabled", "updated_at").
+		Set("cursor_uid = CASE WHEN qa.enabled THEN qa.cursor_uid ELSE ? END", uidV7Floor(alert.UpdatedAt)).
+		Where("uid = ?", alert.UID).
+		Exec(ctx)
+	if err != nil {
+		if isUniqueViolation(err, "query_alerts_name_uniq") {
+			return ErrQueryAlertDuplicate
+		}
+
+		return fmt.Errorf("update query alert: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrQueryAlertNotFound
+	}
+
+	return nil
+}
+
+// DeleteQueryAlert hard-deletes an alert; its recorded matches cascade away.
+func (s *Store) DeleteQueryAlert(ctx context.Context, uid uuid.UUID) error {
+	res, err := s.db.NewDelete().
+		Model((*QueryAlert)(nil)).
+		Where("uid = ?", uid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("delete query alert: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrQueryAlertNotFound
+	}
+
+	return nil
+}
+
+// EvaluateQueryAlert matches the alert against the queries logged after its
+// cursor and before until, at most limit of them (0 = no limit), oldest
+// first. The matches are recorded and the cursor moved in one transaction,
+// conditioned on the cursor the alert was read with: when several instances
+// evaluate the same alert, only one gets its matches, the others none.
+//
+// Queries are matched as logged by then: until should lag behind the
+// current time, so the queries of every instance are committed, and their
+// completion recorded, by the time their turn comes.
+func (s *Store) EvaluateQueryAlert(ctx context.Context, alert *QueryAlert, until time.Time, limit int) ([]Query, error) {
+	bound := uidV7Floor(until)
+	if bytes.Compare(bound[:], alert.CursorUID[:]) <= 0 {
+		return nil, nil
+	}
+
+	filter := alert.Filter.QueryFilter()
+	filter.AfterUID = &alert.CursorUID
+	filter.BeforeUID = &bound
+	filter.Limit = limit
+
+	var matches []Query
+
+	if err := s.selectQueries(&matches, filter).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("match query alert: %w", err)
+	}
+
+	// A full page may stop short of the bound: resume after its last query.
+	cursor := bound
+	if limit > 0 && len(matches) == limit {
+		cursor = matches[len(matches)-1].UID
+	}
+
+	now := time.Now()
+
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		q := tx.NewUpdate().
+			Model((*QueryAlert)(nil)).
+			Set("cursor_uid = ?", cursor).
+			Set("last_evaluated_at = ?", now).
+			Set("match_count = match_count + ?", len(matches)).
+			Where("uid = ?", alert.UID).
+			Where("cursor_uid = ?", alert.CursorUID)
+
+		if len(matches) > 0 {
+			q = q.Set("last_matched_at = ?", now)
+		}
+
+		res, err := q.Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("move query alert cursor: %w", err)
+		}
+
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			return errQueryAlertEvaluated
+		}
+
+		if len(matches) == 0 {
+			return nil
+		}
+
+		records := make([]QueryAlertMatch, len(matches))
+		for i := range matches {
+			records[i] = QueryAlertMatch{AlertUID: alert.UID, QueryUID: matches[i].UID, MatchedAt: now}
+		}
+
+		if _, err := tx.NewInsert().Model(&records).On("CONFLICT (alert_uid, query_uid) DO NOTHING").Exec(ctx); err != nil {
+			return fmt.Errorf("record query alert matches: %w", err)
+		}
+
+		return nil
+	})
+	if errors.Is(err, errQueryAlertEvaluated) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	alert.CursorUID = cursor
+	alert.LastEvaluatedAt = &now
+	alert.MatchCount += int64(len(matches))
+
+	if len(matches) > 0 {
+		alert.LastMatchedAt = &now
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUIDv7Floor(t *testing.T) {
+	t.Parallel()
+
+	at := time.Now()
+	floor := uidV7Floor(at)
+
+	if floor.Version() != 7 || floor.Variant() != uuid.RFC4122 {
+		t.Errorf("uidV7Floor() = %s, want a UUIDv7", floor)
+	}
+
+	if sec, nsec := floor.Time().UnixTime(); time.Unix(sec, nsec).UnixMilli() != at.UnixMilli() {
+		t.Errorf("uidV7Floor() time = %v, want %v", time.Unix(sec, nsec), at)
+	}
+
+	later := newUIDv7()
+	if bytes.Compare(floor[:], later[:]) > 0 {
+		t.Errorf("uidV7Floor() = %s, greater than the later UID %s", floor, later)
+	}
+
+	next := uidV7Floor(at.Add(time.Millisecond))
+	if bytes.Compare(next[:], floor[:]) <= 0 {
+		t.Errorf("uidV7Floor() of the next millisecond = %s, not greater than %s", next, floor)
+	}
+}
+
+func TestQueryAlerts(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "qalert")
+
+	// Logged before the alert: never matched.
+	if _, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: "DELETE FROM archive"}); err != nil {
+		t.Fatalf("CreateQuery() error = %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	alert, err := store.CreateQueryAlert(ctx, &QueryAlert{
+		Name:    "Deletes",
+		Filter:  QueryAlertFilter{SQLRegex: `^delete\s`, DatabaseID: &conn.DatabaseID},
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateQueryAlert() error = %v", err)
+	}
+
+	if _, err := store.CreateQueryAlert(ctx, &QueryAlert{Name: "deletes", Enabled: true}); !errors.Is(err, ErrQueryAlertDuplicate) {
+		t.Errorf("CreateQueryAlert() with a taken name error = %v, want %v", err, ErrQueryAlertDuplicate)
+	}
+
+	var deletes []uuid.UUID
+
+	for _, sql := range []string{"DELETE FROM orders", "SELECT 1", "delete from items"} {
+		q, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: sql})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+
+		if sql != "SELECT 1" {
+			deletes = append(deletes, q.UID)
+		}
+	}
+
+	until := time.Now().Add(time.Second)
+	stale := *alert
+
+	matches, err := store.EvaluateQueryAlert(ctx, alert, until, 1)
+	if err != nil {
+		t.Fatalf("EvaluateQueryAlert() error = %v", err)
+	}
+
+	if len(matches) != 1 || matches[0].UID != deletes[0] {
+		t.Fatalf("EvaluateQueryAlert() = %v, want the first DELETE", matches)
+	}
+
+	// Another instance holding the alert as it was gets nothing.
+	if matches, err := store.EvaluateQueryAlert(ctx, &stale, until, 0); err != nil || len(matches) != 0 {
+		t.Errorf("EvaluateQueryAlert() of a stale alert = %v, %v, want nothing", matches, err)
+	}
+
+	matches, err = store.EvaluateQueryAlert(ctx, alert, until, 0)
+	if err != nil {
+		t.Fatalf("EvaluateQueryAlert() error = %v", err)
+	}
+
+	if len(matches) != 1 || matches[0].UID != deletes[1] {
+		t.Fatalf("EvaluateQueryAlert() = %v, want the second DELETE", matches)
+	}
+
+	if matches, err := store.EvaluateQueryAlert(ctx, alert, until, 0); err != nil || len(matches) != 0 {
+		t.Errorf("EvaluateQueryAlert() past the bound = %v, %v, want nothing", matches, err)
+	}
+
+	got, err := store.GetQueryAlert(ctx, alert.UID)
+	if err != nil {
+		t.Fatalf("GetQueryAlert() error = %v", err)
+	}
+
+	if got.MatchCount != 2 || got.LastMatchedAt == nil || got.Filter.SQLRegex != `^delete\s` {
+		t.Errorf("GetQueryAlert() = %+v, want 2 matches and the saved filter", got)
+	}
+
+	matched, err := store.ListQueries(ctx, QueryFilter{AlertID: &alert.UID})
+	if err != nil {
+		t.Fatalf("ListQueries() error = %v", err)
+	}
+
+	if len(matched) != 2 || matched[0].UID != deletes[1] || matched[1].UID != deletes[0] {
+		t.Errorf("ListQueries(alert) = %v, want both DELETEs, newest first", matched)
+	}
+
+	got.Enabled = false
+	if err := store.UpdateQueryAlert(ctx, got); err != nil {
+		t.Fatalf("UpdateQueryAlert() error = %v", err)
+	}
+
+	if err := store.DeleteQueryAlert(ctx, alert.UID); err != nil {
+		t.Fatalf("DeleteQueryAlert() error = %v", err)
+	}
+
+	if _, err := store.GetQueryAlert(ctx, alert.UID); !errors.Is(err, ErrQueryAlertNotFound) {
+		t.Errorf("GetQueryAlert() after delete error = %v, want %v", err, ErrQueryAlertNotFound)
+	}
+
+	if err := store.DeleteQueryAlert(ctx, alert.UID); !errors.Is(err, ErrQueryAlertNotFound) {
+		t.Errorf("DeleteQueryAlert() twice error = %v, want %v", err, ErrQueryAlertNotFound)
+	}
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// queryDedup remembers the last query logged on each connection, so that
+// identical consecutive statements (health checks running "SELECT 1" in a
+// loop) are folded into it instead of each adding a row.
+type queryDedup struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	conns map[uuid.UUID]*lastQuery
+}
+
+// lastQuery is the last query logged on a connection. Its mutex serializes
+// the CreateQuery calls of the connection.
+type lastQuery struct {
+	mu         sync.Mutex
+	uid        uuid.UUID
+	sqlText    string
+	executedAt time.Time
+}
+
+// newQueryDedup returns nil when window disables deduplication.
+func newQueryDedup(window time.Duration) *queryDedup {
+	if window <= 0 {
+		return nil
+	}
+
+	return &queryDedup{window: window, conns: make(map[uuid.UUID]*lastQuery)}
+}
+
+// acquire returns the last query of the connection, locked.
+func (d *queryDedup) acquire(connectionID uuid.UUID) *lastQuery {
+	d.mu.Lock()
+
+	last, ok := d.conns[connectionID]
+	if !ok {
+		last = &lastQuery{}
+		d.conns[connectionID] = last
+	}
+
+	d.mu.Unlock()
+
+	last.mu.Lock()
+
+	return last
+}
+
+// forget drops the state of a closed connection.
+func (d *queryDedup) forget(connectionID uuid.UUID) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	delete(d.conns, connectionID)
+	d.mu.Unlock()
+}
+
+// repeats reports whether query can be folded into last: same SQL text,
+// within the window of last's first execution, and nothing worth keeping
+// apart (an error, parameters, a COPY or an unfinished query).
+func (d *queryDedup) repeats(last *lastQuery, query *Query) bool {
+	if last.uid == uuid.Nil || !foldable(query) || query.SQLText != last.sqlText {
+		return false
+	}
+
+	age := query.ExecutedAt.Sub(last.executedAt)
+
+	return age >= 0 && age < d.window
+}
+
+// remember makes query the one later statements are compared to, or clears
+// the state when query cannot be folded into, so that only consecutive
+// statements are folded.
+func (l *lastQuery) remember(query *Query) {
+	if !foldable(query) {
+		l.reset()
+
+		return
+	}
+
+	l.uid = query.UID
+	l.sqlText = query.SQLText
+	l.executedAt = query.ExecutedAt
+}
+
+func (l *lastQuery) reset() {
+	l.uid = uuid.Nil
+	l.sqlText = ""
+	l.executedAt = time.Time{}
+}
+
+// foldable reports whether query may be folded with identical ones. Queries
+// logged before completion (no duration yet) are excluded, as their rows and
+// completion are written to them afterwards.
+func foldable(query *Query) bool {
+	return query.Error == nil &&
+		query.Parameters == nil &&
+		query.CopyDirection == nil &&
+		query.DurationMs != nil &&
+		!query.SQLTruncated
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestQueryDedupRepeats(t *testing.T) {
+	t.Parallel()
+
+	if newQueryDedup(0) != nil {
+		t.Fatal("newQueryDedup(0) != nil, want deduplication disabled")
+	}
+
+	start := time.Now()
+	duration := 1.5
+	errMsg := "boom"
+	copyDir := "out"
+	query := func(sqlText string, offset time.Duration) *Query {
+		return &Query{UID: uuid.New(), SQLText: sqlText, ExecutedAt: start.Add(offset), DurationMs: &duration}
+	}
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  bool
+	}{
+		{"identical", query("SELECT 1", time.Second), true},
+		{"other statement", query("SELECT 2", time.Second), false},
+		{"past the window", query("SELECT 1", 10*time.Second), false},
+		{"failed", &Query{SQLText: "SELECT 1", ExecutedAt: start, DurationMs: &duration, Error: &errMsg}, false},
+		{"parameters", &Query{SQLText: "SELECT 1", ExecutedAt: start, DurationMs: &duration, Parameters: &QueryParameters{}}, false},
+		{"copy", &Query{SQLText: "SELECT 1", ExecutedAt: start, DurationMs: &duration, CopyDirection: &copyDir}, false},
+		{"unfinished", &Query{SQLText: "SELECT 1", ExecutedAt: start}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := newQueryDedup(10 * time.Second)
+			connectionID := uuid.New()
+
+			last := d.acquire(connectionID)
+			last.remember(query("SELECT 1", 0))
+
+			if got := d.repeats(last, tt.query); got != tt.want {
+				t.Errorf("repeats() = %v, want %v", got, tt.want)
+			}
+
+			last.mu.Unlock()
+		})
+	}
+}
+
+func TestQueryDedupOnlyFoldsConsecutiveStatements(t *testing.T) {
+	t.Parallel()
+
+	d := newQueryDedup(time.Minute)
+	connectionID := uuid.New()
+	duration := 1.0
+	errMsg := "boom"
+	now := time.Now()
+
+	last := d.acquire(connectionID)
+	last.remember(&Query{UID: uuid.New(), SQLText: "SELECT 1", ExecutedAt: now, DurationMs: &duration})
+	last.remember(&Query{UID: uuid.New(), SQLText: "SELECT 1", ExecutedAt: now, DurationMs: &duration, Error: &errMsg})
+
+	if d.repeats(last, &Query{SQLText: "SELECT 1", ExecutedAt: now, DurationMs: &duration}) {
+		t.Error("folded into a query preceding a failed one")
+	}
+
+	last.mu.Unlock()
+
+	d.forget(connectionID)
+
+	if len(d.conns) != 0 {
+		t.Errorf("%d connections remembered after forget, want 0", len(d.conns))
+	}
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// QueryFeed fans out the queries logged by this process to live subscribers,
+// such as the API's query stream. Like the revocation registry it carries no
+// database state and only sees the proxies of its own DBBat instance.
+//
+// Publishing never blocks the proxy: a subscriber that does not keep up
+// misses queries, counted by its Dropped method.
+type QueryFeed struct {
+	mu   sync.Mutex
+	subs map[*QuerySubscription]struct{}
+}
+
+// QuerySubscription receives the queries published to a QueryFeed on C until
+// Close.
+type QuerySubscription struct {
+	C <-chan Query
+
+	ch      chan Query
+	dropped atomic.Int64
+	feed    *QueryFeed
+}
+
+// NewQueryFeed creates a feed without subscribers.
+func NewQueryFeed() *QueryFeed {
+	return &QueryFeed{
+		subs: make(map[*QuerySubscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber buffering up to buffer queries. Calling on
+// a nil feed returns a subscription that never receives anything.
+func (f *QueryFeed) Subscribe(buffer int) *QuerySubscription {
+	ch := make(chan Query, buffer)
+	sub := &QuerySubscription{C: ch, ch: ch, feed: f}
+
+	if f == nil {
+		return sub
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.subs[sub] = struct{}{}
+
+	return sub
+}
+
+// Close stops the subscription. Safe to call more than once.
+func (sub *QuerySubscription) Close() {
+	if sub.feed == nil {
+		return
+	}
+
+	sub.feed.mu.Lock()
+	defer sub.feed.mu.Unlock()
+
+	delete(sub.feed.subs, sub)
+}
+
+// Dropped returns the number of queries missed since its previous call
+// because the subscriber's buffer was full.
+func (sub *QuerySubscription) Dropped() int64 {
+	return sub.dropped.Swap(0)
+}
+
+// publish hands query to every subscriber without blocking.
+func (f *QueryFeed) publish(query *Query) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.subs {
+		select {
+		case sub.ch <- *query:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
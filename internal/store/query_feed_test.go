@@ -0,0 +1,62 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestQueryFeed_PublishToSubscribers(t *testing.T) {
+	t.Parallel()
+
+	feed := NewQueryFeed()
+	sub := feed.Subscribe(1)
+	closed := feed.Subscribe(1)
+	closed.Close()
+
+	first := &Query{UID: uuid.New()}
+	feed.publish(first)
+
+	select {
+	case got := <-sub.C:
+		if got.UID != first.UID {
+			t.Fatalf("received query %s, want %s", got.UID, first.UID)
+		}
+	default:
+		t.Fatal("subscriber did not receive the query")
+	}
+
+	select {
+	case <-closed.C:
+		t.Fatal("closed subscription received a query")
+	default:
+	}
+
+	// A full buffer drops rather than blocks the publisher.
+	feed.publish(&Query{UID: uuid.New()})
+	feed.publish(&Query{UID: uuid.New()})
+
+	if n := sub.Dropped(); n != 1 {
+		t.Fatalf("Dropped() = %d, want 1", n)
+	}
+
+	if n := sub.Dropped(); n != 0 {
+		t.Fatalf("Dropped() after reading = %d, want 0", n)
+	}
+}
+
+func TestQueryFeed_NilSafety(t *testing.T) {
+	t.Parallel()
+
+	var feed *QueryFeed
+
+	sub := feed.Subscribe(1)
+	feed.publish(&Query{UID: uuid.New()})
+	sub.Close()
+
+	select {
+	case <-sub.C:
+		t.Fatal("subscription of a nil feed received a query")
+	default:
+	}
+}
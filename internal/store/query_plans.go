@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// QueryPlan is the plan of a slow query, captured in the background with
+// EXPLAIN (FORMAT JSON) and the database's stored credentials. Error says why
+// a capture failed, Plan is then empty.
+type QueryPlan struct {
+	bun.BaseModel `bun:"table:query_plans,alias:qp"`
+
+	QueryUID    uuid.UUID       `bun:"query_uid,pk,type:uuid" json:"query_uid"`
+	DatabaseID  uuid.UUID       `bun:"database_id,notnull,type:uuid" json:"database_id"`
+	Fingerprint string          `bun:"fingerprint,nullzero" json:"fingerprint,omitempty"`
+	Plan        json.RawMessage `bun:"plan,type:jsonb,nullzero" json:"plan,omitempty"`
+	Error       *string         `bun:"error" json:"error,omitempty"`
+	CapturedAt  time.Time       `bun:"captured_at,notnull,default:current_timestamp" json:"captured_at"`
+}
+
+// SaveQueryPlan records the plan of a query, replacing the one it had.
+func (s *Store) SaveQueryPlan(ctx context.Context, plan *QueryPlan) error {
+	if plan.CapturedAt.IsZero() {
+		plan.CapturedAt = time.Now()
+	}
+
+	_, err := s.db.NewInsert().
+		Model(plan).
+		On("CONFLICT (query_uid) DO UPDATE").
+		Set("plan = EXCLUDED.plan").
+		Set("error = EXCLUDED.error").
+		Set("captured_at = EXCLUDED.captured_at").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save query plan: %w", err)
+	}
+
+	return nil
+}
+
+// GetQueryPlan returns the plan captured for a query or, when the query was
+// not explained itself, the latest one captured for a statement of the same
+// fingerprint on the same database: its QueryUID then differs from query's.
+func (s *Store) GetQueryPlan(ctx context.Context, query *Query) (*QueryPlan, error) {
+	plan := new(QueryPlan)
+
+	err := s.db.NewSelect().Model(plan).Where("qp.query_uid = ?", query.UID).Scan(ctx)
+	if err == nil {
+		return plan, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get query plan: %w", err)
+	}
+
+	if query.DatabaseID == nil || query.Fingerprint == "" {
+		return nil, ErrQueryPlanNotFound
+	}
+
+	err = s.db.NewSelect().
+		Model(plan).
+		Where("qp.database_id = ?", *query.DatabaseID).
+		Where("qp.fingerprint = ?", query.Fingerprint).
+		Where("qp.plan IS NOT NULL").
+		OrderExpr("qp.captured_at DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQueryPlanNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get query plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// SlowQueryThresholds returns the slow query threshold of every PostgreSQL
+// database that has one.
+func (s *Store) SlowQueryThresholds(ctx context.Context) (map[uuid.UUID]time.Duration, error) {
+	var rows []struct {
+		UID         uuid.UUID `bun:"uid"`
+		ThresholdMs int       `bun:"slow_query_threshold_ms"`
+	}
+
+	err := s.db.NewSelect().
+		Model((*Server)(nil)).
+		Column("uid", "slow_query_threshold_ms").
+		Where("slow_query_threshold_ms IS NOT NULL").
+		Where("protocol = ?", ProtocolPostgreSQL).
+		Scan(ctx, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slow query thresholds: %w", err)
+	}
+
+	thresholds := make(map[uuid.UUID]time.Duration, len(rows))
+	for _, row := range rows {
+		thresholds[row.UID] = time.Duration(row.ThresholdMs) * time.Millisecond
+	}
+
+	return thresholds, nil
+}
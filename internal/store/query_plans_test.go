@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryPlans(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "queryplans")
+
+	newQuery := func(sql string) *Query {
+		t.Helper()
+
+		query, err := store.CreateQuery(ctx, &Query{
+			ConnectionID: conn.UID,
+			SQLText:      sql,
+			Fingerprint:  "cccc",
+			ExecutedAt:   time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+
+		return query
+	}
+
+	explained := newQuery("SELECT * FROM t WHERE id = 1")
+	later := newQuery("SELECT * FROM t WHERE id = 2")
+
+	if _, err := store.GetQueryPlan(ctx, later); !errors.Is(err, ErrQueryPlanNotFound) {
+		t.Fatalf("GetQueryPlan() before any capture error = %v, want ErrQueryPlanNotFound", err)
+	}
+
+	err := store.SaveQueryPlan(ctx, &QueryPlan{
+		QueryUID:    explained.UID,
+		DatabaseID:  *explained.DatabaseID,
+		Fingerprint: explained.Fingerprint,
+		Plan:        json.RawMessage(`[{"Plan": {"Node Type": "Seq Scan"}}]`),
+	})
+	if err != nil {
+		t.Fatalf("SaveQueryPlan() error = %v", err)
+	}
+
+	plan, err := store.GetQueryPlan(ctx, explained)
+	if err != nil {
+		t.Fatalf("GetQueryPlan() error = %v", err)
+	}
+
+	if plan.QueryUID != explained.UID || len(plan.Plan) == 0 || plan.Error != nil {
+		t.Errorf("GetQueryPlan() = %+v, want the captured plan", plan)
+	}
+
+	// A statement of the same fingerprint shows the plan captured for another.
+	plan, err = store.GetQueryPlan(ctx, later)
+	if err != nil {
+		t.Fatalf("GetQueryPlan() of the same fingerprint error = %v", err)
+	}
+
+	if plan.QueryUID != explained.UID {
+		t.Errorf("GetQueryPlan().QueryUID = %s, want %s", plan.QueryUID, explained.UID)
+	}
+
+	threshold := 250
+	if err := store.UpdateServer(ctx, conn.DatabaseID, ServerUpdate{SlowQueryThresholdMs: &threshold}, nil); err != nil {
+		t.Fatalf("UpdateServer() error = %v", err)
+	}
+
+	thresholds, err := store.SlowQueryThresholds(ctx)
+	if err != nil {
+		t.Fatalf("SlowQueryThresholds() error = %v", err)
+	}
+
+	if thresholds[conn.DatabaseID] != 250*time.Millisecond {
+		t.Errorf("SlowQueryThresholds()[database] = %v, want 250ms", thresholds[conn.DatabaseID])
+	}
+}
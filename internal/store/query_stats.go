@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Orders of the query statistics, by decreasing value.
+const (
+	QueryStatsSortCalls         = "calls"
+	QueryStatsSortTotalDuration = "total_duration"
+	QueryStatsSortAvgDuration   = "avg_duration"
+	QueryStatsSortP95Duration   = "p95_duration"
+	QueryStatsSortRows          = "rows"
+)
+
+// queryStatsOrders maps each sort of the query statistics to its ORDER BY.
+var queryStatsOrders = map[string]string{
+	QueryStatsSortCalls:         "calls DESC",
+	QueryStatsSortTotalDuration: "total_duration_ms DESC NULLS LAST",
+	QueryStatsSortAvgDuration:   "avg_duration_ms DESC NULLS LAST",
+	QueryStatsSortP95Duration:   "p95_duration_ms DESC NULLS LAST",
+	QueryStatsSortRows:          "total_rows DESC",
+}
+
+// ValidQueryStatsSort reports whether sort is one of the QueryStatsSort
+// constants.
+func ValidQueryStatsSort(sort string) bool {
+	_, ok := queryStatsOrders[sort]
+
+	return ok
+}
+
+// QueryStatsFilter selects the queries aggregated by GetQueryStats, with the
+// filters of the query log, and orders the statistics (QueryStatsSortCalls
+// by default).
+type QueryStatsFilter struct {
+	QueryFilter
+	Sort string
+}
+
+// QueryStat aggregates the logged executions of one statement fingerprint on
+// one database, like a pg_stat_statements entry. Calls counts the statements
+// folded into a repeated query, while durations and rows only cover the
+// first of them, the only one measured.
+type QueryStat struct {
+	Fingerprint     string     `bun:"fingerprint" json:"fingerprint"`
+	DatabaseID      *uuid.UUID `bun:"database_id,type:uuid" json:"database_id"`
+	SampleSQL       string     `bun:"sample_sql" json:"-"` // One of the statements, for the API to show normalized
+	Calls           int64      `bun:"calls" json:"calls"`
+	Errors          int64      `bun:"errors" json:"errors"`
+	TotalDurationMs *float64   `bun:"total_duration_ms" json:"total_duration_ms"`
+	AvgDurationMs   *float64   `bun:"avg_duration_ms" json:"avg_duration_ms"`
+	P95DurationMs   *float64   `bun:"p95_duration_ms" json:"p95_duration_ms"`
+	TotalRows       int64      `bun:"total_rows" json:"total_rows"`
+	FirstExecutedAt time.Time  `bun:"first_executed_at" json:"first_executed_at"`
+	LastExecutedAt  time.Time  `bun:"last_executed_at" json:"last_executed_at"`
+}
+
+// GetQueryStats aggregates the fingerprinted queries matching the filter per
+// fingerprint and database.
+// Served by the storage replica when one is configured and healthy.
+func (s *Store) GetQueryStats(ctx context.Context, filter QueryStatsFilter) ([]QueryStat, error) {
+	stats := []QueryStat{}
+
+	if err := s.scanReadOnly(ctx, s.selectQueryStats(filter), &stats); err != nil {
+		return nil, fmt.Errorf("failed to aggregate queries: %w", err)
+	}
+
+	return stats, nil
+}
+
+// CountQueryStats returns the number of statistics GetQueryStats returns for
+// the filter, regardless of its Limit and Offset.
+// Served by the storage replica when one is configured and healthy.
+func (s *Store) CountQueryStats(ctx context.Context, filter QueryStatsFilter) (int, error) {
+	n, err := s.countReadOnly(ctx, s.selectQueryStats(filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count query statistics: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectQueryStats builds the aggregation of the queries matching filter.
+func (s *Store) selectQueryStats(filter QueryStatsFilter) *bun.SelectQuery {
+	order, ok := queryStatsOrders[filter.Sort]
+	if !ok {
+		order = queryStatsOrders[QueryStatsSortCalls]
+	}
+
+	q := s.db.NewSelect().
+		TableExpr("queries AS q").
+		ColumnExpr("q.fingerprint, q.database_id").
+		ColumnExpr("min(q.sql_text) AS sample_sql").
+		ColumnExpr("sum(q.repeat_count) AS calls").
+		ColumnExpr("coalesce(sum(q.repeat_count) FILTER (WHERE q.error IS NOT NULL), 0) AS errors").
+		ColumnExpr("sum(q.duration_ms)::float8 AS total_duration_ms").
+		ColumnExpr("avg(q.duration_ms)::float8 AS avg_duration_ms").
+		ColumnExpr("percentile_cont(0.95) WITHIN GROUP (ORDER BY q.duration_ms) AS p95_duration_ms").
+		ColumnExpr("coalesce(sum(q.rows_affected), 0) AS total_rows").
+		ColumnExpr("min(q.executed_at) AS first_executed_at").
+		ColumnExpr("max(coalesce(q.last_executed_at, q.executed_at)) AS last_executed_at").
+		Where("q.fingerprint IS NOT NULL")
+
+	q = whereQueries(q, filter.QueryFilter).
+		Group("q.fingerprint", "q.database_id").
+		OrderExpr(order).
+		OrderExpr("q.fingerprint")
+
+	return paginate(q, filter.Limit, filter.Offset)
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetQueryStats(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "querystats")
+	queryError := "boom"
+
+	for i, q := range []struct {
+		fingerprint string
+		durationMs  float64
+		rows        int64
+		err         *string
+	}{
+		{fingerprint: "aaaa", durationMs: 10, rows: 1},
+		{fingerprint: "aaaa", durationMs: 20, rows: 2},
+		{fingerprint: "aaaa", durationMs: 30, rows: 3, err: &queryError},
+		{fingerprint: "bbbb", durationMs: 500, rows: 0},
+		{fingerprint: "", durationMs: 1000, rows: 100}, // Logged before fingerprinting
+	} {
+		rows := q.rows
+		duration := q.durationMs
+
+		_, err := store.CreateQuery(ctx, &Query{
+			ConnectionID: conn.UID,
+			SQLText:      "SELECT " + string(rune('a'+i)),
+			Fingerprint:  q.fingerprint,
+			ExecutedAt:   time.Now(),
+			DurationMs:   &duration,
+			RowsAffected: &rows,
+			Error:        q.err,
+		})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+	}
+
+	filter := QueryStatsFilter{QueryFilter: QueryFilter{ConnectionID: &conn.UID}}
+
+	stats, err := store.GetQueryStats(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetQueryStats() error = %v", err)
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("GetQueryStats() returned %d statistics, want 2", len(stats))
+	}
+
+	top := stats[0]
+	if top.Fingerprint != "aaaa" || top.Calls != 3 || top.Errors != 1 || top.TotalRows != 6 {
+		t.Errorf("GetQueryStats()[0] = %+v, want aaaa with 3 calls, 1 error and 6 rows", top)
+	}
+
+	if top.AvgDurationMs == nil || *top.AvgDurationMs != 20 {
+		t.Errorf("GetQueryStats()[0].AvgDurationMs = %v, want 20", top.AvgDurationMs)
+	}
+
+	if top.P95DurationMs == nil || *top.P95DurationMs != 29 {
+		t.Errorf("GetQueryStats()[0].P95DurationMs = %v, want 29", top.P95DurationMs)
+	}
+
+	if top.DatabaseID == nil || top.SampleSQL != "SELECT a" {
+		t.Errorf("GetQueryStats()[0] database = %v, sample = %q", top.DatabaseID, top.SampleSQL)
+	}
+
+	filter.Sort = QueryStatsSortAvgDuration
+
+	stats, err = store.GetQueryStats(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetQueryStats() error = %v", err)
+	}
+
+	if stats[0].Fingerprint != "bbbb" {
+		t.Errorf("GetQueryStats() by avg_duration starts with %s, want bbbb", stats[0].Fingerprint)
+	}
+
+	filter.Fingerprint = "bbbb"
+
+	total, err := store.CountQueryStats(ctx, filter)
+	if err != nil {
+		t.Fatalf("CountQueryStats() error = %v", err)
+	}
+
+	if total != 1 {
+		t.Errorf("CountQueryStats() = %d, want 1", total)
+	}
+}
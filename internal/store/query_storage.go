@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+// ErrInvalidQueryStorage is returned when a database's query storage
+// overrides are malformed.
+var ErrInvalidQueryStorage = errors.New("invalid query storage overrides")
+
+// QueryStorageOverrides replace, for one database, the query storage
+// configuration the proxies capture result rows with and the retention the
+// janitor applies to its queries. Unset fields keep the global value; a zero
+// retention keeps the database's queries or rows forever.
+type QueryStorageOverrides struct {
+	StoreResults   *bool  `json:"store_results,omitempty"`
+	MaxResultRows  *int   `json:"max_result_rows,omitempty"`
+	MaxResultBytes *int64 `json:"max_result_bytes,omitempty"`
+	QueriesDays    *int   `json:"queries_days,omitempty"`
+	RowsDays       *int   `json:"rows_days,omitempty"`
+}
+
+// IsZero reports whether the overrides replace nothing.
+func (o *QueryStorageOverrides) IsZero() bool {
+	return o == nil || *o == (QueryStorageOverrides{})
+}
+
+// Validate checks the limits are positive and the retentions not negative.
+func (o *QueryStorageOverrides) Validate() error {
+	switch {
+	case o.MaxResultRows != nil && *o.MaxResultRows <= 0:
+		return fmt.Errorf("%w: max_result_rows must be positive", ErrInvalidQueryStorage)
+	case o.MaxResultBytes != nil && *o.MaxResultBytes <= 0:
+		return fmt.Errorf("%w: max_result_bytes must be positive", ErrInvalidQueryStorage)
+	case o.QueriesDays != nil && *o.QueriesDays < 0:
+		return fmt.Errorf("%w: queries_days must not be negative", ErrInvalidQueryStorage)
+	case o.RowsDays != nil && *o.RowsDays < 0:
+		return fmt.Errorf("%w: rows_days must not be negative", ErrInvalidQueryStorage)
+	}
+
+	return nil
+}
+
+// EffectiveQueryStorage returns cfg with the server's query storage overrides
+// applied: the settings the proxies capture the server's result rows with.
+func (s *Server) EffectiveQueryStorage(cfg config.QueryStorageConfig) config.QueryStorageConfig {
+	if s == nil || s.QueryStorage == nil {
+		return cfg
+	}
+
+	o := s.QueryStorage
+
+	if o.StoreResults != nil {
+		cfg.StoreResults = *o.StoreResults
+	}
+
+	if o.MaxResultRows != nil {
+		cfg.MaxResultRows = *o.MaxResultRows
+	}
+
+	if o.MaxResultBytes != nil {
+		cfg.MaxResultBytes = *o.MaxResultBytes
+	}
+
+	return cfg
+}
+
+// queryStorageRetention is the retention override of a database.
+type queryStorageRetention struct {
+	UID       uuid.UUID             `bun:"uid"`
+	Overrides QueryStorageOverrides `bun:"query_storage,type:jsonb"`
+}
+
+// purgeQueryStorageOverrides deletes the queries and result rows of every
+// database with a retention override past it, relative to now. Deleted
+// databases keep being purged until their queries are gone.
+func (s *Store) purgeQueryStorageOverrides(ctx context.Context, batch int, now time.Time, result *PurgeResult) error {
+	var retentions []queryStorageRetention
+	if err := s.db.NewRaw(`SELECT uid, query_storage FROM servers WHERE query_storage IS NOT NULL`).
+		Scan(ctx, &retentions); err != nil {
+		return fmt.Errorf("failed to list database retention overrides: %w", err)
+	}
+
+	for _, retention := range retentions {
+		if days := retention.Overrides.QueriesDays; days != nil && *days > 0 {
+			cutoff := now.AddDate(0, 0, -*days)
+			if err := s.purgeQueriesBefore(ctx, batch, cutoff, &retention.UID, result); err != nil {
+				return fmt.Errorf("database %s: %w", retention.UID, err)
+			}
+		}
+
+		if days := retention.Overrides.RowsDays; days != nil && *days > 0 {
+			cutoff := now.AddDate(0, 0, -*days)
+			if err := s.purgeRowsBefore(ctx, batch, cutoff, &retention.UID, result); err != nil {
+				return fmt.Errorf("database %s: %w", retention.UID, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/config"
+)
+
+func TestQueryStorageOverridesValidate(t *testing.T) {
+	t.Parallel()
+
+	zero, negative, positive := 0, -1, 10
+	zeroBytes := int64(0)
+
+	for _, tc := range []struct {
+		name      string
+		overrides QueryStorageOverrides
+		valid     bool
+	}{
+		{"empty", QueryStorageOverrides{}, true},
+		{"limits and retention", QueryStorageOverrides{MaxResultRows: &positive, QueriesDays: &positive, RowsDays: &zero}, true},
+		{"zero rows", QueryStorageOverrides{MaxResultRows: &zero}, false},
+		{"zero bytes", QueryStorageOverrides{MaxResultBytes: &zeroBytes}, false},
+		{"negative queries days", QueryStorageOverrides{QueriesDays: &negative}, false},
+		{"negative rows days", QueryStorageOverrides{RowsDays: &negative}, false},
+	} {
+		err := tc.overrides.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%s: Validate() = %v, want nil", tc.name, err)
+		}
+
+		if !tc.valid && !errors.Is(err, ErrInvalidQueryStorage) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidQueryStorage", tc.name, err)
+		}
+	}
+}
+
+func TestEffectiveQueryStorage(t *testing.T) {
+	t.Parallel()
+
+	global := config.QueryStorageConfig{StoreResults: true, MaxResultRows: 100000, MaxResultBytes: 104857600}
+	off, rows := false, 10
+
+	if got := (*Server)(nil).EffectiveQueryStorage(global); got != global {
+		t.Errorf("nil server: EffectiveQueryStorage() = %+v, want %+v", got, global)
+	}
+
+	if got := (&Server{}).EffectiveQueryStorage(global); got != global {
+		t.Errorf("no overrides: EffectiveQueryStorage() = %+v, want %+v", got, global)
+	}
+
+	srv := &Server{QueryStorage: &QueryStorageOverrides{StoreResults: &off, MaxResultRows: &rows}}
+	want := config.QueryStorageConfig{StoreResults: false, MaxResultRows: 10, MaxResultBytes: 104857600}
+
+	if got := srv.EffectiveQueryStorage(global); got != want {
+		t.Errorf("EffectiveQueryStorage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPurge_QueryStorageOverrides(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	day := 24 * time.Hour
+
+	shortLived := createTestConnection(t, ctx, store, "overrides-short")
+	kept := createTestConnection(t, ctx, store, "overrides-kept")
+	global := createTestConnection(t, ctx, store, "overrides-global")
+
+	createQuery := func(conn *Connection, age time.Duration) *Query {
+		t.Helper()
+
+		query, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: "SELECT 1", ExecutedAt: now.Add(-age)})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+
+		if err := store.StoreQueryRows(ctx, query.UID, []QueryRow{{RowNumber: 0, RowData: json.RawMessage(`{"n":1}`), RowSizeBytes: 100}}); err != nil {
+			t.Fatalf("StoreQueryRows() error = %v", err)
+		}
+
+		return query
+	}
+
+	shortQuery := createQuery(shortLived, 10*day) // past its database's queries_days
+	shortRows := createQuery(shortLived, 3*day)   // past its database's rows_days only
+	keptQuery := createQuery(kept, 100*day)       // its database keeps queries forever
+	globalQuery := createQuery(global, 100*day)   // past the policy's queries_days
+	globalRecent := createQuery(global, 10*day)   // within the policy
+
+	one, seven, forever := 1, 7, 0
+
+	for conn, overrides := range map[*Connection]*QueryStorageOverrides{
+		shortLived: {QueriesDays: &seven, RowsDays: &one},
+		kept:       {QueriesDays: &forever},
+	} {
+		if err := store.UpdateServer(ctx, conn.DatabaseID, ServerUpdate{QueryStorage: overrides}, nil); err != nil {
+			t.Fatalf("UpdateServer() error = %v", err)
+		}
+	}
+
+	result, err := store.Purge(ctx, RetentionPolicy{QueriesAge: 90 * day, BatchSize: 1}, now)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	if result.Queries != 2 || result.Rows != 1 {
+		t.Errorf("Purge() = %+v, want 2 queries and 1 row", *result)
+	}
+
+	for _, tc := range []struct {
+		query   *Query
+		present bool
+		rows    int
+	}{
+		{shortQuery, false, 0},
+		{shortRows, true, 0},
+		{keptQuery, true, 1},
+		{globalQuery, false, 0},
+		{globalRecent, true, 1},
+	} {
+		_, err := store.GetQuery(ctx, tc.query.UID)
+		if present := err == nil; present != tc.present {
+			t.Errorf("query %s present = %v, want %v", tc.query.UID, present, tc.present)
+		}
+
+		var n int
+		if err := store.db.NewRaw("SELECT COUNT(*) FROM query_rows WHERE query_id = ?", tc.query.UID).Scan(ctx, &n); err != nil {
+			t.Fatalf("count rows: %v", err)
+		}
+
+		if n != tc.rows {
+			t.Errorf("query %s has %d rows, want %d", tc.query.UID, n, tc.rows)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Rate limit override parameters: the global_parameters of GroupRateLimit
+// hold the per-role overrides of the API rate limit, keyed
+// KeyRateLimitRolePrefix + role, in requests per minute.
+const (
+	GroupRateLimit         = "rate_limit"
+	KeyRateLimitRolePrefix = "role."
+)
+
+// GetRoleRateLimits returns the per-role overrides of the API rate limit, by
+// role. Values that are not positive integers are skipped.
+func (s *Store) GetRoleRateLimits(ctx context.Context) (map[string]int, error) {
+	params, err := s.GetParameters(ctx, GroupRateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]int, len(params))
+
+	for _, p := range params {
+		role, ok := strings.CutPrefix(p.Key, KeyRateLimitRolePrefix)
+		if !ok {
+			continue
+		}
+
+		if n, err := strconv.Atoi(p.Value); err == nil && n > 0 {
+			limits[role] = n
+		}
+	}
+
+	return limits, nil
+}
+
+// SetRoleRateLimit overrides the API rate limit of role.
+func (s *Store) SetRoleRateLimit(ctx context.Context, role string, requestsPerMinute int) error {
+	return s.SetParameter(ctx, GroupRateLimit, KeyRateLimitRolePrefix+role, strconv.Itoa(requestsPerMinute))
+}
+
+// DeleteRoleRateLimit removes the override of the API rate limit of role.
+// Returns ErrParameterNotFound when it has none.
+func (s *Store) DeleteRoleRateLimit(ctx context.Context, role string) error {
+	return s.DeleteParameter(ctx, GroupRateLimit, KeyRateLimitRolePrefix+role)
+}
+
+// SetUserRateLimit overrides the API rate limit of a user; nil removes the
+// override.
+func (s *Store) SetUserRateLimit(ctx context.Context, uid uuid.UUID, requestsPerMinute *int) error {
+	result, err := s.db.NewUpdate().
+		Model((*User)(nil)).
+		Set("rate_limit_per_minute = ?", requestsPerMinute).
+		Set("updated_at = NOW()").
+		Where("uid = ?", uid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to set user rate limit: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
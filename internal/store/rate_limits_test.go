@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitOverrides(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	t.Run("role overrides", func(t *testing.T) {
+		require.NoError(t, s.SetRoleRateLimit(ctx, RoleViewer, 300))
+		require.NoError(t, s.SetRoleRateLimit(ctx, RoleAuditor, 120))
+		require.NoError(t, s.SetRoleRateLimit(ctx, RoleViewer, 600))
+		// Hand-edited through the parameters API: skipped.
+		require.NoError(t, s.SetParameter(ctx, GroupRateLimit, KeyRateLimitRolePrefix+RoleConnector, "lots"))
+
+		limits, err := s.GetRoleRateLimits(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{RoleViewer: 600, RoleAuditor: 120}, limits)
+
+		require.NoError(t, s.DeleteRoleRateLimit(ctx, RoleAuditor))
+		require.ErrorIs(t, s.DeleteRoleRateLimit(ctx, RoleAuditor), ErrParameterNotFound)
+
+		limits, err = s.GetRoleRateLimits(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{RoleViewer: 600}, limits)
+	})
+
+	t.Run("user override", func(t *testing.T) {
+		user, err := s.CreateUser(ctx, "ratelimited", "hash", []string{RoleConnector})
+		require.NoError(t, err)
+		assert.Nil(t, user.RateLimitPerMinute)
+
+		limit := 1000
+		require.NoError(t, s.SetUserRateLimit(ctx, user.UID, &limit))
+
+		user, err = s.GetUserByUID(ctx, user.UID)
+		require.NoError(t, err)
+		require.NotNil(t, user.RateLimitPerMinute)
+		assert.Equal(t, 1000, *user.RateLimitPerMinute)
+
+		require.NoError(t, s.SetUserRateLimit(ctx, user.UID, nil))
+
+		user, err = s.GetUserByUID(ctx, user.UID)
+		require.NoError(t, err)
+		assert.Nil(t, user.RateLimitPerMinute)
+
+		require.ErrorIs(t, s.SetUserRateLimit(ctx, uuid.New(), &limit), ErrUserNotFound)
+	})
+}
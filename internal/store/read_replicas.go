@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidReadReplica is returned when an entry of a database's read
+// replica list is malformed or duplicated.
+var ErrInvalidReadReplica = errors.New("invalid read replica")
+
+// readReplicasProtocols lists the protocols whose proxy routes read-only
+// sessions to read replicas.
+var readReplicasProtocols = []string{ProtocolPostgreSQL}
+
+// SupportsReadReplicas reports whether the proxy of protocol routes sessions
+// to read replicas.
+func SupportsReadReplicas(protocol string) bool {
+	return slices.Contains(readReplicasProtocols, protocol)
+}
+
+// NormalizeReadReplicas validates a read replica list and returns its
+// entries trimmed. An entry is host or host:port ([host]:port for IPv6
+// addresses); each may appear once.
+func NormalizeReadReplicas(replicas []string) ([]string, error) {
+	if replicas == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(replicas))
+
+	for _, entry := range replicas {
+		entry = strings.TrimSpace(entry)
+		if _, _, err := splitReplicaAddr(entry, 1); err != nil {
+			return nil, err
+		}
+
+		if slices.Contains(normalized, entry) {
+			return nil, fmt.Errorf("%w: %q given more than once", ErrInvalidReadReplica, entry)
+		}
+
+		normalized = append(normalized, entry)
+	}
+
+	return normalized, nil
+}
+
+// splitReplicaAddr splits a read replica entry into its host and port, an
+// entry without a port taking defaultPort.
+func splitReplicaAddr(entry string, defaultPort int) (string, int, error) {
+	host, portText, err := net.SplitHostPort(entry)
+	if err != nil {
+		host, portText = strings.TrimSuffix(strings.TrimPrefix(entry, "["), "]"), ""
+	}
+
+	if host == "" || strings.ContainsFunc(host, unicode.IsSpace) || strings.ContainsAny(host, "/[]") {
+		return "", 0, fmt.Errorf("%w: %q must be written host or host:port", ErrInvalidReadReplica, entry)
+	}
+
+	if portText == "" {
+		return host, defaultPort, nil
+	}
+
+	port, err := strconv.Atoi(portText)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("%w: %q has an invalid port", ErrInvalidReadReplica, entry)
+	}
+
+	return host, port, nil
+}
+
+// ReadReplicaTargets returns a copy of the server for each of its read
+// replicas, addressing the replica instead of the primary. A replica without
+// a port listens on the primary's. Everything else, credentials, TLS and SSH
+// tunnel included, is the primary's: replicas are expected to be reachable
+// the same way. Malformed entries, which NormalizeReadReplicas keeps out, are
+// skipped.
+func (db *Server) ReadReplicaTargets() []*Server {
+	targets := make([]*Server, 0, len(db.ReadReplicas))
+
+	for _, entry := range db.ReadReplicas {
+		host, port, err := splitReplicaAddr(entry, db.Port)
+		if err != nil {
+			continue
+		}
+
+		target := *db
+		target.Host = host
+		target.Port = port
+		targets = append(targets, &target)
+	}
+
+	return targets
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeReadReplicas(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		replicas []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "nil", replicas: nil, want: nil},
+		{name: "empty", replicas: []string{}, want: []string{}},
+		{name: "hosts", replicas: []string{" replica-1 ", "replica-2:5433"}, want: []string{"replica-1", "replica-2:5433"}},
+		{name: "ipv6", replicas: []string{"[::1]:5433", "::1"}, want: []string{"[::1]:5433", "::1"}},
+		{name: "duplicate", replicas: []string{"replica-1", "replica-1 "}, wantErr: true},
+		{name: "empty entry", replicas: []string{""}, wantErr: true},
+		{name: "port only", replicas: []string{":5432"}, wantErr: true},
+		{name: "bad port", replicas: []string{"replica-1:99999"}, wantErr: true},
+		{name: "url", replicas: []string{"postgres://replica-1"}, wantErr: true},
+		{name: "whitespace", replicas: []string{"replica 1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NormalizeReadReplicas(tt.replicas)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeReadReplicas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if !errors.Is(err, ErrInvalidReadReplica) {
+					t.Errorf("NormalizeReadReplicas() error = %v, want %v", err, ErrInvalidReadReplica)
+				}
+
+				return
+			}
+
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("NormalizeReadReplicas() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_ReadReplicaTargets(t *testing.T) {
+	t.Parallel()
+
+	db := &Server{
+		Name:         "orders",
+		Host:         "primary",
+		Port:         5432,
+		Username:     "app",
+		ReadReplicas: []string{"replica-1", "replica-2:5433", "[::1]:6432"},
+	}
+
+	targets := db.ReadReplicaTargets()
+
+	want := []struct {
+		host string
+		port int
+	}{{"replica-1", 5432}, {"replica-2", 5433}, {"::1", 6432}}
+
+	if len(targets) != len(want) {
+		t.Fatalf("ReadReplicaTargets() returned %d targets, want %d", len(targets), len(want))
+	}
+
+	for i, target := range targets {
+		if target.Host != want[i].host || target.Port != want[i].port {
+			t.Errorf("target %d = %s:%d, want %s:%d", i, target.Host, target.Port, want[i].host, want[i].port)
+		}
+
+		if target.Name != db.Name || target.Username != db.Username {
+			t.Errorf("target %d does not keep the primary's settings", i)
+		}
+	}
+
+	if db.Host != "primary" {
+		t.Errorf("ReadReplicaTargets() modified the primary: host = %q", db.Host)
+	}
+}
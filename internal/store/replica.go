@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// Replica status values reported by ReplicaStatus.
+const (
+	ReplicaStatusNone    = ""        // no replica configured
+	ReplicaStatusHealthy = "healthy" // reads are served by the replica
+	ReplicaStatusLagging = "lagging" // replica is behind by more than the allowed lag
+	ReplicaStatusDown    = "down"    // replica is unreachable
+)
+
+// DefaultReplicaMaxLag is the replication lag beyond which reads go back to
+// the primary.
+const DefaultReplicaMaxLag = 30 * time.Second
+
+// replicaCheckInterval is how often the replica's reachability and lag are
+// re-evaluated.
+const replicaCheckInterval = 10 * time.Second
+
+// replicaLagQuery returns the replica's replay lag in seconds. A replica that
+// has replayed everything it received is not lagging even when the primary is
+// idle (pg_last_xact_replay_timestamp then keeps aging), and a server that is
+// not in recovery reports 0 so a misconfigured DSN pointing at a primary still
+// works.
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+// replica is a read-only storage connection used for heavy list queries. A
+// background checker tracks its health; reads fall back to the primary while
+// it is down or lagging.
+type replica struct {
+	db     *bun.DB
+	maxLag time.Duration
+
+	mu     sync.RWMutex
+	status string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// openReplica connects to the replica and starts its health checker. An
+// unreachable replica is not fatal: it starts out "down" and is picked up by
+// the checker once it becomes available.
+func openReplica(ctx context.Context, dsn string, options Options) *replica {
+	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
+	applyPoolOptions(sqldb, options)
+
+	checkCtx, cancel := context.WithCancel(context.Background())
+
+	r := &replica{
+		db:     bun.NewDB(sqldb, pgdialect.New()),
+		maxLag: options.ReplicaMaxLag,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	if r.maxLag <= 0 {
+		r.maxLag = DefaultReplicaMaxLag
+	}
+
+	r.check(ctx)
+
+	go r.run(checkCtx)
+
+	return r
+}
+
+// run re-checks the replica until the context is cancelled.
+func (r *replica) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx)
+		}
+	}
+}
+
+// check measures replication lag and updates the replica's status.
+func (r *replica) check(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, replicaCheckInterval)
+	defer cancel()
+
+	var lagSeconds float64
+	if err := r.db.QueryRowContext(checkCtx, replicaLagQuery).Scan(&lagSeconds); err != nil {
+		if ctx.Err() == nil {
+			r.setStatus(ReplicaStatusDown, slog.Any("error", err))
+		}
+
+		return
+	}
+
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	if lag > r.maxLag {
+		r.setStatus(ReplicaStatusLagging, slog.Duration("lag", lag), slog.Duration("max_lag", r.maxLag))
+
+		return
+	}
+
+	r.setStatus(ReplicaStatusHealthy)
+}
+
+// markDown takes the replica out of rotation after a failed read, until the
+// next successful check.
+func (r *replica) markDown(err error) {
+	r.setStatus(ReplicaStatusDown, slog.Any("error", err))
+}
+
+// setStatus records a status change, logging transitions only.
+func (r *replica) setStatus(status string, attrs ...any) {
+	r.mu.Lock()
+	previous := r.status
+	r.status = status
+	r.mu.Unlock()
+
+	if previous == status {
+		return
+	}
+
+	args := append([]any{slog.String("status", status)}, attrs...)
+
+	if status == ReplicaStatusHealthy {
+		slog.InfoContext(context.Background(), "Storage replica serving reads", args...)
+	} else {
+		slog.WarnContext(context.Background(), "Storage replica unavailable, reading from primary", args...)
+	}
+}
+
+// getStatus returns the last observed status.
+func (r *replica) getStatus() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.status
+}
+
+// close stops the checker and closes the replica pool.
+func (r *replica) close() error {
+	r.cancel()
+	<-r.done
+
+	return r.db.Close()
+}
+
+// ReplicaStatus reports the state of the storage read replica, or
+// ReplicaStatusNone when none is configured.
+func (s *Store) ReplicaStatus() string {
+	if s.replica == nil {
+		return ReplicaStatusNone
+	}
+
+	return s.replica.getStatus()
+}
+
+// scanReadOnly executes a read-only select on the replica when it is healthy,
+// and on the primary otherwise. A replica failure is retried on the primary
+// and takes the replica out of rotation, so API reads keep working while it is
+// down. Only use it for queries that tolerate replication lag (listings of
+// logged activity), never for reads that must observe a just-made write.
+func (s *Store) scanReadOnly(ctx context.Context, q *bun.SelectQuery, dest ...any) error {
+	if s.replica != nil && s.replica.getStatus() == ReplicaStatusHealthy {
+		err := q.Conn(s.replica.db).Scan(ctx, dest...)
+		if err == nil || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+			return err
+		}
+
+		s.replica.markDown(fmt.Errorf("read failed: %w", err))
+		q = q.Conn(s.db)
+	}
+
+	return q.Scan(ctx, dest...)
+}
+
+// countReadOnly counts the rows of a read-only select like scanReadOnly
+// scans them: on the replica when it is healthy, on the primary otherwise.
+func (s *Store) countReadOnly(ctx context.Context, q *bun.SelectQuery) (int, error) {
+	if s.replica != nil && s.replica.getStatus() == ReplicaStatusHealthy {
+		n, err := q.Conn(s.replica.db).Count(ctx)
+		if err == nil || ctx.Err() != nil {
+			return n, err
+		}
+
+		s.replica.markDown(fmt.Errorf("count failed: %w", err))
+		q = q.Conn(s.db)
+	}
+
+	return q.Count(ctx)
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidResultMasking is returned when a database's result masking is
+// malformed.
+var ErrInvalidResultMasking = errors.New("invalid result masking")
+
+// ResultMasking names the result columns of a database whose values are
+// replaced by a marker before captured rows are stored, so passwords or
+// emails read through the proxy never reach query_rows. Clients still get the
+// real values: it is the mask_pii control that masks what they see.
+type ResultMasking struct {
+	// ColumnPatterns are regular expressions matched case-insensitively
+	// against column names.
+	ColumnPatterns []string `json:"column_patterns,omitempty"`
+	// Columns are column names, optionally qualified by their table (and
+	// schema) as table.column. A qualified column is masked in the results
+	// of statements reading or writing that table.
+	Columns []string `json:"columns,omitempty"`
+}
+
+// IsZero reports whether the masking masks nothing.
+func (m *ResultMasking) IsZero() bool {
+	return m == nil || (len(m.ColumnPatterns) == 0 && len(m.Columns) == 0)
+}
+
+// Validate checks the patterns compile and the columns are well-formed.
+func (m *ResultMasking) Validate() error {
+	for _, pattern := range m.ColumnPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%w: column pattern %q: %w", ErrInvalidResultMasking, pattern, err)
+		}
+	}
+
+	for _, column := range m.Columns {
+		for part := range strings.SplitSeq(column, ".") {
+			if strings.TrimSpace(part) == "" {
+				return fmt.Errorf("%w: column %q must be column or table.column", ErrInvalidResultMasking, column)
+			}
+		}
+	}
+
+	return nil
+}
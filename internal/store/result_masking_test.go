@@ -0,0 +1,32 @@
+package store
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResultMaskingValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		masking ResultMasking
+		valid   bool
+	}{
+		{"empty", ResultMasking{}, true},
+		{"patterns and columns", ResultMasking{ColumnPatterns: []string{"^pass"}, Columns: []string{"ssn", "users.email", "public.users.email"}}, true},
+		{"bad pattern", ResultMasking{ColumnPatterns: []string{"("}}, false},
+		{"empty column", ResultMasking{Columns: []string{""}}, false},
+		{"empty table", ResultMasking{Columns: []string{".email"}}, false},
+		{"trailing dot", ResultMasking{Columns: []string{"users."}}, false},
+	} {
+		err := tc.masking.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%s: Validate() = %v, want nil", tc.name, err)
+		}
+
+		if !tc.valid && !errors.Is(err, ErrInvalidResultMasking) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidResultMasking", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPurgeBatchSize is how many queries, connections or audit events a
+// purge deletes per statement, used when RetentionPolicy leaves it unset.
+const DefaultPurgeBatchSize = 1000
+
+// RetentionPolicy says what Purge deletes. A zero age or size keeps the
+// corresponding data forever.
+type RetentionPolicy struct {
+	// QueriesAge deletes queries executed longer ago than this, with their
+	// result rows.
+	QueriesAge time.Duration
+	// RowsAge deletes the result rows of queries executed longer ago than
+	// this; the queries themselves are kept.
+	RowsAge time.Duration
+	// ConnectionsAge deletes connections that ended longer ago than this.
+	// A connection is only deleted once none of its queries is left.
+	ConnectionsAge time.Duration
+	// AuditAge deletes audit events logged longer ago than this.
+	AuditAge time.Duration
+	// MaxRowBytes caps the storage used by result rows, compacted ones
+	// included: past it, the result rows of the oldest queries are deleted.
+	MaxRowBytes int64
+	// BatchSize bounds each delete statement (0 = DefaultPurgeBatchSize).
+	BatchSize int
+}
+
+// Enabled reports whether the policy deletes anything.
+func (p RetentionPolicy) Enabled() bool {
+	return p.QueriesAge > 0 || p.RowsAge > 0 || p.ConnectionsAge > 0 || p.AuditAge > 0 || p.MaxRowBytes > 0
+}
+
+// PurgeResult reports what a Purge deleted.
+type PurgeResult struct {
+	Queries     int64 `json:"queries"`
+	Rows        int64 `json:"rows"`
+	RowBlobs    int64 `json:"row_blobs"`
+	Connections int64 `json:"connections"`
+	AuditEvents int64 `json:"audit_events"`
+}
+
+// Purge deletes the data the policy no longer retains, relative to now, the
+// queries and result rows past the retention overrides of the databases and
+// the result rows past their row quotas.
+// Every category is deleted in batches of short statements, oldest first, so
+// a purge never holds long locks and an interrupted one keeps what it did.
+// The returned result covers the work done before an error.
+func (s *Store) Purge(ctx context.Context, policy RetentionPolicy, now time.Time) (*PurgeResult, error) {
+	batch := policy.BatchSize
+	if batch <= 0 {
+		batch = DefaultPurgeBatchSize
+	}
+
+	result := &PurgeResult{}
+
+	// Queries cascade to their rows and blobs; those are counted only when
+	// deleted on their own.
+	if policy.QueriesAge > 0 {
+		if err := s.purgeQueriesBefore(ctx, batch, now.Add(-policy.QueriesAge), nil, result); err != nil {
+			return result, err
+		}
+	}
+
+	if policy.RowsAge > 0 {
+		if err := s.purgeRowsBefore(ctx, batch, now.Add(-policy.RowsAge), nil, result); err != nil {
+			return result, err
+		}
+	}
+
+	// Databases' retention overrides are enforced whatever the policy.
+	if err := s.purgeQueryStorageOverrides(ctx, batch, now, result); err != nil {
+		return result, err
+	}
+
+	if policy.MaxRowBytes > 0 {
+		if err := s.purgeRowsOverSize(ctx, batch, policy.MaxRowBytes, result); err != nil {
+			return result, err
+		}
+	}
+
+	// Databases' row quotas are enforced whatever the policy.
+	if err := s.purgeRowQuotas(ctx, batch, result); err != nil {
+		return result, err
+	}
+
+	if policy.ConnectionsAge > 0 {
+		n, err := s.purgeBatches(ctx, batch, `DELETE FROM connections WHERE uid IN (
+			SELECT c.uid FROM connections c
+			WHERE COALESCE(c.disconnected_at, c.last_activity_at) < ?
+			AND NOT EXISTS (SELECT 1 FROM queries q WHERE q.connection_id = c.uid)
+			ORDER BY c.connected_at LIMIT ?)`, now.Add(-policy.ConnectionsAge))
+		result.Connections += n
+
+		if err != nil {
+			return result, fmt.Errorf("failed to purge connections: %w", err)
+		}
+	}
+
+	if policy.AuditAge > 0 {
+		n, err := s.purgeBatches(ctx, batch, `DELETE FROM audit_log WHERE uid IN (
+			SELECT uid FROM audit_log WHERE created_at < ? ORDER BY created_at LIMIT ?)`, now.Add(-policy.AuditAge))
+		result.AuditEvents += n
+
+		if err != nil {
+			return result, fmt.Errorf("failed to purge audit events: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// purgeBatches runs a delete statement taking args then a batch size until it
+// deletes less than a full batch, and returns the total deleted.
+func (s *Store) purgeBatches(ctx context.Context, batch int, query string, args ...any) (int64, error) {
+	var total int64
+
+	args = append(args, batch)
+
+	for {
+		res, err := s.db.NewRaw(query, args...).Exec(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+
+		if n < int64(batch) {
+			return total, nil
+		}
+	}
+}
+
+// retentionFilter restricts the queries q a purge deletes to those of
+// databaseID or, when nil, to those of the databases without a retention
+// override for key, which are purged on their own.
+func retentionFilter(key string, databaseID *uuid.UUID) (string, []any) {
+	if databaseID != nil {
+		return "AND q.database_id = ?", []any{*databaseID}
+	}
+
+	return `AND NOT EXISTS (SELECT 1 FROM servers s
+		WHERE s.uid = q.database_id AND s.query_storage->'` + key + `' IS NOT NULL)`, nil
+}
+
+// purgeQueriesBefore deletes the queries executed before cutoff, of
+// databaseID or of every database without a queries_days override when nil.
+func (s *Store) purgeQueriesBefore(ctx context.Context, batch int, cutoff time.Time, databaseID *uuid.UUID, result *PurgeResult) error {
+	filter, args := retentionFilter("queries_days", databaseID)
+
+	n, err := s.purgeBatches(ctx, batch, `DELETE FROM queries WHERE uid IN (
+		SELECT q.uid FROM queries q WHERE q.executed_at < ? `+filter+`
+		ORDER BY q.executed_at LIMIT ?)`, append([]any{cutoff}, args...)...)
+	result.Queries += n
+
+	if err != nil {
+		return fmt.Errorf("failed to purge queries: %w", err)
+	}
+
+	return nil
+}
+
+// purgeRowsBefore deletes the result rows of queries executed before cutoff,
+// of databaseID or of every database without a rows_days override when nil.
+func (s *Store) purgeRowsBefore(ctx context.Context, batch int, cutoff time.Time, databaseID *uuid.UUID, result *PurgeResult) error {
+	filter, args := retentionFilter("rows_days", databaseID)
+	args = append([]any{cutoff}, args...)
+
+	n, err := s.purgeBatches(ctx, batch, `DELETE FROM query_rows WHERE query_id IN (
+		SELECT q.uid FROM queries q
+		WHERE q.executed_at < ? `+filter+` AND EXISTS (SELECT 1 FROM query_rows qr WHERE qr.query_id = q.uid)
+		ORDER BY q.executed_at LIMIT ?)`, args...)
+	result.Rows += n
+
+	if err != nil {
+		return fmt.Errorf("failed to purge query rows: %w", err)
+	}
+
+	n, err = s.purgeBatches(ctx, batch, `DELETE FROM query_row_blobs WHERE query_id IN (
+		SELECT q.uid FROM queries q JOIN query_row_blobs b ON b.query_id = q.uid
+		WHERE q.executed_at < ? `+filter+` ORDER BY q.executed_at LIMIT ?)`, args...)
+	result.RowBlobs += n
+
+	if err != nil {
+		return fmt.Errorf("failed to purge row blobs: %w", err)
+	}
+
+	return nil
+}
+
+// rowStorageQuery sums the bytes held by result rows: the declared size of
+// uncompacted rows and the compressed size of blobs.
+const rowStorageQuery = `SELECT
+	(SELECT COALESCE(SUM(row_size_bytes), 0) FROM query_rows) +
+	(SELECT COALESCE(SUM(octet_length(data)), 0) FROM query_row_blobs)`
+
+// purgeRowsOverSize deletes the result rows of the oldest queries until the
+// result rows fit in maxBytes.
+func (s *Store) purgeRowsOverSize(ctx context.Context, batch int, maxBytes int64, result *PurgeResult) error {
+	var total int64
+	if err := s.db.NewRaw(rowStorageQuery).Scan(ctx, &total); err != nil {
+		return fmt.Errorf("failed to measure row storage: %w", err)
+	}
+
+	for total > maxBytes {
+		purged, err := s.purgeOldestRows(ctx, batch, nil)
+		if err != nil {
+			return fmt.Errorf("failed to purge oldest query rows: %w", err)
+		}
+
+		result.Rows += purged.rows
+		result.RowBlobs += purged.blobs
+
+		if purged.rows == 0 && purged.blobs == 0 {
+			return nil // nothing left to delete
+		}
+
+		total -= purged.bytes
+	}
+
+	return nil
+}
+
+// purgedRows is what deleting the result rows of a batch of queries freed.
+type purgedRows struct {
+	rows     int64 // Deleted query_rows
+	blobs    int64 // Deleted query_row_blobs
+	rowCount int64 // Deleted result rows, compacted ones included
+	bytes    int64 // Freed storage, counted as rowStorageQuery does
+}
+
+// purgeOldestRows deletes the result rows of one batch of the oldest queries
+// holding rows, whatever their form, of databaseID or of every database when
+// nil.
+func (s *Store) purgeOldestRows(ctx context.Context, batch int, databaseID *uuid.UUID) (purgedRows, error) {
+	filter := ""
+	args := []any{}
+
+	if databaseID != nil {
+		filter = "AND q.database_id = ?"
+		args = append(args, *databaseID)
+	}
+
+	args = append(args, batch)
+
+	var purged purgedRows
+
+	err := s.db.NewRaw(`WITH oldest AS (
+		SELECT q.uid FROM queries q
+		WHERE (EXISTS (SELECT 1 FROM query_rows qr WHERE qr.query_id = q.uid)
+		OR EXISTS (SELECT 1 FROM query_row_blobs b WHERE b.query_id = q.uid)) `+filter+`
+		ORDER BY q.executed_at LIMIT ?
+	), deleted_rows AS (
+		DELETE FROM query_rows WHERE query_id IN (SELECT uid FROM oldest) RETURNING row_size_bytes
+	), deleted_blobs AS (
+		DELETE FROM query_row_blobs WHERE query_id IN (SELECT uid FROM oldest) RETURNING row_count, octet_length(data) AS size
+	)
+	SELECT
+		(SELECT COUNT(*) FROM deleted_rows),
+		(SELECT COUNT(*) FROM deleted_blobs),
+		(SELECT COUNT(*) FROM deleted_rows) +
+		(SELECT COALESCE(SUM(row_count), 0) FROM deleted_blobs),
+		(SELECT COALESCE(SUM(row_size_bytes), 0) FROM deleted_rows) +
+		(SELECT COALESCE(SUM(size), 0) FROM deleted_blobs)`, args...).
+		Scan(ctx, &purged.rows, &purged.blobs, &purged.rowCount, &purged.bytes)
+
+	return purged, err
+}
+
+// Janitor applies a retention policy in the background, once at start and
+// then at every interval, until Shutdown.
+type Janitor struct {
+	store    *Store
+	policy   RetentionPolicy
+	interval time.Duration
+	logger   *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartJanitor starts purging what policy no longer retains every interval.
+func (s *Store) StartJanitor(policy RetentionPolicy, interval time.Duration, logger *slog.Logger) *Janitor {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := &Janitor{
+		store:    s,
+		policy:   policy,
+		interval: interval,
+		logger:   logger.With(slog.String("component", "retention")),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go j.run(ctx)
+
+	return j
+}
+
+// Shutdown stops the janitor, interrupting a purge in progress, and waits for
+// it to return.
+func (j *Janitor) Shutdown(ctx context.Context) error {
+	j.cancel()
+
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("retention janitor shutdown interrupted: %w", ctx.Err())
+	}
+}
+
+func (j *Janitor) run(ctx context.Context) {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge runs one pass, logging its outcome: a failed pass is retried at the
+// next interval.
+func (j *Janitor) purge(ctx context.Context) {
+	result, err := j.store.Purge(ctx, j.policy, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			j.logger.ErrorContext(ctx, "Retention purge failed", slog.Any("error", err))
+		}
+
+		return
+	}
+
+	if *result == (PurgeResult{}) {
+		return
+	}
+
+	j.logger.InfoContext(ctx, "Retention purge completed",
+		slog.Int64("queries", result.Queries),
+		slog.Int64("rows", result.Rows),
+		slog.Int64("row_blobs", result.RowBlobs),
+		slog.Int64("connections", result.Connections),
+		slog.Int64("audit_events", result.AuditEvents))
+}
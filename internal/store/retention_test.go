@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPurge(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	day := 24 * time.Hour
+
+	createQuery := func(conn *Connection, age time.Duration, rows int) *Query {
+		t.Helper()
+
+		query, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: "SELECT 1", ExecutedAt: now.Add(-age)})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+
+		if rows > 0 {
+			stored := make([]QueryRow, rows)
+			for i := range stored {
+				stored[i] = QueryRow{RowNumber: i, RowData: json.RawMessage(`{"n":1}`), RowSizeBytes: 100}
+			}
+
+			if err := store.StoreQueryRows(ctx, query.UID, stored); err != nil {
+				t.Fatalf("StoreQueryRows() error = %v", err)
+			}
+		}
+
+		return query
+	}
+
+	count := func(table string) int {
+		t.Helper()
+
+		var n int
+		if err := store.db.NewRaw("SELECT COUNT(*) FROM "+table).Scan(ctx, &n); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+
+		return n
+	}
+
+	ended := createTestConnection(t, ctx, store, "purge-ended")
+	if _, err := store.db.NewRaw("UPDATE connections SET disconnected_at = ? WHERE uid = ?", now.Add(-100*day), ended.UID).Exec(ctx); err != nil {
+		t.Fatalf("end connection: %v", err)
+	}
+
+	live := createTestConnection(t, ctx, store, "purge-live")
+
+	createQuery(ended, 100*day, 2)           // past queries_days
+	rowsOnly := createQuery(live, 40*day, 3) // past rows_days only
+	oldest := createQuery(live, 20*day, 4)   // over max_bytes
+	createQuery(live, time.Hour, 5)          // kept
+
+	if err := store.LogAuditEvent(ctx, &AuditEvent{EventType: "test.old"}); err != nil {
+		t.Fatalf("LogAuditEvent() error = %v", err)
+	}
+	if _, err := store.db.NewRaw("UPDATE audit_log SET created_at = ? WHERE event_type = 'test.old'", now.Add(-400*day)).Exec(ctx); err != nil {
+		t.Fatalf("age audit event: %v", err)
+	}
+	if err := store.LogAuditEvent(ctx, &AuditEvent{EventType: "test.new"}); err != nil {
+		t.Fatalf("LogAuditEvent() error = %v", err)
+	}
+
+	policy := RetentionPolicy{
+		QueriesAge:     90 * day,
+		RowsAge:        30 * day,
+		ConnectionsAge: 90 * day,
+		AuditAge:       365 * day,
+		MaxRowBytes:    500, // the recent query's 5 rows only
+		BatchSize:      1,   // exercise batching
+	}
+
+	result, err := store.Purge(ctx, policy, now)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	if result.Queries != 1 || result.Rows != 3+4 || result.RowBlobs != 0 || result.Connections != 1 {
+		t.Errorf("Purge() = %+v, want 1 query, 7 rows and 1 connection", *result)
+	}
+
+	if n := count("queries"); n != 3 {
+		t.Errorf("%d queries left, want 3", n)
+	}
+	if n := count("query_rows"); n != 5 {
+		t.Errorf("%d query rows left, want 5", n)
+	}
+	if n := count("connections"); n != 1 {
+		t.Errorf("%d connections left, want 1", n)
+	}
+	if n := count("audit_log WHERE event_type LIKE 'test.%'"); n != 1 {
+		t.Errorf("%d audit events left, want 1", n)
+	}
+
+	for _, query := range []*Query{rowsOnly, oldest} {
+		if _, err := store.GetQuery(ctx, query.UID); err != nil {
+			t.Errorf("query %s purged with its rows: %v", query.UID, err)
+		}
+	}
+
+	again, err := store.Purge(ctx, policy, now)
+	if err != nil {
+		t.Fatalf("Purge() second pass error = %v", err)
+	}
+
+	if again.Queries != 0 || again.Rows != 0 || again.Connections != 0 || again.AuditEvents != 0 {
+		t.Errorf("second pass = %+v, want nothing", *again)
+	}
+}
+
+func TestPurge_CompactedRowsOverSize(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	conn := createTestConnection(t, ctx, store, "purge-blobs")
+
+	query, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: "SELECT 1", ExecutedAt: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateQuery() error = %v", err)
+	}
+
+	if err := store.StoreQueryRows(ctx, query.UID, []QueryRow{{RowNumber: 0, RowData: json.RawMessage(`{"n":1}`), RowSizeBytes: 7}}); err != nil {
+		t.Fatalf("StoreQueryRows() error = %v", err)
+	}
+
+	if _, err := store.CompactQueryRows(ctx, now, 10); err != nil {
+		t.Fatalf("CompactQueryRows() error = %v", err)
+	}
+
+	result, err := store.Purge(ctx, RetentionPolicy{MaxRowBytes: 1}, now)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	if result.RowBlobs != 1 || result.Rows != 0 || result.Queries != 0 {
+		t.Errorf("Purge() = %+v, want one row blob", *result)
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The zstd codecs of the compressed result rows. EncodeAll and DecodeAll are
+// safe for concurrent use.
+var (
+	rowEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	rowDecoder, _ = zstd.NewReader(nil)
+)
+
+// newQueryRowModel returns the stored form of row: its JSON as is, or
+// zstd-compressed when compress is set.
+func newQueryRowModel(queryUID uuid.UUID, row QueryRow, compress bool) QueryRowModel {
+	m := QueryRowModel{
+		UID:          newUIDv7(),
+		QueryID:      queryUID,
+		RowNumber:    row.RowNumber,
+		RowSizeBytes: row.RowSizeBytes,
+	}
+
+	if compress {
+		m.RowDataZstd = rowEncoder.EncodeAll(row.RowData, nil)
+	} else {
+		m.RowData = row.RowData
+	}
+
+	return m
+}
+
+// toRow returns the row m stores, decompressing its data.
+func (m *QueryRowModel) toRow() (QueryRow, error) {
+	row := QueryRow{RowNumber: m.RowNumber, RowData: m.RowData, RowSizeBytes: m.RowSizeBytes}
+
+	if m.RowDataZstd != nil {
+		data, err := rowDecoder.DecodeAll(m.RowDataZstd, nil)
+		if err != nil {
+			return row, fmt.Errorf("failed to decompress row %d: %w", m.RowNumber, err)
+		}
+
+		row.RowData = json.RawMessage(data)
+	}
+
+	return row, nil
+}
+
+// toRows returns the rows models store.
+func toRows(models []QueryRowModel) ([]QueryRow, error) {
+	rows := make([]QueryRow, len(models))
+
+	for i := range models {
+		row, err := models[i].toRow()
+		if err != nil {
+			return nil, err
+		}
+
+		rows[i] = row
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestQueryRowModelCompression(t *testing.T) {
+	t.Parallel()
+
+	row := QueryRow{RowNumber: 3, RowData: json.RawMessage(`{"id":3,"body":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}`), RowSizeBytes: 41}
+
+	for _, compress := range []bool{false, true} {
+		m := newQueryRowModel(uuid.New(), row, compress)
+
+		if compress && (m.RowData != nil || len(m.RowDataZstd) == 0) {
+			t.Errorf("compressed model = %+v, want only RowDataZstd", m)
+		}
+
+		if !compress && (m.RowDataZstd != nil || m.RowData == nil) {
+			t.Errorf("uncompressed model = %+v, want only RowData", m)
+		}
+
+		got, err := m.toRow()
+		if err != nil {
+			t.Fatalf("toRow(compress=%v) error = %v", compress, err)
+		}
+
+		if got.RowNumber != row.RowNumber || string(got.RowData) != string(row.RowData) || got.RowSizeBytes != row.RowSizeBytes {
+			t.Errorf("toRow(compress=%v) = %+v, want %+v", compress, got, row)
+		}
+	}
+
+	corrupt := QueryRowModel{RowNumber: 1, RowDataZstd: []byte("not zstd")}
+	if _, err := corrupt.toRow(); err == nil {
+		t.Error("toRow() should fail on garbage")
+	}
+}
+
+func TestStoreQueryRows_Compressed(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "zstd")
+
+	storeRows := func(compress bool, sql string) *Query {
+		t.Helper()
+
+		query, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: sql, ExecutedAt: time.Now()})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+
+		store.compressRows = compress
+
+		if err := store.StoreQueryRows(ctx, query.UID, []QueryRow{
+			{RowNumber: 1, RowData: json.RawMessage(`{"id": 1, "name": "item1"}`), RowSizeBytes: 25},
+			{RowNumber: 2, RowData: json.RawMessage(`{"id": 2, "name": "item2"}`), RowSizeBytes: 25},
+		}); err != nil {
+			t.Fatalf("StoreQueryRows() error = %v", err)
+		}
+
+		return query
+	}
+
+	compressed := storeRows(true, "SELECT * FROM compressed")
+	plain := storeRows(false, "SELECT * FROM plain")
+
+	stored, err := store.db.NewSelect().
+		Model((*QueryRowModel)(nil)).
+		Where("query_id = ? AND row_data IS NULL AND row_data_zstd IS NOT NULL", compressed.UID).
+		Count(ctx)
+	if err != nil {
+		t.Fatalf("count compressed rows: %v", err)
+	}
+
+	if stored != 2 {
+		t.Errorf("%d rows stored compressed, want 2", stored)
+	}
+
+	// Rows read back alike, however they were stored.
+	for _, query := range []*Query{compressed, plain} {
+		page, err := store.GetQueryRows(ctx, query.UID, "", 10)
+		if err != nil {
+			t.Fatalf("GetQueryRows() error = %v", err)
+		}
+
+		if page.TotalRows != 2 || len(page.Rows) != 2 {
+			t.Fatalf("GetQueryRows(%s) = %d rows of %d, want 2", query.SQLText, len(page.Rows), page.TotalRows)
+		}
+
+		var got map[string]any
+		if err := json.Unmarshal(page.Rows[1].RowData, &got); err != nil || got["name"] != "item2" {
+			t.Errorf("GetQueryRows(%s) row 2 = %s", query.SQLText, page.Rows[1].RowData)
+		}
+
+		withRows, err := store.GetQueryWithRows(ctx, query.UID)
+		if err != nil {
+			t.Fatalf("GetQueryWithRows() error = %v", err)
+		}
+
+		if len(withRows.Rows) != 2 {
+			t.Errorf("GetQueryWithRows(%s) returned %d rows, want 2", query.SQLText, len(withRows.Rows))
+		}
+	}
+
+	// Compaction decompresses them into its blob.
+	result, err := store.CompactQueryRows(ctx, time.Now().Add(time.Minute), 10)
+	if err != nil {
+		t.Fatalf("CompactQueryRows() error = %v", err)
+	}
+
+	if result.Queries != 2 || result.Rows != 4 {
+		t.Errorf("CompactQueryRows() = %+v, want 2 queries and 4 rows", result)
+	}
+
+	page, err := store.GetQueryRows(ctx, compressed.UID, "", 10)
+	if err != nil {
+		t.Fatalf("GetQueryRows() after compaction error = %v", err)
+	}
+
+	var first map[string]any
+	if len(page.Rows) != 2 || json.Unmarshal(page.Rows[0].RowData, &first) != nil || first["name"] != "item1" {
+		t.Errorf("GetQueryRows() after compaction = %+v", page.Rows)
+	}
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidRowQuota is returned when a database's row quota is malformed.
+var ErrInvalidRowQuota = errors.New("invalid row quota")
+
+// RowQuota caps the result rows retained for one database, compacted ones
+// included. It is a soft quota: proxies keep capturing past it, and the
+// retention janitor deletes the result rows of the database's oldest queries
+// until it fits again, so one chatty target cannot fill the storage database.
+type RowQuota struct {
+	MaxRows  *int64 `json:"max_rows,omitempty"`
+	MaxBytes *int64 `json:"max_bytes,omitempty"`
+}
+
+// IsZero reports whether the quota caps nothing.
+func (q *RowQuota) IsZero() bool {
+	return q == nil || (q.MaxRows == nil && q.MaxBytes == nil)
+}
+
+// Validate checks the caps are positive.
+func (q *RowQuota) Validate() error {
+	switch {
+	case q.MaxRows != nil && *q.MaxRows <= 0:
+		return fmt.Errorf("%w: max_rows must be positive", ErrInvalidRowQuota)
+	case q.MaxBytes != nil && *q.MaxBytes <= 0:
+		return fmt.Errorf("%w: max_bytes must be positive", ErrInvalidRowQuota)
+	}
+
+	return nil
+}
+
+// exceeded reports whether rows result rows taking bytes are over the quota.
+func (q *RowQuota) exceeded(rows, bytes int64) bool {
+	return (q.MaxRows != nil && rows > *q.MaxRows) || (q.MaxBytes != nil && bytes > *q.MaxBytes)
+}
+
+// rowQuotaUsage is the result row storage of a database with a row quota.
+type rowQuotaUsage struct {
+	UID   uuid.UUID `bun:"uid"`
+	Quota RowQuota  `bun:"row_quota,type:jsonb"`
+	Rows  int64     `bun:"rows"`
+	Bytes int64     `bun:"bytes"`
+}
+
+// purgeRowQuotas deletes the result rows of the oldest queries of every
+// database over its row quota until it fits. Bytes are counted like
+// MaxRowBytes; rows count the compacted ones.
+func (s *Store) purgeRowQuotas(ctx context.Context, batch int, result *PurgeResult) error {
+	var usages []rowQuotaUsage
+	if err := s.db.NewRaw(`SELECT s.uid, s.row_quota,
+		(SELECT COUNT(*) FROM query_rows qr JOIN queries q ON q.uid = qr.query_id WHERE q.database_id = s.uid) +
+		(SELECT COALESCE(SUM(b.row_count), 0) FROM query_row_blobs b JOIN queries q ON q.uid = b.query_id WHERE q.database_id = s.uid) AS rows,
+		(SELECT COALESCE(SUM(qr.row_size_bytes), 0) FROM query_rows qr JOIN queries q ON q.uid = qr.query_id WHERE q.database_id = s.uid) +
+		(SELECT COALESCE(SUM(octet_length(b.data)), 0) FROM query_row_blobs b JOIN queries q ON q.uid = b.query_id WHERE q.database_id = s.uid) AS bytes
+		FROM servers s WHERE s.row_quota IS NOT NULL`).
+		Scan(ctx, &usages); err != nil {
+		return fmt.Errorf("failed to measure database row storage: %w", err)
+	}
+
+	for _, usage := range usages {
+		rows, bytes := usage.Rows, usage.Bytes
+
+		for usage.Quota.exceeded(rows, bytes) {
+			purged, err := s.purgeOldestRows(ctx, batch, &usage.UID)
+			if err != nil {
+				return fmt.Errorf("failed to purge query rows of database %s: %w", usage.UID, err)
+			}
+
+			result.Rows += purged.rows
+			result.RowBlobs += purged.blobs
+
+			if purged.rows == 0 && purged.blobs == 0 {
+				break // nothing left to delete
+			}
+
+			rows -= purged.rowCount
+			bytes -= purged.bytes
+		}
+	}
+
+	return nil
+}
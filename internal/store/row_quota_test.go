@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRowQuotaValidate(t *testing.T) {
+	t.Parallel()
+
+	zero, positive := int64(0), int64(10)
+
+	for _, tc := range []struct {
+		name  string
+		quota RowQuota
+		valid bool
+	}{
+		{"empty", RowQuota{}, true},
+		{"rows and bytes", RowQuota{MaxRows: &positive, MaxBytes: &positive}, true},
+		{"zero rows", RowQuota{MaxRows: &zero}, false},
+		{"zero bytes", RowQuota{MaxBytes: &zero}, false},
+	} {
+		err := tc.quota.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%s: Validate() = %v, want nil", tc.name, err)
+		}
+
+		if !tc.valid && !errors.Is(err, ErrInvalidRowQuota) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidRowQuota", tc.name, err)
+		}
+	}
+}
+
+func TestPurge_RowQuota(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	chatty := createTestConnection(t, ctx, store, "quota-chatty")
+	quiet := createTestConnection(t, ctx, store, "quota-quiet")
+
+	createQuery := func(conn *Connection, age time.Duration, rows int) *Query {
+		t.Helper()
+
+		query, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: "SELECT 1", ExecutedAt: now.Add(-age)})
+		if err != nil {
+			t.Fatalf("CreateQuery() error = %v", err)
+		}
+
+		stored := make([]QueryRow, rows)
+		for i := range stored {
+			stored[i] = QueryRow{RowNumber: i, RowData: json.RawMessage(`{"n":1}`), RowSizeBytes: 100}
+		}
+
+		if err := store.StoreQueryRows(ctx, query.UID, stored); err != nil {
+			t.Fatalf("StoreQueryRows() error = %v", err)
+		}
+
+		return query
+	}
+
+	oldest := createQuery(chatty, 3*time.Hour, 4)
+	older := createQuery(chatty, 2*time.Hour, 3)
+	recent := createQuery(chatty, time.Hour, 2)
+	quietQuery := createQuery(quiet, 4*time.Hour, 5)
+
+	maxRows := int64(5)
+	if err := store.UpdateServer(ctx, chatty.DatabaseID, ServerUpdate{RowQuota: &RowQuota{MaxRows: &maxRows}}, nil); err != nil {
+		t.Fatalf("UpdateServer() error = %v", err)
+	}
+
+	result, err := store.Purge(ctx, RetentionPolicy{BatchSize: 1}, now)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	// 9 rows over a cap of 5: the oldest query's 4 rows go, the next ones fit.
+	if result.Rows != 4 || result.Queries != 0 {
+		t.Errorf("Purge() = %+v, want 4 rows", *result)
+	}
+
+	for _, tc := range []struct {
+		query *Query
+		rows  int
+	}{
+		{oldest, 0},
+		{older, 3},
+		{recent, 2},
+		{quietQuery, 5}, // another database, older but without a quota
+	} {
+		var n int
+		if err := store.db.NewRaw("SELECT COUNT(*) FROM query_rows WHERE query_id = ?", tc.query.UID).Scan(ctx, &n); err != nil {
+			t.Fatalf("count rows: %v", err)
+		}
+
+		if n != tc.rows {
+			t.Errorf("query %s has %d rows, want %d", tc.query.UID, n, tc.rows)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// SearchFilter scopes a per-entity search. Term is matched case-insensitively
+// as a substring of each entity's human-readable fields; a term that parses
+// as a UUID also matches the entity's UID exactly.
+type SearchFilter struct {
+	Term string
+	// UserID restricts grants and connections to those of one user
+	// (connectors only ever see their own).
+	UserID *uuid.UUID
+	// ListableOnly restricts servers to those marked listable.
+	ListableOnly bool
+	Limit        int
+}
+
+// likeEscaper escapes LIKE metacharacters so the search term is matched
+// literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// pattern returns the ILIKE pattern for the term.
+func (f SearchFilter) pattern() string {
+	return "%" + likeEscaper.Replace(f.Term) + "%"
+}
+
+// matchUID adds an "OR <column> = uid" clause when the term is a UUID.
+func (f SearchFilter) matchUID(q *bun.SelectQuery, column string) *bun.SelectQuery {
+	if uid, err := uuid.Parse(f.Term); err == nil {
+		return q.WhereOr("? = ?", bun.Ident(column), uid)
+	}
+
+	return q
+}
+
+// limit applies the filter's result cap.
+func (f SearchFilter) limit(q *bun.SelectQuery) *bun.SelectQuery {
+	if f.Limit > 0 {
+		return q.Limit(f.Limit)
+	}
+
+	return q
+}
+
+// SearchUsers returns users whose username matches.
+func (s *Store) SearchUsers(ctx context.Context, filter SearchFilter) ([]User, error) {
+	var users []User
+
+	q := s.db.NewSelect().
+		Model(&users).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			q = q.Where("u.username ILIKE ?", filter.pattern())
+			return filter.matchUID(q, "u.uid")
+		})
+
+	if filter.UserID != nil {
+		q = q.Where("u.uid = ?", *filter.UserID)
+	}
+
+	if err := filter.limit(q.Order("u.username ASC")).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	if users == nil {
+		users = []User{}
+	}
+
+	return users, nil
+}
+
+// SearchServers returns database targets whose name, description or database
+// name matches. SSH bastions are never returned.
+func (s *Store) SearchServers(ctx context.Context, filter SearchFilter) ([]Server, error) {
+	var servers []Server
+
+	q := s.db.NewSelect().
+		Model(&servers).
+		Where("d.protocol <> ?", ProtocolSSH).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			q = q.Where("d.name ILIKE ?", filter.pattern()).
+				WhereOr("d.description ILIKE ?", filter.pattern()).
+				WhereOr("d.database_name ILIKE ?", filter.pattern())
+			return filter.matchUID(q, "d.uid")
+		})
+
+	if filter.ListableOnly {
+		q = q.Where("d.listable = ?", true)
+	}
+
+	if err := filter.limit(q.Order("d.name ASC")).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to search databases: %w", err)
+	}
+
+	if servers == nil {
+		servers = []Server{}
+	}
+
+	return servers, nil
+}
+
+// SearchGrants returns grants whose grantee username or database name
+// matches, most recent first.
+func (s *Store) SearchGrants(ctx context.Context, filter SearchFilter) ([]Grant, error) {
+	var grants []Grant
+
+	q := s.db.NewSelect().
+		Model(&grants).
+		Join("JOIN users AS u ON u.uid = ag.user_id").
+		Join("JOIN servers AS d ON d.uid = ag.database_id").
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			q = q.Where("u.username ILIKE ?", filter.pattern()).
+				WhereOr("d.name ILIKE ?", filter.pattern())
+			return filter.matchUID(q, "ag.uid")
+		})
+
+	if filter.UserID != nil {
+		q = q.Where("ag.user_id = ?", *filter.UserID)
+	}
+
+	if err := filter.limit(q.Order("ag.created_at DESC")).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to search grants: %w", err)
+	}
+
+	if grants == nil {
+		grants = []Grant{}
+	}
+
+	return grants, nil
+}
+
+// SearchConnections returns connections whose user, database name or source
+// IP matches, most recent first.
+func (s *Store) SearchConnections(ctx context.Context, filter SearchFilter) ([]Connection, error) {
+	var connections []Connection
+
+	q := s.db.NewSelect().
+		Model(&connections).
+		Join("JOIN users AS u ON u.uid = c.user_id").
+		Join("JOIN servers AS d ON d.uid = c.database_id").
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			q = q.Where("u.username ILIKE ?", filter.pattern()).
+				WhereOr("d.name ILIKE ?", filter.pattern()).
+				WhereOr("host(c.source_ip) ILIKE ?", filter.pattern())
+			return filter.matchUID(q, "c.uid")
+		})
+
+	if filter.UserID != nil {
+		q = q.Where("c.user_id = ?", *filter.UserID)
+	}
+
+	if err := s.scanReadOnly(ctx, filter.limit(q.Order("c.uid DESC"))); err != nil {
+		return nil, fmt.Errorf("failed to search connections: %w", err)
+	}
+
+	if connections == nil {
+		connections = []Connection{}
+	}
+
+	return connections, nil
+}
+
+// SearchQueries returns queries whose SQL text matches, most recent first.
+func (s *Store) SearchQueries(ctx context.Context, filter SearchFilter) ([]Query, error) {
+	var queries []Query
+
+	q := s.db.NewSelect().
+		Model(&queries).
+		ColumnExpr("q.uid, q.connection_id, q.sql_text, q.parameters, q.executed_at, q.duration_ms, q.rows_affected, q.error, c.user_id, c.database_id").
+		Join("JOIN connections c ON q.connection_id = c.uid").
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			q = q.Where("q.sql_text ILIKE ?", filter.pattern())
+			return filter.matchUID(q, "q.uid")
+		})
+
+	if filter.UserID != nil {
+		q = q.Where("c.user_id = ?", *filter.UserID)
+	}
+
+	if err := s.scanReadOnly(ctx, filter.limit(q.Order("q.uid DESC"))); err != nil {
+		return nil, fmt.Errorf("failed to search queries: %w", err)
+	}
+
+	if queries == nil {
+		queries = []Query{}
+	}
+
+	return queries, nil
+}
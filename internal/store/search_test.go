@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	store := setupTestStoreNoCleanup(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "search_1")
+	other := createTestConnection(t, ctx, store, "searchx1")
+
+	if _, err := store.CreateQuery(ctx, &Query{ConnectionID: conn.UID, SQLText: "SELECT * FROM invoices_2024"}); err != nil {
+		t.Fatalf("CreateQuery() error = %v", err)
+	}
+
+	t.Run("LIKE metacharacters match literally", func(t *testing.T) {
+		users, err := store.SearchUsers(ctx, SearchFilter{Term: "search_1"})
+		if err != nil {
+			t.Fatalf("SearchUsers() error = %v", err)
+		}
+
+		// "_" must not act as a wildcard and match "searchx1".
+		if len(users) != 1 || users[0].UID != conn.UserID {
+			t.Errorf("SearchUsers() = %+v, want only the search_1 user", users)
+		}
+	})
+
+	t.Run("UUID term matches UID", func(t *testing.T) {
+		connections, err := store.SearchConnections(ctx, SearchFilter{Term: other.UID.String()})
+		if err != nil {
+			t.Fatalf("SearchConnections() error = %v", err)
+		}
+
+		if len(connections) != 1 || connections[0].UID != other.UID {
+			t.Errorf("SearchConnections() = %+v, want the searched connection", connections)
+		}
+	})
+
+	t.Run("user scope", func(t *testing.T) {
+		connections, err := store.SearchConnections(ctx, SearchFilter{Term: "grantdb_search", UserID: &other.UserID})
+		if err != nil {
+			t.Fatalf("SearchConnections() error = %v", err)
+		}
+
+		for _, c := range connections {
+			if c.UserID != other.UserID {
+				t.Errorf("SearchConnections() returned another user's connection %s", c.UID)
+			}
+		}
+	})
+
+	t.Run("queries by SQL text", func(t *testing.T) {
+		queries, err := store.SearchQueries(ctx, SearchFilter{Term: "INVOICES_2024", Limit: 5})
+		if err != nil {
+			t.Fatalf("SearchQueries() error = %v", err)
+		}
+
+		if len(queries) != 1 || queries[0].UserID == nil || *queries[0].UserID != conn.UserID {
+			t.Errorf("SearchQueries() = %+v, want the invoices query", queries)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ErrServerHealthNotFound is returned when a server has not been checked yet.
+var ErrServerHealthNotFound = errors.New("server health not found")
+
+// RecordServerHealth stores the outcome of a check, replacing the previous
+// one. LastOKAt is set from CheckedAt when the check passed, and kept from
+// the previous check otherwise.
+func (s *Store) RecordServerHealth(ctx context.Context, health *ServerHealth) error {
+	if health.OK {
+		health.LastOKAt = &health.CheckedAt
+	}
+
+	if _, err := s.db.NewInsert().
+		Model(health).
+		On("CONFLICT (server_id) DO UPDATE").
+		Set("checked_at = EXCLUDED.checked_at").
+		Set("ok = EXCLUDED.ok").
+		Set("stage = EXCLUDED.stage").
+		Set("code = EXCLUDED.code").
+		Set("message = EXCLUDED.message").
+		Set("duration_ms = EXCLUDED.duration_ms").
+		Set("last_ok_at = COALESCE(EXCLUDED.last_ok_at, sh.last_ok_at)").
+		Returning("last_ok_at").
+		Exec(ctx); err != nil {
+		return fmt.Errorf("record server health: %w", err)
+	}
+
+	return nil
+}
+
+// GetServerHealth returns the latest check of a server.
+func (s *Store) GetServerHealth(ctx context.Context, serverID uuid.UUID) (*ServerHealth, error) {
+	health := new(ServerHealth)
+
+	if err := s.db.NewSelect().Model(health).Where("server_id = ?", serverID).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrServerHealthNotFound
+		}
+
+		return nil, fmt.Errorf("get server health: %w", err)
+	}
+
+	return health, nil
+}
+
+// ListServerHealth returns the latest check of every checked server, by
+// server UID.
+func (s *Store) ListServerHealth(ctx context.Context) (map[uuid.UUID]*ServerHealth, error) {
+	var checks []ServerHealth
+
+	if err := s.db.NewSelect().Model(&checks).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("list server health: %w", err)
+	}
+
+	byServer := make(map[uuid.UUID]*ServerHealth, len(checks))
+	for i := range checks {
+		byServer[checks[i].ServerID] = &checks[i]
+	}
+
+	return byServer, nil
+}
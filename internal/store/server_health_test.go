@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestServerHealth(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	_, database := createTestUserAndDatabase(t, ctx, store, "health")
+
+	if _, err := store.GetServerHealth(ctx, database.UID); !errors.Is(err, ErrServerHealthNotFound) {
+		t.Fatalf("GetServerHealth() before any check error = %v, want %v", err, ErrServerHealthNotFound)
+	}
+
+	passedAt := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+	if err := store.RecordServerHealth(ctx, &ServerHealth{
+		ServerID: database.UID, CheckedAt: passedAt, OK: true, Stage: "target_auth", Code: "ok", DurationMs: 12,
+	}); err != nil {
+		t.Fatalf("RecordServerHealth() error = %v", err)
+	}
+
+	if err := store.RecordServerHealth(ctx, &ServerHealth{
+		ServerID: database.UID, CheckedAt: time.Now(), Stage: "target_dial", Code: "unreachable",
+		Message: "the connection was refused: check the host and port", DurationMs: 3,
+	}); err != nil {
+		t.Fatalf("RecordServerHealth() error = %v", err)
+	}
+
+	health, err := store.GetServerHealth(ctx, database.UID)
+	if err != nil {
+		t.Fatalf("GetServerHealth() error = %v", err)
+	}
+
+	if health.OK || health.Code != "unreachable" {
+		t.Errorf("GetServerHealth() = %+v, want the failed check", health)
+	}
+
+	// The failure keeps the time of the last passed check.
+	if health.LastOKAt == nil || !health.LastOKAt.Equal(passedAt) {
+		t.Errorf("LastOKAt = %v, want %v", health.LastOKAt, passedAt)
+	}
+
+	all, err := store.ListServerHealth(ctx)
+	if err != nil || all[database.UID] == nil {
+		t.Errorf("ListServerHealth() = %v, %v, want the server's health", all, err)
+	}
+}
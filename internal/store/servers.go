@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
 
 	"github.com/fclairamb/dbbat/internal/crypto"
 )
@@ -30,6 +31,7 @@ func (s *Store) CreateServer(ctx context.Context, db *Server, encryptionKey []by
 	}
 
 	plainPassword := db.Password
+	plainReadOnlyPassword := db.ReadOnlyPassword
 
 	// Capture plaintext SSH secrets (if any) before they are cleared; they are
 	// encrypted after the UID is known, exactly like the password.
@@ -39,21 +41,36 @@ func (s *Store) CreateServer(ctx context.Context, db *Server, encryptionKey []by
 	}
 
 	result := &Server{
-		Name:              db.Name,
-		Description:       db.Description,
-		Host:              db.Host,
-		Port:              db.Port,
-		DatabaseName:      db.DatabaseName,
-		Username:          db.Username,
-		SSLMode:           db.SSLMode,
-		Protocol:          db.Protocol,
-		OracleServiceName: db.OracleServiceName,
-		ViaUID:            db.ViaUID,
-		ProtocolData:      db.ProtocolData,
-		Listable:          db.Listable,
-		CreatedBy:         db.CreatedBy,
-		CreatedAt:         time.Now(),
-		UpdatedAt:         time.Now(),
+		Name:                 db.Name,
+		Description:          db.Description,
+		Host:                 db.Host,
+		Port:                 db.Port,
+		DatabaseName:         db.DatabaseName,
+		Username:             db.Username,
+		ReadOnlyUsername:     db.ReadOnlyUsername,
+		SecretRef:            db.SecretRef,
+		ReadReplicas:         db.ReadReplicas,
+		SSLMode:              db.SSLMode,
+		SSLRootCert:          db.SSLRootCert,
+		GrantDefaults:        db.GrantDefaults,
+		RowQuota:             db.RowQuota,
+		ResultMasking:        db.ResultMasking,
+		QueryStorage:         db.QueryStorage,
+		Protocol:             db.Protocol,
+		OracleServiceName:    db.OracleServiceName,
+		ViaUID:               db.ViaUID,
+		ProtocolData:         db.ProtocolData,
+		Listable:             db.Listable,
+		BlockedMessage:       db.BlockedMessage,
+		SlowQueryThresholdMs: db.SlowQueryThresholdMs,
+		Labels:               db.Labels,
+		CreatedBy:            db.CreatedBy,
+		CreatedAt:            time.Now(),
+		UpdatedAt:            time.Now(),
+	}
+
+	if result.ReadReplicas == nil {
+		result.ReadReplicas = []string{}
 	}
 
 	// Use a transaction to insert with a placeholder, get UID, then update with real encrypted password
@@ -86,9 +103,21 @@ func (s *Store) CreateServer(ctx context.Context, db *Server, encryptionKey []by
 
 	// Update with the real encrypted password
 	result.PasswordEncrypted = passwordEncrypted
+	columns := []string{"password_encrypted"}
+
+	if result.ReadOnlyUsername != "" {
+		readOnlyEncrypted, err := crypto.Encrypt([]byte(plainReadOnlyPassword), encryptionKey, aad)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt read-only password: %w", err)
+		}
+
+		result.ReadOnlyPasswordEncrypted = readOnlyEncrypted
+		columns = append(columns, "readonly_password_encrypted")
+	}
+
 	_, err = tx.NewUpdate().
 		Model(result).
-		Column("password_encrypted").
+		Column(columns...).
 		WherePK().
 		Exec(ctx)
 	if err != nil {
@@ -116,6 +145,72 @@ func (s *Store) CreateServer(ctx context.Context, db *Server, encryptionKey []by
 	return result, nil
 }
 
+// CloneServer creates a copy of an existing server under a new name, applying
+// the overrides in clone. Stored secrets are decrypted and re-encrypted for the
+// clone, since their AAD is bound to the server UID. A learned SSH host key is
+// not copied: the clone pins its own on first connect.
+func (s *Store) CloneServer(
+	ctx context.Context, uid uuid.UUID, clone ServerClone, encryptionKey []byte,
+) (*Server, error) {
+	src, err := s.GetServerByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := src.DecryptPassword(encryptionKey); err != nil {
+		return nil, err
+	}
+
+	if err := src.decryptReadOnlyPassword(encryptionKey); err != nil {
+		return nil, err
+	}
+
+	if err := src.DecryptSSHSecrets(encryptionKey); err != nil {
+		return nil, err
+	}
+
+	var protocolData *ServerProtocolData
+	if src.ProtocolData != nil {
+		protocolData = &ServerProtocolData{}
+		if mongo := src.MongoData(); mongo != nil {
+			copied := *mongo
+			protocolData.MongoDB = &copied
+		}
+		if sd := src.SSHData(); sd != nil {
+			protocolData.SSH = &SSHServerData{PrivateKey: sd.PrivateKey, Passphrase: sd.Passphrase}
+		}
+	}
+
+	return s.CreateServer(ctx, &Server{
+		Name:                 clone.Name,
+		Description:          valueOrDefault(clone.Description, src.Description),
+		Host:                 valueOrDefault(clone.Host, src.Host),
+		Port:                 valueOrDefaultInt(clone.Port, src.Port),
+		DatabaseName:         valueOrDefault(clone.DatabaseName, src.DatabaseName),
+		Username:             src.Username,
+		Password:             src.Password,
+		ReadOnlyUsername:     src.ReadOnlyUsername,
+		ReadOnlyPassword:     src.ReadOnlyPassword,
+		SecretRef:            src.SecretRef,
+		ReadReplicas:         src.ReadReplicas,
+		SSLMode:              src.SSLMode,
+		SSLRootCert:          src.SSLRootCert,
+		GrantDefaults:        src.GrantDefaults,
+		RowQuota:             src.RowQuota,
+		ResultMasking:        src.ResultMasking,
+		QueryStorage:         src.QueryStorage,
+		Protocol:             src.Protocol,
+		OracleServiceName:    src.OracleServiceName,
+		ViaUID:               src.ViaUID,
+		ProtocolData:         protocolData,
+		Listable:             src.Listable,
+		BlockedMessage:       src.BlockedMessage,
+		SlowQueryThresholdMs: src.SlowQueryThresholdMs,
+		Labels:               src.Labels,
+		CreatedBy:            clone.CreatedBy,
+	}, encryptionKey)
+}
+
 // encryptSSHSecrets encrypts the plaintext PrivateKey/Passphrase on sd in place
 // (AAD-bound to the server UID) and clears the plaintext fields, mirroring the
 // password-encryption pattern.
@@ -214,13 +309,10 @@ func (s *Store) validateViaUID(ctx context.Context, selfUID, viaUID uuid.UUID) e
 // ListSSHServers returns every SSH bastion row (protocol = 'ssh'), for the
 // admin SSH-server management view and the "via SSH server" selector. These
 // rows are excluded from every grantable/connectable target listing.
-func (s *Store) ListSSHServers(ctx context.Context) ([]Server, error) {
+func (s *Store) ListSSHServers(ctx context.Context, filter ServerFilter) ([]Server, error) {
 	var servers []Server
-	err := s.db.NewSelect().
-		Model(&servers).
-		Where("protocol = ?", ProtocolSSH).
-		Order("name ASC").
-		Scan(ctx)
+
+	err := s.selectServers(&servers, filter, sshServers).Scan(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list ssh servers: %w", err)
 	}
@@ -230,6 +322,52 @@ func (s *Store) ListSSHServers(ctx context.Context) ([]Server, error) {
 	return servers, nil
 }
 
+// CountSSHServers returns the number of SSH bastion rows matching the filter,
+// regardless of its Limit and Offset.
+func (s *Store) CountSSHServers(ctx context.Context, filter ServerFilter) (int, error) {
+	return s.countServers(ctx, filter, sshServers)
+}
+
+// sshServers keeps the SSH bastion rows.
+func sshServers(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.Where("protocol = ?", ProtocolSSH)
+}
+
+// targetServers keeps the database targets: an SSH bastion is a dial path,
+// never a grantable/connectable target.
+func targetServers(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.Where("protocol <> ?", ProtocolSSH)
+}
+
+// listableServers keeps the targets marked as listable.
+func listableServers(q *bun.SelectQuery) *bun.SelectQuery {
+	return targetServers(q).Where("listable = ?", true)
+}
+
+// selectServers builds the select of the servers of scope matching filter
+// into dest, ordered by name.
+func (s *Store) selectServers(
+	dest *[]Server, filter ServerFilter, scope func(*bun.SelectQuery) *bun.SelectQuery,
+) *bun.SelectQuery {
+	q := s.db.NewSelect().Model(dest).Apply(scope)
+	q = whereLabels(q, "labels", filter.Labels)
+
+	return paginate(q.Order("name ASC"), filter.Limit, filter.Offset)
+}
+
+func (s *Store) countServers(
+	ctx context.Context, filter ServerFilter, scope func(*bun.SelectQuery) *bun.SelectQuery,
+) (int, error) {
+	var servers []Server
+
+	n, err := s.selectServers(&servers, filter, scope).Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count databases: %w", err)
+	}
+
+	return n, nil
+}
+
 // GetServerByName retrieves a database by name
 func (s *Store) GetServerByName(ctx context.Context, name string) (*Server, error) {
 	db := new(Server)
@@ -296,15 +434,10 @@ func (s *Store) ListServersByOracleServiceName(ctx context.Context, serviceName
 // ListListableServers retrieves databases that are marked as listable.
 // Used by the non-admin listing path so any authenticated user can discover
 // databases available to request access to.
-func (s *Store) ListListableServers(ctx context.Context) ([]Server, error) {
+func (s *Store) ListListableServers(ctx context.Context, filter ServerFilter) ([]Server, error) {
 	var databases []Server
-	err := s.db.NewSelect().
-		Model(&databases).
-		Where("listable = ?", true).
-		// Targets only: SSH bastions are never grantable/listable targets.
-		Where("protocol <> ?", ProtocolSSH).
-		Order("name ASC").
-		Scan(ctx)
+
+	err := s.selectServers(&databases, filter, listableServers).Scan(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list listable databases: %w", err)
 	}
@@ -314,6 +447,12 @@ func (s *Store) ListListableServers(ctx context.Context) ([]Server, error) {
 	return databases, nil
 }
 
+// CountListableServers returns the number of listable databases matching the
+// filter, regardless of its Limit and Offset.
+func (s *Store) CountListableServers(ctx context.Context, filter ServerFilter) (int, error) {
+	return s.countServers(ctx, filter, listableServers)
+}
+
 // GetServerByUID retrieves a database by UID
 func (s *Store) GetServerByUID(ctx context.Context, uid uuid.UUID) (*Server, error) {
 	db := new(Server)
@@ -333,13 +472,10 @@ func (s *Store) GetServerByUID(ctx context.Context, uid uuid.UUID) (*Server, err
 // ListServers retrieves all database *targets* (every protocol except 'ssh').
 // SSH bastions are managed separately via ListSSHServers so they never leak
 // into grantable/connectable target contexts (dropdowns, admin database list).
-func (s *Store) ListServers(ctx context.Context) ([]Server, error) {
+func (s *Store) ListServers(ctx context.Context, filter ServerFilter) ([]Server, error) {
 	var databases []Server
-	err := s.db.NewSelect().
-		Model(&databases).
-		Where("protocol <> ?", ProtocolSSH).
-		Order("name ASC").
-		Scan(ctx)
+
+	err := s.selectServers(&databases, filter, targetServers).Scan(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list databases: %w", err)
 	}
@@ -349,6 +485,12 @@ func (s *Store) ListServers(ctx context.Context) ([]Server, error) {
 	return databases, nil
 }
 
+// CountServers returns the number of database targets matching the filter,
+// regardless of its Limit and Offset.
+func (s *Store) CountServers(ctx context.Context, filter ServerFilter) (int, error) {
+	return s.countServers(ctx, filter, targetServers)
+}
+
 // checkStorageDSNConflict verifies that a database update won't result in matching the storage DSN.
 func (s *Store) checkStorageDSNConflict(ctx context.Context, uid uuid.UUID, updates ServerUpdate) error {
 	if updates.Host == nil && updates.Port == nil && updates.DatabaseName == nil {
@@ -416,6 +558,16 @@ func (s *Store) UpdateServer(ctx context.Context, uid uuid.UUID, updates ServerU
 		q = q.Set("password_encrypted = ?", passwordEncrypted)
 	}
 
+	// Clearing the read-only username removes its password too.
+	if updates.ReadOnlyPassword != nil && (updates.ReadOnlyUsername == nil || *updates.ReadOnlyUsername != "") {
+		aad := crypto.ServerAAD(uid.String())
+		readOnlyEncrypted, err := crypto.Encrypt([]byte(*updates.ReadOnlyPassword), encryptionKey, aad)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt read-only password: %w", err)
+		}
+		q = q.Set("readonly_password_encrypted = ?", readOnlyEncrypted)
+	}
+
 	// SSH secrets: encrypt (AAD-bound to the UID) and write the merged
 	// protocol_data, preserving any other protocol_data keys.
 	if updates.SSHPrivateKey != nil || updates.SSHPassphrase != nil {
@@ -462,9 +614,25 @@ func applyServerColumnUpdates(q *bun.UpdateQuery, updates ServerUpdate) *bun.Upd
 	if updates.Username != nil {
 		q = q.Set("username = ?", *updates.Username)
 	}
+	if updates.ReadOnlyUsername != nil {
+		if *updates.ReadOnlyUsername == "" {
+			q = q.Set("readonly_username = NULL").Set("readonly_password_encrypted = NULL")
+		} else {
+			q = q.Set("readonly_username = ?", *updates.ReadOnlyUsername)
+		}
+	}
+	if updates.SecretRef != nil {
+		q = q.Set("secret_ref = NULLIF(?, '')", *updates.SecretRef)
+	}
+	if updates.ReadReplicas != nil {
+		q = q.Set("read_replicas = ?", pgdialect.Array(updates.ReadReplicas))
+	}
 	if updates.SSLMode != nil {
 		q = q.Set("ssl_mode = ?", *updates.SSLMode)
 	}
+	if updates.SSLRootCert != nil {
+		q = q.Set("ssl_root_cert = NULLIF(?, '')", *updates.SSLRootCert)
+	}
 	if updates.Protocol != nil {
 		q = q.Set("protocol = ?", *updates.Protocol)
 	}
@@ -484,6 +652,43 @@ func applyServerColumnUpdates(q *bun.UpdateQuery, updates ServerUpdate) *bun.Upd
 	if updates.Listable != nil {
 		q = q.Set("listable = ?", *updates.Listable)
 	}
+	if updates.BlockedMessage != nil {
+		q = q.Set("blocked_message = NULLIF(?, '')", *updates.BlockedMessage)
+	}
+	if updates.SlowQueryThresholdMs != nil {
+		q = q.Set("slow_query_threshold_ms = NULLIF(?, 0)", *updates.SlowQueryThresholdMs)
+	}
+	if updates.Labels != nil {
+		q = q.Set("labels = ?::jsonb", updates.Labels.jsonb())
+	}
+	if defaults := updates.GrantDefaults; defaults != nil {
+		if defaults.IsZero() {
+			q = q.Set("grant_defaults = NULL")
+		} else {
+			q = q.Set("grant_defaults = ?", defaults)
+		}
+	}
+	if quota := updates.RowQuota; quota != nil {
+		if quota.IsZero() {
+			q = q.Set("row_quota = NULL")
+		} else {
+			q = q.Set("row_quota = ?", quota)
+		}
+	}
+	if masking := updates.ResultMasking; masking != nil {
+		if masking.IsZero() {
+			q = q.Set("result_masking = NULL")
+		} else {
+			q = q.Set("result_masking = ?", masking)
+		}
+	}
+	if overrides := updates.QueryStorage; overrides != nil {
+		if overrides.IsZero() {
+			q = q.Set("query_storage = NULL")
+		} else {
+			q = q.Set("query_storage = ?", overrides)
+		}
+	}
 	if updates.ClearViaUID {
 		q = q.Set("via_uid = NULL")
 	} else if updates.ViaUID != nil {
@@ -526,26 +731,59 @@ func (s *Store) mergedSSHSecrets(ctx context.Context, uid uuid.UUID, updates Ser
 	return current.ProtocolData, nil
 }
 
-// DeleteServer deletes a database
-func (s *Store) DeleteServer(ctx context.Context, uid uuid.UUID) error {
-	result, err := s.db.NewDelete().
-		Model((*Server)(nil)).
-		Where("uid = ?", uid).
-		Exec(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to delete database: %w", err)
-	}
+// DeleteServer soft-deletes a database on behalf of deletedBy. Grants on it are
+// revoked and pending grant requests for it cancelled; connections and queries
+// are kept. An SSH server still used as a tunnel by another server cannot be
+// deleted (ErrServerInUse).
+func (s *Store) DeleteServer(ctx context.Context, uid, deletedBy uuid.UUID) (*DeletionCascade, error) {
+	cascade := &DeletionCascade{}
+
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		tunnelled, err := tx.NewSelect().
+			Model((*Server)(nil)).
+			Where("via_uid = ?", uid).
+			Exists(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check tunnelled databases: %w", err)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+		if tunnelled {
+			return ErrServerInUse
+		}
 
-	if rowsAffected == 0 {
-		return ErrServerNotFound
+		result, err := tx.NewDelete().
+			Model((*Server)(nil)).
+			Where("uid = ?", uid).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to delete database: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return ErrServerNotFound
+		}
+
+		now := time.Now()
+
+		if cascade.RevokedGrants, err = revokeActiveGrants(ctx, tx, "database_id", uid, deletedBy, now); err != nil {
+			return err
+		}
+
+		cascade.CancelledRequests, err = cancelPendingGrantRequests(
+			ctx, tx, "database_id", uid, deletedBy, cancelReasonServerDeleted, now)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return cascade, nil
 }
 
 // MongoAuthSourceOrDefault returns the upstream MongoDB SCRAM authSource
@@ -559,6 +797,45 @@ func (db *Server) MongoAuthSourceOrDefault() string {
 	return "admin"
 }
 
+// UseUpstreamCredentials decrypts the credentials a proxy logs in upstream
+// with for a grant of accessLevel into Username and Password: the read-only
+// ones for read-only grants when the database has them, the main ones
+// otherwise. Proxies call it on their session's own copy of the server.
+func (db *Server) UseUpstreamCredentials(encryptionKey []byte, accessLevel string) error {
+	if !db.UsesReadOnlyCredentials(accessLevel) {
+		return db.DecryptPassword(encryptionKey)
+	}
+
+	if err := db.decryptReadOnlyPassword(encryptionKey); err != nil {
+		return err
+	}
+
+	db.Username, db.Password = db.ReadOnlyUsername, db.ReadOnlyPassword
+
+	return nil
+}
+
+// UsesReadOnlyCredentials reports whether a grant of accessLevel logs in
+// upstream with the read-only credentials rather than the main ones.
+func (db *Server) UsesReadOnlyCredentials(accessLevel string) bool {
+	return accessLevel == AccessLevelReadOnly && db.ReadOnlyUsername != ""
+}
+
+// decryptReadOnlyPassword decrypts the read-only password, if any, into
+// ReadOnlyPassword.
+func (db *Server) decryptReadOnlyPassword(encryptionKey []byte) error {
+	if db.ReadOnlyUsername == "" {
+		return nil
+	}
+
+	plaintext, err := crypto.Decrypt(db.ReadOnlyPasswordEncrypted, encryptionKey, crypto.ServerAAD(db.UID.String()))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt read-only password: %w", err)
+	}
+	db.ReadOnlyPassword = string(plaintext)
+	return nil
+}
+
 // DecryptPassword decrypts a database password using AAD bound to the database UID.
 func (db *Server) DecryptPassword(encryptionKey []byte) error {
 	aad := crypto.ServerAAD(db.UID.String())
@@ -74,7 +74,7 @@ func TestSSHServer_ExcludedFromTargets(t *testing.T) {
 		t.Fatalf("CreateServer(target) error = %v", err)
 	}
 
-	targets, err := s.ListServers(ctx)
+	targets, err := s.ListServers(ctx, ServerFilter{})
 	if err != nil {
 		t.Fatalf("ListServers() error = %v", err)
 	}
@@ -84,7 +84,7 @@ func TestSSHServer_ExcludedFromTargets(t *testing.T) {
 		}
 	}
 
-	listable, err := s.ListListableServers(ctx)
+	listable, err := s.ListListableServers(ctx, ServerFilter{})
 	if err != nil {
 		t.Fatalf("ListListableServers() error = %v", err)
 	}
@@ -100,7 +100,7 @@ func TestSSHServer_ExcludedFromTargets(t *testing.T) {
 	}
 
 	// But the dedicated SSH listing does return it.
-	sshList, err := s.ListSSHServers(ctx)
+	sshList, err := s.ListSSHServers(ctx, ServerFilter{})
 	if err != nil {
 		t.Fatalf("ListSSHServers() error = %v", err)
 	}
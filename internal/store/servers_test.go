@@ -310,7 +310,7 @@ func TestListDatabases(t *testing.T) {
 	key := testEncryptionKey()
 
 	t.Run("empty list", func(t *testing.T) {
-		dbs, err := store.ListServers(ctx)
+		dbs, err := store.ListServers(ctx, ServerFilter{})
 		if err != nil {
 			t.Fatalf("ListServers() error = %v", err)
 		}
@@ -337,7 +337,7 @@ func TestListDatabases(t *testing.T) {
 	}
 
 	t.Run("with databases", func(t *testing.T) {
-		dbs, err := store.ListServers(ctx)
+		dbs, err := store.ListServers(ctx, ServerFilter{})
 		if err != nil {
 			t.Fatalf("ListServers() error = %v", err)
 		}
@@ -389,6 +389,28 @@ func TestUpdateDatabase(t *testing.T) {
 		}
 	})
 
+	t.Run("set and clear blocked message", func(t *testing.T) {
+		msg := "Request write access at https://dbbat.example.com"
+		if err := store.UpdateServer(ctx, created.UID, ServerUpdate{BlockedMessage: &msg}, key); err != nil {
+			t.Fatalf("UpdateServer() error = %v", err)
+		}
+
+		found, _ := store.GetServerByUID(ctx, created.UID)
+		if found.BlockedMessage != msg {
+			t.Errorf("db.BlockedMessage = %q, want %q", found.BlockedMessage, msg)
+		}
+
+		empty := ""
+		if err := store.UpdateServer(ctx, created.UID, ServerUpdate{BlockedMessage: &empty}, key); err != nil {
+			t.Fatalf("UpdateServer() error = %v", err)
+		}
+
+		found, _ = store.GetServerByUID(ctx, created.UID)
+		if found.BlockedMessage != "" {
+			t.Errorf("db.BlockedMessage = %q, want cleared", found.BlockedMessage)
+		}
+	})
+
 	t.Run("update host and port", func(t *testing.T) {
 		newHost := "newhost"
 		newPort := 5433
@@ -453,7 +475,7 @@ func TestDeleteDatabase(t *testing.T) {
 	}
 
 	t.Run("delete existing database", func(t *testing.T) {
-		err := store.DeleteServer(ctx, created.UID)
+		_, err := store.DeleteServer(ctx, created.UID, uuid.Nil)
 		if err != nil {
 			t.Fatalf("DeleteServer() error = %v", err)
 		}
@@ -465,7 +487,7 @@ func TestDeleteDatabase(t *testing.T) {
 	})
 
 	t.Run("delete non-existing database", func(t *testing.T) {
-		err := store.DeleteServer(ctx, uuid.New())
+		_, err := store.DeleteServer(ctx, uuid.New(), uuid.Nil)
 		if !errors.Is(err, ErrServerNotFound) {
 			t.Errorf("DeleteServer() error = %v, want %v", err, ErrServerNotFound)
 		}
@@ -517,6 +539,111 @@ func TestDecryptPassword(t *testing.T) {
 	})
 }
 
+func TestUseUpstreamCredentials(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	key := testEncryptionKey()
+
+	created, err := store.CreateServer(ctx, &Server{
+		Name:             "readonlycreds",
+		Host:             "localhost",
+		Port:             5432,
+		DatabaseName:     "db",
+		Username:         "writer",
+		Password:         "writerpass",
+		ReadOnlyUsername: "reader",
+		ReadOnlyPassword: "readerpass",
+		SSLMode:          "disable",
+	}, key)
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+
+	credentials := func(uid uuid.UUID, accessLevel string) (string, string) {
+		t.Helper()
+
+		srv, err := store.GetServerByUID(ctx, uid)
+		if err != nil {
+			t.Fatalf("GetServerByUID() error = %v", err)
+		}
+
+		if err := srv.UseUpstreamCredentials(key, accessLevel); err != nil {
+			t.Fatalf("UseUpstreamCredentials() error = %v", err)
+		}
+
+		return srv.Username, srv.Password
+	}
+
+	if user, pass := credentials(created.UID, AccessLevelReadOnly); user != "reader" || pass != "readerpass" {
+		t.Errorf("read-only credentials = %s/%s, want reader/readerpass", user, pass)
+	}
+
+	if user, pass := credentials(created.UID, AccessLevelReadWrite); user != "writer" || pass != "writerpass" {
+		t.Errorf("read-write credentials = %s/%s, want writer/writerpass", user, pass)
+	}
+
+	clone, err := store.CloneServer(ctx, created.UID, ServerClone{Name: "readonlycreds-clone"}, key)
+	if err != nil {
+		t.Fatalf("CloneServer() error = %v", err)
+	}
+
+	if user, pass := credentials(clone.UID, AccessLevelReadOnly); user != "reader" || pass != "readerpass" {
+		t.Errorf("cloned read-only credentials = %s/%s, want reader/readerpass", user, pass)
+	}
+
+	empty := ""
+	if err := store.UpdateServer(ctx, created.UID, ServerUpdate{ReadOnlyUsername: &empty}, key); err != nil {
+		t.Fatalf("UpdateServer() error = %v", err)
+	}
+
+	// Without read-only credentials, read-only grants log in as the main user.
+	if user, pass := credentials(created.UID, AccessLevelReadOnly); user != "writer" || pass != "writerpass" {
+		t.Errorf("credentials after removal = %s/%s, want writer/writerpass", user, pass)
+	}
+}
+
+func TestServerSecretRef(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	key := testEncryptionKey()
+
+	created, err := store.CreateServer(ctx, &Server{
+		Name:         "secretref",
+		Host:         "localhost",
+		Port:         5432,
+		DatabaseName: "db",
+		Username:     "app",
+		SecretRef:    "vault://secret/dbbat/orders",
+		SSLMode:      "disable",
+	}, key)
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+
+	clone, err := store.CloneServer(ctx, created.UID, ServerClone{Name: "secretref-clone"}, key)
+	if err != nil {
+		t.Fatalf("CloneServer() error = %v", err)
+	}
+
+	if clone.SecretRef != "vault://secret/dbbat/orders" {
+		t.Errorf("clone SecretRef = %q, want the source's", clone.SecretRef)
+	}
+
+	empty := ""
+	if err := store.UpdateServer(ctx, created.UID, ServerUpdate{SecretRef: &empty}, key); err != nil {
+		t.Fatalf("UpdateServer() error = %v", err)
+	}
+
+	got, err := store.GetServerByUID(ctx, created.UID)
+	if err != nil {
+		t.Fatalf("GetServerByUID() error = %v", err)
+	}
+
+	if got.SecretRef != "" {
+		t.Errorf("SecretRef after clearing = %q, want empty", got.SecretRef)
+	}
+}
+
 func TestCreateDatabase_DefaultListable(t *testing.T) {
 	t.Parallel()
 
@@ -648,7 +775,7 @@ func TestListListableDatabases(t *testing.T) {
 		t.Fatalf("CreateServer(hidden) error = %v", err)
 	}
 
-	all, err := s.ListListableServers(ctx)
+	all, err := s.ListListableServers(ctx, ServerFilter{})
 	if err != nil {
 		t.Fatalf("ListListableServers() error = %v", err)
 	}
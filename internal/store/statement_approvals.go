@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var (
+	// ErrStatementApprovalNotFound is returned when a statement approval UID
+	// misses.
+	ErrStatementApprovalNotFound = errors.New("statement approval not found")
+	// ErrStatementApprovalNotPending is returned when a statement approval is
+	// decided once it is no longer pending, or past its expiry.
+	ErrStatementApprovalNotPending = errors.New("statement approval not pending")
+	// ErrSelfApproval is returned when a user approves their own statement:
+	// the control asks for a second person.
+	ErrSelfApproval = errors.New("a statement cannot be approved by its own user")
+)
+
+// CreateStatementApproval inserts a new pending statement approval.
+func (s *Store) CreateStatementApproval(ctx context.Context, approval *StatementApproval) (*StatementApproval, error) {
+	result := &StatementApproval{
+		ConnectionID: approval.ConnectionID,
+		GrantID:      approval.GrantID,
+		UserID:       approval.UserID,
+		DatabaseID:   approval.DatabaseID,
+		SQLText:      approval.SQLText,
+		Status:       StatementApprovalPending,
+		RequestedAt:  time.Now(),
+		ExpiresAt:    approval.ExpiresAt,
+	}
+
+	_, err := s.db.NewInsert().
+		Model(result).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create statement approval: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetStatementApproval fetches a statement approval by UID.
+func (s *Store) GetStatementApproval(ctx context.Context, uid uuid.UUID) (*StatementApproval, error) {
+	approval := new(StatementApproval)
+
+	err := s.db.NewSelect().
+		Model(approval).
+		Where("uid = ?", uid).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStatementApprovalNotFound
+		}
+
+		return nil, fmt.Errorf("get statement approval: %w", err)
+	}
+
+	return approval, nil
+}
+
+// ListStatementApprovals returns statement approvals matching the filter,
+// newest first.
+func (s *Store) ListStatementApprovals(ctx context.Context, filter StatementApprovalFilter) ([]StatementApproval, error) {
+	var approvals []StatementApproval
+
+	if err := s.selectStatementApprovals(&approvals, filter).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("list statement approvals: %w", err)
+	}
+
+	return approvals, nil
+}
+
+// CountStatementApprovals returns the number of statement approvals matching the filter,
+// regardless of its Limit and Offset.
+func (s *Store) CountStatementApprovals(ctx context.Context, filter StatementApprovalFilter) (int, error) {
+	var approvals []StatementApproval
+
+	n, err := s.selectStatementApprovals(&approvals, filter).Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("count statement approvals: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectStatementApprovals builds the select of the statement approvals matching filter into dest,
+// newest first.
+func (s *Store) selectStatementApprovals(dest *[]StatementApproval, filter StatementApprovalFilter) *bun.SelectQuery {
+	q := s.db.NewSelect().Model(dest)
+
+	if filter.UserID != nil {
+		q = q.Where("user_id = ?", *filter.UserID)
+	}
+
+	if filter.DatabaseID != nil {
+		q = q.Where("database_id = ?", *filter.DatabaseID)
+	}
+
+	if filter.Status != nil {
+		q = q.Where("status = ?", *filter.Status)
+	}
+
+	return paginate(q.Order("requested_at DESC"), filter.Limit, filter.Offset)
+}
+
+// ApproveStatementApproval atomically transitions a pending statement
+// approval to approved. The decider must not be the statement's user.
+func (s *Store) ApproveStatementApproval(ctx context.Context, uid, decidedBy uuid.UUID) (*StatementApproval, error) {
+	return s.decideStatementApproval(ctx, uid, StatementApprovalApproved, &decidedBy, "")
+}
+
+// DenyStatementApproval atomically transitions a pending statement approval
+// to denied with an optional reason.
+func (s *Store) DenyStatementApproval(ctx context.Context, uid, decidedBy uuid.UUID, reason string) (*StatementApproval, error) {
+	return s.decideStatementApproval(ctx, uid, StatementApprovalDenied, &decidedBy, reason)
+}
+
+// ExpireStatementApproval transitions a statement approval still pending to
+// expired. The proxy calls it once it stops waiting, so that a late decision
+// cannot be mistaken for one it acted on.
+func (s *Store) ExpireStatementApproval(ctx context.Context, uid uuid.UUID) (*StatementApproval, error) {
+	return s.decideStatementApproval(ctx, uid, StatementApprovalExpired, nil, "")
+}
+
+// decideStatementApproval is the shared transition of statement approvals
+// out of pending. Approvals and denials are refused past the expiry, which
+// only the proxy acts on.
+func (s *Store) decideStatementApproval(
+	ctx context.Context,
+	uid uuid.UUID,
+	target StatementApprovalStatus,
+	decidedBy *uuid.UUID,
+	reason string,
+) (*StatementApproval, error) {
+	var updated *StatementApproval
+
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		approval := new(StatementApproval)
+		if err := tx.NewSelect().Model(approval).Where("uid = ?", uid).For("UPDATE").Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrStatementApprovalNotFound
+			}
+
+			return fmt.Errorf("select statement approval: %w", err)
+		}
+
+		now := time.Now()
+
+		if approval.Status != StatementApprovalPending ||
+			(target != StatementApprovalExpired && !now.Before(approval.ExpiresAt)) {
+			return ErrStatementApprovalNotPending
+		}
+
+		if target == StatementApprovalApproved && decidedBy != nil && *decidedBy == approval.UserID {
+			return ErrSelfApproval
+		}
+
+		approval.Status = target
+		approval.DecidedAt = &now
+		approval.DecidedBy = decidedBy
+
+		if reason != "" {
+			approval.DecisionReason = &reason
+		}
+
+		if _, err := tx.NewUpdate().Model(approval).
+			Column("status", "decided_at", "decided_by", "decision_reason").
+			Where("uid = ?", uid).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("update statement approval: %w", err)
+		}
+
+		updated = approval
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
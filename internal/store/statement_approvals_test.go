@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func createTestStatementApproval(t *testing.T, ctx context.Context, s *Store, suffix string, expiresAt time.Time) (*User, *StatementApproval) {
+	t.Helper()
+
+	user, database := createTestUserAndDatabase(t, ctx, s, "approval_"+suffix)
+	admin := createTestAdmin(t, ctx, s, "approval_admin_"+suffix)
+
+	now := time.Now()
+
+	grant, err := s.CreateGrant(ctx, &Grant{
+		UserID:     user.UID,
+		DatabaseID: database.UID,
+		Controls:   []string{ControlWriteRequiresApproval},
+		GrantedBy:  admin.UID,
+		StartsAt:   now,
+		ExpiresAt:  now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateGrant: %v", err)
+	}
+
+	approval, err := s.CreateStatementApproval(ctx, &StatementApproval{
+		ConnectionID: uuid.New(),
+		GrantID:      grant.UID,
+		UserID:       user.UID,
+		DatabaseID:   database.UID,
+		SQLText:      "DELETE FROM users WHERE id = 1",
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("CreateStatementApproval: %v", err)
+	}
+
+	return admin, approval
+}
+
+func TestStatementApproval_Approve(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, approval := createTestStatementApproval(t, ctx, store, "approve", time.Now().Add(time.Minute))
+
+	if approval.Status != StatementApprovalPending {
+		t.Fatalf("status = %q, want pending", approval.Status)
+	}
+
+	if _, err := store.ApproveStatementApproval(ctx, approval.UID, approval.UserID); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("self approval error = %v, want %v", err, ErrSelfApproval)
+	}
+
+	approved, err := store.ApproveStatementApproval(ctx, approval.UID, admin.UID)
+	if err != nil {
+		t.Fatalf("ApproveStatementApproval: %v", err)
+	}
+
+	if approved.Status != StatementApprovalApproved || approved.DecidedBy == nil || *approved.DecidedBy != admin.UID {
+		t.Errorf("approved = %+v", approved)
+	}
+
+	if _, err := store.ExpireStatementApproval(ctx, approval.UID); !errors.Is(err, ErrStatementApprovalNotPending) {
+		t.Errorf("expire after approval error = %v, want %v", err, ErrStatementApprovalNotPending)
+	}
+
+	got, err := store.GetStatementApproval(ctx, approval.UID)
+	if err != nil || got.Status != StatementApprovalApproved {
+		t.Errorf("GetStatementApproval = %+v, %v", got, err)
+	}
+}
+
+func TestStatementApproval_DenyAndList(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, approval := createTestStatementApproval(t, ctx, store, "deny", time.Now().Add(time.Minute))
+
+	denied, err := store.DenyStatementApproval(ctx, approval.UID, admin.UID, "wrong table")
+	if err != nil {
+		t.Fatalf("DenyStatementApproval: %v", err)
+	}
+
+	if denied.Status != StatementApprovalDenied || denied.DecisionReason == nil || *denied.DecisionReason != "wrong table" {
+		t.Errorf("denied = %+v", denied)
+	}
+
+	pending := StatementApprovalPending
+
+	approvals, err := store.ListStatementApprovals(ctx, StatementApprovalFilter{Status: &pending})
+	if err != nil {
+		t.Fatalf("ListStatementApprovals: %v", err)
+	}
+
+	if len(approvals) != 0 {
+		t.Errorf("pending approvals = %d, want 0", len(approvals))
+	}
+
+	approvals, err = store.ListStatementApprovals(ctx, StatementApprovalFilter{UserID: &approval.UserID})
+	if err != nil || len(approvals) != 1 {
+		t.Errorf("user approvals = %d, %v, want 1", len(approvals), err)
+	}
+}
+
+func TestStatementApproval_Expired(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	admin, approval := createTestStatementApproval(t, ctx, store, "expired", time.Now().Add(-time.Second))
+
+	if _, err := store.ApproveStatementApproval(ctx, approval.UID, admin.UID); !errors.Is(err, ErrStatementApprovalNotPending) {
+		t.Errorf("late approval error = %v, want %v", err, ErrStatementApprovalNotPending)
+	}
+
+	expired, err := store.ExpireStatementApproval(ctx, approval.UID)
+	if err != nil {
+		t.Fatalf("ExpireStatementApproval: %v", err)
+	}
+
+	if expired.Status != StatementApprovalExpired || expired.DecidedBy != nil {
+		t.Errorf("expired = %+v", expired)
+	}
+
+	if _, err := store.GetStatementApproval(ctx, uuid.New()); !errors.Is(err, ErrStatementApprovalNotFound) {
+		t.Errorf("missing approval error = %v, want %v", err, ErrStatementApprovalNotFound)
+	}
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// storageTables are the tables reported by GetStorageUsage: the high-volume ones
+// written on every proxied session, with the column dating each row. query_rows
+// and query_row_blobs have no timestamp of their own and are dated through their
+// query.
+var storageTables = []struct {
+	name       string
+	countSince string
+}{
+	{name: "queries", countSince: `SELECT count(*) FROM queries WHERE executed_at >= ?`},
+	{
+		name: "query_rows",
+		countSince: `SELECT count(*) FROM query_rows qr
+			JOIN queries q ON q.uid = qr.query_id
+			WHERE q.executed_at >= ?`,
+	},
+	{
+		name: "query_row_blobs",
+		countSince: `SELECT count(*) FROM query_row_blobs qrb
+			JOIN queries q ON q.uid = qrb.query_id
+			WHERE q.executed_at >= ?`,
+	},
+	{name: "connections", countSince: `SELECT count(*) FROM connections WHERE connected_at >= ?`},
+	{name: "audit_log", countSince: `SELECT count(*) FROM audit_log WHERE created_at >= ?`},
+}
+
+// TableStorage is the disk usage and recent growth of one storage table.
+type TableStorage struct {
+	Name string `json:"name"`
+	// TableBytes includes TOAST, IndexBytes all indexes; TotalBytes is both.
+	TableBytes int64 `json:"table_bytes"`
+	IndexBytes int64 `json:"index_bytes"`
+	TotalBytes int64 `json:"total_bytes"`
+	// Rows is the planner's estimate, exact only right after an ANALYZE.
+	Rows         int64 `json:"rows"`
+	RowsLastDay  int64 `json:"rows_last_day"`
+	RowsLastWeek int64 `json:"rows_last_week"`
+	// BytesPerDay is the average daily growth over the last week, assuming
+	// new rows are the size of the current average row.
+	BytesPerDay int64 `json:"bytes_per_day"`
+}
+
+// StorageUsage reports how much space dbbat's own storage database uses.
+type StorageUsage struct {
+	// DatabaseBytes is the size of the whole storage database.
+	DatabaseBytes int64          `json:"database_bytes"`
+	Tables        []TableStorage `json:"tables"`
+	SampledAt     time.Time      `json:"sampled_at"`
+}
+
+// BytesPerDay is the summed daily growth of the reported tables.
+func (u *StorageUsage) BytesPerDay() int64 {
+	var total int64
+	for _, t := range u.Tables {
+		total += t.BytesPerDay
+	}
+
+	return total
+}
+
+// GetStorageUsage measures the storage database and its high-volume tables.
+// Growth is counted from now backwards, one day and one week.
+func (s *Store) GetStorageUsage(ctx context.Context, now time.Time) (*StorageUsage, error) {
+	usage := &StorageUsage{SampledAt: now}
+
+	if err := s.db.NewRaw("SELECT pg_database_size(current_database())").
+		Scan(ctx, &usage.DatabaseBytes); err != nil {
+		return nil, fmt.Errorf("failed to get database size: %w", err)
+	}
+
+	for _, table := range storageTables {
+		t := TableStorage{Name: table.name}
+
+		var reltuples float64
+		if err := s.db.NewRaw(`SELECT pg_table_size(c.oid), pg_indexes_size(c.oid), c.reltuples
+			FROM pg_class c WHERE c.oid = to_regclass(?)`, table.name).
+			Scan(ctx, &t.TableBytes, &t.IndexBytes, &reltuples); err != nil {
+			return nil, fmt.Errorf("failed to get size of %s: %w", table.name, err)
+		}
+
+		t.TotalBytes = t.TableBytes + t.IndexBytes
+		t.Rows = int64(reltuples)
+
+		// A table never analyzed reports -1, one analyzed while empty 0; count
+		// those, they cannot be large yet.
+		if reltuples <= 0 {
+			if err := s.db.NewRaw("SELECT count(*) FROM ?", bun.Ident(table.name)).
+				Scan(ctx, &t.Rows); err != nil {
+				return nil, fmt.Errorf("failed to count %s: %w", table.name, err)
+			}
+		}
+
+		if err := s.db.NewRaw(table.countSince, now.Add(-24*time.Hour)).Scan(ctx, &t.RowsLastDay); err != nil {
+			return nil, fmt.Errorf("failed to count recent %s: %w", table.name, err)
+		}
+
+		if err := s.db.NewRaw(table.countSince, now.Add(-7*24*time.Hour)).Scan(ctx, &t.RowsLastWeek); err != nil {
+			return nil, fmt.Errorf("failed to count recent %s: %w", table.name, err)
+		}
+
+		if t.Rows > 0 {
+			bytesPerRow := float64(t.TotalBytes) / float64(t.Rows)
+			t.BytesPerDay = int64(bytesPerRow * float64(t.RowsLastWeek) / 7)
+		}
+
+		usage.Tables = append(usage.Tables, t)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetStorageUsage(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	conn := createTestConnection(t, ctx, store, "storage")
+	now := time.Now()
+
+	for i, executedAt := range []time.Time{now.Add(-time.Hour), now.Add(-3 * 24 * time.Hour), now.Add(-30 * 24 * time.Hour)} {
+		if _, err := store.CreateQuery(ctx, &Query{
+			ConnectionID: conn.UID,
+			SQLText:      "SELECT 1",
+			ExecutedAt:   executedAt,
+		}); err != nil {
+			t.Fatalf("CreateQuery(%d) error = %v", i, err)
+		}
+	}
+
+	usage, err := store.GetStorageUsage(ctx, now)
+	if err != nil {
+		t.Fatalf("GetStorageUsage() error = %v", err)
+	}
+
+	if usage.DatabaseBytes <= 0 {
+		t.Errorf("DatabaseBytes = %d, want > 0", usage.DatabaseBytes)
+	}
+
+	tables := make(map[string]TableStorage, len(usage.Tables))
+	for _, table := range usage.Tables {
+		tables[table.Name] = table
+	}
+
+	for _, name := range []string{"queries", "query_rows", "connections", "audit_log"} {
+		if _, ok := tables[name]; !ok {
+			t.Errorf("table %s missing from the report", name)
+		}
+	}
+
+	queries := tables["queries"]
+	if queries.RowsLastDay != 1 || queries.RowsLastWeek != 2 {
+		t.Errorf("queries rows last day/week = %d/%d, want 1/2", queries.RowsLastDay, queries.RowsLastWeek)
+	}
+
+	if queries.TotalBytes != queries.TableBytes+queries.IndexBytes {
+		t.Errorf("TotalBytes = %d, want table + index bytes", queries.TotalBytes)
+	}
+
+	if queries.BytesPerDay <= 0 {
+		t.Errorf("queries BytesPerDay = %d, want > 0", queries.BytesPerDay)
+	}
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
@@ -20,21 +22,87 @@ import (
 
 // Store provides access to the database
 type Store struct {
-	db          *bun.DB
+	db          bun.IDB                   // Pool, or the transaction of a Store handed out by WithTx
+	pool        *bun.DB                   // Underlying connection pool
 	storageDSN  string                    // Parsed storage DSN for security validation
 	authCache   *cache.AuthCache          // Optional auth cache for API key verification
 	revocations *cache.RevocationRegistry // In-process fan-out of grant revocations to live proxy sessions
+	replica     *replica                  // Optional read replica for heavy list queries
+	observer    QueryObserver             // Optional consumer of logged queries
+	audit       AuditObserver             // Optional consumer of logged audit events
+	auditTx     *[]*AuditLog              // Audit events of a WithTx transaction, notified once it commits
+
+	terminations *cache.TerminationRegistry // In-process fan-out of connection terminations to live proxy sessions
+	queryFeed    *QueryFeed                 // In-process fan-out of logged queries to live API streams
+	instanceID   uuid.UUID                  // This process's instance, stamped on its connections; Nil until registered
+
+	maxSQLTextBytes int         // Truncate logged SQL text beyond this size (0 = no limit)
+	queryDedup      *queryDedup // Folds repeated statements, nil when disabled
+	compressRows    bool        // Store result rows zstd-compressed
+
+	migrationLockTimeout time.Duration // Wait for another instance's migrations
 }
 
 // Options configures Store creation.
 type Options struct {
 	// DropTablesFirst drops all tables before running migrations (for test mode)
 	DropTablesFirst bool
+
+	// SkipMigrations leaves the schema untouched, e.g. to plan migrations
+	// without applying them.
+	SkipMigrations bool
+	// MigrationLockTimeout is how long to wait while another instance runs
+	// migrations (0 = DefaultMigrationLockTimeout).
+	MigrationLockTimeout time.Duration
+
+	// MaxSQLTextBytes truncates the SQL text of logged queries beyond this
+	// size, keeping its start and end (0 = no limit).
+	MaxSQLTextBytes int
+
+	// QueryDedupWindow folds identical consecutive statements of a
+	// connection, within this window of the first one, into a single logged
+	// query (0 = disabled).
+	QueryDedupWindow time.Duration
+
+	// CompressRows stores the result rows zstd-compressed rather than as
+	// JSONB. Rows stored either way are read back alike.
+	CompressRows bool
+
+	// MaxOpenConns caps open storage connections (0 = DefaultMaxOpenConns).
+	MaxOpenConns int
+	// MaxIdleConns caps idle storage connections (0 = DefaultMaxIdleConns,
+	// negative = keep none).
+	MaxIdleConns int
+	// ConnMaxLifetime recycles connections older than this (0 = no limit).
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime closes connections idle longer than this (0 = no limit).
+	ConnMaxIdleTime time.Duration
+
+	// ReplicaDSN points at a read replica of the storage database. When set,
+	// heavy list queries are served by it while it is healthy.
+	ReplicaDSN string
+	// ReplicaMaxLag is the replication lag beyond which reads fall back to
+	// the primary (0 = DefaultReplicaMaxLag).
+	ReplicaMaxLag time.Duration
+}
+
+// Default connection pool sizing, used when Options leaves it unset.
+const (
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 25
+	DefaultConnMaxLifetime = 5 * time.Minute
+)
+
+// defaultOptions is used when New is called without Options.
+var defaultOptions = Options{
+	MaxOpenConns:    DefaultMaxOpenConns,
+	MaxIdleConns:    DefaultMaxIdleConns,
+	ConnMaxLifetime: DefaultConnMaxLifetime,
 }
 
 // New creates a new Store instance and runs migrations
 func New(ctx context.Context, dsn string, opts ...Options) (*Store, error) {
-	var options Options
+	options := defaultOptions
 	if len(opts) > 0 {
 		options = opts[0]
 	}
@@ -43,9 +111,7 @@ func New(ctx context.Context, dsn string, opts ...Options) (*Store, error) {
 	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
 
 	// Configure connection pool
-	sqldb.SetMaxOpenConns(25)
-	sqldb.SetMaxIdleConns(25)
-	sqldb.SetConnMaxLifetime(5 * time.Minute)
+	applyPoolOptions(sqldb, options)
 
 	// Create bun.DB
 	db := bun.NewDB(sqldb, pgdialect.New())
@@ -56,7 +122,18 @@ func New(ctx context.Context, dsn string, opts ...Options) (*Store, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	s := &Store{db: db, storageDSN: dsn, revocations: cache.NewRevocationRegistry()}
+	s := &Store{
+		db:                   db,
+		pool:                 db,
+		storageDSN:           dsn,
+		revocations:          cache.NewRevocationRegistry(),
+		terminations:         cache.NewTerminationRegistry(),
+		queryFeed:            NewQueryFeed(),
+		migrationLockTimeout: cmp.Or(options.MigrationLockTimeout, DefaultMigrationLockTimeout),
+		maxSQLTextBytes:      options.MaxSQLTextBytes,
+		queryDedup:           newQueryDedup(options.QueryDedupWindow),
+		compressRows:         options.CompressRows,
+	}
 
 	// Drop all tables first if requested (for test mode)
 	if options.DropTablesFirst {
@@ -67,29 +144,91 @@ func New(ctx context.Context, dsn string, opts ...Options) (*Store, error) {
 	}
 
 	// Run migrations
-	if err := s.runMigrations(ctx); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	if !options.SkipMigrations {
+		if err := s.runMigrations(ctx); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+	}
+
+	if options.ReplicaDSN != "" {
+		s.replica = openReplica(ctx, options.ReplicaDSN, options)
 	}
 
 	return s, nil
 }
 
+// applyPoolOptions sizes a connection pool from Options.
+func applyPoolOptions(sqldb *sql.DB, options Options) {
+	sqldb.SetMaxOpenConns(cmp.Or(options.MaxOpenConns, DefaultMaxOpenConns))
+	sqldb.SetMaxIdleConns(cmp.Or(options.MaxIdleConns, DefaultMaxIdleConns))
+	sqldb.SetConnMaxLifetime(options.ConnMaxLifetime)
+	sqldb.SetConnMaxIdleTime(options.ConnMaxIdleTime)
+}
+
 // Close closes the database connection pool
 func (s *Store) Close() {
-	if err := s.db.Close(); err != nil {
+	if s.replica != nil {
+		if err := s.replica.close(); err != nil {
+			slog.ErrorContext(context.Background(), "failed to close storage replica", slog.Any("error", err))
+		}
+	}
+
+	if err := s.pool.Close(); err != nil {
 		slog.ErrorContext(context.Background(), "failed to close database", slog.Any("error", err))
 	}
 }
 
 // Health checks if the database is healthy
 func (s *Store) Health(ctx context.Context) error {
-	return s.db.PingContext(ctx)
+	return s.pool.PingContext(ctx)
+}
+
+// PoolStats returns statistics of the storage connection pool. A growing
+// WaitCount means callers are queueing for connections and MaxOpenConns is
+// too low for the load.
+func (s *Store) PoolStats() sql.DBStats {
+	return s.pool.Stats()
 }
 
 // DB returns the underlying bun.DB for advanced operations
 func (s *Store) DB() *bun.DB {
-	return s.db
+	return s.pool
+}
+
+// WithTx runs fn in a single storage transaction. The Store passed to fn is
+// bound to that transaction, so every write made through it commits or rolls
+// back together and a multi-write operation (e.g. a change plus its audit
+// event) cannot be left half-applied. Store methods that open their own
+// transaction become savepoints inside it. The transactional Store must not be
+// used after fn returns.
+func (s *Store) WithTx(ctx context.Context, fn func(ctx context.Context, tx *Store) error) error {
+	var logged []*AuditLog
+
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		txStore := *s
+		txStore.db = tx
+		// Reads must observe the transaction's own writes.
+		txStore.replica = nil
+		// Audit events are only notified once committed.
+		txStore.auditTx = &logged
+
+		return fn(ctx, &txStore)
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.auditTx != nil {
+		// Nested: the outer transaction has yet to commit.
+		*s.auditTx = append(*s.auditTx, logged...)
+	} else if s.audit != nil {
+		for _, entry := range logged {
+			s.audit.AuditLogged(entry)
+		}
+	}
+
+	return nil
 }
 
 // SetAuthCache sets the authentication cache for API key verification.
@@ -97,6 +236,42 @@ func (s *Store) SetAuthCache(authCache *cache.AuthCache) {
 	s.authCache = authCache
 }
 
+// AddQueryHook registers a hook run around every query on the storage
+// database and its replica (e.g. the self-observability recorder).
+func (s *Store) AddQueryHook(hook bun.QueryHook) {
+	s.pool.AddQueryHook(hook)
+
+	if s.replica != nil {
+		s.replica.db.AddQueryHook(hook)
+	}
+}
+
+// QueryObserver is notified of every query logged by CreateQuery, whatever
+// the proxy protocol. QueryLogged is called on the proxy session's goroutine
+// and must not block.
+type QueryObserver interface {
+	QueryLogged(query *Query)
+}
+
+// SetQueryObserver registers the consumer of logged queries (e.g. the
+// lineage exporter). Must be called before the proxies start.
+func (s *Store) SetQueryObserver(observer QueryObserver) {
+	s.observer = observer
+}
+
+// AuditObserver is notified of every audit event logged by LogAuditEvent,
+// once it is committed. AuditLogged is called on the logging goroutine and
+// must not block.
+type AuditObserver interface {
+	AuditLogged(entry *AuditLog)
+}
+
+// SetAuditObserver registers the consumer of logged audit events (e.g. the
+// audit sink pipeline). Must be called before the API and proxies start.
+func (s *Store) SetAuditObserver(observer AuditObserver) {
+	s.audit = observer
+}
+
 // Revocations returns the process-wide grant-revocation registry that live
 // proxy sessions register with and the API's revoke handler signals. Always
 // non-nil for a store built via New. Nil-safe on both a nil *Store receiver and
@@ -111,9 +286,38 @@ func (s *Store) Revocations() *cache.RevocationRegistry {
 	return s.revocations
 }
 
+// Terminations returns the process-wide registry of the live proxy sessions by
+// connection UID, which the API's terminate handler signals. Nil-safe like
+// Revocations.
+func (s *Store) Terminations() *cache.TerminationRegistry {
+	if s == nil {
+		return nil
+	}
+
+	return s.terminations
+}
+
+// QueryFeed returns the process-wide feed of logged queries that the API's
+// query stream subscribes to. Nil-safe like Revocations; a nil feed's
+// subscriptions never receive anything.
+func (s *Store) QueryFeed() *QueryFeed {
+	if s == nil {
+		return nil
+	}
+
+	return s.queryFeed
+}
+
 // runMigrations runs the database schema migrations
 func (s *Store) runMigrations(ctx context.Context) error {
-	migrator := migrate.NewMigrator(s.db, migrations.Migrations)
+	return s.withMigrationLock(ctx, func() error {
+		return s.migrate(ctx)
+	})
+}
+
+// migrate applies pending migrations; callers hold the migration lock.
+func (s *Store) migrate(ctx context.Context) error {
+	migrator := migrate.NewMigrator(s.pool, migrations.Migrations)
 
 	// Initialize bun_migrations table
 	if err := migrator.Init(ctx); err != nil {
@@ -142,7 +346,15 @@ func (s *Store) Migrate(ctx context.Context) error {
 
 // Rollback rolls back the last migration group
 func (s *Store) Rollback(ctx context.Context) error {
-	migrator := migrate.NewMigrator(s.db, migrations.Migrations)
+	return s.withMigrationLock(ctx, func() error {
+		return s.rollback(ctx)
+	})
+}
+
+// rollback rolls back the last migration group; callers hold the migration
+// lock.
+func (s *Store) rollback(ctx context.Context) error {
+	migrator := migrate.NewMigrator(s.pool, migrations.Migrations)
 
 	if err := migrator.Init(ctx); err != nil {
 		return fmt.Errorf("failed to init migrator: %w", err)
@@ -174,16 +386,24 @@ func (s *Store) DropAllTables(ctx context.Context) error {
 	// Tables to drop in order (respecting foreign key constraints)
 	// Must be in reverse dependency order
 	tables := []string{
+		"server_health",
+		"grant_templates",
+		"query_alert_matches",
+		"query_alerts",
+		"query_row_blobs",
 		"query_rows",
 		"queries",
 		"connections",
+		"instances",
 		"grant_requests",
 		"grant_definitions",
+		"proxy_credentials",
 		"access_grants",
 		"api_keys",
 		"audit_log",
 		"oauth_states",
 		"user_identities",
+		"user_preferences",
 		"user_group_members",
 		"user_groups",
 		"global_parameters",
@@ -195,7 +415,7 @@ func (s *Store) DropAllTables(ctx context.Context) error {
 	}
 
 	for _, table := range tables {
-		_, err := s.db.NewDropTable().
+		_, err := s.pool.NewDropTable().
 			Table(table).
 			IfExists().
 			Cascade().
@@ -211,7 +431,7 @@ func (s *Store) DropAllTables(ctx context.Context) error {
 	}
 
 	for _, typeName := range types {
-		_, err := s.db.ExecContext(ctx, "DROP TYPE IF EXISTS "+typeName+" CASCADE")
+		_, err := s.pool.ExecContext(ctx, "DROP TYPE IF EXISTS "+typeName+" CASCADE")
 		if err != nil {
 			return fmt.Errorf("failed to drop type %s: %w", typeName, err)
 		}
@@ -223,7 +443,7 @@ func (s *Store) DropAllTables(ctx context.Context) error {
 
 // MigrationStatus returns the status of all migrations
 func (s *Store) MigrationStatus(ctx context.Context) ([]MigrationInfo, error) {
-	migrator := migrate.NewMigrator(s.db, migrations.Migrations)
+	migrator := migrate.NewMigrator(s.pool, migrations.Migrations)
 
 	if err := migrator.Init(ctx); err != nil {
 		return nil, fmt.Errorf("failed to init migrator: %w", err)
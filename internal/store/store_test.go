@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -89,9 +90,11 @@ func setupTestStore(t *testing.T) *Store {
 
 	// Clean up tables in correct order (respecting foreign keys)
 	cleanupTables := []string{
+		"query_row_blobs",
 		"query_rows",
 		"queries",
 		"connections",
+		"instances",
 		"grant_requests",
 		"access_grants",
 		"grant_definitions",
@@ -153,6 +156,40 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestPoolStats(t *testing.T) {
+	dsn := setupPostgresContainer(t)
+	ctx := context.Background()
+
+	t.Run("defaults", func(t *testing.T) {
+		store, err := New(ctx, dsn)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer store.Close()
+
+		if got := store.PoolStats().MaxOpenConnections; got != DefaultMaxOpenConns {
+			t.Errorf("MaxOpenConnections = %d, want %d", got, DefaultMaxOpenConns)
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		store, err := New(ctx, dsn, Options{MaxOpenConns: 7})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer store.Close()
+
+		stats := store.PoolStats()
+		if stats.MaxOpenConnections != 7 {
+			t.Errorf("MaxOpenConnections = %d, want 7", stats.MaxOpenConnections)
+		}
+
+		if stats.OpenConnections == 0 {
+			t.Error("expected at least one open connection after New()")
+		}
+	})
+}
+
 func TestDB(t *testing.T) {
 	store := setupTestStore(t)
 
@@ -162,6 +199,53 @@ func TestDB(t *testing.T) {
 	}
 }
 
+func TestWithTx(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	t.Run("commits on success", func(t *testing.T) {
+		err := store.WithTx(ctx, func(ctx context.Context, tx *Store) error {
+			user, err := tx.CreateUser(ctx, "tx_commit", "hash", nil)
+			if err != nil {
+				return err
+			}
+
+			return tx.LogAuditEvent(ctx, &AuditEvent{EventType: "user.created", UserID: &user.UID})
+		})
+		if err != nil {
+			t.Fatalf("WithTx() error = %v", err)
+		}
+
+		if _, err := store.GetUserByUsername(ctx, "tx_commit"); err != nil {
+			t.Errorf("GetUserByUsername() error = %v", err)
+		}
+	})
+
+	t.Run("rolls back every write on error", func(t *testing.T) {
+		errBoom := errors.New("boom")
+
+		err := store.WithTx(ctx, func(ctx context.Context, tx *Store) error {
+			if _, err := tx.CreateUser(ctx, "tx_rollback", "hash", nil); err != nil {
+				return err
+			}
+
+			// The transaction's own writes are visible inside it.
+			if _, err := tx.GetUserByUsername(ctx, "tx_rollback"); err != nil {
+				return err
+			}
+
+			return errBoom
+		})
+		if !errors.Is(err, errBoom) {
+			t.Fatalf("WithTx() error = %v, want %v", err, errBoom)
+		}
+
+		if _, err := store.GetUserByUsername(ctx, "tx_rollback"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("GetUserByUsername() error = %v, want %v", err, ErrUserNotFound)
+		}
+	})
+}
+
 func TestParsePostgresDSN(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -1,6 +1,11 @@
 package store
 
-import "github.com/google/uuid"
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // newUIDv7 generates a new UUIDv7 for high-volume tables.
 // UUIDv7 is time-ordered for better B-tree index performance.
@@ -12,3 +17,18 @@ func newUIDv7() uuid.UUID {
 	}
 	return uid
 }
+
+// uidV7Floor returns the smallest UUIDv7 of the millisecond of t: the UIDs
+// generated from then on all compare greater or equal.
+func uidV7Floor(t time.Time) uuid.UUID {
+	var uid uuid.UUID
+
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli())) //nolint:gosec // timestamps after 1970
+	copy(uid[:6], ms[2:])
+
+	uid[6] = 0x70 // Version 7
+	uid[8] = 0x80 // RFC 9562 variant
+
+	return uid
+}
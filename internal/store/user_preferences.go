@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ErrPreferenceNotFound is returned when a user has no preference for a key.
+var ErrPreferenceNotFound = errors.New("preference not found")
+
+// ErrTooManyPreferences is returned when saving a new key would exceed
+// MaxUserPreferences.
+var ErrTooManyPreferences = errors.New("too many preferences")
+
+// MaxUserPreferences caps the number of preference keys per user, so the
+// table cannot be used as general-purpose storage.
+const MaxUserPreferences = 100
+
+// ListUserPreferences returns all preferences of a user, ordered by key.
+func (s *Store) ListUserPreferences(ctx context.Context, userID uuid.UUID) ([]UserPreference, error) {
+	var prefs []UserPreference
+	err := s.db.NewSelect().
+		Model(&prefs).
+		Where("user_id = ?", userID).
+		Order("key ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list preferences: %w", err)
+	}
+	if prefs == nil {
+		prefs = []UserPreference{}
+	}
+	return prefs, nil
+}
+
+// GetUserPreference returns one preference of a user.
+func (s *Store) GetUserPreference(ctx context.Context, userID uuid.UUID, key string) (*UserPreference, error) {
+	pref := new(UserPreference)
+	err := s.db.NewSelect().
+		Model(pref).
+		Where("user_id = ?", userID).
+		Where("key = ?", key).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPreferenceNotFound
+		}
+		return nil, fmt.Errorf("failed to get preference: %w", err)
+	}
+	return pref, nil
+}
+
+// SetUserPreference creates or replaces a preference. Creating a new key
+// fails with ErrTooManyPreferences once the user holds MaxUserPreferences.
+func (s *Store) SetUserPreference(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (*UserPreference, error) {
+	pref := &UserPreference{UserID: userID, Key: key, Value: value}
+
+	// The cap is checked in the INSERT itself: its SELECT yields no row (and
+	// RETURNING nothing) when the key is new and the user is at the cap.
+	// Updating an existing key is always allowed.
+	err := s.db.NewRaw(
+		`INSERT INTO user_preferences (user_id, key, value, updated_at)
+		SELECT ?, ?, ?, NOW()
+		WHERE EXISTS (SELECT 1 FROM user_preferences WHERE user_id = ? AND key = ?)
+		   OR (SELECT COUNT(*) FROM user_preferences WHERE user_id = ?) < ?
+		ON CONFLICT (user_id, key)
+		DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()
+		RETURNING updated_at`,
+		userID, key, string(value), userID, key, userID, MaxUserPreferences,
+	).Scan(ctx, &pref.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTooManyPreferences
+		}
+		return nil, fmt.Errorf("failed to set preference: %w", err)
+	}
+
+	return pref, nil
+}
+
+// DeleteUserPreference removes a preference.
+func (s *Store) DeleteUserPreference(ctx context.Context, userID uuid.UUID, key string) error {
+	result, err := s.db.NewDelete().
+		Model((*UserPreference)(nil)).
+		Where("user_id = ?", userID).
+		Where("key = ?", key).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete preference: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrPreferenceNotFound
+	}
+	return nil
+}
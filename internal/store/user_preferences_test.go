@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUserPreferences(t *testing.T) {
+	store := setupTestStoreNoCleanup(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "prefuser", "hash", []string{RoleConnector})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	t.Run("set, get and replace", func(t *testing.T) {
+		if _, err := store.SetUserPreference(ctx, user.UID, "layout", json.RawMessage(`{"cols":["a"]}`)); err != nil {
+			t.Fatalf("SetUserPreference() error = %v", err)
+		}
+
+		if _, err := store.SetUserPreference(ctx, user.UID, "layout", json.RawMessage(`{"cols":["b"]}`)); err != nil {
+			t.Fatalf("SetUserPreference() replace error = %v", err)
+		}
+
+		pref, err := store.GetUserPreference(ctx, user.UID, "layout")
+		if err != nil {
+			t.Fatalf("GetUserPreference() error = %v", err)
+		}
+
+		var value map[string][]string
+		if err := json.Unmarshal(pref.Value, &value); err != nil || value["cols"][0] != "b" {
+			t.Errorf("GetUserPreference() value = %s, want the replaced value", pref.Value)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		if _, err := store.GetUserPreference(ctx, user.UID, "nope"); !errors.Is(err, ErrPreferenceNotFound) {
+			t.Errorf("GetUserPreference() error = %v, want ErrPreferenceNotFound", err)
+		}
+
+		if err := store.DeleteUserPreference(ctx, user.UID, "nope"); !errors.Is(err, ErrPreferenceNotFound) {
+			t.Errorf("DeleteUserPreference() error = %v, want ErrPreferenceNotFound", err)
+		}
+	})
+
+	t.Run("cap on number of keys", func(t *testing.T) {
+		prefs, err := store.ListUserPreferences(ctx, user.UID)
+		if err != nil {
+			t.Fatalf("ListUserPreferences() error = %v", err)
+		}
+
+		for i := len(prefs); i < MaxUserPreferences; i++ {
+			if _, err := store.SetUserPreference(ctx, user.UID, fmt.Sprintf("k%03d", i), json.RawMessage(`1`)); err != nil {
+				t.Fatalf("SetUserPreference(%d) error = %v", i, err)
+			}
+		}
+
+		if _, err := store.SetUserPreference(ctx, user.UID, "one-too-many", json.RawMessage(`1`)); !errors.Is(err, ErrTooManyPreferences) {
+			t.Errorf("SetUserPreference() error = %v, want ErrTooManyPreferences", err)
+		}
+
+		// Updating an existing key is still allowed at the cap.
+		if _, err := store.SetUserPreference(ctx, user.UID, "layout", json.RawMessage(`null`)); err != nil {
+			t.Errorf("SetUserPreference() update at cap error = %v", err)
+		}
+	})
+}
@@ -77,13 +77,11 @@ func (s *Store) GetUserByUID(ctx context.Context, uid uuid.UUID) (*User, error)
 	return user, nil
 }
 
-// ListUsers retrieves all users
-func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
+// ListUsers retrieves all users matching the filter
+func (s *Store) ListUsers(ctx context.Context, filter UserFilter) ([]User, error) {
 	var users []User
-	err := s.db.NewSelect().
-		Model(&users).
-		Order("username ASC").
-		Scan(ctx)
+
+	err := s.selectUsers(&users, filter).Scan(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -93,6 +91,60 @@ func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
 	return users, nil
 }
 
+// CountUsers returns the number of users matching the filter, regardless of
+// its Limit and Offset.
+func (s *Store) CountUsers(ctx context.Context, filter UserFilter) (int, error) {
+	var users []User
+
+	n, err := s.selectUsers(&users, filter).Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return n, nil
+}
+
+// selectUsers builds the select of the users matching filter into dest.
+func (s *Store) selectUsers(dest *[]User, filter UserFilter) *bun.SelectQuery {
+	q := s.db.NewSelect().Model(dest)
+	q = whereLabels(q, "labels", filter.Labels)
+
+	return paginate(q.Order("username ASC"), filter.Limit, filter.Offset)
+}
+
+// UpgradePasswordHash rehashes a password with the configured algorithm when
+// its stored hash, currentHash, was produced with another algorithm or other
+// parameters. The caller must have verified password against currentHash.
+// The hash is only replaced if it did not change meanwhile, and
+// password_changed_at is left untouched. Reports whether it was replaced.
+func (s *Store) UpgradePasswordHash(ctx context.Context, uid uuid.UUID, currentHash, password string) (bool, error) {
+	if !crypto.NeedsRehash(currentHash) {
+		return false, nil
+	}
+
+	newHash, err := crypto.HashPassword(password)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := s.db.NewUpdate().
+		Model((*User)(nil)).
+		Set("password_hash = ?", newHash).
+		Where("uid = ?", uid).
+		Where("password_hash = ?", currentHash).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to upgrade password hash: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
 // CountAdmins returns the number of users holding the admin role
 func (s *Store) CountAdmins(ctx context.Context) (int, error) {
 	count, err := s.db.NewSelect().
@@ -121,6 +173,10 @@ func (s *Store) UpdateUser(ctx context.Context, uid uuid.UUID, updates UserUpdat
 		q = q.Set("roles = ?", pgdialect.Array(updates.Roles))
 	}
 
+	if updates.Labels != nil {
+		q = q.Set("labels = ?::jsonb", updates.Labels.jsonb())
+	}
+
 	result, err := q.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
@@ -138,16 +194,14 @@ func (s *Store) UpdateUser(ctx context.Context, uid uuid.UUID, updates UserUpdat
 	return nil
 }
 
-// DeleteUser deletes a user and all of their linked OAuth identities.
-func (s *Store) DeleteUser(ctx context.Context, uid uuid.UUID) error {
-	return s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
-		if _, err := tx.NewDelete().
-			Model((*UserIdentity)(nil)).
-			Where("user_id = ?", uid).
-			Exec(ctx); err != nil {
-			return fmt.Errorf("failed to delete user identities: %w", err)
-		}
+// DeleteUser soft-deletes a user on behalf of deletedBy. Their grants and API
+// keys are revoked and their pending grant requests cancelled; identities,
+// group memberships and preferences are removed. Connections, queries and
+// audit events are kept and still reference the soft-deleted user row.
+func (s *Store) DeleteUser(ctx context.Context, uid, deletedBy uuid.UUID) (*DeletionCascade, error) {
+	cascade := &DeletionCascade{}
 
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
 		result, err := tx.NewDelete().
 			Model((*User)(nil)).
 			Where("uid = ?", uid).
@@ -165,8 +219,60 @@ func (s *Store) DeleteUser(ctx context.Context, uid uuid.UUID) error {
 			return ErrUserNotFound
 		}
 
+		now := time.Now()
+
+		if cascade.RevokedGrants, err = revokeActiveGrants(ctx, tx, "user_id", uid, deletedBy, now); err != nil {
+			return err
+		}
+
+		keys, err := tx.NewUpdate().
+			Model((*APIKey)(nil)).
+			Set("revoked_at = ?", now).
+			Set("revoked_by = ?", actorOrNull(deletedBy)).
+			Where("user_id = ?", uid).
+			Where("revoked_at IS NULL").
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to revoke API keys: %w", err)
+		}
+
+		revokedKeys, err := keys.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		cascade.RevokedAPIKeys = int(revokedKeys)
+
+		cascade.CancelledRequests, err = cancelPendingGrantRequests(
+			ctx, tx, "user_id", uid, deletedBy, cancelReasonUserDeleted, now)
+		if err != nil {
+			return err
+		}
+
+		for _, model := range []struct {
+			table  any
+			column string
+			what   string
+		}{
+			{(*UserIdentity)(nil), "user_id", "user identities"},
+			{(*UserGroupMember)(nil), "user_uid", "group memberships"},
+			{(*UserPreference)(nil), "user_id", "user preferences"},
+		} {
+			if _, err := tx.NewDelete().
+				Model(model.table).
+				Where("? = ?", bun.Ident(model.column), uid).
+				Exec(ctx); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", model.what, err)
+			}
+		}
+
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cascade, nil
 }
 
 // EnsureUserOracleSalts returns the user's shared O5LOGON salts, generating and
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/crypto"
 )
 
 func TestCreateUser(t *testing.T) {
@@ -165,7 +167,7 @@ func TestListUsers(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("empty list", func(t *testing.T) {
-		users, err := store.ListUsers(ctx)
+		users, err := store.ListUsers(ctx, UserFilter{})
 		if err != nil {
 			t.Fatalf("ListUsers() error = %v", err)
 		}
@@ -185,7 +187,7 @@ func TestListUsers(t *testing.T) {
 	}
 
 	t.Run("with users", func(t *testing.T) {
-		users, err := store.ListUsers(ctx)
+		users, err := store.ListUsers(ctx, UserFilter{})
 		if err != nil {
 			t.Fatalf("ListUsers() error = %v", err)
 		}
@@ -201,6 +203,26 @@ func TestListUsers(t *testing.T) {
 			t.Errorf("ListUsers()[1].Username = %q, want %q", users[1].Username, "bob")
 		}
 	})
+
+	t.Run("paginated", func(t *testing.T) {
+		filter := UserFilter{Limit: 1, Offset: 1}
+
+		users, err := store.ListUsers(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		if len(users) != 1 || users[0].Username != "bob" {
+			t.Errorf("ListUsers(limit 1, offset 1) = %v, want [bob]", users)
+		}
+
+		n, err := store.CountUsers(ctx, filter)
+		if err != nil {
+			t.Fatalf("CountUsers() error = %v", err)
+		}
+		if n != 2 {
+			t.Errorf("CountUsers() = %d, want 2", n)
+		}
+	})
 }
 
 func TestUpdateUser(t *testing.T) {
@@ -280,6 +302,59 @@ func TestUpdateUser(t *testing.T) {
 	})
 }
 
+func TestUpgradePasswordHash(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	// A bcrypt hash, while the default algorithm is argon2id.
+	params := crypto.DefaultHashParams()
+	params.Algorithm = crypto.HashAlgorithmBcrypt
+	params.BcryptCost = 4
+
+	oldHash, err := crypto.HashPasswordWithParams("s3cret", params)
+	if err != nil {
+		t.Fatalf("HashPasswordWithParams() error = %v", err)
+	}
+
+	created, err := store.CreateUser(ctx, "legacyhash", oldHash, []string{RoleConnector})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	upgraded, err := store.UpgradePasswordHash(ctx, created.UID, oldHash, "s3cret")
+	if err != nil || !upgraded {
+		t.Fatalf("UpgradePasswordHash() = %v, %v; want true", upgraded, err)
+	}
+
+	user, err := store.GetUserByUID(ctx, created.UID)
+	if err != nil {
+		t.Fatalf("GetUserByUID() error = %v", err)
+	}
+
+	if crypto.HashAlgorithm(user.PasswordHash) != crypto.HashAlgorithmArgon2id {
+		t.Errorf("PasswordHash = %q, want an argon2id hash", user.PasswordHash)
+	}
+
+	if ok, _ := crypto.VerifyPassword(user.PasswordHash, "s3cret"); !ok {
+		t.Error("upgraded hash does not verify the password")
+	}
+
+	if user.PasswordChangedAt != nil {
+		t.Errorf("PasswordChangedAt = %v, want unchanged", user.PasswordChangedAt)
+	}
+
+	// The hash changed meanwhile: the stale one is not applied.
+	upgraded, err = store.UpgradePasswordHash(ctx, created.UID, oldHash, "s3cret")
+	if err != nil || upgraded {
+		t.Errorf("UpgradePasswordHash(stale hash) = %v, %v; want false", upgraded, err)
+	}
+
+	upgraded, err = store.UpgradePasswordHash(ctx, created.UID, user.PasswordHash, "s3cret")
+	if err != nil || upgraded {
+		t.Errorf("UpgradePasswordHash(current hash) = %v, %v; want false", upgraded, err)
+	}
+}
+
 func TestDeleteUser(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -291,7 +366,7 @@ func TestDeleteUser(t *testing.T) {
 	}
 
 	t.Run("delete existing user", func(t *testing.T) {
-		err := store.DeleteUser(ctx, created.UID)
+		_, err := store.DeleteUser(ctx, created.UID, uuid.Nil)
 		if err != nil {
 			t.Fatalf("DeleteUser() error = %v", err)
 		}
@@ -304,7 +379,7 @@ func TestDeleteUser(t *testing.T) {
 	})
 
 	t.Run("delete non-existing user", func(t *testing.T) {
-		err := store.DeleteUser(ctx, uuid.New())
+		_, err := store.DeleteUser(ctx, uuid.New(), uuid.Nil)
 		if !errors.Is(err, ErrUserNotFound) {
 			t.Errorf("DeleteUser() error = %v, want %v", err, ErrUserNotFound)
 		}
@@ -332,7 +407,7 @@ func TestDeleteUserCascadesToIdentities(t *testing.T) {
 		t.Fatalf("CreateUserIdentity() error = %v", err)
 	}
 
-	if err := s.DeleteUser(ctx, user.UID); err != nil {
+	if _, err := s.DeleteUser(ctx, user.UID, uuid.Nil); err != nil {
 		t.Fatalf("DeleteUser() error = %v", err)
 	}
 
@@ -0,0 +1,257 @@
+// Package tail follows the query log from the storage database and prints
+// new entries to a terminal, for `dbbat tail`.
+package tail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fclairamb/dbbat/internal/sqlnorm"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// pollBatch bounds the queries fetched per poll; a full batch is followed by
+// an immediate poll so bursts are drained without waiting for the interval.
+const pollBatch = 500
+
+// ANSI escape sequences used when color is enabled.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// slowQuery is the duration from which a query's timing is highlighted.
+const slowQuery = time.Second
+
+// Options selects and formats the followed queries.
+type Options struct {
+	DatabaseID  *uuid.UUID
+	UserID      *uuid.UUID
+	ErrorsOnly  bool
+	MinDuration time.Duration
+	// Backlog is how many past queries are printed before following.
+	Backlog  int
+	Interval time.Duration
+	Color    bool
+	// Redact masks literal values, like the API does without sql:raw.
+	Redact bool
+}
+
+// Follower polls the query log and writes matching queries, oldest first.
+type Follower struct {
+	store *store.Store
+	opts  Options
+	out   io.Writer
+
+	// users and servers cache the names printed for each query.
+	users   map[uuid.UUID]string
+	servers map[uuid.UUID]*store.Server
+}
+
+// New creates a follower writing to out.
+func New(dataStore *store.Store, opts Options, out io.Writer) *Follower {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+
+	return &Follower{
+		store:   dataStore,
+		opts:    opts,
+		out:     out,
+		users:   make(map[uuid.UUID]string),
+		servers: make(map[uuid.UUID]*store.Server),
+	}
+}
+
+// Run prints the backlog then follows the log until ctx is done. Queries are
+// read by UID (UUIDv7, so time-ordered): a query whose insert commits after a
+// newer one was already printed is skipped.
+func (f *Follower) Run(ctx context.Context) error {
+	cursor, err := f.printBacklog(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(f.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		for {
+			queries, err := f.store.ListQueries(ctx, f.filter(&cursor, pollBatch))
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return nil
+				}
+
+				return fmt.Errorf("failed to poll queries: %w", err)
+			}
+
+			for i := range queries {
+				f.print(ctx, &queries[i])
+				cursor = queries[i].UID
+			}
+
+			if len(queries) < pollBatch {
+				break
+			}
+		}
+	}
+}
+
+// printBacklog prints the most recent queries and returns the cursor to
+// follow from.
+func (f *Follower) printBacklog(ctx context.Context) (uuid.UUID, error) {
+	// A UUIDv7 for now: anything logged from here on sorts after it.
+	cursor, err := uuid.NewV7()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create cursor: %w", err)
+	}
+
+	if f.opts.Backlog <= 0 {
+		return cursor, nil
+	}
+
+	queries, err := f.store.ListQueries(ctx, f.filter(nil, f.opts.Backlog))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to list queries: %w", err)
+	}
+
+	slices.Reverse(queries)
+
+	for i := range queries {
+		f.print(ctx, &queries[i])
+	}
+
+	if len(queries) > 0 {
+		cursor = queries[len(queries)-1].UID
+	}
+
+	return cursor, nil
+}
+
+func (f *Follower) filter(after *uuid.UUID, limit int) store.QueryFilter {
+	return store.QueryFilter{
+		UserID:     f.opts.UserID,
+		DatabaseID: f.opts.DatabaseID,
+		AfterUID:   after,
+		Limit:      limit,
+	}
+}
+
+// print writes a query if it passes the error and duration filters.
+func (f *Follower) print(ctx context.Context, q *store.Query) {
+	if f.opts.ErrorsOnly && q.Error == nil {
+		return
+	}
+
+	if f.opts.MinDuration > 0 && (q.DurationMs == nil || *q.DurationMs < float64(f.opts.MinDuration.Milliseconds())) {
+		return
+	}
+
+	user, srv := f.resolve(ctx, q)
+
+	if f.opts.Redact {
+		q.SQLText = sqlnorm.Normalize(q.SQLText, srv.Protocol)
+	}
+
+	_, _ = fmt.Fprintln(f.out, formatQuery(q, user, srv.Name, f.opts.Color))
+}
+
+// resolve returns the user name and server of a query, falling back to
+// short UIDs when they were deleted.
+func (f *Follower) resolve(ctx context.Context, q *store.Query) (string, *store.Server) {
+	user, srv := "?", &store.Server{Name: "?"}
+
+	if q.UserID != nil {
+		name, ok := f.users[*q.UserID]
+		if !ok {
+			name = q.UserID.String()[:8]
+			if u, err := f.store.GetUserByUID(ctx, *q.UserID); err == nil {
+				name = u.Username
+			}
+
+			f.users[*q.UserID] = name
+		}
+
+		user = name
+	}
+
+	if q.DatabaseID != nil {
+		s, ok := f.servers[*q.DatabaseID]
+		if !ok {
+			s = &store.Server{Name: q.DatabaseID.String()[:8]}
+			if found, err := f.store.GetServerByUID(ctx, *q.DatabaseID); err == nil {
+				s = found
+			}
+
+			f.servers[*q.DatabaseID] = s
+		}
+
+		srv = s
+	}
+
+	return user, srv
+}
+
+// formatQuery renders one query on a single line:
+//
+//	2026-10-15 08:00:00.123 alice@warehouse 12.5ms 3 rows SELECT ...
+func formatQuery(q *store.Query, user, database string, color bool) string {
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+
+		return code + s + ansiReset
+	}
+
+	var b strings.Builder
+
+	b.WriteString(paint(ansiDim, q.ExecutedAt.Local().Format("2006-01-02 15:04:05.000")))
+	b.WriteString(" ")
+	b.WriteString(paint(ansiCyan, user+"@"+database))
+
+	if q.DurationMs != nil {
+		duration := fmt.Sprintf("%.1fms", *q.DurationMs)
+		if *q.DurationMs >= float64(slowQuery.Milliseconds()) {
+			duration = paint(ansiYellow, duration)
+		}
+
+		b.WriteString(" ")
+		b.WriteString(duration)
+	}
+
+	if q.RowsAffected != nil {
+		fmt.Fprintf(&b, " %d rows", *q.RowsAffected)
+	}
+
+	b.WriteString(" ")
+
+	sql := strings.Join(strings.Fields(q.SQLText), " ")
+	if q.Error != nil {
+		b.WriteString(paint(ansiRed, sql))
+		b.WriteString(" ")
+		b.WriteString(paint(ansiRed, "ERROR: "+*q.Error))
+	} else {
+		b.WriteString(paint(ansiGreen, sql))
+	}
+
+	return b.String()
+}
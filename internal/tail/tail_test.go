@@ -0,0 +1,65 @@
+package tail
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+func TestFormatQuery(t *testing.T) {
+	t.Parallel()
+
+	executedAt := time.Date(2026, 10, 15, 8, 0, 0, 123_000_000, time.UTC)
+	stamp := executedAt.Local().Format("2006-01-02 15:04:05.000")
+	duration := 12.5
+	slow := 2500.0
+	rows := int64(3)
+	errMsg := "relation \"nope\" does not exist"
+
+	tests := []struct {
+		name  string
+		query store.Query
+		color bool
+		want  string
+	}{
+		{
+			name: "plain",
+			query: store.Query{
+				ExecutedAt:   executedAt,
+				DurationMs:   &duration,
+				RowsAffected: &rows,
+				SQLText:      "SELECT *\n  FROM users\n  WHERE id = 1",
+			},
+			want: stamp + " alice@warehouse 12.5ms 3 rows SELECT * FROM users WHERE id = 1",
+		},
+		{
+			name:  "error",
+			query: store.Query{ExecutedAt: executedAt, SQLText: "SELECT * FROM nope", Error: &errMsg},
+			want:  stamp + ` alice@warehouse SELECT * FROM nope ERROR: relation "nope" does not exist`,
+		},
+		{
+			name:  "colored slow query",
+			query: store.Query{ExecutedAt: executedAt, DurationMs: &slow, SQLText: "SELECT pg_sleep(2.5)"},
+			color: true,
+			want: ansiDim + stamp + ansiReset + " " + ansiCyan + "alice@warehouse" + ansiReset + " " +
+				ansiYellow + "2500.0ms" + ansiReset + " " + ansiGreen + "SELECT pg_sleep(2.5)" + ansiReset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := formatQuery(&tt.query, "alice", "warehouse", tt.color)
+			if got != tt.want {
+				t.Errorf("formatQuery() = %q, want %q", got, tt.want)
+			}
+
+			if !tt.color && strings.Contains(got, "\x1b[") {
+				t.Errorf("formatQuery() without color contains escape sequences: %q", got)
+			}
+		})
+	}
+}
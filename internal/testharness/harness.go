@@ -0,0 +1,319 @@
+// Package testharness runs dbbat end-to-end against real databases for
+// integration tests: a storage PostgreSQL and a target PostgreSQL in
+// containers, a store with a user, server and grant, and a started proxy.
+// Tests connect through the proxy with pgx and assert on what was captured.
+//
+// It needs Docker. Tests using it carry the integration build tag:
+//
+//	go test -tags integration ./internal/testharness/
+package testharness
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/fclairamb/dbbat/internal/config"
+	"github.com/fclairamb/dbbat/internal/crypto"
+	"github.com/fclairamb/dbbat/internal/proxy/postgresql"
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// Default images; PG_TEST_IMAGE and DBBAT_STORE_TEST_IMAGE override them, as
+// for the proxy integration suites.
+const (
+	DefaultTargetImage  = "postgres:16-alpine"
+	DefaultStorageImage = "postgres:15-alpine"
+)
+
+// Credentials of the containers and of the dbbat user the harness creates.
+const (
+	Username       = "dbbattest"
+	Password       = "dbbattest"
+	TargetDatabase = "testdb"
+
+	containerUser     = "postgres"
+	containerPassword = "postgres"
+	startupTimeout    = 120 * time.Second
+)
+
+// waitTimeout bounds how long the Wait* helpers poll the store: queries are
+// logged once their results are complete, slightly after the client sees them.
+const waitTimeout = 5 * time.Second
+
+// Options tweaks what New builds. The zero value is a plain grant with
+// result capture enabled.
+type Options struct {
+	// Controls of the initial grant (read_only, block_copy, ...).
+	Controls []string
+	// QueryStorage overrides the result capture settings.
+	QueryStorage *config.QueryStorageConfig
+	// PG configures the proxy listener (TLS, ...).
+	PG config.PGConfig
+}
+
+// Harness is a running dbbat proxy in front of a target PostgreSQL.
+type Harness struct {
+	T      testing.TB
+	Store  *store.Store
+	Proxy  *postgresql.Server
+	User   *store.User
+	Server *store.Server
+	EncKey []byte
+
+	// TargetDSN connects to the target database directly, bypassing dbbat,
+	// for fixtures and for checking side effects.
+	TargetDSN string
+}
+
+// New starts the containers, the store and the proxy. Everything is torn
+// down by t.Cleanup.
+func New(t testing.TB, opts Options) *Harness {
+	t.Helper()
+
+	ctx := context.Background()
+
+	targetHost, targetPort := StartPostgres(ctx, t, envOr("PG_TEST_IMAGE", DefaultTargetImage), TargetDatabase)
+	storeHost, storePort := StartPostgres(ctx, t, envOr("DBBAT_STORE_TEST_IMAGE", DefaultStorageImage), "dbbat_test")
+
+	dataStore, err := store.New(ctx, postgresDSN(storeHost, storePort, "dbbat_test"))
+	require.NoError(t, err)
+	t.Cleanup(func() { dataStore.Close() })
+
+	hash, err := crypto.HashPassword(Password)
+	require.NoError(t, err)
+
+	user, err := dataStore.CreateUser(ctx, Username, hash, []string{store.RoleConnector})
+	require.NoError(t, err)
+
+	encKey := make([]byte, 32)
+	for i := range encKey {
+		encKey[i] = byte(i + 1)
+	}
+
+	srv, err := dataStore.CreateServer(ctx, &store.Server{
+		Name:         TargetDatabase,
+		Host:         targetHost,
+		Port:         targetPort,
+		DatabaseName: TargetDatabase,
+		Username:     containerUser,
+		Password:     containerPassword,
+		Protocol:     store.ProtocolPostgreSQL,
+		SSLMode:      "disable",
+	}, encKey)
+	require.NoError(t, err)
+
+	queryStorage := config.QueryStorageConfig{
+		StoreResults:   true,
+		MaxResultRows:  1000,
+		MaxResultBytes: 1024 * 1024,
+	}
+	if opts.QueryStorage != nil {
+		queryStorage = *opts.QueryStorage
+	}
+
+	proxy, err := postgresql.NewServer(dataStore, encKey, queryStorage, config.DumpConfig{}, nil,
+		opts.PG, config.ProxyProtocolConfig{}, config.SessionConfig{}, slog.Default())
+	require.NoError(t, err)
+
+	go func() { _ = proxy.Start("127.0.0.1:0") }()
+
+	t.Cleanup(func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = proxy.Shutdown(shutdownCtx)
+	})
+
+	require.Eventually(t, func() bool { return proxy.Addr() != nil },
+		5*time.Second, 50*time.Millisecond, "proxy never started listening")
+
+	h := &Harness{
+		T:         t,
+		Store:     dataStore,
+		Proxy:     proxy,
+		User:      user,
+		Server:    srv,
+		EncKey:    encKey,
+		TargetDSN: postgresDSN(targetHost, targetPort, TargetDatabase),
+	}
+
+	h.SetControls(ctx, opts.Controls...)
+
+	return h
+}
+
+// StartPostgres runs a PostgreSQL container and returns its mapped address.
+func StartPostgres(ctx context.Context, t testing.TB, image, dbName string) (string, int) {
+	t.Helper()
+
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        image,
+			ExposedPorts: []string{"5432/tcp"},
+			Env: map[string]string{
+				"POSTGRES_DB":       dbName,
+				"POSTGRES_USER":     containerUser,
+				"POSTGRES_PASSWORD": containerPassword,
+			},
+			WaitingFor: wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(startupTimeout),
+		},
+		Started: true,
+	})
+	require.NoError(t, err, "start postgres container (%s)", image)
+	t.Cleanup(func() { _ = c.Terminate(context.Background()) })
+
+	host, err := c.Host(ctx)
+	require.NoError(t, err)
+
+	port, err := c.MappedPort(ctx, "5432")
+	require.NoError(t, err)
+
+	return host, int(port.Num())
+}
+
+// DSN returns a client DSN pointing at the proxy.
+func (h *Harness) DSN(sslMode string) string {
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
+		Username, Password, h.Proxy.Addr().String(), TargetDatabase, sslMode)
+}
+
+// Connect opens a connection through the proxy, closed at cleanup.
+func (h *Harness) Connect(ctx context.Context) *pgx.Conn {
+	h.T.Helper()
+
+	conn, err := pgx.Connect(ctx, h.DSN("disable"))
+	require.NoError(h.T, err)
+	h.T.Cleanup(func() { _ = conn.Close(context.Background()) })
+
+	return conn
+}
+
+// ConnectTarget opens a direct connection to the target database, closed at
+// cleanup. Nothing done through it is logged by dbbat.
+func (h *Harness) ConnectTarget(ctx context.Context) *pgx.Conn {
+	h.T.Helper()
+
+	conn, err := pgx.Connect(ctx, h.TargetDSN)
+	require.NoError(h.T, err)
+	h.T.Cleanup(func() { _ = conn.Close(context.Background()) })
+
+	return conn
+}
+
+// SetControls revokes the user's active grants and installs a new one with
+// the given controls. Sessions opened afterwards use it.
+func (h *Harness) SetControls(ctx context.Context, controls ...string) {
+	h.T.Helper()
+
+	grants, err := h.Store.ListGrants(ctx, store.GrantFilter{UserID: &h.User.UID, ActiveOnly: true})
+	require.NoError(h.T, err)
+
+	for _, g := range grants {
+		require.NoError(h.T, h.Store.RevokeGrant(ctx, g.UID, h.User.UID))
+	}
+
+	if controls == nil {
+		controls = []string{}
+	}
+
+	_, err = h.Store.CreateGrant(ctx, &store.Grant{
+		UserID:     h.User.UID,
+		DatabaseID: h.Server.UID,
+		GrantedBy:  h.User.UID,
+		Controls:   controls,
+		StartsAt:   time.Now().Add(-time.Hour),
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+	})
+	require.NoError(h.T, err)
+}
+
+// Queries returns the logged queries of the harness user, oldest first.
+func (h *Harness) Queries(ctx context.Context) []store.Query {
+	h.T.Helper()
+
+	queries, err := h.Store.ListQueries(ctx, store.QueryFilter{UserID: &h.User.UID, Limit: 1000})
+	require.NoError(h.T, err)
+
+	// ListQueries returns the most recent first.
+	slices.Reverse(queries)
+
+	return queries
+}
+
+// WaitForQuery waits for a logged query whose SQL text contains substr
+// (case-insensitively) and returns the most recent one.
+func (h *Harness) WaitForQuery(ctx context.Context, substr string) *store.Query {
+	h.T.Helper()
+
+	var found *store.Query
+
+	require.Eventually(h.T, func() bool {
+		found = h.findQuery(ctx, substr)
+
+		return found != nil
+	}, waitTimeout, 100*time.Millisecond, "no logged query contains %q", substr)
+
+	return found
+}
+
+// RequireNoQuery fails the test if a query containing substr was logged.
+func (h *Harness) RequireNoQuery(ctx context.Context, substr string) {
+	h.T.Helper()
+
+	require.Nil(h.T, h.findQuery(ctx, substr), "unexpected logged query containing %q", substr)
+}
+
+func (h *Harness) findQuery(ctx context.Context, substr string) *store.Query {
+	queries, err := h.Store.ListQueries(ctx, store.QueryFilter{UserID: &h.User.UID, Limit: 1000})
+	if err != nil {
+		return nil
+	}
+
+	needle := strings.ToLower(substr)
+
+	for i := range queries {
+		if strings.Contains(strings.ToLower(queries[i].SQLText), needle) {
+			return &queries[i]
+		}
+	}
+
+	return nil
+}
+
+// Rows returns the captured result rows of a query.
+func (h *Harness) Rows(ctx context.Context, query *store.Query) []store.QueryRow {
+	h.T.Helper()
+
+	result, err := h.Store.GetQueryRows(ctx, query.UID, "", store.MaxQueryRowsLimit)
+	require.NoError(h.T, err)
+
+	return result.Rows
+}
+
+func postgresDSN(host string, port int, dbName string) string {
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable",
+		containerUser, containerPassword, net.JoinHostPort(host, strconv.Itoa(port)), dbName)
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+
+	return fallback
+}
@@ -0,0 +1,92 @@
+//go:build integration
+
+package testharness
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fclairamb/dbbat/internal/store"
+)
+
+// TestHarness_ReadOnlyEnforcement checks a read_only grant lets reads
+// through, refuses writes, and that the refused write never reached the
+// target.
+func TestHarness_ReadOnlyEnforcement(t *testing.T) {
+	ctx := context.Background()
+	h := New(t, Options{Controls: []string{store.ControlReadOnly}})
+
+	target := h.ConnectTarget(ctx)
+	_, err := target.Exec(ctx, "CREATE TABLE ro (id int); INSERT INTO ro VALUES (1)")
+	require.NoError(t, err)
+
+	conn := h.Connect(ctx)
+
+	var count int
+	require.NoError(t, conn.QueryRow(ctx, "SELECT count(*) FROM ro").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	_, err = conn.Exec(ctx, "INSERT INTO ro VALUES (2)")
+	require.Error(t, err, "insert must be refused under a read-only grant")
+
+	require.NoError(t, target.QueryRow(ctx, "SELECT count(*) FROM ro").Scan(&count))
+	assert.Equal(t, 1, count, "refused insert must not reach the target")
+
+	h.WaitForQuery(ctx, "SELECT count(*) FROM ro")
+}
+
+// TestHarness_CopyCapture checks COPY in both directions is logged with its
+// format and direction, and COPY TO rows are captured.
+func TestHarness_CopyCapture(t *testing.T) {
+	ctx := context.Background()
+	h := New(t, Options{})
+
+	conn := h.Connect(ctx)
+
+	_, err := conn.Exec(ctx, "CREATE TABLE cp (id int, label text)")
+	require.NoError(t, err)
+
+	_, err = conn.PgConn().CopyFrom(ctx, strings.NewReader("1,one\n2,two\n"), "COPY cp FROM STDIN WITH (FORMAT csv)")
+	require.NoError(t, err)
+
+	var out strings.Builder
+	_, err = conn.PgConn().CopyTo(ctx, &out, "COPY cp TO STDOUT WITH (FORMAT csv)")
+	require.NoError(t, err)
+	assert.Equal(t, "1,one\n2,two\n", out.String())
+
+	in := h.WaitForQuery(ctx, "COPY cp FROM STDIN")
+	require.NotNil(t, in.CopyDirection)
+	assert.Equal(t, "in", *in.CopyDirection)
+
+	copyOut := h.WaitForQuery(ctx, "COPY cp TO STDOUT")
+	require.NotNil(t, copyOut.CopyDirection)
+	assert.Equal(t, "out", *copyOut.CopyDirection)
+	require.NotNil(t, copyOut.CopyFormat)
+	assert.Equal(t, "text", *copyOut.CopyFormat, "CSV travels in the text wire format")
+	assert.Len(t, h.Rows(ctx, copyOut), 2)
+}
+
+// TestHarness_ExtendedProtocol checks a Parse/Bind/Execute round-trip is
+// logged with its bind parameters and result rows.
+func TestHarness_ExtendedProtocol(t *testing.T) {
+	ctx := context.Background()
+	h := New(t, Options{})
+
+	conn := h.Connect(ctx)
+
+	var label string
+	require.NoError(t, conn.QueryRow(ctx, "SELECT $1::text AS label", "answer").Scan(&label))
+	assert.Equal(t, "answer", label)
+
+	query := h.WaitForQuery(ctx, "SELECT $1::text")
+	require.NotNil(t, query.Parameters)
+	assert.Equal(t, []string{"answer"}, query.Parameters.Values)
+
+	rows := h.Rows(ctx, query)
+	require.Len(t, rows, 1)
+	assert.Contains(t, string(rows[0].RowData), "answer")
+}
@@ -9,6 +9,8 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"syscall"
 	"time"
 
@@ -16,22 +18,35 @@ import (
 	"github.com/urfave/cli/v3"
 
 	"github.com/fclairamb/dbbat/internal/api"
+	"github.com/fclairamb/dbbat/internal/audit"
+	"github.com/fclairamb/dbbat/internal/auth/ldap"
 	"github.com/fclairamb/dbbat/internal/cache"
 	"github.com/fclairamb/dbbat/internal/config"
 	"github.com/fclairamb/dbbat/internal/crypto"
 	"github.com/fclairamb/dbbat/internal/dump"
+	"github.com/fclairamb/dbbat/internal/lineage"
+	"github.com/fclairamb/dbbat/internal/posture"
+	"github.com/fclairamb/dbbat/internal/proxy/conncheck"
 	"github.com/fclairamb/dbbat/internal/proxy/mongodb"
 	"github.com/fclairamb/dbbat/internal/proxy/mysql"
 	"github.com/fclairamb/dbbat/internal/proxy/oracle"
 	"github.com/fclairamb/dbbat/internal/proxy/postgresql"
+	"github.com/fclairamb/dbbat/internal/proxy/shared"
+	"github.com/fclairamb/dbbat/internal/queryalerts"
+	"github.com/fclairamb/dbbat/internal/queryplans"
+	"github.com/fclairamb/dbbat/internal/secrets"
+	"github.com/fclairamb/dbbat/internal/spill"
 	"github.com/fclairamb/dbbat/internal/store"
+	"github.com/fclairamb/dbbat/internal/tail"
+	"github.com/fclairamb/dbbat/internal/version"
 )
 
 const shutdownTimeout = 30 * time.Second
 
 // setupLogger creates the logger, optionally writing to a file in test mode.
 // Returns the logger and a cleanup function to close the log file (if any).
-func setupLogger(runMode config.RunMode, level slog.Level) (*slog.Logger, func()) {
+// A *slog.LevelVar level can be changed afterwards.
+func setupLogger(runMode config.RunMode, level slog.Leveler) (*slog.Logger, func()) {
 	var writer io.Writer = os.Stdout
 	var cleanup func()
 
@@ -73,6 +88,7 @@ type cliFlags struct {
 	dsn        string
 	key        string
 	keyFile    string
+	keyCommand string
 	configFile string
 	logLevel   string
 }
@@ -117,6 +133,11 @@ func CmdRun() {
 				Usage:       "Path to file containing encryption key",
 				Destination: &flags.keyFile,
 			},
+			&cli.StringFlag{
+				Name:        "key-command",
+				Usage:       "External shell command printing the encryption key",
+				Destination: &flags.keyCommand,
+			},
 			&cli.StringFlag{
 				Name:        "config",
 				Aliases:     []string{"c"},
@@ -145,7 +166,21 @@ func CmdRun() {
 					{
 						Name:  "migrate",
 						Usage: "Run pending migrations",
-						Action: func(ctx context.Context, _ *cli.Command) error {
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "List pending migrations without applying them",
+							},
+							&cli.BoolFlag{
+								Name:  "print-sql",
+								Usage: "Print the SQL of pending migrations to stdout instead of applying it",
+							},
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							if cmd.Bool("dry-run") || cmd.Bool("print-sql") {
+								return runMigratePlan(ctx, flags, cmd.Bool("print-sql"))
+							}
+
 							return runMigrate(ctx, flags)
 						},
 					},
@@ -163,6 +198,75 @@ func CmdRun() {
 							return runMigrationStatus(ctx, flags)
 						},
 					},
+					{
+						Name:  "compact",
+						Usage: "Fold the result rows of old queries into compressed blobs",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{
+								Name:  "older-than",
+								Usage: "Compact queries executed longer ago than this (default: query_storage.compact_after)",
+							},
+							&cli.IntFlag{
+								Name:  "batch-size",
+								Usage: "Queries compacted per pass",
+								Value: defaultCompactBatchSize,
+							},
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							return runCompact(ctx, flags, cmd.Duration("older-than"), cmd.Int("batch-size"))
+						},
+					},
+					{
+						Name:  "purge",
+						Usage: "Delete the queries, result rows, connections and audit events past the retention policy and the database row quotas",
+						Action: func(ctx context.Context, _ *cli.Command) error {
+							return runPurge(ctx, flags)
+						},
+					},
+				},
+			},
+			{
+				Name:  "tail",
+				Usage: "Follow the query log from the storage database",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "database",
+						Usage: "Only show queries on this database (name)",
+					},
+					&cli.StringFlag{
+						Name:  "user",
+						Usage: "Only show queries of this user (username)",
+					},
+					&cli.BoolFlag{
+						Name:  "errors",
+						Usage: "Only show failed queries",
+					},
+					&cli.DurationFlag{
+						Name:  "min-duration",
+						Usage: "Only show queries that took at least this long",
+					},
+					&cli.IntFlag{
+						Name:    "lines",
+						Aliases: []string{"n"},
+						Usage:   "Past queries to print before following",
+						Value:   defaultTailLines,
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Usage: "Polling interval",
+						Value: time.Second,
+					},
+					&cli.BoolFlag{
+						Name:  "redact",
+						Usage: "Replace literal values with placeholders",
+					},
+					&cli.BoolFlag{
+						Name:  "no-color",
+						Usage: "Disable colors (also disabled when NO_COLOR is set or stdout is not a terminal)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runTail(ctx, flags, cmd)
 				},
 			},
 			{
@@ -216,6 +320,9 @@ func buildCLIOverrides(flags *cliFlags) func(*config.Config) {
 		if flags.keyFile != "" {
 			cfg.KeyFile = flags.keyFile
 		}
+		if flags.keyCommand != "" {
+			cfg.KeyCommand = flags.keyCommand
+		}
 		if flags.configFile != "" {
 			cfg.ConfigFile = flags.configFile
 		}
@@ -230,7 +337,25 @@ func loadConfigWithCLI(flags *cliFlags) (*config.Config, error) {
 	opts := config.LoadOptions{
 		ConfigFile: flags.configFile,
 	}
-	return config.Load(opts, buildCLIOverrides(flags))
+
+	cfg, err := config.Load(opts, buildCLIOverrides(flags))
+	if err != nil {
+		return nil, err
+	}
+
+	crypto.SetHashParams(hashParams(cfg))
+
+	return cfg, nil
+}
+
+// hashParams selects the algorithm new password and API key hashes use. The
+// Argon2id cost stays at the crypto package defaults.
+func hashParams(cfg *config.Config) crypto.HashParams {
+	params := crypto.DefaultHashParams()
+	params.Algorithm = cfg.Hash.Algorithm
+	params.BcryptCost = cfg.Hash.BcryptCost
+
+	return params
 }
 
 func runServer(ctx context.Context, flags *cliFlags) error {
@@ -240,8 +365,10 @@ func runServer(ctx context.Context, flags *cliFlags) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	// Setup logger with run mode and log level from config
-	logLevel := config.ParseLogLevel(cfg.LogLevel)
+	// Setup logger with run mode and log level from config; the level
+	// follows configuration reloads.
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(config.ParseLogLevel(cfg.LogLevel))
 	logger, logCleanup := setupLogger(cfg.RunMode, logLevel)
 	if logCleanup != nil {
 		defer logCleanup()
@@ -257,9 +384,8 @@ func runServer(ctx context.Context, flags *cliFlags) error {
 	)
 
 	// Initialize store (with table drop if in test or demo mode)
-	storeOpts := store.Options{
-		DropTablesFirst: cfg.RunMode == config.RunModeTest || cfg.RunMode == config.RunModeDemo,
-	}
+	storeOpts := storeOptions(cfg)
+	storeOpts.DropTablesFirst = cfg.RunMode == config.RunModeTest || cfg.RunMode == config.RunModeDemo
 	if cfg.RunMode == config.RunModeTest {
 		logger.InfoContext(ctx, "Test mode enabled, will drop all tables before migration")
 	}
@@ -277,9 +403,6 @@ func runServer(ctx context.Context, flags *cliFlags) error {
 
 	logger.InfoContext(ctx, "Server connection established")
 
-	// Check for database configurations that match the storage DSN
-	checkDatabaseConfigurations(ctx, dataStore, logger)
-
 	// Ensure default admin exists
 	defaultPassword := "admin"
 
@@ -294,20 +417,74 @@ func runServer(ctx context.Context, flags *cliFlags) error {
 
 	logger.InfoContext(ctx, "Default admin user ensured (username: admin, password: admin)")
 
-	// Provision test data if in test mode
+	// Provision test data if in test mode. Each provisioning runs in one
+	// transaction so a failure halfway does not leave a partial data set.
 	if cfg.RunMode == config.RunModeTest {
-		if err := provisionTestData(ctx, dataStore, cfg.EncryptionKey, logger); err != nil {
+		err := dataStore.WithTx(ctx, func(ctx context.Context, tx *store.Store) error {
+			return provisionTestData(ctx, tx, cfg.EncryptionKey, logger)
+		})
+		if err != nil {
 			return fmt.Errorf("failed to provision test data: %w", err)
 		}
 	}
 
 	// Provision demo data if in demo mode
 	if cfg.RunMode == config.RunModeDemo {
-		if err := provisionDemoData(ctx, dataStore, cfg, logger); err != nil {
+		err := dataStore.WithTx(ctx, func(ctx context.Context, tx *store.Store) error {
+			return provisionDemoData(ctx, tx, cfg, logger)
+		})
+		if err != nil {
 			return fmt.Errorf("failed to provision demo data: %w", err)
 		}
 	}
 
+	// Check the deployment for weak settings, now that the admin user and
+	// any provisioned data exist
+	if err := checkPosture(ctx, cfg, dataStore, logger); err != nil {
+		return err
+	}
+
+	// Register this instance before anything can record a connection
+	if err := registerInstance(ctx, cfg, dataStore, logger); err != nil {
+		return err
+	}
+
+	// Export lineage events (if configured); registered before the proxies
+	// start logging queries.
+	lineageExporter := startLineageExporter(ctx, cfg, dataStore, logger)
+
+	auditPipeline, err := startAuditPipeline(ctx, cfg, dataStore, logger)
+	if err != nil {
+		return err
+	}
+
+	// Check the LDAP configuration before anything authenticates with it
+	var directory *ldap.Directory
+	if cfg.LDAP.Enabled() {
+		if directory, err = ldap.New(cfg.LDAP); err != nil {
+			return fmt.Errorf("invalid LDAP configuration: %w", err)
+		}
+	}
+
+	// Upstream passwords referenced by a secret_ref are fetched by every
+	// proxy, and by the connectivity checks, through the process-wide resolver
+	secretsResolver, err := secrets.NewResolver(cfg.Secrets)
+	if err != nil {
+		return fmt.Errorf("invalid secrets configuration: %w", err)
+	}
+
+	secrets.SetDefault(secretsResolver)
+
+	// Results past the query storage limits are spilled by the PostgreSQL
+	// and MySQL proxies, and handed out by the API, through the process-wide
+	// spiller
+	spiller, err := spill.New(cfg.ResultSpill)
+	if err != nil {
+		return fmt.Errorf("invalid result spill configuration: %w", err)
+	}
+
+	spill.SetDefault(spiller)
+
 	// Start API server
 	apiServer := api.NewServer(dataStore, cfg.EncryptionKey, logger, cfg)
 
@@ -327,8 +504,13 @@ func runServer(ctx context.Context, flags *cliFlags) error {
 		MaxSize:    cfg.AuthCache.MaxSize,
 	})
 
+	// Directory users authenticate to the proxies with their LDAP password
+	if directory != nil {
+		proxyAuthCache.SetDirectory(directory)
+	}
+
 	// Start proxy server
-	proxyServer, err := postgresql.NewServer(dataStore, cfg.EncryptionKey, cfg.QueryStorage, cfg.Dump, proxyAuthCache, cfg.PG, logger)
+	proxyServer, err := postgresql.NewServer(dataStore, cfg.EncryptionKey, cfg.QueryStorage, cfg.Dump, proxyAuthCache, cfg.PG, cfg.ProxyProtocol, cfg.Session, logger)
 	if err != nil {
 		logger.ErrorContext(ctx, "PostgreSQL proxy server init failed", slog.Any("error", err))
 		os.Exit(1)
@@ -343,7 +525,9 @@ func runServer(ctx context.Context, flags *cliFlags) error {
 
 	logger.InfoContext(ctx, "Proxy server started",
 		slog.String("addr", cfg.ListenPG),
-		slog.Bool("tls", !cfg.PG.TLS.Disable))
+		slog.Bool("tls", !cfg.PG.TLS.Disable),
+		slog.Bool("proxy_protocol", cfg.ProxyProtocol.Enabled),
+		slog.Int("additional_listeners", len(cfg.PG.Listeners)))
 
 	// Start Oracle proxy server (if configured)
 	oracleServer := startOracleProxy(ctx, cfg, dataStore, proxyAuthCache, logger)
@@ -354,19 +538,92 @@ func runServer(ctx context.Context, flags *cliFlags) error {
 	// Start MongoDB proxy server (if configured)
 	mongoServer := startMongoProxy(ctx, cfg, dataStore, proxyAuthCache, logger)
 
+	// Apply configuration reloads (SIGHUP or POST /api/v1/admin/reload)
+	reloader := &configReloader{
+		flags:     flags,
+		cfg:       cfg,
+		logger:    logger,
+		logLevel:  logLevel,
+		api:       apiServer,
+		authCache: proxyAuthCache,
+		proxies:   []queryStorageSetter{proxyServer},
+	}
+
 	// Wait for shutdown signal and gracefully stop all servers
 	servers := []shutdownable{apiServer, proxyServer}
 	if oracleServer != nil {
 		servers = append(servers, oracleServer)
+		reloader.proxies = append(reloader.proxies, oracleServer)
 	}
 	if mysqlServer != nil {
 		servers = append(servers, mysqlServer)
+		reloader.proxies = append(reloader.proxies, mysqlServer)
 	}
 	if mongoServer != nil {
 		servers = append(servers, mongoServer)
+		reloader.proxies = append(reloader.proxies, mongoServer)
+	}
+
+	apiServer.SetConfigReloader(reloader.reload)
+	reloader.watchSignal(ctx)
+	// Last, so the queries logged while the proxies drain are still exported.
+	if lineageExporter != nil {
+		servers = append(servers, lineageExporter)
+	}
+	// After the servers, so the audit events they log while stopping are copied.
+	if auditPipeline != nil {
+		servers = append(servers, auditPipeline)
+	}
+	servers = append(servers, startRetentionJanitor(ctx, cfg, dataStore, logger))
+	servers = append(servers, startQueryAlertEvaluator(ctx, cfg, dataStore, logger))
+	servers = append(servers, startQueryPlanCollector(ctx, cfg, dataStore, logger))
+	if monitor := startHealthCheckMonitor(ctx, cfg, dataStore, logger); monitor != nil {
+		servers = append(servers, monitor)
+	}
+	// Last, so the instance stays live until everything else has stopped.
+	servers = append(servers, dataStore.StartInstanceHeartbeat(store.InstanceHeartbeatInterval, logger))
+
+	drainTimeout, _ := cfg.DrainWait()
+
+	return awaitShutdown(ctx, logger, drainTimeout, servers...)
+}
+
+// registerInstance records this process in the instance inventory with its
+// version and the addresses of its enabled listeners.
+func registerInstance(ctx context.Context, cfg *config.Config, dataStore *store.Store, logger *slog.Logger) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	listen := map[string]string{}
+
+	for name, addr := range map[string]string{
+		"api":    cfg.ListenAPI,
+		"pg":     cfg.ListenPG,
+		"oracle": cfg.ListenOracle,
+		"mysql":  cfg.ListenMySQL,
+		"mongo":  cfg.ListenMongo,
+	} {
+		if addr != "" {
+			listen[name] = addr
+		}
+	}
+
+	for _, listener := range cfg.PG.Listeners {
+		listen["pg:"+listener.DisplayName()] = listener.Addr
+	}
+
+	instance := &store.Instance{Hostname: hostname, Version: version.Version, Listen: listen}
+	if err := dataStore.RegisterInstance(ctx, instance); err != nil {
+		return err
 	}
 
-	return awaitShutdown(ctx, logger, servers...)
+	logger.InfoContext(ctx, "Instance registered",
+		slog.String("instance_id", instance.UID.String()),
+		slog.String("hostname", hostname))
+
+	return nil
 }
 
 // shutdownable is implemented by servers that support graceful shutdown.
@@ -374,12 +631,175 @@ type shutdownable interface {
 	Shutdown(ctx context.Context) error
 }
 
+// drainable is implemented by the proxies, which can stop accepting sessions
+// and wait for theirs to end before shutting down.
+type drainable interface {
+	Drain(ctx context.Context) error
+}
+
+// queryStorageSetter is implemented by the proxies, whose query storage
+// limits follow configuration reloads.
+type queryStorageSetter interface {
+	SetQueryStorage(cfg config.QueryStorageConfig)
+}
+
+// configReloader re-reads the configuration and applies the settings that can
+// change without a restart: rate limits, query storage limits, auth cache,
+// hash parameters and log level. The proxy sessions in progress are kept.
+type configReloader struct {
+	flags     *cliFlags
+	logger    *slog.Logger
+	logLevel  *slog.LevelVar
+	api       *api.Server
+	authCache *cache.AuthCache // The proxies' auth cache; the API has its own
+	proxies   []queryStorageSetter
+
+	mu  sync.Mutex
+	cfg *config.Config // The configuration in effect
+}
+
+// watchSignal reloads the configuration on every SIGHUP.
+func (r *configReloader) watchSignal(ctx context.Context) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		for range sigChan {
+			if _, err := r.reload(ctx); err != nil {
+				r.logger.ErrorContext(ctx, "Configuration reload failed, keeping the current one", slog.Any("error", err))
+			}
+		}
+	}()
+}
+
+// reload loads the configuration like at startup and applies what changed.
+// An invalid configuration is rejected as a whole. Changes to the other
+// settings are logged and wait for the next restart.
+func (r *configReloader) reload(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.Load(config.LoadOptions{ConfigFile: r.flags.configFile}, buildCLIOverrides(r.flags))
+	if err != nil {
+		return nil, err
+	}
+
+	old := r.cfg
+	changed := []string{}
+
+	if cfg.RateLimit != old.RateLimit || cfg.AuthCache != old.AuthCache ||
+		queryStorageLimits(cfg.QueryStorage) != queryStorageLimits(old.QueryStorage) {
+		r.api.Reconfigure(cfg)
+	}
+
+	if cfg.RateLimit != old.RateLimit {
+		changed = append(changed, "rate_limit")
+	}
+
+	if queryStorageLimits(cfg.QueryStorage) != queryStorageLimits(old.QueryStorage) {
+		for _, proxy := range r.proxies {
+			proxy.SetQueryStorage(cfg.QueryStorage)
+		}
+
+		changed = append(changed, "query_storage")
+	}
+
+	if cfg.AuthCache != old.AuthCache {
+		r.authCache.Reconfigure(cache.AuthCacheConfig{
+			Enabled:    cfg.AuthCache.Enabled,
+			TTLSeconds: cfg.AuthCache.TTLSeconds,
+			MaxSize:    cfg.AuthCache.MaxSize,
+		})
+
+		changed = append(changed, "auth_cache")
+	}
+
+	if hashParams(cfg) != hashParams(old) {
+		crypto.SetHashParams(hashParams(cfg))
+
+		changed = append(changed, "hash")
+	}
+
+	if level := config.ParseLogLevel(cfg.LogLevel); level != r.logLevel.Level() {
+		r.logLevel.Set(level)
+
+		changed = append(changed, "log_level")
+	}
+
+	// Whatever else differs once the applied settings are set aside.
+	rest := *cfg
+	rest.RateLimit = old.RateLimit
+	rest.AuthCache = old.AuthCache
+	rest.Hash = old.Hash
+	rest.LogLevel = old.LogLevel
+	rest.QueryStorage.StoreResults = old.QueryStorage.StoreResults
+	rest.QueryStorage.MaxResultRows = old.QueryStorage.MaxResultRows
+	rest.QueryStorage.MaxResultBytes = old.QueryStorage.MaxResultBytes
+
+	if !reflect.DeepEqual(&rest, old) {
+		r.logger.WarnContext(ctx, "Configuration changes other than rate limits, query storage limits, auth cache, hash and log level apply on restart")
+	}
+
+	r.cfg = cfg
+
+	r.logger.InfoContext(ctx, "Configuration reloaded", slog.Any("changed", changed))
+
+	return changed, nil
+}
+
+// queryStorageLimits returns the query storage settings the proxies apply on
+// reload; the others are read once, at startup.
+func queryStorageLimits(q config.QueryStorageConfig) config.QueryStorageConfig {
+	return config.QueryStorageConfig{
+		StoreResults:   q.StoreResults,
+		MaxResultRows:  q.MaxResultRows,
+		MaxResultBytes: q.MaxResultBytes,
+	}
+}
+
+// storeOptions maps the storage pool, replica, migration lock and query
+// logging configuration onto store.Options.
+// Durations were validated by config.Load.
+func storeOptions(cfg *config.Config) store.Options {
+	lifetime, _ := cfg.StoragePool.Lifetime()
+	idleTime, _ := cfg.StoragePool.IdleTime()
+	replicaLag, _ := cfg.ReplicaLag()
+	lockTimeout, _ := cfg.MigrationLockWait()
+	dedupWindow, _ := cfg.QueryStorage.DedupAge()
+
+	return store.Options{
+		MaxOpenConns:         cfg.StoragePool.MaxOpenConns,
+		MaxIdleConns:         cfg.StoragePool.MaxIdleConns,
+		ConnMaxLifetime:      lifetime,
+		ConnMaxIdleTime:      idleTime,
+		ReplicaDSN:           cfg.ReplicaDSN,
+		ReplicaMaxLag:        replicaLag,
+		MigrationLockTimeout: lockTimeout,
+		MaxSQLTextBytes:      cfg.QueryStorage.MaxSQLBytes,
+		QueryDedupWindow:     dedupWindow,
+		CompressRows:         cfg.QueryStorage.CompressRows,
+	}
+}
+
 // awaitShutdown waits for an OS interrupt signal and then gracefully shuts down all servers.
-func awaitShutdown(ctx context.Context, logger *slog.Logger, servers ...shutdownable) error {
+// A drain, requested with SIGUSR1 or through the API, first lets the proxy
+// sessions end for up to drainTimeout; an interrupt signal cuts it short.
+func awaitShutdown(ctx context.Context, logger *slog.Logger, drainTimeout time.Duration, servers ...shutdownable) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	<-sigChan
+	drainChan := make(chan os.Signal, 1)
+	signal.Notify(drainChan, syscall.SIGUSR1)
+
+	select {
+	case <-sigChan:
+	case <-drainChan:
+		shared.StartDraining()
+		drainServers(ctx, logger, drainTimeout, sigChan, servers)
+	case <-shared.DrainRequested():
+		drainServers(ctx, logger, drainTimeout, sigChan, servers)
+	}
+
 	logger.InfoContext(ctx, "Shutdown signal received, gracefully shutting down...")
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
@@ -396,12 +816,163 @@ func awaitShutdown(ctx context.Context, logger *slog.Logger, servers ...shutdown
 	return nil
 }
 
+// drainServers stops the proxies from accepting sessions and waits for theirs
+// to end, until drainTimeout or an interrupt signal.
+func drainServers(ctx context.Context, logger *slog.Logger, drainTimeout time.Duration, sigChan <-chan os.Signal, servers []shutdownable) {
+	logger.InfoContext(ctx, "Draining, waiting for the proxy sessions to end...",
+		slog.Duration("timeout", drainTimeout),
+		slog.Any("active_sessions", shared.ActiveSessions()))
+
+	drainCtx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-sigChan:
+			cancel()
+		case <-drainCtx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for _, srv := range servers {
+		if d, ok := srv.(drainable); ok {
+			wg.Go(func() {
+				if err := d.Drain(drainCtx); err != nil {
+					logger.WarnContext(ctx, "proxy drain incomplete", slog.Any("error", err))
+				}
+			})
+		}
+	}
+
+	wg.Wait()
+
+	logger.InfoContext(ctx, "Drain complete", slog.Any("active_sessions", shared.ActiveSessions()))
+}
+
+func startLineageExporter(ctx context.Context, cfg *config.Config, dataStore *store.Store, logger *slog.Logger) *lineage.Exporter {
+	if !cfg.Lineage.Enabled() {
+		return nil
+	}
+
+	exporter := lineage.NewExporter(dataStore, cfg.Lineage, logger)
+	dataStore.SetQueryObserver(exporter)
+	exporter.Start()
+
+	logger.InfoContext(ctx, "OpenLineage export enabled",
+		slog.String("url", cfg.Lineage.URL),
+		slog.String("namespace", cfg.Lineage.Namespace))
+
+	return exporter
+}
+
+func startAuditPipeline(ctx context.Context, cfg *config.Config, dataStore *store.Store, logger *slog.Logger) (*audit.Pipeline, error) {
+	if !cfg.Audit.Enabled() {
+		return nil, nil //nolint:nilnil // No sink configured is not an error
+	}
+
+	pipeline, err := audit.NewPipeline(cfg.Audit, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start audit pipeline: %w", err)
+	}
+
+	dataStore.SetAuditObserver(pipeline)
+	pipeline.Start()
+
+	logger.InfoContext(ctx, "Audit event sinks enabled", slog.Any("sinks", pipeline.Sinks()))
+
+	return pipeline, nil
+}
+
+// retentionPolicy maps the retention configuration onto store.RetentionPolicy.
+func retentionPolicy(cfg config.RetentionConfig) store.RetentionPolicy {
+	const day = 24 * time.Hour
+
+	return store.RetentionPolicy{
+		QueriesAge:     time.Duration(cfg.QueriesDays) * day,
+		RowsAge:        time.Duration(cfg.RowsDays) * day,
+		ConnectionsAge: time.Duration(cfg.ConnectionsDays) * day,
+		AuditAge:       time.Duration(cfg.AuditDays) * day,
+		MaxRowBytes:    cfg.MaxBytes,
+		BatchSize:      cfg.BatchSize,
+	}
+}
+
+// startRetentionJanitor starts the retention janitor. It always runs, as
+// databases may carry a row quota even when no retention limit is configured.
+func startRetentionJanitor(ctx context.Context, cfg *config.Config, dataStore *store.Store, logger *slog.Logger) *store.Janitor {
+	// Validated by config.Load.
+	interval, _ := cfg.Retention.RunInterval()
+
+	if !cfg.Retention.Enabled() {
+		logger.DebugContext(ctx, "Retention janitor enforcing database row quotas only",
+			slog.Duration("interval", interval))
+
+		return dataStore.StartJanitor(retentionPolicy(cfg.Retention), interval, logger)
+	}
+
+	logger.InfoContext(ctx, "Retention janitor enabled",
+		slog.Int("queries_days", cfg.Retention.QueriesDays),
+		slog.Int("rows_days", cfg.Retention.RowsDays),
+		slog.Int("connections_days", cfg.Retention.ConnectionsDays),
+		slog.Int("audit_days", cfg.Retention.AuditDays),
+		slog.Int64("max_bytes", cfg.Retention.MaxBytes),
+		slog.Duration("interval", interval))
+
+	return dataStore.StartJanitor(retentionPolicy(cfg.Retention), interval, logger)
+}
+
+// startQueryAlertEvaluator starts the evaluation of query alerts. It always
+// runs: alerts are defined at runtime through the API.
+func startQueryAlertEvaluator(ctx context.Context, cfg *config.Config, dataStore *store.Store, logger *slog.Logger) *queryalerts.Evaluator {
+	// Validated by config.Load.
+	interval, _ := cfg.QueryAlerts.RunInterval()
+
+	logger.DebugContext(ctx, "Query alert evaluator started", slog.Duration("interval", interval))
+
+	evaluator := queryalerts.NewEvaluator(dataStore, interval, logger)
+	evaluator.Start()
+
+	return evaluator
+}
+
+// startQueryPlanCollector starts capturing the plans of slow queries. It
+// always runs: slow query thresholds are set per database through the API.
+func startQueryPlanCollector(ctx context.Context, cfg *config.Config, dataStore *store.Store, logger *slog.Logger) *queryplans.Collector {
+	logger.DebugContext(ctx, "Query plan collector started")
+
+	collector := queryplans.NewCollector(dataStore, cfg.EncryptionKey, logger)
+	collector.Start()
+
+	return collector
+}
+
+// startHealthCheckMonitor starts the background checks of the database
+// targets, unless disabled.
+func startHealthCheckMonitor(ctx context.Context, cfg *config.Config, dataStore *store.Store, logger *slog.Logger) *conncheck.Monitor {
+	// Validated by config.Load.
+	interval, _ := cfg.HealthChecks.RunInterval()
+	if interval == 0 {
+		logger.InfoContext(ctx, "Database health checks disabled")
+
+		return nil
+	}
+
+	logger.DebugContext(ctx, "Database health checks started", slog.Duration("interval", interval))
+
+	monitor := conncheck.NewMonitor(dataStore, cfg.EncryptionKey, interval, logger)
+	monitor.Start()
+
+	return monitor
+}
+
 func startOracleProxy(ctx context.Context, cfg *config.Config, dataStore *store.Store, authCache *cache.AuthCache, logger *slog.Logger) *oracle.Server {
 	if cfg.ListenOracle == "" {
 		return nil
 	}
 
-	srv := oracle.NewServer(dataStore, cfg.EncryptionKey, authCache, cfg.QueryStorage, cfg.Dump, logger)
+	srv := oracle.NewServer(dataStore, cfg.EncryptionKey, authCache, cfg.QueryStorage, cfg.Dump, cfg.ProxyProtocol, cfg.Session, logger)
 
 	go func() {
 		if err := srv.Start(cfg.ListenOracle); err != nil {
@@ -420,7 +991,7 @@ func startMySQLProxy(ctx context.Context, cfg *config.Config, dataStore *store.S
 		return nil
 	}
 
-	srv, err := mysql.NewServer(dataStore, cfg.EncryptionKey, cfg.QueryStorage, cfg.Dump, authCache, cfg.MySQL, logger)
+	srv, err := mysql.NewServer(dataStore, cfg.EncryptionKey, cfg.QueryStorage, cfg.Dump, authCache, cfg.MySQL, cfg.ProxyProtocol, cfg.Session, logger)
 	if err != nil {
 		logger.ErrorContext(ctx, "MySQL proxy server init failed", slog.Any("error", err))
 		os.Exit(1)
@@ -445,7 +1016,7 @@ func startMongoProxy(ctx context.Context, cfg *config.Config, dataStore *store.S
 		return nil
 	}
 
-	srv, err := mongodb.NewServer(dataStore, cfg.EncryptionKey, cfg.QueryStorage, cfg.Dump, authCache, cfg.Mongo, logger)
+	srv, err := mongodb.NewServer(dataStore, cfg.EncryptionKey, cfg.QueryStorage, cfg.Dump, authCache, cfg.Mongo, cfg.ProxyProtocol, cfg.Session, logger)
 	if err != nil {
 		logger.ErrorContext(ctx, "MongoDB proxy server init failed", slog.Any("error", err))
 		os.Exit(1)
@@ -480,7 +1051,7 @@ func runMigrate(ctx context.Context, flags *cliFlags) error {
 
 	logger.InfoContext(ctx, "Running migrations")
 
-	dataStore, err := store.New(ctx, cfg.DSN)
+	dataStore, err := store.New(ctx, cfg.DSN, storeOptions(cfg))
 	if err != nil {
 		return fmt.Errorf("failed to initialize store: %w", err)
 	}
@@ -494,6 +1065,46 @@ func runMigrate(ctx context.Context, flags *cliFlags) error {
 	return nil
 }
 
+// runMigratePlan reports pending migrations without applying them. With
+// printSQL the full script, bookkeeping included, goes to stdout so a DBA can
+// apply it through their own change management.
+func runMigratePlan(ctx context.Context, flags *cliFlags, printSQL bool) error {
+	cfg, err := loadConfigWithCLI(flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logLevel := config.ParseLogLevel(cfg.LogLevel)
+	logger, logCleanup := setupLogger(cfg.RunMode, logLevel)
+	if logCleanup != nil {
+		defer logCleanup()
+	}
+	slog.SetDefault(logger)
+
+	dataStore, err := store.New(ctx, cfg.DSN, store.Options{SkipMigrations: true})
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+	defer dataStore.Close()
+
+	plan, err := dataStore.PlanMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to plan migrations: %w", err)
+	}
+
+	if printSQL {
+		_, err := fmt.Fprint(os.Stdout, plan.SQL())
+		return err
+	}
+
+	logger.InfoContext(ctx, "Dry run: migrations not applied", slog.Int("pending", len(plan.Pending)))
+	for _, m := range plan.Pending {
+		logger.InfoContext(ctx, "Pending migration", slog.String("name", m.Name), slog.String("comment", m.Comment))
+	}
+
+	return nil
+}
+
 func runRollback(ctx context.Context, flags *cliFlags) error {
 	cfg, err := loadConfigWithCLI(flags)
 	if err != nil {
@@ -509,7 +1120,7 @@ func runRollback(ctx context.Context, flags *cliFlags) error {
 
 	logger.InfoContext(ctx, "Rolling back migrations")
 
-	dataStore, err := store.New(ctx, cfg.DSN)
+	dataStore, err := store.New(ctx, cfg.DSN, storeOptions(cfg))
 	if err != nil {
 		return fmt.Errorf("failed to initialize store: %w", err)
 	}
@@ -559,6 +1170,165 @@ func runMigrationStatus(ctx context.Context, flags *cliFlags) error {
 	return nil
 }
 
+// defaultCompactBatchSize is how many queries `db compact` handles per pass.
+const defaultCompactBatchSize = 500
+
+func runCompact(ctx context.Context, flags *cliFlags, olderThan time.Duration, batchSize int) error {
+	cfg, err := loadConfigWithCLI(flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logLevel := config.ParseLogLevel(cfg.LogLevel)
+	logger, logCleanup := setupLogger(cfg.RunMode, logLevel)
+	if logCleanup != nil {
+		defer logCleanup()
+	}
+	slog.SetDefault(logger)
+
+	if olderThan == 0 {
+		olderThan, err = cfg.QueryStorage.CompactAge()
+		if err != nil {
+			return fmt.Errorf("invalid query_storage.compact_after: %w", err)
+		}
+	}
+
+	dataStore, err := store.New(ctx, cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+	defer dataStore.Close()
+
+	before := time.Now().Add(-olderThan)
+	logger.InfoContext(ctx, "Compacting query rows", slog.Time("before", before))
+
+	total := store.CompactionResult{}
+
+	for {
+		result, err := dataStore.CompactQueryRows(ctx, before, max(batchSize, 1))
+		if result != nil {
+			total.Queries += result.Queries
+			total.Rows += result.Rows
+			total.RawBytes += result.RawBytes
+			total.CompressedBytes += result.CompressedBytes
+		}
+
+		if err != nil {
+			return fmt.Errorf("compaction failed after %d queries: %w", total.Queries, err)
+		}
+
+		if result.Queries == 0 {
+			break
+		}
+
+		logger.InfoContext(ctx, "Compaction progress", slog.Int("queries", total.Queries), slog.Int("rows", total.Rows))
+	}
+
+	logger.InfoContext(ctx, "Compaction completed",
+		slog.Int("queries", total.Queries),
+		slog.Int("rows", total.Rows),
+		slog.Int64("raw_bytes", total.RawBytes),
+		slog.Int64("compressed_bytes", total.CompressedBytes))
+
+	return nil
+}
+
+func runPurge(ctx context.Context, flags *cliFlags) error {
+	cfg, err := loadConfigWithCLI(flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logLevel := config.ParseLogLevel(cfg.LogLevel)
+	logger, logCleanup := setupLogger(cfg.RunMode, logLevel)
+	if logCleanup != nil {
+		defer logCleanup()
+	}
+	slog.SetDefault(logger)
+
+	dataStore, err := store.New(ctx, cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+	defer dataStore.Close()
+
+	logger.InfoContext(ctx, "Purging data past the retention policy")
+
+	result, err := dataStore.Purge(ctx, retentionPolicy(cfg.Retention), time.Now())
+	if err != nil {
+		return fmt.Errorf("purge failed: %w", err)
+	}
+
+	logger.InfoContext(ctx, "Purge completed",
+		slog.Int64("queries", result.Queries),
+		slog.Int64("rows", result.Rows),
+		slog.Int64("row_blobs", result.RowBlobs),
+		slog.Int64("connections", result.Connections),
+		slog.Int64("audit_events", result.AuditEvents))
+
+	return nil
+}
+
+// defaultTailLines is how many past queries `tail` prints before following.
+const defaultTailLines = 10
+
+func runTail(ctx context.Context, flags *cliFlags, cmd *cli.Command) error {
+	cfg, err := loadConfigWithCLI(flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Query lines go to stdout; keep logs out of the way.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: config.ParseLogLevel(cfg.LogLevel),
+	})))
+
+	dataStore, err := store.New(ctx, cfg.DSN, store.Options{SkipMigrations: true})
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+	defer dataStore.Close()
+
+	opts := tail.Options{
+		ErrorsOnly:  cmd.Bool("errors"),
+		MinDuration: cmd.Duration("min-duration"),
+		Backlog:     cmd.Int("lines"),
+		Interval:    cmd.Duration("interval"),
+		Redact:      cmd.Bool("redact"),
+		Color:       !cmd.Bool("no-color") && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout),
+	}
+
+	if name := cmd.String("database"); name != "" {
+		srv, err := dataStore.GetServerByName(ctx, name)
+		if err != nil {
+			return fmt.Errorf("database %q: %w", name, err)
+		}
+
+		opts.DatabaseID = &srv.UID
+	}
+
+	if username := cmd.String("user"); username != "" {
+		user, err := dataStore.GetUserByUsername(ctx, username)
+		if err != nil {
+			return fmt.Errorf("user %q: %w", username, err)
+		}
+
+		opts.UserID = &user.UID
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return tail.New(dataStore, opts, os.Stdout).Run(ctx)
+}
+
+// isTerminal reports whether f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func provisionTestData(ctx context.Context, dataStore *store.Store, encryptionKey []byte, logger *slog.Logger) error {
 	logger.InfoContext(ctx, "Test mode: provisioning test data...")
 
@@ -869,7 +1639,7 @@ func seedSampleQuery(ctx context.Context, dataStore *store.Store, userID, databa
 	}
 
 	// Close the connection so it doesn't linger as an "active" connection.
-	if err := dataStore.CloseConnection(ctx, conn.UID); err != nil {
+	if err := dataStore.CloseConnection(ctx, conn.UID, store.DisconnectReasonClientClosed); err != nil {
 		return fmt.Errorf("failed to close sample connection: %w", err)
 	}
 	return nil
@@ -877,6 +1647,8 @@ func seedSampleQuery(ctx context.Context, dataStore *store.Store, userID, databa
 
 var errDumpAnonymiseUsage = errors.New("usage: dbbat dump anonymise <input-file> [output-file]")
 
+var errWeakPosture = errors.New("refusing to start with strict_posture")
+
 func runDumpAnonymise(cmd *cli.Command) error {
 	args := cmd.Args()
 	if args.Len() < 1 {
@@ -900,22 +1672,20 @@ func runDumpAnonymise(cmd *cli.Command) error {
 	return nil
 }
 
-// checkDatabaseConfigurations checks if any configured target databases match the storage DSN.
-// Logs a warning for each match found. This handles databases that were configured
-// before the storage DSN validation was added.
-func checkDatabaseConfigurations(ctx context.Context, dataStore *store.Store, logger *slog.Logger) {
-	databases, err := dataStore.ListServers(ctx)
-	if err != nil {
-		logger.WarnContext(ctx, "failed to check database configurations", slog.Any("error", err))
-		return
+// checkPosture logs the weak settings of the deployment and, in strict
+// mode, refuses to start when there is any.
+func checkPosture(ctx context.Context, cfg *config.Config, dataStore *store.Store, logger *slog.Logger) error {
+	findings := posture.Check(ctx, cfg, dataStore, logger)
+
+	for _, finding := range findings {
+		logger.WarnContext(ctx, "SECURITY WARNING: "+finding.Message,
+			slog.String("check", finding.Check),
+			slog.String("recommendation", finding.Recommendation))
 	}
 
-	for _, db := range databases {
-		if dataStore.MatchesStorageDSN(db.Host, db.Port, db.DatabaseName) {
-			logger.WarnContext(ctx, "SECURITY WARNING: database configuration matches storage DSN",
-				slog.String("database_name", db.Name),
-				slog.String("target", fmt.Sprintf("%s:%d/%s", db.Host, db.Port, db.DatabaseName)),
-				slog.String("recommendation", "use a separate database for DBBat storage to prevent privilege escalation"))
-		}
+	if cfg.StrictPosture && len(findings) > 0 {
+		return fmt.Errorf("%w: %d weak setting(s), see the warnings above", errWeakPosture, len(findings))
 	}
+
+	return nil
 }